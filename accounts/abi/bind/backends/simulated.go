@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -70,6 +71,10 @@ type SimulatedBackend struct {
 
 	events *filters.EventSystem // Event system for filtering log events live
 
+	headLag      uint64 // Number of blocks the reported head trails the actual one (atomic)
+	receiptDelay uint64 // Number of blocks after which receipts become available (atomic)
+	reorgs       int    // Number of simulated reorgs, used to make side chains unique
+
 	config *params.ChainConfig
 }
 
@@ -164,6 +169,69 @@ func (b *SimulatedBackend) Fork(ctx context.Context, parent common.Hash) error {
 	return nil
 }
 
+// Reorg replaces the last depth blocks of the canonical chain with an empty side
+// chain one block longer, emitting the same events as a reorg on a live network.
+// Transactions of the dropped blocks are not included in the new chain, so they
+// can be re-sent like after a real reorg. The pending block must be empty.
+func (b *SimulatedBackend) Reorg(depth uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pendingBlock.Transactions()) != 0 {
+		return errors.New("pending block dirty")
+	}
+	head := b.blockchain.CurrentBlock()
+	if depth == 0 || depth > head.NumberU64() {
+		return fmt.Errorf("invalid reorg depth %d at block %d", depth, head.NumberU64())
+	}
+	b.reorgs++
+	extra := []byte(fmt.Sprintf("reorg %d", b.reorgs))
+
+	parent := b.blockchain.GetBlockByNumber(head.NumberU64() - depth)
+	blocks, _ := core.GenerateChain(b.config, parent, ethash.NewFaker(), b.database, int(depth)+1, func(i int, gen *core.BlockGen) {
+		gen.SetExtra(extra)
+	})
+	if _, err := b.blockchain.InsertChain(blocks); err != nil {
+		return err
+	}
+	b.rollback(blocks[len(blocks)-1])
+	return nil
+}
+
+// SetHeadLag makes the backend trail the actual chain head by the given number
+// of blocks, as a lagging node would: the latest block, new head notifications
+// and receipts only reflect the chain up to the lagging head. Zero disables the
+// lag.
+func (b *SimulatedBackend) SetHeadLag(blocks uint64) {
+	atomic.StoreUint64(&b.headLag, blocks)
+}
+
+// SetReceiptDelay makes receipts unavailable until the given number of blocks
+// have been added on top of the block including the transaction, as on nodes
+// which index receipts asynchronously. Zero disables the delay.
+func (b *SimulatedBackend) SetReceiptDelay(blocks uint64) {
+	atomic.StoreUint64(&b.receiptDelay, blocks)
+}
+
+// visibleHead returns the head block reported to callers, trailing the actual
+// head by the configured lag.
+func (b *SimulatedBackend) visibleHead() *types.Block {
+	head := b.blockchain.CurrentBlock()
+	lag := atomic.LoadUint64(&b.headLag)
+	if lag == 0 {
+		return head
+	}
+	if head.NumberU64() <= lag {
+		return b.blockchain.Genesis()
+	}
+	return b.blockchain.GetBlockByNumber(head.NumberU64() - lag)
+}
+
+// visible returns whether a block with the given number is known to callers.
+func (b *SimulatedBackend) visible(number uint64) bool {
+	return atomic.LoadUint64(&b.headLag) == 0 || number <= b.visibleHead().NumberU64()
+}
+
 // stateByBlockNumber retrieves a state by a given blocknumber.
 func (b *SimulatedBackend) stateByBlockNumber(ctx context.Context, blockNumber *big.Int) (*state.StateDB, error) {
 	if blockNumber == nil || blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) == 0 {
@@ -234,10 +302,13 @@ func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	receipt, _, _, _ := rawdb.ReadReceipt(b.database, txHash, b.config)
+	receipt, _, number, _ := rawdb.ReadReceipt(b.database, txHash, b.config)
 	if receipt == nil {
 		return nil, ethereum.NotFound
 	}
+	if number+atomic.LoadUint64(&b.receiptDelay) > b.visibleHead().NumberU64() {
+		return nil, ethereum.NotFound
+	}
 	return receipt, nil
 }
 
@@ -275,7 +346,7 @@ func (b *SimulatedBackend) blockByHash(ctx context.Context, hash common.Hash) (*
 	}
 
 	block := b.blockchain.GetBlockByHash(hash)
-	if block != nil {
+	if block != nil && b.visible(block.NumberU64()) {
 		return block, nil
 	}
 
@@ -295,11 +366,11 @@ func (b *SimulatedBackend) BlockByNumber(ctx context.Context, number *big.Int) (
 // (associated with its hash) if found without Lock.
 func (b *SimulatedBackend) blockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
 	if number == nil || number.Cmp(b.pendingBlock.Number()) == 0 {
-		return b.blockchain.CurrentBlock(), nil
+		return b.visibleHead(), nil
 	}
 
 	block := b.blockchain.GetBlockByNumber(uint64(number.Int64()))
-	if block == nil {
+	if block == nil || !b.visible(block.NumberU64()) {
 		return nil, errBlockDoesNotExist
 	}
 
@@ -316,7 +387,7 @@ func (b *SimulatedBackend) HeaderByHash(ctx context.Context, hash common.Hash) (
 	}
 
 	header := b.blockchain.GetHeaderByHash(hash)
-	if header == nil {
+	if header == nil || !b.visible(header.Number.Uint64()) {
 		return nil, errBlockDoesNotExist
 	}
 
@@ -330,9 +401,11 @@ func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, block *big.Int) (
 	defer b.mu.Unlock()
 
 	if block == nil || block.Cmp(b.pendingBlock.Number()) == 0 {
-		return b.blockchain.CurrentHeader(), nil
+		return b.visibleHead().Header(), nil
+	}
+	if !b.visible(block.Uint64()) {
+		return nil, nil
 	}
-
 	return b.blockchain.GetHeaderByNumber(uint64(block.Int64())), nil
 }
 
@@ -760,6 +833,15 @@ func (b *SimulatedBackend) SubscribeNewHead(ctx context.Context, ch chan<- *type
 		for {
 			select {
 			case head := <-sink:
+				// Report the head trailing the new one if lagging
+				if lag := atomic.LoadUint64(&b.headLag); lag > 0 {
+					if head.Number.Uint64() <= lag {
+						continue
+					}
+					if head = b.blockchain.GetHeaderByNumber(head.Number.Uint64() - lag); head == nil {
+						continue
+					}
+				}
 				select {
 				case ch <- head:
 				case err := <-sub.Err():
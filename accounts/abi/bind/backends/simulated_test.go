@@ -1374,3 +1374,104 @@ func TestCommitReturnValue(t *testing.T) {
 		t.Error("Could not retrieve the just created block (side-chain)")
 	}
 }
+
+// Tests that a lagging backend reports the head and blocks trailing the actual
+// chain head.
+func TestHeadLag(t *testing.T) {
+	sim := simTestBackend(crypto.PubkeyToAddress(testKey.PublicKey))
+	defer sim.Close()
+
+	heads := make(chan *types.Header, 10)
+	sub, _ := sim.SubscribeNewHead(context.Background(), heads)
+	defer sub.Unsubscribe()
+
+	sim.SetHeadLag(2)
+	for i := 0; i < 5; i++ {
+		sim.Commit()
+	}
+	head, err := sim.HeaderByNumber(context.Background(), nil)
+	if err != nil || head.Number.Uint64() != 3 {
+		t.Fatalf("head mismatch: have %v (%v), want 3", head.Number, err)
+	}
+	if block, _ := sim.BlockByNumber(context.Background(), big.NewInt(4)); block != nil {
+		t.Errorf("block beyond lagging head returned")
+	}
+	if header, _ := sim.HeaderByNumber(context.Background(), big.NewInt(5)); header != nil {
+		t.Errorf("header beyond lagging head returned")
+	}
+	for want := uint64(1); want <= 3; want++ {
+		select {
+		case head := <-heads:
+			if head.Number.Uint64() != want {
+				t.Fatalf("head event mismatch: have %d, want %d", head.Number, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("head event %d missing", want)
+		}
+	}
+	sim.SetHeadLag(0)
+	if head, _ := sim.HeaderByNumber(context.Background(), nil); head.Number.Uint64() != 5 {
+		t.Errorf("head mismatch after disabling lag: have %d, want 5", head.Number)
+	}
+}
+
+// Tests that receipts are only served once enough blocks were added on top.
+func TestReceiptDelay(t *testing.T) {
+	testAddr := crypto.PubkeyToAddress(testKey.PublicKey)
+	sim := simTestBackend(testAddr)
+	defer sim.Close()
+
+	sim.SetReceiptDelay(2)
+
+	head, _ := sim.HeaderByNumber(context.Background(), nil)
+	gasPrice := new(big.Int).Add(head.BaseFee, big.NewInt(1))
+	tx, _ := types.SignTx(types.NewTransaction(0, testAddr, big.NewInt(1000), params.TxGas, gasPrice, nil), types.HomesteadSigner{}, testKey)
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("could not add tx to pending block: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		sim.Commit()
+		receipt, _ := sim.TransactionReceipt(context.Background(), tx.Hash())
+		if (receipt != nil) != (i == 2) {
+			t.Errorf("commit %d: receipt availability mismatch: have %v", i, receipt != nil)
+		}
+	}
+}
+
+// Tests that simulated reorgs replace the requested number of blocks and drop
+// their transactions.
+func TestReorg(t *testing.T) {
+	testAddr := crypto.PubkeyToAddress(testKey.PublicKey)
+	sim := simTestBackend(testAddr)
+	defer sim.Close()
+
+	head, _ := sim.HeaderByNumber(context.Background(), nil)
+	gasPrice := new(big.Int).Add(head.BaseFee, big.NewInt(1))
+	tx, _ := types.SignTx(types.NewTransaction(0, testAddr, big.NewInt(1000), params.TxGas, gasPrice, nil), types.HomesteadSigner{}, testKey)
+	sim.SendTransaction(context.Background(), tx)
+	for i := 0; i < 3; i++ {
+		sim.Commit()
+	}
+	old := sim.blockchain.CurrentBlock()
+	if err := sim.Reorg(0); err == nil {
+		t.Errorf("empty reorg accepted")
+	}
+	if err := sim.Reorg(4); err == nil {
+		t.Errorf("reorg beyond genesis accepted")
+	}
+	for i := 0; i < 2; i++ {
+		if err := sim.Reorg(3); err != nil {
+			t.Fatalf("reorg %d failed: %v", i, err)
+		}
+	}
+	head, _ = sim.HeaderByNumber(context.Background(), nil)
+	if head.Number.Uint64() != 5 {
+		t.Errorf("head number mismatch: have %d, want 5", head.Number)
+	}
+	if block := sim.blockchain.GetBlockByNumber(3); block.Hash() == old.Hash() {
+		t.Errorf("block 3 not replaced")
+	}
+	if receipt, _ := sim.TransactionReceipt(context.Background(), tx.Hash()); receipt != nil {
+		t.Errorf("transaction of dropped block still included")
+	}
+}
@@ -60,6 +60,8 @@ type TransactOpts struct {
 	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
 
 	NoSend bool // Do all transact steps but do not send the transaction
+
+	Retry *RetryPolicy // Policy to resubmit rejected or stuck transactions (nil = no retries)
 }
 
 // FilterOpts is the collection of options to fine tune filtering for events
@@ -76,6 +78,9 @@ type FilterOpts struct {
 type WatchOpts struct {
 	Start   *uint64         // Start of the queried range (nil = latest)
 	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
+
+	Reconnect bool            // Resubscribe and backfill missed logs if the subscription fails
+	Gaps      chan<- WatchGap // Notified of block ranges whose logs could not be backfilled (nil = log only)
 }
 
 // MetaData collects all metadata for a bound contract.
@@ -361,27 +366,7 @@ func (c *BoundContract) getNonce(opts *TransactOpts) (uint64, error) {
 // transact executes an actual transaction invocation, first deriving any missing
 // authorization fields, and then scheduling the transaction for execution.
 func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, input []byte) (*types.Transaction, error) {
-	if opts.GasPrice != nil && (opts.GasFeeCap != nil || opts.GasTipCap != nil) {
-		return nil, errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified")
-	}
-	// Create the transaction
-	var (
-		rawTx *types.Transaction
-		err   error
-	)
-	if opts.GasPrice != nil {
-		rawTx, err = c.createLegacyTx(opts, contract, input)
-	} else {
-		// Only query for basefee if gasPrice not specified
-		if head, errHead := c.transactor.HeaderByNumber(ensureContext(opts.Context), nil); errHead != nil {
-			return nil, errHead
-		} else if head.BaseFee != nil {
-			rawTx, err = c.createDynamicTx(opts, contract, input, head)
-		} else {
-			// Chain is not London ready -> use legacy transaction
-			rawTx, err = c.createLegacyTx(opts, contract, input)
-		}
-	}
+	rawTx, err := c.createTx(opts, contract, input)
 	if err != nil {
 		return nil, err
 	}
@@ -396,12 +381,36 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 	if opts.NoSend {
 		return signedTx, nil
 	}
+	if opts.Retry != nil {
+		return c.sendWithRetry(opts, contract, input, signedTx)
+	}
 	if err := c.transactor.SendTransaction(ensureContext(opts.Context), signedTx); err != nil {
 		return nil, err
 	}
 	return signedTx, nil
 }
 
+// createTx assembles an unsigned transaction, deriving any missing fee, gas and
+// nonce fields.
+func (c *BoundContract) createTx(opts *TransactOpts, contract *common.Address, input []byte) (*types.Transaction, error) {
+	if opts.GasPrice != nil && (opts.GasFeeCap != nil || opts.GasTipCap != nil) {
+		return nil, errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified")
+	}
+	if opts.GasPrice != nil {
+		return c.createLegacyTx(opts, contract, input)
+	}
+	// Only query for basefee if gasPrice not specified
+	head, err := c.transactor.HeaderByNumber(ensureContext(opts.Context), nil)
+	if err != nil {
+		return nil, err
+	}
+	if head.BaseFee != nil {
+		return c.createDynamicTx(opts, contract, input, head)
+	}
+	// Chain is not London ready -> use legacy transaction
+	return c.createLegacyTx(opts, contract, input)
+}
+
 // FilterLogs filters contract logs for past blocks, returning the necessary
 // channels to construct a strongly typed bound iterator on top of them.
 func (c *BoundContract) FilterLogs(opts *FilterOpts, name string, query ...[]interface{}) (chan types.Log, event.Subscription, error) {
@@ -475,6 +484,13 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 	if opts.Start != nil {
 		config.FromBlock = new(big.Int).SetUint64(*opts.Start)
 	}
+	if opts.Reconnect {
+		sub, err := c.watchLogsReconnecting(opts, config, logs)
+		if err != nil {
+			return nil, nil, err
+		}
+		return logs, sub, nil
+	}
 	sub, err := c.filterer.SubscribeFilterLogs(ensureContext(opts.Context), config, logs)
 	if err != nil {
 		return nil, nil, err
@@ -0,0 +1,314 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// defaultRetryAttempts is the number of submissions made if the policy does
+	// not specify one.
+	defaultRetryAttempts = 3
+
+	// defaultBumpPercent is the fee increase of replacement transactions if the
+	// policy does not specify one. It matches the default minimum price bump
+	// required by the transaction pool.
+	defaultBumpPercent = 10
+)
+
+// errFeeCapReached is returned if the fees of a transaction can't be bumped any
+// further without exceeding the configured maximum.
+var errFeeCapReached = errors.New("fee cap reached")
+
+// RetryPolicy configures how a transactor resubmits transactions which were
+// rejected by the node or which are not included in time. Errors are matched by
+// their message, since the node's errors arrive as plain strings over RPC.
+type RetryPolicy struct {
+	MaxAttempts  int           // Maximum number of submissions, including replacements (0 = 3)
+	RefreshNonce bool          // Re-query the pending nonce if the node reports it too low (only if TransactOpts.Nonce is nil)
+	BumpTimeout  time.Duration // Time to wait for inclusion before replacing with higher fees (0 = never bump)
+	BumpPercent  uint64        // Fee increase of replacements in percent (0 = 10)
+	MaxFeeCap    *big.Int      // Upper bound for the gas price or fee cap of replacements (nil = unbounded)
+	PollInterval time.Duration // Interval between receipt queries while waiting for inclusion (0 = 1s)
+
+	OnReplace func(old, replacement *types.Transaction) // Optional callback for every replacement sent
+}
+
+func (p *RetryPolicy) attempts() int {
+	if p.MaxAttempts <= 0 {
+		return defaultRetryAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) bumpPercent() uint64 {
+	if p.BumpPercent == 0 {
+		return defaultBumpPercent
+	}
+	return p.BumpPercent
+}
+
+func (p *RetryPolicy) pollInterval() time.Duration {
+	if p.PollInterval <= 0 {
+		return time.Second
+	}
+	return p.PollInterval
+}
+
+// TransactOptsPolicy is a simplified retry policy for the sessions of generated
+// bindings. If set on a session, its transactions are resubmitted with bumped
+// tips whenever they are not included within the deadline, and the session
+// methods only return once one of the submissions has been included.
+type TransactOptsPolicy struct {
+	MaxRetries int           // Maximum number of resubmissions after the first submission
+	TipBump    uint64        // Tip and fee cap increase of resubmissions in percent (0 = 10)
+	Deadline   time.Duration // Time to wait for inclusion before resubmitting (0 = never resubmit)
+}
+
+// retryPolicy converts the session policy into a retry policy, keeping any other
+// settings of the base policy.
+func (p *TransactOptsPolicy) retryPolicy(base *RetryPolicy) *RetryPolicy {
+	policy := new(RetryPolicy)
+	if base != nil {
+		*policy = *base
+	}
+	policy.MaxAttempts = p.MaxRetries + 1
+	policy.BumpPercent = p.TipBump
+	policy.BumpTimeout = p.Deadline
+	return policy
+}
+
+// isNonceTooLow reports whether the node rejected a transaction because its nonce
+// has already been used.
+func isNonceTooLow(err error) bool {
+	return strings.Contains(err.Error(), "nonce too low")
+}
+
+// isUnderpriced reports whether the node rejected a transaction, or a replacement,
+// because of its fees.
+func isUnderpriced(err error) bool {
+	return strings.Contains(err.Error(), "underpriced")
+}
+
+// sendWithRetry submits a signed transaction according to the retry policy of
+// the options. If the nonce is reported too low, the transaction is recreated
+// with a fresh nonce; if it is reported underpriced, its fees are bumped.
+func (c *BoundContract) sendWithRetry(opts *TransactOpts, contract *common.Address, input []byte, tx *types.Transaction) (*types.Transaction, error) {
+	policy := opts.Retry
+	for attempt := 1; ; attempt++ {
+		err := c.transactor.SendTransaction(ensureContext(opts.Context), tx)
+		if err == nil {
+			return tx, nil
+		}
+		if attempt >= policy.attempts() {
+			return nil, err
+		}
+		var rawTx *types.Transaction
+		switch {
+		case isNonceTooLow(err) && policy.RefreshNonce && opts.Nonce == nil:
+			log.Debug("Transaction nonce too low, refreshing", "nonce", tx.Nonce())
+			if rawTx, err = c.createTx(opts, contract, input); err != nil {
+				return nil, err
+			}
+		case isUnderpriced(err):
+			log.Debug("Transaction underpriced, bumping fees", "hash", tx.Hash())
+			if rawTx, err = bumpFees(tx, policy.bumpPercent(), policy.MaxFeeCap); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+		if tx, err = opts.Signer(opts.From, rawTx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// WaitMined waits for a transaction sent through the contract, or any of its
+// replacements, to be included. If the retry policy of the options sets a bump
+// timeout, the transaction is replaced with one paying higher fees every time
+// the timeout elapses, until the maximum number of attempts is reached. The
+// receipt of whichever transaction was included is returned.
+func (c *BoundContract) WaitMined(ctx context.Context, b DeployBackend, opts *TransactOpts, tx *types.Transaction) (*types.Receipt, error) {
+	policy := opts.Retry
+	if policy == nil {
+		return WaitMined(ctx, b, tx)
+	}
+	ticker := time.NewTicker(policy.pollInterval())
+	defer ticker.Stop()
+
+	var (
+		sent     = []*types.Transaction{tx}
+		lastSent = time.Now()
+		bumping  = policy.BumpTimeout > 0
+	)
+	for {
+		for _, tx := range sent {
+			receipt, err := b.TransactionReceipt(ctx, tx.Hash())
+			if err == nil {
+				return receipt, nil
+			}
+			if !errors.Is(err, ethereum.NotFound) {
+				log.Trace("Receipt retrieval failed", "hash", tx.Hash(), "err", err)
+			}
+		}
+		if bumping && time.Since(lastSent) >= policy.BumpTimeout {
+			if len(sent) >= policy.attempts() {
+				bumping = false
+			} else if replacement, err := c.replace(ctx, opts, sent[len(sent)-1]); err != nil {
+				log.Debug("Failed to replace stuck transaction", "hash", tx.Hash(), "err", err)
+				bumping = !errors.Is(err, errFeeCapReached) && !isNonceTooLow(err)
+			} else {
+				if policy.OnReplace != nil {
+					policy.OnReplace(sent[len(sent)-1], replacement)
+				}
+				sent = append(sent, replacement)
+			}
+			lastSent = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// replace signs and sends a copy of the transaction paying higher fees.
+func (c *BoundContract) replace(ctx context.Context, opts *TransactOpts, tx *types.Transaction) (*types.Transaction, error) {
+	rawTx, err := bumpFees(tx, opts.Retry.bumpPercent(), opts.Retry.MaxFeeCap)
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := opts.Signer(opts.From, rawTx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.transactor.SendTransaction(ctx, signedTx); err != nil {
+		return nil, err
+	}
+	return signedTx, nil
+}
+
+// bumpFees returns an unsigned copy of the transaction with its fees raised by
+// the given percentage, capped at maxFee if set.
+func bumpFees(tx *types.Transaction, percent uint64, maxFee *big.Int) (*types.Transaction, error) {
+	bump := func(v *big.Int) *big.Int {
+		bumped := new(big.Int).Mul(v, new(big.Int).SetUint64(100+percent))
+		bumped.Div(bumped, big.NewInt(100))
+		if bumped.Cmp(v) <= 0 {
+			bumped.Add(v, common.Big1)
+		}
+		return bumped
+	}
+	capped := func(v *big.Int) *big.Int {
+		if maxFee != nil && v.Cmp(maxFee) > 0 {
+			return new(big.Int).Set(maxFee)
+		}
+		return v
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		price := capped(bump(tx.GasPrice()))
+		if price.Cmp(tx.GasPrice()) <= 0 {
+			return nil, errFeeCapReached
+		}
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: price,
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		}), nil
+
+	case types.DynamicFeeTxType:
+		feeCap := capped(bump(tx.GasFeeCap()))
+		if feeCap.Cmp(tx.GasFeeCap()) <= 0 {
+			return nil, errFeeCapReached
+		}
+		tipCap := bump(tx.GasTipCap())
+		if tipCap.Cmp(feeCap) > 0 {
+			tipCap = feeCap
+		}
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasTipCap:  tipCap,
+			GasFeeCap:  feeCap,
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("can't bump fees of transaction type %d", tx.Type())
+	}
+}
+
+// TransactWithPolicy sends a transaction through the given transact function,
+// which is used by the sessions of generated bindings. If a policy is set, it
+// waits for the transaction to be included, resubmitting it with bumped tips
+// according to the policy, and returns the submission which was included.
+func (c *BoundContract) TransactWithPolicy(opts *TransactOpts, policy *TransactOptsPolicy, transact func(*TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	if policy == nil || opts.NoSend {
+		return transact(opts)
+	}
+	backend, ok := c.transactor.(DeployBackend)
+	if !ok {
+		return nil, errors.New("transactor can't retrieve receipts to wait for inclusion")
+	}
+	// Apply the policy on a copy, the options of the session must not change
+	var (
+		sessionOpts = *opts
+		sent        = make(map[common.Hash]*types.Transaction)
+	)
+	sessionOpts.Retry = policy.retryPolicy(opts.Retry)
+	sessionOpts.Retry.OnReplace = func(old, replacement *types.Transaction) {
+		sent[replacement.Hash()] = replacement
+		if opts.Retry != nil && opts.Retry.OnReplace != nil {
+			opts.Retry.OnReplace(old, replacement)
+		}
+	}
+	tx, err := transact(&sessionOpts)
+	if err != nil {
+		return nil, err
+	}
+	sent[tx.Hash()] = tx
+
+	receipt, err := c.WaitMined(ensureContext(opts.Context), backend, &sessionOpts, tx)
+	if err != nil {
+		return nil, err
+	}
+	if included, ok := sent[receipt.TxHash]; ok {
+		return included, nil
+	}
+	return nil, fmt.Errorf("receipt of unknown transaction %x", receipt.TxHash)
+}
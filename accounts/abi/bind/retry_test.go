@@ -0,0 +1,182 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// retryTransactor is a transactor rejecting submissions with queued errors and
+// tracking the accepted ones.
+type retryTransactor struct {
+	mockTransactor
+	nonce  uint64
+	errs   []error
+	sent   []*types.Transaction
+	mined  common.Hash
+	minedN int // Number of accepted submissions after which the last one is mined
+}
+
+func (rt *retryTransactor) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return rt.nonce, nil
+}
+
+func (rt *retryTransactor) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if len(rt.errs) > 0 {
+		err := rt.errs[0]
+		rt.errs = rt.errs[1:]
+		return err
+	}
+	rt.sent = append(rt.sent, tx)
+	if len(rt.sent) == rt.minedN {
+		rt.mined = tx.Hash()
+	}
+	return nil
+}
+
+func (rt *retryTransactor) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	if hash == rt.mined {
+		return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful}, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (rt *retryTransactor) CodeAt(ctx context.Context, account common.Address, number *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func TestTransactRetry(t *testing.T) {
+	rt := &retryTransactor{
+		mockTransactor: mockTransactor{baseFee: big.NewInt(100), gasTipCap: big.NewInt(10)},
+		errs: []error{
+			errors.New("nonce too low"),
+			errors.New("replacement transaction underpriced"),
+		},
+	}
+	c := bind.NewBoundContract(common.Address{}, abi.ABI{}, nil, rt, nil)
+	opts := &bind.TransactOpts{
+		Signer:   mockSign,
+		GasLimit: 21000,
+		Retry:    &bind.RetryPolicy{RefreshNonce: true},
+	}
+	// The first failure should refresh the nonce, the second one bump the fees
+	rt.nonce = 5
+	tx, err := c.RawTransact(opts, nil)
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if tx.Nonce() != 5 {
+		t.Errorf("nonce mismatch: have %d, want 5", tx.Nonce())
+	}
+	if tx.GasTipCap().Cmp(big.NewInt(11)) != 0 || tx.GasFeeCap().Cmp(big.NewInt(231)) != 0 {
+		t.Errorf("fees not bumped: tip %v, cap %v", tx.GasTipCap(), tx.GasFeeCap())
+	}
+	// Without a policy, errors should be returned as is
+	rt.errs = []error{errors.New("nonce too low")}
+	opts.Retry = nil
+	if _, err := c.RawTransact(opts, nil); err == nil {
+		t.Error("error not returned without retry policy")
+	}
+	// Retries should stop after the maximum number of attempts
+	rt.errs = []error{errors.New("underpriced"), errors.New("underpriced"), errors.New("underpriced")}
+	opts.Retry = &bind.RetryPolicy{MaxAttempts: 2}
+	if _, err := c.RawTransact(opts, nil); err == nil {
+		t.Error("retried beyond the maximum attempts")
+	}
+}
+
+func TestWaitMinedBump(t *testing.T) {
+	rt := &retryTransactor{
+		mockTransactor: mockTransactor{baseFee: big.NewInt(100), gasTipCap: big.NewInt(10)},
+		minedN:         3,
+	}
+	c := bind.NewBoundContract(common.Address{}, abi.ABI{}, nil, rt, nil)
+
+	var replaced int
+	opts := &bind.TransactOpts{
+		Signer:   mockSign,
+		GasLimit: 21000,
+		Retry: &bind.RetryPolicy{
+			BumpTimeout:  time.Millisecond,
+			MaxFeeCap:    big.NewInt(250),
+			PollInterval: time.Millisecond,
+			OnReplace:    func(old, replacement *types.Transaction) { replaced++ },
+		},
+	}
+	tx, err := c.RawTransact(opts, nil)
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receipt, err := c.WaitMined(ctx, rt, opts, tx)
+	if err != nil {
+		t.Fatalf("failed to wait for transaction: %v", err)
+	}
+	if receipt.TxHash != rt.sent[2].Hash() || replaced != 2 {
+		t.Errorf("unexpected replacement: %d replacements, mined %x", replaced, receipt.TxHash)
+	}
+	if cap := rt.sent[2].GasFeeCap(); cap.Cmp(big.NewInt(250)) != 0 {
+		t.Errorf("fee cap mismatch: have %v, want 250", cap)
+	}
+}
+
+func TestTransactWithPolicy(t *testing.T) {
+	rt := &retryTransactor{
+		mockTransactor: mockTransactor{baseFee: big.NewInt(100), gasTipCap: big.NewInt(10)},
+		minedN:         2,
+	}
+	c := bind.NewBoundContract(common.Address{}, abi.ABI{}, nil, rt, nil)
+
+	opts := &bind.TransactOpts{Signer: mockSign, GasLimit: 21000}
+	policy := &bind.TransactOptsPolicy{MaxRetries: 3, TipBump: 50, Deadline: time.Millisecond}
+	transact := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return c.RawTransact(opts, nil)
+	}
+	tx, err := c.TransactWithPolicy(opts, policy, transact)
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if len(rt.sent) != 2 || tx.Hash() != rt.sent[1].Hash() {
+		t.Fatalf("included transaction not returned: %d sent", len(rt.sent))
+	}
+	if tip := tx.GasTipCap(); tip.Cmp(big.NewInt(15)) != 0 {
+		t.Errorf("tip mismatch: have %v, want 15", tip)
+	}
+	if opts.Retry != nil {
+		t.Error("session options modified")
+	}
+	// Without a policy, the transaction should be sent once without waiting
+	rt.sent, rt.minedN = nil, 0
+	if _, err := c.TransactWithPolicy(opts, nil, transact); err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+	if len(rt.sent) != 1 {
+		t.Errorf("transaction sent %d times, want 1", len(rt.sent))
+	}
+}
@@ -199,6 +199,7 @@ var (
 	  Contract     *{{.Type}}        // Generic contract binding to set the session for
 	  CallOpts     bind.CallOpts     // Call options to use throughout this session
 	  TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+	  Policy       *bind.TransactOptsPolicy // Optional policy to resubmit transactions until included (nil = send once)
 	}
 
 	// {{.Type}}CallerSession is an auto generated read-only Go binding around an Ethereum contract,
@@ -213,6 +214,7 @@ var (
 	type {{.Type}}TransactorSession struct {
 	  Contract     *{{.Type}}Transactor // Generic contract transactor binding to set the session for
 	  TransactOpts bind.TransactOpts    // Transaction auth options to use throughout this session
+	  Policy       *bind.TransactOptsPolicy // Optional policy to resubmit transactions until included (nil = send once)
 	}
 
 	// {{.Type}}Raw is an auto generated low-level Go binding around an Ethereum contract.
@@ -367,14 +369,18 @@ var (
 		//
 		// Solidity: {{.Original.String}}
 		func (_{{$contract.Type}} *{{$contract.Type}}Session) {{.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type $structs}} {{end}}) (*types.Transaction, error) {
-		  return _{{$contract.Type}}.Contract.{{.Normalized.Name}}(&_{{$contract.Type}}.TransactOpts {{range $i, $_ := .Normalized.Inputs}}, {{.Name}}{{end}})
+		  return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.TransactWithPolicy(&_{{$contract.Type}}.TransactOpts, _{{$contract.Type}}.Policy, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return _{{$contract.Type}}.Contract.{{.Normalized.Name}}(opts {{range $i, $_ := .Normalized.Inputs}}, {{.Name}}{{end}})
+		  })
 		}
 
 		// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.ID}}.
 		//
 		// Solidity: {{.Original.String}}
 		func (_{{$contract.Type}} *{{$contract.Type}}TransactorSession) {{.Normalized.Name}}({{range $i, $_ := .Normalized.Inputs}}{{if ne $i 0}},{{end}} {{.Name}} {{bindtype .Type $structs}} {{end}}) (*types.Transaction, error) {
-		  return _{{$contract.Type}}.Contract.{{.Normalized.Name}}(&_{{$contract.Type}}.TransactOpts {{range $i, $_ := .Normalized.Inputs}}, {{.Name}}{{end}})
+		  return _{{$contract.Type}}.Contract.contract.TransactWithPolicy(&_{{$contract.Type}}.TransactOpts, _{{$contract.Type}}.Policy, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return _{{$contract.Type}}.Contract.{{.Normalized.Name}}(opts {{range $i, $_ := .Normalized.Inputs}}, {{.Name}}{{end}})
+		  })
 		}
 	{{end}}
 
@@ -390,14 +396,18 @@ var (
 		//
 		// Solidity: {{.Fallback.Original.String}}
 		func (_{{$contract.Type}} *{{$contract.Type}}Session) Fallback(calldata []byte) (*types.Transaction, error) {
-		  return _{{$contract.Type}}.Contract.Fallback(&_{{$contract.Type}}.TransactOpts, calldata)
+		  return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.TransactWithPolicy(&_{{$contract.Type}}.TransactOpts, _{{$contract.Type}}.Policy, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return _{{$contract.Type}}.Contract.Fallback(opts, calldata)
+		  })
 		}
 	
 		// Fallback is a paid mutator transaction binding the contract fallback function.
 		// 
 		// Solidity: {{.Fallback.Original.String}}
 		func (_{{$contract.Type}} *{{$contract.Type}}TransactorSession) Fallback(calldata []byte) (*types.Transaction, error) {
-		  return _{{$contract.Type}}.Contract.Fallback(&_{{$contract.Type}}.TransactOpts, calldata)
+		  return _{{$contract.Type}}.Contract.contract.TransactWithPolicy(&_{{$contract.Type}}.TransactOpts, _{{$contract.Type}}.Policy, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return _{{$contract.Type}}.Contract.Fallback(opts, calldata)
+		  })
 		}
 	{{end}}
 
@@ -413,14 +423,18 @@ var (
 		//
 		// Solidity: {{.Receive.Original.String}}
 		func (_{{$contract.Type}} *{{$contract.Type}}Session) Receive() (*types.Transaction, error) {
-		  return _{{$contract.Type}}.Contract.Receive(&_{{$contract.Type}}.TransactOpts)
+		  return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.TransactWithPolicy(&_{{$contract.Type}}.TransactOpts, _{{$contract.Type}}.Policy, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return _{{$contract.Type}}.Contract.Receive(opts)
+		  })
 		}
 	
 		// Receive is a paid mutator transaction binding the contract receive function.
 		// 
 		// Solidity: {{.Receive.Original.String}}
 		func (_{{$contract.Type}} *{{$contract.Type}}TransactorSession) Receive() (*types.Transaction, error) {
-		  return _{{$contract.Type}}.Contract.Receive(&_{{$contract.Type}}.TransactOpts)
+		  return _{{$contract.Type}}.Contract.contract.TransactWithPolicy(&_{{$contract.Type}}.TransactOpts, _{{$contract.Type}}.Policy, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return _{{$contract.Type}}.Contract.Receive(opts)
+		  })
 		}
 	{{end}}
 
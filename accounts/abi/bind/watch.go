@@ -0,0 +1,229 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// reconnectBackoff is the initial delay between resubscription attempts. It
+	// doubles with every failed attempt up to maxReconnectBackoff.
+	reconnectBackoff    = time.Second
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// errNoCursor is reported in a gap if the watcher can't tell where it stopped.
+var errNoCursor = errors.New("last seen block unknown")
+
+// WatchGap notifies a reconnecting log watcher's user that the logs emitted from
+// block From onwards, up to the point of resubscription, could not be retrieved
+// and may have been missed.
+type WatchGap struct {
+	From uint64 // First block whose logs may have been missed (0 if unknown)
+	Err  error  // Error preventing the missed logs from being backfilled
+}
+
+// headReader is implemented by filterers able to report the chain head, which
+// lets a watcher backfill even if no logs were delivered before a disconnect.
+type headReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// logKey identifies a delivered log for deduplication.
+type logKey struct {
+	block   common.Hash
+	index   uint
+	removed bool
+}
+
+// logWatcher is a log subscription which survives failures of the underlying
+// subscription by resubscribing and backfilling the logs missed in between from
+// the last seen block.
+type logWatcher struct {
+	filterer ContractFilterer
+	query    ethereum.FilterQuery
+	opts     *WatchOpts
+	sink     chan<- types.Log
+	raw      chan types.Log
+
+	cursor uint64            // Block to backfill from after a resubscription
+	known  bool              // Whether the cursor is known
+	seen   map[logKey]uint64 // Delivered logs at or after the cursor, mapped to their block number
+}
+
+// watchLogsReconnecting subscribes to the logs matching the query, resubscribing
+// whenever the subscription fails.
+func (c *BoundContract) watchLogsReconnecting(opts *WatchOpts, query ethereum.FilterQuery, sink chan<- types.Log) (event.Subscription, error) {
+	w := &logWatcher{
+		filterer: c.filterer,
+		query:    query,
+		opts:     opts,
+		sink:     sink,
+		raw:      make(chan types.Log, cap(sink)),
+		seen:     make(map[logKey]uint64),
+	}
+	if opts.Start != nil {
+		w.cursor, w.known = *opts.Start, true
+	}
+	sub, err := w.subscribe()
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		return w.loop(sub, quit)
+	}), nil
+}
+
+// subscribe creates the underlying subscription and records the current head
+// as the cursor if no better one is known.
+func (w *logWatcher) subscribe() (ethereum.Subscription, error) {
+	ctx := ensureContext(w.opts.Context)
+	sub, err := w.filterer.SubscribeFilterLogs(ctx, w.query, w.raw)
+	if err != nil {
+		return nil, err
+	}
+	if reader, ok := w.filterer.(headReader); ok && !w.known {
+		if head, err := reader.HeaderByNumber(ctx, nil); err == nil {
+			w.cursor, w.known = head.Number.Uint64(), true
+		}
+	}
+	return sub, nil
+}
+
+// loop forwards the logs of the underlying subscription to the sink until the
+// watcher is unsubscribed, resubscribing if the subscription fails.
+func (w *logWatcher) loop(sub ethereum.Subscription, quit <-chan struct{}) error {
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	for {
+		select {
+		case log := <-w.raw:
+			if !w.deliver(log, quit) {
+				return nil
+			}
+		case err := <-sub.Err():
+			log.Warn("Log subscription failed, resubscribing", "err", err)
+			sub.Unsubscribe()
+			if sub = w.resubscribe(quit); sub == nil {
+				return nil
+			}
+		case <-quit:
+			return nil
+		}
+	}
+}
+
+// resubscribe recreates the underlying subscription, backing off between failed
+// attempts, and backfills the logs missed since the cursor. It returns nil if
+// the watcher was unsubscribed in the meantime.
+func (w *logWatcher) resubscribe(quit <-chan struct{}) ethereum.Subscription {
+	var (
+		backoff = reconnectBackoff
+		from    = w.cursor
+		known   = w.known
+	)
+	for {
+		select {
+		case <-quit:
+			return nil
+		case <-time.After(backoff):
+		}
+		sub, err := w.subscribe()
+		if err == nil {
+			if !w.backfill(from, known, quit) {
+				sub.Unsubscribe()
+				return nil
+			}
+			return sub
+		}
+		log.Debug("Failed to resubscribe to logs", "err", err)
+		if backoff *= 2; backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
+
+// backfill delivers the logs emitted since the given block, announcing a gap if
+// they can't be retrieved. It returns false if the watcher was unsubscribed.
+func (w *logWatcher) backfill(from uint64, known bool, quit <-chan struct{}) bool {
+	var err error
+	if known {
+		query := w.query
+		query.FromBlock, query.ToBlock = new(big.Int).SetUint64(from), nil
+
+		var logs []types.Log
+		if logs, err = w.filterer.FilterLogs(ensureContext(w.opts.Context), query); err == nil {
+			for _, log := range logs {
+				if !w.deliver(log, quit) {
+					return false
+				}
+			}
+			return true
+		}
+	} else {
+		from, err = 0, errNoCursor
+	}
+	gap := WatchGap{From: from, Err: err}
+	log.Warn("Missed logs while resubscribing", "from", gap.From, "err", gap.Err)
+	if w.opts.Gaps == nil {
+		return true
+	}
+	select {
+	case w.opts.Gaps <- gap:
+		return true
+	case <-quit:
+		return false
+	}
+}
+
+// deliver forwards a log to the sink unless it was already delivered. It returns
+// false if the watcher was unsubscribed.
+func (w *logWatcher) deliver(log types.Log, quit <-chan struct{}) bool {
+	key := logKey{block: log.BlockHash, index: log.Index, removed: log.Removed}
+	if _, ok := w.seen[key]; ok {
+		return true
+	}
+	w.seen[key] = log.BlockNumber
+
+	if !log.Removed && (!w.known || log.BlockNumber > w.cursor) {
+		w.cursor, w.known = log.BlockNumber, true
+		for key, number := range w.seen {
+			if number < w.cursor {
+				delete(w.seen, key)
+			}
+		}
+	}
+	select {
+	case w.sink <- log:
+		return true
+	case <-quit:
+		return false
+	}
+}
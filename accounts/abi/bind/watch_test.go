@@ -0,0 +1,136 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// flakySub is a log subscription which can be failed by the test.
+type flakySub struct {
+	err  chan error
+	once sync.Once
+}
+
+func (s *flakySub) Err() <-chan error { return s.err }
+func (s *flakySub) Unsubscribe()      { s.once.Do(func() { close(s.err) }) }
+
+// flakyFilterer is a filterer whose subscriptions can be failed, serving a fixed
+// log history for backfilling.
+type flakyFilterer struct {
+	mu        sync.Mutex
+	history   []types.Log
+	filterErr error
+	subs      chan *flakySub
+	sinks     chan chan<- types.Log
+}
+
+func (f *flakyFilterer) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.filterErr != nil {
+		return nil, f.filterErr
+	}
+	var logs []types.Log
+	for _, log := range f.history {
+		if log.BlockNumber >= query.FromBlock.Uint64() {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (f *flakyFilterer) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	sub := &flakySub{err: make(chan error, 1)}
+	f.subs <- sub
+	f.sinks <- ch
+	return sub, nil
+}
+
+func TestWatchLogsReconnect(t *testing.T) {
+	defer func(backoff time.Duration) { reconnectBackoff = backoff }(reconnectBackoff)
+	reconnectBackoff = time.Millisecond
+
+	var (
+		event  = abi.Event{Name: "Transfer", ID: common.Hash{0x01}}
+		logAt  = func(n uint64) types.Log { return types.Log{BlockNumber: n, BlockHash: common.Hash{byte(n)}} }
+		f      = &flakyFilterer{subs: make(chan *flakySub, 1), sinks: make(chan chan<- types.Log, 1)}
+		gaps   = make(chan WatchGap, 1)
+		c      = NewBoundContract(common.Address{}, abi.ABI{Events: map[string]abi.Event{"Transfer": event}}, nil, nil, f)
+		start  = uint64(1)
+		expect = func(logs chan types.Log, n uint64) {
+			t.Helper()
+			select {
+			case log := <-logs:
+				if log.BlockNumber != n {
+					t.Fatalf("log mismatch: have block %d, want %d", log.BlockNumber, n)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("log of block %d not delivered", n)
+			}
+		}
+	)
+	logs, sub, err := c.WatchLogs(&WatchOpts{Start: &start, Reconnect: true, Gaps: gaps}, "Transfer")
+	if err != nil {
+		t.Fatalf("failed to watch logs: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Deliver a log, then fail the subscription while another one is emitted
+	first, sink := <-f.subs, <-f.sinks
+	sink <- logAt(1)
+	expect(logs, 1)
+
+	f.mu.Lock()
+	f.history = []types.Log{logAt(1), logAt(2)}
+	f.mu.Unlock()
+	first.err <- errors.New("connection lost")
+
+	// The missed log should be backfilled without repeating the delivered one
+	second, sink := <-f.subs, <-f.sinks
+	expect(logs, 2)
+	sink <- logAt(2)
+	sink <- logAt(3)
+	expect(logs, 3)
+
+	// If backfilling fails, a gap should be announced
+	f.mu.Lock()
+	f.filterErr = errors.New("pruned")
+	f.mu.Unlock()
+	second.err <- errors.New("connection lost")
+	<-f.subs
+	<-f.sinks
+
+	select {
+	case gap := <-gaps:
+		if gap.From != 3 || gap.Err == nil {
+			t.Errorf("gap mismatch: have %+v, want from 3", gap)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("gap not announced")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// The functions in this file are allocation free alternatives to ABI.Pack and
+// ABI.Unpack for the most common call signatures. They append to a caller
+// provided buffer, so a service reusing its buffers does not allocate at all.
+// The packers and selectors are generated into gen_fastpath.go.
+
+//go:generate go run ./fastpathgen -shapes uint256,uint256 -out gen_fastpath.go "balanceOf(address owner)" "transfer(address to,uint256 amount)"
+
+var errShortWord = errors.New("abi: return data shorter than a word")
+
+// appendAddress appends an address as a left padded word.
+func appendAddress(dst []byte, addr common.Address) []byte {
+	var word [32]byte
+	copy(word[12:], addr[:])
+	return append(dst, word[:]...)
+}
+
+// appendUint256 appends an unsigned integer as a big endian word.
+func appendUint256(dst []byte, v *uint256.Int) []byte {
+	word := v.Bytes32()
+	return append(dst, word[:]...)
+}
+
+// UnpackUint256 decodes the first return value of a call as an uint256 into out.
+func UnpackUint256(data []byte, out *uint256.Int) error {
+	if len(data) < 32 {
+		return errShortWord
+	}
+	out.SetBytes32(data[:32])
+	return nil
+}
+
+// UnpackAddress decodes the first return value of a call as an address.
+func UnpackAddress(data []byte) (common.Address, error) {
+	if len(data) < 32 {
+		return common.Address{}, errShortWord
+	}
+	for _, b := range data[:12] {
+		if b != 0 {
+			return common.Address{}, errors.New("abi: improperly encoded address value")
+		}
+	}
+	return common.BytesToAddress(data[12:32]), nil
+}
+
+// UnpackBool decodes the first return value of a call as a bool, such as the
+// result of an ERC-20 transfer.
+func UnpackBool(data []byte) (bool, error) {
+	if len(data) < 32 {
+		return false, errShortWord
+	}
+	return readBool(data[:32])
+}
@@ -0,0 +1,141 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+const fastPathABI = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"add","inputs":[{"name":"a","type":"uint256"},{"name":"b","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+// Tests that the fast path produces the same encoding as the reflection based one.
+func TestFastPathPack(t *testing.T) {
+	abi, err := JSON(strings.NewReader(fastPathABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		addr   = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		amount = uint256.NewInt(0).Lsh(uint256.NewInt(1), 200)
+	)
+	want, _ := abi.Pack("transfer", addr, amount.ToBig())
+	if have := PackTransfer(nil, addr, amount); !bytes.Equal(have, want) {
+		t.Errorf("transfer mismatch: have %x, want %x", have, want)
+	}
+	want, _ = abi.Pack("balanceOf", addr)
+	if have := PackBalanceOf(nil, addr); !bytes.Equal(have, want) {
+		t.Errorf("balanceOf mismatch: have %x, want %x", have, want)
+	}
+	var selector [4]byte
+	copy(selector[:], abi.Methods["add"].ID)
+	want, _ = abi.Pack("add", big.NewInt(1), amount.ToBig())
+	if have := PackUint256PairCall(nil, selector, uint256.NewInt(1), amount); !bytes.Equal(have, want) {
+		t.Errorf("add mismatch: have %x, want %x", have, want)
+	}
+}
+
+// Tests that the fast path decodes return data like the reflection based one.
+func TestFastPathUnpack(t *testing.T) {
+	abi, err := JSON(strings.NewReader(fastPathABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := new(big.Int).Lsh(big.NewInt(3), 180)
+	data, _ := abi.Methods["balanceOf"].Outputs.Pack(amount)
+
+	var have uint256.Int
+	if err := UnpackUint256(data, &have); err != nil || have.ToBig().Cmp(amount) != 0 {
+		t.Errorf("uint256 mismatch: have %v (%v), want %v", have.ToBig(), err, amount)
+	}
+	data, _ = abi.Methods["transfer"].Outputs.Pack(true)
+	if ok, err := UnpackBool(data); err != nil || !ok {
+		t.Errorf("bool mismatch: have %v (%v), want true", ok, err)
+	}
+	data[0] = 1
+	if _, err := UnpackBool(data); err == nil {
+		t.Error("malformed bool accepted")
+	}
+	if err := UnpackUint256(data[:31], &have); err == nil {
+		t.Error("short return data accepted")
+	}
+}
+
+func TestFastPathAllocs(t *testing.T) {
+	var (
+		buf    = make([]byte, 0, 68)
+		addr   = common.Address{0x01}
+		amount = uint256.NewInt(1000)
+		out    uint256.Int
+	)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = PackTransfer(buf[:0], addr, amount)
+		UnpackUint256(buf[36:], &out)
+	})
+	if allocs != 0 {
+		t.Errorf("fast path allocated %v times", allocs)
+	}
+}
+
+func BenchmarkPackTransfer(b *testing.B) {
+	abi, _ := JSON(strings.NewReader(fastPathABI))
+	var (
+		addr   = common.Address{0x01}
+		amount = uint256.NewInt(1000)
+	)
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			abi.Pack("transfer", addr, amount.ToBig())
+		}
+	})
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := make([]byte, 0, 68)
+		for i := 0; i < b.N; i++ {
+			buf = PackTransfer(buf[:0], addr, amount)
+		}
+	})
+}
+
+func BenchmarkUnpackUint256(b *testing.B) {
+	abi, _ := JSON(strings.NewReader(fastPathABI))
+	data, _ := abi.Methods["balanceOf"].Outputs.Pack(big.NewInt(1000))
+
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			abi.Unpack("balanceOf", data)
+		}
+	})
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+		var out uint256.Int
+		for i := 0; i < b.N; i++ {
+			UnpackUint256(data, &out)
+		}
+	})
+}
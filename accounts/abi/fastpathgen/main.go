@@ -0,0 +1,260 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Command fastpathgen generates the allocation free packers of package abi.
+//
+// Each argument is a method signature with named parameters, for example
+// "transfer(address to,uint256 amount)". For every method, a selector and a
+// packer named after the method are generated, along with a generic packer for
+// its parameter types which takes the selector as an argument. Packers for
+// additional parameter lists can be requested with the -shapes flag.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func main() {
+	var (
+		output = flag.String("out", "-", "output file (default is stdout)")
+		pkg    = flag.String("package", "abi", "package name of the generated file")
+		shapes = flag.String("shapes", "", "additional parameter lists to generate packers for, separated by ';'")
+	)
+	flag.Parse()
+
+	var extra []string
+	if *shapes != "" {
+		extra = strings.Split(*shapes, ";")
+	}
+	code, err := generate(*pkg, flag.Args(), extra)
+	if err != nil {
+		fatal(err)
+	}
+	if *output == "-" {
+		os.Stdout.Write(code)
+	} else if err := os.WriteFile(*output, code, 0644); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, args...)
+	os.Exit(1)
+}
+
+// param is a single parameter of a packer.
+type param struct {
+	Name   string // Go parameter name
+	Type   string // Solidity type
+	GoType string // Go type of the parameter
+	Append string // Function appending the parameter to the call data
+}
+
+// goTypes maps the supported Solidity types to their Go type, appender and the
+// package providing the Go type.
+var goTypes = map[string][3]string{
+	"address": {"common.Address", "appendAddress", "github.com/ethereum/go-ethereum/common"},
+	"uint256": {"*uint256.Int", "appendUint256", "github.com/holiman/uint256"},
+}
+
+// shape is a parameter list shared by any number of methods.
+type shape struct {
+	Name   string // Name of the generic packer, without the Pack prefix
+	Params []param
+}
+
+// method is a method with a dedicated selector and packer.
+type method struct {
+	Name      string // Go name of the method
+	Signature string // Canonical signature the selector is derived from
+	Selector  string // Selector as a Go byte array literal
+	Shape     *shape
+	Params    []param
+}
+
+// newShape creates the generic packer for a parameter list. The packer is named
+// after the parameter types, with two identical types making a pair.
+func newShape(types []string) (*shape, error) {
+	var (
+		s     = new(shape)
+		count = make(map[string]int)
+	)
+	for _, typ := range types {
+		count[typ]++
+	}
+	for i, typ := range types {
+		conv, ok := goTypes[typ]
+		if !ok {
+			return nil, fmt.Errorf("unsupported parameter type %q", typ)
+		}
+		name := "addr"
+		if typ != "address" {
+			name = "v"
+		}
+		if count[typ] > 1 {
+			name = fmt.Sprintf("%s%d", name, i)
+		}
+		s.Params = append(s.Params, param{Name: name, Type: typ, GoType: conv[0], Append: conv[1]})
+	}
+	if len(types) == 2 && types[0] == types[1] {
+		s.Name = title(types[0]) + "PairCall"
+	} else {
+		for _, typ := range types {
+			s.Name += title(typ)
+		}
+		s.Name += "Call"
+	}
+	return s, nil
+}
+
+// parseMethod parses a method signature with named parameters.
+func parseMethod(sig string) (name string, types, names []string, err error) {
+	open, close := strings.IndexByte(sig, '('), strings.LastIndexByte(sig, ')')
+	if open <= 0 || close != len(sig)-1 {
+		return "", nil, nil, fmt.Errorf("invalid method signature %q", sig)
+	}
+	name = sig[:open]
+	if args := strings.TrimSpace(sig[open+1 : close]); args != "" {
+		for _, arg := range strings.Split(args, ",") {
+			fields := strings.Fields(arg)
+			if len(fields) != 2 {
+				return "", nil, nil, fmt.Errorf("invalid parameter %q in %q", arg, sig)
+			}
+			types, names = append(types, fields[0]), append(names, fields[1])
+		}
+	}
+	return name, types, names, nil
+}
+
+// generate produces the Go source of the packers for the given methods and
+// additional parameter lists.
+func generate(pkg string, methods []string, shapes []string) ([]byte, error) {
+	var (
+		data = struct {
+			Package string
+			Imports []string
+			Shapes  []*shape
+			Methods []*method
+		}{Package: pkg}
+		known   = make(map[string]*shape)
+		imports = make(map[string]bool)
+	)
+	addShape := func(types []string) (*shape, error) {
+		key := strings.Join(types, ",")
+		if s, ok := known[key]; ok {
+			return s, nil
+		}
+		s, err := newShape(types)
+		if err != nil {
+			return nil, err
+		}
+		known[key] = s
+		data.Shapes = append(data.Shapes, s)
+
+		for _, typ := range types {
+			imports[goTypes[typ][2]] = true
+		}
+		return s, nil
+	}
+	for _, sig := range methods {
+		name, types, names, err := parseMethod(sig)
+		if err != nil {
+			return nil, err
+		}
+		s, err := addShape(types)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", sig, err)
+		}
+		m := &method{
+			Name:      title(name),
+			Signature: fmt.Sprintf("%s(%s)", name, strings.Join(types, ",")),
+			Shape:     s,
+		}
+		id := crypto.Keccak256([]byte(m.Signature))[:4]
+		m.Selector = fmt.Sprintf("[4]byte{%#02x, %#02x, %#02x, %#02x}", id[0], id[1], id[2], id[3])
+		for i, p := range s.Params {
+			p.Name = names[i]
+			m.Params = append(m.Params, p)
+		}
+		data.Methods = append(data.Methods, m)
+	}
+	for _, list := range shapes {
+		if _, err := addShape(strings.Split(list, ",")); err != nil {
+			return nil, err
+		}
+	}
+	for path := range imports {
+		data.Imports = append(data.Imports, path)
+	}
+	sort.Strings(data.Imports)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// title upper cases the first letter of an identifier.
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+	return string(unicode.ToUpper(rune(s[0]))) + s[1:]
+}
+
+var tmpl = template.Must(template.New("").Parse(`// Code generated by fastpathgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+
+var (
+{{- range .Methods}}
+	// {{.Name}}Selector is the selector of the {{.Signature}} method.
+	{{.Name}}Selector = {{.Selector}}
+{{end -}}
+)
+{{range .Shapes}}
+// Pack{{.Name}} appends the call data of a method taking ({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Type}}{{end}}) to dst.
+func Pack{{.Name}}(dst []byte, selector [4]byte{{range .Params}}, {{.Name}} {{.GoType}}{{end}}) []byte {
+	dst = append(dst, selector[:]...)
+{{- range .Params}}
+	dst = {{.Append}}(dst, {{.Name}})
+{{- end}}
+	return dst
+}
+{{end}}
+{{- range .Methods}}
+// Pack{{.Name}} appends the call data of a {{.Signature}} call to dst.
+func Pack{{.Name}}(dst []byte{{range .Params}}, {{.Name}} {{.GoType}}{{end}}) []byte {
+	return Pack{{.Shape.Name}}(dst, {{.Name}}Selector{{range .Params}}, {{.Name}}{{end}})
+}
+{{end}}`))
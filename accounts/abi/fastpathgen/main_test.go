@@ -0,0 +1,47 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// Tests that the checked in packers match the output of the generator.
+func TestGeneratedUpToDate(t *testing.T) {
+	want, err := os.ReadFile("../gen_fastpath.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := generate("abi", []string{"balanceOf(address owner)", "transfer(address to,uint256 amount)"}, []string{"uint256,uint256"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, want) {
+		t.Fatalf("gen_fastpath.go is out of date, run go generate in accounts/abi:\n%s", have)
+	}
+}
+
+// Tests that unsupported signatures are rejected.
+func TestGenerateErrors(t *testing.T) {
+	for _, sig := range []string{"transfer", "transfer(address)", "transfer(string to)"} {
+		if _, err := generate("abi", []string{sig}, nil); err == nil {
+			t.Errorf("%q: expected error", sig)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package metadata stores the metadata of verified contracts, such as their
+// name and ABI, keyed by the hash of their code. The metadata is imported from
+// source verification services like Sourcify and used to annotate traces and
+// decode calldata.
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Verification levels of the sources of a contract.
+const (
+	FullMatch    = "full"    // Sources and compiler metadata match the deployed code
+	PartialMatch = "partial" // Sources match the deployed code, up to the compiler metadata
+)
+
+// Metadata describes a verified contract.
+type Metadata struct {
+	Name         string          `json:"name"`
+	SourcePath   string          `json:"sourcePath,omitempty"` // Path of the source file declaring the contract
+	SourceHash   common.Hash     `json:"sourceHash"`           // Keccak256 hash of the declaring source file
+	Language     string          `json:"language,omitempty"`
+	Compiler     string          `json:"compiler,omitempty"` // Version of the compiler
+	Verification string          `json:"verification,omitempty"`
+	ABI          json.RawMessage `json:"abi"`
+
+	abiOnce sync.Once
+	abi     *abi.ABI
+	abiErr  error
+}
+
+// ParsedABI returns the parsed ABI of the contract.
+func (m *Metadata) ParsedABI() (*abi.ABI, error) {
+	m.abiOnce.Do(func() {
+		parsed, err := abi.JSON(bytes.NewReader(m.ABI))
+		m.abi, m.abiErr = &parsed, err
+	})
+	return m.abi, m.abiErr
+}
+
+// Method returns the method of the contract called with the given calldata, or
+// nil if it isn't known.
+func (m *Metadata) Method(input []byte) *abi.Method {
+	if len(input) < 4 {
+		return nil
+	}
+	parsed, err := m.ParsedABI()
+	if err != nil {
+		return nil
+	}
+	method, err := parsed.MethodById(input[:4])
+	if err != nil {
+		return nil
+	}
+	return method
+}
+
+// sourcifyMetadata is the subset of the compiler metadata JSON, as stored by
+// Sourcify along with the verified sources, needed to describe a contract.
+type sourcifyMetadata struct {
+	Compiler struct {
+		Version string `json:"version"`
+	} `json:"compiler"`
+	Language string `json:"language"`
+	Output   struct {
+		ABI json.RawMessage `json:"abi"`
+	} `json:"output"`
+	Settings struct {
+		CompilationTarget map[string]string `json:"compilationTarget"` // source path -> contract name
+	} `json:"settings"`
+	Sources map[string]struct {
+		Keccak256 common.Hash `json:"keccak256"`
+	} `json:"sources"`
+}
+
+// ParseSourcify creates the metadata of a contract from its compiler metadata
+// JSON (metadata.json in Sourcify repositories).
+func ParseSourcify(data []byte) (*Metadata, error) {
+	var meta sourcifyMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	if len(meta.Settings.CompilationTarget) != 1 {
+		return nil, fmt.Errorf("expected 1 compilation target, have %d", len(meta.Settings.CompilationTarget))
+	}
+	m := &Metadata{
+		Language: meta.Language,
+		Compiler: meta.Compiler.Version,
+		ABI:      meta.Output.ABI,
+	}
+	for path, name := range meta.Settings.CompilationTarget {
+		m.SourcePath, m.Name = path, name
+	}
+	source, ok := meta.Sources[m.SourcePath]
+	if !ok {
+		return nil, fmt.Errorf("missing source %s", m.SourcePath)
+	}
+	m.SourceHash = source.Keccak256
+	if len(m.ABI) == 0 {
+		return nil, errors.New("missing abi")
+	}
+	if _, err := m.ParsedABI(); err != nil {
+		return nil, fmt.Errorf("invalid abi: %v", err)
+	}
+	return m, nil
+}
+
+// Skeleton returns the code stripped of the CBOR encoded compiler metadata the
+// Solidity and Vyper compilers append to it. Contracts compiled from the same
+// sources with different metadata, e.g. source file paths or comments, share
+// the same skeleton. Code without compiler metadata is returned unchanged.
+func Skeleton(code []byte) []byte {
+	if len(code) < 2 {
+		return code
+	}
+	size := int(code[len(code)-2])<<8 | int(code[len(code)-1])
+	if size == 0 || size+2 > len(code) {
+		return code
+	}
+	start := len(code) - 2 - size
+	if code[start]&0xe0 != 0xa0 { // CBOR map
+		return code
+	}
+	return code[:start]
+}
+
+// Write stores the metadata of the contract with the given code.
+func Write(db ethdb.KeyValueWriter, code []byte, meta *Metadata) error {
+	enc, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	hash := crypto.Keccak256Hash(code)
+	rawdb.WriteContractMetadata(db, hash, enc)
+	if skeleton := Skeleton(code); len(skeleton) < len(code) {
+		rawdb.WriteContractSkeleton(db, crypto.Keccak256Hash(skeleton), hash)
+	}
+	return nil
+}
+
+// Read retrieves the metadata stored for the code with the given hash.
+func Read(db ethdb.KeyValueReader, hash common.Hash) *Metadata {
+	enc := rawdb.ReadContractMetadata(db, hash)
+	if len(enc) == 0 {
+		return nil
+	}
+	meta := new(Metadata)
+	if err := json.Unmarshal(enc, meta); err != nil {
+		return nil
+	}
+	return meta
+}
+
+// Lookup retrieves the metadata of the contract with the given code and code
+// hash. If no metadata is stored for the exact code, the metadata of a contract
+// with the same code skeleton is returned, reported as a similar match.
+func Lookup(db ethdb.KeyValueReader, hash common.Hash, code []byte) (meta *Metadata, similar bool) {
+	if meta := Read(db, hash); meta != nil {
+		return meta, false
+	}
+	skeleton := Skeleton(code)
+	if len(skeleton) == len(code) {
+		return nil, false
+	}
+	if original := rawdb.ReadContractSkeleton(db, crypto.Keccak256Hash(skeleton)); original != (common.Hash{}) {
+		if meta := Read(db, original); meta != nil {
+			return meta, true
+		}
+	}
+	return nil, false
+}
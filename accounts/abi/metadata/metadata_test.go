@@ -0,0 +1,178 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testSourcifyMetadata = `{
+	"compiler": {"version": "0.8.17+commit.8df45f5f"},
+	"language": "Solidity",
+	"output": {
+		"abi": [{"inputs": [{"name": "to", "type": "address"}, {"name": "amount", "type": "uint256"}], "name": "transfer", "outputs": [{"type": "bool"}], "stateMutability": "nonpayable", "type": "function"}]
+	},
+	"settings": {"compilationTarget": {"contracts/Token.sol": "Token"}},
+	"sources": {
+		"contracts/Token.sol": {"keccak256": "0x1f3b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809"},
+		"contracts/IERC20.sol": {"keccak256": "0x0000000000000000000000000000000000000000000000000000000000000001"}
+	}
+}`
+
+// withMetadata appends a CBOR encoded compiler metadata trailer to the code.
+func withMetadata(code []byte, ipfs byte) []byte {
+	trailer := []byte{0xa2, 0x64, 'i', 'p', 'f', 's', 0x42, ipfs, ipfs, 0x64, 's', 'o', 'l', 'c', 0x43, 0, 8, 17}
+	code = append(append([]byte{}, code...), trailer...)
+	return append(code, 0, byte(len(trailer)))
+}
+
+func TestParseSourcify(t *testing.T) {
+	meta, err := ParseSourcify([]byte(testSourcifyMetadata))
+	if err != nil {
+		t.Fatalf("failed to parse metadata: %v", err)
+	}
+	if meta.Name != "Token" || meta.SourcePath != "contracts/Token.sol" || meta.Language != "Solidity" || meta.Compiler != "0.8.17+commit.8df45f5f" {
+		t.Errorf("metadata mismatch: %+v", meta)
+	}
+	if want := common.HexToHash("0x1f3b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809"); meta.SourceHash != want {
+		t.Errorf("source hash mismatch: have %x, want %x", meta.SourceHash, want)
+	}
+	input := append(crypto.Keccak256([]byte("transfer(address,uint256)"))[:4], make([]byte, 64)...)
+	if method := meta.Method(input); method == nil || method.Sig != "transfer(address,uint256)" {
+		t.Errorf("method mismatch: have %v", method)
+	}
+	if method := meta.Method([]byte{1, 2, 3, 4}); method != nil {
+		t.Errorf("unexpected method for unknown selector: %v", method)
+	}
+	for _, invalid := range []string{
+		`{"settings": {"compilationTarget": {}}}`,
+		`{"settings": {"compilationTarget": {"a.sol": "A"}}, "sources": {"a.sol": {}}}`,
+		`{"settings": {"compilationTarget": {"a.sol": "A"}}, "output": {"abi": []}}`,
+	} {
+		if _, err := ParseSourcify([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	code := []byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x00}
+	if skeleton := Skeleton(withMetadata(code, 1)); string(skeleton) != string(code) {
+		t.Errorf("skeleton mismatch: have %x, want %x", skeleton, code)
+	}
+	for _, plain := range [][]byte{nil, {0x00}, code, append(code, 0x00, 0x03)} {
+		if skeleton := Skeleton(plain); string(skeleton) != string(plain) {
+			t.Errorf("code without metadata changed: have %x, want %x", skeleton, plain)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	if rawdb.HasContractMetadata(db) {
+		t.Fatal("empty database reports contract metadata")
+	}
+	meta, _ := ParseSourcify([]byte(testSourcifyMetadata))
+	verified := withMetadata([]byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x00}, 1)
+	if err := Write(db, verified, meta); err != nil {
+		t.Fatalf("failed to write metadata: %v", err)
+	}
+	if !rawdb.HasContractMetadata(db) {
+		t.Fatal("database doesn't report stored contract metadata")
+	}
+	tests := []struct {
+		code    []byte
+		found   bool
+		similar bool
+	}{
+		{verified, true, false},
+		{withMetadata([]byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x00}, 2), true, true},
+		{withMetadata([]byte{0x60, 0x80, 0x60, 0x40, 0x52, 0xfe}, 1), false, false},
+		{[]byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x00}, false, false},
+	}
+	for i, tt := range tests {
+		have, similar := Lookup(db, crypto.Keccak256Hash(tt.code), tt.code)
+		if (have != nil) != tt.found || similar != tt.similar {
+			t.Errorf("test %d: lookup mismatch: have found %v similar %v, want found %v similar %v", i, have != nil, similar, tt.found, tt.similar)
+			continue
+		}
+		if have != nil && (have.Name != "Token" || have.SourceHash != meta.SourceHash) {
+			t.Errorf("test %d: metadata mismatch: %+v", i, have)
+		}
+		if have != nil {
+			if _, err := have.ParsedABI(); err != nil {
+				t.Errorf("test %d: failed to parse stored abi: %v", i, err)
+			}
+		}
+	}
+}
+
+type testCodeReader map[common.Address][]byte
+
+func (r testCodeReader) GetCode(addr common.Address) []byte { return r[addr] }
+
+func TestImportSourcify(t *testing.T) {
+	var (
+		root     = t.TempDir()
+		deployed = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		partial  = common.HexToAddress("0x2000000000000000000000000000000000000002")
+		missing  = common.HexToAddress("0x3000000000000000000000000000000000000003")
+		state    = testCodeReader{
+			deployed: withMetadata([]byte{0x00}, 1),
+			partial:  withMetadata([]byte{0xfe}, 1),
+		}
+	)
+	write := func(match string, addr common.Address) {
+		dir := filepath.Join(root, "contracts", match, "5", addr.Hex())
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(testSourcifyMetadata), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("full_match", deployed)
+	write("full_match", missing)
+	write("partial_match", partial)
+
+	db := rawdb.NewMemoryDatabase()
+	if _, err := ImportSourcify(db, state, root, 1); err == nil {
+		t.Fatal("expected error importing unknown chain")
+	}
+	imported, err := ImportSourcify(db, state, root, 5)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if imported != 2 {
+		t.Fatalf("imported contract count mismatch: have %d, want 2", imported)
+	}
+	for addr, verification := range map[common.Address]string{deployed: FullMatch, partial: PartialMatch} {
+		meta := Read(db, crypto.Keccak256Hash(state[addr]))
+		if meta == nil {
+			t.Fatalf("metadata of %x not imported", addr)
+		}
+		if meta.Verification != verification {
+			t.Errorf("verification of %x mismatch: have %s, want %s", addr, meta.Verification, verification)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// CodeReader provides the code deployed at an address.
+type CodeReader interface {
+	GetCode(addr common.Address) []byte
+}
+
+// ImportSourcify imports the metadata of the verified contracts of a chain from
+// a Sourcify repository export, laid out as
+//
+//	<root>/contracts/{full_match,partial_match}/<chain id>/<address>/metadata.json
+//
+// The metadata is stored for the code deployed at the contract addresses in the
+// given state. Contracts without code in the state are skipped, full matches take
+// precedence over partial ones. The number of imported contracts is returned.
+func ImportSourcify(db ethdb.KeyValueWriter, state CodeReader, root string, chainID uint64) (int, error) {
+	dirs := []struct {
+		name         string
+		verification string
+	}{
+		{"partial_match", PartialMatch},
+		{"full_match", FullMatch},
+	}
+	var (
+		imported int
+		found    bool
+	)
+	for _, dir := range dirs {
+		chainDir := filepath.Join(root, "contracts", dir.name, strconv.FormatUint(chainID, 10))
+		entries, err := os.ReadDir(chainDir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return imported, err
+		}
+		found = true
+		for _, entry := range entries {
+			if !entry.IsDir() || !common.IsHexAddress(entry.Name()) {
+				continue
+			}
+			address := common.HexToAddress(entry.Name())
+			code := state.GetCode(address)
+			if len(code) == 0 {
+				log.Debug("Skipping undeployed contract", "address", address)
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(chainDir, entry.Name(), "metadata.json"))
+			if err != nil {
+				return imported, err
+			}
+			meta, err := ParseSourcify(data)
+			if err != nil {
+				return imported, fmt.Errorf("contract %s: %v", address.Hex(), err)
+			}
+			meta.Verification = dir.verification
+			if err := Write(db, code, meta); err != nil {
+				return imported, err
+			}
+			imported++
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("no contracts of chain %d found in %s", chainID, root)
+	}
+	return imported, nil
+}
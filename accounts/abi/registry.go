@@ -0,0 +1,320 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ErrUnknownMethod is returned by Registry.Resolve if no registered ABI has a
+// method matching the call data.
+var ErrUnknownMethod = errors.New("no matching method found")
+
+// Registry is a collection of contract ABIs, resolving call data to the methods
+// of the known contracts. ABIs can be registered under a contract name and bound
+// to the addresses the contract is deployed at, in which case calls to these
+// addresses are decoded with the ABI of the contract first.
+type Registry struct {
+	lock      sync.RWMutex
+	contracts map[string]*ABI
+	order     []string                     // Contract names in registration order
+	addresses map[common.Address]string    // Deployed addresses of contracts
+	selectors map[[4]byte][]registryMethod // Methods of all contracts by selector
+}
+
+// registryMethod is a method of a registered contract.
+type registryMethod struct {
+	contract string
+	method   *Method
+}
+
+// NewRegistry creates an empty ABI registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		contracts: make(map[string]*ABI),
+		addresses: make(map[common.Address]string),
+		selectors: make(map[[4]byte][]registryMethod),
+	}
+}
+
+// Register adds the ABI of a contract to the registry, replacing any ABI
+// previously registered under the same name.
+func (r *Registry) Register(name string, abi *ABI) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, exists := r.contracts[name]; exists {
+		r.unregister(name)
+	}
+	r.contracts[name] = abi
+	r.order = append(r.order, name)
+
+	for _, method := range abi.Methods {
+		method := method
+
+		var id [4]byte
+		copy(id[:], method.ID)
+		r.selectors[id] = append(r.selectors[id], registryMethod{contract: name, method: &method})
+	}
+}
+
+// unregister removes a contract from the registry. The lock must be held.
+func (r *Registry) unregister(name string) {
+	delete(r.contracts, name)
+	for i, contract := range r.order {
+		if contract == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	for id, methods := range r.selectors {
+		kept := methods[:0]
+		for _, m := range methods {
+			if m.contract != name {
+				kept = append(kept, m)
+			}
+		}
+		if len(kept) == 0 {
+			delete(r.selectors, id)
+		} else {
+			r.selectors[id] = kept
+		}
+	}
+}
+
+// Load parses a JSON ABI definition and registers it under the given name.
+func (r *Registry) Load(name string, reader io.Reader) error {
+	abi, err := JSON(reader)
+	if err != nil {
+		return fmt.Errorf("invalid ABI of contract %s: %v", name, err)
+	}
+	r.Register(name, &abi)
+	return nil
+}
+
+// Bind associates a deployed contract address with a registered contract.
+func (r *Registry) Bind(address common.Address, name string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.contracts[name]; !ok {
+		return fmt.Errorf("unknown contract %s", name)
+	}
+	r.addresses[address] = name
+	return nil
+}
+
+// Contracts returns the names of the registered contracts, sorted.
+func (r *Registry) Contracts() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.contracts))
+	for name := range r.contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the signatures of the registered methods with the 4 byte
+// selector the data starts with, without duplicates.
+func (r *Registry) Lookup(selector []byte) []string {
+	if len(selector) < 4 {
+		return nil
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var (
+		id   [4]byte
+		sigs []string
+		seen = make(map[string]bool)
+	)
+	copy(id[:], selector)
+	for _, m := range r.selectors[id] {
+		if !seen[m.method.Sig] {
+			seen[m.method.Sig] = true
+			sigs = append(sigs, m.method.Sig)
+		}
+	}
+	return sigs
+}
+
+// Resolve decodes call data sent to the given address, which is nil for contract
+// creations, with the registered ABIs. If the address is bound to a contract,
+// its ABI is tried first, otherwise the methods of all contracts with a matching
+// selector are tried in registration order. Only call data which is encoded
+// exactly as its decoded arguments would be, without any trailing or padding
+// garbage, is considered a match.
+func (r *Registry) Resolve(to *common.Address, calldata []byte) (*DecodedCall, error) {
+	return r.resolve(to, calldata, false)
+}
+
+// ResolveBound decodes call data sent to the given address with the ABI of the
+// contract bound to it only, returning ErrUnknownMethod if no contract is bound
+// to the address or its ABI has no matching method.
+func (r *Registry) ResolveBound(to common.Address, calldata []byte) (*DecodedCall, error) {
+	return r.resolve(&to, calldata, true)
+}
+
+// resolve decodes call data with the ABI of the contract bound to the target
+// address and, unless boundOnly is set, with the ABIs of all other contracts.
+func (r *Registry) resolve(to *common.Address, calldata []byte, boundOnly bool) (*DecodedCall, error) {
+	if len(calldata) < 4 {
+		return nil, fmt.Errorf("invalid call data, incomplete method selector (%d bytes < 4)", len(calldata))
+	}
+	r.lock.RLock()
+	var (
+		id         [4]byte
+		candidates []registryMethod
+	)
+	copy(id[:], calldata)
+	if to != nil {
+		if name, ok := r.addresses[*to]; ok {
+			for _, m := range r.selectors[id] {
+				if m.contract == name {
+					candidates = append(candidates, m)
+				}
+			}
+		}
+	}
+	if !boundOnly {
+		for _, name := range r.order {
+			for _, m := range r.selectors[id] {
+				if m.contract == name {
+					candidates = append(candidates, m)
+				}
+			}
+		}
+	}
+	r.lock.RUnlock()
+
+	err := ErrUnknownMethod
+	for _, m := range candidates {
+		var call *DecodedCall
+		if call, err = decodeCall(m.method, calldata[4:]); err == nil {
+			call.Contract = m.contract
+			return call, nil
+		}
+	}
+	return nil, err
+}
+
+// decodeCall decodes the arguments of a method call, ensuring they are encoded
+// canonically.
+func decodeCall(method *Method, argdata []byte) (*DecodedCall, error) {
+	values, err := method.Inputs.UnpackValues(argdata)
+	if err != nil {
+		return nil, fmt.Errorf("signature %q matches, but arguments mismatch: %v", method.Sig, err)
+	}
+	encoded, err := method.Inputs.PackValues(values)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(encoded, argdata) {
+		return nil, fmt.Errorf("signature %q matches, but call data is not canonically encoded", method.Sig)
+	}
+	call := &DecodedCall{Method: method}
+	for i, input := range method.Inputs {
+		name := input.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		call.Args = append(call.Args, DecodedArg{Name: name, Type: input.Type.String(), Value: values[i]})
+	}
+	return call, nil
+}
+
+// DecodedCall is a method call decoded with a registered ABI.
+type DecodedCall struct {
+	Contract string // Name of the contract the ABI was registered under
+	Method   *Method
+	Args     []DecodedArg
+}
+
+// DecodedArg is a decoded argument of a method call.
+type DecodedArg struct {
+	Name  string
+	Type  string
+	Value interface{}
+}
+
+// String returns a human-readable summary of the call, such as
+// transfer(to=0x…, amount=1000).
+func (c *DecodedCall) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.Name + "=" + FormatValue(arg.Value)
+	}
+	return fmt.Sprintf("%s(%s)", c.Method.RawName, strings.Join(args, ", "))
+}
+
+// FormatValue renders a decoded ABI value human-readably: addresses checksummed,
+// integers in decimal, byte arrays and slices in hex and arrays and tuples as
+// bracketed lists.
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case common.Address:
+		return v.Hex()
+	case *big.Int:
+		return v.String()
+	case []byte:
+		return hexutil.Encode(v)
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+	val := reflect.ValueOf(value)
+	switch val.Kind() {
+	case reflect.Array:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, val.Len())
+			reflect.Copy(reflect.ValueOf(b), val)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		items := make([]string, val.Len())
+		for i := range items {
+			items[i] = FormatValue(val.Index(i).Interface())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Struct:
+		items := make([]string, val.NumField())
+		for i := range items {
+			items[i] = FormatValue(val.Field(i).Interface())
+		}
+		return "(" + strings.Join(items, ", ") + ")"
+	case reflect.Ptr:
+		if val.IsNil() {
+			return "nil"
+		}
+		return FormatValue(val.Elem().Interface())
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	registryTokenABI = `[
+		{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+		{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}]}
+	]`
+	// registryOtherABI declares a transfer method with the same signature, but
+	// different argument names.
+	registryOtherABI = `[
+		{"type":"function","name":"transfer","inputs":[{"name":"recipient","type":"address"},{"name":"value","type":"uint256"}]},
+		{"type":"function","name":"batch","inputs":[{"name":"","type":"bytes4[]"},{"name":"data","type":"tuple","components":[{"name":"a","type":"string"},{"name":"b","type":"bool"}]}]}
+	]`
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	registry := NewRegistry()
+	if err := registry.Load("Token", strings.NewReader(registryTokenABI)); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Load("Other", strings.NewReader(registryOtherABI)); err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func TestRegistryResolve(t *testing.T) {
+	var (
+		registry = newTestRegistry(t)
+		to       = common.HexToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+		other    = common.Address{0x01}
+	)
+	token, _ := JSON(strings.NewReader(registryTokenABI))
+	data, err := token.Pack("transfer", to, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a bound address, the first registered contract wins
+	call, err := registry.Resolve(&other, data)
+	if err != nil {
+		t.Fatalf("failed to resolve call: %v", err)
+	}
+	if call.Contract != "Token" {
+		t.Errorf("contract mismatch: have %s, want Token", call.Contract)
+	}
+	if have, want := call.String(), "transfer(to=0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed, amount=1000)"; have != want {
+		t.Errorf("summary mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if _, err := registry.ResolveBound(other, data); err != ErrUnknownMethod {
+		t.Errorf("resolved call to unbound address: have %v, want %v", err, ErrUnknownMethod)
+	}
+	// Calls to bound addresses are decoded with the ABI of their contract
+	if err := registry.Bind(other, "Other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Bind(other, "Missing"); err == nil {
+		t.Error("bound unknown contract")
+	}
+	call, err = registry.Resolve(&other, data)
+	if err != nil {
+		t.Fatalf("failed to resolve call: %v", err)
+	}
+	if have, want := call.String(), "transfer(recipient=0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed, value=1000)"; have != want {
+		t.Errorf("summary mismatch:\nhave %s\nwant %s", have, want)
+	}
+	if call, err = registry.ResolveBound(other, data); err != nil || call.Contract != "Other" {
+		t.Errorf("bound call mismatch: have %v, %v", call, err)
+	}
+	// Unknown selectors and non-canonical arguments must not be resolved
+	if _, err := registry.Resolve(nil, []byte{1, 2, 3, 4}); err != ErrUnknownMethod {
+		t.Errorf("unknown selector: have %v, want %v", err, ErrUnknownMethod)
+	}
+	if _, err := registry.Resolve(nil, append(data, make([]byte, 32)...)); err == nil {
+		t.Error("resolved call with trailing data")
+	}
+	dirty := common.CopyBytes(data)
+	dirty[4] = 0xff // garbage in the address padding
+	if _, err := registry.Resolve(nil, dirty); err == nil {
+		t.Error("resolved call with dirty padding")
+	}
+}
+
+func TestRegistryComplexArguments(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	other, _ := JSON(strings.NewReader(registryOtherABI))
+	tuple := struct {
+		A string
+		B bool
+	}{"hello", true}
+	data, err := other.Pack("batch", [][4]byte{{0xa9, 0x05, 0x9c, 0xbb}, {0x09, 0x5e, 0xa7, 0xb3}}, tuple)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := registry.Resolve(nil, data)
+	if err != nil {
+		t.Fatalf("failed to resolve call: %v", err)
+	}
+	if have, want := call.String(), `batch(arg0=[0xa9059cbb, 0x095ea7b3], data=("hello", true))`; have != want {
+		t.Errorf("summary mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	registry := newTestRegistry(t)
+
+	token, _ := JSON(strings.NewReader(registryTokenABI))
+	sigs := registry.Lookup(token.Methods["transfer"].ID)
+	if len(sigs) != 1 || sigs[0] != "transfer(address,uint256)" {
+		t.Errorf("lookup mismatch: have %v", sigs)
+	}
+	if sigs := registry.Lookup([]byte{1, 2, 3, 4}); len(sigs) != 0 {
+		t.Errorf("unknown selector resolved to %v", sigs)
+	}
+	// Replacing a contract must drop its old methods
+	if err := registry.Load("Token", strings.NewReader(`[]`)); err != nil {
+		t.Fatal(err)
+	}
+	if have := registry.Contracts(); len(have) != 2 {
+		t.Errorf("contracts mismatch: have %v", have)
+	}
+	call, err := registry.Resolve(nil, append(token.Methods["transfer"].ID, make([]byte, 64)...))
+	if err != nil {
+		t.Fatalf("failed to resolve call: %v", err)
+	}
+	if call.Contract != "Other" {
+		t.Errorf("contract mismatch: have %s, want Other", call.Contract)
+	}
+	if sigs := registry.Lookup(token.Methods["approve"].ID); len(sigs) != 0 {
+		t.Errorf("replaced method still known: %v", sigs)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package storage decodes the state variables of Solidity contracts from their
+// raw storage, based on the storage layout emitted by the compiler
+// (solc --storage-layout).
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Encodings of the storage layout types.
+const (
+	encodingInplace      = "inplace"
+	encodingMapping      = "mapping"
+	encodingDynamicArray = "dynamic_array"
+	encodingBytes        = "bytes"
+)
+
+// Layout is the storage layout of a contract.
+type Layout struct {
+	Storage []*Variable      `json:"storage"`
+	Types   map[string]*Type `json:"types"`
+}
+
+// Variable is a state variable or struct member in a storage layout.
+type Variable struct {
+	Label  string `json:"label"`
+	Offset uint   `json:"offset"` // Byte offset within the slot, from the right
+	Slot   string `json:"slot"`   // Decimal slot number, relative to the struct for members
+	Type   string `json:"type"`   // Identifier of the type in the layout's type table
+}
+
+// Type is a type in a storage layout.
+type Type struct {
+	Encoding      string      `json:"encoding"`
+	Label         string      `json:"label"`
+	NumberOfBytes string      `json:"numberOfBytes"`
+	Key           string      `json:"key,omitempty"`     // Key type of mappings
+	Value         string      `json:"value,omitempty"`   // Value type of mappings
+	Base          string      `json:"base,omitempty"`    // Element type of arrays
+	Members       []*Variable `json:"members,omitempty"` // Members of structs
+
+	size uint64 // Parsed NumberOfBytes
+}
+
+// ParseLayout parses a storage layout as emitted by solc, either on its own or
+// as the storageLayout field of a contract's standard JSON output.
+func ParseLayout(data []byte) (*Layout, error) {
+	var layout Layout
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return nil, err
+	}
+	if layout.Storage == nil {
+		var wrapped struct {
+			StorageLayout *Layout `json:"storageLayout"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.StorageLayout != nil {
+			layout = *wrapped.StorageLayout
+		}
+	}
+	if err := layout.validate(); err != nil {
+		return nil, err
+	}
+	return &layout, nil
+}
+
+// validate checks that all referenced types exist and parses their sizes.
+func (l *Layout) validate() error {
+	for id, typ := range l.Types {
+		size, err := strconv.ParseUint(typ.NumberOfBytes, 10, 64)
+		if err != nil || size == 0 {
+			return fmt.Errorf("type %s: invalid size %q", id, typ.NumberOfBytes)
+		}
+		typ.size = size
+		for _, ref := range []string{typ.Key, typ.Value, typ.Base} {
+			if _, ok := l.Types[ref]; ref != "" && !ok {
+				return fmt.Errorf("type %s: unknown type %s", id, ref)
+			}
+		}
+		if err := l.validateVariables(typ.Members); err != nil {
+			return fmt.Errorf("type %s: %v", id, err)
+		}
+	}
+	return l.validateVariables(l.Storage)
+}
+
+func (l *Layout) validateVariables(vars []*Variable) error {
+	for _, v := range vars {
+		if _, ok := l.Types[v.Type]; !ok {
+			return fmt.Errorf("variable %s: unknown type %s", v.Label, v.Type)
+		}
+		if _, ok := new(big.Int).SetString(v.Slot, 10); !ok {
+			return fmt.Errorf("variable %s: invalid slot %q", v.Label, v.Slot)
+		}
+		if v.Offset >= 32 {
+			return fmt.Errorf("variable %s: invalid offset %d", v.Label, v.Offset)
+		}
+	}
+	return nil
+}
+
+// variable returns the variable with the given label.
+func variable(vars []*Variable, label string) *Variable {
+	for _, v := range vars {
+		if v.Label == label {
+			return v
+		}
+	}
+	return nil
+}
+
+// staticLength returns the length of a static array type, parsed from the last
+// dimension of its label, e.g. 3 for "uint256[2][3]".
+func (t *Type) staticLength() (uint64, bool) {
+	if !strings.HasSuffix(t.Label, "]") {
+		return 0, false
+	}
+	start := strings.LastIndex(t.Label, "[")
+	n, err := strconv.ParseUint(t.Label[start+1:len(t.Label)-1], 10, 64)
+	return n, err == nil
+}
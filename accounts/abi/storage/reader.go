@@ -0,0 +1,474 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxArrayItems is the maximum number of elements of a dynamic array decoded
+// when reading it as a whole. Larger arrays have to be read element by element.
+const maxArrayItems = 1024
+
+var (
+	errMapping  = errors.New("mappings can't be read as a whole, index them")
+	errTooLarge = errors.New("array too large to be read as a whole, index it")
+)
+
+// Backend is the storage access needed by a Reader. It is implemented by
+// ethclient.Client, using eth_getStorageAt.
+type Backend interface {
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// Reader decodes the state variables of a contract.
+type Reader struct {
+	backend Backend
+	address common.Address
+	layout  *Layout
+}
+
+// NewReader creates a reader for the state variables of the contract at the given
+// address, laid out according to the given storage layout.
+func NewReader(backend Backend, address common.Address, layout *Layout) *Reader {
+	return &Reader{backend: backend, address: address, layout: layout}
+}
+
+// location is the position of a value in storage.
+type location struct {
+	slot   *big.Int
+	offset uint64
+	typ    *Type
+}
+
+// Read decodes the value at the given path at the given block (nil = latest).
+//
+// The path starts with the name of a state variable, followed by any number of
+// struct member accesses (".member") and mapping or array indexing ("[key]").
+// Mapping keys are given in their natural notation: hex for addresses and fixed
+// bytes, decimal or hex for integers, true or false for booleans and optionally
+// quoted text for strings, e.g. `balances[0x1234...]` or `users["alice"].age`.
+//
+// Values are decoded as *big.Int for integers, common.Address, bool, string and
+// hexutil.Bytes for the remaining primitives. Structs are decoded into maps by
+// member name and arrays into slices.
+func (r *Reader) Read(ctx context.Context, path string, block *big.Int) (interface{}, error) {
+	loc, err := r.locate(ctx, path, block)
+	if err != nil {
+		return nil, err
+	}
+	return r.decode(ctx, loc, block)
+}
+
+// Slot returns the storage slot and the byte offset within the slot of the value
+// at the given path. Paths indexing dynamic arrays are bounds checked against
+// the array's length at the given block.
+func (r *Reader) Slot(ctx context.Context, path string, block *big.Int) (common.Hash, uint64, error) {
+	loc, err := r.locate(ctx, path, block)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	return common.BigToHash(loc.slot), loc.offset, nil
+}
+
+// locate resolves a path to a storage location.
+func (r *Reader) locate(ctx context.Context, path string, block *big.Int) (*location, error) {
+	name, accessors, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	v := variable(r.layout.Storage, name)
+	if v == nil {
+		return nil, fmt.Errorf("unknown variable %q", name)
+	}
+	slot, _ := new(big.Int).SetString(v.Slot, 10)
+	loc := &location{slot: slot, offset: uint64(v.Offset), typ: r.layout.Types[v.Type]}
+
+	for _, acc := range accessors {
+		if loc, err = r.access(ctx, loc, acc, block); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return loc, nil
+}
+
+// accessor is a member access or an index in a path.
+type accessor struct {
+	member string
+	index  string
+	quoted bool // Whether the index was a quoted string
+}
+
+// parsePath splits a path into the variable name and its accessors.
+func parsePath(path string) (string, []accessor, error) {
+	end := strings.IndexAny(path, ".[")
+	if end < 0 {
+		end = len(path)
+	}
+	name, rest := strings.TrimSpace(path[:end]), path[end:]
+	if name == "" {
+		return "", nil, fmt.Errorf("invalid path %q", path)
+	}
+	var accessors []accessor
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return "", nil, fmt.Errorf("invalid path %q: empty member", path)
+			}
+			accessors = append(accessors, accessor{member: rest[1 : end+1]})
+			rest = rest[end+1:]
+
+		case '[':
+			if len(rest) > 1 && rest[1] == '"' {
+				end := strings.Index(rest[2:], `"]`)
+				if end < 0 {
+					return "", nil, fmt.Errorf("invalid path %q: unterminated string", path)
+				}
+				accessors = append(accessors, accessor{index: rest[2 : end+2], quoted: true})
+				rest = rest[end+4:]
+				continue
+			}
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return "", nil, fmt.Errorf("invalid path %q: unterminated index", path)
+			}
+			accessors = append(accessors, accessor{index: strings.TrimSpace(rest[1:end])})
+			rest = rest[end+1:]
+
+		default:
+			return "", nil, fmt.Errorf("invalid path %q at %q", path, rest)
+		}
+	}
+	return name, accessors, nil
+}
+
+// access applies a single accessor to a location.
+func (r *Reader) access(ctx context.Context, loc *location, acc accessor, block *big.Int) (*location, error) {
+	typ := loc.typ
+	if acc.member != "" {
+		member := variable(typ.Members, acc.member)
+		if member == nil {
+			return nil, fmt.Errorf("%s has no member %q", typ.Label, acc.member)
+		}
+		slot, _ := new(big.Int).SetString(member.Slot, 10)
+		return &location{
+			slot:   addSlot(loc.slot, slot),
+			offset: uint64(member.Offset),
+			typ:    r.layout.Types[member.Type],
+		}, nil
+	}
+	switch typ.Encoding {
+	case encodingMapping:
+		key, err := encodeKey(r.layout.Types[typ.Key], acc)
+		if err != nil {
+			return nil, err
+		}
+		slot := crypto.Keccak256(key, common.BigToHash(loc.slot).Bytes())
+		return &location{slot: new(big.Int).SetBytes(slot), typ: r.layout.Types[typ.Value]}, nil
+
+	case encodingDynamicArray:
+		index, err := parseIndex(acc)
+		if err != nil {
+			return nil, err
+		}
+		length, err := r.word(ctx, loc.slot, block)
+		if err != nil {
+			return nil, err
+		}
+		if new(big.Int).SetBytes(length).Cmp(new(big.Int).SetUint64(index)) <= 0 {
+			return nil, fmt.Errorf("index %d out of bounds", index)
+		}
+		data := new(big.Int).SetBytes(crypto.Keccak256(common.BigToHash(loc.slot).Bytes()))
+		return r.element(data, typ, index), nil
+
+	case encodingInplace:
+		length, ok := typ.staticLength()
+		if typ.Base == "" || !ok {
+			return nil, fmt.Errorf("%s can't be indexed", typ.Label)
+		}
+		index, err := parseIndex(acc)
+		if err != nil {
+			return nil, err
+		}
+		if index >= length {
+			return nil, fmt.Errorf("index %d out of bounds", index)
+		}
+		return r.element(loc.slot, typ, index), nil
+
+	default:
+		return nil, fmt.Errorf("%s can't be indexed", typ.Label)
+	}
+}
+
+// element returns the location of an array element. Elements smaller than half
+// a slot are packed, larger ones occupy whole slots.
+func (r *Reader) element(data *big.Int, array *Type, index uint64) *location {
+	base := r.layout.Types[array.Base]
+	if base.size <= 16 {
+		perSlot := 32 / base.size
+		return &location{
+			slot:   addSlot(data, new(big.Int).SetUint64(index/perSlot)),
+			offset: (index % perSlot) * base.size,
+			typ:    base,
+		}
+	}
+	slots := (base.size + 31) / 32
+	return &location{
+		slot: addSlot(data, new(big.Int).Mul(new(big.Int).SetUint64(index), new(big.Int).SetUint64(slots))),
+		typ:  base,
+	}
+}
+
+// addSlot adds two slot numbers modulo 2^256.
+func addSlot(a, b *big.Int) *big.Int {
+	return math.U256(new(big.Int).Add(a, b))
+}
+
+// parseIndex parses an array index.
+func parseIndex(acc accessor) (uint64, error) {
+	if acc.quoted {
+		return 0, fmt.Errorf("invalid array index %q", acc.index)
+	}
+	index, err := strconv.ParseUint(acc.index, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array index %q", acc.index)
+	}
+	return index, nil
+}
+
+// encodeKey encodes a mapping key the way solidity hashes it into the slot.
+func encodeKey(typ *Type, acc accessor) ([]byte, error) {
+	label := typ.Label
+	switch {
+	case typ.Encoding == encodingBytes:
+		if label == "string" || acc.quoted {
+			return []byte(acc.index), nil
+		}
+		return hexutil.Decode(acc.index)
+
+	case acc.quoted:
+		return nil, fmt.Errorf("invalid %s key %q", label, acc.index)
+
+	case label == "bool":
+		switch acc.index {
+		case "true":
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		case "false":
+			return make([]byte, 32), nil
+		}
+		return nil, fmt.Errorf("invalid bool key %q", acc.index)
+
+	case isAddress(label):
+		if !common.IsHexAddress(acc.index) {
+			return nil, fmt.Errorf("invalid address key %q", acc.index)
+		}
+		return common.LeftPadBytes(common.HexToAddress(acc.index).Bytes(), 32), nil
+
+	case strings.HasPrefix(label, "bytes"):
+		key, err := hexutil.Decode(acc.index)
+		if err != nil || uint64(len(key)) != typ.size {
+			return nil, fmt.Errorf("invalid %s key %q", label, acc.index)
+		}
+		return common.RightPadBytes(key, 32), nil
+
+	case isInteger(label):
+		key, ok := math.ParseBig256(acc.index)
+		if !ok {
+			if strings.HasPrefix(acc.index, "-") && strings.HasPrefix(label, "int") {
+				key, ok = new(big.Int).SetString(acc.index, 0)
+			}
+			if !ok {
+				return nil, fmt.Errorf("invalid %s key %q", label, acc.index)
+			}
+		}
+		return math.U256Bytes(key), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported mapping key type %s", label)
+	}
+}
+
+// word reads a storage slot.
+func (r *Reader) word(ctx context.Context, slot *big.Int, block *big.Int) ([]byte, error) {
+	word, err := r.backend.StorageAt(ctx, r.address, common.BigToHash(slot), block)
+	if err != nil {
+		return nil, err
+	}
+	return common.LeftPadBytes(word, 32), nil
+}
+
+// decode reads and decodes the value at a location.
+func (r *Reader) decode(ctx context.Context, loc *location, block *big.Int) (interface{}, error) {
+	typ := loc.typ
+	switch typ.Encoding {
+	case encodingMapping:
+		return nil, errMapping
+
+	case encodingBytes:
+		return r.decodeBytes(ctx, loc, block)
+
+	case encodingDynamicArray:
+		length, err := r.word(ctx, loc.slot, block)
+		if err != nil {
+			return nil, err
+		}
+		n := new(big.Int).SetBytes(length)
+		if n.Cmp(big.NewInt(maxArrayItems)) > 0 {
+			return nil, errTooLarge
+		}
+		data := new(big.Int).SetBytes(crypto.Keccak256(common.BigToHash(loc.slot).Bytes()))
+		return r.decodeArray(ctx, data, typ, n.Uint64(), block)
+
+	case encodingInplace:
+		if len(typ.Members) > 0 {
+			values := make(map[string]interface{}, len(typ.Members))
+			for _, member := range typ.Members {
+				slot, _ := new(big.Int).SetString(member.Slot, 10)
+				value, err := r.decode(ctx, &location{
+					slot:   addSlot(loc.slot, slot),
+					offset: uint64(member.Offset),
+					typ:    r.layout.Types[member.Type],
+				}, block)
+				if err != nil && err != errMapping {
+					return nil, err
+				}
+				values[member.Label] = value
+			}
+			return values, nil
+		}
+		if length, ok := typ.staticLength(); ok && typ.Base != "" {
+			if length > maxArrayItems {
+				return nil, errTooLarge
+			}
+			return r.decodeArray(ctx, loc.slot, typ, length, block)
+		}
+		word, err := r.word(ctx, loc.slot, block)
+		if err != nil {
+			return nil, err
+		}
+		if loc.offset+typ.size > 32 {
+			return nil, fmt.Errorf("invalid layout: %s at offset %d", typ.Label, loc.offset)
+		}
+		return decodePrimitive(typ, word[32-loc.offset-typ.size:32-loc.offset])
+
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", typ.Encoding)
+	}
+}
+
+// decodeArray decodes the elements of an array stored from the given slot.
+func (r *Reader) decodeArray(ctx context.Context, data *big.Int, typ *Type, length uint64, block *big.Int) ([]interface{}, error) {
+	values := make([]interface{}, 0, length)
+	for i := uint64(0); i < length; i++ {
+		value, err := r.decode(ctx, r.element(data, typ, i), block)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// decodeBytes decodes a string or bytes value. Values shorter than 32 bytes are
+// stored in the slot along with their doubled length, longer ones store their
+// doubled length plus one in the slot and their data from the slot's hash on.
+func (r *Reader) decodeBytes(ctx context.Context, loc *location, block *big.Int) (interface{}, error) {
+	word, err := r.word(ctx, loc.slot, block)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if word[31]&1 == 0 {
+		length := int(word[31] / 2)
+		if length > 31 {
+			return nil, fmt.Errorf("invalid short %s length %d", loc.typ.Label, length)
+		}
+		data = word[:length]
+	} else {
+		length := new(big.Int).Rsh(new(big.Int).SetBytes(word), 1)
+		if length.Cmp(big.NewInt(32*maxArrayItems)) > 0 {
+			return nil, errTooLarge
+		}
+		var (
+			n    = length.Uint64()
+			slot = new(big.Int).SetBytes(crypto.Keccak256(common.BigToHash(loc.slot).Bytes()))
+		)
+		for uint64(len(data)) < n {
+			word, err := r.word(ctx, slot, block)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, word...)
+			slot = addSlot(slot, common.Big1)
+		}
+		data = data[:n]
+	}
+	if loc.typ.Label == "string" {
+		return string(data), nil
+	}
+	return hexutil.Bytes(data), nil
+}
+
+// decodePrimitive decodes a value occupying part of a slot.
+func decodePrimitive(typ *Type, data []byte) (interface{}, error) {
+	label := typ.Label
+	switch {
+	case label == "bool":
+		return data[len(data)-1] != 0, nil
+
+	case isAddress(label):
+		return common.BytesToAddress(data), nil
+
+	case strings.HasPrefix(label, "int"):
+		value := new(big.Int).SetBytes(data)
+		if len(data) > 0 && data[0]&0x80 != 0 {
+			value.Sub(value, new(big.Int).Lsh(common.Big1, uint(8*len(data))))
+		}
+		return value, nil
+
+	case isInteger(label):
+		return new(big.Int).SetBytes(data), nil
+
+	default:
+		return hexutil.Bytes(common.CopyBytes(data)), nil
+	}
+}
+
+// isAddress reports whether a type label denotes an address.
+func isAddress(label string) bool {
+	return label == "address" || label == "address payable" || strings.HasPrefix(label, "contract ")
+}
+
+// isInteger reports whether a type label denotes an integer, including enums.
+func isInteger(label string) bool {
+	return strings.HasPrefix(label, "uint") || strings.HasPrefix(label, "int") || strings.HasPrefix(label, "enum ")
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The layout of:
+//
+//	contract Test {
+//	    uint128 a;
+//	    int64 b;
+//	    mapping(address => uint256) balances;
+//	    uint128[] list;
+//	    string name;
+//	    struct User { address addr; bool active; }
+//	    mapping(string => User) users;
+//	    address[2] pair;
+//	}
+const testLayout = `{
+	"storage": [
+		{"label": "a", "offset": 0, "slot": "0", "type": "t_uint128"},
+		{"label": "b", "offset": 16, "slot": "0", "type": "t_int64"},
+		{"label": "balances", "offset": 0, "slot": "1", "type": "t_mapping(t_address,t_uint256)"},
+		{"label": "list", "offset": 0, "slot": "2", "type": "t_array(t_uint128)dyn_storage"},
+		{"label": "name", "offset": 0, "slot": "3", "type": "t_string_storage"},
+		{"label": "users", "offset": 0, "slot": "4", "type": "t_mapping(t_string_memory_ptr,t_struct(User)_storage)"},
+		{"label": "pair", "offset": 0, "slot": "5", "type": "t_array(t_address)2_storage"}
+	],
+	"types": {
+		"t_address": {"encoding": "inplace", "label": "address", "numberOfBytes": "20"},
+		"t_bool": {"encoding": "inplace", "label": "bool", "numberOfBytes": "1"},
+		"t_int64": {"encoding": "inplace", "label": "int64", "numberOfBytes": "8"},
+		"t_uint128": {"encoding": "inplace", "label": "uint128", "numberOfBytes": "16"},
+		"t_uint256": {"encoding": "inplace", "label": "uint256", "numberOfBytes": "32"},
+		"t_string_storage": {"encoding": "bytes", "label": "string", "numberOfBytes": "32"},
+		"t_string_memory_ptr": {"encoding": "bytes", "label": "string", "numberOfBytes": "32"},
+		"t_mapping(t_address,t_uint256)": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => uint256)", "numberOfBytes": "32", "value": "t_uint256"},
+		"t_mapping(t_string_memory_ptr,t_struct(User)_storage)": {"encoding": "mapping", "key": "t_string_memory_ptr", "label": "mapping(string => struct Test.User)", "numberOfBytes": "32", "value": "t_struct(User)_storage"},
+		"t_array(t_uint128)dyn_storage": {"base": "t_uint128", "encoding": "dynamic_array", "label": "uint128[]", "numberOfBytes": "32"},
+		"t_array(t_address)2_storage": {"base": "t_address", "encoding": "inplace", "label": "address[2]", "numberOfBytes": "64"},
+		"t_struct(User)_storage": {"encoding": "inplace", "label": "struct Test.User", "numberOfBytes": "32", "members": [
+			{"label": "addr", "offset": 0, "slot": "0", "type": "t_address"},
+			{"label": "active", "offset": 20, "slot": "0", "type": "t_bool"}
+		]}
+	}
+}`
+
+// memoryStorage is a backend serving storage slots from memory.
+type memoryStorage map[common.Hash]common.Hash
+
+func (s memoryStorage) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	value := s[key]
+	return value[:], nil
+}
+
+func slotHash(data ...[]byte) *big.Int {
+	return new(big.Int).SetBytes(crypto.Keccak256(data...))
+}
+
+func TestReader(t *testing.T) {
+	layout, err := ParseLayout([]byte(testLayout))
+	if err != nil {
+		t.Fatalf("failed to parse layout: %v", err)
+	}
+	var (
+		db    = make(memoryStorage)
+		alice = common.HexToAddress("0x00000000000000000000000000000000000a11ce")
+		set   = func(slot *big.Int, value []byte) {
+			db[common.BigToHash(slot)] = common.BytesToHash(value)
+		}
+	)
+	// a = 7, b = -2 packed into slot 0
+	set(big.NewInt(0), common.FromHex("0x00000000000000000000fffffffffffffffe00000000000000000000000000000007"))
+
+	// balances[alice] = 1000
+	set(slotHash(common.LeftPadBytes(alice.Bytes(), 32), common.BigToHash(big.NewInt(1)).Bytes()), big.NewInt(1000).Bytes())
+
+	// list = [1, 2, 3], packed two per slot
+	set(big.NewInt(2), []byte{3})
+	list := slotHash(common.BigToHash(big.NewInt(2)).Bytes())
+	set(list, common.FromHex("0x0000000000000000000000000000000200000000000000000000000000000001"))
+	set(new(big.Int).Add(list, common.Big1), []byte{3})
+
+	// name is a long string stored out of place
+	name := strings.Repeat("geth", 10)
+	set(big.NewInt(3), []byte{byte(2*len(name) + 1)})
+	data := slotHash(common.BigToHash(big.NewInt(3)).Bytes())
+	set(data, []byte(name[:32]))
+	db[common.BigToHash(new(big.Int).Add(data, common.Big1))] = common.BytesToHash(common.RightPadBytes([]byte(name[32:]), 32))
+
+	// users["alice"] = User(alice, true)
+	user := slotHash([]byte("alice"), common.BigToHash(big.NewInt(4)).Bytes())
+	set(user, append([]byte{1}, alice.Bytes()...))
+
+	// pair = [alice, 0]
+	set(big.NewInt(5), alice.Bytes())
+
+	reader := NewReader(db, common.Address{}, layout)
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"a", big.NewInt(7)},
+		{"b", big.NewInt(-2)},
+		{"balances[" + alice.Hex() + "]", big.NewInt(1000)},
+		{"balances[0x0000000000000000000000000000000000000001]", new(big.Int)},
+		{"list", []interface{}{big.NewInt(1), big.NewInt(2), big.NewInt(3)}},
+		{"list[2]", big.NewInt(3)},
+		{"name", name},
+		{`users["alice"]`, map[string]interface{}{"addr": alice, "active": true}},
+		{`users["alice"].active`, true},
+		{"pair[0]", alice},
+		{"pair", []interface{}{alice, common.Address{}}},
+	}
+	for _, tt := range tests {
+		have, err := reader.Read(context.Background(), tt.path, nil)
+		if err != nil {
+			t.Errorf("%s: read failed: %v", tt.path, err)
+			continue
+		}
+		if fmt.Sprint(have) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: value mismatch: have %v, want %v", tt.path, have, tt.want)
+		}
+	}
+	for _, path := range []string{"balances", "list[3]", "pair[2]", "missing", "a[0]", "users[0x01].x", "name.x"} {
+		if _, err := reader.Read(context.Background(), path, nil); err == nil {
+			t.Errorf("%s: invalid read succeeded", path)
+		}
+	}
+	slot, offset, err := reader.Slot(context.Background(), "b", nil)
+	if err != nil || slot != (common.Hash{}) || offset != 16 {
+		t.Errorf("slot mismatch: have %x/%d (%v), want 0/16", slot, offset, err)
+	}
+}
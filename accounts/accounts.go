@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -136,6 +137,16 @@ type Wallet interface {
 	// SignTextWithPassphrase is identical to Signtext, but also takes a password
 	SignTextWithPassphrase(account Account, passphrase string, hash []byte) ([]byte, error)
 
+	// SignTypedData requests the wallet to sign the given EIP-712 typed data.
+	// Compared to signing the pre-hashed data via SignData, it allows wallets to
+	// show the structured content of the data to the user before signing.
+	//
+	// If the wallet requires additional authentication to sign the request, an
+	// AuthNeededError instance will be returned, as for SignData.
+	//
+	// This method should return the signature in 'canonical' format, with v 0 or 1.
+	SignTypedData(account Account, typedData apitypes.TypedData) ([]byte, error)
+
 	// SignTx requests the wallet to sign the given transaction.
 	//
 	// It looks up the account specified either solely via its address contained within,
@@ -214,6 +225,20 @@ const (
 
 	// WalletDropped
 	WalletDropped
+
+	// KeyFileCorrupted is fired when the key file backing a keystore wallet is
+	// modified on disk and no longer contains a valid key. The wallet is dropped
+	// too, the event carries the account as it was before the modification.
+	KeyFileCorrupted
+
+	// KeyFileReplaced is fired when the key file backing a keystore wallet is
+	// overwritten on disk with a different key. The event carries the wallet of
+	// the new key.
+	KeyFileReplaced
+
+	// PassphraseRotated is fired when the key file backing a keystore wallet is
+	// re-encrypted, either through the keystore or by another process.
+	PassphraseRotated
 )
 
 // WalletEvent is an event fired by an account backend when a wallet arrival or
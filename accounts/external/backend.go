@@ -19,6 +19,7 @@ package external
 import (
 	"fmt"
 	"math/big"
+	"strings"
 	"sync"
 
 	"github.com/ethereum/go-ethereum"
@@ -40,8 +41,19 @@ func (eb *ExternalBackend) Wallets() []accounts.Wallet {
 	return eb.signers
 }
 
+// NewExternalBackend creates a backend for the external signer at the given
+// endpoint. Endpoints with the grpc:// or grpcs:// scheme are served by a gRPC
+// signer, any other by a clef-type JSON-RPC signer.
 func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
-	signer, err := NewExternalSigner(endpoint)
+	var (
+		signer accounts.Wallet
+		err    error
+	)
+	if strings.HasPrefix(endpoint, "grpc://") || strings.HasPrefix(endpoint, "grpcs://") {
+		signer, err = NewGRPCSigner(endpoint)
+	} else {
+		signer, err = NewExternalSigner(endpoint)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +62,17 @@ func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
 	}, nil
 }
 
+// RegisterRemote connects to the external signer at the given endpoint and adds
+// it to the account manager, making its accounts available like local ones.
+func RegisterRemote(am *accounts.Manager, endpoint string) error {
+	backend, err := NewExternalBackend(endpoint)
+	if err != nil {
+		return err
+	}
+	am.AddBackend(backend)
+	return nil
+}
+
 func (eb *ExternalBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
 	return event.NewSubscription(func(quit <-chan struct{}) error {
 		<-quit
@@ -186,6 +209,22 @@ func (api *ExternalSigner) SignText(account accounts.Account, text []byte) ([]by
 	return signature, nil
 }
 
+// SignTypedData sends the EIP-712 typed data to the external signer, which can
+// display its structured content for confirmation.
+func (api *ExternalSigner) SignTypedData(account accounts.Account, typedData apitypes.TypedData) ([]byte, error) {
+	var signature hexutil.Bytes
+	var signAddress = common.NewMixedcaseAddress(account.Address)
+	if err := api.client.Call(&signature, "account_signTypedData",
+		&signAddress, // Need to use the pointer here, because of how MarshalJSON is defined
+		typedData); err != nil {
+		return nil, err
+	}
+	if signature[64] == 27 || signature[64] == 28 {
+		signature[64] -= 27 // Transform V from Ethereum-legacy to 0/1
+	}
+	return signature, nil
+}
+
 // signTransactionResult represents the signinig result returned by clef.
 type signTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -0,0 +1,292 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/grpcwire"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// grpcService is the path prefix of the methods of the signer service defined
+// in signer.proto.
+const grpcService = "/ethereum.signer.v1.Signer/"
+
+// GRPCSigner is a wallet backed by a remote signer speaking the gRPC protocol
+// defined in signer.proto, e.g. a signing service fronting an HSM.
+type GRPCSigner struct {
+	client   *grpcwire.Client
+	endpoint string
+	status   string
+	cacheMu  sync.RWMutex
+	cache    []accounts.Account
+}
+
+// NewGRPCSigner connects to the remote signer at the given grpc:// or grpcs://
+// endpoint.
+func NewGRPCSigner(endpoint string) (*GRPCSigner, error) {
+	client, err := grpcwire.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	signer := &GRPCSigner{
+		client:   client,
+		endpoint: endpoint,
+	}
+	// Check if reachable
+	res, err := signer.call(grpcService+"Version", nil)
+	if err != nil {
+		return nil, err
+	}
+	version, err := grpcwire.DecodeString(res, 1)
+	if err != nil {
+		return nil, err
+	}
+	signer.status = fmt.Sprintf("ok [version=%v]", version)
+	return signer, nil
+}
+
+func (api *GRPCSigner) URL() accounts.URL {
+	return accounts.URL{
+		Scheme: "extapi",
+		Path:   api.endpoint,
+	}
+}
+
+func (api *GRPCSigner) Status() (string, error) {
+	return api.status, nil
+}
+
+func (api *GRPCSigner) Open(passphrase string) error {
+	return fmt.Errorf("operation not supported on external signers")
+}
+
+func (api *GRPCSigner) Close() error {
+	return fmt.Errorf("operation not supported on external signers")
+}
+
+func (api *GRPCSigner) Accounts() []accounts.Account {
+	var accnts []accounts.Account
+	res, err := api.listAccounts()
+	if err != nil {
+		log.Error("account listing failed", "error", err)
+		return accnts
+	}
+	for _, addr := range res {
+		accnts = append(accnts, accounts.Account{
+			URL:     api.URL(),
+			Address: addr,
+		})
+	}
+	api.cacheMu.Lock()
+	api.cache = accnts
+	api.cacheMu.Unlock()
+	return accnts
+}
+
+func (api *GRPCSigner) Contains(account accounts.Account) bool {
+	api.cacheMu.RLock()
+	defer api.cacheMu.RUnlock()
+	if api.cache == nil {
+		// If we haven't already fetched the accounts, it's time to do so now
+		api.cacheMu.RUnlock()
+		api.Accounts()
+		api.cacheMu.RLock()
+	}
+	for _, a := range api.cache {
+		if a.Address == account.Address && (account.URL == (accounts.URL{}) || account.URL == api.URL()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (api *GRPCSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, fmt.Errorf("operation not supported on external signers")
+}
+
+func (api *GRPCSigner) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	log.Error("operation SelfDerive not supported on external signers")
+}
+
+// SignData signs keccak256(data). The mimetype parameter describes the type of data being signed
+func (api *GRPCSigner) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	signature, err := api.signData(account, mimeType, data)
+	if err != nil {
+		return nil, err
+	}
+	// If V is on 27/28-form, convert to 0/1 for Clique
+	if mimeType == accounts.MimetypeClique && (signature[64] == 27 || signature[64] == 28) {
+		signature[64] -= 27 // Transform V from 27/28 to 0/1 for Clique use
+	}
+	return signature, nil
+}
+
+func (api *GRPCSigner) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	signature, err := api.signData(account, accounts.MimetypeTextPlain, text)
+	if err != nil {
+		return nil, err
+	}
+	if signature[64] == 27 || signature[64] == 28 {
+		signature[64] -= 27 // Transform V from Ethereum-legacy to 0/1
+	}
+	return signature, nil
+}
+
+// SignTypedData sends the EIP-712 typed data to the remote signer, which can
+// display its structured content for confirmation.
+func (api *GRPCSigner) SignTypedData(account accounts.Account, typedData apitypes.TypedData) ([]byte, error) {
+	data, err := json.Marshal(typedData)
+	if err != nil {
+		return nil, err
+	}
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, account.Address[:])
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendBytes(req, data)
+
+	signature, err := api.sign(grpcService+"SignTypedData", req)
+	if err != nil {
+		return nil, err
+	}
+	if signature[64] == 27 || signature[64] == 28 {
+		signature[64] -= 27 // Transform V from Ethereum-legacy to 0/1
+	}
+	return signature, nil
+}
+
+// SignTx sends the transaction to the remote signer. If chainID is nil, or
+// zero, the chain ID will be assigned by the signer. The signed transaction is
+// checked to be the requested one, signed by the requested account.
+func (api *GRPCSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	unsigned, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, account.Address[:])
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendBytes(req, unsigned)
+	if chainID != nil && chainID.Sign() != 0 {
+		req = protowire.AppendTag(req, 3, protowire.BytesType)
+		req = protowire.AppendBytes(req, chainID.Bytes())
+	}
+	res, err := api.call(grpcService+"SignTransaction", req)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := grpcwire.DecodeBytes(res, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 1 {
+		return nil, errors.New("missing signed transaction")
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(fields[0]); err != nil {
+		return nil, fmt.Errorf("invalid signed transaction: %v", err)
+	}
+	signer := types.LatestSignerForChainID(signed.ChainId())
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, errors.New("signer returned a different transaction")
+	}
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, err
+	}
+	if sender != account.Address {
+		return nil, fmt.Errorf("transaction signed by %x, want %x", sender, account.Address)
+	}
+	return signed, nil
+}
+
+func (api *GRPCSigner) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return []byte{}, fmt.Errorf("password-operations not supported on external signers")
+}
+
+func (api *GRPCSigner) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, fmt.Errorf("password-operations not supported on external signers")
+}
+
+func (api *GRPCSigner) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return nil, fmt.Errorf("password-operations not supported on external signers")
+}
+
+func (api *GRPCSigner) signData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, account.Address[:])
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendString(req, mimeType)
+	req = protowire.AppendTag(req, 3, protowire.BytesType)
+	req = protowire.AppendBytes(req, data)
+
+	return api.sign(grpcService+"SignData", req)
+}
+
+// sign calls a signing method of the remote signer, returning the signature.
+func (api *GRPCSigner) sign(method string, req []byte) ([]byte, error) {
+	res, err := api.call(method, req)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := grpcwire.DecodeBytes(res, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 1 || len(fields[0]) != 65 {
+		return nil, errors.New("invalid signature returned by signer")
+	}
+	return fields[0], nil
+}
+
+func (api *GRPCSigner) listAccounts() ([]common.Address, error) {
+	res, err := api.call(grpcService+"ListAccounts", nil)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := grpcwire.DecodeBytes(res, 1)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]common.Address, 0, len(fields))
+	for _, field := range fields {
+		if len(field) != common.AddressLength {
+			return nil, fmt.Errorf("invalid account address %x", field)
+		}
+		addrs = append(addrs, common.BytesToAddress(field))
+	}
+	return addrs, nil
+}
+
+func (api *GRPCSigner) call(method string, req []byte) ([]byte, error) {
+	return api.client.Call(context.Background(), method, req)
+}
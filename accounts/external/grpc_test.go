@@ -0,0 +1,199 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/grpcwire"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+// testGRPCSigner is a remote signer implementing the service of signer.proto
+// with a single key.
+type testGRPCSigner struct {
+	forgeTx bool // Whether to sign a different transaction than requested
+}
+
+func (s *testGRPCSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if len(body) < 5 || r.Header.Get("Content-Type") != "application/grpc+proto" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	req := body[5:]
+	w.Header().Set("Content-Type", "application/grpc+proto")
+
+	var (
+		res []byte
+		err error
+	)
+	switch strings.TrimPrefix(r.URL.Path, grpcService) {
+	case "Version":
+		res = protowire.AppendTag(res, 1, protowire.BytesType)
+		res = protowire.AppendString(res, "1.0.0")
+	case "ListAccounts":
+		addr := crypto.PubkeyToAddress(testKey.PublicKey)
+		res = protowire.AppendTag(res, 1, protowire.BytesType)
+		res = protowire.AppendBytes(res, addr[:])
+	case "SignData":
+		data, _ := grpcwire.DecodeBytes(req, 3)
+		var sig []byte
+		sig, err = crypto.Sign(accounts.TextHash(data[0]), testKey)
+		res = protowire.AppendTag(res, 1, protowire.BytesType)
+		res = protowire.AppendBytes(res, sig)
+	case "SignTypedData":
+		data, _ := grpcwire.DecodeBytes(req, 2)
+		var typedData apitypes.TypedData
+		if err = json.Unmarshal(data[0], &typedData); err == nil {
+			var hash, sig []byte
+			if hash, _, err = apitypes.TypedDataAndHash(typedData); err == nil {
+				if sig, err = crypto.Sign(hash, testKey); err == nil {
+					sig[64] += 27 // Legacy V, like clef
+					res = protowire.AppendTag(res, 1, protowire.BytesType)
+					res = protowire.AppendBytes(res, sig)
+				}
+			}
+		}
+	case "SignTransaction":
+		fields, _ := grpcwire.DecodeBytes(req, 2)
+		chainID, _ := grpcwire.DecodeBytes(req, 3)
+		tx := new(types.Transaction)
+		if err = tx.UnmarshalBinary(fields[0]); err == nil {
+			if s.forgeTx {
+				tx = types.NewTransaction(tx.Nonce()+1, common.Address{}, tx.Value(), tx.Gas(), tx.GasPrice(), nil)
+			}
+			signer := types.LatestSignerForChainID(new(big.Int).SetBytes(chainID[0]))
+			if tx, err = types.SignTx(tx, signer, testKey); err == nil {
+				signed, _ := tx.MarshalBinary()
+				res = protowire.AppendTag(res, 1, protowire.BytesType)
+				res = protowire.AppendBytes(res, signed)
+			}
+		}
+	default:
+		w.Header().Set("Grpc-Status", "12")
+		w.Header().Set("Grpc-Message", "unknown method")
+		return
+	}
+	if err != nil {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "13")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", err.Error())
+		return
+	}
+	frame := make([]byte, 5)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(res)))
+	w.Write(append(frame, res...))
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+}
+
+func newTestGRPCSigner(t *testing.T, service *testGRPCSigner) *GRPCSigner {
+	server := httptest.NewServer(h2c.NewHandler(service, new(http2.Server)))
+	t.Cleanup(server.Close)
+
+	signer, err := NewGRPCSigner("grpc://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestGRPCSigner(t *testing.T) {
+	signer := newTestGRPCSigner(t, new(testGRPCSigner))
+	if status, _ := signer.Status(); status != "ok [version=1.0.0]" {
+		t.Fatalf("status mismatch: have %q", status)
+	}
+	accs := signer.Accounts()
+	if len(accs) != 1 || accs[0].Address != crypto.PubkeyToAddress(testKey.PublicKey) {
+		t.Fatalf("accounts mismatch: %v", accs)
+	}
+	if !signer.Contains(accs[0]) {
+		t.Fatal("listed account not contained")
+	}
+	// Sign a text and recover the signer
+	text := []byte("hello")
+	sig, err := signer.SignText(accs[0], text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash(text), sig)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != accs[0].Address {
+		t.Fatalf("text signature mismatch: %v", err)
+	}
+	// Sign typed data and recover the signer
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {{Name: "name", Type: "string"}, {Name: "chainId", Type: "uint256"}},
+			"Mail":         {{Name: "contents", Type: "string"}},
+		},
+		PrimaryType: "Mail",
+		Domain:      apitypes.TypedDataDomain{Name: "Test", ChainId: math.NewHexOrDecimal256(1)},
+		Message:     apitypes.TypedDataMessage{"contents": "hello"},
+	}
+	if sig, err = signer.SignTypedData(accs[0], typedData); err != nil {
+		t.Fatal(err)
+	}
+	hash, _, _ := apitypes.TypedDataAndHash(typedData)
+	pubkey, err = crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != accs[0].Address {
+		t.Fatalf("typed data signature mismatch: %v", err)
+	}
+	// Sign a transaction
+	chainID := big.NewInt(1337)
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 1, Gas: 21000, GasFeeCap: big.NewInt(1), GasTipCap: big.NewInt(1)})
+	signed, err := signer.SignTx(accs[0], tx, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed); err != nil || sender != accs[0].Address {
+		t.Fatalf("transaction sender mismatch: have %x (%v)", sender, err)
+	}
+}
+
+func TestGRPCSignerForgedTransaction(t *testing.T) {
+	signer := newTestGRPCSigner(t, &testGRPCSigner{forgeTx: true})
+
+	chainID := big.NewInt(1337)
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000, GasPrice: big.NewInt(1)})
+	if _, err := signer.SignTx(signer.Accounts()[0], tx, chainID); err == nil {
+		t.Fatal("forged transaction accepted")
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	signer := newTestGRPCSigner(t, new(testGRPCSigner))
+	_, err := signer.call(grpcService+"Unknown", nil)
+	if err, ok := err.(*grpcwire.Error); !ok || err.Code != 12 || err.Message != "unknown method" {
+		t.Fatalf("error mismatch: %v", err)
+	}
+}
@@ -178,3 +178,43 @@ func LedgerLiveIterator(base DerivationPath) func() DerivationPath {
 		return path
 	}
 }
+
+// TemplateIterator creates a BIP-32 path iterator from a derivation path template
+// in which exactly one component is the placeholder `x` (optionally hardened), and
+// progresses by increasing that component: i.e. for m/44'/60'/x'/0/0 it yields
+// m/44'/60'/0'/0/0, m/44'/60'/1'/0/0, m/44'/60'/2'/0/0, ... m/44'/60'/N'/0/0.
+func TemplateIterator(template string) (func() DerivationPath, error) {
+	components := strings.Split(template, "/")
+
+	// Locate the placeholder component and replace it with the first index
+	index := -1
+	for i, component := range components {
+		if strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(component), "'")) != "x" {
+			continue
+		}
+		if index != -1 {
+			return nil, errors.New("multiple placeholders in derivation path template")
+		}
+		index = i
+		components[i] = strings.Replace(component, "x", "0", 1)
+	}
+	if index == -1 {
+		return nil, errors.New("missing placeholder in derivation path template")
+	}
+	base, err := ParseDerivationPath(strings.Join(components, "/"))
+	if err != nil {
+		return nil, err
+	}
+	// Relative paths get the root prepended and absolute ones lose the `m`, so
+	// count the position of the placeholder from the end of the path
+	pos := len(base) - (len(components) - index)
+
+	path := make(DerivationPath, len(base))
+	copy(path[:], base[:])
+	// Set it back by one, so the first call gives the first result
+	path[pos]--
+	return func() DerivationPath {
+		path[pos]++
+		return path
+	}, nil
+}
@@ -116,3 +116,40 @@ func TestHdPathIteration(t *testing.T) {
 			"m/44'/60'/8'/0/0", "m/44'/60'/9'/0/0",
 		})
 }
+
+func TestHdPathTemplateIteration(t *testing.T) {
+	next, err := TemplateIterator("m/44'/60'/x'/0/0")
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	testDerive(t, next,
+		[]string{
+			"m/44'/60'/0'/0/0", "m/44'/60'/1'/0/0",
+			"m/44'/60'/2'/0/0", "m/44'/60'/3'/0/0",
+		})
+
+	next, err = TemplateIterator("m/44'/60'/0'/x")
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	testDerive(t, next,
+		[]string{
+			"m/44'/60'/0'/0", "m/44'/60'/0'/1",
+			"m/44'/60'/0'/2", "m/44'/60'/0'/3",
+		})
+
+	next, err = TemplateIterator("x/5")
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	testDerive(t, next,
+		[]string{
+			"m/44'/60'/0'/0/0/5", "m/44'/60'/0'/0/1/5",
+		})
+
+	for _, template := range []string{"m/44'/60'/0'/0", "m/44'/x'/x'/0", "m/44'/60'/y'/x"} {
+		if _, err := TemplateIterator(template); err == nil {
+			t.Errorf("template %q: expected error", template)
+		}
+	}
+}
@@ -17,8 +17,9 @@
 package keystore
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -30,6 +31,7 @@ import (
 	mapset "github.com/deckarep/golang-set"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -72,6 +74,24 @@ type accountCache struct {
 	throttle *time.Timer
 	notify   chan struct{}
 	fileC    fileCache
+	files    map[string]keyFile // Contents of the key files, by path
+	events   []keyFileEvent     // Key file changes not yet reported as wallet events
+}
+
+// keyFile identifies the contents of a key file, to tell apart the kind of
+// modifications done to it.
+type keyFile struct {
+	address common.Address // Address of the key stored in the file
+	id      string         // Key id stored in the file
+	hash    common.Hash    // Hash of the file content
+	corrupt bool           // Whether the file was last seen without a valid key
+}
+
+// keyFileEvent is a modification of a key file detected while scanning the
+// keystore directory.
+type keyFileEvent struct {
+	account accounts.Account
+	kind    accounts.WalletEventType
 }
 
 func newAccountCache(keydir string) (*accountCache, chan struct{}) {
@@ -80,6 +100,7 @@ func newAccountCache(keydir string) (*accountCache, chan struct{}) {
 		byAddr: make(map[common.Address][]accounts.Account),
 		notify: make(chan struct{}, 1),
 		fileC:  fileCache{all: mapset.NewThreadUnsafeSet()},
+		files:  make(map[string]keyFile),
 	}
 	ac.watcher = newWatcher(ac)
 	return ac, ac.notify
@@ -215,6 +236,35 @@ func (ac *accountCache) maybeReload() {
 	ac.scanAccounts()
 }
 
+// rewrote records the new contents of a key file rewritten by the keystore itself,
+// so scanning it doesn't report the change. It returns whether the contents were
+// not yet known, i.e. whether the change should be reported by the caller.
+func (ac *accountCache) rewrote(path string) bool {
+	_, file, err := readKeyFile(path)
+	if err != nil {
+		return false
+	}
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if ac.files[path] == file {
+		return false
+	}
+	ac.files[path] = file
+	return true
+}
+
+// takeEvents returns and clears the key file changes detected since the last
+// call.
+func (ac *accountCache) takeEvents() []keyFileEvent {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	events := ac.events
+	ac.events = nil
+	return events
+}
+
 func (ac *accountCache) close() {
 	ac.mu.Lock()
 	ac.watcher.close()
@@ -240,54 +290,43 @@ func (ac *accountCache) scanAccounts() error {
 	if creates.Cardinality() == 0 && deletes.Cardinality() == 0 && updates.Cardinality() == 0 {
 		return nil
 	}
-	// Create a helper method to scan the contents of the key files
-	var (
-		buf = new(bufio.Reader)
-		key struct {
-			Address string `json:"address"`
-		}
-	)
-	readAccount := func(path string) *accounts.Account {
-		fd, err := os.Open(path)
-		if err != nil {
-			log.Trace("Failed to open keystore file", "path", path, "err", err)
-			return nil
-		}
-		defer fd.Close()
-		buf.Reset(fd)
-		// Parse the address.
-		key.Address = ""
-		err = json.NewDecoder(buf).Decode(&key)
-		addr := common.HexToAddress(key.Address)
-		switch {
-		case err != nil:
-			log.Debug("Failed to decode keystore key", "path", path, "err", err)
-		case addr == common.Address{}:
-			log.Debug("Failed to decode keystore key", "path", path, "err", "missing or zero address")
-		default:
-			return &accounts.Account{
-				Address: addr,
-				URL:     accounts.URL{Scheme: KeyStoreScheme, Path: path},
-			}
-		}
-		return nil
-	}
 	// Process all the file diffs
 	start := time.Now()
 
-	for _, p := range creates.ToSlice() {
-		if a := readAccount(p.(string)); a != nil {
-			ac.add(*a)
-		}
-	}
 	for _, p := range deletes.ToSlice() {
-		ac.deleteByFile(p.(string))
+		path := p.(string)
+		ac.deleteByFile(path)
+		ac.setFile(path, nil)
 	}
-	for _, p := range updates.ToSlice() {
+	// Created files may already be known if the keystore rewrote them before
+	// the scan noticed, so handle them the same way as updates.
+	for _, p := range append(creates.ToSlice(), updates.ToSlice()...) {
 		path := p.(string)
 		ac.deleteByFile(path)
-		if a := readAccount(path); a != nil {
-			ac.add(*a)
+
+		prev, known := ac.file(path)
+		a, file, err := readKeyFile(path)
+		if err != nil {
+			// Keep tracking the previous key, the file may be mid-write
+			if known && !prev.corrupt {
+				log.Warn("Keystore file corrupted", "path", path, "address", prev.address, "err", err)
+				ac.addEvent(accounts.Account{Address: prev.address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: path}}, accounts.KeyFileCorrupted)
+
+				prev.corrupt = true
+				ac.setFile(path, &prev)
+			}
+			continue
+		}
+		ac.add(a)
+		ac.setFile(path, &file)
+
+		switch {
+		case !known:
+		case prev.address != file.address || prev.id != file.id:
+			log.Warn("Keystore file replaced", "path", path, "old", prev.address, "new", file.address)
+			ac.addEvent(a, accounts.KeyFileReplaced)
+		case prev.hash != file.hash:
+			ac.addEvent(a, accounts.PassphraseRotated)
 		}
 	}
 	end := time.Now()
@@ -299,3 +338,59 @@ func (ac *accountCache) scanAccounts() error {
 	log.Trace("Handled keystore changes", "time", end.Sub(start))
 	return nil
 }
+
+// file returns the tracked contents of the key file at the given path.
+func (ac *accountCache) file(path string) (keyFile, bool) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	file, ok := ac.files[path]
+	return file, ok
+}
+
+// setFile records the contents of the key file at the given path, nil forgets
+// the path.
+func (ac *accountCache) setFile(path string, file *keyFile) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if file == nil {
+		delete(ac.files, path)
+	} else {
+		ac.files[path] = *file
+	}
+}
+
+func (ac *accountCache) addEvent(a accounts.Account, kind accounts.WalletEventType) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	ac.events = append(ac.events, keyFileEvent{account: a, kind: kind})
+}
+
+// readKeyFile parses the account and identifies the contents of a key file.
+func readKeyFile(path string) (accounts.Account, keyFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Trace("Failed to open keystore file", "path", path, "err", err)
+		return accounts.Account{}, keyFile{}, err
+	}
+	var key struct {
+		Address string `json:"address"`
+		Id      string `json:"id"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(content)).Decode(&key); err != nil {
+		log.Debug("Failed to decode keystore key", "path", path, "err", err)
+		return accounts.Account{}, keyFile{}, err
+	}
+	addr := common.HexToAddress(key.Address)
+	if addr == (common.Address{}) {
+		log.Debug("Failed to decode keystore key", "path", path, "err", "missing or zero address")
+		return accounts.Account{}, keyFile{}, errors.New("missing or zero address")
+	}
+	account := accounts.Account{
+		Address: addr,
+		URL:     accounts.URL{Scheme: KeyStoreScheme, Path: path},
+	}
+	return account, keyFile{address: addr, id: key.Id, hash: crypto.Keccak256Hash(content)}, nil
+}
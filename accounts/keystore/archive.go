@@ -0,0 +1,159 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+)
+
+const (
+	// archiveVersion is the version of the encrypted key archive JSON format.
+	archiveVersion = 1
+
+	// archiveMaxKeySize is the maximum size of a key file accepted from an archive.
+	archiveMaxKeySize = 64 * 1024
+)
+
+// encryptedArchiveJSON is the JSON format of a passphrase-encrypted archive of
+// keys. The encrypted data is a gzipped tarball of V3 JSON key files.
+type encryptedArchiveJSON struct {
+	Keys    int        `json:"keys"`
+	Crypto  CryptoJSON `json:"crypto"`
+	Version int        `json:"version"`
+}
+
+// ExportArchive bundles the keys of the given accounts into a single encrypted
+// archive, e.g. for migrating many keys to another machine at once. The accounts
+// are unlocked with the passphrase, and both the archive and the V3 JSON keys
+// contained in it are encrypted with it.
+func (ks *KeyStore) ExportArchive(accts []accounts.Account, passphrase string) ([]byte, error) {
+	var (
+		buf  = new(bytes.Buffer)
+		zw   = gzip.NewWriter(buf)
+		tw   = tar.NewWriter(zw)
+		now  = time.Now()
+		seen = make(map[string]bool)
+	)
+	for _, a := range accts {
+		keyJSON, err := ks.Export(a, passphrase, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export account %x: %v", a.Address, err)
+		}
+		name := hex.EncodeToString(a.Address[:]) + ".json"
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(keyJSON)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(keyJSON); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	N, P := ks.scryptParams()
+	cryptoStruct, err := EncryptDataV3(buf.Bytes(), []byte(passphrase), N, P)
+	zeroBytes(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encryptedArchiveJSON{
+		Keys:    len(seen),
+		Crypto:  cryptoStruct,
+		Version: archiveVersion,
+	})
+}
+
+// ImportArchive decrypts an archive created by ExportArchive and stores all keys
+// contained in it into the key directory, encrypted with the same passphrase.
+// Accounts already present in the keystore are skipped. The imported accounts
+// are returned, including those imported before an error was encountered.
+func (ks *KeyStore) ImportArchive(data []byte, passphrase string) ([]accounts.Account, error) {
+	archive := new(encryptedArchiveJSON)
+	if err := json.Unmarshal(data, archive); err != nil {
+		return nil, err
+	}
+	if archive.Version != archiveVersion {
+		return nil, fmt.Errorf("unsupported key archive version: %d", archive.Version)
+	}
+	plain, err := DecryptDataV3(archive.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(plain)
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tr       = tar.NewReader(zr)
+		imported []accounts.Account
+	)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > archiveMaxKeySize {
+			return imported, fmt.Errorf("key file %s too large: %d bytes", hdr.Name, hdr.Size)
+		}
+		keyJSON, err := io.ReadAll(tr)
+		if err != nil {
+			return imported, err
+		}
+		a, err := ks.Import(keyJSON, passphrase, passphrase)
+		zeroBytes(keyJSON)
+		if errors.Is(err, ErrAccountAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return imported, fmt.Errorf("failed to import key file %s: %v", hdr.Name, err)
+		}
+		imported = append(imported, a)
+	}
+	return imported, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+)
+
+func TestArchiveImportExport(t *testing.T) {
+	_, ks := tmpKeyStore(t, true)
+
+	var accts []accounts.Account
+	for i := 0; i < 3; i++ {
+		acc, err := ks.NewAccount("pass")
+		if err != nil {
+			t.Fatalf("failed to create account: %v", err)
+		}
+		accts = append(accts, acc)
+	}
+	archive, err := ks.ExportArchive(accts, "pass")
+	if err != nil {
+		t.Fatalf("failed to export archive: %v", err)
+	}
+	if _, err := ks.ExportArchive(accts, "wrong"); err == nil {
+		t.Error("exporting with invalid password succeeded")
+	}
+	_, ks2 := tmpKeyStore(t, true)
+	if _, err := ks2.ImportArchive(archive, "wrong"); err != ErrDecrypt {
+		t.Errorf("wrong error for invalid password: have %v, want %v", err, ErrDecrypt)
+	}
+	// Pre-import one of the accounts, which should be skipped
+	keyJSON, err := ks.Export(accts[1], "pass", "pass")
+	if err != nil {
+		t.Fatalf("failed to export account: %v", err)
+	}
+	if _, err := ks2.Import(keyJSON, "pass", "pass"); err != nil {
+		t.Fatalf("failed to import account: %v", err)
+	}
+	imported, err := ks2.ImportArchive(archive, "pass")
+	if err != nil {
+		t.Fatalf("failed to import archive: %v", err)
+	}
+	if len(imported) != 2 {
+		t.Fatalf("imported account count mismatch: have %d, want 2", len(imported))
+	}
+	for _, acc := range accts {
+		if !ks2.HasAddress(acc.Address) {
+			t.Errorf("account %x missing after import", acc.Address)
+		}
+		if err := ks2.Unlock(accounts.Account{Address: acc.Address}, "pass"); err != nil {
+			t.Errorf("failed to unlock imported account %x: %v", acc.Address, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+// keychainService is the service name the keys are stored under in the OS
+// keychain.
+const keychainService = "go-ethereum"
+
+var errNoKeychainKey = errors.New("key file is not backed by the OS keychain")
+
+// keychain is a secret store provided by the operating system, protecting the
+// secrets with the credentials of the logged in user.
+type keychain interface {
+	// Name returns the name of the keychain, recorded in the key files.
+	Name() string
+
+	// Store saves the secret under the given id. The returned data is kept in
+	// the key file and passed to Load, for keychains that seal the secret
+	// instead of storing it.
+	Store(id string, secret []byte) ([]byte, error)
+
+	// Load retrieves the secret stored under the given id.
+	Load(id string, sealed []byte) ([]byte, error)
+
+	// Delete removes the secret stored under the given id.
+	Delete(id string) error
+}
+
+// NewOSKeyStore creates a keystore for the given directory, keeping the private
+// keys in the keychain of the operating system instead of encrypting them with a
+// passphrase. The key files in the directory only reference the keychain entries.
+//
+// Access to the keys is guarded by the operating system, the passphrases given
+// to the keystore are ignored.
+func NewOSKeyStore(keydir string) (*KeyStore, error) {
+	kc, err := newOSKeychain()
+	if err != nil {
+		return nil, err
+	}
+	return newKeychainKeyStore(keydir, kc), nil
+}
+
+func newKeychainKeyStore(keydir string, kc keychain) *KeyStore {
+	keydir, _ = filepath.Abs(keydir)
+	ks := &KeyStore{storage: &keyStoreOS{keydir, kc}}
+	ks.init(keydir)
+	return ks
+}
+
+// keychainKeyJSON is the content of the key file of a keychain backed key.
+type keychainKeyJSON struct {
+	Address  string `json:"address"`
+	Id       string `json:"id"`
+	Version  int    `json:"version"`
+	Keychain struct {
+		Name   string `json:"name"`
+		Sealed string `json:"sealed,omitempty"`
+	} `json:"keychain"`
+}
+
+type keyStoreOS struct {
+	keysDirPath string
+	keychain    keychain
+}
+
+func (ks keyStoreOS) GetKey(addr common.Address, filename, auth string) (*Key, error) {
+	keyJSON, err := ks.readKeyFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := hex.DecodeString(keyJSON.Keychain.Sealed)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ks.keychain.Load(keyJSON.Id, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key from %s keychain: %v", ks.keychain.Name(), err)
+	}
+	privkey, err := crypto.ToECDSA(secret)
+	for i := range secret {
+		secret[i] = 0
+	}
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(keyJSON.Id)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{Id: id, Address: crypto.PubkeyToAddress(privkey.PublicKey), PrivateKey: privkey}
+
+	// Make sure we're really operating on the requested key (no swap attacks)
+	if key.Address != addr {
+		return nil, fmt.Errorf("key content mismatch: have account %x, want %x", key.Address, addr)
+	}
+	return key, nil
+}
+
+func (ks keyStoreOS) StoreKey(filename string, key *Key, auth string) error {
+	secret := crypto.FromECDSA(key.PrivateKey)
+	sealed, err := ks.keychain.Store(key.Id.String(), secret)
+	for i := range secret {
+		secret[i] = 0
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store key in %s keychain: %v", ks.keychain.Name(), err)
+	}
+	keyJSON := keychainKeyJSON{
+		Address: hex.EncodeToString(key.Address[:]),
+		Id:      key.Id.String(),
+		Version: version,
+	}
+	keyJSON.Keychain.Name = ks.keychain.Name()
+	keyJSON.Keychain.Sealed = hex.EncodeToString(sealed)
+
+	content, err := json.Marshal(keyJSON)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(filename, content)
+}
+
+// DeleteKey removes the keychain entry referenced by the key file.
+func (ks keyStoreOS) DeleteKey(filename string) error {
+	keyJSON, err := ks.readKeyFile(filename)
+	if err != nil {
+		return err
+	}
+	return ks.keychain.Delete(keyJSON.Id)
+}
+
+func (ks keyStoreOS) JoinPath(filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+	return filepath.Join(ks.keysDirPath, filename)
+}
+
+// readKeyFile loads a key file, ensuring it references a key of the keychain.
+func (ks keyStoreOS) readKeyFile(filename string) (*keychainKeyJSON, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	keyJSON := new(keychainKeyJSON)
+	if err := json.Unmarshal(content, keyJSON); err != nil {
+		return nil, err
+	}
+	if keyJSON.Keychain.Name == "" {
+		return nil, errNoKeychainKey
+	}
+	if keyJSON.Keychain.Name != ks.keychain.Name() {
+		return nil, fmt.Errorf("key stored in %s keychain, have %s", keyJSON.Keychain.Name, ks.keychain.Name())
+	}
+	return keyJSON, nil
+}
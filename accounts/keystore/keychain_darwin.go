@@ -0,0 +1,65 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build darwin && !ios
+// +build darwin,!ios
+
+package keystore
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// macKeychain stores the keys as generic passwords in the login keychain of
+// macOS, using the security tool.
+type macKeychain struct{}
+
+func newOSKeychain() (keychain, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, fmt.Errorf("macOS keychain unavailable: %v", err)
+	}
+	return macKeychain{}, nil
+}
+
+func (macKeychain) Name() string { return "macos" }
+
+func (macKeychain) Store(id string, secret []byte) ([]byte, error) {
+	// The secret is passed via the interactive mode of the tool, as command
+	// line arguments are visible to other processes.
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %x\n", keychainService, id, secret))
+	if _, err := runKeychainTool(cmd); err != nil {
+		return nil, err
+	}
+	// Interactive mode does not report failures via the exit code
+	_, err := runKeychainTool(exec.Command("security", "find-generic-password", "-s", keychainService, "-a", id))
+	return nil, err
+}
+
+func (macKeychain) Load(id string, sealed []byte) ([]byte, error) {
+	out, err := runKeychainTool(exec.Command("security", "find-generic-password", "-s", keychainService, "-a", id, "-w"))
+	if err != nil {
+		return nil, err
+	}
+	return decodeKeychainSecret(out)
+}
+
+func (macKeychain) Delete(id string) error {
+	_, err := runKeychainTool(exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", id))
+	return err
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build (darwin && !ios) || linux
+// +build darwin,!ios linux
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runKeychainTool executes a command line tool of the OS keychain, returning
+// its output. The error output of the tool is included in the returned error.
+func runKeychainTool(cmd *exec.Cmd) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %v: %s", cmd.Args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %v", cmd.Args[0], err)
+	}
+	return out, nil
+}
+
+// decodeKeychainSecret decodes the hex encoded secret printed by a keychain tool.
+func decodeKeychainSecret(out []byte) ([]byte, error) {
+	secret, err := hex.DecodeString(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid keychain entry: %v", err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretServiceKeychain stores the keys in the keyring of the freedesktop.org
+// secret service, e.g. GNOME Keyring or KWallet, using the secret-tool utility.
+type secretServiceKeychain struct{}
+
+func newOSKeychain() (keychain, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, fmt.Errorf("secret service unavailable: %v", err)
+	}
+	return secretServiceKeychain{}, nil
+}
+
+func (secretServiceKeychain) Name() string { return "secret-service" }
+
+func (secretServiceKeychain) Store(id string, secret []byte) ([]byte, error) {
+	cmd := exec.Command("secret-tool", "store", "--label=Ethereum key "+id, "service", keychainService, "account", id)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("%x", secret))
+	_, err := runKeychainTool(cmd)
+	return nil, err
+}
+
+func (secretServiceKeychain) Load(id string, sealed []byte) ([]byte, error) {
+	out, err := runKeychainTool(exec.Command("secret-tool", "lookup", "service", keychainService, "account", id))
+	if err != nil {
+		return nil, err
+	}
+	return decodeKeychainSecret(out)
+}
+
+func (secretServiceKeychain) Delete(id string) error {
+	_, err := runKeychainTool(exec.Command("secret-tool", "clear", "service", keychainService, "account", id))
+	return err
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// memoryKeychain is an in-memory keychain for testing.
+type memoryKeychain map[string][]byte
+
+func (kc memoryKeychain) Name() string { return "memory" }
+
+func (kc memoryKeychain) Store(id string, secret []byte) ([]byte, error) {
+	kc[id] = append([]byte{}, secret...)
+	return nil, nil
+}
+
+func (kc memoryKeychain) Load(id string, sealed []byte) ([]byte, error) {
+	secret, ok := kc[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return append([]byte{}, secret...), nil
+}
+
+func (kc memoryKeychain) Delete(id string) error {
+	delete(kc, id)
+	return nil
+}
+
+func TestKeychainKeyStore(t *testing.T) {
+	kc := make(memoryKeychain)
+	ks := newKeychainKeyStore(t.TempDir(), kc)
+
+	a, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kc) != 1 {
+		t.Fatalf("keychain entries mismatch: have %d, want 1", len(kc))
+	}
+	// The key file must not contain the private key
+	content, err := os.ReadFile(a.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range kc {
+		if containsHex(content, secret) {
+			t.Fatal("key file contains the private key")
+		}
+	}
+	// Passphrases are ignored, the keychain guards the keys
+	if err := ks.Unlock(a, "anything"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.SignHash(a, make([]byte, 32)); err != nil {
+		t.Fatal(err)
+	}
+	// Imported keys end up in the keychain too
+	key, _ := crypto.GenerateKey()
+	imported, err := ks.ImportECDSA(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Address != crypto.PubkeyToAddress(key.PublicKey) || len(kc) != 2 {
+		t.Fatalf("import mismatch: address %x, %d keychain entries", imported.Address, len(kc))
+	}
+	// Deleting an account drops the keychain entry
+	if err := ks.Delete(a, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(kc) != 1 {
+		t.Fatalf("keychain entries mismatch after delete: have %d, want 1", len(kc))
+	}
+	if _, err := os.Stat(a.URL.Path); !os.IsNotExist(err) {
+		t.Fatalf("key file not deleted: %v", err)
+	}
+}
+
+// Tests that regular key files in the directory are not mistaken for keychain
+// backed ones.
+func TestKeychainKeyStoreForeignKey(t *testing.T) {
+	dir := t.TempDir()
+	plain := NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+	a, err := plain.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := newKeychainKeyStore(dir, make(memoryKeychain))
+	if err := ks.Unlock(a, "foo"); !errors.Is(err, errNoKeychainKey) {
+		t.Fatalf("unlock error mismatch: have %v, want %v", err, errNoKeychainKey)
+	}
+}
+
+func containsHex(content, secret []byte) bool {
+	return strings.Contains(strings.ToLower(string(content)), hex.EncodeToString(secret))
+}
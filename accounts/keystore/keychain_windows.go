@@ -0,0 +1,67 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/sys/windows"
+)
+
+// dpapiKeychain seals the keys with the Windows Data Protection API, binding
+// them to the credentials of the user. The sealed keys are kept in the key
+// files, so there is nothing to store or delete in the OS.
+type dpapiKeychain struct{}
+
+func newOSKeychain() (keychain, error) {
+	return dpapiKeychain{}, nil
+}
+
+func (dpapiKeychain) Name() string { return "dpapi" }
+
+func (dpapiKeychain) Store(id string, secret []byte) ([]byte, error) {
+	return dpapiCall(secret, func(in, out *windows.DataBlob) error {
+		return windows.CryptProtectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, out)
+	})
+}
+
+func (dpapiKeychain) Load(id string, sealed []byte) ([]byte, error) {
+	return dpapiCall(sealed, func(in, out *windows.DataBlob) error {
+		return windows.CryptUnprotectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, out)
+	})
+}
+
+func (dpapiKeychain) Delete(id string) error { return nil }
+
+// dpapiCall runs a DPAPI function on the data, copying the result out of the
+// memory allocated by the system.
+func dpapiCall(data []byte, fn func(in, out *windows.DataBlob) error) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty data")
+	}
+	var (
+		in  = windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+		out windows.DataBlob
+	)
+	if err := fn(&in, &out); err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return common.CopyBytes(unsafe.Slice(out.Data, out.Size)), nil
+}
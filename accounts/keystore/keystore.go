@@ -172,6 +172,11 @@ func (ks *KeyStore) refreshWallets() {
 		events = append(events, accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletDropped})
 	}
 	ks.wallets = wallets
+
+	// Report any tampering with the key files detected by the cache
+	for _, ev := range ks.cache.takeEvents() {
+		events = append(events, accounts.WalletEvent{Wallet: ks.wallet(ev.account), Kind: ev.kind})
+	}
 	ks.mu.Unlock()
 
 	// Fire all wallet events and return
@@ -180,6 +185,17 @@ func (ks *KeyStore) refreshWallets() {
 	}
 }
 
+// wallet returns the wallet of the given account, creating a detached one if
+// the account is not tracked (anymore). Callers must hold ks.mu.
+func (ks *KeyStore) wallet(account accounts.Account) accounts.Wallet {
+	for _, wallet := range ks.wallets {
+		if wallet.Accounts()[0] == account {
+			return wallet
+		}
+	}
+	return &keystoreWallet{account: account, keystore: ks}
+}
+
 // Subscribe implements accounts.Backend, creating an async subscription to
 // receive notifications on the addition or removal of keystore wallets.
 func (ks *KeyStore) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
@@ -247,6 +263,13 @@ func (ks *KeyStore) Delete(a accounts.Account, passphrase string) error {
 	if err != nil {
 		return err
 	}
+	// Keys kept outside of the key file are dropped from their store first,
+	// while the file referencing them still exists.
+	if storage, ok := ks.storage.(interface{ DeleteKey(string) error }); ok {
+		if err := storage.DeleteKey(a.URL.Path); err != nil {
+			return err
+		}
+	}
 	// The order is crucial here. The key is dropped from the
 	// cache after the file is gone so that a reload happening in
 	// between won't insert it into the cache again.
@@ -424,13 +447,17 @@ func (ks *KeyStore) Export(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return nil, err
 	}
-	var N, P int
+	N, P := ks.scryptParams()
+	return EncryptKey(key, newPassphrase, N, P)
+}
+
+// scryptParams returns the scrypt parameters to use when exporting keys out of
+// the keystore.
+func (ks *KeyStore) scryptParams() (N, P int) {
 	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
+		return store.scryptN, store.scryptP
 	}
-	return EncryptKey(key, newPassphrase, N, P)
+	return StandardScryptN, StandardScryptP
 }
 
 // Import stores the given encrypted JSON key into the key directory.
@@ -483,7 +510,17 @@ func (ks *KeyStore) Update(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return err
 	}
-	return ks.storage.StoreKey(a.URL.Path, key, newPassphrase)
+	if err := ks.storage.StoreKey(a.URL.Path, key, newPassphrase); err != nil {
+		return err
+	}
+	// Report the rotation unless the watcher already picked it up
+	if ks.cache.rewrote(a.URL.Path) {
+		ks.mu.RLock()
+		wallet := ks.wallet(a)
+		ks.mu.RUnlock()
+		ks.updateFeed.Send(accounts.WalletEvent{Wallet: wallet, Kind: accounts.PassphraseRotated})
+	}
+	return nil
 }
 
 // ImportPreSaleKey decrypts the given Ethereum presale wallet and stores
@@ -17,6 +17,7 @@
 package keystore
 
 import (
+	crand "crypto/rand"
 	"math/rand"
 	"os"
 	"runtime"
@@ -29,8 +30,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
 var testSigData = make([]byte, 32)
@@ -112,6 +115,47 @@ func TestSignWithPassphrase(t *testing.T) {
 	}
 }
 
+func TestSignTypedData(t *testing.T) {
+	_, ks := tmpKeyStore(t, true)
+
+	pass := "passwd"
+	acc, err := ks.NewAccount(pass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typedData := apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {{Name: "name", Type: "string"}, {Name: "chainId", Type: "uint256"}},
+			"Mail":         {{Name: "contents", Type: "string"}},
+		},
+		PrimaryType: "Mail",
+		Domain:      apitypes.TypedDataDomain{Name: "Test", ChainId: math.NewHexOrDecimal256(1)},
+		Message:     apitypes.TypedDataMessage{"contents": "hello"},
+	}
+	wallet := ks.Wallets()[0]
+	if _, err := wallet.SignTypedData(acc, typedData); err != ErrLocked {
+		t.Fatalf("signing with locked account: have %v, want %v", err, ErrLocked)
+	}
+	if err := ks.Unlock(acc, pass); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := wallet.SignTypedData(acc, typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := crypto.PubkeyToAddress(*pubkey); addr != acc.Address {
+		t.Fatalf("signer mismatch: have %x, want %x", addr, acc.Address)
+	}
+}
+
 func TestTimedUnlock(t *testing.T) {
 	_, ks := tmpKeyStore(t, true)
 
@@ -334,6 +378,76 @@ func TestWalletNotifications(t *testing.T) {
 	checkEvents(t, wantEvents, events)
 }
 
+// TestKeyFileEvents tests that passphrase rotations and modifications of the key
+// files on disk are reported to the wallet subscribers.
+func TestKeyFileEvents(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+
+	account, err := ks.NewAccount("old")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	other, err := newKey(crand.Reader)
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	otherJSON, err := EncryptKey(other, "", veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	updates := make(chan accounts.WalletEvent, 16)
+	sub := ks.Subscribe(updates)
+	defer sub.Unsubscribe()
+
+	wait := func(kind accounts.WalletEventType, want common.Address) {
+		t.Helper()
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case ev := <-updates:
+				if ev.Kind != kind {
+					continue
+				}
+				if have := ev.Wallet.Accounts()[0].Address; have != want {
+					t.Fatalf("event %d: account mismatch: have %x, want %x", kind, have, want)
+				}
+				return
+			case <-timeout:
+				t.Fatalf("timeout waiting for event %d", kind)
+			}
+		}
+	}
+	// Rotating the passphrase through the keystore is reported once
+	if err := ks.Update(account, "old", "new"); err != nil {
+		t.Fatalf("failed to update account: %v", err)
+	}
+	wait(accounts.PassphraseRotated, account.Address)
+
+	// Overwriting the key file with a different key is reported
+	time.Sleep(time.Second) // ensure a different modification time
+	if err := os.WriteFile(account.URL.Path, otherJSON, 0600); err != nil {
+		t.Fatal(err)
+	}
+	wait(accounts.KeyFileReplaced, other.Address)
+
+	// Overwriting the key file with garbage is reported
+	time.Sleep(time.Second)
+	if err := os.WriteFile(account.URL.Path, []byte("foo"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wait(accounts.KeyFileCorrupted, other.Address)
+
+	// No duplicate rotation event must have been emitted
+	for len(updates) > 0 {
+		if ev := <-updates; ev.Kind == accounts.PassphraseRotated {
+			t.Errorf("unexpected passphrase rotation event for %v", ev.Wallet.URL())
+		}
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("unexpected key files in keystore: %d", len(files))
+	}
+}
+
 // TestImportExport tests the import functionality of a keystore.
 func TestImportECDSA(t *testing.T) {
 	_, ks := tmpKeyStore(t, true)
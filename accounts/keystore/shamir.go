@@ -0,0 +1,134 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"errors"
+	"io"
+)
+
+// Shamir secret sharing over GF(2^8), using the AES reduction polynomial
+// x^8 + x^4 + x^3 + x + 1 and generator 3. Every byte of the secret is shared
+// independently with its own random polynomial, share i being the evaluation
+// of all the polynomials at x = i.
+
+var (
+	gfExp [510]byte // Exponentials, doubled up to avoid a modulo on multiplication
+	gfLog [256]byte // Discrete logarithms (log of zero is undefined)
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i], gfExp[i+255] = x, x
+		gfLog[x] = byte(i)
+
+		// Multiply by the generator: x*3 = x*2 ^ x
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+}
+
+// gfMul multiplies two field elements.
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfDiv divides two field elements, b must be non-zero.
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// splitSecret splits the secret into n shares, any k of which suffice to
+// reconstruct it. Share i (0 based) belongs to the x coordinate i+1.
+func splitSecret(rand io.Reader, secret []byte, n, k int) ([][]byte, error) {
+	if k < 1 || n < k || n > 255 {
+		return nil, errors.New("invalid share threshold")
+	}
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret))
+	}
+	coeffs := make([]byte, k)
+	for j, b := range secret {
+		// Random polynomial of degree k-1 with the secret as the constant term
+		coeffs[0] = b
+		if _, err := io.ReadFull(rand, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			// Evaluate with Horner's method at x = i+1
+			var (
+				x = byte(i + 1)
+				y byte
+			)
+			for c := k - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coeffs[c]
+			}
+			shares[i][j] = y
+		}
+	}
+	for i := range coeffs {
+		coeffs[i] = 0
+	}
+	return shares, nil
+}
+
+// combineShares reconstructs a secret from shares at the given x coordinates
+// using Lagrange interpolation at zero.
+func combineShares(xs []byte, shares [][]byte) ([]byte, error) {
+	if len(xs) == 0 || len(xs) != len(shares) {
+		return nil, errors.New("invalid share set")
+	}
+	for i, x := range xs {
+		if x == 0 {
+			return nil, errors.New("invalid share index")
+		}
+		if len(shares[i]) != len(shares[0]) {
+			return nil, errors.New("share length mismatch")
+		}
+		for _, x2 := range xs[:i] {
+			if x == x2 {
+				return nil, errors.New("duplicate share index")
+			}
+		}
+	}
+	secret := make([]byte, len(shares[0]))
+	for i, xi := range xs {
+		// Lagrange basis polynomial i evaluated at zero: prod(xj / (xj - xi))
+		basis := byte(1)
+		for j, xj := range xs {
+			if i != j {
+				basis = gfMul(basis, gfDiv(xj, xj^xi))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= gfMul(shares[i][b], basis)
+		}
+	}
+	return secret, nil
+}
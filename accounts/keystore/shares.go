@@ -0,0 +1,177 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// shareVersion is the version of the encrypted key share JSON format.
+const shareVersion = 1
+
+var (
+	// ErrShareMismatch is returned if the key shares being imported belong to
+	// different keys or sharing schemes.
+	ErrShareMismatch = errors.New("key shares mismatch")
+
+	// ErrNotEnoughShares is returned if fewer key shares are being imported than
+	// the threshold required to reconstruct the key.
+	ErrNotEnoughShares = errors.New("not enough key shares")
+)
+
+// encryptedShareJSON is the JSON format of a single passphrase-encrypted share
+// of a private key.
+type encryptedShareJSON struct {
+	Address   string     `json:"address"`
+	Index     int        `json:"index"`
+	Threshold int        `json:"threshold"`
+	Shares    int        `json:"shares"`
+	Crypto    CryptoJSON `json:"crypto"`
+	Version   int        `json:"version"`
+}
+
+// ExportShares splits the private key of an account into n Shamir shares, any k
+// of which can reconstruct the key via ImportShares. Every share is encrypted as
+// a JSON blob with its own passphrase, so sharePassphrases must have n elements.
+func (ks *KeyStore) ExportShares(a accounts.Account, passphrase string, n, k int, sharePassphrases []string) ([][]byte, error) {
+	if len(sharePassphrases) != n {
+		return nil, fmt.Errorf("share passphrase count mismatch: have %d, want %d", len(sharePassphrases), n)
+	}
+	_, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key.PrivateKey)
+
+	secret := math.PaddedBigBytes(key.PrivateKey.D, 32)
+	defer zeroBytes(secret)
+
+	shares, err := splitSecret(crand.Reader, secret, n, k)
+	if err != nil {
+		return nil, err
+	}
+	N, P := ks.scryptParams()
+
+	blobs := make([][]byte, n)
+	for i, share := range shares {
+		cryptoStruct, err := EncryptDataV3(share, []byte(sharePassphrases[i]), N, P)
+		zeroBytes(share)
+		if err != nil {
+			return nil, err
+		}
+		blobs[i], err = json.Marshal(&encryptedShareJSON{
+			Address:   hex.EncodeToString(key.Address[:]),
+			Index:     i + 1,
+			Threshold: k,
+			Shares:    n,
+			Crypto:    cryptoStruct,
+			Version:   shareVersion,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return blobs, nil
+}
+
+// ImportShares reconstructs a private key from encrypted shares created by
+// ExportShares and stores it into the key directory, encrypting it with
+// newPassphrase. The i-th share is decrypted with the i-th share passphrase.
+func (ks *KeyStore) ImportShares(shares [][]byte, sharePassphrases []string, newPassphrase string) (accounts.Account, error) {
+	if len(sharePassphrases) != len(shares) {
+		return accounts.Account{}, fmt.Errorf("share passphrase count mismatch: have %d, want %d", len(sharePassphrases), len(shares))
+	}
+	if len(shares) == 0 {
+		return accounts.Account{}, ErrNotEnoughShares
+	}
+	var (
+		first *encryptedShareJSON
+		xs    = make([]byte, len(shares))
+		ys    = make([][]byte, len(shares))
+	)
+	defer func() {
+		for _, y := range ys {
+			zeroBytes(y)
+		}
+	}()
+	for i, blob := range shares {
+		share := new(encryptedShareJSON)
+		if err := json.Unmarshal(blob, share); err != nil {
+			return accounts.Account{}, err
+		}
+		if share.Version != shareVersion {
+			return accounts.Account{}, fmt.Errorf("unsupported key share version: %d", share.Version)
+		}
+		if share.Index < 1 || share.Index > 255 {
+			return accounts.Account{}, fmt.Errorf("invalid key share index: %d", share.Index)
+		}
+		if first == nil {
+			first = share
+		} else if share.Address != first.Address || share.Threshold != first.Threshold || share.Shares != first.Shares {
+			return accounts.Account{}, ErrShareMismatch
+		}
+		y, err := DecryptDataV3(share.Crypto, sharePassphrases[i])
+		if err != nil {
+			return accounts.Account{}, err
+		}
+		xs[i], ys[i] = byte(share.Index), y
+	}
+	if len(shares) < first.Threshold {
+		return accounts.Account{}, ErrNotEnoughShares
+	}
+	secret, err := combineShares(xs, ys)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	defer zeroBytes(secret)
+
+	priv, err := crypto.ToECDSA(secret)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	key := newKeyFromECDSA(priv)
+	defer zeroKey(key.PrivateKey)
+
+	if key.Address != common.HexToAddress(first.Address) {
+		return accounts.Account{}, ErrShareMismatch
+	}
+	ks.importMu.Lock()
+	defer ks.importMu.Unlock()
+
+	if ks.cache.hasAddress(key.Address) {
+		return accounts.Account{
+			Address: key.Address,
+		}, ErrAccountAlreadyExists
+	}
+	return ks.importKey(key, newPassphrase)
+}
+
+// zeroBytes zeroes a byte slice in memory.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
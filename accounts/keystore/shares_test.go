@@ -0,0 +1,91 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"testing"
+)
+
+// Tests that any k out of n Shamir shares reconstruct the original secret.
+func TestShamirSplitCombine(t *testing.T) {
+	secret := make([]byte, 32)
+	crand.Read(secret)
+
+	shares, err := splitSecret(crand.Reader, secret, 5, 3)
+	if err != nil {
+		t.Fatalf("failed to split secret: %v", err)
+	}
+	for _, set := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var (
+			xs []byte
+			ys [][]byte
+		)
+		for _, i := range set {
+			xs, ys = append(xs, byte(i+1)), append(ys, shares[i])
+		}
+		have, err := combineShares(xs, ys)
+		if err != nil {
+			t.Fatalf("set %v: failed to combine shares: %v", set, err)
+		}
+		if !bytes.Equal(have, secret) {
+			t.Errorf("set %v: secret mismatch: have %x, want %x", set, have, secret)
+		}
+	}
+	// Fewer shares than the threshold should not leak the secret
+	have, _ := combineShares([]byte{1, 2}, shares[:2])
+	if bytes.Equal(have, secret) {
+		t.Errorf("secret reconstructed below threshold")
+	}
+}
+
+func TestExportImportShares(t *testing.T) {
+	_, ks := tmpKeyStore(t, true)
+	a, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwords := []string{"a", "b", "c", "d"}
+	shares, err := ks.ExportShares(a, "foo", 4, 2, passwords)
+	if err != nil {
+		t.Fatalf("failed to export shares: %v", err)
+	}
+	if len(shares) != 4 {
+		t.Fatalf("share count mismatch: have %d, want 4", len(shares))
+	}
+	_, ks2 := tmpKeyStore(t, true)
+	if _, err := ks2.ImportShares(shares[1:2], passwords[1:2], "bar"); err != ErrNotEnoughShares {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNotEnoughShares)
+	}
+	if _, err := ks2.ImportShares(shares[1:3], []string{"b", "x"}, "bar"); err != ErrDecrypt {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	imported, err := ks2.ImportShares([][]byte{shares[3], shares[0]}, []string{"d", "a"}, "bar")
+	if err != nil {
+		t.Fatalf("failed to import shares: %v", err)
+	}
+	if imported.Address != a.Address {
+		t.Fatalf("imported address mismatch: have %x, want %x", imported.Address, a.Address)
+	}
+	if err := ks2.Unlock(imported, "bar"); err != nil {
+		t.Fatalf("failed to unlock imported account: %v", err)
+	}
+	if _, err := ks2.ImportShares(shares[:2], passwords[:2], "bar"); err != ErrAccountAlreadyExists {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrAccountAlreadyExists)
+	}
+}
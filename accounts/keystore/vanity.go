@@ -0,0 +1,149 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+)
+
+// vanityProgressCycle is the interval at which vanity search progress is reported.
+const vanityProgressCycle = time.Second
+
+// VanityConfig describes the address pattern a vanity key search should look for.
+type VanityConfig struct {
+	Prefix  string // Hex prefix (without 0x) the address should start with
+	Suffix  string // Hex suffix the address should end with
+	Workers int    // Number of concurrent search threads (0 = number of CPUs)
+
+	// Progress, if set, is periodically invoked with the total number of keys
+	// tried so far. It is called from a single goroutine.
+	Progress func(tried uint64)
+}
+
+// validate checks the search pattern and normalizes it to lowercase hex.
+func (c *VanityConfig) validate() error {
+	c.Prefix = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(c.Prefix, "0x"), "0X"))
+	c.Suffix = strings.ToLower(c.Suffix)
+
+	if c.Prefix == "" && c.Suffix == "" {
+		return errors.New("empty vanity pattern")
+	}
+	if len(c.Prefix)+len(c.Suffix) > 2*20 {
+		return errors.New("vanity pattern longer than an address")
+	}
+	for _, r := range c.Prefix + c.Suffix {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return fmt.Errorf("invalid hex character %q in vanity pattern", r)
+		}
+	}
+	if c.Workers <= 0 {
+		c.Workers = runtime.NumCPU()
+	}
+	return nil
+}
+
+// NewVanityAccount generates random keys on multiple threads until one is found
+// whose address matches the requested prefix and/or suffix. The matching key is
+// stored into the key directory immediately, encrypted with the passphrase.
+//
+// The search can be aborted via the context, in which case its error is returned.
+func (ks *KeyStore) NewVanityAccount(ctx context.Context, config VanityConfig, passphrase string) (accounts.Account, error) {
+	if err := config.validate(); err != nil {
+		return accounts.Account{}, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		tried uint64
+		found = make(chan *Key, 1)
+		errc  = make(chan error, 1)
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				key, err := newKey(crand.Reader)
+				if err != nil {
+					select {
+					case errc <- err:
+					default:
+					}
+					return
+				}
+				atomic.AddUint64(&tried, 1)
+
+				addr := hex.EncodeToString(key.Address[:])
+				if strings.HasPrefix(addr, config.Prefix) && strings.HasSuffix(addr, config.Suffix) {
+					select {
+					case found <- key:
+					default:
+						zeroKey(key.PrivateKey)
+					}
+					return
+				}
+				zeroKey(key.PrivateKey)
+			}
+		}()
+	}
+	// Wait for a match, reporting progress in the meantime
+	progress := time.NewTicker(vanityProgressCycle)
+	defer progress.Stop()
+
+	var key *Key
+	for key == nil {
+		select {
+		case key = <-found:
+		case err := <-errc:
+			cancel()
+			wg.Wait()
+			return accounts.Account{}, err
+		case <-ctx.Done():
+			wg.Wait()
+			return accounts.Account{}, ctx.Err()
+		case <-progress.C:
+			if config.Progress != nil {
+				config.Progress(atomic.LoadUint64(&tried))
+			}
+		}
+	}
+	cancel()
+	wg.Wait()
+	defer zeroKey(key.PrivateKey)
+
+	if config.Progress != nil {
+		config.Progress(atomic.LoadUint64(&tried))
+	}
+	ks.importMu.Lock()
+	defer ks.importMu.Unlock()
+
+	return ks.importKey(key, passphrase)
+}
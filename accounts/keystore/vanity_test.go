@@ -0,0 +1,66 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestNewVanityAccount(t *testing.T) {
+	_, ks := tmpKeyStore(t, true)
+
+	a, err := ks.NewVanityAccount(context.Background(), VanityConfig{Prefix: "0xA", Suffix: "b", Workers: 2}, "foo")
+	if err != nil {
+		t.Fatalf("vanity search failed: %v", err)
+	}
+	addr := hex.EncodeToString(a.Address[:])
+	if !strings.HasPrefix(addr, "a") || !strings.HasSuffix(addr, "b") {
+		t.Fatalf("address %x doesn't match pattern", a.Address)
+	}
+	if !ks.HasAddress(a.Address) {
+		t.Fatalf("vanity account %x not stored", a.Address)
+	}
+	if err := ks.Unlock(a, "foo"); err != nil {
+		t.Fatalf("failed to unlock vanity account: %v", err)
+	}
+}
+
+func TestNewVanityAccountCancel(t *testing.T) {
+	_, ks := tmpKeyStore(t, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ks.NewVanityAccount(ctx, VanityConfig{Prefix: strings.Repeat("0", 40)}, "foo"); err != context.Canceled {
+		t.Fatalf("error mismatch: have %v, want %v", err, context.Canceled)
+	}
+	if len(ks.Accounts()) != 0 {
+		t.Fatalf("cancelled search stored an account")
+	}
+}
+
+func TestNewVanityAccountInvalid(t *testing.T) {
+	_, ks := tmpKeyStore(t, true)
+
+	for _, config := range []VanityConfig{{}, {Prefix: "xyz"}, {Suffix: strings.Repeat("a", 41)}} {
+		if _, err := ks.NewVanityAccount(context.Background(), config, "foo"); err == nil {
+			t.Errorf("config %+v: expected error", config)
+		}
+	}
+}
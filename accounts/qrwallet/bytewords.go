@@ -0,0 +1,97 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qrwallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strings"
+)
+
+// bytewords is the word list of the Bytewords encoding (BCR-2020-012). In the
+// minimal form used by URs, every byte is encoded by the first and last letter
+// of its word.
+var bytewords = strings.Fields(`
+	able acid also apex aqua arch atom aunt away axis back bald barn belt beta bias
+	blue body brag brew bulb buzz calm cash cats chef city claw code cola cook cost
+	crux curl cusp cyan dark data days deli dice diet door down draw drop drum dull
+	duty each easy echo edge epic even exam exit eyes fact fair fern figs film fish
+	fizz flap flew flux foxy free frog fuel fund gala game gear gems gift girl glow
+	good gray grim guru gush gyro half hang hard hawk heat help high hill holy hope
+	horn huts iced idea idle inch inky into iris iron item jade jazz join jolt jowl
+	judo jugs jump junk jury keep keno kept keys kick kiln king kite kiwi knob lamb
+	lava lazy leaf legs liar limp lion list logo loud love luau luck lung main many
+	math maze memo menu meow mild mint miss monk nail navy need news next noon note
+	numb obey oboe omit onyx open oval owls paid part peck play plus poem pool pose
+	puff puma purr quad quiz race ramp real redo rich road rock roof ruby ruin runs
+	rust safe saga scar sets silk skew slot soap solo song stub surf swan taco task
+	taxi tent tied time tiny toil tomb toys trip tuna twin ugly undo unit urge user
+	vast very veto vial vibe view visa void vows wall wand warm wasp wave waxy webs
+	what when whiz wolf work yank yawn yell yoga yurt zaps zero zest zinc zone zoom
+`)
+
+// minimalBytewords maps the minimal encodings back to the byte values.
+var minimalBytewords = func() map[string]byte {
+	m := make(map[string]byte, len(bytewords))
+	for i, word := range bytewords {
+		m[word[:1]+word[3:]] = byte(i)
+	}
+	return m
+}()
+
+var errBytewordsChecksum = errors.New("invalid bytewords checksum")
+
+// encodeBytewords encodes data in minimal Bytewords, appending its CRC32 checksum.
+func encodeBytewords(data []byte) string {
+	var checksum [4]byte
+	binary.BigEndian.PutUint32(checksum[:], crc32.ChecksumIEEE(data))
+
+	var b strings.Builder
+	b.Grow(2 * (len(data) + 4))
+	for _, c := range append(append([]byte{}, data...), checksum[:]...) {
+		word := bytewords[c]
+		b.WriteByte(word[0])
+		b.WriteByte(word[3])
+	}
+	return b.String()
+}
+
+// decodeBytewords decodes minimal Bytewords, verifying and stripping the trailing
+// CRC32 checksum.
+func decodeBytewords(s string) ([]byte, error) {
+	s = strings.ToLower(s)
+	if len(s)%2 != 0 {
+		return nil, errors.New("invalid bytewords length")
+	}
+	data := make([]byte, len(s)/2)
+	for i := range data {
+		c, ok := minimalBytewords[s[2*i:2*i+2]]
+		if !ok {
+			return nil, errors.New("invalid byteword")
+		}
+		data[i] = c
+	}
+	if len(data) < 4 {
+		return nil, errBytewordsChecksum
+	}
+	body, checksum := data[:len(data)-4], data[len(data)-4:]
+	if binary.BigEndian.Uint32(checksum) != crc32.ChecksumIEEE(body) {
+		return nil, errBytewordsChecksum
+	}
+	return body, nil
+}
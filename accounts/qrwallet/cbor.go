@@ -0,0 +1,225 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qrwallet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTagged = 6
+	cborSimple = 7
+)
+
+// cborMaxDepth is the maximum nesting of CBOR items accepted by the decoder.
+const cborMaxDepth = 16
+
+var errCBORTruncated = errors.New("truncated CBOR item")
+
+// cborTag is a decoded tagged CBOR item.
+type cborTag struct {
+	Number  uint64
+	Content interface{}
+}
+
+// cborWriter encodes the subset of CBOR used by the UR registry types. Maps and
+// arrays are written by their header, followed by their elements.
+type cborWriter struct {
+	buf []byte
+}
+
+func (w *cborWriter) head(major byte, n uint64) {
+	switch {
+	case n < 24:
+		w.buf = append(w.buf, major<<5|byte(n))
+	case n <= 0xff:
+		w.buf = append(w.buf, major<<5|24, byte(n))
+	case n <= 0xffff:
+		w.buf = append(w.buf, major<<5|25, 0, 0)
+		binary.BigEndian.PutUint16(w.buf[len(w.buf)-2:], uint16(n))
+	case n <= 0xffffffff:
+		w.buf = append(w.buf, major<<5|26, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(w.buf[len(w.buf)-4:], uint32(n))
+	default:
+		w.buf = append(w.buf, major<<5|27, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(w.buf[len(w.buf)-8:], n)
+	}
+}
+
+func (w *cborWriter) uint(n uint64)   { w.head(cborUint, n) }
+func (w *cborWriter) array(n int)     { w.head(cborArray, uint64(n)) }
+func (w *cborWriter) mapHeader(n int) { w.head(cborMap, uint64(n)) }
+func (w *cborWriter) tag(n uint64)    { w.head(cborTagged, n) }
+
+func (w *cborWriter) bytes(b []byte) {
+	w.head(cborBytes, uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *cborWriter) text(s string) {
+	w.head(cborText, uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *cborWriter) bool(v bool) {
+	if v {
+		w.buf = append(w.buf, cborSimple<<5|21)
+	} else {
+		w.buf = append(w.buf, cborSimple<<5|20)
+	}
+}
+
+// decodeCBOR decodes a single CBOR item spanning all of data. Unsigned integers
+// are returned as uint64, negative ones as int64, byte and text strings as []byte
+// and string, arrays as []interface{}, maps as map[uint64]interface{} (only
+// unsigned keys are supported) and tags as cborTag.
+func decodeCBOR(data []byte) (interface{}, error) {
+	v, rest, err := decodeCBORItem(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d trailing bytes after CBOR item", len(rest))
+	}
+	return v, nil
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, errors.New("CBOR nesting too deep")
+	}
+	if len(data) == 0 {
+		return nil, nil, errCBORTruncated
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	if major == cborSimple {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22:
+			return nil, data, nil
+		}
+		return nil, nil, fmt.Errorf("unsupported CBOR simple value %d", info)
+	}
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info == 24:
+		if len(data) < 1 {
+			return nil, nil, errCBORTruncated
+		}
+		n, data = uint64(data[0]), data[1:]
+	case info == 25:
+		if len(data) < 2 {
+			return nil, nil, errCBORTruncated
+		}
+		n, data = uint64(binary.BigEndian.Uint16(data)), data[2:]
+	case info == 26:
+		if len(data) < 4 {
+			return nil, nil, errCBORTruncated
+		}
+		n, data = uint64(binary.BigEndian.Uint32(data)), data[4:]
+	case info == 27:
+		if len(data) < 8 {
+			return nil, nil, errCBORTruncated
+		}
+		n, data = binary.BigEndian.Uint64(data), data[8:]
+	default:
+		return nil, nil, errors.New("indefinite length CBOR items not supported")
+	}
+	switch major {
+	case cborUint:
+		return n, data, nil
+
+	case cborNegInt:
+		if n > 1<<63-1 {
+			return nil, nil, errors.New("CBOR integer overflow")
+		}
+		return -1 - int64(n), data, nil
+
+	case cborBytes, cborText:
+		if uint64(len(data)) < n {
+			return nil, nil, errCBORTruncated
+		}
+		if major == cborText {
+			return string(data[:n]), data[n:], nil
+		}
+		return append([]byte{}, data[:n]...), data[n:], nil
+
+	case cborArray:
+		if uint64(len(data)) < n {
+			return nil, nil, errCBORTruncated
+		}
+		items := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var (
+				item interface{}
+				err  error
+			)
+			if item, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, data, nil
+
+	case cborMap:
+		if uint64(len(data)) < 2*n {
+			return nil, nil, errCBORTruncated
+		}
+		items := make(map[uint64]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var (
+				key, value interface{}
+				err        error
+			)
+			if key, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			k, ok := key.(uint64)
+			if !ok {
+				return nil, nil, fmt.Errorf("unsupported CBOR map key %v", key)
+			}
+			if value, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items[k] = value
+		}
+		return items, data, nil
+
+	default: // cborTagged
+		content, rest, err := decodeCBORItem(data, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cborTag{Number: n, Content: content}, rest, nil
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package qrwallet implements support for air-gapped hardware wallets signing via
+// QR codes, like the Keystone, following the BC-UR (EIP-4527) scheme.
+//
+// Sign requests are encoded as UR parts to be displayed to the wallet as an
+// (animated) QR code, and the signatures are scanned back from the screen of
+// the wallet. Displaying and scanning is done by a Transport provided by the
+// application.
+package qrwallet
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Scheme is the protocol scheme prefixing account and wallet URLs.
+const Scheme = "qr"
+
+// errWalletImported is returned when importing a wallet which was imported before.
+var errWalletImported = errors.New("wallet already imported")
+
+// Hub is an accounts.Backend managing the air-gapped wallets imported from the
+// keys they export.
+type Hub struct {
+	wallets []accounts.Wallet // Wallets imported so far, sorted by URL
+	lock    sync.RWMutex
+
+	updateFeed  event.Feed
+	updateScope event.SubscriptionScope
+}
+
+// NewHub creates an empty air-gapped wallet hub.
+func NewHub() *Hub {
+	return new(Hub)
+}
+
+// NewHubFromFile creates an air-gapped wallet hub with the wallets exporting the
+// keys listed in the given file, as accepted by ImportKeys.
+func NewHubFromFile(path string, transport Transport) (*Hub, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hub := NewHub()
+	if err := hub.ImportKeys(f, transport); err != nil {
+		return nil, err
+	}
+	return hub, nil
+}
+
+// Wallets implements accounts.Backend, returning all the imported wallets.
+func (hub *Hub) Wallets() []accounts.Wallet {
+	hub.lock.RLock()
+	defer hub.lock.RUnlock()
+
+	cpy := make([]accounts.Wallet, len(hub.wallets))
+	copy(cpy, hub.wallets)
+	return cpy
+}
+
+// Subscribe implements accounts.Backend, creating an async subscription to
+// receive notifications on the addition of wallets.
+func (hub *Hub) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return hub.updateScope.Track(hub.updateFeed.Subscribe(sink))
+}
+
+// Import adds the wallet exporting the given crypto-hdkey UR, scanned from the
+// wallet in one or more parts. The transport is used to exchange the signing
+// requests with the wallet.
+func (hub *Hub) Import(parts []string, transport Transport) (*Wallet, error) {
+	var dec urDecoder
+	for _, part := range parts {
+		if err := dec.receive(part); err != nil {
+			return nil, err
+		}
+	}
+	if dec.result == nil {
+		return nil, errURIncomplete
+	}
+	return hub.importUR(dec.result, transport)
+}
+
+// ImportKeys adds the wallets exporting the crypto-hdkey URs read from r, one UR
+// part per line. The parts of a multi-part UR are expected on consecutive lines.
+// Wallets imported before are skipped.
+func (hub *Hub) ImportKeys(r io.Reader, transport Transport) error {
+	var (
+		scanner = bufio.NewScanner(r)
+		dec     urDecoder
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		part := strings.TrimSpace(scanner.Text())
+		if part == "" || strings.HasPrefix(part, "#") {
+			continue
+		}
+		if err := dec.receive(part); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if dec.result == nil {
+			continue
+		}
+		if _, err := hub.importUR(dec.result, transport); err != nil && err != errWalletImported {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		dec = urDecoder{}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if dec.typ != "" {
+		return errURIncomplete
+	}
+	return nil
+}
+
+// importUR adds the wallet exporting the given crypto-hdkey UR.
+func (hub *Hub) importUR(result *ur, transport Transport) (*Wallet, error) {
+	if result.Type != urTypeHDKey {
+		return nil, fmt.Errorf("unexpected UR type %q, want %s", result.Type, urTypeHDKey)
+	}
+	key, err := decodeHDKey(result.CBOR)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := newWallet(key, transport)
+	if err != nil {
+		return nil, err
+	}
+	hub.lock.Lock()
+	n := sort.Search(len(hub.wallets), func(i int) bool {
+		return hub.wallets[i].URL().Cmp(wallet.url) >= 0
+	})
+	if n < len(hub.wallets) && hub.wallets[n].URL() == wallet.url {
+		hub.lock.Unlock()
+		return nil, errWalletImported
+	}
+	hub.wallets = append(hub.wallets[:n], append([]accounts.Wallet{wallet}, hub.wallets[n:]...)...)
+	hub.lock.Unlock()
+
+	hub.updateFeed.Send(accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletArrived})
+	return wallet, nil
+}
+
+// Close terminates all subscriptions of the hub.
+func (hub *Hub) Close() {
+	hub.updateScope.Close()
+}
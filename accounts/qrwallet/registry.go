@@ -0,0 +1,264 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qrwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// UR types exchanged with the signer, registered in BCR-2020-006 and EIP-4527.
+const (
+	urTypeHDKey       = "crypto-hdkey"
+	urTypeSignRequest = "eth-sign-request"
+	urTypeSignature   = "eth-signature"
+)
+
+// CBOR tags of the registry types.
+const (
+	tagUUID        = 37
+	tagHDKey       = 303
+	tagKeypath     = 304
+	tagSignRequest = 401
+	tagSignature   = 402
+)
+
+// Data types of an eth-sign-request.
+const (
+	dataTypeTransaction      = 1 // RLP encoded legacy transaction
+	dataTypeTypedData        = 2 // EIP-712 typed data
+	dataTypePersonalMessage  = 3 // Message signed with the EIP-191 personal prefix
+	dataTypeTypedTransaction = 4 // EIP-2718 typed transaction signing payload
+)
+
+// keypath is a BIP-32 derivation path (crypto-keypath).
+type keypath struct {
+	components  accounts.DerivationPath
+	fingerprint uint32 // Fingerprint of the source (master) key, zero if unknown
+}
+
+func (p keypath) encode(w *cborWriter) {
+	w.tag(tagKeypath)
+	if p.fingerprint != 0 {
+		w.mapHeader(2)
+	} else {
+		w.mapHeader(1)
+	}
+	w.uint(1)
+	w.array(2 * len(p.components))
+	for _, component := range p.components {
+		w.uint(uint64(component &^ 0x80000000))
+		w.bool(component >= 0x80000000)
+	}
+	if p.fingerprint != 0 {
+		w.uint(2)
+		w.uint(uint64(p.fingerprint))
+	}
+}
+
+func decodeKeypath(item interface{}) (keypath, error) {
+	if tag, ok := item.(cborTag); ok {
+		if tag.Number != tagKeypath {
+			return keypath{}, fmt.Errorf("unexpected keypath tag %d", tag.Number)
+		}
+		item = tag.Content
+	}
+	fields, ok := item.(map[uint64]interface{})
+	if !ok {
+		return keypath{}, errors.New("invalid keypath")
+	}
+	var path keypath
+	components, ok := fields[1].([]interface{})
+	if !ok || len(components)%2 != 0 {
+		return keypath{}, errors.New("invalid keypath components")
+	}
+	for i := 0; i < len(components); i += 2 {
+		index, ok1 := components[i].(uint64)
+		hardened, ok2 := components[i+1].(bool)
+		if !ok1 || !ok2 || index >= 0x80000000 {
+			return keypath{}, errors.New("unsupported keypath component")
+		}
+		if hardened {
+			index |= 0x80000000
+		}
+		path.components = append(path.components, uint32(index))
+	}
+	if fingerprint, ok := fields[2].(uint64); ok {
+		path.fingerprint = uint32(fingerprint)
+	}
+	return path, nil
+}
+
+// hdKey is an extended public key exported by the signer (crypto-hdkey), from
+// which the accounts of the wallet are derived.
+type hdKey struct {
+	key       *ecdsa.PublicKey
+	chainCode []byte
+	origin    keypath // Derivation path of the key from the master key
+	name      string
+}
+
+func decodeHDKey(data []byte) (*hdKey, error) {
+	item, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	if tag, ok := item.(cborTag); ok && tag.Number == tagHDKey {
+		item = tag.Content
+	}
+	fields, ok := item.(map[uint64]interface{})
+	if !ok {
+		return nil, errors.New("invalid hdkey")
+	}
+	if private, _ := fields[2].(bool); private {
+		return nil, errors.New("private hdkey not accepted")
+	}
+	keyData, ok := fields[3].([]byte)
+	if !ok {
+		return nil, errors.New("hdkey lacks key data")
+	}
+	key, err := crypto.DecompressPubkey(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hdkey key data: %v", err)
+	}
+	chainCode, ok := fields[4].([]byte)
+	if !ok || len(chainCode) != 32 {
+		return nil, errors.New("hdkey lacks chain code")
+	}
+	k := &hdKey{key: key, chainCode: chainCode}
+	if origin, ok := fields[6]; ok {
+		if k.origin, err = decodeKeypath(origin); err != nil {
+			return nil, err
+		}
+	}
+	k.name, _ = fields[9].(string)
+	return k, nil
+}
+
+// derive computes the address of the account at the given path relative to the
+// key. Only non-hardened derivation is possible from a public key.
+func (k *hdKey) derive(path []uint32) (common.Address, error) {
+	var (
+		curve = crypto.S256()
+		n     = curve.Params().N
+		pub   = k.key
+		chain = k.chainCode
+	)
+	for _, index := range path {
+		if index >= 0x80000000 {
+			return common.Address{}, errors.New("hardened derivation not possible from public key")
+		}
+		data := append(crypto.CompressPubkey(pub), 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+		mac := hmac.New(sha512.New, chain)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		if new(big.Int).SetBytes(sum[:32]).Cmp(n) >= 0 {
+			return common.Address{}, errors.New("invalid derived key")
+		}
+		x, y := curve.ScalarBaseMult(sum[:32])
+		x, y = curve.Add(x, y, pub.X, pub.Y)
+		if x.Sign() == 0 && y.Sign() == 0 {
+			return common.Address{}, errors.New("invalid derived key")
+		}
+		pub, chain = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, sum[32:]
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// signRequest is a request for the signer to sign data (eth-sign-request).
+type signRequest struct {
+	id       [16]byte
+	data     []byte
+	dataType uint64
+	chainID  *big.Int // Omitted if nil
+	path     keypath
+	address  common.Address
+	origin   string
+}
+
+func (r *signRequest) encode() ur {
+	fields := 6
+	if r.chainID != nil {
+		fields++
+	}
+	w := new(cborWriter)
+	w.mapHeader(fields)
+	w.uint(1)
+	w.tag(tagUUID)
+	w.bytes(r.id[:])
+	w.uint(2)
+	w.bytes(r.data)
+	w.uint(3)
+	w.uint(r.dataType)
+	if r.chainID != nil {
+		w.uint(4)
+		w.uint(r.chainID.Uint64())
+	}
+	w.uint(5)
+	r.path.encode(w)
+	w.uint(6)
+	w.bytes(r.address[:])
+	w.uint(7)
+	w.text(r.origin)
+	return ur{Type: urTypeSignRequest, CBOR: w.buf}
+}
+
+// signature is the response of the signer to a sign request (eth-signature).
+type signature struct {
+	id        [16]byte
+	signature []byte // [R || S || V], V may be longer than a byte for EIP-155
+}
+
+func decodeSignature(data []byte) (*signature, error) {
+	item, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	if tag, ok := item.(cborTag); ok && tag.Number == tagSignature {
+		item = tag.Content
+	}
+	fields, ok := item.(map[uint64]interface{})
+	if !ok {
+		return nil, errors.New("invalid signature")
+	}
+	sig := new(signature)
+	tag, ok := fields[1].(cborTag)
+	if !ok || tag.Number != tagUUID {
+		return nil, errors.New("signature lacks request id")
+	}
+	id, ok := tag.Content.([]byte)
+	if !ok || len(id) != len(sig.id) {
+		return nil, errors.New("invalid signature request id")
+	}
+	copy(sig.id[:], id)
+	if sig.signature, ok = fields[2].([]byte); !ok || len(sig.signature) < 65 {
+		return nil, errors.New("invalid signature")
+	}
+	return sig, nil
+}
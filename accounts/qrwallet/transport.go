@@ -0,0 +1,58 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qrwallet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// streamTransport is a Transport exchanging the UR parts as lines of text.
+type streamTransport struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// NewStreamTransport creates a transport writing the UR parts of sign requests to
+// out, one per line, and reading the parts scanned from the wallet from in, one
+// per line. It suits external QR code renderers and scanners acting as keyboards.
+func NewStreamTransport(in io.Reader, out io.Writer) Transport {
+	return &streamTransport{in: bufio.NewScanner(in), out: out}
+}
+
+func (t *streamTransport) Display(parts []string) error {
+	for _, part := range parts {
+		if _, err := fmt.Fprintln(t.out, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *streamTransport) Scan() (string, error) {
+	for t.in.Scan() {
+		if line := strings.TrimSpace(t.in.Text()); line != "" {
+			return line, nil
+		}
+	}
+	if err := t.in.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
@@ -0,0 +1,457 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qrwallet
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+const (
+	// urMinFragmentLen is the minimum length of the fragments of multi-part URs.
+	urMinFragmentLen = 10
+
+	// urMaxMessageLen is the maximum length of a multi-part UR message accepted
+	// by the decoder.
+	urMaxMessageLen = 1024 * 1024
+)
+
+var errURIncomplete = errors.New("incomplete UR")
+
+// ur is a Uniform Resource (BCR-2020-005), a CBOR message of a registered type.
+type ur struct {
+	Type string
+	CBOR []byte
+}
+
+// urEncoder splits a UR into parts small enough to fit into a QR code. Messages
+// longer than a single fragment are encoded as an endless sequence of fountain
+// coded parts, which can be shown as an animated QR code: the decoder can pick
+// up any sufficiently large subset of the parts to reassemble the message.
+type urEncoder struct {
+	ur        ur
+	checksum  uint32
+	fragments [][]byte
+	seqNum    uint32
+}
+
+func newUREncoder(u ur, maxFragmentLen int) *urEncoder {
+	fragmentLen := nominalFragmentLen(len(u.CBOR), urMinFragmentLen, maxFragmentLen)
+
+	// Split the message into equally sized, zero padded fragments
+	var fragments [][]byte
+	for i := 0; i < len(u.CBOR); i += fragmentLen {
+		fragment := make([]byte, fragmentLen)
+		copy(fragment, u.CBOR[i:])
+		fragments = append(fragments, fragment)
+	}
+	return &urEncoder{
+		ur:        u,
+		checksum:  crc32.ChecksumIEEE(u.CBOR),
+		fragments: fragments,
+	}
+}
+
+// nominalFragmentLen returns the fragment length which splits the message into
+// the fewest fragments not longer than maxLen.
+func nominalFragmentLen(messageLen, minLen, maxLen int) int {
+	fragmentLen := messageLen
+	for count := 1; count <= messageLen/minLen; count++ {
+		fragmentLen = (messageLen + count - 1) / count
+		if fragmentLen <= maxLen {
+			break
+		}
+	}
+	if fragmentLen == 0 {
+		fragmentLen = 1
+	}
+	return fragmentLen
+}
+
+// singlePart reports whether the UR fits into a single part.
+func (e *urEncoder) singlePart() bool {
+	return len(e.fragments) <= 1
+}
+
+// seqLen returns the number of fragments of the message.
+func (e *urEncoder) seqLen() int {
+	return len(e.fragments)
+}
+
+// nextPart returns the next part of the UR. A single-part UR is returned in
+// full every time.
+func (e *urEncoder) nextPart() string {
+	if e.singlePart() {
+		return "ur:" + e.ur.Type + "/" + encodeBytewords(e.ur.CBOR)
+	}
+	e.seqNum++
+	seqLen := uint32(len(e.fragments))
+
+	data := make([]byte, len(e.fragments[0]))
+	for _, index := range chooseFragments(e.seqNum, seqLen, e.checksum) {
+		xorInto(data, e.fragments[index])
+	}
+	w := new(cborWriter)
+	w.array(5)
+	w.uint(uint64(e.seqNum))
+	w.uint(uint64(seqLen))
+	w.uint(uint64(len(e.ur.CBOR)))
+	w.uint(uint64(e.checksum))
+	w.bytes(data)
+	return fmt.Sprintf("ur:%s/%d-%d/%s", e.ur.Type, e.seqNum, seqLen, encodeBytewords(w.buf))
+}
+
+// urDecoder reassembles a UR from its parts, received in any order.
+type urDecoder struct {
+	result *ur
+
+	// Parameters of the multi-part message, set by the first part
+	typ         string
+	seqLen      int
+	messageLen  int
+	checksum    uint32
+	fragmentLen int
+
+	simple map[int][]byte // Recovered fragments
+	mixed  []*urMixedPart // Received fragment combinations not yet reduced
+}
+
+// urMixedPart is a multi-part UR part combining several fragments.
+type urMixedPart struct {
+	indexes map[int]bool
+	data    []byte
+}
+
+// receive processes a scanned UR part.
+func (d *urDecoder) receive(part string) error {
+	if d.result != nil {
+		return nil
+	}
+	part = strings.ToLower(part)
+	if !strings.HasPrefix(part, "ur:") {
+		return errors.New("invalid UR scheme")
+	}
+	components := strings.Split(part[3:], "/")
+	typ := components[0]
+	if !validURType(typ) {
+		return fmt.Errorf("invalid UR type %q", typ)
+	}
+	switch len(components) {
+	case 2:
+		message, err := decodeBytewords(components[1])
+		if err != nil {
+			return err
+		}
+		d.result = &ur{Type: typ, CBOR: message}
+		return nil
+	case 3:
+		return d.receiveMultiPart(typ, components[1], components[2])
+	default:
+		return errors.New("invalid UR path")
+	}
+}
+
+func (d *urDecoder) receiveMultiPart(typ, seq, body string) error {
+	data, err := decodeBytewords(body)
+	if err != nil {
+		return err
+	}
+	item, err := decodeCBOR(data)
+	if err != nil {
+		return err
+	}
+	fields, ok := item.([]interface{})
+	if !ok || len(fields) != 5 {
+		return errors.New("invalid multi-part UR")
+	}
+	var header [4]uint64
+	for i := range header {
+		if header[i], ok = fields[i].(uint64); !ok {
+			return errors.New("invalid multi-part UR header")
+		}
+	}
+	fragment, ok := fields[4].([]byte)
+	if !ok {
+		return errors.New("invalid multi-part UR fragment")
+	}
+	seqNum, seqLen, messageLen, checksum := header[0], header[1], header[2], header[3]
+	num, total, err := parseURSeq(seq)
+	if err != nil {
+		return err
+	}
+	if num != seqNum || total != seqLen {
+		return fmt.Errorf("multi-part UR sequence mismatch: %s", seq)
+	}
+	if seqNum == 0 || seqNum > math.MaxUint32 || seqLen == 0 || checksum > math.MaxUint32 {
+		return errors.New("invalid multi-part UR header")
+	}
+	if messageLen == 0 || messageLen > urMaxMessageLen || uint64(len(fragment))*seqLen < messageLen {
+		return errors.New("invalid multi-part UR length")
+	}
+	if d.typ == "" {
+		d.typ = typ
+		d.seqLen = int(seqLen)
+		d.messageLen = int(messageLen)
+		d.checksum = uint32(checksum)
+		d.fragmentLen = len(fragment)
+		d.simple = make(map[int][]byte)
+	} else if typ != d.typ || int(seqLen) != d.seqLen || int(messageLen) != d.messageLen || uint32(checksum) != d.checksum || len(fragment) != d.fragmentLen {
+		return errors.New("multi-part UR parts of different messages")
+	}
+	indexes := chooseFragments(uint32(seqNum), uint32(seqLen), uint32(checksum))
+	if len(indexes) == 1 {
+		d.addSimple(indexes[0], fragment)
+	} else {
+		d.addMixed(indexes, fragment)
+	}
+	if len(d.simple) < d.seqLen {
+		return nil
+	}
+	message := make([]byte, 0, d.seqLen*d.fragmentLen)
+	for i := 0; i < d.seqLen; i++ {
+		message = append(message, d.simple[i]...)
+	}
+	message = message[:d.messageLen]
+	if crc32.ChecksumIEEE(message) != d.checksum {
+		return errors.New("multi-part UR checksum mismatch")
+	}
+	d.result = &ur{Type: d.typ, CBOR: message}
+	return nil
+}
+
+// addSimple stores a recovered fragment and reduces the mixed parts containing it.
+func (d *urDecoder) addSimple(index int, data []byte) {
+	queue := []int{index}
+	d.simple[index] = data
+
+	for len(queue) > 0 {
+		index, queue = queue[0], queue[1:]
+
+		mixed := d.mixed[:0]
+		for _, part := range d.mixed {
+			if part.indexes[index] {
+				xorInto(part.data, d.simple[index])
+				delete(part.indexes, index)
+			}
+			if len(part.indexes) == 1 {
+				for i := range part.indexes {
+					if _, ok := d.simple[i]; !ok {
+						d.simple[i] = part.data
+						queue = append(queue, i)
+					}
+				}
+				continue
+			}
+			mixed = append(mixed, part)
+		}
+		d.mixed = mixed
+	}
+}
+
+// addMixed reduces a part by the recovered fragments, storing it if it still
+// combines several unknown fragments.
+func (d *urDecoder) addMixed(indexes []int, data []byte) {
+	part := &urMixedPart{indexes: make(map[int]bool), data: append([]byte{}, data...)}
+	for _, index := range indexes {
+		if fragment, ok := d.simple[index]; ok {
+			xorInto(part.data, fragment)
+		} else {
+			part.indexes[index] = true
+		}
+	}
+	switch len(part.indexes) {
+	case 0:
+		return
+	case 1:
+		for index := range part.indexes {
+			d.addSimple(index, part.data)
+		}
+	default:
+		d.mixed = append(d.mixed, part)
+	}
+}
+
+// validURType reports whether the string is a valid UR type.
+func validURType(typ string) bool {
+	if typ == "" {
+		return false
+	}
+	for _, c := range typ {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func xorInto(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// chooseFragments returns the indexes of the fragments combined in the part with
+// the given sequence number, as defined by the UR fountain code.
+func chooseFragments(seqNum, seqLen, checksum uint32) []int {
+	if seqNum <= seqLen {
+		return []int{int(seqNum - 1)}
+	}
+	var seed [8]byte
+	binary.BigEndian.PutUint32(seed[:4], seqNum)
+	binary.BigEndian.PutUint32(seed[4:], checksum)
+	rng := newXoshiro256(seed[:])
+
+	// Choose the degree with probabilities proportional to 1/degree
+	probs := make([]float64, seqLen)
+	for i := range probs {
+		probs[i] = 1 / float64(i+1)
+	}
+	degree := newRandomSampler(probs).next(rng) + 1
+
+	// Pick that many of the shuffled fragment indexes
+	remaining := make([]int, seqLen)
+	for i := range remaining {
+		remaining[i] = i
+	}
+	shuffled := make([]int, 0, seqLen)
+	for len(remaining) > 0 {
+		i := rng.nextInt(0, uint64(len(remaining)-1))
+		shuffled = append(shuffled, remaining[i])
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	return shuffled[:degree]
+}
+
+// xoshiro256 is the xoshiro256** pseudo random number generator, seeded with the
+// SHA-256 hash of the seed data, as used by the UR fountain code.
+type xoshiro256 [4]uint64
+
+func newXoshiro256(seed []byte) *xoshiro256 {
+	digest := sha256.Sum256(seed)
+
+	x := new(xoshiro256)
+	for i := range x {
+		x[i] = binary.BigEndian.Uint64(digest[8*i:])
+	}
+	return x
+}
+
+func (x *xoshiro256) next() uint64 {
+	result := bits.RotateLeft64(x[1]*5, 7) * 9
+	t := x[1] << 17
+
+	x[2] ^= x[0]
+	x[3] ^= x[1]
+	x[1] ^= x[2]
+	x[0] ^= x[3]
+	x[2] ^= t
+	x[3] = bits.RotateLeft64(x[3], 45)
+
+	return result
+}
+
+func (x *xoshiro256) nextDouble() float64 {
+	return float64(x.next()) / (float64(math.MaxUint64) + 1)
+}
+
+func (x *xoshiro256) nextInt(low, high uint64) int {
+	return int(uint64(x.nextDouble()*float64(high-low+1)) + low)
+}
+
+// randomSampler samples indexes with the given weights using Vose's alias method.
+type randomSampler struct {
+	probs   []float64
+	aliases []int
+}
+
+func newRandomSampler(weights []float64) *randomSampler {
+	var (
+		n   = len(weights)
+		sum float64
+	)
+	for _, w := range weights {
+		sum += w
+	}
+	p := make([]float64, n)
+	for i, w := range weights {
+		p[i] = w * float64(n) / sum
+	}
+	var small, large []int
+	for i := n - 1; i >= 0; i-- {
+		if p[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+	s := &randomSampler{probs: make([]float64, n), aliases: make([]int, n)}
+	for len(small) > 0 && len(large) > 0 {
+		a := small[len(small)-1]
+		small = small[:len(small)-1]
+		g := large[len(large)-1]
+		large = large[:len(large)-1]
+
+		s.probs[a] = p[a]
+		s.aliases[a] = g
+		p[g] += p[a] - 1
+		if p[g] < 1 {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+	for _, i := range large {
+		s.probs[i] = 1
+	}
+	for _, i := range small {
+		s.probs[i] = 1 // Only due to numerical instability
+	}
+	return s
+}
+
+func (s *randomSampler) next(rng *xoshiro256) int {
+	r1, r2 := rng.nextDouble(), rng.nextDouble()
+	i := int(float64(len(s.probs)) * r1)
+	if r2 < s.probs[i] {
+		return i
+	}
+	return s.aliases[i]
+}
+
+// parseURSeq parses the sequence component of a multi-part UR.
+func parseURSeq(seq string) (uint64, uint64, error) {
+	i := strings.IndexByte(seq, '-')
+	if i < 0 {
+		return 0, 0, errors.New("invalid UR sequence")
+	}
+	num, err := strconv.ParseUint(seq[:i], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err := strconv.ParseUint(seq[i+1:], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return num, total, nil
+}
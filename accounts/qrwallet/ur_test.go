@@ -0,0 +1,101 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qrwallet
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// makeMessage creates the pseudo random test message of the UR reference
+// implementation.
+func makeMessage(n int, seed string) []byte {
+	rng := newXoshiro256([]byte(seed))
+	msg := make([]byte, n)
+	for i := range msg {
+		msg[i] = byte(rng.nextInt(0, 255))
+	}
+	return msg
+}
+
+func TestBytewords(t *testing.T) {
+	data := []byte{0, 1, 2, 128, 255}
+	if enc := encodeBytewords(data); enc != "aeadaolazmjendeoti" {
+		t.Fatalf("wrong encoding: have %s, want aeadaolazmjendeoti", enc)
+	}
+	dec, err := decodeBytewords("AEADAOLAZMJENDEOTI")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dec, data) {
+		t.Fatalf("wrong decoding: have %x, want %x", dec, data)
+	}
+	if _, err := decodeBytewords("aeadaolazmjendeota"); err == nil {
+		t.Fatal("invalid checksum accepted")
+	}
+}
+
+func TestXoshiro(t *testing.T) {
+	want := []uint64{42, 81, 85, 8, 82, 84, 76, 73, 70, 88, 2, 74, 40, 48, 77, 54, 88, 7, 5, 88}
+	rng := newXoshiro256([]byte("Wolf"))
+	for i, w := range want {
+		if have := rng.next() % 100; have != w {
+			t.Fatalf("output %d mismatch: have %d, want %d", i, have, w)
+		}
+	}
+}
+
+func TestURMultiPartEncoding(t *testing.T) {
+	w := new(cborWriter)
+	w.bytes(makeMessage(256, "Wolf"))
+	enc := newUREncoder(ur{Type: "bytes", CBOR: w.buf}, 30)
+
+	want := "ur:bytes/1-9/lpadascfadaxcywenbpljkhdcahkadaemejtswhhylkepmykhhtsytsnoyoyaxaedsuttydmmhhpktpmsrjtdkgslpgh"
+	if part := enc.nextPart(); part != want {
+		t.Fatalf("first part mismatch:\nhave %s\nwant %s", part, want)
+	}
+}
+
+func TestURRoundtrip(t *testing.T) {
+	for _, size := range []int{5, 100, 1000, 5000} {
+		for _, skip := range []int{0, 2, 3} {
+			t.Run(fmt.Sprintf("size=%d/skip=%d", size, skip), func(t *testing.T) {
+				message := makeMessage(size, "roundtrip")
+				enc := newUREncoder(ur{Type: "bytes", CBOR: message}, 200)
+
+				var dec urDecoder
+				for i := 0; dec.result == nil; i++ {
+					if i > 10*enc.seqLen()+10 {
+						t.Fatalf("decoding not complete after %d parts", i)
+					}
+					part := enc.nextPart()
+					// Drop some parts to exercise the fountain decoding
+					if skip > 0 && i%skip == 0 && !enc.singlePart() {
+						continue
+					}
+					if err := dec.receive(part); err != nil {
+						t.Fatalf("part %d: %v", i, err)
+					}
+				}
+				if dec.result.Type != "bytes" || !bytes.Equal(dec.result.CBOR, message) {
+					t.Fatal("decoded message mismatch")
+				}
+			})
+		}
+	}
+}
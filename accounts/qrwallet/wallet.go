@@ -0,0 +1,388 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qrwallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxFragmentLen is the maximum number of message bytes carried by a single
+	// QR code shown to the wallet.
+	maxFragmentLen = 200
+
+	// requestOrigin is the origin reported to the wallet in sign requests.
+	requestOrigin = "go-ethereum"
+)
+
+// Transport displays the sign requests to the air-gapped wallet and scans back
+// its responses, e.g. via a screen and a camera.
+type Transport interface {
+	// Display shows a UR to the wallet. If it consists of multiple parts, they
+	// should be cycled through as an animated QR code until the next call.
+	Display(parts []string) error
+
+	// Scan returns the next UR part scanned from the wallet. It blocks until a
+	// QR code is scanned, or returns an error if the user aborts signing.
+	Scan() (string, error)
+}
+
+// Wallet is an air-gapped hardware wallet, signing requests exchanged via QR
+// codes. Its accounts are derived from the extended public key exported by the
+// wallet.
+type Wallet struct {
+	url       accounts.URL
+	key       *hdKey
+	transport Transport
+
+	accounts []accounts.Account                         // List of derived accounts pinned on the wallet
+	paths    map[common.Address]accounts.DerivationPath // Known derivation paths for signing operations
+	lock     sync.RWMutex                               // Protects the account fields
+
+	signLock sync.Mutex // Serializes the sign requests shown to the user
+}
+
+// newWallet creates a wallet for the given exported key, with the first account
+// derived below it pinned.
+func newWallet(key *hdKey, transport Transport) (*Wallet, error) {
+	id := crypto.Keccak256(crypto.CompressPubkey(key.key))
+	w := &Wallet{
+		url:       accounts.URL{Scheme: Scheme, Path: fmt.Sprintf("%x", id[:8])},
+		key:       key,
+		transport: transport,
+		paths:     make(map[common.Address]accounts.DerivationPath),
+	}
+	first := append(append(accounts.DerivationPath{}, key.origin.components...), 0, 0)
+	if _, err := w.Derive(first, true); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// URL implements accounts.Wallet, returning the URL of the wallet, derived from
+// its exported key.
+func (w *Wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet. Air-gapped wallets have no connection
+// state to report.
+func (w *Wallet) Status() (string, error) {
+	if w.key.name != "" {
+		return fmt.Sprintf("Air-gapped (%s)", w.key.name), nil
+	}
+	return "Air-gapped", nil
+}
+
+// Open implements accounts.Wallet. Air-gapped wallets need no opening, and are
+// unlocked on the device itself.
+func (w *Wallet) Open(passphrase string) error {
+	return nil
+}
+
+// Close implements accounts.Wallet.
+func (w *Wallet) Close() error {
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the list of accounts pinned to
+// the wallet.
+func (w *Wallet) Accounts() []accounts.Account {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not pinned into this wallet instance.
+func (w *Wallet) Contains(account accounts.Account) bool {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	_, exists := w.paths[account.Address]
+	return exists
+}
+
+// Derive implements accounts.Wallet, deriving a new account at the specific
+// derivation path. The path must lie below the path of the key exported by the
+// wallet, with only non-hardened components following it.
+func (w *Wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	origin := w.key.origin.components
+	if len(path) < len(origin) {
+		return accounts.Account{}, fmt.Errorf("path %v not below exported key %v", path, origin)
+	}
+	for i, component := range origin {
+		if path[i] != component {
+			return accounts.Account{}, fmt.Errorf("path %v not below exported key %v", path, origin)
+		}
+	}
+	address, err := w.key.derive(path[len(origin):])
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	account := accounts.Account{
+		Address: address,
+		URL:     accounts.URL{Scheme: w.url.Scheme, Path: fmt.Sprintf("%s/%s", w.url.Path, path)},
+	}
+	if !pin {
+		return account, nil
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if _, ok := w.paths[address]; !ok {
+		w.accounts = append(w.accounts, account)
+		w.paths[address] = append(accounts.DerivationPath{}, path...)
+	}
+	return account, nil
+}
+
+// SelfDerive implements accounts.Wallet. Automatic account discovery is not
+// supported by air-gapped wallets, accounts need to be derived explicitly.
+func (w *Wallet) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	log.Error("Operation SelfDerive not supported on air-gapped wallets")
+}
+
+// SignData implements accounts.Wallet. Only plain text data is supported, which
+// is signed as a personal message.
+func (w *Wallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	if mimeType != accounts.MimetypeTextPlain {
+		return nil, accounts.ErrNotSupported
+	}
+	return w.SignText(account, data)
+}
+
+// SignDataWithPassphrase implements accounts.Wallet, attempting to sign the given
+// data with the given account using passphrase as extra authentication. Since
+// the passphrase is entered on the device, it is ignored.
+func (w *Wallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return w.SignData(account, mimeType, data)
+}
+
+// SignText implements accounts.Wallet, requesting the wallet to sign the text
+// with the EIP-191 personal message prefix.
+func (w *Wallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	return w.signMessage(account, dataTypePersonalMessage, text, accounts.TextHash(text))
+}
+
+// SignTypedData implements accounts.Wallet, sending the EIP-712 typed data to
+// the wallet, which displays its structured content for confirmation.
+func (w *Wallet) SignTypedData(account accounts.Account, typedData apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(typedData)
+	if err != nil {
+		return nil, err
+	}
+	return w.signMessage(account, dataTypeTypedData, data, hash)
+}
+
+// signMessage requests the wallet to sign a message, verifying the returned
+// signature of the given message hash.
+func (w *Wallet) signMessage(account accounts.Account, dataType uint64, data []byte, hash []byte) ([]byte, error) {
+	sig, err := w.sign(account, dataType, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	v, err := recoveryID(sig[64:], nil)
+	if err != nil {
+		return nil, err
+	}
+	signature := append(append([]byte{}, sig[:64]...), v)
+
+	// Verify the signer, the wallet might have used a different key
+	pubkey, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return nil, err
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != account.Address {
+		return nil, fmt.Errorf("signer mismatch: expected %s, got %s", account.Address.Hex(), signer.Hex())
+	}
+	return signature, nil
+}
+
+// SignTextWithPassphrase implements accounts.Wallet, ignoring the passphrase as
+// it is entered on the device.
+func (w *Wallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return w.SignText(account, text)
+}
+
+// SignTx implements accounts.Wallet, requesting the wallet to sign the given
+// transaction. Legacy transactions are signed with EIP-155 replay protection
+// only if a chain ID is given.
+func (w *Wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	var (
+		signer   types.Signer
+		dataType uint64
+		payload  []byte
+		err      error
+	)
+	switch {
+	case tx.Type() == types.LegacyTxType && chainID == nil:
+		signer, dataType = new(types.HomesteadSigner), dataTypeTransaction
+		payload, err = rlp.EncodeToBytes([]interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()})
+
+	case tx.Type() == types.LegacyTxType:
+		signer, dataType = types.NewEIP155Signer(chainID), dataTypeTransaction
+		payload, err = rlp.EncodeToBytes([]interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), chainID, uint(0), uint(0)})
+
+	case chainID == nil:
+		return nil, errors.New("typed transactions require a chain ID")
+
+	case tx.Type() == types.AccessListTxType:
+		signer, dataType = types.NewEIP2930Signer(chainID), dataTypeTypedTransaction
+		payload, err = rlp.EncodeToBytes([]interface{}{chainID, tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()})
+
+	case tx.Type() == types.DynamicFeeTxType:
+		signer, dataType = types.NewLondonSigner(chainID), dataTypeTypedTransaction
+		payload, err = rlp.EncodeToBytes([]interface{}{chainID, tx.Nonce(), tx.GasTipCap(), tx.GasFeeCap(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), tx.AccessList()})
+
+	default:
+		return nil, fmt.Errorf("unsupported transaction type %d", tx.Type())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dataType == dataTypeTypedTransaction {
+		payload = append([]byte{tx.Type()}, payload...)
+	}
+	sig, err := w.sign(account, dataType, payload, chainID)
+	if err != nil {
+		return nil, err
+	}
+	var eip155ChainID *big.Int
+	if tx.Type() == types.LegacyTxType {
+		eip155ChainID = chainID
+	}
+	v, err := recoveryID(sig[64:], eip155ChainID)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := tx.WithSignature(signer, append(append([]byte{}, sig[:64]...), v))
+	if err != nil {
+		return nil, err
+	}
+	// Verify the sender to avoid surprises with the wallet using another key
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, err
+	}
+	if sender != account.Address {
+		return nil, fmt.Errorf("signer mismatch: expected %s, got %s", account.Address.Hex(), sender.Hex())
+	}
+	return signed, nil
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, ignoring the passphrase as it
+// is entered on the device.
+func (w *Wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
+
+// sign shows a sign request to the wallet and waits for its signature to be
+// scanned back.
+func (w *Wallet) sign(account accounts.Account, dataType uint64, data []byte, chainID *big.Int) ([]byte, error) {
+	w.lock.RLock()
+	path, ok := w.paths[account.Address]
+	w.lock.RUnlock()
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	if chainID != nil && !chainID.IsUint64() {
+		return nil, fmt.Errorf("unsupported chain ID %v", chainID)
+	}
+	req := &signRequest{
+		id:       uuid.New(),
+		data:     data,
+		dataType: dataType,
+		chainID:  chainID,
+		path:     keypath{components: path, fingerprint: w.key.origin.fingerprint},
+		address:  account.Address,
+		origin:   requestOrigin,
+	}
+	// Show the fragments, followed by as many fountain coded parts for the
+	// wallet to recover from missed frames
+	enc := newUREncoder(req.encode(), maxFragmentLen)
+	parts := []string{enc.nextPart()}
+	if !enc.singlePart() {
+		for i := 1; i < 2*enc.seqLen(); i++ {
+			parts = append(parts, enc.nextPart())
+		}
+	}
+	w.signLock.Lock()
+	defer w.signLock.Unlock()
+
+	if err := w.transport.Display(parts); err != nil {
+		return nil, err
+	}
+	var dec urDecoder
+	for dec.result == nil {
+		part, err := w.transport.Scan()
+		if err != nil {
+			return nil, err
+		}
+		if err := dec.receive(part); err != nil {
+			return nil, err
+		}
+	}
+	if dec.result.Type != urTypeSignature {
+		return nil, fmt.Errorf("unexpected UR type %q, want %s", dec.result.Type, urTypeSignature)
+	}
+	sig, err := decodeSignature(dec.result.CBOR)
+	if err != nil {
+		return nil, err
+	}
+	if sig.id != req.id {
+		return nil, errors.New("signature for a different request")
+	}
+	return sig.signature, nil
+}
+
+// recoveryID converts the V value of a signature to the 0/1 recovery id. It can
+// be EIP-155 encoded if the chain ID is given, or be in the 27/28 form.
+func recoveryID(v []byte, chainID *big.Int) (byte, error) {
+	id := new(big.Int).SetBytes(v)
+	switch {
+	case chainID != nil && id.Cmp(big.NewInt(35)) >= 0:
+		id.Sub(id, new(big.Int).Add(new(big.Int).Lsh(chainID, 1), big.NewInt(35)))
+	case id.Cmp(big.NewInt(27)) >= 0:
+		id.Sub(id, big.NewInt(27))
+	}
+	if !id.IsUint64() || id.Uint64() > 1 {
+		return 0, fmt.Errorf("invalid signature V value %x", v)
+	}
+	return byte(id.Uint64()), nil
+}
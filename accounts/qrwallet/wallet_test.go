@@ -0,0 +1,263 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package qrwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testDevice simulates an air-gapped wallet, answering the displayed sign
+// requests with keys derived from a BIP-32 seed.
+type testDevice struct {
+	t      *testing.T
+	seed   []byte
+	eip155 bool     // Whether to return EIP-155 encoded V values for legacy transactions
+	parts  []string // Response parts waiting to be scanned
+}
+
+// derive computes the private key and chain code at the given path.
+func (d *testDevice) derive(path []uint32) (*ecdsa.PrivateKey, []byte) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(d.seed)
+	sum := mac.Sum(nil)
+
+	n := crypto.S256().Params().N
+	priv, chain := new(big.Int).SetBytes(sum[:32]), sum[32:]
+	for _, index := range path {
+		var data []byte
+		if index >= 0x80000000 {
+			data = append([]byte{0}, math.PaddedBigBytes(priv, 32)...)
+		} else {
+			key, _ := crypto.ToECDSA(math.PaddedBigBytes(priv, 32))
+			data = crypto.CompressPubkey(&key.PublicKey)
+		}
+		data = append(data, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+		mac := hmac.New(sha512.New, chain)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		priv = new(big.Int).Add(new(big.Int).SetBytes(sum[:32]), priv)
+		priv.Mod(priv, n)
+		chain = sum[32:]
+	}
+	key, err := crypto.ToECDSA(math.PaddedBigBytes(priv, 32))
+	if err != nil {
+		d.t.Fatal(err)
+	}
+	return key, chain
+}
+
+// export returns the crypto-hdkey UR of the key at the given path.
+func (d *testDevice) export(path accounts.DerivationPath) string {
+	key, chain := d.derive(path)
+
+	w := new(cborWriter)
+	w.mapHeader(4)
+	w.uint(3)
+	w.bytes(crypto.CompressPubkey(&key.PublicKey))
+	w.uint(4)
+	w.bytes(chain)
+	w.uint(6)
+	keypath{components: path, fingerprint: 0x12345678}.encode(w)
+	w.uint(9)
+	w.text("Test")
+	return newUREncoder(ur{Type: urTypeHDKey, CBOR: w.buf}, maxFragmentLen).nextPart()
+}
+
+func (d *testDevice) Display(parts []string) error {
+	var dec urDecoder
+	for _, part := range parts {
+		if err := dec.receive(part); err != nil {
+			return err
+		}
+	}
+	if dec.result == nil || dec.result.Type != urTypeSignRequest {
+		return errors.New("invalid sign request")
+	}
+	item, err := decodeCBOR(dec.result.CBOR)
+	if err != nil {
+		return err
+	}
+	fields := item.(map[uint64]interface{})
+	path, err := decodeKeypath(fields[5])
+	if err != nil {
+		return err
+	}
+	if path.fingerprint != 0x12345678 {
+		d.t.Errorf("source fingerprint mismatch: have %x", path.fingerprint)
+	}
+	key, _ := d.derive(path.components)
+	if addr := crypto.PubkeyToAddress(key.PublicKey); !bytes.Equal(fields[6].([]byte), addr[:]) {
+		d.t.Errorf("request address mismatch: have %x, want %x", fields[6], addr)
+	}
+	data := fields[2].([]byte)
+
+	var hash []byte
+	switch fields[3].(uint64) {
+	case dataTypePersonalMessage:
+		hash = accounts.TextHash(data)
+	case dataTypeTransaction, dataTypeTypedTransaction:
+		hash = crypto.Keccak256(data)
+	default:
+		return errors.New("unsupported data type")
+	}
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		return err
+	}
+	v := new(big.Int).SetUint64(uint64(sig[64]) + 27)
+	if chainID, ok := fields[4].(uint64); ok && fields[3].(uint64) == dataTypeTransaction && d.eip155 {
+		v.SetUint64(chainID*2 + 35 + uint64(sig[64]))
+	}
+	w := new(cborWriter)
+	w.mapHeader(2)
+	w.uint(1)
+	w.tag(tagUUID)
+	w.bytes(fields[1].(cborTag).Content.([]byte))
+	w.uint(2)
+	w.bytes(append(sig[:64], v.Bytes()...))
+
+	// Return the signature as a multi-part UR, skipping some parts
+	enc := newUREncoder(ur{Type: urTypeSignature, CBOR: w.buf}, 20)
+	d.parts = d.parts[:0]
+	for i := 0; i < 6*enc.seqLen(); i++ {
+		if part := enc.nextPart(); i%3 != 1 {
+			d.parts = append(d.parts, part)
+		}
+	}
+	return nil
+}
+
+func (d *testDevice) Scan() (string, error) {
+	if len(d.parts) == 0 {
+		return "", errors.New("nothing to scan")
+	}
+	part := d.parts[0]
+	d.parts = d.parts[1:]
+	return part, nil
+}
+
+func TestWalletSigning(t *testing.T) {
+	device := &testDevice{t: t, seed: []byte("air-gapped test wallet seed")}
+
+	hub := NewHub()
+	defer hub.Close()
+
+	origin := accounts.DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000}
+	wallet, err := hub.Import([]string{device.export(origin)}, device)
+	if err != nil {
+		t.Fatalf("failed to import wallet: %v", err)
+	}
+	if _, err := hub.Import([]string{device.export(origin)}, device); err == nil {
+		t.Fatal("duplicate wallet imported")
+	}
+	if wallets := hub.Wallets(); len(wallets) != 1 || wallets[0] != wallet {
+		t.Fatalf("hub wallets mismatch: %v", wallets)
+	}
+	// The first account should be derived and pinned on import
+	key, _ := device.derive(accounts.DefaultBaseDerivationPath)
+	accs := wallet.Accounts()
+	if len(accs) != 1 || accs[0].Address != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("account mismatch: have %v, want %x", accs, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	path := accounts.DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000, 0, 1}
+	second, err := wallet.Derive(path, true)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	if key, _ := device.derive(path); second.Address != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("derived account mismatch: have %x, want %x", second.Address, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	if _, err := wallet.Derive(accounts.DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000001, 0, 0}, false); err == nil {
+		t.Fatal("derived account outside of exported key")
+	}
+	chainID := big.NewInt(1337)
+	to := common.Address{0xaa}
+	txs := []*types.Transaction{
+		types.NewTransaction(1, to, big.NewInt(1), 21000, big.NewInt(1), nil),
+		types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Nonce: 2, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 50000, To: &to, Data: make([]byte, 1000)}),
+		types.NewTx(&types.AccessListTx{ChainID: chainID, Nonce: 3, GasPrice: big.NewInt(1), Gas: 50000, To: &to, AccessList: types.AccessList{{Address: to}}}),
+	}
+	for _, account := range []accounts.Account{accs[0], second} {
+		for _, eip155 := range []bool{false, true} {
+			device.eip155 = eip155
+			for i, tx := range txs {
+				signed, err := wallet.SignTx(account, tx, chainID)
+				if err != nil {
+					t.Fatalf("tx %d: failed to sign: %v", i, err)
+				}
+				if signed.Hash() == tx.Hash() {
+					t.Fatalf("tx %d: transaction not signed", i)
+				}
+			}
+		}
+		sig, err := wallet.SignText(account, []byte("hello"))
+		if err != nil {
+			t.Fatalf("failed to sign text: %v", err)
+		}
+		if sig[64] > 1 {
+			t.Fatalf("signature V not normalized: %d", sig[64])
+		}
+	}
+	if _, err := wallet.SignText(accounts.Account{Address: common.Address{1}}, []byte("hello")); err != accounts.ErrUnknownAccount {
+		t.Fatalf("wrong error for unknown account: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+}
+
+func TestImportKeys(t *testing.T) {
+	var (
+		first  = &testDevice{t: t, seed: []byte("first air-gapped test wallet")}
+		second = &testDevice{t: t, seed: []byte("second air-gapped test wallet")}
+		origin = accounts.DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000}
+	)
+	keys := strings.Join([]string{
+		"# Keys exported by the air-gapped wallets",
+		first.export(origin),
+		"",
+		second.export(origin),
+		first.export(origin),
+	}, "\n")
+
+	hub := NewHub()
+	defer hub.Close()
+
+	if err := hub.ImportKeys(strings.NewReader(keys), first); err != nil {
+		t.Fatalf("failed to import keys: %v", err)
+	}
+	if wallets := hub.Wallets(); len(wallets) != 2 {
+		t.Fatalf("imported wallet count mismatch: have %d, want 2", len(wallets))
+	}
+	if err := hub.ImportKeys(strings.NewReader("ur:crypto-hdkey/invalid"), first); err == nil {
+		t.Fatal("invalid key imported")
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	pcsc "github.com/gballet/go-libpcsclite"
 	"github.com/status-im/keycard-go/derivationpath"
 )
@@ -742,6 +743,16 @@ func (w *Wallet) SignText(account accounts.Account, text []byte) ([]byte, error)
 	return w.signHash(account, accounts.TextHash(text))
 }
 
+// SignTypedData implements accounts.Wallet, attempting to sign the EIP-712 hash
+// of the given typed data with the given account.
+func (w *Wallet) SignTypedData(account accounts.Account, typedData apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	return w.signHash(account, hash)
+}
+
 // SignTextWithPassphrase implements accounts.Wallet, attempting to sign the
 // given hash with the given account using passphrase as extra authentication
 func (w *Wallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
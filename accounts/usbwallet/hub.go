@@ -52,6 +52,9 @@ type Hub struct {
 	endpointID int                     // USB endpoint identifier used for non-macOS device discovery
 	makeDriver func(log.Logger) driver // Factory method to construct a vendor specific driver
 
+	enumerate func(vendorID uint16) ([]usb.DeviceInfo, error) // Device enumerator (overridden by simulated hubs)
+	open      func(info usb.DeviceInfo) (usb.Device, error)   // Device opener (overridden by simulated hubs)
+
 	refreshed   time.Time               // Time instance when the list of wallets was last refreshed
 	wallets     []accounts.Wallet       // List of USB wallet devices currently tracking
 	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
@@ -109,7 +112,13 @@ func newHub(scheme string, vendorID uint16, productIDs []uint16, usageID uint16,
 		usageID:    usageID,
 		endpointID: endpointID,
 		makeDriver: makeDriver,
-		quit:       make(chan chan error),
+		enumerate: func(vendorID uint16) ([]usb.DeviceInfo, error) {
+			return usb.Enumerate(vendorID, 0)
+		},
+		open: func(info usb.DeviceInfo) (usb.Device, error) {
+			return info.Open()
+		},
+		quit: make(chan chan error),
 	}
 	hub.refreshWallets()
 	return hub, nil
@@ -160,7 +169,7 @@ func (hub *Hub) refreshWallets() {
 			return
 		}
 	}
-	infos, err := usb.Enumerate(hub.vendorID, 0)
+	infos, err := hub.enumerate(hub.vendorID)
 	if err != nil {
 		failcount := atomic.AddUint32(&hub.enumFails, 1)
 		if runtime.GOOS == "linux" {
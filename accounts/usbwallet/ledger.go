@@ -21,12 +21,16 @@
 package usbwallet
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -35,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
 // ledgerOpcode is an enumeration encoding the supported Ledger opcodes.
@@ -53,12 +58,44 @@ const (
 	ledgerOpSignTransaction  ledgerOpcode = 0x04 // Signs an Ethereum transaction after having the user validate the parameters
 	ledgerOpGetConfiguration ledgerOpcode = 0x06 // Returns specific wallet application configuration
 	ledgerOpSignTypedMessage ledgerOpcode = 0x0c // Signs an Ethereum message following the EIP 712 specification
+	ledgerOpEIP712StructDef  ledgerOpcode = 0x1a // Sends the definition of an EIP 712 struct type
+	ledgerOpEIP712StructImpl ledgerOpcode = 0x1c // Sends the values of an EIP 712 struct
 
 	ledgerP1DirectlyFetchAddress    ledgerParam1 = 0x00 // Return address directly from the wallet
 	ledgerP1InitTypedMessageData    ledgerParam1 = 0x00 // First chunk of Typed Message data
 	ledgerP1InitTransactionData     ledgerParam1 = 0x00 // First transaction data block for signing
 	ledgerP1ContTransactionData     ledgerParam1 = 0x80 // Subsequent transaction data block for signing
+	ledgerP1CompleteSend            ledgerParam1 = 0x00 // Last (or only) chunk of an EIP 712 struct value
+	ledgerP1PartialSend             ledgerParam1 = 0x01 // Non-final chunk of an EIP 712 struct value
 	ledgerP2DiscardAddressChainCode ledgerParam2 = 0x00 // Do not return the chain code along with the address
+	ledgerP2TypedMessageHashes      ledgerParam2 = 0x00 // Sign the EIP 712 domain and message hashes
+	ledgerP2TypedMessageFull        ledgerParam2 = 0x01 // Sign the EIP 712 message sent field by field
+	ledgerP2StructName              ledgerParam2 = 0x00 // Name of an EIP 712 struct type or root struct
+	ledgerP2StructArray             ledgerParam2 = 0x0f // Size of an EIP 712 array value
+	ledgerP2StructField             ledgerParam2 = 0xff // Definition or value of an EIP 712 struct field
+)
+
+// Status words of the Ledger replies.
+const (
+	ledgerStatusOK          = 0x9000 // Command executed successfully
+	ledgerStatusDenied      = 0x6985 // Request denied by the user
+	ledgerStatusInvalidData = 0x6a80 // Malformed command payload
+	ledgerStatusUnknownIns  = 0x6d00 // Instruction not supported
+)
+
+// EIP 712 field types, as encoded in the struct definitions sent to the Ledger.
+const (
+	ledgerEIP712Custom     = 0 // Struct type defined by the message
+	ledgerEIP712Int        = 1
+	ledgerEIP712Uint       = 2
+	ledgerEIP712Address    = 3
+	ledgerEIP712Bool       = 4
+	ledgerEIP712String     = 5
+	ledgerEIP712FixedBytes = 6
+	ledgerEIP712Bytes      = 7
+
+	ledgerEIP712TypeArray = 0x80 // Flag set on array field types
+	ledgerEIP712TypeSize  = 0x40 // Flag set on field types followed by their size
 )
 
 // errLedgerReplyInvalidHeader is the error message returned by a Ledger data exchange
@@ -190,6 +227,25 @@ func (w *ledgerDriver) SignTypedMessage(path accounts.DerivationPath, domainHash
 	return w.ledgerSignTypedMessage(path, domainHash, messageHash)
 }
 
+// SignTypedData implements usbwallet.driver, sending the types and values of the
+// typed data to the Ledger for the user to review the message field by field, and
+// waiting for the user to sign or deny it.
+//
+// Note: this was introduced in the 1.9.19 version of the Ethereum app, which isn't
+// available on the Nano S. The message filters of the app aren't used, as they
+// need to be signed by Ledger, hence the device displays every field.
+func (w *ledgerDriver) SignTypedData(path accounts.DerivationPath, typedData apitypes.TypedData) ([]byte, error) {
+	// If the Ethereum app doesn't run, abort
+	if w.offline() {
+		return nil, accounts.ErrWalletClosed
+	}
+	// Ensure the wallet is capable of displaying the typed data
+	if v := w.version; v[0] < 1 || (v[0] == 1 && (v[1] < 9 || (v[1] == 9 && v[2] < 19))) {
+		return nil, accounts.ErrNotSupported
+	}
+	return w.ledgerSignTypedData(path, &typedData)
+}
+
 // ledgerVersion retrieves the current version of the Ethereum wallet app running
 // on the Ledger wallet.
 //
@@ -435,7 +491,7 @@ func (w *ledgerDriver) ledgerSignTypedMessage(derivationPath []uint32, domainHas
 	)
 
 	// Send the message over, ensuring it's processed correctly
-	reply, err = w.ledgerExchange(ledgerOpSignTypedMessage, op, 0, payload)
+	reply, err = w.ledgerExchange(ledgerOpSignTypedMessage, op, ledgerP2TypedMessageHashes, payload)
 
 	if err != nil {
 		return nil, err
@@ -449,6 +505,345 @@ func (w *ledgerDriver) ledgerSignTypedMessage(derivationPath []uint32, domainHas
 	return signature, nil
 }
 
+// ledgerSignTypedData sends the typed data to the Ledger wallet field by field,
+// and waits for the user to confirm or deny the message.
+//
+// The definitions of all struct types are sent first, each as its name followed
+// by its fields:
+//
+//   CLA | INS | P1 | P2                          | Lc       | Le
+//   ----+-----+----+-----------------------------+----------+---
+//    E0 | 1A  | 00 | struct name : 00            | variable | 00
+//       |     |    | struct field : FF           |          |
+//
+// Where a field definition is:
+//
+//   Description                                             | Length
+//   --------------------------------------------------------+----------
+//   Type (array : 80 | sized : 40 | type id)                | 1 byte
+//   Custom type name length and name (if custom)            | variable
+//   Type size (if sized, e.g. in bytes for integers)        | 1 byte
+//   Array level count, and per level 00 or 01 and size      | variable
+//   Field name length and name                              | variable
+//
+// Then the values of the domain and of the message, each as the name of its root
+// struct followed by its field values in depth first order, arrays preceded by
+// their size:
+//
+//   CLA | INS | P1                | P2                 | Lc       | Le
+//   ----+-----+-------------------+--------------------+----------+---
+//    E0 | 1C  | complete : 00     | root struct : 00   | variable | 00
+//       |     | partial : 01      | array size : 0F    |          |
+//       |     |                   | field value : FF   |          |
+//
+// Field values are prefixed with their 2 byte length and sent in chunks of 255
+// bytes, all but the last marked as partial. Finally the signing is requested:
+//
+//   CLA | INS | P1 | P2 | Lc       | Le
+//   ----+-----+----+----+----------+---
+//    E0 | 0C  | 00 | 01 | variable | 41
+//
+// With the BIP 32 derivation path as input, and the V, R, S signature as output.
+func (w *ledgerDriver) ledgerSignTypedData(derivationPath []uint32, typedData *apitypes.TypedData) ([]byte, error) {
+	// Send the definitions of all the struct types, in a deterministic order
+	names := make([]string, 0, len(typedData.Types))
+	for name := range typedData.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := w.ledgerEIP712Send(ledgerOpEIP712StructDef, ledgerP1CompleteSend, ledgerP2StructName, []byte(name)); err != nil {
+			return nil, err
+		}
+		for _, field := range typedData.Types[name] {
+			def, err := ledgerEIP712FieldDef(typedData.Types, field)
+			if err != nil {
+				return nil, err
+			}
+			if err := w.ledgerEIP712Send(ledgerOpEIP712StructDef, ledgerP1CompleteSend, ledgerP2StructField, def); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Send the values of the domain and of the message
+	if err := w.ledgerEIP712SendStruct(typedData, "EIP712Domain", typedData.Domain.Map()); err != nil {
+		return nil, err
+	}
+	if err := w.ledgerEIP712SendStruct(typedData, typedData.PrimaryType, typedData.Message); err != nil {
+		return nil, err
+	}
+	// Flatten the derivation path into the Ledger request and request signing
+	path := make([]byte, 1+4*len(derivationPath))
+	path[0] = byte(len(derivationPath))
+	for i, component := range derivationPath {
+		binary.BigEndian.PutUint32(path[1+4*i:], component)
+	}
+	reply, err := w.ledgerEIP712Exchange(ledgerOpSignTypedMessage, ledgerP1InitTypedMessageData, ledgerP2TypedMessageFull, path)
+	if err != nil {
+		return nil, err
+	}
+	// Extract the Ethereum signature and do a sanity validation
+	if len(reply) != crypto.SignatureLength {
+		return nil, errors.New("reply lacks signature")
+	}
+	return append(reply[1:], reply[0]), nil
+}
+
+// ledgerEIP712SendStruct sends the name of a root struct and its field values.
+func (w *ledgerDriver) ledgerEIP712SendStruct(typedData *apitypes.TypedData, name string, data map[string]interface{}) error {
+	if err := w.ledgerEIP712Send(ledgerOpEIP712StructImpl, ledgerP1CompleteSend, ledgerP2StructName, []byte(name)); err != nil {
+		return err
+	}
+	return w.ledgerEIP712SendFields(typedData, name, data)
+}
+
+// ledgerEIP712SendFields sends the field values of a struct.
+func (w *ledgerDriver) ledgerEIP712SendFields(typedData *apitypes.TypedData, name string, data map[string]interface{}) error {
+	for _, field := range typedData.Types[name] {
+		base, levels, err := ledgerEIP712ParseType(field.Type)
+		if err != nil {
+			return err
+		}
+		if err := w.ledgerEIP712SendValue(typedData, base, levels, data[field.Name]); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// ledgerEIP712SendValue sends a field value of the given base type, nested in
+// arrays of the given levels.
+func (w *ledgerDriver) ledgerEIP712SendValue(typedData *apitypes.TypedData, base string, levels []int, value interface{}) error {
+	if len(levels) > 0 {
+		items, ok := value.([]interface{})
+		if !ok || len(items) > 255 || (levels[0] > 0 && len(items) != levels[0]) {
+			return fmt.Errorf("invalid array value %v", value)
+		}
+		if err := w.ledgerEIP712Send(ledgerOpEIP712StructImpl, ledgerP1CompleteSend, ledgerP2StructArray, []byte{byte(len(items))}); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := w.ledgerEIP712SendValue(typedData, base, levels[1:], item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, ok := typedData.Types[base]; ok {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid struct value %v", value)
+		}
+		return w.ledgerEIP712SendFields(typedData, base, fields)
+	}
+	enc, err := ledgerEIP712EncodeValue(typedData, base, value)
+	if err != nil {
+		return err
+	}
+	// Prefix the value with its length and stream it in chunks
+	payload := make([]byte, 2, 2+len(enc))
+	binary.BigEndian.PutUint16(payload, uint16(len(enc)))
+	payload = append(payload, enc...)
+
+	for len(payload) > 0 {
+		chunk, op := payload, ledgerP1CompleteSend
+		if len(chunk) > 255 {
+			chunk, op = chunk[:255], ledgerP1PartialSend
+		}
+		payload = payload[len(chunk):]
+
+		if err := w.ledgerEIP712Send(ledgerOpEIP712StructImpl, op, ledgerP2StructField, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ledgerEIP712Send sends a part of the typed data to the Ledger.
+func (w *ledgerDriver) ledgerEIP712Send(opcode ledgerOpcode, p1 ledgerParam1, p2 ledgerParam2, data []byte) error {
+	_, err := w.ledgerEIP712Exchange(opcode, p1, p2, data)
+	return err
+}
+
+// ledgerEIP712Exchange performs a data exchange with the Ledger wallet, failing
+// unless the command succeeds. Apps unable to handle typed data reject the first
+// command as unknown, which is reported as accounts.ErrNotSupported.
+func (w *ledgerDriver) ledgerEIP712Exchange(opcode ledgerOpcode, p1 ledgerParam1, p2 ledgerParam2, data []byte) ([]byte, error) {
+	reply, status, err := w.ledgerExchangeStatus(opcode, p1, p2, data)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case ledgerStatusOK:
+		return reply, nil
+	case ledgerStatusUnknownIns:
+		return nil, accounts.ErrNotSupported
+	case ledgerStatusDenied:
+		return nil, errors.New("ledger: request denied by the user")
+	default:
+		return nil, fmt.Errorf("ledger: request failed with status %#04x", status)
+	}
+}
+
+// ledgerEIP712ParseType splits an EIP 712 field type into its base type and the
+// sizes of its array levels from left to right, zero for dynamic arrays.
+func ledgerEIP712ParseType(typ string) (string, []int, error) {
+	start := strings.IndexByte(typ, '[')
+	if start < 0 {
+		return typ, nil, nil
+	}
+	var (
+		base   = typ[:start]
+		rest   = typ[start:]
+		levels []int
+	)
+	for rest != "" {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return "", nil, fmt.Errorf("invalid type %q", typ)
+		}
+		size := 0
+		if end > 1 {
+			n, err := strconv.Atoi(rest[1:end])
+			if err != nil || n <= 0 || n > 255 {
+				return "", nil, fmt.Errorf("invalid array size in type %q", typ)
+			}
+			size = n
+		}
+		levels = append(levels, size)
+		rest = rest[end+1:]
+	}
+	return base, levels, nil
+}
+
+// ledgerEIP712Primitive returns the id of a primitive type and its size: the width
+// in bytes of integers and the length of fixed bytes, or zero if not sized.
+func ledgerEIP712Primitive(typ string) (byte, int, error) {
+	switch {
+	case typ == "address":
+		return ledgerEIP712Address, 0, nil
+	case typ == "bool":
+		return ledgerEIP712Bool, 0, nil
+	case typ == "string":
+		return ledgerEIP712String, 0, nil
+	case typ == "bytes":
+		return ledgerEIP712Bytes, 0, nil
+	case strings.HasPrefix(typ, "bytes"):
+		n, err := strconv.Atoi(typ[len("bytes"):])
+		if err != nil || n < 1 || n > 32 {
+			return 0, 0, fmt.Errorf("invalid type %q", typ)
+		}
+		return ledgerEIP712FixedBytes, n, nil
+	case strings.HasPrefix(typ, "int"), strings.HasPrefix(typ, "uint"):
+		id, bits := byte(ledgerEIP712Int), strings.TrimPrefix(typ, "int")
+		if strings.HasPrefix(typ, "uint") {
+			id, bits = ledgerEIP712Uint, strings.TrimPrefix(typ, "uint")
+		}
+		if bits == "" {
+			return id, 32, nil
+		}
+		n, err := strconv.Atoi(bits)
+		if err != nil || n < 8 || n > 256 || n%8 != 0 {
+			return 0, 0, fmt.Errorf("invalid type %q", typ)
+		}
+		return id, n / 8, nil
+	default:
+		return 0, 0, fmt.Errorf("unknown type %q", typ)
+	}
+}
+
+// ledgerEIP712FieldDef encodes the definition of a struct field.
+func ledgerEIP712FieldDef(types apitypes.Types, field apitypes.Type) ([]byte, error) {
+	base, levels, err := ledgerEIP712ParseType(field.Type)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		id   byte = ledgerEIP712Custom
+		size int
+	)
+	if _, ok := types[base]; !ok {
+		if id, size, err = ledgerEIP712Primitive(base); err != nil {
+			return nil, err
+		}
+	}
+	if len(base) > 255 || len(field.Name) > 255 || len(levels) > 255 {
+		return nil, fmt.Errorf("field %s too long", field.Name)
+	}
+	def := []byte{id}
+	if id == ledgerEIP712Custom {
+		def = append(def, byte(len(base)))
+		def = append(def, base...)
+	}
+	if size > 0 {
+		def[0] |= ledgerEIP712TypeSize
+		def = append(def, byte(size))
+	}
+	if len(levels) > 0 {
+		def[0] |= ledgerEIP712TypeArray
+		def = append(def, byte(len(levels)))
+		for _, level := range levels {
+			if level == 0 {
+				def = append(def, 0x00)
+			} else {
+				def = append(def, 0x01, byte(level))
+			}
+		}
+	}
+	def = append(def, byte(len(field.Name)))
+	return append(def, field.Name...), nil
+}
+
+// ledgerEIP712EncodeValue encodes a primitive field value. Integers are sent in
+// minimal big endian form, or as two's complement of the type width if negative.
+// Addresses, booleans and fixed bytes are sent in their natural width, strings
+// and dynamic bytes as is.
+func ledgerEIP712EncodeValue(typedData *apitypes.TypedData, typ string, value interface{}) ([]byte, error) {
+	switch typ {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string value %v", value)
+		}
+		return []byte(s), nil
+
+	case "bytes":
+		switch v := value.(type) {
+		case []byte:
+			return v, nil
+		case hexutil.Bytes:
+			return v, nil
+		case string:
+			return hexutil.Decode(v)
+		}
+		return nil, fmt.Errorf("invalid bytes value %v", value)
+	}
+	id, size, err := ledgerEIP712Primitive(typ)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := typedData.EncodePrimitiveValue(typ, value, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch id {
+	case ledgerEIP712Address:
+		return enc[12:], nil
+	case ledgerEIP712Bool:
+		return enc[31:], nil
+	case ledgerEIP712FixedBytes:
+		return enc[:size], nil
+	}
+	if id == ledgerEIP712Int && enc[0]&0x80 != 0 {
+		return enc[32-size:], nil
+	}
+	if enc = bytes.TrimLeft(enc, "\x00"); len(enc) == 0 {
+		return []byte{0}, nil
+	}
+	return enc, nil
+}
+
 // ledgerExchange performs a data exchange with the Ledger wallet, sending it a
 // message and retrieving the response.
 //
@@ -483,6 +878,13 @@ func (w *ledgerDriver) ledgerSignTypedMessage(derivationPath []uint32, domainHas
 //  APDU length              | 1 byte
 //  Optional APDU data       | arbitrary
 func (w *ledgerDriver) ledgerExchange(opcode ledgerOpcode, p1 ledgerParam1, p2 ledgerParam2, data []byte) ([]byte, error) {
+	reply, _, err := w.ledgerExchangeStatus(opcode, p1, p2, data)
+	return reply, err
+}
+
+// ledgerExchangeStatus performs a data exchange with the Ledger wallet like
+// ledgerExchange, additionally returning the status word of the reply.
+func (w *ledgerDriver) ledgerExchangeStatus(opcode ledgerOpcode, p1 ledgerParam1, p2 ledgerParam2, data []byte) ([]byte, uint16, error) {
 	// Construct the message payload, possibly split into multiple chunks
 	apdu := make([]byte, 2, 7+len(data))
 
@@ -510,7 +912,7 @@ func (w *ledgerDriver) ledgerExchange(opcode ledgerOpcode, p1 ledgerParam1, p2 l
 		// Send over to the device
 		w.log.Trace("Data chunk sent to the Ledger", "chunk", hexutil.Bytes(chunk))
 		if _, err := w.device.Write(chunk); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 	}
 	// Stream the reply back from the wallet in 64 byte chunks
@@ -519,13 +921,13 @@ func (w *ledgerDriver) ledgerExchange(opcode ledgerOpcode, p1 ledgerParam1, p2 l
 	for {
 		// Read the next chunk from the Ledger wallet
 		if _, err := io.ReadFull(w.device, chunk); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		w.log.Trace("Data chunk received from the Ledger", "chunk", hexutil.Bytes(chunk))
 
 		// Make sure the transport header matches
 		if chunk[0] != 0x01 || chunk[1] != 0x01 || chunk[2] != 0x05 {
-			return nil, errLedgerReplyInvalidHeader
+			return nil, 0, errLedgerReplyInvalidHeader
 		}
 		// If it's the first chunk, retrieve the total message length
 		var payload []byte
@@ -544,5 +946,8 @@ func (w *ledgerDriver) ledgerExchange(opcode ledgerOpcode, p1 ledgerParam1, p2 l
 			break
 		}
 	}
-	return reply[:len(reply)-2], nil
+	if len(reply) < 2 {
+		return nil, 0, errors.New("ledger: reply lacks status")
+	}
+	return reply[:len(reply)-2], binary.BigEndian.Uint16(reply[len(reply)-2:]), nil
 }
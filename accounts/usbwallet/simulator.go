@@ -0,0 +1,638 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains a software simulation of a Ledger hardware wallet, speaking
+// the same HID transport and APDU protocol as the real devices. Keys are derived
+// deterministically from a seed via BIP-32, so wallet flows can be tested in CI
+// without any physical hardware attached. The Trezor counterpart can be found in
+// simulator_trezor.go.
+
+package usbwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/karalabe/usb"
+)
+
+// simulatedLedgerPath is the device path the simulated Ledger is reported under.
+const simulatedLedgerPath = "simulated-ledger"
+
+// Status words returned by the simulated Ledger at the end of each reply.
+var errSimulatorNoReply = errors.New("simulator: no pending reply")
+
+// NewSimulatedLedgerHub creates a hardware wallet manager with a single simulated
+// Ledger device attached to it, deriving all its keys deterministically from the
+// given BIP-32 seed. It is meant for testing only and must never hold real funds.
+func NewSimulatedLedgerHub(seed []byte) (*Hub, error) {
+	master, err := newSimulatedMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	info := usb.DeviceInfo{
+		Path:         simulatedLedgerPath,
+		VendorID:     0x2c97,
+		ProductID:    0x0001,
+		Manufacturer: "Ledger",
+		Product:      "Simulated Nano S",
+		UsagePage:    0xffa0,
+	}
+	return newSimulatedHub(LedgerScheme, info, newLedgerDriver, func() usb.Device {
+		return &ledgerSimulator{master: master}
+	}), nil
+}
+
+// newSimulatedHub creates a hardware wallet manager with a single simulated device
+// attached, opening a fresh simulator instance on every connection.
+func newSimulatedHub(scheme string, info usb.DeviceInfo, makeDriver func(log.Logger) driver, device func() usb.Device) *Hub {
+	hub := &Hub{
+		scheme:     scheme,
+		vendorID:   info.VendorID,
+		productIDs: []uint16{info.ProductID},
+		usageID:    info.UsagePage,
+		makeDriver: makeDriver,
+		enumerate: func(vendorID uint16) ([]usb.DeviceInfo, error) {
+			return []usb.DeviceInfo{info}, nil
+		},
+		open: func(info usb.DeviceInfo) (usb.Device, error) {
+			return device(), nil
+		},
+		quit: make(chan chan error),
+	}
+	hub.refreshWallets()
+	return hub
+}
+
+// simulatedKey is an extended BIP-32 private key.
+type simulatedKey struct {
+	priv  *big.Int
+	chain []byte
+}
+
+// newSimulatedMasterKey derives the BIP-32 master key from a seed.
+func newSimulatedMasterKey(seed []byte) (*simulatedKey, error) {
+	if len(seed) == 0 {
+		return nil, errors.New("empty simulator seed")
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	priv := new(big.Int).SetBytes(sum[:32])
+	if priv.Sign() == 0 || priv.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errors.New("invalid simulator seed")
+	}
+	return &simulatedKey{priv: priv, chain: sum[32:]}, nil
+}
+
+// derive computes the private key at the given derivation path.
+func (k *simulatedKey) derive(path []uint32) (*ecdsa.PrivateKey, error) {
+	var (
+		n     = crypto.S256().Params().N
+		priv  = new(big.Int).Set(k.priv)
+		chain = k.chain
+	)
+	for _, index := range path {
+		var data []byte
+		if index >= 0x80000000 {
+			data = append([]byte{0x00}, math.PaddedBigBytes(priv, 32)...)
+		} else {
+			key, err := crypto.ToECDSA(math.PaddedBigBytes(priv, 32))
+			if err != nil {
+				return nil, err
+			}
+			data = crypto.CompressPubkey(&key.PublicKey)
+		}
+		data = append(data, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+		mac := hmac.New(sha512.New, chain)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		tweak := new(big.Int).SetBytes(sum[:32])
+		if tweak.Cmp(n) >= 0 {
+			return nil, errors.New("invalid derived key")
+		}
+		priv = tweak.Add(tweak, priv).Mod(tweak, n)
+		if priv.Sign() == 0 {
+			return nil, errors.New("invalid derived key")
+		}
+		chain = sum[32:]
+	}
+	return crypto.ToECDSA(math.PaddedBigBytes(priv, 32))
+}
+
+// ledgerSimulator implements usb.Device, answering Ledger APDU requests with a
+// software key derived from a master seed.
+type ledgerSimulator struct {
+	master *simulatedKey
+
+	request []byte   // APDU being reassembled from the received chunks
+	length  int      // Total length of the APDU being reassembled
+	replies [][]byte // Reply chunks waiting to be read
+
+	signPath []uint32 // Derivation path of the transaction being signed
+	signData []byte   // Transaction RLP being assembled from multiple APDUs
+
+	typedTypes  apitypes.Types   // EIP 712 struct types defined so far
+	typedStruct string           // Name of the EIP 712 struct type being defined
+	typedValues []simulatedValue // EIP 712 values received so far, in order
+	typedField  []byte           // EIP 712 field value being assembled from partial chunks
+
+	lock sync.Mutex
+}
+
+// Close implements usb.Device.
+func (s *ledgerSimulator) Close() error {
+	return nil
+}
+
+// Write implements usb.Device, accepting a single 64 byte transport chunk.
+func (s *ledgerSimulator) Write(chunk []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(chunk) < 5 || chunk[0] != 0x01 || chunk[1] != 0x01 || chunk[2] != 0x05 {
+		return 0, errLedgerReplyInvalidHeader
+	}
+	if binary.BigEndian.Uint16(chunk[3:5]) == 0 {
+		if len(chunk) < 7 {
+			return 0, errLedgerReplyInvalidHeader
+		}
+		s.length = int(binary.BigEndian.Uint16(chunk[5:7]))
+		s.request = append(s.request[:0], chunk[7:]...)
+	} else {
+		s.request = append(s.request, chunk[5:]...)
+	}
+	if len(s.request) >= s.length {
+		s.reply(s.handle(s.request[:s.length]))
+		s.request = s.request[:0]
+	}
+	return len(chunk), nil
+}
+
+// Read implements usb.Device, returning the next pending reply chunk.
+func (s *ledgerSimulator) Read(b []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.replies) == 0 {
+		return 0, errSimulatorNoReply
+	}
+	n := copy(b, s.replies[0])
+	s.replies = s.replies[1:]
+	return n, nil
+}
+
+// reply splits a reply payload into transport chunks and queues them up.
+func (s *ledgerSimulator) reply(payload []byte) {
+	data := make([]byte, 2, 2+len(payload))
+	binary.BigEndian.PutUint16(data, uint16(len(payload)))
+	data = append(data, payload...)
+
+	for seq := 0; len(data) > 0; seq++ {
+		chunk := make([]byte, 64)
+		copy(chunk, []byte{0x01, 0x01, 0x05})
+		binary.BigEndian.PutUint16(chunk[3:], uint16(seq))
+		data = data[copy(chunk[5:], data):]
+		s.replies = append(s.replies, chunk)
+	}
+}
+
+// handle executes a single APDU command, returning the reply payload with the
+// status word appended.
+func (s *ledgerSimulator) handle(apdu []byte) []byte {
+	if len(apdu) < 5 || len(apdu) != 5+int(apdu[4]) {
+		return appendStatus(nil, ledgerStatusInvalidData)
+	}
+	var (
+		op   = ledgerOpcode(apdu[1])
+		p1   = ledgerParam1(apdu[2])
+		data = apdu[5:]
+	)
+	switch op {
+	case ledgerOpGetConfiguration:
+		return appendStatus([]byte{0x00, 1, 9, 19}, ledgerStatusOK)
+
+	case ledgerOpRetrieveAddress:
+		path, _, err := parseSimulatorPath(data)
+		if err != nil {
+			return appendStatus(nil, ledgerStatusInvalidData)
+		}
+		key, err := s.master.derive(path)
+		if err != nil {
+			return appendStatus(nil, ledgerStatusInvalidData)
+		}
+		pubkey := crypto.FromECDSAPub(&key.PublicKey)
+		address := crypto.PubkeyToAddress(key.PublicKey)
+
+		reply := append([]byte{byte(len(pubkey))}, pubkey...)
+		reply = append(reply, byte(2*len(address)))
+		reply = append(reply, []byte(hex.EncodeToString(address[:]))...)
+		return appendStatus(reply, ledgerStatusOK)
+
+	case ledgerOpSignTransaction:
+		if p1 == ledgerP1InitTransactionData {
+			path, rest, err := parseSimulatorPath(data)
+			if err != nil {
+				return appendStatus(nil, ledgerStatusInvalidData)
+			}
+			s.signPath, s.signData = path, append([]byte{}, rest...)
+		} else {
+			s.signData = append(s.signData, data...)
+		}
+		// Wait for more chunks until the entire transaction RLP is received
+		if _, _, rest, err := rlp.Split(s.signData); err != nil || len(rest) > 0 {
+			return appendStatus(nil, ledgerStatusOK)
+		}
+		return appendStatus(s.signTx(), ledgerStatusOK)
+
+	case ledgerOpEIP712StructDef:
+		switch ledgerParam2(apdu[3]) {
+		case ledgerP2StructName:
+			if s.typedTypes == nil {
+				s.typedTypes = make(apitypes.Types)
+			}
+			s.typedStruct = string(data)
+			s.typedTypes[s.typedStruct] = []apitypes.Type{}
+		case ledgerP2StructField:
+			field, err := parseSimulatorFieldDef(data)
+			if err != nil || s.typedStruct == "" {
+				return appendStatus(nil, ledgerStatusInvalidData)
+			}
+			s.typedTypes[s.typedStruct] = append(s.typedTypes[s.typedStruct], field)
+		default:
+			return appendStatus(nil, ledgerStatusInvalidData)
+		}
+		return appendStatus(nil, ledgerStatusOK)
+
+	case ledgerOpEIP712StructImpl:
+		switch p2 := ledgerParam2(apdu[3]); p2 {
+		case ledgerP2StructName, ledgerP2StructArray:
+			s.typedValues = append(s.typedValues, simulatedValue{kind: p2, data: append([]byte{}, data...)})
+		case ledgerP2StructField:
+			s.typedField = append(s.typedField, data...)
+			if p1 == ledgerP1CompleteSend {
+				s.typedValues = append(s.typedValues, simulatedValue{kind: p2, data: s.typedField})
+				s.typedField = nil
+			}
+		default:
+			return appendStatus(nil, ledgerStatusInvalidData)
+		}
+		return appendStatus(nil, ledgerStatusOK)
+
+	case ledgerOpSignTypedMessage:
+		path, rest, err := parseSimulatorPath(data)
+		if err != nil {
+			return appendStatus(nil, ledgerStatusInvalidData)
+		}
+		if ledgerParam2(apdu[3]) == ledgerP2TypedMessageFull {
+			defer func() { s.typedTypes, s.typedStruct, s.typedValues, s.typedField = nil, "", nil, nil }()
+
+			if len(rest) != 0 {
+				return appendStatus(nil, ledgerStatusInvalidData)
+			}
+			reply, err := s.signTypedData(path)
+			if err != nil {
+				log.Debug("Simulated Ledger received invalid typed data", "err", err)
+				return appendStatus(nil, ledgerStatusInvalidData)
+			}
+			return appendStatus(reply, ledgerStatusOK)
+		}
+		if len(rest) != 64 {
+			return appendStatus(nil, ledgerStatusInvalidData)
+		}
+		key, err := s.master.derive(path)
+		if err != nil {
+			return appendStatus(nil, ledgerStatusInvalidData)
+		}
+		sig, err := crypto.Sign(crypto.Keccak256([]byte{0x19, 0x01}, rest), key)
+		if err != nil {
+			return appendStatus(nil, ledgerStatusInvalidData)
+		}
+		return appendStatus(append([]byte{27 + sig[64]}, sig[:64]...), ledgerStatusOK)
+
+	default:
+		return appendStatus(nil, ledgerStatusUnknownIns)
+	}
+}
+
+// signTx signs the fully assembled transaction RLP, returning the V || R || S
+// signature in the format of the Ledger Ethereum app.
+func (s *ledgerSimulator) signTx() []byte {
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(s.signData, &fields); err != nil {
+		log.Debug("Simulated Ledger received invalid transaction", "err", err)
+		return nil
+	}
+	key, err := s.master.derive(s.signPath)
+	if err != nil {
+		return nil
+	}
+	sig, err := crypto.Sign(crypto.Keccak256(s.signData), key)
+	if err != nil {
+		return nil
+	}
+	v := sig[64]
+	if len(fields) == 9 {
+		// EIP-155 transaction, the Ledger returns the low byte of the full V
+		chainID := new(big.Int)
+		if err := rlp.DecodeBytes(fields[6], chainID); err != nil {
+			return nil
+		}
+		v += byte(chainID.Uint64()*2 + 35)
+	}
+	return append([]byte{v}, sig[:64]...)
+}
+
+// simulatedValue is an EIP 712 value sent to the simulated Ledger: the name of a
+// root struct, the size of an array or a field value.
+type simulatedValue struct {
+	kind ledgerParam2
+	data []byte
+}
+
+// signTypedData reassembles the typed data from the received struct definitions
+// and values, returning its V || R || S signature.
+func (s *ledgerSimulator) signTypedData(path []uint32) ([]byte, error) {
+	r := &simulatedTypedDataReader{types: s.typedTypes, values: s.typedValues}
+
+	domain, err := r.readRoot()
+	if err != nil {
+		return nil, err
+	}
+	if domain.name != "EIP712Domain" {
+		return nil, errors.New("missing domain")
+	}
+	message, err := r.readRoot()
+	if err != nil {
+		return nil, err
+	}
+	if len(r.values) != 0 {
+		return nil, errors.New("excess values")
+	}
+	typedData := apitypes.TypedData{
+		Types:       s.typedTypes,
+		PrimaryType: message.name,
+		Message:     message.data,
+	}
+	typedData.Domain.Name, _ = domain.data["name"].(string)
+	typedData.Domain.Version, _ = domain.data["version"].(string)
+	typedData.Domain.VerifyingContract, _ = domain.data["verifyingContract"].(string)
+	typedData.Domain.Salt, _ = domain.data["salt"].(string)
+	if chainID, ok := domain.data["chainId"].(string); ok {
+		typedData.Domain.ChainId = new(math.HexOrDecimal256)
+		if err := typedData.Domain.ChainId.UnmarshalText([]byte(chainID)); err != nil {
+			return nil, err
+		}
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.master.derive(path)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{27 + sig[64]}, sig[:64]...), nil
+}
+
+// simulatedTypedDataReader decodes the EIP 712 values received by the simulated
+// Ledger into the JSON form of typed data messages.
+type simulatedTypedDataReader struct {
+	types  apitypes.Types
+	values []simulatedValue
+}
+
+// simulatedStruct is a decoded root struct.
+type simulatedStruct struct {
+	name string
+	data map[string]interface{}
+}
+
+func (r *simulatedTypedDataReader) next(kind ledgerParam2) ([]byte, error) {
+	if len(r.values) == 0 || r.values[0].kind != kind {
+		return nil, errors.New("unexpected value")
+	}
+	data := r.values[0].data
+	r.values = r.values[1:]
+	return data, nil
+}
+
+func (r *simulatedTypedDataReader) readRoot() (*simulatedStruct, error) {
+	name, err := r.next(ledgerP2StructName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.readStruct(string(name))
+	if err != nil {
+		return nil, err
+	}
+	return &simulatedStruct{name: string(name), data: data}, nil
+}
+
+func (r *simulatedTypedDataReader) readStruct(name string) (map[string]interface{}, error) {
+	fields, ok := r.types[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined struct %q", name)
+	}
+	data := make(map[string]interface{})
+	for _, field := range fields {
+		base, levels, err := ledgerEIP712ParseType(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		if data[field.Name], err = r.readValue(base, levels); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (r *simulatedTypedDataReader) readValue(base string, levels []int) (interface{}, error) {
+	if len(levels) > 0 {
+		size, err := r.next(ledgerP2StructArray)
+		if err != nil || len(size) != 1 {
+			return nil, errors.New("invalid array size")
+		}
+		items := make([]interface{}, size[0])
+		for i := range items {
+			if items[i], err = r.readValue(base, levels[1:]); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	if _, ok := r.types[base]; ok {
+		return r.readStruct(base)
+	}
+	data, err := r.next(ledgerP2StructField)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2 || int(binary.BigEndian.Uint16(data)) != len(data)-2 {
+		return nil, errors.New("invalid field value length")
+	}
+	data = data[2:]
+
+	id, size, err := ledgerEIP712Primitive(base)
+	if err != nil {
+		return nil, err
+	}
+	switch id {
+	case ledgerEIP712String:
+		return string(data), nil
+	case ledgerEIP712Bytes, ledgerEIP712FixedBytes:
+		return hexutil.Encode(data), nil
+	case ledgerEIP712Address:
+		return common.BytesToAddress(data).Hex(), nil
+	case ledgerEIP712Bool:
+		return len(data) == 1 && data[0] != 0, nil
+	}
+	if len(data) == 0 || len(data) > size {
+		return nil, errors.New("invalid integer value")
+	}
+	value := new(big.Int).SetBytes(data)
+	if id == ledgerEIP712Int && len(data) == size && data[0]&0x80 != 0 {
+		value.Sub(value, new(big.Int).Lsh(common.Big1, uint(8*size)))
+	}
+	return value.String(), nil
+}
+
+// parseSimulatorFieldDef decodes the definition of an EIP 712 struct field.
+func parseSimulatorFieldDef(data []byte) (apitypes.Type, error) {
+	errInvalid := errors.New("invalid field definition")
+
+	read := func(n int) ([]byte, error) {
+		if len(data) < n {
+			return nil, errInvalid
+		}
+		b := data[:n]
+		data = data[n:]
+		return b, nil
+	}
+	desc, err := read(1)
+	if err != nil {
+		return apitypes.Type{}, err
+	}
+	var typ string
+	switch id := desc[0] &^ (ledgerEIP712TypeArray | ledgerEIP712TypeSize); id {
+	case ledgerEIP712Custom:
+		n, err := read(1)
+		if err != nil {
+			return apitypes.Type{}, err
+		}
+		name, err := read(int(n[0]))
+		if err != nil {
+			return apitypes.Type{}, err
+		}
+		typ = string(name)
+	case ledgerEIP712Address:
+		typ = "address"
+	case ledgerEIP712Bool:
+		typ = "bool"
+	case ledgerEIP712String:
+		typ = "string"
+	case ledgerEIP712Bytes:
+		typ = "bytes"
+	case ledgerEIP712Int, ledgerEIP712Uint, ledgerEIP712FixedBytes:
+		if desc[0]&ledgerEIP712TypeSize == 0 {
+			return apitypes.Type{}, errInvalid
+		}
+		size, err := read(1)
+		if err != nil {
+			return apitypes.Type{}, err
+		}
+		switch id {
+		case ledgerEIP712Int:
+			typ = fmt.Sprintf("int%d", 8*int(size[0]))
+		case ledgerEIP712Uint:
+			typ = fmt.Sprintf("uint%d", 8*int(size[0]))
+		default:
+			typ = fmt.Sprintf("bytes%d", size[0])
+		}
+	default:
+		return apitypes.Type{}, errInvalid
+	}
+	if desc[0]&ledgerEIP712TypeArray != 0 {
+		count, err := read(1)
+		if err != nil {
+			return apitypes.Type{}, err
+		}
+		for i := 0; i < int(count[0]); i++ {
+			level, err := read(1)
+			if err != nil {
+				return apitypes.Type{}, err
+			}
+			if level[0] == 0 {
+				typ += "[]"
+				continue
+			}
+			size, err := read(1)
+			if err != nil {
+				return apitypes.Type{}, err
+			}
+			typ += fmt.Sprintf("[%d]", size[0])
+		}
+	}
+	n, err := read(1)
+	if err != nil {
+		return apitypes.Type{}, err
+	}
+	name, err := read(int(n[0]))
+	if err != nil || len(data) != 0 {
+		return apitypes.Type{}, errInvalid
+	}
+	return apitypes.Type{Name: string(name), Type: typ}, nil
+}
+
+// parseSimulatorPath splits a Ledger request payload into the BIP-32 path it
+// starts with and the remaining data.
+func parseSimulatorPath(data []byte) ([]uint32, []byte, error) {
+	if len(data) < 1 || len(data) < 1+4*int(data[0]) {
+		return nil, nil, errors.New("invalid derivation path")
+	}
+	path := make([]uint32, data[0])
+	for i := range path {
+		path[i] = binary.BigEndian.Uint32(data[1+4*i:])
+	}
+	return path, data[1+4*len(path):], nil
+}
+
+// appendStatus appends a status word to an APDU reply payload.
+func appendStatus(reply []byte, sw uint16) []byte {
+	return append(reply, byte(sw>>8), byte(sw))
+}
@@ -0,0 +1,282 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Tests the simulated key derivation against the BIP-32 test vector 1.
+func TestSimulatedDerivation(t *testing.T) {
+	master, err := newSimulatedMasterKey(common.FromHex("000102030405060708090a0b0c0d0e0f"))
+	if err != nil {
+		t.Fatalf("failed to create master key: %v", err)
+	}
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+	}
+	for _, tt := range tests {
+		path, _ := accounts.ParseDerivationPath(tt.path)
+		key, err := master.derive(path)
+		if err != nil {
+			t.Fatalf("%s: failed to derive key: %v", tt.path, err)
+		}
+		if have := common.Bytes2Hex(math.PaddedBigBytes(key.D, 32)); have != tt.key {
+			t.Errorf("%s: key mismatch: have %s, want %s", tt.path, have, tt.key)
+		}
+	}
+}
+
+// Tests that the simulated Ledger can be driven through the standard wallet flow.
+func TestSimulatedLedgerWallet(t *testing.T) {
+	seed := []byte("simulated ledger test seed")
+	hub, err := NewSimulatedLedgerHub(seed)
+	if err != nil {
+		t.Fatalf("failed to create simulated hub: %v", err)
+	}
+	wallets := hub.Wallets()
+	if len(wallets) != 1 {
+		t.Fatalf("wallet count mismatch: have %d, want 1", len(wallets))
+	}
+	wallet := wallets[0]
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	defer wallet.Close()
+
+	if status, err := wallet.Status(); err != nil || status != "Ethereum app v1.9.19 online" {
+		t.Fatalf("unexpected wallet status: %q, %v", status, err)
+	}
+	// Derive an account and cross check it with the expected key
+	account, err := wallet.Derive(accounts.DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	master, _ := newSimulatedMasterKey(seed)
+	key, _ := master.derive(accounts.DefaultBaseDerivationPath)
+	if want := crypto.PubkeyToAddress(key.PublicKey); account.Address != want {
+		t.Fatalf("derived address mismatch: have %x, want %x", account.Address, want)
+	}
+	// Sign a transaction large enough to be split into multiple chunks
+	to := common.HexToAddress("0x01")
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, To: &to, Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1), Data: bytes.Repeat([]byte{0xff}, 600)})
+
+	signed, err := wallet.SignTx(account, tx, big.NewInt(1337))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(1337)), signed)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if sender != account.Address {
+		t.Fatalf("sender mismatch: have %x, want %x", sender, account.Address)
+	}
+	// Sign an EIP-712 typed message
+	data := append([]byte{0x19, 0x01}, bytes.Repeat([]byte{0xaa}, 64)...)
+	sig, err := wallet.SignData(account, accounts.MimetypeTypedData, data)
+	if err != nil {
+		t.Fatalf("failed to sign typed data: %v", err)
+	}
+	sig[64] -= 27
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(data), sig)
+	if err != nil {
+		t.Fatalf("failed to recover typed data signer: %v", err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != account.Address {
+		t.Fatalf("typed data signer mismatch: have %x, want %x", signer, account.Address)
+	}
+	// Sign EIP-712 typed data, sent to the device field by field
+	typedData := testTypedData()
+	sig, err = wallet.SignTypedData(account, typedData)
+	if err != nil {
+		t.Fatalf("failed to sign typed data field by field: %v", err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	sig[64] -= 27
+	if pubkey, err = crypto.SigToPub(hash, sig); err != nil {
+		t.Fatalf("failed to recover typed data signer: %v", err)
+	}
+	if signer := crypto.PubkeyToAddress(*pubkey); signer != account.Address {
+		t.Fatalf("typed data signer mismatch: have %x, want %x", signer, account.Address)
+	}
+}
+
+// Tests that typed data is sent to the Ledger field by field. The simulator signs
+// the typed data it reassembles, so any field lost on the way breaks the signature.
+func TestSimulatedLedgerTypedData(t *testing.T) {
+	master, err := newSimulatedMasterKey([]byte("simulated ledger test seed"))
+	if err != nil {
+		t.Fatalf("failed to create master key: %v", err)
+	}
+	driver := newLedgerDriver(log.Root()).(*ledgerDriver)
+	if err := driver.Open(&ledgerSimulator{master: master}, ""); err != nil {
+		t.Fatalf("failed to open driver: %v", err)
+	}
+	typedData := testTypedData()
+	sig, err := driver.SignTypedData(accounts.DefaultBaseDerivationPath, typedData)
+	if err != nil {
+		t.Fatalf("failed to sign typed data: %v", err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	sig[64] -= 27
+	pubkey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		t.Fatalf("failed to recover typed data signer: %v", err)
+	}
+	key, _ := master.derive(accounts.DefaultBaseDerivationPath)
+	if signer, want := crypto.PubkeyToAddress(*pubkey), crypto.PubkeyToAddress(key.PublicKey); signer != want {
+		t.Fatalf("typed data signer mismatch: have %x, want %x", signer, want)
+	}
+	// Older apps can't display typed data
+	driver.version = [3]byte{1, 9, 18}
+	if _, err := driver.SignTypedData(accounts.DefaultBaseDerivationPath, typedData); err != accounts.ErrNotSupported {
+		t.Fatalf("unexpected error on old app: have %v, want %v", err, accounts.ErrNotSupported)
+	}
+}
+
+// testTypedData returns typed data using nested structs, arrays and values long
+// enough to be sent in multiple chunks.
+// Tests that the simulated Trezor can be driven through the standard wallet flow.
+func TestSimulatedTrezorWallet(t *testing.T) {
+	seed := []byte("simulated trezor test seed")
+	hub, err := NewSimulatedTrezorHub(seed)
+	if err != nil {
+		t.Fatalf("failed to create simulated hub: %v", err)
+	}
+	wallets := hub.Wallets()
+	if len(wallets) != 1 {
+		t.Fatalf("wallet count mismatch: have %d, want 1", len(wallets))
+	}
+	wallet := wallets[0]
+	if err := wallet.Open(""); err != nil {
+		t.Fatalf("failed to open wallet: %v", err)
+	}
+	defer wallet.Close()
+
+	if status, err := wallet.Status(); err != nil || status != "Trezor v1.9.0 'Simulated' online" {
+		t.Fatalf("unexpected wallet status: %q, %v", status, err)
+	}
+	// Derive an account and cross check it with the expected key
+	account, err := wallet.Derive(accounts.DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	master, _ := newSimulatedMasterKey(seed)
+	key, _ := master.derive(accounts.DefaultBaseDerivationPath)
+	if want := crypto.PubkeyToAddress(key.PublicKey); account.Address != want {
+		t.Fatalf("derived address mismatch: have %x, want %x", account.Address, want)
+	}
+	// Sign a transaction large enough for its payload to be streamed in chunks
+	to := common.HexToAddress("0x01")
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, To: &to, Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1), Data: bytes.Repeat([]byte{0xff}, 2500)})
+
+	signed, err := wallet.SignTx(account, tx, big.NewInt(1337))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	sender, err := types.Sender(types.NewEIP155Signer(big.NewInt(1337)), signed)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if sender != account.Address {
+		t.Fatalf("sender mismatch: have %x, want %x", sender, account.Address)
+	}
+	if !bytes.Equal(signed.Data(), tx.Data()) {
+		t.Fatalf("signed payload mismatch")
+	}
+}
+
+func testTypedData() apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Person": {
+				{Name: "name", Type: "string"},
+				{Name: "wallets", Type: "address[]"},
+			},
+			"Mail": {
+				{Name: "from", Type: "Person"},
+				{Name: "to", Type: "Person[]"},
+				{Name: "contents", Type: "string"},
+				{Name: "attachment", Type: "bytes"},
+				{Name: "tag", Type: "bytes4"},
+				{Name: "delta", Type: "int64"},
+				{Name: "amount", Type: "uint128"},
+				{Name: "urgent", Type: "bool"},
+				{Name: "levels", Type: "uint8[]"},
+			},
+		},
+		PrimaryType: "Mail",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Ether Mail",
+			Version:           "1",
+			ChainId:           math.NewHexOrDecimal256(1337),
+			VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+		},
+		Message: apitypes.TypedDataMessage{
+			"from": map[string]interface{}{
+				"name":    "Cow",
+				"wallets": []interface{}{"0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", "0xDeaDbeefdEAdbeefdEadbEEFdeadbeEFdEaDbeeF"},
+			},
+			"to": []interface{}{
+				map[string]interface{}{
+					"name":    "Bob",
+					"wallets": []interface{}{"0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+				},
+				map[string]interface{}{
+					"name":    "Alice",
+					"wallets": []interface{}{},
+				},
+			},
+			"contents":   strings.Repeat("Hello, Bob! ", 40),
+			"attachment": "0x" + strings.Repeat("ab", 300),
+			"tag":        "0xdeadbeef",
+			"delta":      "-1000",
+			"amount":     "0x0",
+			"urgent":     true,
+			"levels":     []interface{}{"1", "255"},
+		},
+	}
+}
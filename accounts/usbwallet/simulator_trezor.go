@@ -0,0 +1,264 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains a software simulation of a Trezor hardware wallet, speaking
+// the same HID transport and protobuf protocol as the real devices. It shares the
+// deterministic key derivation of the simulated Ledger.
+
+package usbwallet
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/usbwallet/trezor"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/golang/protobuf/proto"
+	"github.com/karalabe/usb"
+)
+
+// simulatedTrezorPath is the device path the simulated Trezor is reported under.
+const simulatedTrezorPath = "simulated-trezor"
+
+// NewSimulatedTrezorHub creates a hardware wallet manager with a single simulated
+// Trezor device attached to it, deriving all its keys deterministically from the
+// given BIP-32 seed. The device has neither a PIN nor a passphrase set. It is
+// meant for testing only and must never hold real funds.
+func NewSimulatedTrezorHub(seed []byte) (*Hub, error) {
+	master, err := newSimulatedMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	info := usb.DeviceInfo{
+		Path:         simulatedTrezorPath,
+		VendorID:     0x534c,
+		ProductID:    0x0001,
+		Manufacturer: "SatoshiLabs",
+		Product:      "Simulated TREZOR",
+		UsagePage:    0xff00,
+	}
+	return newSimulatedHub(TrezorScheme, info, newTrezorDriver, func() usb.Device {
+		return &trezorSimulator{master: master}
+	}), nil
+}
+
+// trezorSimulator implements usb.Device, answering Trezor protobuf messages with a
+// software key derived from a master seed.
+type trezorSimulator struct {
+	master *simulatedKey
+
+	kind    uint16   // Type of the message being reassembled from the received chunks
+	request []byte   // Message being reassembled from the received chunks
+	length  int      // Total length of the message being reassembled
+	partial bool     // Whether a message is being reassembled
+	replies [][]byte // Reply chunks waiting to be read
+
+	signTx   *trezor.EthereumSignTx // Transaction being signed
+	signData []byte                 // Transaction payload received so far
+	confirm  proto.Message          // Reply withheld until the user confirms on the device
+
+	lock sync.Mutex
+}
+
+// Close implements usb.Device.
+func (s *trezorSimulator) Close() error {
+	return nil
+}
+
+// Write implements usb.Device, accepting a single 64 byte transport chunk.
+func (s *trezorSimulator) Write(chunk []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(chunk) < 1 || chunk[0] != 0x3f {
+		return 0, errTrezorReplyInvalidHeader
+	}
+	if !s.partial {
+		if len(chunk) < 9 || chunk[1] != 0x23 || chunk[2] != 0x23 {
+			return 0, errTrezorReplyInvalidHeader
+		}
+		s.kind = binary.BigEndian.Uint16(chunk[3:5])
+		s.length = int(binary.BigEndian.Uint32(chunk[5:9]))
+		s.request = append(s.request[:0], chunk[9:]...)
+		s.partial = true
+	} else {
+		s.request = append(s.request, chunk[1:]...)
+	}
+	if len(s.request) >= s.length {
+		s.partial = false
+		s.reply(s.handle(trezor.MessageType(s.kind), s.request[:s.length]))
+	}
+	return len(chunk), nil
+}
+
+// Read implements usb.Device, returning the next pending reply chunk.
+func (s *trezorSimulator) Read(b []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.replies) == 0 {
+		return 0, errSimulatorNoReply
+	}
+	n := copy(b, s.replies[0])
+	s.replies = s.replies[1:]
+	return n, nil
+}
+
+// reply encodes a reply message into transport chunks and queues them up.
+func (s *trezorSimulator) reply(msg proto.Message) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		msg, data = trezorFailure(trezor.Failure_Failure_FirmwareError, err.Error()), nil
+	}
+	payload := make([]byte, 8, 8+len(data))
+	copy(payload, []byte{0x23, 0x23})
+	binary.BigEndian.PutUint16(payload[2:], trezor.Type(msg))
+	binary.BigEndian.PutUint32(payload[4:], uint32(len(data)))
+	payload = append(payload, data...)
+
+	for len(payload) > 0 {
+		chunk := make([]byte, 64)
+		chunk[0] = 0x3f
+		payload = payload[copy(chunk[1:], payload):]
+		s.replies = append(s.replies, chunk)
+	}
+}
+
+// handle executes a single request message, returning the reply message.
+func (s *trezorSimulator) handle(kind trezor.MessageType, data []byte) proto.Message {
+	switch kind {
+	case trezor.MessageType_MessageType_Initialize:
+		s.signTx, s.signData, s.confirm = nil, nil, nil
+		return &trezor.Features{
+			Vendor:       proto.String("trezor.io"),
+			MajorVersion: proto.Uint32(1),
+			MinorVersion: proto.Uint32(9),
+			PatchVersion: proto.Uint32(0),
+			Label:        proto.String("Simulated"),
+			Initialized:  proto.Bool(true),
+		}
+
+	case trezor.MessageType_MessageType_Ping:
+		req := new(trezor.Ping)
+		if err := proto.Unmarshal(data, req); err != nil {
+			return trezorFailure(trezor.Failure_Failure_DataError, err.Error())
+		}
+		return &trezor.Success{Message: req.Message}
+
+	case trezor.MessageType_MessageType_ButtonAck:
+		if s.confirm == nil {
+			return trezorFailure(trezor.Failure_Failure_UnexpectedMessage, "Unexpected message")
+		}
+		reply := s.confirm
+		s.confirm = nil
+		return reply
+
+	case trezor.MessageType_MessageType_EthereumGetAddress:
+		req := new(trezor.EthereumGetAddress)
+		if err := proto.Unmarshal(data, req); err != nil {
+			return trezorFailure(trezor.Failure_Failure_DataError, err.Error())
+		}
+		key, err := s.master.derive(req.AddressN)
+		if err != nil {
+			return trezorFailure(trezor.Failure_Failure_DataError, err.Error())
+		}
+		address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+		return &trezor.EthereumAddress{AddressHex: &address}
+
+	case trezor.MessageType_MessageType_EthereumSignTx:
+		req := new(trezor.EthereumSignTx)
+		if err := proto.Unmarshal(data, req); err != nil {
+			return trezorFailure(trezor.Failure_Failure_DataError, err.Error())
+		}
+		s.signTx, s.signData = req, append([]byte{}, req.DataInitialChunk...)
+		return s.continueSign()
+
+	case trezor.MessageType_MessageType_EthereumTxAck:
+		req := new(trezor.EthereumTxAck)
+		if err := proto.Unmarshal(data, req); err != nil {
+			return trezorFailure(trezor.Failure_Failure_DataError, err.Error())
+		}
+		if s.signTx == nil {
+			return trezorFailure(trezor.Failure_Failure_UnexpectedMessage, "Unexpected message")
+		}
+		s.signData = append(s.signData, req.DataChunk...)
+		return s.continueSign()
+
+	default:
+		return trezorFailure(trezor.Failure_Failure_UnexpectedMessage, "Unexpected message")
+	}
+}
+
+// continueSign requests the next chunk of the transaction payload, or asks the
+// user to confirm the transaction once the entire payload has been received.
+func (s *trezorSimulator) continueSign() proto.Message {
+	if left := int(s.signTx.GetDataLength()) - len(s.signData); left > 0 {
+		if left > 1024 {
+			left = 1024
+		}
+		length := uint32(left)
+		return &trezor.EthereumTxRequest{DataLength: &length}
+	}
+	defer func() { s.signTx, s.signData = nil, nil }()
+
+	s.confirm = s.sign()
+	return &trezor.ButtonRequest{Code: trezor.ButtonRequest_ButtonRequest_SignTx.Enum()}
+}
+
+// sign signs the fully received transaction, returning the signature in the
+// format of the Trezor firmware.
+func (s *trezorSimulator) sign() proto.Message {
+	req := s.signTx
+	tx := &types.LegacyTx{
+		Nonce:    new(big.Int).SetBytes(req.Nonce).Uint64(),
+		GasPrice: new(big.Int).SetBytes(req.GasPrice),
+		Gas:      new(big.Int).SetBytes(req.GasLimit).Uint64(),
+		Value:    new(big.Int).SetBytes(req.Value),
+		Data:     s.signData,
+	}
+	if req.GetToHex() != "" {
+		to := common.HexToAddress(req.GetToHex())
+		tx.To = &to
+	} else if len(req.ToBin) > 0 {
+		to := common.BytesToAddress(req.ToBin)
+		tx.To = &to
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if req.ChainId != nil {
+		signer = types.NewEIP155Signer(new(big.Int).SetUint64(uint64(req.GetChainId())))
+	}
+	key, err := s.master.derive(req.AddressN)
+	if err != nil {
+		return trezorFailure(trezor.Failure_Failure_DataError, err.Error())
+	}
+	sig, err := crypto.Sign(signer.Hash(types.NewTx(tx)).Bytes(), key)
+	if err != nil {
+		return trezorFailure(trezor.Failure_Failure_FirmwareError, err.Error())
+	}
+	v := uint32(sig[64]) + 27
+	if req.ChainId != nil {
+		v = uint32(sig[64]) + 2*req.GetChainId() + 35
+	}
+	return &trezor.EthereumTxRequest{SignatureV: &v, SignatureR: sig[:32], SignatureS: sig[32:64]}
+}
+
+// trezorFailure creates a failure reply.
+func trezorFailure(code trezor.Failure_FailureType, message string) *trezor.Failure {
+	return &trezor.Failure{Code: code.Enum(), Message: &message}
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/golang/protobuf/proto"
 )
 
@@ -189,6 +190,10 @@ func (w *trezorDriver) SignTypedMessage(path accounts.DerivationPath, domainHash
 	return nil, accounts.ErrNotSupported
 }
 
+func (w *trezorDriver) SignTypedData(path accounts.DerivationPath, typedData apitypes.TypedData) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
 // trezorDerive sends a derivation request to the Trezor device and returns the
 // Ethereum address located on that path.
 func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, error) {
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/karalabe/usb"
 )
 
@@ -69,6 +70,11 @@ type driver interface {
 	SignTx(path accounts.DerivationPath, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error)
 
 	SignTypedMessage(path accounts.DerivationPath, messageHash []byte, domainHash []byte) ([]byte, error)
+
+	// SignTypedData sends the EIP-712 typed data to the USB device field by field
+	// and waits for the user to confirm or deny it. Devices unable to display the
+	// fields return accounts.ErrNotSupported.
+	SignTypedData(path accounts.DerivationPath, typedData apitypes.TypedData) ([]byte, error)
 }
 
 // wallet represents the common functionality shared by all USB hardware
@@ -148,7 +154,7 @@ func (w *wallet) Open(passphrase string) error {
 	}
 	// Make sure the actual device connection is done only once
 	if w.device == nil {
-		device, err := w.info.Open()
+		device, err := w.hub.open(w.info)
 		if err != nil {
 			return err
 		}
@@ -532,6 +538,14 @@ func (w *wallet) SignData(account accounts.Account, mimeType string, data []byte
 	}
 
 	// dispatch to 712 signing if the mimetype is TypedData and the format matches
+	return w.signWithDevice(account, func(path accounts.DerivationPath) ([]byte, error) {
+		return w.driver.SignTypedMessage(path, data[2:34], data[34:66])
+	})
+}
+
+// signWithDevice runs a signing request of the driver for the given account,
+// holding the device for the duration of the user confirmation.
+func (w *wallet) signWithDevice(account accounts.Account, sign func(path accounts.DerivationPath) ([]byte, error)) ([]byte, error) {
 	w.stateLock.RLock() // Comms have own mutex, this is for the state fields
 	defer w.stateLock.RUnlock()
 
@@ -559,8 +573,8 @@ func (w *wallet) SignData(account accounts.Account, mimeType string, data []byte
 		w.hub.commsPend--
 		w.hub.commsLock.Unlock()
 	}()
-	// Sign the transaction
-	signature, err := w.driver.SignTypedMessage(path, data[2:34], data[34:66])
+	// Sign the data
+	signature, err := sign(path)
 	if err != nil {
 		return nil, err
 	}
@@ -578,6 +592,24 @@ func (w *wallet) SignText(account accounts.Account, text []byte) ([]byte, error)
 	return w.signHash(account, accounts.TextHash(text))
 }
 
+// SignTypedData implements accounts.Wallet, sending the EIP-712 typed data to the
+// device for the user to review its fields before signing. Devices unable to
+// display the fields are sent the domain separator and the message hash instead.
+func (w *wallet) SignTypedData(account accounts.Account, typedData apitypes.TypedData) ([]byte, error) {
+	_, rawData, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := w.signWithDevice(account, func(path accounts.DerivationPath) ([]byte, error) {
+		return w.driver.SignTypedData(path, typedData)
+	})
+	if err == accounts.ErrNotSupported {
+		w.log.Warn("Device can't display typed data, signing its hashes")
+		return w.SignData(account, accounts.MimetypeTypedData, []byte(rawData))
+	}
+	return signature, err
+}
+
 // SignTx implements accounts.Wallet. It sends the transaction over to the Ledger
 // wallet to request a confirmation from the user. It returns either the signed
 // transaction or a failure if the user denied the transaction.
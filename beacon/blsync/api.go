@@ -0,0 +1,262 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// emptyCodeHash is the code hash of accounts without code.
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// EthAPI serves the eth namespace methods which can be answered from verified
+// headers. State is retrieved from the execution RPC along with proofs, which
+// are verified against the state roots of the headers.
+type EthAPI struct {
+	c *Client
+}
+
+// ChainId returns the chain ID of the network.
+func (api *EthAPI) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(api.c.chainID)
+}
+
+// BlockNumber returns the number of the latest verified block.
+func (api *EthAPI) BlockNumber() (hexutil.Uint64, error) {
+	header, err := api.c.header(rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.BlockNumber), nil
+}
+
+// GetBlockByNumber returns the header fields of a recent verified block. The
+// transactions are not available.
+func (api *EthAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	header, err := api.c.header(rpc.BlockNumberOrHashWithNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	return rpcMarshalHeader(header), nil
+}
+
+// GetBlockByHash returns the header fields of a recent verified block. The
+// transactions are not available.
+func (api *EthAPI) GetBlockByHash(hash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	header, err := api.c.header(rpc.BlockNumberOrHashWithHash(hash, false))
+	if err != nil {
+		return nil, err
+	}
+	return rpcMarshalHeader(header), nil
+}
+
+// GetProof returns the verified merkle proof of an account and some of its
+// storage slots.
+func (api *EthAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.AccountResult, error) {
+	header, err := api.c.header(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return api.proof(ctx, header, address, storageKeys)
+}
+
+// GetBalance returns the verified balance of an account.
+func (api *EthAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	res, err := api.GetProof(ctx, address, nil, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return res.Balance, nil
+}
+
+// GetTransactionCount returns the verified nonce of an account.
+func (api *EthAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	res, err := api.GetProof(ctx, address, nil, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Nonce, nil
+}
+
+// GetStorageAt returns the verified value of a storage slot of an account.
+func (api *EthAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	res, err := api.GetProof(ctx, address, []string{key}, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return common.BigToHash(res.StorageProof[0].Value.ToInt()).Bytes(), nil
+}
+
+// GetCode returns the code of an account, verified against its code hash.
+func (api *EthAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	header, err := api.c.header(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.proof(ctx, header, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.CodeHash == emptyCodeHash {
+		return hexutil.Bytes{}, nil
+	}
+	var code hexutil.Bytes
+	if err := api.c.exec.CallContext(ctx, &code, "eth_getCode", address, rpc.BlockNumberOrHashWithHash(header.BlockHash, false)); err != nil {
+		return nil, err
+	}
+	if hash := crypto.Keccak256Hash(code); hash != res.CodeHash {
+		return nil, fmt.Errorf("code hash mismatch: have %x, want %x", hash, res.CodeHash)
+	}
+	return code, nil
+}
+
+// proof retrieves the proof of an account from the execution RPC and verifies it
+// against the state root of the header.
+func (api *EthAPI) proof(ctx context.Context, header *types.ExecutionHeader, address common.Address, storageKeys []string) (*ethapi.AccountResult, error) {
+	if storageKeys == nil {
+		storageKeys = []string{}
+	}
+	var res ethapi.AccountResult
+	if err := api.c.exec.CallContext(ctx, &res, "eth_getProof", address, storageKeys, rpc.BlockNumberOrHashWithHash(header.BlockHash, false)); err != nil {
+		return nil, err
+	}
+	if res.Address != address {
+		return nil, fmt.Errorf("proof of account %x, want %x", res.Address, address)
+	}
+	if len(res.StorageProof) != len(storageKeys) {
+		return nil, fmt.Errorf("have %d storage proofs for %d keys", len(res.StorageProof), len(storageKeys))
+	}
+	for i, key := range storageKeys {
+		if common.HexToHash(res.StorageProof[i].Key) != common.HexToHash(key) {
+			return nil, fmt.Errorf("storage proof of key %s, want %s", res.StorageProof[i].Key, key)
+		}
+	}
+	if err := verifyProof(header.StateRoot, &res); err != nil {
+		return nil, fmt.Errorf("invalid proof of account %x: %v", address, err)
+	}
+	return &res, nil
+}
+
+// verifyProof checks the account and storage values of an eth_getProof result
+// against the state root.
+func verifyProof(root common.Hash, res *ethapi.AccountResult) error {
+	value, err := verifyTrieProof(root, crypto.Keccak256(res.Address[:]), res.AccountProof)
+	if err != nil {
+		return err
+	}
+	account := ethtypes.StateAccount{
+		Balance:  new(big.Int),
+		Root:     ethtypes.EmptyRootHash,
+		CodeHash: emptyCodeHash[:],
+	}
+	if value != nil {
+		if err := rlp.DecodeBytes(value, &account); err != nil {
+			return err
+		}
+	}
+	if res.Balance == nil || account.Balance.Cmp(res.Balance.ToInt()) != 0 {
+		return errors.New("balance mismatch")
+	}
+	if account.Nonce != uint64(res.Nonce) {
+		return errors.New("nonce mismatch")
+	}
+	if account.Root != res.StorageHash {
+		return errors.New("storage root mismatch")
+	}
+	if !bytes.Equal(account.CodeHash, res.CodeHash[:]) {
+		return errors.New("code hash mismatch")
+	}
+	for _, slot := range res.StorageProof {
+		key := common.HexToHash(slot.Key)
+		value, err := verifyTrieProof(account.Root, crypto.Keccak256(key[:]), slot.Proof)
+		if err != nil {
+			return fmt.Errorf("storage slot %x: %v", key, err)
+		}
+		stored := new(big.Int)
+		if value != nil {
+			var content []byte
+			if err := rlp.DecodeBytes(value, &content); err != nil {
+				return fmt.Errorf("storage slot %x: %v", key, err)
+			}
+			stored.SetBytes(content)
+		}
+		if slot.Value == nil || stored.Cmp(slot.Value.ToInt()) != 0 {
+			return fmt.Errorf("storage slot %x: value mismatch", key)
+		}
+	}
+	return nil
+}
+
+// verifyTrieProof verifies a merkle proof of the trie with the given root,
+// returning the proven value or nil if the key is proven to be absent.
+func verifyTrieProof(root common.Hash, key []byte, proof []string) ([]byte, error) {
+	if root == ethtypes.EmptyRootHash {
+		return nil, nil
+	}
+	db := memorydb.New()
+	for _, node := range proof {
+		blob, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, err
+		}
+		db.Put(crypto.Keccak256(blob), blob)
+	}
+	return trie.VerifyProof(root, key, db)
+}
+
+// rpcMarshalHeader converts an execution header into the RPC representation of
+// a block, omitting the fields not committed to by the beacon chain.
+func rpcMarshalHeader(header *types.ExecutionHeader) map[string]interface{} {
+	fields := map[string]interface{}{
+		"number":        (*hexutil.Big)(new(big.Int).SetUint64(header.BlockNumber)),
+		"hash":          header.BlockHash,
+		"parentHash":    header.ParentHash,
+		"miner":         header.FeeRecipient,
+		"stateRoot":     header.StateRoot,
+		"receiptsRoot":  header.ReceiptsRoot,
+		"logsBloom":     header.LogsBloom,
+		"mixHash":       header.PrevRandao,
+		"gasLimit":      hexutil.Uint64(header.GasLimit),
+		"gasUsed":       hexutil.Uint64(header.GasUsed),
+		"timestamp":     hexutil.Uint64(header.Timestamp),
+		"extraData":     header.ExtraData,
+		"baseFeePerGas": (*hexutil.Big)(header.BaseFeePerGas),
+		"difficulty":    (*hexutil.Big)(new(big.Int)),
+		"nonce":         ethtypes.BlockNonce{},
+		"sha3Uncles":    ethtypes.EmptyUncleHash,
+	}
+	if header.BlobGasUsed != nil {
+		fields["blobGasUsed"] = hexutil.Uint64(*header.BlobGasUsed)
+		fields["excessBlobGas"] = hexutil.Uint64(*header.ExcessBlobGas)
+	}
+	return fields
+}
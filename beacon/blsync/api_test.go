@@ -0,0 +1,120 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blsync
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+)
+
+func TestVerifyProof(t *testing.T) {
+	var (
+		addr    = common.Address{0x01}
+		missing = common.Address{0x02}
+		slot    = common.Hash{0x03}
+		code    = []byte{0x60, 0x00}
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetBalance(addr, big.NewInt(1000))
+	statedb.SetNonce(addr, 5)
+	statedb.SetCode(addr, code)
+	statedb.SetState(addr, slot, common.Hash{31: 0x2a})
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, statedb.Database(), nil)
+
+	// result creates an eth_getProof response for the account and slot
+	result := func(addr common.Address) *ethapi.AccountResult {
+		accountProof, err := statedb.GetProof(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		storageProof, err := statedb.GetStorageProof(addr, slot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := &ethapi.AccountResult{
+			Address:      addr,
+			AccountProof: toHexSlice(accountProof),
+			Balance:      (*hexutil.Big)(statedb.GetBalance(addr)),
+			CodeHash:     statedb.GetCodeHash(addr),
+			Nonce:        hexutil.Uint64(statedb.GetNonce(addr)),
+			StorageHash:  statedb.StorageTrie(addr).Hash(),
+			StorageProof: []ethapi.StorageResult{{
+				Key:   slot.Hex(),
+				Value: (*hexutil.Big)(statedb.GetState(addr, slot).Big()),
+				Proof: toHexSlice(storageProof),
+			}},
+		}
+		return res
+	}
+	if err := verifyProof(root, result(addr)); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	// Non-existent accounts are proven empty
+	proof, err := statedb.GetProof(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty := &ethapi.AccountResult{
+		Address:      missing,
+		AccountProof: toHexSlice(proof),
+		Balance:      new(hexutil.Big),
+		CodeHash:     crypto.Keccak256Hash(nil),
+		StorageHash:  ethtypes.EmptyRootHash,
+	}
+	if err := verifyProof(root, empty); err != nil {
+		t.Fatalf("proof of missing account rejected: %v", err)
+	}
+	empty.Balance = (*hexutil.Big)(big.NewInt(1))
+	if err := verifyProof(root, empty); err == nil {
+		t.Fatal("forged balance of missing account accepted")
+	}
+	// Forged account and storage fields must be detected
+	for name, forge := range map[string]func(res *ethapi.AccountResult){
+		"balance":     func(res *ethapi.AccountResult) { res.Balance = (*hexutil.Big)(big.NewInt(1001)) },
+		"nonce":       func(res *ethapi.AccountResult) { res.Nonce++ },
+		"codehash":    func(res *ethapi.AccountResult) { res.CodeHash = common.Hash{0xff} },
+		"storagehash": func(res *ethapi.AccountResult) { res.StorageHash = common.Hash{0xff} },
+		"slot":        func(res *ethapi.AccountResult) { res.StorageProof[0].Value = (*hexutil.Big)(big.NewInt(1)) },
+		"proof":       func(res *ethapi.AccountResult) { res.AccountProof = res.AccountProof[:0] },
+	} {
+		res := result(addr)
+		forge(res)
+		if err := verifyProof(root, res); err == nil {
+			t.Errorf("%s: forged proof accepted", name)
+		}
+	}
+}
+
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
+}
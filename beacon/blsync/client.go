@@ -0,0 +1,285 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package blsync implements a node following the chain through a beacon chain
+// light client instead of the execution layer protocols. It serves the subset of
+// the eth namespace which can be answered from verified headers, verifying the
+// state proofs of an untrusted execution RPC provider against them.
+package blsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/beacon/light"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	syncInterval      = 12 * time.Second // Interval of polling the beacon API, one slot
+	requestTimeout    = 30 * time.Second // Timeout of a sync round
+	maxRecentHeaders  = 256              // Number of recent verified execution headers kept
+	maxCatchupBatches = 16               // Limit of update batches requested in a sync round
+)
+
+// Config contains the settings of the beacon light client sync mode.
+type Config struct {
+	Network      string      // Name of the network: mainnet, sepolia or goerli
+	BeaconAPI    string      // URL of the beacon node API serving light client data
+	Checkpoint   common.Hash // Trusted beacon block root to start syncing from
+	ExecutionRPC string      // URL of the untrusted execution RPC serving state proofs
+}
+
+// network is a chain supported by the beacon light client.
+type network struct {
+	beacon  *types.ChainConfig
+	chainID *big.Int
+}
+
+var networks = map[string]network{
+	"mainnet": {types.MainnetConfig, params.MainnetChainConfig.ChainID},
+	"sepolia": {types.SepoliaConfig, params.SepoliaChainConfig.ChainID},
+	"goerli":  {types.GoerliConfig, params.GoerliChainConfig.ChainID},
+}
+
+// Client is a node service following the chain with a beacon light client.
+type Client struct {
+	config  Config
+	chainID *big.Int
+	api     *light.BeaconAPI
+	lc      *light.LightClient
+	exec    *rpc.Client
+
+	lock    sync.RWMutex
+	headers map[common.Hash]*types.ExecutionHeader // Recent verified execution headers
+	numbers map[uint64]common.Hash                 // Hashes of the recent headers by number
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a beacon light client and registers it along with its RPC API on
+// the given node.
+func New(stack *node.Node, config *Config) (*Client, error) {
+	c, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "eth",
+		Service:   &EthAPI{c},
+	}})
+	stack.RegisterLifecycle(c)
+	return c, nil
+}
+
+func newClient(config *Config) (*Client, error) {
+	network, ok := networks[config.Network]
+	if !ok {
+		return nil, fmt.Errorf("beacon light client not supported on network %q", config.Network)
+	}
+	if config.BeaconAPI == "" {
+		return nil, errors.New("beacon API URL not set")
+	}
+	if config.Checkpoint == (common.Hash{}) {
+		return nil, errors.New("checkpoint not set")
+	}
+	if config.ExecutionRPC == "" {
+		return nil, errors.New("execution RPC URL not set")
+	}
+	return &Client{
+		config:  *config,
+		chainID: network.chainID,
+		api:     light.NewBeaconAPI(config.BeaconAPI),
+		lc:      light.NewLightClient(network.beacon),
+		headers: make(map[common.Hash]*types.ExecutionHeader),
+		numbers: make(map[uint64]common.Hash),
+	}, nil
+}
+
+// Start implements node.Lifecycle, connecting to the execution RPC and starting
+// to follow the chain.
+func (c *Client) Start() error {
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	exec, err := rpc.DialContext(c.ctx, c.config.ExecutionRPC)
+	if err != nil {
+		c.cancel()
+		return fmt.Errorf("failed to connect to execution RPC: %v", err)
+	}
+	c.exec = exec
+
+	c.wg.Add(1)
+	go c.loop()
+	log.Info("Started beacon light client", "network", c.config.Network, "checkpoint", c.config.Checkpoint)
+	return nil
+}
+
+// Stop implements node.Lifecycle.
+func (c *Client) Stop() error {
+	c.cancel()
+	c.wg.Wait()
+	c.exec.Close()
+	return nil
+}
+
+// loop bootstraps the light client and keeps it in sync with the beacon API.
+func (c *Client) loop() {
+	defer c.wg.Done()
+
+	var (
+		timer        = time.NewTimer(0)
+		bootstrapped bool
+	)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		ctx, cancel := context.WithTimeout(c.ctx, requestTimeout)
+		var err error
+		if !bootstrapped {
+			if err = c.bootstrap(ctx); err == nil {
+				bootstrapped = true
+			}
+		}
+		if bootstrapped {
+			err = c.sync(ctx)
+		}
+		cancel()
+		if err != nil && c.ctx.Err() == nil {
+			log.Warn("Beacon light client sync failed", "err", err)
+		}
+		timer.Reset(syncInterval)
+	}
+}
+
+// bootstrap initializes the light client from the checkpoint.
+func (c *Client) bootstrap(ctx context.Context) error {
+	bootstrap, err := c.api.Bootstrap(ctx, c.config.Checkpoint)
+	if err != nil {
+		return err
+	}
+	if err := c.lc.Bootstrap(c.config.Checkpoint, bootstrap); err != nil {
+		return err
+	}
+	c.addHeader(c.lc.Finalized().Execution)
+	return nil
+}
+
+// sync catches up with the sync committees, then processes the latest finality
+// and optimistic updates.
+func (c *Client) sync(ctx context.Context) error {
+	for i := 0; i < maxCatchupBatches; i++ {
+		latest, _ := c.lc.LatestCommitteePeriod()
+		updates, err := c.api.Updates(ctx, latest, light.MaxUpdatesPerRequest)
+		if err != nil {
+			return err
+		}
+		for _, update := range updates {
+			if err := c.lc.ProcessUpdate(update); err != nil {
+				return fmt.Errorf("invalid update at slot %d: %v", update.AttestedHeader.Beacon.Slot, err)
+			}
+		}
+		if next, _ := c.lc.LatestCommitteePeriod(); next == latest {
+			break
+		}
+	}
+	finality, err := c.api.FinalityUpdate(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.lc.ProcessUpdate(finality); err != nil {
+		return fmt.Errorf("invalid finality update: %v", err)
+	}
+	optimistic, err := c.api.OptimisticUpdate(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.lc.ProcessUpdate(optimistic); err != nil {
+		return fmt.Errorf("invalid optimistic update: %v", err)
+	}
+	finalized, head := c.lc.Finalized(), c.lc.Optimistic()
+	c.addHeader(finalized.Execution)
+	c.addHeader(head.Execution)
+	log.Debug("Beacon light client synced", "head", head.Execution.BlockNumber, "finalized", finalized.Execution.BlockNumber)
+	return nil
+}
+
+// addHeader records a verified execution header, dropping the old ones.
+func (c *Client) addHeader(header *types.ExecutionHeader) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if old, ok := c.numbers[header.BlockNumber]; ok && old != header.BlockHash {
+		delete(c.headers, old)
+	}
+	c.headers[header.BlockHash] = header
+	c.numbers[header.BlockNumber] = header.BlockHash
+
+	for number, hash := range c.numbers {
+		if number+maxRecentHeaders <= header.BlockNumber {
+			delete(c.numbers, number)
+			delete(c.headers, hash)
+		}
+	}
+}
+
+// header resolves a block number or hash to a verified execution header.
+func (c *Client) header(blockNrOrHash rpc.BlockNumberOrHash) (*types.ExecutionHeader, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		c.lock.RLock()
+		defer c.lock.RUnlock()
+
+		if header, ok := c.headers[hash]; ok {
+			return header, nil
+		}
+		return nil, fmt.Errorf("block %x not available, only recent verified blocks are served", hash)
+	}
+	number, _ := blockNrOrHash.Number()
+	switch number {
+	case rpc.LatestBlockNumber, rpc.PendingBlockNumber:
+		if head := c.lc.Optimistic(); head != nil {
+			return head.Execution, nil
+		}
+		return nil, errors.New("beacon light client not synced")
+	case rpc.FinalizedBlockNumber, rpc.SafeBlockNumber:
+		if finalized := c.lc.Finalized(); finalized != nil {
+			return finalized.Execution, nil
+		}
+		return nil, errors.New("beacon light client not synced")
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if number >= 0 {
+		if hash, ok := c.numbers[uint64(number)]; ok {
+			return c.headers[hash], nil
+		}
+	}
+	return nil, fmt.Errorf("block %d not available, only recent verified blocks are served", number)
+}
@@ -0,0 +1,138 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxUpdatesPerRequest is the maximum number of periods requested at once, as
+// limited by MAX_REQUEST_LIGHT_CLIENT_UPDATES of the beacon API.
+const MaxUpdatesPerRequest = 128
+
+// BeaconAPI is a client of the light client endpoints of the beacon node API.
+// The served data is untrusted, it is verified by the light client.
+type BeaconAPI struct {
+	url    string
+	client *http.Client
+}
+
+// NewBeaconAPI creates a client for the beacon node API at the given URL.
+func NewBeaconAPI(url string) *BeaconAPI {
+	return &BeaconAPI{
+		url:    strings.TrimRight(url, "/"),
+		client: new(http.Client),
+	}
+}
+
+// versionedData is the envelope of the light client data served by the API.
+type versionedData struct {
+	Version string          `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Bootstrap retrieves the bootstrap data of the given checkpoint block root.
+func (api *BeaconAPI) Bootstrap(ctx context.Context, checkpoint common.Hash) (*types.Bootstrap, error) {
+	var (
+		resp      versionedData
+		bootstrap types.Bootstrap
+	)
+	if err := api.get(ctx, "/eth/v1/beacon/light_client/bootstrap/"+checkpoint.Hex(), &resp); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(resp.Data, &bootstrap); err != nil {
+		return nil, fmt.Errorf("invalid bootstrap: %v", err)
+	}
+	return &bootstrap, nil
+}
+
+// Updates retrieves the best updates of the given range of sync committee
+// periods, each proving the committee of the following period.
+func (api *BeaconAPI) Updates(ctx context.Context, start, count uint64) ([]*types.Update, error) {
+	if count > MaxUpdatesPerRequest {
+		count = MaxUpdatesPerRequest
+	}
+	var resp []versionedData
+	query := url.Values{
+		"start_period": {fmt.Sprint(start)},
+		"count":        {fmt.Sprint(count)},
+	}
+	if err := api.get(ctx, "/eth/v1/beacon/light_client/updates?"+query.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	updates := make([]*types.Update, len(resp))
+	for i, data := range resp {
+		updates[i] = new(types.Update)
+		if err := json.Unmarshal(data.Data, updates[i]); err != nil {
+			return nil, fmt.Errorf("invalid update: %v", err)
+		}
+	}
+	return updates, nil
+}
+
+// FinalityUpdate retrieves the latest update proving a finalized header.
+func (api *BeaconAPI) FinalityUpdate(ctx context.Context) (*types.Update, error) {
+	return api.update(ctx, "/eth/v1/beacon/light_client/finality_update")
+}
+
+// OptimisticUpdate retrieves the latest update attesting to the head.
+func (api *BeaconAPI) OptimisticUpdate(ctx context.Context) (*types.Update, error) {
+	return api.update(ctx, "/eth/v1/beacon/light_client/optimistic_update")
+}
+
+func (api *BeaconAPI) update(ctx context.Context, path string) (*types.Update, error) {
+	var (
+		resp   versionedData
+		update types.Update
+	)
+	if err := api.get(ctx, path, &resp); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(resp.Data, &update); err != nil {
+		return nil, fmt.Errorf("invalid update: %v", err)
+	}
+	return &update, nil
+}
+
+// get requests a JSON resource of the API.
+func (api *BeaconAPI) get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("beacon API request %s failed: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
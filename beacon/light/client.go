@@ -0,0 +1,192 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package light implements a beacon chain light client, following the head of
+// the chain through the signatures of the sync committees starting from a
+// trusted checkpoint.
+package light
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	errNotBootstrapped   = errors.New("light client not bootstrapped")
+	errUnknownCommittee  = errors.New("sync committee of signature period unknown")
+	errConflictCommittee = errors.New("conflicting sync committee")
+)
+
+// LightClient verifies light client updates, tracking the known sync committees
+// and the latest finalized and optimistic heads of the chain.
+type LightClient struct {
+	config *types.ChainConfig
+
+	lock       sync.RWMutex
+	committees map[uint64]*types.SyncCommittee // Verified committees by period
+	finalized  *types.LightClientHeader
+	optimistic *types.LightClientHeader
+}
+
+// NewLightClient creates a light client for the given beacon chain.
+func NewLightClient(config *types.ChainConfig) *LightClient {
+	return &LightClient{
+		config:     config,
+		committees: make(map[uint64]*types.SyncCommittee),
+	}
+}
+
+// Bootstrap initializes the light client from the bootstrap data of the trusted
+// checkpoint.
+func (lc *LightClient) Bootstrap(checkpoint common.Hash, bootstrap *types.Bootstrap) error {
+	header := &bootstrap.Header
+	if root := header.Beacon.Hash(); root != checkpoint {
+		return fmt.Errorf("bootstrap header root mismatch: have %x, want %x", root, checkpoint)
+	}
+	if err := header.Verify(); err != nil {
+		return err
+	}
+	committee := bootstrap.CurrentSyncCommittee
+	if committee == nil {
+		return errors.New("bootstrap without sync committee")
+	}
+	if err := committee.Validate(); err != nil {
+		return err
+	}
+	gindex := lc.config.CurrentSyncCommitteeGindex(&header.Beacon)
+	if !types.VerifyProof(header.Beacon.StateRoot, gindex, committee.Root(), bootstrap.CurrentSyncCommitteeBranch) {
+		return errors.New("invalid current sync committee branch")
+	}
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+
+	lc.committees[header.Beacon.SyncPeriod()] = committee
+	lc.finalized, lc.optimistic = header, header
+	log.Info("Bootstrapped beacon light client", "slot", header.Beacon.Slot, "block", header.Execution.BlockNumber)
+	return nil
+}
+
+// ProcessUpdate verifies a light client update signed by a known sync committee,
+// advancing the heads and learning the next sync committee if it is proven.
+func (lc *LightClient) ProcessUpdate(update *types.Update) error {
+	lc.lock.RLock()
+	bootstrapped := lc.finalized != nil
+	committee := lc.committees[types.SyncPeriod(update.SignatureSlot)]
+	lc.lock.RUnlock()
+
+	if !bootstrapped {
+		return errNotBootstrapped
+	}
+	attested := &update.AttestedHeader
+	if update.SignatureSlot <= attested.Beacon.Slot {
+		return fmt.Errorf("signature slot %d not after attested slot %d", update.SignatureSlot, attested.Beacon.Slot)
+	}
+	if committee == nil {
+		return errUnknownCommittee
+	}
+	if err := attested.Verify(); err != nil {
+		return err
+	}
+	if err := committee.VerifySignature(lc.config.SigningRoot(&attested.Beacon, update.SignatureSlot), &update.SyncAggregate); err != nil {
+		return err
+	}
+	// The attested header is valid, verify the proofs against its state
+	if update.FinalizedHeader != nil {
+		finalized := update.FinalizedHeader
+		gindex := lc.config.FinalizedRootGindex(&attested.Beacon)
+		if !types.VerifyProof(attested.Beacon.StateRoot, gindex, finalized.Beacon.Hash(), update.FinalityBranch) {
+			return errors.New("invalid finality branch")
+		}
+		if err := finalized.Verify(); err != nil {
+			return err
+		}
+	}
+	if update.NextSyncCommittee != nil {
+		if err := update.NextSyncCommittee.Validate(); err != nil {
+			return err
+		}
+		gindex := lc.config.NextSyncCommitteeGindex(&attested.Beacon)
+		if !types.VerifyProof(attested.Beacon.StateRoot, gindex, update.NextSyncCommittee.Root(), update.NextSyncCommitteeBranch) {
+			return errors.New("invalid next sync committee branch")
+		}
+	}
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+
+	if next := update.NextSyncCommittee; next != nil {
+		period := attested.Beacon.SyncPeriod() + 1
+		if known, ok := lc.committees[period]; ok {
+			if known.Root() != next.Root() {
+				return errConflictCommittee
+			}
+		} else {
+			lc.committees[period] = next
+			log.Debug("Learned next sync committee", "period", period)
+		}
+	}
+	if finalized := update.FinalizedHeader; finalized != nil && finalized.Beacon.Slot > lc.finalized.Beacon.Slot {
+		lc.finalized = finalized
+		// Committees before the finalized period are not needed any more
+		for period := range lc.committees {
+			if period+1 < finalized.Beacon.SyncPeriod() {
+				delete(lc.committees, period)
+			}
+		}
+	}
+	if attested.Beacon.Slot > lc.optimistic.Beacon.Slot {
+		lc.optimistic = attested
+	}
+	return nil
+}
+
+// Finalized returns the latest finalized header, or nil if not bootstrapped.
+func (lc *LightClient) Finalized() *types.LightClientHeader {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+
+	return lc.finalized
+}
+
+// Optimistic returns the latest header signed by the sync committee, or nil if
+// not bootstrapped.
+func (lc *LightClient) Optimistic() *types.LightClientHeader {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+
+	return lc.optimistic
+}
+
+// LatestCommitteePeriod returns the latest period with a known sync committee.
+func (lc *LightClient) LatestCommitteePeriod() (uint64, bool) {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+
+	var (
+		latest uint64
+		found  bool
+	)
+	for period := range lc.committees {
+		if !found || period > latest {
+			latest, found = period, true
+		}
+	}
+	return latest, found
+}
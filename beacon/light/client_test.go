@@ -0,0 +1,242 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/ssz"
+)
+
+// merkleTree is a sparse merkle tree with the given leaves, filled up with
+// arbitrary chunks to the given depth.
+type merkleTree struct {
+	leaves map[uint64]common.Hash
+	depth  int
+}
+
+func (t *merkleTree) node(gindex uint64) common.Hash {
+	if leaf, ok := t.leaves[gindex]; ok {
+		return leaf
+	}
+	if gindex >= 1<<t.depth {
+		return common.BigToHash(new(big.Int).SetUint64(gindex))
+	}
+	return ssz.Merkleize([][32]byte{t.node(2 * gindex), t.node(2*gindex + 1)}, 0)
+}
+
+func (t *merkleTree) branch(gindex uint64) []common.Hash {
+	var branch []common.Hash
+	for ; gindex > 1; gindex >>= 1 {
+		branch = append(branch, t.node(gindex^1))
+	}
+	return branch
+}
+
+// testChain creates light client data of a beacon chain whose sync committees
+// all consist of the same key.
+type testChain struct {
+	t         *testing.T
+	config    *types.ChainConfig
+	key       *bls.SecretKey
+	committee *types.SyncCommittee
+}
+
+func newTestChain(t *testing.T) *testChain {
+	key, err := bls.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	committee := &types.SyncCommittee{AggregatePubkey: key.PublicKey().Bytes()}
+	for i := 0; i < types.SyncCommitteeSize; i++ {
+		committee.Pubkeys = append(committee.Pubkeys, key.PublicKey().Bytes())
+	}
+	return &testChain{t: t, config: types.MainnetConfig, key: key, committee: committee}
+}
+
+// header creates a light client header of the given slot, with a state
+// containing the given leaves.
+func (c *testChain) header(slot uint64, state map[uint64]common.Hash) *types.LightClientHeader {
+	exec := &types.ExecutionHeader{
+		BlockNumber:   slot,
+		BlockHash:     common.BigToHash(new(big.Int).SetUint64(slot)),
+		ExtraData:     hexutil.Bytes("test"),
+		BaseFeePerGas: (*math.HexOrDecimal256)(big.NewInt(7)),
+	}
+	root, err := exec.Root()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	body := &merkleTree{leaves: map[uint64]common.Hash{types.ExecutionPayloadGindex: root}, depth: 4}
+	stateTree := &merkleTree{leaves: state, depth: 7}
+	return &types.LightClientHeader{
+		Beacon: types.Header{
+			Slot:       slot,
+			ParentRoot: common.Hash{0x01},
+			StateRoot:  stateTree.node(1),
+			BodyRoot:   body.node(1),
+		},
+		Execution:       exec,
+		ExecutionBranch: body.branch(types.ExecutionPayloadGindex),
+	}
+}
+
+func (c *testChain) bootstrap(slot uint64) (common.Hash, *types.Bootstrap) {
+	gindex := c.config.CurrentSyncCommitteeGindex(&types.Header{Slot: slot})
+	state := &merkleTree{leaves: map[uint64]common.Hash{gindex: c.committee.Root()}, depth: 7}
+	header := c.header(slot, state.leaves)
+	return header.Beacon.Hash(), &types.Bootstrap{
+		Header:                     *header,
+		CurrentSyncCommittee:       c.committee,
+		CurrentSyncCommitteeBranch: state.branch(gindex),
+	}
+}
+
+// update creates an update attesting to a header of the given slot, proving
+// the finalized header and the next committee if requested. The update is
+// signed by the given number of committee members.
+func (c *testChain) update(slot uint64, finalized *types.LightClientHeader, next bool, signers int) *types.Update {
+	var (
+		update = new(types.Update)
+		state  = &merkleTree{leaves: make(map[uint64]common.Hash), depth: 7}
+		probe  = &types.Header{Slot: slot}
+	)
+	if finalized != nil {
+		state.leaves[c.config.FinalizedRootGindex(probe)] = finalized.Beacon.Hash()
+	}
+	if next {
+		state.leaves[c.config.NextSyncCommitteeGindex(probe)] = c.committee.Root()
+	}
+	update.AttestedHeader = *c.header(slot, state.leaves)
+	if finalized != nil {
+		update.FinalizedHeader = finalized
+		update.FinalityBranch = state.branch(c.config.FinalizedRootGindex(probe))
+	}
+	if next {
+		update.NextSyncCommittee = c.committee
+		update.NextSyncCommitteeBranch = state.branch(c.config.NextSyncCommitteeGindex(probe))
+	}
+	update.SignatureSlot = slot + 1
+	c.sign(update, c.key, signers)
+	return update
+}
+
+func (c *testChain) sign(update *types.Update, key *bls.SecretKey, signers int) {
+	var (
+		root = c.config.SigningRoot(&update.AttestedHeader.Beacon, update.SignatureSlot)
+		sig  = bls.Sign(key, root[:])
+		sigs = make([]*bls.Signature, signers)
+		bits = make(hexutil.Bytes, types.SyncCommitteeSize/8)
+	)
+	for i := range sigs {
+		sigs[i] = sig
+		bits[i/8] |= 1 << (i % 8)
+	}
+	agg, err := bls.Aggregate(sigs)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	update.SyncAggregate = types.SyncAggregate{Bits: bits, Signature: agg.Bytes()}
+}
+
+func TestLightClient(t *testing.T) {
+	// Run the checks before and after the state growth of Electra
+	period := uint64(types.SlotsPerEpoch * types.EpochsPerSyncCommitteePeriod)
+	t.Run("capella", func(t *testing.T) { testLightClient(t, 200*period+100) })
+	t.Run("electra", func(t *testing.T) { testLightClient(t, 1500*period+100) })
+}
+
+func testLightClient(t *testing.T, start uint64) {
+	var (
+		chain  = newTestChain(t)
+		lc     = NewLightClient(chain.config)
+		period = types.SyncPeriod(start)
+	)
+	if err := lc.ProcessUpdate(chain.update(start+1, nil, false, 400)); err != errNotBootstrapped {
+		t.Fatalf("update before bootstrap: have %v, want %v", err, errNotBootstrapped)
+	}
+	checkpoint, bootstrap := chain.bootstrap(start)
+	if err := lc.Bootstrap(common.Hash{0xff}, bootstrap); err == nil {
+		t.Fatal("bootstrap accepted with wrong checkpoint")
+	}
+	if err := lc.Bootstrap(checkpoint, bootstrap); err != nil {
+		t.Fatalf("bootstrap failed: %v", err)
+	}
+	if latest, _ := lc.LatestCommitteePeriod(); latest != period {
+		t.Fatalf("committee period mismatch: have %d, want %d", latest, period)
+	}
+	// Process a valid update finalizing a header and proving the next committee
+	finalized := chain.header(start+32, nil)
+	if err := lc.ProcessUpdate(chain.update(start+64, finalized, true, 400)); err != nil {
+		t.Fatalf("valid update rejected: %v", err)
+	}
+	if head := lc.Optimistic(); head.Beacon.Slot != start+64 {
+		t.Fatalf("optimistic head mismatch: have %d, want %d", head.Beacon.Slot, start+64)
+	}
+	if head := lc.Finalized(); head.Beacon.Slot != start+32 {
+		t.Fatalf("finalized head mismatch: have %d, want %d", head.Beacon.Slot, start+32)
+	}
+	if latest, _ := lc.LatestCommitteePeriod(); latest != period+1 {
+		t.Fatalf("next committee not learned: have period %d, want %d", latest, period+1)
+	}
+	// Invalid updates must be rejected without changing the heads
+	other, _ := bls.GenerateKey()
+	forged := chain.update(start+96, nil, false, 400)
+	chain.sign(forged, other, 400)
+
+	badFinality := chain.update(start+96, chain.header(start+80, nil), false, 400)
+	badFinality.FinalizedHeader = chain.header(start+81, nil)
+
+	future := chain.update(start+96, nil, false, 400)
+	future.SignatureSlot += 2 * types.SlotsPerEpoch * types.EpochsPerSyncCommitteePeriod
+
+	badExecution := chain.update(start+96, nil, false, 400)
+	badExecution.AttestedHeader.Execution.GasUsed++
+
+	for name, test := range map[string]struct {
+		update *types.Update
+		err    error
+	}{
+		"forged":        {update: forged},
+		"participation": {update: chain.update(start+96, nil, false, 300)},
+		"finality":      {update: badFinality},
+		"committee":     {update: future, err: errUnknownCommittee},
+		"execution":     {update: badExecution},
+	} {
+		err := lc.ProcessUpdate(test.update)
+		if err == nil || (test.err != nil && err != test.err) {
+			t.Errorf("%s: error mismatch: have %v, want %v", name, err, test.err)
+		}
+	}
+	if head := lc.Optimistic(); head.Beacon.Slot != start+64 {
+		t.Fatalf("optimistic head changed by invalid update: have %d", head.Beacon.Slot)
+	}
+	// Updates signed by the learned committee are accepted in the next period
+	next := start + types.SlotsPerEpoch*types.EpochsPerSyncCommitteePeriod
+	if err := lc.ProcessUpdate(chain.update(next, nil, false, 512)); err != nil {
+		t.Fatalf("update of next period rejected: %v", err)
+	}
+	if head := lc.Optimistic(); head.Beacon.Slot != next {
+		t.Fatalf("optimistic head mismatch: have %d, want %d", head.Beacon.Slot, next)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/bls"
+	"github.com/ethereum/go-ethereum/ssz"
+)
+
+const (
+	pubkeyLength    = 48
+	signatureLength = 96
+)
+
+var errInsufficientParticipants = errors.New("insufficient sync committee participation")
+
+// SyncCommittee is the set of validators signing the beacon headers during a
+// sync committee period.
+type SyncCommittee struct {
+	Pubkeys         []hexutil.Bytes `json:"pubkeys"`
+	AggregatePubkey hexutil.Bytes   `json:"aggregate_pubkey"`
+
+	keysOnce sync.Once
+	keys     []*bls.PublicKey
+	keysErr  error
+}
+
+// Validate checks the sizes of the committee and its keys.
+func (sc *SyncCommittee) Validate() error {
+	if len(sc.Pubkeys) != SyncCommitteeSize {
+		return fmt.Errorf("invalid sync committee size %d", len(sc.Pubkeys))
+	}
+	for i, key := range sc.Pubkeys {
+		if len(key) != pubkeyLength {
+			return fmt.Errorf("invalid length of sync committee key %d", i)
+		}
+	}
+	if len(sc.AggregatePubkey) != pubkeyLength {
+		return errors.New("invalid length of aggregate sync committee key")
+	}
+	return nil
+}
+
+// Root returns the hash tree root of the committee. The committee must be valid.
+func (sc *SyncCommittee) Root() common.Hash {
+	roots := make([][32]byte, len(sc.Pubkeys))
+	for i, key := range sc.Pubkeys {
+		roots[i] = ssz.VectorRoot(key)
+	}
+	return ssz.Merkleize([][32]byte{
+		ssz.Merkleize(roots, SyncCommitteeSize),
+		ssz.VectorRoot(sc.AggregatePubkey),
+	}, 0)
+}
+
+// publicKeys returns the deserialized keys of the committee members.
+func (sc *SyncCommittee) publicKeys() ([]*bls.PublicKey, error) {
+	sc.keysOnce.Do(func() {
+		keys := make([]*bls.PublicKey, len(sc.Pubkeys))
+		for i, key := range sc.Pubkeys {
+			if keys[i], sc.keysErr = bls.PublicKeyFromBytes(key); sc.keysErr != nil {
+				sc.keysErr = fmt.Errorf("sync committee key %d: %v", i, sc.keysErr)
+				return
+			}
+		}
+		sc.keys = keys
+	})
+	return sc.keys, sc.keysErr
+}
+
+// VerifySignature checks that a supermajority of the committee signed the
+// message with the aggregate signature.
+func (sc *SyncCommittee) VerifySignature(signingRoot common.Hash, aggregate *SyncAggregate) error {
+	if len(aggregate.Bits) != SyncCommitteeSize/8 || len(aggregate.Signature) != signatureLength {
+		return errors.New("invalid sync aggregate")
+	}
+	if aggregate.Participants()*3 < SyncCommitteeSize*2 {
+		return errInsufficientParticipants
+	}
+	keys, err := sc.publicKeys()
+	if err != nil {
+		return err
+	}
+	signers := make([]*bls.PublicKey, 0, SyncCommitteeSize)
+	for i, key := range keys {
+		if aggregate.Bits[i/8]&(1<<(i%8)) != 0 {
+			signers = append(signers, key)
+		}
+	}
+	sig, err := bls.SignatureFromBytes(aggregate.Signature)
+	if err != nil {
+		return err
+	}
+	if !bls.FastAggregateVerify(signers, signingRoot[:], sig) {
+		return errors.New("invalid sync committee signature")
+	}
+	return nil
+}
+
+// SyncAggregate is the aggregate signature of a sync committee, along with the
+// bitfield of the participating members.
+type SyncAggregate struct {
+	Bits      hexutil.Bytes `json:"sync_committee_bits"`
+	Signature hexutil.Bytes `json:"sync_committee_signature"`
+}
+
+// Participants returns the number of committee members who signed.
+func (a *SyncAggregate) Participants() int {
+	var count int
+	for _, b := range a.Bits {
+		count += bits.OnesCount8(b)
+	}
+	return count
+}
+
+// Bootstrap is the light client bootstrap data of a trusted checkpoint: its
+// header and the sync committee of its period, proven against its state.
+type Bootstrap struct {
+	Header                     LightClientHeader `json:"header"`
+	CurrentSyncCommittee       *SyncCommittee    `json:"current_sync_committee"`
+	CurrentSyncCommitteeBranch []common.Hash     `json:"current_sync_committee_branch"`
+}
+
+// Update is a light client update: a header attested by the sync committee,
+// optionally proving the finalized header and the next sync committee.
+type Update struct {
+	AttestedHeader          LightClientHeader  `json:"attested_header"`
+	NextSyncCommittee       *SyncCommittee     `json:"next_sync_committee,omitempty"`
+	NextSyncCommitteeBranch []common.Hash      `json:"next_sync_committee_branch,omitempty"`
+	FinalizedHeader         *LightClientHeader `json:"finalized_header,omitempty"`
+	FinalityBranch          []common.Hash      `json:"finality_branch,omitempty"`
+	SyncAggregate           SyncAggregate      `json:"sync_aggregate"`
+	SignatureSlot           uint64             `json:"signature_slot,string"`
+}
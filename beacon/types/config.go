@@ -0,0 +1,188 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ssz"
+)
+
+const (
+	SlotsPerEpoch                = 32  // Slots in an epoch
+	EpochsPerSyncCommitteePeriod = 256 // Epochs a sync committee is active for
+	SyncCommitteeSize            = 512 // Members of a sync committee
+
+	// ExecutionPayloadGindex is the generalized index of the execution payload in
+	// the beacon block body.
+	ExecutionPayloadGindex = 25
+)
+
+// Generalized indices of the fields of the beacon state proven to light clients,
+// which changed with the growth of the state in Electra.
+const (
+	finalizedRootGindex               = 105
+	currentSyncCommitteeGindex        = 54
+	nextSyncCommitteeGindex           = 55
+	finalizedRootGindexElectra        = 169
+	currentSyncCommitteeGindexElectra = 86
+	nextSyncCommitteeGindexElectra    = 87
+)
+
+// domainSyncCommittee is the signature domain type of sync committee messages.
+const domainSyncCommittee = 7
+
+// Fork is a beacon chain fork, changing the fork version of the signatures.
+type Fork struct {
+	Name    string
+	Epoch   uint64
+	Version [4]byte
+}
+
+// ChainConfig contains the parameters of a beacon chain needed to verify the
+// signatures of its sync committees.
+type ChainConfig struct {
+	GenesisValidatorsRoot common.Hash
+	Forks                 []Fork // Ordered by epoch
+}
+
+// Beacon chain configurations of the supported networks.
+var (
+	MainnetConfig = &ChainConfig{
+		GenesisValidatorsRoot: common.HexToHash("0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"),
+		Forks: []Fork{
+			{Name: "phase0", Epoch: 0, Version: [4]byte{0x00, 0x00, 0x00, 0x00}},
+			{Name: "altair", Epoch: 74240, Version: [4]byte{0x01, 0x00, 0x00, 0x00}},
+			{Name: "bellatrix", Epoch: 144896, Version: [4]byte{0x02, 0x00, 0x00, 0x00}},
+			{Name: "capella", Epoch: 194048, Version: [4]byte{0x03, 0x00, 0x00, 0x00}},
+			{Name: "deneb", Epoch: 269568, Version: [4]byte{0x04, 0x00, 0x00, 0x00}},
+			{Name: "electra", Epoch: 364032, Version: [4]byte{0x05, 0x00, 0x00, 0x00}},
+			{Name: "fulu", Epoch: 411392, Version: [4]byte{0x06, 0x00, 0x00, 0x00}},
+		},
+	}
+	SepoliaConfig = &ChainConfig{
+		GenesisValidatorsRoot: common.HexToHash("0xd8ea171f3c94aea21ebc42a1ed61052acf3f9209c00e4efbaaddac09ed9b8078"),
+		Forks: []Fork{
+			{Name: "phase0", Epoch: 0, Version: [4]byte{0x90, 0x00, 0x00, 0x69}},
+			{Name: "altair", Epoch: 50, Version: [4]byte{0x90, 0x00, 0x00, 0x70}},
+			{Name: "bellatrix", Epoch: 100, Version: [4]byte{0x90, 0x00, 0x00, 0x71}},
+			{Name: "capella", Epoch: 56832, Version: [4]byte{0x90, 0x00, 0x00, 0x72}},
+			{Name: "deneb", Epoch: 132608, Version: [4]byte{0x90, 0x00, 0x00, 0x73}},
+			{Name: "electra", Epoch: 222464, Version: [4]byte{0x90, 0x00, 0x00, 0x74}},
+			{Name: "fulu", Epoch: 272640, Version: [4]byte{0x90, 0x00, 0x00, 0x75}},
+		},
+	}
+	GoerliConfig = &ChainConfig{
+		GenesisValidatorsRoot: common.HexToHash("0x043db0d9a83813551ee2f33450d23797757d430911a9320530ad8a0eabc43efb"),
+		Forks: []Fork{
+			{Name: "phase0", Epoch: 0, Version: [4]byte{0x00, 0x00, 0x10, 0x20}},
+			{Name: "altair", Epoch: 36660, Version: [4]byte{0x01, 0x00, 0x10, 0x20}},
+			{Name: "bellatrix", Epoch: 112260, Version: [4]byte{0x02, 0x00, 0x10, 0x20}},
+			{Name: "capella", Epoch: 162304, Version: [4]byte{0x03, 0x00, 0x10, 0x20}},
+			{Name: "deneb", Epoch: 231680, Version: [4]byte{0x04, 0x00, 0x10, 0x20}},
+		},
+	}
+)
+
+// fork returns the fork active at the given epoch.
+func (c *ChainConfig) fork(epoch uint64) Fork {
+	i := sort.Search(len(c.Forks), func(i int) bool { return c.Forks[i].Epoch > epoch })
+	if i == 0 {
+		return Fork{}
+	}
+	return c.Forks[i-1]
+}
+
+// isElectra reports whether the Electra fork is active at the given epoch.
+func (c *ChainConfig) isElectra(epoch uint64) bool {
+	for _, f := range c.Forks {
+		if f.Name == "electra" {
+			return epoch >= f.Epoch
+		}
+	}
+	return false
+}
+
+// SigningRoot returns the message signed by the sync committee attesting to the
+// given header in the given slot.
+func (c *ChainConfig) SigningRoot(header *Header, signatureSlot uint64) common.Hash {
+	// The fork version is the one of the slot before the signature
+	slot := signatureSlot
+	if slot > 0 {
+		slot--
+	}
+	var (
+		fork    = c.fork(slot / SlotsPerEpoch)
+		version [32]byte
+	)
+	copy(version[:], fork.Version[:])
+	forkDataRoot := ssz.Merkleize([][32]byte{version, c.GenesisValidatorsRoot}, 0)
+
+	var domain [32]byte
+	domain[0] = domainSyncCommittee
+	copy(domain[4:], forkDataRoot[:28])
+	return ssz.Merkleize([][32]byte{header.Hash(), domain}, 0)
+}
+
+// FinalizedRootGindex returns the generalized index of the finalized checkpoint
+// root in the state of the given header.
+func (c *ChainConfig) FinalizedRootGindex(header *Header) uint64 {
+	if c.isElectra(header.Epoch()) {
+		return finalizedRootGindexElectra
+	}
+	return finalizedRootGindex
+}
+
+// CurrentSyncCommitteeGindex returns the generalized index of the current sync
+// committee in the state of the given header.
+func (c *ChainConfig) CurrentSyncCommitteeGindex(header *Header) uint64 {
+	if c.isElectra(header.Epoch()) {
+		return currentSyncCommitteeGindexElectra
+	}
+	return currentSyncCommitteeGindex
+}
+
+// NextSyncCommitteeGindex returns the generalized index of the next sync
+// committee in the state of the given header.
+func (c *ChainConfig) NextSyncCommitteeGindex(header *Header) uint64 {
+	if c.isElectra(header.Epoch()) {
+		return nextSyncCommitteeGindexElectra
+	}
+	return nextSyncCommitteeGindex
+}
+
+// VerifyProof checks a merkle branch proving that leaf is at the generalized
+// index of the tree with the given root.
+func VerifyProof(root common.Hash, gindex uint64, leaf common.Hash, branch []common.Hash) bool {
+	if gindex == 0 {
+		return false
+	}
+	node := [32]byte(leaf)
+	for _, sibling := range branch {
+		if gindex == 1 {
+			return false
+		}
+		if gindex&1 == 0 {
+			node = ssz.Merkleize([][32]byte{node, sibling}, 0)
+		} else {
+			node = ssz.Merkleize([][32]byte{sibling, node}, 0)
+		}
+		gindex >>= 1
+	}
+	return gindex == 1 && common.Hash(node) == root
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package types implements the beacon chain data structures needed by a light
+// client following the chain through sync committee signatures: block headers,
+// sync committees and the light client messages served by the beacon API.
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ssz"
+)
+
+// Header is a beacon block header.
+type Header struct {
+	Slot          uint64      `json:"slot,string"`
+	ProposerIndex uint64      `json:"proposer_index,string"`
+	ParentRoot    common.Hash `json:"parent_root"`
+	StateRoot     common.Hash `json:"state_root"`
+	BodyRoot      common.Hash `json:"body_root"`
+}
+
+// Hash returns the hash tree root of the header, which is the block root.
+func (h *Header) Hash() common.Hash {
+	return ssz.Merkleize([][32]byte{
+		ssz.Uint64Root(h.Slot),
+		ssz.Uint64Root(h.ProposerIndex),
+		h.ParentRoot,
+		h.StateRoot,
+		h.BodyRoot,
+	}, 0)
+}
+
+// Epoch returns the epoch of the header.
+func (h *Header) Epoch() uint64 {
+	return h.Slot / SlotsPerEpoch
+}
+
+// SyncPeriod returns the sync committee period of the header.
+func (h *Header) SyncPeriod() uint64 {
+	return SyncPeriod(h.Slot)
+}
+
+// ExecutionHeader is the header of the execution payload of a beacon block, as
+// included in the light client headers since Capella.
+type ExecutionHeader struct {
+	ParentHash       common.Hash           `json:"parent_hash"`
+	FeeRecipient     common.Address        `json:"fee_recipient"`
+	StateRoot        common.Hash           `json:"state_root"`
+	ReceiptsRoot     common.Hash           `json:"receipts_root"`
+	LogsBloom        types.Bloom           `json:"logs_bloom"`
+	PrevRandao       common.Hash           `json:"prev_randao"`
+	BlockNumber      uint64                `json:"block_number,string"`
+	GasLimit         uint64                `json:"gas_limit,string"`
+	GasUsed          uint64                `json:"gas_used,string"`
+	Timestamp        uint64                `json:"timestamp,string"`
+	ExtraData        hexutil.Bytes         `json:"extra_data"`
+	BaseFeePerGas    *math.HexOrDecimal256 `json:"base_fee_per_gas"`
+	BlockHash        common.Hash           `json:"block_hash"`
+	TransactionsRoot common.Hash           `json:"transactions_root"`
+	WithdrawalsRoot  common.Hash           `json:"withdrawals_root"`
+	BlobGasUsed      *math.HexOrDecimal64  `json:"blob_gas_used,omitempty"`   // Since Deneb
+	ExcessBlobGas    *math.HexOrDecimal64  `json:"excess_blob_gas,omitempty"` // Since Deneb
+}
+
+// maxExtraDataBytes is the limit of the extra data of execution payloads.
+const maxExtraDataBytes = 32
+
+var errInvalidExecutionHeader = errors.New("invalid execution header")
+
+// Root returns the hash tree root of the execution header.
+func (h *ExecutionHeader) Root() (common.Hash, error) {
+	if len(h.ExtraData) > maxExtraDataBytes || h.BaseFeePerGas == nil || (h.BlobGasUsed == nil) != (h.ExcessBlobGas == nil) {
+		return common.Hash{}, errInvalidExecutionHeader
+	}
+	var feeRecipient, baseFee [32]byte
+	copy(feeRecipient[:], h.FeeRecipient[:])
+	fee, err := ssz.Uint256((*big.Int)(h.BaseFeePerGas))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	copy(baseFee[:], fee)
+
+	fields := [][32]byte{
+		h.ParentHash,
+		feeRecipient,
+		h.StateRoot,
+		h.ReceiptsRoot,
+		ssz.VectorRoot(h.LogsBloom[:]),
+		h.PrevRandao,
+		ssz.Uint64Root(h.BlockNumber),
+		ssz.Uint64Root(h.GasLimit),
+		ssz.Uint64Root(h.GasUsed),
+		ssz.Uint64Root(h.Timestamp),
+		ssz.ByteListRoot(h.ExtraData, maxExtraDataBytes),
+		baseFee,
+		h.BlockHash,
+		h.TransactionsRoot,
+		h.WithdrawalsRoot,
+	}
+	if h.BlobGasUsed != nil {
+		fields = append(fields, ssz.Uint64Root(uint64(*h.BlobGasUsed)), ssz.Uint64Root(uint64(*h.ExcessBlobGas)))
+	}
+	return ssz.Merkleize(fields, 0), nil
+}
+
+// LightClientHeader is a beacon header along with the header of its execution
+// payload, proven against the block body.
+type LightClientHeader struct {
+	Beacon          Header           `json:"beacon"`
+	Execution       *ExecutionHeader `json:"execution"`
+	ExecutionBranch []common.Hash    `json:"execution_branch"`
+}
+
+// Verify checks that the execution header belongs to the beacon header.
+func (h *LightClientHeader) Verify() error {
+	if h.Execution == nil {
+		return fmt.Errorf("header at slot %d has no execution header", h.Beacon.Slot)
+	}
+	root, err := h.Execution.Root()
+	if err != nil {
+		return err
+	}
+	if !VerifyProof(h.Beacon.BodyRoot, ExecutionPayloadGindex, root, h.ExecutionBranch) {
+		return fmt.Errorf("invalid execution branch of header at slot %d", h.Beacon.Slot)
+	}
+	return nil
+}
+
+// SyncPeriod returns the sync committee period containing the slot.
+func SyncPeriod(slot uint64) uint64 {
+	return slot / (SlotsPerEpoch * EpochsPerSyncCommitteePeriod)
+}
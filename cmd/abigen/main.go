@@ -27,7 +27,6 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common/compiler"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/urfave/cli/v2"
@@ -59,6 +58,10 @@ var (
 		Name:  "combined-json",
 		Usage: "Path to the combined-json file generated by compiler, - for STDIN",
 	}
+	artifactFlag = &cli.StringSliceFlag{
+		Name:  "artifact",
+		Usage: "Path to a Foundry or Hardhat contract artifact json, may be repeated",
+	}
 	excFlag = &cli.StringFlag{
 		Name:  "exc",
 		Usage: "Comma separated types to exclude from binding",
@@ -90,6 +93,7 @@ func init() {
 		binFlag,
 		typeFlag,
 		jsonFlag,
+		artifactFlag,
 		excFlag,
 		pkgFlag,
 		outFlag,
@@ -100,7 +104,7 @@ func init() {
 }
 
 func abigen(c *cli.Context) error {
-	utils.CheckExclusive(c, abiFlag, jsonFlag) // Only one source can be selected.
+	utils.CheckExclusive(c, abiFlag, jsonFlag, artifactFlag) // Only one source can be selected.
 
 	if c.String(pkgFlag.Name) == "" {
 		utils.Fatalf("No destination package specified (--pkg)")
@@ -186,6 +190,26 @@ func abigen(c *cli.Context) error {
 				utils.Fatalf("Failed to read contract information from json output: %v", err)
 			}
 		}
+		// Artifacts may link libraries, which have to be bound too for the deploy
+		// methods to deploy them, so keep track of them for verification.
+		linked := make(map[string]string)
+		if c.IsSet(artifactFlag.Name) {
+			contracts = make(map[string]*compiler.Contract)
+			for _, path := range c.StringSlice(artifactFlag.Name) {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					utils.Fatalf("Failed to read artifact: %v", err)
+				}
+				artifact, err := compiler.ParseArtifact(data, path)
+				if err != nil {
+					utils.Fatalf("Failed to parse artifact %s: %v", path, err)
+				}
+				contracts[artifact.Name] = artifact.Contract
+				for pattern, name := range artifact.Libraries {
+					linked[pattern] = name
+				}
+			}
+		}
 		// Gather all non-excluded contract for binding
 		for name, contract := range contracts {
 			if exclude[strings.ToLower(name)] {
@@ -205,8 +229,23 @@ func abigen(c *cli.Context) error {
 			// hex encoding of the keccak256 hash of the fully qualified library name.
 			// Note that the fully qualified library name is the path of its source
 			// file and the library name separated by ":".
-			libPattern := crypto.Keccak256Hash([]byte(name)).String()[2:36] // the first 2 chars are 0x
-			libs[libPattern] = nameParts[len(nameParts)-1]
+			libs[compiler.LibraryPlaceholder(name)] = nameParts[len(nameParts)-1]
+		}
+		// Resolve the libraries by contract name too, since artifacts may not
+		// know the source path their placeholders are derived from.
+		for pattern, name := range linked {
+			if _, ok := libs[pattern]; ok {
+				continue
+			}
+			nameParts := strings.Split(name, ":")
+			for _, kind := range types {
+				if kind == nameParts[len(nameParts)-1] {
+					libs[pattern] = kind
+				}
+			}
+			if _, ok := libs[pattern]; !ok {
+				utils.Fatalf("Linked library %s not bound, add its artifact (--%s)", name, artifactFlag.Name)
+			}
 		}
 	}
 	// Extract all aliases from the flags
@@ -34,7 +34,9 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/qrwallet"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -115,6 +117,10 @@ var (
 		Usage: "File used for writing new 4byte-identifiers submitted via API",
 		Value: "./4byte-custom.json",
 	}
+	abiFlag = &cli.StringSliceFlag{
+		Name:  "abi",
+		Usage: "Contract ABI file to decode transactions with, registered under its file name. Prefix with <address>: to bind it to a deployed contract",
+	}
 	auditLogFlag = &cli.StringFlag{
 		Name:  "auditlog",
 		Usage: "File used to emit audit logs. Set to \"\" to disable",
@@ -122,7 +128,7 @@ var (
 	}
 	ruleFlag = &cli.StringFlag{
 		Name:  "rules",
-		Usage: "Path to the rule file to auto-authorize requests with",
+		Usage: "Path to the rule file to auto-authorize requests with (JavaScript, or a YAML/JSON policy)",
 	}
 	stdiouiFlag = &cli.BoolFlag{
 		Name: "stdio-ui",
@@ -206,6 +212,22 @@ The newaccount command creates a new keystore-backed account. It is a convenienc
 which can be used in lieu of an external UI.`,
 	}
 
+	importSignaturesCommand = &cli.Command{
+		Action:    importSignatures,
+		Name:      "import-signatures",
+		Usage:     "Import method and event signatures into the custom 4byte database",
+		ArgsUsage: "<file>",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			customDBFlag,
+			acceptFlag,
+		},
+		Description: `
+The import-signatures command adds the signatures of a dump to the custom 4byte database. The dump
+is either a JSON object mapping hex identifiers to signatures, a JSON array of signatures, or plain
+text with one signature per line. Event signatures in the latter two are prefixed by 'event '.`,
+	}
+
 	gendocCommand = &cli.Command{
 		Action: GenDoc,
 		Name:   "gendoc",
@@ -233,6 +255,7 @@ func init() {
 		utils.LightKDFFlag,
 		utils.NoUSBFlag,
 		utils.SmartCardDaemonPathFlag,
+		utils.QRWalletKeysFlag,
 		utils.HTTPListenAddrFlag,
 		utils.HTTPVirtualHostsFlag,
 		utils.IPCDisabledFlag,
@@ -241,6 +264,7 @@ func init() {
 		rpcPortFlag,
 		signerSecretFlag,
 		customDBFlag,
+		abiFlag,
 		auditLogFlag,
 		ruleFlag,
 		stdiouiFlag,
@@ -254,6 +278,7 @@ func init() {
 		setCredentialCommand,
 		delCredentialCommand,
 		newAccountCommand,
+		importSignaturesCommand,
 		gendocCommand,
 	}
 }
@@ -440,6 +465,31 @@ func newAccount(c *cli.Context) error {
 	return err
 }
 
+func importSignatures(c *cli.Context) error {
+	if c.NArg() < 1 {
+		utils.Fatalf("This command requires a file to be passed as an argument")
+	}
+	if err := initialize(c); err != nil {
+		return err
+	}
+	db, err := fourbyte.NewWithFile(c.String(customDBFlag.Name))
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	dump, err := os.Open(c.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to open signature dump: %v", err)
+	}
+	defer dump.Close()
+
+	added, err := db.Import(dump)
+	if err != nil {
+		utils.Fatalf("Failed to import signatures: %v", err)
+	}
+	log.Info("Imported signatures", "added", added, "file", c.String(customDBFlag.Name))
+	return nil
+}
+
 func initialize(c *cli.Context) error {
 	// Set up the logger to print everything
 	logOutput := os.Stdout
@@ -465,6 +515,35 @@ func initialize(c *cli.Context) error {
 	return nil
 }
 
+// loadABIRegistry loads the contract ABI files given as [<address>:]<file>, each
+// registered under its file name without extension.
+func loadABIRegistry(specs []string) (*abi.Registry, error) {
+	registry := abi.NewRegistry()
+	for _, spec := range specs {
+		var address *common.Address
+		if i := strings.Index(spec, ":"); i > 0 && common.IsHexAddress(spec[:i]) {
+			addr := common.HexToAddress(spec[:i])
+			address, spec = &addr, spec[i+1:]
+		}
+		file, err := os.Open(spec)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(spec), filepath.Ext(spec))
+		err = registry.Load(name, file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		if address != nil {
+			if err := registry.Bind(*address, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return registry, nil
+}
+
 // ipcEndpoint resolves an IPC endpoint based on a configured value, taking into
 // account the set data folders as well as the designated platform we're currently
 // running on.
@@ -513,6 +592,15 @@ func signer(c *cli.Context) error {
 	embeds, locals := db.Size()
 	log.Info("Loaded 4byte database", "embeds", embeds, "locals", locals, "local", fourByteLocal)
 
+	if specs := c.StringSlice(abiFlag.Name); len(specs) > 0 {
+		registry, err := loadABIRegistry(specs)
+		if err != nil {
+			utils.Fatalf("Failed to load contract ABIs: %v", err)
+		}
+		db.SetRegistry(registry)
+		log.Info("Loaded contract ABIs", "contracts", strings.Join(registry.Contracts(), ","))
+	}
+
 	var (
 		api       core.ExternalAPI
 		pwStorage storage.Storage = &storage.NoStorage{}
@@ -544,6 +632,14 @@ func signer(c *cli.Context) error {
 				storedShasum, _ := configStorage.Get("ruleset_sha256")
 				if storedShasum != foundShaSum {
 					log.Warn("Rule hash not attested, disabling", "hash", foundShaSum, "attested", storedShasum)
+				} else if ext := strings.ToLower(filepath.Ext(ruleFile)); ext == ".yaml" || ext == ".yml" || ext == ".json" {
+					// Initialize the declarative policy
+					policy, err := rules.NewPolicyEvaluator(ui, jsStorage, ruleJS)
+					if err != nil {
+						utils.Fatalf(err.Error())
+					}
+					ui = policy
+					log.Info("Policy engine configured", "file", ruleFile)
 				} else {
 					// Initialize rules
 					ruleEngine, err := rules.NewRuleEvaluator(ui, jsStorage)
@@ -564,10 +660,19 @@ func signer(c *cli.Context) error {
 		advanced = c.Bool(advancedMode.Name)
 		nousb    = c.Bool(utils.NoUSBFlag.Name)
 		scpath   = c.String(utils.SmartCardDaemonPathFlag.Name)
+		qrkeys   = c.String(utils.QRWalletKeysFlag.Name)
 	)
 	log.Info("Starting signer", "chainid", chainId, "keystore", ksLoc,
 		"light-kdf", lightKdf, "advanced", advanced)
 	am := core.StartClefAccountManager(ksLoc, nousb, lightKdf, scpath)
+	if qrkeys != "" {
+		// Import the air-gapped wallets, exchanging sign requests via the UI
+		if qrhub, err := qrwallet.NewHubFromFile(qrkeys, core.NewUITransport(ui)); err != nil {
+			log.Warn("Failed to import QR wallets, disabling", "err", err)
+		} else {
+			am.AddBackend(qrhub)
+		}
+	}
 	apiImpl := core.NewSignerAPI(am, chainId, nousb, ui, db, advanced, pwStorage)
 
 	// Establish the bidirectional communication, by creating a new UI backend and registering
@@ -592,13 +697,17 @@ func signer(c *cli.Context) error {
 			Namespace: "account",
 			Service:   api,
 		},
+		{
+			Namespace: "signer",
+			Service:   fourbyte.NewAPI(db),
+		},
 	}
 	if c.Bool(utils.HTTPEnabledFlag.Name) {
 		vhosts := utils.SplitAndTrim(c.String(utils.HTTPVirtualHostsFlag.Name))
 		cors := utils.SplitAndTrim(c.String(utils.HTTPCORSDomainFlag.Name))
 
 		srv := rpc.NewServer()
-		err := node.RegisterApis(rpcAPI, []string{"account"}, srv)
+		err := node.RegisterApis(rpcAPI, []string{"account", "signer"}, srv)
 		if err != nil {
 			utils.Fatalf("Could not register API: %w", err)
 		}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"strings"
@@ -26,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/p2p/crawler"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params"
@@ -187,9 +189,11 @@ func discv4ResolveJSON(ctx *cli.Context) error {
 	// Run the crawler.
 	disc := startV4(ctx)
 	defer disc.Close()
-	c := newCrawler(inputSet, disc, enode.IterNodes(nodeargs))
-	c.revalidateInterval = 0
-	output := c.run(0)
+	c := crawler.New(crawler.NodeSet(inputSet), crawler.Config{
+		Resolver:  disc,
+		Iterators: []enode.Iterator{enode.IterNodes(nodeargs)},
+	})
+	output := nodeSet(c.Run(context.Background()))
 	writeNodesJSON(nodesFile, output)
 	return nil
 }
@@ -206,9 +210,13 @@ func discv4Crawl(ctx *cli.Context) error {
 
 	disc := startV4(ctx)
 	defer disc.Close()
-	c := newCrawler(inputSet, disc, disc.RandomNodes())
-	c.revalidateInterval = 10 * time.Minute
-	output := c.run(ctx.Duration(crawlTimeoutFlag.Name))
+	c := crawler.New(crawler.NodeSet(inputSet), crawler.Config{
+		Resolver:           disc,
+		Iterators:          []enode.Iterator{disc.RandomNodes()},
+		RevalidateInterval: 10 * time.Minute,
+		Timeout:            ctx.Duration(crawlTimeoutFlag.Name),
+	})
+	output := nodeSet(c.Run(context.Background()))
 	writeNodesJSON(nodesFile, output)
 	return nil
 }
@@ -17,12 +17,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/v5test"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p/crawler"
 	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/urfave/cli/v2"
 )
 
@@ -109,9 +112,13 @@ func discv5Crawl(ctx *cli.Context) error {
 
 	disc := startV5(ctx)
 	defer disc.Close()
-	c := newCrawler(inputSet, disc, disc.RandomNodes())
-	c.revalidateInterval = 10 * time.Minute
-	output := c.run(ctx.Duration(crawlTimeoutFlag.Name))
+	c := crawler.New(crawler.NodeSet(inputSet), crawler.Config{
+		Resolver:           disc,
+		Iterators:          []enode.Iterator{disc.RandomNodes()},
+		RevalidateInterval: 10 * time.Minute,
+		Timeout:            ctx.Duration(crawlTimeoutFlag.Name),
+	})
+	output := nodeSet(c.Run(context.Background()))
 	writeNodesJSON(nodesFile, output)
 	return nil
 }
@@ -22,9 +22,9 @@ import (
 	"fmt"
 	"os"
 	"sort"
-	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p/crawler"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 )
 
@@ -34,19 +34,7 @@ const jsonIndent = "    "
 // as a JSON object.
 type nodeSet map[enode.ID]nodeJSON
 
-type nodeJSON struct {
-	Seq uint64      `json:"seq"`
-	N   *enode.Node `json:"record"`
-
-	// The score tracks how many liveness checks were performed. It is incremented by one
-	// every time the node passes a check, and halved every time it doesn't.
-	Score int `json:"score,omitempty"`
-	// These two track the time of last successful contact.
-	FirstResponse time.Time `json:"firstResponse,omitempty"`
-	LastResponse  time.Time `json:"lastResponse,omitempty"`
-	// This one tracks the time of our last attempt to contact the node.
-	LastCheck time.Time `json:"lastCheck,omitempty"`
-}
+type nodeJSON = crawler.NodeRecord
 
 func loadNodesJSON(file string) nodeSet {
 	var nodes nodeSet
@@ -0,0 +1,119 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/urfave/cli/v2"
+)
+
+var bundleCommand = &cli.Command{
+	Action:    bundleCmd,
+	Name:      "bundle",
+	Usage:     "re-executes a transaction from an execution bundle",
+	ArgsUsage: "<file>",
+	Description: `
+The bundle command replays a transaction using only the contents of an execution
+bundle, as exported by the debug_executionBundle RPC method, and checks that the
+outcome matches the one recorded on the originating node.`,
+}
+
+// BundleRunResult contains the outcome of re-executing an execution bundle, any
+// mismatch with the recorded result and a dump of the final state if requested.
+type BundleRunResult struct {
+	Pass   bool               `json:"pass"`
+	Error  string             `json:"error,omitempty"`
+	Result *core.BundleResult `json:"result,omitempty"`
+	State  *state.Dump        `json:"state,omitempty"`
+}
+
+func bundleCmd(ctx *cli.Context) error {
+	if len(ctx.Args().First()) == 0 {
+		return errors.New("path-to-bundle argument required")
+	}
+	// Configure the go-ethereum logger
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(ctx.Int(VerbosityFlag.Name)))
+	log.Root().SetHandler(glogger)
+
+	// Configure the EVM logger
+	config := &logger.Config{
+		EnableMemory:     !ctx.Bool(DisableMemoryFlag.Name),
+		DisableStack:     ctx.Bool(DisableStackFlag.Name),
+		DisableStorage:   ctx.Bool(DisableStorageFlag.Name),
+		EnableReturnData: !ctx.Bool(DisableReturnDataFlag.Name),
+	}
+	var (
+		tracer   vm.EVMLogger
+		debugger *logger.StructLogger
+	)
+	switch {
+	case ctx.Bool(MachineFlag.Name):
+		tracer = logger.NewJSONLogger(config, os.Stderr)
+
+	case ctx.Bool(DebugFlag.Name):
+		debugger = logger.NewStructLogger(config)
+		tracer = debugger
+	}
+	// Load the bundle from the input file
+	src, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	var bundle core.ExecutionBundle
+	if err = json.Unmarshal(src, &bundle); err != nil {
+		return err
+	}
+	// Re-execute the bundle and compare against the recorded outcome
+	cfg := vm.Config{
+		Tracer: tracer,
+		Debug:  tracer != nil,
+	}
+	res, s, err := bundle.Run(cfg)
+	if err != nil {
+		return err
+	}
+	result := &BundleRunResult{Pass: true, Result: res}
+	if err := bundle.Check(res); err != nil {
+		result.Pass, result.Error = false, err.Error()
+	}
+	if ctx.Bool(DumpFlag.Name) {
+		dump := s.RawDump(nil)
+		result.State = &dump
+	}
+	// Print any structured logs collected
+	if debugger != nil {
+		fmt.Fprintln(os.Stderr, "#### TRACE ####")
+		logger.WriteTrace(os.Stderr, debugger.StructLogs())
+	}
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(out))
+	if !result.Pass {
+		return errors.New("bundle re-execution mismatch")
+	}
+	return nil
+}
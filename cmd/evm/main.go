@@ -223,6 +223,7 @@ func init() {
 		disasmCommand,
 		runCommand,
 		stateTestCommand,
+		bundleCommand,
 		stateTransitionCommand,
 		transactionCommand,
 		blockBuilderCommand,
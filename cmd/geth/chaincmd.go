@@ -17,15 +17,20 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/metadata"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -34,15 +39,22 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/clone"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/urfave/cli/v2"
 )
 
 var (
+	cloneFromFlag = &cli.StringFlag{
+		Name:  "from",
+		Usage: "Address (host:port) of the node serving the clone",
+	}
 	initCommand = &cli.Command{
 		Action:    initGenesis,
 		Name:      "init",
@@ -64,6 +76,22 @@ It expects the genesis file as argument.`,
 		Flags:     utils.NetworkFlags,
 		Description: `
 The dumpgenesis command dumps the genesis block configuration in JSON format to stdout.`,
+	}
+	cloneCommand = &cli.Command{
+		Action: cloneChain,
+		Name:   "clone",
+		Usage:  "Clone the chain and state database of another node",
+		Flags: flags.Merge([]cli.Flag{
+			cloneFromFlag,
+			utils.CloneSecretFlag,
+			utils.CacheFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `
+The clone command copies the complete database of another node run by the same
+operator into the empty local database, without going through peer discovery
+and sync. The source node must run with --clone.listen and the same secret file
+passed via --clone.secret. The transfer is authenticated and encrypted with a
+key derived from the shared secret.`,
 	}
 	importCommand = &cli.Command{
 		Action:    importChain,
@@ -92,6 +120,8 @@ The dumpgenesis command dumps the genesis block configuration in JSON format to
 			utils.MetricsInfluxDBBucketFlag,
 			utils.MetricsInfluxDBOrganizationFlag,
 			utils.TxLookupLimitFlag,
+			utils.AddressLogIndexFlag,
+			utils.InternalTxIndexFlag,
 		}, utils.DatabasePathFlags),
 		Description: `
 The import command imports blocks from an RLP-encoded form. The form can be one file
@@ -115,6 +145,38 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+	}
+	exportHistoryCommand = &cli.Command{
+		Action:    exportHistory,
+		Name:      "export-history",
+		Usage:     "Export blockchain history to era1 archives",
+		ArgsUsage: "<dir> <first> <last>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		}, utils.DatabasePathFlags),
+		Description: `
+The export-history command exports the blocks, receipts and total difficulties
+in the given range into era1 archives of 8192 blocks each, named
+<network>-<epoch>-<root>.era1, along with a checksums.txt file listing their
+sha256 checksums and an index.txt file listing their block ranges. The archives
+can be distributed over HTTP to bootstrap nodes with "geth import-history", or
+to be used as sync sources with --history.mirrors.`,
+	}
+	importHistoryCommand = &cli.Command{
+		Action:    importHistory,
+		Name:      "import-history",
+		Usage:     "Import blockchain history from era1 archives",
+		ArgsUsage: "<dir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.TxLookupLimitFlag,
+		}, utils.DatabasePathFlags, utils.NetworkFlags),
+		Description: `
+The import-history command imports the era1 archives of the configured network
+from the given directory, as produced by "geth export-history". The archives are
+checked against checksums.txt and their accumulators, then the blocks and
+receipts are inserted without being executed. The state can be synced
+afterwards with snap sync.`,
 	}
 	importPreimagesCommand = &cli.Command{
 		Action:    importPreimages,
@@ -160,6 +222,46 @@ It's deprecated, please use "geth db export" instead.
 		}, utils.DatabasePathFlags),
 		Description: `
 This command dumps out the state for a given block (or latest, if none provided).
+`,
+	}
+	genesisFromStateCommand = &cli.Command{
+		Action:    genesisFromState,
+		Name:      "genesis-from-state",
+		Usage:     "Create a genesis JSON mirroring the state of a specific block",
+		ArgsUsage: "[? <blockHash> | <blockNum>]",
+		Flags: flags.Merge([]cli.Flag{
+			utils.CacheFlag,
+			utils.DumpAddressesFlag,
+			utils.DumpAddressFileFlag,
+		}, utils.DatabasePathFlags),
+		Description: `
+This command exports the state of a given block (or latest, if none provided) as
+the allocation of a genesis JSON printed to stdout, for bootstrapping a fork or
+test network mirroring the balances and contracts of this chain.
+
+The allocation can be limited to selected accounts with --addresses and
+--addressfile. Otherwise all accounts are exported, as long as their address
+preimages are known. Consensus specific fields of the genesis (e.g. the clique
+signers) need to be filled in manually.
+`,
+	}
+	importContractMetadataCommand = &cli.Command{
+		Action:    importContractMetadata,
+		Name:      "import-contract-metadata",
+		Usage:     "Import the metadata of verified contracts from a Sourcify repository",
+		ArgsUsage: "<repositoryDir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.CacheFlag,
+		}, utils.DatabasePathFlags),
+		Description: `
+This command imports the name, ABI and source hash of the verified contracts of
+this chain from a Sourcify repository export, i.e. a directory containing
+contracts/full_match/<chainId>/<address>/metadata.json files.
+
+The metadata is stored by the hash of the code deployed at the contract addresses
+in the head state, and is returned by eth_getContractMetadata. Tracing with the
+callTracer and eth_decodeTransaction use it to name the called contracts and
+decode the calldata.
 `,
 	}
 )
@@ -214,6 +316,32 @@ func dumpGenesis(ctx *cli.Context) error {
 	return nil
 }
 
+func cloneChain(ctx *cli.Context) error {
+	if !ctx.IsSet(cloneFromFlag.Name) {
+		utils.Fatalf("This command requires --%s", cloneFromFlag.Name)
+	}
+	secret, err := clone.LoadSecret(ctx.String(utils.CloneSecretFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to load clone secret: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	// Abort the transfer on interrupt
+	cctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	start := time.Now()
+	if err := clone.Clone(cctx, ctx.String(cloneFromFlag.Name), secret, db); err != nil {
+		utils.Fatalf("Clone error: %v", err)
+	}
+	fmt.Printf("Clone done in %v\n", time.Since(start))
+	return nil
+}
+
 func importChain(ctx *cli.Context) error {
 	if ctx.Args().Len() < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -331,6 +459,62 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+func exportHistory(ctx *cli.Context) error {
+	if ctx.Args().Len() != 3 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, _ := utils.MakeChain(ctx, stack)
+	start := time.Now()
+
+	var (
+		dir         = ctx.Args().Get(0)
+		first, ferr = strconv.ParseInt(ctx.Args().Get(1), 10, 64)
+		last, lerr  = strconv.ParseInt(ctx.Args().Get(2), 10, 64)
+	)
+	if ferr != nil || lerr != nil {
+		utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+	}
+	if first < 0 || last < 0 {
+		utils.Fatalf("Export error: block number must be greater than 0\n")
+	}
+	if head := chain.CurrentFastBlock(); uint64(last) > head.NumberU64() {
+		utils.Fatalf("Export error: block number %d larger than head block %d\n", uint64(last), head.NumberU64())
+	}
+	if err := utils.ExportHistory(chain, dir, uint64(first), uint64(last), era.MaxEra1Size); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+func importHistory(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+
+	network := "unknown"
+	if name, ok := params.NetworkNames[chain.Config().ChainID.String()]; ok {
+		network = name
+	}
+	start := time.Now()
+	if err := utils.ImportHistory(chain, db, ctx.Args().First(), network); err != nil {
+		utils.Fatalf("Import error: %v\n", err)
+	}
+	chain.Stop()
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if ctx.Args().Len() < 1 {
@@ -368,11 +552,12 @@ func exportPreimages(ctx *cli.Context) error {
 	return nil
 }
 
-func parseDumpConfig(ctx *cli.Context, stack *node.Node) (*state.DumpConfig, ethdb.Database, common.Hash, error) {
-	db := utils.MakeChainDatabase(ctx, stack, true)
+// readDumpHeader returns the header of the block given as command argument, or
+// the head header if none is given.
+func readDumpHeader(ctx *cli.Context, db ethdb.Database) (*types.Header, error) {
 	var header *types.Header
 	if ctx.NArg() > 1 {
-		return nil, nil, common.Hash{}, fmt.Errorf("expected 1 argument (number or hash), got %d", ctx.NArg())
+		return nil, fmt.Errorf("expected 1 argument (number or hash), got %d", ctx.NArg())
 	}
 	if ctx.NArg() == 1 {
 		arg := ctx.Args().First()
@@ -381,17 +566,17 @@ func parseDumpConfig(ctx *cli.Context, stack *node.Node) (*state.DumpConfig, eth
 			if number := rawdb.ReadHeaderNumber(db, hash); number != nil {
 				header = rawdb.ReadHeader(db, hash, *number)
 			} else {
-				return nil, nil, common.Hash{}, fmt.Errorf("block %x not found", hash)
+				return nil, fmt.Errorf("block %x not found", hash)
 			}
 		} else {
 			number, err := strconv.Atoi(arg)
 			if err != nil {
-				return nil, nil, common.Hash{}, err
+				return nil, err
 			}
 			if hash := rawdb.ReadCanonicalHash(db, uint64(number)); hash != (common.Hash{}) {
 				header = rawdb.ReadHeader(db, hash, uint64(number))
 			} else {
-				return nil, nil, common.Hash{}, fmt.Errorf("header for block %d not found", number)
+				return nil, fmt.Errorf("header for block %d not found", number)
 			}
 		}
 	} else {
@@ -399,7 +584,16 @@ func parseDumpConfig(ctx *cli.Context, stack *node.Node) (*state.DumpConfig, eth
 		header = rawdb.ReadHeadHeader(db)
 	}
 	if header == nil {
-		return nil, nil, common.Hash{}, errors.New("no head block found")
+		return nil, errors.New("no head block found")
+	}
+	return header, nil
+}
+
+func parseDumpConfig(ctx *cli.Context, stack *node.Node) (*state.DumpConfig, ethdb.Database, common.Hash, error) {
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	header, err := readDumpHeader(ctx, db)
+	if err != nil {
+		return nil, nil, common.Hash{}, err
 	}
 	startArg := common.FromHex(ctx.String(utils.StartKeyFlag.Name))
 	var start common.Hash
@@ -451,6 +645,97 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+func genesisFromState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	header, err := readDumpHeader(ctx, db)
+	if err != nil {
+		return err
+	}
+	addresses, err := parseDumpAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil {
+		return errors.New("chain config not found")
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(db), nil)
+	if err != nil {
+		return err
+	}
+	log.Info("Creating genesis from state", "block", header.Number, "hash", header.Hash(), "accounts", len(addresses))
+	genesis, err := core.GenesisFromState(config, header, statedb, addresses)
+	if err != nil {
+		return err
+	}
+	log.Info("Created genesis from state", "accounts", len(genesis.Alloc))
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(genesis)
+}
+
+func importContractMetadata(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil {
+		return errors.New("chain config not found")
+	}
+	header := rawdb.ReadHeadHeader(db)
+	if header == nil {
+		return errors.New("no head block found")
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(db), nil)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	imported, err := metadata.ImportSourcify(db, statedb, ctx.Args().First(), config.ChainID.Uint64())
+	if err != nil {
+		return err
+	}
+	log.Info("Imported contract metadata", "contracts", imported, "block", header.Number, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// parseDumpAddresses returns the accounts given via --addresses and --addressfile.
+func parseDumpAddresses(ctx *cli.Context) ([]common.Address, error) {
+	var items []string
+	if list := ctx.String(utils.DumpAddressesFlag.Name); list != "" {
+		items = append(items, strings.Split(list, ",")...)
+	}
+	if file := ctx.String(utils.DumpAddressFileFlag.Name); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, strings.Split(string(content), "\n")...)
+	}
+	var addresses []common.Address
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !common.IsHexAddress(item) {
+			return nil, fmt.Errorf("invalid address: %q", item)
+		}
+		addresses = append(addresses, common.HexToAddress(item))
+	}
+	return addresses, nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -28,11 +28,15 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/external"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/accounts/qrwallet"
 	"github.com/ethereum/go-ethereum/accounts/scwallet"
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/beacon/blsync"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/tracers/plugins"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
@@ -90,6 +94,7 @@ type gethConfig struct {
 	Node     node.Config
 	Ethstats ethstatsConfig
 	Metrics  metrics.Config
+	Blsync   blsync.Config
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -145,6 +150,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	}
 
 	utils.SetEthConfig(ctx, stack, &cfg.Eth)
+	utils.SetBlsyncConfig(ctx, &cfg.Blsync)
 	if ctx.IsSet(utils.EthStatsURLFlag.Name) {
 		cfg.Ethstats.URL = ctx.String(utils.EthStatsURLFlag.Name)
 	}
@@ -163,6 +169,16 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		override := ctx.Bool(utils.OverrideTerminalTotalDifficultyPassed.Name)
 		cfg.Eth.OverrideTerminalTotalDifficultyPassed = &override
 	}
+	if ctx.IsSet(utils.OverrideEOF.Name) {
+		cfg.Eth.OverrideEOF = flags.GlobalBig(ctx, utils.OverrideEOF.Name)
+	}
+	setupTracerPlugins(ctx)
+	// Follow the chain with the beacon light client instead of the Ethereum
+	// service if requested, which only serves a subset of the eth namespace
+	if cfg.Eth.SyncMode == downloader.LightBeaconSync {
+		utils.RegisterBlsyncService(stack, &cfg.Blsync)
+		return stack, nil
+	}
 	backend, eth := utils.RegisterEthService(stack, &cfg.Eth)
 	// Warn users to migrate if they have a legacy freezer format.
 	if eth != nil && !ctx.IsSet(utils.IgnoreLegacyReceiptsFlag.Name) {
@@ -181,6 +197,18 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 			utils.Fatalf("Database has receipts with a legacy format. Please run `geth db freezer-migrate`.")
 		}
 	}
+	// Serve database clones if requested
+	if eth != nil && ctx.IsSet(utils.CloneListenFlag.Name) {
+		utils.RegisterCloneService(stack, eth.BlockChain(), eth.ChainDb(), ctx.String(utils.CloneListenFlag.Name), ctx.String(utils.CloneSecretFlag.Name))
+	}
+	// Serve the change-log to read replicas if requested
+	if eth != nil && ctx.IsSet(utils.ChangeLogListenFlag.Name) {
+		utils.RegisterChangeLogService(stack, cfg.Eth.ChangeLogDir, ctx.String(utils.ChangeLogListenFlag.Name))
+	}
+	// Serve the chain state to external EVMs if requested
+	if eth != nil && ctx.IsSet(utils.StateListenFlag.Name) {
+		utils.RegisterStateService(stack, eth.BlockChain(), ctx.String(utils.StateListenFlag.Name))
+	}
 	// Configure GraphQL if requested
 	if ctx.IsSet(utils.GraphQLEnabledFlag.Name) {
 		utils.RegisterGraphQLService(stack, backend, cfg.Node)
@@ -221,6 +249,17 @@ func dumpConfig(ctx *cli.Context) error {
 	return nil
 }
 
+// setupTracerPlugins loads the tracer plugins configured on the command line.
+func setupTracerPlugins(ctx *cli.Context) {
+	for _, path := range ctx.StringSlice(utils.VMTracePluginFlag.Name) {
+		names, err := plugins.Load(path)
+		if err != nil {
+			utils.Fatalf("Failed to load tracer plugin %s: %v", path, err)
+		}
+		log.Info("Loaded tracer plugin", "path", path, "tracers", names)
+	}
+}
+
 func applyMetricConfig(ctx *cli.Context, cfg *gethConfig) {
 	if ctx.IsSet(utils.MetricsEnabledFlag.Name) {
 		cfg.Metrics.Enabled = ctx.Bool(utils.MetricsEnabledFlag.Name)
@@ -264,6 +303,18 @@ func applyMetricConfig(ctx *cli.Context, cfg *gethConfig) {
 	if ctx.IsSet(utils.MetricsInfluxDBOrganizationFlag.Name) {
 		cfg.Metrics.InfluxDBOrganization = ctx.String(utils.MetricsInfluxDBOrganizationFlag.Name)
 	}
+	if ctx.IsSet(utils.MetricsEnableOTLPFlag.Name) {
+		cfg.Metrics.EnableOTLP = ctx.Bool(utils.MetricsEnableOTLPFlag.Name)
+	}
+	if ctx.IsSet(utils.MetricsOTLPEndpointFlag.Name) {
+		cfg.Metrics.OTLPEndpoint = ctx.String(utils.MetricsOTLPEndpointFlag.Name)
+	}
+	if ctx.IsSet(utils.MetricsOTLPResourceFlag.Name) {
+		cfg.Metrics.OTLPResource = ctx.String(utils.MetricsOTLPResourceFlag.Name)
+	}
+	if ctx.IsSet(utils.MetricsOTLPHeadersFlag.Name) {
+		cfg.Metrics.OTLPHeaders = ctx.String(utils.MetricsOTLPHeadersFlag.Name)
+	}
 }
 
 func deprecated(field string) bool {
@@ -324,6 +375,20 @@ func setAccountManagerBackends(stack *node.Node) error {
 			am.AddBackend(trezorhub)
 		}
 	}
+	if len(conf.USBSimulatorSeed) > 0 {
+		// Start simulated Ledger and Trezor hubs for testing wallet flows
+		log.Warn("Attaching simulated hardware wallets, do not use with real funds")
+		if simhub, err := usbwallet.NewSimulatedLedgerHub([]byte(conf.USBSimulatorSeed)); err != nil {
+			log.Warn(fmt.Sprintf("Failed to start simulated Ledger hub, disabling: %v", err))
+		} else {
+			am.AddBackend(simhub)
+		}
+		if simhub, err := usbwallet.NewSimulatedTrezorHub([]byte(conf.USBSimulatorSeed)); err != nil {
+			log.Warn(fmt.Sprintf("Failed to start simulated Trezor hub, disabling: %v", err))
+		} else {
+			am.AddBackend(simhub)
+		}
+	}
 	if len(conf.SmartCardDaemonPath) > 0 {
 		// Start a smart card hub
 		if schub, err := scwallet.NewHub(conf.SmartCardDaemonPath, scwallet.Scheme, keydir); err != nil {
@@ -332,6 +397,14 @@ func setAccountManagerBackends(stack *node.Node) error {
 			am.AddBackend(schub)
 		}
 	}
+	if len(conf.QRWalletKeys) > 0 {
+		// Import the air-gapped wallets, exchanging sign requests via stdio
+		if qrhub, err := qrwallet.NewHubFromFile(conf.QRWalletKeys, qrwallet.NewStreamTransport(os.Stdin, os.Stdout)); err != nil {
+			log.Warn(fmt.Sprintf("Failed to import QR wallets, disabling: %v", err))
+		} else {
+			am.AddBackend(qrhub)
+		}
+	}
 
 	return nil
 }
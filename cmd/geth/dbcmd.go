@@ -18,7 +18,9 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -44,6 +46,26 @@ import (
 )
 
 var (
+	freezerTableFlag = &cli.StringSliceFlag{
+		Name:  "table",
+		Usage: "Freezer table to rewrite, all tables if unset (may be repeated)",
+	}
+	freezerCompressionFlag = &cli.StringFlag{
+		Name:  "compression",
+		Usage: "Compression to rewrite the freezer tables with (" + rawdb.FreezerCompressionZstd + ", " + rawdb.FreezerCompressionSnappy + " or " + rawdb.FreezerCompressionNone + ")",
+	}
+	freezerFileSizeFlag = &cli.Uint64Flag{
+		Name:  "filesize",
+		Usage: "Maximum size of the rewritten freezer data files in bytes",
+	}
+	freezerDropRetiredFlag = &cli.BoolFlag{
+		Name:  "drop-retired",
+		Usage: "Delete the files of freezer tables no longer in use",
+	}
+	freezerAttachFlag = &cli.StringFlag{
+		Name:  "attach",
+		Usage: "Endpoint of a running node to rewrite the freezer tables of",
+	}
 	removedbCommand = &cli.Command{
 		Action:    removeDB,
 		Name:      "removedb",
@@ -69,6 +91,7 @@ Remove blockchain and state databases`,
 			dbImportCmd,
 			dbExportCmd,
 			dbMetadataCmd,
+			dbTiersCmd,
 			dbMigrateFreezerCmd,
 			dbCheckStateContentCmd,
 		},
@@ -195,6 +218,18 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbTiersCmd = &cli.Command{
+		Action: showTiers,
+		Name:   "tiers",
+		Usage:  "Shows the disk usage of the hot and cold database tiers",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command shows the disk usage of the hot tier (key-value store with
+the state and recent chain data) and the cold tier (ancient store with immutable
+chain data) of the database. Chain data is migrated to the cold tier automatically
+as it ages, so --datadir.ancient can point to slower, larger storage.`,
+	}
 	dbMigrateFreezerCmd = &cli.Command{
 		Action:    freezerMigrate,
 		Name:      "freezer-migrate",
@@ -202,9 +237,21 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		ArgsUsage: "",
 		Flags: flags.Merge([]cli.Flag{
 			utils.SyncModeFlag,
+			freezerTableFlag,
+			freezerCompressionFlag,
+			freezerFileSizeFlag,
+			freezerDropRetiredFlag,
+			freezerAttachFlag,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: `The freezer-migrate command checks your database for receipts in a legacy format and updates those.
-WARNING: please back-up the receipt files in your ancients before running this command.`,
+
+If any of --table, --compression, --filesize or --drop-retired is given, it rewrites
+the freezer tables in place instead: recompressing them, splitting them into data
+files of the given size and deleting the files of tables no longer in use. With
+--attach, the tables of a running node are rewritten without stopping it. A table
+is switched to its rewritten files atomically, so an interrupted rewrite leaves it
+usable and the unused files are deleted when the database is opened again.
+WARNING: please back-up the files in your ancients before running this command.`,
 	}
 )
 
@@ -746,13 +793,72 @@ func showMetaData(ctx *cli.Context) error {
 	return nil
 }
 
+func showTiers(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	tiers, err := utils.DatabaseTiers(ctx, stack)
+	if err != nil {
+		return err
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Tier", "Path", "Size", "Free"})
+	for _, tier := range tiers {
+		table.Append([]string{tier.Name, tier.Path, common.StorageSize(tier.Size).String(), common.StorageSize(tier.Free).String()})
+	}
+	table.Render()
+	return nil
+}
+
 func freezerMigrate(ctx *cli.Context) error {
+	size := ctx.Uint64(freezerFileSizeFlag.Name)
+	if size > math.MaxUint32 {
+		return fmt.Errorf("file size %d exceeds the limit of %d", size, uint32(math.MaxUint32))
+	}
+	config := &rawdb.FreezerMigrationConfig{
+		Tables:      ctx.StringSlice(freezerTableFlag.Name),
+		Compression: ctx.String(freezerCompressionFlag.Name),
+		MaxFileSize: uint32(size),
+		DropRetired: ctx.Bool(freezerDropRetiredFlag.Name),
+	}
+	rewrite := len(config.Tables) > 0 || config.Compression != "" || config.MaxFileSize > 0 || config.DropRetired
+
+	// Rewrite the tables of a running node if requested
+	if ctx.IsSet(freezerAttachFlag.Name) {
+		if !rewrite {
+			return errors.New("only freezer table rewrites are supported on running nodes")
+		}
+		client, err := dialRPC(ctx.String(freezerAttachFlag.Name))
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		log.Info("Rewriting freezer tables of running node", "tables", config.Tables, "compression", config.Compression, "filesize", config.MaxFileSize)
+		start := time.Now()
+		if err := client.Call(nil, "debug_chaindbMigrateFreezer", config); err != nil {
+			return err
+		}
+		log.Info("Migration finished", "duration", time.Since(start))
+		return nil
+	}
 	stack, _ := makeConfigNode(ctx)
 	defer stack.Close()
 
 	db := utils.MakeChainDatabase(ctx, stack, false)
 	defer db.Close()
 
+	if rewrite {
+		start := time.Now()
+		if err := rawdb.MigrateFreezer(db, config); err != nil {
+			return err
+		}
+		if err := db.Close(); err != nil {
+			return err
+		}
+		log.Info("Migration finished", "duration", time.Since(start))
+		return nil
+	}
 	// Check first block for legacy receipt format
 	numAncients, err := db.Ancients()
 	if err != nil {
@@ -0,0 +1,307 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/genesis"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	genesisChainIDFlag = &cli.Uint64Flag{
+		Name:  "chainid",
+		Usage: "Chain id of the network",
+		Value: 1337,
+	}
+	genesisConsensusFlag = &cli.StringFlag{
+		Name:  "consensus",
+		Usage: "Consensus engine of the network (ethash or clique)",
+		Value: "clique",
+	}
+	genesisPeriodFlag = &cli.Uint64Flag{
+		Name:  "clique.period",
+		Usage: "Clique block period in seconds",
+		Value: 15,
+	}
+	genesisEpochFlag = &cli.Uint64Flag{
+		Name:  "clique.epoch",
+		Usage: "Clique epoch length in blocks",
+		Value: 30000,
+	}
+	genesisSignerFlag = &cli.StringSliceFlag{
+		Name:  "signer",
+		Usage: "Address of an initial clique signer (may be repeated)",
+	}
+	genesisAllocFlag = &cli.StringSliceFlag{
+		Name:  "alloc",
+		Usage: "Prefunded account as address=wei (may be repeated)",
+	}
+	genesisPrecompilesFlag = &cli.BoolFlag{
+		Name:  "precompiles",
+		Usage: "Fund the precompiled contracts with 1 wei each",
+		Value: true,
+	}
+	genesisForksUpToFlag = &cli.StringFlag{
+		Name:  "forks.upto",
+		Usage: "Last fork active from genesis (" + strings.Join(genesis.Forks, ", ") + ")",
+		Value: "grayGlacier",
+	}
+	genesisForkFlag = &cli.StringSliceFlag{
+		Name:  "fork",
+		Usage: "Scheduled fork as name=block (may be repeated)",
+	}
+	genesisGasLimitFlag = &cli.Uint64Flag{
+		Name:  "gaslimit",
+		Usage: "Gas limit of the genesis block",
+		Value: 30000000,
+	}
+	genesisOutputFlag = &cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the genesis to (default = stdout)",
+	}
+	genesisInteractiveFlag = &cli.BoolFlag{
+		Name:  "interactive",
+		Usage: "Ask for the genesis parameters, using the flags as defaults",
+	}
+	genesisCommand = &cli.Command{
+		Name:  "genesis",
+		Usage: "Genesis file operations",
+		Subcommands: []*cli.Command{
+			genesisNewCommand,
+		},
+	}
+	genesisNewCommand = &cli.Command{
+		Action: newGenesis,
+		Name:   "new",
+		Usage:  "Generate a genesis file for a private network",
+		Flags: []cli.Flag{
+			genesisChainIDFlag,
+			genesisConsensusFlag,
+			genesisPeriodFlag,
+			genesisEpochFlag,
+			genesisSignerFlag,
+			genesisAllocFlag,
+			genesisPrecompilesFlag,
+			genesisForksUpToFlag,
+			genesisForkFlag,
+			genesisGasLimitFlag,
+			genesisOutputFlag,
+			genesisInteractiveFlag,
+		},
+		Description: `
+The genesis new command generates a validated genesis file for a private network
+from the given flags, or by asking for the parameters if --interactive is set.
+Example:
+
+    geth genesis new --chainid 12345 --signer 0x... --alloc 0x...=1000000000000000000000`,
+	}
+)
+
+// genesisSpec holds the parameters of a genesis to generate.
+type genesisSpec struct {
+	chainID     uint64
+	consensus   string
+	period      uint64
+	epoch       uint64
+	signers     []string
+	allocs      []string
+	precompiles bool
+	forksUpTo   string
+	forks       []string
+	gasLimit    uint64
+}
+
+// newGenesis generates a genesis file from the command line flags or from
+// interactive input.
+func newGenesis(ctx *cli.Context) error {
+	spec := &genesisSpec{
+		chainID:     ctx.Uint64(genesisChainIDFlag.Name),
+		consensus:   ctx.String(genesisConsensusFlag.Name),
+		period:      ctx.Uint64(genesisPeriodFlag.Name),
+		epoch:       ctx.Uint64(genesisEpochFlag.Name),
+		signers:     ctx.StringSlice(genesisSignerFlag.Name),
+		allocs:      ctx.StringSlice(genesisAllocFlag.Name),
+		precompiles: ctx.Bool(genesisPrecompilesFlag.Name),
+		forksUpTo:   ctx.String(genesisForksUpToFlag.Name),
+		forks:       ctx.StringSlice(genesisForkFlag.Name),
+		gasLimit:    ctx.Uint64(genesisGasLimitFlag.Name),
+	}
+	if ctx.Bool(genesisInteractiveFlag.Name) {
+		if err := spec.prompt(); err != nil {
+			utils.Fatalf("Failed to read input: %v", err)
+		}
+	}
+	gen, err := spec.build()
+	if err != nil {
+		utils.Fatalf("Invalid genesis: %v", err)
+	}
+	out, _ := json.MarshalIndent(gen, "", "  ")
+	out = append(out, '\n')
+
+	if path := ctx.String(genesisOutputFlag.Name); path != "" {
+		if err := os.WriteFile(path, out, 0644); err != nil {
+			utils.Fatalf("Failed to write genesis: %v", err)
+		}
+		return nil
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// build composes the genesis described by the spec.
+func (spec *genesisSpec) build() (*core.Genesis, error) {
+	b := genesis.NewBuilder(spec.chainID)
+	if err := b.ForksUpTo(spec.forksUpTo); err != nil {
+		return nil, err
+	}
+	for _, fork := range spec.forks {
+		parts := strings.SplitN(fork, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid fork %q, want name=block", fork)
+		}
+		name := parts[0]
+		block, err := strconv.ParseUint(parts[1], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block of fork %s: %v", name, err)
+		}
+		if err := b.SetFork(name, block); err != nil {
+			return nil, err
+		}
+	}
+	switch spec.consensus {
+	case "ethash":
+		b.SetEthash()
+	case "clique":
+		var signers []common.Address
+		for _, signer := range spec.signers {
+			if !common.IsHexAddress(signer) {
+				return nil, fmt.Errorf("invalid signer address %q", signer)
+			}
+			signers = append(signers, common.HexToAddress(signer))
+		}
+		b.SetClique(spec.period, spec.epoch, signers...)
+	default:
+		return nil, fmt.Errorf("unknown consensus engine %q", spec.consensus)
+	}
+	for _, alloc := range spec.allocs {
+		parts := strings.SplitN(alloc, "=", 2)
+		if len(parts) != 2 || !common.IsHexAddress(parts[0]) {
+			return nil, fmt.Errorf("invalid alloc %q, want address=wei", alloc)
+		}
+		balance, ok := math.ParseBig256(parts[1])
+		if !ok {
+			return nil, fmt.Errorf("invalid balance %q", parts[1])
+		}
+		b.Fund(common.HexToAddress(parts[0]), balance)
+	}
+	if spec.precompiles {
+		b.FundPrecompiles()
+	}
+	b.SetGasLimit(spec.gasLimit)
+	return b.Build()
+}
+
+// prompt asks for the genesis parameters, offering the current ones as defaults.
+func (spec *genesisSpec) prompt() error {
+	var err error
+	if spec.chainID, err = promptUint64("Chain id", spec.chainID); err != nil {
+		return err
+	}
+	if spec.consensus, err = promptString("Consensus engine (ethash, clique)", spec.consensus); err != nil {
+		return err
+	}
+	if spec.consensus == "clique" {
+		if spec.period, err = promptUint64("Block period in seconds", spec.period); err != nil {
+			return err
+		}
+		if spec.signers, err = promptList("Signer addresses", spec.signers); err != nil {
+			return err
+		}
+	}
+	if spec.allocs, err = promptList("Prefunded accounts (address=wei)", spec.allocs); err != nil {
+		return err
+	}
+	if spec.precompiles, err = prompt.Stdin.PromptConfirm("Fund the precompiles with 1 wei?"); err != nil {
+		return err
+	}
+	if spec.forksUpTo, err = promptString("Last fork active from genesis", spec.forksUpTo); err != nil {
+		return err
+	}
+	if spec.forks, err = promptList("Later forks (name=block)", spec.forks); err != nil {
+		return err
+	}
+	spec.gasLimit, err = promptUint64("Gas limit", spec.gasLimit)
+	return err
+}
+
+// promptString asks for a value, returning the default if none is given.
+func promptString(question string, def string) (string, error) {
+	input, err := prompt.Stdin.PromptInput(fmt.Sprintf("%s [%s]: ", question, def))
+	if err != nil {
+		return "", err
+	}
+	if input = strings.TrimSpace(input); input == "" {
+		return def, nil
+	}
+	return input, nil
+}
+
+// promptUint64 asks for a number, repeating the question until it is valid.
+func promptUint64(question string, def uint64) (uint64, error) {
+	for {
+		input, err := promptString(question, strconv.FormatUint(def, 10))
+		if err != nil {
+			return 0, err
+		}
+		if n, ok := math.ParseUint64(input); ok {
+			return n, nil
+		}
+		fmt.Printf("Invalid number %q\n", input)
+	}
+}
+
+// promptList asks for a list of values, one per line, until an empty line is
+// entered. The default is kept if no values are given.
+func promptList(question string, def []string) ([]string, error) {
+	fmt.Printf("%s, one per line, empty line to finish [%s]\n", question, strings.Join(def, " "))
+	var list []string
+	for {
+		input, err := prompt.Stdin.PromptInput("> ")
+		if err != nil {
+			return nil, err
+		}
+		if input = strings.TrimSpace(input); input == "" {
+			break
+		}
+		list = append(list, input)
+	}
+	if len(list) == 0 {
+		return def, nil
+	}
+	return list, nil
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/internal/supervisor"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
@@ -68,9 +69,12 @@ var (
 		utils.ExternalSignerFlag,
 		utils.NoUSBFlag,
 		utils.USBFlag,
+		utils.USBSimulatorFlag,
 		utils.SmartCardDaemonPathFlag,
+		utils.QRWalletKeysFlag,
 		utils.OverrideTerminalTotalDifficulty,
 		utils.OverrideTerminalTotalDifficultyPassed,
+		utils.OverrideEOF,
 		utils.EthashCacheDirFlag,
 		utils.EthashCachesInMemoryFlag,
 		utils.EthashCachesOnDiskFlag,
@@ -90,11 +94,26 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolAuditFlag,
 		utils.SyncModeFlag,
+		utils.HistoryMirrorsFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.NoTxIndexFlag,
+		utils.TxLookupBackfillFlag,
+		utils.AddressLogIndexFlag,
+		utils.InternalTxIndexFlag,
+		utils.NoBloomIndexFlag,
+		utils.ChangeLogDirFlag,
+		utils.ChangeLogKeyFileFlag,
+		utils.ChangeLogListenFlag,
+		utils.ReplicaSourceFlag,
+		utils.StandbyFlag,
+		utils.CloneListenFlag,
+		utils.CloneSecretFlag,
+		utils.StateListenFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -105,6 +124,9 @@ var (
 		utils.UltraLightFractionFlag,
 		utils.UltraLightOnlyAnnounceFlag,
 		utils.LightNoSyncServeFlag,
+		utils.BlsyncBeaconAPIFlag,
+		utils.BlsyncCheckpointFlag,
+		utils.BlsyncExecutionFlag,
 		utils.EthRequiredBlocksFlag,
 		utils.LegacyWhitelistFlag,
 		utils.BloomFilterSizeFlag,
@@ -113,14 +135,21 @@ var (
 		utils.CacheTrieFlag,
 		utils.CacheTrieJournalFlag,
 		utils.CacheTrieRejournalFlag,
+		utils.CacheTrieParallelismFlag,
 		utils.CacheGCFlag,
 		utils.CacheSnapshotFlag,
+		utils.CacheAnalysisFlag,
 		utils.CacheNoPrefetchFlag,
 		utils.CachePreimagesFlag,
 		utils.FDLimitFlag,
 		utils.ListenPortFlag,
 		utils.DiscoveryPortFlag,
 		utils.MaxPeersFlag,
+		utils.AdaptivePeersFlag,
+		utils.MinPeersFlag,
+		utils.AdaptivePeersCPUFlag,
+		utils.AdaptivePeersDiskWaitFlag,
+		utils.AdaptivePeersBandwidthFlag,
 		utils.MaxPendingPeersFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerThreadsFlag,
@@ -132,6 +161,9 @@ var (
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerifyFlag,
+		utils.MinerSealApprovalFlag,
+		utils.MinerSealApprovalTimeoutFlag,
+		utils.MinerSealApprovalAllowFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -142,7 +174,9 @@ var (
 		utils.DeveloperFlag,
 		utils.DeveloperPeriodFlag,
 		utils.DeveloperGasLimitFlag,
+		utils.DeveloperForceReorgFlag,
 		utils.VMEnableDebugFlag,
+		utils.VMTracePluginFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
 		utils.FakePoWFlag,
@@ -171,6 +205,14 @@ var (
 		utils.GraphQLVirtualHostsFlag,
 		utils.HTTPApiFlag,
 		utils.HTTPPathPrefixFlag,
+		utils.RPCAccessLogFlag,
+		utils.RPCAccessLogSampleFlag,
+		utils.RPCAccessLogRedactFlag,
+		utils.RPCAccessLogMaxSizeFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateLimitBurstFlag,
+		utils.RPCSignResponsesFlag,
+		utils.RPCSignedMethodsFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
@@ -183,6 +225,7 @@ var (
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCCacheFlag,
 		utils.AllowUnprotectedTxs,
 	}
 
@@ -201,6 +244,15 @@ var (
 		utils.MetricsInfluxDBTokenFlag,
 		utils.MetricsInfluxDBBucketFlag,
 		utils.MetricsInfluxDBOrganizationFlag,
+		utils.MetricsEnableOTLPFlag,
+		utils.MetricsOTLPEndpointFlag,
+		utils.MetricsOTLPResourceFlag,
+		utils.MetricsOTLPHeadersFlag,
+		utils.AlertsHeadTimeoutFlag,
+		utils.AlertsMinPeersFlag,
+		utils.AlertsRejectRateFlag,
+		utils.AlertsForkchoiceTimeoutFlag,
+		utils.AlertsWebhookFlag,
 	}
 )
 
@@ -214,11 +266,18 @@ func init() {
 		initCommand,
 		importCommand,
 		exportCommand,
+		importHistoryCommand,
+		exportHistoryCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
 		removedbCommand,
 		dumpCommand,
 		dumpGenesisCommand,
+		genesisFromStateCommand,
+		importContractMetadataCommand,
+		cloneCommand,
+		// See genesiscmd.go:
+		genesisCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -234,6 +293,9 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See replaycmd.go
+		replayCommand,
+		rpcConformanceCommand,
 		// see dbcmd.go
 		dbCommand,
 		// See cmd/utils/flags_legacy.go
@@ -346,10 +408,14 @@ func geth(ctx *cli.Context) error {
 	}
 
 	prepare(ctx)
+
+	// Connect to the service manager before the database is opened, which can
+	// take longer than the Windows service manager is willing to wait.
+	sup := supervisor.New(ctx.App.Name)
 	stack, backend := makeFullNode(ctx)
 	defer stack.Close()
 
-	startNode(ctx, stack, backend, false)
+	startNode(ctx, stack, backend, sup, false)
 	stack.Wait()
 	return nil
 }
@@ -357,11 +423,11 @@ func geth(ctx *cli.Context) error {
 // startNode boots up the system node and all registered protocols, after which
 // it unlocks any requested accounts, and starts the RPC/IPC interfaces and the
 // miner.
-func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend, isConsole bool) {
+func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend, sup *supervisor.Supervisor, isConsole bool) {
 	debug.Memsize.Add("node", stack)
 
 	// Start up the node itself
-	utils.StartNode(ctx, stack, isConsole)
+	utils.StartNode(ctx, stack, sup, isConsole)
 
 	// Unlock any account specifically requested
 	unlockAccounts(ctx, stack)
@@ -406,6 +472,12 @@ func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend, isCon
 			case accounts.WalletDropped:
 				log.Info("Old wallet dropped", "url", event.Wallet.URL())
 				event.Wallet.Close()
+			case accounts.KeyFileCorrupted:
+				log.Warn("Keystore file corrupted on disk", "url", event.Wallet.URL())
+			case accounts.KeyFileReplaced:
+				log.Warn("Keystore file replaced on disk", "url", event.Wallet.URL(), "address", event.Wallet.Accounts()[0].Address)
+			case accounts.PassphraseRotated:
+				log.Info("Keystore passphrase rotated", "url", event.Wallet.URL())
 			}
 		}
 	}()
@@ -0,0 +1,227 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/blockstm"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	replayBlockFlag = &cli.Uint64Flag{
+		Name:  "block",
+		Usage: "Number of the (first) block to replay",
+	}
+	replayCountFlag = &cli.Uint64Flag{
+		Name:  "count",
+		Usage: "Number of consecutive blocks to replay",
+		Value: 1,
+	}
+	replayWorkersFlag = &cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of parallel workers to simulate",
+		Value: runtime.NumCPU(),
+	}
+	replayStrategyFlag = &cli.StringSliceFlag{
+		Name:  "strategy",
+		Usage: "Parallel execution strategies to evaluate (" + strings.Join(blockstm.Strategies, ", ") + ")",
+		Value: cli.NewStringSlice(blockstm.Strategies...),
+	}
+	replayJSONFlag = &cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the results of every block as JSON instead of a summary table",
+	}
+	replayCommand = &cli.Command{
+		Action: replay,
+		Name:   "replay",
+		Usage:  "Re-execute blocks to evaluate parallel execution strategies",
+		Flags: flags.Merge([]cli.Flag{
+			replayBlockFlag,
+			replayCountFlag,
+			replayWorkersFlag,
+			replayStrategyFlag,
+			replayJSONFlag,
+			utils.CacheFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `
+The replay command re-executes blocks on top of their parent state, recording the
+state read and written by every transaction. From the resulting dependencies and
+the measured execution times, it estimates the conflict rates and speedups of
+Block-STM style parallel execution strategies:
+
+  sequential  execute transactions one by one (baseline)
+  optimistic  execute all speculatively, re-execute conflicting ones sequentially
+  stm         execute all speculatively, re-execute conflicting ones in parallel rounds
+  dag         execute each transaction once its dependencies finished
+
+The state of the parent blocks must be available, so replaying older blocks
+requires an archive node.`,
+	}
+)
+
+// replayBlockResult is the JSON output of a replayed block.
+type replayBlockResult struct {
+	Number     uint64                 `json:"number"`
+	Hash       common.Hash            `json:"hash"`
+	Txs        int                    `json:"txs"`
+	Gas        uint64                 `json:"gas"`
+	Conflicts  int                    `json:"conflicts"`
+	Sequential time.Duration          `json:"sequential"`
+	Strategies []*replayStrategyStats `json:"strategies"`
+}
+
+// replayStrategyStats is the estimated performance of a strategy.
+type replayStrategyStats struct {
+	Strategy   string        `json:"strategy"`
+	Workers    int           `json:"workers"`
+	Executions int           `json:"executions"`
+	Rounds     int           `json:"rounds"`
+	Time       time.Duration `json:"time"`
+	Speedup    float64       `json:"speedup"`
+}
+
+func replay(ctx *cli.Context) error {
+	if !ctx.IsSet(replayBlockFlag.Name) {
+		utils.Fatalf("This command requires --%s", replayBlockFlag.Name)
+	}
+	var (
+		first      = ctx.Uint64(replayBlockFlag.Name)
+		count      = ctx.Uint64(replayCountFlag.Name)
+		workers    = ctx.Int(replayWorkersFlag.Name)
+		strategies = ctx.StringSlice(replayStrategyFlag.Name)
+	)
+	if first == 0 || count == 0 {
+		utils.Fatalf("Invalid block range, the genesis cannot be replayed")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	var (
+		totals = make(map[string]*replayStrategyStats)
+		txs    int
+		confs  int
+		seq    time.Duration
+		enc    = json.NewEncoder(os.Stdout)
+	)
+	for number := first; number < first+count; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			utils.Fatalf("Block %d not found", number)
+		}
+		parent := chain.GetBlock(block.ParentHash(), number-1)
+		if parent == nil {
+			utils.Fatalf("Parent of block %d not found", number)
+		}
+		statedb, err := chain.StateAt(parent.Root())
+		if err != nil {
+			utils.Fatalf("State of block %d unavailable: %v", number-1, err)
+		}
+		profile, err := blockstm.Profile(chain.Config(), chain, block, statedb)
+		if err != nil {
+			utils.Fatalf("Failed to replay block %d: %v", number, err)
+		}
+		result := &replayBlockResult{
+			Number:     number,
+			Hash:       block.Hash(),
+			Txs:        len(profile.Txs),
+			Gas:        block.GasUsed(),
+			Conflicts:  profile.Conflicts(),
+			Sequential: profile.SequentialTime(),
+		}
+		for _, strategy := range strategies {
+			res, err := blockstm.Simulate(profile, strategy, workers)
+			if err != nil {
+				utils.Fatalf("Failed to simulate block %d: %v", number, err)
+			}
+			stats := &replayStrategyStats{
+				Strategy:   res.Strategy,
+				Workers:    res.Workers,
+				Executions: res.Executions,
+				Rounds:     res.Rounds,
+				Time:       res.Time,
+				Speedup:    speedup(result.Sequential, res.Time),
+			}
+			result.Strategies = append(result.Strategies, stats)
+
+			total, ok := totals[strategy]
+			if !ok {
+				total = &replayStrategyStats{Strategy: res.Strategy, Workers: res.Workers}
+				totals[strategy] = total
+			}
+			total.Executions += res.Executions
+			total.Rounds += res.Rounds
+			total.Time += res.Time
+		}
+		txs, confs, seq = txs+result.Txs, confs+result.Conflicts, seq+result.Sequential
+
+		if ctx.Bool(replayJSONFlag.Name) {
+			if err := enc.Encode(result); err != nil {
+				return err
+			}
+			continue
+		}
+		log.Info("Replayed block", "number", number, "txs", result.Txs, "gas", result.Gas, "conflicts", result.Conflicts, "elapsed", common.PrettyDuration(result.Sequential))
+	}
+	if ctx.Bool(replayJSONFlag.Name) {
+		return nil
+	}
+	var rate float64
+	if txs > 0 {
+		rate = 100 * float64(confs) / float64(txs)
+	}
+	fmt.Printf("Blocks: %d, transactions: %d, conflicting: %d (%.1f%%)\n", count, txs, confs, rate)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Strategy", "Workers", "Executions", "Rounds", "Time", "Speedup"})
+	for _, strategy := range strategies {
+		total := totals[strategy]
+		table.Append([]string{
+			total.Strategy,
+			fmt.Sprint(total.Workers),
+			fmt.Sprint(total.Executions),
+			fmt.Sprint(total.Rounds),
+			common.PrettyDuration(total.Time).String(),
+			fmt.Sprintf("%.2fx", speedup(seq, total.Time)),
+		})
+	}
+	table.Render()
+	return nil
+}
+
+// speedup returns the speedup of a parallel execution over the sequential one.
+func speedup(sequential, parallel time.Duration) float64 {
+	if parallel == 0 {
+		return 1
+	}
+	return float64(sequential) / float64(parallel)
+}
@@ -0,0 +1,326 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/urfave/cli/v2"
+)
+
+// bundledRPCTests are the test vectors run by default, in the format of the
+// execution-apis test suite.
+//
+//go:embed rpctests
+var bundledRPCTests embed.FS
+
+var (
+	rpcTestsFlag = &cli.StringFlag{
+		Name:  "tests",
+		Usage: "Directory of the test vectors to run (default = bundled vectors)",
+	}
+	rpcTestsRunFlag = &cli.StringFlag{
+		Name:  "run",
+		Usage: "Run only the tests whose name (method/test) matches the regular expression",
+	}
+	rpcConformanceCommand = &cli.Command{
+		Action: testRPCConformance,
+		Name:   "test-rpc-conformance",
+		Usage:  "Check the RPC API for deviations from the execution-apis test vectors",
+		Flags: []cli.Flag{
+			rpcTestsFlag,
+			rpcTestsRunFlag,
+		},
+		Description: `
+The test-rpc-conformance command starts an in-memory node, imports the test chain
+and runs the test vectors against its RPC API, reporting every response deviating
+from the expected one. The command fails if any test does.
+
+The test vectors use the layout of the ethereum/execution-apis test suite:
+
+  genesis.json         genesis of the test chain
+  chain.rlp            blocks of the test chain (optional)
+  <method>/<test>.io   request and expected response, on lines starting
+                       with ">> " and "<< " respectively
+
+Results are compared as JSON values, error responses only by their error code.`,
+	}
+)
+
+// rpcTest is a single test vector, a sequence of requests with their expected
+// responses.
+type rpcTest struct {
+	name      string
+	exchanges []rpcExchange
+}
+
+type rpcExchange struct {
+	request  []byte
+	response []byte
+}
+
+// rpcTestRequest and rpcTestResponse are the parts of the JSON-RPC messages
+// relevant to the tests.
+type rpcTestRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcTestResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+func testRPCConformance(ctx *cli.Context) error {
+	var fsys fs.FS
+	if dir := ctx.String(rpcTestsFlag.Name); dir != "" {
+		fsys = os.DirFS(dir)
+	} else {
+		fsys, _ = fs.Sub(bundledRPCTests, "rpctests")
+	}
+	var filter *regexp.Regexp
+	if expr := ctx.String(rpcTestsRunFlag.Name); expr != "" {
+		var err error
+		if filter, err = regexp.Compile(expr); err != nil {
+			utils.Fatalf("Invalid --%s expression: %v", rpcTestsRunFlag.Name, err)
+		}
+	}
+	tests, err := loadRPCTests(fsys, filter)
+	if err != nil {
+		utils.Fatalf("Failed to load test vectors: %v", err)
+	}
+	stack, err := newRPCTestNode(fsys)
+	if err != nil {
+		utils.Fatalf("Failed to start test node: %v", err)
+	}
+	defer stack.Close()
+
+	client, err := stack.Attach()
+	if err != nil {
+		utils.Fatalf("Failed to attach to test node: %v", err)
+	}
+	defer client.Close()
+
+	failed := 0
+	for _, test := range tests {
+		if err := runRPCTest(client, test); err != nil {
+			fmt.Printf("FAIL %s: %v\n", test.name, err)
+			failed++
+		}
+	}
+	fmt.Printf("%d tests, %d passed, %d failed\n", len(tests), len(tests)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d tests deviating from the test vectors", failed)
+	}
+	return nil
+}
+
+// loadRPCTests loads all test vectors whose name matches the filter, sorted by
+// name.
+func loadRPCTests(fsys fs.FS, filter *regexp.Regexp) ([]*rpcTest, error) {
+	var tests []*rpcTest
+	err := fs.WalkDir(fsys, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(file) != ".io" {
+			return err
+		}
+		name := strings.TrimSuffix(file, ".io")
+		if filter != nil && !filter.MatchString(name) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		test, err := parseRPCTest(name, data)
+		if err != nil {
+			return err
+		}
+		tests = append(tests, test)
+		return nil
+	})
+	sort.Slice(tests, func(i, j int) bool { return tests[i].name < tests[j].name })
+	return tests, err
+}
+
+// parseRPCTest parses a test vector, skipping comment lines.
+func parseRPCTest(name string, data []byte) (*rpcTest, error) {
+	test := &rpcTest{name: name}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "//"):
+		case strings.HasPrefix(line, ">> "):
+			test.exchanges = append(test.exchanges, rpcExchange{request: []byte(line[3:])})
+		case strings.HasPrefix(line, "<< "):
+			if len(test.exchanges) == 0 || test.exchanges[len(test.exchanges)-1].response != nil {
+				return nil, fmt.Errorf("%s:%d: response without request", name, i+1)
+			}
+			test.exchanges[len(test.exchanges)-1].response = []byte(line[3:])
+		default:
+			return nil, fmt.Errorf("%s:%d: invalid line", name, i+1)
+		}
+	}
+	if len(test.exchanges) == 0 {
+		return nil, fmt.Errorf("%s: no requests", name)
+	}
+	for _, ex := range test.exchanges {
+		if ex.response == nil {
+			return nil, fmt.Errorf("%s: request without response", name)
+		}
+	}
+	return test, nil
+}
+
+// newRPCTestNode starts an in-memory node with the test chain.
+func newRPCTestNode(fsys fs.FS) (*node.Node, error) {
+	data, err := fs.ReadFile(fsys, "genesis.json")
+	if err != nil {
+		return nil, err
+	}
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal(data, genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis: %v", err)
+	}
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		return nil, err
+	}
+	config := ethconfig.Defaults
+	config.Genesis = genesis
+	config.NetworkId = genesis.Config.ChainID.Uint64()
+	config.Ethash.PowMode = ethash.ModeFake
+
+	backend, err := eth.New(stack, &config)
+	if err != nil {
+		stack.Close()
+		return nil, err
+	}
+	if err := stack.Start(); err != nil {
+		stack.Close()
+		return nil, err
+	}
+	blocks, err := readRPCTestChain(fsys)
+	if err == nil && len(blocks) > 0 {
+		_, err = backend.BlockChain().InsertChain(blocks)
+	}
+	if err != nil {
+		stack.Close()
+		return nil, fmt.Errorf("failed to import test chain: %v", err)
+	}
+	return stack, nil
+}
+
+// readRPCTestChain reads the blocks of the test chain, if there are any.
+func readRPCTestChain(fsys fs.FS) ([]*types.Block, error) {
+	file, err := fsys.Open("chain.rlp")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		stream = rlp.NewStream(file, 0)
+		blocks []*types.Block
+	)
+	for {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			return blocks, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("block %d: %v", len(blocks), err)
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+// runRPCTest sends the requests of a test vector, returning the first deviation
+// from the expected responses.
+func runRPCTest(client *rpc.Client, test *rpcTest) error {
+	for i, ex := range test.exchanges {
+		var req rpcTestRequest
+		if err := json.Unmarshal(ex.request, &req); err != nil {
+			return fmt.Errorf("invalid request %d: %v", i, err)
+		}
+		var want rpcTestResponse
+		if err := json.Unmarshal(ex.response, &want); err != nil {
+			return fmt.Errorf("invalid response %d: %v", i, err)
+		}
+		args := make([]interface{}, len(req.Params))
+		for j, param := range req.Params {
+			args[j] = param
+		}
+		var result json.RawMessage
+		err := client.CallContext(context.Background(), &result, req.Method, args...)
+		if want.Error != nil {
+			rpcErr, ok := err.(rpc.Error)
+			if !ok {
+				return fmt.Errorf("%s: have result %s, want error code %d", req.Method, result, want.Error.Code)
+			}
+			if rpcErr.ErrorCode() != want.Error.Code {
+				return fmt.Errorf("%s: have error code %d (%v), want %d", req.Method, rpcErr.ErrorCode(), err, want.Error.Code)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: have error %v, want result %s", req.Method, err, want.Result)
+		}
+		// Null results are left empty by the client, and may be omitted in vectors
+		if len(result) == 0 {
+			result = json.RawMessage("null")
+		}
+		if len(want.Result) == 0 {
+			want.Result = json.RawMessage("null")
+		}
+		var have, expected interface{}
+		if err := json.Unmarshal(result, &have); err != nil {
+			return fmt.Errorf("%s: invalid result: %v", req.Method, err)
+		}
+		if err := json.Unmarshal(want.Result, &expected); err != nil {
+			return fmt.Errorf("invalid expected result %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, expected) {
+			return fmt.Errorf("%s: result mismatch\nhave %s\nwant %s", req.Method, result, want.Result)
+		}
+	}
+	return nil
+}
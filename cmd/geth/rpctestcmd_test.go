@@ -0,0 +1,87 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// Tests that the node conforms to the bundled test vectors, and that deviations
+// from them are detected.
+func TestRPCConformance(t *testing.T) {
+	fsys, _ := fs.Sub(bundledRPCTests, "rpctests")
+	tests, err := loadRPCTests(fsys, nil)
+	if err != nil {
+		t.Fatalf("failed to load test vectors: %v", err)
+	}
+	if len(tests) == 0 {
+		t.Fatal("no bundled test vectors")
+	}
+	stack, err := newRPCTestNode(fsys)
+	if err != nil {
+		t.Fatalf("failed to start test node: %v", err)
+	}
+	defer stack.Close()
+
+	client, _ := stack.Attach()
+	defer client.Close()
+
+	for _, test := range tests {
+		if err := runRPCTest(client, test); err != nil {
+			t.Errorf("test %s failed: %v", test.name, err)
+		}
+	}
+	deviations := []string{
+		">> {\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"eth_chainId\",\"params\":[]}\n<< {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":\"0x1\"}",
+		">> {\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"eth_chainId\",\"params\":[]}\n<< {\"jsonrpc\":\"2.0\",\"id\":1,\"error\":{\"code\":-32602}}",
+		">> {\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"eth_getBlockByNumber\",\"params\":[\"0xzz\",false]}\n<< {\"jsonrpc\":\"2.0\",\"id\":1,\"error\":{\"code\":-32000}}",
+	}
+	for i, vector := range deviations {
+		test, err := parseRPCTest("deviation", []byte(vector))
+		if err != nil {
+			t.Fatalf("deviation %d: failed to parse: %v", i, err)
+		}
+		if err := runRPCTest(client, test); err == nil {
+			t.Errorf("deviation %d: not detected", i)
+		}
+	}
+}
+
+func TestParseRPCTest(t *testing.T) {
+	tests := []struct {
+		vector string
+		err    string
+	}{
+		{"// comment\n>> {}\n<< {}\n>> {}\n<< {}\n", ""},
+		{"", "no requests"},
+		{">> {}\n", "request without response"},
+		{"<< {}\n", "response without request"},
+		{">> {}\n<< {}\n<< {}\n", "response without request"},
+		{">> {}\nfoo\n<< {}\n", "invalid line"},
+	}
+	for i, test := range tests {
+		_, err := parseRPCTest("test", []byte(test.vector))
+		if test.err == "" && err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, test.err)
+		}
+	}
+}
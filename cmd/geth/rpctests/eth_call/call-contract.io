@@ -0,0 +1,3 @@
+// calls a contract returning its storage
+>> {"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{"from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000000100"},"latest"]}
+<< {"jsonrpc":"2.0","id":1,"result":"0x000000000000000000000000000000000000000000000000000000000000002a"}
@@ -0,0 +1,3 @@
+// retrieves the chain ID
+>> {"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}
+<< {"jsonrpc":"2.0","id":1,"result":"0x539"}
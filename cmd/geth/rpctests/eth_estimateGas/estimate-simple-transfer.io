@@ -0,0 +1,3 @@
+// estimates the gas of a value transfer
+>> {"jsonrpc":"2.0","id":1,"method":"eth_estimateGas","params":[{"from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x00000000000000000000000000000000000000aa","value":"0x1"}]}
+<< {"jsonrpc":"2.0","id":1,"result":"0x5208"}
@@ -0,0 +1,3 @@
+// retrieves the balance of the recipient at a block hash
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x00000000000000000000000000000000000000aa",{"blockHash":"0x9482ad2b7470923a53b2265365e4a4156af4271f53f653f52b0427a34af14c02"}]}
+<< {"jsonrpc":"2.0","id":1,"result":"0x3e8"}
@@ -0,0 +1,3 @@
+// retrieves the balance of the sender account
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x71562b71999873db5b286df957af199ec94617f7","latest"]}
+<< {"jsonrpc":"2.0","id":1,"result":"0x3635c965cc27743a40"}
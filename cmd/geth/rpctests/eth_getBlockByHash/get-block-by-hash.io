@@ -0,0 +1,3 @@
+// retrieves a block by its hash
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getBlockByHash","params":["0x9482ad2b7470923a53b2265365e4a4156af4271f53f653f52b0427a34af14c02",false]}
+<< {"jsonrpc":"2.0","id":1,"result":{"baseFeePerGas":"0x342770c0","difficulty":"0x20000","extraData":"0x","gasLimit":"0x1c9c380","gasUsed":"0x5208","hash":"0x9482ad2b7470923a53b2265365e4a4156af4271f53f653f52b0427a34af14c02","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x00000000000000000000000000000000000000cc","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x1","parentHash":"0xa308118d086ba272448f1bc85c482a44776b6b6b974539a97b75eadf9e131e05","receiptsRoot":"0x056b23fbba480696b65fe5a59b8f2148a1299103c4f57df839233af2cf4ca2d2","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","size":"0x26d","stateRoot":"0x78f43285455ebb6b0fbe98ebd71e411f623dcf534294192138487824d840f501","timestamp":"0xa","totalDifficulty":"0x40000","transactions":["0xeb37d2058d9b7e6afd798ef510009680bf3d896ea1c526306ca21eb0ad5272bd"],"transactionsRoot":"0xe21d8c8f704ce1d2a9e75c4f3879f1accc3b72a22ce0f444cf8785579f3497be","uncles":[]}}
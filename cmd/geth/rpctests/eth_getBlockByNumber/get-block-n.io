@@ -0,0 +1,3 @@
+// retrieves a block with its full transactions
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["0x2",true]}
+<< {"jsonrpc":"2.0","id":1,"result":{"baseFeePerGas":"0x2da4d8cd","difficulty":"0x20000","extraData":"0x","gasLimit":"0x1c9c380","gasUsed":"0x5228","hash":"0x63cfdc4aa18d51ae67eea6d5d782ae7fc1dd9ff79dc66638243e20deb4ede024","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x00000000000000000000000000000000000000cc","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x2","parentHash":"0x9482ad2b7470923a53b2265365e4a4156af4271f53f653f52b0427a34af14c02","receiptsRoot":"0x186d44f7567ba6ef3e8ffff818342ef898eb8b24f644cbc9b362582c4d90cb11","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","size":"0x279","stateRoot":"0xc3a09603a2b95975da37a60d623d0ba4c5cc4f2dd89b18f7a38fad2a16003efe","timestamp":"0x14","totalDifficulty":"0x60000","transactions":[{"blockHash":"0x63cfdc4aa18d51ae67eea6d5d782ae7fc1dd9ff79dc66638243e20deb4ede024","blockNumber":"0x2","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0xc350","gasPrice":"0x693fa2cd","maxFeePerGas":"0xb2d05e00","maxPriorityFeePerGas":"0x3b9aca00","hash":"0xe80fcb4334e3cb9639266984ea3cb43a9d4cbfc8f513e98db5b6c2f0b9f816a2","input":"0x0102","nonce":"0x1","to":"0x00000000000000000000000000000000000000aa","transactionIndex":"0x0","value":"0x7d0","type":"0x2","accessList":[],"chainId":"0x539","v":"0x0","r":"0xc9e0e8cf833706ab000ce6f0c3569f8dc04bba316d61f9be083ec9821a52ae81","s":"0x73110c2c0688f38ba0853724933e17d504bfd419dbd525a6bd976ccfbcc8a41"}],"transactionsRoot":"0x8c9a19f8484dd513c412ef0a7765b80a194d199b165a9560147e6e2167ac126d","uncles":[]}}
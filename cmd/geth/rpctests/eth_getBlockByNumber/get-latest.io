@@ -0,0 +1,3 @@
+// retrieves the head block
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["latest",false]}
+<< {"jsonrpc":"2.0","id":1,"result":{"baseFeePerGas":"0x27f249fb","difficulty":"0x20000","extraData":"0x","gasLimit":"0x1c9c380","gasUsed":"0x0","hash":"0xabc346cf40d6e90fa33ea1c081a02021318cdeea186ad47abef9754b7b44be6a","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x00000000000000000000000000000000000000cc","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x3","parentHash":"0x63cfdc4aa18d51ae67eea6d5d782ae7fc1dd9ff79dc66638243e20deb4ede024","receiptsRoot":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","size":"0x201","stateRoot":"0x7022c70a3bed5a897d8b2612fd7585707a6d16291b4e03df2587cac5d3dddea5","timestamp":"0x1e","totalDifficulty":"0x80000","transactions":[],"transactionsRoot":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","uncles":[]}}
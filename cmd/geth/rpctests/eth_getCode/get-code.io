@@ -0,0 +1,3 @@
+// retrieves the code of a contract
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getCode","params":["0x0000000000000000000000000000000000000100","latest"]}
+<< {"jsonrpc":"2.0","id":1,"result":"0x60005460005260206000f3"}
@@ -0,0 +1,3 @@
+// retrieves a dynamic fee transaction
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getTransactionByHash","params":["0xe80fcb4334e3cb9639266984ea3cb43a9d4cbfc8f513e98db5b6c2f0b9f816a2"]}
+<< {"jsonrpc":"2.0","id":1,"result":{"blockHash":"0x63cfdc4aa18d51ae67eea6d5d782ae7fc1dd9ff79dc66638243e20deb4ede024","blockNumber":"0x2","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0xc350","gasPrice":"0x693fa2cd","maxFeePerGas":"0xb2d05e00","maxPriorityFeePerGas":"0x3b9aca00","hash":"0xe80fcb4334e3cb9639266984ea3cb43a9d4cbfc8f513e98db5b6c2f0b9f816a2","input":"0x0102","nonce":"0x1","to":"0x00000000000000000000000000000000000000aa","transactionIndex":"0x0","value":"0x7d0","type":"0x2","accessList":[],"chainId":"0x539","v":"0x0","r":"0xc9e0e8cf833706ab000ce6f0c3569f8dc04bba316d61f9be083ec9821a52ae81","s":"0x73110c2c0688f38ba0853724933e17d504bfd419dbd525a6bd976ccfbcc8a41"}}
@@ -0,0 +1,3 @@
+// retrieves a legacy transaction
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getTransactionByHash","params":["0xeb37d2058d9b7e6afd798ef510009680bf3d896ea1c526306ca21eb0ad5272bd"]}
+<< {"jsonrpc":"2.0","id":1,"result":{"blockHash":"0x9482ad2b7470923a53b2265365e4a4156af4271f53f653f52b0427a34af14c02","blockNumber":"0x1","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x77359400","hash":"0xeb37d2058d9b7e6afd798ef510009680bf3d896ea1c526306ca21eb0ad5272bd","input":"0x","nonce":"0x0","to":"0x00000000000000000000000000000000000000aa","transactionIndex":"0x0","value":"0x3e8","type":"0x0","chainId":"0x539","v":"0xa95","r":"0x7bcd4dfb086c0f1a13c4a61775845d7b741b549860e5698b8acfc9e257268491","s":"0x7f181adced7fb8a6147e4a2940a265b22fc6b69ec7a8fb12b5c1b72ef993bd61"}}
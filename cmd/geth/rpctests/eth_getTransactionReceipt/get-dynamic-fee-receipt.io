@@ -0,0 +1,3 @@
+// retrieves the receipt of a dynamic fee transaction
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getTransactionReceipt","params":["0xe80fcb4334e3cb9639266984ea3cb43a9d4cbfc8f513e98db5b6c2f0b9f816a2"]}
+<< {"jsonrpc":"2.0","id":1,"result":{"blockHash":"0x63cfdc4aa18d51ae67eea6d5d782ae7fc1dd9ff79dc66638243e20deb4ede024","blockNumber":"0x2","contractAddress":null,"cumulativeGasUsed":"0x5228","effectiveGasPrice":"0x693fa2cd","from":"0x71562b71999873db5b286df957af199ec94617f7","gasUsed":"0x5228","logs":[],"logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","status":"0x1","to":"0x00000000000000000000000000000000000000aa","transactionHash":"0xe80fcb4334e3cb9639266984ea3cb43a9d4cbfc8f513e98db5b6c2f0b9f816a2","transactionIndex":"0x0","type":"0x2"}}
@@ -0,0 +1,3 @@
+// retrieves the receipt of a legacy transaction
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getTransactionReceipt","params":["0xeb37d2058d9b7e6afd798ef510009680bf3d896ea1c526306ca21eb0ad5272bd"]}
+<< {"jsonrpc":"2.0","id":1,"result":{"blockHash":"0x9482ad2b7470923a53b2265365e4a4156af4271f53f653f52b0427a34af14c02","blockNumber":"0x1","contractAddress":null,"cumulativeGasUsed":"0x5208","effectiveGasPrice":"0x77359400","from":"0x71562b71999873db5b286df957af199ec94617f7","gasUsed":"0x5208","logs":[],"logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","status":"0x1","to":"0x00000000000000000000000000000000000000aa","transactionHash":"0xeb37d2058d9b7e6afd798ef510009680bf3d896ea1c526306ca21eb0ad5272bd","transactionIndex":"0x0","type":"0x0"}}
@@ -20,17 +20,21 @@ package utils
 import (
 	"bufio"
 	"compress/gzip"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -38,9 +42,13 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/debug"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/internal/supervisor"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/urfave/cli/v2"
 )
 
@@ -68,13 +76,27 @@ func Fatalf(format string, args ...interface{}) {
 	os.Exit(1)
 }
 
-func StartNode(ctx *cli.Context, stack *node.Node, isConsole bool) {
-	if err := stack.Start(); err != nil {
+// StartNode starts the node and reports its lifecycle to the service manager
+// (systemd, Windows SCM) running it, treating its stop requests like a SIGTERM.
+func StartNode(ctx *cli.Context, stack *node.Node, sup *supervisor.Supervisor, isConsole bool) {
+	if err := sup.Start(stack.Start); err != nil {
 		Fatalf("Error starting protocol stack: %v", err)
 	}
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sup.StopRequested()
+		select {
+		case sigc <- syscall.SIGTERM:
+		default:
+		}
+	}()
+	go func() {
+		stack.Wait()
+		sup.Stopped()
+	}()
 	go func() {
-		sigc := make(chan os.Signal, 1)
-		signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
 		defer signal.Stop(sigc)
 
 		minFreeDiskSpace := 2 * ethconfig.Defaults.TrieDirtyCache // Default 2 * 256Mb
@@ -89,6 +111,7 @@ func StartNode(ctx *cli.Context, stack *node.Node, isConsole bool) {
 
 		shutdown := func() {
 			log.Info("Got interrupt, shutting down...")
+			sup.Stopping()
 			go stack.Close()
 			for i := 10; i > 0; i-- {
 				<-sigc
@@ -287,6 +310,319 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	return nil
 }
 
+// ExportHistory exports the blocks, receipts and total difficulties in the range
+// [first, last] into era1 files of step blocks each in the given directory,
+// along with a checksums.txt file listing the sha256 checksums of the files and
+// an index.txt file listing their names and block ranges, one file per line as
+// "<name> <first block> <block count>".
+func ExportHistory(bc *core.BlockChain, dir string, first, last, step uint64) error {
+	log.Info("Exporting blockchain history", "dir", dir)
+	if step == 0 || step > era.MaxEra1Size {
+		return fmt.Errorf("invalid era1 size %d", step)
+	}
+	if head := bc.CurrentBlock().NumberU64(); head < last {
+		log.Warn("Last block beyond head, setting last = head", "head", head, "last", last)
+		last = head
+	}
+	if first > last {
+		return fmt.Errorf("invalid range: first block %d after last %d", first, last)
+	}
+	network := "unknown"
+	if name, ok := params.NetworkNames[bc.Config().ChainID.String()]; ok {
+		network = name
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	var (
+		start     = time.Now()
+		reported  = time.Now()
+		checksums []string
+		index     []string
+	)
+	for i := first; i <= last; i += step {
+		epoch := int(i / step)
+		tmp := filepath.Join(dir, era.Filename(network, epoch, common.Hash{})+".tmp")
+		root, err := exportEra(bc, tmp, i, last, step)
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		filename := filepath.Join(dir, era.Filename(network, epoch, root))
+		if err := os.Rename(tmp, filename); err != nil {
+			return err
+		}
+		checksum, err := fileChecksum(filename)
+		if err != nil {
+			return err
+		}
+		checksums = append(checksums, checksum.Hex())
+
+		count := step
+		if last-i < step {
+			count = last - i + 1
+		}
+		index = append(index, fmt.Sprintf("%s %d %d", filepath.Base(filename), i, count))
+
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Exporting blocks", "exported", i+step-first, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+		if last-i < step {
+			break
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(strings.Join(checksums, "\n")), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.txt"), []byte(strings.Join(index, "\n")), os.ModePerm); err != nil {
+		return err
+	}
+	log.Info("Exported blockchain history", "dir", dir, "files", len(checksums), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// exportEra writes the blocks starting at first, up to step blocks but not past
+// last, into a single era1 file, returning its accumulator root.
+func exportEra(bc *core.BlockChain, filename string, first, last, step uint64) (common.Hash, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("could not create era1 file: %w", err)
+	}
+	defer f.Close()
+
+	w := era.NewBuilder(f)
+	for n := first; n < first+step && n <= last; n++ {
+		block := bc.GetBlockByNumber(n)
+		if block == nil {
+			return common.Hash{}, fmt.Errorf("export failed on #%d: not found", n)
+		}
+		receipts := bc.GetReceiptsByHash(block.Hash())
+		if receipts == nil && len(block.Transactions()) > 0 {
+			return common.Hash{}, fmt.Errorf("export failed on #%d: receipts not found", n)
+		}
+		td := bc.GetTd(block.Hash(), n)
+		if td == nil {
+			return common.Hash{}, fmt.Errorf("export failed on #%d: total difficulty not found", n)
+		}
+		if err := w.Add(block, receipts, td); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	root, err := w.Finalize()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("export failed to finalize %s: %w", filename, err)
+	}
+	return root, f.Sync()
+}
+
+// ImportHistory imports the era1 files of the given network from the directory,
+// created by ExportHistory. The files are checked against checksums.txt, and the
+// content of every file against its accumulator, before being inserted into the
+// chain without execution.
+func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, network string) error {
+	files, err := era.ReadDir(dir, network)
+	if err != nil {
+		return fmt.Errorf("unable to read era1 directory: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no era1 files found for network %s in %s", network, dir)
+	}
+	checksums, err := readChecksums(filepath.Join(dir, "checksums.txt"))
+	if err != nil {
+		return fmt.Errorf("unable to read checksums.txt: %w", err)
+	}
+	if len(checksums) != len(files) {
+		return fmt.Errorf("mismatched era1 files and checksum entries: have %d files, %d checksums", len(files), len(checksums))
+	}
+	var (
+		start    = time.Now()
+		reported = time.Now()
+		imported = 0
+	)
+	for i, file := range files {
+		path := filepath.Join(dir, file)
+		checksum, err := fileChecksum(path)
+		if err != nil {
+			return err
+		}
+		if checksum != checksums[i] {
+			return fmt.Errorf("checksum mismatch of %s: have %x, want %x", file, checksum, checksums[i])
+		}
+		n, err := importEra(chain, db, path)
+		if err != nil {
+			return fmt.Errorf("error importing %s: %w", file, err)
+		}
+		imported += n
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Importing era1 files", "head", chain.CurrentFastBlock().NumberU64(), "imported", imported, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	}
+	log.Info("Imported blockchain history", "dir", dir, "blocks", imported, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// importEra verifies and imports a single era1 file, returning the number of
+// blocks inserted.
+func importEra(chain *core.BlockChain, db ethdb.Database, path string) (int, error) {
+	e, err := era.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer e.Close()
+
+	// Verify the full content of the file before inserting anything
+	root, err := e.Accumulator()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		hashes = make([]common.Hash, 0, e.Count())
+		tds    = make([]*big.Int, 0, e.Count())
+	)
+	for n := e.Start(); n < e.Start()+e.Count(); n++ {
+		block, receipts, td, err := e.ReadBlock(n)
+		if err != nil {
+			return 0, err
+		}
+		if err := verifyBlockContent(block, receipts); err != nil {
+			return 0, err
+		}
+		hashes, tds = append(hashes, block.Hash()), append(tds, td)
+	}
+	if have, err := era.ComputeAccumulator(hashes, tds); err != nil {
+		return 0, err
+	} else if have != root {
+		return 0, fmt.Errorf("accumulator mismatch: have %x, want %x", have, root)
+	}
+	// Insert the missing blocks in contiguous batches
+	var (
+		blocks   = make(types.Blocks, 0, importBatchSize)
+		receipts = make([]types.Receipts, 0, importBatchSize)
+		batchTds = make([]*big.Int, 0, importBatchSize)
+		imported = 0
+		flush    = func() error {
+			if len(blocks) == 0 {
+				return nil
+			}
+			if err := insertHistory(chain, db, blocks, receipts, batchTds); err != nil {
+				return err
+			}
+			imported += len(blocks)
+			blocks, receipts, batchTds = blocks[:0], receipts[:0], batchTds[:0]
+			return nil
+		}
+	)
+	for i, n := 0, e.Start(); n < e.Start()+e.Count(); i, n = i+1, n+1 {
+		if n == 0 {
+			if hashes[i] != chain.Genesis().Hash() {
+				return 0, fmt.Errorf("genesis mismatch: have %x, want %x", hashes[i], chain.Genesis().Hash())
+			}
+			continue
+		}
+		if chain.HasBlock(hashes[i], n) {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		block, rs, _, err := e.ReadBlock(n)
+		if err != nil {
+			return 0, err
+		}
+		blocks, receipts, batchTds = append(blocks, block), append(receipts, rs), append(batchTds, tds[i])
+		if len(blocks) == importBatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// verifyBlockContent checks that the body and the receipts of a block match the
+// roots committed to by its header.
+func verifyBlockContent(block *types.Block, receipts types.Receipts) error {
+	header := block.Header()
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash {
+		return fmt.Errorf("block #%d: transaction root mismatch: have %x, want %x", block.NumberU64(), hash, header.TxHash)
+	}
+	if hash := types.CalcUncleHash(block.Uncles()); hash != header.UncleHash {
+		return fmt.Errorf("block #%d: uncle root mismatch: have %x, want %x", block.NumberU64(), hash, header.UncleHash)
+	}
+	if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != header.ReceiptHash {
+		return fmt.Errorf("block #%d: receipt root mismatch: have %x, want %x", block.NumberU64(), hash, header.ReceiptHash)
+	}
+	return nil
+}
+
+// insertHistory inserts a contiguous batch of verified blocks and receipts
+// without executing them, checking the resulting total difficulties against the
+// ones of the archive.
+func insertHistory(chain *core.BlockChain, db ethdb.Database, blocks types.Blocks, receipts []types.Receipts, tds []*big.Int) error {
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if _, err := chain.InsertHeaderChain(headers, 100); err != nil {
+		return err
+	}
+	for i, block := range blocks {
+		if td := chain.GetTd(block.Hash(), block.NumberU64()); td == nil || td.Cmp(tds[i]) != 0 {
+			return fmt.Errorf("block #%d: total difficulty mismatch: have %v, want %v", block.NumberU64(), td, tds[i])
+		}
+	}
+	// Write straight into the ancient store if the blocks extend it
+	var (
+		first        = blocks[0].NumberU64()
+		ancientLimit uint64
+	)
+	if frozen, err := db.Ancients(); err == nil && (frozen == first || (frozen == 0 && first == 1)) {
+		ancientLimit = blocks[len(blocks)-1].NumberU64()
+	}
+	_, err := chain.InsertReceiptChain(blocks, receipts, ancientLimit)
+	return err
+}
+
+// fileChecksum returns the sha256 checksum of a file.
+func fileChecksum(path string) (common.Hash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(h.Sum(nil)), nil
+}
+
+// readChecksums reads a checksums file, one hex encoded checksum per line.
+func readChecksums(path string) ([]common.Hash, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var checksums []common.Hash
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		b, err := hexutil.Decode(line)
+		if err != nil || len(b) != common.HashLength {
+			return nil, fmt.Errorf("invalid checksum %q", line)
+		}
+		checksums = append(checksums, common.BytesToHash(b))
+	}
+	return checksums, nil
+}
+
 // ImportPreimages imports a batch of exported hash preimages into the database.
 // It's a part of the deprecated functionality, should be removed in the future.
 func ImportPreimages(db ethdb.Database, fn string) error {
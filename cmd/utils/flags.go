@@ -31,19 +31,25 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/beacon/blsync"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/remote"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/alerts"
 	ethcatalyst "github.com/ethereum/go-ethereum/eth/catalyst"
+	"github.com/ethereum/go-ethereum/eth/clone"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/replica"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
@@ -57,6 +63,7 @@ import (
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/metrics/exp"
 	"github.com/ethereum/go-ethereum/metrics/influxdb"
+	"github.com/ethereum/go-ethereum/metrics/otlp"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -64,6 +71,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 	pcsclite "github.com/gballet/go-libpcsclite"
 	gopsutil "github.com/shirou/gopsutil/mem"
 	"github.com/urfave/cli/v2"
@@ -94,6 +102,22 @@ var (
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	DBEncryptionKeyFlag = &cli.StringFlag{
+		Name:     "db.encryptionkey",
+		Usage:    "File containing the hex-encoded 32 byte master key to encrypt the databases at rest",
+		Category: flags.EthCategory,
+	}
+	DBEngineFlag = &cli.StringFlag{
+		Name:     "db.engine",
+		Usage:    "Database storage engine (\"leveldb\" or \"remote\")",
+		Value:    "leveldb",
+		Category: flags.EthCategory,
+	}
+	DBRemoteEndpointsFlag = &cli.StringFlag{
+		Name:     "db.remote.endpoints",
+		Usage:    "Comma separated grpc:// or grpcs:// endpoints of the remote key-value store (--db.engine=remote)",
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -109,12 +133,22 @@ var (
 		Usage:    "Enable monitoring and management of USB hardware wallets",
 		Category: flags.AccountCategory,
 	}
+	USBSimulatorFlag = &cli.StringFlag{
+		Name:     "usb.simulator",
+		Usage:    "Attach simulated Ledger and Trezor hardware wallets derived from the given seed (testing only)",
+		Category: flags.AccountCategory,
+	}
 	SmartCardDaemonPathFlag = &cli.StringFlag{
 		Name:     "pcscdpath",
 		Usage:    "Path to the smartcard daemon (pcscd) socket file",
 		Value:    pcsclite.PCSCDSockName,
 		Category: flags.AccountCategory,
 	}
+	QRWalletKeysFlag = &cli.StringFlag{
+		Name:     "qrwallet.keys",
+		Usage:    "File with the keys exported by air-gapped QR code wallets (crypto-hdkey URs, one part per line)",
+		Category: flags.AccountCategory,
+	}
 	NetworkIdFlag = &cli.Uint64Flag{
 		Name:     "networkid",
 		Usage:    "Explicitly set network id (integer)(For testnets: use --ropsten, --rinkeby, --goerli instead)",
@@ -169,6 +203,11 @@ var (
 		Value:    11500000,
 		Category: flags.DevCategory,
 	}
+	DeveloperForceReorgFlag = &cli.BoolFlag{
+		Name:     "dev.forcereorg",
+		Usage:    "Enable debug_forceReorg to simulate chain reorgs (single signer clique networks only)",
+		Category: flags.DevCategory,
+	}
 
 	IdentityFlag = &cli.StringFlag{
 		Name:     "identity",
@@ -215,14 +254,27 @@ var (
 		Usage: "Max number of elements (0 = no limit)",
 		Value: 0,
 	}
+	DumpAddressesFlag = &cli.StringFlag{
+		Name:  "addresses",
+		Usage: "Comma separated list of accounts to include (default = all accounts)",
+	}
+	DumpAddressFileFlag = &cli.StringFlag{
+		Name:  "addressfile",
+		Usage: "File containing accounts to include, one address per line",
+	}
 
 	defaultSyncMode = ethconfig.Defaults.SyncMode
 	SyncModeFlag    = &flags.TextMarshalerFlag{
 		Name:     "syncmode",
-		Usage:    `Blockchain sync mode ("snap", "full" or "light")`,
+		Usage:    `Blockchain sync mode ("snap", "full", "light" or "light-beacon")`,
 		Value:    &defaultSyncMode,
 		Category: flags.EthCategory,
 	}
+	HistoryMirrorsFlag = &cli.StringFlag{
+		Name:     "history.mirrors",
+		Usage:    "Comma separated HTTP(S) URLs of era1 archives (as written by export-history) to download historical bodies and receipts from during sync",
+		Category: flags.EthCategory,
+	}
 	GCModeFlag = &cli.StringFlag{
 		Name:     "gcmode",
 		Usage:    `Blockchain garbage collection mode ("full", "archive")`,
@@ -241,6 +293,71 @@ var (
 		Value:    ethconfig.Defaults.TxLookupLimit,
 		Category: flags.EthCategory,
 	}
+	NoTxIndexFlag = &cli.BoolFlag{
+		Name:     "notxindex",
+		Usage:    "Disable the transaction index, dropping any existing one (lookups only succeed with --txlookup.backfill)",
+		Category: flags.EthCategory,
+	}
+	TxLookupBackfillFlag = &cli.Uint64Flag{
+		Name:     "txlookup.backfill",
+		Usage:    "Number of unindexed blocks to search on transaction lookup misses, indexing the blocks of found transactions (0 = disabled)",
+		Category: flags.EthCategory,
+	}
+	AddressLogIndexFlag = &cli.BoolFlag{
+		Name:     "addresslogindex",
+		Usage:    "Index blocks by the addresses emitting logs, speeding up address-only log queries",
+		Category: flags.EthCategory,
+	}
+	InternalTxIndexFlag = &cli.BoolFlag{
+		Name:     "internaltxindex",
+		Usage:    "Index the value transfers of internal calls by address, traced on block import (eth_getInternalTransactions)",
+		Category: flags.EthCategory,
+	}
+	NoBloomIndexFlag = &cli.BoolFlag{
+		Name:     "nobloomindex",
+		Usage:    "Disable the bloom bits log index, filtering logs by scanning receipts (for small private chains, best with --addresslogindex)",
+		Category: flags.EthCategory,
+	}
+	ChangeLogDirFlag = &cli.StringFlag{
+		Name:     "changelog.dir",
+		Usage:    "Directory to export the canonical chain into, feeding read replicas",
+		Category: flags.EthCategory,
+	}
+	ChangeLogKeyFileFlag = &cli.StringFlag{
+		Name:     "changelog.keyfile",
+		Usage:    "File holding the hex encoded 32 byte key sealing the change-log, shared by the primary and its replicas",
+		Category: flags.EthCategory,
+	}
+	ChangeLogListenFlag = &cli.StringFlag{
+		Name:     "changelog.listen",
+		Usage:    "Listening address for serving the --changelog.dir change-log to read replicas over gRPC",
+		Category: flags.EthCategory,
+	}
+	ReplicaSourceFlag = &cli.StringFlag{
+		Name:     "replica.source",
+		Usage:    "Directory, gRPC or HTTP(S) URL of a primary node's change-log to follow as a read replica (disables p2p networking)",
+		Category: flags.EthCategory,
+	}
+	StandbyFlag = &cli.BoolFlag{
+		Name:     "standby",
+		Usage:    "Run as hot standby of the --replica.source primary sharing its node key, holding back networking and mining until promoted via admin_promote",
+		Category: flags.EthCategory,
+	}
+	CloneListenFlag = &cli.StringFlag{
+		Name:     "clone.listen",
+		Usage:    "Listening address for serving the database to nodes cloning this one",
+		Category: flags.EthCategory,
+	}
+	CloneSecretFlag = &cli.StringFlag{
+		Name:     "clone.secret",
+		Usage:    "File containing the hex-encoded secret (at least 32 bytes) authenticating database clones",
+		Category: flags.EthCategory,
+	}
+	StateListenFlag = &cli.StringFlag{
+		Name:     "state.listen",
+		Usage:    "Listening address for serving the chain state to external EVM implementations over gRPC (unauthenticated)",
+		Category: flags.EthCategory,
+	}
 	LightKDFFlag = &cli.BoolFlag{
 		Name:     "lightkdf",
 		Usage:    "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -272,6 +389,11 @@ var (
 		Usage:    "Manually specify TerminalTotalDifficultyPassed, overriding the bundled setting",
 		Category: flags.EthCategory,
 	}
+	OverrideEOF = &flags.BigFlag{
+		Name:     "override.eof",
+		Usage:    "Manually specify the EOF (EVM object format) activation block, overriding the bundled setting",
+		Category: flags.EthCategory,
+	}
 	// Light server and client settings
 	LightServeFlag = &cli.IntFlag{
 		Name:     "light.serve",
@@ -324,6 +446,21 @@ var (
 		Usage:    "Enables serving light clients before syncing",
 		Category: flags.LightCategory,
 	}
+	BlsyncBeaconAPIFlag = &cli.StringFlag{
+		Name:     "blsync.beaconapi",
+		Usage:    "URL of the beacon node API serving light client data (--syncmode light-beacon)",
+		Category: flags.LightCategory,
+	}
+	BlsyncCheckpointFlag = &cli.StringFlag{
+		Name:     "blsync.checkpoint",
+		Usage:    "Trusted beacon block root to start the beacon light client from",
+		Category: flags.LightCategory,
+	}
+	BlsyncExecutionFlag = &cli.StringFlag{
+		Name:     "blsync.execution",
+		Usage:    "URL of the untrusted execution RPC serving the state proofs verified by the beacon light client",
+		Category: flags.LightCategory,
+	}
 
 	// Ethash settings
 	EthashCacheDirFlag = &flags.DirectoryFlag{
@@ -431,6 +568,11 @@ var (
 		Value:    ethconfig.Defaults.TxPool.GlobalQueue,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolAuditFlag = &cli.IntFlag{
+		Name:     "txpool.audit",
+		Usage:    "Number of recent transactions to keep a propagation audit trail for (0 = disabled)",
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolLifetimeFlag = &cli.DurationFlag{
 		Name:     "txpool.lifetime",
 		Usage:    "Maximum amount of time non-executable transaction are queued",
@@ -469,6 +611,11 @@ var (
 		Value:    ethconfig.Defaults.TrieCleanCacheRejournal,
 		Category: flags.PerfCategory,
 	}
+	CacheTrieParallelismFlag = &cli.IntFlag{
+		Name:     "cache.trie.parallelism",
+		Usage:    "Number of threads to hash and commit large tries with (0 = number of CPUs)",
+		Category: flags.PerfCategory,
+	}
 	CacheGCFlag = &cli.IntFlag{
 		Name:     "cache.gc",
 		Usage:    "Percentage of cache memory allowance to use for trie pruning (default = 25% full mode, 0% archive mode)",
@@ -481,6 +628,12 @@ var (
 		Value:    10,
 		Category: flags.PerfCategory,
 	}
+	CacheAnalysisFlag = &cli.IntFlag{
+		Name:     "cache.analysis",
+		Usage:    "Megabytes of memory allocated to caching contract code analysis across transactions (0 = disabled)",
+		Value:    ethconfig.Defaults.AnalysisCache,
+		Category: flags.PerfCategory,
+	}
 	CacheNoPrefetchFlag = &cli.BoolFlag{
 		Name:     "cache.noprefetch",
 		Usage:    "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
@@ -553,6 +706,21 @@ var (
 		Usage:    "Disable remote sealing verification",
 		Category: flags.MinerCategory,
 	}
+	MinerSealApprovalFlag = &cli.StringFlag{
+		Name:     "miner.sealapproval",
+		Usage:    "HTTP URL to submit blocks to for approval before sealing them",
+		Category: flags.MinerCategory,
+	}
+	MinerSealApprovalTimeoutFlag = &cli.DurationFlag{
+		Name:     "miner.sealapproval.timeout",
+		Usage:    "Time to wait for the seal approval endpoint to answer (default = 2s)",
+		Category: flags.MinerCategory,
+	}
+	MinerSealApprovalAllowFlag = &cli.BoolFlag{
+		Name:     "miner.sealapproval.allow",
+		Usage:    "Seal blocks if the seal approval endpoint fails to answer (default = deny)",
+		Category: flags.MinerCategory,
+	}
 
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
@@ -569,7 +737,7 @@ var (
 	}
 	ExternalSignerFlag = &cli.StringFlag{
 		Name:     "signer",
-		Usage:    "External signer (url or path to ipc file)",
+		Usage:    "External signer (url, grpc:// or grpcs:// url of a gRPC signer, or path to ipc file)",
 		Value:    "",
 		Category: flags.AccountCategory,
 	}
@@ -585,6 +753,11 @@ var (
 		Usage:    "Record information useful for VM and contract debugging",
 		Category: flags.VMCategory,
 	}
+	VMTracePluginFlag = &cli.StringSliceFlag{
+		Name:     "vmtrace.plugin",
+		Usage:    "Tracer plugin (.so) to load, making its tracers available by name to the tracing APIs",
+		Category: flags.VMCategory,
+	}
 
 	// API options.
 	RPCGlobalGasCapFlag = &cli.Uint64Flag{
@@ -605,6 +778,12 @@ var (
 		Value:    ethconfig.Defaults.RPCTxFeeCap,
 		Category: flags.APICategory,
 	}
+	RPCCacheFlag = &cli.IntFlag{
+		Name:     "rpc.cache",
+		Usage:    "Number of responses of idempotent calls (blocks by hash, receipts, calls on finalized blocks) to cache (0 = disabled)",
+		Value:    ethconfig.Defaults.RPCCacheSize,
+		Category: flags.APICategory,
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = &cli.StringFlag{
 		Name:     "authrpc.addr",
@@ -705,6 +884,50 @@ var (
 		Value:    "",
 		Category: flags.APICategory,
 	}
+	RPCAccessLogFlag = &cli.StringFlag{
+		Name:     "rpc.accesslog",
+		Usage:    "File to write a JSON access log of the calls served over HTTP and WebSocket to",
+		Category: flags.APICategory,
+	}
+	RPCAccessLogSampleFlag = &cli.Float64Flag{
+		Name:     "rpc.accesslog.sample",
+		Usage:    "Fraction of successful calls to write to the access log (failed calls are always logged)",
+		Value:    node.DefaultConfig.RPCAccessLogSample,
+		Category: flags.APICategory,
+	}
+	RPCAccessLogRedactFlag = &cli.StringFlag{
+		Name:     "rpc.accesslog.redact",
+		Usage:    "Comma separated list of methods whose parameters are omitted from the access log ('*' suffix for prefixes)",
+		Value:    strings.Join(node.DefaultConfig.RPCAccessLogRedact, ","),
+		Category: flags.APICategory,
+	}
+	RPCAccessLogMaxSizeFlag = &cli.IntFlag{
+		Name:     "rpc.accesslog.maxsize",
+		Usage:    "Size in megabytes after which the access log is rotated (0 = no rotation)",
+		Value:    node.DefaultConfig.RPCAccessLogMaxSize,
+		Category: flags.APICategory,
+	}
+	RPCRateLimitFlag = &cli.Float64Flag{
+		Name:     "rpc.ratelimit",
+		Usage:    "Maximum average number of calls per second served to a client IP over HTTP and WebSocket (per-method limits via the config file)",
+		Category: flags.APICategory,
+	}
+	RPCRateLimitBurstFlag = &cli.IntFlag{
+		Name:     "rpc.ratelimit.burst",
+		Usage:    "Number of calls a client IP may make in a burst above --rpc.ratelimit (default = the rate)",
+		Category: flags.APICategory,
+	}
+	RPCSignResponsesFlag = &cli.BoolFlag{
+		Name:     "rpc.signresponses",
+		Usage:    "Sign the results of selected methods served over HTTP and WebSocket with the node key",
+		Category: flags.APICategory,
+	}
+	RPCSignedMethodsFlag = &cli.StringFlag{
+		Name:     "rpc.signresponses.methods",
+		Usage:    "Comma separated list of methods whose results are signed with --rpc.signresponses",
+		Value:    strings.Join(node.DefaultConfig.RPCSignedMethods, ","),
+		Category: flags.APICategory,
+	}
 	GraphQLEnabledFlag = &cli.BoolFlag{
 		Name:     "graphql",
 		Usage:    "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -780,6 +1003,33 @@ var (
 		Value:    node.DefaultConfig.P2P.MaxPeers,
 		Category: flags.NetworkingCategory,
 	}
+	AdaptivePeersFlag = &cli.BoolFlag{
+		Name:     "maxpeers.adaptive",
+		Usage:    "Lower the peer limit while CPU, disk or bandwidth are under pressure and raise it back when idle",
+		Category: flags.NetworkingCategory,
+	}
+	MinPeersFlag = &cli.IntFlag{
+		Name:     "maxpeers.min",
+		Usage:    "Lowest peer limit the adaptive peer count may scale down to (0 = 1/4 of maxpeers)",
+		Category: flags.NetworkingCategory,
+	}
+	AdaptivePeersCPUFlag = &cli.Float64Flag{
+		Name:     "maxpeers.adaptive.cpu",
+		Usage:    "Fraction of the total CPU time in use above which the adaptive peer limit is lowered (0 = disabled)",
+		Value:    node.DefaultConfig.P2P.AdaptiveCPULimit,
+		Category: flags.NetworkingCategory,
+	}
+	AdaptivePeersDiskWaitFlag = &cli.Float64Flag{
+		Name:     "maxpeers.adaptive.diskwait",
+		Usage:    "Fraction of the total CPU time spent waiting on disk above which the adaptive peer limit is lowered (0 = disabled)",
+		Value:    node.DefaultConfig.P2P.AdaptiveDiskWaitLimit,
+		Category: flags.NetworkingCategory,
+	}
+	AdaptivePeersBandwidthFlag = &cli.Uint64Flag{
+		Name:     "maxpeers.adaptive.bandwidth",
+		Usage:    "P2P traffic in bytes per second above which the adaptive peer limit is lowered (0 = disabled)",
+		Category: flags.NetworkingCategory,
+	}
 	MaxPendingPeersFlag = &cli.IntFlag{
 		Name:     "maxpendpeers",
 		Usage:    "Maximum number of pending connection attempts (defaults used if set to 0)",
@@ -969,6 +1219,56 @@ var (
 		Value:    metrics.DefaultConfig.InfluxDBOrganization,
 		Category: flags.MetricsCategory,
 	}
+
+	MetricsEnableOTLPFlag = &cli.BoolFlag{
+		Name:     "metrics.otlp",
+		Usage:    "Enable metrics export/push to an OpenTelemetry collector over OTLP/HTTP",
+		Category: flags.MetricsCategory,
+	}
+	MetricsOTLPEndpointFlag = &cli.StringFlag{
+		Name:     "metrics.otlp.endpoint",
+		Usage:    "OTLP/HTTP metrics endpoint of the OpenTelemetry collector",
+		Value:    metrics.DefaultConfig.OTLPEndpoint,
+		Category: flags.MetricsCategory,
+	}
+	MetricsOTLPResourceFlag = &cli.StringFlag{
+		Name:     "metrics.otlp.resource",
+		Usage:    "Comma-separated resource attributes (key=value) identifying the node to the collector",
+		Value:    metrics.DefaultConfig.OTLPResource,
+		Category: flags.MetricsCategory,
+	}
+	MetricsOTLPHeadersFlag = &cli.StringFlag{
+		Name:     "metrics.otlp.headers",
+		Usage:    "Comma-separated HTTP headers (key=value) sent to the collector, e.g. for authentication",
+		Category: flags.MetricsCategory,
+	}
+
+	// Stall detector flags
+	AlertsHeadTimeoutFlag = &cli.DurationFlag{
+		Name:     "alerts.head",
+		Usage:    "Alert if no new chain head was imported for the given time",
+		Category: flags.MetricsCategory,
+	}
+	AlertsMinPeersFlag = &cli.IntFlag{
+		Name:     "alerts.peers",
+		Usage:    "Alert if the peer count drops below the given number",
+		Category: flags.MetricsCategory,
+	}
+	AlertsRejectRateFlag = &cli.Float64Flag{
+		Name:     "alerts.txrejects",
+		Usage:    "Alert if the transaction pool rejects more transactions per second (requires --metrics)",
+		Category: flags.MetricsCategory,
+	}
+	AlertsForkchoiceTimeoutFlag = &cli.DurationFlag{
+		Name:     "alerts.forkchoice",
+		Usage:    "Alert if no forkchoice update was received for the given time after the merge",
+		Category: flags.MetricsCategory,
+	}
+	AlertsWebhookFlag = &cli.StringFlag{
+		Name:     "alerts.webhook",
+		Usage:    "HTTP URL to post alert notifications to",
+		Category: flags.MetricsCategory,
+	}
 )
 
 var (
@@ -989,6 +1289,9 @@ var (
 	DatabasePathFlags = []cli.Flag{
 		DataDirFlag,
 		AncientFlag,
+		DBEncryptionKeyFlag,
+		DBEngineFlag,
+		DBRemoteEndpointsFlag,
 		RemoteDBFlag,
 	}
 )
@@ -1190,6 +1493,30 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(HTTPPathPrefixFlag.Name) {
 		cfg.HTTPPathPrefix = ctx.String(HTTPPathPrefixFlag.Name)
 	}
+	if ctx.IsSet(RPCAccessLogFlag.Name) {
+		cfg.RPCAccessLog = ctx.String(RPCAccessLogFlag.Name)
+	}
+	if ctx.IsSet(RPCAccessLogSampleFlag.Name) {
+		cfg.RPCAccessLogSample = ctx.Float64(RPCAccessLogSampleFlag.Name)
+	}
+	if ctx.IsSet(RPCAccessLogRedactFlag.Name) {
+		cfg.RPCAccessLogRedact = SplitAndTrim(ctx.String(RPCAccessLogRedactFlag.Name))
+	}
+	if ctx.IsSet(RPCAccessLogMaxSizeFlag.Name) {
+		cfg.RPCAccessLogMaxSize = ctx.Int(RPCAccessLogMaxSizeFlag.Name)
+	}
+	if ctx.IsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit.Client = &rpc.RateLimit{
+			Rate:  ctx.Float64(RPCRateLimitFlag.Name),
+			Burst: ctx.Int(RPCRateLimitBurstFlag.Name),
+		}
+	}
+	if ctx.IsSet(RPCSignResponsesFlag.Name) {
+		cfg.RPCSignResponses = ctx.Bool(RPCSignResponsesFlag.Name)
+	}
+	if ctx.IsSet(RPCSignedMethodsFlag.Name) {
+		cfg.RPCSignedMethods = SplitAndTrim(ctx.String(RPCSignedMethodsFlag.Name))
+	}
 	if ctx.IsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.Bool(AllowUnprotectedTxs.Name)
 	}
@@ -1279,6 +1606,39 @@ func setLes(ctx *cli.Context, cfg *ethconfig.Config) {
 	}
 }
 
+// SetBlsyncConfig applies the beacon light client related command line flags to
+// the config.
+func SetBlsyncConfig(ctx *cli.Context, cfg *blsync.Config) {
+	switch {
+	case ctx.Bool(DeveloperFlag.Name):
+		cfg.Network = "dev"
+	case ctx.Bool(MainnetFlag.Name):
+		cfg.Network = "mainnet"
+	default:
+		for _, flag := range TestnetFlags {
+			if name := flag.Names()[0]; ctx.Bool(name) {
+				cfg.Network = name
+			}
+		}
+	}
+	if cfg.Network == "" {
+		cfg.Network = "mainnet"
+	}
+	if ctx.IsSet(BlsyncBeaconAPIFlag.Name) {
+		cfg.BeaconAPI = ctx.String(BlsyncBeaconAPIFlag.Name)
+	}
+	if ctx.IsSet(BlsyncCheckpointFlag.Name) {
+		checkpoint, err := hexutil.Decode(ctx.String(BlsyncCheckpointFlag.Name))
+		if err != nil || len(checkpoint) != common.HashLength {
+			Fatalf("Invalid beacon light client checkpoint %q", ctx.String(BlsyncCheckpointFlag.Name))
+		}
+		cfg.Checkpoint = common.BytesToHash(checkpoint)
+	}
+	if ctx.IsSet(BlsyncExecutionFlag.Name) {
+		cfg.ExecutionRPC = ctx.String(BlsyncExecutionFlag.Name)
+	}
+}
+
 // MakeDatabaseHandles raises out the number of allowed file handles per process
 // for Geth and returns half of the allowance to assign to the database.
 func MakeDatabaseHandles(max int) int {
@@ -1409,6 +1769,21 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	}
 	log.Info("Maximum peer count", "ETH", ethPeers, "LES", lightPeers, "total", cfg.MaxPeers)
 
+	if ctx.IsSet(AdaptivePeersFlag.Name) {
+		cfg.AdaptivePeers = ctx.Bool(AdaptivePeersFlag.Name)
+	}
+	if ctx.IsSet(MinPeersFlag.Name) {
+		cfg.MinPeers = ctx.Int(MinPeersFlag.Name)
+	}
+	if ctx.IsSet(AdaptivePeersCPUFlag.Name) {
+		cfg.AdaptiveCPULimit = ctx.Float64(AdaptivePeersCPUFlag.Name)
+	}
+	if ctx.IsSet(AdaptivePeersDiskWaitFlag.Name) {
+		cfg.AdaptiveDiskWaitLimit = ctx.Float64(AdaptivePeersDiskWaitFlag.Name)
+	}
+	if ctx.IsSet(AdaptivePeersBandwidthFlag.Name) {
+		cfg.AdaptiveBandwidthLimit = ctx.Uint64(AdaptivePeersBandwidthFlag.Name)
+	}
 	if ctx.IsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.Int(MaxPendingPeersFlag.Name)
 	}
@@ -1434,6 +1809,14 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NetRestrict = list
 	}
 
+	if ctx.IsSet(ReplicaSourceFlag.Name) && !ctx.Bool(StandbyFlag.Name) {
+		// Read replicas receive their blocks from the primary node only.
+		cfg.MaxPeers = 0
+		cfg.ListenAddr = ""
+		cfg.NoDial = true
+		cfg.NoDiscovery = true
+		cfg.DiscoveryV5 = false
+	}
 	if ctx.Bool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
 		cfg.MaxPeers = 0
@@ -1458,6 +1841,26 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(JWTSecretFlag.Name) {
 		cfg.JWTSecret = ctx.String(JWTSecretFlag.Name)
 	}
+	if ctx.Bool(StandbyFlag.Name) {
+		// Standby nodes take over the networking of the primary once promoted.
+		cfg.P2PStandby = true
+	}
+	if ctx.IsSet(DBEncryptionKeyFlag.Name) {
+		cfg.DBEncryptionKey = ctx.String(DBEncryptionKeyFlag.Name)
+	}
+	if ctx.IsSet(DBEngineFlag.Name) {
+		switch engine := ctx.String(DBEngineFlag.Name); engine {
+		case "leveldb":
+		case "remote":
+			cfg.DBEngine = engine
+			cfg.DBRemoteEndpoints = SplitAndTrim(ctx.String(DBRemoteEndpointsFlag.Name))
+			if len(cfg.DBRemoteEndpoints) == 0 {
+				Fatalf("Option %q is required by --%s=remote", DBRemoteEndpointsFlag.Name, DBEngineFlag.Name)
+			}
+		default:
+			Fatalf("Invalid choice for db.engine '%s', allowed 'leveldb' or 'remote'", engine)
+		}
+	}
 
 	if ctx.IsSet(ExternalSignerFlag.Name) {
 		cfg.ExternalSigner = ctx.String(ExternalSignerFlag.Name)
@@ -1478,6 +1881,12 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.IsSet(USBFlag.Name) {
 		cfg.USB = ctx.Bool(USBFlag.Name)
 	}
+	if ctx.IsSet(USBSimulatorFlag.Name) {
+		cfg.USBSimulatorSeed = ctx.String(USBSimulatorFlag.Name)
+	}
+	if ctx.IsSet(QRWalletKeysFlag.Name) {
+		cfg.QRWalletKeys = ctx.String(QRWalletKeysFlag.Name)
+	}
 	if ctx.IsSet(InsecureUnlockAllowedFlag.Name) {
 		cfg.InsecureUnlockAllowed = ctx.Bool(InsecureUnlockAllowedFlag.Name)
 	}
@@ -1642,11 +2051,38 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerNoVerifyFlag.Name) {
 		cfg.Noverify = ctx.Bool(MinerNoVerifyFlag.Name)
 	}
+	if ctx.IsSet(MinerSealApprovalFlag.Name) {
+		cfg.SealApproval = ctx.String(MinerSealApprovalFlag.Name)
+	}
+	if ctx.IsSet(MinerSealApprovalTimeoutFlag.Name) {
+		cfg.SealApprovalTimeout = ctx.Duration(MinerSealApprovalTimeoutFlag.Name)
+	}
+	if ctx.IsSet(MinerSealApprovalAllowFlag.Name) {
+		cfg.SealApprovalAllow = ctx.Bool(MinerSealApprovalAllowFlag.Name)
+	}
 	if ctx.IsSet(LegacyMinerGasTargetFlag.Name) {
 		log.Warn("The generic --miner.gastarget flag is deprecated and will be removed in the future!")
 	}
 }
 
+func setAlerts(ctx *cli.Context, cfg *alerts.Config) {
+	if ctx.IsSet(AlertsHeadTimeoutFlag.Name) {
+		cfg.HeadTimeout = ctx.Duration(AlertsHeadTimeoutFlag.Name)
+	}
+	if ctx.IsSet(AlertsMinPeersFlag.Name) {
+		cfg.MinPeers = ctx.Int(AlertsMinPeersFlag.Name)
+	}
+	if ctx.IsSet(AlertsRejectRateFlag.Name) {
+		cfg.RejectRate = ctx.Float64(AlertsRejectRateFlag.Name)
+	}
+	if ctx.IsSet(AlertsForkchoiceTimeoutFlag.Name) {
+		cfg.ForkchoiceTimeout = ctx.Duration(AlertsForkchoiceTimeoutFlag.Name)
+	}
+	if ctx.IsSet(AlertsWebhookFlag.Name) {
+		cfg.Webhook = ctx.String(AlertsWebhookFlag.Name)
+	}
+}
+
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
 	requiredBlocks := ctx.String(EthRequiredBlocksFlag.Name)
 	if requiredBlocks == "" {
@@ -1722,6 +2158,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, RopstenFlag, RinkebyFlag, GoerliFlag, SepoliaFlag, KilnFlag)
 	CheckExclusive(ctx, LightServeFlag, SyncModeFlag, "light")
 	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
+	CheckExclusive(ctx, NoTxIndexFlag, TxLookupLimitFlag)
 	if ctx.String(GCModeFlag.Name) == "archive" && ctx.Uint64(TxLookupLimitFlag.Name) != 0 {
 		ctx.Set(TxLookupLimitFlag.Name, "0")
 		log.Warn("Disable transaction unindexing for archive node")
@@ -1738,6 +2175,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
+	setAlerts(ctx, &cfg.Alerts)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
 
@@ -1764,6 +2202,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *flags.GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	if ctx.IsSet(HistoryMirrorsFlag.Name) {
+		cfg.HistoryMirrors = SplitAndTrim(ctx.String(HistoryMirrorsFlag.Name))
+	}
 	if ctx.IsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.Uint64(NetworkIdFlag.Name)
 	}
@@ -1793,6 +2234,42 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(NoTxIndexFlag.Name) {
+		cfg.NoTxIndex = ctx.Bool(NoTxIndexFlag.Name)
+	}
+	if ctx.IsSet(TxLookupBackfillFlag.Name) {
+		cfg.TxLookupBackfill = ctx.Uint64(TxLookupBackfillFlag.Name)
+	}
+	if ctx.IsSet(DeveloperForceReorgFlag.Name) {
+		cfg.AllowForceReorg = ctx.Bool(DeveloperForceReorgFlag.Name)
+	}
+	if ctx.IsSet(AddressLogIndexFlag.Name) {
+		cfg.AddressLogIndex = ctx.Bool(AddressLogIndexFlag.Name)
+	}
+	if ctx.IsSet(InternalTxIndexFlag.Name) {
+		cfg.InternalTxIndex = ctx.Bool(InternalTxIndexFlag.Name)
+	}
+	if ctx.IsSet(NoBloomIndexFlag.Name) {
+		cfg.NoBloomIndex = ctx.Bool(NoBloomIndexFlag.Name)
+	}
+	if ctx.IsSet(TxPoolAuditFlag.Name) {
+		cfg.TxAuditLimit = ctx.Int(TxPoolAuditFlag.Name)
+	}
+	if ctx.IsSet(ChangeLogDirFlag.Name) {
+		cfg.ChangeLogDir = ctx.String(ChangeLogDirFlag.Name)
+	}
+	if ctx.IsSet(ChangeLogKeyFileFlag.Name) {
+		cfg.ChangeLogKeyFile = ctx.String(ChangeLogKeyFileFlag.Name)
+	}
+	if ctx.IsSet(ReplicaSourceFlag.Name) {
+		cfg.ReplicaSource = ctx.String(ReplicaSourceFlag.Name)
+	}
+	if ctx.Bool(StandbyFlag.Name) {
+		if !ctx.IsSet(ReplicaSourceFlag.Name) {
+			Fatalf("Option --%s requires --%s", StandbyFlag.Name, ReplicaSourceFlag.Name)
+		}
+		cfg.Standby = true
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
 	}
@@ -1802,12 +2279,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CacheTrieRejournalFlag.Name) {
 		cfg.TrieCleanCacheRejournal = ctx.Duration(CacheTrieRejournalFlag.Name)
 	}
+	if ctx.IsSet(CacheTrieParallelismFlag.Name) {
+		cfg.TrieParallelism = ctx.Int(CacheTrieParallelismFlag.Name)
+	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheGCFlag.Name) {
 		cfg.TrieDirtyCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheGCFlag.Name) / 100
 	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheSnapshotFlag.Name) {
 		cfg.SnapshotCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheSnapshotFlag.Name) / 100
 	}
+	if ctx.IsSet(CacheAnalysisFlag.Name) {
+		cfg.AnalysisCache = ctx.Int(CacheAnalysisFlag.Name)
+	}
 	if !ctx.Bool(SnapshotFlag.Name) {
 		// If snap-sync is requested, this flag is also required
 		if cfg.SyncMode == downloader.SnapSync {
@@ -1839,6 +2322,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.Float64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.IsSet(RPCCacheFlag.Name) {
+		cfg.RPCCacheSize = ctx.Int(RPCCacheFlag.Name)
+	}
 	if ctx.IsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.IsSet(DNSDiscoveryFlag.Name) {
@@ -2013,6 +2499,42 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 	}
 }
 
+// RegisterBlsyncService adds a beacon light client following the chain instead
+// of the Ethereum service to the stack.
+func RegisterBlsyncService(stack *node.Node, cfg *blsync.Config) {
+	if _, err := blsync.New(stack, cfg); err != nil {
+		Fatalf("Failed to register the beacon light client: %v", err)
+	}
+}
+
+// RegisterCloneService configures the server streaming the chain database to
+// nodes cloning this one.
+func RegisterCloneService(stack *node.Node, chain *core.BlockChain, db ethdb.Database, addr string, secretFile string) {
+	if secretFile == "" {
+		Fatalf("Serving database clones requires --%s", CloneSecretFlag.Name)
+	}
+	secret, err := clone.LoadSecret(secretFile)
+	if err != nil {
+		Fatalf("Failed to load clone secret: %v", err)
+	}
+	clone.New(stack, chain, db, addr, secret)
+}
+
+// RegisterChangeLogService configures the gRPC server publishing the change-log
+// exported into dir to read replicas.
+func RegisterChangeLogService(stack *node.Node, dir string, addr string) {
+	if dir == "" {
+		Fatalf("Serving the change-log requires --%s", ChangeLogDirFlag.Name)
+	}
+	stack.RegisterLifecycle(replica.NewServer(stack.ResolvePath(dir), addr))
+}
+
+// RegisterStateService configures the gRPC server providing the chain state to
+// external EVM implementations.
+func RegisterStateService(stack *node.Node, chain *core.BlockChain, addr string) {
+	stack.RegisterLifecycle(remote.NewServer(chain, addr))
+}
+
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
 	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
@@ -2071,6 +2593,17 @@ func SetupMetrics(ctx *cli.Context) {
 			go influxdb.InfluxDBV2WithTags(metrics.DefaultRegistry, 10*time.Second, endpoint, token, bucket, organization, "geth.", tagsMap)
 		}
 
+		if ctx.Bool(MetricsEnableOTLPFlag.Name) {
+			var (
+				endpoint = ctx.String(MetricsOTLPEndpointFlag.Name)
+				resource = SplitTagsFlag(ctx.String(MetricsOTLPResourceFlag.Name))
+				headers  = splitHeadersFlag(ctx.String(MetricsOTLPHeadersFlag.Name))
+			)
+			log.Info("Enabling metrics export to OpenTelemetry collector", "endpoint", endpoint)
+
+			go otlp.OTLP(metrics.DefaultRegistry, 10*time.Second, endpoint, "geth.", resource, headers)
+		}
+
 		if ctx.IsSet(MetricsHTTPFlag.Name) {
 			address := fmt.Sprintf("%s:%d", ctx.String(MetricsHTTPFlag.Name), ctx.Int(MetricsPortFlag.Name))
 			log.Info("Enabling stand-alone metrics HTTP endpoint", "address", address)
@@ -2096,6 +2629,18 @@ func SplitTagsFlag(tagsFlag string) map[string]string {
 	return tagsMap
 }
 
+// splitHeadersFlag parses a comma-separated list of key=value HTTP headers. Unlike
+// tags, header values may contain '=' themselves, e.g. base64 encoded tokens.
+func splitHeadersFlag(headersFlag string) map[string]string {
+	headers := make(map[string]string)
+	for _, h := range strings.Split(headersFlag, ",") {
+		if kv := strings.SplitN(h, "=", 2); len(kv) == 2 {
+			headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return headers
+}
+
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.Database {
 	var (
@@ -2165,8 +2710,12 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		TrieDirtyLimit:      ethconfig.Defaults.TrieDirtyCache,
 		TrieDirtyDisabled:   ctx.String(GCModeFlag.Name) == "archive",
 		TrieTimeLimit:       ethconfig.Defaults.TrieTimeout,
+		TrieParallelism:     ctx.Int(CacheTrieParallelismFlag.Name),
 		SnapshotLimit:       ethconfig.Defaults.SnapshotCache,
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
+		// Keep maintaining an existing index, offline imports would leave gaps otherwise
+		AddressLogIndex: ctx.Bool(AddressLogIndexFlag.Name) || rawdb.ReadAddressLogIndexTail(chainDb) != nil,
+		InternalTxIndex: ctx.Bool(InternalTxIndexFlag.Name) || rawdb.ReadInternalTxIndexTail(chainDb) != nil,
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
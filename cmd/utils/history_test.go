@@ -0,0 +1,139 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestHistoryImportAndExport(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		db     = rawdb.NewMemoryDatabase()
+		gblock = genesis.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(genesis.Config, gblock, ethash.NewFaker(), db, 300, func(i int, g *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(g.TxNonce(address), common.Address{0x01}, big.NewInt(1), params.TxGas, g.BaseFee(), nil), signer, key)
+		g.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+	// Export the history and check the produced files
+	dir := t.TempDir()
+	if err := ExportHistory(chain, dir, 0, 300, 128); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	files, err := era.ReadDir(dir, "mainnet")
+	if err != nil {
+		t.Fatalf("error reading era1 directory: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("file count mismatch: have %d, want 3", len(files))
+	}
+	for i, file := range files {
+		e, err := era.Open(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("error opening %s: %v", file, err)
+		}
+		if want := uint64(i * 128); e.Start() != want {
+			t.Errorf("%s: start mismatch: have %d, want %d", file, e.Start(), want)
+		}
+		e.Close()
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "index.txt"))
+	if err != nil {
+		t.Fatalf("error reading index: %v", err)
+	}
+	want := fmt.Sprintf("%s 0 128\n%s 128 128\n%s 256 45", files[0], files[1], files[2])
+	if string(index) != want {
+		t.Errorf("index mismatch: have %q, want %q", index, want)
+	}
+	// Import the history into a fresh node without executing it
+	db2, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("error creating database: %v", err)
+	}
+	defer db2.Close()
+	genesis.MustCommit(db2)
+	imported, err := core.NewBlockChain(db2, nil, genesis.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer imported.Stop()
+	if err := ImportHistory(imported, db2, dir, "mainnet"); err != nil {
+		t.Fatalf("error importing history: %v", err)
+	}
+	if head := imported.CurrentFastBlock(); head.Hash() != chain.CurrentBlock().Hash() {
+		t.Fatalf("head mismatch: have #%d, want #%d", head.NumberU64(), chain.CurrentBlock().NumberU64())
+	}
+	if frozen, _ := db2.Ancients(); frozen != 301 {
+		t.Errorf("ancient count mismatch: have %d, want 301", frozen)
+	}
+	for _, want := range blocks {
+		block := imported.GetBlockByNumber(want.NumberU64())
+		if block == nil || block.Hash() != want.Hash() {
+			t.Fatalf("block #%d missing after import", want.NumberU64())
+		}
+		receipts := imported.GetReceiptsByHash(want.Hash())
+		if len(receipts) != 1 || receipts[0].TxHash != want.Transactions()[0].Hash() {
+			t.Fatalf("receipts of block #%d missing after import", want.NumberU64())
+		}
+	}
+	// Importing again is a no-op
+	if err := ImportHistory(imported, db2, dir, "mainnet"); err != nil {
+		t.Fatalf("error reimporting history: %v", err)
+	}
+	// Corrupted files must be rejected
+	path := filepath.Join(dir, files[1])
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[100] ^= 0xff
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportHistory(imported, db2, dir, "mainnet"); err == nil {
+		t.Fatal("expected checksum error for corrupted file")
+	}
+}
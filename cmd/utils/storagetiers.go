@@ -0,0 +1,89 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"io/fs"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/urfave/cli/v2"
+)
+
+// StorageTier describes the disk usage of one tier of the chain database.
+type StorageTier struct {
+	Name string // Name of the tier (hot or cold)
+	Path string // Directory the tier is stored in
+	Size uint64 // Total size of the files in the tier
+	Free uint64 // Free space left on the filesystem holding the tier
+}
+
+// DatabaseTiers reports the disk usage of the chain database, split into the hot
+// tier (key-value store with recent chain data and state) and the cold tier
+// (ancient store holding immutable chain data). The freezer migrates chain data
+// from the hot tier to the cold tier automatically once it is old enough, so
+// pointing --datadir.ancient at slower, larger storage keeps only the data that
+// is accessed frequently on the fast disk.
+func DatabaseTiers(ctx *cli.Context, stack *node.Node) ([]StorageTier, error) {
+	hot := stack.ResolvePath("chaindata")
+	cold := stack.ResolveAncient("chaindata", ctx.String(AncientFlag.Name))
+	return storageTiers(hot, cold)
+}
+
+func storageTiers(hot, cold string) ([]StorageTier, error) {
+	tiers := []StorageTier{{Name: "hot", Path: hot}, {Name: "cold", Path: cold}}
+	for i := range tiers {
+		// Don't count the cold tier into the hot one if it's nested inside
+		var skip string
+		if i == 0 {
+			skip = cold
+		}
+		size, err := directorySize(tiers[i].Path, skip)
+		if err != nil {
+			return nil, err
+		}
+		tiers[i].Size = size
+		if tiers[i].Free, err = getFreeDiskSpace(tiers[i].Path); err != nil {
+			return nil, err
+		}
+	}
+	return tiers, nil
+}
+
+// directorySize returns the total size of the files within a directory, except
+// for the ones within the skipped subdirectory.
+func directorySize(path string, skip string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if skip != "" && filepath.Clean(file) == filepath.Clean(skip) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += uint64(info.Size())
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStorageTiers(t *testing.T) {
+	var (
+		hot  = t.TempDir()
+		cold = filepath.Join(hot, "ancient")
+	)
+	if err := os.MkdirAll(cold, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(hot, "000001.ldb"), make([]byte, 100), 0644)
+	os.WriteFile(filepath.Join(cold, "headers.0000.cdat"), make([]byte, 1000), 0644)
+
+	tiers, err := storageTiers(hot, cold)
+	if err != nil {
+		t.Fatalf("failed to compute tiers: %v", err)
+	}
+	if len(tiers) != 2 {
+		t.Fatalf("wrong number of tiers: have %d, want 2", len(tiers))
+	}
+	if tiers[0].Name != "hot" || tiers[0].Size != 100 {
+		t.Errorf("hot tier mismatch: %+v", tiers[0])
+	}
+	if tiers[1].Name != "cold" || tiers[1].Size != 1000 {
+		t.Errorf("cold tier mismatch: %+v", tiers[1])
+	}
+	if tiers[0].Free == 0 {
+		t.Errorf("free space not reported")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package compiler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Artifact is a contract compiled by a development framework such as Foundry
+// or Hardhat.
+type Artifact struct {
+	Name      string            // Fully qualified name: source path and contract name separated by ":"
+	Contract  *Contract         // Compiled contract
+	Libraries map[string]string // Fully qualified names of the linked libraries, keyed by placeholder
+}
+
+// artifactJSON is the union of the Foundry and Hardhat artifact formats. The
+// bytecode fields are objects in Foundry artifacts and plain strings in Hardhat
+// ones, where the link references are stored at the top level instead.
+type artifactJSON struct {
+	ABI              interface{}     `json:"abi"`
+	Bytecode         json.RawMessage `json:"bytecode"`
+	DeployedBytecode json.RawMessage `json:"deployedBytecode"`
+
+	// Foundry specific fields
+	MethodIdentifiers map[string]string `json:"methodIdentifiers"`
+	Metadata          json.RawMessage   `json:"metadata"`
+	RawMetadata       string            `json:"rawMetadata"`
+
+	// Hardhat specific fields
+	ContractName   string                            `json:"contractName"`
+	SourceName     string                            `json:"sourceName"`
+	LinkReferences map[string]map[string]interface{} `json:"linkReferences"`
+}
+
+// artifactBytecode is the bytecode object of a Foundry artifact.
+type artifactBytecode struct {
+	Object         string                            `json:"object"`
+	LinkReferences map[string]map[string]interface{} `json:"linkReferences"`
+}
+
+// artifactMetadata is the part of the solc metadata naming the compiled contract.
+type artifactMetadata struct {
+	Compiler struct {
+		Version string `json:"version"`
+	} `json:"compiler"`
+	Language string `json:"language"`
+	Settings struct {
+		CompilationTarget map[string]string `json:"compilationTarget"`
+	} `json:"settings"`
+}
+
+// ParseArtifact parses a Foundry or Hardhat contract artifact. The path of the
+// artifact is used to name the contract if the artifact doesn't contain its
+// name, following Foundry's out/<source>/<contract>.json layout.
+//
+// Linked libraries are reported by the placeholders left in the creation code.
+// Immutable references only concern the runtime code, which is deployed by the
+// constructor, so they need no resolution for binding.
+func ParseArtifact(data []byte, path string) (*Artifact, error) {
+	var raw artifactJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid artifact: %v", err)
+	}
+	if raw.ABI == nil {
+		return nil, errors.New("artifact contains no abi")
+	}
+	var (
+		contract = &Contract{Hashes: raw.MethodIdentifiers}
+		links    = raw.LinkReferences
+		meta     artifactMetadata
+	)
+	contract.Info.AbiDefinition = raw.ABI
+
+	// Extract the bytecode from either artifact format
+	var code artifactBytecode
+	if err := json.Unmarshal(raw.Bytecode, &contract.Code); err != nil {
+		if err := json.Unmarshal(raw.Bytecode, &code); err != nil {
+			return nil, fmt.Errorf("invalid artifact bytecode: %v", err)
+		}
+		contract.Code, links = code.Object, code.LinkReferences
+	}
+	if err := json.Unmarshal(raw.DeployedBytecode, &contract.RuntimeCode); err != nil {
+		var runtime artifactBytecode
+		if err := json.Unmarshal(raw.DeployedBytecode, &runtime); err == nil {
+			contract.RuntimeCode = runtime.Object
+		}
+	}
+	contract.Code = strings.TrimPrefix(contract.Code, "0x")
+	contract.RuntimeCode = strings.TrimPrefix(contract.RuntimeCode, "0x")
+
+	// Foundry stores the metadata either as an object or as a raw string
+	if len(raw.Metadata) > 0 && json.Unmarshal(raw.Metadata, &meta) == nil {
+		contract.Info.Metadata = string(raw.Metadata)
+	} else if raw.RawMetadata != "" {
+		json.Unmarshal([]byte(raw.RawMetadata), &meta)
+		contract.Info.Metadata = raw.RawMetadata
+	}
+	contract.Info.Language = meta.Language
+	contract.Info.CompilerVersion = meta.Compiler.Version
+
+	// Name the contract by the most specific information available
+	artifact := &Artifact{Contract: contract, Libraries: make(map[string]string)}
+	switch {
+	case raw.ContractName != "":
+		artifact.Name = raw.SourceName + ":" + raw.ContractName
+	case len(meta.Settings.CompilationTarget) == 1:
+		for source, name := range meta.Settings.CompilationTarget {
+			artifact.Name = source + ":" + name
+		}
+	default:
+		artifact.Name = filepath.Base(filepath.Dir(path)) + ":" + strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	contract.Info.Source = artifact.Name[:strings.LastIndex(artifact.Name, ":")]
+
+	for source, libs := range links {
+		for name := range libs {
+			artifact.Libraries[LibraryPlaceholder(source+":"+name)] = source + ":" + name
+		}
+	}
+	return artifact, nil
+}
+
+// LibraryPlaceholder returns the placeholder solc leaves in bytecode linking the
+// library with the given fully qualified name, without the surrounding "__$"
+// and "$__" markers: a 34 character prefix of the hex encoded keccak256 hash of
+// the name.
+func LibraryPlaceholder(name string) string {
+	return crypto.Keccak256Hash([]byte(name)).String()[2:36]
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package compiler
+
+import (
+	"encoding/hex"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests that library placeholders match the ones emitted by solc.
+func TestLibraryPlaceholder(t *testing.T) {
+	tests := []struct {
+		name        string
+		placeholder string
+	}{
+		// Example from the solidity documentation
+		{"libraries/bigint.sol:BigInt", "30bbc0abd4d6364515865950d3e0d10953"},
+		// Placeholders linked by the testdata artifacts
+		{"src/Math.sol:Math", "22ef75b31e2d998cd01172b890884772a9"},
+		{"contracts/Math.sol:Math", "6ad30996409d058139477db06ae39abaac"},
+	}
+	for _, tt := range tests {
+		if have := LibraryPlaceholder(tt.name); have != tt.placeholder {
+			t.Errorf("%s: placeholder mismatch: have %s, want %s", tt.name, have, tt.placeholder)
+		}
+	}
+}
+
+// Tests that Foundry and Hardhat artifacts are parsed, named and their library
+// links resolved correctly.
+func TestParseArtifact(t *testing.T) {
+	tests := []struct {
+		path    string
+		name    string
+		version string
+		hashes  map[string]string
+		libs    map[string]string
+		offset  int // Byte offset of the library placeholder in the creation code
+	}{
+		{
+			path:    "testdata/foundry/out/UseLibrary.sol/UseLibrary.json",
+			name:    "src/UseLibrary.sol:UseLibrary",
+			version: "0.5.9+commit.e560f70d",
+			hashes:  map[string]string{"add(uint256,uint256)": "771602f7"},
+			libs:    map[string]string{"22ef75b31e2d998cd01172b890884772a9": "src/Math.sol:Math"},
+			offset:  131,
+		},
+		{
+			path:    "testdata/foundry/out/Math.sol/Math.json",
+			name:    "src/Math.sol:Math",
+			version: "0.5.9+commit.e560f70d",
+			hashes:  map[string]string{"add(uint256,uint256)": "771602f7"},
+			libs:    map[string]string{},
+		},
+		{
+			path:   "testdata/hardhat/artifacts/contracts/UseLibrary.sol/UseLibrary.json",
+			name:   "contracts/UseLibrary.sol:UseLibrary",
+			libs:   map[string]string{"6ad30996409d058139477db06ae39abaac": "contracts/Math.sol:Math"},
+			offset: 131,
+		},
+		{
+			path: "testdata/hardhat/artifacts/contracts/Math.sol/Math.json",
+			name: "contracts/Math.sol:Math",
+			libs: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(tt.path)
+		if err != nil {
+			t.Fatalf("%s: failed to read artifact: %v", tt.path, err)
+		}
+		artifact, err := ParseArtifact(data, tt.path)
+		if err != nil {
+			t.Fatalf("%s: failed to parse artifact: %v", tt.path, err)
+		}
+		if artifact.Name != tt.name {
+			t.Errorf("%s: name mismatch: have %s, want %s", tt.path, artifact.Name, tt.name)
+		}
+		if source := tt.name[:strings.LastIndex(tt.name, ":")]; artifact.Contract.Info.Source != source {
+			t.Errorf("%s: source mismatch: have %s, want %s", tt.path, artifact.Contract.Info.Source, source)
+		}
+		if artifact.Contract.Info.CompilerVersion != tt.version {
+			t.Errorf("%s: compiler version mismatch: have %s, want %s", tt.path, artifact.Contract.Info.CompilerVersion, tt.version)
+		}
+		if len(artifact.Contract.Hashes) != 0 || len(tt.hashes) != 0 {
+			if !reflect.DeepEqual(artifact.Contract.Hashes, tt.hashes) {
+				t.Errorf("%s: method hashes mismatch: have %v, want %v", tt.path, artifact.Contract.Hashes, tt.hashes)
+			}
+		}
+		if !reflect.DeepEqual(artifact.Libraries, tt.libs) {
+			t.Errorf("%s: libraries mismatch: have %v, want %v", tt.path, artifact.Libraries, tt.libs)
+		}
+		// Link the libraries into the creation and runtime code and ensure the
+		// result is valid bytecode with the placeholders where solc left them
+		code, runtime := artifact.Contract.Code, artifact.Contract.RuntimeCode
+		if strings.HasPrefix(code, "0x") || strings.HasPrefix(runtime, "0x") {
+			t.Errorf("%s: bytecode not stripped of hex prefix", tt.path)
+		}
+		for placeholder := range artifact.Libraries {
+			marker := "__$" + placeholder + "$__"
+			if index := strings.Index(code, marker); index != 2*tt.offset {
+				t.Errorf("%s: placeholder offset mismatch: have %d, want %d", tt.path, index/2, tt.offset)
+			}
+			address := strings.Repeat("11", 20)
+			code = strings.ReplaceAll(code, marker, address)
+			runtime = strings.ReplaceAll(runtime, marker, address)
+		}
+		if _, err := hex.DecodeString(code); err != nil {
+			t.Errorf("%s: invalid linked creation code: %v", tt.path, err)
+		}
+		if _, err := hex.DecodeString(runtime); err != nil {
+			t.Errorf("%s: invalid linked runtime code: %v", tt.path, err)
+		}
+	}
+}
+
+// Tests that artifacts without any embedded name are named by their path, and
+// that malformed artifacts are rejected.
+func TestParseArtifactFallbacks(t *testing.T) {
+	artifact, err := ParseArtifact([]byte(`{"abi": [], "bytecode": {"object": "0x6080"}}`), "out/Token.sol/Token.json")
+	if err != nil {
+		t.Fatalf("failed to parse artifact: %v", err)
+	}
+	if artifact.Name != "Token.sol:Token" {
+		t.Errorf("name mismatch: have %s, want %s", artifact.Name, "Token.sol:Token")
+	}
+	if artifact.Contract.Code != "6080" {
+		t.Errorf("code mismatch: have %s, want %s", artifact.Contract.Code, "6080")
+	}
+	failures := []struct {
+		data string
+		err  string
+	}{
+		{`not json`, "invalid artifact"},
+		{`{"bytecode": "0x6080"}`, "artifact contains no abi"},
+		{`{"abi": [], "bytecode": 5}`, "invalid artifact bytecode"},
+	}
+	for _, tt := range failures {
+		if _, err := ParseArtifact([]byte(tt.data), "out/Token.sol/Token.json"); err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %s", tt.data, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// errNotSoleSigner is returned when forging a branch while the local signer is
+// not the only authorized signer, so it can't seal consecutive blocks alone.
+var errNotSoleSigner = errors.New("local signer is not the only authorized signer")
+
+// ForgeBranch creates a branch of n empty blocks on top of parent, sealed by the
+// local signer. A branch longer than the canonical chain above parent is heavier
+// and replaces it once imported, which allows simulating reorgs on development
+// networks. As every block is sealed locally, the local signer must be the only
+// authorized one.
+//
+// The blocks are spaced by the block period, the last ones may thus be ahead of
+// the clock and can only be imported once their time has come.
+func (c *Clique) ForgeBranch(chain consensus.ChainHeaderReader, parent *types.Header, n int) ([]*types.Block, error) {
+	c.lock.RLock()
+	signer, signFn := c.signer, c.signFn
+	c.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errUnauthorizedSigner
+	}
+	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, authorized := snap.Signers[signer]; !authorized || len(snap.Signers) != 1 {
+		return nil, errNotSoleSigner
+	}
+	period := c.config.Period
+	if period == 0 {
+		period = 1
+	}
+	blocks := make([]*types.Block, 0, n)
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			ParentHash:  parent.Hash(),
+			UncleHash:   types.EmptyUncleHash,
+			Root:        parent.Root, // Empty blocks don't change the state in clique
+			TxHash:      types.EmptyRootHash,
+			ReceiptHash: types.EmptyRootHash,
+			Difficulty:  new(big.Int).Set(diffInTurn),
+			Number:      new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit:    parent.GasLimit,
+			Time:        parent.Time + period,
+			Extra:       make([]byte, extraVanity),
+		}
+		// Use a random vanity, so the branch differs from the canonical chain even
+		// if its blocks are empty with the same timestamps
+		if _, err := crand.Read(header.Extra); err != nil {
+			return nil, err
+		}
+		if header.Number.Uint64()%c.config.Epoch == 0 {
+			header.Extra = append(header.Extra, signer[:]...)
+		}
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+
+		if chain.Config().IsLondon(header.Number) {
+			header.BaseFee = misc.CalcBaseFee(chain.Config(), parent)
+		}
+		sighash, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeClique, CliqueRLP(header))
+		if err != nil {
+			return nil, err
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
+
+		blocks = append(blocks, types.NewBlockWithHeader(header))
+		parent = header
+	}
+	return blocks, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a forged branch replaces the head of a single signer chain.
+func TestForgeBranch(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = *params.AllCliqueProtocolChanges.Clique
+	)
+	config.Epoch = 4 // Make the branch span a checkpoint
+	engine := New(&config, db)
+	engine.Authorize(addr, func(account accounts.Account, mimeType string, message []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(message), key)
+	})
+	genspec := &core.Genesis{
+		Config:    params.AllCliqueProtocolChanges,
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+		Timestamp: uint64(time.Now().Unix()) - 100,
+	}
+	copy(genspec.ExtraData[extraVanity:], addr[:])
+	genesis := genspec.MustCommit(db)
+
+	chain, _ := core.NewBlockChain(db, nil, params.AllCliqueProtocolChanges, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	blocks, err := engine.ForgeBranch(chain, genesis.Header(), 6)
+	if err != nil {
+		t.Fatalf("failed to forge chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Replace the last three blocks
+	ancestor := chain.GetHeaderByNumber(3)
+	branch, err := engine.ForgeBranch(chain, ancestor, 4)
+	if err != nil {
+		t.Fatalf("failed to forge branch: %v", err)
+	}
+	if branch[0].Hash() == blocks[3].Hash() {
+		t.Fatal("branch identical to the canonical chain")
+	}
+	if signer, err := engine.Author(branch[0].Header()); err != nil || signer != addr {
+		t.Fatalf("signer mismatch: have %x (%v), want %x", signer, err, addr)
+	}
+	if _, err := chain.InsertChain(branch); err != nil {
+		t.Fatalf("failed to insert branch: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != branch[3].Hash() {
+		t.Fatalf("head mismatch: have %d (%x), want %d (%x)", head.NumberU64(), head.Hash(), branch[3].NumberU64(), branch[3].Hash())
+	}
+	// Forging fails if the local signer is not authorized
+	engine.Authorize(common.Address{1}, nil)
+	if _, err := engine.ForgeBranch(chain, ancestor, 1); err == nil {
+		t.Fatal("forged branch without being authorized")
+	}
+}
@@ -516,6 +516,12 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 				addresses = append(addresses, address)
 			}
 		}
+		// Propose dropping signers which stopped sealing, unless explicitly overridden
+		for _, inactive := range snap.inactive(number) {
+			if _, ok := c.proposals[inactive]; !ok && inactive != c.signer {
+				addresses = append(addresses, inactive)
+			}
+		}
 		// If there's pending proposals, cast a vote on them
 		if len(addresses) > 0 {
 			header.Coinbase = addresses[rand.Intn(len(addresses))]
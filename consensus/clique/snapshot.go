@@ -19,6 +19,7 @@ package clique
 import (
 	"bytes"
 	"encoding/json"
+	"math/big"
 	"sort"
 	"time"
 
@@ -57,6 +58,7 @@ type Snapshot struct {
 	Recents map[uint64]common.Address   `json:"recents"` // Set of recent signers for spam protections
 	Votes   []*Vote                     `json:"votes"`   // List of votes cast in chronological order
 	Tally   map[common.Address]Tally    `json:"tally"`   // Current vote tally to avoid recalculating
+	Sealed  map[common.Address]uint64   `json:"sealed"`  // Last block sealed by each signer (or its authorization)
 }
 
 // signersAscending implements the sort interface to allow sorting a list of addresses
@@ -78,9 +80,11 @@ func newSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, number uin
 		Signers:  make(map[common.Address]struct{}),
 		Recents:  make(map[uint64]common.Address),
 		Tally:    make(map[common.Address]Tally),
+		Sealed:   make(map[common.Address]uint64),
 	}
 	for _, signer := range signers {
 		snap.Signers[signer] = struct{}{}
+		snap.Sealed[signer] = number
 	}
 	return snap
 }
@@ -98,6 +102,13 @@ func loadSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, db ethdb.
 	snap.config = config
 	snap.sigcache = sigcache
 
+	// Snapshots stored before sealing activity was tracked start counting now
+	if snap.Sealed == nil {
+		snap.Sealed = make(map[common.Address]uint64)
+		for signer := range snap.Signers {
+			snap.Sealed[signer] = snap.Number
+		}
+	}
 	return snap, nil
 }
 
@@ -121,6 +132,7 @@ func (s *Snapshot) copy() *Snapshot {
 		Recents:  make(map[uint64]common.Address),
 		Votes:    make([]*Vote, len(s.Votes)),
 		Tally:    make(map[common.Address]Tally),
+		Sealed:   make(map[common.Address]uint64),
 	}
 	for signer := range s.Signers {
 		cpy.Signers[signer] = struct{}{}
@@ -131,6 +143,9 @@ func (s *Snapshot) copy() *Snapshot {
 	for address, tally := range s.Tally {
 		cpy.Tally[address] = tally
 	}
+	for signer, number := range s.Sealed {
+		cpy.Sealed[signer] = number
+	}
 	copy(cpy.Votes, s.Votes)
 
 	return cpy
@@ -143,24 +158,66 @@ func (s *Snapshot) validVote(address common.Address, authorize bool) bool {
 	return (signer && !authorize) || (!signer && authorize)
 }
 
-// cast adds a new vote into the tally.
-func (s *Snapshot) cast(address common.Address, authorize bool) bool {
+// weight returns the voting weight of a signer at the given block. Until the
+// weights fork activates, every signer has a weight of one.
+func (s *Snapshot) weight(signer common.Address, number uint64) int {
+	if !s.config.IsWeighted(new(big.Int).SetUint64(number)) {
+		return 1
+	}
+	if weight := s.config.Weights[signer]; weight > 0 {
+		return int(weight)
+	}
+	return 1
+}
+
+// threshold returns the vote weight a proposal needs to exceed to pass at the
+// given block, which is half of the total weight of the current signers.
+func (s *Snapshot) threshold(number uint64) int {
+	var total int
+	for signer := range s.Signers {
+		total += s.weight(signer, number)
+	}
+	return total / 2
+}
+
+// inactive retrieves the signers that have not sealed a block in the configured
+// number of epochs before the given block, in ascending order.
+func (s *Snapshot) inactive(number uint64) []common.Address {
+	if s.config.InactivityEpochs == 0 {
+		return nil
+	}
+	var (
+		limit = s.config.InactivityEpochs * s.config.Epoch
+		sigs  []common.Address
+	)
+	for _, signer := range s.signers() {
+		if number > s.Sealed[signer]+limit {
+			sigs = append(sigs, signer)
+		}
+	}
+	return sigs
+}
+
+// cast adds a new vote of the given signer, cast in the given block, into the
+// tally.
+func (s *Snapshot) cast(signer common.Address, number uint64, address common.Address, authorize bool) bool {
 	// Ensure the vote is meaningful
 	if !s.validVote(address, authorize) {
 		return false
 	}
 	// Cast the vote into an existing or new tally
 	if old, ok := s.Tally[address]; ok {
-		old.Votes++
+		old.Votes += s.weight(signer, number)
 		s.Tally[address] = old
 	} else {
-		s.Tally[address] = Tally{Authorize: authorize, Votes: 1}
+		s.Tally[address] = Tally{Authorize: authorize, Votes: s.weight(signer, number)}
 	}
 	return true
 }
 
-// uncast removes a previously cast vote from the tally.
-func (s *Snapshot) uncast(address common.Address, authorize bool) bool {
+// uncast removes a previously cast vote of the given signer, cast in the given
+// block, from the tally.
+func (s *Snapshot) uncast(signer common.Address, number uint64, address common.Address, authorize bool) bool {
 	// If there's no tally, it's a dangling vote, just drop
 	tally, ok := s.Tally[address]
 	if !ok {
@@ -171,8 +228,8 @@ func (s *Snapshot) uncast(address common.Address, authorize bool) bool {
 		return false
 	}
 	// Otherwise revert the vote
-	if tally.Votes > 1 {
-		tally.Votes--
+	if weight := s.weight(signer, number); tally.Votes > weight {
+		tally.Votes -= weight
 		s.Tally[address] = tally
 	} else {
 		delete(s.Tally, address)
@@ -228,12 +285,13 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 			}
 		}
 		snap.Recents[number] = signer
+		snap.Sealed[signer] = number
 
 		// Header authorized, discard any previous votes from the signer
 		for i, vote := range snap.Votes {
 			if vote.Signer == signer && vote.Address == header.Coinbase {
 				// Uncast the vote from the cached tally
-				snap.uncast(vote.Address, vote.Authorize)
+				snap.uncast(signer, vote.Block, vote.Address, vote.Authorize)
 
 				// Uncast the vote from the chronological list
 				snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
@@ -250,7 +308,7 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 		default:
 			return nil, errInvalidVote
 		}
-		if snap.cast(header.Coinbase, authorize) {
+		if snap.cast(signer, number, header.Coinbase, authorize) {
 			snap.Votes = append(snap.Votes, &Vote{
 				Signer:    signer,
 				Block:     number,
@@ -259,11 +317,13 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 			})
 		}
 		// If the vote passed, update the list of signers
-		if tally := snap.Tally[header.Coinbase]; tally.Votes > len(snap.Signers)/2 {
+		if tally := snap.Tally[header.Coinbase]; tally.Votes > snap.threshold(number) {
 			if tally.Authorize {
 				snap.Signers[header.Coinbase] = struct{}{}
+				snap.Sealed[header.Coinbase] = number
 			} else {
 				delete(snap.Signers, header.Coinbase)
+				delete(snap.Sealed, header.Coinbase)
 
 				// Signer list shrunk, delete any leftover recent caches
 				if limit := uint64(len(snap.Signers)/2 + 1); number >= limit {
@@ -273,7 +333,7 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 				for i := 0; i < len(snap.Votes); i++ {
 					if snap.Votes[i].Signer == header.Coinbase {
 						// Uncast the vote from the cached tally
-						snap.uncast(snap.Votes[i].Address, snap.Votes[i].Authorize)
+						snap.uncast(snap.Votes[i].Signer, snap.Votes[i].Block, snap.Votes[i].Address, snap.Votes[i].Authorize)
 
 						// Uncast the vote from the chronological list
 						snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 // testerAccountPool is a pool to maintain currently active tester accounts,
@@ -102,6 +103,8 @@ func TestClique(t *testing.T) {
 	tests := []struct {
 		epoch   uint64
 		signers []string
+		weights map[string]uint64
+		weighed uint64 // Block the weights activate at
 		votes   []testerVote
 		results []string
 		failure error
@@ -375,6 +378,46 @@ func TestClique(t *testing.T) {
 				{signer: "A", newbatch: true},
 			},
 			failure: errRecentlySigned,
+		}, {
+			// A heavy signer should be able to pass a proposal on its own
+			signers: []string{"A", "B", "C"},
+			weights: map[string]uint64{"A": 3},
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C", "D"},
+		}, {
+			// Light signers should not be able to outvote a heavy one
+			signers: []string{"A", "B", "C"},
+			weights: map[string]uint64{"C": 4},
+			votes: []testerVote{
+				{signer: "A", voted: "C", auth: false},
+				{signer: "B", voted: "C", auth: false},
+			},
+			results: []string{"A", "B", "C"},
+		}, {
+			// Votes cast before the weights fork count as a single vote, and are
+			// replaced by the weighted vote when cast again after the fork
+			signers: []string{"A", "B", "C"},
+			weights: map[string]uint64{"A": 3},
+			weighed: 3,
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B"},
+				{signer: "A", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C", "D"},
+		}, {
+			// Weights must not take effect before their fork block
+			signers: []string{"A", "B", "C"},
+			weights: map[string]uint64{"A": 3},
+			weighed: 4,
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B"},
+				{signer: "A", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C"},
 		},
 	}
 	// Run through the scenarios and test them
@@ -411,6 +454,13 @@ func TestClique(t *testing.T) {
 			Period: 1,
 			Epoch:  tt.epoch,
 		}
+		if tt.weights != nil {
+			config.Clique.Weights = make(map[common.Address]uint64)
+			config.Clique.WeightsBlock = new(big.Int).SetUint64(tt.weighed)
+			for signer, weight := range tt.weights {
+				config.Clique.Weights[accounts.address(signer)] = weight
+			}
+		}
 		engine := New(config.Clique, db)
 		engine.fakeDiff = true
 
@@ -504,3 +554,47 @@ func TestClique(t *testing.T) {
 		}
 	}
 }
+
+// Tests that signers which stopped sealing are reported as inactive once the
+// configured number of epochs passed, and that the tracking survives votes.
+func TestInactiveSigners(t *testing.T) {
+	var (
+		accounts    = newTesterAccountPool()
+		config      = &params.CliqueConfig{Period: 1, Epoch: 3, InactivityEpochs: 1}
+		signers     = []common.Address{accounts.address("A"), accounts.address("B"), accounts.address("C")}
+		sigcache, _ = lru.NewARC(inmemorySignatures)
+	)
+	snap := newSnapshot(config, sigcache, 0, common.Hash{}, signers)
+
+	seal := func(signer string, voted string) {
+		header := &types.Header{
+			Number:   new(big.Int).SetUint64(snap.Number + 1),
+			Coinbase: accounts.address(voted),
+			Extra:    make([]byte, extraVanity+extraSeal),
+		}
+		accounts.sign(header, signer)
+
+		var err error
+		if snap, err = snap.apply([]*types.Header{header}); err != nil {
+			t.Fatalf("failed to apply block %d: %v", header.Number, err)
+		}
+	}
+	seal("A", "")
+	seal("B", "")
+	if inactive := snap.inactive(snap.Number + 1); len(inactive) != 0 {
+		t.Fatalf("inactive signers reported too early: %x", inactive)
+	}
+	seal("A", "")
+	if inactive := snap.inactive(snap.Number + 1); len(inactive) != 1 || inactive[0] != accounts.address("C") {
+		t.Fatalf("inactive signers mismatch: have %x, want %x", inactive, accounts.address("C"))
+	}
+	// Dropping the inactive signer should stop reporting it
+	seal("B", "C")
+	seal("A", "C")
+	if _, ok := snap.Signers[accounts.address("C")]; ok {
+		t.Fatalf("inactive signer not dropped")
+	}
+	if inactive := snap.inactive(snap.Number + 1); len(inactive) != 0 {
+		t.Fatalf("inactive signers mismatch: have %x, want none", inactive)
+	}
+}
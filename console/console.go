@@ -142,6 +142,7 @@ func (c *Console) init(preload []string) error {
 	c.jsre.Do(func(vm *goja.Runtime) {
 		c.initAdmin(vm, bridge)
 		c.initPersonal(vm, bridge)
+		c.initUtils(vm)
 	})
 
 	// Preload JavaScript files.
@@ -0,0 +1,447 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/jsre"
+)
+
+// etherUnits maps the names of the ether denominations to their decimals, as in
+// the unit table of web3.js.
+var etherUnits = map[string]int{
+	"noether":    0,
+	"wei":        0,
+	"kwei":       3,
+	"babbage":    3,
+	"femtoether": 3,
+	"mwei":       6,
+	"lovelace":   6,
+	"picoether":  6,
+	"gwei":       9,
+	"shannon":    9,
+	"nanoether":  9,
+	"nano":       9,
+	"szabo":      12,
+	"microether": 12,
+	"micro":      12,
+	"finney":     15,
+	"milliether": 15,
+	"milli":      15,
+	"ether":      18,
+	"kether":     21,
+	"grand":      21,
+	"mether":     24,
+	"gether":     27,
+	"tether":     30,
+}
+
+// initUtils creates the utils object holding helpers implemented in Go, which
+// unlike their web3.js counterparts don't lose precision on large numbers.
+func (c *Console) initUtils(vm *goja.Runtime) {
+	utils := vm.NewObject()
+	utils.Set("toWei", jsre.MakeCallback(vm, toWei))
+	utils.Set("fromWei", jsre.MakeCallback(vm, fromWei))
+	utils.Set("keccak256", jsre.MakeCallback(vm, keccak256))
+	utils.Set("namehash", jsre.MakeCallback(vm, namehash))
+	utils.Set("toChecksumAddress", jsre.MakeCallback(vm, toChecksumAddress))
+	utils.Set("isChecksumAddress", jsre.MakeCallback(vm, isChecksumAddress))
+
+	abiObj := vm.NewObject()
+	abiObj.Set("encode", jsre.MakeCallback(vm, abiEncode))
+	abiObj.Set("decode", jsre.MakeCallback(vm, abiDecode))
+	utils.Set("abi", abiObj)
+
+	vm.Set("utils", utils)
+}
+
+// unitDecimals returns the decimals of the unit given as optional argument n of
+// the call, defaulting to ether.
+func unitDecimals(call jsre.Call, n int) (int, error) {
+	unit := call.Argument(n)
+	if goja.IsUndefined(unit) || goja.IsNull(unit) {
+		return etherUnits["ether"], nil
+	}
+	decimals, ok := etherUnits[strings.ToLower(unit.String())]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", unit.String())
+	}
+	return decimals, nil
+}
+
+// parseNumber parses a JS number, a decimal or hex string or a BigNumber into an
+// exact rational value.
+func parseNumber(v goja.Value) (*big.Rat, error) {
+	if goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, errors.New("missing number")
+	}
+	s := strings.TrimSpace(v.String())
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		n, err := hexutil.DecodeBig(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %v", s, err)
+		}
+		return new(big.Rat).SetInt(n), nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	return r, nil
+}
+
+// parseInteger is like parseNumber, but rejects fractional values.
+func parseInteger(v goja.Value) (*big.Int, error) {
+	r, err := parseNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsInt() {
+		return nil, fmt.Errorf("%s is not an integer", r.FloatString(18))
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// toWei converts an amount in the given unit into wei, returned as a decimal
+// string.
+func toWei(call jsre.Call) (goja.Value, error) {
+	if len(call.Arguments) < 1 {
+		return nil, errors.New("usage: utils.toWei(<amount>, [unit])")
+	}
+	amount, err := parseNumber(call.Argument(0))
+	if err != nil {
+		return nil, err
+	}
+	decimals, err := unitDecimals(call, 1)
+	if err != nil {
+		return nil, err
+	}
+	wei := amount.Mul(amount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	if !wei.IsInt() {
+		return nil, fmt.Errorf("too many decimal places for unit with %d decimals", decimals)
+	}
+	return call.VM.ToValue(wei.Num().String()), nil
+}
+
+// fromWei converts an amount of wei into the given unit, returned as a decimal
+// string without trailing zeroes.
+func fromWei(call jsre.Call) (goja.Value, error) {
+	if len(call.Arguments) < 1 {
+		return nil, errors.New("usage: utils.fromWei(<wei>, [unit])")
+	}
+	wei, err := parseInteger(call.Argument(0))
+	if err != nil {
+		return nil, err
+	}
+	decimals, err := unitDecimals(call, 1)
+	if err != nil {
+		return nil, err
+	}
+	amount := new(big.Rat).SetFrac(wei, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	s := amount.FloatString(decimals)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return call.VM.ToValue(s), nil
+}
+
+// parseData interprets a 0x-prefixed string as hex encoded bytes, and any
+// other string as UTF-8 text.
+func parseData(v goja.Value) ([]byte, error) {
+	s := v.String()
+	if strings.HasPrefix(s, "0x") {
+		return hexutil.Decode(s)
+	}
+	return []byte(s), nil
+}
+
+// keccak256 hashes hex encoded data or text.
+func keccak256(call jsre.Call) (goja.Value, error) {
+	if len(call.Arguments) != 1 {
+		return nil, errors.New("usage: utils.keccak256(<hex data or text>)")
+	}
+	data, err := parseData(call.Argument(0))
+	if err != nil {
+		return nil, err
+	}
+	return call.VM.ToValue(crypto.Keccak256Hash(data).Hex()), nil
+}
+
+// namehash computes the ENS node of a name as defined by EIP-137. The name is
+// expected to be normalized already.
+func namehash(call jsre.Call) (goja.Value, error) {
+	if len(call.Arguments) != 1 {
+		return nil, errors.New("usage: utils.namehash(<name>)")
+	}
+	return call.VM.ToValue(ensNode(call.Argument(0).String()).Hex()), nil
+}
+
+func ensNode(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node[:], crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// toChecksumAddress returns the EIP-55 checksummed form of an address.
+func toChecksumAddress(call jsre.Call) (goja.Value, error) {
+	if len(call.Arguments) != 1 {
+		return nil, errors.New("usage: utils.toChecksumAddress(<address>)")
+	}
+	addr := call.Argument(0).String()
+	if !common.IsHexAddress(addr) {
+		return nil, fmt.Errorf("invalid address %q", addr)
+	}
+	return call.VM.ToValue(common.HexToAddress(addr).Hex()), nil
+}
+
+// isChecksumAddress reports whether an address is in its EIP-55 checksummed form.
+func isChecksumAddress(call jsre.Call) (goja.Value, error) {
+	if len(call.Arguments) != 1 {
+		return nil, errors.New("usage: utils.isChecksumAddress(<address>)")
+	}
+	addr := call.Argument(0).String()
+	if !strings.HasPrefix(addr, "0x") || !common.IsHexAddress(addr) {
+		return call.VM.ToValue(false), nil
+	}
+	return call.VM.ToValue(common.HexToAddress(addr).Hex() == addr), nil
+}
+
+// abiArguments parses a JS array of ABI type names, e.g. ["uint256", "address[]"].
+func abiArguments(v goja.Value) (abi.Arguments, error) {
+	names, ok := v.Export().([]interface{})
+	if !ok {
+		return nil, errors.New("types must be an array of strings")
+	}
+	args := make(abi.Arguments, len(names))
+	for i, name := range names {
+		s, ok := name.(string)
+		if !ok {
+			return nil, fmt.Errorf("type %d is not a string", i)
+		}
+		typ, err := abi.NewType(s, "", nil)
+		if err != nil {
+			return nil, fmt.Errorf("type %d: %v", i, err)
+		}
+		args[i] = abi.Argument{Type: typ}
+	}
+	return args, nil
+}
+
+// abiEncode ABI encodes values of the given types, e.g.
+// utils.abi.encode(["address", "uint256"], ["0x...", "1000"]).
+func abiEncode(call jsre.Call) (goja.Value, error) {
+	if len(call.Arguments) != 2 {
+		return nil, errors.New("usage: utils.abi.encode(<types>, <values>)")
+	}
+	args, err := abiArguments(call.Argument(0))
+	if err != nil {
+		return nil, err
+	}
+	values, err := jsArray(call.VM, call.Argument(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(args) {
+		return nil, fmt.Errorf("have %d values for %d types", len(values), len(args))
+	}
+	packed := make([]interface{}, len(args))
+	for i, arg := range args {
+		v, err := toABIValue(call.VM, arg.Type, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %v", i, err)
+		}
+		packed[i] = v.Interface()
+	}
+	data, err := args.Pack(packed...)
+	if err != nil {
+		return nil, err
+	}
+	return call.VM.ToValue(hexutil.Encode(data)), nil
+}
+
+// abiDecode decodes ABI encoded data into values of the given types. Integers
+// are returned as decimal strings to preserve their precision.
+func abiDecode(call jsre.Call) (goja.Value, error) {
+	if len(call.Arguments) != 2 {
+		return nil, errors.New("usage: utils.abi.decode(<types>, <hex data>)")
+	}
+	args, err := abiArguments(call.Argument(0))
+	if err != nil {
+		return nil, err
+	}
+	data, err := hexutil.Decode(call.Argument(1).String())
+	if err != nil {
+		return nil, err
+	}
+	values, err := args.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, len(values))
+	for i, arg := range args {
+		result[i] = fromABIValue(arg.Type, reflect.ValueOf(values[i]))
+	}
+	return call.VM.ToValue(result), nil
+}
+
+// jsArray returns the elements of a JS array.
+func jsArray(vm *goja.Runtime, v goja.Value) ([]goja.Value, error) {
+	if goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, errors.New("missing array")
+	}
+	obj := v.ToObject(vm)
+	if obj.ClassName() != "Array" {
+		return nil, errors.New("value is not an array")
+	}
+	items := make([]goja.Value, obj.Get("length").ToInteger())
+	for i := range items {
+		items[i] = obj.Get(strconv.Itoa(i))
+	}
+	return items, nil
+}
+
+// toABIValue converts a JS value into the Go type the abi package packs for the
+// given ABI type.
+func toABIValue(vm *goja.Runtime, typ abi.Type, v goja.Value) (reflect.Value, error) {
+	out := reflect.New(typ.GetType()).Elem()
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		n, err := parseInteger(v)
+		if err != nil {
+			return out, err
+		}
+		if typ.T == abi.UintTy && n.Sign() < 0 {
+			return out, fmt.Errorf("negative value %v for %v", n, typ)
+		}
+		if !fitsABIInteger(n, typ) {
+			return out, fmt.Errorf("value %v overflows %v", n, typ)
+		}
+		switch out.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			out.SetInt(n.Int64())
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			out.SetUint(n.Uint64())
+		default:
+			out.Set(reflect.ValueOf(n))
+		}
+	case abi.BoolTy:
+		out.SetBool(v.ToBoolean())
+	case abi.StringTy:
+		out.SetString(v.String())
+	case abi.AddressTy:
+		if !common.IsHexAddress(v.String()) {
+			return out, fmt.Errorf("invalid address %q", v.String())
+		}
+		out.Set(reflect.ValueOf(common.HexToAddress(v.String())))
+	case abi.BytesTy:
+		data, err := hexutil.Decode(v.String())
+		if err != nil {
+			return out, err
+		}
+		out.SetBytes(data)
+	case abi.FixedBytesTy:
+		data, err := hexutil.Decode(v.String())
+		if err != nil {
+			return out, err
+		}
+		if len(data) != typ.Size {
+			return out, fmt.Errorf("have %d bytes for %v", len(data), typ)
+		}
+		reflect.Copy(out, reflect.ValueOf(data))
+	case abi.SliceTy, abi.ArrayTy:
+		items, err := jsArray(vm, v)
+		if err != nil {
+			return out, err
+		}
+		if typ.T == abi.ArrayTy && len(items) != typ.Size {
+			return out, fmt.Errorf("have %d elements for %v", len(items), typ)
+		}
+		if typ.T == abi.SliceTy {
+			out.Set(reflect.MakeSlice(out.Type(), len(items), len(items)))
+		}
+		for i, item := range items {
+			elem, err := toABIValue(vm, *typ.Elem, item)
+			if err != nil {
+				return out, err
+			}
+			out.Index(i).Set(elem)
+		}
+	default:
+		return out, fmt.Errorf("unsupported type %v", typ)
+	}
+	return out, nil
+}
+
+// fitsABIInteger reports whether the integer is within the range of the given
+// intN or uintN type.
+func fitsABIInteger(n *big.Int, typ abi.Type) bool {
+	if typ.T == abi.UintTy {
+		return n.Sign() >= 0 && n.BitLen() <= typ.Size
+	}
+	// Signed values span [-2^(N-1), 2^(N-1)-1]
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(typ.Size-1))
+	if n.Sign() < 0 {
+		return n.CmpAbs(limit) <= 0
+	}
+	return n.Cmp(limit) < 0
+}
+
+// fromABIValue converts a value unpacked by the abi package into a JS friendly
+// representation.
+func fromABIValue(typ abi.Type, v reflect.Value) interface{} {
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		if value, ok := v.Interface().(*big.Int); ok {
+			return value.String()
+		}
+		if v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64 {
+			return strconv.FormatInt(v.Int(), 10)
+		}
+		return strconv.FormatUint(v.Uint(), 10)
+	case abi.AddressTy:
+		return v.Interface().(common.Address).Hex()
+	case abi.BytesTy, abi.FixedBytesTy, abi.FunctionTy:
+		blob := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(blob), v)
+		return hexutil.Encode(blob)
+	case abi.SliceTy, abi.ArrayTy:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = fromABIValue(*typ.Elem, v.Index(i))
+		}
+		return items
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package console
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+func TestUtils(t *testing.T) {
+	vm := goja.New()
+	new(Console).initUtils(vm)
+
+	tests := []struct {
+		code string
+		want string
+		err  string
+	}{
+		{code: `utils.toWei("1")`, want: "1000000000000000000"},
+		{code: `utils.toWei(1.5, "gwei")`, want: "1500000000"},
+		{code: `utils.toWei("123456789.123456789123456789", "ether")`, want: "123456789123456789123456789"},
+		{code: `utils.toWei("1e3", "wei")`, want: "1000"},
+		{code: `utils.toWei("0.1", "wei")`, err: "too many decimal places"},
+		{code: `utils.toWei("1", "foo")`, err: "unknown unit"},
+		{code: `utils.fromWei("123456789012345678901234567890")`, want: "123456789012.34567890123456789"},
+		{code: `utils.fromWei("0x3b9aca00", "gwei")`, want: "1"},
+		{code: `utils.fromWei("0")`, want: "0"},
+		{code: `utils.fromWei("1.5")`, err: "not an integer"},
+		{code: `utils.keccak256("")`, want: "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{code: `utils.keccak256("0x")`, want: "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{code: `utils.keccak256("transfer(address,uint256)").slice(0, 10)`, want: "0xa9059cbb"},
+		{code: `utils.namehash("")`, want: "0x0000000000000000000000000000000000000000000000000000000000000000"},
+		{code: `utils.namehash("eth")`, want: "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae"},
+		{code: `utils.namehash("foo.eth")`, want: "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f"},
+		{code: `utils.toChecksumAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")`, want: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{code: `utils.toChecksumAddress("0x1234")`, err: "invalid address"},
+		{code: `utils.isChecksumAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")`, want: "true"},
+		{code: `utils.isChecksumAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")`, want: "false"},
+		{
+			code: `utils.abi.encode(["uint256", "address"], ["1", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"])`,
+			want: "0x0000000000000000000000000000000000000000000000000000000000000001" +
+				"0000000000000000000000005aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		},
+		{code: `utils.abi.encode(["uint8"], [256])`, err: "overflows uint8"},
+		{code: `utils.abi.encode(["uint72"], ["0x1000000000000000000"])`, err: "overflows uint72"},
+		{code: `utils.abi.encode(["uint256"], ["115792089237316195423570985008687907853269984665640564039457584007913129639936"])`, err: "overflows uint256"},
+		{code: `utils.abi.encode(["int72"], ["0x800000000000000000"])`, err: "overflows int72"},
+		{code: `utils.abi.encode(["int72"], ["-0x800000000000000001"])`, err: "overflows int72"},
+		{
+			code: `utils.abi.encode(["int72", "uint72"], ["-0x800000000000000000", "0xffffffffffffffffff"])`,
+			want: "0xffffffffffffffffffffffffffffffffffffffffffffff800000000000000000" +
+				"0000000000000000000000000000000000000000000000ffffffffffffffffff",
+		},
+		{code: `utils.abi.encode(["uint256"], [-1])`, err: "negative value"},
+		{code: `utils.abi.encode(["bytes4"], ["0x1234"])`, err: "have 2 bytes"},
+		{code: `utils.abi.encode(["uint256"], [])`, err: "have 0 values for 1 types"},
+		{
+			code: `JSON.stringify(utils.abi.decode(["uint256", "int8", "address", "bytes", "string[]", "bool"],
+				utils.abi.encode(["uint256", "int8", "address", "bytes", "string[]", "bool"],
+					["115792089237316195423570985008687907853269984665640564039457584007913129639935", -5,
+					 "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", "0xcafe", ["a", "b"], true])))`,
+			want: `["115792089237316195423570985008687907853269984665640564039457584007913129639935","-5",` +
+				`"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed","0xcafe",["a","b"],true]`,
+		},
+	}
+	for i, test := range tests {
+		result, err := vm.RunString(test.code)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("test %d: error mismatch: have %v, want %q", i, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if have := result.String(); have != test.want {
+			t.Errorf("test %d: result mismatch:\nhave %s\nwant %s", i, have, test.want)
+		}
+	}
+}
@@ -17,9 +17,9 @@
 package asm
 
 import (
-	"testing"
-
 	"encoding/hex"
+	"fmt"
+	"testing"
 )
 
 // Tests disassembling the instructions for valid evm code
@@ -72,3 +72,42 @@ func TestInstructionIteratorEmpty(t *testing.T) {
 		t.Errorf("Expected 0, but got %v instead.", cnt)
 	}
 }
+
+// Tests lifting a minimal ABI dispatcher into basic blocks.
+func TestLift(t *testing.T) {
+	// PUSH1 0, CALLDATALOAD, PUSH1 0xe0, SHR, DUP1, PUSH4 0xaabbccdd, EQ,
+	// PUSH1 0x12, JUMPI, DUP1, REVERT, JUMPDEST, STOP
+	code, _ := hex.DecodeString("600035" + "60e01c" + "8063aabbccdd14" + "601257" + "80fd" + "5b00")
+	prog := Lift(code)
+
+	if len(prog.Blocks) != 3 {
+		t.Fatalf("block count mismatch: have %d, want 3", len(prog.Blocks))
+	}
+	want := []struct {
+		start, end uint64
+		jumpdest   bool
+		succs      []uint64
+	}{
+		{0, 16, false, []uint64{16, 18}},
+		{16, 18, false, nil},
+		{18, 20, true, nil},
+	}
+	for i, block := range prog.Blocks {
+		if block.Start != want[i].start || block.End != want[i].end || block.JumpDest != want[i].jumpdest {
+			t.Errorf("block %d: have [%d, %d) jumpdest %v, want [%d, %d) jumpdest %v", i,
+				block.Start, block.End, block.JumpDest, want[i].start, want[i].end, want[i].jumpdest)
+		}
+		if fmt.Sprint(block.Succs) != fmt.Sprint(want[i].succs) {
+			t.Errorf("block %d: successors mismatch: have %v, want %v", i, block.Succs, want[i].succs)
+		}
+	}
+	if selector, ok := prog.Selectors[18]; !ok || hex.EncodeToString(selector[:]) != "aabbccdd" {
+		t.Errorf("selector mismatch: have %x (%v), want aabbccdd", selector, ok)
+	}
+	if block := prog.Block(17); block == nil || block.Start != 16 {
+		t.Errorf("block lookup mismatch: have %v", block)
+	}
+	if block := prog.Block(20); block != nil {
+		t.Errorf("expected no block past the code, have %v", block)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package asm
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Instruction is a single decoded EVM instruction.
+type Instruction struct {
+	PC  uint64
+	Op  vm.OpCode
+	Arg []byte // Immediate argument of PUSH instructions
+}
+
+// Block is a basic block of EVM code: a sequence of instructions which is only
+// entered at its first instruction and only left after its last one.
+type Block struct {
+	Start    uint64        // PC of the first instruction
+	End      uint64        // PC following the last instruction
+	JumpDest bool          // Whether the block starts with a valid jump destination
+	Instrs   []Instruction // Instructions of the block in code order
+	Succs    []uint64      // Statically known successor blocks
+}
+
+// Program is the control flow structure lifted from EVM code.
+type Program struct {
+	Blocks    []*Block           // Basic blocks in code order
+	Selectors map[uint64][4]byte // Function selectors dispatched to, keyed by entry block
+}
+
+// Lift splits the code into basic blocks, resolves the jump targets pushed right
+// before a jump and detects the function selectors of the ABI dispatcher. Code
+// following a truncated PUSH at the end is ignored.
+func Lift(code []byte) *Program {
+	prog := &Program{Selectors: make(map[uint64][4]byte)}
+
+	var (
+		it    = NewInstructionIterator(code)
+		block *Block
+	)
+	for it.Next() {
+		instr := Instruction{PC: it.PC(), Op: it.Op(), Arg: it.Arg()}
+		if block != nil && instr.Op == vm.JUMPDEST {
+			block.Succs = append(block.Succs, instr.PC)
+			block = nil
+		}
+		if block == nil {
+			block = &Block{Start: instr.PC, JumpDest: instr.Op == vm.JUMPDEST}
+			prog.Blocks = append(prog.Blocks, block)
+		}
+		block.Instrs = append(block.Instrs, instr)
+		block.End = instr.PC + 1 + uint64(len(instr.Arg))
+
+		switch instr.Op {
+		case vm.JUMPI:
+			block.Succs = append(block.Succs, block.End)
+			fallthrough
+		case vm.JUMP, vm.STOP, vm.RETURN, vm.REVERT, vm.INVALID, vm.SELFDESTRUCT:
+			block = nil
+		}
+	}
+	// Resolve the jump targets and dispatcher entries now that all jump
+	// destinations are known.
+	for _, block := range prog.Blocks {
+		n := len(block.Instrs)
+		if n < 2 {
+			continue
+		}
+		last := block.Instrs[n-1]
+		if last.Op != vm.JUMP && last.Op != vm.JUMPI {
+			continue
+		}
+		dest, ok := prog.jumpTarget(block.Instrs[n-2])
+		if !ok {
+			continue
+		}
+		block.Succs = append(block.Succs, dest)
+		if last.Op == vm.JUMPI {
+			if selector, ok := dispatchSelector(block.Instrs[:n-2]); ok {
+				prog.Selectors[dest] = selector
+			}
+		}
+	}
+	return prog
+}
+
+// jumpTarget returns the valid jump destination pushed by the instruction.
+func (p *Program) jumpTarget(push Instruction) (uint64, bool) {
+	if !push.Op.IsPush() || len(push.Arg) > 8 {
+		return 0, false
+	}
+	dest := new(big.Int).SetBytes(push.Arg).Uint64()
+	if block := p.Block(dest); block == nil || block.Start != dest || !block.JumpDest {
+		return 0, false
+	}
+	return dest, true
+}
+
+// dispatchSelector matches the tail of the instructions preceding the PUSH of a
+// conditional jump against the selector comparison emitted by Solidity and Vyper
+// dispatchers, i.e. a PUSH4 followed by an EQ with at most one instruction (the
+// DUP of the calldata selector) in between.
+func dispatchSelector(instrs []Instruction) (selector [4]byte, ok bool) {
+	n := len(instrs)
+	if n < 2 || instrs[n-1].Op != vm.EQ {
+		return selector, false
+	}
+	for i := n - 2; i >= 0 && i >= n-3; i-- {
+		if instrs[i].Op == vm.PUSH4 {
+			copy(selector[:], instrs[i].Arg)
+			return selector, true
+		}
+	}
+	return selector, false
+}
+
+// Block returns the basic block containing the given PC, or nil if the PC is
+// not at or inside an instruction of the code.
+func (p *Program) Block(pc uint64) *Block {
+	i := sort.Search(len(p.Blocks), func(i int) bool { return p.Blocks[i].End > pc })
+	if i < len(p.Blocks) && p.Blocks[i].Start <= pc {
+		return p.Blocks[i]
+	}
+	return nil
+}
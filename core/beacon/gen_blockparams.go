@@ -18,11 +18,15 @@ func (p PayloadAttributesV1) MarshalJSON() ([]byte, error) {
 		Timestamp             hexutil.Uint64 `json:"timestamp"     gencodec:"required"`
 		Random                common.Hash    `json:"prevRandao"        gencodec:"required"`
 		SuggestedFeeRecipient common.Address `json:"suggestedFeeRecipient"  gencodec:"required"`
+		Withdrawals           []*Withdrawal  `json:"withdrawals"`
+		BeaconRoot            *common.Hash   `json:"parentBeaconBlockRoot"`
 	}
 	var enc PayloadAttributesV1
 	enc.Timestamp = hexutil.Uint64(p.Timestamp)
 	enc.Random = p.Random
 	enc.SuggestedFeeRecipient = p.SuggestedFeeRecipient
+	enc.Withdrawals = p.Withdrawals
+	enc.BeaconRoot = p.BeaconRoot
 	return json.Marshal(&enc)
 }
 
@@ -32,6 +36,8 @@ func (p *PayloadAttributesV1) UnmarshalJSON(input []byte) error {
 		Timestamp             *hexutil.Uint64 `json:"timestamp"     gencodec:"required"`
 		Random                *common.Hash    `json:"prevRandao"        gencodec:"required"`
 		SuggestedFeeRecipient *common.Address `json:"suggestedFeeRecipient"  gencodec:"required"`
+		Withdrawals           []*Withdrawal   `json:"withdrawals"`
+		BeaconRoot            *common.Hash    `json:"parentBeaconBlockRoot"`
 	}
 	var dec PayloadAttributesV1
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -49,5 +55,11 @@ func (p *PayloadAttributesV1) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'suggestedFeeRecipient' for PayloadAttributesV1")
 	}
 	p.SuggestedFeeRecipient = *dec.SuggestedFeeRecipient
+	if dec.Withdrawals != nil {
+		p.Withdrawals = dec.Withdrawals
+	}
+	if dec.BeaconRoot != nil {
+		p.BeaconRoot = dec.BeaconRoot
+	}
 	return nil
 }
@@ -17,6 +17,7 @@
 package beacon
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -33,6 +34,8 @@ type PayloadAttributesV1 struct {
 	Timestamp             uint64         `json:"timestamp"     gencodec:"required"`
 	Random                common.Hash    `json:"prevRandao"        gencodec:"required"`
 	SuggestedFeeRecipient common.Address `json:"suggestedFeeRecipient"  gencodec:"required"`
+	Withdrawals           []*Withdrawal  `json:"withdrawals"`
+	BeaconRoot            *common.Hash   `json:"parentBeaconBlockRoot"`
 }
 
 // JSON type overrides for PayloadAttributesV1.
@@ -58,6 +61,9 @@ type ExecutableDataV1 struct {
 	BaseFeePerGas *big.Int       `json:"baseFeePerGas" gencodec:"required"`
 	BlockHash     common.Hash    `json:"blockHash"     gencodec:"required"`
 	Transactions  [][]byte       `json:"transactions"  gencodec:"required"`
+	Withdrawals   []*Withdrawal  `json:"withdrawals"`
+	BlobGasUsed   *uint64        `json:"blobGasUsed"`
+	ExcessBlobGas *uint64        `json:"excessBlobGas"`
 }
 
 // JSON type overrides for executableData.
@@ -70,6 +76,37 @@ type executableDataMarshaling struct {
 	ExtraData     hexutil.Bytes
 	LogsBloom     hexutil.Bytes
 	Transactions  []hexutil.Bytes
+	BlobGasUsed   *hexutil.Uint64
+	ExcessBlobGas *hexutil.Uint64
+}
+
+// Withdrawal is a validator withdrawal of the consensus layer (EIP-4895). They
+// are not supported by this chain, payloads and payload attributes carry an
+// empty list since Prague.
+type Withdrawal struct {
+	Index     hexutil.Uint64 `json:"index"`
+	Validator hexutil.Uint64 `json:"validatorIndex"`
+	Address   common.Address `json:"address"`
+	Amount    hexutil.Uint64 `json:"amount"`
+}
+
+// BlobsBundleV1 holds the blobs of a payload along with their commitments and
+// proofs. Blob transactions are not supported by this chain, the bundle is
+// always empty.
+type BlobsBundleV1 struct {
+	Commitments []hexutil.Bytes `json:"commitments"`
+	Proofs      []hexutil.Bytes `json:"proofs"`
+	Blobs       []hexutil.Bytes `json:"blobs"`
+}
+
+// ExecutionPayloadEnvelope is the response of engine_getPayloadV4, holding the
+// payload along with its value and execution layer requests (EIP-7685).
+type ExecutionPayloadEnvelope struct {
+	ExecutionPayload  *ExecutableDataV1 `json:"executionPayload"`
+	BlockValue        *hexutil.Big      `json:"blockValue"`
+	BlobsBundle       *BlobsBundleV1    `json:"blobsBundle"`
+	Override          bool              `json:"shouldOverrideBuilder"`
+	ExecutionRequests []hexutil.Bytes   `json:"executionRequests"`
 }
 
 type PayloadStatusV1 struct {
@@ -140,7 +177,12 @@ func decodeTransactions(enc [][]byte) ([]*types.Transaction, error) {
 // 		uncleHash = emptyUncleHash
 // 		difficulty = 0
 // and that the blockhash of the constructed block matches the parameters.
-func ExecutableDataToBlock(params ExecutableDataV1) (*types.Block, error) {
+//
+// Payloads since Prague come with the beacon root of the parent block and the
+// execution layer requests, both of which are committed to by the block hash,
+// and must be nil before. The requests are expected in the encoding of
+// types.Requests.Encode.
+func ExecutableDataToBlock(params ExecutableDataV1, beaconRoot *common.Hash, requests [][]byte) (*types.Block, error) {
 	txs, err := decodeTransactions(params.Transactions)
 	if err != nil {
 		return nil, err
@@ -172,6 +214,32 @@ func ExecutableDataToBlock(params ExecutableDataV1) (*types.Block, error) {
 		Extra:       params.ExtraData,
 		MixDigest:   params.Random,
 	}
+	if beaconRoot == nil {
+		if params.Withdrawals != nil || params.BlobGasUsed != nil || params.ExcessBlobGas != nil || requests != nil {
+			return nil, errors.New("unexpected Prague fields in payload")
+		}
+	} else {
+		// Neither withdrawals nor blobs are supported, the corresponding fields
+		// must hold the empty values.
+		if params.Withdrawals == nil || params.BlobGasUsed == nil || params.ExcessBlobGas == nil || requests == nil {
+			return nil, errors.New("missing Prague fields in payload")
+		}
+		if len(params.Withdrawals) != 0 {
+			return nil, fmt.Errorf("unsupported withdrawals: %d", len(params.Withdrawals))
+		}
+		if *params.BlobGasUsed != 0 || *params.ExcessBlobGas != 0 {
+			return nil, fmt.Errorf("unsupported blob gas: used %d, excess %d", *params.BlobGasUsed, *params.ExcessBlobGas)
+		}
+		var (
+			withdrawalsHash = types.EmptyRootHash
+			root            = *beaconRoot
+			requestsHash    = types.CalcRequestsHash(requests)
+		)
+		header.WithdrawalsHash = &withdrawalsHash
+		header.BlobGasUsed, header.ExcessBlobGas = new(uint64), new(uint64)
+		header.ParentBeaconRoot = &root
+		header.RequestsHash = &requestsHash
+	}
 	block := types.NewBlockWithHeader(header).WithBody(txs, nil /* uncles */)
 	if block.Hash() != params.BlockHash {
 		return nil, fmt.Errorf("blockhash mismatch, want %x, got %x", params.BlockHash, block.Hash())
@@ -182,7 +250,7 @@ func ExecutableDataToBlock(params ExecutableDataV1) (*types.Block, error) {
 // BlockToExecutableData constructs the executableDataV1 structure by filling the
 // fields from the given block. It assumes the given block is post-merge block.
 func BlockToExecutableData(block *types.Block) *ExecutableDataV1 {
-	return &ExecutableDataV1{
+	data := &ExecutableDataV1{
 		BlockHash:     block.Hash(),
 		ParentHash:    block.ParentHash(),
 		FeeRecipient:  block.Coinbase(),
@@ -198,4 +266,25 @@ func BlockToExecutableData(block *types.Block) *ExecutableDataV1 {
 		Random:        block.MixDigest(),
 		ExtraData:     block.Extra(),
 	}
+	if header := block.Header(); header.WithdrawalsHash != nil {
+		data.Withdrawals = []*Withdrawal{}
+		data.BlobGasUsed, data.ExcessBlobGas = header.BlobGasUsed, header.ExcessBlobGas
+	}
+	return data
+}
+
+// BlockToExecutionPayloadEnvelope constructs the engine_getPayloadV4 response of
+// the given block, its value to the fee recipient and its requests.
+func BlockToExecutionPayloadEnvelope(block *types.Block, value *big.Int, requests types.Requests) *ExecutionPayloadEnvelope {
+	encoded := requests.Encode()
+	executionRequests := make([]hexutil.Bytes, len(encoded))
+	for i, list := range encoded {
+		executionRequests[i] = list
+	}
+	return &ExecutionPayloadEnvelope{
+		ExecutionPayload:  BlockToExecutableData(block),
+		BlockValue:        (*hexutil.Big)(value),
+		BlobsBundle:       &BlobsBundleV1{Commitments: []hexutil.Bytes{}, Proofs: []hexutil.Bytes{}, Blobs: []hexutil.Bytes{}},
+		ExecutionRequests: executionRequests,
+	}
 }
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/consensus"
@@ -65,6 +66,9 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	if err := verifyPragueFields(v.config, header); err != nil {
+		return err
+	}
 	if !v.bc.HasBlockAndState(block.ParentHash(), block.NumberU64()-1) {
 		if !v.bc.HasBlock(block.ParentHash(), block.NumberU64()-1) {
 			return consensus.ErrUnknownAncestor
@@ -74,6 +78,31 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	return nil
 }
 
+// verifyPragueFields checks that the header fields following the base fee are
+// present exactly since Prague. The requests hash is checked against the actual
+// requests when processing the block. As neither withdrawals nor blobs exist on
+// this chain, their fields must hold the empty values.
+func verifyPragueFields(config *params.ChainConfig, header *types.Header) error {
+	if !config.IsPrague(header.Number) {
+		if header.WithdrawalsHash != nil || header.BlobGasUsed != nil || header.ExcessBlobGas != nil ||
+			header.ParentBeaconRoot != nil || header.RequestsHash != nil {
+			return errors.New("unexpected Prague header fields before Prague")
+		}
+		return nil
+	}
+	switch {
+	case header.WithdrawalsHash == nil || header.BlobGasUsed == nil || header.ExcessBlobGas == nil || header.ParentBeaconRoot == nil:
+		return errors.New("missing Cancun header fields since Prague")
+	case header.RequestsHash == nil:
+		return errors.New("missing requests hash since Prague")
+	case *header.WithdrawalsHash != types.EmptyRootHash:
+		return fmt.Errorf("invalid withdrawals hash: have %x, want %x", *header.WithdrawalsHash, types.EmptyRootHash)
+	case *header.BlobGasUsed != 0 || *header.ExcessBlobGas != 0:
+		return fmt.Errorf("invalid blob gas: used %d, excess %d", *header.BlobGasUsed, *header.ExcessBlobGas)
+	}
+	return nil
+}
+
 // ValidateState validates the various changes that happen after a state
 // transition, such as amount of used gas, the receipt roots and the state root
 // itself. ValidateState returns a database batch if the validation was a success
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"sort"
 	"sync"
@@ -89,6 +90,8 @@ const (
 	blockCacheLimit     = 256
 	receiptsCacheLimit  = 32
 	txLookupCacheLimit  = 1024
+	txLookupMissLimit   = 4096
+	maxTxLookupBackfill = 4
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
 	TriesInMemory       = 128
@@ -128,9 +131,17 @@ type CacheConfig struct {
 	TrieCleanNoPrefetch bool          // Whether to disable heuristic state prefetching for followup blocks
 	TrieDirtyLimit      int           // Memory limit (MB) at which to start flushing dirty trie nodes to disk
 	TrieDirtyDisabled   bool          // Whether to disable trie write caching and GC altogether (archive node)
+	TrieDirtyMax        int           // Memory limit (MB) of dirty trie nodes above which flushing is forced (0 = twice TrieDirtyLimit)
+	TrieFlushLimit      int           // Memory limit (MB) of dirty trie nodes flushed to disk per block (0 = unlimited)
+	TrieReorgDepth      uint64        // Number of recent state tries kept in memory for reorgs (at least TriesInMemory)
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
+	TrieParallelism     int           // Number of threads to hash and commit large tries with (0 = number of CPUs)
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	AddressLogIndex     bool          // Whether to index blocks by the addresses emitting logs in them
+	InternalTxIndex     bool          // Whether to index the value transfers of nested calls by address
+	NoTxIndex           bool          // Whether to disable (and drop) the transaction index
+	TxLookupBackfill    uint64        // Number of unindexed blocks to search on transaction lookup misses
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -168,6 +179,8 @@ type BlockChain struct {
 	triegc *prque.Prque   // Priority queue mapping block numbers to tries to gc
 	gcproc time.Duration  // Accumulates canonical block processing for trie dumping
 
+	flushPolicy atomic.Value // Policy of flushing dirty trie nodes (*TrieFlushPolicy), changeable at runtime
+
 	// txLookupLimit is the maximum number of blocks from head whose tx indices
 	// are reserved:
 	//  * 0:   means no limit and regenerate any missing indexes
@@ -193,6 +206,7 @@ type BlockChain struct {
 	currentFastBlock      atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 	currentFinalizedBlock atomic.Value // Current finalized head
 	currentSafeBlock      atomic.Value // Current safe head
+	milestone             atomic.Value // Latest signed milestone the chain must not reorg past
 
 	stateCache    state.Database // State database to reuse between imports (contains state cache)
 	bodyCache     *lru.Cache     // Cache for the most recent block bodies
@@ -200,6 +214,8 @@ type BlockChain struct {
 	receiptsCache *lru.Cache     // Cache for the most recent receipts per block
 	blockCache    *lru.Cache     // Cache for the most recent entire blocks
 	txLookupCache *lru.Cache     // Cache for the most recent transaction lookup data.
+	txLookupMiss  *lru.Cache     // Cache for the transactions not found by the backfill since the last head
+	txLookupSlots chan struct{}  // Semaphore limiting the concurrent transaction lookup backfills
 	futureBlocks  *lru.Cache     // future blocks are blocks added for later processing
 
 	wg            sync.WaitGroup //
@@ -227,6 +243,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	receiptsCache, _ := lru.New(receiptsCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
 	txLookupCache, _ := lru.New(txLookupCacheLimit)
+	txLookupMiss, _ := lru.New(txLookupMissLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 
 	bc := &BlockChain{
@@ -235,9 +252,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		db:          db,
 		triegc:      prque.New(nil),
 		stateCache: state.NewDatabaseWithConfig(db, &trie.Config{
-			Cache:     cacheConfig.TrieCleanLimit,
-			Journal:   cacheConfig.TrieCleanJournal,
-			Preimages: cacheConfig.Preimages,
+			Cache:       cacheConfig.TrieCleanLimit,
+			Journal:     cacheConfig.TrieCleanJournal,
+			Preimages:   cacheConfig.Preimages,
+			Parallelism: cacheConfig.TrieParallelism,
 		}),
 		quit:          make(chan struct{}),
 		chainmu:       syncx.NewClosableMutex(),
@@ -246,6 +264,8 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		receiptsCache: receiptsCache,
 		blockCache:    blockCache,
 		txLookupCache: txLookupCache,
+		txLookupMiss:  txLookupMiss,
+		txLookupSlots: make(chan struct{}, maxTxLookupBackfill),
 		futureBlocks:  futureBlocks,
 		engine:        engine,
 		vmConfig:      vmConfig,
@@ -265,6 +285,8 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		return nil, ErrNoGenesis
 	}
 
+	bc.flushPolicy.Store(newTrieFlushPolicy(cacheConfig))
+
 	var nilBlock *types.Block
 	bc.currentBlock.Store(nilBlock)
 	bc.currentFastBlock.Store(nilBlock)
@@ -388,6 +410,41 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	bc.wg.Add(1)
 	go bc.updateFutureBlocks()
 
+	// Start the address log index at the next block if newly enabled, or drop
+	// it if disabled, as it would have gaps afterwards. Entries left over by a
+	// drop which did not complete are deleted in either case.
+	current := bc.CurrentBlock().NumberU64()
+	if bc.cacheConfig.AddressLogIndex {
+		if rawdb.ReadAddressLogIndexTail(bc.db) == nil {
+			rawdb.WriteAddressLogIndexTail(bc.db, current+1)
+			bc.dropIndex("address log", rawdb.DeleteAddressLogIndex, current+1)
+		}
+	} else {
+		if rawdb.ReadAddressLogIndexTail(bc.db) != nil {
+			log.Info("Dropping address log index")
+			rawdb.DeleteAddressLogIndexTail(bc.db)
+		}
+		bc.dropIndex("address log", rawdb.DeleteAddressLogIndex, math.MaxUint64)
+	}
+	// Likewise for the internal transaction index, which needs its own tracer
+	// on block imports.
+	if bc.cacheConfig.InternalTxIndex && bc.vmConfig.Debug {
+		log.Warn("Internal transaction index unavailable while tracing imports")
+		bc.cacheConfig.InternalTxIndex = false
+	}
+	if bc.cacheConfig.InternalTxIndex {
+		if rawdb.ReadInternalTxIndexTail(bc.db) == nil {
+			rawdb.WriteInternalTxIndexTail(bc.db, current+1)
+			bc.dropIndex("internal transaction", rawdb.DeleteInternalTxIndex, current+1)
+		}
+	} else {
+		if rawdb.ReadInternalTxIndexTail(bc.db) != nil {
+			log.Info("Dropping internal transaction index")
+			rawdb.DeleteInternalTxIndexTail(bc.db)
+		}
+		bc.dropIndex("internal transaction", rawdb.DeleteInternalTxIndex, math.MaxUint64)
+	}
+
 	// Start tx indexer/unindexer.
 	if txLookupLimit != nil {
 		bc.txLookupLimit = *txLookupLimit
@@ -508,6 +565,45 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	return err
 }
 
+// Milestone is a signed block number and hash pair which the canonical chain is
+// required to contain. Reorgs replacing the milestone block are rejected.
+type Milestone struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// SetMilestone sets the latest milestone, overriding any previous one.
+func (bc *BlockChain) SetMilestone(milestone Milestone) {
+	bc.milestone.Store(milestone)
+	if hash := rawdb.ReadCanonicalHash(bc.db, milestone.Number); hash != (common.Hash{}) && hash != milestone.Hash {
+		log.Error("Local chain conflicts with milestone", "number", milestone.Number, "have", hash, "want", milestone.Hash)
+	}
+}
+
+// CurrentMilestone retrieves the latest milestone, if any was set.
+func (bc *BlockChain) CurrentMilestone() (Milestone, bool) {
+	milestone, ok := bc.milestone.Load().(Milestone)
+	return milestone, ok
+}
+
+// checkMilestone verifies that a reorg onto a new chain segment, ordered from
+// head towards the common ancestor with the old chain, doesn't replace the
+// milestone block. Reorgs forking off below the milestone are only allowed if
+// the new chain contains the milestone block itself.
+func (bc *BlockChain) checkMilestone(commonBlock *types.Block, newChain types.Blocks) error {
+	milestone, ok := bc.CurrentMilestone()
+	if !ok || commonBlock.NumberU64() >= milestone.Number {
+		return nil
+	}
+	if len(newChain) == 0 || newChain[0].NumberU64() < milestone.Number {
+		return fmt.Errorf("%w: number %d, fork at %d", ErrMilestoneReorg, milestone.Number, commonBlock.NumberU64())
+	}
+	if block := newChain[newChain[0].NumberU64()-milestone.Number]; block.Hash() != milestone.Hash {
+		return fmt.Errorf("%w: number %d, have %x, want %x", ErrMilestoneReorg, milestone.Number, block.Hash(), milestone.Hash)
+	}
+	return nil
+}
+
 // SetFinalized sets the finalized block.
 func (bc *BlockChain) SetFinalized(block *types.Block) {
 	bc.currentFinalizedBlock.Store(block)
@@ -683,6 +779,7 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, root common.Hash, repair bo
 	bc.receiptsCache.Purge()
 	bc.blockCache.Purge()
 	bc.txLookupCache.Purge()
+	bc.txLookupMiss.Purge()
 	bc.futureBlocks.Purge()
 
 	// Clear safe block, finalized block if needed
@@ -716,6 +813,7 @@ func (bc *BlockChain) SnapSyncCommitHead(hash common.Hash) error {
 	}
 	bc.currentBlock.Store(block)
 	headBlockGauge.Update(int64(block.NumberU64()))
+	bc.txLookupMiss.Purge()
 	bc.chainmu.Unlock()
 
 	// Destroy any existing state snapshot and regenerate it in the background,
@@ -813,7 +911,9 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 	rawdb.WriteHeadHeaderHash(batch, block.Hash())
 	rawdb.WriteHeadFastBlockHash(batch, block.Hash())
 	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
-	rawdb.WriteTxLookupEntriesByBlock(batch, block)
+	if !bc.cacheConfig.NoTxIndex {
+		rawdb.WriteTxLookupEntriesByBlock(batch, block)
+	}
 	rawdb.WriteHeadBlockHash(batch, block.Hash())
 
 	// Flush the whole batch into the disk, exit the node if failed
@@ -828,6 +928,9 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 
 	bc.currentBlock.Store(block)
 	headBlockGauge.Update(int64(block.NumberU64()))
+
+	// The new head may contain or index transactions missed before
+	bc.txLookupMiss.Purge()
 }
 
 // Stop stops the blockchain service. If any imports are currently in progress
@@ -853,6 +956,12 @@ func (bc *BlockChain) Stop() {
 	bc.chainmu.Close()
 	bc.wg.Wait()
 
+	// Wait for the running transaction lookup backfills by taking all their
+	// slots, they abort on the closed quit channel.
+	for i := 0; i < cap(bc.txLookupSlots); i++ {
+		bc.txLookupSlots <- struct{}{}
+	}
+
 	// Ensure that the entirety of the state snapshot is journalled to disk.
 	var snapBase common.Hash
 	if bc.snaps != nil {
@@ -1056,11 +1165,16 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		// generated.
 		var batch = bc.db.NewBatch()
 		for i, block := range blockChain {
-			if bc.txLookupLimit == 0 || ancientLimit <= bc.txLookupLimit || block.NumberU64() >= ancientLimit-bc.txLookupLimit {
+			if bc.cacheConfig.NoTxIndex {
+				// Transaction index disabled, skip
+			} else if bc.txLookupLimit == 0 || ancientLimit <= bc.txLookupLimit || block.NumberU64() >= ancientLimit-bc.txLookupLimit {
 				rawdb.WriteTxLookupEntriesByBlock(batch, block)
 			} else if rawdb.ReadTxIndexTail(bc.db) != nil {
 				rawdb.WriteTxLookupEntriesByBlock(batch, block)
 			}
+			if bc.cacheConfig.AddressLogIndex {
+				rawdb.WriteAddressLogIndex(batch, block.NumberU64(), receiptChain[i])
+			}
 			stats.processed++
 
 			if batch.ValueSize() > ethdb.IdealBatchSize || i == len(blockChain)-1 {
@@ -1142,7 +1256,12 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 			// Write all the data out into the database
 			rawdb.WriteBody(batch, block.Hash(), block.NumberU64(), block.Body())
 			rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receiptChain[i])
-			rawdb.WriteTxLookupEntriesByBlock(batch, block) // Always write tx indices for live blocks, we assume they are needed
+			if !bc.cacheConfig.NoTxIndex {
+				rawdb.WriteTxLookupEntriesByBlock(batch, block) // Always write tx indices for live blocks, we assume they are needed
+			}
+			if bc.cacheConfig.AddressLogIndex {
+				rawdb.WriteAddressLogIndex(batch, block.NumberU64(), receiptChain[i])
+			}
 
 			// Write everything belongs to the blocks into the database. So that
 			// we can ensure all components of body is completed(body, receipts,
@@ -1201,6 +1320,14 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 	}
 
 	head := blockChain[len(blockChain)-1]
+
+	// Blocks imported without execution have no internal transfers to index, move
+	// the index tail past them
+	if bc.cacheConfig.InternalTxIndex {
+		if tail := rawdb.ReadInternalTxIndexTail(bc.db); tail != nil && *tail <= head.NumberU64() {
+			rawdb.WriteInternalTxIndexTail(bc.db, head.NumberU64()+1)
+		}
+	}
 	context := []interface{}{
 		"count", stats.processed, "elapsed", common.PrettyDuration(time.Since(start)),
 		"number", head.Number(), "hash", head.Hash(), "age", common.PrettyAge(time.Unix(int64(head.Time()), 0)),
@@ -1248,7 +1375,7 @@ func (bc *BlockChain) writeKnownBlock(block *types.Block) error {
 
 // writeBlockWithState writes block, metadata and corresponding state data to the
 // database.
-func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB) error {
+func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, itxs []*types.InternalTx, state *state.StateDB) error {
 	// Calculate the total difficulty of the block
 	ptd := bc.GetTd(block.ParentHash(), block.NumberU64()-1)
 	if ptd == nil {
@@ -1266,6 +1393,12 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	rawdb.WriteBlock(blockBatch, block)
 	rawdb.WriteReceipts(blockBatch, block.Hash(), block.NumberU64(), receipts)
 	rawdb.WritePreimages(blockBatch, state.Preimages())
+	if bc.cacheConfig.AddressLogIndex {
+		rawdb.WriteAddressLogIndex(blockBatch, block.NumberU64(), receipts)
+	}
+	if bc.cacheConfig.InternalTxIndex {
+		rawdb.WriteInternalTxIndex(blockBatch, block.Hash(), block.NumberU64(), itxs)
+	}
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
@@ -1284,46 +1417,59 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 		triedb.Reference(root, common.Hash{}) // metadata reference to keep trie alive
 		bc.triegc.Push(root, -int64(block.NumberU64()))
 
-		if current := block.NumberU64(); current > TriesInMemory {
-			// If we exceeded our memory allowance, flush matured singleton nodes to disk
-			var (
-				nodes, imgs = triedb.Size()
-				limit       = common.StorageSize(bc.cacheConfig.TrieDirtyLimit) * 1024 * 1024
-			)
-			if nodes > limit || imgs > 4*1024*1024 {
-				triedb.Cap(limit - ethdb.IdealBatchSize)
+		policy := bc.TrieFlushPolicy()
+		current := block.NumberU64()
+
+		// Before the reorg depth is reached, every state is still retained in memory,
+		// so only enforce the hard ceiling of the dirty nodes.
+		if current <= policy.ReorgDepth {
+			if nodes, _ := triedb.Size(); nodes > policy.dirtyMax() {
+				triedb.Cap(policy.dirtyMax())
 			}
-			// Find the next state trie we need to commit
-			chosen := current - TriesInMemory
-
-			// If we exceeded out time allowance, flush an entire trie to disk
-			if bc.gcproc > bc.cacheConfig.TrieTimeLimit {
-				// If the header is missing (canonical chain behind), we're reorging a low
-				// diff sidechain. Suspend committing until this operation is completed.
-				header := bc.GetHeaderByNumber(chosen)
-				if header == nil {
-					log.Warn("Reorg in progress, trie commit postponed", "number", chosen)
-				} else {
-					// If we're exceeding limits but haven't reached a large enough memory gap,
-					// warn the user that the system is becoming unstable.
-					if chosen < lastWrite+TriesInMemory && bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
-						log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/TriesInMemory)
-					}
-					// Flush an entire trie and restart the counters
-					triedb.Commit(header.Root, true, nil)
-					lastWrite = chosen
-					bc.gcproc = 0
-				}
+			return nil
+		}
+		// Find the next state trie we need to commit
+		chosen := current - policy.ReorgDepth
+
+		// If we exceeded our memory allowance, flush matured singleton nodes to disk
+		nodes, imgs := triedb.Size()
+		if target, ok := policy.flushTarget(nodes, imgs); ok {
+			triedb.Cap(target)
+
+			// Flushing proceeds from the oldest nodes, so once the root of a retained
+			// state is flushed, the entire state is on disk and needs no commit.
+			if header := bc.GetHeaderByNumber(chosen); header != nil && rawdb.HasTrieNode(bc.db, header.Root) {
+				lastWrite = chosen
+				bc.gcproc = 0
 			}
-			// Garbage collect anything below our required write retention
-			for !bc.triegc.Empty() {
-				root, number := bc.triegc.Pop()
-				if uint64(-number) > chosen {
-					bc.triegc.Push(root, number)
-					break
+		}
+		// If we exceeded out time allowance, flush an entire trie to disk
+		if bc.gcproc > bc.cacheConfig.TrieTimeLimit {
+			// If the header is missing (canonical chain behind), we're reorging a low
+			// diff sidechain. Suspend committing until this operation is completed.
+			header := bc.GetHeaderByNumber(chosen)
+			if header == nil {
+				log.Warn("Reorg in progress, trie commit postponed", "number", chosen)
+			} else {
+				// If we're exceeding limits but haven't reached a large enough memory gap,
+				// warn the user that the system is becoming unstable.
+				if chosen < lastWrite+policy.ReorgDepth && bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
+					log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/float64(policy.ReorgDepth))
 				}
-				triedb.Dereference(root.(common.Hash))
+				// Flush an entire trie and restart the counters
+				triedb.Commit(header.Root, true, nil)
+				lastWrite = chosen
+				bc.gcproc = 0
+			}
+		}
+		// Garbage collect anything below our required write retention
+		for !bc.triegc.Empty() {
+			root, number := bc.triegc.Pop()
+			if uint64(-number) > chosen {
+				bc.triegc.Push(root, number)
+				break
 			}
+			triedb.Dereference(root.(common.Hash))
 		}
 	}
 	return nil
@@ -1337,13 +1483,18 @@ func (bc *BlockChain) WriteBlockAndSetHead(block *types.Block, receipts []*types
 	}
 	defer bc.chainmu.Unlock()
 
-	return bc.writeBlockAndSetHead(block, receipts, logs, state, emitHeadEvent)
+	// Blocks built outside of the import, e.g. by the miner, are not traced yet
+	var itxs []*types.InternalTx
+	if bc.cacheConfig.InternalTxIndex {
+		itxs = bc.traceInternalTxs(block)
+	}
+	return bc.writeBlockAndSetHead(block, receipts, itxs, logs, state, emitHeadEvent)
 }
 
 // writeBlockAndSetHead is the internal implementation of WriteBlockAndSetHead.
 // This function expects the chain mutex to be held.
-func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
-	if err := bc.writeBlockWithState(block, receipts, state); err != nil {
+func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types.Receipt, itxs []*types.InternalTx, logs []*types.Log, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
+	if err := bc.writeBlockWithState(block, receipts, itxs, state); err != nil {
 		return NonStatTy, err
 	}
 	currentBlock := bc.CurrentBlock()
@@ -1441,6 +1592,32 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 	return bc.insertChain(chain, true, true)
 }
 
+// InsertChainIfHead is like InsertChain, but only imports the chain if the head
+// block is still the given one. The check and the import happen under the chain
+// mutex, so no other import can move the head in between.
+func (bc *BlockChain) InsertChainIfHead(head common.Hash, chain types.Blocks) (int, error) {
+	if len(chain) == 0 {
+		return 0, nil
+	}
+	for i := 1; i < len(chain); i++ {
+		if chain[i].NumberU64() != chain[i-1].NumberU64()+1 || chain[i].ParentHash() != chain[i-1].Hash() {
+			return 0, fmt.Errorf("non contiguous insert: item %d is #%d, item %d is #%d", i-1, chain[i-1].NumberU64(), i, chain[i].NumberU64())
+		}
+	}
+	bc.blockProcFeed.Send(true)
+	defer bc.blockProcFeed.Send(false)
+
+	if !bc.chainmu.TryLock() {
+		return 0, errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	if current := bc.CurrentBlock().Hash(); current != head {
+		return 0, fmt.Errorf("head changed from %x to %x", head, current)
+	}
+	return bc.insertChain(chain, true, true)
+}
+
 // insertChain is the internal implementation of InsertChain, which assumes that
 // 1) chains are contiguous, and 2) The chain mutex is held.
 //
@@ -1666,9 +1843,18 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals, setHead bool)
 			}
 		}
 
-		// Process block using the parent state as reference point
+		// Process block using the parent state as reference point, collecting the
+		// internal transfers if they are indexed
+		var (
+			vmConfig  = bc.vmConfig
+			itxTracer *internalTxTracer
+		)
+		if bc.cacheConfig.InternalTxIndex {
+			itxTracer = newInternalTxTracer(block)
+			vmConfig.Debug, vmConfig.Tracer = true, itxTracer
+		}
 		substart := time.Now()
-		receipts, logs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
+		receipts, logs, usedGas, err := bc.processor.Process(block, statedb, vmConfig)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			atomic.StoreUint32(&followupInterrupt, 1)
@@ -1704,12 +1890,18 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals, setHead bool)
 
 		// Write the block to the chain and get the status.
 		substart = time.Now()
-		var status WriteStatus
+		var (
+			status WriteStatus
+			itxs   []*types.InternalTx
+		)
+		if itxTracer != nil {
+			itxs = itxTracer.transfers()
+		}
 		if !setHead {
 			// Don't set the head, only insert the block
-			err = bc.writeBlockWithState(block, receipts, statedb)
+			err = bc.writeBlockWithState(block, receipts, itxs, statedb)
 		} else {
-			status, err = bc.writeBlockAndSetHead(block, receipts, logs, statedb, false)
+			status, err = bc.writeBlockAndSetHead(block, receipts, itxs, logs, statedb, false)
 		}
 		atomic.StoreUint32(&followupInterrupt, 1)
 		if err != nil {
@@ -2069,6 +2261,10 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		}
 	}
 
+	// Ensure the reorg doesn't replace a signed milestone
+	if err := bc.checkMilestone(commonBlock, newChain); err != nil {
+		return err
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Info
@@ -2271,6 +2467,22 @@ func (bc *BlockChain) skipBlock(err error, it *insertIterator) bool {
 	return false
 }
 
+// dropIndex deletes the entries of an address index below the given block in the
+// background, as an index which was disabled or started afresh would otherwise
+// leave them in the database forever.
+func (bc *BlockChain) dropIndex(name string, drop func(ethdb.KeyValueStore, uint64, chan struct{}) (int, bool), limit uint64) {
+	bc.wg.Add(1)
+	go func() {
+		defer bc.wg.Done()
+
+		start := time.Now()
+		deleted, done := drop(bc.db, limit, bc.quit)
+		if deleted > 0 {
+			log.Info(fmt.Sprintf("Deleted %s index entries", name), "count", deleted, "complete", done, "elapsed", common.PrettyDuration(time.Since(start)))
+		}
+	}()
+}
+
 // maintainTxIndex is responsible for the construction and deletion of the
 // transaction index.
 //
@@ -2281,9 +2493,28 @@ func (bc *BlockChain) skipBlock(err error, it *insertIterator) bool {
 // The user can adjust the txlookuplimit value for each launch after fast
 // sync, Geth will automatically construct the missing indices and delete
 // the extra indices.
+//
+// If the transaction index is disabled, any existing indices are deleted and
+// the index tail is kept right above the head, so that reenabling the index
+// later reconstructs it from there.
 func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	defer bc.wg.Done()
 
+	if bc.cacheConfig.NoTxIndex {
+		// Drop the indices left over from before disabling the index. Entries
+		// written since by lookup backfills are below the tail and retained.
+		var (
+			head = bc.CurrentBlock().NumberU64()
+			from uint64
+		)
+		if tail := rawdb.ReadTxIndexTail(bc.db); tail != nil {
+			from = *tail
+		}
+		if from <= head {
+			rawdb.UnindexTransactions(bc.db, from, head+1, bc.quit)
+		}
+		ancients = 0
+	}
 	// Before starting the actual maintenance, we need to handle a special case,
 	// where user might init Geth with an external ancient database. If so, we
 	// need to reindex all necessary transactions before starting to process any
@@ -2300,6 +2531,11 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	indexBlocks := func(tail *uint64, head uint64, done chan struct{}) {
 		defer func() { done <- struct{}{} }()
 
+		// If the index is disabled, move the tail along with the head
+		if bc.cacheConfig.NoTxIndex {
+			rawdb.WriteTxIndexTail(bc.db, head+1)
+			return
+		}
 		// If the user just upgraded Geth to a new version which supports transaction
 		// index pruning, write the new tail and remove anything older.
 		if tail == nil {
@@ -0,0 +1,121 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// errReorgDepthTooLow is returned if a flush policy would keep fewer state tries
+// in memory than the chain relies on being available.
+var errReorgDepthTooLow = errors.New("reorg depth below the number of tries kept in memory")
+
+// errDirtyMaxTooLow is returned if a flush policy sets the hard ceiling of the
+// dirty trie nodes below the size at which flushing starts.
+var errDirtyMaxTooLow = errors.New("dirty trie maximum below the dirty limit")
+
+// TrieFlushPolicy determines when the dirty trie nodes held in memory by a full
+// node are flushed to disk.
+//
+// Once the dirty nodes exceed the dirty limit, the oldest nodes are flushed, at
+// most flush limit bytes per block, so that the cache converges back to its limit
+// over several blocks instead of stalling a single one. Should the dirty nodes
+// still grow past the dirty maximum, e.g. while the reorg depth keeps every state
+// in memory, the flush limit is ignored and the nodes above the maximum are
+// flushed regardless of the block. States retained for reorgs
+// are never flushed as a whole; they reach the disk through these incremental
+// flushes, and the time limit of the cache config only forces a commit when that
+// has not happened for too long.
+type TrieFlushPolicy struct {
+	DirtyLimit common.StorageSize `json:"dirtyLimit"` // Size of the dirty trie nodes at which flushing starts
+	DirtyMax   common.StorageSize `json:"dirtyMax"`   // Hard ceiling of the dirty trie nodes held in memory (0 = twice the dirty limit)
+	FlushLimit common.StorageSize `json:"flushLimit"` // Maximum size of the trie nodes flushed per block (0 = unlimited)
+	ReorgDepth uint64             `json:"reorgDepth"` // Number of recent state tries kept in memory to handle reorgs
+}
+
+// newTrieFlushPolicy creates the initial flush policy from the cache config.
+func newTrieFlushPolicy(config *CacheConfig) *TrieFlushPolicy {
+	policy := &TrieFlushPolicy{
+		DirtyLimit: common.StorageSize(config.TrieDirtyLimit) * 1024 * 1024,
+		DirtyMax:   common.StorageSize(config.TrieDirtyMax) * 1024 * 1024,
+		FlushLimit: common.StorageSize(config.TrieFlushLimit) * 1024 * 1024,
+		ReorgDepth: config.TrieReorgDepth,
+	}
+	if policy.ReorgDepth < TriesInMemory {
+		policy.ReorgDepth = TriesInMemory
+	}
+	return policy
+}
+
+// flushTarget returns the size the dirty trie nodes should be reduced to, or
+// false if no flush is needed.
+func (p *TrieFlushPolicy) flushTarget(nodes, preimages common.StorageSize) (common.StorageSize, bool) {
+	if nodes <= p.DirtyLimit && preimages <= 4*1024*1024 {
+		return 0, false
+	}
+	target := p.DirtyLimit - ethdb.IdealBatchSize
+	if p.FlushLimit > 0 && nodes-target > p.FlushLimit {
+		target = nodes - p.FlushLimit
+		if ceiling := p.dirtyMax(); target > ceiling {
+			target = ceiling
+		}
+	}
+	return target, true
+}
+
+// dirtyMax returns the hard ceiling of the dirty trie nodes held in memory.
+func (p *TrieFlushPolicy) dirtyMax() common.StorageSize {
+	if p.DirtyMax == 0 {
+		return 2 * p.DirtyLimit
+	}
+	return p.DirtyMax
+}
+
+// TrieFlushPolicy returns the current policy of flushing dirty trie nodes.
+func (bc *BlockChain) TrieFlushPolicy() TrieFlushPolicy {
+	return *bc.flushPolicy.Load().(*TrieFlushPolicy)
+}
+
+// SetTrieFlushPolicy changes the policy of flushing dirty trie nodes, taking
+// effect from the next imported block.
+func (bc *BlockChain) SetTrieFlushPolicy(policy TrieFlushPolicy) error {
+	if policy.ReorgDepth < TriesInMemory {
+		return errReorgDepthTooLow
+	}
+	if policy.DirtyMax != 0 && policy.DirtyMax < policy.DirtyLimit {
+		return errDirtyMaxTooLow
+	}
+	bc.flushPolicy.Store(&policy)
+	return nil
+}
+
+// FlushHeadState writes the state trie of the current head block from memory to
+// disk, so that a copy of the database contains the head state.
+func (bc *BlockChain) FlushHeadState() error {
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	if bc.cacheConfig.TrieDirtyDisabled {
+		return nil // Archive nodes write all tries through
+	}
+	return bc.stateCache.TrieDB().Commit(bc.CurrentBlock().Root(), false, nil)
+}
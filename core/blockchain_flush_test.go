@@ -0,0 +1,147 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestTrieFlushTarget(t *testing.T) {
+	const mb = 1024 * 1024
+	policy := &TrieFlushPolicy{DirtyLimit: 256 * mb, DirtyMax: 320 * mb, FlushLimit: 16 * mb}
+
+	tests := []struct {
+		nodes, preimages common.StorageSize
+		target           common.StorageSize
+		flush            bool
+	}{
+		{nodes: 100 * mb, flush: false},
+		{nodes: 256 * mb, flush: false},
+		{nodes: 260 * mb, target: 256*mb - ethdb.IdealBatchSize, flush: true}, // Small excess flushed at once
+		{nodes: 320 * mb, target: 304 * mb, flush: true},                      // Large excess spread over blocks
+		{nodes: 400 * mb, target: 320 * mb, flush: true},                      // Excess above the ceiling flushed at once
+		{nodes: 100 * mb, preimages: 5 * mb, target: 256*mb - ethdb.IdealBatchSize, flush: true},
+	}
+	for i, tt := range tests {
+		target, flush := policy.flushTarget(tt.nodes, tt.preimages)
+		if flush != tt.flush || target != tt.target {
+			t.Errorf("test %d: flush target mismatch: have %v/%v, want %v/%v", i, target, flush, tt.target, tt.flush)
+		}
+	}
+	// Without an explicit ceiling, twice the dirty limit should be enforced
+	policy.DirtyMax = 0
+	if target, _ := policy.flushTarget(600*mb, 0); target != 512*mb {
+		t.Errorf("default ceiling flush target mismatch: have %v, want %v", target, 512*mb)
+	}
+	// Without a flush limit, the excess should always be flushed at once
+	policy.FlushLimit = 0
+	if target, _ := policy.flushTarget(400*mb, 0); target != 256*mb-ethdb.IdealBatchSize {
+		t.Errorf("unlimited flush target mismatch: have %v, want %v", target, 256*mb-ethdb.IdealBatchSize)
+	}
+}
+
+func TestSetTrieFlushPolicy(t *testing.T) {
+	_, chain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if have := chain.TrieFlushPolicy(); have.ReorgDepth != TriesInMemory || have.DirtyLimit != 256*1024*1024 {
+		t.Fatalf("initial policy mismatch: %+v", have)
+	}
+	if err := chain.SetTrieFlushPolicy(TrieFlushPolicy{ReorgDepth: TriesInMemory - 1}); err != errReorgDepthTooLow {
+		t.Fatalf("shallow reorg depth error mismatch: have %v, want %v", err, errReorgDepthTooLow)
+	}
+	if err := chain.SetTrieFlushPolicy(TrieFlushPolicy{DirtyLimit: 1024, DirtyMax: 512, ReorgDepth: TriesInMemory}); err != errDirtyMaxTooLow {
+		t.Fatalf("low dirty maximum error mismatch: have %v, want %v", err, errDirtyMaxTooLow)
+	}
+	want := TrieFlushPolicy{DirtyLimit: 1024, DirtyMax: 2048, FlushLimit: 512, ReorgDepth: 2 * TriesInMemory}
+	if err := chain.SetTrieFlushPolicy(want); err != nil {
+		t.Fatalf("failed to set policy: %v", err)
+	}
+	if have := chain.TrieFlushPolicy(); have != want {
+		t.Fatalf("policy mismatch: have %+v, want %+v", have, want)
+	}
+}
+
+func TestFlushHeadState(t *testing.T) {
+	var (
+		gspec   = &Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+		genesis = gspec.MustCommit(rawdb.NewMemoryDatabase())
+		db      = rawdb.NewMemoryDatabase()
+	)
+	gspec.MustCommit(db)
+	blocks := makeBlockChain(genesis, 8, ethash.NewFaker(), rawdb.NewMemoryDatabase(), canonicalSeed)
+
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	root := chain.CurrentBlock().Root()
+	if ok, _ := db.Has(root.Bytes()); ok {
+		t.Fatalf("head state written before flush")
+	}
+	if err := chain.FlushHeadState(); err != nil {
+		t.Fatalf("failed to flush head state: %v", err)
+	}
+	if ok, _ := db.Has(root.Bytes()); !ok {
+		t.Fatalf("head state missing after flush")
+	}
+}
+
+func TestTrieDirtyCeiling(t *testing.T) {
+	var (
+		gspec   = &Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+		genesis = gspec.MustCommit(rawdb.NewMemoryDatabase())
+		db      = rawdb.NewMemoryDatabase()
+	)
+	gspec.MustCommit(db)
+	blocks := makeBlockChain(genesis, 8, ethash.NewFaker(), rawdb.NewMemoryDatabase(), canonicalSeed)
+
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// All imported states are within the reorg depth, only the ceiling may flush them
+	if err := chain.SetTrieFlushPolicy(TrieFlushPolicy{DirtyLimit: 1, DirtyMax: 1, ReorgDepth: TriesInMemory}); err != nil {
+		t.Fatalf("failed to set policy: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if nodes, _ := chain.stateCache.TrieDB().Size(); nodes > 1 {
+		t.Fatalf("dirty nodes above ceiling: have %v, want at most 1", nodes)
+	}
+	if ok, _ := db.Has(chain.CurrentBlock().Root().Bytes()); !ok {
+		t.Fatalf("head state not flushed above the ceiling")
+	}
+}
@@ -256,13 +256,90 @@ func (bc *BlockChain) GetTransactionLookup(hash common.Hash) *rawdb.LegacyTxLook
 	}
 	tx, blockHash, blockNumber, txIndex := rawdb.ReadTransaction(bc.db, hash)
 	if tx == nil {
-		return nil
+		return bc.backfillTxLookup(hash)
 	}
 	lookup := &rawdb.LegacyTxLookupEntry{BlockHash: blockHash, BlockIndex: blockNumber, Index: txIndex}
 	bc.txLookupCache.Add(hash, lookup)
 	return lookup
 }
 
+// backfillTxLookup searches the bodies of the most recent blocks below the
+// transaction index for the given transaction, up to the configured backfill
+// depth. If found, the whole block containing it is indexed.
+//
+// Misses are remembered until the next head block, and only a few searches run
+// at a time, so that polling for unknown transactions can't hammer the disk.
+func (bc *BlockChain) backfillTxLookup(hash common.Hash) *rawdb.LegacyTxLookupEntry {
+	if bc.cacheConfig.TxLookupBackfill == 0 {
+		return nil
+	}
+	if bc.txLookupMiss.Contains(hash) {
+		return nil
+	}
+	select {
+	case bc.txLookupSlots <- struct{}{}:
+		defer func() { <-bc.txLookupSlots }()
+	case <-bc.quit:
+		return nil
+	}
+	// Another search might have finished with the same hash while waiting
+	if lookup, exist := bc.txLookupCache.Get(hash); exist {
+		return lookup.(*rawdb.LegacyTxLookupEntry)
+	}
+	if bc.txLookupMiss.Contains(hash) {
+		return nil
+	}
+	lookup := bc.searchTxLookup(hash)
+	if lookup == nil {
+		bc.txLookupMiss.Add(hash, struct{}{})
+	}
+	return lookup
+}
+
+// searchTxLookup scans the bodies below the transaction index for the given
+// transaction, indexing the block containing it if found.
+func (bc *BlockChain) searchTxLookup(hash common.Hash) *rawdb.LegacyTxLookupEntry {
+	from := bc.CurrentBlock().NumberU64()
+	if tail := rawdb.ReadTxIndexTail(bc.db); tail != nil && *tail <= from {
+		if *tail == 0 {
+			return nil // Whole chain indexed
+		}
+		from = *tail - 1
+	}
+	for i := uint64(0); i < bc.cacheConfig.TxLookupBackfill && i <= from; i++ {
+		// Abort the search if the chain is shutting down
+		select {
+		case <-bc.quit:
+			return nil
+		default:
+		}
+		number := from - i
+		blockHash := rawdb.ReadCanonicalHash(bc.db, number)
+		if blockHash == (common.Hash{}) {
+			return nil
+		}
+		body := rawdb.ReadBody(bc.db, blockHash, number)
+		if body == nil {
+			return nil // Body pruned, so are all older ones
+		}
+		for txIndex, tx := range body.Transactions {
+			if tx.Hash() != hash {
+				continue
+			}
+			hashes := make([]common.Hash, len(body.Transactions))
+			for j, tx := range body.Transactions {
+				hashes[j] = tx.Hash()
+			}
+			rawdb.WriteTxLookupEntries(bc.db, number, hashes)
+
+			lookup := &rawdb.LegacyTxLookupEntry{BlockHash: blockHash, BlockIndex: number, Index: uint64(txIndex)}
+			bc.txLookupCache.Add(hash, lookup)
+			return lookup
+		}
+	}
+	return nil
+}
+
 // GetTd retrieves a block's total difficulty in the canonical chain from the
 // database by hash and number, caching it if found.
 func (bc *BlockChain) GetTd(hash common.Hash, number uint64) *big.Int {
@@ -373,6 +450,35 @@ func (bc *BlockChain) TxLookupLimit() uint64 {
 	return bc.txLookupLimit
 }
 
+// TxIndexProgress returns the number of blocks covered by the transaction index
+// and the number of blocks still to be indexed to reach the configured limit.
+// Both are zero if the transaction index is disabled.
+func (bc *BlockChain) TxIndexProgress() (indexed uint64, remaining uint64) {
+	if bc.cacheConfig.NoTxIndex {
+		return 0, 0
+	}
+	stored := rawdb.ReadTxIndexTail(bc.db)
+	if stored == nil {
+		return 0, 0 // Indexer not run yet, new blocks are indexed regardless
+	}
+	var (
+		head = bc.CurrentBlock().NumberU64()
+		tail = *stored
+	)
+	if tail > head+1 {
+		tail = head + 1 // Chain rewound below the index tail
+	}
+	var target uint64
+	if limit := bc.TxLookupLimit(); limit != 0 && head >= limit {
+		target = head - limit + 1
+	}
+	indexed = head + 1 - tail
+	if tail > target {
+		remaining = tail - target
+	}
+	return indexed, remaining
+}
+
 // SubscribeRemovedLogsEvent registers a subscription of RemovedLogsEvent.
 func (bc *BlockChain) SubscribeRemovedLogsEvent(ch chan<- RemovedLogsEvent) event.Subscription {
 	return bc.scope.Track(bc.rmLogsFeed.Subscribe(ch))
@@ -2695,6 +2695,99 @@ func TestSkipStaleTxIndicesInSnapSync(t *testing.T) {
 	check(&tail, chain)
 }
 
+func TestTxLookupBackfill(t *testing.T) {
+	// Configure and generate a sample block chain
+	var (
+		gendb   = rawdb.NewMemoryDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(100000000000000000)
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: funds}}}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 128, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	txOf := func(number uint64) common.Hash {
+		return blocks[number-1].Transactions()[0].Hash()
+	}
+	newChain := func(noTxIndex bool, limit uint64) *BlockChain {
+		db := rawdb.NewMemoryDatabase()
+		gspec.MustCommit(db)
+
+		config := *defaultCacheConfig
+		config.NoTxIndex = noTxIndex
+		config.TxLookupBackfill = 16
+		chain, err := NewBlockChain(db, &config, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, &limit)
+		if err != nil {
+			t.Fatalf("failed to create tester chain: %v", err)
+		}
+		if n, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+		}
+		time.Sleep(50 * time.Millisecond) // Wait for indices maintenance
+		return chain
+	}
+	// Disabled index, lookups are only served from the recent backfill range
+	chain := newChain(true, 0)
+	if tail := rawdb.ReadTxIndexTail(chain.db); tail == nil || *tail != 129 {
+		t.Fatalf("index tail mismatch: have %v, want 129", tail)
+	}
+	for i := uint64(1); i <= 128; i++ {
+		if rawdb.ReadTxLookupEntry(chain.db, txOf(i)) != nil {
+			t.Fatalf("block %d: transaction indexed with disabled index", i)
+		}
+	}
+	if lookup := chain.GetTransactionLookup(txOf(120)); lookup == nil || lookup.BlockIndex != 120 {
+		t.Fatalf("backfilled lookup mismatch: have %v", lookup)
+	}
+	if number := rawdb.ReadTxLookupEntry(chain.db, txOf(120)); number == nil || *number != 120 {
+		t.Fatalf("backfilled lookup not persisted")
+	}
+	if lookup := chain.GetTransactionLookup(txOf(100)); lookup != nil {
+		t.Fatalf("lookup beyond backfill range found: %v", lookup)
+	}
+	if !chain.txLookupMiss.Contains(txOf(100)) {
+		t.Fatalf("lookup miss not cached")
+	}
+	if indexed, remaining := chain.TxIndexProgress(); indexed != 0 || remaining != 0 {
+		t.Fatalf("progress mismatch: have %d/%d, want 0/0", indexed, remaining)
+	}
+	if err := chain.SetHead(127); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if chain.txLookupMiss.Len() != 0 {
+		t.Fatalf("lookup misses not cleared on new head")
+	}
+	chain.Stop()
+
+	// Limited index, lookups are backfilled right below the index tail
+	chain = newChain(false, 32)
+	if tail := rawdb.ReadTxIndexTail(chain.db); tail == nil || *tail != 97 {
+		t.Fatalf("index tail mismatch: have %v, want 97", tail)
+	}
+	if lookup := chain.GetTransactionLookup(txOf(90)); lookup == nil || lookup.BlockIndex != 90 {
+		t.Fatalf("backfilled lookup mismatch: have %v", lookup)
+	}
+	if lookup := chain.GetTransactionLookup(txOf(50)); lookup != nil {
+		t.Fatalf("lookup beyond backfill range found: %v", lookup)
+	}
+	if indexed, remaining := chain.TxIndexProgress(); indexed != 32 || remaining != 0 {
+		t.Fatalf("progress mismatch: have %d/%d, want 32/0", indexed, remaining)
+	}
+	chain.Stop()
+
+	// No backfills are run after shutdown
+	if lookup := chain.GetTransactionLookup(txOf(91)); lookup != nil {
+		t.Fatalf("lookup backfilled after shutdown: %v", lookup)
+	}
+}
+
 // Benchmarks large blocks with value transfers to non-existing accounts
 func benchmarkLargeNumberOfValueToNonexisting(b *testing.B, numTxs, numBlocks int, recipientFn func(uint64) common.Address, dataFn func(uint64) []byte) {
 	var (
@@ -3865,3 +3958,105 @@ func TestCanonicalHashMarker(t *testing.T) {
 		}
 	}
 }
+
+// Tests that reorgs replacing a signed milestone block are rejected, while ones
+// above the milestone are still allowed.
+func TestMilestoneReorg(t *testing.T) {
+	db, blockchain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	genesis := blockchain.CurrentBlock()
+	canonical, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 5, func(i int, b *BlockGen) {})
+	if _, err := blockchain.InsertChain(canonical); err != nil {
+		t.Fatalf("failed to insert canonical chain: %v", err)
+	}
+	blockchain.SetMilestone(Milestone{Number: 3, Hash: canonical[2].Hash()})
+
+	// A heavier fork from genesis would replace the milestone and must be rejected
+	fork, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 6, func(i int, b *BlockGen) {
+		b.OffsetTime(-9)
+	})
+	if _, err := blockchain.InsertChain(fork); !errors.Is(err, ErrMilestoneReorg) {
+		t.Fatalf("milestone reorg error mismatch: have %v, want %v", err, ErrMilestoneReorg)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != canonical[4].Hash() {
+		t.Fatalf("head changed by rejected reorg: have %x, want %x", head, canonical[4].Hash())
+	}
+	// A heavier fork above the milestone should be accepted
+	fork, _ = GenerateChain(params.TestChainConfig, canonical[2], ethash.NewFaker(), db, 3, func(i int, b *BlockGen) {
+		b.OffsetTime(-9)
+	})
+	if _, err := blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to reorg above milestone: %v", err)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != fork[2].Hash() {
+		t.Fatalf("head mismatch after reorg: have %x, want %x", head, fork[2].Hash())
+	}
+}
+
+// Tests that a reorg extending the head past the milestone is rejected if it
+// doesn't contain the milestone block, even though no blocks are dropped.
+func TestMilestoneReorgExtension(t *testing.T) {
+	db, blockchain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	canonical, _ := GenerateChain(params.TestChainConfig, blockchain.CurrentBlock(), ethash.NewFaker(), db, 2, func(i int, b *BlockGen) {})
+	if _, err := blockchain.InsertChain(canonical); err != nil {
+		t.Fatalf("failed to insert canonical chain: %v", err)
+	}
+	blockchain.SetMilestone(Milestone{Number: 3, Hash: common.Hash{0x01}})
+
+	// Import blocks extending the head without making them canonical
+	ext, _ := GenerateChain(params.TestChainConfig, canonical[1], ethash.NewFaker(), db, 3, func(i int, b *BlockGen) {})
+	for _, block := range ext {
+		if err := blockchain.InsertBlockWithoutSetHead(block); err != nil {
+			t.Fatalf("failed to insert block %d: %v", block.NumberU64(), err)
+		}
+	}
+	if _, err := blockchain.SetCanonical(ext[2]); !errors.Is(err, ErrMilestoneReorg) {
+		t.Fatalf("milestone reorg error mismatch: have %v, want %v", err, ErrMilestoneReorg)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != canonical[1].Hash() {
+		t.Fatalf("head changed by rejected reorg: have %x, want %x", head, canonical[1].Hash())
+	}
+	// With the milestone matching the extension, it should be accepted
+	blockchain.SetMilestone(Milestone{Number: 3, Hash: ext[0].Hash()})
+	if _, err := blockchain.SetCanonical(ext[2]); err != nil {
+		t.Fatalf("failed to extend past matching milestone: %v", err)
+	}
+}
+
+// Tests that conditional imports are rejected once the head moved.
+func TestInsertChainIfHead(t *testing.T) {
+	db, blockchain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	genesis := blockchain.CurrentBlock()
+	canonical, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 1, func(i int, b *BlockGen) {})
+	fork, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 2, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{0x01}) })
+
+	if _, err := blockchain.InsertChainIfHead(genesis.Hash(), canonical); err != nil {
+		t.Fatalf("failed to insert chain on expected head: %v", err)
+	}
+	if _, err := blockchain.InsertChainIfHead(genesis.Hash(), fork); err == nil {
+		t.Fatal("inserted chain on stale head")
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != canonical[0].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head, canonical[0].Hash())
+	}
+	if _, err := blockchain.InsertChainIfHead(canonical[0].Hash(), fork); err != nil {
+		t.Fatalf("failed to insert fork on expected head: %v", err)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != fork[1].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head, fork[1].Hash())
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blockstm
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// maxInitCodeRange is the maximum memory range of CREATE2 init code hashed to
+// determine the created address. Expanding memory beyond it costs more gas than
+// any block provides, so such creations fail before accessing any state.
+const maxInitCodeRange = 1 << 25
+
+// Key identifies a unit of state tracked for conflicts: either an account as a
+// whole (balance, nonce and code), or one of its storage slots.
+type Key struct {
+	Address common.Address
+	Slot    common.Hash
+	Storage bool // Whether the key is a storage slot rather than the account
+}
+
+// accountState is the part of an account compared to detect writes.
+type accountState struct {
+	balance  *big.Int
+	nonce    uint64
+	codeHash common.Hash
+}
+
+// accessTracer records the state a transaction reads, along with the values of
+// the touched state before the transaction, so the writes can be determined
+// after it is applied.
+type accessTracer struct {
+	env      *vm.EVM
+	reads    map[Key]struct{}
+	accounts map[common.Address]accountState // Account values before first access
+	slots    map[Key]common.Hash             // Slot values before first access
+}
+
+func newAccessTracer() *accessTracer {
+	return &accessTracer{
+		reads:    make(map[Key]struct{}),
+		accounts: make(map[common.Address]accountState),
+		slots:    make(map[Key]common.Hash),
+	}
+}
+
+// touchAccount records the access of an account, and its value if accessed for
+// the first time.
+func (t *accessTracer) touchAccount(db vm.StateDB, addr common.Address, read bool) {
+	if read {
+		t.reads[Key{Address: addr}] = struct{}{}
+	}
+	if _, ok := t.accounts[addr]; !ok {
+		t.accounts[addr] = accountState{
+			balance:  new(big.Int).Set(db.GetBalance(addr)),
+			nonce:    db.GetNonce(addr),
+			codeHash: db.GetCodeHash(addr),
+		}
+	}
+}
+
+// touchSlot records the access of a storage slot, and its value if accessed for
+// the first time.
+func (t *accessTracer) touchSlot(db vm.StateDB, addr common.Address, slot common.Hash) {
+	key := Key{Address: addr, Slot: slot, Storage: true}
+	t.reads[key] = struct{}{}
+	if _, ok := t.slots[key]; !ok {
+		t.slots[key] = db.GetState(addr, slot)
+	}
+}
+
+// writes returns the tracked state whose value differs from the one before the
+// transaction.
+func (t *accessTracer) writes(db *state.StateDB) map[Key]struct{} {
+	writes := make(map[Key]struct{})
+	for addr, prev := range t.accounts {
+		if db.GetBalance(addr).Cmp(prev.balance) != 0 || db.GetNonce(addr) != prev.nonce || db.GetCodeHash(addr) != prev.codeHash {
+			writes[Key{Address: addr}] = struct{}{}
+		}
+	}
+	for key, prev := range t.slots {
+		if db.GetState(key.Address, key.Slot) != prev {
+			writes[key] = struct{}{}
+		}
+	}
+	return writes
+}
+
+func (t *accessTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *accessTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *accessTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+}
+
+func (t *accessTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {}
+
+func (t *accessTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.touchAccount(t.env.StateDB, from, true)
+	t.touchAccount(t.env.StateDB, to, true)
+}
+
+func (t *accessTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+// CaptureState records the state accessed by an opcode, before it is executed.
+func (t *accessTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	var (
+		db    = t.env.StateDB
+		stack = scope.Stack.Data()
+		size  = len(stack)
+	)
+	switch {
+	case size >= 1 && (op == vm.SLOAD || op == vm.SSTORE):
+		t.touchSlot(db, scope.Contract.Address(), common.Hash(stack[size-1].Bytes32()))
+	case size >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE):
+		t.touchAccount(db, common.Address(stack[size-1].Bytes20()), true)
+	case size >= 1 && op == vm.SELFDESTRUCT:
+		t.touchAccount(db, scope.Contract.Address(), true)
+		t.touchAccount(db, common.Address(stack[size-1].Bytes20()), true)
+	case op == vm.SELFBALANCE:
+		t.touchAccount(db, scope.Contract.Address(), true)
+	case size >= 2 && (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE):
+		t.touchAccount(db, scope.Contract.Address(), true)
+		t.touchAccount(db, common.Address(stack[size-2].Bytes20()), true)
+	case op == vm.CREATE:
+		addr := scope.Contract.Address()
+		t.touchAccount(db, addr, true)
+		t.touchAccount(db, crypto.CreateAddress(addr, db.GetNonce(addr)), true)
+	case size >= 4 && op == vm.CREATE2:
+		addr := scope.Contract.Address()
+		t.touchAccount(db, addr, true)
+		if code, ok := initCode(scope.Memory, stack[size-2], stack[size-3]); ok {
+			salt := stack[size-4]
+			t.touchAccount(db, crypto.CreateAddress2(addr, salt.Bytes32(), crypto.Keccak256(code)), true)
+		}
+	}
+}
+
+// initCode returns the init code of a CREATE2 operation, which may extend past
+// the current memory, as the EVM expands memory with zeroes before running the
+// operation. False is returned if the range is too large to ever be paid for.
+func initCode(mem *vm.Memory, offset, length uint256.Int) ([]byte, bool) {
+	if length.IsZero() {
+		return nil, true
+	}
+	if !offset.IsUint64() || !length.IsUint64() || offset.Uint64() > maxInitCodeRange || length.Uint64() > maxInitCodeRange {
+		return nil, false
+	}
+	code := make([]byte, length.Uint64())
+	if start := offset.Uint64(); start < uint64(mem.Len()) {
+		copy(code, mem.Data()[start:])
+	}
+	return code, true
+}
+
+func (t *accessTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
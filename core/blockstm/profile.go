@@ -0,0 +1,150 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package blockstm profiles the transactions of blocks for optimistic parallel
+// execution in the style of Block-STM, and estimates the conflict rates and
+// speedups of different scheduling strategies.
+package blockstm
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// TxProfile is the execution profile of a transaction.
+type TxProfile struct {
+	Hash   common.Hash
+	Gas    uint64
+	Time   time.Duration    // Execution time without tracing
+	Reads  map[Key]struct{} // State read by the transaction
+	Writes map[Key]struct{} // State modified by the transaction
+	Deps   []int            // Indices of earlier transactions writing state read by this one
+}
+
+// BlockProfile is the execution profile of all transactions in a block.
+type BlockProfile struct {
+	Number uint64
+	Hash   common.Hash
+	Txs    []*TxProfile
+}
+
+// Conflicts returns the number of transactions depending on earlier ones, i.e.
+// those which would be invalidated if executed speculatively on the pre-state.
+func (p *BlockProfile) Conflicts() int {
+	var conflicts int
+	for _, tx := range p.Txs {
+		if len(tx.Deps) > 0 {
+			conflicts++
+		}
+	}
+	return conflicts
+}
+
+// SequentialTime returns the time needed to execute the transactions one by one.
+func (p *BlockProfile) SequentialTime() time.Duration {
+	var total time.Duration
+	for _, tx := range p.Txs {
+		total += tx.Time
+	}
+	return total
+}
+
+// Profile executes the transactions of a block on top of the state of its parent
+// twice: once traced to collect the state each transaction reads and writes, and
+// once untraced to measure execution times. The fee payments to the coinbase are
+// not considered reads, as parallel executors accumulate them separately.
+func Profile(config *params.ChainConfig, chain core.ChainContext, block *types.Block, statedb *state.StateDB) (*BlockProfile, error) {
+	var (
+		header   = block.Header()
+		signer   = types.MakeSigner(config, header.Number)
+		txs      = block.Transactions()
+		profile  = &BlockProfile{Number: block.NumberU64(), Hash: block.Hash(), Txs: make([]*TxProfile, len(txs))}
+		timingdb = statedb.Copy()
+	)
+	if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(block.Number()) == 0 {
+		misc.ApplyDAOHardFork(statedb)
+		misc.ApplyDAOHardFork(timingdb)
+	}
+	// Trace the state accesses of the transactions
+	var (
+		gp      = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas = new(uint64)
+	)
+	for i, tx := range txs {
+		msg, err := tx.AsMessage(signer, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		tracer := newAccessTracer()
+		tracer.touchAccount(statedb, msg.From(), true)
+		if to := msg.To(); to != nil {
+			tracer.touchAccount(statedb, *to, true)
+		}
+		tracer.touchAccount(statedb, header.Coinbase, false)
+
+		statedb.Prepare(tx.Hash(), i)
+		gas := *usedGas
+		if _, err := core.ApplyTransaction(config, chain, nil, gp, statedb, header, tx, usedGas, vm.Config{Debug: true, Tracer: tracer}); err != nil {
+			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		profile.Txs[i] = &TxProfile{
+			Hash:   tx.Hash(),
+			Gas:    *usedGas - gas,
+			Reads:  tracer.reads,
+			Writes: tracer.writes(statedb),
+		}
+	}
+	if *usedGas != block.GasUsed() {
+		return nil, fmt.Errorf("gas used mismatch: have %d, want %d", *usedGas, block.GasUsed())
+	}
+	// Measure the execution times without the tracing overhead
+	gp, usedGas = new(core.GasPool).AddGas(block.GasLimit()), new(uint64)
+	for i, tx := range txs {
+		timingdb.Prepare(tx.Hash(), i)
+		start := time.Now()
+		if _, err := core.ApplyTransaction(config, chain, nil, gp, timingdb, header, tx, usedGas, vm.Config{}); err != nil {
+			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		profile.Txs[i].Time = time.Since(start)
+	}
+	// Derive the read-after-write dependencies between the transactions
+	writers := make(map[Key][]int)
+	for i, tx := range profile.Txs {
+		deps := make(map[int]struct{})
+		for key := range tx.Reads {
+			for _, writer := range writers[key] {
+				deps[writer] = struct{}{}
+			}
+		}
+		for dep := range deps {
+			tx.Deps = append(tx.Deps, dep)
+		}
+		sort.Ints(tx.Deps)
+		for key := range tx.Writes {
+			writers[key] = append(writers[key], i)
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blockstm
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the address created by CREATE2 is tracked even if the init code
+// lies beyond the memory expanded so far.
+func TestAccessCreate2UnexpandedMemory(t *testing.T) {
+	var (
+		tracer = newAccessTracer()
+		sender = common.Address{0x01}
+		self   = common.Address{0x02}
+	)
+	// PUSH1 0xff, PUSH1 0, MSTORE, then CREATE2 with the init code at 0x10..0x30,
+	// straddling the end of the expanded memory:
+	// PUSH1 0 (salt), PUSH1 0x20 (length), PUSH1 0x10 (offset), PUSH1 0 (value), CREATE2
+	code := []byte{
+		byte(vm.PUSH1), 0xff, byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x10, byte(vm.PUSH1), 0, byte(vm.CREATE2),
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(self, code)
+
+	_, _, err := runtime.Call(self, nil, &runtime.Config{
+		Origin:    sender,
+		State:     statedb,
+		GasLimit:  1000000,
+		EVMConfig: vm.Config{Debug: true, Tracer: tracer},
+	})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	initcode := make([]byte, 0x20)
+	initcode[0x0f] = 0xff
+	created := crypto.CreateAddress2(self, [32]byte{}, crypto.Keccak256(initcode))
+	if _, ok := tracer.reads[Key{Address: created}]; !ok {
+		t.Errorf("created address %x not tracked", created)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 3)
+		alloc = make(core.GenesisAlloc)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = core.GenesisAccount{Balance: big.NewInt(params.Ether)}
+	}
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: alloc}
+		genesis = gspec.MustCommit(db)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) {
+		send := func(key *ecdsa.PrivateKey, to common.Address) {
+			nonce := gen.TxNonce(crypto.PubkeyToAddress(key.PublicKey))
+			gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &to, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: new(big.Int).Add(gen.BaseFee(), big.NewInt(1))}))
+		}
+		send(keys[0], common.Address{0x0a}) // independent
+		send(keys[1], common.Address{0x0b}) // independent
+		send(keys[0], common.Address{0x0c}) // same sender as the first
+		send(keys[2], common.Address{0x0a}) // same recipient as the first
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	statedb, err := chain.StateAt(genesis.Root())
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	profile, err := Profile(gspec.Config, chain, blocks[0], statedb)
+	if err != nil {
+		t.Fatalf("failed to profile block: %v", err)
+	}
+	want := [][]int{nil, nil, {0}, {0}}
+	for i, tx := range profile.Txs {
+		if !reflect.DeepEqual(tx.Deps, want[i]) {
+			t.Errorf("tx %d: dependencies mismatch: have %v, want %v", i, tx.Deps, want[i])
+		}
+		if tx.Gas != params.TxGas {
+			t.Errorf("tx %d: gas mismatch: have %d, want %d", i, tx.Gas, params.TxGas)
+		}
+		if _, ok := tx.Writes[Key{Address: blocks[0].Coinbase()}]; !ok {
+			t.Errorf("tx %d: fee payment not recorded as write", i)
+		}
+	}
+	if conflicts := profile.Conflicts(); conflicts != 2 {
+		t.Errorf("conflicts mismatch: have %d, want 2", conflicts)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blockstm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Scheduling strategies for parallel execution.
+const (
+	// Sequential executes the transactions one by one, as done today.
+	Sequential = "sequential"
+
+	// Optimistic executes all transactions speculatively on the pre-state, then
+	// validates them in order and re-executes the invalidated ones sequentially.
+	Optimistic = "optimistic"
+
+	// STM executes all transactions speculatively, then validates them and
+	// re-executes the invalidated ones in parallel rounds until all are valid.
+	STM = "stm"
+
+	// DAG executes every transaction once, as soon as all transactions it
+	// depends on have finished, assuming the dependencies are known upfront.
+	DAG = "dag"
+)
+
+// Strategies lists the supported scheduling strategies.
+var Strategies = []string{Sequential, Optimistic, STM, DAG}
+
+// Result is the estimated outcome of executing a block with a given strategy.
+type Result struct {
+	Strategy   string
+	Workers    int
+	Executions int           // Transaction executions, including re-executions
+	Rounds     int           // Validation rounds needed to commit all transactions
+	Time       time.Duration // Estimated execution time
+}
+
+// Simulate estimates the execution time of a profiled block with the given
+// strategy and number of workers, based on the measured transaction execution
+// times and dependencies. Scheduling and validation overheads are not modelled.
+func Simulate(profile *BlockProfile, strategy string, workers int) (*Result, error) {
+	if workers < 1 {
+		return nil, errors.New("at least one worker is required")
+	}
+	res := &Result{Strategy: strategy, Workers: workers}
+	switch strategy {
+	case Sequential:
+		res.Workers, res.Executions, res.Rounds = 1, len(profile.Txs), 1
+		res.Time = profile.SequentialTime()
+
+	case Optimistic:
+		res.Executions, res.Rounds = len(profile.Txs), 1
+		res.Time = makespan(profile.Txs, workers)
+		for _, tx := range profile.Txs {
+			if len(tx.Deps) > 0 {
+				res.Executions++
+				res.Time += tx.Time
+			}
+		}
+		if res.Executions > len(profile.Txs) {
+			res.Rounds++
+		}
+
+	case STM:
+		// A transaction becomes valid in the round after all its dependencies
+		// did, being re-executed in every round until then.
+		levels := make([]int, len(profile.Txs))
+		for i, tx := range profile.Txs {
+			for _, dep := range tx.Deps {
+				if levels[dep]+1 > levels[i] {
+					levels[i] = levels[dep] + 1
+				}
+			}
+			if levels[i]+1 > res.Rounds {
+				res.Rounds = levels[i] + 1
+			}
+		}
+		for round := 0; round < res.Rounds; round++ {
+			var pending []*TxProfile
+			for i, tx := range profile.Txs {
+				if levels[i] >= round {
+					pending = append(pending, tx)
+				}
+			}
+			res.Executions += len(pending)
+			res.Time += makespan(pending, workers)
+		}
+
+	case DAG:
+		res.Executions, res.Rounds = len(profile.Txs), 1
+		var (
+			free     = make([]time.Duration, workers) // Time each worker becomes idle
+			finished = make([]time.Duration, len(profile.Txs))
+		)
+		for i, tx := range profile.Txs {
+			worker := earliest(free)
+			start := free[worker]
+			for _, dep := range tx.Deps {
+				if finished[dep] > start {
+					start = finished[dep]
+				}
+			}
+			finished[i] = start + tx.Time
+			free[worker] = finished[i]
+			if finished[i] > res.Time {
+				res.Time = finished[i]
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", strategy)
+	}
+	return res, nil
+}
+
+// makespan returns the time needed to execute the transactions on the given
+// number of workers, handing them out in order to the first idle worker.
+func makespan(txs []*TxProfile, workers int) time.Duration {
+	var (
+		free  = make([]time.Duration, workers)
+		total time.Duration
+	)
+	for _, tx := range txs {
+		worker := earliest(free)
+		free[worker] += tx.Time
+		if free[worker] > total {
+			total = free[worker]
+		}
+	}
+	return total
+}
+
+// earliest returns the index of the worker becoming idle first.
+func earliest(free []time.Duration) int {
+	var idx int
+	for i := range free {
+		if free[i] < free[idx] {
+			idx = i
+		}
+	}
+	return idx
+}
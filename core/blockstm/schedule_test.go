@@ -0,0 +1,68 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blockstm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	// Four transactions of 10ms each, the third depending on the first and the
+	// fourth on the third
+	profile := &BlockProfile{Txs: []*TxProfile{
+		{Time: 10 * time.Millisecond},
+		{Time: 10 * time.Millisecond},
+		{Time: 10 * time.Millisecond, Deps: []int{0}},
+		{Time: 10 * time.Millisecond, Deps: []int{2}},
+	}}
+	if conflicts := profile.Conflicts(); conflicts != 2 {
+		t.Fatalf("conflicts mismatch: have %d, want 2", conflicts)
+	}
+	tests := []struct {
+		strategy   string
+		workers    int
+		time       time.Duration
+		executions int
+		rounds     int
+	}{
+		{Sequential, 4, 40 * time.Millisecond, 4, 1},
+		{Optimistic, 1, 60 * time.Millisecond, 6, 2},
+		{Optimistic, 4, 30 * time.Millisecond, 6, 2},
+		{STM, 4, 30 * time.Millisecond, 7, 3},
+		{STM, 2, 40 * time.Millisecond, 7, 3},
+		{DAG, 4, 30 * time.Millisecond, 4, 1},
+		{DAG, 2, 30 * time.Millisecond, 4, 1},
+		{DAG, 1, 40 * time.Millisecond, 4, 1},
+	}
+	for i, tt := range tests {
+		res, err := Simulate(profile, tt.strategy, tt.workers)
+		if err != nil {
+			t.Fatalf("test %d: simulation failed: %v", i, err)
+		}
+		if res.Time != tt.time || res.Executions != tt.executions || res.Rounds != tt.rounds {
+			t.Errorf("test %d (%s, %d workers): have time %v, executions %d, rounds %d; want %v, %d, %d",
+				i, tt.strategy, tt.workers, res.Time, res.Executions, res.Rounds, tt.time, tt.executions, tt.rounds)
+		}
+	}
+	if _, err := Simulate(profile, "magic", 4); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+	if _, err := Simulate(profile, DAG, 0); err == nil {
+		t.Error("zero workers accepted")
+	}
+}
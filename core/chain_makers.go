@@ -256,6 +256,19 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 			gen(i, b)
 		}
 		if b.engine != nil {
+			// Collect the requests of the block since Prague
+			var requests types.Requests
+			if config.IsPrague(b.header.Number) {
+				blockContext := NewEVMBlockContext(b.header, nil, &b.header.Coinbase)
+				vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, config, vm.Config{})
+
+				var err error
+				if requests, err = ProcessRequests(config, vmenv, statedb, b.receipts); err != nil {
+					panic(fmt.Sprintf("request processing error: %v", err))
+				}
+				hash := requests.Hash()
+				b.header.RequestsHash = &hash
+			}
 			// Finalize and seal the block
 			block, _ := b.engine.FinalizeAndAssemble(chainreader, b.header, statedb, b.txs, b.uncles, b.receipts)
 
@@ -312,6 +325,12 @@ func makeHeader(chain consensus.ChainReader, parent *types.Block, state *state.S
 			header.GasLimit = CalcGasLimit(parentGasLimit, parentGasLimit)
 		}
 	}
+	if chain.Config().IsPrague(header.Number) {
+		withdrawalsHash := types.EmptyRootHash
+		header.WithdrawalsHash = &withdrawalsHash
+		header.BlobGasUsed, header.ExcessBlobGas = new(uint64), new(uint64)
+		header.ParentBeaconRoot = new(common.Hash)
+	}
 	return header
 }
 
@@ -29,6 +29,10 @@ var (
 	// ErrBannedHash is returned if a block to import is on the banned list.
 	ErrBannedHash = errors.New("banned hash")
 
+	// ErrMilestoneReorg is returned if a block to import would reorg the chain
+	// past a signed milestone.
+	ErrMilestoneReorg = errors.New("reorg beyond milestone")
+
 	// ErrNoGenesis is returned when there is no Genesis Block.
 	ErrNoGenesis = errors.New("genesis not found in chain")
 
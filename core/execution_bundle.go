@@ -0,0 +1,244 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ExecutionBundle is a portable, self-contained record of a transaction execution:
+// the transaction itself, the block environment it ran in and the value of every
+// account, storage slot and block hash it accessed. It allows re-executing the
+// transaction offline, without access to the chain it was originally part of.
+type ExecutionBundle struct {
+	Config      *params.ChainConfig            `json:"config"`
+	Env         BundleEnv                      `json:"env"`
+	Tx          hexutil.Bytes                  `json:"tx"`
+	TxIndex     hexutil.Uint64                 `json:"txIndex"`
+	Pre         GenesisAlloc                   `json:"pre"`
+	BlockHashes map[hexutil.Uint64]common.Hash `json:"blockHashes,omitempty"`
+	Result      *BundleResult                  `json:"result"`
+}
+
+// BundleEnv is the block environment of the transaction in an execution bundle.
+type BundleEnv struct {
+	Coinbase   common.Address `json:"coinbase"`
+	GasLimit   hexutil.Uint64 `json:"gasLimit"`
+	Number     *hexutil.Big   `json:"number"`
+	Time       *hexutil.Big   `json:"timestamp"`
+	Difficulty *hexutil.Big   `json:"difficulty"`
+	BaseFee    *hexutil.Big   `json:"baseFee,omitempty"`
+	Random     *common.Hash   `json:"random,omitempty"`
+}
+
+// BundleResult is the outcome of executing the transaction of a bundle.
+type BundleResult struct {
+	UsedGas    hexutil.Uint64 `json:"gasUsed"`
+	Error      string         `json:"error,omitempty"`
+	ReturnData hexutil.Bytes  `json:"returnData,omitempty"`
+	Logs       []*types.Log   `json:"logs"`
+}
+
+// RecordExecutionBundle executes a transaction on top of the given state, which
+// has to be positioned right before the transaction within its block, recording
+// all state accesses into an execution bundle. The state is modified by the
+// execution and should be discarded afterwards.
+func RecordExecutionBundle(config *params.ChainConfig, blockCtx vm.BlockContext, statedb *state.StateDB, tx *types.Transaction, txIndex int) (*ExecutionBundle, error) {
+	signer := types.MakeSigner(config, blockCtx.BlockNumber)
+	msg, err := tx.AsMessage(signer, blockCtx.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	// Track the block hashes retrieved by the BLOCKHASH opcode
+	var (
+		hashes  = make(map[hexutil.Uint64]common.Hash)
+		getHash = blockCtx.GetHash
+	)
+	blockCtx.GetHash = func(n uint64) common.Hash {
+		hash := getHash(n)
+		hashes[hexutil.Uint64(n)] = hash
+		return hash
+	}
+	statedb.StartRecording()
+	evm := vm.NewEVM(blockCtx, NewEVMTxContext(msg), statedb, config, vm.Config{})
+	statedb.Prepare(tx.Hash(), txIndex)
+	res, err := ApplyMessage(evm, msg, new(GasPool).AddGas(tx.Gas()))
+	accounts := statedb.StopRecording()
+	if err != nil {
+		return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+	}
+	pre := make(GenesisAlloc, len(accounts))
+	for addr, account := range accounts {
+		pre[addr] = GenesisAccount{
+			Code:    account.Code,
+			Storage: account.Storage,
+			Balance: account.Balance,
+			Nonce:   account.Nonce,
+		}
+	}
+	bundle := &ExecutionBundle{
+		Config: config,
+		Env: BundleEnv{
+			Coinbase:   blockCtx.Coinbase,
+			GasLimit:   hexutil.Uint64(blockCtx.GasLimit),
+			Number:     (*hexutil.Big)(blockCtx.BlockNumber),
+			Time:       (*hexutil.Big)(blockCtx.Time),
+			Difficulty: (*hexutil.Big)(blockCtx.Difficulty),
+			BaseFee:    (*hexutil.Big)(blockCtx.BaseFee),
+			Random:     blockCtx.Random,
+		},
+		Tx:      enc,
+		TxIndex: hexutil.Uint64(txIndex),
+		Pre:     pre,
+		Result:  newBundleResult(res, statedb.GetLogs(tx.Hash(), common.Hash{})),
+	}
+	if len(hashes) > 0 {
+		bundle.BlockHashes = hashes
+	}
+	return bundle, nil
+}
+
+// newBundleResult assembles the result of a bundle execution.
+func newBundleResult(res *ExecutionResult, logs []*types.Log) *BundleResult {
+	result := &BundleResult{
+		UsedGas:    hexutil.Uint64(res.UsedGas),
+		ReturnData: res.ReturnData,
+		Logs:       logs,
+	}
+	if res.Err != nil {
+		result.Error = res.Err.Error()
+	}
+	if result.Logs == nil {
+		result.Logs = []*types.Log{}
+	}
+	return result
+}
+
+// Run re-executes the transaction of the bundle using nothing but the contents
+// of the bundle, returning the result of the execution along with the resulting
+// state.
+func (b *ExecutionBundle) Run(vmConfig vm.Config) (*BundleResult, *state.StateDB, error) {
+	if b.Config == nil || b.Env.Number == nil || b.Env.Time == nil || b.Env.Difficulty == nil {
+		return nil, nil, errors.New("incomplete execution bundle")
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(b.Tx); err != nil {
+		return nil, nil, fmt.Errorf("invalid transaction: %v", err)
+	}
+	// Assemble the pre-state in an ephemeral database, committing it so that the
+	// recorded values are seen as the original values of the transaction
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, err := state.New(common.Hash{}, db, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for addr, account := range b.Pre {
+		statedb.SetBalance(addr, account.Balance)
+		statedb.SetCode(addr, account.Code)
+		statedb.SetNonce(addr, account.Nonce)
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if statedb, err = state.New(root, db, nil); err != nil {
+		return nil, nil, err
+	}
+	// Execute the transaction in the recorded environment
+	blockCtx := vm.BlockContext{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		GetHash: func(n uint64) common.Hash {
+			return b.BlockHashes[hexutil.Uint64(n)]
+		},
+		Coinbase:    b.Env.Coinbase,
+		GasLimit:    uint64(b.Env.GasLimit),
+		BlockNumber: b.Env.Number.ToInt(),
+		Time:        b.Env.Time.ToInt(),
+		Difficulty:  b.Env.Difficulty.ToInt(),
+		BaseFee:     b.Env.BaseFee.ToInt(),
+		Random:      b.Env.Random,
+	}
+	msg, err := tx.AsMessage(types.MakeSigner(b.Config, blockCtx.BlockNumber), blockCtx.BaseFee)
+	if err != nil {
+		return nil, nil, err
+	}
+	evm := vm.NewEVM(blockCtx, NewEVMTxContext(msg), statedb, b.Config, vmConfig)
+	statedb.Prepare(tx.Hash(), int(b.TxIndex))
+	res, err := ApplyMessage(evm, msg, new(GasPool).AddGas(tx.Gas()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+	}
+	statedb.Finalise(b.Config.IsEIP158(blockCtx.BlockNumber))
+	return newBundleResult(res, statedb.GetLogs(tx.Hash(), common.Hash{})), statedb, nil
+}
+
+// Check verifies that the result of a re-execution matches the result recorded
+// in the bundle. The position of the logs in the chain is not compared.
+func (b *ExecutionBundle) Check(res *BundleResult) error {
+	want := b.Result
+	if want == nil {
+		return errors.New("no result recorded in execution bundle")
+	}
+	switch {
+	case res.UsedGas != want.UsedGas:
+		return fmt.Errorf("gas used mismatch: have %d, want %d", res.UsedGas, want.UsedGas)
+	case res.Error != want.Error:
+		return fmt.Errorf("execution error mismatch: have %q, want %q", res.Error, want.Error)
+	case !bytes.Equal(res.ReturnData, want.ReturnData):
+		return fmt.Errorf("return data mismatch: have %x, want %x", res.ReturnData, want.ReturnData)
+	case len(res.Logs) != len(want.Logs):
+		return fmt.Errorf("log count mismatch: have %d, want %d", len(res.Logs), len(want.Logs))
+	}
+	for i, have := range res.Logs {
+		if !equalLogContent(have, want.Logs[i]) {
+			return fmt.Errorf("log %d mismatch", i)
+		}
+	}
+	return nil
+}
+
+// equalLogContent reports whether two logs were emitted by the same contract
+// with the same topics and data, ignoring their position in the chain.
+func equalLogContent(a, b *types.Log) bool {
+	if a.Address != b.Address || !bytes.Equal(a.Data, b.Data) || len(a.Topics) != len(b.Topics) {
+		return false
+	}
+	for i := range a.Topics {
+		if a.Topics[i] != b.Topics[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that an execution bundle captures exactly the state a transaction depends
+// on, and that re-executing it offline reproduces the original outcome.
+func TestExecutionBundle(t *testing.T) {
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		contract  = common.HexToAddress("0xaaaa")
+		unrelated = common.HexToAddress("0xbbbb")
+		db        = rawdb.NewMemoryDatabase()
+		gspec     = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				contract: {
+					// Increment slot 0, then log the previous block hash
+					Code:    common.FromHex("0x600054600101600055600143034060006000a100"),
+					Storage: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(5))},
+					Balance: big.NewInt(0),
+				},
+				unrelated: {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{{}: {0x01}}},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, receipts := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, b *BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), contract, big.NewInt(0), 100000, b.BaseFee(), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	block := blocks[0]
+
+	// Position the state right before the second transaction and record it
+	statedb, _ := state.New(genesis.Root(), state.NewDatabase(db), nil)
+	blockCtx := NewEVMBlockContext(block.Header(), nil, &block.Header().Coinbase)
+
+	first := block.Transactions()[0]
+	msg, _ := first.AsMessage(signer, block.BaseFee())
+	statedb.Prepare(first.Hash(), 0)
+	if _, err := ApplyMessage(vm.NewEVM(blockCtx, NewEVMTxContext(msg), statedb, gspec.Config, vm.Config{}), msg, new(GasPool).AddGas(first.Gas())); err != nil {
+		t.Fatalf("failed to apply first transaction: %v", err)
+	}
+	statedb.Finalise(true)
+
+	bundle, err := RecordExecutionBundle(gspec.Config, blockCtx, statedb, block.Transactions()[1], 1)
+	if err != nil {
+		t.Fatalf("failed to record bundle: %v", err)
+	}
+	if have, want := uint64(bundle.Result.UsedGas), receipts[0][1].GasUsed; have != want {
+		t.Errorf("recorded gas mismatch: have %d, want %d", have, want)
+	}
+	if _, ok := bundle.Pre[unrelated]; ok {
+		t.Errorf("untouched account recorded")
+	}
+	if have, want := bundle.Pre[contract].Storage[common.Hash{}], common.BigToHash(big.NewInt(6)); have != want {
+		t.Errorf("slot recorded with wrong value: have %x, want %x", have, want)
+	}
+	if have, want := bundle.Pre[sender].Nonce, uint64(1); have != want {
+		t.Errorf("sender recorded with wrong nonce: have %d, want %d", have, want)
+	}
+	if have, want := bundle.BlockHashes[0], genesis.Hash(); have != want {
+		t.Errorf("block hash recorded with wrong value: have %x, want %x", have, want)
+	}
+	if len(bundle.Result.Logs) != 1 || bundle.Result.Logs[0].Topics[0] != genesis.Hash() {
+		t.Fatalf("unexpected logs recorded: %v", bundle.Result.Logs)
+	}
+	// Round trip the bundle through its encoding and replay it
+	blob, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("failed to encode bundle: %v", err)
+	}
+	replay := new(ExecutionBundle)
+	if err := json.Unmarshal(blob, replay); err != nil {
+		t.Fatalf("failed to decode bundle: %v", err)
+	}
+	res, post, err := replay.Run(vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to run bundle: %v", err)
+	}
+	if err := replay.Check(res); err != nil {
+		t.Fatalf("replay mismatch: %v", err)
+	}
+	if have, want := post.GetState(contract, common.Hash{}), common.BigToHash(big.NewInt(7)); have != want {
+		t.Errorf("post state mismatch: have %x, want %x", have, want)
+	}
+	// Tamper with the recorded state and ensure the replay diverges
+	replay.Pre[contract].Storage[common.Hash{}] = common.Hash{}
+	if res, _, err = replay.Run(vm.Config{}); err != nil {
+		t.Fatalf("failed to run tampered bundle: %v", err)
+	}
+	if err := replay.Check(res); err == nil {
+		t.Fatalf("tampered bundle replayed without mismatch")
+	}
+}
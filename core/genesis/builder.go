@@ -0,0 +1,239 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package genesis composes and validates genesis specifications for private
+// networks.
+package genesis
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Forks is the list of block number based forks, in activation order.
+var Forks = []string{
+	"homestead", "eip150", "eip155", "eip158", "byzantium", "constantinople",
+	"petersburg", "istanbul", "muirGlacier", "berlin", "london", "arrowGlacier",
+	"grayGlacier", "mergeNetsplit", "shanghai", "cancun",
+}
+
+// defaultFork is the last fork activated at genesis by new builders.
+const defaultFork = "grayGlacier"
+
+// ForkBlock returns the activation block of the named fork in the chain config,
+// or nil if the fork is not scheduled.
+func ForkBlock(config *params.ChainConfig, name string) (*big.Int, error) {
+	field, err := forkBlock(config, name)
+	if err != nil {
+		return nil, err
+	}
+	return *field, nil
+}
+
+// forkBlock returns the field of the chain config holding the activation block
+// of the named fork.
+func forkBlock(config *params.ChainConfig, name string) (**big.Int, error) {
+	switch name {
+	case "homestead":
+		return &config.HomesteadBlock, nil
+	case "eip150":
+		return &config.EIP150Block, nil
+	case "eip155":
+		return &config.EIP155Block, nil
+	case "eip158":
+		return &config.EIP158Block, nil
+	case "byzantium":
+		return &config.ByzantiumBlock, nil
+	case "constantinople":
+		return &config.ConstantinopleBlock, nil
+	case "petersburg":
+		return &config.PetersburgBlock, nil
+	case "istanbul":
+		return &config.IstanbulBlock, nil
+	case "muirGlacier":
+		return &config.MuirGlacierBlock, nil
+	case "berlin":
+		return &config.BerlinBlock, nil
+	case "london":
+		return &config.LondonBlock, nil
+	case "arrowGlacier":
+		return &config.ArrowGlacierBlock, nil
+	case "grayGlacier":
+		return &config.GrayGlacierBlock, nil
+	case "mergeNetsplit":
+		return &config.MergeNetsplitBlock, nil
+	case "shanghai":
+		return &config.ShanghaiBlock, nil
+	case "cancun":
+		return &config.CancunBlock, nil
+	case "p256Verify":
+		return &config.P256VerifyBlock, nil
+	case "eof":
+		return &config.EOFBlock, nil
+	}
+	return nil, fmt.Errorf("unknown fork %q", name)
+}
+
+// Builder composes a genesis specification. A new builder describes an ethash
+// network with all forks up to Gray Glacier active from genesis; the methods
+// adjust it, and Build validates and returns the result.
+type Builder struct {
+	genesis *core.Genesis
+}
+
+// NewBuilder creates a genesis builder for the given chain id.
+func NewBuilder(chainID uint64) *Builder {
+	b := &Builder{
+		genesis: &core.Genesis{
+			Config:    &params.ChainConfig{ChainID: new(big.Int).SetUint64(chainID)},
+			Timestamp: uint64(time.Now().Unix()),
+			GasLimit:  params.GenesisGasLimit,
+			Alloc:     make(core.GenesisAlloc),
+		},
+	}
+	b.SetEthash()
+	b.ForksUpTo(defaultFork)
+	return b
+}
+
+// SetFork schedules the named fork at the given block.
+func (b *Builder) SetFork(name string, block uint64) error {
+	field, err := forkBlock(b.genesis.Config, name)
+	if err != nil {
+		return err
+	}
+	*field = new(big.Int).SetUint64(block)
+	return nil
+}
+
+// DisableFork removes the named fork from the schedule.
+func (b *Builder) DisableFork(name string) error {
+	field, err := forkBlock(b.genesis.Config, name)
+	if err != nil {
+		return err
+	}
+	*field = nil
+	return nil
+}
+
+// ForksUpTo activates all forks up to and including the named one at genesis,
+// and disables all later ones.
+func (b *Builder) ForksUpTo(name string) error {
+	if _, err := forkBlock(b.genesis.Config, name); err != nil {
+		return err
+	}
+	active := true
+	for _, fork := range Forks {
+		field, _ := forkBlock(b.genesis.Config, fork)
+		if active {
+			*field = new(big.Int)
+		} else {
+			*field = nil
+		}
+		if fork == name {
+			active = false
+		}
+	}
+	return nil
+}
+
+// SetEthash configures the network to use proof-of-work.
+func (b *Builder) SetEthash() {
+	b.genesis.Config.Ethash = new(params.EthashConfig)
+	b.genesis.Config.Clique = nil
+	b.genesis.Difficulty = big.NewInt(524288)
+	b.genesis.ExtraData = nil
+}
+
+// SetClique configures the network to use proof-of-authority with the given
+// block period, epoch length and initial signers.
+func (b *Builder) SetClique(period, epoch uint64, signers ...common.Address) {
+	b.genesis.Config.Ethash = nil
+	b.genesis.Config.Clique = &params.CliqueConfig{Period: period, Epoch: epoch}
+	b.genesis.Difficulty = big.NewInt(1)
+
+	sorted := append([]common.Address{}, signers...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	extra := make([]byte, 32, 32+len(sorted)*common.AddressLength+crypto.SignatureLength)
+	for _, signer := range sorted {
+		extra = append(extra, signer[:]...)
+	}
+	b.genesis.ExtraData = append(extra, make([]byte, crypto.SignatureLength)...)
+}
+
+// SetGasLimit sets the gas limit of the genesis block.
+func (b *Builder) SetGasLimit(limit uint64) {
+	b.genesis.GasLimit = limit
+}
+
+// SetTimestamp sets the timestamp of the genesis block.
+func (b *Builder) SetTimestamp(timestamp uint64) {
+	b.genesis.Timestamp = timestamp
+}
+
+// Fund prefunds an account with the given balance, in wei.
+func (b *Builder) Fund(addr common.Address, balance *big.Int) {
+	account := b.genesis.Alloc[addr]
+	account.Balance = new(big.Int).Set(balance)
+	b.genesis.Alloc[addr] = account
+}
+
+// SetAccount sets the complete genesis state of an account, e.g. to predeploy
+// a contract.
+func (b *Builder) SetAccount(addr common.Address, account core.GenesisAccount) {
+	b.genesis.Alloc[addr] = account
+}
+
+// FundPrecompiles funds the precompiled contracts with 1 wei each, to prevent
+// them from being removed as empty accounts by EIP-158.
+func (b *Builder) FundPrecompiles() {
+	for _, addr := range vm.PrecompiledAddressesBerlin {
+		if _, ok := b.genesis.Alloc[addr]; !ok {
+			b.Fund(addr, big.NewInt(1))
+		}
+	}
+}
+
+// Build validates the composed genesis and returns a copy of it.
+func (b *Builder) Build() (*core.Genesis, error) {
+	genesis := *b.genesis
+	config := *genesis.Config
+	genesis.Config = &config
+	if config.Clique != nil {
+		clique := *config.Clique
+		config.Clique = &clique
+	}
+	genesis.ExtraData = common.CopyBytes(genesis.ExtraData)
+	genesis.Alloc = make(core.GenesisAlloc, len(b.genesis.Alloc))
+	for addr, account := range b.genesis.Alloc {
+		genesis.Alloc[addr] = account
+	}
+	if err := Validate(&genesis); err != nil {
+		return nil, err
+	}
+	return &genesis, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package genesis
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestBuildClique(t *testing.T) {
+	var (
+		signerA = common.Address{0xbb}
+		signerB = common.Address{0xaa}
+		funded  = common.Address{0x01, 0x02}
+	)
+	b := NewBuilder(12345)
+	b.SetClique(5, 30000, signerA, signerB)
+	b.Fund(funded, big.NewInt(1000))
+	b.FundPrecompiles()
+	if err := b.ForksUpTo("berlin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetFork("london", 100); err != nil {
+		t.Fatal(err)
+	}
+	genesis, err := b.Build()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	if genesis.Config.LondonBlock.Uint64() != 100 || genesis.Config.BerlinBlock.Sign() != 0 || genesis.Config.ArrowGlacierBlock != nil {
+		t.Errorf("unexpected fork schedule: %v", genesis.Config)
+	}
+	if len(genesis.Alloc) != len(vm.PrecompiledAddressesBerlin)+1 || genesis.Alloc[funded].Balance.Int64() != 1000 {
+		t.Errorf("unexpected alloc: %v", genesis.Alloc)
+	}
+	// The genesis must be committable and carry the sorted signer list
+	block := genesis.MustCommit(rawdb.NewMemoryDatabase())
+	if signers := cliqueSigners(block.Extra()); len(signers) != 2 || signers[0] != signerB || signers[1] != signerA {
+		t.Errorf("unexpected signers: %v", signers)
+	}
+	// Modifying the builder afterwards must not affect the built genesis
+	b.Fund(funded, big.NewInt(1))
+	b.SetFork("london", 200)
+	if genesis.Alloc[funded].Balance.Int64() != 1000 || genesis.Config.LondonBlock.Uint64() != 100 {
+		t.Error("built genesis modified by builder")
+	}
+}
+
+// cliqueSigners extracts the signer list from clique genesis extra-data.
+func cliqueSigners(extra []byte) []common.Address {
+	list := extra[32 : len(extra)-65]
+	signers := make([]common.Address, len(list)/common.AddressLength)
+	for i := range signers {
+		copy(signers[i][:], list[i*common.AddressLength:])
+	}
+	return signers
+}
+
+func TestBuildInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *Builder) error
+	}{
+		{"zero chain id", func(b *Builder) error {
+			*b = *NewBuilder(0)
+			return nil
+		}},
+		{"no signers", func(b *Builder) error {
+			b.SetClique(15, 30000)
+			return nil
+		}},
+		{"zero epoch", func(b *Builder) error {
+			b.SetClique(15, 0, common.Address{1})
+			return nil
+		}},
+		{"fork order", func(b *Builder) error {
+			return b.SetFork("berlin", 10)
+		}},
+		{"missing fork", func(b *Builder) error {
+			return b.DisableFork("istanbul")
+		}},
+		{"gas limit", func(b *Builder) error {
+			b.SetGasLimit(params.MinGasLimit - 1)
+			return nil
+		}},
+		{"negative balance", func(b *Builder) error {
+			b.Fund(common.Address{1}, big.NewInt(-1))
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		b := NewBuilder(1)
+		if err := tt.build(b); err != nil {
+			t.Fatalf("%s: setup failed: %v", tt.name, err)
+		}
+		if _, err := b.Build(); err == nil {
+			t.Errorf("%s: invalid genesis accepted", tt.name)
+		}
+	}
+	if err := NewBuilder(1).SetFork("frontier", 1); err == nil {
+		t.Error("unknown fork accepted")
+	}
+}
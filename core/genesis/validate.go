@@ -0,0 +1,95 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package genesis
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Validate checks a genesis specification for mistakes that would produce an
+// unusable network.
+func Validate(genesis *core.Genesis) error {
+	config := genesis.Config
+	if config == nil {
+		return errors.New("missing chain config")
+	}
+	if config.ChainID == nil || config.ChainID.Sign() <= 0 {
+		return errors.New("chain id must be positive")
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		return err
+	}
+	if err := vm.ValidateGasOverrides(config); err != nil {
+		return err
+	}
+	if genesis.GasLimit < params.MinGasLimit || genesis.GasLimit > params.MaxGasLimit {
+		return fmt.Errorf("gas limit %d out of range [%d, %d]", genesis.GasLimit, params.MinGasLimit, params.MaxGasLimit)
+	}
+	switch {
+	case config.Ethash != nil && config.Clique != nil:
+		return errors.New("both ethash and clique configured")
+
+	case config.Clique != nil:
+		if config.Clique.Epoch == 0 {
+			return errors.New("clique epoch must be positive")
+		}
+		if err := validateCliqueExtra(genesis.ExtraData); err != nil {
+			return err
+		}
+
+	case config.Ethash != nil:
+		if uint64(len(genesis.ExtraData)) > params.MaximumExtraDataSize {
+			return fmt.Errorf("extra data too long: %d > %d bytes", len(genesis.ExtraData), params.MaximumExtraDataSize)
+		}
+
+	case config.TerminalTotalDifficulty == nil:
+		return errors.New("no consensus engine configured")
+	}
+	if config.TerminalTotalDifficulty == nil && (genesis.Difficulty == nil || genesis.Difficulty.Sign() <= 0) {
+		return errors.New("difficulty must be positive before the merge")
+	}
+	for addr, account := range genesis.Alloc {
+		if account.Balance == nil || account.Balance.Sign() < 0 {
+			return fmt.Errorf("account %s: invalid balance", addr)
+		}
+	}
+	return nil
+}
+
+// validateCliqueExtra checks that the extra-data of a clique genesis consists of
+// the vanity, at least one signer and the (empty) seal.
+func validateCliqueExtra(extra []byte) error {
+	const vanity = 32
+	if len(extra) < vanity+crypto.SignatureLength {
+		return errors.New("clique extra data too short")
+	}
+	signers := len(extra) - vanity - crypto.SignatureLength
+	if signers%common.AddressLength != 0 {
+		return errors.New("clique extra data has invalid signer list")
+	}
+	if signers == 0 {
+		return errors.New("no clique signers")
+	}
+	return nil
+}
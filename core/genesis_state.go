@@ -0,0 +1,133 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// GenesisFromState creates a genesis specification whose allocation mirrors the
+// state at the given header, for bootstrapping a fork or test network from an
+// existing chain. If addresses is non-empty only those accounts are included,
+// otherwise all accounts whose address preimage is known. The preimages of the
+// storage slots of all included accounts are required.
+//
+// The header fields carried over are the gas limit, difficulty, base fee and
+// timestamp. Consensus specific fields like the clique signer list in the extra
+// data need to be filled in by the caller.
+func GenesisFromState(config *params.ChainConfig, header *types.Header, statedb *state.StateDB, addresses []common.Address) (*Genesis, error) {
+	alloc := make(GenesisAlloc)
+	if len(addresses) > 0 {
+		for _, addr := range addresses {
+			if !statedb.Exist(addr) {
+				continue
+			}
+			alloc[addr] = GenesisAccount{
+				Code:    statedb.GetCode(addr),
+				Balance: statedb.GetBalance(addr),
+				Nonce:   statedb.GetNonce(addr),
+			}
+		}
+	} else {
+		collector := &allocCollector{alloc: alloc}
+		statedb.DumpToCollector(collector, &state.DumpConfig{SkipStorage: true, OnlyWithAddresses: true})
+		if collector.err != nil {
+			return nil, collector.err
+		}
+	}
+	for addr, account := range alloc {
+		storage, err := dumpStorage(statedb, addr)
+		if err != nil {
+			return nil, err
+		}
+		account.Storage = storage
+		alloc[addr] = account
+	}
+	genesis := &Genesis{
+		Config:     config,
+		Timestamp:  header.Time,
+		GasLimit:   header.GasLimit,
+		Difficulty: new(big.Int).Set(header.Difficulty),
+		Alloc:      alloc,
+	}
+	if header.BaseFee != nil {
+		genesis.BaseFee = new(big.Int).Set(header.BaseFee)
+	}
+	return genesis, nil
+}
+
+// allocCollector is a state.DumpCollector assembling a genesis allocation.
+type allocCollector struct {
+	alloc GenesisAlloc
+	err   error
+}
+
+// OnRoot implements state.DumpCollector.
+func (c *allocCollector) OnRoot(common.Hash) {}
+
+// OnAccount implements state.DumpCollector.
+func (c *allocCollector) OnAccount(addr common.Address, account state.DumpAccount) {
+	balance, ok := new(big.Int).SetString(account.Balance, 10)
+	if !ok {
+		if c.err == nil {
+			c.err = fmt.Errorf("invalid balance %q of account %x", account.Balance, addr)
+		}
+		return
+	}
+	genesisAccount := GenesisAccount{
+		Code:    account.Code,
+		Balance: balance,
+		Nonce:   account.Nonce,
+	}
+	c.alloc[addr] = genesisAccount
+}
+
+// dumpStorage returns the storage slots of an account. Unlike the state dumps, it
+// fails if a slot cannot be exported due to a missing preimage.
+func dumpStorage(statedb *state.StateDB, addr common.Address) (map[common.Hash]common.Hash, error) {
+	tr := statedb.StorageTrie(addr)
+	if tr == nil {
+		return nil, nil
+	}
+	var (
+		storage map[common.Hash]common.Hash
+		it      = trie.NewIterator(tr.NodeIterator(nil))
+	)
+	for it.Next() {
+		key := tr.GetKey(it.Key)
+		if key == nil {
+			return nil, fmt.Errorf("missing preimage of storage slot %x of account %x", it.Key, addr)
+		}
+		_, content, _, err := rlp.Split(it.Value)
+		if err != nil {
+			return nil, err
+		}
+		if storage == nil {
+			storage = make(map[common.Hash]common.Hash)
+		}
+		storage[common.BytesToHash(key)] = common.BytesToHash(content)
+	}
+	return storage, it.Err
+}
@@ -25,9 +25,12 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 func TestInvalidCliqueConfig(t *testing.T) {
@@ -239,3 +242,52 @@ func TestReadWriteGenesisAlloc(t *testing.T) {
 		}
 	}
 }
+
+func TestGenesisFromState(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		sdb   = state.NewDatabaseWithConfig(db, &trie.Config{Preimages: true})
+		alloc = GenesisAlloc{
+			{1}: {Balance: big.NewInt(1), Nonce: 3},
+			{2}: {Balance: big.NewInt(2), Code: []byte{0x60, 0x00}, Storage: map[common.Hash]common.Hash{{1}: {1}, {2}: {2}}},
+			{3}: {Balance: big.NewInt(3)},
+		}
+	)
+	statedb, _ := state.New(common.Hash{}, sdb, nil)
+	for addr, account := range alloc {
+		statedb.AddBalance(addr, account.Balance)
+		statedb.SetCode(addr, account.Code)
+		statedb.SetNonce(addr, account.Nonce)
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	root, _ := statedb.Commit(false)
+	sdb.TrieDB().Commit(root, true, nil)
+
+	header := &types.Header{Root: root, GasLimit: 30_000_000, Difficulty: big.NewInt(1), BaseFee: big.NewInt(7), Time: 42}
+	for _, addrs := range [][]common.Address{nil, {{1}, {2}, {3}, {4}}} {
+		statedb, _ := state.New(root, sdb, nil)
+		genesis, err := GenesisFromState(params.TestChainConfig, header, statedb, addrs)
+		if err != nil {
+			t.Fatalf("failed to create genesis: %v", err)
+		}
+		if !reflect.DeepEqual(genesis.Alloc, alloc) {
+			t.Errorf("allocation mismatch (addresses %v):\nhave %v\nwant %v", addrs, spew.Sdump(genesis.Alloc), spew.Sdump(alloc))
+		}
+		if hash, _ := genesis.Alloc.deriveHash(); hash != root {
+			t.Errorf("state root mismatch: have %x, want %x", hash, root)
+		}
+		if genesis.GasLimit != header.GasLimit || genesis.Timestamp != header.Time || genesis.BaseFee.Cmp(header.BaseFee) != 0 {
+			t.Errorf("header fields not carried over")
+		}
+	}
+	statedb, _ = state.New(root, sdb, nil)
+	genesis, err := GenesisFromState(params.TestChainConfig, header, statedb, []common.Address{{2}})
+	if err != nil {
+		t.Fatalf("failed to create genesis: %v", err)
+	}
+	if len(genesis.Alloc) != 1 || !reflect.DeepEqual(genesis.Alloc[common.Address{2}], alloc[common.Address{2}]) {
+		t.Errorf("filtered allocation mismatch: %v", spew.Sdump(genesis.Alloc))
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// traceInternalTxs executes a block which was not imported through insertChain,
+// e.g. one sealed by the local miner, to collect its internal value transfers.
+func (bc *BlockChain) traceInternalTxs(block *types.Block) []*types.InternalTx {
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		log.Warn("Missing parent for internal transaction tracing", "number", block.Number(), "hash", block.Hash())
+		return nil
+	}
+	statedb, err := bc.StateAt(parent.Root)
+	if err != nil {
+		log.Warn("Missing state for internal transaction tracing", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return nil
+	}
+	var (
+		tracer   = newInternalTxTracer(block)
+		vmConfig = bc.vmConfig
+	)
+	vmConfig.Debug, vmConfig.Tracer = true, tracer
+	if _, _, _, err := bc.processor.Process(block, statedb, vmConfig); err != nil {
+		log.Warn("Failed to trace internal transactions", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return nil
+	}
+	return tracer.transfers()
+}
+
+// internalTxTracer is a lightweight tracer collecting the value transfers of the
+// nested calls of the transactions in a block, for the internal transaction index.
+type internalTxTracer struct {
+	block   *types.Block
+	txIndex int                 // Index of the transaction being executed
+	pending []*types.InternalTx // Transfers of the transaction being executed
+	frames  []int               // Number of pending transfers at the start of each open call frame
+	itxs    []*types.InternalTx // Transfers of the executed transactions
+}
+
+func newInternalTxTracer(block *types.Block) *internalTxTracer {
+	return &internalTxTracer{block: block, txIndex: -1}
+}
+
+// transfers returns the value transfers of the executed transactions.
+func (t *internalTxTracer) transfers() []*types.InternalTx {
+	return t.itxs
+}
+
+func (t *internalTxTracer) CaptureTxStart(gasLimit uint64) {
+	t.txIndex++
+	t.pending, t.frames = t.pending[:0], t.frames[:0]
+}
+
+func (t *internalTxTracer) CaptureTxEnd(restGas uint64) {
+	txs := t.block.Transactions()
+	if t.txIndex >= len(txs) {
+		return
+	}
+	for i, itx := range t.pending {
+		itx.BlockNumber = t.block.NumberU64()
+		itx.BlockHash = t.block.Hash()
+		itx.TxHash = txs[t.txIndex].Hash()
+		itx.TxIndex = uint(t.txIndex)
+		itx.Index = uint(i)
+		t.itxs = append(t.itxs, itx)
+	}
+	t.pending = t.pending[:0]
+}
+
+func (t *internalTxTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+
+func (t *internalTxTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	// A failed transaction reverts all the nested transfers
+	if err != nil {
+		t.pending = t.pending[:0]
+	}
+}
+
+func (t *internalTxTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.frames = append(t.frames, len(t.pending))
+
+	// CALLCODE keeps the value with the caller, it's not a transfer
+	if typ == vm.CALLCODE || value == nil || value.Sign() == 0 {
+		return
+	}
+	t.pending = append(t.pending, &types.InternalTx{
+		Type:  typ.String(),
+		From:  from,
+		To:    to,
+		Value: new(big.Int).Set(value),
+	})
+}
+
+func (t *internalTxTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	// A failed call frame reverts its own and all nested transfers
+	start := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+	if err != nil {
+		t.pending = t.pending[:start]
+	}
+}
+
+func (t *internalTxTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (t *internalTxTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
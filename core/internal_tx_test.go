@@ -0,0 +1,165 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// forwarderCode returns contract code forwarding the call value to the target,
+// optionally reverting afterwards.
+func forwarderCode(target common.Address, revert bool) []byte {
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.CALLVALUE), byte(vm.PUSH20),
+	}
+	code = append(code, target.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL))
+	if revert {
+		return append(code, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT))
+	}
+	return append(code, byte(vm.STOP))
+}
+
+// Tests that the value transfers of nested calls are indexed on import, leaving
+// out those of reverted frames.
+func TestInternalTxIndex(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+
+		sink      = common.HexToAddress("0xbbbb") // Receives the forwarded value
+		forwarder = common.HexToAddress("0xaaaa") // Forwards to the sink
+		reverter  = common.HexToAddress("0xcccc") // Forwards to the sink and reverts
+		nested    = common.HexToAddress("0xdddd") // Forwards to the reverter
+		chained   = common.HexToAddress("0xeeee") // Forwards to the forwarder
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				sender:    {Balance: big.NewInt(params.Ether)},
+				forwarder: {Code: forwarderCode(sink, false), Balance: common.Big0},
+				reverter:  {Code: forwarderCode(sink, true), Balance: common.Big0},
+				nested:    {Code: forwarderCode(reverter, false), Balance: common.Big0},
+				chained:   {Code: forwarderCode(forwarder, false), Balance: common.Big0},
+			},
+		}
+		engine = ethash.NewFaker()
+		db     = rawdb.NewMemoryDatabase()
+	)
+	genesis := gspec.MustCommit(db)
+	signer := types.LatestSigner(gspec.Config)
+
+	calls := []struct {
+		to    common.Address
+		value int64
+	}{
+		{forwarder, 100}, // Single transfer
+		{reverter, 50},   // Failed transaction
+		{nested, 70},     // Reverted nested call
+		{forwarder, 0},   // No value
+		{chained, 30},    // Two transfers
+	}
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 1, func(i int, b *BlockGen) {
+		for nonce, call := range calls {
+			tx, _ := types.SignTx(types.NewTransaction(uint64(nonce), call.to, big.NewInt(call.value), 100000, b.header.BaseFee, nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	diskdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(diskdb)
+
+	chain, err := NewBlockChain(diskdb, &CacheConfig{TrieDirtyDisabled: true, InternalTxIndex: true}, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if tail := rawdb.ReadInternalTxIndexTail(diskdb); tail == nil || *tail != 1 {
+		t.Fatalf("index tail mismatch: have %v, want 1", tail)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	var (
+		block = blocks[0]
+		txs   = block.Transactions()
+	)
+	transfer := func(tx, index uint, from, to common.Address, value int64) *types.InternalTx {
+		return &types.InternalTx{
+			Type: "CALL", From: from, To: to, Value: big.NewInt(value),
+			BlockNumber: 1, BlockHash: block.Hash(), TxHash: txs[tx].Hash(), TxIndex: tx, Index: index,
+		}
+	}
+	var (
+		first  = transfer(0, 0, forwarder, sink, 100)
+		second = transfer(4, 0, chained, forwarder, 30)
+		third  = transfer(4, 1, forwarder, sink, 30)
+	)
+	tests := []struct {
+		address common.Address
+		want    []*types.InternalTx
+	}{
+		{sink, []*types.InternalTx{first, third}},
+		{forwarder, []*types.InternalTx{first, second, third}},
+		{chained, []*types.InternalTx{second}},
+		{reverter, nil},
+		{nested, nil},
+	}
+	for _, tt := range tests {
+		var have []*types.InternalTx
+		err := rawdb.IterateInternalTxs(diskdb, tt.address, 0, 1, func(itx *types.InternalTx) bool {
+			have = append(have, itx)
+			return true
+		})
+		if err != nil {
+			t.Fatalf("%x: failed to iterate index: %v", tt.address, err)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("%x: transfers mismatch:\nhave %+v\nwant %+v", tt.address, have, tt.want)
+		}
+	}
+	// Blocks not imported through the chain, e.g. mined ones, are traced separately
+	if have, want := chain.traceInternalTxs(block), []*types.InternalTx{first, second, third}; !reflect.DeepEqual(have, want) {
+		t.Errorf("traced transfers mismatch:\nhave %+v\nwant %+v", have, want)
+	}
+	// Disabling the index should drop the existing entries along with the tail
+	chain.Stop()
+	chain, err = NewBlockChain(diskdb, &CacheConfig{TrieDirtyDisabled: true}, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to recreate chain: %v", err)
+	}
+	chain.Stop()
+
+	if tail := rawdb.ReadInternalTxIndexTail(diskdb); tail != nil {
+		t.Fatalf("index tail retained: %d", *tail)
+	}
+	rawdb.IterateInternalTxs(diskdb, forwarder, 0, 1, func(itx *types.InternalTx) bool {
+		t.Errorf("transfer retained: %+v", itx)
+		return true
+	})
+}
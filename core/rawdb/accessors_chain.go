@@ -278,6 +278,60 @@ func WriteTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadAddressLogIndexTail retrieves the number of the oldest block whose logs
+// have been indexed by address. Nil is returned if the index is not maintained.
+func ReadAddressLogIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(addressLogIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteAddressLogIndexTail stores the number of the oldest block whose logs
+// have been indexed by address.
+func WriteAddressLogIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(addressLogIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the address log index tail", "err", err)
+	}
+}
+
+// DeleteAddressLogIndexTail removes the address log index tail, marking the
+// index as not maintained.
+func DeleteAddressLogIndexTail(db ethdb.KeyValueWriter) {
+	if err := db.Delete(addressLogIndexTailKey); err != nil {
+		log.Crit("Failed to delete the address log index tail", "err", err)
+	}
+}
+
+// ReadInternalTxIndexTail retrieves the number of the oldest block whose internal
+// value transfers have been indexed. Nil is returned if the index is not maintained.
+func ReadInternalTxIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(internalTxIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteInternalTxIndexTail stores the number of the oldest block whose internal
+// value transfers have been indexed.
+func WriteInternalTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(internalTxIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the internal transaction index tail", "err", err)
+	}
+}
+
+// DeleteInternalTxIndexTail removes the internal transaction index tail, marking
+// the index as not maintained.
+func DeleteInternalTxIndexTail(db ethdb.KeyValueWriter) {
+	if err := db.Delete(internalTxIndexTailKey); err != nil {
+		log.Crit("Failed to delete the internal transaction index tail", "err", err)
+	}
+}
+
 // ReadFastTxLookupLimit retrieves the tx lookup limit used in fast sync.
 func ReadFastTxLookupLimit(db ethdb.KeyValueReader) *uint64 {
 	data, _ := db.Get(fastTxLookupLimitKey)
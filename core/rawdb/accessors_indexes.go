@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -93,6 +94,177 @@ func DeleteTxLookupEntries(db ethdb.KeyValueWriter, hashes []common.Hash) {
 	}
 }
 
+// WriteAddressLogIndex indexes the block by the addresses of the contracts
+// which emitted logs in it.
+func WriteAddressLogIndex(db ethdb.KeyValueWriter, number uint64, receipts types.Receipts) {
+	seen := make(map[common.Address]struct{})
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			seen[l.Address] = struct{}{}
+		}
+	}
+	for address := range seen {
+		if err := db.Put(addressLogKey(address, number), nil); err != nil {
+			log.Crit("Failed to store address log index entry", "err", err)
+		}
+	}
+}
+
+// ReadAddressLogBlocks retrieves the numbers of the blocks within [from, to] in
+// which the given address emitted logs. The index is maintained for all imported
+// blocks, so the numbers may include blocks which are not canonical anymore.
+func ReadAddressLogBlocks(db ethdb.Iteratee, address common.Address, from, to uint64) []uint64 {
+	prefix := append(addressLogPrefix, address.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var numbers []uint64
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+8 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(it.Key()[len(prefix):])
+		if number > to {
+			break
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers
+}
+
+// internalTxRLP is the storage encoding of an internal transfer, its position is
+// part of the database key.
+type internalTxRLP struct {
+	TxHash common.Hash
+	Type   string
+	From   common.Address
+	To     common.Address
+	Value  *big.Int
+}
+
+// WriteInternalTxIndex indexes the internal value transfers of a block by the
+// addresses of their senders and recipients.
+func WriteInternalTxIndex(db ethdb.KeyValueWriter, hash common.Hash, number uint64, itxs []*types.InternalTx) {
+	for _, itx := range itxs {
+		data, err := rlp.EncodeToBytes(&internalTxRLP{TxHash: itx.TxHash, Type: itx.Type, From: itx.From, To: itx.To, Value: itx.Value})
+		if err != nil {
+			log.Crit("Failed to encode internal transaction", "err", err)
+		}
+		if err := db.Put(internalTxKey(itx.From, number, hash, itx.TxIndex, itx.Index), data); err != nil {
+			log.Crit("Failed to store internal transaction index entry", "err", err)
+		}
+		if itx.To != itx.From {
+			if err := db.Put(internalTxKey(itx.To, number, hash, itx.TxIndex, itx.Index), data); err != nil {
+				log.Crit("Failed to store internal transaction index entry", "err", err)
+			}
+		}
+	}
+}
+
+// IterateInternalTxs calls fn with the indexed internal transfers sent or received
+// by the given address within the blocks [from, to] in chain order, until fn
+// returns false. The index is maintained for all imported blocks, so transfers of
+// blocks which are not canonical anymore are included too.
+func IterateInternalTxs(db ethdb.Iteratee, address common.Address, from, to uint64, fn func(*types.InternalTx) bool) error {
+	prefix := append(internalTxPrefix, address.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+8+common.HashLength+8 {
+			continue
+		}
+		key = key[len(prefix):]
+		number := binary.BigEndian.Uint64(key)
+		if number > to {
+			break
+		}
+		var entry internalTxRLP
+		if err := rlp.DecodeBytes(it.Value(), &entry); err != nil {
+			return err
+		}
+		itx := &types.InternalTx{
+			Type:        entry.Type,
+			From:        entry.From,
+			To:          entry.To,
+			Value:       entry.Value,
+			BlockNumber: number,
+			BlockHash:   common.BytesToHash(key[8 : 8+common.HashLength]),
+			TxHash:      entry.TxHash,
+			TxIndex:     uint(binary.BigEndian.Uint32(key[8+common.HashLength:])),
+			Index:       uint(binary.BigEndian.Uint32(key[12+common.HashLength:])),
+		}
+		if !fn(itx) {
+			break
+		}
+	}
+	return it.Error()
+}
+
+// DeleteAddressLogIndex removes the address log index entries of all blocks below
+// the given limit. It returns the number of deleted entries and whether all of
+// them were deleted before the interrupt channel closed.
+func DeleteAddressLogIndex(db ethdb.KeyValueStore, limit uint64, interrupt chan struct{}) (int, bool) {
+	return deleteAddressIndex(db, addressLogPrefix, 0, limit, interrupt)
+}
+
+// DeleteInternalTxIndex removes the internal transaction index entries of all
+// blocks below the given limit. It returns the number of deleted entries and
+// whether all of them were deleted before the interrupt channel closed.
+func DeleteInternalTxIndex(db ethdb.KeyValueStore, limit uint64, interrupt chan struct{}) (int, bool) {
+	return deleteAddressIndex(db, internalTxPrefix, common.HashLength+8, limit, interrupt)
+}
+
+// deleteAddressIndex removes the entries of an index keyed by prefix + address +
+// block number + suffix for all blocks below the given limit. The key length is
+// checked, as other database keys share the single byte prefixes.
+func deleteAddressIndex(db ethdb.KeyValueStore, prefix []byte, suffix int, limit uint64, interrupt chan struct{}) (int, bool) {
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var (
+		batch   = db.NewBatch()
+		deleted int
+	)
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+common.AddressLength+8+suffix {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(prefix)+common.AddressLength:]) >= limit {
+			continue
+		}
+		if err := batch.Delete(key); err != nil {
+			log.Crit("Failed to delete index entry", "err", err)
+		}
+		deleted++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Error("Failed to delete index entries", "err", err)
+				return deleted, false
+			}
+			batch.Reset()
+
+			select {
+			case <-interrupt:
+				return deleted, false
+			default:
+			}
+		}
+	}
+	// The drop runs in the background and may race with the database being
+	// closed, so write failures are reported rather than being fatal.
+	if batch.ValueSize() > 0 {
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to delete index entries", "err", err)
+			return deleted, false
+		}
+	}
+	return deleted, true
+}
+
 // ReadTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransaction(db ethdb.Reader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
@@ -19,7 +19,9 @@ package rawdb
 import (
 	"bytes"
 	"hash"
+	"math"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -177,3 +179,89 @@ func TestDeleteBloomBits(t *testing.T) {
 	check(1, 1, params.MainnetGenesisHash, true)
 	check(1, 1, params.RinkebyGenesisHash, true)
 }
+
+func TestAddressLogIndex(t *testing.T) {
+	var (
+		db    = NewMemoryDatabase()
+		addr1 = common.Address{0x01}
+		addr2 = common.Address{0x02}
+	)
+	receipt := func(addrs ...common.Address) *types.Receipt {
+		r := new(types.Receipt)
+		for _, addr := range addrs {
+			r.Logs = append(r.Logs, &types.Log{Address: addr})
+		}
+		return r
+	}
+	WriteAddressLogIndex(db, 1, types.Receipts{receipt(addr1, addr1), receipt(addr2)})
+	WriteAddressLogIndex(db, 5, types.Receipts{receipt(addr1)})
+	WriteAddressLogIndex(db, 256, types.Receipts{receipt(), receipt(addr1)})
+	WriteAddressLogIndex(db, 300, types.Receipts{receipt(addr2)})
+
+	tests := []struct {
+		addr     common.Address
+		from, to uint64
+		want     []uint64
+	}{
+		{addr1, 0, 1000, []uint64{1, 5, 256}},
+		{addr1, 2, 256, []uint64{5, 256}},
+		{addr1, 6, 255, nil},
+		{addr2, 0, 1000, []uint64{1, 300}},
+		{common.Address{0x03}, 0, 1000, nil},
+	}
+	for i, tt := range tests {
+		if have := ReadAddressLogBlocks(db, tt.addr, tt.from, tt.to); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	if tail := ReadAddressLogIndexTail(db); tail != nil {
+		t.Fatalf("unexpected index tail %d", *tail)
+	}
+	WriteAddressLogIndexTail(db, 1)
+	if tail := ReadAddressLogIndexTail(db); tail == nil || *tail != 1 {
+		t.Fatalf("index tail mismatch: have %v, want 1", tail)
+	}
+	DeleteAddressLogIndexTail(db)
+	if tail := ReadAddressLogIndexTail(db); tail != nil {
+		t.Fatalf("index tail not deleted")
+	}
+	// Deleting the index should only remove the entries below the limit, and no
+	// other keys sharing the prefix
+	WriteHeadBlockHash(db, common.Hash{0xff})
+	if deleted, done := DeleteAddressLogIndex(db, 256, nil); deleted != 3 || !done {
+		t.Fatalf("deleted entries mismatch: have %d/%v, want 3/true", deleted, done)
+	}
+	if have := ReadAddressLogBlocks(db, addr1, 0, 1000); !reflect.DeepEqual(have, []uint64{256}) {
+		t.Fatalf("retained blocks mismatch: have %v, want [256]", have)
+	}
+	if deleted, _ := DeleteAddressLogIndex(db, math.MaxUint64, nil); deleted != 2 {
+		t.Fatalf("deleted entries mismatch: have %d, want 2", deleted)
+	}
+	if have := ReadHeadBlockHash(db); have != (common.Hash{0xff}) {
+		t.Fatalf("unrelated key deleted")
+	}
+}
+
+func TestDeleteInternalTxIndex(t *testing.T) {
+	var (
+		db    = NewMemoryDatabase()
+		addr1 = common.Address{0x01}
+		addr2 = common.Address{0x02}
+	)
+	WriteInternalTxIndex(db, common.Hash{0x01}, 1, []*types.InternalTx{{From: addr1, To: addr2, Value: big.NewInt(1)}})
+	WriteInternalTxIndex(db, common.Hash{0x02}, 2, []*types.InternalTx{{From: addr2, To: addr2, Value: big.NewInt(1)}})
+	WriteTxIndexTail(db, 7)
+
+	if deleted, done := DeleteInternalTxIndex(db, math.MaxUint64, nil); deleted != 3 || !done {
+		t.Fatalf("deleted entries mismatch: have %d/%v, want 3/true", deleted, done)
+	}
+	for _, addr := range []common.Address{addr1, addr2} {
+		IterateInternalTxs(db, addr, 0, math.MaxUint64, func(itx *types.InternalTx) bool {
+			t.Errorf("%x: transfer retained: %+v", addr, itx)
+			return true
+		})
+	}
+	if tail := ReadTxIndexTail(db); tail == nil || *tail != 7 {
+		t.Fatalf("unrelated key deleted")
+	}
+}
@@ -118,3 +118,53 @@ func DeleteTrieNode(db ethdb.KeyValueWriter, hash common.Hash) {
 		log.Crit("Failed to delete trie node", "err", err)
 	}
 }
+
+// ReadContractMetadata retrieves the encoded metadata of the contract with the
+// provided code hash.
+func ReadContractMetadata(db ethdb.KeyValueReader, hash common.Hash) []byte {
+	data, _ := db.Get(contractMetaKey(hash))
+	return data
+}
+
+// WriteContractMetadata stores the encoded metadata of the contract with the
+// provided code hash.
+func WriteContractMetadata(db ethdb.KeyValueWriter, hash common.Hash, data []byte) {
+	if err := db.Put(contractMetaKey(hash), data); err != nil {
+		log.Crit("Failed to store contract metadata", "err", err)
+	}
+}
+
+// DeleteContractMetadata removes the metadata of the contract with the provided
+// code hash.
+func DeleteContractMetadata(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(contractMetaKey(hash)); err != nil {
+		log.Crit("Failed to delete contract metadata", "err", err)
+	}
+}
+
+// HasContractMetadata reports whether the metadata of any contract is stored.
+func HasContractMetadata(db ethdb.Iteratee) bool {
+	it := db.NewIterator(contractMetaPrefix, nil)
+	defer it.Release()
+
+	return it.Next()
+}
+
+// ReadContractSkeleton retrieves the hash of the code with metadata whose code
+// skeleton, i.e. the code stripped of its compiler metadata, has the provided
+// hash.
+func ReadContractSkeleton(db ethdb.KeyValueReader, skeleton common.Hash) common.Hash {
+	data, _ := db.Get(contractSkeletonKey(skeleton))
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteContractSkeleton maps the hash of a code skeleton to the hash of the code
+// with metadata it was derived from.
+func WriteContractSkeleton(db ethdb.KeyValueWriter, skeleton common.Hash, hash common.Hash) {
+	if err := db.Put(contractSkeletonKey(skeleton), hash.Bytes()); err != nil {
+		log.Crit("Failed to store contract skeleton", "err", err)
+	}
+}
@@ -46,6 +46,18 @@ var chainFreezerNoSnappy = map[string]bool{
 	chainFreezerDifficultyTable: true,
 }
 
+// ChainFreezerTables returns the names of the chain freezer tables in the order
+// their items are written.
+func ChainFreezerTables() []string {
+	return []string{
+		chainFreezerHeaderTable,
+		chainFreezerHashTable,
+		chainFreezerBodiesTable,
+		chainFreezerReceiptTable,
+		chainFreezerDifficultyTable,
+	}
+}
+
 // The list of identifiers of ancient stores.
 var (
 	chainFreezerName = "chain" // the folder name of chain segment ancient store.
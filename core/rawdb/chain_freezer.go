@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"crypto/cipher"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -54,9 +55,10 @@ type chainFreezer struct {
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
 }
 
-// newChainFreezer initializes the freezer for ancient chain data.
-func newChainFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*chainFreezer, error) {
-	freezer, err := NewFreezer(datadir, namespace, readonly, maxTableSize, tables)
+// newChainFreezer initializes the freezer for ancient chain data, encrypting it
+// with the given cipher if it's non-nil.
+func newChainFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool, aead cipher.AEAD) (*chainFreezer, error) {
+	freezer, err := newFreezer(datadir, namespace, readonly, maxTableSize, tables, aead)
 	if err != nil {
 		return nil, err
 	}
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"errors"
 	"fmt"
 	"os"
@@ -27,6 +28,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encrypted"
 	"github.com/ethereum/go-ethereum/ethdb/leveldb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/log"
@@ -197,8 +199,23 @@ func resolveChainFreezerDir(ancient string) string {
 // storage. The passed ancient indicates the path of root ancient directory
 // where the chain freezer can be opened.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	return newDatabaseWithFreezer(db, ancient, namespace, readonly, nil)
+}
+
+// NewEncryptedDatabaseWithFreezer creates a high level database on top of a given
+// key-value data store with a freezer, encrypting both the key-value store and the
+// ancient chain segments with the given cipher.
+func NewEncryptedDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool, aead cipher.AEAD) (ethdb.Database, error) {
+	edb, err := encrypted.New(db, aead, readonly)
+	if err != nil {
+		return nil, err
+	}
+	return newDatabaseWithFreezer(edb, ancient, namespace, readonly, aead)
+}
+
+func newDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool, aead cipher.AEAD) (ethdb.Database, error) {
 	// Create the idle freezer instance
-	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly, freezerTableSize, chainFreezerNoSnappy)
+	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly, freezerTableSize, chainFreezerNoSnappy, aead)
 	if err != nil {
 		return nil, err
 	}
@@ -321,6 +338,37 @@ func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, ancient
 	return frdb, nil
 }
 
+// NewEncryptedLevelDBDatabase creates a persistent key-value database without a
+// freezer, encrypting all values with the given cipher.
+func NewEncryptedLevelDBDatabase(file string, cache int, handles int, namespace string, readonly bool, aead cipher.AEAD) (ethdb.Database, error) {
+	kvdb, err := leveldb.New(file, cache, handles, namespace, readonly)
+	if err != nil {
+		return nil, err
+	}
+	db, err := encrypted.New(kvdb, aead, readonly)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return NewDatabase(db), nil
+}
+
+// NewEncryptedLevelDBDatabaseWithFreezer creates a persistent key-value database
+// with a freezer moving immutable chain segments into cold storage, encrypting
+// both with the given cipher.
+func NewEncryptedLevelDBDatabaseWithFreezer(file string, cache int, handles int, ancient string, namespace string, readonly bool, aead cipher.AEAD) (ethdb.Database, error) {
+	kvdb, err := leveldb.New(file, cache, handles, namespace, readonly)
+	if err != nil {
+		return nil, err
+	}
+	frdb, err := NewEncryptedDatabaseWithFreezer(kvdb, ancient, namespace, readonly, aead)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
 type counter uint64
 
 func (c counter) String() string {
@@ -372,6 +420,9 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		tries           stat
 		codes           stat
 		txLookups       stat
+		addressLogs     stat
+		internalTxs     stat
+		contractMetas   stat
 		accountSnaps    stat
 		storageSnaps    stat
 		preimages       stat
@@ -423,6 +474,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			codes.Add(size)
 		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
 			txLookups.Add(size)
+		case bytes.HasPrefix(key, addressLogPrefix) && len(key) == (len(addressLogPrefix)+common.AddressLength+8):
+			addressLogs.Add(size)
+		case bytes.HasPrefix(key, internalTxPrefix) && len(key) == (len(internalTxPrefix)+common.AddressLength+8+common.HashLength+8):
+			internalTxs.Add(size)
+		case bytes.HasPrefix(key, contractMetaPrefix) && len(key) == (len(contractMetaPrefix)+common.HashLength):
+			contractMetas.Add(size)
+		case bytes.HasPrefix(key, contractSkeletonPrefix) && len(key) == (len(contractSkeletonPrefix)+common.HashLength):
+			contractMetas.Add(size)
 		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
 			accountSnaps.Add(size)
 		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
@@ -456,6 +515,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
+				addressLogIndexTailKey, internalTxIndexTailKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -495,8 +555,11 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block number->hash", numHashPairings.Size(), numHashPairings.Count()},
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
+		{"Key-Value store", "Address log index", addressLogs.Size(), addressLogs.Count()},
+		{"Key-Value store", "Internal transaction index", internalTxs.Size(), internalTxs.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
+		{"Key-Value store", "Contract metadata", contractMetas.Size(), contractMetas.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
 		{"Key-Value store", "Account snapshot", accountSnaps.Size(), accountSnaps.Count()},
@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"crypto/cipher"
 	"errors"
 	"fmt"
 	"math"
@@ -74,6 +75,7 @@ type Freezer struct {
 	writeBatch *freezerBatch
 
 	readonly     bool
+	datadir      string                   // Directory holding the table files
 	tables       map[string]*freezerTable // Data tables for storing everything
 	instanceLock fileutil.Releaser        // File-system lock to prevent double opens
 	closeOnce    sync.Once
@@ -85,6 +87,12 @@ type Freezer struct {
 // The 'tables' argument defines the data tables. If the value of a map
 // entry is true, snappy compression is disabled for the table.
 func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, maxTableSize, tables, nil)
+}
+
+// newFreezer creates a freezer instance, encrypting all table items with the
+// given cipher if it's non-nil.
+func newFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool, aead cipher.AEAD) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -107,6 +115,7 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	// Open all the supported data tables
 	freezer := &Freezer{
 		readonly:     readonly,
+		datadir:      datadir,
 		tables:       make(map[string]*freezerTable),
 		instanceLock: lock,
 	}
@@ -121,6 +130,7 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 			lock.Release()
 			return nil, err
 		}
+		table.aead = aead
 		freezer.tables[name] = table
 	}
 
@@ -426,10 +436,11 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	// Set up new dir for the migrated table, the content of which
 	// we'll at the end move over to the ancients dir.
 	migrationPath := filepath.Join(ancientsPath, "migration")
-	newTable, err := newFreezerTable(migrationPath, kind, table.noCompression, false)
+	newTable, err := openTable(migrationPath, kind, table.gen, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, false)
 	if err != nil {
 		return err
 	}
+	newTable.aead = table.aead
 	var (
 		batch  = newTable.newBatch()
 		out    []byte
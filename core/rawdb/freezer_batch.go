@@ -18,11 +18,14 @@ package rawdb
 
 import (
 	"fmt"
+	"sync"
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/ethdb/encrypted"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
 // This is the maximum amount of data that will be buffered in memory
@@ -86,6 +89,7 @@ type freezerTableBatch struct {
 	t *freezerTable
 
 	sb          *snappyBuffer
+	zb          *zstdBuffer
 	encBuffer   writeBuffer
 	dataBuffer  []byte
 	indexBuffer []byte
@@ -96,8 +100,11 @@ type freezerTableBatch struct {
 // newBatch creates a new batch for the freezer table.
 func (t *freezerTable) newBatch() *freezerTableBatch {
 	batch := &freezerTableBatch{t: t}
-	if !t.noCompression {
+	switch t.gen.Compression {
+	case FreezerCompressionSnappy:
 		batch.sb = new(snappyBuffer)
+	case FreezerCompressionZstd:
+		batch.zb = new(zstdBuffer)
 	}
 	batch.reset()
 	return batch
@@ -124,9 +131,9 @@ func (batch *freezerTableBatch) Append(item uint64, data interface{}) error {
 	if err := rlp.Encode(&batch.encBuffer, data); err != nil {
 		return err
 	}
-	encItem := batch.encBuffer.data
-	if batch.sb != nil {
-		encItem = batch.sb.compress(encItem)
+	encItem := batch.compress(batch.encBuffer.data)
+	if batch.t.aead != nil {
+		encItem = encrypted.Seal(batch.t.aead, encItem, batch.t.itemAD(item))
 	}
 	return batch.appendItem(encItem)
 }
@@ -139,13 +146,24 @@ func (batch *freezerTableBatch) AppendRaw(item uint64, blob []byte) error {
 		return fmt.Errorf("%w: have %d want %d", errOutOrderInsertion, item, batch.curItem)
 	}
 
-	encItem := blob
-	if batch.sb != nil {
-		encItem = batch.sb.compress(blob)
+	encItem := batch.compress(blob)
+	if batch.t.aead != nil {
+		encItem = encrypted.Seal(batch.t.aead, encItem, batch.t.itemAD(item))
 	}
 	return batch.appendItem(encItem)
 }
 
+// compress compresses the data with the compression scheme of the table.
+func (batch *freezerTableBatch) compress(data []byte) []byte {
+	switch {
+	case batch.sb != nil:
+		return batch.sb.compress(data)
+	case batch.zb != nil:
+		return batch.zb.compress(data)
+	}
+	return data
+}
+
 func (batch *freezerTableBatch) appendItem(data []byte) error {
 	// Check if item fits into current data file.
 	itemSize := int64(len(data))
@@ -233,6 +251,35 @@ func (s *snappyBuffer) compress(data []byte) []byte {
 	return s.dst
 }
 
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+// zstdCodec returns the zstd encoder and decoder shared by all freezer tables,
+// creating them on first use. Both are safe for concurrent use through EncodeAll
+// and DecodeAll.
+func zstdCodec() (*zstd.Encoder, *zstd.Decoder) {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder
+}
+
+// zstdBuffer writes zstd frames, and can be reused.
+type zstdBuffer struct {
+	dst []byte
+}
+
+// compress zstd-compresses the data.
+func (z *zstdBuffer) compress(data []byte) []byte {
+	enc, _ := zstdCodec()
+	z.dst = enc.EncodeAll(data, z.dst[:0])
+	return z.dst
+}
+
 // writeBuffer implements io.Writer for a byte slice.
 type writeBuffer struct {
 	data []byte
@@ -0,0 +1,446 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The compression schemes of the freezer tables.
+const (
+	FreezerCompressionNone   = "none"   // Items are stored as is
+	FreezerCompressionSnappy = "snappy" // Items are snappy compressed
+	FreezerCompressionZstd   = "zstd"   // Items are zstd compressed
+)
+
+// freezerCompressions are the supported compression schemes of freezer tables.
+var freezerCompressions = []string{FreezerCompressionNone, FreezerCompressionSnappy, FreezerCompressionZstd}
+
+// FreezerMigrationConfig selects the changes to apply to the ancient tables when
+// rewriting them in place.
+type FreezerMigrationConfig struct {
+	Tables      []string `json:"tables"`      // Tables to rewrite, all of them if empty
+	Compression string   `json:"compression"` // Compression to rewrite the tables with, empty keeps the current one
+	MaxFileSize uint32   `json:"maxFileSize"` // Size limit of the rewritten data files, zero keeps the current one
+	DropRetired bool     `json:"dropRetired"` // Delete the files of tables the freezer doesn't use anymore
+}
+
+// MigrateFreezer rewrites the tables of the chain freezer of the given database
+// as configured. The database stays usable during the migration, which may take
+// a long time though.
+func MigrateFreezer(db ethdb.Database, config *FreezerMigrationConfig) error {
+	frdb, ok := db.(*freezerdb)
+	if !ok {
+		return errors.New("database has no ancient store")
+	}
+	freezer := frdb.AncientStore.(*chainFreezer).Freezer
+
+	if config.Compression != "" && !validCompression(config.Compression) {
+		return fmt.Errorf("unsupported compression %q, supported ones: %v", config.Compression, freezerCompressions)
+	}
+	tables := config.Tables
+	if len(tables) == 0 {
+		tables = ChainFreezerTables()
+	}
+	for _, name := range tables {
+		table, ok := freezer.tables[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", errUnknownTable, name)
+		}
+		compression := config.Compression
+		if compression == "" {
+			compression = table.gen.Compression
+		}
+		if err := freezer.RewriteTable(name, compression, config.MaxFileSize); err != nil {
+			return fmt.Errorf("failed to rewrite table %s: %w", name, err)
+		}
+	}
+	if config.DropRetired {
+		if _, err := freezer.DropRetiredTables(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validCompression reports whether the compression scheme is supported.
+func validCompression(compression string) bool {
+	for _, supported := range freezerCompressions {
+		if compression == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// RewriteTable rewrites all items of a table into the files of a new generation,
+// compressing them with the given scheme and splitting them into files of at
+// most maxFileSize bytes, or the current limit if zero. The table switches to
+// the new files afterwards and the old ones are deleted.
+//
+// The freezer stays usable while the items are copied, writers are only blocked
+// to copy the items appended meanwhile and to switch the files. The switch is a
+// single rename of the generation marker of the table, so an interrupted rewrite
+// leaves either the old or the new files in use, and the other ones are deleted
+// when the freezer is opened again.
+func (f *Freezer) RewriteTable(kind string, compression string, maxFileSize uint32) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	if !validCompression(compression) {
+		return fmt.Errorf("unsupported compression %q", compression)
+	}
+	table, ok := f.tables[kind]
+	if !ok {
+		return errUnknownTable
+	}
+	table.lock.RLock()
+	offset, hidden, current, gen := table.itemOffset, atomic.LoadUint64(&table.itemHidden), table.maxFileSize, table.gen
+	table.lock.RUnlock()
+
+	// The tail of a table is encoded in its index, which the rewrite doesn't
+	// carry over.
+	if offset > 0 || hidden > 0 {
+		return fmt.Errorf("rewrite not supported for tail-deleted freezers")
+	}
+	if maxFileSize == 0 {
+		maxFileSize = current
+	}
+	// Start from scratch, leftovers of a failed rewrite may be of a different
+	// configuration
+	next := tableGeneration{Number: gen.Number + 1, Compression: compression}
+	discard := func() error {
+		return removeTableFiles(f.datadir, kind, func(number uint64) bool { return number == next.Number })
+	}
+	if err := discard(); err != nil {
+		return err
+	}
+	rewritten, err := openTable(f.datadir, kind, next, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, maxFileSize, false)
+	if err != nil {
+		return err
+	}
+	rewritten.aead = table.aead
+
+	start := time.Now()
+	log.Info("Rewriting freezer table", "table", kind, "items", atomic.LoadUint64(&table.items), "compression", compression, "filesize", maxFileSize)
+
+	// Copy the bulk of the items without blocking the freezer
+	if err := copyTableItems(table, rewritten, atomic.LoadUint64(&table.items), start); err != nil {
+		rewritten.Close()
+		discard()
+		return err
+	}
+	// Block writers, copy the items appended meanwhile and switch the files
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	items := atomic.LoadUint64(&table.items)
+	if items < atomic.LoadUint64(&rewritten.items) {
+		if err := rewritten.truncateHead(items); err != nil {
+			rewritten.Close()
+			discard()
+			return err
+		}
+	}
+	if err := copyTableItems(table, rewritten, items, start); err != nil {
+		rewritten.Close()
+		discard()
+		return err
+	}
+	if err := rewritten.Sync(); err != nil {
+		rewritten.Close()
+		discard()
+		return err
+	}
+	if err := rewritten.Close(); err != nil {
+		discard()
+		return err
+	}
+	if err := table.replace(next, maxFileSize); err != nil {
+		return err
+	}
+	// The pending batch of the table is bound to the previous compression
+	f.writeBatch = newFreezerBatch(f)
+
+	size, _ := table.size()
+	log.Info("Rewrote freezer table", "table", kind, "items", items, "size", common.StorageSize(size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// copyTableItems appends the items of the source table to the destination table,
+// starting from the first item the destination is missing, until the given one.
+func copyTableItems(src, dst *freezerTable, until uint64, start time.Time) error {
+	var (
+		batch     = dst.newBatch()
+		batchSize = uint64(1024)
+		maxBytes  = uint64(1024 * 1024)
+		logged    = time.Now()
+	)
+	for i := atomic.LoadUint64(&dst.items); i < until; {
+		count := batchSize
+		if i+count > until {
+			count = until - i
+		}
+		items, err := src.RetrieveItems(i, count, maxBytes)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := batch.AppendRaw(i, item); err != nil {
+				return err
+			}
+			i++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Rewriting freezer table", "table", src.name, "copied", i, "items", until, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return batch.commit()
+}
+
+// replace switches the table to the files of the given generation and reopens it
+// with them, deleting the files of the previous generation.
+func (t *freezerTable) replace(gen tableGeneration, maxFileSize uint32) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	oldSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	// Switch to the new files, from here on they are used even if interrupted
+	if err := writeTableGeneration(t.path, t.name, gen); err != nil {
+		return err
+	}
+	// Release the files of the previous generation and reopen the table
+	t.index.Close()
+	t.meta.Close()
+	for num, f := range t.files {
+		f.Close()
+		delete(t.files, num)
+	}
+	fresh, err := openTable(t.path, t.name, gen, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, maxFileSize, false)
+	if err != nil {
+		return err
+	}
+	atomic.StoreUint64(&t.items, atomic.LoadUint64(&fresh.items))
+	atomic.StoreUint64(&t.itemHidden, atomic.LoadUint64(&fresh.itemHidden))
+	t.itemOffset = fresh.itemOffset
+	t.gen = gen
+	t.maxFileSize = maxFileSize
+	t.head, t.index, t.meta, t.files = fresh.head, fresh.index, fresh.meta, fresh.files
+	t.headId, t.tailId, t.headBytes = fresh.headId, fresh.tailId, fresh.headBytes
+
+	stale := func(number uint64) bool { return number != gen.Number }
+	if err := removeTableFiles(t.path, t.name, stale); err != nil {
+		return err
+	}
+	newSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	t.sizeGauge.Dec(int64(oldSize))
+	t.sizeGauge.Inc(int64(newSize))
+	return nil
+}
+
+// tableGeneration is the layout of the files of a freezer table. The files of a
+// rewritten table are named after its generation, which is recorded along with
+// their compression in the generation marker of the table.
+type tableGeneration struct {
+	Number      uint64 // Number of rewrites of the table, zero if there's no marker
+	Compression string // Compression scheme of the items
+}
+
+// file returns the base name of the files of the table in this generation.
+func (gen tableGeneration) file(name string) string {
+	if gen.Number == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s.g%d", name, gen.Number)
+}
+
+// generationFileName returns the name of the generation marker of a table.
+func generationFileName(name string) string {
+	return fmt.Sprintf("%s.gen", name)
+}
+
+// readTableGeneration returns the generation of the files of a table. Tables
+// which were never rewritten use the given compression setting.
+func readTableGeneration(path, name string, noCompression bool) (tableGeneration, error) {
+	blob, err := os.ReadFile(filepath.Join(path, generationFileName(name)))
+	if os.IsNotExist(err) {
+		gen := tableGeneration{Compression: FreezerCompressionSnappy}
+		if noCompression {
+			gen.Compression = FreezerCompressionNone
+		}
+		return gen, nil
+	}
+	if err != nil {
+		return tableGeneration{}, err
+	}
+	var gen tableGeneration
+	if err := rlp.DecodeBytes(blob, &gen); err != nil {
+		return tableGeneration{}, fmt.Errorf("invalid generation marker of freezer table %s: %v", name, err)
+	}
+	if !validCompression(gen.Compression) {
+		return tableGeneration{}, fmt.Errorf("unsupported compression %q of freezer table %s", gen.Compression, name)
+	}
+	return gen, nil
+}
+
+// writeTableGeneration atomically replaces the generation marker of a table, by
+// writing it to a temporary file and renaming that over the marker.
+func writeTableGeneration(path, name string, gen tableGeneration) error {
+	blob, err := rlp.EncodeToBytes(&gen)
+	if err != nil {
+		return err
+	}
+	marker := filepath.Join(path, generationFileName(name))
+	f, err := os.Create(marker + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(blob); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(marker+".tmp", marker); err != nil {
+		return err
+	}
+	// Persist the rename before any of the previous files is deleted
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// removeTableFiles deletes the files of a table belonging to the generations
+// matched by stale, as well as a leftover temporary generation marker.
+func removeTableFiles(path, name string, stale func(number uint64) bool) error {
+	files, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || tableOfFile(file.Name()) != name || file.Name() == generationFileName(name) {
+			continue
+		}
+		if !stale(generationOfFile(file.Name())) {
+			continue
+		}
+		log.Info("Removing stale freezer table file", "database", path, "file", file.Name())
+		if err := os.Remove(filepath.Join(path, file.Name())); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(filepath.Join(path, generationFileName(name)+".tmp")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DropRetiredTables deletes the files of all tables in the freezer directory
+// which are not part of the freezer anymore, returning the names of the dropped
+// tables.
+func (f *Freezer) DropRetiredTables() ([]string, error) {
+	if f.readonly {
+		return nil, errReadOnly
+	}
+	files, err := os.ReadDir(f.datadir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		dropped []string
+		seen    = make(map[string]bool)
+	)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		name := tableOfFile(file.Name())
+		if name == "" {
+			continue
+		}
+		if _, ok := f.tables[name]; ok {
+			continue
+		}
+		if !seen[name] {
+			log.Info("Dropping retired freezer table", "database", f.datadir, "table", name)
+			dropped, seen[name] = append(dropped, name), true
+		}
+		if err := os.Remove(filepath.Join(f.datadir, file.Name())); err != nil {
+			return dropped, err
+		}
+	}
+	return dropped, nil
+}
+
+// tableOfFile returns the name of the table a freezer file belongs to, or an
+// empty string if it's not a table file.
+func tableOfFile(file string) string {
+	parts := strings.Split(file, ".")
+	if len(parts) < 2 || len(parts) > 4 {
+		return ""
+	}
+	switch parts[len(parts)-1] {
+	case "ridx", "cidx", "zidx", "meta", "rdat", "cdat", "zdat", "gen":
+		return parts[0]
+	}
+	return ""
+}
+
+// generationOfFile returns the generation a freezer table file belongs to, which
+// follows the table name in the files of rewritten tables.
+func generationOfFile(file string) uint64 {
+	parts := strings.Split(file, ".")
+	if len(parts) < 3 || !strings.HasPrefix(parts[1], "g") {
+		return 0
+	}
+	number, err := strconv.ParseUint(parts[1][1:], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return number
+}
@@ -0,0 +1,252 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// appendTestItems appends items to all tables of the freezer, starting at the
+// given item number.
+func appendTestItems(t *testing.T, f *Freezer, from, count int) {
+	t.Helper()
+
+	_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := from; i < from+count; i++ {
+			for kind := range f.tables {
+				if err := op.AppendRaw(kind, uint64(i), getChunk(100+i%50, i)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("ModifyAncients failed:", err)
+	}
+}
+
+// checkTestItems checks that all tables of the freezer contain the items added
+// by appendTestItems.
+func checkTestItems(t *testing.T, f *Freezer, count int) {
+	t.Helper()
+
+	for kind := range f.tables {
+		checkAncientCount(t, f, kind, uint64(count))
+		for i := 0; i < count; i++ {
+			blob, err := f.Ancient(kind, uint64(i))
+			if err != nil {
+				t.Fatalf("table %s: failed to read item %d: %v", kind, i, err)
+			}
+			if !bytes.Equal(blob, getChunk(100+i%50, i)) {
+				t.Fatalf("table %s: wrong item %d: %x", kind, i, blob)
+			}
+		}
+	}
+}
+
+func TestFreezerRewriteTable(t *testing.T) {
+	t.Parallel()
+
+	tables := map[string]bool{"compressed": false, "raw": true}
+	f, dir := newFreezerForTesting(t, tables)
+	appendTestItems(t, f, 0, 100)
+
+	// Decompress one table and zstd compress the other one, changing the file sizes
+	if err := f.RewriteTable("compressed", FreezerCompressionNone, 1000); err != nil {
+		t.Fatal("failed to rewrite table:", err)
+	}
+	if err := f.RewriteTable("raw", FreezerCompressionZstd, 5000); err != nil {
+		t.Fatal("failed to rewrite table:", err)
+	}
+	if err := f.RewriteTable("raw", "lz4", 0); err == nil {
+		t.Fatal("unsupported compression accepted")
+	}
+	checkTestItems(t, f, 100)
+
+	if table := f.tables["compressed"]; table.gen.Compression != FreezerCompressionNone || table.maxFileSize != 1000 || table.headId < 10 {
+		t.Fatalf("table not rewritten: compression %s, filesize %d, files %d", table.gen.Compression, table.maxFileSize, table.headId+1)
+	}
+	// The freezer must remain writable, with the new compression
+	appendTestItems(t, f, 100, 50)
+	checkTestItems(t, f, 150)
+
+	// No files of the previous generation or temporary markers must be left
+	for _, pattern := range []string{"compressed.*cdat", "compressed.cidx", "raw.*rdat", "raw.ridx", "*.tmp"} {
+		if matches, _ := filepath.Glob(filepath.Join(dir, pattern)); len(matches) > 0 {
+			t.Errorf("leftover files: %v", matches)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Reopening with the original configuration must use the new compression
+	f, err := NewFreezer(dir, "", false, 2049, tables)
+	if err != nil {
+		t.Fatal("can't reopen freezer", err)
+	}
+	defer f.Close()
+
+	if f.tables["compressed"].gen.Compression != FreezerCompressionNone || f.tables["raw"].gen.Compression != FreezerCompressionZstd {
+		t.Fatal("on-disk compression not detected")
+	}
+	checkTestItems(t, f, 150)
+}
+
+func TestFreezerRewriteRecovery(t *testing.T) {
+	t.Parallel()
+
+	tables := map[string]bool{"a": false}
+	f, dir := newFreezerForTesting(t, tables)
+	appendTestItems(t, f, 0, 100)
+
+	// Copy the table into the files of the next generation, as a rewrite does
+	// before switching to them
+	next := tableGeneration{Number: 1, Compression: FreezerCompressionZstd}
+	rewrite := func() {
+		t.Helper()
+
+		table, err := openTable(dir, "a", next, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 1000, false)
+		if err != nil {
+			t.Fatal("failed to open next generation:", err)
+		}
+		if err := copyTableItems(f.tables["a"], table, 100, time.Now()); err != nil {
+			t.Fatal("failed to copy items:", err)
+		}
+		if err := table.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reopen := func() {
+		t.Helper()
+
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if f, err = NewFreezer(dir, "", false, 2049, tables); err != nil {
+			t.Fatal("can't reopen freezer", err)
+		}
+	}
+	// Interrupted before the switch, the new files must be discarded
+	rewrite()
+	reopen()
+	if gen := f.tables["a"].gen; gen.Number != 0 || gen.Compression != FreezerCompressionSnappy {
+		t.Fatalf("generation mismatch: have %+v", gen)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "a.g1.*")); len(matches) > 0 {
+		t.Fatalf("files of discarded generation left: %v", matches)
+	}
+	checkTestItems(t, f, 100)
+
+	// Interrupted after the switch, the old files must be discarded
+	rewrite()
+	if err := writeTableGeneration(dir, "a", next); err != nil {
+		t.Fatal("failed to switch generation:", err)
+	}
+	reopen()
+	defer f.Close()
+
+	if gen := f.tables["a"].gen; gen != next {
+		t.Fatalf("generation mismatch: have %+v, want %+v", gen, next)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "a.*cdat")); len(matches) > 0 {
+		t.Fatalf("files of previous generation left: %v", matches)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "a.cidx")); len(matches) > 0 {
+		t.Fatalf("index of previous generation left: %v", matches)
+	}
+	checkTestItems(t, f, 100)
+}
+
+func TestFreezerDropRetiredTables(t *testing.T) {
+	t.Parallel()
+
+	f, dir := newFreezerForTesting(t, map[string]bool{"a": false, "b": true, "retired": false})
+	appendTestItems(t, f, 0, 50)
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFreezer(dir, "", false, 2049, map[string]bool{"a": false, "b": true})
+	if err != nil {
+		t.Fatal("can't reopen freezer", err)
+	}
+	defer f.Close()
+
+	dropped, err := f.DropRetiredTables()
+	if err != nil {
+		t.Fatal("failed to drop tables:", err)
+	}
+	if len(dropped) != 1 || dropped[0] != "retired" {
+		t.Fatalf("dropped tables mismatch: %v", dropped)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "retired.*")); len(matches) > 0 {
+		t.Errorf("retired table files left: %v", matches)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "FLOCK")); err != nil {
+		t.Errorf("non-table file removed: %v", err)
+	}
+	checkTestItems(t, f, 50)
+}
+
+func TestMigrateFreezer(t *testing.T) {
+	t.Parallel()
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatal("can't open database", err)
+	}
+	defer db.Close()
+
+	freezer := db.(*freezerdb).AncientStore.(*chainFreezer).Freezer
+	appendTestItems(t, freezer, 0, 20)
+
+	if err := MigrateFreezer(db, &FreezerMigrationConfig{Compression: "lz4"}); err == nil {
+		t.Fatal("unsupported compression accepted")
+	}
+	if err := MigrateFreezer(db, &FreezerMigrationConfig{Tables: []string{"unknown"}}); err == nil {
+		t.Fatal("unknown table accepted")
+	}
+	config := &FreezerMigrationConfig{
+		Tables:      []string{chainFreezerHeaderTable, chainFreezerBodiesTable},
+		Compression: FreezerCompressionZstd,
+		DropRetired: true,
+	}
+	if err := MigrateFreezer(db, config); err != nil {
+		t.Fatal("failed to migrate freezer:", err)
+	}
+	for kind, table := range freezer.tables {
+		want := FreezerCompressionSnappy
+		switch {
+		case kind == chainFreezerHeaderTable || kind == chainFreezerBodiesTable:
+			want = FreezerCompressionZstd
+		case chainFreezerNoSnappy[kind]:
+			want = FreezerCompressionNone
+		}
+		if table.gen.Compression != want {
+			t.Errorf("table %s: compression mismatch: have %s, want %s", kind, table.gen.Compression, want)
+		}
+	}
+	checkTestItems(t, freezer, 20)
+}
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -28,6 +29,7 @@ import (
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/encrypted"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/golang/snappy"
@@ -101,11 +103,12 @@ type freezerTable struct {
 	// should never be lower than itemOffset.
 	itemHidden uint64
 
-	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
-	readonly      bool
-	maxFileSize   uint32 // Max file size for data-files
-	name          string
-	path          string
+	gen         tableGeneration // Generation and compression of the table files. Note: changed only by rewriting the table
+	aead        cipher.AEAD     // if non-nil, items are encrypted after compression. Note: does not work retroactively
+	readonly    bool
+	maxFileSize uint32 // Max file size for data-files
+	name        string
+	path        string
 
 	head   *os.File            // File descriptor for the data head of the table
 	index  *os.File            // File descriptor for the indexEntry file of the table
@@ -131,17 +134,32 @@ func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerT
 // newTable opens a freezer table, creating the data and index files if they are
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
+//
+// If the table was rewritten, the files of its current generation are opened
+// regardless of noCompression, and the files of any other generation are deleted.
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	gen, err := readTableGeneration(path, name, noCompression)
+	if err != nil {
+		return nil, err
+	}
+	if !readonly {
+		// Clean up after a rewrite interrupted before or after switching generations
+		stale := func(number uint64) bool { return number != gen.Number }
+		if err := removeTableFiles(path, name, stale); err != nil {
+			return nil, err
+		}
+	}
+	return openTable(path, name, gen, readMeter, writeMeter, sizeGauge, maxFilesize, readonly)
+}
+
+// openTable opens the files of the given generation of a freezer table, creating
+// them if they are non-existent.
+func openTable(path string, name string, gen tableGeneration, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, readonly bool) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
-	var idxName string
-	if noCompression {
-		idxName = fmt.Sprintf("%s.ridx", name) // raw index file
-	} else {
-		idxName = fmt.Sprintf("%s.cidx", name) // compressed index file
-	}
+	idxName := indexFileName(gen.file(name), gen.Compression)
 	var (
 		err   error
 		index *os.File
@@ -161,7 +179,7 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		// will suddenly break lots of database relevant commands. So the metadata file
 		// is always opened for mutation and nothing else will be written except
 		// the initialization.
-		meta, err = openFreezerFileForAppend(filepath.Join(path, fmt.Sprintf("%s.meta", name)))
+		meta, err = openFreezerFileForAppend(filepath.Join(path, fmt.Sprintf("%s.meta", gen.file(name))))
 		if err != nil {
 			return nil, err
 		}
@@ -170,25 +188,25 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 		if err != nil {
 			return nil, err
 		}
-		meta, err = openFreezerFileForAppend(filepath.Join(path, fmt.Sprintf("%s.meta", name)))
+		meta, err = openFreezerFileForAppend(filepath.Join(path, fmt.Sprintf("%s.meta", gen.file(name))))
 		if err != nil {
 			return nil, err
 		}
 	}
 	// Create the table and repair any past inconsistency
 	tab := &freezerTable{
-		index:         index,
-		meta:          meta,
-		files:         make(map[uint32]*os.File),
-		readMeter:     readMeter,
-		writeMeter:    writeMeter,
-		sizeGauge:     sizeGauge,
-		name:          name,
-		path:          path,
-		logger:        log.New("database", path, "table", name),
-		noCompression: noCompression,
-		readonly:      readonly,
-		maxFileSize:   maxFilesize,
+		index:       index,
+		meta:        meta,
+		files:       make(map[uint32]*os.File),
+		readMeter:   readMeter,
+		writeMeter:  writeMeter,
+		sizeGauge:   sizeGauge,
+		name:        name,
+		path:        path,
+		logger:      log.New("database", path, "table", name),
+		gen:         gen,
+		readonly:    readonly,
+		maxFileSize: maxFilesize,
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
@@ -205,6 +223,30 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 	return tab, nil
 }
 
+// indexFileName returns the name of the index file of a table.
+func indexFileName(name string, compression string) string {
+	switch compression {
+	case FreezerCompressionNone:
+		return fmt.Sprintf("%s.ridx", name) // raw index file
+	case FreezerCompressionZstd:
+		return fmt.Sprintf("%s.zidx", name) // zstd compressed index file
+	default:
+		return fmt.Sprintf("%s.cidx", name) // compressed index file
+	}
+}
+
+// dataFileName returns the name of a data file of a table.
+func dataFileName(name string, num uint32, compression string) string {
+	switch compression {
+	case FreezerCompressionNone:
+		return fmt.Sprintf("%s.%04d.rdat", name, num)
+	case FreezerCompressionZstd:
+		return fmt.Sprintf("%s.%04d.zdat", name, num)
+	default:
+		return fmt.Sprintf("%s.%04d.cdat", name, num)
+	}
+}
+
 // repair cross-checks the head and the index file and truncates them to
 // be in sync with each other after a potential crash / data loss.
 func (t *freezerTable) repair() error {
@@ -588,13 +630,7 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(filepath.Join(t.path, dataFileName(t.gen.file(t.name), num, t.gen.Compression)))
 		if err != nil {
 			return nil, err
 		}
@@ -699,18 +735,32 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 		offset     int // offset for reading
 		outputSize int // size of uncompressed data
 	)
-	// Now slice up the data and decompress.
+	// Now slice up the data, decrypt and decompress.
 	for i, diskSize := range sizes {
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
+		if t.aead != nil {
+			if item, err = encrypted.Open(t.aead, item, t.itemAD(start+uint64(i))); err != nil {
+				return nil, err
+			}
+			diskSize = len(item)
+		}
 		decompressedSize := diskSize
-		if !t.noCompression {
+		switch t.gen.Compression {
+		case FreezerCompressionSnappy:
 			decompressedSize, _ = snappy.DecodedLen(item)
+		case FreezerCompressionZstd:
+			// The size is only known from the frame header, decode right away
+			_, dec := zstdCodec()
+			if item, err = dec.DecodeAll(item, nil); err != nil {
+				return nil, err
+			}
+			decompressedSize = len(item)
 		}
 		if i > 0 && uint64(outputSize+decompressedSize) > maxBytes {
 			break
 		}
-		if !t.noCompression {
+		if t.gen.Compression == FreezerCompressionSnappy {
 			data, err := snappy.Decode(nil, item)
 			if err != nil {
 				return nil, err
@@ -724,6 +774,15 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 	return output, nil
 }
 
+// itemAD returns the additional data authenticated along an encrypted item, which
+// binds it to its position in the table.
+func (t *freezerTable) itemAD(item uint64) []byte {
+	ad := make([]byte, len(t.name)+8)
+	copy(ad, t.name)
+	binary.BigEndian.PutUint64(ad[len(t.name):], item)
+	return ad
+}
+
 // retrieveItems reads up to 'count' items from the table. It reads at least
 // one item, but otherwise avoids reading more than maxBytes bytes.
 // It returns the (potentially compressed) data, and the sizes.
@@ -28,6 +28,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encrypted"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/stretchr/testify/require"
 )
@@ -283,6 +284,64 @@ func TestFreezerReadonlyValidate(t *testing.T) {
 	}
 }
 
+// Tests that an encrypted freezer stores no plain text data and that its items
+// can't be read back with a different key.
+func TestFreezerEncrypted(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		tables = map[string]bool{"raw": true, "snappy": false}
+		key    = bytes.Repeat([]byte{0x01}, encrypted.KeySize)
+		item   = bytes.Repeat([]byte("plaintext"), 32)
+	)
+	aead, _ := encrypted.NewCipher(key)
+	f, err := newFreezer(dir, "", false, 2049, tables, aead)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			if err := op.AppendRaw("raw", i, item); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("snappy", i, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("ModifyAncients failed:", err)
+	}
+	for _, kind := range []string{"raw", "snappy"} {
+		items, err := f.AncientRange(kind, 0, 10, 1<<20)
+		if err != nil {
+			t.Fatalf("failed to read %s items: %v", kind, err)
+		}
+		if len(items) != 10 || !bytes.Equal(items[9], item) {
+			t.Fatalf("wrong %s items read back", kind)
+		}
+	}
+	require.NoError(t, f.Close())
+
+	files, _ := os.ReadDir(dir)
+	for _, file := range files {
+		data, _ := os.ReadFile(path.Join(dir, file.Name()))
+		if bytes.Contains(data, []byte("plaintext")) {
+			t.Fatalf("plain text found in %s", file.Name())
+		}
+	}
+	// Reading with a different key must fail
+	aead, _ = encrypted.NewCipher(bytes.Repeat([]byte{0x02}, encrypted.KeySize))
+	f, err = newFreezer(dir, "", true, 2049, tables, aead)
+	if err != nil {
+		t.Fatal("can't reopen freezer", err)
+	}
+	defer f.Close()
+	if _, err := f.Ancient("raw", 0); !errors.Is(err, encrypted.ErrDecrypt) {
+		t.Fatalf("wrong key error mismatch: %v", err)
+	}
+}
+
 func newFreezerForTesting(t *testing.T, tables map[string]bool) (*Freezer, string) {
 	t.Helper()
 
@@ -0,0 +1,101 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import "github.com/ethereum/go-ethereum/ethdb"
+
+// readonlydb is a database wrapper rejecting all modifications, handed out to
+// code which must not write to the database, e.g. the RPC APIs of read replicas.
+type readonlydb struct {
+	ethdb.Database
+}
+
+// NewReadOnlyDatabase returns a view of the database which can be read from, but
+// which fails any attempt to modify the key-value store or the ancient store.
+func NewReadOnlyDatabase(db ethdb.Database) ethdb.Database {
+	return &readonlydb{db}
+}
+
+// Close is a noop, the wrapped database is closed by its owner.
+func (db *readonlydb) Close() error {
+	return nil
+}
+
+// Put returns an error as the database is read only.
+func (db *readonlydb) Put(key []byte, value []byte) error {
+	return errReadOnly
+}
+
+// Delete returns an error as the database is read only.
+func (db *readonlydb) Delete(key []byte) error {
+	return errReadOnly
+}
+
+// NewBatch returns a batch which can't be written.
+func (db *readonlydb) NewBatch() ethdb.Batch {
+	return readonlyBatch{db.Database.NewBatch()}
+}
+
+// NewBatchWithSize returns a batch which can't be written.
+func (db *readonlydb) NewBatchWithSize(size int) ethdb.Batch {
+	return readonlyBatch{db.Database.NewBatchWithSize(size)}
+}
+
+// Compact returns an error as the database is read only.
+func (db *readonlydb) Compact(start []byte, limit []byte) error {
+	return errReadOnly
+}
+
+// ModifyAncients returns an error as the database is read only.
+func (db *readonlydb) ModifyAncients(func(ethdb.AncientWriteOp) error) (int64, error) {
+	return 0, errReadOnly
+}
+
+// TruncateHead returns an error as the database is read only.
+func (db *readonlydb) TruncateHead(items uint64) error {
+	return errReadOnly
+}
+
+// TruncateTail returns an error as the database is read only.
+func (db *readonlydb) TruncateTail(items uint64) error {
+	return errReadOnly
+}
+
+// MigrateTable returns an error as the database is read only.
+func (db *readonlydb) MigrateTable(kind string, convert convertLegacyFn) error {
+	return errReadOnly
+}
+
+// readonlyBatch is a batch of a read only database, rejecting any write.
+type readonlyBatch struct {
+	ethdb.Batch
+}
+
+// Put returns an error as the database is read only.
+func (b readonlyBatch) Put(key []byte, value []byte) error {
+	return errReadOnly
+}
+
+// Delete returns an error as the database is read only.
+func (b readonlyBatch) Delete(key []byte) error {
+	return errReadOnly
+}
+
+// Write returns an error as the database is read only.
+func (b readonlyBatch) Write() error {
+	return errReadOnly
+}
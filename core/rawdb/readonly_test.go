@@ -0,0 +1,59 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that a read only database view serves the data of the wrapped database,
+// but rejects any modification.
+func TestReadOnlyDatabase(t *testing.T) {
+	db := NewMemoryDatabase()
+	db.Put([]byte("key"), []byte("value"))
+	ro := NewReadOnlyDatabase(db)
+
+	if value, err := ro.Get([]byte("key")); err != nil || !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("read mismatch: have %x, %v", value, err)
+	}
+	if err := ro.Put([]byte("key"), []byte("other")); err != errReadOnly {
+		t.Fatalf("put error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	if err := ro.Delete([]byte("key")); err != errReadOnly {
+		t.Fatalf("delete error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	batch := ro.NewBatch()
+	batch.Put([]byte("key"), []byte("other"))
+	if err := batch.Write(); err != errReadOnly {
+		t.Fatalf("batch error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	if err := ro.Compact(nil, nil); err != errReadOnly {
+		t.Fatalf("compact error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	if _, err := ro.ModifyAncients(func(ethdb.AncientWriteOp) error { return nil }); err != errReadOnly {
+		t.Fatalf("ancient write error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	if err := ro.TruncateHead(0); err != errReadOnly {
+		t.Fatalf("truncation error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	if value, _ := db.Get([]byte("key")); !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("database modified through read only view: %x", value)
+	}
+}
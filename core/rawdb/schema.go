@@ -72,6 +72,14 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// addressLogIndexTailKey tracks the oldest block whose logs have been indexed
+	// by emitting address.
+	addressLogIndexTailKey = []byte("AddressLogIndexTail")
+
+	// internalTxIndexTailKey tracks the oldest block whose internal value transfers
+	// have been indexed.
+	internalTxIndexTailKey = []byte("InternalTxIndexTail")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
@@ -94,6 +102,8 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
+	addressLogPrefix      = []byte("L") // addressLogPrefix + address + num (uint64 big endian) -> empty marker
+	internalTxPrefix      = []byte("T") // internalTxPrefix + address + num (uint64 big endian) + hash + tx index (uint32 big endian) + index (uint32 big endian) -> internal transfer
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
@@ -104,6 +114,9 @@ var (
 	configPrefix   = []byte("ethereum-config-")  // config prefix for the db
 	genesisPrefix  = []byte("ethereum-genesis-") // genesis state prefix for the db
 
+	contractMetaPrefix     = []byte("contract-meta-")     // contractMetaPrefix + code hash -> contract metadata
+	contractSkeletonPrefix = []byte("contract-skeleton-") // contractSkeletonPrefix + skeleton hash -> code hash
+
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 
@@ -166,6 +179,23 @@ func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
 }
 
+// addressLogKey = addressLogPrefix + address + num (uint64 big endian)
+func addressLogKey(address common.Address, number uint64) []byte {
+	return append(append(addressLogPrefix, address.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// internalTxKey = internalTxPrefix + address + num (uint64 big endian) + hash + tx index (uint32 big endian) + index (uint32 big endian)
+func internalTxKey(address common.Address, number uint64, hash common.Hash, txIndex, index uint) []byte {
+	key := make([]byte, len(internalTxPrefix)+common.AddressLength+8+common.HashLength+8)
+	n := copy(key, internalTxPrefix)
+	n += copy(key[n:], address.Bytes())
+	binary.BigEndian.PutUint64(key[n:], number)
+	n += 8 + copy(key[n+8:], hash.Bytes())
+	binary.BigEndian.PutUint32(key[n:], uint32(txIndex))
+	binary.BigEndian.PutUint32(key[n+4:], uint32(index))
+	return key
+}
+
 // accountSnapshotKey = SnapshotAccountPrefix + hash
 func accountSnapshotKey(hash common.Hash) []byte {
 	return append(SnapshotAccountPrefix, hash.Bytes()...)
@@ -215,6 +245,16 @@ func IsCodeKey(key []byte) (bool, []byte) {
 	return false, nil
 }
 
+// contractMetaKey = contractMetaPrefix + code hash
+func contractMetaKey(hash common.Hash) []byte {
+	return append(contractMetaPrefix, hash.Bytes()...)
+}
+
+// contractSkeletonKey = contractSkeletonPrefix + skeleton hash
+func contractSkeletonKey(hash common.Hash) []byte {
+	return append(contractSkeletonPrefix, hash.Bytes()...)
+}
+
 // configKey = configPrefix + hash
 func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
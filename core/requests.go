@@ -0,0 +1,174 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// depositEventTopic is the topic of the DepositEvent logs of the deposit contract:
+// DepositEvent(bytes pubkey, bytes withdrawal_credentials, bytes amount,
+// bytes signature, bytes index).
+var depositEventTopic = common.HexToHash("0x649bbc62d0e31342afea4e5cd82d4049e7e1ee912fc0889aa790803be39038c5")
+
+// depositLogSize is the size of the ABI encoded data of deposit logs: the offsets
+// of the five fields, followed by each field as length and padded content.
+const depositLogSize = 576
+
+var errInvalidDepositLog = errors.New("invalid deposit log")
+
+// ProcessRequests collects the requests of a block after its transactions have
+// been applied (EIP-7685): the deposits logged by the deposit contract
+// (EIP-6110), followed by the requests dequeued by system calls to the withdrawal
+// (EIP-7002) and consolidation (EIP-7251) request contracts.
+//
+// The returned list is never nil, distinguishing blocks without requests from
+// the ones preceding Prague.
+func ProcessRequests(config *params.ChainConfig, evm *vm.EVM, statedb *state.StateDB, receipts types.Receipts) (types.Requests, error) {
+	requests := make(types.Requests, 0)
+	if config.DepositContractAddress != nil {
+		deposits, err := ParseDepositLogs(receipts, *config.DepositContractAddress)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, deposits...)
+	}
+	withdrawals, err := processRequestQueue(evm, statedb, params.WithdrawalQueueAddress, types.WithdrawalRequestType)
+	if err != nil {
+		return nil, err
+	}
+	requests = append(requests, withdrawals...)
+
+	consolidations, err := processRequestQueue(evm, statedb, params.ConsolidationQueueAddress, types.ConsolidationRequestType)
+	if err != nil {
+		return nil, err
+	}
+	return append(requests, consolidations...), nil
+}
+
+// ParseDepositLogs extracts the deposit requests from the logs emitted by the
+// deposit contract at the given address.
+func ParseDepositLogs(receipts types.Receipts, contract common.Address) (types.Requests, error) {
+	var deposits types.Requests
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if log.Address != contract || len(log.Topics) == 0 || log.Topics[0] != depositEventTopic {
+				continue
+			}
+			deposit, err := unpackDepositLog(log.Data)
+			if err != nil {
+				return nil, fmt.Errorf("tx %x: %w", log.TxHash, err)
+			}
+			deposits = append(deposits, types.NewRequest(deposit))
+		}
+	}
+	return deposits, nil
+}
+
+// unpackDepositLog decodes the ABI encoded data of a DepositEvent log.
+func unpackDepositLog(data []byte) (*types.Deposit, error) {
+	if len(data) != depositLogSize {
+		return nil, fmt.Errorf("%w: size %d, want %d", errInvalidDepositLog, len(data), depositLogSize)
+	}
+	// field returns the content of the i'th bytes field, checking its size
+	field := func(i int, size uint64) ([]byte, error) {
+		offset := new(big.Int).SetBytes(data[i*32 : (i+1)*32])
+		if !offset.IsUint64() || offset.Uint64()+32 > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: field %d offset out of bounds", errInvalidDepositLog, i)
+		}
+		start := offset.Uint64()
+		length := new(big.Int).SetBytes(data[start : start+32])
+		if !length.IsUint64() || length.Uint64() != size {
+			return nil, fmt.Errorf("%w: field %d has size %v, want %d", errInvalidDepositLog, i, length, size)
+		}
+		if start+32+size > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: field %d out of bounds", errInvalidDepositLog, i)
+		}
+		return data[start+32 : start+32+size], nil
+	}
+	var (
+		deposit = new(types.Deposit)
+		fields  = make([][]byte, 5)
+		sizes   = []uint64{48, 32, 8, 96, 8}
+		err     error
+	)
+	for i, size := range sizes {
+		if fields[i], err = field(i, size); err != nil {
+			return nil, err
+		}
+	}
+	copy(deposit.PublicKey[:], fields[0])
+	copy(deposit.WithdrawalCredentials[:], fields[1])
+	deposit.Amount = binary.LittleEndian.Uint64(fields[2])
+	copy(deposit.Signature[:], fields[3])
+	deposit.Index = binary.LittleEndian.Uint64(fields[4])
+	return deposit, nil
+}
+
+// processRequestQueue dequeues the requests of the given type from a request
+// predeploy contract by calling it from the system address. The contract returns
+// the flat encodings of the requests, concatenated.
+func processRequestQueue(evm *vm.EVM, statedb *state.StateDB, contract common.Address, typ byte) (types.Requests, error) {
+	if statedb.GetCodeSize(contract) == 0 {
+		return nil, fmt.Errorf("request contract %x not deployed", contract)
+	}
+	evm.Reset(vm.TxContext{Origin: params.SystemAddress, GasPrice: new(big.Int)}, statedb)
+	statedb.AddAddressToAccessList(contract)
+
+	ret, _, err := evm.Call(vm.AccountRef(params.SystemAddress), contract, nil, params.SystemCallGas, new(big.Int))
+	statedb.Finalise(true)
+	if err != nil {
+		return nil, fmt.Errorf("request contract %x failed: %v", contract, err)
+	}
+	var (
+		requests types.Requests
+		size     = requestSize(typ)
+	)
+	if len(ret)%size != 0 {
+		return nil, fmt.Errorf("request contract %x returned %d bytes, not a multiple of %d", contract, len(ret), size)
+	}
+	for i := 0; i < len(ret); i += size {
+		request := new(types.Request)
+		if err := request.UnmarshalBinary(append([]byte{typ}, ret[i:i+size]...)); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// requestSize returns the size of the flat encoding of the requests of the given
+// type returned by the request contracts.
+func requestSize(typ byte) int {
+	switch typ {
+	case types.WithdrawalRequestType:
+		return types.WithdrawalRequestSize
+	case types.ConsolidationRequestType:
+		return types.ConsolidationRequestSize
+	default:
+		panic(fmt.Sprintf("no request contract for request type %d", typ))
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// depositLogData returns the ABI encoding of a DepositEvent of the deposit.
+func depositLogData(d *types.Deposit) []byte {
+	var (
+		data   = make([]byte, depositLogSize)
+		amount = make([]byte, 8)
+		index  = make([]byte, 8)
+	)
+	binary.LittleEndian.PutUint64(amount, d.Amount)
+	binary.LittleEndian.PutUint64(index, d.Index)
+
+	offset := 5 * 32
+	for i, field := range [][]byte{d.PublicKey[:], d.WithdrawalCredentials[:], amount, d.Signature[:], index} {
+		new(big.Int).SetUint64(uint64(offset)).FillBytes(data[i*32 : (i+1)*32])
+		new(big.Int).SetUint64(uint64(len(field))).FillBytes(data[offset : offset+32])
+		copy(data[offset+32:], field)
+		offset += 32 + (len(field)+31)/32*32
+	}
+	return data
+}
+
+func TestParseDepositLogs(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa")
+		deposit  = &types.Deposit{
+			PublicKey:             [48]byte{1, 2, 3},
+			WithdrawalCredentials: common.Hash{4, 5, 6},
+			Amount:                32_000_000_000,
+			Signature:             [96]byte{7, 8, 9},
+			Index:                 42,
+		}
+		data = depositLogData(deposit)
+	)
+	receipts := types.Receipts{{Logs: []*types.Log{
+		{Address: contract, Topics: []common.Hash{depositEventTopic}, Data: data},
+		{Address: common.Address{1}, Topics: []common.Hash{depositEventTopic}, Data: data}, // other contract
+		{Address: contract, Topics: []common.Hash{{1}}, Data: data},                        // other event
+	}}}
+	requests, err := ParseDepositLogs(receipts, contract)
+	if err != nil {
+		t.Fatalf("failed to parse deposit logs: %v", err)
+	}
+	if want := (types.Requests{types.NewRequest(deposit)}); !reflect.DeepEqual(requests, want) {
+		t.Fatalf("wrong deposits: have %v, want %v", requests, want)
+	}
+	// Malformed deposit logs must be rejected
+	short := *receipts[0].Logs[0]
+	short.Data = data[:len(data)-1]
+	if _, err := ParseDepositLogs(types.Receipts{{Logs: []*types.Log{&short}}}, contract); err == nil {
+		t.Error("no error for short deposit log")
+	}
+	bad := *receipts[0].Logs[0]
+	bad.Data = common.CopyBytes(data)
+	bad.Data[31] = 0xff // offset of the public key out of bounds
+	if _, err := ParseDepositLogs(types.Receipts{{Logs: []*types.Log{&bad}}}, contract); err == nil {
+		t.Error("no error for invalid deposit log offset")
+	}
+}
+
+// TestPragueRequests checks that the requests of a chain transitioning to Prague
+// are collected from the deposit logs and the request contracts, and validated
+// on import.
+func TestPragueRequests(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa")
+		config   = *params.TestChainConfig
+		engine   = ethash.NewFaker()
+		db       = rawdb.NewMemoryDatabase()
+
+		deposit    = &types.Deposit{PublicKey: [48]byte{1}, Amount: 32_000_000_000, Index: 1}
+		withdrawal = &types.WithdrawalRequest{SourceAddress: address, ValidatorPubkey: [48]byte{2}, Amount: 1}
+	)
+	config.PragueBlock = big.NewInt(2)
+	config.DepositContractAddress = &contract
+
+	// The deposit contract logs its calldata, the withdrawal request contract
+	// always returns the same request and the consolidation one none
+	depositCode := append([]byte{
+		byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLDATACOPY),
+		byte(vm.PUSH32)}, depositEventTopic[:]...)
+	depositCode = append(depositCode, byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0, byte(vm.LOG1), byte(vm.STOP))
+
+	withdrawalData, _ := types.NewRequest(withdrawal).MarshalBinary()
+	withdrawalCode := append([]byte{
+		byte(vm.PUSH1), types.WithdrawalRequestSize, byte(vm.PUSH1), 12, byte(vm.PUSH1), 0, byte(vm.CODECOPY),
+		byte(vm.PUSH1), types.WithdrawalRequestSize, byte(vm.PUSH1), 0, byte(vm.RETURN)}, withdrawalData[1:]...)
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc: GenesisAlloc{
+			address:                          {Balance: big.NewInt(1000000000000000)},
+			contract:                         {Code: depositCode, Balance: common.Big0},
+			params.WithdrawalQueueAddress:    {Code: withdrawalCode, Balance: common.Big0},
+			params.ConsolidationQueueAddress: {Code: []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.RETURN)}, Balance: common.Big0},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 3, func(i int, b *BlockGen) {
+		if i != 1 {
+			return
+		}
+		signer := types.LatestSigner(gspec.Config)
+		tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    b.TxNonce(address),
+			To:       &contract,
+			Gas:      100000,
+			GasPrice: b.header.BaseFee,
+			Data:     depositLogData(deposit),
+		})
+		b.AddTx(tx)
+	})
+	if blocks[0].Header().RequestsHash != nil {
+		t.Fatal("pre-Prague block has requests hash")
+	}
+	want := []types.Requests{
+		{types.NewRequest(deposit), types.NewRequest(withdrawal)},
+		{types.NewRequest(withdrawal)},
+	}
+	for i, block := range blocks[1:] {
+		if hash := block.Header().RequestsHash; hash == nil || *hash != want[i].Hash() {
+			t.Fatalf("block %d: wrong requests hash: have %v, want %x", block.NumberU64(), hash, want[i].Hash())
+		}
+	}
+	diskdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(diskdb)
+
+	chain, err := NewBlockChain(diskdb, nil, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks[:2]); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	// Prague blocks without the Cancun header fields must be rejected
+	header := blocks[2].Header()
+	header.ParentBeaconRoot = nil
+	if err := chain.Validator().ValidateBody(blocks[2].WithSeal(header)); err == nil {
+		t.Fatal("no error for Prague block without parent beacon root")
+	}
+	// Blocks committing to other requests must be rejected
+	header = blocks[2].Header()
+	forged := (types.Requests{types.NewRequest(deposit)}).Hash()
+	header.RequestsHash = &forged
+	if _, err := chain.InsertChain(types.Blocks{blocks[2].WithSeal(header)}); err == nil {
+		t.Fatal("no error for block with forged requests hash")
+	}
+	if n, err := chain.InsertChain(blocks[2:]); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n+2, err)
+	}
+}
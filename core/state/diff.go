@@ -0,0 +1,241 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// StateDiff is the difference between two states. Accounts are ordered by the
+// hash of their address.
+type StateDiff struct {
+	Created  []*AccountDiff `json:"created"`
+	Deleted  []*AccountDiff `json:"deleted"`
+	Modified []*AccountDiff `json:"modified"`
+}
+
+// AccountDiff is the difference of an account between two states. Before is
+// nil for created accounts, After is nil for deleted ones.
+type AccountDiff struct {
+	AddressHash common.Hash     `json:"addressHash"`
+	Address     *common.Address `json:"address,omitempty"` // Set if the preimage is known
+	Before      *DiffAccount    `json:"before,omitempty"`
+	After       *DiffAccount    `json:"after,omitempty"`
+	Storage     []*SlotDiff     `json:"storage,omitempty"`
+}
+
+// DiffAccount is the content of an account in a state diff.
+type DiffAccount struct {
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	Balance  *hexutil.Big   `json:"balance"`
+	Root     common.Hash    `json:"root"`
+	CodeHash common.Hash    `json:"codeHash"`
+}
+
+// SlotDiff is the difference of a storage slot between two states. Slots don't
+// exist while their value is zero, so created slots have a zero Before value and
+// deleted ones a zero After value.
+type SlotDiff struct {
+	KeyHash common.Hash  `json:"keyHash"`
+	Key     *common.Hash `json:"key,omitempty"` // Set if the preimage is known
+	Before  common.Hash  `json:"before"`
+	After   common.Hash  `json:"after"`
+}
+
+// DiffAt returns the accounts and storage slots which differ between the states
+// with roots rootA and rootB. Only the parts of the tries which differ are
+// visited, so the cost depends on the size of the difference rather than on
+// the size of the states.
+//
+// Addresses and slot keys are resolved if the database recorded their
+// preimages.
+func DiffAt(db Database, rootA, rootB common.Hash) (*StateDiff, error) {
+	triedb := db.TrieDB()
+	trA, err := trie.New(common.Hash{}, rootA, triedb)
+	if err != nil {
+		return nil, err
+	}
+	trB, err := trie.New(common.Hash{}, rootB, triedb)
+	if err != nil {
+		return nil, err
+	}
+	// The preimage lookup doesn't depend on the trie content
+	preimages, err := trie.NewStateTrie(common.Hash{}, common.Hash{}, triedb)
+	if err != nil {
+		return nil, err
+	}
+	diff := &StateDiff{
+		Created:  []*AccountDiff{},
+		Deleted:  []*AccountDiff{},
+		Modified: []*AccountDiff{},
+	}
+	err = diffTries(trA, trB, func(hash, blobA, blobB []byte) error {
+		acc := &AccountDiff{AddressHash: common.BytesToHash(hash)}
+		if key := preimages.GetKey(hash); key != nil {
+			addr := common.BytesToAddress(key)
+			acc.Address = &addr
+		}
+		var err error
+		if acc.Before, err = decodeDiffAccount(blobA); err != nil {
+			return err
+		}
+		if acc.After, err = decodeDiffAccount(blobB); err != nil {
+			return err
+		}
+		storageA, storageB := emptyRoot, emptyRoot
+		if acc.Before != nil {
+			storageA = acc.Before.Root
+		}
+		if acc.After != nil {
+			storageB = acc.After.Root
+		}
+		if storageA != storageB {
+			if acc.Storage, err = diffStorage(triedb, preimages, acc.AddressHash, storageA, storageB); err != nil {
+				return err
+			}
+		}
+		switch {
+		case acc.Before == nil:
+			diff.Created = append(diff.Created, acc)
+		case acc.After == nil:
+			diff.Deleted = append(diff.Deleted, acc)
+		default:
+			diff.Modified = append(diff.Modified, acc)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// diffStorage returns the slots which differ between two storage tries of an
+// account, ordered by the hash of their key.
+func diffStorage(triedb *trie.Database, preimages *trie.StateTrie, addrHash, rootA, rootB common.Hash) ([]*SlotDiff, error) {
+	trA, err := trie.New(addrHash, rootA, triedb)
+	if err != nil {
+		return nil, err
+	}
+	trB, err := trie.New(addrHash, rootB, triedb)
+	if err != nil {
+		return nil, err
+	}
+	var slots []*SlotDiff
+	err = diffTries(trA, trB, func(hash, blobA, blobB []byte) error {
+		slot := &SlotDiff{KeyHash: common.BytesToHash(hash)}
+		if key := preimages.GetKey(hash); key != nil {
+			k := common.BytesToHash(key)
+			slot.Key = &k
+		}
+		var err error
+		if slot.Before, err = decodeDiffSlot(blobA); err != nil {
+			return err
+		}
+		if slot.After, err = decodeDiffSlot(blobB); err != nil {
+			return err
+		}
+		slots = append(slots, slot)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return bytes.Compare(slots[i].KeyHash[:], slots[j].KeyHash[:]) < 0
+	})
+	return slots, nil
+}
+
+// diffTries calls fn for every key whose value differs between the tries, with
+// a nil value if the key is missing from one of them. Keys present in b are
+// visited first, followed by the keys only present in a.
+func diffTries(a, b *trie.Trie, fn func(key, valueA, valueB []byte) error) error {
+	// Added and changed keys are the leaves of b which are not in a
+	it, _ := trie.NewDifferenceIterator(a.NodeIterator(nil), b.NodeIterator(nil))
+	iter := trie.NewIterator(it)
+	for iter.Next() {
+		valueA, err := a.TryGet(iter.Key)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(valueA, iter.Value) {
+			continue // The leaf only moved within the trie
+		}
+		if err := fn(iter.Key, valueA, iter.Value); err != nil {
+			return err
+		}
+	}
+	if iter.Err != nil {
+		return iter.Err
+	}
+	// Deleted keys are the leaves of a which are missing in b
+	it, _ = trie.NewDifferenceIterator(b.NodeIterator(nil), a.NodeIterator(nil))
+	iter = trie.NewIterator(it)
+	for iter.Next() {
+		valueB, err := b.TryGet(iter.Key)
+		if err != nil {
+			return err
+		}
+		if valueB != nil {
+			continue // Changed or moved, visited before
+		}
+		if err := fn(iter.Key, iter.Value, nil); err != nil {
+			return err
+		}
+	}
+	return iter.Err
+}
+
+// decodeDiffAccount decodes an account of the account trie, returning nil for
+// missing accounts.
+func decodeDiffAccount(blob []byte) (*DiffAccount, error) {
+	if blob == nil {
+		return nil, nil
+	}
+	var acc types.StateAccount
+	if err := rlp.DecodeBytes(blob, &acc); err != nil {
+		return nil, fmt.Errorf("invalid account: %v", err)
+	}
+	return &DiffAccount{
+		Nonce:    hexutil.Uint64(acc.Nonce),
+		Balance:  (*hexutil.Big)(acc.Balance),
+		Root:     acc.Root,
+		CodeHash: common.BytesToHash(acc.CodeHash),
+	}, nil
+}
+
+// decodeDiffSlot decodes a value of a storage trie, which is zero for missing
+// slots.
+func decodeDiffSlot(blob []byte) (common.Hash, error) {
+	if blob == nil {
+		return common.Hash{}, nil
+	}
+	_, content, _, err := rlp.Split(blob)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid storage slot: %v", err)
+	}
+	return common.BytesToHash(content), nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestDiffAt(t *testing.T) {
+	var (
+		db      = NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), &trie.Config{Preimages: true})
+		addrs   = []common.Address{{1}, {2}, {3}, {4}}
+		created = common.Address{5}
+		slot1   = common.Hash{1}
+		slot2   = common.Hash{2}
+		one     = common.Hash{31: 1}
+		two     = common.Hash{31: 2}
+	)
+	commit := func(statedb *StateDB) common.Hash {
+		root, err := statedb.Commit(true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.TrieDB().Commit(root, false, nil); err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+	statedb, _ := New(common.Hash{}, db, nil)
+	for i, addr := range addrs {
+		statedb.SetBalance(addr, big.NewInt(int64(i+1)))
+	}
+	statedb.SetState(addrs[1], slot1, one)
+	statedb.SetState(addrs[1], slot2, one)
+	rootA := commit(statedb)
+
+	statedb, _ = New(rootA, db, nil)
+	statedb.SetNonce(addrs[0], 1)
+	statedb.SetState(addrs[1], slot1, two)
+	statedb.SetState(addrs[1], slot2, common.Hash{})
+	statedb.SetState(addrs[1], common.Hash{3}, one)
+	statedb.Suicide(addrs[2])
+	statedb.SetBalance(created, big.NewInt(10))
+	rootB := commit(statedb)
+
+	diff, err := DiffAt(db, rootA, rootB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Created) != 1 || *diff.Created[0].Address != created || diff.Created[0].After.Balance.ToInt().Int64() != 10 {
+		t.Errorf("wrong created accounts: %+v", diff.Created)
+	}
+	if len(diff.Deleted) != 1 || *diff.Deleted[0].Address != addrs[2] || diff.Deleted[0].Before.Balance.ToInt().Int64() != 3 {
+		t.Errorf("wrong deleted accounts: %+v", diff.Deleted)
+	}
+	if len(diff.Modified) != 2 {
+		t.Fatalf("wrong number of modified accounts: have %d, want 2", len(diff.Modified))
+	}
+	modified := make(map[common.Address]*AccountDiff)
+	for _, acc := range diff.Modified {
+		if acc.AddressHash != crypto.Keccak256Hash(acc.Address[:]) {
+			t.Errorf("address hash mismatch for %x", acc.Address)
+		}
+		modified[*acc.Address] = acc
+	}
+	if acc := modified[addrs[0]]; acc == nil || acc.Before.Nonce != 0 || acc.After.Nonce != 1 || len(acc.Storage) != 0 {
+		t.Errorf("wrong diff of nonce change: %+v", acc)
+	}
+	acc := modified[addrs[1]]
+	if acc == nil || len(acc.Storage) != 3 {
+		t.Fatalf("wrong diff of storage change: %+v", acc)
+	}
+	slots := make(map[common.Hash]*SlotDiff)
+	for _, slot := range acc.Storage {
+		slots[*slot.Key] = slot
+	}
+	for key, want := range map[common.Hash][2]common.Hash{slot1: {one, two}, slot2: {one, {}}, {3}: {{}, one}} {
+		if slot := slots[key]; slot == nil || slot.Before != want[0] || slot.After != want[1] {
+			t.Errorf("slot %x: wrong diff %+v", key, slot)
+		}
+	}
+	// Diffing the other way around reverses the result
+	reverse, err := DiffAt(db, rootB, rootA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverse.Created) != 1 || *reverse.Created[0].Address != addrs[2] {
+		t.Errorf("wrong created accounts in reverse diff: %+v", reverse.Created)
+	}
+	if len(reverse.Deleted) != 1 || *reverse.Deleted[0].Address != created {
+		t.Errorf("wrong deleted accounts in reverse diff: %+v", reverse.Deleted)
+	}
+	// Identical states have no difference
+	if same, err := DiffAt(db, rootA, rootA); err != nil || len(same.Created)+len(same.Deleted)+len(same.Modified) != 0 {
+		t.Errorf("non-empty diff of identical states: %+v (%v)", same, err)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// StateReader provides read access to the world state. It is the minimal view
+// of the state an EVM implementation needs to execute transactions, allowing
+// them to run against state kept elsewhere, e.g. in a remote node.
+//
+// Accessors don't return errors, like StateDB. Implementations reading from a
+// backing store remember the first failure and report it in Error.
+type StateReader interface {
+	// Exist reports whether the account exists in the state. Suicided accounts
+	// still exist until the state is finalised.
+	Exist(addr common.Address) bool
+
+	// Empty reports whether the account is non-existent or empty, according to
+	// the EIP-161 definition (balance = nonce = code = 0).
+	Empty(addr common.Address) bool
+
+	GetBalance(addr common.Address) *big.Int
+	GetNonce(addr common.Address) uint64
+	GetCode(addr common.Address) []byte
+	GetCodeHash(addr common.Address) common.Hash
+	GetState(addr common.Address, key common.Hash) common.Hash
+
+	// Error returns the first failure of the backing store, if any.
+	Error() error
+}
+
+// StateWriter provides write access to the world state. Modifications are
+// visible through the StateReader of the same state.
+type StateWriter interface {
+	SetBalance(addr common.Address, amount *big.Int)
+	SetNonce(addr common.Address, nonce uint64)
+	SetCode(addr common.Address, code []byte)
+	SetState(addr common.Address, key, value common.Hash)
+
+	// Suicide marks the account as suicided, clearing its balance. It returns
+	// false if the account doesn't exist.
+	Suicide(addr common.Address) bool
+
+	// Snapshot returns an identifier for the current revision of the state,
+	// which RevertToSnapshot reverts to. Finalising the state invalidates all
+	// revisions.
+	Snapshot() int
+	RevertToSnapshot(revid int)
+}
+
+var (
+	_ StateReader = (*StateDB)(nil)
+	_ StateWriter = (*StateDB)(nil)
+)
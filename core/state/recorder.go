@@ -0,0 +1,99 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RecordedAccount is the value of an account as it was at its first access
+// during a recording, along with the storage slots that have been accessed.
+type RecordedAccount struct {
+	Balance *big.Int
+	Nonce   uint64
+	Code    []byte
+	Storage map[common.Hash]common.Hash
+}
+
+// accessRecorder tracks the first access of every account and storage slot,
+// capturing the values they held before being touched by the execution.
+type accessRecorder struct {
+	accounts map[common.Address]*RecordedAccount
+	absent   map[common.Address]struct{} // Accounts that didn't exist at first access
+}
+
+// StartRecording makes the state remember the value of every account and
+// storage slot at its first access from now on, until StopRecording is called.
+// Starting the recording at a transaction boundary captures the exact subset
+// of the state needed to re-execute the transaction on its own.
+//
+// Note, the recorder is not carried over to copies of the state.
+func (s *StateDB) StartRecording() {
+	s.recorder = &accessRecorder{
+		accounts: make(map[common.Address]*RecordedAccount),
+		absent:   make(map[common.Address]struct{}),
+	}
+}
+
+// StopRecording stops tracking state accesses and returns the recorded values.
+// Accounts which didn't exist at the time of their first access are omitted.
+func (s *StateDB) StopRecording() map[common.Address]*RecordedAccount {
+	if s.recorder == nil {
+		return nil
+	}
+	accounts := s.recorder.accounts
+	s.recorder = nil
+	return accounts
+}
+
+// recordAccount captures the current value of an account if this is its first
+// access since the recording started. A nil or deleted object is recorded as
+// non-existent.
+func (s *StateDB) recordAccount(addr common.Address, obj *stateObject) {
+	r := s.recorder
+	if _, ok := r.accounts[addr]; ok {
+		return
+	}
+	if _, ok := r.absent[addr]; ok {
+		return
+	}
+	if obj == nil || obj.deleted {
+		r.absent[addr] = struct{}{}
+		return
+	}
+	r.accounts[addr] = &RecordedAccount{
+		Balance: new(big.Int).Set(obj.Balance()),
+		Nonce:   obj.Nonce(),
+		Code:    common.CopyBytes(obj.Code(s.db)),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// recordSlot captures the value of a storage slot if this is its first access
+// since the recording started. Slots of accounts that were created during the
+// recording are not tracked, their pre-value being empty by definition.
+func (s *StateDB) recordSlot(addr common.Address, key common.Hash, value common.Hash) {
+	account := s.recorder.accounts[addr]
+	if account == nil {
+		return
+	}
+	if _, ok := account.Storage[key]; !ok {
+		account.Storage[key] = value
+	}
+}
@@ -0,0 +1,398 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remote
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/internal/grpcwire"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Client is a connection to a state server.
+type Client struct {
+	client *grpcwire.Client
+}
+
+// Dial connects to the state server at the given grpc:// or grpcs:// endpoint.
+func Dial(endpoint string) (*Client, error) {
+	client, err := grpcwire.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{client: client}, nil
+}
+
+// Open opens the state with the given root on the server, or the state of its
+// current head block if the root is empty.
+func (c *Client) Open(ctx context.Context, root common.Hash) (*State, error) {
+	var req []byte
+	if root != (common.Hash{}) {
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, root[:])
+	}
+	res, err := c.client.Call(ctx, "/"+service+"/OpenState", req)
+	if err != nil {
+		return nil, err
+	}
+	id, err := decodeSessionID(res)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = decodeHash(res, 2, false); err != nil {
+		return nil, err
+	}
+	return &State{
+		client:   c.client,
+		session:  id,
+		root:     root,
+		accounts: make(map[common.Address]*account),
+		code:     make(map[common.Address][]byte),
+		storage:  make(map[common.Address]map[common.Hash]common.Hash),
+	}, nil
+}
+
+// account is the cached account data of a remote state.
+type account struct {
+	exists   bool
+	empty    bool
+	balance  *big.Int
+	nonce    uint64
+	codeHash common.Hash
+}
+
+// State is a state opened on a state server. It implements state.StateReader
+// and state.StateWriter, modifying the copy of the state held by the server.
+// Reads are cached, so a State must not be shared with other clients of the
+// same session.
+//
+// Like StateDB, the methods don't return errors. The first failure is kept and
+// returned by Error, the state shouldn't be used further afterwards.
+type State struct {
+	client  *grpcwire.Client
+	session sessionID
+	root    common.Hash
+	err     error
+
+	accounts map[common.Address]*account
+	code     map[common.Address][]byte
+	storage  map[common.Address]map[common.Hash]common.Hash
+}
+
+var (
+	_ state.StateReader = (*State)(nil)
+	_ state.StateWriter = (*State)(nil)
+)
+
+// Root returns the root of the opened state.
+func (s *State) Root() common.Hash {
+	return s.root
+}
+
+// Error returns the first failure of the state.
+func (s *State) Error() error {
+	return s.err
+}
+
+// Exist reports whether the account exists in the state.
+func (s *State) Exist(addr common.Address) bool {
+	return s.account(addr).exists
+}
+
+// Empty reports whether the account is non-existent or empty.
+func (s *State) Empty(addr common.Address) bool {
+	return s.account(addr).empty
+}
+
+// GetBalance returns the balance of the account.
+func (s *State) GetBalance(addr common.Address) *big.Int {
+	return new(big.Int).Set(s.account(addr).balance)
+}
+
+// GetNonce returns the nonce of the account.
+func (s *State) GetNonce(addr common.Address) uint64 {
+	return s.account(addr).nonce
+}
+
+// GetCodeHash returns the code hash of the account.
+func (s *State) GetCodeHash(addr common.Address) common.Hash {
+	return s.account(addr).codeHash
+}
+
+// GetCode returns the code of the account.
+func (s *State) GetCode(addr common.Address) []byte {
+	if code, ok := s.code[addr]; ok {
+		return code
+	}
+	res, err := s.call("GetCode", s.accountRequest(addr))
+	if err != nil {
+		return nil
+	}
+	codes, err := grpcwire.DecodeBytes(res, 1)
+	if err != nil {
+		s.setError(err)
+		return nil
+	}
+	var code []byte
+	if len(codes) > 0 {
+		code = common.CopyBytes(codes[len(codes)-1])
+	}
+	s.code[addr] = code
+	return code
+}
+
+// GetState returns the value of a storage slot of the account.
+func (s *State) GetState(addr common.Address, key common.Hash) common.Hash {
+	if value, ok := s.storage[addr][key]; ok {
+		return value
+	}
+	req := s.accountRequest(addr)
+	req = protowire.AppendTag(req, 3, protowire.BytesType)
+	req = protowire.AppendBytes(req, key[:])
+	res, err := s.call("GetStorage", req)
+	if err != nil {
+		return common.Hash{}
+	}
+	value, err := decodeHash(res, 1, false)
+	if err != nil {
+		s.setError(err)
+		return common.Hash{}
+	}
+	s.cacheStorage(addr, key, value)
+	return value
+}
+
+// SetBalance sets the balance of the account.
+func (s *State) SetBalance(addr common.Address, amount *big.Int) {
+	req := s.accountRequest(addr)
+	req = protowire.AppendTag(req, 3, protowire.BytesType)
+	req = protowire.AppendBytes(req, amount.Bytes())
+	s.update("SetBalance", addr, req)
+}
+
+// SetNonce sets the nonce of the account.
+func (s *State) SetNonce(addr common.Address, nonce uint64) {
+	req := s.accountRequest(addr)
+	req = protowire.AppendTag(req, 3, protowire.VarintType)
+	req = protowire.AppendVarint(req, nonce)
+	s.update("SetNonce", addr, req)
+}
+
+// SetCode sets the code of the account.
+func (s *State) SetCode(addr common.Address, code []byte) {
+	req := s.accountRequest(addr)
+	req = protowire.AppendTag(req, 3, protowire.BytesType)
+	req = protowire.AppendBytes(req, code)
+	s.update("SetCode", addr, req)
+	delete(s.code, addr)
+}
+
+// SetState sets the value of a storage slot of the account.
+func (s *State) SetState(addr common.Address, key, value common.Hash) {
+	req := s.accountRequest(addr)
+	req = protowire.AppendTag(req, 3, protowire.BytesType)
+	req = protowire.AppendBytes(req, key[:])
+	req = protowire.AppendTag(req, 4, protowire.BytesType)
+	req = protowire.AppendBytes(req, value[:])
+	if s.update("SetStorage", addr, req) {
+		s.cacheStorage(addr, key, value)
+	}
+}
+
+// Suicide marks the account as suicided, returning whether it existed.
+func (s *State) Suicide(addr common.Address) bool {
+	res, err := s.call("Suicide", s.accountRequest(addr))
+	delete(s.accounts, addr)
+	if err != nil {
+		return false
+	}
+	existed, err := grpcwire.DecodeUint(res, 1)
+	if err != nil {
+		s.setError(err)
+		return false
+	}
+	return existed != 0
+}
+
+// IntermediateRoot finalises the modifications made so far and returns the
+// resulting state root. Empty accounts are deleted if deleteEmpty is set.
+func (s *State) IntermediateRoot(deleteEmpty bool) (common.Hash, error) {
+	req := s.sessionRequest()
+	req = protowire.AppendTag(req, 2, protowire.VarintType)
+	req = protowire.AppendVarint(req, protowire.EncodeBool(deleteEmpty))
+	res, err := s.call("IntermediateRoot", req)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// Finalisation clears the storage of suicided accounts and may delete
+	// empty ones, drop all cached data.
+	s.dropCaches()
+
+	root, err := decodeHash(res, 1, false)
+	if err != nil {
+		s.setError(err)
+	}
+	return root, err
+}
+
+// Snapshot returns an identifier for the current revision of the state. It
+// returns -1 if the state failed.
+func (s *State) Snapshot() int {
+	res, err := s.call("Snapshot", s.sessionRequest())
+	if err != nil {
+		return -1
+	}
+	revid, err := grpcwire.DecodeUint(res, 1)
+	if err != nil {
+		s.setError(err)
+		return -1
+	}
+	return int(revid)
+}
+
+// RevertToSnapshot reverts all modifications made since the given revision.
+// Reverting to an unknown revision fails the state.
+func (s *State) RevertToSnapshot(revid int) {
+	if revid < 0 {
+		s.setError(errors.New("invalid snapshot"))
+		return
+	}
+	req := s.sessionRequest()
+	req = protowire.AppendTag(req, 2, protowire.VarintType)
+	req = protowire.AppendVarint(req, uint64(revid))
+	s.call("RevertToSnapshot", req)
+	s.dropCaches()
+}
+
+// Close closes the session, discarding all modifications.
+func (s *State) Close() error {
+	_, err := s.client.Call(context.Background(), "/"+service+"/CloseState", s.sessionRequest())
+	return err
+}
+
+// dropCaches discards all cached reads.
+func (s *State) dropCaches() {
+	s.accounts = make(map[common.Address]*account)
+	s.code = make(map[common.Address][]byte)
+	s.storage = make(map[common.Address]map[common.Hash]common.Hash)
+}
+
+// account returns the cached data of the account, retrieving it if needed.
+func (s *State) account(addr common.Address) *account {
+	if acc, ok := s.accounts[addr]; ok {
+		return acc
+	}
+	acc := &account{balance: new(big.Int)}
+	res, err := s.call("GetAccount", s.accountRequest(addr))
+	if err != nil {
+		return acc
+	}
+	if err := decodeAccount(res, acc); err != nil {
+		s.setError(err)
+		return &account{balance: new(big.Int)}
+	}
+	s.accounts[addr] = acc
+	return acc
+}
+
+// update sends an account modification, dropping the cached account data. It
+// returns whether the modification succeeded.
+func (s *State) update(method string, addr common.Address, req []byte) bool {
+	delete(s.accounts, addr)
+	_, err := s.call(method, req)
+	return err == nil
+}
+
+func (s *State) cacheStorage(addr common.Address, key, value common.Hash) {
+	slots := s.storage[addr]
+	if slots == nil {
+		slots = make(map[common.Hash]common.Hash)
+		s.storage[addr] = slots
+	}
+	slots[key] = value
+}
+
+func (s *State) sessionRequest() []byte {
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, s.session[:])
+	return req
+}
+
+func (s *State) accountRequest(addr common.Address) []byte {
+	req := s.sessionRequest()
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendBytes(req, addr[:])
+	return req
+}
+
+// call invokes a method of the session, failing without contacting the server
+// once an error occurred.
+func (s *State) call(method string, req []byte) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	res, err := s.client.Call(context.Background(), "/"+service+"/"+method, req)
+	if err != nil {
+		s.setError(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *State) setError(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func decodeAccount(res []byte, acc *account) error {
+	exists, err := grpcwire.DecodeUint(res, 1)
+	if err != nil {
+		return err
+	}
+	empty, err := grpcwire.DecodeUint(res, 2)
+	if err != nil {
+		return err
+	}
+	balance, err := grpcwire.DecodeBytes(res, 3)
+	if err != nil {
+		return err
+	}
+	if len(balance) > 0 {
+		acc.balance.SetBytes(balance[len(balance)-1])
+	}
+	if acc.nonce, err = grpcwire.DecodeUint(res, 4); err != nil {
+		return err
+	}
+	codeHash, err := grpcwire.DecodeBytes(res, 5)
+	if err != nil {
+		return err
+	}
+	if len(codeHash) > 0 {
+		if len(codeHash[len(codeHash)-1]) != common.HashLength {
+			return errors.New("invalid code hash")
+		}
+		acc.codeHash = common.BytesToHash(codeHash[len(codeHash)-1])
+	}
+	acc.exists, acc.empty = exists != 0, empty != 0
+	return nil
+}
@@ -0,0 +1,259 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remote
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/grpcwire"
+)
+
+type testBackend struct {
+	db   state.Database
+	head common.Hash
+}
+
+func (b *testBackend) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Root: b.head})
+}
+
+func (b *testBackend) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, b.db, nil)
+}
+
+var (
+	testAddr1 = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testAddr2 = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testAddr3 = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testKey   = common.HexToHash("0x01")
+)
+
+func newTestServer(t *testing.T) (*testBackend, *Server, *Client) {
+	backend := &testBackend{db: state.NewDatabase(rawdb.NewMemoryDatabase())}
+	statedb, _ := state.New(common.Hash{}, backend.db, nil)
+	statedb.SetBalance(testAddr1, big.NewInt(100))
+	statedb.SetNonce(testAddr1, 5)
+	statedb.SetCode(testAddr2, []byte{0x60, 0x00})
+	statedb.SetState(testAddr2, testKey, common.HexToHash("0xff"))
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.db.TrieDB().Commit(root, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	backend.head = root
+
+	server := NewServer(backend, "127.0.0.1:0")
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Stop() })
+
+	client, err := Dial("grpc://" + server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend, server, client
+}
+
+// modify applies the same modifications to a local or remote state.
+func modify(w state.StateWriter) {
+	w.SetBalance(testAddr3, big.NewInt(7))
+	w.SetNonce(testAddr1, 6)
+	w.SetCode(testAddr3, []byte{0x00})
+	w.SetState(testAddr2, testKey, common.Hash{})
+	w.SetState(testAddr3, testKey, common.HexToHash("0x02"))
+}
+
+func TestRemoteState(t *testing.T) {
+	backend, _, client := newTestServer(t)
+
+	remote, err := client.Open(context.Background(), common.Hash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	if remote.Root() != backend.head {
+		t.Fatalf("wrong root: have %x, want %x", remote.Root(), backend.head)
+	}
+	// Check the reads against the local state
+	local, _ := backend.StateAt(backend.head)
+	for _, addr := range []common.Address{testAddr1, testAddr2, testAddr3} {
+		if have, want := remote.Exist(addr), local.Exist(addr); have != want {
+			t.Errorf("%x: exist mismatch: have %v, want %v", addr, have, want)
+		}
+		if have, want := remote.Empty(addr), local.Empty(addr); have != want {
+			t.Errorf("%x: empty mismatch: have %v, want %v", addr, have, want)
+		}
+		if have, want := remote.GetBalance(addr), local.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Errorf("%x: balance mismatch: have %v, want %v", addr, have, want)
+		}
+		if have, want := remote.GetNonce(addr), local.GetNonce(addr); have != want {
+			t.Errorf("%x: nonce mismatch: have %d, want %d", addr, have, want)
+		}
+		if have, want := remote.GetCodeHash(addr), local.GetCodeHash(addr); have != want {
+			t.Errorf("%x: code hash mismatch: have %x, want %x", addr, have, want)
+		}
+		if have, want := remote.GetCode(addr), local.GetCode(addr); string(have) != string(want) {
+			t.Errorf("%x: code mismatch: have %x, want %x", addr, have, want)
+		}
+		if have, want := remote.GetState(addr, testKey), local.GetState(addr, testKey); have != want {
+			t.Errorf("%x: storage mismatch: have %x, want %x", addr, have, want)
+		}
+	}
+	// Modify both states and compare the results
+	modify(local)
+	modify(remote)
+	if remote.GetNonce(testAddr1) != 6 || remote.GetState(testAddr3, testKey) != common.HexToHash("0x02") {
+		t.Error("modifications not visible")
+	}
+	if have := remote.GetCodeHash(testAddr3); have != crypto.Keccak256Hash([]byte{0x00}) {
+		t.Errorf("wrong code hash after modification: %x", have)
+	}
+	if !remote.Suicide(testAddr2) || remote.Suicide(common.Address{0xff}) {
+		t.Error("wrong suicide result")
+	}
+	local.Suicide(testAddr2)
+
+	root, err := remote.IntermediateRoot(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := local.IntermediateRoot(true); root != want {
+		t.Fatalf("root mismatch: have %x, want %x", root, want)
+	}
+	if remote.Exist(testAddr2) {
+		t.Error("suicided account exists after finalisation")
+	}
+	if err := remote.Error(); err != nil {
+		t.Fatal(err)
+	}
+	// The backend state must not be modified
+	if statedb, _ := backend.StateAt(backend.head); statedb.GetNonce(testAddr1) != 5 {
+		t.Error("backend state modified")
+	}
+}
+
+func TestRemoteStateErrors(t *testing.T) {
+	_, _, client := newTestServer(t)
+
+	if _, err := client.Open(context.Background(), common.HexToHash("0x1234")); err == nil {
+		t.Fatal("opened missing state")
+	}
+	remote, err := client.Open(context.Background(), common.Hash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Accessing a closed state fails and the failure is kept
+	if remote.GetBalance(testAddr1).Sign() != 0 {
+		t.Error("non-zero balance from closed state")
+	}
+	err = remote.Error()
+	if err, ok := err.(*grpcwire.Error); !ok || err.Code != grpcwire.CodeNotFound {
+		t.Fatalf("wrong error: %v", err)
+	}
+	if remote.GetNonce(testAddr1) != 0 || remote.Error() != err {
+		t.Error("state usable after failure")
+	}
+}
+
+func TestRemoteStateSnapshots(t *testing.T) {
+	_, _, client := newTestServer(t)
+
+	remote, err := client.Open(context.Background(), common.Hash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+
+	outer := remote.Snapshot()
+	remote.SetNonce(testAddr1, 6)
+	inner := remote.Snapshot()
+	remote.SetBalance(testAddr1, big.NewInt(1))
+	if remote.GetBalance(testAddr1).Int64() != 1 {
+		t.Fatal("modification not visible")
+	}
+	remote.RevertToSnapshot(inner)
+	if remote.GetBalance(testAddr1).Int64() != 100 || remote.GetNonce(testAddr1) != 6 {
+		t.Fatalf("wrong state after inner revert: balance %v, nonce %d", remote.GetBalance(testAddr1), remote.GetNonce(testAddr1))
+	}
+	remote.RevertToSnapshot(outer)
+	if remote.GetNonce(testAddr1) != 5 {
+		t.Fatalf("wrong nonce after outer revert: %d", remote.GetNonce(testAddr1))
+	}
+	if err := remote.Error(); err != nil {
+		t.Fatal(err)
+	}
+	// Reverted revisions are invalidated
+	remote.RevertToSnapshot(inner)
+	if err, ok := remote.Error().(*grpcwire.Error); !ok || err.Code != grpcwire.CodeInvalidArgument {
+		t.Fatalf("wrong error: %v", remote.Error())
+	}
+}
+
+func TestRemoteStateSessions(t *testing.T) {
+	_, server, client := newTestServer(t)
+	clock := new(mclock.Simulated)
+	server.clock = clock
+
+	remote, err := client.Open(context.Background(), common.Hash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sessions of other clients can't be accessed by guessing
+	other := *remote
+	other.session[0]++
+	if other.GetNonce(testAddr1) != 0 || other.Error() == nil {
+		t.Error("accessed state with altered session identifier")
+	}
+	// Sessions are closed once idle for too long
+	clock.Run(sessionTimeout / 2)
+	if remote.GetNonce(testAddr1) != 5 {
+		t.Fatalf("session closed early: %v", remote.Error())
+	}
+	clock.Run(sessionTimeout + 1)
+	remote.SetNonce(testAddr1, 6)
+	if err, ok := remote.Error().(*grpcwire.Error); !ok || err.Code != grpcwire.CodeNotFound {
+		t.Fatalf("wrong error: %v", remote.Error())
+	}
+	// The number of sessions is limited
+	for i := 0; i < maxSessions; i++ {
+		if _, err := client.Open(context.Background(), common.Hash{}); err != nil {
+			t.Fatalf("failed to open session %d: %v", i, err)
+		}
+	}
+	if _, err := client.Open(context.Background(), common.Hash{}); err == nil {
+		t.Fatal("opened session beyond the limit")
+	}
+	// Expired sessions make room for new ones
+	clock.Run(sessionTimeout + 1)
+	if _, err := client.Open(context.Background(), common.Hash{}); err != nil {
+		t.Fatalf("failed to open session after expiry: %v", err)
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package remote serves the state of a node to external EVM implementations over
+// gRPC, and implements the state.StateReader and state.StateWriter interfaces on
+// top of the service. The service is defined in state.proto.
+package remote
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/grpcwire"
+	"github.com/ethereum/go-ethereum/log"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// service is the name of the gRPC service defined in state.proto.
+	service = "ethereum.state.v1.State"
+
+	// maxSessions is the maximum number of states open at the same time.
+	maxSessions = 64
+
+	// sessionTimeout is the time after which idle sessions are closed.
+	sessionTimeout = 5 * time.Minute
+
+	// sessionIDLength is the length of the random session identifiers, long
+	// enough that clients can't guess the sessions of others.
+	sessionIDLength = 16
+
+	// codeResourceExhausted is the gRPC status returned if too many sessions
+	// are open.
+	codeResourceExhausted = 8
+)
+
+// Backend provides the states served to the clients.
+type Backend interface {
+	CurrentBlock() *types.Block
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// sessionID identifies a state opened by a client.
+type sessionID [sessionIDLength]byte
+
+// session is a state opened by a client.
+type session struct {
+	state     *state.StateDB
+	snapshots []int // Revisions of the state which can be reverted to, ascending
+	used      mclock.AbsTime
+}
+
+// Server serves the states of the backend over gRPC. Clients modify their own
+// copy of a state, nothing is ever written to the backend.
+type Server struct {
+	backend Backend
+	addr    string
+
+	listener net.Listener
+	server   *http.Server
+
+	clock    mclock.Clock
+	lock     sync.Mutex
+	sessions map[sessionID]*session
+}
+
+// NewServer creates a state server for the backend, listening on addr.
+func NewServer(backend Backend, addr string) *Server {
+	s := &Server{
+		backend:  backend,
+		addr:     addr,
+		clock:    mclock.System{},
+		sessions: make(map[sessionID]*session),
+	}
+	srv := grpcwire.NewServer(service)
+	srv.Register("OpenState", s.openState)
+	srv.Register("CloseState", s.closeState)
+	srv.Register("GetAccount", s.getAccount)
+	srv.Register("GetCode", s.getCode)
+	srv.Register("GetStorage", s.getStorage)
+	srv.Register("SetBalance", s.setBalance)
+	srv.Register("SetNonce", s.setNonce)
+	srv.Register("SetCode", s.setCode)
+	srv.Register("SetStorage", s.setStorage)
+	srv.Register("Suicide", s.suicide)
+	srv.Register("IntermediateRoot", s.intermediateRoot)
+	srv.Register("Snapshot", s.snapshot)
+	srv.Register("RevertToSnapshot", s.revertToSnapshot)
+	s.server = &http.Server{Handler: srv.Handler()}
+	return s
+}
+
+// Start implements node.Lifecycle, opening the listener.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	log.Info("State server started", "addr", listener.Addr())
+
+	go s.server.Serve(listener)
+	return nil
+}
+
+// Stop implements node.Lifecycle, closing the listener and all sessions.
+func (s *Server) Stop() error {
+	s.server.Close()
+
+	s.lock.Lock()
+	s.sessions = make(map[sessionID]*session)
+	s.lock.Unlock()
+	return nil
+}
+
+// Addr returns the listening address of the server.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) openState(req []byte) ([]byte, error) {
+	root, err := decodeHash(req, 1, true)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	if root == (common.Hash{}) {
+		root = s.backend.CurrentBlock().Root()
+	}
+	statedb, err := s.backend.StateAt(root)
+	if err != nil {
+		return nil, &grpcwire.Error{Code: grpcwire.CodeNotFound, Message: err.Error()}
+	}
+	var id sessionID
+	if _, err := crand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Close the sessions abandoned by their clients before counting
+	now := s.clock.Now()
+	for id, sess := range s.sessions {
+		if now.Sub(sess.used) > sessionTimeout {
+			delete(s.sessions, id)
+		}
+	}
+	if len(s.sessions) >= maxSessions {
+		return nil, &grpcwire.Error{Code: codeResourceExhausted, Message: "too many open states"}
+	}
+	s.sessions[id] = &session{state: statedb, used: now}
+
+	var res []byte
+	res = protowire.AppendTag(res, 1, protowire.BytesType)
+	res = protowire.AppendBytes(res, id[:])
+	res = protowire.AppendTag(res, 2, protowire.BytesType)
+	res = protowire.AppendBytes(res, root[:])
+	return res, nil
+}
+
+func (s *Server) closeState(req []byte) ([]byte, error) {
+	id, err := decodeSessionID(req)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.sessions[id]; !ok {
+		return nil, errUnknownSession
+	}
+	delete(s.sessions, id)
+	return nil, nil
+}
+
+func (s *Server) getAccount(req []byte) ([]byte, error) {
+	var res []byte
+	err := s.withAccount(req, func(statedb *state.StateDB, addr common.Address) {
+		codeHash := statedb.GetCodeHash(addr)
+		res = protowire.AppendTag(res, 1, protowire.VarintType)
+		res = protowire.AppendVarint(res, protowire.EncodeBool(statedb.Exist(addr)))
+		res = protowire.AppendTag(res, 2, protowire.VarintType)
+		res = protowire.AppendVarint(res, protowire.EncodeBool(statedb.Empty(addr)))
+		res = protowire.AppendTag(res, 3, protowire.BytesType)
+		res = protowire.AppendBytes(res, statedb.GetBalance(addr).Bytes())
+		res = protowire.AppendTag(res, 4, protowire.VarintType)
+		res = protowire.AppendVarint(res, statedb.GetNonce(addr))
+		res = protowire.AppendTag(res, 5, protowire.BytesType)
+		res = protowire.AppendBytes(res, codeHash[:])
+	})
+	return res, err
+}
+
+func (s *Server) getCode(req []byte) ([]byte, error) {
+	var res []byte
+	err := s.withAccount(req, func(statedb *state.StateDB, addr common.Address) {
+		res = protowire.AppendTag(res, 1, protowire.BytesType)
+		res = protowire.AppendBytes(res, statedb.GetCode(addr))
+	})
+	return res, err
+}
+
+func (s *Server) getStorage(req []byte) ([]byte, error) {
+	key, err := decodeHash(req, 3, false)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	var res []byte
+	err = s.withAccount(req, func(statedb *state.StateDB, addr common.Address) {
+		value := statedb.GetState(addr, key)
+		res = protowire.AppendTag(res, 1, protowire.BytesType)
+		res = protowire.AppendBytes(res, value[:])
+	})
+	return res, err
+}
+
+func (s *Server) setBalance(req []byte) ([]byte, error) {
+	balance, err := grpcwire.DecodeBytes(req, 3)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	amount := new(big.Int)
+	if len(balance) > 0 {
+		amount.SetBytes(balance[len(balance)-1])
+	}
+	return nil, s.withAccount(req, func(statedb *state.StateDB, addr common.Address) {
+		statedb.SetBalance(addr, amount)
+	})
+}
+
+func (s *Server) setNonce(req []byte) ([]byte, error) {
+	nonce, err := grpcwire.DecodeUint(req, 3)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	return nil, s.withAccount(req, func(statedb *state.StateDB, addr common.Address) {
+		statedb.SetNonce(addr, nonce)
+	})
+}
+
+func (s *Server) setCode(req []byte) ([]byte, error) {
+	code, err := grpcwire.DecodeBytes(req, 3)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	var c []byte
+	if len(code) > 0 {
+		c = common.CopyBytes(code[len(code)-1])
+	}
+	return nil, s.withAccount(req, func(statedb *state.StateDB, addr common.Address) {
+		statedb.SetCode(addr, c)
+	})
+}
+
+func (s *Server) setStorage(req []byte) ([]byte, error) {
+	key, err := decodeHash(req, 3, false)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	value, err := decodeHash(req, 4, false)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	return nil, s.withAccount(req, func(statedb *state.StateDB, addr common.Address) {
+		statedb.SetState(addr, key, value)
+	})
+}
+
+func (s *Server) suicide(req []byte) ([]byte, error) {
+	var res []byte
+	err := s.withAccount(req, func(statedb *state.StateDB, addr common.Address) {
+		res = protowire.AppendTag(res, 1, protowire.VarintType)
+		res = protowire.AppendVarint(res, protowire.EncodeBool(statedb.Suicide(addr)))
+	})
+	return res, err
+}
+
+func (s *Server) intermediateRoot(req []byte) ([]byte, error) {
+	deleteEmpty, err := grpcwire.DecodeUint(req, 2)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	var res []byte
+	err = s.withSession(req, func(sess *session) error {
+		// Finalisation discards all revisions of the state
+		root := sess.state.IntermediateRoot(deleteEmpty != 0)
+		sess.snapshots = sess.snapshots[:0]
+
+		res = protowire.AppendTag(res, 1, protowire.BytesType)
+		res = protowire.AppendBytes(res, root[:])
+		return nil
+	})
+	return res, err
+}
+
+func (s *Server) snapshot(req []byte) ([]byte, error) {
+	var res []byte
+	err := s.withSession(req, func(sess *session) error {
+		revid := sess.state.Snapshot()
+		sess.snapshots = append(sess.snapshots, revid)
+
+		res = protowire.AppendTag(res, 1, protowire.VarintType)
+		res = protowire.AppendVarint(res, uint64(revid))
+		return nil
+	})
+	return res, err
+}
+
+func (s *Server) revertToSnapshot(req []byte) ([]byte, error) {
+	revid, err := grpcwire.DecodeUint(req, 2)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	return nil, s.withSession(req, func(sess *session) error {
+		// Reverting to an unknown revision panics, so check it first
+		for i, snapshot := range sess.snapshots {
+			if uint64(snapshot) == revid {
+				sess.state.RevertToSnapshot(snapshot)
+				sess.snapshots = sess.snapshots[:i]
+				return nil
+			}
+		}
+		return invalidArgument(fmt.Errorf("unknown snapshot %d", revid))
+	})
+}
+
+// withState runs fn on the state of the session referenced by the request,
+// failing if the backing database reported an error.
+func (s *Server) withState(req []byte, fn func(statedb *state.StateDB)) error {
+	return s.withSession(req, func(sess *session) error {
+		fn(sess.state)
+		return nil
+	})
+}
+
+// withSession runs fn on the session referenced by the request, failing if fn
+// does or the backing database reported an error.
+func (s *Server) withSession(req []byte, fn func(sess *session) error) error {
+	id, err := decodeSessionID(req)
+	if err != nil {
+		return invalidArgument(err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return errUnknownSession
+	}
+	now := s.clock.Now()
+	if now.Sub(sess.used) > sessionTimeout {
+		delete(s.sessions, id)
+		return errUnknownSession
+	}
+	sess.used = now
+	if err := fn(sess); err != nil {
+		return err
+	}
+	if err := sess.state.Error(); err != nil {
+		// The state is unusable after a database failure
+		delete(s.sessions, id)
+		return err
+	}
+	return nil
+}
+
+// withAccount is like withState, also decoding the account address of the
+// request.
+func (s *Server) withAccount(req []byte, fn func(statedb *state.StateDB, addr common.Address)) error {
+	addrs, err := grpcwire.DecodeBytes(req, 2)
+	if err != nil {
+		return invalidArgument(err)
+	}
+	if len(addrs) == 0 || len(addrs[len(addrs)-1]) != common.AddressLength {
+		return invalidArgument(errors.New("invalid account address"))
+	}
+	addr := common.BytesToAddress(addrs[len(addrs)-1])
+	return s.withState(req, func(statedb *state.StateDB) {
+		fn(statedb, addr)
+	})
+}
+
+var errUnknownSession = &grpcwire.Error{Code: grpcwire.CodeNotFound, Message: "unknown state session"}
+
+func invalidArgument(err error) error {
+	return &grpcwire.Error{Code: grpcwire.CodeInvalidArgument, Message: err.Error()}
+}
+
+// decodeSessionID decodes the session identifier of a request.
+func decodeSessionID(msg []byte) (sessionID, error) {
+	var id sessionID
+	values, err := grpcwire.DecodeBytes(msg, 1)
+	if err != nil {
+		return id, err
+	}
+	if len(values) == 0 || len(values[len(values)-1]) != sessionIDLength {
+		return id, errors.New("invalid session identifier")
+	}
+	copy(id[:], values[len(values)-1])
+	return id, nil
+}
+
+// decodeHash decodes a 32 byte field of a message.
+func decodeHash(msg []byte, field protowire.Number, optional bool) (common.Hash, error) {
+	values, err := grpcwire.DecodeBytes(msg, field)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(values) == 0 || len(values[len(values)-1]) == 0 {
+		if optional {
+			return common.Hash{}, nil
+		}
+		return common.Hash{}, fmt.Errorf("missing field %d", field)
+	}
+	value := values[len(values)-1]
+	if len(value) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid length %d of field %d", len(value), field)
+	}
+	return common.BytesToHash(value), nil
+}
@@ -181,7 +181,11 @@ func (s *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		return value
 	}
 	// Otherwise return the entry's original value
-	return s.GetCommittedState(db, key)
+	value = s.GetCommittedState(db, key)
+	if s.db.recorder != nil {
+		s.db.recordSlot(s.address, key, value)
+	}
+	return value
 }
 
 // GetCommittedState retrieves a value from the committed account storage trie.
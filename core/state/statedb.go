@@ -102,6 +102,9 @@ type StateDB struct {
 	// Per-transaction access list
 	accessList *accessList
 
+	// Recorder of the pre-values of accessed state, nil unless recording
+	recorder *accessRecorder
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
 	journal        *journal
@@ -343,7 +346,11 @@ func (s *StateDB) GetStorageProof(a common.Address, key common.Hash) ([][]byte,
 func (s *StateDB) GetCommittedState(addr common.Address, hash common.Hash) common.Hash {
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
-		return stateObject.GetCommittedState(s.db, hash)
+		value := stateObject.GetCommittedState(s.db, hash)
+		if s.recorder != nil {
+			s.recordSlot(addr, hash, value)
+		}
+		return value
 	}
 	return common.Hash{}
 }
@@ -493,7 +500,11 @@ func (s *StateDB) deleteStateObject(obj *stateObject) {
 // the object is not found or was deleted in this execution context. If you need
 // to differentiate between non-existent/just-deleted, use getDeletedStateObject.
 func (s *StateDB) getStateObject(addr common.Address) *stateObject {
-	if obj := s.getDeletedStateObject(addr); obj != nil && !obj.deleted {
+	obj := s.getDeletedStateObject(addr)
+	if s.recorder != nil {
+		s.recordAccount(addr, obj)
+	}
+	if obj != nil && !obj.deleted {
 		return obj
 	}
 	return nil
@@ -573,6 +584,9 @@ func (s *StateDB) GetOrNewStateObject(addr common.Address) *stateObject {
 // the given address, it is overwritten and returned as the second return value.
 func (s *StateDB) createObject(addr common.Address) (newobj, prev *stateObject) {
 	prev = s.getDeletedStateObject(addr) // Note, prev might have been deleted, we need that!
+	if s.recorder != nil {
+		s.recordAccount(addr, prev)
+	}
 
 	var prevdestruct bool
 	if s.snap != nil && prev != nil {
@@ -56,6 +56,8 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 // Process returns the receipts and logs accumulated during the process and
 // returns the amount of gas that was used in the process. If any of the
 // transactions failed to execute due to insufficient gas it will return an error.
+// Since Prague, the requests of the block are collected and checked against the
+// requests hash of the header as well.
 func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
 	var (
 		receipts    types.Receipts
@@ -86,6 +88,16 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
 	}
+	if p.config.IsPrague(blockNumber) {
+		requests, err := ProcessRequests(p.config, vmenv, statedb, receipts)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("could not process requests: %w", err)
+		}
+		hash := requests.Hash()
+		if header.RequestsHash == nil || *header.RequestsHash != hash {
+			return nil, nil, 0, fmt.Errorf("invalid requests hash (remote: %v local: %x)", header.RequestsHash, hash)
+		}
+	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles())
 
@@ -114,15 +114,47 @@ func (result *ExecutionResult) Revert() []byte {
 	return common.CopyBytes(result.ReturnData)
 }
 
+// IntrinsicGasVersion is the version of the intrinsic gas calculation exposed by
+// IntrinsicGasOf. It is bumped whenever the calculation itself changes, letting
+// external users detect that their own implementation needs to be revisited.
+const IntrinsicGasVersion = 1
+
+// IntrinsicGasCost is the itemised intrinsic gas of a transaction.
+type IntrinsicGasCost struct {
+	Base         uint64 // Flat cost of a transaction or contract creation
+	Data         uint64 // Cost of the payload
+	AccessList   uint64 // Cost of the EIP-2930 access list
+	Total        uint64 // Sum of all the above
+	ZeroBytes    uint64 // Number of zero bytes in the payload
+	NonZeroBytes uint64 // Number of non-zero bytes in the payload
+}
+
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
 func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation bool, isHomestead, isEIP2028 bool) (uint64, error) {
+	cost, err := intrinsicGasCost(data, accessList, isContractCreation, isHomestead, isEIP2028)
+	if err != nil {
+		return 0, err
+	}
+	return cost.Total, nil
+}
+
+// IntrinsicGasOf computes the itemised intrinsic gas for a message with the given
+// data under the given chain rules.
+func IntrinsicGasOf(data []byte, accessList types.AccessList, isContractCreation bool, rules params.Rules) (*IntrinsicGasCost, error) {
+	return intrinsicGasCost(data, accessList, isContractCreation, rules.IsHomestead, rules.IsIstanbul)
+}
+
+func intrinsicGasCost(data []byte, accessList types.AccessList, isContractCreation bool, isHomestead, isEIP2028 bool) (*IntrinsicGasCost, error) {
+	cost := new(IntrinsicGasCost)
+
 	// Set the starting gas for the raw transaction
-	var gas uint64
 	if isContractCreation && isHomestead {
-		gas = params.TxGasContractCreation
+		cost.Base = params.TxGasContractCreation
 	} else {
-		gas = params.TxGas
+		cost.Base = params.TxGas
 	}
+	gas := cost.Base
+
 	// Bump the required gas by the amount of transactional data
 	if len(data) > 0 {
 		// Zero and non-zero bytes are priced differently
@@ -138,21 +170,26 @@ func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation b
 			nonZeroGas = params.TxDataNonZeroGasEIP2028
 		}
 		if (math.MaxUint64-gas)/nonZeroGas < nz {
-			return 0, ErrGasUintOverflow
+			return nil, ErrGasUintOverflow
 		}
 		gas += nz * nonZeroGas
 
 		z := uint64(len(data)) - nz
 		if (math.MaxUint64-gas)/params.TxDataZeroGas < z {
-			return 0, ErrGasUintOverflow
+			return nil, ErrGasUintOverflow
 		}
 		gas += z * params.TxDataZeroGas
+
+		cost.Data = gas - cost.Base
+		cost.ZeroBytes, cost.NonZeroBytes = z, nz
 	}
 	if accessList != nil {
-		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
-		gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+		cost.AccessList = uint64(len(accessList)) * params.TxAccessListAddressGas
+		cost.AccessList += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+		gas += cost.AccessList
 	}
-	return gas, nil
+	cost.Total = gas
+	return cost, nil
 }
 
 // NewStateTransition initialises and returns a new state transition object.
@@ -0,0 +1,60 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the itemised intrinsic gas follows the fork rules and adds up to
+// the plain intrinsic gas.
+func TestIntrinsicGasOf(t *testing.T) {
+	var (
+		data       = []byte{0, 0, 1, 2}
+		accessList = types.AccessList{{Address: common.Address{1}, StorageKeys: []common.Hash{{}, {1}}}}
+		frontier   = params.Rules{}
+		istanbul   = params.Rules{IsHomestead: true, IsIstanbul: true}
+	)
+	tests := []struct {
+		rules  params.Rules
+		create bool
+		want   IntrinsicGasCost
+	}{
+		{frontier, true, IntrinsicGasCost{Base: 21000, Data: 2*4 + 2*68, AccessList: 2400 + 2*1900, ZeroBytes: 2, NonZeroBytes: 2}},
+		{istanbul, false, IntrinsicGasCost{Base: 21000, Data: 2*4 + 2*16, AccessList: 2400 + 2*1900, ZeroBytes: 2, NonZeroBytes: 2}},
+		{istanbul, true, IntrinsicGasCost{Base: 53000, Data: 2*4 + 2*16, AccessList: 2400 + 2*1900, ZeroBytes: 2, NonZeroBytes: 2}},
+	}
+	for i, test := range tests {
+		test.want.Total = test.want.Base + test.want.Data + test.want.AccessList
+
+		cost, err := IntrinsicGasOf(data, accessList, test.create, test.rules)
+		if err != nil {
+			t.Fatalf("test %d: failed to compute intrinsic gas: %v", i, err)
+		}
+		if *cost != test.want {
+			t.Errorf("test %d: cost mismatch: have %+v, want %+v", i, *cost, test.want)
+		}
+		gas, _ := IntrinsicGas(data, accessList, test.create, test.rules.IsHomestead, test.rules.IsIstanbul)
+		if gas != cost.Total {
+			t.Errorf("test %d: total mismatch: have %d, want %d", i, cost.Total, gas)
+		}
+	}
+}
@@ -563,7 +563,10 @@ func (l *txPricedList) underpricedFor(h *priceHeap, tx *types.Transaction) bool
 //
 // Note local transaction won't be considered for eviction.
 func (l *txPricedList) Discard(slots int, force bool) (types.Transactions, bool) {
-	drop := make(types.Transactions, 0, slots) // Remote underpriced transactions to drop
+	var (
+		drop = make(types.Transactions, 0, slots) // Remote underpriced transactions to drop
+		kept types.Transactions                   // Priority lane transactions to put back
+	)
 	for slots > 0 {
 		if len(l.urgent.list)*floatingRatio > len(l.floating.list)*urgentRatio || floatingRatio == 0 {
 			// Discard stale transactions if found during cleanup
@@ -585,11 +588,19 @@ func (l *txPricedList) Discard(slots int, force bool) (types.Transactions, bool)
 				atomic.AddInt64(&l.stales, -1)
 				continue
 			}
+			// Transactions of the priority lane are evicted by their own rules
+			if l.all.IsPriority(tx.Hash()) {
+				kept = append(kept, tx)
+				continue
+			}
 			// Non stale transaction found, discard it
 			drop = append(drop, tx)
 			slots -= numSlots(tx)
 		}
 	}
+	for _, tx := range kept {
+		heap.Push(&l.floating, tx)
+	}
 	// If we still can't make enough room for the new transaction
 	if slots > 0 && !force {
 		for _, tx := range drop {
@@ -58,6 +58,10 @@ var (
 	// within the pool.
 	ErrAlreadyKnown = errors.New("already known")
 
+	// ErrAlreadyIncluded is returned if the transaction was included in one of the
+	// recent blocks.
+	ErrAlreadyIncluded = errors.New("already included")
+
 	// ErrInvalidSender is returned if the transaction contains an invalid signature.
 	ErrInvalidSender = errors.New("invalid sender")
 
@@ -69,6 +73,10 @@ var (
 	// another remote transaction.
 	ErrTxPoolOverflow = errors.New("txpool is full")
 
+	// ErrTxPoolLaneFull is returned if the lane of the transaction pool reserved
+	// for local transactions is full.
+	ErrTxPoolLaneFull = errors.New("txpool lane is full")
+
 	// ErrReplaceUnderpriced is returned if a transaction is attempted to be replaced
 	// with a different one without the required price bump.
 	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
@@ -112,6 +120,8 @@ var (
 	invalidTxMeter     = metrics.NewRegisteredMeter("txpool/invalid", nil)
 	underpricedTxMeter = metrics.NewRegisteredMeter("txpool/underpriced", nil)
 	overflowedTxMeter  = metrics.NewRegisteredMeter("txpool/overflowed", nil)
+	includedTxMeter    = metrics.NewRegisteredMeter("txpool/included", nil)       // Dropped due to recent inclusion
+	includedBytesMeter = metrics.NewRegisteredMeter("txpool/included/bytes", nil) // Size of the transactions dropped due to recent inclusion
 	// throttleTxMeter counts how many transactions are rejected due to too-many-changes between
 	// txpool reorgs.
 	throttleTxMeter = metrics.NewRegisteredMeter("txpool/throttle", nil)
@@ -165,6 +175,12 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	LocalSlots    uint64 // Maximum number of slots of the local lane, not counted towards the global limits (0 = no local lane)
+	PrioritySlots uint64 // Maximum number of slots of the priority lane, not counted towards the global limits (0 = no priority lane)
+	PriorityTip   uint64 // Minimum gas tip of remote transactions to enter the priority lane
+
+	RecentBlocks uint64 // Number of recent blocks whose transactions are dropped without validation (0 = disabled)
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -182,6 +198,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	RecentBlocks: 64,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -256,6 +274,7 @@ type TxPool struct {
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price
+	recent  *recentTxs                   // Transactions included in the recent blocks
 
 	chainHeadCh     chan ChainHeadEvent
 	chainHeadSub    event.Subscription
@@ -290,6 +309,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
 		all:             newTxLookup(),
+		recent:          newRecentTxs(config.RecentBlocks),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
@@ -670,8 +690,16 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		invalidTxMeter.Mark(1)
 		return false, err
 	}
+	// If the lane of the transaction is full, make room in it. Transactions not
+	// admitted to a dedicated lane compete for the globally shared slots.
+	lane := pool.laneOf(tx, isLocal)
+	if lane != remoteLane {
+		if lane, err = pool.makeLaneRoom(tx, lane); err != nil {
+			return false, err
+		}
+	}
 	// If the transaction pool is full, discard underpriced transactions
-	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
+	if lane == remoteLane && uint64(pool.remoteSlots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
 		if !isLocal && pool.priced.Underpriced(tx) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
@@ -690,7 +718,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		// New transaction is better than our worse ones, make room for it.
 		// If it's a local transaction, forcibly discard all available transactions.
 		// Otherwise if we can't make enough room for new one, abort the operation.
-		drop, success := pool.priced.Discard(pool.all.Slots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), isLocal)
+		drop, success := pool.priced.Discard(pool.remoteSlots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), isLocal)
 
 		// Special case, we still can't make the room for the new remote one.
 		if !isLocal && !success {
@@ -724,6 +752,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
+		if lane == priorityLane {
+			pool.all.Prioritize(hash)
+		}
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -737,6 +768,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	if err != nil {
 		return false, err
 	}
+	if lane == priorityLane {
+		pool.all.Prioritize(hash)
+	}
 	// Mark local addresses and journal local transactions
 	if local && !pool.locals.contains(from) {
 		log.Info("Setting new local account", "address", from)
@@ -899,6 +933,13 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 			knownTxMeter.Mark(1)
 			continue
 		}
+		// If the transaction was included in a recent block, it arrived late
+		if pool.recent.contains(tx.Hash()) {
+			errs[i] = ErrAlreadyIncluded
+			includedTxMeter.Mark(1)
+			includedBytesMeter.Mark(int64(tx.Size()))
+			continue
+		}
 		// Exclude transactions with invalid signatures as soon as
 		// possible and cache senders in transactions before
 		// obtaining lock
@@ -1249,6 +1290,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 				// If the reorg ended up on a lower number, it's indicative of setHead being the cause
 				log.Debug("Skipping transaction reset caused by setHead",
 					"old", oldHead.Hash(), "oldnum", oldNum, "new", newHead.Hash(), "newnum", newNum)
+				pool.recent.truncate(newNum)
 				// We still need to update the current state s.th. the lost transactions can be readded by the user
 			} else {
 				for rem.NumberU64() > add.NumberU64() {
@@ -1278,8 +1320,16 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 					}
 				}
 				reinject = types.TxDifference(discarded, included)
+
+				pool.recent.forget(discarded)
+				pool.recent.add(newNum, included)
 			}
 		}
+	} else if oldHead != nil {
+		// Plain chain extension, track the transactions of the new block
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			pool.recent.add(block.NumberU64(), block.Transactions())
+		}
 	}
 	// Initialize the internal state to the current head
 	if newHead == nil {
@@ -1376,9 +1426,12 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 // pending limit. The algorithm tries to reduce transaction counts by an approximately
 // equal number for all for accounts with many pending transactions.
 func (pool *TxPool) truncatePending() {
+	// Transactions of the dedicated lanes don't count towards the global limits
+	counts := make(map[common.Address]int, len(pool.pending))
 	pending := uint64(0)
-	for _, list := range pool.pending {
-		pending += uint64(list.Len())
+	for addr, list := range pool.pending {
+		counts[addr] = pool.lanelessLen(list)
+		pending += uint64(counts[addr])
 	}
 	if pending <= pool.config.GlobalSlots {
 		return
@@ -1387,12 +1440,39 @@ func (pool *TxPool) truncatePending() {
 	pendingBeforeCap := pending
 	// Assemble a spam order to penalize large transactors first
 	spammers := prque.New(nil)
-	for addr, list := range pool.pending {
+	for addr, count := range counts {
 		// Only evict transactions from high rollers
-		if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
-			spammers.Push(addr, int64(list.Len()))
+		if !pool.locals.contains(addr) && uint64(count) > pool.config.AccountSlots {
+			spammers.Push(addr, int64(count))
 		}
 	}
+	// capOffender drops the highest nonce transaction of an offender, unless it
+	// is in a dedicated lane. Such an account can't be reduced any further, so it
+	// is excluded from the equalization.
+	capOffender := func(addr common.Address) {
+		list := pool.pending[addr]
+		if !pool.evictable(list) {
+			counts[addr] = 0
+			return
+		}
+		caps := list.Cap(list.Len() - 1)
+		for _, tx := range caps {
+			// Drop the transaction from the global pools too
+			hash := tx.Hash()
+			pool.all.Remove(hash)
+
+			// Update the account nonce to the dropped transaction
+			pool.pendingNonces.setIfLower(addr, tx.Nonce())
+			log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
+		}
+		pool.priced.Removed(len(caps))
+		pendingGauge.Dec(int64(len(caps)))
+		if pool.locals.contains(addr) {
+			localGauge.Dec(int64(len(caps)))
+		}
+		counts[addr]--
+		pending--
+	}
 	// Gradually drop transactions from offenders
 	offenders := []common.Address{}
 	for pending > pool.config.GlobalSlots && !spammers.Empty() {
@@ -1403,29 +1483,12 @@ func (pool *TxPool) truncatePending() {
 		// Equalize balances until all the same or below threshold
 		if len(offenders) > 1 {
 			// Calculate the equalization threshold for all current offenders
-			threshold := pool.pending[offender.(common.Address)].Len()
+			threshold := counts[offender.(common.Address)]
 
 			// Iteratively reduce all offenders until below limit or threshold reached
-			for pending > pool.config.GlobalSlots && pool.pending[offenders[len(offenders)-2]].Len() > threshold {
+			for pending > pool.config.GlobalSlots && counts[offenders[len(offenders)-2]] > threshold {
 				for i := 0; i < len(offenders)-1; i++ {
-					list := pool.pending[offenders[i]]
-
-					caps := list.Cap(list.Len() - 1)
-					for _, tx := range caps {
-						// Drop the transaction from the global pools too
-						hash := tx.Hash()
-						pool.all.Remove(hash)
-
-						// Update the account nonce to the dropped transaction
-						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
-						log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
-					}
-					pool.priced.Removed(len(caps))
-					pendingGauge.Dec(int64(len(caps)))
-					if pool.locals.contains(offenders[i]) {
-						localGauge.Dec(int64(len(caps)))
-					}
-					pending--
+					capOffender(offenders[i])
 				}
 			}
 		}
@@ -1433,26 +1496,9 @@ func (pool *TxPool) truncatePending() {
 
 	// If still above threshold, reduce to limit or min allowance
 	if pending > pool.config.GlobalSlots && len(offenders) > 0 {
-		for pending > pool.config.GlobalSlots && uint64(pool.pending[offenders[len(offenders)-1]].Len()) > pool.config.AccountSlots {
+		for pending > pool.config.GlobalSlots && uint64(counts[offenders[len(offenders)-1]]) > pool.config.AccountSlots {
 			for _, addr := range offenders {
-				list := pool.pending[addr]
-
-				caps := list.Cap(list.Len() - 1)
-				for _, tx := range caps {
-					// Drop the transaction from the global pools too
-					hash := tx.Hash()
-					pool.all.Remove(hash)
-
-					// Update the account nonce to the dropped transaction
-					pool.pendingNonces.setIfLower(addr, tx.Nonce())
-					log.Trace("Removed fairness-exceeding pending transaction", "hash", hash)
-				}
-				pool.priced.Removed(len(caps))
-				pendingGauge.Dec(int64(len(caps)))
-				if pool.locals.contains(addr) {
-					localGauge.Dec(int64(len(caps)))
-				}
-				pending--
+				capOffender(addr)
 			}
 		}
 	}
@@ -1461,9 +1507,10 @@ func (pool *TxPool) truncatePending() {
 
 // truncateQueue drops the oldest transactions in the queue if the pool is above the global queue limit.
 func (pool *TxPool) truncateQueue() {
+	// Transactions of the dedicated lanes don't count towards the global limits
 	queued := uint64(0)
 	for _, list := range pool.queue {
-		queued += uint64(list.Len())
+		queued += uint64(pool.lanelessLen(list))
 	}
 	if queued <= pool.config.GlobalQueue {
 		return
@@ -1485,18 +1532,12 @@ func (pool *TxPool) truncateQueue() {
 
 		addresses = addresses[:len(addresses)-1]
 
-		// Drop all transactions if they are less than the overflow
-		if size := uint64(list.Len()); size <= drop {
-			for _, tx := range list.Flatten() {
-				pool.removeTx(tx.Hash(), true)
-			}
-			drop -= size
-			queuedRateLimitMeter.Mark(int64(size))
-			continue
-		}
-		// Otherwise drop only last few transactions
+		// Drop the last few transactions, keeping those of the dedicated lanes
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			if pool.inLane(txs[i].Hash()) {
+				continue
+			}
 			pool.removeTx(txs[i].Hash(), true)
 			drop--
 			queuedRateLimitMeter.Mark(1)
@@ -1663,13 +1704,18 @@ type txLookup struct {
 	lock    sync.RWMutex
 	locals  map[common.Hash]*types.Transaction
 	remotes map[common.Hash]*types.Transaction
+
+	localSlots    int                      // Slots used by local transactions
+	prioritySlots int                      // Slots used by the priority lane
+	priority      map[common.Hash]struct{} // Remote transactions in the priority lane
 }
 
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		locals:  make(map[common.Hash]*types.Transaction),
-		remotes: make(map[common.Hash]*types.Transaction),
+		locals:   make(map[common.Hash]*types.Transaction),
+		remotes:  make(map[common.Hash]*types.Transaction),
+		priority: make(map[common.Hash]struct{}),
 	}
 }
 
@@ -1755,6 +1801,59 @@ func (t *txLookup) Slots() int {
 	return t.slots
 }
 
+// LocalSlots returns the current number of slots used by local transactions.
+func (t *txLookup) LocalSlots() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.localSlots
+}
+
+// PrioritySlots returns the current number of slots used by the transactions
+// of the priority lane.
+func (t *txLookup) PrioritySlots() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.prioritySlots
+}
+
+// Prioritize moves a remote transaction into the priority lane.
+func (t *txLookup) Prioritize(hash common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tx, ok := t.remotes[hash]
+	if !ok {
+		return
+	}
+	if _, ok := t.priority[hash]; !ok {
+		t.priority[hash] = struct{}{}
+		t.prioritySlots += numSlots(tx)
+	}
+}
+
+// IsPriority returns whether the transaction is in the priority lane.
+func (t *txLookup) IsPriority(hash common.Hash) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	_, ok := t.priority[hash]
+	return ok
+}
+
+// PriorityTxs returns the transactions of the priority lane.
+func (t *txLookup) PriorityTxs() types.Transactions {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	txs := make(types.Transactions, 0, len(t.priority))
+	for hash := range t.priority {
+		txs = append(txs, t.remotes[hash])
+	}
+	return txs
+}
+
 // Add adds a transaction to the lookup.
 func (t *txLookup) Add(tx *types.Transaction, local bool) {
 	t.lock.Lock()
@@ -1765,6 +1864,7 @@ func (t *txLookup) Add(tx *types.Transaction, local bool) {
 
 	if local {
 		t.locals[tx.Hash()] = tx
+		t.localSlots += numSlots(tx)
 	} else {
 		t.remotes[tx.Hash()] = tx
 	}
@@ -1786,8 +1886,15 @@ func (t *txLookup) Remove(hash common.Hash) {
 	t.slots -= numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 
+	if _, ok := t.locals[hash]; ok {
+		t.localSlots -= numSlots(tx)
+	}
+	if _, ok := t.priority[hash]; ok {
+		t.prioritySlots -= numSlots(tx)
+	}
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
+	delete(t.priority, hash)
 }
 
 // RemoteToLocals migrates the transactions belongs to the given locals to locals
@@ -1800,6 +1907,11 @@ func (t *txLookup) RemoteToLocals(locals *accountSet) int {
 	for hash, tx := range t.remotes {
 		if locals.containsTx(tx) {
 			t.locals[hash] = tx
+			t.localSlots += numSlots(tx)
+			if _, ok := t.priority[hash]; ok {
+				t.prioritySlots -= numSlots(tx)
+				delete(t.priority, hash)
+			}
 			delete(t.remotes, hash)
 			migrated += 1
 		}
@@ -0,0 +1,132 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// txPoolDumpVersion is the version of the transaction pool dump format.
+const txPoolDumpVersion = 1
+
+// txPoolDump is the serialized content of a transaction pool.
+type txPoolDump struct {
+	Version uint
+	Txs     []txPoolDumpTx
+	Beats   []txPoolDumpBeat
+}
+
+// txPoolDumpTx is a pool transaction along with its pool state.
+type txPoolDumpTx struct {
+	Tx     *types.Transaction
+	Local  bool
+	Queued bool
+	Time   uint64 // Arrival time in unix nanoseconds
+}
+
+// txPoolDumpBeat is the last heartbeat of an account with queued transactions.
+type txPoolDumpBeat struct {
+	Addr common.Address
+	Time uint64 // Heartbeat time in unix nanoseconds
+}
+
+// TxPoolLoadStats contains the outcome of restoring a transaction pool dump.
+type TxPoolLoadStats struct {
+	Loaded  int `json:"loaded"`  // Number of transactions added to the pool
+	Dropped int `json:"dropped"` // Number of transactions rejected by the pool
+}
+
+// Dump serializes the entire content of the pool, including whether transactions
+// are queued or pending, their arrival times and the queue heartbeats, into w.
+func (pool *TxPool) Dump(w io.Writer) (int, error) {
+	pool.mu.RLock()
+	dump := txPoolDump{Version: txPoolDumpVersion}
+	collect := func(lists map[common.Address]*txList, queued bool) {
+		for addr, list := range lists {
+			local := pool.locals.contains(addr)
+			for _, tx := range list.Flatten() {
+				dump.Txs = append(dump.Txs, txPoolDumpTx{
+					Tx:     tx,
+					Local:  local,
+					Queued: queued,
+					Time:   uint64(tx.Time().UnixNano()),
+				})
+			}
+		}
+	}
+	collect(pool.pending, false)
+	collect(pool.queue, true)
+	for addr, beat := range pool.beats {
+		dump.Beats = append(dump.Beats, txPoolDumpBeat{Addr: addr, Time: uint64(beat.UnixNano())})
+	}
+	pool.mu.RUnlock()
+
+	return len(dump.Txs), rlp.Encode(w, &dump)
+}
+
+// Load restores a pool dump created by Dump, adding its transactions with their
+// original arrival times and locality. Whether a transaction ends up pending or
+// queued is decided by the state of the local chain, so the original split is
+// only reproduced if both nodes are on the same head.
+func (pool *TxPool) Load(r io.Reader) (*TxPoolLoadStats, error) {
+	var dump txPoolDump
+	if err := rlp.Decode(r, &dump); err != nil {
+		return nil, err
+	}
+	if dump.Version != txPoolDumpVersion {
+		return nil, fmt.Errorf("unsupported pool dump version %d", dump.Version)
+	}
+	var locals, remotes []*types.Transaction
+	for _, entry := range dump.Txs {
+		entry.Tx.SetTime(time.Unix(0, int64(entry.Time)))
+		if entry.Local {
+			locals = append(locals, entry.Tx)
+		} else {
+			remotes = append(remotes, entry.Tx)
+		}
+	}
+	stats := new(TxPoolLoadStats)
+	count := func(errs []error) {
+		for _, err := range errs {
+			if err != nil {
+				stats.Dropped++
+			} else {
+				stats.Loaded++
+			}
+		}
+	}
+	count(pool.addTxs(locals, !pool.config.NoLocals, true))
+	count(pool.addTxs(remotes, false, true))
+
+	// Restore the heartbeats of accounts still known by the pool, so queued
+	// transactions expire as they would have on the originating node.
+	pool.mu.Lock()
+	for _, beat := range dump.Beats {
+		if _, ok := pool.beats[beat.Addr]; ok {
+			pool.beats[beat.Addr] = time.Unix(0, int64(beat.Time))
+		}
+	}
+	pool.mu.Unlock()
+
+	return stats, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that a pool dump restores the same pending and queued transactions with
+// their arrival times into another pool.
+func TestTxPoolDumpLoad(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+	remote, _ := crypto.GenerateKey()
+
+	addr, remoteAddr := crypto.PubkeyToAddress(key.PublicKey), crypto.PubkeyToAddress(remote.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+	testAddBalance(pool, remoteAddr, big.NewInt(1000000000))
+
+	if err := pool.AddLocal(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddLocal(transaction(2, 100000, key)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	arrival := time.Unix(1000, 0)
+	tx := transaction(0, 100000, remote)
+	tx.SetTime(arrival)
+	if err := pool.addRemoteSync(tx); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	var buf bytes.Buffer
+	if n, err := pool.Dump(&buf); err != nil || n != 3 {
+		t.Fatalf("failed to dump pool: %d, %v", n, err)
+	}
+	restored, _ := setupTxPool()
+	defer restored.Stop()
+	testAddBalance(restored, addr, big.NewInt(1000000000))
+	testAddBalance(restored, remoteAddr, big.NewInt(1000000000))
+
+	stats, err := restored.Load(&buf)
+	if err != nil {
+		t.Fatalf("failed to load pool: %v", err)
+	}
+	if stats.Loaded != 3 || stats.Dropped != 0 {
+		t.Fatalf("load stats mismatch: have %+v, want 3 loaded", stats)
+	}
+	if pending, queued := restored.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool size mismatch: have %d/%d, want 2/1", pending, queued)
+	}
+	if locals := restored.Locals(); len(locals) != 1 || locals[0] != addr {
+		t.Errorf("locals mismatch: have %v, want %v", locals, addr)
+	}
+	if have := restored.Get(tx.Hash()).Time(); !have.Equal(arrival) {
+		t.Errorf("arrival time mismatch: have %v, want %v", have, arrival)
+	}
+	if err := validateTxPoolInternals(restored); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
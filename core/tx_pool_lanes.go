@@ -0,0 +1,178 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// txLane is a capacity bounded partition of the transaction pool. Transactions
+// only compete for room with the other transactions of their lane, so a flood
+// of gossiped transactions can't crowd out local or high tipping ones.
+type txLane int
+
+const (
+	remoteLane   txLane = iota // Gossiped transactions (and local ones without a local lane), bounded by the global limits
+	localLane                  // Local transactions, never evicted
+	priorityLane               // Gossiped transactions paying at least the priority tip, evicted by lowest tip
+)
+
+func (lane txLane) String() string {
+	switch lane {
+	case localLane:
+		return "local"
+	case priorityLane:
+		return "priority"
+	default:
+		return "remote"
+	}
+}
+
+// TxLaneStats is the slot usage of a lane of the transaction pool.
+type TxLaneStats struct {
+	Slots int // Number of slots used by the transactions of the lane
+	Limit int // Maximum number of slots of the lane
+}
+
+// LaneStats retrieves the slot usage of the lanes of the pool, keyed by the lane
+// name. Dedicated lanes are only included if they are enabled.
+func (pool *TxPool) LaneStats() map[string]TxLaneStats {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	stats := map[string]TxLaneStats{
+		remoteLane.String(): {
+			Slots: pool.remoteSlots(),
+			Limit: int(pool.config.GlobalSlots + pool.config.GlobalQueue),
+		},
+	}
+	if pool.config.LocalSlots > 0 {
+		stats[localLane.String()] = TxLaneStats{Slots: pool.all.LocalSlots(), Limit: int(pool.config.LocalSlots)}
+	}
+	if pool.config.PrioritySlots > 0 {
+		stats[priorityLane.String()] = TxLaneStats{Slots: pool.all.PrioritySlots(), Limit: int(pool.config.PrioritySlots)}
+	}
+	return stats
+}
+
+// laneOf returns the lane a new transaction is admitted to.
+func (pool *TxPool) laneOf(tx *types.Transaction, local bool) txLane {
+	switch {
+	case local && pool.config.LocalSlots > 0:
+		return localLane
+	case !local && pool.config.PrioritySlots > 0 && tx.GasTipCapIntCmp(new(big.Int).SetUint64(pool.config.PriorityTip)) >= 0:
+		return priorityLane
+	default:
+		return remoteLane
+	}
+}
+
+// remoteSlots returns the number of slots used by the transactions which are
+// not in a dedicated lane and count towards the global limits.
+func (pool *TxPool) remoteSlots() int {
+	slots := pool.all.Slots() - pool.all.PrioritySlots()
+	if pool.config.LocalSlots > 0 {
+		slots -= pool.all.LocalSlots()
+	}
+	return slots
+}
+
+// inLane reports whether a transaction is held in a dedicated lane, exempting it
+// from the global limits.
+func (pool *TxPool) inLane(hash common.Hash) bool {
+	if pool.all.IsPriority(hash) {
+		return true
+	}
+	return pool.config.LocalSlots > 0 && pool.all.GetLocal(hash) != nil
+}
+
+// lanelessLen returns the number of transactions of a list which count towards
+// the global limits.
+func (pool *TxPool) lanelessLen(list *txList) int {
+	if pool.config.LocalSlots == 0 && pool.config.PrioritySlots == 0 {
+		return list.Len()
+	}
+	var count int
+	for _, tx := range list.Flatten() {
+		if !pool.inLane(tx.Hash()) {
+			count++
+		}
+	}
+	return count
+}
+
+// evictable reports whether the highest nonce transaction of a list may be
+// dropped when truncating the pool, which it can't if held in a dedicated lane.
+func (pool *TxPool) evictable(list *txList) bool {
+	last := list.LastElement()
+	return last != nil && !pool.inLane(last.Hash())
+}
+
+// makeLaneRoom ensures there is room for a new transaction in its dedicated lane
+// and returns the lane the transaction is to be added to. A full local lane
+// rejects the transaction. A full priority lane evicts its lowest tipping
+// transactions if the new one tips more, otherwise the transaction is admitted
+// to the remote lane instead.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) makeLaneRoom(tx *types.Transaction, lane txLane) (txLane, error) {
+	switch lane {
+	case localLane:
+		if uint64(pool.all.LocalSlots()+numSlots(tx)) > pool.config.LocalSlots {
+			log.Trace("Discarding transaction of full lane", "hash", tx.Hash(), "lane", lane)
+			overflowedTxMeter.Mark(1)
+			return lane, ErrTxPoolLaneFull
+		}
+		return lane, nil
+
+	case priorityLane:
+		need := pool.all.PrioritySlots() + numSlots(tx) - int(pool.config.PrioritySlots)
+		if need <= 0 {
+			return lane, nil
+		}
+		candidates := pool.all.PriorityTxs()
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].GasTipCapCmp(candidates[j]) < 0
+		})
+		var drop types.Transactions
+		for _, victim := range candidates {
+			if need <= 0 || victim.GasTipCapCmp(tx) >= 0 {
+				break
+			}
+			drop = append(drop, victim)
+			need -= numSlots(victim)
+		}
+		if need > 0 {
+			return remoteLane, nil
+		}
+		pool.changesSinceReorg += len(drop)
+		for _, victim := range drop {
+			log.Trace("Discarding outbid priority transaction", "hash", victim.Hash(), "gasTipCap", victim.GasTipCap())
+			underpricedTxMeter.Mark(1)
+			pool.removeTx(victim.Hash(), true)
+		}
+		return lane, nil
+
+	default:
+		return lane, nil
+	}
+}
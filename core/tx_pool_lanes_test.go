@@ -0,0 +1,138 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that local and high tipping transactions are admitted to their own lanes
+// and only compete for room with the transactions of the same lane.
+func TestTransactionPoolLanes(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupTxPoolWithConfig(eip1559Config)
+	defer pool.Stop()
+
+	pool.config.GlobalSlots = 4
+	pool.config.GlobalQueue = 0
+	pool.config.LocalSlots = 1
+	pool.config.PrioritySlots = 2
+	pool.config.PriorityTip = 10
+
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	// Fill up the remote lane with cheap gossiped transactions
+	for nonce := uint64(0); nonce < 4; nonce++ {
+		if err := pool.AddRemote(dynamicFeeTx(nonce, 100000, big.NewInt(1), big.NewInt(1), keys[0])); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	// Local transactions should not evict remote ones, but be bounded by their lane
+	if err := pool.AddLocal(dynamicFeeTx(0, 100000, big.NewInt(1), big.NewInt(1), keys[1])); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddLocal(dynamicFeeTx(1, 100000, big.NewInt(1), big.NewInt(1), keys[1])); err != ErrTxPoolLaneFull {
+		t.Fatalf("local lane overflow error mismatch: have %v, want %v", err, ErrTxPoolLaneFull)
+	}
+	// High tipping transactions should fill the priority lane, evicting by tip
+	low := dynamicFeeTx(0, 100000, big.NewInt(10), big.NewInt(10), keys[2])
+	high := dynamicFeeTx(0, 100000, big.NewInt(20), big.NewInt(20), keys[3])
+	for _, tx := range []*types.Transaction{low, high} {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add priority transaction: %v", err)
+		}
+	}
+	if err := pool.AddRemote(dynamicFeeTx(0, 100000, big.NewInt(15), big.NewInt(15), keys[4])); err != nil {
+		t.Fatalf("failed to add outbidding priority transaction: %v", err)
+	}
+	if pool.Has(low.Hash()) {
+		t.Errorf("lowest tipping priority transaction not evicted")
+	}
+	// Priority transactions not outbidding the lane should compete in the remote lane
+	if err := pool.AddRemote(dynamicFeeTx(0, 100000, big.NewInt(12), big.NewInt(12), keys[2])); err != nil {
+		t.Fatalf("failed to add priority transaction to remote lane: %v", err)
+	}
+	if !pool.Has(high.Hash()) {
+		t.Errorf("priority transaction evicted by remote lane")
+	}
+	want := map[string]TxLaneStats{
+		"remote":   {Slots: 4, Limit: 4},
+		"local":    {Slots: 1, Limit: 1},
+		"priority": {Slots: 2, Limit: 2},
+	}
+	stats := pool.LaneStats()
+	for name, lane := range want {
+		if stats[name] != lane {
+			t.Errorf("lane %s stats mismatch: have %+v, want %+v", name, stats[name], lane)
+		}
+	}
+	if count := pool.all.Count(); count != 7 {
+		t.Errorf("transaction count mismatch: have %d, want %d", count, 7)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that transactions of the dedicated lanes neither count towards the global
+// limits nor get evicted when the pool is truncated.
+func TestTransactionPoolLanesTruncate(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupTxPoolWithConfig(eip1559Config)
+	defer pool.Stop()
+
+	pool.config.GlobalSlots = 2
+	pool.config.GlobalQueue = 10
+	pool.config.AccountSlots = 1
+	pool.config.PrioritySlots = 4
+	pool.config.PriorityTip = 10
+
+	keys := make([]*ecdsa.PrivateKey, 2)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	for nonce := uint64(0); nonce < 4; nonce++ {
+		if err := pool.AddRemotesSync([]*types.Transaction{dynamicFeeTx(nonce, 100000, big.NewInt(20), big.NewInt(20), keys[0])})[0]; err != nil {
+			t.Fatalf("failed to add priority transaction %d: %v", nonce, err)
+		}
+	}
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		if err := pool.AddRemotesSync([]*types.Transaction{dynamicFeeTx(nonce, 100000, big.NewInt(1), big.NewInt(1), keys[1])})[0]; err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	if have := pool.pending[crypto.PubkeyToAddress(keys[0].PublicKey)].Len(); have != 4 {
+		t.Errorf("priority transactions truncated: have %d, want %d", have, 4)
+	}
+	if have := pool.pending[crypto.PubkeyToAddress(keys[1].PublicKey)].Len(); have != 2 {
+		t.Errorf("remote transactions not truncated: have %d, want %d", have, 2)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
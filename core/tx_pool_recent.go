@@ -0,0 +1,106 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// recentTxs tracks the transactions included in the most recent blocks, so that
+// the pool can drop them without validation if they arrive late via gossip.
+type recentTxs struct {
+	limit  uint64                   // Number of blocks to track (0 = disabled)
+	lock   sync.RWMutex             // Protects the sets, checked without the pool lock
+	txs    map[common.Hash]uint64   // Transaction hash -> number of the including block
+	blocks map[uint64][]common.Hash // Block number -> hashes of the included transactions
+}
+
+func newRecentTxs(limit uint64) *recentTxs {
+	return &recentTxs{
+		limit:  limit,
+		txs:    make(map[common.Hash]uint64),
+		blocks: make(map[uint64][]common.Hash),
+	}
+}
+
+// contains reports whether the transaction was included in a recent block.
+func (r *recentTxs) contains(hash common.Hash) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	_, ok := r.txs[hash]
+	return ok
+}
+
+// add records the transactions included in the block with the given number and
+// expires the blocks which are no longer recent.
+func (r *recentTxs) add(number uint64, txs types.Transactions) {
+	if r.limit == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, tx := range txs {
+		hash := tx.Hash()
+		r.txs[hash] = number
+		r.blocks[number] = append(r.blocks[number], hash)
+	}
+	for n := range r.blocks {
+		if n+r.limit <= number {
+			r.drop(n)
+		}
+	}
+}
+
+// forget removes transactions which are no longer included, e.g. because their
+// block was reorged out of the chain.
+func (r *recentTxs) forget(txs types.Transactions) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, tx := range txs {
+		delete(r.txs, tx.Hash())
+	}
+}
+
+// truncate removes the transactions of all blocks above the given number, e.g.
+// after the chain head was rewound.
+func (r *recentTxs) truncate(number uint64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for n := range r.blocks {
+		if n > number {
+			r.drop(n)
+		}
+	}
+}
+
+// drop removes the transactions of a block, unless they were included again in
+// another block since. The caller must hold the lock.
+func (r *recentTxs) drop(number uint64) {
+	for _, hash := range r.blocks[number] {
+		if r.txs[hash] == number {
+			delete(r.txs, hash)
+		}
+	}
+	delete(r.blocks, number)
+}
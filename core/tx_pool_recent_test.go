@@ -0,0 +1,70 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that transactions included in recent blocks are dropped without being
+// validated, and accepted again once they were reorged out or became stale.
+func TestTransactionPoolRecentlyIncluded(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	pool.recent.limit = 2
+
+	var (
+		tx0 = transaction(0, 100000, key)
+		tx1 = transaction(1, 100000, key)
+	)
+	pool.recent.add(1, types.Transactions{tx0})
+	pool.recent.add(2, types.Transactions{tx1})
+
+	if err := pool.AddRemote(tx0); err != ErrAlreadyIncluded {
+		t.Fatalf("included transaction error mismatch: have %v, want %v", err, ErrAlreadyIncluded)
+	}
+	// Reorged out transactions should be accepted again
+	pool.recent.forget(types.Transactions{tx0})
+	if err := pool.AddRemote(tx0); err != nil {
+		t.Fatalf("failed to add reorged transaction: %v", err)
+	}
+	// Transactions should expire once their block is no longer recent
+	if err := pool.AddRemote(tx1); err != ErrAlreadyIncluded {
+		t.Fatalf("included transaction error mismatch: have %v, want %v", err, ErrAlreadyIncluded)
+	}
+	pool.recent.add(4, nil)
+	if pool.recent.contains(tx1.Hash()) {
+		t.Fatalf("stale transaction still tracked")
+	}
+	if len(pool.recent.blocks) != 0 {
+		t.Fatalf("stale blocks still tracked: %d", len(pool.recent.blocks))
+	}
+	// Rewinding the chain should drop the transactions of the removed blocks
+	pool.recent.add(5, types.Transactions{tx1})
+	pool.recent.truncate(4)
+	if pool.recent.contains(tx1.Hash()) {
+		t.Fatalf("rewound transaction still tracked")
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// InternalTx is a value transfer made by a message call, contract creation or
+// self-destruct nested within a transaction, as recorded by the internal
+// transaction index. Transfers of reverted call frames are not included.
+type InternalTx struct {
+	Type  string         // Opcode of the transfer: CALL, CREATE, CREATE2 or SELFDESTRUCT
+	From  common.Address // Account sending the value
+	To    common.Address // Account receiving the value
+	Value *big.Int       // Amount of wei transferred
+
+	// Derived fields, filled in by the node.
+	BlockNumber uint64      // Number of the block containing the transaction
+	BlockHash   common.Hash // Hash of the block containing the transaction
+	TxHash      common.Hash // Hash of the enclosing transaction
+	TxIndex     uint        // Index of the enclosing transaction in the block
+	Index       uint        // Index of the transfer within the transaction
+}
@@ -0,0 +1,310 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrRequestTypeNotSupported = errors.New("request type not supported")
+	errShortTypedRequest       = errors.New("typed request too short")
+	errEmptyRequestList        = errors.New("empty request list")
+	errRequestListOrder        = errors.New("request lists not in ascending type order")
+)
+
+// EmptyRequestsHash is the requests hash of a block without any requests.
+var EmptyRequestsHash = CalcRequestsHash(nil)
+
+// Request types of EIP-7685.
+const (
+	DepositRequestType       = 0x00 // EIP-6110
+	WithdrawalRequestType    = 0x01 // EIP-7002
+	ConsolidationRequestType = 0x02 // EIP-7251
+)
+
+// Sizes of the flat encodings of the request types, without the type byte.
+const (
+	DepositRequestSize       = 48 + 32 + 8 + 96 + 8
+	WithdrawalRequestSize    = 20 + 48 + 8
+	ConsolidationRequestSize = 20 + 48 + 48
+)
+
+// Request is a request triggered on the execution layer and processed by the
+// consensus layer (EIP-7685), such as a validator deposit or exit. Requests are
+// not part of the block, only their hash is committed to in the header since
+// Prague. They are handed to the consensus layer through the engine API.
+type Request struct {
+	inner RequestData
+}
+
+// NewRequest creates a new request.
+func NewRequest(inner RequestData) *Request {
+	return &Request{inner: inner.copy()}
+}
+
+// RequestData is the underlying data of a request.
+//
+// This is implemented by Deposit, WithdrawalRequest and ConsolidationRequest.
+type RequestData interface {
+	requestType() byte   // returns the type ID
+	copy() RequestData   // creates a deep copy
+	encode() []byte      // encodes the fields as a flat byte string
+	decode([]byte) error // decodes the flat encoding of the fields
+}
+
+// Type returns the request type.
+func (r *Request) Type() uint8 {
+	return r.inner.requestType()
+}
+
+// Inner returns a copy of the underlying data of the request.
+func (r *Request) Inner() RequestData {
+	return r.inner.copy()
+}
+
+// MarshalBinary returns the canonical encoding of the request: the type byte
+// followed by the flat encoding of the request fields.
+func (r *Request) MarshalBinary() ([]byte, error) {
+	return append([]byte{r.Type()}, r.inner.encode()...), nil
+}
+
+// UnmarshalBinary decodes the canonical encoding of a request.
+func (r *Request) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 {
+		return errShortTypedRequest
+	}
+	inner, err := newRequestData(b[0])
+	if err != nil {
+		return err
+	}
+	if err := inner.decode(b[1:]); err != nil {
+		return err
+	}
+	r.inner = inner
+	return nil
+}
+
+// newRequestData returns an empty request of the given type.
+func newRequestData(typ byte) (RequestData, error) {
+	switch typ {
+	case DepositRequestType:
+		return new(Deposit), nil
+	case WithdrawalRequestType:
+		return new(WithdrawalRequest), nil
+	case ConsolidationRequestType:
+		return new(ConsolidationRequest), nil
+	default:
+		return nil, ErrRequestTypeNotSupported
+	}
+}
+
+// requestSize returns the size of the flat encoding of a request type.
+func requestSize(typ byte) int {
+	switch typ {
+	case DepositRequestType:
+		return DepositRequestSize
+	case WithdrawalRequestType:
+		return WithdrawalRequestSize
+	default:
+		return ConsolidationRequestSize
+	}
+}
+
+// Requests is a list of requests, ordered by request type.
+type Requests []*Request
+
+// Encode returns the execution requests of the list as defined by EIP-7685:
+// one entry per request type, consisting of the type byte followed by the
+// concatenated flat encodings of all requests of that type. Types without any
+// requests are left out, the entries are in ascending type order.
+func (s Requests) Encode() [][]byte {
+	var lists [][]byte
+	for _, typ := range []byte{DepositRequestType, WithdrawalRequestType, ConsolidationRequestType} {
+		var list []byte
+		for _, r := range s {
+			if r.Type() != typ {
+				continue
+			}
+			if list == nil {
+				list = []byte{typ}
+			}
+			list = append(list, r.inner.encode()...)
+		}
+		if list != nil {
+			lists = append(lists, list)
+		}
+	}
+	return lists
+}
+
+// DecodeRequests decodes the execution requests produced by Requests.Encode,
+// verifying that every entry is non-empty and that the entries are in strictly
+// ascending type order.
+func DecodeRequests(lists [][]byte) (Requests, error) {
+	var requests Requests
+	for i, list := range lists {
+		if len(list) < 2 {
+			return nil, errEmptyRequestList
+		}
+		if i > 0 && list[0] <= lists[i-1][0] {
+			return nil, errRequestListOrder
+		}
+		if _, err := newRequestData(list[0]); err != nil {
+			return nil, err
+		}
+		size := requestSize(list[0])
+		if (len(list)-1)%size != 0 {
+			return nil, fmt.Errorf("invalid request list size %d for type %d", len(list)-1, list[0])
+		}
+		for data := list[1:]; len(data) > 0; data = data[size:] {
+			inner, _ := newRequestData(list[0])
+			if err := inner.decode(data[:size]); err != nil {
+				return nil, err
+			}
+			requests = append(requests, &Request{inner: inner})
+		}
+	}
+	return requests, nil
+}
+
+// CalcRequestsHash computes the requests hash committed to in the header from
+// the encoded execution requests:
+//
+//	sha256(sha256(requests_0) ++ sha256(requests_1) ++ ...)
+func CalcRequestsHash(lists [][]byte) common.Hash {
+	outer := sha256.New()
+	for _, list := range lists {
+		inner := sha256.Sum256(list)
+		outer.Write(inner[:])
+	}
+	return common.BytesToHash(outer.Sum(nil))
+}
+
+// Hash returns the requests hash of the list.
+func (s Requests) Hash() common.Hash {
+	return CalcRequestsHash(s.Encode())
+}
+
+// Deposit is a validator deposit made through the deposit contract (EIP-6110).
+type Deposit struct {
+	PublicKey             [48]byte
+	WithdrawalCredentials common.Hash
+	Amount                uint64 // Amount in gwei
+	Signature             [96]byte
+	Index                 uint64
+}
+
+func (d *Deposit) requestType() byte { return DepositRequestType }
+
+func (d *Deposit) copy() RequestData {
+	cpy := *d
+	return &cpy
+}
+
+func (d *Deposit) encode() []byte {
+	b := make([]byte, DepositRequestSize)
+	copy(b, d.PublicKey[:])
+	copy(b[48:], d.WithdrawalCredentials[:])
+	binary.LittleEndian.PutUint64(b[80:], d.Amount)
+	copy(b[88:], d.Signature[:])
+	binary.LittleEndian.PutUint64(b[184:], d.Index)
+	return b
+}
+
+func (d *Deposit) decode(b []byte) error {
+	if len(b) != DepositRequestSize {
+		return fmt.Errorf("invalid deposit request size %d, want %d", len(b), DepositRequestSize)
+	}
+	copy(d.PublicKey[:], b[:48])
+	copy(d.WithdrawalCredentials[:], b[48:80])
+	d.Amount = binary.LittleEndian.Uint64(b[80:88])
+	copy(d.Signature[:], b[88:184])
+	d.Index = binary.LittleEndian.Uint64(b[184:])
+	return nil
+}
+
+// WithdrawalRequest is a request to exit a validator or to withdraw part of its
+// balance, made through the withdrawal request contract (EIP-7002).
+type WithdrawalRequest struct {
+	SourceAddress   common.Address
+	ValidatorPubkey [48]byte
+	Amount          uint64 // Amount in gwei, zero for a full exit
+}
+
+func (w *WithdrawalRequest) requestType() byte { return WithdrawalRequestType }
+
+func (w *WithdrawalRequest) copy() RequestData {
+	cpy := *w
+	return &cpy
+}
+
+func (w *WithdrawalRequest) encode() []byte {
+	b := make([]byte, WithdrawalRequestSize)
+	copy(b, w.SourceAddress[:])
+	copy(b[20:], w.ValidatorPubkey[:])
+	binary.BigEndian.PutUint64(b[68:], w.Amount)
+	return b
+}
+
+func (w *WithdrawalRequest) decode(b []byte) error {
+	if len(b) != WithdrawalRequestSize {
+		return fmt.Errorf("invalid withdrawal request size %d, want %d", len(b), WithdrawalRequestSize)
+	}
+	copy(w.SourceAddress[:], b[:20])
+	copy(w.ValidatorPubkey[:], b[20:68])
+	w.Amount = binary.BigEndian.Uint64(b[68:])
+	return nil
+}
+
+// ConsolidationRequest is a request to merge the balance of a validator into
+// another one, made through the consolidation request contract (EIP-7251).
+type ConsolidationRequest struct {
+	SourceAddress common.Address
+	SourcePubkey  [48]byte
+	TargetPubkey  [48]byte
+}
+
+func (c *ConsolidationRequest) requestType() byte { return ConsolidationRequestType }
+
+func (c *ConsolidationRequest) copy() RequestData {
+	cpy := *c
+	return &cpy
+}
+
+func (c *ConsolidationRequest) encode() []byte {
+	b := make([]byte, ConsolidationRequestSize)
+	copy(b, c.SourceAddress[:])
+	copy(b[20:], c.SourcePubkey[:])
+	copy(b[68:], c.TargetPubkey[:])
+	return b
+}
+
+func (c *ConsolidationRequest) decode(b []byte) error {
+	if len(b) != ConsolidationRequestSize {
+		return fmt.Errorf("invalid consolidation request size %d, want %d", len(b), ConsolidationRequestSize)
+	}
+	copy(c.SourceAddress[:], b[:20])
+	copy(c.SourcePubkey[:], b[20:68])
+	copy(c.TargetPubkey[:], b[68:])
+	return nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// requestJSON is the JSON representation of requests.
+type requestJSON struct {
+	Type hexutil.Uint64 `json:"type"`
+
+	// Deposit fields:
+	Pubkey                *hexutil.Bytes  `json:"pubkey,omitempty"`
+	WithdrawalCredentials *common.Hash    `json:"withdrawalCredentials,omitempty"`
+	Signature             *hexutil.Bytes  `json:"signature,omitempty"`
+	Index                 *hexutil.Uint64 `json:"index,omitempty"`
+
+	// Withdrawal and consolidation request fields:
+	SourceAddress   *common.Address `json:"sourceAddress,omitempty"`
+	ValidatorPubkey *hexutil.Bytes  `json:"validatorPubkey,omitempty"`
+	SourcePubkey    *hexutil.Bytes  `json:"sourcePubkey,omitempty"`
+	TargetPubkey    *hexutil.Bytes  `json:"targetPubkey,omitempty"`
+
+	// Deposit and withdrawal request fields:
+	Amount *hexutil.Uint64 `json:"amount,omitempty"`
+}
+
+// MarshalJSON marshals as JSON.
+func (r *Request) MarshalJSON() ([]byte, error) {
+	enc := requestJSON{Type: hexutil.Uint64(r.Type())}
+
+	switch req := r.inner.(type) {
+	case *Deposit:
+		enc.Pubkey = fixedBytes(req.PublicKey[:])
+		enc.WithdrawalCredentials = &req.WithdrawalCredentials
+		enc.Amount = (*hexutil.Uint64)(&req.Amount)
+		enc.Signature = fixedBytes(req.Signature[:])
+		enc.Index = (*hexutil.Uint64)(&req.Index)
+	case *WithdrawalRequest:
+		enc.SourceAddress = &req.SourceAddress
+		enc.ValidatorPubkey = fixedBytes(req.ValidatorPubkey[:])
+		enc.Amount = (*hexutil.Uint64)(&req.Amount)
+	case *ConsolidationRequest:
+		enc.SourceAddress = &req.SourceAddress
+		enc.SourcePubkey = fixedBytes(req.SourcePubkey[:])
+		enc.TargetPubkey = fixedBytes(req.TargetPubkey[:])
+	}
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON unmarshals from JSON.
+func (r *Request) UnmarshalJSON(input []byte) error {
+	var dec requestJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	// Decode / verify fields according to request type.
+	var inner RequestData
+	switch dec.Type {
+	case DepositRequestType:
+		var req Deposit
+		inner = &req
+		if err := decodeFixedBytes("pubkey", dec.Pubkey, req.PublicKey[:]); err != nil {
+			return err
+		}
+		if dec.WithdrawalCredentials == nil {
+			return missingRequestField("withdrawalCredentials")
+		}
+		req.WithdrawalCredentials = *dec.WithdrawalCredentials
+		if dec.Amount == nil {
+			return missingRequestField("amount")
+		}
+		req.Amount = uint64(*dec.Amount)
+		if err := decodeFixedBytes("signature", dec.Signature, req.Signature[:]); err != nil {
+			return err
+		}
+		if dec.Index == nil {
+			return missingRequestField("index")
+		}
+		req.Index = uint64(*dec.Index)
+
+	case WithdrawalRequestType:
+		var req WithdrawalRequest
+		inner = &req
+		if dec.SourceAddress == nil {
+			return missingRequestField("sourceAddress")
+		}
+		req.SourceAddress = *dec.SourceAddress
+		if err := decodeFixedBytes("validatorPubkey", dec.ValidatorPubkey, req.ValidatorPubkey[:]); err != nil {
+			return err
+		}
+		if dec.Amount == nil {
+			return missingRequestField("amount")
+		}
+		req.Amount = uint64(*dec.Amount)
+
+	case ConsolidationRequestType:
+		var req ConsolidationRequest
+		inner = &req
+		if dec.SourceAddress == nil {
+			return missingRequestField("sourceAddress")
+		}
+		req.SourceAddress = *dec.SourceAddress
+		if err := decodeFixedBytes("sourcePubkey", dec.SourcePubkey, req.SourcePubkey[:]); err != nil {
+			return err
+		}
+		if err := decodeFixedBytes("targetPubkey", dec.TargetPubkey, req.TargetPubkey[:]); err != nil {
+			return err
+		}
+
+	default:
+		return ErrRequestTypeNotSupported
+	}
+	r.inner = inner
+	return nil
+}
+
+// fixedBytes converts a fixed size byte field for encoding.
+func fixedBytes(b []byte) *hexutil.Bytes {
+	return (*hexutil.Bytes)(&b)
+}
+
+func missingRequestField(field string) error {
+	return fmt.Errorf("missing required field '%s' in request", field)
+}
+
+// decodeFixedBytes copies a required byte field of the given size into out.
+func decodeFixedBytes(field string, b *hexutil.Bytes, out []byte) error {
+	if b == nil {
+		return missingRequestField(field)
+	}
+	if len(*b) != len(out) {
+		return fmt.Errorf("invalid length of field '%s' in request: have %d, want %d", field, len(*b), len(out))
+	}
+	copy(out, *b)
+	return nil
+}
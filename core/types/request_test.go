@@ -0,0 +1,206 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var testRequests = Requests{
+	NewRequest(&Deposit{
+		PublicKey:             [48]byte{0x01, 0x02},
+		WithdrawalCredentials: common.HexToHash("0x0100000000000000000000000000000000000000000000000000000000001234"),
+		Amount:                32_000_000_000,
+		Signature:             [96]byte{0x03, 0x04},
+		Index:                 7,
+	}),
+	NewRequest(&WithdrawalRequest{
+		SourceAddress:   common.HexToAddress("0x1000000000000000000000000000000000000001"),
+		ValidatorPubkey: [48]byte{0x05},
+		Amount:          1_000_000_000,
+	}),
+	NewRequest(&ConsolidationRequest{
+		SourceAddress: common.HexToAddress("0x2000000000000000000000000000000000000002"),
+		SourcePubkey:  [48]byte{0x06},
+		TargetPubkey:  [48]byte{0x07},
+	}),
+}
+
+func TestRequestEncoding(t *testing.T) {
+	sizes := []int{DepositRequestSize, WithdrawalRequestSize, ConsolidationRequestSize}
+	for i, req := range testRequests {
+		blob, err := req.MarshalBinary()
+		if err != nil {
+			t.Fatalf("request %d: marshal error: %v", i, err)
+		}
+		if len(blob) != 1+sizes[i] || blob[0] != req.Type() {
+			t.Fatalf("request %d: wrong encoding %x", i, blob)
+		}
+		dec := new(Request)
+		if err := dec.UnmarshalBinary(blob); err != nil {
+			t.Fatalf("request %d: unmarshal error: %v", i, err)
+		}
+		if !reflect.DeepEqual(dec, req) {
+			t.Fatalf("request %d: binary round trip mismatch: have %v, want %v", i, dec.Inner(), req.Inner())
+		}
+		// Truncated and unknown requests must be rejected
+		if err := new(Request).UnmarshalBinary(blob[:len(blob)-1]); err == nil {
+			t.Errorf("request %d: no error for truncated encoding", i)
+		}
+	}
+	if err := new(Request).UnmarshalBinary([]byte{0x7f}); !errors.Is(err, ErrRequestTypeNotSupported) {
+		t.Errorf("wrong error for unknown request type: %v", err)
+	}
+}
+
+func TestRequestJSON(t *testing.T) {
+	for i, req := range testRequests {
+		enc, err := json.Marshal(req)
+		if err != nil {
+			t.Fatalf("request %d: marshal error: %v", i, err)
+		}
+		dec := new(Request)
+		if err := json.Unmarshal(enc, dec); err != nil {
+			t.Fatalf("request %d: unmarshal error: %v", i, err)
+		}
+		if !reflect.DeepEqual(dec, req) {
+			t.Fatalf("request %d: JSON round trip mismatch: %s", i, enc)
+		}
+	}
+	tests := []string{
+		`{"type":"0x1","validatorPubkey":"0x` + common.Bytes2Hex(make([]byte, 48)) + `","amount":"0x1"}`,                      // missing source
+		`{"type":"0x1","sourceAddress":"0x1000000000000000000000000000000000000001","validatorPubkey":"0x01","amount":"0x1"}`, // short pubkey
+		`{"type":"0x9"}`, // unknown type
+	}
+	for _, input := range tests {
+		if err := json.Unmarshal([]byte(input), new(Request)); err == nil {
+			t.Errorf("no error for invalid request %s", input)
+		}
+	}
+}
+
+func TestRequestsEncode(t *testing.T) {
+	// Requests of the same type are grouped in a single entry
+	requests := append(Requests{testRequests[0]}, testRequests...)
+	lists := requests.Encode()
+	if len(lists) != 3 {
+		t.Fatalf("wrong number of request lists: have %d, want 3", len(lists))
+	}
+	if len(lists[0]) != 1+2*DepositRequestSize || lists[0][0] != DepositRequestType {
+		t.Fatalf("wrong deposit list %x", lists[0])
+	}
+	dec, err := DecodeRequests(lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dec, requests) {
+		t.Fatal("request lists round trip mismatch")
+	}
+	// Types without requests are left out
+	if lists := (Requests{testRequests[2]}).Encode(); len(lists) != 1 || lists[0][0] != ConsolidationRequestType {
+		t.Fatalf("wrong request lists %x", lists)
+	}
+	if lists := (Requests{}).Encode(); len(lists) != 0 {
+		t.Fatalf("request lists for no requests: %x", lists)
+	}
+	// Invalid request lists must be rejected
+	invalid := [][][]byte{
+		{{DepositRequestType}},                  // empty list
+		{lists[1], lists[0]},                    // wrong order
+		{lists[0], lists[0]},                    // duplicate type
+		{lists[0][:len(lists[0])-1]},            // truncated
+		{append([]byte{0x7f}, lists[0][1:]...)}, // unknown type
+	}
+	for i, lists := range invalid {
+		if _, err := DecodeRequests(lists); err == nil {
+			t.Errorf("test %d: no error for invalid request lists", i)
+		}
+	}
+}
+
+func TestRequestsHash(t *testing.T) {
+	want := common.HexToHash("0xe3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	if EmptyRequestsHash != want {
+		t.Fatalf("wrong empty requests hash: have %x, want %x", EmptyRequestsHash, want)
+	}
+	lists := testRequests.Encode()
+	outer := make([]byte, 0, 3*32)
+	for _, list := range lists {
+		h := sha256.Sum256(list)
+		outer = append(outer, h[:]...)
+	}
+	if have, want := testRequests.Hash(), common.Hash(sha256.Sum256(outer)); have != want {
+		t.Fatalf("wrong requests hash: have %x, want %x", have, want)
+	}
+}
+
+func TestHeaderRequestsHashEncoding(t *testing.T) {
+	var (
+		zero   uint64
+		hash   = testRequests.Hash()
+		header = &Header{
+			Number:           common.Big1,
+			Difficulty:       common.Big0,
+			BaseFee:          common.Big1,
+			WithdrawalsHash:  &EmptyRootHash,
+			BlobGasUsed:      &zero,
+			ExcessBlobGas:    &zero,
+			ParentBeaconRoot: &common.Hash{},
+			RequestsHash:     &hash,
+		}
+	)
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The requests hash is the last field, following the Cancun fields
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(enc, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 21 {
+		t.Fatalf("wrong number of header fields: have %d, want 21", len(fields))
+	}
+	last, _ := rlp.EncodeToBytes(hash)
+	if !bytes.Equal(fields[20], last) {
+		t.Fatalf("requests hash at wrong position: have %x, want %x", fields[20], last)
+	}
+	dec := new(Header)
+	if err := rlp.DecodeBytes(enc, dec); err != nil {
+		t.Fatal(err)
+	}
+	if dec.Hash() != header.Hash() || dec.RequestsHash == nil || *dec.RequestsHash != hash {
+		t.Fatal("header round trip mismatch")
+	}
+	// Headers preceding Prague have no requests hash
+	header.RequestsHash = nil
+	enc, _ = rlp.EncodeToBytes(header)
+	if err := rlp.DecodeBytes(enc, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 20 {
+		t.Fatalf("wrong number of pre-Prague header fields: have %d, want 20", len(fields))
+	}
+}
@@ -0,0 +1,808 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ssz"
+)
+
+// List limits of the SSZ encodings, following the consensus layer and EIP-6493
+// where they define them.
+const (
+	sszMaxExtraDataBytes        = 32      // Extra data of beacon chain headers
+	sszMaxSealedExtraDataBytes  = 1 << 16 // Extra data of headers sealed by ethash or clique, e.g. signer lists
+	sszMaxUncles                = 2
+	sszMaxTransactions          = 1 << 20
+	sszMaxCalldataSize          = 1 << 24
+	sszMaxAccessListSize        = 1 << 19
+	sszMaxAccessListStorageKeys = 1 << 19
+	sszMaxLogsPerReceipt        = 1 << 21
+	sszMaxTopicsPerLog          = 4
+	sszMaxLogDataSize           = 1 << 24
+)
+
+var (
+	errSSZNumber    = errors.New("ssz: block number exceeds 64 bits")
+	errSSZExtraData = errors.New("ssz: extra data too long")
+	errSSZCalldata  = errors.New("ssz: transaction data too long")
+	errSSZTopics    = errors.New("ssz: too many log topics")
+	errSSZLogData   = errors.New("ssz: log data too long")
+	errSSZLimit     = errors.New("ssz: list exceeds its limit")
+)
+
+// sszField is a field of an SSZ container: its serialization and how to compute
+// its hash tree root.
+type sszField struct {
+	data     []byte
+	variable bool
+	root     func() [32]byte
+}
+
+// sszFixed is a field of a basic type or a byte vector.
+func sszFixed(data []byte) sszField {
+	return sszField{data: data, root: func() [32]byte { return ssz.VectorRoot(data) }}
+}
+
+func sszUint64(v uint64) sszField {
+	return sszFixed(ssz.Uint64(v))
+}
+
+// sszByteList is a byte list field with the given maximum length.
+func sszByteList(data []byte, limit uint64) sszField {
+	return sszField{data: data, variable: true, root: func() [32]byte { return ssz.ByteListRoot(data, limit) }}
+}
+
+// sszOptional is a field of a basic type or a byte vector which may be absent,
+// encoded as a list with at most one element. A nil value is absent.
+func sszOptional(value []byte) sszField {
+	return sszField{data: value, variable: true, root: func() [32]byte {
+		if value == nil {
+			return ssz.ListRoot(nil, 1)
+		}
+		return ssz.ListRoot([][32]byte{ssz.VectorRoot(value)}, 1)
+	}}
+}
+
+// sszList is a list field of containers with the given maximum length.
+func sszList(elems [][]sszField, limit uint64) sszField {
+	var (
+		encs     = make([][]byte, len(elems))
+		variable = false
+	)
+	for i, elem := range elems {
+		encs[i] = sszEncode(elem)
+		for _, field := range elem {
+			variable = variable || field.variable
+		}
+	}
+	return sszField{data: ssz.EncodeList(encs, variable), variable: true, root: func() [32]byte {
+		roots := make([][32]byte, len(elems))
+		for i, elem := range elems {
+			roots[i] = sszRoot(elem)
+		}
+		return ssz.ListRoot(roots, limit)
+	}}
+}
+
+// sszEncode returns the serialization of a container.
+func sszEncode(fields []sszField) []byte {
+	var c ssz.Container
+	for _, field := range fields {
+		if field.variable {
+			c.Variable(field.data)
+		} else {
+			c.Fixed(field.data)
+		}
+	}
+	return c.Bytes()
+}
+
+// sszRoot returns the hash tree root of a container.
+func sszRoot(fields []sszField) [32]byte {
+	roots := make([][32]byte, len(fields))
+	for i, field := range fields {
+		roots[i] = field.root()
+	}
+	return ssz.Merkleize(roots, 0)
+}
+
+// sszBytes returns a copy of a decoded byte field, never nil.
+func sszBytes(data []byte) []byte {
+	return append([]byte{}, data...)
+}
+
+// sszUint256s decodes a list of serialized uint256 values.
+func sszUint256s(fields ...[]byte) ([]*big.Int, error) {
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		v, err := ssz.DecodeUint256(field)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// sszHeaderSizes are the field sizes of the SSZ encoding of headers.
+var sszHeaderSizes = []int{32, 32, 20, 32, 32, 32, BloomByteLength, 32, 8, 8, 8, 8, ssz.Variable, 32, 8, ssz.Variable, ssz.Variable, ssz.Variable, ssz.Variable, ssz.Variable, ssz.Variable}
+
+func (h *Header) sszFields() ([]sszField, error) {
+	if h.Number == nil || !h.Number.IsUint64() {
+		return nil, errSSZNumber
+	}
+	limit := sszExtraDataLimit(h.Difficulty)
+	if len(h.Extra) > limit {
+		return nil, errSSZExtraData
+	}
+	difficulty, err := ssz.Uint256(h.Difficulty)
+	if err != nil {
+		return nil, err
+	}
+	var baseFee, blobGasUsed, excessBlobGas []byte
+	if h.BaseFee != nil {
+		if baseFee, err = ssz.Uint256(h.BaseFee); err != nil {
+			return nil, err
+		}
+	}
+	if h.BlobGasUsed != nil {
+		blobGasUsed = ssz.Uint64(*h.BlobGasUsed)
+	}
+	if h.ExcessBlobGas != nil {
+		excessBlobGas = ssz.Uint64(*h.ExcessBlobGas)
+	}
+	return []sszField{
+		sszFixed(h.ParentHash[:]),
+		sszFixed(h.UncleHash[:]),
+		sszFixed(h.Coinbase[:]),
+		sszFixed(h.Root[:]),
+		sszFixed(h.TxHash[:]),
+		sszFixed(h.ReceiptHash[:]),
+		sszFixed(h.Bloom[:]),
+		sszFixed(difficulty),
+		sszUint64(h.Number.Uint64()),
+		sszUint64(h.GasLimit),
+		sszUint64(h.GasUsed),
+		sszUint64(h.Time),
+		sszByteList(h.Extra, uint64(limit)),
+		sszFixed(h.MixDigest[:]),
+		sszFixed(h.Nonce[:]),
+		sszOptional(baseFee),
+		sszOptional(sszHashBytes(h.WithdrawalsHash)),
+		sszOptional(blobGasUsed),
+		sszOptional(excessBlobGas),
+		sszOptional(sszHashBytes(h.ParentBeaconRoot)),
+		sszOptional(sszHashBytes(h.RequestsHash)),
+	}, nil
+}
+
+// sszHashBytes returns the content of an optional hash field.
+func sszHashBytes(h *common.Hash) []byte {
+	if h == nil {
+		return nil
+	}
+	return h[:]
+}
+
+// sszDecodeHash decodes an optional hash field.
+func sszDecodeHash(data []byte) (*common.Hash, error) {
+	switch len(data) {
+	case 0:
+		return nil, nil
+	case common.HashLength:
+		h := common.BytesToHash(data)
+		return &h, nil
+	default:
+		return nil, ssz.ErrSize
+	}
+}
+
+// sszDecodeUint64 decodes an optional uint64 field.
+func sszDecodeUint64(data []byte) (*uint64, error) {
+	switch len(data) {
+	case 0:
+		return nil, nil
+	case 8:
+		v, err := ssz.DecodeUint64(data)
+		return &v, err
+	default:
+		return nil, ssz.ErrSize
+	}
+}
+
+// sszExtraDataLimit returns the extra data limit of a header. The consensus layer
+// limit only holds for beacon chain headers, which have no difficulty, while the
+// extra data of sealed headers, such as clique signatures, can be longer.
+func sszExtraDataLimit(difficulty *big.Int) int {
+	if difficulty != nil && difficulty.Sign() > 0 {
+		return sszMaxSealedExtraDataBytes
+	}
+	return sszMaxExtraDataBytes
+}
+
+// MarshalSSZ returns the SSZ encoding of the header. The base fee is encoded as
+// a list of at most one uint256, empty for headers preceding London. The fields
+// added by later forks are encoded likewise, empty for headers preceding them.
+func (h *Header) MarshalSSZ() ([]byte, error) {
+	fields, err := h.sszFields()
+	if err != nil {
+		return nil, err
+	}
+	return sszEncode(fields), nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the header.
+func (h *Header) HashTreeRoot() (common.Hash, error) {
+	fields, err := h.sszFields()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return sszRoot(fields), nil
+}
+
+// UnmarshalSSZ decodes the SSZ encoding of a header.
+func (h *Header) UnmarshalSSZ(data []byte) error {
+	fields, err := ssz.SplitContainer(data, sszHeaderSizes...)
+	if err != nil {
+		return err
+	}
+	ints, err := sszUint256s(fields[7])
+	if err != nil {
+		return err
+	}
+	if len(fields[12]) > sszExtraDataLimit(ints[0]) {
+		return errSSZExtraData
+	}
+	var (
+		number, _   = ssz.DecodeUint64(fields[8])
+		gasLimit, _ = ssz.DecodeUint64(fields[9])
+		gasUsed, _  = ssz.DecodeUint64(fields[10])
+		time, _     = ssz.DecodeUint64(fields[11])
+	)
+	dec := Header{
+		ParentHash:  common.BytesToHash(fields[0]),
+		UncleHash:   common.BytesToHash(fields[1]),
+		Coinbase:    common.BytesToAddress(fields[2]),
+		Root:        common.BytesToHash(fields[3]),
+		TxHash:      common.BytesToHash(fields[4]),
+		ReceiptHash: common.BytesToHash(fields[5]),
+		Bloom:       BytesToBloom(fields[6]),
+		Difficulty:  ints[0],
+		Number:      new(big.Int).SetUint64(number),
+		GasLimit:    gasLimit,
+		GasUsed:     gasUsed,
+		Time:        time,
+		Extra:       sszBytes(fields[12]),
+		MixDigest:   common.BytesToHash(fields[13]),
+	}
+	copy(dec.Nonce[:], fields[14])
+
+	switch len(fields[15]) {
+	case 0:
+	case 32:
+		if dec.BaseFee, err = ssz.DecodeUint256(fields[15]); err != nil {
+			return err
+		}
+	default:
+		return ssz.ErrSize
+	}
+	if dec.WithdrawalsHash, err = sszDecodeHash(fields[16]); err != nil {
+		return err
+	}
+	if dec.BlobGasUsed, err = sszDecodeUint64(fields[17]); err != nil {
+		return err
+	}
+	if dec.ExcessBlobGas, err = sszDecodeUint64(fields[18]); err != nil {
+		return err
+	}
+	if dec.ParentBeaconRoot, err = sszDecodeHash(fields[19]); err != nil {
+		return err
+	}
+	if dec.RequestsHash, err = sszDecodeHash(fields[20]); err != nil {
+		return err
+	}
+	*h = dec
+	return nil
+}
+
+// sszAccessList returns the field of an access list.
+func sszAccessList(al AccessList) sszField {
+	tuples := make([][]sszField, len(al))
+	for i := range al {
+		tuples[i] = []sszField{
+			sszFixed(al[i].Address[:]),
+			sszHashList(al[i].StorageKeys, sszMaxAccessListStorageKeys),
+		}
+	}
+	return sszList(tuples, sszMaxAccessListSize)
+}
+
+// sszHashList is a list field of hashes with the given maximum length.
+func sszHashList(keys []common.Hash, limit uint64) sszField {
+	encs := make([][]byte, len(keys))
+	for i := range keys {
+		encs[i] = keys[i][:]
+	}
+	return sszField{data: ssz.EncodeList(encs, false), variable: true, root: func() [32]byte {
+		roots := make([][32]byte, len(keys))
+		for i := range keys {
+			roots[i] = keys[i]
+		}
+		return ssz.ListRoot(roots, limit)
+	}}
+}
+
+// decodeSSZAccessList decodes the SSZ encoding of an access list.
+func decodeSSZAccessList(data []byte) (AccessList, error) {
+	tuples, err := ssz.SplitList(data, ssz.Variable, sszMaxAccessListSize)
+	if err != nil {
+		return nil, err
+	}
+	al := make(AccessList, len(tuples))
+	for i, tuple := range tuples {
+		fields, err := ssz.SplitContainer(tuple, common.AddressLength, ssz.Variable)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := ssz.SplitList(fields[1], common.HashLength, sszMaxAccessListStorageKeys)
+		if err != nil {
+			return nil, err
+		}
+		al[i] = AccessTuple{
+			Address:     common.BytesToAddress(fields[0]),
+			StorageKeys: make([]common.Hash, len(keys)),
+		}
+		for j, key := range keys {
+			al[i].StorageKeys[j] = common.BytesToHash(key)
+		}
+	}
+	return al, nil
+}
+
+// sszUint256Fields returns the fields of uint256 values.
+func sszUint256Fields(values ...*big.Int) ([]sszField, error) {
+	fields := make([]sszField, len(values))
+	for i, v := range values {
+		enc, err := ssz.Uint256(v)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = sszFixed(enc)
+	}
+	return fields, nil
+}
+
+func (tx *Transaction) sszFields() ([]sszField, error) {
+	if len(tx.Data()) > sszMaxCalldataSize {
+		return nil, errSSZCalldata
+	}
+	var to []byte
+	if addr := tx.To(); addr != nil {
+		to = addr[:]
+	}
+	v, r, s := tx.RawSignatureValues()
+	sig, err := sszUint256Fields(v, r, s)
+	if err != nil {
+		return nil, err
+	}
+	var fields []sszField
+	switch inner := tx.inner.(type) {
+	case *LegacyTx:
+		ints, err := sszUint256Fields(inner.GasPrice, inner.Value)
+		if err != nil {
+			return nil, err
+		}
+		fields = []sszField{
+			sszUint64(inner.Nonce),
+			ints[0],
+			sszUint64(inner.Gas),
+			sszOptional(to),
+			ints[1],
+			sszByteList(inner.Data, sszMaxCalldataSize),
+		}
+	case *AccessListTx:
+		ints, err := sszUint256Fields(inner.ChainID, inner.GasPrice, inner.Value)
+		if err != nil {
+			return nil, err
+		}
+		fields = []sszField{
+			ints[0],
+			sszUint64(inner.Nonce),
+			ints[1],
+			sszUint64(inner.Gas),
+			sszOptional(to),
+			ints[2],
+			sszByteList(inner.Data, sszMaxCalldataSize),
+			sszAccessList(inner.AccessList),
+		}
+	case *DynamicFeeTx:
+		ints, err := sszUint256Fields(inner.ChainID, inner.GasTipCap, inner.GasFeeCap, inner.Value)
+		if err != nil {
+			return nil, err
+		}
+		fields = []sszField{
+			ints[0],
+			sszUint64(inner.Nonce),
+			ints[1],
+			ints[2],
+			sszUint64(inner.Gas),
+			sszOptional(to),
+			ints[3],
+			sszByteList(inner.Data, sszMaxCalldataSize),
+			sszAccessList(inner.AccessList),
+		}
+	default:
+		return nil, ErrTxTypeNotSupported
+	}
+	return append(fields, sig...), nil
+}
+
+// MarshalSSZ returns the SSZ encoding of the transaction, a union of the
+// transaction types selected by the type byte.
+func (tx *Transaction) MarshalSSZ() ([]byte, error) {
+	fields, err := tx.sszFields()
+	if err != nil {
+		return nil, err
+	}
+	return ssz.EncodeUnion(tx.Type(), sszEncode(fields)), nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the transaction.
+func (tx *Transaction) HashTreeRoot() (common.Hash, error) {
+	fields, err := tx.sszFields()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return ssz.MixInSelector(sszRoot(fields), tx.Type()), nil
+}
+
+// UnmarshalSSZ decodes the SSZ encoding of a transaction.
+func (tx *Transaction) UnmarshalSSZ(data []byte) error {
+	typ, data, err := ssz.SplitUnion(data)
+	if err != nil {
+		return err
+	}
+	var inner TxData
+	switch typ {
+	case LegacyTxType:
+		inner, err = decodeSSZLegacyTx(data)
+	case AccessListTxType:
+		inner, err = decodeSSZAccessListTx(data)
+	case DynamicFeeTxType:
+		inner, err = decodeSSZDynamicFeeTx(data)
+	default:
+		err = ErrTxTypeNotSupported
+	}
+	if err != nil {
+		return err
+	}
+	tx.setDecoded(inner, 0)
+	return nil
+}
+
+func decodeSSZLegacyTx(data []byte) (TxData, error) {
+	fields, err := ssz.SplitContainer(data, 8, 32, 8, ssz.Variable, 32, ssz.Variable, 32, 32, 32)
+	if err != nil {
+		return nil, err
+	}
+	ints, err := sszUint256s(fields[1], fields[4], fields[6], fields[7], fields[8])
+	if err != nil {
+		return nil, err
+	}
+	to, err := decodeSSZTo(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	if len(fields[5]) > sszMaxCalldataSize {
+		return nil, errSSZCalldata
+	}
+	nonce, _ := ssz.DecodeUint64(fields[0])
+	gas, _ := ssz.DecodeUint64(fields[2])
+	return &LegacyTx{
+		Nonce:    nonce,
+		GasPrice: ints[0],
+		Gas:      gas,
+		To:       to,
+		Value:    ints[1],
+		Data:     sszBytes(fields[5]),
+		V:        ints[2],
+		R:        ints[3],
+		S:        ints[4],
+	}, nil
+}
+
+func decodeSSZAccessListTx(data []byte) (TxData, error) {
+	fields, err := ssz.SplitContainer(data, 32, 8, 32, 8, ssz.Variable, 32, ssz.Variable, ssz.Variable, 32, 32, 32)
+	if err != nil {
+		return nil, err
+	}
+	ints, err := sszUint256s(fields[0], fields[2], fields[5], fields[8], fields[9], fields[10])
+	if err != nil {
+		return nil, err
+	}
+	to, err := decodeSSZTo(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	if len(fields[6]) > sszMaxCalldataSize {
+		return nil, errSSZCalldata
+	}
+	al, err := decodeSSZAccessList(fields[7])
+	if err != nil {
+		return nil, err
+	}
+	nonce, _ := ssz.DecodeUint64(fields[1])
+	gas, _ := ssz.DecodeUint64(fields[3])
+	return &AccessListTx{
+		ChainID:    ints[0],
+		Nonce:      nonce,
+		GasPrice:   ints[1],
+		Gas:        gas,
+		To:         to,
+		Value:      ints[2],
+		Data:       sszBytes(fields[6]),
+		AccessList: al,
+		V:          ints[3],
+		R:          ints[4],
+		S:          ints[5],
+	}, nil
+}
+
+func decodeSSZDynamicFeeTx(data []byte) (TxData, error) {
+	fields, err := ssz.SplitContainer(data, 32, 8, 32, 32, 8, ssz.Variable, 32, ssz.Variable, ssz.Variable, 32, 32, 32)
+	if err != nil {
+		return nil, err
+	}
+	ints, err := sszUint256s(fields[0], fields[2], fields[3], fields[6], fields[9], fields[10], fields[11])
+	if err != nil {
+		return nil, err
+	}
+	to, err := decodeSSZTo(fields[5])
+	if err != nil {
+		return nil, err
+	}
+	if len(fields[7]) > sszMaxCalldataSize {
+		return nil, errSSZCalldata
+	}
+	al, err := decodeSSZAccessList(fields[8])
+	if err != nil {
+		return nil, err
+	}
+	nonce, _ := ssz.DecodeUint64(fields[1])
+	gas, _ := ssz.DecodeUint64(fields[4])
+	return &DynamicFeeTx{
+		ChainID:    ints[0],
+		Nonce:      nonce,
+		GasTipCap:  ints[1],
+		GasFeeCap:  ints[2],
+		Gas:        gas,
+		To:         to,
+		Value:      ints[3],
+		Data:       sszBytes(fields[7]),
+		AccessList: al,
+		V:          ints[4],
+		R:          ints[5],
+		S:          ints[6],
+	}, nil
+}
+
+// decodeSSZTo decodes the optional recipient of a transaction.
+func decodeSSZTo(data []byte) (*common.Address, error) {
+	switch len(data) {
+	case 0:
+		return nil, nil
+	case common.AddressLength:
+		addr := common.BytesToAddress(data)
+		return &addr, nil
+	default:
+		return nil, ssz.ErrSize
+	}
+}
+
+func (r *Receipt) sszFields() ([]sszField, error) {
+	if len(r.Logs) > sszMaxLogsPerReceipt {
+		return nil, errSSZLimit
+	}
+	logs := make([][]sszField, len(r.Logs))
+	for i, log := range r.Logs {
+		if len(log.Topics) > sszMaxTopicsPerLog {
+			return nil, errSSZTopics
+		}
+		if len(log.Data) > sszMaxLogDataSize {
+			return nil, errSSZLogData
+		}
+		logs[i] = []sszField{
+			sszFixed(log.Address[:]),
+			sszHashList(log.Topics, sszMaxTopicsPerLog),
+			sszByteList(log.Data, sszMaxLogDataSize),
+		}
+	}
+	if len(r.PostState) != 0 && len(r.PostState) != common.HashLength {
+		return nil, ssz.ErrSize
+	}
+	return []sszField{
+		sszFixed([]byte{r.Type}),
+		sszByteList(r.PostState, common.HashLength),
+		sszUint64(r.Status),
+		sszUint64(r.CumulativeGasUsed),
+		sszFixed(r.Bloom[:]),
+		sszList(logs, sszMaxLogsPerReceipt),
+	}, nil
+}
+
+// MarshalSSZ returns the SSZ encoding of the consensus fields of the receipt.
+// The post state is empty for receipts carrying a status.
+func (r *Receipt) MarshalSSZ() ([]byte, error) {
+	fields, err := r.sszFields()
+	if err != nil {
+		return nil, err
+	}
+	return sszEncode(fields), nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the consensus fields of the
+// receipt.
+func (r *Receipt) HashTreeRoot() (common.Hash, error) {
+	fields, err := r.sszFields()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return sszRoot(fields), nil
+}
+
+// UnmarshalSSZ decodes the SSZ encoding of the consensus fields of a receipt.
+func (r *Receipt) UnmarshalSSZ(data []byte) error {
+	fields, err := ssz.SplitContainer(data, 1, ssz.Variable, 8, 8, BloomByteLength, ssz.Variable)
+	if err != nil {
+		return err
+	}
+	if len(fields[1]) != 0 && len(fields[1]) != common.HashLength {
+		return ssz.ErrSize
+	}
+	encs, err := ssz.SplitList(fields[5], ssz.Variable, sszMaxLogsPerReceipt)
+	if err != nil {
+		return err
+	}
+	logs := make([]*Log, len(encs))
+	for i, enc := range encs {
+		fields, err := ssz.SplitContainer(enc, common.AddressLength, ssz.Variable, ssz.Variable)
+		if err != nil {
+			return err
+		}
+		topics, err := ssz.SplitList(fields[1], common.HashLength, sszMaxTopicsPerLog)
+		if err != nil {
+			return err
+		}
+		if len(fields[2]) > sszMaxLogDataSize {
+			return errSSZLogData
+		}
+		logs[i] = &Log{
+			Address: common.BytesToAddress(fields[0]),
+			Topics:  make([]common.Hash, len(topics)),
+			Data:    sszBytes(fields[2]),
+		}
+		for j, topic := range topics {
+			logs[i].Topics[j] = common.BytesToHash(topic)
+		}
+	}
+	status, _ := ssz.DecodeUint64(fields[2])
+	cumulativeGasUsed, _ := ssz.DecodeUint64(fields[3])
+
+	r.Type = fields[0][0]
+	r.PostState = nil
+	if len(fields[1]) > 0 {
+		r.PostState = sszBytes(fields[1])
+	}
+	r.Status = status
+	r.CumulativeGasUsed = cumulativeGasUsed
+	r.Bloom = BytesToBloom(fields[4])
+	r.Logs = logs
+	return nil
+}
+
+func (b *Body) sszFields() ([]sszField, error) {
+	if len(b.Transactions) > sszMaxTransactions || len(b.Uncles) > sszMaxUncles {
+		return nil, errSSZLimit
+	}
+	var (
+		txs     = make([][]byte, len(b.Transactions))
+		txRoots = make([]func() [32]byte, len(b.Transactions))
+	)
+	for i, tx := range b.Transactions {
+		fields, err := tx.sszFields()
+		if err != nil {
+			return nil, err
+		}
+		typ := tx.Type()
+		txs[i] = ssz.EncodeUnion(typ, sszEncode(fields))
+		txRoots[i] = func() [32]byte { return ssz.MixInSelector(sszRoot(fields), typ) }
+	}
+	uncles := make([][]sszField, len(b.Uncles))
+	for i, uncle := range b.Uncles {
+		fields, err := uncle.sszFields()
+		if err != nil {
+			return nil, err
+		}
+		uncles[i] = fields
+	}
+	return []sszField{
+		{data: ssz.EncodeList(txs, true), variable: true, root: func() [32]byte {
+			roots := make([][32]byte, len(txRoots))
+			for i, root := range txRoots {
+				roots[i] = root()
+			}
+			return ssz.ListRoot(roots, sszMaxTransactions)
+		}},
+		sszList(uncles, sszMaxUncles),
+	}, nil
+}
+
+// MarshalSSZ returns the SSZ encoding of the block body.
+func (b *Body) MarshalSSZ() ([]byte, error) {
+	fields, err := b.sszFields()
+	if err != nil {
+		return nil, err
+	}
+	return sszEncode(fields), nil
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the block body.
+func (b *Body) HashTreeRoot() (common.Hash, error) {
+	fields, err := b.sszFields()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return sszRoot(fields), nil
+}
+
+// UnmarshalSSZ decodes the SSZ encoding of a block body.
+func (b *Body) UnmarshalSSZ(data []byte) error {
+	fields, err := ssz.SplitContainer(data, ssz.Variable, ssz.Variable)
+	if err != nil {
+		return err
+	}
+	txs, err := ssz.SplitList(fields[0], ssz.Variable, sszMaxTransactions)
+	if err != nil {
+		return err
+	}
+	uncles, err := ssz.SplitList(fields[1], ssz.Variable, sszMaxUncles)
+	if err != nil {
+		return err
+	}
+	dec := Body{
+		Transactions: make([]*Transaction, len(txs)),
+		Uncles:       make([]*Header, len(uncles)),
+	}
+	for i, enc := range txs {
+		dec.Transactions[i] = new(Transaction)
+		if err := dec.Transactions[i].UnmarshalSSZ(enc); err != nil {
+			return err
+		}
+	}
+	for i, enc := range uncles {
+		dec.Uncles[i] = new(Header)
+		if err := dec.Uncles[i].UnmarshalSSZ(enc); err != nil {
+			return err
+		}
+	}
+	*b = dec
+	return nil
+}
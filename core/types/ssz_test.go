@@ -0,0 +1,236 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func sszTestTxs(t *testing.T) []*Transaction {
+	key, _ := crypto.GenerateKey()
+	signer := LatestSignerForChainID(big.NewInt(1))
+	accesses := AccessList{{Address: testAddr, StorageKeys: []common.Hash{{1}, {2}}}, {Address: common.Address{3}}}
+
+	var txs []*Transaction
+	for _, inner := range []TxData{
+		&LegacyTx{Nonce: 1, GasPrice: big.NewInt(10), Gas: 21000, To: &testAddr, Value: big.NewInt(5)},
+		&LegacyTx{Nonce: 2, GasPrice: big.NewInt(10), Gas: 100000, Data: []byte{0x60, 0x00}},
+		&AccessListTx{ChainID: big.NewInt(1), Nonce: 3, GasPrice: big.NewInt(10), Gas: 50000, To: &testAddr, AccessList: accesses},
+		&DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 4, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(20), Gas: 50000, Value: big.NewInt(7), Data: []byte{1, 2, 3}, AccessList: accesses},
+	} {
+		tx, err := SignNewTx(key, signer, inner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+func TestTransactionSSZ(t *testing.T) {
+	roots := make(map[common.Hash]bool)
+	for i, tx := range sszTestTxs(t) {
+		enc, err := tx.MarshalSSZ()
+		if err != nil {
+			t.Fatalf("tx %d: encoding failed: %v", i, err)
+		}
+		if enc[0] != tx.Type() {
+			t.Errorf("tx %d: union selector mismatch: have %d, want %d", i, enc[0], tx.Type())
+		}
+		dec := new(Transaction)
+		if err := dec.UnmarshalSSZ(enc); err != nil {
+			t.Fatalf("tx %d: decoding failed: %v", i, err)
+		}
+		if dec.Hash() != tx.Hash() {
+			t.Errorf("tx %d: hash mismatch after round trip", i)
+		}
+		root, err := tx.HashTreeRoot()
+		if err != nil {
+			t.Fatalf("tx %d: root failed: %v", i, err)
+		}
+		if roots[root] {
+			t.Errorf("tx %d: duplicate hash tree root", i)
+		}
+		roots[root] = true
+
+		if err := dec.UnmarshalSSZ(enc[:40]); err == nil {
+			t.Errorf("tx %d: truncated encoding accepted", i)
+		}
+	}
+}
+
+func TestHeaderSSZ(t *testing.T) {
+	header := &Header{
+		ParentHash: common.Hash{1},
+		Coinbase:   testAddr,
+		Difficulty: big.NewInt(131072),
+		Number:     big.NewInt(100),
+		GasLimit:   params.GenesisGasLimit,
+		GasUsed:    21000,
+		Time:       1000,
+		Extra:      []byte("extra"),
+		Nonce:      EncodeNonce(42),
+	}
+	for _, baseFee := range []*big.Int{nil, big.NewInt(params.InitialBaseFee)} {
+		header.BaseFee = baseFee
+
+		enc, err := header.MarshalSSZ()
+		if err != nil {
+			t.Fatalf("encoding failed: %v", err)
+		}
+		dec := new(Header)
+		if err := dec.UnmarshalSSZ(enc); err != nil {
+			t.Fatalf("decoding failed: %v", err)
+		}
+		if dec.Hash() != header.Hash() {
+			t.Errorf("hash mismatch after round trip (base fee %v)", baseFee)
+		}
+		if (dec.BaseFee == nil) != (baseFee == nil) {
+			t.Errorf("base fee presence mismatch: have %v, want %v", dec.BaseFee, baseFee)
+		}
+	}
+	// Prague headers carry the Cancun fields and the requests hash
+	var (
+		blobGas      uint64 = 131072
+		requestsHash        = EmptyRequestsHash
+	)
+	header.WithdrawalsHash = &EmptyRootHash
+	header.BlobGasUsed, header.ExcessBlobGas = &blobGas, new(uint64)
+	header.ParentBeaconRoot = &common.Hash{2}
+	header.RequestsHash = &requestsHash
+
+	enc, err := header.MarshalSSZ()
+	if err != nil {
+		t.Fatalf("encoding failed: %v", err)
+	}
+	dec := new(Header)
+	if err := dec.UnmarshalSSZ(enc); err != nil {
+		t.Fatalf("decoding failed: %v", err)
+	}
+	if !reflect.DeepEqual(dec, header) {
+		t.Errorf("mismatch after round trip:\nhave %+v\nwant %+v", dec, header)
+	}
+	root, _ := header.HashTreeRoot()
+	header.GasUsed++
+	if changed, _ := header.HashTreeRoot(); changed == root {
+		t.Error("hash tree root not affected by field change")
+	}
+	header.Extra = make([]byte, sszMaxSealedExtraDataBytes+1)
+	if _, err := header.MarshalSSZ(); err != errSSZExtraData {
+		t.Errorf("extra data error mismatch: have %v, want %v", err, errSSZExtraData)
+	}
+	// Beacon chain headers are held to the extra data limit of the consensus layer
+	header.Difficulty = new(big.Int)
+	header.Extra = make([]byte, sszMaxExtraDataBytes+1)
+	if _, err := header.MarshalSSZ(); err != errSSZExtraData {
+		t.Errorf("beacon extra data error mismatch: have %v, want %v", err, errSSZExtraData)
+	}
+}
+
+func TestCliqueHeaderSSZ(t *testing.T) {
+	// A clique checkpoint header carries the vanity, the signer list and the seal
+	header := &Header{
+		ParentHash: common.Hash{1},
+		Difficulty: big.NewInt(2),
+		Number:     big.NewInt(30000),
+		GasLimit:   params.GenesisGasLimit,
+		Time:       1000,
+		Extra:      make([]byte, 32+3*common.AddressLength+crypto.SignatureLength),
+	}
+	enc, err := header.MarshalSSZ()
+	if err != nil {
+		t.Fatalf("encoding failed: %v", err)
+	}
+	dec := new(Header)
+	if err := dec.UnmarshalSSZ(enc); err != nil {
+		t.Fatalf("decoding failed: %v", err)
+	}
+	if dec.Hash() != header.Hash() {
+		t.Error("hash mismatch after round trip")
+	}
+	if _, err := header.HashTreeRoot(); err != nil {
+		t.Errorf("root failed: %v", err)
+	}
+}
+
+func TestReceiptSSZ(t *testing.T) {
+	receipts := []*Receipt{
+		{Type: DynamicFeeTxType, Status: ReceiptStatusSuccessful, CumulativeGasUsed: 50000, Logs: []*Log{
+			{Address: testAddr, Topics: []common.Hash{{1}, {2}}, Data: []byte{1, 2, 3}},
+			{Address: common.Address{1}, Topics: []common.Hash{}, Data: []byte{}},
+		}},
+		{PostState: common.Hash{2}.Bytes(), CumulativeGasUsed: 21000, Logs: []*Log{}},
+	}
+	for i, receipt := range receipts {
+		receipt.Bloom = CreateBloom(Receipts{receipt})
+
+		enc, err := receipt.MarshalSSZ()
+		if err != nil {
+			t.Fatalf("receipt %d: encoding failed: %v", i, err)
+		}
+		dec := new(Receipt)
+		if err := dec.UnmarshalSSZ(enc); err != nil {
+			t.Fatalf("receipt %d: decoding failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(dec, receipt) {
+			t.Errorf("receipt %d: mismatch after round trip:\nhave %+v\nwant %+v", i, dec, receipt)
+		}
+		if _, err := receipt.HashTreeRoot(); err != nil {
+			t.Errorf("receipt %d: root failed: %v", i, err)
+		}
+	}
+}
+
+func TestBodySSZ(t *testing.T) {
+	body := &Body{
+		Transactions: sszTestTxs(t),
+		Uncles:       []*Header{{Difficulty: big.NewInt(1), Number: big.NewInt(1), Extra: []byte{}}},
+	}
+	enc, err := body.MarshalSSZ()
+	if err != nil {
+		t.Fatalf("encoding failed: %v", err)
+	}
+	dec := new(Body)
+	if err := dec.UnmarshalSSZ(enc); err != nil {
+		t.Fatalf("decoding failed: %v", err)
+	}
+	if DeriveSha(Transactions(dec.Transactions), newHasher()) != DeriveSha(Transactions(body.Transactions), newHasher()) {
+		t.Error("transactions mismatch after round trip")
+	}
+	if CalcUncleHash(dec.Uncles) != CalcUncleHash(body.Uncles) {
+		t.Error("uncles mismatch after round trip")
+	}
+	root, err := body.HashTreeRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decRoot, _ := dec.HashTreeRoot()
+	if root != decRoot {
+		t.Error("hash tree root mismatch after round trip")
+	}
+	reenc, _ := dec.MarshalSSZ()
+	if !bytes.Equal(enc, reenc) {
+		t.Error("encoding mismatch after round trip")
+	}
+}
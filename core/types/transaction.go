@@ -356,6 +356,17 @@ func (tx *Transaction) EffectiveGasTipIntCmp(other *big.Int, baseFee *big.Int) i
 	return tx.EffectiveGasTipValue(baseFee).Cmp(other)
 }
 
+// Time returns the time the transaction was first seen locally.
+func (tx *Transaction) Time() time.Time {
+	return tx.time
+}
+
+// SetTime overrides the time the transaction was first seen locally. It is used
+// to restore the arrival time of transactions loaded from a dump.
+func (tx *Transaction) SetTime(t time.Time) {
+	tx.time = t
+}
+
 // Hash returns the transaction hash.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
@@ -0,0 +1,64 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	analysisCacheHitMeter  = metrics.NewRegisteredMeter("vm/analysis/hit", nil)
+	analysisCacheMissMeter = metrics.NewRegisteredMeter("vm/analysis/miss", nil)
+)
+
+// analysisCache holds the resident JUMPDEST analysis cache shared by all EVM
+// instances of the process, so hot contracts are only analysed once across
+// transactions, blocks and RPC calls. It is nil while the cache is disabled.
+var analysisCache atomic.Value
+
+// SetAnalysisCache sets the size in bytes of the process wide cache of code
+// analysis results, replacing any previous cache. A size of zero disables the
+// cache, leaving the analysis reuse to within a single transaction.
+func SetAnalysisCache(size int) {
+	var cache *fastcache.Cache
+	if size > 0 {
+		cache = fastcache.New(size)
+	}
+	analysisCache.Store(cache)
+}
+
+// cachedCodeBitmap returns the JUMPDEST analysis of the code with the given
+// hash, consulting the resident cache before analysing the code.
+func cachedCodeBitmap(hash common.Hash, code []byte) bitvec {
+	cache, _ := analysisCache.Load().(*fastcache.Cache)
+	if cache == nil {
+		return codeBitmap(code)
+	}
+	if blob, ok := cache.HasGet(nil, hash[:]); ok {
+		analysisCacheHitMeter.Mark(1)
+		return blob
+	}
+	analysisCacheMissMeter.Mark(1)
+
+	analysis := codeBitmap(code)
+	cache.Set(hash[:], analysis)
+	return analysis
+}
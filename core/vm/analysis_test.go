@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"bytes"
 	"math/bits"
 	"testing"
 
@@ -59,6 +60,30 @@ func TestJumpDestAnalysis(t *testing.T) {
 	}
 }
 
+// Tests that code analysis results are reused from the resident cache.
+func TestCachedCodeBitmap(t *testing.T) {
+	SetAnalysisCache(1024 * 1024)
+	defer SetAnalysisCache(0)
+
+	var (
+		code = []byte{byte(PUSH1), 0x01, byte(JUMPDEST)}
+		hash = crypto.Keccak256Hash(code)
+	)
+	want := codeBitmap(code)
+	if have := cachedCodeBitmap(hash, code); !bytes.Equal(have, want) {
+		t.Fatalf("analysis mismatch: have %x, want %x", have, want)
+	}
+	// Analysing different code under the same hash must hit the cache
+	if have := cachedCodeBitmap(hash, []byte{byte(JUMPDEST)}); !bytes.Equal(have, want) {
+		t.Fatalf("cached analysis mismatch: have %x, want %x", have, want)
+	}
+	// Disabling the cache must analyse the code again
+	SetAnalysisCache(0)
+	if have := cachedCodeBitmap(hash, []byte{byte(JUMPDEST)}); bytes.Equal(have, want) {
+		t.Fatalf("analysis served from disabled cache")
+	}
+}
+
 const analysisCodeSize = 1200 * 1024
 
 func BenchmarkJumpdestAnalysis_1200k(bench *testing.B) {
@@ -53,6 +53,10 @@ type Contract struct {
 	jumpdests map[common.Hash]bitvec // Aggregated result of JUMPDEST analysis.
 	analysis  bitvec                 // Locally cached result of JUMPDEST analysis
 
+	container *eofContainer   // Parsed EOF container, nil for legacy code
+	section   uint64          // Index of the executing EOF code section
+	retStack  []returnContext // Return stack of the EOF function calls
+
 	Code     []byte
 	CodeHash common.Hash
 	CodeAddr *common.Address
@@ -62,6 +66,12 @@ type Contract struct {
 	value *big.Int
 }
 
+// returnContext is the state of a calling EOF function, restored by RETF.
+type returnContext struct {
+	section uint64
+	pc      uint64
+}
+
 // NewContract returns a new contract environment for the execution of EVM.
 func NewContract(caller ContractRef, object ContractRef, value *big.Int, gas uint64) *Contract {
 	c := &Contract{CallerAddress: caller.Address(), caller: caller, self: object}
@@ -110,9 +120,9 @@ func (c *Contract) isCode(udest uint64) bool {
 		// Does parent context have the analysis?
 		analysis, exist := c.jumpdests[c.CodeHash]
 		if !exist {
-			// Do the analysis and save in parent context
-			// We do not need to store it in c.analysis
-			analysis = codeBitmap(c.Code)
+			// Do the analysis (or fetch it from the resident cache) and
+			// save in parent context. We do not need to store it in c.analysis
+			analysis = cachedCodeBitmap(c.CodeHash, c.Code)
 			c.jumpdests[c.CodeHash] = analysis
 		}
 		// Also stash it in current contract for faster access
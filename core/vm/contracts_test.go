@@ -20,11 +20,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -65,6 +67,7 @@ var allPrecompiles = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{16}):   &bls12381Pairing{},
 	common.BytesToAddress([]byte{17}):   &bls12381MapG1{},
 	common.BytesToAddress([]byte{18}):   &bls12381MapG2{},
+	common.BytesToAddress([]byte{1, 0}): &p256Verify{},
 }
 
 // EIP-152 test vectors
@@ -272,6 +275,36 @@ func TestPrecompileBlake2FMalformedInput(t *testing.T) {
 
 func TestPrecompiledEcrecover(t *testing.T) { testJson("ecRecover", "01", t) }
 
+func TestPrecompiledP256Verify(t *testing.T)      { testJson("p256Verify", "0100", t) }
+func BenchmarkPrecompiledP256Verify(b *testing.B) { benchJson("p256Verify", "0100", b) }
+
+// Tests that the P256 verification precompile is only active once enabled in
+// the chain config.
+func TestP256VerifyActivation(t *testing.T) {
+	addr := common.BytesToAddress([]byte{1, 0})
+	config := *params.TestChainConfig
+	for _, block := range []*big.Int{nil, big.NewInt(1)} {
+		config.P256VerifyBlock = block
+		evm := NewEVM(BlockContext{BlockNumber: big.NewInt(0)}, TxContext{}, nil, &config, Config{})
+		if _, ok := evm.precompile(addr); ok {
+			t.Errorf("activation block %v: precompile active at block 0", block)
+		}
+	}
+	config.P256VerifyBlock = big.NewInt(0)
+	rules := config.Rules(big.NewInt(0), false)
+	evm := NewEVM(BlockContext{BlockNumber: big.NewInt(0)}, TxContext{}, nil, &config, Config{})
+	if _, ok := evm.precompile(addr); !ok {
+		t.Errorf("precompile not active")
+	}
+	active := ActivePrecompiles(rules)
+	if len(active) != len(PrecompiledAddressesBerlin)+1 || active[len(active)-1] != addr {
+		t.Errorf("active precompiles mismatch: have %v", active)
+	}
+	if len(ActivePrecompiles(params.TestRules)) != len(PrecompiledAddressesBerlin) {
+		t.Errorf("default precompiles modified")
+	}
+}
+
 func testJson(name, addr string, t *testing.T) {
 	tests, err := loadJson(name)
 	if err != nil {
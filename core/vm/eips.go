@@ -17,8 +17,10 @@
 package vm
 
 import (
+	"encoding/binary"
 	"fmt"
 	"sort"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
@@ -192,3 +194,115 @@ func opPush0(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]by
 	scope.Stack.push(new(uint256.Int))
 	return nil, nil
 }
+
+// enableEOF applies the EVM object format changes to the given jump table. It
+// is used for the code of EOF containers only, legacy code keeps executing with
+// the unmodified jump table.
+// - EIP-4200: Define RJUMP and RJUMPI
+// - EIP-4750: Define CALLF and RETF
+// - Undefine JUMP, JUMPI and PC, which are superseded by relative jumps and functions
+func enableEOF(jt *JumpTable) {
+	undefined := &operation{
+		execute:   opUndefined,
+		maxStack:  maxStack(0, 0),
+		undefined: true,
+	}
+	jt[JUMP] = undefined
+	jt[JUMPI] = undefined
+	jt[PC] = undefined
+
+	jt[RJUMP] = &operation{
+		execute:     opRjump,
+		constantGas: GasQuickStep,
+		minStack:    minStack(0, 0),
+		maxStack:    maxStack(0, 0),
+	}
+	jt[RJUMPI] = &operation{
+		execute:     opRjumpi,
+		constantGas: params.RjumpiGas,
+		minStack:    minStack(1, 0),
+		maxStack:    maxStack(1, 0),
+	}
+	jt[CALLF] = &operation{
+		execute:     opCallf,
+		constantGas: GasFastStep,
+		minStack:    minStack(0, 0),
+		maxStack:    maxStack(0, 0),
+	}
+	jt[RETF] = &operation{
+		execute:     opRetf,
+		constantGas: GasFastestStep,
+		minStack:    minStack(0, 0),
+		maxStack:    maxStack(0, 0),
+	}
+}
+
+// readImmediate16 returns the two byte immediate of the instruction at pc.
+func readImmediate16(code []byte, pc uint64) uint16 {
+	var imm [2]byte
+	if pc+1 < uint64(len(code)) {
+		copy(imm[:], code[pc+1:])
+	}
+	return binary.BigEndian.Uint16(imm[:])
+}
+
+// opRjump implements the RJUMP opcode
+func opRjump(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	if atomic.LoadInt32(&interpreter.evm.abort) != 0 {
+		return nil, errStopToken
+	}
+	offset := int16(readImmediate16(scope.Contract.Code, *pc))
+	*pc = uint64(int64(*pc) + eofRelativeJumpWidth + int64(offset)) // pc will be increased by the interpreter loop
+	return nil, nil
+}
+
+// opRjumpi implements the RJUMPI opcode
+func opRjumpi(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	cond := scope.Stack.pop()
+	if cond.IsZero() {
+		*pc += eofRelativeJumpWidth
+		return nil, nil
+	}
+	return opRjump(pc, interpreter, scope)
+}
+
+// opCallf implements the CALLF opcode
+func opCallf(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		contract = scope.Contract
+		idx      = int(readImmediate16(contract.Code, *pc))
+	)
+	if contract.container == nil || idx >= len(contract.container.types) {
+		return nil, errInvalidCallTarget
+	}
+	if len(contract.retStack) >= eofReturnStackLimit {
+		return nil, ErrReturnStackExceeded
+	}
+	typ := contract.container.types[idx]
+	if sLen := scope.Stack.len(); sLen < int(typ.inputs) {
+		return nil, &ErrStackUnderflow{stackLen: sLen, required: int(typ.inputs)}
+	} else if limit := int(params.StackLimit) - int(typ.maxStackHeight) + int(typ.inputs); sLen > limit {
+		return nil, &ErrStackOverflow{stackLen: sLen, limit: limit}
+	}
+	contract.retStack = append(contract.retStack, returnContext{
+		section: contract.section,
+		pc:      *pc + 1 + eofRelativeJumpWidth,
+	})
+	contract.section = uint64(idx)
+	*pc = contract.container.codeOffsets[idx] - 1 // pc will be increased by the interpreter loop
+	return nil, nil
+}
+
+// opRetf implements the RETF opcode
+func opRetf(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	contract := scope.Contract
+	if len(contract.retStack) == 0 {
+		// Returning from the first code section ends the execution
+		return nil, errStopToken
+	}
+	ret := contract.retStack[len(contract.retStack)-1]
+	contract.retStack = contract.retStack[:len(contract.retStack)-1]
+	contract.section = ret.section
+	*pc = ret.pc - 1 // pc will be increased by the interpreter loop
+	return nil, nil
+}
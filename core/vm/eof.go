@@ -0,0 +1,270 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// The EVM object format (EOF) of EIP-3540 packs the code of a contract into a
+// container of typed sections, allowing the code to be validated once at
+// deployment instead of being analysed on every execution:
+//
+//	container := magic, version, types_header, code_header, data_header, terminator, body
+//	magic     := 0xef00
+//	version   := 0x01
+//	types_header := 0x01, types_size (2 bytes)
+//	code_header  := 0x02, num_code_sections (2 bytes), code_size (2 bytes)+
+//	data_header  := 0x04, data_size (2 bytes)
+//	terminator   := 0x00
+//	body := types_section, code_section+, data_section
+//
+// The types section holds the inputs, outputs and maximum stack height of each
+// code section, which are the functions called via CALLF (EIP-4750).
+const (
+	eofFormatByte = 0xef
+	eofMagicByte  = 0x00
+	eof1Version   = 0x01
+
+	kindTypes = 0x01
+	kindCode  = 0x02
+	kindData  = 0x04
+
+	eofTypeSize          = 4    // inputs (1 byte), outputs (1 byte), max stack height (2 bytes)
+	eofMaxCodeSections   = 1024 // Maximum number of code sections in a container
+	eofMaxFunctionIO     = 127  // Maximum number of inputs or outputs of a function
+	eofReturnStackLimit  = 1024 // Maximum depth of the CALLF return stack
+	eofMinContainerSize  = 15   // Size of the header of a container with a single code section
+	eofRelativeJumpWidth = 2    // Size of the RJUMP, RJUMPI and CALLF immediates
+)
+
+var (
+	errInvalidMagic            = errors.New("invalid magic")
+	errInvalidVersion          = errors.New("invalid version")
+	errMissingHeader           = errors.New("missing section header")
+	errInvalidTypeSize         = errors.New("invalid type section size")
+	errInvalidCodeSectionCount = errors.New("invalid number of code sections")
+	errEmptyCodeSection        = errors.New("empty code section")
+	errMissingTerminator       = errors.New("missing header terminator")
+	errInvalidContainerSize    = errors.New("container size does not match header")
+	errInvalidSection0Type     = errors.New("first code section must have zero inputs and outputs")
+	errInvalidFunctionType     = errors.New("invalid code section type")
+	errUndefinedInstruction    = errors.New("undefined instruction")
+	errTruncatedImmediate      = errors.New("truncated immediate")
+	errInvalidJumpDest         = errors.New("invalid relative jump destination")
+	errInvalidCallTarget       = errors.New("invalid code section index")
+	errInvalidCodeTermination  = errors.New("code section does not end with a terminating instruction")
+)
+
+// functionMetadata is the type of a code section.
+type functionMetadata struct {
+	inputs         uint8
+	outputs        uint8
+	maxStackHeight uint16
+}
+
+// eofContainer is the parsed header of an EOF container. The code sections are
+// referenced by their offsets into the container, so that the program counter
+// of the interpreter keeps indexing the whole contract code.
+type eofContainer struct {
+	types       []functionMetadata
+	codeOffsets []uint64
+	codeSizes   []uint64
+	dataOffset  uint64
+}
+
+// hasEOFMagic reports whether the code starts with the EOF magic.
+func hasEOFMagic(code []byte) bool {
+	return len(code) >= 2 && code[0] == eofFormatByte && code[1] == eofMagicByte
+}
+
+// parseEOF parses the header and the types section of an EOF container,
+// checking that the section sizes are consistent with the container. The code
+// of the sections is not validated.
+func parseEOF(code []byte) (*eofContainer, error) {
+	if !hasEOFMagic(code) {
+		return nil, errInvalidMagic
+	}
+	if len(code) < 3 || code[2] != eof1Version {
+		return nil, errInvalidVersion
+	}
+	if len(code) < eofMinContainerSize {
+		return nil, errMissingHeader
+	}
+	pos := 3
+
+	// Parse the section headers
+	typesSize, err := parseSectionSize(code, &pos, kindTypes)
+	if err != nil {
+		return nil, err
+	}
+	if typesSize == 0 || typesSize%eofTypeSize != 0 {
+		return nil, fmt.Errorf("%w: %d", errInvalidTypeSize, typesSize)
+	}
+	sections, err := parseSectionSize(code, &pos, kindCode)
+	if err != nil {
+		return nil, err
+	}
+	if sections == 0 || sections > eofMaxCodeSections {
+		return nil, fmt.Errorf("%w: %d", errInvalidCodeSectionCount, sections)
+	}
+	if sections*eofTypeSize != typesSize {
+		return nil, fmt.Errorf("%w: have %d, want %d", errInvalidTypeSize, typesSize, sections*eofTypeSize)
+	}
+	if len(code) < pos+2*sections {
+		return nil, errMissingHeader
+	}
+	codeSizes := make([]uint64, sections)
+	for i := range codeSizes {
+		codeSizes[i] = uint64(binary.BigEndian.Uint16(code[pos:]))
+		if codeSizes[i] == 0 {
+			return nil, fmt.Errorf("%w: section %d", errEmptyCodeSection, i)
+		}
+		pos += 2
+	}
+	dataSize, err := parseSectionSize(code, &pos, kindData)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) <= pos || code[pos] != 0 {
+		return nil, errMissingTerminator
+	}
+	pos++
+
+	// Check the body against the header
+	size := uint64(pos) + uint64(typesSize) + uint64(dataSize)
+	for _, codeSize := range codeSizes {
+		size += codeSize
+	}
+	if size != uint64(len(code)) {
+		return nil, fmt.Errorf("%w: have %d, want %d", errInvalidContainerSize, len(code), size)
+	}
+	container := &eofContainer{
+		types:       make([]functionMetadata, sections),
+		codeOffsets: make([]uint64, sections),
+		codeSizes:   codeSizes,
+	}
+	for i := range container.types {
+		typ := functionMetadata{
+			inputs:         code[pos],
+			outputs:        code[pos+1],
+			maxStackHeight: binary.BigEndian.Uint16(code[pos+2:]),
+		}
+		if typ.inputs > eofMaxFunctionIO || typ.outputs > eofMaxFunctionIO || uint64(typ.maxStackHeight) > params.StackLimit {
+			return nil, fmt.Errorf("%w: section %d", errInvalidFunctionType, i)
+		}
+		container.types[i] = typ
+		pos += eofTypeSize
+	}
+	if container.types[0].inputs != 0 || container.types[0].outputs != 0 {
+		return nil, errInvalidSection0Type
+	}
+	offset := uint64(pos)
+	for i, codeSize := range codeSizes {
+		container.codeOffsets[i] = offset
+		offset += codeSize
+	}
+	container.dataOffset = offset
+	return container, nil
+}
+
+// parseSectionSize parses a section header of the given kind, returning the
+// size field of the header.
+func parseSectionSize(code []byte, pos *int, kind byte) (int, error) {
+	if len(code) < *pos+3 || code[*pos] != kind {
+		return 0, fmt.Errorf("%w: kind %#x", errMissingHeader, kind)
+	}
+	size := int(binary.BigEndian.Uint16(code[*pos+1:]))
+	*pos += 3
+	return size, nil
+}
+
+// validateEOF parses an EOF container and validates the code of all its
+// sections against the given instruction set.
+func validateEOF(code []byte, jt *JumpTable) (*eofContainer, error) {
+	container, err := parseEOF(code)
+	if err != nil {
+		return nil, err
+	}
+	for i := range container.codeOffsets {
+		if err := container.validateCode(code, i, jt); err != nil {
+			return nil, fmt.Errorf("section %d: %w", i, err)
+		}
+	}
+	return container, nil
+}
+
+// validateCode checks the code of a section as specified by EIP-3670, EIP-4200
+// and EIP-4750: all instructions must be defined with complete immediates, the
+// relative jumps must land on an instruction of the same section, CALLF must
+// target an existing section and the code must end with a terminating
+// instruction. Stack heights are not validated and are checked at runtime.
+func (c *eofContainer) validateCode(container []byte, section int, jt *JumpTable) error {
+	var (
+		code       = container[c.codeOffsets[section] : c.codeOffsets[section]+c.codeSizes[section]]
+		immediates = make(bitvec, len(code)/8+1)
+		dests      []int
+		op         OpCode
+	)
+	for pos := 0; pos < len(code); {
+		op = OpCode(code[pos])
+		if jt[op].undefined && op != INVALID {
+			return fmt.Errorf("%w: %v at %d", errUndefinedInstruction, op, pos)
+		}
+		size := 0
+		switch {
+		case op >= PUSH1 && op <= PUSH32:
+			size = int(op-PUSH1) + 1
+		case op == RJUMP || op == RJUMPI || op == CALLF:
+			size = eofRelativeJumpWidth
+		}
+		if size > 0 && pos+size >= len(code) {
+			return fmt.Errorf("%w: %v at %d", errTruncatedImmediate, op, pos)
+		}
+		switch op {
+		case RJUMP, RJUMPI:
+			dest := pos + 1 + size + int(int16(binary.BigEndian.Uint16(code[pos+1:])))
+			if dest < 0 || dest >= len(code) {
+				return fmt.Errorf("%w: %d at %d", errInvalidJumpDest, dest, pos)
+			}
+			dests = append(dests, dest)
+		case CALLF:
+			if idx := int(binary.BigEndian.Uint16(code[pos+1:])); idx >= len(c.types) {
+				return fmt.Errorf("%w: %d at %d", errInvalidCallTarget, idx, pos)
+			}
+		}
+		for i := 1; i <= size; i++ {
+			immediates.set1(uint64(pos + i))
+		}
+		pos += 1 + size
+	}
+	switch op {
+	case STOP, RETURN, REVERT, INVALID, SELFDESTRUCT, RETF, RJUMP:
+	default:
+		return fmt.Errorf("%w: %v", errInvalidCodeTermination, op)
+	}
+	for _, dest := range dests {
+		if !immediates.codeSegment(uint64(dest)) {
+			return fmt.Errorf("%w: %d points into immediate", errInvalidJumpDest, dest)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// makeEOF assembles an EOF container from its sections.
+func makeEOF(types []functionMetadata, code [][]byte, data []byte) []byte {
+	u16 := func(n int) []byte {
+		return []byte{byte(n >> 8), byte(n)}
+	}
+	out := []byte{eofFormatByte, eofMagicByte, eof1Version}
+	out = append(append(out, kindTypes), u16(len(types)*eofTypeSize)...)
+	out = append(append(out, kindCode), u16(len(code))...)
+	for _, c := range code {
+		out = append(out, u16(len(c))...)
+	}
+	out = append(append(out, kindData), u16(len(data))...)
+	out = append(out, 0)
+	for _, typ := range types {
+		out = append(out, typ.inputs, typ.outputs)
+		out = append(out, u16(int(typ.maxStackHeight))...)
+	}
+	for _, c := range code {
+		out = append(out, c...)
+	}
+	return append(out, data...)
+}
+
+var eofMain = []functionMetadata{{maxStackHeight: 2}}
+
+func TestEOFValidation(t *testing.T) {
+	jt := newLondonInstructionSet()
+	enableEOF(&jt)
+
+	tests := []struct {
+		code []byte
+		err  error
+	}{
+		{makeEOF(eofMain, [][]byte{{byte(STOP)}}, nil), nil},
+		{makeEOF(eofMain, [][]byte{{byte(PUSH1), 1, byte(STOP)}}, []byte{1, 2, 3}), nil},
+		{makeEOF(eofMain, [][]byte{{byte(RJUMP), 0xff, 0xfd}}, nil), nil}, // infinite loop
+		{makeEOF(eofMain, [][]byte{{byte(PUSH1), 1, byte(RJUMPI), 0, 1, byte(INVALID), byte(STOP)}}, nil), nil},
+		{makeEOF([]functionMetadata{{}, {outputs: 1, maxStackHeight: 1}}, [][]byte{{byte(CALLF), 0, 1, byte(STOP)}, {byte(PUSH1), 0, byte(RETF)}}, nil), nil},
+
+		{[]byte{0xef}, errInvalidMagic},
+		{[]byte{0xef, 0x00, 0x02}, errInvalidVersion},
+		{makeEOF(eofMain, [][]byte{{byte(STOP)}}, nil)[:14], errMissingHeader},
+		{append(makeEOF(eofMain, [][]byte{{byte(STOP)}}, nil), 0), errInvalidContainerSize},
+		{makeEOF(eofMain, [][]byte{{byte(STOP)}}, []byte{1})[:20], errInvalidContainerSize},
+		{makeEOF(eofMain, [][]byte{{}}, nil), errEmptyCodeSection},
+		{makeEOF([]functionMetadata{{inputs: 1}}, [][]byte{{byte(STOP)}}, nil), errInvalidSection0Type},
+		{makeEOF(append(eofMain, eofMain...), [][]byte{{byte(STOP)}}, nil), errInvalidTypeSize},
+		{makeEOF(eofMain, [][]byte{{byte(JUMPDEST), byte(PC), byte(STOP)}}, nil), errUndefinedInstruction},
+		{makeEOF(eofMain, [][]byte{{0x0c, byte(STOP)}}, nil), errUndefinedInstruction},
+		{makeEOF(eofMain, [][]byte{{byte(PUSH2), 1}}, nil), errTruncatedImmediate},
+		{makeEOF(eofMain, [][]byte{{byte(PUSH1)}}, nil), errTruncatedImmediate},
+		{makeEOF(eofMain, [][]byte{{byte(RJUMP), 0}}, nil), errTruncatedImmediate},
+		{makeEOF(eofMain, [][]byte{{byte(RJUMP), 0, 1}}, nil), errInvalidJumpDest},
+		{makeEOF(eofMain, [][]byte{{byte(RJUMP), 0xff, 0xf0}}, nil), errInvalidJumpDest},
+		{makeEOF(eofMain, [][]byte{{byte(PUSH1), 1, byte(RJUMPI), 0xff, 0xfc, byte(STOP)}}, nil), errInvalidJumpDest},
+		{makeEOF(eofMain, [][]byte{{byte(CALLF), 0, 1, byte(STOP)}}, nil), errInvalidCallTarget},
+		{makeEOF(eofMain, [][]byte{{byte(PUSH1), 1}}, nil), errInvalidCodeTermination},
+	}
+	for i, tt := range tests {
+		_, err := validateEOF(tt.code, &jt)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+func newEOFTestEVM(eof bool) (*EVM, *state.StateDB) {
+	config := *params.AllEthashProtocolChanges
+	if eof {
+		config.EOFBlock = big.NewInt(0)
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(0),
+	}
+	return NewEVM(vmctx, TxContext{}, statedb, &config, Config{}), statedb
+}
+
+func TestEOFExecution(t *testing.T) {
+	// The main section calls a function returning 42 through a conditional
+	// relative jump, and returns the result in memory.
+	code := makeEOF(
+		[]functionMetadata{{maxStackHeight: 2}, {outputs: 1, maxStackHeight: 2}},
+		[][]byte{
+			{byte(CALLF), 0, 1, byte(PUSH1), 0, byte(MSTORE), byte(PUSH1), 32, byte(PUSH1), 0, byte(RETURN)},
+			{byte(PUSH1), 42, byte(PUSH1), 1, byte(RJUMPI), 0, 1, byte(INVALID), byte(RETF)},
+		},
+		nil,
+	)
+	address := common.BytesToAddress([]byte("contract"))
+	for _, eof := range []bool{true, false} {
+		evm, statedb := newEOFTestEVM(eof)
+		statedb.SetCode(address, code)
+
+		ret, _, err := evm.Call(AccountRef(common.Address{}), address, nil, math.MaxUint64, new(big.Int))
+		if !eof {
+			if _, ok := err.(*ErrInvalidOpCode); !ok {
+				t.Fatalf("legacy execution of EOF code: have error %v, want invalid opcode", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		if have := new(big.Int).SetBytes(ret); have.Cmp(big.NewInt(42)) != 0 {
+			t.Fatalf("result mismatch: have %v, want 42", have)
+		}
+	}
+}
+
+func TestEOFCreate(t *testing.T) {
+	runtime := makeEOF(eofMain, [][]byte{{byte(STOP)}}, nil)
+
+	// initcode copies its data section into memory and returns it
+	initcode := func(deployed []byte) []byte {
+		const codeSize = 12
+		return makeEOF(eofMain, [][]byte{{
+			byte(PUSH1), byte(len(deployed)), byte(PUSH1), eofMinContainerSize + eofTypeSize + codeSize, byte(PUSH1), 0, byte(CODECOPY),
+			byte(PUSH1), byte(len(deployed)), byte(PUSH1), 0, byte(RETURN),
+		}}, deployed)
+	}
+	tests := []struct {
+		code []byte
+		err  error
+	}{
+		{initcode(runtime), nil},
+		{initcode([]byte{byte(STOP)}), ErrLegacyCode},
+		{initcode(runtime[:len(runtime)-1]), ErrInvalidEOFCode},
+		{runtime[:len(runtime)-1], ErrInvalidEOFCode},
+	}
+	for i, tt := range tests {
+		evm, statedb := newEOFTestEVM(true)
+		_, addr, _, err := evm.Create(AccountRef(common.Address{}), tt.code, 1000000, new(big.Int))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err == nil && string(statedb.GetCode(addr)) != string(runtime) {
+			t.Errorf("test %d: deployed code mismatch: have %x, want %x", i, statedb.GetCode(addr), runtime)
+		}
+	}
+}
@@ -36,6 +36,9 @@ var (
 	ErrGasUintOverflow          = errors.New("gas uint64 overflow")
 	ErrInvalidCode              = errors.New("invalid code: must not begin with 0xef")
 	ErrNonceUintOverflow        = errors.New("nonce uint64 overflow")
+	ErrInvalidEOFCode           = errors.New("invalid EOF code")
+	ErrLegacyCode               = errors.New("invalid code: EOF contract must not deploy legacy code")
+	ErrReturnStackExceeded      = errors.New("return stack limit reached")
 
 	// errStopToken is an internal token indicating interpreter loop termination,
 	// never returned to outside callers.
@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"fmt"
 	"math/big"
 	"sync/atomic"
 	"time"
@@ -54,6 +55,12 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 		precompiles = PrecompiledContractsHomestead
 	}
 	p, ok := precompiles[addr]
+	if !ok && evm.chainRules.IsP256Verify {
+		p, ok = PrecompiledContractsP256Verify[addr]
+	}
+	if ok {
+		p = repricePrecompile(p, addr, evm.gasOverrides)
+	}
 	return p, ok
 }
 
@@ -108,6 +115,8 @@ type EVM struct {
 	chainConfig *params.ChainConfig
 	// chain rules contains the chain rules for the current epoch
 	chainRules params.Rules
+	// gas overrides of the chain config active at the current block
+	gasOverrides *params.GasOverrides
 	// virtual machine configuration options used to initialise the
 	// evm.
 	Config Config
@@ -134,6 +143,7 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig
 		chainConfig: chainConfig,
 		chainRules:  chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Random != nil),
 	}
+	evm.gasOverrides = chainConfig.GasOverridesAt(blockCtx.BlockNumber)
 	evm.interpreter = NewEVMInterpreter(evm, config)
 	return evm
 }
@@ -450,15 +460,35 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 
 	start := time.Now()
 
-	ret, err := evm.interpreter.Run(contract, nil, false)
+	// EOF initcode is validated before its execution, invalid containers abort
+	// the creation.
+	var (
+		ret []byte
+		err error
+	)
+	if evm.chainRules.IsEOF && hasEOFMagic(codeAndHash.code) {
+		if contract.container, err = validateEOF(codeAndHash.code, evm.interpreter.eofTable); err != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidEOFCode, err)
+		}
+	}
+	if err == nil {
+		ret, err = evm.interpreter.Run(contract, nil, false)
+	}
 
 	// Check whether the max code size has been exceeded, assign err if the case.
 	if err == nil && evm.chainRules.IsEIP158 && len(ret) > params.MaxCodeSize {
 		err = ErrMaxCodeSizeExceeded
 	}
 
-	// Reject code starting with 0xEF if EIP-3541 is enabled.
-	if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon {
+	// Reject code starting with 0xEF if EIP-3541 is enabled, unless it is a valid
+	// EOF container deployed by EOF initcode. EOF initcode may only deploy EOF.
+	if err == nil && contract.container != nil {
+		if !hasEOFMagic(ret) {
+			err = ErrLegacyCode
+		} else if _, verr := validateEOF(ret, evm.interpreter.eofTable); verr != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidEOFCode, verr)
+		}
+	} else if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon {
 		err = ErrInvalidCode
 	}
 
@@ -0,0 +1,104 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ValidateGasOverrides checks that the gas overrides of the chain config, if any,
+// only reprice known opcodes and precompiles.
+//
+// Only the constant gas of opcodes can be overridden. Opcodes with a dynamic gas
+// component, e.g. for memory expansion or cold state access, are rejected if
+// they have one in the instruction set active at the activation block or in the
+// one of the latest configured fork.
+func ValidateGasOverrides(config *params.ChainConfig) error {
+	overrides := config.GasOverrides
+	if overrides == nil {
+		return nil
+	}
+	activation := overrides.Block
+	if activation == nil {
+		activation = new(big.Int)
+	}
+	sets := []*JumpTable{
+		instructionSetForRules(config.Rules(activation, false)),
+		instructionSetForRules(config.Rules(new(big.Int).SetUint64(math.MaxUint64), config.TerminalTotalDifficulty != nil)),
+	}
+	for name := range overrides.Opcodes {
+		op, ok := stringToOp[name]
+		if !ok {
+			return fmt.Errorf("gas override for unknown opcode %q", name)
+		}
+		for _, set := range sets {
+			if set[op].dynamicGas != nil {
+				return fmt.Errorf("gas override for opcode %q with dynamic gas cost, only constant costs can be overridden", name)
+			}
+		}
+	}
+	for addr := range overrides.Precompiles {
+		_, ok := PrecompiledContractsBerlin[addr]
+		if !ok {
+			_, ok = PrecompiledContractsP256Verify[addr]
+		}
+		if !ok {
+			return fmt.Errorf("gas override for unknown precompile %x", addr)
+		}
+	}
+	return nil
+}
+
+// applyGasOverrides replaces the constant gas of the overridden opcodes in the
+// jump table. The operations are copied, leaving the shared instruction sets
+// untouched.
+func applyGasOverrides(table *JumpTable, opcodes map[string]uint64) {
+	for name, gas := range opcodes {
+		op := StringToOp(name)
+		repriced := *table[op]
+		repriced.constantGas = gas
+		table[op] = &repriced
+	}
+}
+
+// repricedPrecompile is a precompiled contract whose gas cost is overridden by
+// the chain config.
+type repricedPrecompile struct {
+	PrecompiledContract
+	gas uint64
+}
+
+// RequiredGas returns the gas set by the override.
+func (p *repricedPrecompile) RequiredGas(input []byte) uint64 {
+	return p.gas
+}
+
+// repricePrecompile wraps the precompile at addr if its gas cost is overridden.
+func repricePrecompile(p PrecompiledContract, addr common.Address, overrides *params.GasOverrides) PrecompiledContract {
+	if overrides == nil {
+		return p
+	}
+	if gas, ok := overrides.Precompiles[addr]; ok {
+		return &repricedPrecompile{PrecompiledContract: p, gas: gas}
+	}
+	return p
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestValidateGasOverrides(t *testing.T) {
+	tests := []struct {
+		overrides *params.GasOverrides
+		valid     bool
+	}{
+		{nil, true},
+		{&params.GasOverrides{Opcodes: map[string]uint64{"ADD": 5, "STOP": 1}}, true},
+		{&params.GasOverrides{Opcodes: map[string]uint64{"SLOAD": 5000}}, false},  // Dynamic since Berlin
+		{&params.GasOverrides{Opcodes: map[string]uint64{"MSTORE": 5}}, false},    // Memory expansion
+		{&params.GasOverrides{Opcodes: map[string]uint64{"BALANCE": 700}}, false}, // Cold account access
+		{&params.GasOverrides{Precompiles: map[common.Address]uint64{common.BytesToAddress([]byte{1}): 10}}, true},
+		{&params.GasOverrides{Precompiles: map[common.Address]uint64{common.BytesToAddress([]byte{1, 0}): 10}}, true},
+		{&params.GasOverrides{Opcodes: map[string]uint64{"SLOWLOAD": 5000}}, false},
+		{&params.GasOverrides{Precompiles: map[common.Address]uint64{common.BytesToAddress([]byte{0x42}): 10}}, false},
+	}
+	for i, test := range tests {
+		config := *params.TestChainConfig
+		config.GasOverrides = test.overrides
+		if err := ValidateGasOverrides(&config); (err == nil) != test.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, test.valid)
+		}
+	}
+}
+
+func TestGasOverrides(t *testing.T) {
+	ecrecover := common.BytesToAddress([]byte{1})
+
+	config := *params.TestChainConfig
+	config.GasOverrides = &params.GasOverrides{
+		Block:       big.NewInt(10),
+		Opcodes:     map[string]uint64{"ADD": 5},
+		Precompiles: map[common.Address]uint64{ecrecover: 1},
+	}
+	for _, test := range []struct {
+		block    int64
+		add, ecr uint64
+	}{
+		{9, GasFastestStep, params.EcrecoverGas},
+		{10, 5, 1},
+	} {
+		evm := NewEVM(BlockContext{BlockNumber: big.NewInt(test.block)}, TxContext{}, nil, &config, Config{})
+		if have := evm.interpreter.cfg.JumpTable[ADD].constantGas; have != test.add {
+			t.Errorf("block %d: ADD gas mismatch: have %d, want %d", test.block, have, test.add)
+		}
+		p, _ := evm.precompile(ecrecover)
+		if have := p.RequiredGas(nil); have != test.ecr {
+			t.Errorf("block %d: ecrecover gas mismatch: have %d, want %d", test.block, have, test.ecr)
+		}
+	}
+	// The shared instruction set must not be modified
+	if have := londonInstructionSet[ADD].constantGas; have != GasFastestStep {
+		t.Errorf("shared instruction set modified: ADD gas %d", have)
+	}
+}
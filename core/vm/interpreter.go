@@ -22,6 +22,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // Config are the configuration options for the Interpreter
@@ -62,34 +63,42 @@ type EVMInterpreter struct {
 
 	readOnly   bool   // Whether to throw on stateful modifications
 	returnData []byte // Last CALL's return data for subsequent reuse
+
+	eofTable *JumpTable // Instruction set of EOF code, nil before the EOF activation
+}
+
+// instructionSetForRules returns the instruction set of the fork active under
+// the given rules.
+func instructionSetForRules(rules params.Rules) *JumpTable {
+	switch {
+	case rules.IsMerge:
+		return &mergeInstructionSet
+	case rules.IsLondon:
+		return &londonInstructionSet
+	case rules.IsBerlin:
+		return &berlinInstructionSet
+	case rules.IsIstanbul:
+		return &istanbulInstructionSet
+	case rules.IsConstantinople:
+		return &constantinopleInstructionSet
+	case rules.IsByzantium:
+		return &byzantiumInstructionSet
+	case rules.IsEIP158:
+		return &spuriousDragonInstructionSet
+	case rules.IsEIP150:
+		return &tangerineWhistleInstructionSet
+	case rules.IsHomestead:
+		return &homesteadInstructionSet
+	default:
+		return &frontierInstructionSet
+	}
 }
 
 // NewEVMInterpreter returns a new instance of the Interpreter.
 func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	// If jump table was not initialised we set the default one.
 	if cfg.JumpTable == nil {
-		switch {
-		case evm.chainRules.IsMerge:
-			cfg.JumpTable = &mergeInstructionSet
-		case evm.chainRules.IsLondon:
-			cfg.JumpTable = &londonInstructionSet
-		case evm.chainRules.IsBerlin:
-			cfg.JumpTable = &berlinInstructionSet
-		case evm.chainRules.IsIstanbul:
-			cfg.JumpTable = &istanbulInstructionSet
-		case evm.chainRules.IsConstantinople:
-			cfg.JumpTable = &constantinopleInstructionSet
-		case evm.chainRules.IsByzantium:
-			cfg.JumpTable = &byzantiumInstructionSet
-		case evm.chainRules.IsEIP158:
-			cfg.JumpTable = &spuriousDragonInstructionSet
-		case evm.chainRules.IsEIP150:
-			cfg.JumpTable = &tangerineWhistleInstructionSet
-		case evm.chainRules.IsHomestead:
-			cfg.JumpTable = &homesteadInstructionSet
-		default:
-			cfg.JumpTable = &frontierInstructionSet
-		}
+		cfg.JumpTable = instructionSetForRules(evm.chainRules)
 		for i, eip := range cfg.ExtraEips {
 			copy := *cfg.JumpTable
 			if err := EnableEIP(eip, &copy); err != nil {
@@ -99,11 +108,22 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 			}
 			cfg.JumpTable = &copy
 		}
+		if evm.gasOverrides != nil && len(evm.gasOverrides.Opcodes) > 0 {
+			copy := *cfg.JumpTable
+			applyGasOverrides(&copy, evm.gasOverrides.Opcodes)
+			cfg.JumpTable = &copy
+		}
+	}
+	var eofTable *JumpTable
+	if evm.chainRules.IsEOF {
+		copy := *cfg.JumpTable
+		enableEOF(&copy)
+		eofTable = &copy
 	}
-
 	return &EVMInterpreter{
-		evm: evm,
-		cfg: cfg,
+		evm:      evm,
+		cfg:      cfg,
+		eofTable: eofTable,
 	}
 }
 
@@ -133,6 +153,17 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	if len(contract.Code) == 0 {
 		return nil, nil
 	}
+	// EOF code is validated at deployment, only the container needs parsing to
+	// locate the code sections.
+	jumpTable := in.cfg.JumpTable
+	if in.eofTable != nil && hasEOFMagic(contract.Code) {
+		if contract.container == nil {
+			if contract.container, err = parseEOF(contract.Code); err != nil {
+				return nil, err
+			}
+		}
+		jumpTable = in.eofTable
+	}
 
 	var (
 		op          OpCode        // current opcode
@@ -162,6 +193,9 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	}()
 	contract.Input = input
 
+	if contract.container != nil {
+		pc = contract.container.codeOffsets[0]
+	}
 	if in.cfg.Debug {
 		defer func() {
 			if err != nil {
@@ -185,7 +219,7 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
 		op = contract.GetOp(pc)
-		operation := in.cfg.JumpTable[op]
+		operation := jumpTable[op]
 		cost = operation.constantGas // For tracing
 		// Validate stack
 		if sLen := stack.len(); sLen < operation.minStack {
@@ -119,6 +119,8 @@ const (
 	MSIZE    OpCode = 0x59
 	GAS      OpCode = 0x5a
 	JUMPDEST OpCode = 0x5b
+	RJUMP    OpCode = 0x5c
+	RJUMPI   OpCode = 0x5d
 	PUSH0    OpCode = 0x5f
 )
 
@@ -207,6 +209,12 @@ const (
 	LOG4
 )
 
+// 0xb0 range - EOF functions.
+const (
+	CALLF OpCode = 0xb0
+	RETF  OpCode = 0xb1
+)
+
 // 0xf0 range - closures.
 const (
 	CREATE       OpCode = 0xf0
@@ -301,6 +309,8 @@ var opCodeToString = map[OpCode]string{
 	MSIZE:    "MSIZE",
 	GAS:      "GAS",
 	JUMPDEST: "JUMPDEST",
+	RJUMP:    "RJUMP",
+	RJUMPI:   "RJUMPI",
 	PUSH0:    "PUSH0",
 
 	// 0x60 range - push.
@@ -376,6 +386,10 @@ var opCodeToString = map[OpCode]string{
 	LOG3:   "LOG3",
 	LOG4:   "LOG4",
 
+	// 0xb0 range.
+	CALLF: "CALLF",
+	RETF:  "RETF",
+
 	// 0xf0 range.
 	CREATE:       "CREATE",
 	CALL:         "CALL",
@@ -465,6 +479,8 @@ var stringToOp = map[string]OpCode{
 	"MSIZE":          MSIZE,
 	"GAS":            GAS,
 	"JUMPDEST":       JUMPDEST,
+	"RJUMP":          RJUMP,
+	"RJUMPI":         RJUMPI,
 	"PUSH0":          PUSH0,
 	"PUSH1":          PUSH1,
 	"PUSH2":          PUSH2,
@@ -535,6 +551,8 @@ var stringToOp = map[string]OpCode{
 	"LOG2":           LOG2,
 	"LOG3":           LOG3,
 	"LOG4":           LOG4,
+	"CALLF":          CALLF,
+	"RETF":           RETF,
 	"CREATE":         CREATE,
 	"CREATE2":        CREATE2,
 	"CALL":           CALL,
@@ -0,0 +1,249 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bls implements BLS signatures over the BLS12-381 curve, following the
+// proof of possession scheme of the IETF BLS signature draft with the ciphersuite
+// used by the Ethereum consensus layer: public keys are G1 points, signatures are
+// G2 points and both are serialized in the compressed ZCash format.
+package bls
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// SecretKeyLength is the length of a serialized secret key.
+	SecretKeyLength = 32
+
+	// PublicKeyLength is the length of a serialized public key.
+	PublicKeyLength = 48
+
+	// SignatureLength is the length of a serialized signature.
+	SignatureLength = 96
+)
+
+var (
+	// signatureDST is the domain separation tag of message signatures.
+	signatureDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+	// popDST is the domain separation tag of proofs of possession.
+	popDST = []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+	// keyGenSalt is the initial salt of the key derivation.
+	keyGenSalt = []byte("BLS-SIG-KEYGEN-SALT-")
+)
+
+var (
+	errShortIKM          = errors.New("bls: key material shorter than 32 bytes")
+	errInvalidSecretKey  = errors.New("bls: invalid secret key")
+	errInfinityPublicKey = errors.New("bls: public key is the point at infinity")
+	errNoSignatures      = errors.New("bls: no signatures to aggregate")
+)
+
+// SecretKey is a BLS secret key.
+type SecretKey struct {
+	k *big.Int
+}
+
+// PublicKey is a BLS public key.
+type PublicKey struct {
+	p *bls12381.PointG1
+}
+
+// Signature is a BLS signature, or an aggregate of signatures.
+//
+// The points of keys and signatures are kept in affine form, so that they are
+// not modified by serialization or pairing and can be used concurrently.
+type Signature struct {
+	p *bls12381.PointG2
+}
+
+// KeyGen derives a secret key from at least 32 bytes of secret key material, as
+// defined by the KeyGen procedure of the IETF BLS signature draft.
+func KeyGen(ikm []byte) (*SecretKey, error) {
+	if len(ikm) < 32 {
+		return nil, errShortIKM
+	}
+	var (
+		salt = keyGenSalt
+		sk   = new(big.Int)
+		okm  = make([]byte, 48)
+	)
+	secret := append(append([]byte{}, ikm...), 0)
+	for sk.Sign() == 0 {
+		h := sha256.Sum256(salt)
+		salt = h[:]
+
+		prk := hkdf.Extract(sha256.New, secret, salt)
+		if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte{0, byte(len(okm))}), okm); err != nil {
+			return nil, err
+		}
+		sk.SetBytes(okm)
+		sk.Mod(sk, bls12381.NewG1().Q())
+	}
+	return &SecretKey{k: sk}, nil
+}
+
+// GenerateKey creates a secret key from random key material.
+func GenerateKey() (*SecretKey, error) {
+	ikm := make([]byte, 32)
+	if _, err := rand.Read(ikm); err != nil {
+		return nil, err
+	}
+	return KeyGen(ikm)
+}
+
+// SecretKeyFromBytes parses a secret key, serialized as a big endian integer.
+func SecretKeyFromBytes(b []byte) (*SecretKey, error) {
+	if len(b) != SecretKeyLength {
+		return nil, errInvalidSecretKey
+	}
+	k := new(big.Int).SetBytes(b)
+	if k.Sign() == 0 || k.Cmp(bls12381.NewG1().Q()) >= 0 {
+		return nil, errInvalidSecretKey
+	}
+	return &SecretKey{k: k}, nil
+}
+
+// Bytes returns the serialized secret key.
+func (sk *SecretKey) Bytes() []byte {
+	b := make([]byte, SecretKeyLength)
+	return sk.k.FillBytes(b)
+}
+
+// PublicKey returns the public key belonging to the secret key.
+func (sk *SecretKey) PublicKey() *PublicKey {
+	g := bls12381.NewG1()
+	return &PublicKey{p: g.Affine(g.MulScalar(g.New(), g.One(), sk.k))}
+}
+
+// PublicKeyFromBytes parses a compressed public key, validating that it is a
+// point of the G1 subgroup other than the point at infinity.
+func PublicKeyFromBytes(b []byte) (*PublicKey, error) {
+	g := bls12381.NewG1()
+	p, err := g.FromCompressed(b)
+	if err != nil {
+		return nil, err
+	}
+	if g.IsZero(p) {
+		return nil, errInfinityPublicKey
+	}
+	return &PublicKey{p: p}, nil
+}
+
+// Bytes returns the compressed public key.
+func (pk *PublicKey) Bytes() []byte {
+	return bls12381.NewG1().ToCompressed(pk.p)
+}
+
+// SignatureFromBytes parses a compressed signature, validating that it is a
+// point of the G2 subgroup.
+func SignatureFromBytes(b []byte) (*Signature, error) {
+	p, err := bls12381.NewG2().FromCompressed(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{p: p}, nil
+}
+
+// Bytes returns the compressed signature.
+func (sig *Signature) Bytes() []byte {
+	return bls12381.NewG2().ToCompressed(sig.p)
+}
+
+// Sign signs a message.
+func Sign(sk *SecretKey, msg []byte) *Signature {
+	return sign(sk, msg, signatureDST)
+}
+
+// Verify checks that the signature of the message was created by the secret key
+// of the public key.
+func Verify(pk *PublicKey, msg []byte, sig *Signature) bool {
+	return verify(pk.p, msg, sig, signatureDST)
+}
+
+// Aggregate combines signatures into a single signature.
+func Aggregate(sigs []*Signature) (*Signature, error) {
+	if len(sigs) == 0 {
+		return nil, errNoSignatures
+	}
+	g := bls12381.NewG2()
+	agg := g.Zero()
+	for _, sig := range sigs {
+		g.Add(agg, agg, sig.p)
+	}
+	return &Signature{p: g.Affine(agg)}, nil
+}
+
+// FastAggregateVerify checks an aggregate signature of the same message by all
+// the public keys.
+//
+// The check is only secure if the possession of the secret keys was proven for
+// all public keys, e.g. by verifying a proof created with PopProve, as it can be
+// forged by crafting public keys otherwise.
+func FastAggregateVerify(pks []*PublicKey, msg []byte, sig *Signature) bool {
+	if len(pks) == 0 {
+		return false
+	}
+	g := bls12381.NewG1()
+	agg := g.Zero()
+	for _, pk := range pks {
+		g.Add(agg, agg, pk.p)
+	}
+	return verify(agg, msg, sig, signatureDST)
+}
+
+// PopProve creates a proof of possession of the secret key.
+func PopProve(sk *SecretKey) *Signature {
+	return sign(sk, sk.PublicKey().Bytes(), popDST)
+}
+
+// PopVerify checks a proof of possession of the secret key of the public key.
+func PopVerify(pk *PublicKey, proof *Signature) bool {
+	return verify(pk.p, pk.Bytes(), proof, popDST)
+}
+
+func sign(sk *SecretKey, msg, dst []byte) *Signature {
+	g := bls12381.NewG2()
+	h, err := g.HashToCurve(msg, dst)
+	if err != nil {
+		panic(err) // Only fails for invalid domain separation tags
+	}
+	return &Signature{p: g.Affine(g.MulScalar(g.New(), h, sk.k))}
+}
+
+// verify checks e(pk, H(msg)) == e(g1, sig).
+func verify(pk *bls12381.PointG1, msg []byte, sig *Signature, dst []byte) bool {
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	if g1.IsZero(pk) {
+		return false
+	}
+	h, err := g2.HashToCurve(msg, dst)
+	if err != nil {
+		return false
+	}
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(pk, h)
+	engine.AddPairInv(g1.One(), sig.p)
+	return engine.Check()
+}
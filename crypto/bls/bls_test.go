@@ -0,0 +1,141 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSerialization(t *testing.T) {
+	// The secret key 1 has the generator of G1 as its public key
+	sk, err := SecretKeyFromBytes(common.LeftPadBytes([]byte{1}, SecretKeyLength))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.FromHex("97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb")
+	if have := sk.PublicKey().Bytes(); !bytes.Equal(have, want) {
+		t.Fatalf("public key mismatch: have %x, want %x", have, want)
+	}
+	pk, err := PublicKeyFromBytes(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := Sign(sk, []byte("message"))
+	dec, err := SignatureFromBytes(sig.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(pk, []byte("message"), dec) {
+		t.Fatal("deserialized signature invalid")
+	}
+	// Invalid keys should be rejected
+	if _, err := SecretKeyFromBytes(make([]byte, SecretKeyLength)); err == nil {
+		t.Fatal("zero secret key accepted")
+	}
+	infinity := make([]byte, PublicKeyLength)
+	infinity[0] = 0xc0
+	if _, err := PublicKeyFromBytes(infinity); err == nil {
+		t.Fatal("public key at infinity accepted")
+	}
+}
+
+// Tests interoperability with the signatures of the Ethereum consensus layer.
+func TestSignKnownAnswer(t *testing.T) {
+	sk, err := SecretKeyFromBytes(common.FromHex("263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.FromHex("b6ed936746e01f8ecf281f020953fbf1f01debd5657c4a383940b020b26507f6076334f91e2366c96e9ab279fb5158090352ea1c5b0c9274504f4f0e7053af24802e51e4568d164fe986834f41e55c8e850ce1f98458c0cfc9ab380b55285a55")
+	if have := Sign(sk, make([]byte, 32)).Bytes(); !bytes.Equal(have, want) {
+		t.Fatalf("signature mismatch: have %x, want %x", have, want)
+	}
+}
+
+func TestKeyGen(t *testing.T) {
+	ikm := bytes.Repeat([]byte{0x42}, 32)
+	sk1, err := KeyGen(ikm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk2, err := KeyGen(ikm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sk1.Bytes(), sk2.Bytes()) {
+		t.Fatal("key generation not deterministic")
+	}
+	if _, err := KeyGen(ikm[:31]); err == nil {
+		t.Fatal("short key material accepted")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	sk, _ := GenerateKey()
+	other, _ := GenerateKey()
+
+	msg := []byte("message")
+	sig := Sign(sk, msg)
+	if !Verify(sk.PublicKey(), msg, sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if Verify(sk.PublicKey(), []byte("other message"), sig) {
+		t.Fatal("signature of other message accepted")
+	}
+	if Verify(other.PublicKey(), msg, sig) {
+		t.Fatal("signature of other key accepted")
+	}
+	// Proofs of possession must not be usable as signatures
+	proof := PopProve(sk)
+	if !PopVerify(sk.PublicKey(), proof) {
+		t.Fatal("valid proof of possession rejected")
+	}
+	if Verify(sk.PublicKey(), sk.PublicKey().Bytes(), proof) {
+		t.Fatal("proof of possession accepted as signature")
+	}
+}
+
+func TestFastAggregateVerify(t *testing.T) {
+	var (
+		msg  = []byte("message")
+		pks  []*PublicKey
+		sigs []*Signature
+	)
+	for i := 0; i < 4; i++ {
+		sk, _ := GenerateKey()
+		pks = append(pks, sk.PublicKey())
+		sigs = append(sigs, Sign(sk, msg))
+	}
+	agg, err := Aggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !FastAggregateVerify(pks, msg, agg) {
+		t.Fatal("valid aggregate signature rejected")
+	}
+	if FastAggregateVerify(pks[1:], msg, agg) {
+		t.Fatal("aggregate signature accepted with missing signer")
+	}
+	if FastAggregateVerify(nil, msg, agg) {
+		t.Fatal("aggregate signature accepted without signers")
+	}
+	if _, err := Aggregate(nil); err == nil {
+		t.Fatal("empty aggregate created")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"errors"
+)
+
+// Flags of the compressed point encoding, set in the most significant bits of
+// the first byte.
+const (
+	flagCompressed = 0x80 // Set for compressed encodings
+	flagInfinity   = 0x40 // Set for the point at infinity
+	flagLargestY   = 0x20 // Set if y is the lexicographically largest root
+	flagMask       = 0xe0
+)
+
+// ToCompressed serializes a G1 point into 48 bytes, in the compressed form
+// defined by the ZCash serialization format.
+func (g *G1) ToCompressed(p *PointG1) []byte {
+	out := make([]byte, 48)
+	if g.IsZero(p) {
+		out[0] = flagCompressed | flagInfinity
+		return out
+	}
+	g.Affine(p)
+	copy(out, toBytes(&p[0]))
+	out[0] |= flagCompressed
+	if isLargest(&p[1]) {
+		out[0] |= flagLargestY
+	}
+	return out
+}
+
+// FromCompressed deserializes a G1 point in compressed form. The point is
+// checked to be on the curve and in the correct subgroup.
+func (g *G1) FromCompressed(in []byte) (*PointG1, error) {
+	if len(in) != 48 {
+		return nil, errors.New("compressed g1 point should be 48 bytes")
+	}
+	buf := make([]byte, 48)
+	copy(buf, in)
+	flags := buf[0] & flagMask
+	buf[0] &^= flagMask
+
+	if flags&flagCompressed == 0 {
+		return nil, errors.New("compression flag not set")
+	}
+	if flags&flagInfinity != 0 {
+		if flags&flagLargestY != 0 || !isZeroBytes(buf) {
+			return nil, errors.New("invalid encoding of point at infinity")
+		}
+		return g.Zero(), nil
+	}
+	x, err := fromBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	// Recover y from y^2 = x^3 + b
+	y := new(fe)
+	square(y, x)
+	mul(y, y, x)
+	add(y, y, b)
+	if !sqrt(y, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	if isLargest(y) != (flags&flagLargestY != 0) {
+		neg(y, y)
+	}
+	p := &PointG1{*x, *y, *new(fe).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in correct subgroup")
+	}
+	return p, nil
+}
+
+// ToCompressed serializes a G2 point into 96 bytes, in the compressed form
+// defined by the ZCash serialization format.
+func (g *G2) ToCompressed(p *PointG2) []byte {
+	out := make([]byte, 96)
+	if g.IsZero(p) {
+		out[0] = flagCompressed | flagInfinity
+		return out
+	}
+	g.Affine(p)
+	copy(out, g.f.toBytes(&p[0]))
+	out[0] |= flagCompressed
+	if isLargest2(&p[1]) {
+		out[0] |= flagLargestY
+	}
+	return out
+}
+
+// FromCompressed deserializes a G2 point in compressed form. The point is
+// checked to be on the curve and in the correct subgroup.
+func (g *G2) FromCompressed(in []byte) (*PointG2, error) {
+	if len(in) != 96 {
+		return nil, errors.New("compressed g2 point should be 96 bytes")
+	}
+	buf := make([]byte, 96)
+	copy(buf, in)
+	flags := buf[0] & flagMask
+	buf[0] &^= flagMask
+
+	if flags&flagCompressed == 0 {
+		return nil, errors.New("compression flag not set")
+	}
+	if flags&flagInfinity != 0 {
+		if flags&flagLargestY != 0 || !isZeroBytes(buf) {
+			return nil, errors.New("invalid encoding of point at infinity")
+		}
+		return g.Zero(), nil
+	}
+	x, err := g.f.fromBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	// Recover y from y^2 = x^3 + b
+	y := new(fe2)
+	g.f.square(y, x)
+	g.f.mul(y, y, x)
+	g.f.add(y, y, b2)
+	if !g.f.sqrt(y, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	if isLargest2(y) != (flags&flagLargestY != 0) {
+		g.f.neg(y, y)
+	}
+	p := &PointG2{*x, *y, *new(fe2).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in correct subgroup")
+	}
+	return p, nil
+}
+
+// isLargest reports whether the element is larger than its negation.
+func isLargest(e *fe) bool {
+	r := new(fe)
+	fromMont(r, e)
+	return r.big().Cmp(pMinus1Over2) > 0
+}
+
+// isLargest2 reports whether the element is lexicographically larger than its
+// negation, comparing the imaginary parts first.
+func isLargest2(e *fe2) bool {
+	if !e[1].isZero() {
+		return isLargest(&e[1])
+	}
+	return isLargest(&e[0])
+}
+
+func isZeroBytes(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
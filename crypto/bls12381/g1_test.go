@@ -53,6 +53,36 @@ func TestG1Serialization(t *testing.T) {
 	}
 }
 
+func TestG1CompressedSerialization(t *testing.T) {
+	g := NewG1()
+	for i := 0; i < fuz; i++ {
+		a := g.rand()
+		b, err := g.FromCompressed(g.ToCompressed(a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g.Equal(a, b) {
+			t.Fatal("bad compressed serialization")
+		}
+	}
+	zero, err := g.FromCompressed(g.ToCompressed(g.Zero()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.IsZero(zero) {
+		t.Fatal("bad compressed serialization of infinity")
+	}
+	// Uncompressed and malformed encodings of infinity should be rejected
+	if _, err := g.FromCompressed(make([]byte, 48)); err == nil {
+		t.Fatal("expected error for missing compression flag")
+	}
+	invalid := make([]byte, 48)
+	invalid[0], invalid[47] = 0xc0, 1
+	if _, err := g.FromCompressed(invalid); err == nil {
+		t.Fatal("expected error for invalid infinity encoding")
+	}
+}
+
 func TestG1IsOnCurve(t *testing.T) {
 	g := NewG1()
 	zero := g.Zero()
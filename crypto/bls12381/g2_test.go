@@ -55,6 +55,36 @@ func TestG2Serialization(t *testing.T) {
 	}
 }
 
+func TestG2CompressedSerialization(t *testing.T) {
+	g := NewG2()
+	for i := 0; i < fuz; i++ {
+		a := g.rand()
+		b, err := g.FromCompressed(g.ToCompressed(a))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g.Equal(a, b) {
+			t.Fatal("bad compressed serialization")
+		}
+	}
+	zero, err := g.FromCompressed(g.ToCompressed(g.Zero()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.IsZero(zero) {
+		t.Fatal("bad compressed serialization of infinity")
+	}
+	// Uncompressed and malformed encodings of infinity should be rejected
+	if _, err := g.FromCompressed(make([]byte, 96)); err == nil {
+		t.Fatal("expected error for missing compression flag")
+	}
+	invalid := make([]byte, 96)
+	invalid[0], invalid[95] = 0xc0, 1
+	if _, err := g.FromCompressed(invalid); err == nil {
+		t.Fatal("expected error for invalid infinity encoding")
+	}
+}
+
 func TestG2IsOnCurve(t *testing.T) {
 	g := NewG2()
 	zero := g.Zero()
@@ -255,6 +285,29 @@ func TestG2MapToCurve(t *testing.T) {
 	}
 }
 
+func TestG2HashToCurve(t *testing.T) {
+	// Test vectors of the BLS12381G2_XMD:SHA-256_SSWU_RO_ suite from RFC 9380
+	dst := []byte("QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_RO_")
+	for i, v := range []struct {
+		msg      []byte
+		expected []byte
+	}{
+		{
+			msg:      []byte(""),
+			expected: common.FromHex("05cb8437535e20ecffaef7752baddf98034139c38452458baeefab379ba13dff5bf5dd71b72418717047f5b0f37da03d" + "0141ebfbdca40eb85b87142e130ab689c673cf60f1a3e98d69335266f30d9b8d4ac44c1038e9dcdd5393faf5c41fb78a" + "12424ac32561493f3fe3c260708a12b7c620e7be00099a974e259ddc7d1f6395c3c811cdd19f1e8dbf3e9ecfdcbab8d6" + "0503921d7f6a12805e72940b963c0cf3471c7b2a524950ca195d11062ee75ec076daf2d4bc358c4b190c0c98064fdd92"),
+		},
+	} {
+		g := NewG2()
+		p, err := g.HashToCurve(v.msg, dst)
+		if err != nil {
+			t.Fatal("hash to curve fails", i, err)
+		}
+		if !bytes.Equal(g.ToBytes(p), v.expected) {
+			t.Fatal("hash to curve fails", i)
+		}
+	}
+}
+
 func BenchmarkG2Add(t *testing.B) {
 	g2 := NewG2()
 	a, b, c := g2.rand(), g2.rand(), PointG2{}
@@ -0,0 +1,107 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// HashToCurve hashes a message to a G2 point with the given domain separation
+// tag, as defined by the BLS12381G2_XMD:SHA-256_SSWU_RO_ suite of RFC 9380.
+func (g *G2) HashToCurve(msg, dst []byte) (*PointG2, error) {
+	u, err := hashToFp2(msg, dst, 2)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]*PointG2, len(u))
+	for i := range u {
+		x, y := swuMapG2(g.f, u[i])
+		isogenyMapG2(g.f, x, y)
+		points[i] = &PointG2{*x, *y, *new(fe2).one()}
+	}
+	p := g.Add(g.New(), points[0], points[1])
+	g.ClearCofactor(p)
+	return g.Affine(p), nil
+}
+
+// hashToFp2 hashes a message to count elements of Fp2.
+func hashToFp2(msg, dst []byte, count int) ([]*fe2, error) {
+	const l = 64 // Bytes per base field element, ceil((ceil(log2(p)) + k) / 8) for k = 128
+	uniform, err := expandMsgXMD(msg, dst, count*2*l)
+	if err != nil {
+		return nil, err
+	}
+	p := modulus.big()
+	elems := make([]*fe2, count)
+	for i := range elems {
+		elems[i] = new(fe2)
+		for j := 0; j < 2; j++ {
+			offset := l * (j + i*2)
+			v := new(big.Int).SetBytes(uniform[offset : offset+l])
+			e, err := fromBig(v.Mod(v, p))
+			if err != nil {
+				return nil, err
+			}
+			elems[i][j] = *e
+		}
+	}
+	return elems, nil
+}
+
+// expandMsgXMD implements expand_message_xmd of RFC 9380 with SHA-256.
+func expandMsgXMD(msg, dst []byte, length int) ([]byte, error) {
+	const (
+		hashSize  = sha256.Size
+		blockSize = sha256.BlockSize
+	)
+	ell := (length + hashSize - 1) / hashSize
+	if ell > 255 || length > 65535 || len(dst) > 255 {
+		return nil, errors.New("invalid expand_message_xmd parameters")
+	}
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	h := sha256.New()
+	h.Write(make([]byte, blockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(length >> 8), byte(length), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{1})
+	h.Write(dstPrime)
+	bi := h.Sum(nil)
+
+	out := make([]byte, 0, ell*hashSize)
+	out = append(out, bi...)
+	for i := 2; i <= ell; i++ {
+		tmp := make([]byte, hashSize)
+		for j := range tmp {
+			tmp[j] = b0[j] ^ bi[j]
+		}
+		h.Reset()
+		h.Write(tmp)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:length], nil
+}
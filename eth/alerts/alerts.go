@@ -0,0 +1,285 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alerts implements built-in stall detectors for the execution layer.
+//
+// Every detector sets a dedicated gauge to 1 while firing and 0 otherwise, so
+// alerts can be defined on the metrics directly, and optionally posts state
+// changes to a webhook, so simple setups need no external rules engine.
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// checkInterval is the time between two evaluations of the detectors.
+	checkInterval = 5 * time.Second
+
+	// webhookTimeout is the time allowed for delivering a webhook notification.
+	webhookTimeout = 10 * time.Second
+)
+
+// Names of the built-in detectors.
+const (
+	HeadStalled       = "head_stalled"
+	PeersLow          = "peers_low"
+	TxPoolRejects     = "txpool_rejects"
+	ForkchoiceStalled = "forkchoice_stalled"
+)
+
+var (
+	headStalledGauge       = metrics.NewRegisteredGauge("alerts/head/stalled", nil)
+	peersLowGauge          = metrics.NewRegisteredGauge("alerts/peers/low", nil)
+	txpoolRejectsGauge     = metrics.NewRegisteredGauge("alerts/txpool/rejects", nil)
+	forkchoiceStalledGauge = metrics.NewRegisteredGauge("alerts/forkchoice/stalled", nil)
+)
+
+// rejectMeters are the transaction pool meters counting rejected transactions.
+var rejectMeters = []string{"txpool/invalid", "txpool/underpriced", "txpool/overflowed"}
+
+// Config contains the thresholds of the stall detectors. Detectors with a zero
+// threshold are disabled.
+type Config struct {
+	HeadTimeout       time.Duration `toml:",omitempty"` // Time without a new chain head before alerting
+	MinPeers          int           `toml:",omitempty"` // Peer count below which to alert
+	RejectRate        float64       `toml:",omitempty"` // Transaction pool rejections per second above which to alert
+	ForkchoiceTimeout time.Duration `toml:",omitempty"` // Time without a forkchoice update after the merge before alerting
+	Webhook           string        `toml:",omitempty"` // HTTP URL to post alert state changes to
+}
+
+// Enabled returns whether any detector is configured.
+func (c *Config) Enabled() bool {
+	return c.HeadTimeout > 0 || c.MinPeers > 0 || c.RejectRate > 0 || c.ForkchoiceTimeout > 0
+}
+
+// Backend is the node the detectors monitor.
+type Backend interface {
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+	PeerCount() int
+	Merged() bool
+}
+
+// Notification is the JSON payload posted to the webhook when an alert starts or
+// stops firing.
+type Notification struct {
+	Alert   string    `json:"alert"`
+	Firing  bool      `json:"firing"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Monitor periodically evaluates the stall detectors.
+type Monitor struct {
+	config  Config
+	backend Backend
+	rejects func() int64 // Cumulative number of rejected transactions
+
+	lock           sync.Mutex
+	lastHead       time.Time
+	lastForkchoice time.Time
+	lastRejects    int64
+	lastCheck      time.Time
+	firing         map[string]bool
+
+	client *http.Client
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New creates a monitor for the node. It returns nil if no detector is enabled.
+func New(config Config, backend Backend) *Monitor {
+	if !config.Enabled() {
+		return nil
+	}
+	now := time.Now()
+	return &Monitor{
+		config:         config,
+		backend:        backend,
+		rejects:        txpoolRejects,
+		lastHead:       now,
+		lastForkchoice: now,
+		lastRejects:    txpoolRejects(),
+		lastCheck:      now,
+		firing:         make(map[string]bool),
+		client:         &http.Client{Timeout: webhookTimeout},
+		quit:           make(chan struct{}),
+	}
+}
+
+// txpoolRejects sums the transaction pool rejection meters. The meters are only
+// updated if metrics collection is enabled.
+func txpoolRejects() int64 {
+	var total int64
+	for _, name := range rejectMeters {
+		if meter, ok := metrics.DefaultRegistry.Get(name).(metrics.Meter); ok {
+			total += meter.Count()
+		}
+	}
+	return total
+}
+
+// Start launches the background detector loop.
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the background detector loop.
+func (m *Monitor) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// ForkchoiceUpdated notifies the monitor of a forkchoice update from the
+// consensus layer.
+func (m *Monitor) ForkchoiceUpdated() {
+	m.lock.Lock()
+	m.lastForkchoice = time.Now()
+	m.lock.Unlock()
+}
+
+// Firing returns the names of the currently firing alerts.
+func (m *Monitor) Firing() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var names []string
+	for _, name := range []string{HeadStalled, PeersLow, TxPoolRejects, ForkchoiceStalled} {
+		if m.firing[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (m *Monitor) loop() {
+	defer m.wg.Done()
+
+	headCh := make(chan core.ChainHeadEvent, 10)
+	sub := m.backend.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-headCh:
+			m.lock.Lock()
+			m.lastHead = time.Now()
+			m.lock.Unlock()
+		case now := <-ticker.C:
+			m.check(now)
+		case <-sub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// check evaluates all enabled detectors at the given time.
+func (m *Monitor) check(now time.Time) {
+	m.lock.Lock()
+	var (
+		sinceHead       = now.Sub(m.lastHead)
+		sinceForkchoice = now.Sub(m.lastForkchoice)
+		rejects         = m.rejects()
+		rate            float64
+	)
+	if elapsed := now.Sub(m.lastCheck); elapsed > 0 {
+		rate = float64(rejects-m.lastRejects) / elapsed.Seconds()
+	}
+	m.lastRejects, m.lastCheck = rejects, now
+	m.lock.Unlock()
+
+	if m.config.HeadTimeout > 0 {
+		m.set(HeadStalled, headStalledGauge, sinceHead > m.config.HeadTimeout,
+			fmt.Sprintf("no new chain head for %v", sinceHead.Round(time.Second)))
+	}
+	if m.config.MinPeers > 0 {
+		peers := m.backend.PeerCount()
+		m.set(PeersLow, peersLowGauge, peers < m.config.MinPeers,
+			fmt.Sprintf("%d peers connected, minimum %d", peers, m.config.MinPeers))
+	}
+	if m.config.RejectRate > 0 {
+		m.set(TxPoolRejects, txpoolRejectsGauge, rate > m.config.RejectRate,
+			fmt.Sprintf("%.2f transactions rejected per second, maximum %.2f", rate, m.config.RejectRate))
+	}
+	if m.config.ForkchoiceTimeout > 0 && m.backend.Merged() {
+		m.set(ForkchoiceStalled, forkchoiceStalledGauge, sinceForkchoice > m.config.ForkchoiceTimeout,
+			fmt.Sprintf("no forkchoice update for %v", sinceForkchoice.Round(time.Second)))
+	}
+}
+
+// set updates the state of an alert, notifying the webhook if it changed.
+func (m *Monitor) set(name string, gauge metrics.Gauge, firing bool, message string) {
+	if firing {
+		gauge.Update(1)
+	} else {
+		gauge.Update(0)
+	}
+	m.lock.Lock()
+	changed := m.firing[name] != firing
+	m.firing[name] = firing
+	m.lock.Unlock()
+
+	if !changed {
+		return
+	}
+	if firing {
+		log.Warn("Alert firing", "alert", name, "reason", message)
+	} else {
+		log.Info("Alert resolved", "alert", name, "status", message)
+	}
+	if m.config.Webhook != "" {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			if err := m.notify(&Notification{Alert: name, Firing: firing, Message: message, Time: time.Now()}); err != nil {
+				log.Warn("Failed to deliver alert notification", "alert", name, "err", err)
+			}
+		}()
+	}
+}
+
+// notify posts an alert notification to the webhook.
+func (m *Monitor) notify(n *Notification) error {
+	blob, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	res, err := m.client.Post(m.config.Webhook, "application/json", bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected HTTP status %s", res.Status)
+	}
+	return nil
+}
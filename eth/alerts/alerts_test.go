@@ -0,0 +1,118 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+type testBackend struct {
+	feed   event.Feed
+	peers  int
+	merged bool
+}
+
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.feed.Subscribe(ch)
+}
+
+func (b *testBackend) PeerCount() int { return b.peers }
+func (b *testBackend) Merged() bool   { return b.merged }
+
+func TestDetectors(t *testing.T) {
+	notes := make(chan *Notification, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := new(Notification)
+		if err := json.NewDecoder(r.Body).Decode(n); err != nil {
+			t.Errorf("failed to decode notification: %v", err)
+		}
+		notes <- n
+	}))
+	defer server.Close()
+
+	var (
+		backend = &testBackend{peers: 10}
+		rejects int64
+		config  = Config{
+			HeadTimeout:       time.Minute,
+			MinPeers:          5,
+			RejectRate:        10,
+			ForkchoiceTimeout: time.Minute,
+			Webhook:           server.URL,
+		}
+	)
+	if New(Config{Webhook: server.URL}, backend) != nil {
+		t.Fatal("monitor created without detectors")
+	}
+	monitor := New(config, backend)
+	monitor.rejects = func() int64 { return rejects }
+	monitor.lastRejects = 0
+
+	start := monitor.lastCheck
+	monitor.check(start.Add(10 * time.Second))
+	if firing := monitor.Firing(); len(firing) != 0 {
+		t.Fatalf("healthy node alerting: %v", firing)
+	}
+	// Stall everything, the forkchoice should only be monitored after the merge
+	backend.peers = 2
+	rejects = 10000
+	monitor.check(start.Add(2 * time.Minute))
+	if firing, want := monitor.Firing(), []string{HeadStalled, PeersLow, TxPoolRejects}; !reflect.DeepEqual(firing, want) {
+		t.Fatalf("firing alerts mismatch: have %v, want %v", firing, want)
+	}
+	backend.merged = true
+	monitor.check(start.Add(3 * time.Minute))
+	if firing, want := monitor.Firing(), []string{HeadStalled, PeersLow, ForkchoiceStalled}; !reflect.DeepEqual(firing, want) {
+		t.Fatalf("firing alerts mismatch: have %v, want %v", firing, want)
+	}
+	// Recover and ensure all alerts are resolved
+	backend.peers = 10
+	monitor.lastHead = start.Add(3 * time.Minute)
+	monitor.lastForkchoice = start.Add(3 * time.Minute)
+	monitor.check(start.Add(3*time.Minute + 10*time.Second))
+	if firing := monitor.Firing(); len(firing) != 0 {
+		t.Fatalf("recovered node alerting: %v", firing)
+	}
+	monitor.wg.Wait()
+
+	// Every change should have been notified: 4 alerts firing and resolving
+	fired := make(map[string]int)
+	for len(notes) > 0 {
+		n := <-notes
+		if n.Firing {
+			fired[n.Alert]++
+		} else {
+			fired[n.Alert]--
+		}
+	}
+	if len(fired) != 4 {
+		t.Fatalf("notified alerts mismatch: have %v, want 4 alerts", fired)
+	}
+	for name, balance := range fired {
+		if balance != 0 {
+			t.Errorf("alert %s: unbalanced notifications", name)
+		}
+	}
+}
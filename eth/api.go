@@ -17,25 +17,32 @@
 package eth
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"os"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
@@ -53,6 +60,9 @@ func NewEthereumAPI(e *Ethereum) *EthereumAPI {
 
 // Etherbase is the address that mining rewards will be send to.
 func (api *EthereumAPI) Etherbase() (common.Address, error) {
+	if addr, ok := api.e.Miner().ScheduledEtherbase(); ok {
+		return addr, nil
+	}
 	return api.e.Etherbase()
 }
 
@@ -129,6 +139,16 @@ func (api *MinerAPI) SetEtherbase(etherbase common.Address) bool {
 	return true
 }
 
+// SetEtherbaseSchedule rotates the fee recipient of the mined blocks between the
+// addresses of the schedule. A null schedule reverts to the etherbase. Payloads
+// built for the consensus client post-merge are not affected.
+func (api *MinerAPI) SetEtherbaseSchedule(schedule *miner.EtherbaseSchedule) (bool, error) {
+	if err := api.e.Miner().SetEtherbaseSchedule(schedule); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // SetRecommitInterval updates the interval for miner sealing work recommitting.
 func (api *MinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
@@ -194,8 +214,18 @@ func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	return true
 }
 
+// Promote turns a hot standby node into the primary, taking over networking with
+// the shared node identity and block production. The former primary must have
+// been stopped before.
+func (api *AdminAPI) Promote(ctx context.Context) error {
+	return api.eth.Promote(ctx)
+}
+
 // ImportChain imports a blockchain from a local file.
 func (api *AdminAPI) ImportChain(file string) (bool, error) {
+	if api.eth.readOnly() {
+		return false, errReadOnlyReplica
+	}
 	// Make sure the can access the file to import
 	in, err := os.Open(file)
 	if err != nil {
@@ -243,6 +273,142 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// ChainConfigDiff is a chain config field differing between two configs.
+type ChainConfigDiff struct {
+	Field    string          `json:"field"`
+	Current  json.RawMessage `json:"current"`
+	Proposed json.RawMessage `json:"proposed"`
+}
+
+// ChainConfigCheckResult is the outcome of checking a chain config against the
+// one the node is running with and the chain it has imported.
+type ChainConfigCheckResult struct {
+	Compatible  bool              `json:"compatible"`
+	Head        hexutil.Uint64    `json:"head"`
+	Differences []ChainConfigDiff `json:"differences"`
+
+	// Reasons the config is unusable, if any
+	ForkOrderError string          `json:"forkOrderError,omitempty"`
+	Incompatible   string          `json:"incompatible,omitempty"` // Fork transition conflicting with the imported chain
+	RewindTo       *hexutil.Uint64 `json:"rewindTo,omitempty"`     // Block the chain would be rewound to on restart
+}
+
+// ChainConfigCheck compares a chain config with the node's current one and checks
+// whether it is compatible with the imported chain, so operators can see the
+// consequences of a config change before restarting the node with it. A config
+// conflicting with already imported forks would rewind the chain on startup.
+func (api *AdminAPI) ChainConfigCheck(config *params.ChainConfig) (*ChainConfigCheckResult, error) {
+	if config == nil {
+		return nil, errors.New("no chain config given")
+	}
+	var (
+		current = api.eth.blockchain.Config()
+		head    = api.eth.blockchain.CurrentBlock().NumberU64()
+	)
+	diffs, err := diffChainConfigs(current, config)
+	if err != nil {
+		return nil, err
+	}
+	result := &ChainConfigCheckResult{
+		Compatible:  true,
+		Head:        hexutil.Uint64(head),
+		Differences: diffs,
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		result.Compatible, result.ForkOrderError = false, err.Error()
+	}
+	if compatErr := current.CheckCompatible(config, head); compatErr != nil {
+		rewind := hexutil.Uint64(compatErr.RewindTo)
+		result.Compatible, result.Incompatible, result.RewindTo = false, compatErr.Error(), &rewind
+	}
+	return result, nil
+}
+
+// diffChainConfigs returns the fields of two chain configs with differing values,
+// comparing them in their JSON representation.
+func diffChainConfigs(current, proposed *params.ChainConfig) ([]ChainConfigDiff, error) {
+	flatten := func(config *params.ChainConfig) (map[string]json.RawMessage, error) {
+		blob, err := json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]json.RawMessage)
+		return fields, json.Unmarshal(blob, &fields)
+	}
+	have, err := flatten(current)
+	if err != nil {
+		return nil, err
+	}
+	want, err := flatten(proposed)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range have {
+		names = append(names, name)
+	}
+	for name := range want {
+		if _, ok := have[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diffs := []ChainConfigDiff{}
+	for _, name := range names {
+		if !bytes.Equal(have[name], want[name]) {
+			diffs = append(diffs, ChainConfigDiff{Field: name, Current: have[name], Proposed: want[name]})
+		}
+	}
+	return diffs, nil
+}
+
+// PeerLatency is the measured latency of a connected peer.
+type PeerLatency struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	RemoteAddr string        `json:"remoteAddress"`
+	RTT        time.Duration `json:"rtt"`     // Smoothed round trip time of the requests served
+	Samples    uint64        `json:"samples"` // Number of requests the round trip time is based on
+	Region     string        `json:"region"`  // Latency region: local, regional, continental, intercontinental or unknown
+}
+
+// PeerLatencyReport is the latency map of the connected peers.
+type PeerLatencyReport struct {
+	Peers   []*PeerLatency `json:"peers"`   // Peers ordered by round trip time, unmeasured ones last
+	Regions map[string]int `json:"regions"` // Number of peers per latency region
+}
+
+// PeerLatency reports the round trip times of the connected peers, which are
+// used to prefer close peers as block and transaction propagation targets.
+func (api *AdminAPI) PeerLatency() *PeerLatencyReport {
+	report := &PeerLatencyReport{
+		Peers:   []*PeerLatency{},
+		Regions: make(map[string]int),
+	}
+	for _, peer := range api.eth.handler.peers.allPeers() {
+		rtt, samples := peer.RTT()
+		entry := &PeerLatency{
+			ID:         peer.ID(),
+			Name:       peer.Fullname(),
+			RemoteAddr: peer.RemoteAddr().String(),
+			RTT:        rtt,
+			Samples:    samples,
+			Region:     latencyRegion(rtt, samples),
+		}
+		report.Peers = append(report.Peers, entry)
+		report.Regions[entry.Region]++
+	}
+	sort.Slice(report.Peers, func(i, j int) bool {
+		a, b := report.Peers[i], report.Peers[j]
+		if (a.Samples > 0) != (b.Samples > 0) {
+			return a.Samples > 0
+		}
+		return a.RTT < b.RTT
+	})
+	return report
+}
+
 // DebugAPI is the collection of Ethereum full node APIs for debugging the
 // protocol.
 type DebugAPI struct {
@@ -254,6 +420,153 @@ func NewDebugAPI(eth *Ethereum) *DebugAPI {
 	return &DebugAPI{eth: eth}
 }
 
+// TrieFlushPolicy returns the policy of flushing dirty trie nodes to disk.
+func (api *DebugAPI) TrieFlushPolicy() core.TrieFlushPolicy {
+	return api.eth.blockchain.TrieFlushPolicy()
+}
+
+// SetTrieFlushPolicy changes the policy of flushing dirty trie nodes to disk,
+// taking effect from the next imported block.
+func (api *DebugAPI) SetTrieFlushPolicy(policy core.TrieFlushPolicy) error {
+	return api.eth.blockchain.SetTrieFlushPolicy(policy)
+}
+
+// TxPropagation returns the audit trail of a transaction: the peer it first
+// arrived from and the peers it was forwarded to. It requires the node to run
+// with transaction auditing enabled.
+func (api *DebugAPI) TxPropagation(hash common.Hash) (*TxPropagation, error) {
+	audit := api.eth.handler.txAudit
+	if audit == nil {
+		return nil, errors.New("transaction auditing disabled")
+	}
+	if r := audit.propagation(hash); r != nil {
+		return r, nil
+	}
+	return nil, fmt.Errorf("no propagation record for transaction %x", hash)
+}
+
+// HistorySegment is the availability of a historical block segment among the
+// peers of the node.
+type HistorySegment struct {
+	First  hexutil.Uint64 `json:"first"`  // First block of the segment
+	Rarity float64        `json:"rarity"` // Fraction of the peers with known availability missing the segment
+}
+
+// RareHistory returns up to limit historical block segments the peers are known
+// to serve or miss, from the rarest to the most common. The node spends less of
+// its history serving budget on rarer segments.
+func (api *DebugAPI) RareHistory(limit int) []HistorySegment {
+	rarity := api.eth.handler.historyRarity
+	segments := []HistorySegment{}
+	for _, first := range rarity.Rarest(limit) {
+		segments = append(segments, HistorySegment{First: hexutil.Uint64(first), Rarity: rarity.Rarity(first)})
+	}
+	return segments
+}
+
+// DumpTxPool writes the entire transaction pool, including queued transactions
+// and arrival times, into a file. It returns the number of dumped transactions.
+func (api *DebugAPI) DumpTxPool(file string) (int, error) {
+	out, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	n, err := api.eth.TxPool().Dump(out)
+	if err != nil {
+		out.Close()
+		return 0, err
+	}
+	return n, out.Close()
+}
+
+// LoadTxPool restores a transaction pool dump created by DumpTxPool, possibly on
+// another node, into the transaction pool.
+func (api *DebugAPI) LoadTxPool(file string) (*core.TxPoolLoadStats, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return api.eth.TxPool().Load(in)
+}
+
+// ForcedReorg describes a reorg forced by ForceReorg.
+type ForcedReorg struct {
+	Ancestor uint64      `json:"ancestor"`
+	OldHead  common.Hash `json:"oldHead"`
+	NewHead  common.Hash `json:"newHead"`
+	Dropped  int         `json:"droppedTransactions"`
+}
+
+// ForceReorg replaces the last depth blocks of the chain by a heavier branch of
+// empty blocks forged by the local signer, in order to rehearse how downstream
+// systems handle reorgs. The transactions of the replaced blocks return to the
+// transaction pool. It has to be enabled with --dev.forcereorg and requires a
+// clique network with the local signer as its only signer, like --dev.
+func (api *DebugAPI) ForceReorg(ctx context.Context, depth uint64) (*ForcedReorg, error) {
+	if !api.eth.config.AllowForceReorg {
+		return nil, errors.New("forced reorgs are disabled, enable them with --dev.forcereorg")
+	}
+	if api.eth.readOnly() {
+		return nil, errReadOnlyReplica
+	}
+	engine := api.eth.Engine()
+	if b, ok := engine.(*beacon.Beacon); ok {
+		engine = b.InnerEngine()
+	}
+	c, ok := engine.(*clique.Clique)
+	if !ok || api.eth.Merger().TDDReached() {
+		return nil, errors.New("forced reorgs are only supported on clique networks")
+	}
+	// Pause local block production, so the miner doesn't extend the chain while
+	// the branch is forged and imported
+	if api.eth.IsMining() {
+		api.eth.Miner().Stop()
+		defer api.eth.StartMining(0)
+	}
+	chain := api.eth.BlockChain()
+	head := chain.CurrentBlock()
+	if depth == 0 || depth > head.NumberU64() {
+		return nil, fmt.Errorf("invalid reorg depth %d with head at %d", depth, head.NumberU64())
+	}
+	ancestor := chain.GetHeaderByNumber(head.NumberU64() - depth)
+
+	// Forge one block more than replaced, so the branch is heavier
+	blocks, err := c.ForgeBranch(chain, ancestor, int(depth)+1)
+	if err != nil {
+		return nil, err
+	}
+	newHead := blocks[len(blocks)-1]
+	if wait := time.Until(time.Unix(int64(newHead.Time()), 0)); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	dropped := 0
+	for n := ancestor.Number.Uint64() + 1; n <= head.NumberU64(); n++ {
+		if block := chain.GetBlockByNumber(n); block != nil {
+			dropped += len(block.Transactions())
+		}
+	}
+	// Blocks imported meanwhile, e.g. sealed before the miner paused, make the
+	// branch stale
+	if _, err := chain.InsertChainIfHead(head.Hash(), blocks); err != nil {
+		return nil, err
+	}
+	if chain.CurrentBlock().Hash() != newHead.Hash() {
+		return nil, errors.New("forged branch did not become canonical")
+	}
+	log.Warn("Forced chain reorg", "depth", depth, "ancestor", ancestor.Number, "oldhead", head.Hash(), "newhead", newHead.Hash())
+	return &ForcedReorg{
+		Ancestor: ancestor.Number.Uint64(),
+		OldHead:  head.Hash(),
+		NewHead:  newHead.Hash(),
+		Dropped:  dropped,
+	}, nil
+}
+
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *DebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
 	opts := &state.DumpConfig{
@@ -287,6 +600,46 @@ func (api *DebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
 	return stateDb.RawDump(opts), nil
 }
 
+// GenesisFromState creates a genesis specification whose allocation mirrors the
+// state of the given accounts at the given block.
+func (api *DebugAPI) GenesisFromState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, addresses []common.Address) (*core.Genesis, error) {
+	// Exporting the entire state is only supported offline via geth genesis-from-state
+	if len(addresses) == 0 {
+		return nil, errors.New("no accounts specified")
+	}
+	stateDb, header, err := api.eth.APIBackend.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if stateDb == nil || err != nil {
+		return nil, err
+	}
+	return core.GenesisFromState(api.eth.blockchain.Config(), header, stateDb, addresses)
+}
+
+// executionBundleReexec is the number of blocks to re-execute at most when the
+// state needed to record an execution bundle is not available.
+const executionBundleReexec = 128
+
+// ExecutionBundle re-executes an included transaction, recording every account,
+// storage slot and block hash it accesses into a self-contained bundle, which
+// can be replayed offline with evm bundle.
+func (api *DebugAPI) ExecutionBundle(ctx context.Context, hash common.Hash) (*core.ExecutionBundle, error) {
+	tx, blockHash, blockNumber, index, err := api.eth.APIBackend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	block := api.eth.blockchain.GetBlock(blockHash, blockNumber)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	_, blockCtx, stateDb, err := api.eth.stateAtTransaction(block, int(index), executionBundleReexec)
+	if err != nil {
+		return nil, err
+	}
+	return core.RecordExecutionBundle(api.eth.blockchain.Config(), blockCtx, stateDb, tx, int(index))
+}
+
 // Preimage is a debug API function that returns the preimage for a sha3 hash, if known.
 func (api *DebugAPI) Preimage(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	if preimage := rawdb.ReadPreimage(api.eth.ChainDb(), hash); preimage != nil {
@@ -445,6 +798,86 @@ func storageRangeAt(st state.Trie, start []byte, maxResult int) (StorageRangeRes
 	return result, nil
 }
 
+// StorageStats summarizes the storage footprint of a contract.
+type StorageStats struct {
+	Root      common.Hash `json:"storageRoot"`
+	Slots     uint64      `json:"slots"`
+	TrieNodes uint64      `json:"trieNodes"`
+
+	// Depths[i] is the number of slots whose lookup has to resolve i trie nodes
+	// from the database, the root included.
+	Depths   []uint64 `json:"depths"`
+	MaxDepth int      `json:"maxDepth"`
+
+	// Estimated disk usage of the storage trie nodes and snapshot entries,
+	// including their database keys.
+	TrieBytes     uint64 `json:"trieBytes"`
+	SnapshotBytes uint64 `json:"snapshotBytes"`
+	TotalBytes    uint64 `json:"totalBytes"`
+}
+
+// StorageStats walks the storage trie of a contract at the given block, latest
+// by default, and reports its slot count, the depth distribution of its slots
+// and an estimate of the disk space it occupies.
+func (api *DebugAPI) StorageStats(ctx context.Context, address common.Address, blockNrOrHash *rpc.BlockNumberOrHash) (*StorageStats, error) {
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	statedb, _, err := api.eth.APIBackend.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	st := statedb.StorageTrie(address)
+	if st == nil {
+		return nil, fmt.Errorf("account %x doesn't exist", address)
+	}
+	return storageStats(ctx, st)
+}
+
+func storageStats(ctx context.Context, st state.Trie) (*StorageStats, error) {
+	var (
+		stats   = &StorageStats{Root: st.Hash(), Depths: []uint64{}}
+		stack   [][]byte // Paths of the standalone nodes above the current node
+		visited int
+		it      = st.NodeIterator(nil)
+	)
+	for it.Next(true) {
+		if visited++; visited%1024 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		path := it.Path()
+		for len(stack) > 0 && !bytes.HasPrefix(path, stack[len(stack)-1]) {
+			stack = stack[:len(stack)-1]
+		}
+		if it.Leaf() {
+			depth := len(stack)
+			for len(stats.Depths) <= depth {
+				stats.Depths = append(stats.Depths, 0)
+			}
+			stats.Depths[depth]++
+			if depth > stats.MaxDepth {
+				stats.MaxDepth = depth
+			}
+			stats.Slots++
+			stats.SnapshotBytes += uint64(len(rawdb.SnapshotStoragePrefix) + 2*common.HashLength + len(it.LeafBlob()))
+			continue
+		}
+		// Embedded nodes are stored inside their parents, only count standalone ones
+		if it.Hash() == (common.Hash{}) {
+			continue
+		}
+		stats.TrieNodes++
+		stats.TrieBytes += uint64(common.HashLength + len(it.NodeBlob()))
+		stack = append(stack, common.CopyBytes(path))
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	stats.TotalBytes = stats.TrieBytes + stats.SnapshotBytes
+	return stats, nil
+}
+
 // GetModifiedAccountsByNumber returns all accounts that have changed between the
 // two blocks specified. A change is defined as a difference in nonce, balance,
 // code hash, or storage hash.
@@ -528,6 +961,27 @@ func (api *DebugAPI) getModifiedAccounts(startBlock, endBlock *types.Block) ([]c
 	return dirty, nil
 }
 
+// StateDiff returns the accounts and storage slots which differ between the
+// states of the two blocks, with their values in both states. Unlike replaying
+// the transactions, only the parts of the state tries which differ are visited.
+func (api *DebugAPI) StateDiff(ctx context.Context, blockA, blockB rpc.BlockNumberOrHash) (*state.StateDiff, error) {
+	headerA, err := api.eth.APIBackend.HeaderByNumberOrHash(ctx, blockA)
+	if err != nil {
+		return nil, err
+	}
+	if headerA == nil {
+		return nil, fmt.Errorf("block %s not found", blockA.String())
+	}
+	headerB, err := api.eth.APIBackend.HeaderByNumberOrHash(ctx, blockB)
+	if err != nil {
+		return nil, err
+	}
+	if headerB == nil {
+		return nil, fmt.Errorf("block %s not found", blockB.String())
+	}
+	return state.DiffAt(api.eth.BlockChain().StateCache(), headerA.Root, headerB.Root)
+}
+
 // GetAccessibleState returns the first number where the node has accessible
 // state on disk. Note this being the post-state of that block and the pre-state
 // of the next block.
@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -59,6 +60,10 @@ func (b *EthAPIBackend) CurrentBlock() *types.Block {
 }
 
 func (b *EthAPIBackend) SetHead(number uint64) {
+	if b.eth.readOnly() {
+		log.Warn("Ignoring head rewind of read-only replica", "number", number)
+		return
+	}
 	b.eth.handler.downloader.Cancel()
 	b.eth.blockchain.SetHead(number)
 }
@@ -257,7 +262,14 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
-	return b.eth.txPool.AddLocal(signedTx)
+	if b.eth.readOnly() {
+		return errReadOnlyReplica
+	}
+	if err := b.eth.txPool.AddLocal(signedTx); err != nil {
+		return err
+	}
+	b.gpo.TrackTransaction(signedTx, b.eth.blockchain.CurrentBlock().NumberU64())
+	return nil
 }
 
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
@@ -275,6 +287,10 @@ func (b *EthAPIBackend) GetPoolTransaction(hash common.Hash) *types.Transaction
 
 func (b *EthAPIBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
 	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.eth.ChainDb(), txHash)
+	if tx == nil && b.eth.blockchain.GetTransactionLookup(txHash) != nil {
+		// The transaction was found in an unindexed block and indexed, retry
+		tx, blockHash, blockNumber, index = rawdb.ReadTransaction(b.eth.ChainDb(), txHash)
+	}
 	return tx, blockHash, blockNumber, index, nil
 }
 
@@ -286,6 +302,10 @@ func (b *EthAPIBackend) Stats() (pending int, queued int) {
 	return b.eth.txPool.Stats()
 }
 
+func (b *EthAPIBackend) TxPoolLanes() map[string]core.TxLaneStats {
+	return b.eth.txPool.LaneStats()
+}
+
 func (b *EthAPIBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	return b.eth.TxPool().Content()
 }
@@ -303,7 +323,9 @@ func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.S
 }
 
 func (b *EthAPIBackend) SyncProgress() ethereum.SyncProgress {
-	return b.eth.Downloader().Progress()
+	prog := b.eth.Downloader().Progress()
+	prog.TxIndexFinishedBlocks, prog.TxIndexRemainingBlocks = b.eth.blockchain.TxIndexProgress()
+	return prog
 }
 
 func (b *EthAPIBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
@@ -314,7 +336,19 @@ func (b *EthAPIBackend) FeeHistory(ctx context.Context, blockCount int, lastBloc
 	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
 }
 
+func (b *EthAPIBackend) FeeHistoryByType(ctx context.Context, blockCount int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (firstBlock *big.Int, reward [][]*big.Int, typeReward []map[uint8][]*big.Int, baseFee []*big.Int, gasUsedRatio []float64, err error) {
+	return b.gpo.FeeHistoryByType(ctx, blockCount, lastBlock, rewardPercentiles)
+}
+
+func (b *EthAPIBackend) FeeCongestion(ctx context.Context) (*gasprice.Congestion, error) {
+	return b.gpo.Congestion(ctx)
+}
+
 func (b *EthAPIBackend) ChainDb() ethdb.Database {
+	// Replicas only import the change-log of the primary, nothing else may write
+	if b.eth.readOnly() {
+		return rawdb.NewReadOnlyDatabase(b.eth.ChainDb())
+	}
 	return b.eth.ChainDb()
 }
 
@@ -342,11 +376,18 @@ func (b *EthAPIBackend) RPCEVMTimeout() time.Duration {
 	return b.eth.config.RPCEVMTimeout
 }
 
+func (b *EthAPIBackend) RPCCacheSize() int {
+	return b.eth.config.RPCCacheSize
+}
+
 func (b *EthAPIBackend) RPCTxFeeCap() float64 {
 	return b.eth.config.RPCTxFeeCap
 }
 
 func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
+	if b.eth.bloomIndexer == nil {
+		return params.BloomBitsBlocks, 0
+	}
 	sections, _, _ := b.eth.bloomIndexer.Sections()
 	return params.BloomBitsBlocks, sections
 }
@@ -0,0 +1,156 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/genesis"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// NodeInfoExtended describes the build and configuration of a node, allowing
+// fleet tooling to verify nodes run equivalent setups. All fields are reported
+// in a deterministic order, so the results of two nodes can be compared as is.
+type NodeInfoExtended struct {
+	Version  string            `json:"version"`
+	Build    map[string]string `json:"build"`    // Go version, platform and build settings
+	Features []string          `json:"features"` // Enabled optional and experimental subsystems
+	Database DatabaseVersions  `json:"database"`
+	Forks    []ForkReadiness   `json:"forks"`
+}
+
+// DatabaseVersions contains the schema versions of the node's database.
+type DatabaseVersions struct {
+	Chain     *hexutil.Uint64 `json:"chain"`     // Schema version of the chain database
+	Supported hexutil.Uint64  `json:"supported"` // Schema version written by this build
+}
+
+// ForkReadiness is the activation status of a fork scheduled in the chain config.
+type ForkReadiness struct {
+	Name      string          `json:"name"`
+	Block     *hexutil.Uint64 `json:"block,omitempty"`
+	TTD       *hexutil.Big    `json:"terminalTotalDifficulty,omitempty"`
+	Active    bool            `json:"active"`
+	Remaining *hexutil.Uint64 `json:"remaining,omitempty"` // Blocks until activation
+}
+
+// Web3API offers node introspection methods in the web3 namespace.
+type Web3API struct {
+	eth *Ethereum
+}
+
+// NewWeb3API creates a new web3 API instance.
+func NewWeb3API(eth *Ethereum) *Web3API {
+	return &Web3API{eth: eth}
+}
+
+// NodeInfoExtended returns the build information, enabled features, database
+// versions and fork readiness of the node.
+func (api *Web3API) NodeInfoExtended() *NodeInfoExtended {
+	info := &NodeInfoExtended{
+		Version:  params.VersionWithMeta,
+		Build:    buildInfo(),
+		Features: api.features(),
+		Database: DatabaseVersions{Supported: hexutil.Uint64(core.BlockChainVersion)},
+	}
+	if version := rawdb.ReadDatabaseVersion(api.eth.chainDb); version != nil {
+		info.Database.Chain = (*hexutil.Uint64)(version)
+	}
+	var (
+		config = api.eth.blockchain.Config()
+		head   = api.eth.blockchain.CurrentBlock().NumberU64()
+	)
+	for _, name := range genesis.Forks {
+		block, _ := genesis.ForkBlock(config, name)
+		if block == nil {
+			continue
+		}
+		number := hexutil.Uint64(block.Uint64())
+		fork := ForkReadiness{Name: name, Block: &number, Active: block.Uint64() <= head}
+		if !fork.Active {
+			remaining := hexutil.Uint64(block.Uint64() - head)
+			fork.Remaining = &remaining
+		}
+		info.Forks = append(info.Forks, fork)
+	}
+	if ttd := config.TerminalTotalDifficulty; ttd != nil {
+		info.Forks = append(info.Forks, ForkReadiness{
+			Name:   "merge",
+			TTD:    (*hexutil.Big)(ttd),
+			Active: api.eth.merger.TDDReached(),
+		})
+	}
+	return info
+}
+
+// buildInfo returns the Go version, platform and build settings the node was
+// compiled with.
+func buildInfo() map[string]string {
+	build := map[string]string{
+		"go":   runtime.Version(),
+		"os":   runtime.GOOS,
+		"arch": runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			build[setting.Key] = setting.Value
+		}
+	}
+	return build
+}
+
+// features returns the optional and experimental subsystems enabled on the node,
+// along with the default ones that were disabled.
+func (api *Web3API) features() []string {
+	config := api.eth.config
+	enabled := map[string]bool{
+		"archive":          config.NoPruning,
+		"snapshot":         config.SnapshotCache > 0,
+		"preimages":        config.Preimages,
+		"addressLogIndex":  config.AddressLogIndex,
+		"internalTxIndex":  config.InternalTxIndex,
+		"noBloomIndex":     config.NoBloomIndex,
+		"noTxIndex":        config.NoTxIndex,
+		"txLookupBackfill": config.TxLookupBackfill > 0,
+		"txAudit":          config.TxAuditLimit > 0,
+		"changeLog":        config.ChangeLogDir != "",
+		"replica":          config.ReplicaSource != "",
+		"standby":          config.Standby,
+		"alerts":           api.eth.alerts != nil,
+		"milestones":       api.eth.milestones != nil,
+		"sealApproval":     config.Miner.SealApproval != "",
+		"forceReorg":       config.AllowForceReorg,
+		"lightServer":      config.LightServ > 0,
+		"metrics":          metrics.Enabled,
+		"metricsExpensive": metrics.EnabledExpensive,
+	}
+	features := make([]string, 0, len(enabled))
+	for name, on := range enabled {
+		if on {
+			features = append(features, name)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
@@ -18,6 +18,7 @@ package eth
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -26,9 +27,16 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -219,3 +227,170 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+func TestStorageStats(t *testing.T) {
+	t.Parallel()
+
+	// Create a state where account 0x01 has a few hundred storage slots
+	var (
+		db       = state.NewDatabase(rawdb.NewMemoryDatabase())
+		sdb, _   = state.New(common.Hash{}, db, nil)
+		addr     = common.Address{0x01}
+		slots    = 300
+		snapSize = 0
+	)
+	for i := 1; i <= slots; i++ {
+		value := common.BigToHash(big.NewInt(int64(i)))
+		sdb.SetState(addr, common.BigToHash(big.NewInt(int64(i))), value)
+
+		enc, _ := rlp.EncodeToBytes(common.TrimLeftZeroes(value[:]))
+		snapSize += 1 + 2*common.HashLength + len(enc)
+	}
+	sdb.SetBalance(common.Address{0x02}, big.NewInt(1))
+	root, _ := sdb.Commit(false)
+	sdb, _ = state.New(root, db, nil)
+
+	stats, err := storageStats(context.Background(), sdb.StorageTrie(addr))
+	if err != nil {
+		t.Fatalf("failed to gather storage stats: %v", err)
+	}
+	if stats.Root != sdb.StorageTrie(addr).Hash() {
+		t.Errorf("storage root mismatch: have %x, want %x", stats.Root, sdb.StorageTrie(addr).Hash())
+	}
+	if stats.Slots != uint64(slots) {
+		t.Errorf("slot count mismatch: have %d, want %d", stats.Slots, slots)
+	}
+	var total uint64
+	for depth, count := range stats.Depths {
+		if depth == 0 && count != 0 {
+			t.Errorf("slots reachable without resolving the root: %d", count)
+		}
+		total += count
+	}
+	if total != uint64(slots) || len(stats.Depths) != stats.MaxDepth+1 {
+		t.Errorf("depth distribution mismatch: %v", stats.Depths)
+	}
+	// With 300 slots, the root and its 16 children are all standalone nodes
+	if stats.MaxDepth < 2 || stats.TrieNodes < 17 {
+		t.Errorf("trie shape mismatch: max depth %d, %d nodes", stats.MaxDepth, stats.TrieNodes)
+	}
+	if stats.SnapshotBytes != uint64(snapSize) {
+		t.Errorf("snapshot size mismatch: have %d, want %d", stats.SnapshotBytes, snapSize)
+	}
+	if stats.TotalBytes != stats.TrieBytes+stats.SnapshotBytes {
+		t.Errorf("total size mismatch: have %d, want %d", stats.TotalBytes, stats.TrieBytes+stats.SnapshotBytes)
+	}
+	// Accounts without storage should report an empty footprint
+	stats, err = storageStats(context.Background(), sdb.StorageTrie(common.Address{0x02}))
+	if err != nil {
+		t.Fatalf("failed to gather storage stats: %v", err)
+	}
+	if stats.Slots != 0 || stats.TrieNodes != 0 || stats.TotalBytes != 0 {
+		t.Errorf("empty storage reported non-empty stats: %+v", stats)
+	}
+}
+
+func TestChainConfigCheck(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = (&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+		engine  = ethash.NewFaker()
+	)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 10, nil)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	api := NewAdminAPI(&Ethereum{blockchain: chain})
+
+	// The running config itself is compatible without differences
+	res, err := api.ChainConfigCheck(params.TestChainConfig)
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if !res.Compatible || len(res.Differences) != 0 || res.Head != 10 {
+		t.Fatalf("unexpected result for current config: %s", dumper.Sdump(res))
+	}
+	// Moving London past imported blocks requires a rewind
+	config := *params.TestChainConfig
+	config.LondonBlock = big.NewInt(5)
+	config.ArrowGlacierBlock = big.NewInt(5)
+	config.GrayGlacierBlock = big.NewInt(5)
+	if res, err = api.ChainConfigCheck(&config); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if res.Compatible || res.RewindTo == nil || *res.RewindTo != 0 || res.ForkOrderError != "" {
+		t.Fatalf("unexpected result for moved fork: %s", dumper.Sdump(res))
+	}
+	if len(res.Differences) != 3 || res.Differences[2].Field != "londonBlock" || string(res.Differences[1].Proposed) != "5" {
+		t.Fatalf("unexpected differences: %s", dumper.Sdump(res.Differences))
+	}
+	// Forks out of order are reported as well
+	config.BerlinBlock = big.NewInt(8)
+	if res, err = api.ChainConfigCheck(&config); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if res.Compatible || res.ForkOrderError == "" || len(res.Differences) != 4 {
+		t.Fatalf("unexpected result for misordered forks: %s", dumper.Sdump(res))
+	}
+}
+
+func TestNodeInfoExtended(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		config = *params.TestChainConfig
+		engine = ethash.NewFaker()
+	)
+	config.LondonBlock = big.NewInt(20)
+	config.ArrowGlacierBlock = nil
+	config.GrayGlacierBlock = nil
+	config.TerminalTotalDifficulty = big.NewInt(1000000000)
+
+	rawdb.WriteDatabaseVersion(db, core.BlockChainVersion)
+	genesis := (&core.Genesis{Config: &config}).MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks, _ := core.GenerateChain(&config, genesis, engine, db, 10, nil)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	api := NewWeb3API(&Ethereum{
+		blockchain: chain,
+		chainDb:    db,
+		merger:     consensus.NewMerger(db),
+		config:     &ethconfig.Config{AddressLogIndex: true, SnapshotCache: 10},
+	})
+	info := api.NodeInfoExtended()
+
+	if info.Database.Chain == nil || uint64(*info.Database.Chain) != core.BlockChainVersion {
+		t.Errorf("wrong database version: %v", info.Database.Chain)
+	}
+	if want := []string{"addressLogIndex", "snapshot"}; !reflect.DeepEqual(info.Features, want) {
+		t.Errorf("wrong features: have %v, want %v", info.Features, want)
+	}
+	forks := make(map[string]ForkReadiness)
+	for _, fork := range info.Forks {
+		forks[fork.Name] = fork
+	}
+	if fork := forks["berlin"]; !fork.Active || fork.Remaining != nil {
+		t.Errorf("berlin not active: %s", dumper.Sdump(fork))
+	}
+	if fork := forks["london"]; fork.Active || fork.Remaining == nil || *fork.Remaining != 10 {
+		t.Errorf("wrong london readiness: %s", dumper.Sdump(fork))
+	}
+	if _, ok := forks["grayGlacier"]; ok {
+		t.Error("unscheduled fork reported")
+	}
+	if fork := forks["merge"]; fork.Active || fork.TTD == nil {
+		t.Errorf("wrong merge readiness: %s", dumper.Sdump(fork))
+	}
+}
@@ -18,6 +18,7 @@
 package eth
 
 import (
+	"crypto/cipher"
 	"errors"
 	"fmt"
 	"math/big"
@@ -39,13 +40,17 @@ import (
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/alerts"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/milestone"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/replica"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encrypted"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/shutdowncheck"
@@ -75,6 +80,11 @@ type Ethereum struct {
 	ethDialCandidates  enode.Iterator
 	snapDialCandidates enode.Iterator
 	merger             *consensus.Merger
+	milestones         *milestone.Tracker
+	alerts             *alerts.Monitor
+	exporter           *replica.Exporter // Change-log exporter feeding read replicas
+	follower           *replica.Follower // Change-log importer if running as a read replica
+	standby            *standby          // Promotion state if running as a hot standby
 
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
@@ -128,6 +138,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	log.Info("Allocated trie memory caches", "clean", common.StorageSize(config.TrieCleanCache)*1024*1024, "dirty", common.StorageSize(config.TrieDirtyCache)*1024*1024)
 
+	// Share code analysis between block processing and RPC calls
+	vm.SetAnalysisCache(config.AnalysisCache * 1024 * 1024)
+
 	// Transfer mining-related config to the ethash config.
 	ethashConfig := config.Ethash
 	ethashConfig.NotifyFull = config.Miner.NotifyFull
@@ -137,7 +150,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
-	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.OverrideTerminalTotalDifficulty, config.OverrideTerminalTotalDifficultyPassed)
+	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.OverrideTerminalTotalDifficulty, config.OverrideTerminalTotalDifficultyPassed, config.OverrideEOF)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
@@ -165,11 +178,15 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		gasPrice:          config.Miner.GasPrice,
 		etherbase:         config.Miner.Etherbase,
 		bloomRequests:     make(chan chan *bloombits.Retrieval),
-		bloomIndexer:      core.NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms),
 		p2pServer:         stack.Server(),
 		shutdownTracker:   shutdowncheck.NewShutdownTracker(chainDb),
 	}
 
+	if config.NoBloomIndex {
+		log.Info("Bloom bits log index disabled")
+	} else {
+		eth.bloomIndexer = core.NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms)
+	}
 	bcVersion := rawdb.ReadDatabaseVersion(chainDb)
 	var dbVer = "<nil>"
 	if bcVersion != nil {
@@ -198,22 +215,59 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieCleanNoPrefetch: config.NoPrefetch,
 			TrieDirtyLimit:      config.TrieDirtyCache,
 			TrieDirtyDisabled:   config.NoPruning,
+			TrieDirtyMax:        config.TrieDirtyMax,
+			TrieFlushLimit:      config.TrieFlushLimit,
+			TrieReorgDepth:      config.TrieReorgDepth,
 			TrieTimeLimit:       config.TrieTimeout,
+			TrieParallelism:     config.TrieParallelism,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			AddressLogIndex:     config.AddressLogIndex,
+			InternalTxIndex:     config.InternalTxIndex,
+			NoTxIndex:           config.NoTxIndex,
+			TxLookupBackfill:    config.TxLookupBackfill,
 		}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
 	if err != nil {
 		return nil, err
 	}
+	eth.milestones = milestone.NewTracker(eth.blockchain)
+	eth.alerts = alerts.New(config.Alerts, alertsBackend{eth})
+	var changeLogKey cipher.AEAD
+	if config.ChangeLogKeyFile != "" {
+		if changeLogKey, err = encrypted.LoadCipher(config.ChangeLogKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to load change-log key: %v", err)
+		}
+	}
+	if config.ChangeLogDir != "" {
+		if eth.exporter, err = replica.NewExporter(eth.blockchain, stack.ResolvePath(config.ChangeLogDir), changeLogKey); err != nil {
+			return nil, err
+		}
+	}
+	if config.ReplicaSource != "" {
+		source, err := replica.NewSource(config.ReplicaSource)
+		if err != nil {
+			return nil, err
+		}
+		eth.follower = replica.NewFollower(eth.blockchain, chainDb, source, changeLogKey)
+	}
+	if config.Standby {
+		if eth.follower == nil || !stack.Config().P2PStandby {
+			return nil, errors.New("standby mode requires a replica source and held back networking")
+		}
+		eth.standby = &standby{startNetworking: stack.StartNetworking}
+	}
+
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
 		eth.blockchain.SetHead(compat.RewindTo)
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
-	eth.bloomIndexer.Start(eth.blockchain)
+	if eth.bloomIndexer != nil {
+		eth.bloomIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
@@ -237,10 +291,18 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		EventMux:       eth.eventMux,
 		Checkpoint:     checkpoint,
 		RequiredBlocks: config.RequiredBlocks,
+		HistoryBudget:  config.HistoryServeBudget,
+		HistoryMirrors: config.HistoryMirrors,
+		TxAuditLimit:   config.TxAuditLimit,
 	}); err != nil {
 		return nil, err
 	}
 
+	if schedule := config.Miner.EtherbaseSchedule; schedule != nil {
+		if err := schedule.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid etherbase schedule: %v", err)
+		}
+	}
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
@@ -327,6 +389,9 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "net",
 			Service:   s.netRPCService,
+		}, {
+			Namespace: "web3",
+			Service:   NewWeb3API(s),
 		},
 	}...)
 }
@@ -425,6 +490,14 @@ func (s *Ethereum) SetEtherbase(etherbase common.Address) {
 // is already running, this method adjust the number of threads allowed to use
 // and updates the minimum price required by the transaction pool.
 func (s *Ethereum) StartMining(threads int) error {
+	// Standby nodes only produce blocks once promoted
+	if s.standby != nil && s.standby.holdMining(&threads) {
+		log.Info("Deferring mining until standby is promoted", "threads", threads)
+		return nil
+	}
+	if s.readOnly() {
+		return errReadOnlyReplica
+	}
 	// Update the thread count within the consensus engine
 	type threaded interface {
 		SetThreads(threads int)
@@ -478,6 +551,10 @@ func (s *Ethereum) StartMining(threads int) error {
 // StopMining terminates the miner, both at the consensus engine level as well as
 // at the block creation level.
 func (s *Ethereum) StopMining() {
+	// Drop any mining deferred until the standby is promoted
+	if s.standby != nil {
+		s.standby.holdMining(nil)
+	}
 	// Update the thread count within the consensus engine
 	type threaded interface {
 		SetThreads(threads int)
@@ -505,6 +582,7 @@ func (s *Ethereum) SetSynced()                         { atomic.StoreUint32(&s.h
 func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
 func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
 func (s *Ethereum) Merger() *consensus.Merger          { return s.merger }
+func (s *Ethereum) Alerts() *alerts.Monitor            { return s.alerts }
 func (s *Ethereum) SyncMode() downloader.SyncMode {
 	mode, _ := s.handler.chainSync.modeAndLocalHead()
 	return mode
@@ -531,6 +609,22 @@ func (s *Ethereum) Start() error {
 	// Regularly update shutdown marker
 	s.shutdownTracker.Start()
 
+	// Start tracking the signed chain milestones if configured
+	if s.milestones != nil {
+		s.milestones.Start()
+	}
+	// Start the stall detectors if configured
+	if s.alerts != nil {
+		s.alerts.Start()
+	}
+	// Start exporting or importing the change-log if configured
+	if s.exporter != nil {
+		s.exporter.Start()
+	}
+	if s.follower != nil {
+		s.follower.Start()
+	}
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := s.p2pServer.MaxPeers
 	if s.config.LightServ > 0 {
@@ -553,7 +647,21 @@ func (s *Ethereum) Stop() error {
 	s.handler.Stop()
 
 	// Then stop everything else.
-	s.bloomIndexer.Close()
+	if s.milestones != nil {
+		s.milestones.Stop()
+	}
+	if s.alerts != nil {
+		s.alerts.Stop()
+	}
+	if s.exporter != nil {
+		s.exporter.Stop()
+	}
+	if s.follower != nil {
+		s.follower.Stop()
+	}
+	if s.bloomIndexer != nil {
+		s.bloomIndexer.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
 	s.miner.Close()
@@ -568,3 +676,15 @@ func (s *Ethereum) Stop() error {
 
 	return nil
 }
+
+// alertsBackend exposes the node state monitored by the stall detectors.
+type alertsBackend struct {
+	eth *Ethereum
+}
+
+func (b alertsBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.eth.blockchain.SubscribeChainHeadEvent(ch)
+}
+
+func (b alertsBackend) PeerCount() int { return b.eth.p2pServer.PeerCount() }
+func (b alertsBackend) Merged() bool   { return b.eth.merger.TDDReached() }
@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -139,6 +140,30 @@ func NewConsensusAPI(eth *eth.Ethereum) *ConsensusAPI {
 // If there are payloadAttributes:
 // 		we try to assemble a block with the payloadAttributes and return its payloadID
 func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributesV1) (beacon.ForkChoiceResponse, error) {
+	if payloadAttributes != nil && (payloadAttributes.Withdrawals != nil || payloadAttributes.BeaconRoot != nil) {
+		return beacon.STATUS_INVALID, beacon.InvalidParams.With(errors.New("withdrawals and beacon root not supported in V1"))
+	}
+	return api.forkchoiceUpdated(update, payloadAttributes, false)
+}
+
+// ForkchoiceUpdatedV3 is equivalent to V1 with the addition of the withdrawals
+// and the parent beacon root in the payload attributes, building Prague payloads.
+// Withdrawals are not supported, the list must be empty.
+func (api *ConsensusAPI) ForkchoiceUpdatedV3(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributesV1) (beacon.ForkChoiceResponse, error) {
+	if payloadAttributes != nil {
+		if payloadAttributes.Withdrawals == nil || payloadAttributes.BeaconRoot == nil {
+			return beacon.STATUS_INVALID, beacon.InvalidParams.With(errors.New("missing withdrawals or beacon root"))
+		}
+		if len(payloadAttributes.Withdrawals) != 0 {
+			return beacon.STATUS_INVALID, beacon.InvalidPayloadAttributes.With(errors.New("withdrawals not supported"))
+		}
+	}
+	return api.forkchoiceUpdated(update, payloadAttributes, true)
+}
+
+// forkchoiceUpdated implements the forkchoice update of all versions, prague
+// telling whether payloads must be built for the Prague fork or preceding it.
+func (api *ConsensusAPI) forkchoiceUpdated(update beacon.ForkchoiceStateV1, payloadAttributes *beacon.PayloadAttributesV1, prague bool) (beacon.ForkChoiceResponse, error) {
 	api.forkchoiceLock.Lock()
 	defer api.forkchoiceLock.Unlock()
 
@@ -152,6 +177,10 @@ func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, pa
 	api.lastForkchoiceUpdate = time.Now()
 	api.lastForkchoiceLock.Unlock()
 
+	if monitor := api.eth.Alerts(); monitor != nil {
+		monitor.ForkchoiceUpdated()
+	}
+
 	// Check whether we have the block yet in our database or not. If not, we'll
 	// need to either trigger a sync, or to reject this forkchoice update for a
 	// reason.
@@ -263,21 +292,40 @@ func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, pa
 	// sealed by the beacon client. The payload will be requested later, and we
 	// might replace it arbitrarily many times in between.
 	if payloadAttributes != nil {
+		if api.eth.BlockChain().Config().IsPrague(new(big.Int).SetUint64(block.NumberU64()+1)) != prague {
+			return valid(nil), beacon.UnsupportedFork.With(fmt.Errorf("wrong forkchoice update version for block %d", block.NumberU64()+1))
+		}
+		args := &miner.BuildPayloadArgs{
+			Parent:       update.HeadBlockHash,
+			Timestamp:    payloadAttributes.Timestamp,
+			FeeRecipient: payloadAttributes.SuggestedFeeRecipient,
+			Random:       payloadAttributes.Random,
+			BeaconRoot:   payloadAttributes.BeaconRoot,
+		}
 		// Create an empty block first which can be used as a fallback
-		empty, err := api.eth.Miner().GetSealingBlockSync(update.HeadBlockHash, payloadAttributes.Timestamp, payloadAttributes.SuggestedFeeRecipient, payloadAttributes.Random, true)
+		empty, err := api.eth.Miner().GetSealingPayloadSync(args, true)
 		if err != nil {
 			log.Error("Failed to create empty sealing payload", "err", err)
 			return valid(nil), beacon.InvalidPayloadAttributes.With(err)
 		}
-		// Send a request to generate a full block in the background.
-		// The result can be obtained via the returned channel.
-		resCh, err := api.eth.Miner().GetSealingBlockAsync(update.HeadBlockHash, payloadAttributes.Timestamp, payloadAttributes.SuggestedFeeRecipient, payloadAttributes.Random, false)
-		if err != nil {
-			log.Error("Failed to create async sealing payload", "err", err)
-			return valid(nil), beacon.InvalidPayloadAttributes.With(err)
+		// Send requests to generate full blocks in the background, locally and
+		// by the external builders. The results can be obtained via the returned
+		// channels, the most valuable block being proposed.
+		req := &payload{args: args, empty: empty, validate: api.eth.Miner().ValidatePayload}
+		for i, builder := range api.eth.Miner().PayloadBuilders() {
+			resCh, err := builder.BuildPayload(args)
+			if err != nil {
+				if i == 0 {
+					log.Error("Failed to create async sealing payload", "err", err)
+					return valid(nil), beacon.InvalidPayloadAttributes.With(err)
+				}
+				log.Warn("External builder failed to build payload", "builder", builder.Name(), "err", err)
+				continue
+			}
+			req.builds = append(req.builds, &payloadBuild{builder: builder.Name(), local: i == 0, result: resCh})
 		}
 		id := computePayloadId(update.HeadBlockHash, payloadAttributes)
-		api.localBlocks.put(id, &payload{empty: empty, result: resCh})
+		api.localBlocks.put(id, req)
 		return valid(&id), nil
 	}
 	return valid(nil), nil
@@ -320,13 +368,68 @@ func (api *ConsensusAPI) GetPayloadV1(payloadID beacon.PayloadID) (*beacon.Execu
 	if data == nil {
 		return nil, beacon.UnknownPayload
 	}
-	return data, nil
+	if api.eth.BlockChain().Config().IsPrague(data.Block.Number()) {
+		return nil, beacon.UnsupportedFork
+	}
+	return beacon.BlockToExecutableData(data.Block), nil
+}
+
+// GetPayloadV4 returns a cached Prague payload by id, along with its value and
+// the execution layer requests of the block.
+func (api *ConsensusAPI) GetPayloadV4(payloadID beacon.PayloadID) (*beacon.ExecutionPayloadEnvelope, error) {
+	log.Trace("Engine API request received", "method", "GetPayload", "id", payloadID)
+	data := api.localBlocks.get(payloadID)
+	if data == nil {
+		return nil, beacon.UnknownPayload
+	}
+	if !api.eth.BlockChain().Config().IsPrague(data.Block.Number()) {
+		return nil, beacon.UnsupportedFork
+	}
+	return beacon.BlockToExecutionPayloadEnvelope(data.Block, data.Value, data.Requests), nil
 }
 
 // NewPayloadV1 creates an Eth1 block, inserts it in the chain, and returns the status of the chain.
 func (api *ConsensusAPI) NewPayloadV1(params beacon.ExecutableDataV1) (beacon.PayloadStatusV1, error) {
+	if api.eth.BlockChain().Config().IsPrague(new(big.Int).SetUint64(params.Number)) {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.UnsupportedFork.With(errors.New("newPayloadV1 called for Prague payload"))
+	}
+	return api.newPayload(params, nil, nil)
+}
+
+// NewPayloadV4 creates a Prague block from the payload, the parent beacon root
+// and the execution layer requests, inserts it in the chain, and returns the
+// status of the chain. Blob transactions are not supported, so the list of
+// expected blob versioned hashes must be empty.
+func (api *ConsensusAPI) NewPayloadV4(params beacon.ExecutableDataV1, versionedHashes []common.Hash, beaconRoot *common.Hash, executionRequests []hexutil.Bytes) (beacon.PayloadStatusV1, error) {
+	switch {
+	case versionedHashes == nil:
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("nil versionedHashes post-cancun"))
+	case beaconRoot == nil:
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("nil beaconRoot post-cancun"))
+	case executionRequests == nil:
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(errors.New("nil executionRequests post-prague"))
+	}
+	if !api.eth.BlockChain().Config().IsPrague(new(big.Int).SetUint64(params.Number)) {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.UnsupportedFork.With(errors.New("newPayloadV4 called for pre-Prague payload"))
+	}
+	requests := make([][]byte, len(executionRequests))
+	for i, list := range executionRequests {
+		requests[i] = list
+	}
+	if _, err := types.DecodeRequests(requests); err != nil {
+		return beacon.PayloadStatusV1{Status: beacon.INVALID}, beacon.InvalidParams.With(err)
+	}
+	if len(versionedHashes) != 0 {
+		return api.invalid(fmt.Errorf("invalid number of versionedHashes: %d blob hashes, none in the payload", len(versionedHashes)), nil), nil
+	}
+	return api.newPayload(params, beaconRoot, requests)
+}
+
+// newPayload implements the new payload method of all versions. The beacon root
+// and requests are nil before Prague.
+func (api *ConsensusAPI) newPayload(params beacon.ExecutableDataV1, beaconRoot *common.Hash, requests [][]byte) (beacon.PayloadStatusV1, error) {
 	log.Trace("Engine API request received", "method", "ExecutePayload", "number", params.Number, "hash", params.BlockHash)
-	block, err := beacon.ExecutableDataToBlock(params)
+	block, err := beacon.ExecutableDataToBlock(params, beaconRoot, requests)
 	if err != nil {
 		log.Debug("Invalid NewPayload params", "params", params, "error", err)
 		return beacon.PayloadStatusV1{Status: beacon.INVALIDBLOCKHASH}, nil
@@ -418,6 +521,9 @@ func computePayloadId(headBlockHash common.Hash, params *beacon.PayloadAttribute
 	binary.Write(hasher, binary.BigEndian, params.Timestamp)
 	hasher.Write(params.Random[:])
 	hasher.Write(params.SuggestedFeeRecipient[:])
+	if params.BeaconRoot != nil {
+		hasher.Write(params.BeaconRoot[:])
+	}
 	var out beacon.PayloadID
 	copy(out[:], hasher.Sum(nil)[:8])
 	return out
@@ -26,14 +26,17 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/beacon"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
@@ -184,6 +187,140 @@ func TestEth2PrepareAndGetPayload(t *testing.T) {
 	}
 }
 
+// testPayloadBuilder is an external builder proposing empty blocks built by the
+// local miner, claiming a fixed value. If payment is set, it instead proposes a
+// block transferring that amount from the tester account to the fee recipient.
+type testPayloadBuilder struct {
+	name    string
+	eth     *eth.Ethereum
+	value   *big.Int
+	payment *big.Int
+	parent  common.Hash // Overrides the parent of the requested block, if set
+}
+
+func (b *testPayloadBuilder) Name() string { return b.name }
+
+func (b *testPayloadBuilder) BuildPayload(args *miner.BuildPayloadArgs) (<-chan *miner.BuiltPayload, error) {
+	var (
+		block *types.Block
+		err   error
+	)
+	if b.payment != nil {
+		block, err = b.buildPayment(args)
+	} else {
+		block, err = b.eth.Miner().GetSealingBlockSync(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if b.parent != (common.Hash{}) {
+		header := block.Header()
+		header.ParentHash = b.parent
+		block = types.NewBlockWithHeader(header)
+	}
+	resCh := make(chan *miner.BuiltPayload, 1)
+	resCh <- &miner.BuiltPayload{Block: block, Value: b.value}
+	return resCh, nil
+}
+
+// buildPayment assembles a block paying the fee recipient directly.
+func (b *testPayloadBuilder) buildPayment(args *miner.BuildPayloadArgs) (*types.Block, error) {
+	chain := b.eth.BlockChain()
+	parent := chain.GetHeaderByHash(args.Parent)
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   args.FeeRecipient,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       args.Timestamp,
+		MixDigest:  args.Random,
+		Difficulty: common.Big0,
+		BaseFee:    misc.CalcBaseFee(chain.Config(), parent),
+	}
+	tx := types.MustSignNewTx(testKey, types.LatestSigner(chain.Config()), &types.DynamicFeeTx{
+		ChainID:   chain.Config().ChainID,
+		Nonce:     statedb.GetNonce(testAddr),
+		To:        &args.FeeRecipient,
+		Value:     b.payment,
+		Gas:       params.TxGas,
+		GasFeeCap: new(big.Int).Mul(header.BaseFee, common.Big2),
+	})
+	statedb.Prepare(tx.Hash(), 0)
+	receipt, err := core.ApplyTransaction(chain.Config(), chain, &header.Coinbase, new(core.GasPool).AddGas(header.GasLimit), statedb, header, tx, &header.GasUsed, *chain.GetVMConfig())
+	if err != nil {
+		return nil, err
+	}
+	return chain.Engine().FinalizeAndAssemble(chain, header, statedb, []*types.Transaction{tx}, nil, []*types.Receipt{receipt})
+}
+
+func TestExternalPayloadBuilders(t *testing.T) {
+	genesis, blocks := generatePreMergeChain(10)
+	genesis.Config.TerminalTotalDifficulty.Sub(genesis.Config.TerminalTotalDifficulty, blocks[9].Difficulty())
+	n, ethservice := startEthService(t, genesis, blocks[:9])
+	defer n.Close()
+
+	api := NewConsensusAPI(ethservice)
+	ethservice.TxPool().AddLocals(blocks[9].Transactions())
+
+	// getPayload requests a payload, returning its number of transactions
+	timestamp := blocks[8].Time() + 5
+	getPayload := func() int {
+		t.Helper()
+
+		blockParams := beacon.PayloadAttributesV1{Timestamp: timestamp}
+		fcState := beacon.ForkchoiceStateV1{HeadBlockHash: blocks[8].Hash()}
+		if _, err := api.ForkchoiceUpdatedV1(fcState, &blockParams); err != nil {
+			t.Fatalf("error preparing payload, err=%v", err)
+		}
+		execData, err := api.GetPayloadV1(computePayloadId(fcState.HeadBlockHash, &blockParams))
+		if err != nil {
+			t.Fatalf("error getting payload, err=%v", err)
+		}
+		timestamp++
+		return len(execData.Transactions)
+	}
+	txs := blocks[9].Transactions().Len()
+
+	// A bogus builder outbidding the local one is discarded
+	bogus := &testPayloadBuilder{name: "bogus", eth: ethservice, value: big.NewInt(params.Ether), parent: common.Hash{1}}
+	if err := ethservice.Miner().RegisterPayloadBuilder(bogus); err != nil {
+		t.Fatalf("failed to register builder: %v", err)
+	}
+	if have := getPayload(); have != txs {
+		t.Fatalf("bogus payload selected: have %d txs, want %d", have, txs)
+	}
+	// A builder lying about the value of its payload is outbid by the local one
+	liar := &testPayloadBuilder{name: "liar", eth: ethservice, value: big.NewInt(params.Ether)}
+	if err := ethservice.Miner().RegisterPayloadBuilder(liar); err != nil {
+		t.Fatalf("failed to register builder: %v", err)
+	}
+	if have := getPayload(); have != txs {
+		t.Fatalf("overvalued payload selected: have %d txs, want %d", have, txs)
+	}
+	// A valid builder outbidding the local one is selected, unless rejected by
+	// the validation hook
+	outbid := &testPayloadBuilder{name: "outbid", eth: ethservice, value: common.Big0, payment: big.NewInt(params.GWei)}
+	if err := ethservice.Miner().RegisterPayloadBuilder(outbid); err != nil {
+		t.Fatalf("failed to register builder: %v", err)
+	}
+	if err := ethservice.Miner().RegisterPayloadBuilder(outbid); err == nil {
+		t.Fatal("builder registered twice")
+	}
+	if have := getPayload(); have != 1 {
+		t.Fatalf("external payload not selected: have %d txs, want 1", have)
+	}
+	ethservice.Miner().SetPayloadValidator(func(args *miner.BuildPayloadArgs, payload *miner.BuiltPayload) error {
+		return fmt.Errorf("rejected payload of value %v", payload.Value)
+	})
+	if have := getPayload(); have != txs {
+		t.Fatalf("rejected payload selected: have %d txs, want %d", have, txs)
+	}
+}
+
 func checkLogEvents(t *testing.T, logsCh <-chan []*types.Log, rmLogsCh <-chan core.RemovedLogsEvent, wantNew, wantRemoved int) {
 	t.Helper()
 
@@ -276,7 +413,7 @@ func TestEth2NewBlock(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to create the executable data %v", err)
 		}
-		block, err := beacon.ExecutableDataToBlock(*execData)
+		block, err := beacon.ExecutableDataToBlock(*execData, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to convert executable data to block %v", err)
 		}
@@ -316,7 +453,7 @@ func TestEth2NewBlock(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to create the executable data %v", err)
 		}
-		block, err := beacon.ExecutableDataToBlock(*execData)
+		block, err := beacon.ExecutableDataToBlock(*execData, nil, nil)
 		if err != nil {
 			t.Fatalf("Failed to convert executable data to block %v", err)
 		}
@@ -859,3 +996,110 @@ func TestNewPayloadOnInvalidTerminalBlock(t *testing.T) {
 		t.Fatalf("error sending invalid forkchoice, invalid status: %v", resp.PayloadStatus.Status)
 	}
 }
+
+// errorCode returns the engine API error code of err, or zero if it has none.
+func errorCode(err error) int {
+	if err, ok := err.(*beacon.EngineAPIError); ok {
+		return err.ErrorCode()
+	}
+	return 0
+}
+
+func TestPraguePayloads(t *testing.T) {
+	// Generate a pre-merge chain on which block 3 activates Prague. The withdrawal
+	// request contract always returns the same request, the consolidation one none.
+	withdrawal := &types.WithdrawalRequest{SourceAddress: testAddr, ValidatorPubkey: [48]byte{1}, Amount: 1}
+	withdrawalData, _ := types.NewRequest(withdrawal).MarshalBinary()
+	withdrawalCode := append([]byte{
+		byte(vm.PUSH1), types.WithdrawalRequestSize, byte(vm.PUSH1), 12, byte(vm.PUSH1), 0, byte(vm.CODECOPY),
+		byte(vm.PUSH1), types.WithdrawalRequestSize, byte(vm.PUSH1), 0, byte(vm.RETURN)}, withdrawalData[1:]...)
+
+	config := *params.AllEthashProtocolChanges
+	config.PragueBlock = big.NewInt(3)
+	genesis := &core.Genesis{
+		Config: &config,
+		Alloc: core.GenesisAlloc{
+			testAddr:                         {Balance: testBalance},
+			params.WithdrawalQueueAddress:    {Code: withdrawalCode, Balance: common.Big0},
+			params.ConsolidationQueueAddress: {Code: []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.RETURN)}, Balance: common.Big0},
+		},
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+		Difficulty: big.NewInt(0),
+	}
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(&config, genesis.MustCommit(db), ethash.NewFaker(), db, 2, func(i int, g *core.BlockGen) { g.OffsetTime(5) })
+	config.TerminalTotalDifficulty = new(big.Int).Add(blocks[0].Difficulty(), blocks[1].Difficulty())
+
+	n, ethservice := startEthService(t, genesis, blocks)
+	defer n.Close()
+
+	var (
+		api     = NewConsensusAPI(ethservice)
+		parent  = blocks[1]
+		root    = common.Hash{0x42}
+		fcState = beacon.ForkchoiceStateV1{HeadBlockHash: parent.Hash()}
+		attrs   = beacon.PayloadAttributesV1{
+			Timestamp:             parent.Time() + 5,
+			SuggestedFeeRecipient: testAddr,
+			Withdrawals:           []*beacon.Withdrawal{},
+			BeaconRoot:            &root,
+		}
+		v1attrs = beacon.PayloadAttributesV1{Timestamp: attrs.Timestamp, SuggestedFeeRecipient: testAddr}
+	)
+	// Prague payloads must be built through the V3 forkchoice update
+	if _, err := api.ForkchoiceUpdatedV1(fcState, &v1attrs); errorCode(err) != beacon.UnsupportedFork.ErrorCode() {
+		t.Fatalf("wrong error for V1 Prague payload attributes: %v", err)
+	}
+	if _, err := api.ForkchoiceUpdatedV3(fcState, &v1attrs); errorCode(err) != beacon.InvalidParams.ErrorCode() {
+		t.Fatalf("wrong error for payload attributes without beacon root: %v", err)
+	}
+	resp, err := api.ForkchoiceUpdatedV3(fcState, &attrs)
+	if err != nil {
+		t.Fatalf("error preparing payload: %v", err)
+	}
+	if _, err := api.GetPayloadV1(*resp.PayloadID); errorCode(err) != beacon.UnsupportedFork.ErrorCode() {
+		t.Fatalf("wrong error for V1 Prague payload retrieval: %v", err)
+	}
+	envelope, err := api.GetPayloadV4(*resp.PayloadID)
+	if err != nil {
+		t.Fatalf("error getting payload: %v", err)
+	}
+	want := (types.Requests{types.NewRequest(withdrawal)}).Encode()
+	if len(envelope.ExecutionRequests) != 1 || !bytes.Equal(envelope.ExecutionRequests[0], want[0]) {
+		t.Fatalf("wrong execution requests: have %x, want %x", envelope.ExecutionRequests, want)
+	}
+	payload := *envelope.ExecutionPayload
+	if payload.Withdrawals == nil || payload.BlobGasUsed == nil || payload.ExcessBlobGas == nil {
+		t.Fatal("missing Cancun fields in Prague payload")
+	}
+	// Invalid payload submissions must be rejected
+	if _, err := api.NewPayloadV1(payload); errorCode(err) != beacon.UnsupportedFork.ErrorCode() {
+		t.Fatalf("wrong error for V1 Prague payload: %v", err)
+	}
+	if _, err := api.NewPayloadV4(payload, []common.Hash{}, &root, nil); errorCode(err) != beacon.InvalidParams.ErrorCode() {
+		t.Fatalf("wrong error for missing execution requests: %v", err)
+	}
+	if _, err := api.NewPayloadV4(payload, []common.Hash{}, &root, []hexutil.Bytes{{types.WithdrawalRequestType}}); errorCode(err) != beacon.InvalidParams.ErrorCode() {
+		t.Fatalf("wrong error for empty request list: %v", err)
+	}
+	status, err := api.NewPayloadV4(payload, []common.Hash{}, &root, []hexutil.Bytes{})
+	if err != nil || status.Status != beacon.INVALIDBLOCKHASH {
+		t.Fatalf("wrong status for payload with other requests: %v %v", status.Status, err)
+	}
+	status, err = api.NewPayloadV4(payload, []common.Hash{}, &common.Hash{}, envelope.ExecutionRequests)
+	if err != nil || status.Status != beacon.INVALIDBLOCKHASH {
+		t.Fatalf("wrong status for payload with other beacon root: %v %v", status.Status, err)
+	}
+	// The payload along with its requests must be accepted
+	status, err = api.NewPayloadV4(payload, []common.Hash{}, &root, envelope.ExecutionRequests)
+	if err != nil || status.Status != beacon.VALID {
+		t.Fatalf("wrong status for valid payload: %v %v", status.Status, err)
+	}
+	header := ethservice.BlockChain().GetHeaderByHash(payload.BlockHash)
+	if header.ParentBeaconRoot == nil || *header.ParentBeaconRoot != root {
+		t.Fatalf("wrong parent beacon root %v", header.ParentBeaconRoot)
+	}
+	if header.RequestsHash == nil || *header.RequestsHash != types.CalcRequestsHash(want) {
+		t.Fatalf("wrong requests hash %v", header.RequestsHash)
+	}
+}
@@ -23,6 +23,8 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/beacon"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 )
 
 // maxTrackedPayloads is the maximum number of prepared payloads the execution
@@ -35,45 +37,85 @@ const maxTrackedPayloads = 10
 // latest one; but have a slight wiggle room for non-ideal conditions.
 const maxTrackedHeaders = 10
 
-// payload wraps the miner's block production channel, allowing the mined block
-// to be retrieved later upon the GetPayload engine API call.
+// payload wraps the block production channels of the payload builders, allowing
+// the most valuable block to be retrieved later upon the GetPayload engine API
+// call.
 type payload struct {
-	lock   sync.Mutex
-	done   bool
-	empty  *types.Block
-	block  *types.Block
-	result chan *types.Block
+	lock     sync.Mutex
+	args     *miner.BuildPayloadArgs
+	empty    *miner.BuiltPayload
+	builds   []*payloadBuild
+	validate miner.PayloadValidator // Validation of external payloads
 }
 
-// resolve extracts the generated full block from the given channel if possible
-// or fallback to empty block as an alternative.
-func (req *payload) resolve() *beacon.ExecutableDataV1 {
+// payloadBuild is a block being built by a single builder.
+type payloadBuild struct {
+	builder string
+	local   bool
+	result  <-chan *miner.BuiltPayload
+	done    bool
+	payload *miner.BuiltPayload // Nil if building or validation failed
+}
+
+// resolve extracts the generated blocks from the builders if possible, and
+// returns the one of the highest value, falling back to the empty block if no
+// builder succeeded.
+func (req *payload) resolve() *miner.BuiltPayload {
 	// this function can be called concurrently, prevent any
 	// concurrency issue in the first place.
 	req.lock.Lock()
 	defer req.lock.Unlock()
 
-	// Try to resolve the full block first if it's not obtained
-	// yet. The returned block can be nil if the generation fails.
-
-	if !req.done {
-		timeout := time.NewTimer(500 * time.Millisecond)
-		defer timeout.Stop()
-
-		select {
-		case req.block = <-req.result:
-			req.done = true
-		case <-timeout.C:
-			// TODO(rjl49345642, Marius), should we keep this
-			// 100ms timeout allowance? Why not just use the
-			// default and then fallback to empty directly?
+	// Try to resolve the blocks not obtained yet, allowing all builders the same
+	// time. The returned blocks can be nil if the generation fails.
+	var (
+		timeout = time.NewTimer(500 * time.Millisecond)
+		expired bool
+	)
+	defer timeout.Stop()
+
+	for _, build := range req.builds {
+		if build.done {
+			continue
+		}
+		if !expired {
+			select {
+			case build.payload = <-build.result:
+				build.done = true
+			case <-timeout.C:
+				expired = true
+			}
+		}
+		if !build.done {
+			// Out of time, only take the block if it's ready
+			select {
+			case build.payload = <-build.result:
+				build.done = true
+			default:
+				continue
+			}
+		}
+		if build.payload != nil && !build.local {
+			if err := req.validate(req.args, build.payload); err != nil {
+				log.Warn("Discarding invalid external payload", "builder", build.builder, "err", err)
+				build.payload = nil
+			}
 		}
 	}
-
-	if req.block != nil {
-		return beacon.BlockToExecutableData(req.block)
+	var best *payloadBuild
+	for _, build := range req.builds {
+		if build.payload == nil {
+			continue
+		}
+		if best == nil || build.payload.Value.Cmp(best.payload.Value) > 0 {
+			best = build
+		}
+	}
+	if best == nil {
+		return req.empty
 	}
-	return beacon.BlockToExecutableData(req.empty)
+	log.Debug("Selected payload", "builder", best.builder, "value", best.payload.Value, "hash", best.payload.Block.Hash())
+	return best.payload
 }
 
 // payloadQueueItem represents an id->payload tuple to store until it's retrieved
@@ -111,7 +153,7 @@ func (q *payloadQueue) put(id beacon.PayloadID, data *payload) {
 }
 
 // get retrieves a previously stored payload item or nil if it does not exist.
-func (q *payloadQueue) get(id beacon.PayloadID) *beacon.ExecutableDataV1 {
+func (q *payloadQueue) get(id beacon.PayloadID) *miner.BuiltPayload {
 	q.lock.RLock()
 	defer q.lock.RUnlock()
 
@@ -0,0 +1,267 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package clone implements seeding a fresh node with the complete chain and state
+// of another node run by the same operator.
+//
+// The source node streams a consistent view of its database, the ancient chain
+// segments followed by all key-value entries, over a single TCP connection to the
+// node being set up. Both sides authenticate each other with a pre-shared secret,
+// from which the key encrypting and authenticating the stream is derived.
+package clone
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// protocolVersion is the version of the transfer stream format.
+	protocolVersion = 2
+
+	// handshakeTimeout is the time allowed for mutual authentication.
+	handshakeTimeout = 10 * time.Second
+
+	// ancientBatchItems is the number of ancient items written at once.
+	ancientBatchItems = 2048
+
+	// minSecretSize is the minimum size of the pre-shared secret.
+	minSecretSize = 32
+
+	// bufferSize is the size of the read and write buffers of the connection.
+	bufferSize = 1024 * 1024
+)
+
+var (
+	errUnauthorized = errors.New("clone peer failed authentication")
+	errNotEmpty     = errors.New("local database is not empty")
+)
+
+// Authentication labels, preventing a peer from reflecting the challenge.
+var (
+	serverLabel = []byte("geth clone server")
+	clientLabel = []byte("geth clone client")
+)
+
+// header is the first message of the transfer stream.
+type header struct {
+	Version  uint
+	Tables   []string // Names of the ancient tables, in the order of the item values
+	Ancients uint64   // Number of ancient items following the header
+}
+
+// entry is a key-value database entry of the transfer stream. An entry with an
+// empty key ends the stream, with the value holding the number of entries sent.
+type entry struct {
+	Key   []byte
+	Value []byte
+}
+
+// LoadSecret reads a hex encoded pre-shared secret from a file.
+func LoadSecret(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	secret := common.FromHex(strings.TrimSpace(string(data)))
+	if len(secret) < minSecretSize {
+		return nil, fmt.Errorf("clone secret too short: have %d bytes, want at least %d", len(secret), minSecretSize)
+	}
+	return secret, nil
+}
+
+// handshake mutually authenticates the two ends of a connection. Each side sends
+// a random challenge and proves knowledge of the secret by returning its MAC. The
+// key of the transfer stream is returned.
+func handshake(conn net.Conn, secret []byte, server bool) ([]byte, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	own, remote := clientLabel, serverLabel
+	if server {
+		own, remote = serverLabel, clientLabel
+	}
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(challenge); err != nil {
+		return nil, err
+	}
+	peerChallenge := make([]byte, 32)
+	if _, err := io.ReadFull(conn, peerChallenge); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(authMAC(secret, own, peerChallenge)); err != nil {
+		return nil, err
+	}
+	mac := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(conn, mac); err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, authMAC(secret, remote, challenge)) {
+		return nil, errUnauthorized
+	}
+	if server {
+		return streamKey(secret, peerChallenge, challenge), nil
+	}
+	return streamKey(secret, challenge, peerChallenge), nil
+}
+
+// authMAC computes the response to an authentication challenge.
+func authMAC(secret, label, challenge []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(label)
+	mac.Write(challenge)
+	return mac.Sum(nil)
+}
+
+// Clone retrieves the complete database of the node serving clones at addr into
+// the local database, which must be empty.
+func Clone(ctx context.Context, addr string, secret []byte, db ethdb.Database) error {
+	if frozen, _ := db.Ancients(); frozen > 0 || rawdb.ReadHeadHeaderHash(db) != (common.Hash{}) {
+		return errNotEmpty
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	key, err := handshake(conn, secret, false)
+	if err != nil {
+		return err
+	}
+	r, err := newSealedReader(bufio.NewReaderSize(conn, bufferSize), key)
+	if err != nil {
+		return err
+	}
+	stream := rlp.NewStream(r, 0)
+
+	var head header
+	if err := stream.Decode(&head); err != nil {
+		return err
+	}
+	if head.Version != protocolVersion {
+		return fmt.Errorf("unsupported clone protocol version %d", head.Version)
+	}
+	log.Info("Cloning database", "addr", addr, "ancients", head.Ancients)
+	if err := receiveAncients(stream, &head, db); err != nil {
+		return fmt.Errorf("failed to receive ancients: %w", err)
+	}
+	if err := receiveEntries(stream, db); err != nil {
+		return fmt.Errorf("failed to receive database entries: %w", err)
+	}
+	return nil
+}
+
+// receiveAncients writes the ancient items of the transfer stream.
+func receiveAncients(stream *rlp.Stream, head *header, db ethdb.Database) error {
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for next := uint64(0); next < head.Ancients; {
+		_, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := 0; i < ancientBatchItems && next < head.Ancients; i++ {
+				var values [][]byte
+				if err := stream.Decode(&values); err != nil {
+					return err
+				}
+				if len(values) != len(head.Tables) {
+					return fmt.Errorf("item %d has %d values, want %d", next, len(values), len(head.Tables))
+				}
+				for j, table := range head.Tables {
+					if err := op.AppendRaw(table, next, values[j]); err != nil {
+						return err
+					}
+				}
+				next++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Cloning ancients", "items", next, "total", head.Ancients, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return db.Sync()
+}
+
+// receiveEntries writes the key-value entries of the transfer stream.
+func receiveEntries(stream *rlp.Stream, db ethdb.Database) error {
+	var (
+		batch  = db.NewBatch()
+		count  uint64
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for {
+		var e entry
+		if err := stream.Decode(&e); err != nil {
+			return err
+		}
+		if len(e.Key) == 0 {
+			if len(e.Value) != 8 || binary.BigEndian.Uint64(e.Value) != count {
+				return errors.New("transfer incomplete")
+			}
+			break
+		}
+		if err := batch.Put(e.Key, e.Value); err != nil {
+			return err
+		}
+		count++
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Cloning database entries", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Cloned database", "entries", count, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+func newTestDatabase(t *testing.T) ethdb.Database {
+	db, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Tests that a clone contains the complete ancient store and key-value store of
+// the source node, and that clients with a different secret are rejected.
+func TestClone(t *testing.T) {
+	source := newTestDatabase(t)
+
+	var (
+		blocks   []*types.Block
+		receipts []types.Receipts
+		parent   common.Hash
+	)
+	for i := 0; i < 100; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Difficulty: big.NewInt(1)}
+		block := types.NewBlockWithHeader(header)
+		blocks, receipts = append(blocks, block), append(receipts, nil)
+		parent = block.Hash()
+	}
+	if _, err := rawdb.WriteAncientBlocks(source, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	rawdb.WriteHeadHeaderHash(source, parent)
+	for i := 0; i < 1000; i++ {
+		source.Put([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	secret := bytes.Repeat([]byte{0x01}, minSecretSize)
+	server := NewServer(source, "127.0.0.1:0", secret)
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	// Clients with the wrong secret must not get any data
+	target := newTestDatabase(t)
+	if err := Clone(context.Background(), server.Addr().String(), bytes.Repeat([]byte{0x02}, minSecretSize), target); !errors.Is(err, errUnauthorized) {
+		t.Fatalf("wrong secret error mismatch: have %v, want %v", err, errUnauthorized)
+	}
+	if err := Clone(context.Background(), server.Addr().String(), secret, target); err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	// Verify the ancients and key-value entries
+	if frozen, _ := target.Ancients(); frozen != uint64(len(blocks)) {
+		t.Fatalf("ancient count mismatch: have %d, want %d", frozen, len(blocks))
+	}
+	for _, table := range rawdb.ChainFreezerTables() {
+		for n := uint64(0); n < uint64(len(blocks)); n++ {
+			want, _ := source.Ancient(table, n)
+			have, _ := target.Ancient(table, n)
+			if !bytes.Equal(have, want) {
+				t.Fatalf("ancient %s item %d mismatch", table, n)
+			}
+		}
+	}
+	it := source.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if have, _ := target.Get(it.Key()); !bytes.Equal(have, it.Value()) {
+			t.Fatalf("entry %q mismatch: have %q, want %q", it.Key(), have, it.Value())
+		}
+	}
+	// Cloning into a non-empty database must fail
+	if err := Clone(context.Background(), server.Addr().String(), secret, target); err != errNotEmpty {
+		t.Fatalf("non-empty database error mismatch: have %v, want %v", err, errNotEmpty)
+	}
+}
+
+// Tests that the transfer stream round-trips, and that modified, reordered or
+// truncated frames are rejected.
+func TestSealedStream(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, 32)
+	data := make([]byte, 3*maxFrameSize+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	var buf bytes.Buffer
+	w, err := newSealedWriter(&buf, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data[:10])
+	w.Write(data[10:])
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	sealed := buf.Bytes()
+	if bytes.Contains(sealed, data[:64]) {
+		t.Fatal("stream not encrypted")
+	}
+	read := func(stream []byte) ([]byte, error) {
+		r, err := newSealedReader(bytes.NewReader(stream), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return io.ReadAll(r)
+	}
+	if have, err := read(sealed); err != nil || !bytes.Equal(have, data) {
+		t.Fatalf("round trip mismatch: err %v", err)
+	}
+	frame := 4 + maxFrameSize + 16
+	tampered := common.CopyBytes(sealed)
+	tampered[100] ^= 0x01
+	if _, err := read(tampered); err != errTampered {
+		t.Fatalf("modified stream error mismatch: have %v, want %v", err, errTampered)
+	}
+	reordered := append(common.CopyBytes(sealed[frame:2*frame]), sealed[:frame]...)
+	if _, err := read(reordered); err != errTampered {
+		t.Fatalf("reordered stream error mismatch: have %v, want %v", err, errTampered)
+	}
+	if _, err := read(sealed[:len(sealed)-1]); err != io.ErrUnexpectedEOF {
+		t.Fatalf("truncated stream error mismatch: have %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
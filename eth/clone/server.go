@@ -0,0 +1,191 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clone
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Server serves the node's database to authenticated clone clients.
+type Server struct {
+	db     ethdb.Database
+	flush  func() error // Persists the in-memory state before a transfer, if set
+	addr   string
+	secret []byte
+
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	lock     sync.Mutex
+	wg       sync.WaitGroup
+}
+
+// New creates a clone server for the database of the chain and registers it with
+// the node. The head state is flushed to disk before every transfer.
+func New(stack *node.Node, chain *core.BlockChain, db ethdb.Database, addr string, secret []byte) *Server {
+	s := NewServer(db, addr, secret)
+	s.flush = chain.FlushHeadState
+	stack.RegisterLifecycle(s)
+	return s
+}
+
+// NewServer creates a clone server for the database, listening on addr.
+func NewServer(db ethdb.Database, addr string, secret []byte) *Server {
+	return &Server{
+		db:     db,
+		addr:   addr,
+		secret: secret,
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+// Start implements node.Lifecycle, opening the listener.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	log.Info("Clone server started", "addr", listener.Addr())
+
+	s.wg.Add(1)
+	go s.serve()
+	return nil
+}
+
+// Stop implements node.Lifecycle, closing the listener and all transfers.
+func (s *Server) Stop() error {
+	s.listener.Close()
+	s.lock.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lock.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+// Addr returns the listening address of the server.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		s.conns[conn] = struct{}{}
+		s.lock.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := s.handle(conn); err != nil {
+				log.Warn("Database clone failed", "remote", conn.RemoteAddr(), "err", err)
+			}
+			s.lock.Lock()
+			delete(s.conns, conn)
+			s.lock.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// handle authenticates a client and streams the database to it.
+func (s *Server) handle(conn net.Conn) error {
+	key, err := handshake(conn, s.secret, true)
+	if err != nil {
+		return err
+	}
+	log.Info("Serving database clone", "remote", conn.RemoteAddr())
+
+	// Write the dirty trie nodes of the head state, which are otherwise missing
+	// from the key-value store of a running node.
+	if s.flush != nil {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	// Open the iterator before looking at the ancients. Items frozen afterwards
+	// are still contained in the iterator's view of the key-value store.
+	it := s.db.NewIterator(nil, nil)
+	defer it.Release()
+
+	frozen, err := s.db.Ancients()
+	if err != nil {
+		return err
+	}
+	var (
+		bw     = bufio.NewWriterSize(conn, bufferSize)
+		tables = rawdb.ChainFreezerTables()
+	)
+	w, err := newSealedWriter(bw, key)
+	if err != nil {
+		return err
+	}
+	if err := rlp.Encode(w, &header{Version: protocolVersion, Tables: tables, Ancients: frozen}); err != nil {
+		return err
+	}
+	values := make([][]byte, len(tables))
+	for n := uint64(0); n < frozen; n++ {
+		for i, table := range tables {
+			if values[i], err = s.db.Ancient(table, n); err != nil {
+				return err
+			}
+		}
+		if err := rlp.Encode(w, values); err != nil {
+			return err
+		}
+	}
+	var count uint64
+	for it.Next() {
+		if err := rlp.Encode(w, &entry{Key: it.Key(), Value: it.Value()}); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], count)
+	if err := rlp.Encode(w, &entry{Value: enc[:]}); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	log.Info("Served database clone", "remote", conn.RemoteAddr(), "ancients", frozen, "entries", count)
+	return nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clone
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// maxFrameSize is the maximum plaintext size of a frame of the sealed stream.
+const maxFrameSize = 64 * 1024
+
+var errTampered = errors.New("clone stream tampered")
+
+// streamLabel separates the stream key from the authentication MACs.
+var streamLabel = []byte("geth clone stream")
+
+// streamKey derives the key sealing the transfer stream from the secret and the
+// challenges of both sides, so every transfer is sealed with a fresh key.
+func streamKey(secret, clientChallenge, serverChallenge []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(streamLabel)
+	mac.Write(clientChallenge)
+	mac.Write(serverChallenge)
+	return mac.Sum(nil)
+}
+
+// newStreamCipher creates the AEAD sealing the frames of the transfer stream.
+func newStreamCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// frameNonce returns the nonce of the frame with the given sequence number. The
+// key is unique to the transfer, so a counter never repeats a nonce, and frames
+// can't be dropped or reordered without failing authentication.
+func frameNonce(aead cipher.AEAD, seq uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+// sealedWriter encrypts and authenticates the data written to it in frames, each
+// prefixed with its sealed size.
+type sealedWriter struct {
+	w    io.Writer
+	aead cipher.AEAD
+	seq  uint64
+	buf  []byte
+}
+
+func newSealedWriter(w io.Writer, key []byte) (*sealedWriter, error) {
+	aead, err := newStreamCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &sealedWriter{w: w, aead: aead, buf: make([]byte, 0, maxFrameSize)}, nil
+}
+
+// Write implements io.Writer, sealing a frame whenever enough data is buffered.
+func (s *sealedWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		n := copy(s.buf[len(s.buf):cap(s.buf)], p)
+		s.buf, p = s.buf[:len(s.buf)+n], p[n:]
+		if len(s.buf) == maxFrameSize {
+			if err := s.Flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Flush seals the buffered data into a frame.
+func (s *sealedWriter) Flush() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	frame := s.aead.Seal(make([]byte, 4, 4+len(s.buf)+s.aead.Overhead()), frameNonce(s.aead, s.seq), s.buf, nil)
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+	s.seq++
+	s.buf = s.buf[:0]
+
+	_, err := s.w.Write(frame)
+	return err
+}
+
+// sealedReader authenticates and decrypts the frames written by a sealedWriter.
+type sealedReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	seq   uint64
+	frame []byte
+	data  []byte
+}
+
+func newSealedReader(r io.Reader, key []byte) (*sealedReader, error) {
+	aead, err := newStreamCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &sealedReader{r: r, aead: aead}, nil
+}
+
+// Read implements io.Reader.
+func (s *sealedReader) Read(p []byte) (int, error) {
+	if len(s.data) == 0 {
+		if err := s.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.data)
+	s.data = s.data[n:]
+	return n, nil
+}
+
+// next reads and opens the next frame of the stream.
+func (s *sealedReader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(s.r, size[:]); err != nil {
+		return err
+	}
+	n := int(binary.BigEndian.Uint32(size[:]))
+	if n <= s.aead.Overhead() || n > maxFrameSize+s.aead.Overhead() {
+		return errTampered
+	}
+	if cap(s.frame) < n {
+		s.frame = make([]byte, n)
+	}
+	s.frame = s.frame[:n]
+	if _, err := io.ReadFull(s.r, s.frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	data, err := s.aead.Open(s.frame[:0], frameNonce(s.aead, s.seq), s.frame, nil)
+	if err != nil {
+		return errTampered
+	}
+	s.seq++
+	s.data = data
+	return nil
+}
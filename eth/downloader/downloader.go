@@ -100,10 +100,11 @@ type Downloader struct {
 	mode uint32         // Synchronisation mode defining the strategy used (per sync cycle), use d.getMode() to get the SyncMode
 	mux  *event.TypeMux // Event multiplexer to announce sync operation events
 
-	checkpoint uint64   // Checkpoint block number to enforce head against (e.g. snap sync)
-	genesis    uint64   // Genesis block number to limit sync to (e.g. light client CHT)
-	queue      *queue   // Scheduler for selecting the hashes to download
-	peers      *peerSet // Set of active peers from which download can proceed
+	checkpoint uint64           // Checkpoint block number to enforce head against (e.g. snap sync)
+	genesis    uint64           // Genesis block number to limit sync to (e.g. light client CHT)
+	queue      *queue           // Scheduler for selecting the hashes to download
+	peers      *peerSet         // Set of active peers from which download can proceed
+	mirrors    []*historyMirror // HTTP(S) era1 archives to retrieve history from
 
 	stateDB ethdb.Database // Database to state sync into (and deduplicate via)
 
@@ -122,6 +123,7 @@ type Downloader struct {
 	// Status
 	synchroniseMock func(id string, hash common.Hash) error // Replacement for synchronise during testing
 	synchronising   int32
+	reverseSyncing  int32 // Whether a reverse header sync is running
 	notified        int32
 	committed       int32
 	ancientLimit    uint64 // The maximum block number which can be regarded as ancient data.
@@ -400,6 +402,9 @@ func (d *Downloader) synchronise(id string, hash common.Hash, td, ttd *big.Int,
 	// Reset the queue, peer set and wake channels to clean any internal leftover state
 	d.queue.Reset(blockCacheMaxItems, blockCacheInitialItems)
 	d.peers.Reset()
+	for _, mirror := range d.mirrors {
+		mirror.conn.Reset()
+	}
 
 	for _, ch := range []chan bool{d.queue.blockWakeCh, d.queue.receiptWakeCh} {
 		select {
@@ -693,6 +698,10 @@ func (d *Downloader) Terminate() {
 
 	// Cancel any pending download requests
 	d.Cancel()
+
+	for _, mirror := range d.mirrors {
+		mirror.close()
+	}
 }
 
 // fetchHead retrieves the head header and prior pivot block (if available) from
@@ -1213,7 +1222,7 @@ func (d *Downloader) fillHeaderSkeleton(from uint64, skeleton []*types.Header) (
 	log.Debug("Filling up skeleton", "from", from)
 	d.queue.ScheduleSkeleton(from, skeleton)
 
-	err := d.concurrentFetch((*headerQueue)(d), false)
+	err := d.concurrentFetch((*headerQueue)(d), false, nil)
 	if err != nil {
 		log.Debug("Skeleton fill failed", "err", err)
 	}
@@ -1229,7 +1238,7 @@ func (d *Downloader) fillHeaderSkeleton(from uint64, skeleton []*types.Header) (
 // and also periodically checking for timeouts.
 func (d *Downloader) fetchBodies(from uint64, beaconMode bool) error {
 	log.Debug("Downloading block bodies", "origin", from)
-	err := d.concurrentFetch((*bodyQueue)(d), beaconMode)
+	err := d.concurrentFetch((*bodyQueue)(d), beaconMode, d.mirrors)
 
 	log.Debug("Block body download terminated", "err", err)
 	return err
@@ -1240,7 +1249,7 @@ func (d *Downloader) fetchBodies(from uint64, beaconMode bool) error {
 // and also periodically checking for timeouts.
 func (d *Downloader) fetchReceipts(from uint64, beaconMode bool) error {
 	log.Debug("Downloading receipts", "origin", from)
-	err := d.concurrentFetch((*receiptQueue)(d), beaconMode)
+	err := d.concurrentFetch((*receiptQueue)(d), beaconMode, d.mirrors)
 
 	log.Debug("Receipt download terminated", "err", err)
 	return err
@@ -76,10 +76,25 @@ type typedQueue interface {
 // concurrentFetch iteratively downloads scheduled block parts, taking available
 // peers, reserving a chunk of fetch requests for each and waiting for delivery
 // or timeouts.
-func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
+//
+// Besides the registered peers, the given history mirrors are also assigned
+// fetches, taking precedence over the remote peers to relieve the network.
+func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool, mirrors []*historyMirror) error {
 	// Create a delivery channel to accept responses from all peers
 	responses := make(chan *eth.Response)
 
+	// Resolve response origins from both the remote peers and the mirrors
+	mirrorConns := make(map[string]*peerConnection)
+	for _, mirror := range mirrors {
+		mirrorConns[mirror.conn.id] = mirror.conn
+	}
+	lookup := func(id string) *peerConnection {
+		if peer := d.peers.Peer(id); peer != nil {
+			return peer
+		}
+		return mirrorConns[id]
+	}
+
 	// Track the currently active requests and their timeout order
 	pending := make(map[string]*eth.Request)
 	defer func() {
@@ -158,12 +173,20 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 			}
 			sort.Sort(&peerCapacitySort{idles, caps})
 
+			// Mirrors never get dropped for stalling, just skip them while busy
+			var mirrorIdles []*peerConnection
+			for _, mirror := range mirrors {
+				if pending[mirror.conn.id] == nil && stales[mirror.conn.id] == nil {
+					mirrorIdles = append(mirrorIdles, mirror.conn)
+				}
+			}
 			var (
+				peerIdles  = len(idles)
 				progressed bool
 				throttled  bool
 				queued     = queue.pending()
 			)
-			for _, peer := range idles {
+			for _, peer := range append(mirrorIdles, idles...) {
 				// Short circuit if throttling activated or there are no more
 				// queued tasks to be retrieved
 				if throttled {
@@ -209,7 +232,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 			}
 			// Make sure that we have peers available for fetching. If all peers have been tried
 			// and all failed throw an error
-			if !progressed && !throttled && len(pending) == 0 && len(idles) == d.peers.Len() && queued > 0 && !beaconMode {
+			if !progressed && !throttled && len(pending) == 0 && peerIdles == d.peers.Len() && queued > 0 && !beaconMode {
 				return errPeersUnavailable
 			}
 		}
@@ -304,7 +327,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 			// requires pushing the measured capacity a bit and seeing how response
 			// times reacts, to it always requests one more than the minimum (i.e.
 			// min 2).
-			peer := d.peers.Peer(req.Peer)
+			peer := lookup(req.Peer)
 			if peer == nil {
 				// If the peer got disconnected in between, we should really have
 				// short-circuited it already. Just in case there's some strange
@@ -312,7 +335,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 				log.Error("Delivery timeout from unknown peer", "peer", req.Peer)
 				continue
 			}
-			if fails > 2 {
+			if _, ok := mirrorConns[peer.id]; ok || fails > 2 {
 				queue.updateCapacity(peer, 0, 0)
 			} else {
 				d.dropPeer(peer.id)
@@ -357,7 +380,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue, beaconMode bool) error {
 
 			// If the peer was previously banned and failed to deliver its pack
 			// in a reasonable time frame, ignore its message.
-			if peer := d.peers.Peer(res.Req.Peer); peer != nil {
+			if peer := lookup(res.Req.Peer); peer != nil {
 				// Deliver the received chunk of data and check chain validity
 				accepted, err := queue.deliver(peer, res)
 				if errors.Is(err, errInvalidChain) {
@@ -79,6 +79,9 @@ func (q *bodyQueue) request(peer *peerConnection, req *fetchRequest, resCh chan
 		q.bodyFetchHook(req.Headers)
 	}
 
+	if mirror, ok := peer.peer.(*historyMirror); ok {
+		return mirror.requestBodies(req.Headers, resCh, q.cancelCh)
+	}
 	hashes := make([]common.Hash, 0, len(req.Headers))
 	for _, header := range req.Headers {
 		hashes = append(hashes, header.Hash())
@@ -78,6 +78,9 @@ func (q *receiptQueue) request(peer *peerConnection, req *fetchRequest, resCh ch
 	if q.receiptFetchHook != nil {
 		q.receiptFetchHook(req.Headers)
 	}
+	if mirror, ok := peer.peer.(*historyMirror); ok {
+		return mirror.requestReceipts(req.Headers, resCh, q.cancelCh)
+	}
 	hashes := make([]common.Hash, 0, len(req.Headers))
 	for _, header := range req.Headers {
 		hashes = append(hashes, header.Hash())
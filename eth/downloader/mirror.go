@@ -0,0 +1,444 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/msgrate"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// mirrorIndexTimeout is the maximum time allowed to retrieve the index files
+	// of a history mirror.
+	mirrorIndexTimeout = 30 * time.Second
+
+	// mirrorFileTimeout is the maximum time allowed to retrieve an era1 file from
+	// a history mirror. It's generous as the files may be large, but ensures that
+	// a stalled mirror can't hang sync.
+	mirrorFileTimeout = 10 * time.Minute
+)
+
+var (
+	errMirrorUnsupported = errors.New("history mirrors only serve bodies and receipts of scheduled headers")
+	errMirrorClosed      = errors.New("history mirror closed")
+)
+
+// mirrorFile is an era1 file listed in the index of a history mirror.
+type mirrorFile struct {
+	name     string
+	start    uint64
+	count    uint64
+	checksum common.Hash
+	broken   bool // Whether the file failed to download or verify
+}
+
+// historyMirror is an HTTP(S) archive of era1 files, as written by the
+// export-history command, used as an additional source of block bodies and
+// receipts during sync. The mirror is exposed to the concurrent fetchers as a
+// pseudo-peer, but it's never registered in the peer set, so headers are always
+// retrieved from the network.
+//
+// The mirror needs to serve an index.txt and a checksums.txt file besides the
+// era1 files. Every file is verified against its checksum after downloading, and
+// every block against the hash of the scheduled header before delivery. Bodies
+// and receipts are further validated by the queue against the header roots.
+type historyMirror struct {
+	url    string
+	client *http.Client
+	conn   *peerConnection // Pseudo-peer to reserve fetches in the queue for
+
+	lock     sync.Mutex
+	files    []*mirrorFile                 // Era1 files of the mirror, nil until the index is loaded
+	current  *mirrorFile                   // Era1 file currently downloaded
+	era      *era.Era                      // Reader of the currently downloaded era1 file
+	tmpfile  string                        // Path of the currently downloaded era1 file
+	fetching map[*mirrorFile]chan struct{} // Era1 files being downloaded, closed when done
+	closed   bool
+}
+
+// newHistoryMirror creates a history mirror serving era1 files from the given
+// base URL.
+func newHistoryMirror(url string) *historyMirror {
+	m := &historyMirror{
+		url:      strings.TrimSuffix(url, "/"),
+		client:   &http.Client{Timeout: mirrorFileTimeout},
+		fetching: make(map[*mirrorFile]chan struct{}),
+	}
+	m.conn = newPeerConnection("mirror:"+m.url, eth.ETH66, m, log.New("mirror", m.url))
+	m.conn.rates = msgrate.NewTracker(map[uint64]float64{
+		eth.BlockBodiesMsg: float64(MaxBlockFetch),
+		eth.ReceiptsMsg:    float64(MaxReceiptFetch),
+	}, time.Second)
+	return m
+}
+
+// Head implements Peer, mirrors are never used as the source of the chain head.
+func (m *historyMirror) Head() (common.Hash, *big.Int) {
+	return common.Hash{}, new(big.Int)
+}
+
+// RequestHeadersByHash implements Peer, but headers are not served by mirrors.
+func (m *historyMirror) RequestHeadersByHash(common.Hash, int, int, bool, chan *eth.Response) (*eth.Request, error) {
+	return nil, errMirrorUnsupported
+}
+
+// RequestHeadersByNumber implements Peer, but headers are not served by mirrors.
+func (m *historyMirror) RequestHeadersByNumber(uint64, int, int, bool, chan *eth.Response) (*eth.Request, error) {
+	return nil, errMirrorUnsupported
+}
+
+// RequestBodies implements Peer, but mirrors locate blocks by number, so bodies
+// can only be requested via requestBodies.
+func (m *historyMirror) RequestBodies([]common.Hash, chan *eth.Response) (*eth.Request, error) {
+	return nil, errMirrorUnsupported
+}
+
+// RequestReceipts implements Peer, but mirrors locate blocks by number, so
+// receipts can only be requested via requestReceipts.
+func (m *historyMirror) RequestReceipts([]common.Hash, chan *eth.Response) (*eth.Request, error) {
+	return nil, errMirrorUnsupported
+}
+
+// requestBodies retrieves the bodies of the given headers from the mirror in the
+// background, delivering them to the sink in the same form a remote peer would.
+// Only the leading headers covered by the mirror are served, an empty response
+// marks all of them as lacking from the mirror.
+func (m *historyMirror) requestBodies(headers []*types.Header, sink chan *eth.Response, cancel chan struct{}) (*eth.Request, error) {
+	req := &eth.Request{Peer: m.conn.id, Sent: time.Now()}
+	go func() {
+		blocks, _ := m.retrieve(headers)
+
+		var (
+			bodies        = make([]*eth.BlockBody, len(blocks))
+			txsHashes     = make([]common.Hash, len(blocks))
+			uncleHashes   = make([]common.Hash, len(blocks))
+			requestHashes = make([]common.Hash, len(blocks))
+			hasher        = trie.NewStackTrie(nil)
+		)
+		for i, block := range blocks {
+			bodies[i] = &eth.BlockBody{Transactions: block.Transactions(), Uncles: block.Uncles()}
+			txsHashes[i] = types.DeriveSha(block.Transactions(), hasher)
+			uncleHashes[i] = types.CalcUncleHash(block.Uncles())
+		}
+		m.deliver(req, (*eth.BlockBodiesPacket)(&bodies), [][]common.Hash{txsHashes, uncleHashes, requestHashes}, sink, cancel)
+	}()
+	return req, nil
+}
+
+// requestReceipts retrieves the receipts of the given headers from the mirror in
+// the background, delivering them to the sink in the same form a remote peer
+// would. Only the leading headers covered by the mirror are served.
+func (m *historyMirror) requestReceipts(headers []*types.Header, sink chan *eth.Response, cancel chan struct{}) (*eth.Request, error) {
+	req := &eth.Request{Peer: m.conn.id, Sent: time.Now()}
+	go func() {
+		_, receipts := m.retrieve(headers)
+
+		var (
+			packet = make([][]*types.Receipt, len(receipts))
+			hashes = make([]common.Hash, len(receipts))
+			hasher = trie.NewStackTrie(nil)
+		)
+		for i, list := range receipts {
+			packet[i] = list
+			hashes[i] = types.DeriveSha(list, hasher)
+		}
+		m.deliver(req, (*eth.ReceiptsPacket)(&packet), hashes, sink, cancel)
+	}()
+	return req, nil
+}
+
+// deliver sends a response to the concurrent fetcher, unless the sync cycle was
+// cancelled in the mean time.
+func (m *historyMirror) deliver(req *eth.Request, res interface{}, meta interface{}, sink chan *eth.Response, cancel chan struct{}) {
+	select {
+	case sink <- &eth.Response{
+		Req:  req,
+		Res:  res,
+		Meta: meta,
+		Time: time.Since(req.Sent),
+		Done: make(chan error, 1), // Ignore the returned status
+	}:
+	case <-cancel:
+	}
+}
+
+// retrieve reads the blocks and receipts of the given headers from the era1
+// files of the mirror, stopping at the first header which the mirror doesn't
+// cover or which doesn't match the archived block.
+func (m *historyMirror) retrieve(headers []*types.Header) ([]*types.Block, []types.Receipts) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return nil, nil
+	}
+	if m.files == nil {
+		if err := m.loadIndex(); err != nil {
+			m.conn.log.Warn("Failed to load history mirror index", "err", err)
+			return nil, nil
+		}
+	}
+	var (
+		blocks   []*types.Block
+		receipts []types.Receipts
+	)
+	for _, header := range headers {
+		// Mirrors archive pre-Prague history only, without execution layer requests
+		if header.RequestsHash != nil {
+			break
+		}
+		number := header.Number.Uint64()
+		if m.current == nil || number < m.current.start || number >= m.current.start+m.current.count {
+			file := m.lookup(number)
+			if file == nil {
+				break
+			}
+			if err := m.open(file); err == errMirrorClosed {
+				break
+			} else if err != nil {
+				m.conn.log.Warn("Failed to retrieve era1 file from history mirror", "file", file.name, "err", err)
+				file.broken = true
+				break
+			}
+		}
+		block, list, _, err := m.era.ReadBlock(number)
+		if err != nil {
+			m.conn.log.Warn("Failed to read block from history mirror", "number", number, "err", err)
+			break
+		}
+		if hash := block.Hash(); hash != header.Hash() {
+			m.conn.log.Warn("History mirror block mismatch", "number", number, "have", hash, "want", header.Hash())
+			break
+		}
+		blocks = append(blocks, block)
+		receipts = append(receipts, list)
+	}
+	return blocks, receipts
+}
+
+// lookup returns the usable era1 file containing the given block, if any.
+func (m *historyMirror) lookup(number uint64) *mirrorFile {
+	for _, file := range m.files {
+		if !file.broken && number >= file.start && number < file.start+file.count {
+			return file
+		}
+	}
+	return nil
+}
+
+// loadIndex retrieves the list of era1 files and their checksums from the mirror.
+func (m *historyMirror) loadIndex() error {
+	index, err := m.fetchLines("index.txt")
+	if err != nil {
+		return err
+	}
+	checksums, err := m.fetchLines("checksums.txt")
+	if err != nil {
+		return err
+	}
+	if len(index) != len(checksums) {
+		return fmt.Errorf("mismatched index and checksum entries: have %d files, %d checksums", len(index), len(checksums))
+	}
+	files := make([]*mirrorFile, 0, len(index))
+	for i, line := range index {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return fmt.Errorf("invalid index entry %q", line)
+		}
+		start, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid index entry %q", line)
+		}
+		count, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil || count == 0 || count > era.MaxEra1Size {
+			return fmt.Errorf("invalid index entry %q", line)
+		}
+		checksum, err := hexutil.Decode(checksums[i])
+		if err != nil || len(checksum) != common.HashLength {
+			return fmt.Errorf("invalid checksum %q", checksums[i])
+		}
+		files = append(files, &mirrorFile{
+			name:     fields[0],
+			start:    start,
+			count:    count,
+			checksum: common.BytesToHash(checksum),
+		})
+	}
+	m.files = files
+	m.conn.log.Info("Loaded history mirror index", "files", len(files))
+	return nil
+}
+
+// fetchLines retrieves a small text file from the mirror, returning its non-empty
+// lines.
+func (m *historyMirror) fetchLines(name string) ([]string, error) {
+	client := &http.Client{Transport: m.client.Transport, Timeout: mirrorIndexTimeout}
+	res, err := client.Get(m.url + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to retrieve %s: %s", name, res.Status)
+	}
+	var (
+		lines   []string
+		scanner = bufio.NewScanner(res.Body)
+	)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// open downloads an era1 file and replaces the currently open file with it. The
+// lock is released during the download and reacquired before returning, if the
+// same file is already being downloaded, that download is waited for instead.
+//
+// The method assumes that the lock is held!
+func (m *historyMirror) open(file *mirrorFile) error {
+	for {
+		done, ok := m.fetching[file]
+		if !ok {
+			break
+		}
+		m.lock.Unlock()
+		<-done
+		m.lock.Lock()
+	}
+	if m.closed {
+		return errMirrorClosed
+	}
+	if m.current == file {
+		return nil
+	}
+	if file.broken {
+		return fmt.Errorf("failed to retrieve %s", file.name)
+	}
+	done := make(chan struct{})
+	m.fetching[file] = done
+	m.lock.Unlock()
+
+	start := time.Now()
+	e, tmpfile, err := m.download(file)
+
+	m.lock.Lock()
+	delete(m.fetching, file)
+	close(done)
+
+	if err != nil {
+		return err
+	}
+	if m.closed {
+		e.Close()
+		os.Remove(tmpfile)
+		return errMirrorClosed
+	}
+	m.release()
+	m.current, m.era, m.tmpfile = file, e, tmpfile
+
+	m.conn.log.Debug("Retrieved era1 file from history mirror", "file", file.name, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// download retrieves an era1 file into a temporary file and verifies its checksum
+// and range.
+func (m *historyMirror) download(file *mirrorFile) (*era.Era, string, error) {
+	res, err := m.client.Get(m.url + "/" + file.name)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to retrieve %s: %s", file.name, res.Status)
+	}
+	f, err := os.CreateTemp("", "mirror-*.era1")
+	if err != nil {
+		return nil, "", err
+	}
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hasher), res.Body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", err
+	}
+	if checksum := common.BytesToHash(hasher.Sum(nil)); checksum != file.checksum {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", fmt.Errorf("checksum mismatch: have %x, want %x", checksum, file.checksum)
+	}
+	e, err := era.From(f)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", err
+	}
+	if e.Start() != file.start || e.Count() != file.count {
+		e.Close()
+		os.Remove(f.Name())
+		return nil, "", fmt.Errorf("range mismatch: have %d+%d, want %d+%d", e.Start(), e.Count(), file.start, file.count)
+	}
+	return e, f.Name(), nil
+}
+
+// release closes and deletes the currently open era1 file.
+func (m *historyMirror) release() {
+	if m.era != nil {
+		m.era.Close()
+		os.Remove(m.tmpfile)
+	}
+	m.current, m.era, m.tmpfile = nil, nil, ""
+}
+
+// close releases the resources of the mirror, which can't be used afterwards.
+func (m *historyMirror) close() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.release()
+	m.closed = true
+}
+
+// SetHistoryMirrors sets the HTTP(S) archives of era1 files to retrieve block
+// bodies and receipts from besides the remote peers. This method is not thread
+// safe and should be set only once on startup before syncing.
+func (d *Downloader) SetHistoryMirrors(urls []string) {
+	for _, url := range urls {
+		d.mirrors = append(d.mirrors, newHistoryMirror(url))
+	}
+}
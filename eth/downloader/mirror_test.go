@@ -0,0 +1,228 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/internal/era"
+)
+
+// newTestMirror exports the blocks of the given chain into era1 files of step
+// blocks each and serves them over HTTP. If corrupt is set, the checksums of the
+// files won't match. The returned counter tracks the era1 file downloads.
+func newTestMirror(t *testing.T, chain *core.BlockChain, step uint64, corrupt bool) (*httptest.Server, *int32) {
+	dir := t.TempDir()
+
+	var (
+		head      = chain.CurrentBlock().NumberU64()
+		index     []string
+		checksums []string
+	)
+	for first := uint64(0); first <= head; first += step {
+		name := fmt.Sprintf("test-%05d.era1", first/step)
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			builder = era.NewBuilder(f)
+			hasher  = sha256.New()
+			count   uint64
+		)
+		for n := first; n < first+step && n <= head; n++ {
+			block := chain.GetBlockByNumber(n)
+			if err := builder.Add(block, chain.GetReceiptsByHash(block.Hash()), chain.GetTd(block.Hash(), n)); err != nil {
+				t.Fatal(err)
+			}
+			count++
+		}
+		if _, err := builder.Finalize(); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		blob, _ := os.ReadFile(filepath.Join(dir, name))
+		hasher.Write(blob)
+		if corrupt {
+			hasher.Write([]byte{0x00})
+		}
+		index = append(index, fmt.Sprintf("%s %d %d", name, first, count))
+		checksums = append(checksums, common.BytesToHash(hasher.Sum(nil)).Hex())
+	}
+	os.WriteFile(filepath.Join(dir, "index.txt"), []byte(strings.Join(index, "\n")), 0644)
+	os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(strings.Join(checksums, "\n")), 0644)
+
+	var (
+		downloads = new(int32)
+		files     = http.FileServer(http.Dir(dir))
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".era1") {
+			atomic.AddInt32(downloads, 1)
+		}
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, downloads
+}
+
+// Tests that bodies and receipts are retrieved from history mirrors alongside
+// the remote peers, and that the synced chain is complete.
+func TestHistoryMirrorSync66Full(t *testing.T) { testHistoryMirrorSync(t, eth.ETH66, FullSync) }
+func TestHistoryMirrorSync66Snap(t *testing.T) { testHistoryMirrorSync(t, eth.ETH66, SnapSync) }
+func TestHistoryMirrorSync67Full(t *testing.T) { testHistoryMirrorSync(t, eth.ETH67, FullSync) }
+func TestHistoryMirrorSync67Snap(t *testing.T) { testHistoryMirrorSync(t, eth.ETH67, SnapSync) }
+
+func testHistoryMirrorSync(t *testing.T, protocol uint, mode SyncMode) {
+	tester := newTester(t)
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	tester.newPeer("peer", protocol, chain.blocks[1:])
+
+	// Serve only part of the chain from the mirror, the rest needs the peer
+	archive := testChainBase.shorten(800 / 2)
+	server, downloads := newTestMirror(t, newTestBlockchain(archive.blocks[1:]), 128, false)
+	tester.downloader.SetHistoryMirrors([]string{server.URL})
+
+	if err := tester.sync("peer", nil, mode); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, len(chain.blocks))
+	if atomic.LoadInt32(downloads) == 0 {
+		t.Fatalf("no era1 files retrieved from the mirror")
+	}
+}
+
+// Tests that a mirror serving files not matching their checksums is not used,
+// but the sync still succeeds with the remote peers.
+func TestHistoryMirrorCorrupt(t *testing.T) {
+	tester := newTester(t)
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheMaxItems - 15)
+	tester.newPeer("peer", eth.ETH67, chain.blocks[1:])
+
+	server, _ := newTestMirror(t, newTestBlockchain(chain.blocks[1:]), 128, true)
+	tester.downloader.SetHistoryMirrors([]string{server.URL})
+
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, len(chain.blocks))
+
+	if file := tester.downloader.mirrors[0].files[0]; !file.broken {
+		t.Errorf("file %s not marked broken", file.name)
+	}
+}
+
+// Tests that a mirror only serves the blocks matching the scheduled headers.
+func TestHistoryMirrorMismatch(t *testing.T) {
+	chain := testChainBase.shorten(800 / 2)
+	server, _ := newTestMirror(t, newTestBlockchain(chain.blocks[1:]), 128, false)
+
+	mirror := newHistoryMirror(server.URL)
+	defer mirror.close()
+
+	// Request a range of the archived chain, with a forked header in between
+	var headers []*types.Header
+	for _, block := range chain.blocks[100:200] {
+		headers = append(headers, block.Header())
+	}
+	headers[50].Extra = []byte("fork")
+
+	blocks, receipts := mirror.retrieve(headers)
+	if len(blocks) != 50 || len(receipts) != 50 {
+		t.Fatalf("served blocks mismatch: have %d blocks, %d receipts, want 50", len(blocks), len(receipts))
+	}
+	for i, block := range blocks {
+		if block.Hash() != headers[i].Hash() {
+			t.Errorf("block %d: hash mismatch: have %x, want %x", i, block.Hash(), headers[i].Hash())
+		}
+	}
+	// Request a range crossing the end of the archive
+	headers = headers[:0]
+	for _, block := range testChainBase.blocks[350:450] {
+		headers = append(headers, block.Header())
+	}
+	if blocks, _ := mirror.retrieve(headers); len(blocks) != 50 {
+		t.Fatalf("served blocks mismatch: have %d, want 50", len(blocks))
+	}
+}
+
+// Tests that a stalled mirror download times out and doesn't block the mirror
+// while it's running.
+func TestHistoryMirrorStalled(t *testing.T) {
+	chain := testChainBase.shorten(800 / 2)
+	server, _ := newTestMirror(t, newTestBlockchain(chain.blocks[1:]), 128, false)
+
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".era1") {
+			<-r.Context().Done()
+			return
+		}
+		http.Redirect(w, r, server.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer stalled.Close()
+
+	mirror := newHistoryMirror(stalled.URL)
+	mirror.client.Timeout = 500 * time.Millisecond
+
+	done := make(chan []*types.Block)
+	go func() {
+		blocks, _ := mirror.retrieve([]*types.Header{chain.blocks[1].Header()})
+		done <- blocks
+	}()
+	// Wait for the download to start and check that the mirror isn't locked
+	for {
+		mirror.lock.Lock()
+		fetching := len(mirror.fetching)
+		mirror.lock.Unlock()
+		if fetching > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case blocks := <-done:
+		t.Fatalf("retrieval finished early with %d blocks", len(blocks))
+	default:
+	}
+	select {
+	case blocks := <-done:
+		if len(blocks) != 0 {
+			t.Fatalf("stalled mirror served %d blocks", len(blocks))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stalled download didn't time out")
+	}
+	mirror.close()
+}
@@ -26,10 +26,14 @@ const (
 	FullSync  SyncMode = iota // Synchronise the entire blockchain history from full blocks
 	SnapSync                  // Download the chain and the state via compact snapshots
 	LightSync                 // Download only the headers and terminate afterwards
+
+	// LightBeaconSync follows the chain through a beacon chain light client
+	// instead of the downloader, see the beacon/blsync package.
+	LightBeaconSync
 )
 
 func (mode SyncMode) IsValid() bool {
-	return mode >= FullSync && mode <= LightSync
+	return mode >= FullSync && mode <= LightBeaconSync
 }
 
 // String implements the stringer interface.
@@ -41,6 +45,8 @@ func (mode SyncMode) String() string {
 		return "snap"
 	case LightSync:
 		return "light"
+	case LightBeaconSync:
+		return "light-beacon"
 	default:
 		return "unknown"
 	}
@@ -54,6 +60,8 @@ func (mode SyncMode) MarshalText() ([]byte, error) {
 		return []byte("snap"), nil
 	case LightSync:
 		return []byte("light"), nil
+	case LightBeaconSync:
+		return []byte("light-beacon"), nil
 	default:
 		return nil, fmt.Errorf("unknown sync mode %d", mode)
 	}
@@ -67,8 +75,10 @@ func (mode *SyncMode) UnmarshalText(text []byte) error {
 		*mode = SnapSync
 	case "light":
 		*mode = LightSync
+	case "light-beacon":
+		*mode = LightBeaconSync
 	default:
-		return fmt.Errorf(`unknown sync mode %q, want "full", "snap" or "light"`, text)
+		return fmt.Errorf(`unknown sync mode %q, want "full", "snap", "light" or "light-beacon"`, text)
 	}
 	return nil
 }
@@ -0,0 +1,227 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// reverseSyncRetry is the time to wait for new peers if none of the connected
+// ones could deliver the next batch of headers.
+const reverseSyncRetry = time.Second
+
+var errReverseSyncBusy = errors.New("reverse header sync already running")
+
+// HeaderBatch is a batch of contiguous headers retrieved by the reverse header
+// sync, newest first. The batch carries the proof of its continuity with the
+// previously retrieved chain: the hash of the first header is the anchor, which
+// is the parent hash of the last header of the previous batch, or the trusted
+// hash the sync started at.
+type HeaderBatch struct {
+	Anchor  common.Hash
+	Headers []*types.Header
+}
+
+// Verify checks that the batch is a non-empty chain of headers linked to the
+// anchor, with consecutive numbers.
+func (b *HeaderBatch) Verify() error {
+	if len(b.Headers) == 0 {
+		return errors.New("empty header batch")
+	}
+	if hash := b.Headers[0].Hash(); hash != b.Anchor {
+		return fmt.Errorf("header batch not anchored: have %x, want %x", hash, b.Anchor)
+	}
+	for i := 1; i < len(b.Headers); i++ {
+		parent, child := b.Headers[i], b.Headers[i-1]
+		if child.ParentHash != parent.Hash() {
+			return fmt.Errorf("broken header chain at #%d", child.Number)
+		}
+		if parent.Number.Uint64()+1 != child.Number.Uint64() {
+			return fmt.Errorf("non-consecutive header numbers #%d and #%d", parent.Number, child.Number)
+		}
+	}
+	return nil
+}
+
+// ReverseSyncConfig configures a reverse header sync.
+type ReverseSyncConfig struct {
+	Target common.Hash    // Trusted hash of the newest header to retrieve
+	Stop   uint64         // Number of the oldest header to retrieve (0 = genesis)
+	DB     ethdb.Database // Database to persist the headers in, the sync ends at headers present in it
+
+	// Deliver is called with every verified batch of headers after it was
+	// persisted. Returning an error aborts the sync.
+	Deliver func(batch *HeaderBatch) error
+}
+
+// ReverseSync retrieves and persists the header chain backwards from the header
+// with the trusted target hash. Since the chain is anchored at a trusted hash,
+// every retrieved header is authenticated by the hash chain alone, without any
+// consensus validation. This allows embedders to bootstrap a header chain from
+// a checkpoint provided out of band, e.g. by a consensus client.
+//
+// The headers are written to the configured database, without touching the
+// canonical chain markers. The sync ends when the stop number or genesis is
+// reached, or when the parent of a retrieved header is already present in the
+// database. It runs independently of the forward sync and returns the oldest
+// retrieved header.
+func (d *Downloader) ReverseSync(ctx context.Context, config ReverseSyncConfig) (*types.Header, error) {
+	if !atomic.CompareAndSwapInt32(&d.reverseSyncing, 0, 1) {
+		return nil, errReverseSyncBusy
+	}
+	defer atomic.StoreInt32(&d.reverseSyncing, 0)
+
+	var (
+		anchor = config.Target
+		number *uint64 // Number of the anchor, unknown until the first batch
+		oldest *types.Header
+		failed = make(map[string]bool) // Peers failing to deliver the current anchor
+		logged = time.Now()
+		pulled int
+	)
+	for {
+		// Figure out the size of the next batch
+		count := MaxHeaderFetch
+		if number != nil && *number-config.Stop+1 < uint64(count) {
+			count = int(*number - config.Stop + 1)
+		}
+		// Retrieve the batch from the first peer which didn't fail on it yet
+		var batch *HeaderBatch
+		for _, peer := range d.peers.AllPeers() {
+			if failed[peer.id] {
+				continue
+			}
+			headers, err := d.fetchReverseHeaders(ctx, peer, anchor, count)
+			if err != nil {
+				if ctx.Err() != nil {
+					return oldest, ctx.Err()
+				}
+				peer.log.Debug("Failed to retrieve reverse headers", "anchor", anchor, "err", err)
+				failed[peer.id] = true
+				continue
+			}
+			batch = &HeaderBatch{Anchor: anchor, Headers: headers}
+			if err := batch.Verify(); err != nil {
+				// The anchor is trusted, invalid chains are junk sent by the peer
+				peer.log.Warn("Invalid reverse headers, dropping peer", "anchor", anchor, "err", err)
+				failed[peer.id] = true
+				if d.dropPeer != nil {
+					d.dropPeer(peer.id)
+				}
+				batch = nil
+				continue
+			}
+			break
+		}
+		if batch == nil {
+			// No peer could deliver, wait for new ones and retry with all of them
+			failed = make(map[string]bool)
+			select {
+			case <-time.After(reverseSyncRetry):
+				continue
+			case <-ctx.Done():
+				return oldest, ctx.Err()
+			}
+		}
+		failed = make(map[string]bool)
+
+		// Drop any headers beyond the stop number or a known header
+		for i, header := range batch.Headers {
+			num := header.Number.Uint64()
+			if num <= config.Stop || num == 0 || rawdb.HasHeader(config.DB, header.ParentHash, num-1) {
+				batch.Headers = batch.Headers[:i+1]
+				break
+			}
+		}
+		dbBatch := config.DB.NewBatch()
+		for _, header := range batch.Headers {
+			rawdb.WriteHeader(dbBatch, header)
+		}
+		if err := dbBatch.Write(); err != nil {
+			return oldest, err
+		}
+		if config.Deliver != nil {
+			if err := config.Deliver(batch); err != nil {
+				return oldest, err
+			}
+		}
+		oldest = batch.Headers[len(batch.Headers)-1]
+		pulled += len(batch.Headers)
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Syncing headers backwards", "downloaded", pulled, "number", oldest.Number)
+			logged = time.Now()
+		}
+		// Terminate at the stop number, genesis or a known header
+		num := oldest.Number.Uint64()
+		if num <= config.Stop || num == 0 || rawdb.HasHeader(config.DB, oldest.ParentHash, num-1) {
+			log.Debug("Reverse header sync finished", "downloaded", pulled, "oldest", num)
+			return oldest, nil
+		}
+		anchor, num = oldest.ParentHash, num-1
+		number = &num
+	}
+}
+
+// fetchReverseHeaders requests a batch of headers from the peer, going backwards
+// from the header with the given hash.
+func (d *Downloader) fetchReverseHeaders(ctx context.Context, peer *peerConnection, hash common.Hash, count int) ([]*types.Header, error) {
+	start := time.Now()
+	resCh := make(chan *eth.Response)
+
+	req, err := peer.peer.RequestHeadersByHash(hash, count, 0, true, resCh)
+	if err != nil {
+		return nil, err
+	}
+	defer req.Close()
+
+	ttl := d.peers.rates.TargetTimeout()
+	timeoutTimer := time.NewTimer(ttl)
+	defer timeoutTimer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case <-timeoutTimer.C:
+		headerTimeoutMeter.Mark(1)
+		d.peers.rates.Update(peer.id, eth.BlockHeadersMsg, 0, 0)
+		return nil, errTimeout
+
+	case res := <-resCh:
+		headers := *res.Res.(*eth.BlockHeadersPacket)
+
+		headerReqTimer.Update(time.Since(start))
+		d.peers.rates.Update(peer.id, eth.BlockHeadersMsg, res.Time, len(headers))
+
+		res.Done <- nil
+		if len(headers) == 0 {
+			return nil, errors.New("no headers delivered")
+		}
+		return headers, nil
+	}
+}
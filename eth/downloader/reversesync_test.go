@@ -0,0 +1,144 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+)
+
+// Tests that a header chain can be retrieved backwards from a trusted hash
+// until genesis, in verified batches.
+func TestReverseSync(t *testing.T) {
+	tester := newTester(t)
+	defer tester.terminate()
+
+	chain := testChainBase
+	tester.newPeer("peer", eth.ETH66, chain.blocks[1:])
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		target  = chain.blocks[len(chain.blocks)-1]
+		batches int
+		next    = target.Hash()
+	)
+	oldest, err := tester.downloader.ReverseSync(context.Background(), ReverseSyncConfig{
+		Target: target.Hash(),
+		DB:     db,
+		Deliver: func(batch *HeaderBatch) error {
+			if batch.Anchor != next {
+				t.Errorf("batch %d: wrong anchor: have %x, want %x", batches, batch.Anchor, next)
+			}
+			if err := batch.Verify(); err != nil {
+				t.Errorf("batch %d: %v", batches, err)
+			}
+			next = batch.Headers[len(batch.Headers)-1].ParentHash
+			batches++
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldest.Number.Uint64() != 0 {
+		t.Fatalf("sync ended at #%d, want genesis", oldest.Number)
+	}
+	if want := (len(chain.blocks) + MaxHeaderFetch - 1) / MaxHeaderFetch; batches != want {
+		t.Errorf("wrong number of batches: have %d, want %d", batches, want)
+	}
+	for _, block := range chain.blocks {
+		if !rawdb.HasHeader(db, block.Hash(), block.NumberU64()) {
+			t.Fatalf("header #%d missing", block.NumberU64())
+		}
+	}
+	if rawdb.ReadCanonicalHash(db, target.NumberU64()) != (common.Hash{}) {
+		t.Error("canonical chain markers written")
+	}
+}
+
+// Tests that the reverse sync ends at the stop number or at headers already
+// present in the database.
+func TestReverseSyncStop(t *testing.T) {
+	tester := newTester(t)
+	defer tester.terminate()
+
+	chain := testChainBase
+	tester.newPeer("peer", eth.ETH66, chain.blocks[1:])
+	target := chain.blocks[len(chain.blocks)-1].Hash()
+
+	oldest, err := tester.downloader.ReverseSync(context.Background(), ReverseSyncConfig{Target: target, Stop: 250, DB: rawdb.NewMemoryDatabase()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldest.Number.Uint64() != 250 {
+		t.Errorf("sync ended at #%d, want #250", oldest.Number)
+	}
+	db := rawdb.NewMemoryDatabase()
+	rawdb.WriteHeader(db, chain.blocks[100].Header())
+	oldest, err = tester.downloader.ReverseSync(context.Background(), ReverseSyncConfig{Target: target, DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldest.Number.Uint64() != 101 {
+		t.Errorf("sync ended at #%d, want #101", oldest.Number)
+	}
+}
+
+// Tests that peers delivering broken header chains are dropped and the sync
+// continues with other peers.
+func TestReverseSyncBadPeer(t *testing.T) {
+	tester := newTester(t)
+	defer tester.terminate()
+
+	chain := testChainBase
+	bad := tester.newPeer("bad", eth.ETH66, chain.blocks[1:])
+	bad.withholdHeaders[chain.blocks[200].Hash()] = struct{}{}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tester.downloader.ReverseSync(context.Background(), ReverseSyncConfig{
+			Target: chain.blocks[len(chain.blocks)-1].Hash(),
+			DB:     rawdb.NewMemoryDatabase(),
+		})
+		done <- err
+	}()
+	for {
+		tester.lock.RLock()
+		_, ok := tester.peers["bad"]
+		tester.lock.RUnlock()
+		if !ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	tester.newPeer("good", eth.ETH66, chain.blocks[1:])
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// The sync is cancelled with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tester.dropPeer("good")
+	if _, err := tester.downloader.ReverseSync(ctx, ReverseSyncConfig{Target: chain.blocks[1].Hash(), DB: rawdb.NewMemoryDatabase()}); err != context.Canceled {
+		t.Fatalf("wrong error: have %v, want %v", err, context.Canceled)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/alerts"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -83,6 +84,7 @@ var Defaults = Config{
 	TrieDirtyCache:          256,
 	TrieTimeout:             60 * time.Minute,
 	SnapshotCache:           102,
+	AnalysisCache:           16,
 	Miner: miner.Config{
 		GasCeil:  30000000,
 		GasPrice: big.NewInt(params.GWei),
@@ -138,11 +140,63 @@ type Config struct {
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
+	// NoTxIndex disables indexing transactions by hash, deleting any existing
+	// index. Transactions can still be looked up through TxLookupBackfill.
+	NoTxIndex bool `toml:",omitempty"`
+
+	// TxLookupBackfill is the number of blocks below the transaction index which
+	// are searched when a lookup misses, indexing the block containing the found
+	// transaction. Zero disables the search.
+	TxLookupBackfill uint64 `toml:",omitempty"`
+
+	// AddressLogIndex enables indexing blocks by the addresses emitting logs in
+	// them, speeding up log filters which only match on addresses.
+	AddressLogIndex bool `toml:",omitempty"`
+
+	// InternalTxIndex enables indexing the value transfers of nested calls by the
+	// addresses of their senders and recipients, traced while importing blocks.
+	InternalTxIndex bool `toml:",omitempty"`
+
+	// NoBloomIndex disables the bloom bits index, serving log filters by scanning
+	// the receipts of the blocks instead. It saves disk space and import overhead
+	// on low-volume private chains, best combined with AddressLogIndex.
+	NoBloomIndex bool `toml:",omitempty"`
+
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
 	// presence of these blocks for every new peer connection.
 	RequiredBlocks map[uint64]common.Hash `toml:"-"`
 
+	// HistoryServeBudget is the number of bytes per second that may be spent on
+	// serving historical blocks and receipts which are widely available from other
+	// peers. Rare history is always served. Zero means unlimited.
+	HistoryServeBudget uint64 `toml:",omitempty"`
+
+	// HistoryMirrors are HTTP(S) archives of era1 files, as written by the
+	// export-history command, to retrieve block bodies and receipts from during
+	// sync besides the remote peers.
+	HistoryMirrors []string `toml:",omitempty"`
+
+	// TxAuditLimit is the number of recent transactions to keep an audit trail of
+	// their propagation through the node for. Zero disables auditing.
+	TxAuditLimit int `toml:",omitempty"`
+
+	// ChangeLogDir is the directory to export every canonical block into, feeding
+	// read replicas of this node.
+	ChangeLogDir string `toml:",omitempty"`
+
+	// ChangeLogKeyFile is the file holding the hex encoded key sealing change-log
+	// segments, shared by a primary and its replicas. Empty for plain text.
+	ChangeLogKeyFile string `toml:",omitempty"`
+
+	// ReplicaSource is the directory, gRPC or HTTP(S) URL of a primary node's change-log.
+	// If set, the node imports blocks from the change-log instead of the network.
+	ReplicaSource string `toml:",omitempty"`
+
+	// Standby runs the replica as hot standby of the primary, sharing its node key
+	// and etherbase. Networking and mining are held back until it's promoted.
+	Standby bool `toml:",omitempty"`
+
 	// Light client options
 	LightServ          int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightIngress       int  `toml:",omitempty"` // Incoming bandwidth limit for light servers
@@ -167,8 +221,13 @@ type Config struct {
 	TrieCleanCacheJournal   string        `toml:",omitempty"` // Disk journal directory for trie cache to survive node restarts
 	TrieCleanCacheRejournal time.Duration `toml:",omitempty"` // Time interval to regenerate the journal for clean cache
 	TrieDirtyCache          int
+	TrieDirtyMax            int    `toml:",omitempty"` // Size (MB) of dirty trie nodes above which flushing is forced (0 = twice TrieDirtyCache)
+	TrieFlushLimit          int    `toml:",omitempty"` // Maximum size (MB) of dirty trie nodes flushed per block (0 = unlimited)
+	TrieReorgDepth          uint64 `toml:",omitempty"` // Number of recent state tries kept in memory for reorgs
+	TrieParallelism         int    `toml:",omitempty"` // Number of threads to hash and commit large tries with (0 = number of CPUs)
 	TrieTimeout             time.Duration
 	SnapshotCache           int
+	AnalysisCache           int // Memory allowance (MB) for caching code analysis across transactions
 	Preimages               bool
 
 	// Mining options
@@ -183,6 +242,9 @@ type Config struct {
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// Stall detector options
+	Alerts alerts.Config
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
@@ -199,6 +261,15 @@ type Config struct {
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// RPCCacheSize is the number of responses of idempotent RPC calls, like blocks
+	// by hash, receipts and calls on finalized blocks, cached in memory. Zero
+	// disables the cache.
+	RPCCacheSize int `toml:",omitempty"`
+
+	// AllowForceReorg enables debug_forceReorg, replacing the head of a single
+	// signer clique chain with a locally forged branch.
+	AllowForceReorg bool `toml:",omitempty"`
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
@@ -210,6 +281,9 @@ type Config struct {
 
 	// OverrideTerminalTotalDifficultyPassed (TODO: remove after the fork)
 	OverrideTerminalTotalDifficultyPassed *bool `toml:",omitempty"`
+
+	// OverrideEOF activates the EVM object format at the given block, for devnets
+	OverrideEOF *big.Int `toml:",omitempty"`
 }
 
 // CreateConsensusEngine creates a consensus engine for the given chain configuration.
@@ -9,6 +9,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/alerts"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/miner"
@@ -26,7 +27,19 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NoPruning                             bool
 		NoPrefetch                            bool
 		TxLookupLimit                         uint64                 `toml:",omitempty"`
+		NoTxIndex                             bool                   `toml:",omitempty"`
+		TxLookupBackfill                      uint64                 `toml:",omitempty"`
+		AddressLogIndex                       bool                   `toml:",omitempty"`
+		InternalTxIndex                       bool                   `toml:",omitempty"`
+		NoBloomIndex                          bool                   `toml:",omitempty"`
 		RequiredBlocks                        map[uint64]common.Hash `toml:"-"`
+		HistoryServeBudget                    uint64                 `toml:",omitempty"`
+		HistoryMirrors                        []string               `toml:",omitempty"`
+		TxAuditLimit                          int                    `toml:",omitempty"`
+		ChangeLogDir                          string                 `toml:",omitempty"`
+		ChangeLogKeyFile                      string                 `toml:",omitempty"`
+		ReplicaSource                         string                 `toml:",omitempty"`
+		Standby                               bool                   `toml:",omitempty"`
 		LightServ                             int                    `toml:",omitempty"`
 		LightIngress                          int                    `toml:",omitempty"`
 		LightEgress                           int                    `toml:",omitempty"`
@@ -45,22 +58,31 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TrieCleanCacheJournal                 string        `toml:",omitempty"`
 		TrieCleanCacheRejournal               time.Duration `toml:",omitempty"`
 		TrieDirtyCache                        int
+		TrieDirtyMax                          int    `toml:",omitempty"`
+		TrieFlushLimit                        int    `toml:",omitempty"`
+		TrieReorgDepth                        uint64 `toml:",omitempty"`
+		TrieParallelism                       int    `toml:",omitempty"`
 		TrieTimeout                           time.Duration
 		SnapshotCache                         int
+		AnalysisCache                         int
 		Preimages                             bool
 		Miner                                 miner.Config
 		Ethash                                ethash.Config
 		TxPool                                core.TxPoolConfig
 		GPO                                   gasprice.Config
+		Alerts                                alerts.Config
 		EnablePreimageRecording               bool
 		DocRoot                               string `toml:"-"`
 		RPCGasCap                             uint64
 		RPCEVMTimeout                         time.Duration
 		RPCTxFeeCap                           float64
+		RPCCacheSize                          int                            `toml:",omitempty"`
+		AllowForceReorg                       bool                           `toml:",omitempty"`
 		Checkpoint                            *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle                      *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideTerminalTotalDifficulty       *big.Int                       `toml:",omitempty"`
 		OverrideTerminalTotalDifficultyPassed *bool                          `toml:",omitempty"`
+		OverrideEOF                           *big.Int                       `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -71,7 +93,19 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
 	enc.TxLookupLimit = c.TxLookupLimit
+	enc.NoTxIndex = c.NoTxIndex
+	enc.TxLookupBackfill = c.TxLookupBackfill
+	enc.AddressLogIndex = c.AddressLogIndex
+	enc.InternalTxIndex = c.InternalTxIndex
+	enc.NoBloomIndex = c.NoBloomIndex
 	enc.RequiredBlocks = c.RequiredBlocks
+	enc.HistoryServeBudget = c.HistoryServeBudget
+	enc.HistoryMirrors = c.HistoryMirrors
+	enc.TxAuditLimit = c.TxAuditLimit
+	enc.ChangeLogDir = c.ChangeLogDir
+	enc.ChangeLogKeyFile = c.ChangeLogKeyFile
+	enc.ReplicaSource = c.ReplicaSource
+	enc.Standby = c.Standby
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
 	enc.LightEgress = c.LightEgress
@@ -90,22 +124,31 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TrieCleanCacheJournal = c.TrieCleanCacheJournal
 	enc.TrieCleanCacheRejournal = c.TrieCleanCacheRejournal
 	enc.TrieDirtyCache = c.TrieDirtyCache
+	enc.TrieDirtyMax = c.TrieDirtyMax
+	enc.TrieFlushLimit = c.TrieFlushLimit
+	enc.TrieReorgDepth = c.TrieReorgDepth
+	enc.TrieParallelism = c.TrieParallelism
 	enc.TrieTimeout = c.TrieTimeout
 	enc.SnapshotCache = c.SnapshotCache
+	enc.AnalysisCache = c.AnalysisCache
 	enc.Preimages = c.Preimages
 	enc.Miner = c.Miner
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
+	enc.Alerts = c.Alerts
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.RPCCacheSize = c.RPCCacheSize
+	enc.AllowForceReorg = c.AllowForceReorg
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
 	enc.OverrideTerminalTotalDifficulty = c.OverrideTerminalTotalDifficulty
 	enc.OverrideTerminalTotalDifficultyPassed = c.OverrideTerminalTotalDifficultyPassed
+	enc.OverrideEOF = c.OverrideEOF
 	return &enc, nil
 }
 
@@ -120,7 +163,19 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NoPruning                             *bool
 		NoPrefetch                            *bool
 		TxLookupLimit                         *uint64                `toml:",omitempty"`
+		NoTxIndex                             *bool                  `toml:",omitempty"`
+		TxLookupBackfill                      *uint64                `toml:",omitempty"`
+		AddressLogIndex                       *bool                  `toml:",omitempty"`
+		InternalTxIndex                       *bool                  `toml:",omitempty"`
+		NoBloomIndex                          *bool                  `toml:",omitempty"`
 		RequiredBlocks                        map[uint64]common.Hash `toml:"-"`
+		HistoryServeBudget                    *uint64                `toml:",omitempty"`
+		HistoryMirrors                        []string               `toml:",omitempty"`
+		TxAuditLimit                          *int                   `toml:",omitempty"`
+		ChangeLogDir                          *string                `toml:",omitempty"`
+		ChangeLogKeyFile                      *string                `toml:",omitempty"`
+		ReplicaSource                         *string                `toml:",omitempty"`
+		Standby                               *bool                  `toml:",omitempty"`
 		LightServ                             *int                   `toml:",omitempty"`
 		LightIngress                          *int                   `toml:",omitempty"`
 		LightEgress                           *int                   `toml:",omitempty"`
@@ -139,22 +194,31 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TrieCleanCacheJournal                 *string        `toml:",omitempty"`
 		TrieCleanCacheRejournal               *time.Duration `toml:",omitempty"`
 		TrieDirtyCache                        *int
+		TrieDirtyMax                          *int    `toml:",omitempty"`
+		TrieFlushLimit                        *int    `toml:",omitempty"`
+		TrieReorgDepth                        *uint64 `toml:",omitempty"`
+		TrieParallelism                       *int    `toml:",omitempty"`
 		TrieTimeout                           *time.Duration
 		SnapshotCache                         *int
+		AnalysisCache                         *int
 		Preimages                             *bool
 		Miner                                 *miner.Config
 		Ethash                                *ethash.Config
 		TxPool                                *core.TxPoolConfig
 		GPO                                   *gasprice.Config
+		Alerts                                *alerts.Config
 		EnablePreimageRecording               *bool
 		DocRoot                               *string `toml:"-"`
 		RPCGasCap                             *uint64
 		RPCEVMTimeout                         *time.Duration
 		RPCTxFeeCap                           *float64
+		RPCCacheSize                          *int                           `toml:",omitempty"`
+		AllowForceReorg                       *bool                          `toml:",omitempty"`
 		Checkpoint                            *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle                      *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideTerminalTotalDifficulty       *big.Int                       `toml:",omitempty"`
 		OverrideTerminalTotalDifficultyPassed *bool                          `toml:",omitempty"`
+		OverrideEOF                           *big.Int                       `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -184,9 +248,45 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
+	if dec.NoTxIndex != nil {
+		c.NoTxIndex = *dec.NoTxIndex
+	}
+	if dec.TxLookupBackfill != nil {
+		c.TxLookupBackfill = *dec.TxLookupBackfill
+	}
+	if dec.AddressLogIndex != nil {
+		c.AddressLogIndex = *dec.AddressLogIndex
+	}
+	if dec.InternalTxIndex != nil {
+		c.InternalTxIndex = *dec.InternalTxIndex
+	}
+	if dec.NoBloomIndex != nil {
+		c.NoBloomIndex = *dec.NoBloomIndex
+	}
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
+	if dec.HistoryServeBudget != nil {
+		c.HistoryServeBudget = *dec.HistoryServeBudget
+	}
+	if dec.HistoryMirrors != nil {
+		c.HistoryMirrors = dec.HistoryMirrors
+	}
+	if dec.TxAuditLimit != nil {
+		c.TxAuditLimit = *dec.TxAuditLimit
+	}
+	if dec.ChangeLogDir != nil {
+		c.ChangeLogDir = *dec.ChangeLogDir
+	}
+	if dec.ChangeLogKeyFile != nil {
+		c.ChangeLogKeyFile = *dec.ChangeLogKeyFile
+	}
+	if dec.ReplicaSource != nil {
+		c.ReplicaSource = *dec.ReplicaSource
+	}
+	if dec.Standby != nil {
+		c.Standby = *dec.Standby
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -241,12 +341,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieDirtyCache != nil {
 		c.TrieDirtyCache = *dec.TrieDirtyCache
 	}
+	if dec.TrieDirtyMax != nil {
+		c.TrieDirtyMax = *dec.TrieDirtyMax
+	}
+	if dec.TrieFlushLimit != nil {
+		c.TrieFlushLimit = *dec.TrieFlushLimit
+	}
+	if dec.TrieReorgDepth != nil {
+		c.TrieReorgDepth = *dec.TrieReorgDepth
+	}
+	if dec.TrieParallelism != nil {
+		c.TrieParallelism = *dec.TrieParallelism
+	}
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
 	if dec.SnapshotCache != nil {
 		c.SnapshotCache = *dec.SnapshotCache
 	}
+	if dec.AnalysisCache != nil {
+		c.AnalysisCache = *dec.AnalysisCache
+	}
 	if dec.Preimages != nil {
 		c.Preimages = *dec.Preimages
 	}
@@ -262,6 +377,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
+	if dec.Alerts != nil {
+		c.Alerts = *dec.Alerts
+	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
@@ -277,6 +395,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.RPCCacheSize != nil {
+		c.RPCCacheSize = *dec.RPCCacheSize
+	}
+	if dec.AllowForceReorg != nil {
+		c.AllowForceReorg = *dec.AllowForceReorg
+	}
 	if dec.Checkpoint != nil {
 		c.Checkpoint = dec.Checkpoint
 	}
@@ -289,5 +413,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.OverrideTerminalTotalDifficultyPassed != nil {
 		c.OverrideTerminalTotalDifficultyPassed = dec.OverrideTerminalTotalDifficultyPassed
 	}
+	if dec.OverrideEOF != nil {
+		c.OverrideEOF = dec.OverrideEOF
+	}
 	return nil
 }
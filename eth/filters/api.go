@@ -51,6 +51,9 @@ type FilterAPI struct {
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
 	timeout   time.Duration
+
+	bulkMu sync.Mutex
+	bulk   map[rpc.ID]*bulkStream // active bulk log subscriptions
 }
 
 // NewFilterAPI returns a new FilterAPI instance.
@@ -60,6 +63,7 @@ func NewFilterAPI(backend Backend, lightMode bool, timeout time.Duration) *Filte
 		events:  NewEventSystem(backend, lightMode),
 		filters: make(map[rpc.ID]*filter),
 		timeout: timeout,
+		bulk:    make(map[rpc.ID]*bulkStream),
 	}
 	go api.timeoutLoop(timeout)
 
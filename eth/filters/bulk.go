@@ -0,0 +1,507 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/snappy"
+)
+
+const (
+	defaultBulkChunkSize = 1000    // Default number of blocks searched per batch
+	maxBulkChunkSize     = 100000  // Maximum number of blocks searched per batch
+	defaultBulkWindow    = 4       // Default number of unacknowledged batches in flight
+	maxBulkWindow        = 64      // Maximum number of unacknowledged batches in flight
+	maxBulkAddresses     = 100000  // Maximum number of addresses in a bulk filter
+	maxBulkBatchLogs     = 10000   // Maximum number of logs in a single batch
+	maxBulkBatchBytes    = 4 << 20 // Maximum approximate JSON size of the logs in a single batch
+	bulkLogOverhead      = 400     // Approximate JSON size of a log without its data and topics
+	bulkReorgDepth       = 64      // Number of delivered blocks checked for reorgs
+)
+
+var (
+	errBulkAddresses   = fmt.Errorf("too many addresses, at most %d allowed", maxBulkAddresses)
+	errBulkUnknown     = errors.New("unknown bulk log subscription")
+	errBulkFutureAck   = errors.New("acknowledged batch not sent yet")
+	errBulkInvalidFrom = errors.New("invalid fromBlock, must be a number, earliest or latest")
+)
+
+// BulkLogsQuery is the filter of a bulk log subscription. On top of the fields
+// of FilterCriteria it accepts the addresses in compressed form, and controls
+// the batching and flow of the stream.
+type BulkLogsQuery struct {
+	FilterCriteria
+
+	ChunkSize uint64 // Maximum number of blocks searched per batch
+	Window    uint64 // Number of batches sent ahead of the acknowledgements
+}
+
+// UnmarshalJSON sets *q fields with given data.
+func (q *BulkLogsQuery) UnmarshalJSON(data []byte) error {
+	if err := q.FilterCriteria.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	var raw struct {
+		CompressedAddresses hexutil.Bytes   `json:"compressedAddresses"`
+		ChunkSize           *hexutil.Uint64 `json:"chunkSize"`
+		Window              *hexutil.Uint64 `json:"window"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw.CompressedAddresses) > 0 {
+		addrs, err := decompressAddresses(raw.CompressedAddresses)
+		if err != nil {
+			return err
+		}
+		q.Addresses = append(q.Addresses, addrs...)
+	}
+	if len(q.Addresses) > maxBulkAddresses {
+		return errBulkAddresses
+	}
+	q.ChunkSize, q.Window = defaultBulkChunkSize, defaultBulkWindow
+	if raw.ChunkSize != nil {
+		if *raw.ChunkSize == 0 || *raw.ChunkSize > maxBulkChunkSize {
+			return fmt.Errorf("invalid chunkSize, must be between 1 and %d", maxBulkChunkSize)
+		}
+		q.ChunkSize = uint64(*raw.ChunkSize)
+	}
+	if raw.Window != nil {
+		if *raw.Window == 0 || *raw.Window > maxBulkWindow {
+			return fmt.Errorf("invalid window, must be between 1 and %d", maxBulkWindow)
+		}
+		q.Window = uint64(*raw.Window)
+	}
+	return nil
+}
+
+// decompressAddresses decodes a snappy compressed concatenation of addresses.
+func decompressAddresses(data []byte) ([]common.Address, error) {
+	size, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed addresses: %v", err)
+	}
+	if size%common.AddressLength != 0 {
+		return nil, fmt.Errorf("invalid compressed addresses: length %d not a multiple of %d", size, common.AddressLength)
+	}
+	if size/common.AddressLength > maxBulkAddresses {
+		return nil, errBulkAddresses
+	}
+	blob, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compressed addresses: %v", err)
+	}
+	addrs := make([]common.Address, len(blob)/common.AddressLength)
+	for i := range addrs {
+		copy(addrs[i][:], blob[i*common.AddressLength:])
+	}
+	return addrs, nil
+}
+
+// LogsBatch is a notification of a bulk log subscription, holding the matching
+// logs of a range of blocks. Logs of blocks reorged out of the chain are sent
+// again with the removed flag set, in a batch covering the reverted blocks.
+type LogsBatch struct {
+	Seq       hexutil.Uint64 `json:"seq"`       // Sequence number to acknowledge the batch with
+	FromBlock hexutil.Uint64 `json:"fromBlock"` // First block of the range
+	ToBlock   hexutil.Uint64 `json:"toBlock"`   // Last block of the range
+	Logs      []*types.Log   `json:"logs"`
+	Done      bool           `json:"done,omitempty"` // Set on the last batch up to the toBlock of the query
+}
+
+// blockRef identifies a delivered block, for reorg detection.
+type blockRef struct {
+	number uint64
+	hash   common.Hash
+}
+
+// bulkStream delivers the logs of a bulk subscription, searching the chain in
+// chunks from the first undelivered block up to the head. At most a window of
+// batches is sent ahead of the client's acknowledgements, afterwards the search
+// pauses until the client catches up, so slow clients don't pile up logs in
+// memory and simply fall behind the head.
+type bulkStream struct {
+	backend  Backend
+	notifier *rpc.Notifier
+	sub      *rpc.Subscription
+	filter   *Filter // Matcher of reverted logs
+	query    BulkLogsQuery
+
+	next   uint64     // First block not yet searched
+	span   uint64     // Number of blocks searched at once, shrunk below the chunk size on dense ranges
+	end    *uint64    // Last block to search, nil to follow the chain
+	recent []blockRef // Last delivered blocks, for reorg detection
+
+	lock  sync.Mutex
+	sent  uint64        // Sequence number of the last sent batch
+	acked uint64        // Sequence number of the last acknowledged batch
+	ackCh chan struct{} // Signals acknowledgements to the stream
+}
+
+// LogsBulk creates a subscription streaming the logs matching the query in
+// batches, starting with the historical logs from the fromBlock of the query.
+// It's meant for indexers filtering on large sets of addresses: the addresses
+// may be given compressed, and the client controls the flow of the stream by
+// acknowledging the received batches with eth_ackLogsBulk.
+func (api *FilterAPI) LogsBulk(ctx context.Context, query BulkLogsQuery) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if query.BlockHash != nil {
+		return nil, errors.New("blockHash not supported by bulk subscriptions")
+	}
+	if query.ChunkSize == 0 {
+		query.ChunkSize = defaultBulkChunkSize
+	}
+	if query.Window == 0 {
+		query.Window = defaultBulkWindow
+	}
+	header, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if header == nil || err != nil {
+		return nil, errors.New("chain head unavailable")
+	}
+	s := &bulkStream{
+		backend:  api.backend,
+		notifier: notifier,
+		sub:      notifier.CreateSubscription(),
+		filter:   newFilter(api.backend, query.Addresses, query.Topics),
+		query:    query,
+		next:     header.Number.Uint64() + 1,
+		span:     query.ChunkSize,
+		ackCh:    make(chan struct{}, 1),
+	}
+	if from := query.FromBlock; from != nil {
+		switch {
+		case from.Sign() >= 0:
+			s.next = from.Uint64()
+		case from.Int64() == rpc.LatestBlockNumber.Int64():
+			s.next = header.Number.Uint64()
+		default:
+			return nil, errBulkInvalidFrom
+		}
+	}
+	if to := query.ToBlock; to != nil && to.Int64() != rpc.LatestBlockNumber.Int64() {
+		if to.Sign() < 0 {
+			return nil, errors.New("invalid toBlock, must be a number or latest")
+		}
+		if to.Uint64() < s.next {
+			return nil, errors.New("invalid block range")
+		}
+		end := to.Uint64()
+		s.end = &end
+	}
+	api.bulkMu.Lock()
+	api.bulk[s.sub.ID] = s
+	api.bulkMu.Unlock()
+
+	go func() {
+		s.run()
+
+		api.bulkMu.Lock()
+		delete(api.bulk, s.sub.ID)
+		api.bulkMu.Unlock()
+	}()
+	return s.sub, nil
+}
+
+// AckLogsBulk acknowledges the receipt of the batches of a bulk log subscription
+// up to and including the given sequence number.
+func (api *FilterAPI) AckLogsBulk(id rpc.ID, seq hexutil.Uint64) error {
+	api.bulkMu.Lock()
+	s := api.bulk[id]
+	api.bulkMu.Unlock()
+
+	if s == nil {
+		return errBulkUnknown
+	}
+	return s.ack(uint64(seq))
+}
+
+// ack records the acknowledgement of the batches up to seq.
+func (s *bulkStream) ack(seq uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if seq > s.sent {
+		return errBulkFutureAck
+	}
+	if seq > s.acked {
+		s.acked = seq
+		select {
+		case s.ackCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// inflight returns the number of sent batches not acknowledged yet.
+func (s *bulkStream) inflight() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.sent - s.acked
+}
+
+// run streams the logs until the subscription ends or the end of the range is
+// reached.
+func (s *bulkStream) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Track the chain events and the end of the subscription on a separate
+	// goroutine, the chain event feed must not be blocked by a slow search.
+	var (
+		heads   = make(chan core.ChainEvent, chainEvChanSize)
+		headSub = s.backend.SubscribeChainEvent(heads)
+		newHead = make(chan struct{}, 1)
+	)
+	go func() {
+		defer headSub.Unsubscribe()
+		for {
+			select {
+			case <-heads:
+				select {
+				case newHead <- struct{}{}:
+				default:
+				}
+			case <-s.sub.Err(): // client send an unsubscribe request
+				cancel()
+				return
+			case <-s.notifier.Closed(): // connection dropped
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for {
+		header, err := s.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil || header == nil {
+			return
+		}
+		head := header.Number.Uint64()
+		if err := s.rewind(ctx); err != nil {
+			log.Debug("Bulk log subscription failed", "id", s.sub.ID, "err", err)
+			return
+		}
+		if s.next <= head {
+			done, err := s.search(ctx, head)
+			if err != nil {
+				log.Debug("Bulk log subscription failed", "id", s.sub.ID, "err", err)
+				return
+			}
+			if done {
+				return
+			}
+			continue
+		}
+		// Caught up with the chain, wait for the next block
+		select {
+		case <-newHead:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// search delivers the logs of the next chunk of blocks up to the head, returning
+// whether the end of the requested range has been reached.
+//
+// All logs of a search are held in memory, so the number of blocks searched at
+// once is halved whenever the logs found exceed the size of a batch, and grown
+// back towards the chunk size when they are well below it.
+func (s *bulkStream) search(ctx context.Context, head uint64) (bool, error) {
+	from, to := s.next, s.next+s.span-1
+	if to > head {
+		to = head
+	}
+	if s.end != nil && to > *s.end {
+		to = *s.end
+	}
+	filter := NewRangeFilter(s.backend, int64(from), int64(to), s.query.Addresses, s.query.Topics)
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return false, err
+	}
+	var size int
+	for _, l := range logs {
+		size += logSize(l)
+	}
+	switch {
+	case size > maxBulkBatchBytes && s.span > 1:
+		s.span /= 2
+	case size < maxBulkBatchBytes/4 && s.span < s.query.ChunkSize:
+		s.span *= 2
+		if s.span > s.query.ChunkSize {
+			s.span = s.query.ChunkSize
+		}
+	}
+	done := s.end != nil && to == *s.end
+	if err := s.send(ctx, from, to, logs, done); err != nil {
+		return false, err
+	}
+	s.next = to + 1
+
+	// Remember the hashes of the last blocks for reorg detection
+	start := from
+	if to-from >= bulkReorgDepth {
+		start = to - bulkReorgDepth + 1
+	}
+	for n := start; n <= to; n++ {
+		header, err := s.backend.HeaderByNumber(ctx, rpc.BlockNumber(n))
+		if err != nil || header == nil {
+			return false, errors.New("delivered block unavailable")
+		}
+		s.recent = append(s.recent, blockRef{n, header.Hash()})
+	}
+	if len(s.recent) > bulkReorgDepth {
+		s.recent = append(s.recent[:0], s.recent[len(s.recent)-bulkReorgDepth:]...)
+	}
+	return done, nil
+}
+
+// rewind checks whether the last delivered blocks are still canonical, sending
+// the logs of the reorged ones again, marked as removed, and moving the search
+// back to the first reorged block. Reorgs deeper than the tracked blocks are
+// only rewound up to the oldest of them.
+func (s *bulkStream) rewind(ctx context.Context) error {
+	i := len(s.recent) - 1
+	for ; i >= 0; i-- {
+		header, err := s.backend.HeaderByNumber(ctx, rpc.BlockNumber(s.recent[i].number))
+		if err != nil {
+			return err
+		}
+		if header != nil && header.Hash() == s.recent[i].hash {
+			break
+		}
+	}
+	reverted := s.recent[i+1:]
+	if len(reverted) == 0 {
+		return nil
+	}
+	var removed []*types.Log
+	for _, ref := range reverted {
+		logsList, err := s.backend.GetLogs(ctx, ref.hash)
+		if err != nil {
+			return err
+		}
+		var unfiltered []*types.Log
+		for _, logs := range logsList {
+			unfiltered = append(unfiltered, logs...)
+		}
+		for _, l := range s.filter.filterLogs(unfiltered) {
+			cpy := *l
+			cpy.Removed = true
+			removed = append(removed, &cpy)
+		}
+	}
+	from, to := reverted[0].number, reverted[len(reverted)-1].number
+	s.recent = s.recent[:i+1]
+	s.next = from
+	if len(removed) == 0 {
+		return nil
+	}
+	return s.send(ctx, from, to, removed, false)
+}
+
+// logSize approximates the JSON encoded size of a log.
+func logSize(l *types.Log) int {
+	return bulkLogOverhead + 2*(len(l.Data)+len(l.Topics)*common.HashLength)
+}
+
+// batchFit returns the number of leading logs fitting into a single batch, which
+// is at least one.
+func batchFit(logs []*types.Log) int {
+	var size int
+	for i, l := range logs {
+		if size += logSize(l); i == maxBulkBatchLogs || (i > 0 && size > maxBulkBatchBytes) {
+			return i
+		}
+	}
+	return len(logs)
+}
+
+// send delivers a range of logs to the client, split into batches of limited
+// size. Before each batch it waits for the client to acknowledge enough earlier
+// batches to stay within the window.
+func (s *bulkStream) send(ctx context.Context, from, to uint64, logs []*types.Log, done bool) error {
+	for {
+		for s.inflight() >= s.query.Window {
+			select {
+			case <-s.ackCh:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		batch := &LogsBatch{
+			FromBlock: hexutil.Uint64(from),
+			ToBlock:   hexutil.Uint64(to),
+			Logs:      logs,
+			Done:      done,
+		}
+		if fit := batchFit(logs); fit < len(logs) {
+			// Split the range at the block of the last log fitting in the batch,
+			// keeping the logs of a block together unless it alone is too big
+			cut := fit
+			for cut > 0 && logs[cut].BlockNumber == logs[cut-1].BlockNumber {
+				cut--
+			}
+			if cut == 0 {
+				cut = fit
+			}
+			batch.Logs, batch.Done = logs[:cut], false
+			if logs[cut].BlockNumber > logs[cut-1].BlockNumber {
+				batch.ToBlock = hexutil.Uint64(logs[cut].BlockNumber - 1)
+			} else {
+				batch.ToBlock = hexutil.Uint64(logs[cut].BlockNumber)
+			}
+		}
+		if batch.Logs == nil {
+			batch.Logs = []*types.Log{}
+		}
+		s.lock.Lock()
+		s.sent++
+		batch.Seq = hexutil.Uint64(s.sent)
+		s.lock.Unlock()
+
+		if err := s.notifier.Notify(s.sub.ID, batch); err != nil {
+			return err
+		}
+		if len(batch.Logs) == len(logs) {
+			return nil
+		}
+		logs = logs[len(batch.Logs):]
+		from = uint64(batch.ToBlock) + 1
+		if logs[0].BlockNumber < from {
+			from = logs[0].BlockNumber
+		}
+	}
+}
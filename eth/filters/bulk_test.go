@@ -0,0 +1,222 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/snappy"
+)
+
+// Tests that bulk log subscriptions stream the historical and new logs in
+// chunks, wait for acknowledgements and revert the logs of reorged blocks.
+func TestLogsBulk(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewFilterAPI(backend, false, deadline)
+		addr    = common.BytesToAddress([]byte("jeff"))
+		other   = common.BytesToAddress([]byte("other"))
+		topic   = common.BytesToHash([]byte("topic"))
+		gspec   = core.Genesis{BaseFee: big.NewInt(params.InitialBaseFee)}
+		genesis = gspec.MustCommit(db)
+	)
+	addLog := func(gen *core.BlockGen, addr common.Address) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr, Topics: []common.Hash{topic}}}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(gen.Number().Int64()), common.Address{}, big.NewInt(0), 0, gen.BaseFee(), nil))
+	}
+	insert := func(blocks []*types.Block, receipts []types.Receipts) {
+		for i, block := range blocks {
+			rawdb.WriteBlock(db, block)
+			rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+			rawdb.WriteHeadBlockHash(db, block.Hash())
+			rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		}
+		backend.chainFeed.Send(core.ChainEvent{Block: blocks[len(blocks)-1], Hash: blocks[len(blocks)-1].Hash()})
+	}
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 20, func(i int, gen *core.BlockGen) {
+		switch i + 1 {
+		case 2, 5, 12, 15:
+			addLog(gen, addr)
+		case 17:
+			addLog(gen, other)
+		}
+	})
+	insert(chain, receipts)
+
+	// Filter on a large compressed address set, which doesn't contain other
+	var blob []byte
+	for i := 0; i < 100; i++ {
+		blob = append(blob, common.BigToAddress(big.NewInt(int64(i))).Bytes()...)
+	}
+	blob = append(blob, addr.Bytes()...)
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	batches := make(chan *LogsBatch, 10)
+	sub, err := client.Subscribe(context.Background(), "eth", batches, "logsBulk", map[string]interface{}{
+		"fromBlock":           "0x0",
+		"compressedAddresses": hexutil.Bytes(snappy.Encode(nil, blob)),
+		"topics":              []interface{}{nil},
+		"chunkSize":           "0x5",
+		"window":              "0x1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	type result struct {
+		from, to uint64
+		logs     []uint64
+		removed  bool
+	}
+	next := func() result {
+		t.Helper()
+		select {
+		case batch := <-batches:
+			res := result{from: uint64(batch.FromBlock), to: uint64(batch.ToBlock)}
+			for _, log := range batch.Logs {
+				res.logs = append(res.logs, log.BlockNumber)
+				res.removed = log.Removed
+			}
+			if err := client.Call(nil, "eth_ackLogsBulk", sub.ID(), batch.Seq); err != nil {
+				t.Fatalf("failed to acknowledge batch %d: %v", batch.Seq, err)
+			}
+			return res
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for batch")
+		}
+		return result{}
+	}
+	// The stream waits for the first batch to be acknowledged
+	time.Sleep(100 * time.Millisecond)
+	if len(batches) != 1 {
+		t.Fatalf("have %d batches before acknowledgement, want 1", len(batches))
+	}
+	want := []result{
+		{0, 4, []uint64{2}, false},
+		{5, 9, []uint64{5}, false},
+		{10, 14, []uint64{12}, false},
+		{15, 19, []uint64{15}, false},
+		{20, 20, nil, false},
+	}
+	for i, w := range want {
+		if have := next(); !reflect.DeepEqual(have, w) {
+			t.Fatalf("batch %d mismatch: have %+v, want %+v", i, have, w)
+		}
+	}
+	// New blocks are streamed once they arrive
+	head, headReceipts := core.GenerateChain(params.TestChainConfig, chain[len(chain)-1], ethash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) {
+		addLog(gen, addr)
+	})
+	insert(head, headReceipts)
+	if have, w := next(), (result{21, 21, []uint64{21}, false}); !reflect.DeepEqual(have, w) {
+		t.Fatalf("new block batch mismatch: have %+v, want %+v", have, w)
+	}
+	// Logs of reorged blocks are reverted and the new chain streamed
+	fork, forkReceipts := core.GenerateChain(params.TestChainConfig, chain[len(chain)-1], ethash.NewFaker(), db, 2, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(other)
+		if i == 1 {
+			addLog(gen, addr)
+		}
+	})
+	insert(fork, forkReceipts)
+	if have, w := next(), (result{21, 21, []uint64{21}, true}); !reflect.DeepEqual(have, w) {
+		t.Fatalf("reorg batch mismatch: have %+v, want %+v", have, w)
+	}
+	if have, w := next(), (result{21, 22, []uint64{22}, false}); !reflect.DeepEqual(have, w) {
+		t.Fatalf("fork batch mismatch: have %+v, want %+v", have, w)
+	}
+}
+
+func TestBulkLogsQuery(t *testing.T) {
+	var blob []byte
+	for i := 0; i < 3; i++ {
+		blob = append(blob, common.BigToAddress(big.NewInt(int64(i))).Bytes()...)
+	}
+	var q BulkLogsQuery
+	input := `{"address": "0x0000000000000000000000000000000000000009", "compressedAddresses": "` + hexutil.Encode(snappy.Encode(nil, blob)) + `"}`
+	if err := q.UnmarshalJSON([]byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Addresses) != 4 || q.Addresses[3] != common.BigToAddress(big.NewInt(2)) {
+		t.Errorf("addresses mismatch: %v", q.Addresses)
+	}
+	if q.ChunkSize != defaultBulkChunkSize || q.Window != defaultBulkWindow {
+		t.Errorf("defaults not applied: chunk size %d, window %d", q.ChunkSize, q.Window)
+	}
+	invalid := []string{
+		`{"compressedAddresses": "` + hexutil.Encode(snappy.Encode(nil, blob[:30])) + `"}`,
+		`{"compressedAddresses": "0x1234"}`,
+		`{"chunkSize": "0x0"}`,
+		`{"window": "0x1000"}`,
+	}
+	for i, input := range invalid {
+		if err := new(BulkLogsQuery).UnmarshalJSON([]byte(input)); err == nil {
+			t.Errorf("input %d: no error for invalid query", i)
+		}
+	}
+}
+
+func TestBulkBatchFit(t *testing.T) {
+	logs := func(n int, data int) []*types.Log {
+		logs := make([]*types.Log, n)
+		for i := range logs {
+			logs[i] = &types.Log{Data: make([]byte, data)}
+		}
+		return logs
+	}
+	tests := []struct {
+		logs []*types.Log
+		want int
+	}{
+		{logs(10, 0), 10},
+		{logs(maxBulkBatchLogs+1, 0), maxBulkBatchLogs},
+		{logs(4, maxBulkBatchBytes/6), 2}, // Capped by size
+		{logs(2, 2*maxBulkBatchBytes), 1}, // Oversized logs are sent alone
+		{append(logs(1, 0), logs(1, 4*maxBulkBatchBytes)...), 1},
+	}
+	for i, tt := range tests {
+		if have := batchFit(tt.logs); have != tt.want {
+			t.Errorf("test %d: fitting logs mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
@@ -20,16 +20,22 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// addressSetThreshold is the number of addresses above which filters look up log
+// addresses in a set instead of scanning the address list.
+const addressSetThreshold = 16
+
 type Backend interface {
 	ChainDb() ethdb.Database
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
@@ -52,9 +58,10 @@ type Backend interface {
 type Filter struct {
 	backend Backend
 
-	db        ethdb.Database
-	addresses []common.Address
-	topics    [][]common.Hash
+	db         ethdb.Database
+	addresses  []common.Address
+	addressSet map[common.Address]struct{} // Lookup set of the addresses, for filters on many
+	topics     [][]common.Hash
 
 	block      common.Hash // Block hash if filtering a single block
 	begin, end int64       // Range interval if filtering multiple blocks
@@ -107,12 +114,19 @@ func NewBlockFilter(backend Backend, block common.Hash, addresses []common.Addre
 // newFilter creates a generic filter that can either filter based on a block hash,
 // or based on range queries. The search criteria needs to be explicitly set.
 func newFilter(backend Backend, addresses []common.Address, topics [][]common.Hash) *Filter {
-	return &Filter{
+	f := &Filter{
 		backend:   backend,
 		addresses: addresses,
 		topics:    topics,
 		db:        backend.ChainDb(),
 	}
+	if len(addresses) > addressSetThreshold {
+		f.addressSet = make(map[common.Address]struct{}, len(addresses))
+		for _, addr := range addresses {
+			f.addressSet[addr] = struct{}{}
+		}
+	}
+	return f
 }
 
 // Logs searches the blockchain for matching log entries, returning all from the
@@ -152,7 +166,40 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == rpc.LatestBlockNumber.Int64() || f.end == rpc.PendingBlockNumber.Int64() {
 		end = head
 	}
-	// Gather all indexed logs, and finish with non indexed ones
+	// Serve address-only filters from the address log index where available,
+	// falling back to the bloom filters for blocks preceding it.
+	var (
+		logs []*types.Log
+		err  error
+	)
+	if tail := f.addressIndexTail(); tail != nil && *tail <= end {
+		if uint64(f.begin) < *tail {
+			if logs, err = f.bloomLogs(ctx, *tail-1); err != nil {
+				return logs, err
+			}
+		}
+		var rest []*types.Log
+		rest, err = f.addressLogs(ctx, end)
+		logs = append(logs, rest...)
+	} else {
+		logs, err = f.bloomLogs(ctx, end)
+	}
+	if err != nil {
+		return logs, err
+	}
+	if pending {
+		pendingLogs, err := f.pendingLogs()
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, pendingLogs...)
+	}
+	return logs, err
+}
+
+// bloomLogs returns the logs matching the filter criteria up to the given block,
+// gathering the bloom bits indexed logs first and finishing with non indexed ones.
+func (f *Filter) bloomLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
 	var (
 		logs           []*types.Log
 		err            error
@@ -169,15 +216,58 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		}
 	}
 	rest, err := f.unindexedLogs(ctx, end)
-	logs = append(logs, rest...)
-	if pending {
-		pendingLogs, err := f.pendingLogs()
+	return append(logs, rest...), err
+}
+
+// addressIndexTail returns the first block covered by the address log index if
+// it can serve the filter. This is the case if it only matches on addresses, or
+// if it matches on addresses and there are no bloom bits to match the topics.
+func (f *Filter) addressIndexTail() *uint64 {
+	if len(f.addresses) == 0 {
+		return nil
+	}
+	if _, sections := f.backend.BloomStatus(); sections > 0 {
+		for _, sub := range f.topics {
+			if len(sub) > 0 {
+				return nil
+			}
+		}
+	}
+	return rawdb.ReadAddressLogIndexTail(f.db)
+}
+
+// addressLogs returns the logs matching the filter criteria up to the given block,
+// based on the blocks indexed for the filtered addresses.
+func (f *Filter) addressLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+	var numbers []uint64
+	for _, addr := range f.addresses {
+		numbers = append(numbers, rawdb.ReadAddressLogBlocks(f.db, addr, uint64(f.begin), end)...)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	var logs []*types.Log
+	for i, number := range numbers {
+		if i > 0 && numbers[i-1] == number {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		// The index may reference blocks reorged out, which the address
+		// check of the canonical block's logs filters out
+		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if header == nil || err != nil {
+			return logs, err
+		}
+		found, err := f.checkMatches(ctx, header)
 		if err != nil {
-			return nil, err
+			return logs, err
 		}
-		logs = append(logs, pendingLogs...)
+		logs = append(logs, found...)
+		f.begin = int64(number) + 1
 	}
-	return logs, err
+	f.begin = int64(end) + 1
+	return logs, nil
 }
 
 // indexedLogs returns the logs matching the filter criteria based on the bloom
@@ -270,7 +360,7 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs [
 	for _, logs := range logsList {
 		unfiltered = append(unfiltered, logs...)
 	}
-	logs = filterLogs(unfiltered, nil, nil, f.addresses, f.topics)
+	logs = f.filterLogs(unfiltered)
 	if len(logs) > 0 {
 		// We have matching logs, check if we need to resolve full logs via the light client
 		if logs[0].TxHash == (common.Hash{}) {
@@ -282,7 +372,7 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs [
 			for _, receipt := range receipts {
 				unfiltered = append(unfiltered, receipt.Logs...)
 			}
-			logs = filterLogs(unfiltered, nil, nil, f.addresses, f.topics)
+			logs = f.filterLogs(unfiltered)
 		}
 		return logs, nil
 	}
@@ -297,11 +387,26 @@ func (f *Filter) pendingLogs() ([]*types.Log, error) {
 		for _, r := range receipts {
 			unfiltered = append(unfiltered, r.Logs...)
 		}
-		return filterLogs(unfiltered, nil, nil, f.addresses, f.topics), nil
+		return f.filterLogs(unfiltered), nil
 	}
 	return nil, nil
 }
 
+// filterLogs returns the logs matching the filter criteria. The addresses of
+// filters on many addresses are checked against a lookup set instead of the list.
+func (f *Filter) filterLogs(logs []*types.Log) []*types.Log {
+	if f.addressSet == nil {
+		return filterLogs(logs, nil, nil, f.addresses, f.topics)
+	}
+	var candidates []*types.Log
+	for _, log := range logs {
+		if _, ok := f.addressSet[log.Address]; ok {
+			candidates = append(candidates, log)
+		}
+	}
+	return filterLogs(candidates, nil, nil, nil, f.topics)
+}
+
 func includes(addresses []common.Address, a common.Address) bool {
 	for _, addr := range addresses {
 		if addr == a {
@@ -19,6 +19,7 @@ package filters
 import (
 	"context"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -230,3 +231,110 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+func TestAddressIndexFilters(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		addr    = common.BytesToAddress([]byte("jeff"))
+		other   = common.BytesToAddress([]byte("other"))
+		gspec   = core.Genesis{BaseFee: big.NewInt(params.InitialBaseFee)}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 20, func(i int, gen *core.BlockGen) {
+		switch i + 1 {
+		case 2, 5, 12, 15:
+			gen.AddUncheckedReceipt(makeReceipt(addr))
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 0, gen.BaseFee(), nil))
+		case 17:
+			gen.AddUncheckedReceipt(makeReceipt(other))
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 0, gen.BaseFee(), nil))
+		}
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Index from block 10 on, leaving out block 15 and adding a stale entry for
+	// block 17 as left behind by a reorg
+	rawdb.WriteAddressLogIndexTail(db, 10)
+	rawdb.WriteAddressLogIndex(db, 12, receipts[11])
+	rawdb.WriteAddressLogIndex(db, 17, types.Receipts{makeReceipt(addr)})
+
+	blocks := func(logs []*types.Log) []uint64 {
+		var numbers []uint64
+		for _, log := range logs {
+			numbers = append(numbers, log.BlockNumber)
+		}
+		return numbers
+	}
+	tests := []struct {
+		begin, end int64
+		want       []uint64
+	}{
+		{0, -1, []uint64{2, 5, 12}}, // bloom before the tail, index after
+		{11, 16, []uint64{12}},      // range covered by the index
+		{0, 9, []uint64{2, 5}},      // range preceding the index
+		{13, -1, nil},               // stale entries are skipped
+	}
+	for i, tt := range tests {
+		logs, err := NewRangeFilter(backend, tt.begin, tt.end, []common.Address{addr}, nil).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: filter failed: %v", i, err)
+		}
+		if have := blocks(logs); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: log blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Without the index, all logs are found via the bloom filters
+	rawdb.DeleteAddressLogIndexTail(db)
+	logs, _ := NewRangeFilter(backend, 0, -1, []common.Address{addr}, nil).Logs(context.Background())
+	if have := blocks(logs); !reflect.DeepEqual(have, []uint64{2, 5, 12, 15}) {
+		t.Errorf("unindexed log blocks mismatch: have %v", have)
+	}
+}
+
+// Tests that without bloom bits, the address log index also serves filters
+// matching on topics.
+func TestAddressIndexTopicFilters(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		addr    = common.BytesToAddress([]byte("jeff"))
+		topic   = common.BytesToHash([]byte("topic"))
+		gspec   = core.Genesis{BaseFee: big.NewInt(params.InitialBaseFee)}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {
+		switch i + 1 {
+		case 3, 6, 8:
+			receipt := makeReceipt(addr)
+			if i+1 != 6 {
+				receipt.Logs[0].Topics = []common.Hash{topic}
+				receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			}
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 0, gen.BaseFee(), nil))
+		}
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Leave block 8 out of the index to verify it's used
+	rawdb.WriteAddressLogIndexTail(db, 0)
+	rawdb.WriteAddressLogIndex(db, 3, receipts[2])
+	rawdb.WriteAddressLogIndex(db, 6, receipts[5])
+
+	logs, err := NewRangeFilter(backend, 0, -1, []common.Address{addr}, [][]common.Hash{{topic}}).Logs(context.Background())
+	if err != nil {
+		t.Fatalf("filter failed: %v", err)
+	}
+	if len(logs) != 1 || logs[0].BlockNumber != 3 {
+		t.Fatalf("log mismatch: have %v", logs)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// maxInclusionSamples is the number of recent local transaction inclusions
+	// the latency signal is computed from.
+	maxInclusionSamples = 64
+
+	// minInclusionSamples is the number of inclusions needed before the latency
+	// signal is taken into account.
+	minInclusionSamples = 4
+
+	// targetInclusionBlocks is the inclusion latency the suggestion aims for. For
+	// every block local transactions take longer, the suggestion is raised by
+	// 1/latencyBumpDenominator.
+	targetInclusionBlocks  = 2
+	latencyBumpDenominator = 8
+
+	// maxTrackedAge is the number of blocks after which a tracked local
+	// transaction is no longer waited for.
+	maxTrackedAge = 256
+)
+
+// Congestion contains the current inputs of the priority fee suggestion besides
+// the tips paid in recent blocks.
+type Congestion struct {
+	PendingTxs       int          `json:"pendingTxs"`       // Number of executable transactions in the pool
+	PendingGas       uint64       `json:"pendingGas"`       // Total gas of the executable transactions in the pool
+	GasLimit         uint64       `json:"gasLimit"`         // Gas limit of the latest block
+	CongestionTip    *hexutil.Big `json:"congestionTip"`    // Tip needed to fit into the next block based on the pool
+	InclusionBlocks  float64      `json:"inclusionBlocks"`  // Median inclusion latency of recent local transactions
+	InclusionSamples int          `json:"inclusionSamples"` // Number of local transactions the latency is based on
+	SuggestedTip     *hexutil.Big `json:"suggestedTip"`     // Resulting priority fee suggestion
+}
+
+// TrackTransaction records the submission of a local transaction, so its inclusion
+// latency can be taken into account in the suggestion.
+func (oracle *Oracle) TrackTransaction(tx *types.Transaction, head uint64) {
+	oracle.trackLock.Lock()
+	defer oracle.trackLock.Unlock()
+
+	oracle.tracked[tx.Hash()] = head
+}
+
+// observeBlock records the inclusion latency of the tracked transactions in a
+// new head block.
+func (oracle *Oracle) observeBlock(block *types.Block) {
+	oracle.trackLock.Lock()
+	defer oracle.trackLock.Unlock()
+
+	if len(oracle.tracked) == 0 {
+		return
+	}
+	number := block.NumberU64()
+	for _, tx := range block.Transactions() {
+		submitted, ok := oracle.tracked[tx.Hash()]
+		if !ok {
+			continue
+		}
+		delete(oracle.tracked, tx.Hash())
+		latency := uint64(1)
+		if number > submitted {
+			latency = number - submitted
+		}
+		oracle.inclusions = append(oracle.inclusions, latency)
+		if len(oracle.inclusions) > maxInclusionSamples {
+			oracle.inclusions = oracle.inclusions[1:]
+		}
+	}
+	for hash, submitted := range oracle.tracked {
+		if submitted+maxTrackedAge < number {
+			delete(oracle.tracked, hash)
+		}
+	}
+}
+
+// inclusionLatency returns the median inclusion latency of recent local
+// transactions in blocks, along with the number of samples.
+func (oracle *Oracle) inclusionLatency() (float64, int) {
+	oracle.trackLock.Lock()
+	samples := make([]uint64, len(oracle.inclusions))
+	copy(samples, oracle.inclusions)
+	oracle.trackLock.Unlock()
+
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	if len(samples)%2 == 0 {
+		return float64(samples[len(samples)/2-1]+samples[len(samples)/2]) / 2, len(samples)
+	}
+	return float64(samples[len(samples)/2]), len(samples)
+}
+
+// congestion computes the pool based inputs of the suggestion for the block
+// following head.
+func (oracle *Oracle) congestion(head *types.Header) *Congestion {
+	c := &Congestion{
+		GasLimit:      head.GasLimit,
+		CongestionTip: new(hexutil.Big),
+	}
+	c.InclusionBlocks, c.InclusionSamples = oracle.inclusionLatency()
+
+	txs, err := oracle.backend.GetPoolTransactions()
+	if err != nil || len(txs) == 0 {
+		return c
+	}
+	var baseFee *big.Int
+	if config := oracle.backend.ChainConfig(); config.IsLondon(new(big.Int).Add(head.Number, common.Big1)) {
+		baseFee = misc.CalcBaseFee(config, head)
+	}
+	// Fill the next block with the best paying transactions of the pool. If they
+	// don't fit, the tip of the first one left out is needed to get in.
+	type pooled struct {
+		tip *big.Int
+		gas uint64
+	}
+	candidates := make([]pooled, 0, len(txs))
+	for _, tx := range txs {
+		tip, err := tx.EffectiveGasTip(baseFee)
+		if err != nil {
+			continue // can't pay the base fee
+		}
+		candidates = append(candidates, pooled{tip, tx.Gas()})
+		c.PendingGas += tx.Gas()
+	}
+	c.PendingTxs = len(candidates)
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].tip.Cmp(candidates[j].tip) > 0 })
+
+	var gas uint64
+	for _, tx := range candidates {
+		if gas += tx.gas; gas > head.GasLimit {
+			c.CongestionTip = (*hexutil.Big)(tx.tip)
+			break
+		}
+	}
+	return c
+}
+
+// adjustForCongestion raises the historical tip suggestion if the pool is too
+// full for it to be included in the next block, or if recent local transactions
+// took too long to get included.
+func (oracle *Oracle) adjustForCongestion(price *big.Int, c *Congestion) *big.Int {
+	if tip := c.CongestionTip.ToInt(); tip.Cmp(price) > 0 {
+		price = new(big.Int).Set(tip)
+	}
+	if c.InclusionSamples >= minInclusionSamples && c.InclusionBlocks > targetInclusionBlocks {
+		excess := int64(c.InclusionBlocks - targetInclusionBlocks + 0.5)
+		bump := new(big.Int).Mul(price, big.NewInt(excess))
+		price = new(big.Int).Add(price, bump.Div(bump, big.NewInt(latencyBumpDenominator)))
+	}
+	return price
+}
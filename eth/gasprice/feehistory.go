@@ -59,6 +59,7 @@ type blockFees struct {
 // processedFees contains the results of a processed block and is also used for caching
 type processedFees struct {
 	reward               []*big.Int
+	typeReward           map[uint8][]*big.Int
 	baseFee, nextBaseFee *big.Int
 	gasUsedRatio         float64
 }
@@ -66,6 +67,7 @@ type processedFees struct {
 // txGasAndReward is sorted in ascending order based on reward
 type (
 	txGasAndReward struct {
+		txType  uint8
 		gasUsed uint64
 		reward  *big.Int
 	}
@@ -103,33 +105,57 @@ func (oracle *Oracle) processBlock(bf *blockFees, percentiles []float64) {
 		return
 	}
 
-	bf.results.reward = make([]*big.Int, len(percentiles))
 	if len(bf.block.Transactions()) == 0 {
 		// return an all zero row if there are no transactions to gather data from
+		bf.results.reward = make([]*big.Int, len(percentiles))
 		for i := range bf.results.reward {
 			bf.results.reward[i] = new(big.Int)
 		}
+		bf.results.typeReward = make(map[uint8][]*big.Int)
 		return
 	}
 
 	sorter := make(sortGasAndReward, len(bf.block.Transactions()))
 	for i, tx := range bf.block.Transactions() {
 		reward, _ := tx.EffectiveGasTip(bf.block.BaseFee())
-		sorter[i] = txGasAndReward{gasUsed: bf.receipts[i].GasUsed, reward: reward}
+		sorter[i] = txGasAndReward{txType: tx.Type(), gasUsed: bf.receipts[i].GasUsed, reward: reward}
 	}
 	sort.Stable(sorter)
+	bf.results.reward = rewardPercentiles(sorter, bf.block.GasUsed(), percentiles)
 
-	var txIndex int
-	sumGasUsed := sorter[0].gasUsed
+	// Break the rewards down by transaction type, the stable sort keeps each
+	// group sorted by reward
+	var (
+		groups  = make(map[uint8]sortGasAndReward)
+		gasUsed = make(map[uint8]uint64)
+	)
+	for _, tx := range sorter {
+		groups[tx.txType] = append(groups[tx.txType], tx)
+		gasUsed[tx.txType] += tx.gasUsed
+	}
+	bf.results.typeReward = make(map[uint8][]*big.Int, len(groups))
+	for txType, group := range groups {
+		bf.results.typeReward[txType] = rewardPercentiles(group, gasUsed[txType], percentiles)
+	}
+}
 
+// rewardPercentiles returns the rewards at the given percentiles of the gas used
+// by the transactions, which must be sorted by reward in ascending order.
+func rewardPercentiles(sorted sortGasAndReward, gasUsed uint64, percentiles []float64) []*big.Int {
+	var (
+		rewards    = make([]*big.Int, len(percentiles))
+		txIndex    int
+		sumGasUsed = sorted[0].gasUsed
+	)
 	for i, p := range percentiles {
-		thresholdGasUsed := uint64(float64(bf.block.GasUsed()) * p / 100)
-		for sumGasUsed < thresholdGasUsed && txIndex < len(bf.block.Transactions())-1 {
+		thresholdGasUsed := uint64(float64(gasUsed) * p / 100)
+		for sumGasUsed < thresholdGasUsed && txIndex < len(sorted)-1 {
 			txIndex++
-			sumGasUsed += sorter[txIndex].gasUsed
+			sumGasUsed += sorted[txIndex].gasUsed
 		}
-		bf.results.reward[i] = sorter[txIndex].reward
+		rewards[i] = sorted[txIndex].reward
 	}
+	return rewards
 }
 
 // resolveBlockRange resolves the specified block range to absolute block numbers while also
@@ -215,8 +241,21 @@ func (oracle *Oracle) resolveBlockRange(ctx context.Context, reqEnd rpc.BlockNum
 // Note: baseFee includes the next block after the newest of the returned range, because this
 // value can be derived from the newest block.
 func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
+	oldest, reward, _, baseFee, gasUsedRatio, err := oracle.feeHistory(ctx, blocks, unresolvedLastBlock, rewardPercentiles)
+	return oldest, reward, baseFee, gasUsedRatio, err
+}
+
+// FeeHistoryByType is like FeeHistory, but additionally breaks the reward
+// percentiles of each block down by transaction type. The percentiles of a type
+// are weighted by the gas used by the transactions of that type only, types
+// without transactions in a block are omitted.
+func (oracle *Oracle) FeeHistoryByType(ctx context.Context, blocks int, unresolvedLastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []map[uint8][]*big.Int, []*big.Int, []float64, error) {
+	return oracle.feeHistory(ctx, blocks, unresolvedLastBlock, rewardPercentiles)
+}
+
+func (oracle *Oracle) feeHistory(ctx context.Context, blocks int, unresolvedLastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []map[uint8][]*big.Int, []*big.Int, []float64, error) {
 	if blocks < 1 {
-		return common.Big0, nil, nil, nil, nil // returning with no data and no error means there are no retrievable blocks
+		return common.Big0, nil, nil, nil, nil, nil // returning with no data and no error means there are no retrievable blocks
 	}
 	maxFeeHistory := oracle.maxHeaderHistory
 	if len(rewardPercentiles) != 0 {
@@ -228,10 +267,10 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 	}
 	for i, p := range rewardPercentiles {
 		if p < 0 || p > 100 {
-			return common.Big0, nil, nil, nil, fmt.Errorf("%w: %f", errInvalidPercentile, p)
+			return common.Big0, nil, nil, nil, nil, fmt.Errorf("%w: %f", errInvalidPercentile, p)
 		}
 		if i > 0 && p < rewardPercentiles[i-1] {
-			return common.Big0, nil, nil, nil, fmt.Errorf("%w: #%d:%f > #%d:%f", errInvalidPercentile, i-1, rewardPercentiles[i-1], i, p)
+			return common.Big0, nil, nil, nil, nil, fmt.Errorf("%w: #%d:%f > #%d:%f", errInvalidPercentile, i-1, rewardPercentiles[i-1], i, p)
 		}
 	}
 	var (
@@ -241,7 +280,7 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 	)
 	pendingBlock, pendingReceipts, lastBlock, blocks, err := oracle.resolveBlockRange(ctx, unresolvedLastBlock, blocks)
 	if err != nil || blocks == 0 {
-		return common.Big0, nil, nil, nil, err
+		return common.Big0, nil, nil, nil, nil, err
 	}
 	oldestBlock := lastBlock + 1 - uint64(blocks)
 
@@ -302,6 +341,7 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 	}
 	var (
 		reward       = make([][]*big.Int, blocks)
+		typeReward   = make([]map[uint8][]*big.Int, blocks)
 		baseFee      = make([]*big.Int, blocks+1)
 		gasUsedRatio = make([]float64, blocks)
 		firstMissing = blocks
@@ -309,11 +349,12 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 	for ; blocks > 0; blocks-- {
 		fees := <-results
 		if fees.err != nil {
-			return common.Big0, nil, nil, nil, fees.err
+			return common.Big0, nil, nil, nil, nil, fees.err
 		}
 		i := int(fees.blockNumber - oldestBlock)
 		if fees.results.baseFee != nil {
 			reward[i], baseFee[i], baseFee[i+1], gasUsedRatio[i] = fees.results.reward, fees.results.baseFee, fees.results.nextBaseFee, fees.results.gasUsedRatio
+			typeReward[i] = fees.results.typeReward
 		} else {
 			// getting no block and no error means we are requesting into the future (might happen because of a reorg)
 			if i < firstMissing {
@@ -322,13 +363,13 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 		}
 	}
 	if firstMissing == 0 {
-		return common.Big0, nil, nil, nil, nil
+		return common.Big0, nil, nil, nil, nil, nil
 	}
 	if len(rewardPercentiles) != 0 {
-		reward = reward[:firstMissing]
+		reward, typeReward = reward[:firstMissing], typeReward[:firstMissing]
 	} else {
-		reward = nil
+		reward, typeReward = nil, nil
 	}
 	baseFee, gasUsedRatio = baseFee[:firstMissing+1], gasUsedRatio[:firstMissing]
-	return new(big.Int).SetUint64(oldestBlock), reward, baseFee, gasUsedRatio, nil
+	return new(big.Int).SetUint64(oldestBlock), reward, typeReward, baseFee, gasUsedRatio, nil
 }
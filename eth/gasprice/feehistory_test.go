@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -89,3 +90,33 @@ func TestFeeHistory(t *testing.T) {
 		}
 	}
 }
+
+func TestFeeHistoryByType(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(16), false)
+	oracle := NewOracle(backend, Config{MaxHeaderHistory: 1000, MaxBlockHistory: 1000})
+
+	// Blocks before the London fork contain a legacy transaction, after it a
+	// dynamic fee one.
+	first, reward, typeReward, _, _, err := oracle.FeeHistoryByType(context.Background(), 4, 17, []float64{50})
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if first.Uint64() != 14 {
+		t.Fatalf("first block mismatch: have %d, want %d", first, 14)
+	}
+	if len(typeReward) != len(reward) {
+		t.Fatalf("typed reward length mismatch: have %d, want %d", len(typeReward), len(reward))
+	}
+	for i, byType := range typeReward {
+		want := uint8(types.LegacyTxType)
+		if first.Uint64()+uint64(i) >= 16 {
+			want = types.DynamicFeeTxType
+		}
+		if len(byType) != 1 || byType[want] == nil {
+			t.Fatalf("block %d: typed rewards mismatch: have %v, want type %d", i, byType, want)
+		}
+		if byType[want][0].Cmp(reward[i][0]) != 0 {
+			t.Errorf("block %d: typed reward mismatch: have %v, want %v", i, byType[want][0], reward[i][0])
+		}
+	}
+}
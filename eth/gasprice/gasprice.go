@@ -23,6 +23,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
@@ -55,6 +56,7 @@ type OracleBackend interface {
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
 	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
 	PendingBlockAndReceipts() (*types.Block, types.Receipts)
+	GetPoolTransactions() (types.Transactions, error)
 	ChainConfig() *params.ChainConfig
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 }
@@ -62,13 +64,19 @@ type OracleBackend interface {
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
 type Oracle struct {
-	backend     OracleBackend
-	lastHead    common.Hash
-	lastPrice   *big.Int
-	maxPrice    *big.Int
-	ignorePrice *big.Int
-	cacheLock   sync.RWMutex
-	fetchLock   sync.Mutex
+	backend        OracleBackend
+	lastHead       common.Hash
+	lastPrice      *big.Int
+	lastSample     *big.Int    // Last tip derived from block history, before congestion adjustments
+	lastCongestion *Congestion // Inputs of the last suggestion
+	maxPrice       *big.Int
+	ignorePrice    *big.Int
+	cacheLock      sync.RWMutex
+	fetchLock      sync.Mutex
+
+	tracked    map[common.Hash]uint64 // Local transactions awaiting inclusion, with their submission head
+	inclusions []uint64               // Inclusion latencies of recent local transactions
+	trackLock  sync.Mutex
 
 	checkBlocks, percentile           int
 	maxHeaderHistory, maxBlockHistory int
@@ -115,6 +123,19 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 	}
 
 	cache, _ := lru.New(2048)
+	oracle := &Oracle{
+		backend:          backend,
+		lastPrice:        params.Default,
+		lastSample:       params.Default,
+		maxPrice:         maxPrice,
+		ignorePrice:      ignorePrice,
+		tracked:          make(map[common.Hash]uint64),
+		checkBlocks:      blocks,
+		percentile:       percent,
+		maxHeaderHistory: maxHeaderHistory,
+		maxBlockHistory:  maxBlockHistory,
+		historyCache:     cache,
+	}
 	headEvent := make(chan core.ChainHeadEvent, 1)
 	backend.SubscribeChainHeadEvent(headEvent)
 	go func() {
@@ -124,25 +145,19 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 				cache.Purge()
 			}
 			lastHead = ev.Block.Hash()
+			oracle.observeBlock(ev.Block)
 		}
 	}()
-
-	return &Oracle{
-		backend:          backend,
-		lastPrice:        params.Default,
-		maxPrice:         maxPrice,
-		ignorePrice:      ignorePrice,
-		checkBlocks:      blocks,
-		percentile:       percent,
-		maxHeaderHistory: maxHeaderHistory,
-		maxBlockHistory:  maxBlockHistory,
-		historyCache:     cache,
-	}
+	return oracle
 }
 
 // SuggestTipCap returns a tip cap so that newly created transaction can have a
 // very high chance to be included in the following blocks.
 //
+// The suggestion is based on the tips paid in recent blocks, raised if the pool
+// holds more than a block worth of better paying transactions or if recently
+// submitted local transactions took long to be included.
+//
 // Note, for legacy transactions and the legacy eth_gasPrice RPC call, it will be
 // necessary to add the basefee to the returned number to fall back to the legacy
 // behavior.
@@ -167,6 +182,10 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 	if headHash == lastHead {
 		return new(big.Int).Set(lastPrice), nil
 	}
+	oracle.cacheLock.RLock()
+	lastSample := oracle.lastSample
+	oracle.cacheLock.RUnlock()
+
 	var (
 		sent, exp int
 		number    = head.Number.Uint64()
@@ -192,7 +211,7 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 		// - All the transactions included are sent by the miner itself.
 		// In these cases, use the latest calculated price for sampling.
 		if len(res.values) == 0 {
-			res.values = []*big.Int{lastPrice}
+			res.values = []*big.Int{lastSample}
 		}
 		// Besides, in order to collect enough data for sampling, if nothing
 		// meaningful returned, try to query more blocks. But the maximum
@@ -205,22 +224,43 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 		}
 		results = append(results, res.values...)
 	}
-	price := lastPrice
+	sample := lastSample
 	if len(results) > 0 {
 		sort.Sort(bigIntArray(results))
-		price = results[(len(results)-1)*oracle.percentile/100]
+		sample = results[(len(results)-1)*oracle.percentile/100]
 	}
+	congestion := oracle.congestion(head)
+	price := oracle.adjustForCongestion(sample, congestion)
 	if price.Cmp(oracle.maxPrice) > 0 {
 		price = new(big.Int).Set(oracle.maxPrice)
 	}
+	congestion.SuggestedTip = (*hexutil.Big)(price)
+
 	oracle.cacheLock.Lock()
 	oracle.lastHead = headHash
 	oracle.lastPrice = price
+	oracle.lastSample = sample
+	oracle.lastCongestion = congestion
 	oracle.cacheLock.Unlock()
 
 	return new(big.Int).Set(price), nil
 }
 
+// Congestion returns the inputs of the current tip suggestion.
+func (oracle *Oracle) Congestion(ctx context.Context) (*Congestion, error) {
+	if _, err := oracle.SuggestTipCap(ctx); err != nil {
+		return nil, err
+	}
+	oracle.cacheLock.RLock()
+	defer oracle.cacheLock.RUnlock()
+
+	if oracle.lastCongestion == nil {
+		return nil, nil
+	}
+	c := *oracle.lastCongestion
+	return &c, nil
+}
+
 type results struct {
 	values []*big.Int
 	err    error
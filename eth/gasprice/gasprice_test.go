@@ -38,7 +38,8 @@ const testHead = 32
 
 type testBackend struct {
 	chain   *core.BlockChain
-	pending bool // pending block available
+	pending bool               // pending block available
+	pool    types.Transactions // executable pool transactions
 }
 
 func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
@@ -105,6 +106,10 @@ func (b *testBackend) PendingBlockAndReceipts() (*types.Block, types.Receipts) {
 	return nil, nil
 }
 
+func (b *testBackend) GetPoolTransactions() (types.Transactions, error) {
+	return b.pool, nil
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chain.Config()
 }
@@ -210,3 +215,59 @@ func TestSuggestTipCap(t *testing.T) {
 		}
 	}
 }
+
+func TestSuggestTipCapCongestion(t *testing.T) {
+	config := Config{
+		Blocks:     3,
+		Percentile: 60,
+		Default:    big.NewInt(params.GWei),
+	}
+	newTx := func(nonce uint64, gas uint64, tip int64) *types.Transaction {
+		return types.NewTx(&types.DynamicFeeTx{
+			Nonce:     nonce,
+			Gas:       gas,
+			GasTipCap: big.NewInt(tip * params.GWei),
+			GasFeeCap: big.NewInt(1000 * params.GWei),
+		})
+	}
+	// A pool holding more than a block worth of better paying transactions
+	// should raise the suggestion to the tip needed to get into the next block.
+	backend := newTestBackend(t, big.NewInt(0), false)
+	limit := backend.chain.CurrentHeader().GasLimit
+	for i := uint64(0); i < 3; i++ {
+		backend.pool = append(backend.pool, newTx(i, limit/2, 60-int64(i)*5))
+	}
+	oracle := NewOracle(backend, config)
+	got, err := oracle.SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve recommended gas price: %v", err)
+	}
+	if want := big.NewInt(50 * params.GWei); got.Cmp(want) != 0 {
+		t.Fatalf("Congested gas price mismatch, want %d, got %d", want, got)
+	}
+	congestion, _ := oracle.Congestion(context.Background())
+	if congestion.PendingTxs != 3 || congestion.PendingGas != limit/2*3 || congestion.SuggestedTip.ToInt().Cmp(got) != 0 {
+		t.Fatalf("Wrong congestion inputs: %+v", congestion)
+	}
+
+	// Slow inclusion of local transactions should raise the suggestion too
+	backend = newTestBackend(t, big.NewInt(0), false)
+	oracle = NewOracle(backend, config)
+	head := backend.chain.CurrentHeader().Number.Uint64()
+	var txs types.Transactions
+	for i := uint64(0); i < minInclusionSamples; i++ {
+		tx := newTx(i, 21000, 1)
+		oracle.TrackTransaction(tx, head)
+		txs = append(txs, tx)
+	}
+	oracle.observeBlock(types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(head + 4)}).WithBody(txs, nil))
+
+	got, err = oracle.SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve recommended gas price: %v", err)
+	}
+	// Sampled price is 30 gwei, raised by 2/8 for two blocks of excess latency
+	if want := big.NewInt(37500 * params.GWei / 1000); got.Cmp(want) != 0 {
+		t.Fatalf("Latency adjusted gas price mismatch, want %d, got %d", want, got)
+	}
+}
@@ -87,6 +87,9 @@ type handlerConfig struct {
 	EventMux       *event.TypeMux            // Legacy event mux, deprecate for `feed`
 	Checkpoint     *params.TrustedCheckpoint // Hard coded checkpoint for sync challenges
 	RequiredBlocks map[uint64]common.Hash    // Hard coded map of required block hashes for sync challenges
+	HistoryBudget  uint64                    // Bytes per second allowed for serving widely available history
+	HistoryMirrors []string                  // HTTP(S) era1 archives to retrieve history from during sync
+	TxAuditLimit   int                       // Number of transactions to keep a propagation audit trail for
 }
 
 type handler struct {
@@ -116,6 +119,8 @@ type handler struct {
 	minedBlockSub *event.TypeMuxSubscription
 
 	requiredBlocks map[uint64]common.Hash
+	historyRarity  *eth.HistoryRarity
+	txAudit        *txAudit
 
 	// channels for fetcher, syncer, txsyncLoop
 	quitSync chan struct{}
@@ -141,6 +146,8 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		peers:          newPeerSet(),
 		merger:         config.Merger,
 		requiredBlocks: config.RequiredBlocks,
+		historyRarity:  eth.NewHistoryRarity(config.HistoryBudget),
+		txAudit:        newTxAudit(config.TxAuditLimit),
 		quitSync:       make(chan struct{}),
 	}
 	if config.Sync == downloader.FullSync {
@@ -193,6 +200,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	}
 	// Construct the downloader (long sync)
 	h.downloader = downloader.New(h.checkpointNumber, config.Database, h.eventMux, h.chain, nil, h.removePeer, success)
+	h.downloader.SetHistoryMirrors(config.HistoryMirrors)
 	if ttd := h.chain.Config().TerminalTotalDifficulty; ttd != nil {
 		if h.chain.Config().TerminalTotalDifficultyPassed {
 			log.Info("Chain post-merge, sync via beacon client")
@@ -523,6 +531,7 @@ func (h *handler) unregisterPeer(id string) {
 	}
 	h.downloader.UnregisterPeer(id)
 	h.txFetcher.Drop(id)
+	h.historyRarity.Unregister(id)
 
 	if err := h.peers.unregisterPeer(id); err != nil {
 		logger.Error("Ethereum peer removal failed", "err", err)
@@ -594,8 +603,11 @@ func (h *handler) BroadcastBlock(block *types.Block, propagate bool) {
 			log.Error("Propagating dangling block", "number", block.Number(), "hash", hash)
 			return
 		}
-		// Send the block to a subset of our peers
-		transfer := peers[:int(math.Sqrt(float64(len(peers))))]
+		// Send the block to a subset of our peers, preferring close ones
+		numDirect := int(math.Sqrt(float64(len(peers))))
+		orderPropagationPeers(peers, numDirect)
+
+		transfer := peers[:numDirect]
 		for _, peer := range transfer {
 			peer.AsyncSendNewBlock(block, td)
 		}
@@ -612,7 +624,7 @@ func (h *handler) BroadcastBlock(block *types.Block, propagate bool) {
 }
 
 // BroadcastTransactions will propagate a batch of transactions
-// - To a square root of all peers
+// - To a square root of all peers, preferring low latency ones
 // - And, separately, as announcements to all peers which are not known to
 // already have the given transaction.
 func (h *handler) BroadcastTransactions(txs types.Transactions) {
@@ -631,6 +643,7 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
 		// Send the tx unconditionally to a subset of our peers
 		numDirect := int(math.Sqrt(float64(len(peers))))
+		orderPropagationPeers(peers, numDirect)
 		for _, peer := range peers[:numDirect] {
 			txset[peer] = append(txset[peer], tx.Hash())
 		}
@@ -643,11 +656,13 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		directPeers++
 		directCount += len(hashes)
 		peer.AsyncSendTransactions(hashes)
+		h.txAudit.forwarded(peer.ID(), hashes, false)
 	}
 	for peer, hashes := range annos {
 		annoPeers++
 		annoCount += len(hashes)
 		peer.AsyncSendPooledTransactionHashes(hashes)
+		h.txAudit.forwarded(peer.ID(), hashes, true)
 	}
 	log.Debug("Transaction broadcast", "txs", len(txs),
 		"announce packs", annoPeers, "announced hashes", annoCount,
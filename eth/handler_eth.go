@@ -55,6 +55,11 @@ func (h *ethHandler) AcceptTxs() bool {
 	return atomic.LoadUint32(&h.acceptTxs) == 1
 }
 
+// HistoryRarity retrieves the tracker of the history availability of peers.
+func (h *ethHandler) HistoryRarity() *eth.HistoryRarity {
+	return h.historyRarity
+}
+
 // Handle is invoked from a peer's message handler when it receives a new remote
 // message that the handler couldn't consume and serve itself.
 func (h *ethHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
@@ -71,9 +76,11 @@ func (h *ethHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
 		return h.txFetcher.Notify(peer.ID(), *packet)
 
 	case *eth.TransactionsPacket:
+		h.txAudit.arrived(peer.ID(), *packet, false)
 		return h.txFetcher.Enqueue(peer.ID(), *packet, false)
 
 	case *eth.PooledTransactionsPacket:
+		h.txAudit.arrived(peer.ID(), *packet, true)
 		return h.txFetcher.Enqueue(peer.ID(), *packet, true)
 
 	default:
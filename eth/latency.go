@@ -0,0 +1,82 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// propagationDiversity is the fraction of the direct propagation targets which
+// are picked at random instead of by latency, so that blocks and transactions
+// keep reaching distant parts of the network directly.
+const propagationDiversity = 0.3
+
+// latencyRegions classifies peers by their round trip time, approximating how
+// far away they are.
+var latencyRegions = []struct {
+	name string
+	max  time.Duration
+}{
+	{"local", 25 * time.Millisecond},
+	{"regional", 100 * time.Millisecond},
+	{"continental", 250 * time.Millisecond},
+}
+
+// latencyRegion returns the latency region of a peer with the given round trip
+// time, measured over the given number of requests.
+func latencyRegion(rtt time.Duration, samples uint64) string {
+	if samples == 0 {
+		return "unknown"
+	}
+	for _, region := range latencyRegions {
+		if rtt < region.max {
+			return region.name
+		}
+	}
+	return "intercontinental"
+}
+
+// orderPropagationPeers reorders the peers in place so that the first n are the
+// preferred direct propagation targets. Most of them are the peers with the
+// lowest round trip times, the rest are picked at random from the remaining
+// peers, as per propagationDiversity. Peers without latency measurements are
+// only ever picked at random.
+func orderPropagationPeers(peers []*ethPeer, n int) {
+	if n <= 0 || n >= len(peers) {
+		return
+	}
+	rtts := make(map[*ethPeer]time.Duration, len(peers))
+	for _, peer := range peers {
+		if rtt, samples := peer.RTT(); samples > 0 {
+			rtts[peer] = rtt
+		}
+	}
+	sort.SliceStable(peers, func(i, j int) bool {
+		rtti, oki := rtts[peers[i]]
+		rttj, okj := rtts[peers[j]]
+		if oki != okj {
+			return oki
+		}
+		return rtti < rttj
+	})
+	rest := peers[n-int(float64(n)*propagationDiversity):]
+	rand.Shuffle(len(rest), func(i, j int) {
+		rest[i], rest[j] = rest[j], rest[i]
+	})
+}
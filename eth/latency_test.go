@@ -0,0 +1,42 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyRegion(t *testing.T) {
+	tests := []struct {
+		rtt     time.Duration
+		samples uint64
+		want    string
+	}{
+		{0, 0, "unknown"},
+		{time.Second, 0, "unknown"},
+		{5 * time.Millisecond, 1, "local"},
+		{25 * time.Millisecond, 3, "regional"},
+		{120 * time.Millisecond, 3, "continental"},
+		{250 * time.Millisecond, 3, "intercontinental"},
+	}
+	for i, tt := range tests {
+		if have := latencyRegion(tt.rtt, tt.samples); have != tt.want {
+			t.Errorf("test %d: region mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package milestone implements signed chain milestones for private networks.
+//
+// A milestone is a finalized block number and hash, signed by a threshold of
+// keys configured in the chain config. Operators publish milestones over HTTPS
+// or as DNS TXT records, and nodes tracking them refuse to reorg past the latest
+// verified one.
+package milestone
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	errNotEnoughSignatures = errors.New("not enough valid milestone signatures")
+	errNoThreshold         = errors.New("milestone threshold not configured")
+)
+
+// Milestone is a signed finalized block of the chain.
+type Milestone struct {
+	Number     uint64          `json:"number"`
+	Hash       common.Hash     `json:"hash"`
+	Signatures []hexutil.Bytes `json:"signatures"`
+}
+
+// SigHash returns the hash signed by milestone signers. It commits to the chain
+// ID so that milestones can't be replayed across networks.
+func SigHash(chainID *big.Int, number uint64, hash common.Hash) common.Hash {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	return crypto.Keccak256Hash([]byte("milestone"), common.BigToHash(chainID).Bytes(), enc[:], hash.Bytes())
+}
+
+// Sign adds a signature of the given key to the milestone.
+func (m *Milestone) Sign(chainID *big.Int, key *ecdsa.PrivateKey) error {
+	sig, err := crypto.Sign(SigHash(chainID, m.Number, m.Hash).Bytes(), key)
+	if err != nil {
+		return err
+	}
+	m.Signatures = append(m.Signatures, sig)
+	return nil
+}
+
+// Verify checks that the milestone is signed by enough distinct trusted signers.
+func (m *Milestone) Verify(chainID *big.Int, config *params.MilestoneConfig) error {
+	if config.Threshold == 0 {
+		return errNoThreshold
+	}
+	trusted := make(map[common.Address]bool, len(config.Signers))
+	for _, signer := range config.Signers {
+		trusted[signer] = true
+	}
+	var (
+		hash   = SigHash(chainID, m.Number, m.Hash)
+		signed = make(map[common.Address]bool)
+	)
+	for _, sig := range m.Signatures {
+		pubkey, err := crypto.SigToPub(hash.Bytes(), sig)
+		if err != nil {
+			continue
+		}
+		if signer := crypto.PubkeyToAddress(*pubkey); trusted[signer] {
+			signed[signer] = true
+		}
+	}
+	if uint64(len(signed)) < config.Threshold {
+		return fmt.Errorf("%w: have %d, want %d", errNotEnoughSignatures, len(signed), config.Threshold)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package milestone
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func newTestSigners(n int) ([]*ecdsa.PrivateKey, []common.Address) {
+	keys := make([]*ecdsa.PrivateKey, n)
+	addrs := make([]common.Address, n)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	return keys, addrs
+}
+
+// Tests that milestones are only accepted with enough distinct trusted signers.
+func TestMilestoneVerify(t *testing.T) {
+	var (
+		chainID     = big.NewInt(1337)
+		keys, addrs = newTestSigners(3)
+		config      = &params.MilestoneConfig{Signers: addrs, Threshold: 2}
+		untrusted   = func() *ecdsa.PrivateKey { key, _ := crypto.GenerateKey(); return key }()
+	)
+	m := &Milestone{Number: 100, Hash: common.Hash{0x01}}
+	m.Sign(chainID, keys[0])
+	if err := m.Verify(chainID, config); err == nil {
+		t.Fatal("milestone accepted with one signature")
+	}
+	m.Sign(chainID, keys[0])
+	m.Sign(chainID, untrusted)
+	if err := m.Verify(chainID, config); err == nil {
+		t.Fatal("milestone accepted with duplicate and untrusted signatures")
+	}
+	m.Sign(chainID, keys[2])
+	if err := m.Verify(chainID, config); err != nil {
+		t.Fatalf("valid milestone rejected: %v", err)
+	}
+	if err := m.Verify(big.NewInt(1), config); err == nil {
+		t.Fatal("milestone accepted on a different chain")
+	}
+}
+
+// testChain is a mock chain recording the milestones set on it.
+type testChain struct {
+	config    *params.ChainConfig
+	milestone *core.Milestone
+}
+
+func (c *testChain) Config() *params.ChainConfig { return c.config }
+
+func (c *testChain) SetMilestone(m core.Milestone) { c.milestone = &m }
+
+func (c *testChain) CurrentMilestone() (core.Milestone, bool) {
+	if c.milestone == nil {
+		return core.Milestone{}, false
+	}
+	return *c.milestone, true
+}
+
+// Tests that the tracker applies the highest valid milestone of all sources.
+func TestTrackerUpdate(t *testing.T) {
+	var (
+		chainID     = big.NewInt(1337)
+		keys, addrs = newTestSigners(2)
+	)
+	serve := func(m *Milestone) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(m)
+		}))
+	}
+	valid := &Milestone{Number: 10, Hash: common.Hash{0x0a}}
+	valid.Sign(chainID, keys[0])
+	valid.Sign(chainID, keys[1])
+
+	forged := &Milestone{Number: 20, Hash: common.Hash{0x14}}
+	forged.Sign(chainID, keys[0])
+
+	validSrv, forgedSrv := serve(valid), serve(forged)
+	defer validSrv.Close()
+	defer forgedSrv.Close()
+
+	chain := &testChain{config: &params.ChainConfig{
+		ChainID: chainID,
+		Milestone: &params.MilestoneConfig{
+			Signers:   addrs,
+			Threshold: 2,
+			URLs:      []string{forgedSrv.URL, validSrv.URL},
+		},
+	}}
+	tracker := NewTracker(chain)
+	if err := tracker.Update(context.Background()); err != nil {
+		t.Fatalf("failed to update milestone: %v", err)
+	}
+	if chain.milestone == nil || chain.milestone.Number != 10 || chain.milestone.Hash != valid.Hash {
+		t.Fatalf("wrong milestone applied: %+v", chain.milestone)
+	}
+	// Older milestones should not override newer ones
+	chain.milestone = &core.Milestone{Number: 50}
+	if err := tracker.Update(context.Background()); err != nil {
+		t.Fatalf("failed to update milestone: %v", err)
+	}
+	if chain.milestone.Number != 50 {
+		t.Fatalf("milestone rolled back to %d", chain.milestone.Number)
+	}
+}
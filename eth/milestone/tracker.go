@@ -0,0 +1,204 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package milestone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// updateInterval is the time between two milestone refreshes.
+	updateInterval = 5 * time.Minute
+
+	// fetchTimeout is the time allowed for retrieving a milestone from a source.
+	fetchTimeout = 30 * time.Second
+
+	// maxMilestoneSize is the maximum size of a published milestone document.
+	maxMilestoneSize = 64 * 1024
+
+	// txtPrefix marks the DNS TXT records containing a milestone.
+	txtPrefix = "milestone="
+)
+
+var errNoMilestone = errors.New("no milestone found")
+
+// Chain is the blockchain the tracker enforces milestones on.
+type Chain interface {
+	Config() *params.ChainConfig
+	SetMilestone(milestone core.Milestone)
+	CurrentMilestone() (core.Milestone, bool)
+}
+
+// Tracker periodically retrieves the milestones published by the configured
+// sources and applies the newest verified one to the chain.
+type Tracker struct {
+	chain  Chain
+	config *params.MilestoneConfig
+	fetch  func(ctx context.Context, source string) (*Milestone, error)
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewTracker creates a milestone tracker for the chain. It returns nil if the
+// chain config doesn't configure milestones.
+func NewTracker(chain Chain) *Tracker {
+	config := chain.Config().Milestone
+	if config == nil {
+		return nil
+	}
+	return &Tracker{
+		chain:  chain,
+		config: config,
+		fetch:  fetch,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start launches the background milestone refresh loop.
+func (t *Tracker) Start() {
+	t.wg.Add(1)
+	go t.loop()
+}
+
+// Stop terminates the background refresh loop.
+func (t *Tracker) Stop() {
+	close(t.quit)
+	t.wg.Wait()
+}
+
+func (t *Tracker) loop() {
+	defer t.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-t.quit
+		cancel()
+	}()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if err := t.Update(ctx); err != nil {
+				log.Warn("Failed to update chain milestone", "err", err)
+			}
+			timer.Reset(updateInterval)
+		case <-t.quit:
+			return
+		}
+	}
+}
+
+// Update retrieves the milestones from all configured sources and applies the
+// highest one with enough valid signatures, if newer than the current one.
+func (t *Tracker) Update(ctx context.Context) error {
+	var (
+		chainID = t.chain.Config().ChainID
+		best    *Milestone
+		lastErr error = errNoMilestone
+	)
+	for _, source := range t.config.URLs {
+		m, err := t.fetch(ctx, source)
+		if err == nil {
+			err = m.Verify(chainID, t.config)
+		}
+		if err != nil {
+			log.Debug("Invalid chain milestone", "source", source, "err", err)
+			lastErr = fmt.Errorf("%s: %w", source, err)
+			continue
+		}
+		if best == nil || m.Number > best.Number {
+			best = m
+		}
+	}
+	if best == nil {
+		return lastErr
+	}
+	if current, ok := t.chain.CurrentMilestone(); ok && current.Number >= best.Number {
+		return nil
+	}
+	log.Info("Updated chain milestone", "number", best.Number, "hash", best.Hash)
+	t.chain.SetMilestone(core.Milestone{Number: best.Number, Hash: best.Hash})
+	return nil
+}
+
+// fetch retrieves a milestone from an HTTP(S) URL or a dns:// TXT record.
+func fetch(ctx context.Context, source string) (*Milestone, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	switch u.Scheme {
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected HTTP status %s", res.Status)
+		}
+		if data, err = io.ReadAll(io.LimitReader(res.Body, maxMilestoneSize)); err != nil {
+			return nil, err
+		}
+	case "dns":
+		records, err := net.DefaultResolver.LookupTXT(ctx, u.Host)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if strings.HasPrefix(record, txtPrefix) {
+				data = []byte(strings.TrimPrefix(record, txtPrefix))
+				break
+			}
+		}
+		if data == nil {
+			return nil, errNoMilestone
+		}
+	default:
+		return nil, fmt.Errorf("unsupported milestone source scheme %q", u.Scheme)
+	}
+	m := new(Milestone)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -240,6 +240,7 @@ func (p *Peer) dispatcher() {
 				// with the matching request. Signal to the delivery routine that
 				// it can wait for a handler response and dispatch the data.
 				res.Time = res.recv.Sub(res.Req.Sent)
+				p.updateRTT(res.Time)
 				resOp.fail <- nil
 
 				// Stop tracking the request, the response dispatcher will deliver
@@ -24,7 +24,6 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
@@ -81,6 +80,10 @@ type Backend interface {
 	// PeerInfo retrieves all known `eth` information about a peer.
 	PeerInfo(id enode.ID) interface{}
 
+	// HistoryRarity retrieves the tracker of which historical block segments
+	// remote peers are able to serve. It may return nil to disable tracking.
+	HistoryRarity() *HistoryRarity
+
 	// Handle is a callback to be invoked when a data packet is received from
 	// the remote peer. Only packets not consumed by the protocol handler will
 	// be forwarded to the backend.
@@ -198,7 +201,7 @@ var eth67 = map[uint64]msgHandler{
 
 // handleMessage is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
-func handleMessage(backend Backend, peer *Peer) error {
+func handleMessage(backend Backend, peer *Peer) (err error) {
 	// Read the next message from the remote peer, and ensure it's fully consumed
 	msg, err := peer.rw.ReadMsg()
 	if err != nil {
@@ -215,17 +218,9 @@ func handleMessage(backend Backend, peer *Peer) error {
 	}
 
 	// Track the amount of time it takes to serve the request and run the handler
-	if metrics.Enabled {
-		h := fmt.Sprintf("%s/%s/%d/%#02x", p2p.HandleHistName, ProtocolName, peer.Version(), msg.Code)
-		defer func(start time.Time) {
-			sampler := func() metrics.Sample {
-				return metrics.ResettingSample(
-					metrics.NewExpDecaySample(1028, 0.015),
-				)
-			}
-			metrics.GetOrRegisterHistogramLazy(h, nil, sampler).Update(time.Since(start).Microseconds())
-		}(time.Now())
-	}
+	defer func(start time.Time) {
+		p2p.MeterHandle(ProtocolName, peer.Version(), msg.Code, start, err)
+	}(time.Now())
 	if handler := handlers[msg.Code]; handler != nil {
 		return handler(backend, msg, peer)
 	}
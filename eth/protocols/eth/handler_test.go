@@ -100,6 +100,8 @@ func (b *testBackend) RunPeer(peer *Peer, handler Handler) error {
 }
 func (b *testBackend) PeerInfo(enode.ID) interface{} { panic("not implemented") }
 
+func (b *testBackend) HistoryRarity() *HistoryRarity { return nil }
+
 func (b *testBackend) AcceptTxs() bool {
 	panic("data processing tests should be done in the handler package")
 }
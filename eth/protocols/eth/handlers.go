@@ -19,6 +19,7 @@ package eth
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -208,17 +209,31 @@ func handleGetBlockBodies66(backend Backend, msg Decoder, peer *Peer) error {
 	if err := msg.Decode(&query); err != nil {
 		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 	}
-	response := ServiceGetBlockBodiesQuery(backend.Chain(), query.GetBlockBodiesPacket)
-	return peer.ReplyBlockBodiesRLP(query.RequestId, response)
+	response, delay := serviceGetBlockBodiesQuery(backend.Chain(), backend.HistoryRarity(), query.GetBlockBodiesPacket)
+	return replyHistory(backend.HistoryRarity(), peer, delay, func(empty bool) error {
+		if empty {
+			return peer.ReplyBlockBodiesRLP(query.RequestId, nil)
+		}
+		return peer.ReplyBlockBodiesRLP(query.RequestId, response)
+	})
 }
 
 // ServiceGetBlockBodiesQuery assembles the response to a body query. It is
 // exposed to allow external packages to test protocol behavior.
 func ServiceGetBlockBodiesQuery(chain *core.BlockChain, query GetBlockBodiesPacket) []rlp.RawValue {
+	bodies, _ := serviceGetBlockBodiesQuery(chain, nil, query)
+	return bodies
+}
+
+// serviceGetBlockBodiesQuery assembles the response to a body query, cutting it
+// short if widely available history exceeds the serving budget. It also returns
+// the time the response should be held back for to stay within the budget.
+func serviceGetBlockBodiesQuery(chain *core.BlockChain, rarity *HistoryRarity, query GetBlockBodiesPacket) ([]rlp.RawValue, time.Duration) {
 	// Gather blocks until the fetch or network limits is reached
 	var (
 		bytes  int
 		bodies []rlp.RawValue
+		delay  time.Duration
 	)
 	for lookups, hash := range query {
 		if bytes >= softResponseLimit || len(bodies) >= maxBodiesServe ||
@@ -226,11 +241,16 @@ func ServiceGetBlockBodiesQuery(chain *core.BlockChain, query GetBlockBodiesPack
 			break
 		}
 		if data := chain.GetBodyRLP(hash); len(data) != 0 {
+			allow, wait := allowHistory(chain, rarity, hash, len(data), len(bodies) == 0)
+			if !allow {
+				break
+			}
 			bodies = append(bodies, data)
 			bytes += len(data)
+			delay += wait
 		}
 	}
-	return bodies
+	return bodies, delay
 }
 
 func handleGetNodeData66(backend Backend, msg Decoder, peer *Peer) error {
@@ -276,17 +296,31 @@ func handleGetReceipts66(backend Backend, msg Decoder, peer *Peer) error {
 	if err := msg.Decode(&query); err != nil {
 		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 	}
-	response := ServiceGetReceiptsQuery(backend.Chain(), query.GetReceiptsPacket)
-	return peer.ReplyReceiptsRLP(query.RequestId, response)
+	response, delay := serviceGetReceiptsQuery(backend.Chain(), backend.HistoryRarity(), query.GetReceiptsPacket)
+	return replyHistory(backend.HistoryRarity(), peer, delay, func(empty bool) error {
+		if empty {
+			return peer.ReplyReceiptsRLP(query.RequestId, nil)
+		}
+		return peer.ReplyReceiptsRLP(query.RequestId, response)
+	})
 }
 
 // ServiceGetReceiptsQuery assembles the response to a receipt query. It is
 // exposed to allow external packages to test protocol behavior.
 func ServiceGetReceiptsQuery(chain *core.BlockChain, query GetReceiptsPacket) []rlp.RawValue {
+	receipts, _ := serviceGetReceiptsQuery(chain, nil, query)
+	return receipts
+}
+
+// serviceGetReceiptsQuery assembles the response to a receipt query, cutting it
+// short if widely available history exceeds the serving budget. It also returns
+// the time the response should be held back for to stay within the budget.
+func serviceGetReceiptsQuery(chain *core.BlockChain, rarity *HistoryRarity, query GetReceiptsPacket) ([]rlp.RawValue, time.Duration) {
 	// Gather state data until the fetch or network limits is reached
 	var (
 		bytes    int
 		receipts []rlp.RawValue
+		delay    time.Duration
 	)
 	for lookups, hash := range query {
 		if bytes >= softResponseLimit || len(receipts) >= maxReceiptsServe ||
@@ -304,11 +338,16 @@ func ServiceGetReceiptsQuery(chain *core.BlockChain, query GetReceiptsPacket) []
 		if encoded, err := rlp.EncodeToBytes(results); err != nil {
 			log.Error("Failed to encode receipt", "err", err)
 		} else {
+			allow, wait := allowHistory(chain, rarity, hash, len(encoded), len(receipts) == 0)
+			if !allow {
+				break
+			}
 			receipts = append(receipts, encoded)
 			bytes += len(encoded)
+			delay += wait
 		}
 	}
-	return receipts
+	return receipts, delay
 }
 
 func handleNewBlockhashes(backend Backend, msg Decoder, peer *Peer) error {
@@ -377,7 +416,15 @@ func handleBlockBodies66(backend Backend, msg Decoder, peer *Peer) error {
 	if err := msg.Decode(res); err != nil {
 		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 	}
+	response := &Response{
+		id:   res.RequestId,
+		code: BlockBodiesMsg,
+		Res:  &res.BlockBodiesPacket,
+	}
 	metadata := func() interface{} {
+		if req, ok := response.Req.data.(*GetBlockBodiesPacket66); ok {
+			observeHistory(backend, peer, req.GetBlockBodiesPacket, len(res.BlockBodiesPacket))
+		}
 		var (
 			txsHashes   = make([]common.Hash, len(res.BlockBodiesPacket))
 			uncleHashes = make([]common.Hash, len(res.BlockBodiesPacket))
@@ -389,11 +436,7 @@ func handleBlockBodies66(backend Backend, msg Decoder, peer *Peer) error {
 		}
 		return [][]common.Hash{txsHashes, uncleHashes}
 	}
-	return peer.dispatchResponse(&Response{
-		id:   res.RequestId,
-		code: BlockBodiesMsg,
-		Res:  &res.BlockBodiesPacket,
-	}, metadata)
+	return peer.dispatchResponse(response, metadata)
 }
 
 func handleNodeData66(backend Backend, msg Decoder, peer *Peer) error {
@@ -415,7 +458,15 @@ func handleReceipts66(backend Backend, msg Decoder, peer *Peer) error {
 	if err := msg.Decode(res); err != nil {
 		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 	}
+	response := &Response{
+		id:   res.RequestId,
+		code: ReceiptsMsg,
+		Res:  &res.ReceiptsPacket,
+	}
 	metadata := func() interface{} {
+		if req, ok := response.Req.data.(*GetReceiptsPacket66); ok {
+			observeHistory(backend, peer, req.GetReceiptsPacket, len(res.ReceiptsPacket))
+		}
 		hasher := trie.NewStackTrie(nil)
 		hashes := make([]common.Hash, len(res.ReceiptsPacket))
 		for i, receipt := range res.ReceiptsPacket {
@@ -423,11 +474,7 @@ func handleReceipts66(backend Backend, msg Decoder, peer *Peer) error {
 		}
 		return hashes
 	}
-	return peer.dispatchResponse(&Response{
-		id:   res.RequestId,
-		code: ReceiptsMsg,
-		Res:  &res.ReceiptsPacket,
-	}, metadata)
+	return peer.dispatchResponse(response, metadata)
 }
 
 func handleNewPooledTransactionHashes(backend Backend, msg Decoder, peer *Peer) error {
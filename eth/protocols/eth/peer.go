@@ -20,6 +20,7 @@ import (
 	"math/big"
 	"math/rand"
 	"sync"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/ethereum/go-ethereum/common"
@@ -54,6 +55,10 @@ const (
 	// dropping broadcasts. Similarly to block propagations, there's no point to queue
 	// above some healthy uncle limit, so use that.
 	maxQueuedBlockAnns = 4
+
+	// rttImpact is the weight of a new round trip time measurement in the
+	// smoothed estimate of a peer.
+	rttImpact = 0.1
 )
 
 // max is a helper function which returns the larger of the two given integers.
@@ -75,6 +80,9 @@ type Peer struct {
 	head common.Hash // Latest advertised head block hash
 	td   *big.Int    // Latest advertised head block total difficulty
 
+	rtt        time.Duration // Smoothed round trip time of the requests served
+	rttSamples uint64        // Number of round trip times measured
+
 	knownBlocks     *knownCache            // Set of block hashes known to be known by this peer
 	queuedBlocks    chan *blockPropagation // Queue of blocks to broadcast to the peer
 	queuedBlockAnns chan *types.Block      // Queue of blocks to announce to the peer
@@ -147,6 +155,30 @@ func (p *Peer) Head() (hash common.Hash, td *big.Int) {
 	return hash, new(big.Int).Set(p.td)
 }
 
+// RTT retrieves the smoothed round trip time of the requests served by the
+// peer and the number of requests it is based on. The round trip time is zero
+// if the peer did not serve any requests yet.
+func (p *Peer) RTT() (time.Duration, uint64) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.rtt, p.rttSamples
+}
+
+// updateRTT folds the round trip time of a served request into the smoothed
+// estimate of the peer.
+func (p *Peer) updateRTT(rtt time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.rttSamples == 0 {
+		p.rtt = rtt
+	} else {
+		p.rtt = time.Duration((1-rttImpact)*float64(p.rtt) + rttImpact*float64(rtt))
+	}
+	p.rttSamples++
+}
+
 // SetHead updates the head hash and total difficulty of the peer.
 func (p *Peer) SetHead(hash common.Hash, td *big.Int) {
 	p.lock.Lock()
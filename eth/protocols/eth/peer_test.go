@@ -22,6 +22,7 @@ package eth
 import (
 	"crypto/rand"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -86,3 +87,22 @@ func TestPeerSet(t *testing.T) {
 		t.Fatalf("bad size")
 	}
 }
+
+func TestPeerRTT(t *testing.T) {
+	var id enode.ID
+	rand.Read(id[:])
+	peer := NewPeer(ETH66, p2p.NewPeer(id, "test", nil), nil, nil)
+	defer peer.Close()
+
+	if rtt, samples := peer.RTT(); rtt != 0 || samples != 0 {
+		t.Fatalf("unmeasured peer has rtt %v over %d samples", rtt, samples)
+	}
+	peer.updateRTT(100 * time.Millisecond)
+	if rtt, samples := peer.RTT(); rtt != 100*time.Millisecond || samples != 1 {
+		t.Fatalf("rtt mismatch: have %v over %d samples, want 100ms over 1", rtt, samples)
+	}
+	peer.updateRTT(200 * time.Millisecond)
+	if rtt, samples := peer.RTT(); rtt != 110*time.Millisecond || samples != 2 {
+		t.Fatalf("rtt mismatch: have %v over %d samples, want 110ms over 2", rtt, samples)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core"
+)
+
+const (
+	// HistorySegmentSize is the number of consecutive blocks grouped together
+	// when tracking which parts of the chain history remote peers can serve.
+	HistorySegmentSize = 8192
+
+	// historyRareThreshold is the fraction of peers known to be missing a
+	// segment above which it is considered rare and served without limits.
+	historyRareThreshold = 0.5
+
+	// historyRecentBlocks is the number of blocks below the chain head which are
+	// considered recent and are never subject to history serving limits.
+	historyRecentBlocks = 90000
+
+	// historyMaxServeDelay is the maximum time a reply is held back to pay for
+	// its first item if the serving budget is exhausted.
+	historyMaxServeDelay = time.Second
+
+	// historyMaxDelayedReplies is the maximum number of replies held back for a
+	// single peer. Further over-budget requests of the peer are answered empty.
+	historyMaxDelayedReplies = 4
+)
+
+// HistoryRarity tracks which historical block segments remote peers are able
+// to serve, based on their replies to our own retrievals. As more nodes prune
+// their chain history, it allows serving the rarest segments first (bittorrent
+// style) by charging the bandwidth spent on history against a budget in
+// proportion to how widely available it is.
+type HistoryRarity struct {
+	peers   map[string]map[uint64]bool // Known segment availability per peer
+	delayed map[string]int             // Number of replies held back per peer
+
+	budget float64        // Bytes per second allowed for common history (0 = unlimited)
+	tokens float64        // Bytes currently allowed to be served for common history
+	last   mclock.AbsTime // Last time the token bucket was refilled
+	clock  mclock.Clock
+
+	lock sync.Mutex
+}
+
+// NewHistoryRarity creates a history availability tracker. The budget is the
+// number of bytes per second that may be spent serving history segments which
+// are widely available from other peers; zero disables the limit.
+func NewHistoryRarity(budget uint64) *HistoryRarity {
+	return newHistoryRarity(budget, mclock.System{})
+}
+
+func newHistoryRarity(budget uint64, clock mclock.Clock) *HistoryRarity {
+	return &HistoryRarity{
+		peers:   make(map[string]map[uint64]bool),
+		delayed: make(map[string]int),
+		budget:  float64(budget),
+		tokens:  float64(budget),
+		last:    clock.Now(),
+		clock:   clock,
+	}
+}
+
+// Observe records whether a peer was able to serve the given historical block.
+func (h *HistoryRarity) Observe(peer string, number uint64, available bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	segments, ok := h.peers[peer]
+	if !ok {
+		segments = make(map[uint64]bool)
+		h.peers[peer] = segments
+	}
+	segments[number/HistorySegmentSize] = available
+}
+
+// Unregister drops all the availability information known about a peer.
+func (h *HistoryRarity) Unregister(peer string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.peers, peer)
+}
+
+// Rarity returns the fraction of peers with known availability of the segment
+// containing the block that are missing it. Segments without any information
+// are considered common.
+func (h *HistoryRarity) Rarity(number uint64) float64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.rarity(number / HistorySegmentSize)
+}
+
+// rarity calculates the rarity of a segment.
+//
+// The method assumes that the lock is held!
+func (h *HistoryRarity) rarity(segment uint64) float64 {
+	var known, missing int
+	for _, segments := range h.peers {
+		if available, ok := segments[segment]; ok {
+			known++
+			if !available {
+				missing++
+			}
+		}
+	}
+	if known == 0 {
+		return 0
+	}
+	return float64(missing) / float64(known)
+}
+
+// Rarest returns the first block numbers of up to limit history segments with
+// any known availability, ordered from rarest to most common.
+func (h *HistoryRarity) Rarest(limit int) []uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	rarities := make(map[uint64]float64)
+	for _, segments := range h.peers {
+		for segment := range segments {
+			if _, ok := rarities[segment]; !ok {
+				rarities[segment] = h.rarity(segment)
+			}
+		}
+	}
+	rarest := make([]uint64, 0, len(rarities))
+	for segment := range rarities {
+		rarest = append(rarest, segment)
+	}
+	sort.Slice(rarest, func(i, j int) bool {
+		if rarities[rarest[i]] != rarities[rarest[j]] {
+			return rarities[rarest[i]] > rarities[rarest[j]]
+		}
+		return rarest[i] < rarest[j]
+	})
+	if len(rarest) > limit {
+		rarest = rarest[:limit]
+	}
+	for i, segment := range rarest {
+		rarest[i] = segment * HistorySegmentSize
+	}
+	return rarest
+}
+
+// allowServe reports whether a historical item of the given size belonging to
+// the given block may be served, and how long the reply should be delayed for it.
+// Recent and rare blocks are always served, others only while the serving budget
+// is not exhausted. The budget is charged less the rarer the segment is, so that
+// scarce history keeps being served while widely available history is throttled.
+// The first item of a reply is always served so that requests make progress, but
+// if it exceeds the budget, the reply is delayed until the budget is paid back.
+func (h *HistoryRarity) allowServe(number, head uint64, size int, first bool) (bool, time.Duration) {
+	if h == nil || h.budget == 0 || number+historyRecentBlocks > head {
+		return true, 0
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	rarity := h.rarity(number / HistorySegmentSize)
+	if rarity >= historyRareThreshold {
+		return true, 0
+	}
+	cost := float64(size) * (1 - rarity/historyRareThreshold)
+
+	// Refill the token bucket and check the budget
+	now := h.clock.Now()
+	h.tokens += h.budget * time.Duration(now-h.last).Seconds()
+	if h.tokens > h.budget {
+		h.tokens = h.budget
+	}
+	h.last = now
+
+	if h.tokens >= cost {
+		h.tokens -= cost
+		return true, 0
+	}
+	if !first {
+		return false, 0
+	}
+	// Over budget, go into debt for the first item and delay the reply
+	delay := time.Duration((cost - h.tokens) / h.budget * float64(time.Second))
+	if delay > historyMaxServeDelay {
+		delay = historyMaxServeDelay
+	}
+	h.tokens -= cost
+	return true, delay
+}
+
+// allowHistory reports whether a historical item of the given block may be added
+// to a response, and how long the response should be held back for it, skipping
+// the header lookup if serving is unlimited anyway.
+func allowHistory(chain *core.BlockChain, rarity *HistoryRarity, hash common.Hash, size int, first bool) (bool, time.Duration) {
+	if rarity == nil || rarity.budget == 0 {
+		return true, 0
+	}
+	header := chain.GetHeaderByHash(hash)
+	if header == nil {
+		return true, 0
+	}
+	return rarity.allowServe(header.Number.Uint64(), chain.CurrentHeader().Number.Uint64(), size, first)
+}
+
+// replyHistory sends a reply containing history, holding it back for the given
+// delay without blocking the message loop of the peer. If too many replies of
+// the peer are held back already, an empty reply is sent right away instead.
+func replyHistory(rarity *HistoryRarity, peer *Peer, delay time.Duration, reply func(empty bool) error) error {
+	if delay == 0 {
+		return reply(false)
+	}
+	rarity.lock.Lock()
+	if rarity.delayed[peer.id] >= historyMaxDelayedReplies {
+		rarity.lock.Unlock()
+		return reply(true)
+	}
+	rarity.delayed[peer.id]++
+	rarity.lock.Unlock()
+
+	rarity.clock.AfterFunc(delay, func() {
+		if err := reply(false); err != nil {
+			peer.Log().Debug("Failed to send delayed history reply", "err", err)
+		}
+		rarity.lock.Lock()
+		defer rarity.lock.Unlock()
+
+		if rarity.delayed[peer.id]--; rarity.delayed[peer.id] <= 0 {
+			delete(rarity.delayed, peer.id)
+		}
+	})
+	return nil
+}
+
+// observeHistory records the history availability of a remote peer based on its
+// reply to one of our body or receipt requests. Replies are served in request
+// order, so the first and last delivered items mark the range available. Since
+// a reply may be cut short for many reasons, a peer is only deemed to miss some
+// history if it could not deliver anything at all.
+func observeHistory(backend Backend, peer *Peer, hashes []common.Hash, delivered int) {
+	rarity := backend.HistoryRarity()
+	if rarity == nil || len(hashes) == 0 {
+		return
+	}
+	chain := backend.Chain()
+	if delivered == 0 {
+		if header := chain.GetHeaderByHash(hashes[0]); header != nil {
+			rarity.Observe(peer.id, header.Number.Uint64(), false)
+		}
+		return
+	}
+	if delivered > len(hashes) {
+		delivered = len(hashes)
+	}
+	for _, hash := range []common.Hash{hashes[0], hashes[delivered-1]} {
+		if header := chain.GetHeaderByHash(hash); header != nil {
+			rarity.Observe(peer.id, header.Number.Uint64(), true)
+		}
+	}
+}
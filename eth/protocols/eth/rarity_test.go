@@ -0,0 +1,185 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that segment rarity is calculated from the peers with known availability
+// and that the rarest segments are reported first.
+func TestHistoryRarity(t *testing.T) {
+	rarity := NewHistoryRarity(0)
+
+	rarity.Observe("a", 0, false)
+	rarity.Observe("b", 1, false)
+	rarity.Observe("c", 2, true)
+	rarity.Observe("a", HistorySegmentSize, true)
+	rarity.Observe("b", HistorySegmentSize+1, false)
+	rarity.Observe("c", 2*HistorySegmentSize, true)
+
+	if r := rarity.Rarity(5); r != 2.0/3 {
+		t.Errorf("segment 0 rarity mismatch: have %v, want %v", r, 2.0/3)
+	}
+	if r := rarity.Rarity(HistorySegmentSize); r != 0.5 {
+		t.Errorf("segment 1 rarity mismatch: have %v, want %v", r, 0.5)
+	}
+	if r := rarity.Rarity(3 * HistorySegmentSize); r != 0 {
+		t.Errorf("unknown segment rarity mismatch: have %v, want %v", r, 0)
+	}
+	want := []uint64{0, HistorySegmentSize, 2 * HistorySegmentSize}
+	if have := rarity.Rarest(10); !reflect.DeepEqual(have, want) {
+		t.Errorf("rarest segments mismatch: have %v, want %v", have, want)
+	}
+	if have := rarity.Rarest(1); !reflect.DeepEqual(have, want[:1]) {
+		t.Errorf("limited rarest segments mismatch: have %v, want %v", have, want[:1])
+	}
+	rarity.Unregister("a")
+	rarity.Unregister("b")
+	if r := rarity.Rarity(0); r != 0 {
+		t.Errorf("segment 0 rarity after unregister mismatch: have %v, want %v", r, 0)
+	}
+}
+
+// Tests that common history is rate limited, while recent and rare history are
+// always served.
+func TestHistoryRarityServeBudget(t *testing.T) {
+	var (
+		clock  = new(mclock.Simulated)
+		rarity = newHistoryRarity(1000, clock)
+		head   = uint64(10 * historyRecentBlocks)
+	)
+	rarity.Observe("a", HistorySegmentSize, false)
+	rarity.Observe("b", HistorySegmentSize, false)
+	rarity.Observe("c", 0, true)
+
+	// Drain the budget with common history
+	if ok, _ := rarity.allowServe(0, head, 600, false); !ok {
+		t.Fatalf("common history rejected within budget")
+	}
+	if ok, _ := rarity.allowServe(0, head, 600, false); ok {
+		t.Fatalf("common history accepted over budget")
+	}
+	// Rare and recent history should not be affected
+	if ok, _ := rarity.allowServe(HistorySegmentSize, head, 600, false); !ok {
+		t.Fatalf("rare history rejected")
+	}
+	if ok, _ := rarity.allowServe(head-1, head, 600, false); !ok {
+		t.Fatalf("recent history rejected")
+	}
+	// The first item of a reply should be served over budget, but delayed
+	ok, delay := rarity.allowServe(0, head, 600, true)
+	if !ok {
+		t.Fatalf("first item rejected over budget")
+	}
+	if delay != 200*time.Millisecond {
+		t.Fatalf("first item delay mismatch: have %v, want %v", delay, 200*time.Millisecond)
+	}
+	// The debt should be paid back before serving further common history
+	clock.Run(delay)
+	if ok, _ := rarity.allowServe(0, head, 600, false); ok {
+		t.Fatalf("common history accepted while in debt")
+	}
+	// Wait for the budget to refill and retry
+	clock.Run(time.Second)
+	if ok, _ := rarity.allowServe(0, head, 600, false); !ok {
+		t.Fatalf("common history rejected after refill")
+	}
+	// Nil trackers and unlimited budgets should allow everything
+	var nilRarity *HistoryRarity
+	if ok, _ := nilRarity.allowServe(0, head, 1<<20, false); !ok {
+		t.Fatalf("nil tracker rejected history")
+	}
+	if ok, _ := NewHistoryRarity(0).allowServe(0, head, 1<<20, false); !ok {
+		t.Fatalf("unlimited tracker rejected history")
+	}
+}
+
+// Tests that the budget is charged less for history the rarer it is.
+func TestHistoryRarityServeCost(t *testing.T) {
+	var (
+		clock  = new(mclock.Simulated)
+		rarity = newHistoryRarity(1000, clock)
+		head   = uint64(10 * historyRecentBlocks)
+	)
+	// Make segment 0 missing from a quarter of the peers
+	rarity.Observe("a", 0, false)
+	rarity.Observe("b", 0, true)
+	rarity.Observe("c", 0, true)
+	rarity.Observe("d", 0, true)
+
+	// Items costing half their size, the budget should fit four of them
+	for i := 0; i < 4; i++ {
+		if ok, _ := rarity.allowServe(0, head, 500, false); !ok {
+			t.Fatalf("item %d rejected within budget", i)
+		}
+	}
+	if ok, _ := rarity.allowServe(0, head, 500, false); ok {
+		t.Fatalf("item accepted over budget")
+	}
+}
+
+// Tests that delayed replies are sent without blocking the caller, and that the
+// number of replies held back for a peer is capped.
+func TestHistoryReplyDelay(t *testing.T) {
+	var (
+		clock  = new(mclock.Simulated)
+		rarity = newHistoryRarity(1000, clock)
+		peer   = NewPeer(ETH66, p2p.NewPeer(enode.ID{1}, "test", nil), nil, nil)
+		sent   []bool
+	)
+	defer peer.Close()
+
+	reply := func(empty bool) error {
+		sent = append(sent, empty)
+		return nil
+	}
+	if err := replyHistory(rarity, peer, 0, reply); err != nil || len(sent) != 1 || sent[0] {
+		t.Fatalf("undelayed reply mismatch: %v, %v", sent, err)
+	}
+	sent = nil
+	for i := 0; i < historyMaxDelayedReplies; i++ {
+		if err := replyHistory(rarity, peer, time.Second, reply); err != nil {
+			t.Fatalf("failed to delay reply: %v", err)
+		}
+	}
+	if len(sent) != 0 {
+		t.Fatalf("delayed replies sent early: %v", sent)
+	}
+	// Over the limit, replies should be sent empty right away
+	if err := replyHistory(rarity, peer, time.Second, reply); err != nil || len(sent) != 1 || !sent[0] {
+		t.Fatalf("over limit reply mismatch: %v, %v", sent, err)
+	}
+	clock.Run(time.Second)
+	if len(sent) != 1+historyMaxDelayedReplies {
+		t.Fatalf("delayed reply count mismatch: have %d, want %d", len(sent)-1, historyMaxDelayedReplies)
+	}
+	for i, empty := range sent[1:] {
+		if empty {
+			t.Errorf("delayed reply %d sent empty", i)
+		}
+	}
+	if n := len(rarity.delayed); n != 0 {
+		t.Errorf("delayed replies still tracked: %d", n)
+	}
+}
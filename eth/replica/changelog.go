@@ -0,0 +1,183 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package replica implements read replicas fed by the change-log of a primary
+// node instead of the p2p network.
+//
+// The primary exports every block that becomes canonical into a change-log, which
+// is a sequence of numbered segment files holding RLP encoded blocks. Segments are
+// written to a directory, which may be shared storage or synced to an object store,
+// and can be served over gRPC (see changelog.proto). Replicas tail the change-log
+// from a directory, an HTTP(S) endpoint or a gRPC server and import the segments
+// in order, following any reorgs of the primary. Other transports can be added by
+// implementing Source.
+//
+// If a key is configured, segments are sealed with it before leaving the primary
+// and authenticated by the replicas, so untrusted storage can neither read nor
+// tamper with the change-log.
+package replica
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb/encrypted"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// maxSegmentSize is the maximum size of a change-log segment accepted by replicas.
+const maxSegmentSize = 512 * 1024 * 1024
+
+// errSegmentNotFound is returned by sources if the requested segment hasn't been
+// published yet.
+var errSegmentNotFound = errors.New("change-log segment not found")
+
+// segmentName returns the file name of a change-log segment.
+func segmentName(seq uint64) string {
+	return fmt.Sprintf("%020d.rlp", seq)
+}
+
+// encodeSegment encodes a change-log segment.
+func encodeSegment(blocks []*types.Block) ([]byte, error) {
+	var enc []byte
+	for _, block := range blocks {
+		blob, err := rlp.EncodeToBytes(block)
+		if err != nil {
+			return nil, err
+		}
+		enc = append(enc, blob...)
+	}
+	return enc, nil
+}
+
+// sealSegment encrypts an encoded change-log segment if a key is configured. The
+// segment name is authenticated along, so segments can't be reordered.
+func sealSegment(aead cipher.AEAD, seq uint64, enc []byte) []byte {
+	if aead == nil {
+		return enc
+	}
+	return encrypted.Seal(aead, enc, []byte(segmentName(seq)))
+}
+
+// openSegment authenticates and decrypts a change-log segment sealed with the key,
+// if one is configured.
+func openSegment(aead cipher.AEAD, seq uint64, data []byte) ([]byte, error) {
+	if aead == nil {
+		return data, nil
+	}
+	return encrypted.Open(aead, data, []byte(segmentName(seq)))
+}
+
+// decodeSegment decodes the blocks of a change-log segment.
+func decodeSegment(enc []byte) ([]*types.Block, error) {
+	var (
+		stream = rlp.NewStream(bytes.NewReader(enc), maxSegmentSize)
+		blocks []*types.Block
+	)
+	for {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			return blocks, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("block %d: %v", len(blocks), err)
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+// Source is a location a primary node's change-log can be retrieved from.
+type Source interface {
+	// Fetch retrieves the contents of a change-log segment as published by the
+	// primary, which may be sealed.
+	Fetch(ctx context.Context, seq uint64) ([]byte, error)
+}
+
+// NewSource creates a change-log source for a gRPC or HTTP(S) URL, or a directory
+// path.
+func NewSource(location string) (Source, error) {
+	switch {
+	case strings.HasPrefix(location, "grpc://"), strings.HasPrefix(location, "grpcs://"):
+		return newGRPCSource(location)
+	case strings.HasPrefix(location, "http://"), strings.HasPrefix(location, "https://"):
+		return &httpSource{base: strings.TrimSuffix(location, "/")}, nil
+	case location == "":
+		return nil, errors.New("empty change-log location")
+	default:
+		return dirSource(strings.TrimPrefix(location, "file://")), nil
+	}
+}
+
+// dirSource reads the change-log from a local or mounted directory.
+type dirSource string
+
+func (dir dirSource) Fetch(ctx context.Context, seq uint64) ([]byte, error) {
+	f, err := os.Open(filepath.Join(string(dir), segmentName(seq)))
+	if os.IsNotExist(err) {
+		return nil, errSegmentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSegment(f)
+}
+
+// httpSource reads the change-log from a web server or object store bucket.
+type httpSource struct {
+	base string
+}
+
+func (s *httpSource) Fetch(ctx context.Context, seq uint64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.base+"/"+segmentName(seq), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return readSegment(res.Body)
+	case http.StatusNotFound, http.StatusForbidden:
+		// Object stores answer 403 for missing keys without list permission
+		return nil, errSegmentNotFound
+	default:
+		return nil, fmt.Errorf("unexpected HTTP status %s", res.Status)
+	}
+}
+
+// readSegment reads a change-log segment, rejecting oversized ones.
+func readSegment(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSegmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSegmentSize {
+		return nil, errors.New("change-log segment too large")
+	}
+	return data, nil
+}
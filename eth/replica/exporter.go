@@ -0,0 +1,208 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// maxSegmentBlocks is the maximum number of blocks exported into one segment.
+	maxSegmentBlocks = 256
+
+	// stateFile is the name of the file tracking the progress of the exporter.
+	stateFile = "EXPORTED"
+)
+
+// exportState is the progress of the change-log export.
+type exportState struct {
+	Seq    uint64      `json:"seq"`    // Sequence number of the next segment
+	Number uint64      `json:"number"` // Number of the last exported block
+	Hash   common.Hash `json:"hash"`   // Hash of the last exported block
+}
+
+// errExportStopped is returned by Export if the exporter is stopped while
+// segments are still being written.
+var errExportStopped = errors.New("change-log export stopped")
+
+// Exporter writes every block becoming canonical on the primary node into the
+// change-log directory.
+type Exporter struct {
+	chain *core.BlockChain
+	dir   string
+	aead  cipher.AEAD // Key sealing the segments, nil to write them in plain text
+	state exportState
+
+	lock sync.Mutex
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewExporter creates a change-log exporter for the chain, resuming the export
+// if the directory already contains a change-log. Segments are sealed with aead,
+// unless it is nil.
+func NewExporter(chain *core.BlockChain, dir string, aead cipher.AEAD) (*Exporter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	e := &Exporter{
+		chain: chain,
+		dir:   dir,
+		aead:  aead,
+		quit:  make(chan struct{}),
+	}
+	blob, err := os.ReadFile(filepath.Join(dir, stateFile))
+	switch {
+	case os.IsNotExist(err):
+		e.state = exportState{Hash: chain.Genesis().Hash()}
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(blob, &e.state); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Start launches the background export loop.
+func (e *Exporter) Start() {
+	e.wg.Add(1)
+	go e.loop()
+}
+
+// Stop terminates the background export loop.
+func (e *Exporter) Stop() {
+	close(e.quit)
+	e.wg.Wait()
+}
+
+func (e *Exporter) loop() {
+	defer e.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := e.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	if err := e.Export(); err != nil && err != errExportStopped {
+		log.Error("Failed to export change-log", "err", err)
+	}
+	for {
+		select {
+		case <-heads:
+			if err := e.Export(); err != nil && err != errExportStopped {
+				log.Error("Failed to export change-log", "err", err)
+			}
+		case <-sub.Err():
+			return
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+// Export writes all canonical blocks not exported yet into new segments. If the
+// chain reorged since the last export, the new canonical blocks are exported from
+// the common ancestor.
+func (e *Exporter) Export() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var (
+		head = e.chain.CurrentBlock().NumberU64()
+		from = e.state.Number + 1
+	)
+	if e.chain.GetCanonicalHash(e.state.Number) != e.state.Hash {
+		old := e.chain.GetHeader(e.state.Hash, e.state.Number)
+		for old != nil && e.chain.GetCanonicalHash(old.Number.Uint64()) != old.Hash() {
+			old = e.chain.GetHeader(old.ParentHash, old.Number.Uint64()-1)
+		}
+		if old == nil {
+			return errors.New("last exported block not found")
+		}
+		from = old.Number.Uint64() + 1
+	}
+	for from <= head {
+		select {
+		case <-e.quit:
+			return errExportStopped
+		default:
+		}
+		to := from + maxSegmentBlocks - 1
+		if to > head {
+			to = head
+		}
+		blocks := make([]*types.Block, 0, to-from+1)
+		for n := from; n <= to; n++ {
+			block := e.chain.GetBlockByNumber(n)
+			if block == nil {
+				return errors.New("canonical block missing")
+			}
+			blocks = append(blocks, block)
+		}
+		if err := e.writeSegment(blocks); err != nil {
+			return err
+		}
+		from = to + 1
+	}
+	return nil
+}
+
+// writeSegment atomically writes the next change-log segment and updates the
+// export progress.
+func (e *Exporter) writeSegment(blocks []*types.Block) error {
+	enc, err := encodeSegment(blocks)
+	if err != nil {
+		return err
+	}
+	data := sealSegment(e.aead, e.state.Seq, enc)
+	if err := writeFileAtomic(filepath.Join(e.dir, segmentName(e.state.Seq)), data); err != nil {
+		return err
+	}
+	last := blocks[len(blocks)-1]
+	state := exportState{Seq: e.state.Seq + 1, Number: last.NumberU64(), Hash: last.Hash()}
+	blob, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(e.dir, stateFile), blob); err != nil {
+		return err
+	}
+	log.Debug("Exported change-log segment", "seq", e.state.Seq, "first", blocks[0].NumberU64(), "last", last.NumberU64())
+	e.state = state
+	return nil
+}
+
+// writeFileAtomic writes a file through a temporary one, so readers never see a
+// partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
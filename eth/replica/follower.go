@@ -0,0 +1,166 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// pollInterval is the time between two checks for new change-log segments once
+// the replica caught up with the primary.
+const pollInterval = time.Second
+
+// positionKey tracks the sequence number of the next change-log segment to import.
+var positionKey = []byte("ReplicaLogPosition")
+
+// Follower imports the change-log of a primary node into the local chain.
+type Follower struct {
+	chain  *core.BlockChain
+	db     ethdb.KeyValueStore
+	source Source
+	aead   cipher.AEAD // Key the segments are sealed with, nil if plain text
+
+	lock sync.Mutex
+	quit chan struct{} // Closed to stop the import loop, nil if not running
+	wg   sync.WaitGroup
+}
+
+// NewFollower creates a replica following the change-log published at source.
+// The import progress is stored in db. Segments are authenticated and decrypted
+// with aead, unless it is nil.
+func NewFollower(chain *core.BlockChain, db ethdb.KeyValueStore, source Source, aead cipher.AEAD) *Follower {
+	return &Follower{
+		chain:  chain,
+		db:     db,
+		source: source,
+		aead:   aead,
+	}
+}
+
+// Start launches the background change-log import loop, unless it's running.
+func (f *Follower) Start() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.quit != nil {
+		return
+	}
+	f.quit = make(chan struct{})
+	f.wg.Add(1)
+	go f.loop(f.quit)
+}
+
+// Stop terminates the background import loop. The follower may be started again
+// afterwards.
+func (f *Follower) Stop() {
+	f.lock.Lock()
+	quit := f.quit
+	f.quit = nil
+	f.lock.Unlock()
+
+	if quit != nil {
+		close(quit)
+		f.wg.Wait()
+	}
+}
+
+func (f *Follower) loop(quit chan struct{}) {
+	defer f.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-quit
+		cancel()
+	}()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if err := f.Sync(ctx); err != nil && ctx.Err() == nil {
+				log.Warn("Failed to import change-log", "err", err)
+			}
+			timer.Reset(pollInterval)
+		case <-quit:
+			return
+		}
+	}
+}
+
+// Sync imports all change-log segments published since the last import, returning
+// once the replica caught up with the primary.
+func (f *Follower) Sync(ctx context.Context) error {
+	seq := f.position()
+	for {
+		data, err := f.source.Fetch(ctx, seq)
+		if errors.Is(err, errSegmentNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		enc, err := openSegment(f.aead, seq, data)
+		if err != nil {
+			return fmt.Errorf("segment %d: %v", seq, err)
+		}
+		blocks, err := decodeSegment(enc)
+		if err != nil {
+			return fmt.Errorf("segment %d: %v", seq, err)
+		}
+		if len(blocks) > 0 {
+			if _, err := f.chain.InsertChain(blocks); err != nil {
+				return err
+			}
+			log.Debug("Imported change-log segment", "seq", seq, "head", blocks[len(blocks)-1].NumberU64())
+		}
+		seq++
+		f.setPosition(seq)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// position returns the sequence number of the next segment to import.
+func (f *Follower) position() uint64 {
+	blob, _ := f.db.Get(positionKey)
+	if len(blob) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(blob)
+}
+
+// setPosition stores the sequence number of the next segment to import.
+func (f *Follower) setPosition(seq uint64) {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], seq)
+	if err := f.db.Put(positionKey, blob[:]); err != nil {
+		log.Crit("Failed to store change-log position", "err", err)
+	}
+}
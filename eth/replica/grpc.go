@@ -0,0 +1,174 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/internal/grpcwire"
+	"github.com/ethereum/go-ethereum/log"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// service is the name of the gRPC service defined in changelog.proto.
+	service = "ethereum.replica.v1.ChangeLog"
+
+	// maxChunkSize is the maximum number of segment bytes sent in one response,
+	// keeping the messages well below the gRPC size limit.
+	maxChunkSize = 1024 * 1024
+)
+
+// Server serves the change-log written by an exporter over gRPC.
+type Server struct {
+	dir  string
+	addr string
+
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer creates a change-log server for the segments in dir, listening on
+// addr. Sealed segments are served as they are.
+func NewServer(dir string, addr string) *Server {
+	s := &Server{dir: dir, addr: addr}
+	srv := grpcwire.NewServer(service)
+	srv.Register("GetSegment", s.getSegment)
+	s.server = &http.Server{Handler: srv.Handler()}
+	return s
+}
+
+// Start implements node.Lifecycle, opening the listener.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	log.Info("Change-log server started", "addr", listener.Addr())
+
+	go s.server.Serve(listener)
+	return nil
+}
+
+// Stop implements node.Lifecycle, closing the listener.
+func (s *Server) Stop() error {
+	return s.server.Close()
+}
+
+// Addr returns the listening address of the server.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) getSegment(req []byte) ([]byte, error) {
+	seq, err := grpcwire.DecodeUint(req, 1)
+	if err != nil {
+		return nil, &grpcwire.Error{Code: grpcwire.CodeInvalidArgument, Message: err.Error()}
+	}
+	offset, err := grpcwire.DecodeUint(req, 2)
+	if err != nil {
+		return nil, &grpcwire.Error{Code: grpcwire.CodeInvalidArgument, Message: err.Error()}
+	}
+	f, err := os.Open(filepath.Join(s.dir, segmentName(seq)))
+	if os.IsNotExist(err) {
+		return nil, &grpcwire.Error{Code: grpcwire.CodeNotFound, Message: errSegmentNotFound.Error()}
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(info.Size())
+	if offset > size {
+		return nil, &grpcwire.Error{Code: grpcwire.CodeInvalidArgument, Message: "offset beyond segment end"}
+	}
+	n := size - offset
+	if n > maxChunkSize {
+		n = maxChunkSize
+	}
+	chunk := make([]byte, n)
+	if _, err := f.ReadAt(chunk, int64(offset)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	var res []byte
+	res = protowire.AppendTag(res, 1, protowire.BytesType)
+	res = protowire.AppendBytes(res, chunk)
+	res = protowire.AppendTag(res, 2, protowire.VarintType)
+	res = protowire.AppendVarint(res, size)
+	return res, nil
+}
+
+// grpcSource reads the change-log from the gRPC server of a primary node.
+type grpcSource struct {
+	client *grpcwire.Client
+}
+
+func newGRPCSource(endpoint string) (*grpcSource, error) {
+	client, err := grpcwire.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcSource{client: client}, nil
+}
+
+func (s *grpcSource) Fetch(ctx context.Context, seq uint64) ([]byte, error) {
+	var data []byte
+	for {
+		var req []byte
+		req = protowire.AppendTag(req, 1, protowire.VarintType)
+		req = protowire.AppendVarint(req, seq)
+		req = protowire.AppendTag(req, 2, protowire.VarintType)
+		req = protowire.AppendVarint(req, uint64(len(data)))
+
+		res, err := s.client.Call(ctx, "/"+service+"/GetSegment", req)
+		if status, ok := err.(*grpcwire.Error); ok && status.Code == grpcwire.CodeNotFound {
+			return nil, errSegmentNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		chunks, err := grpcwire.DecodeBytes(res, 1)
+		if err != nil {
+			return nil, err
+		}
+		size, err := grpcwire.DecodeUint(res, 2)
+		if err != nil {
+			return nil, err
+		}
+		if size > maxSegmentSize {
+			return nil, errors.New("change-log segment too large")
+		}
+		for _, chunk := range chunks {
+			data = append(data, chunk...)
+		}
+		switch {
+		case uint64(len(data)) == size:
+			return data, nil
+		case uint64(len(data)) > size || len(chunks) == 0:
+			return nil, errors.New("inconsistent change-log segment chunks")
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package replica
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encrypted"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func newTestChain(t *testing.T) (*core.BlockChain, ethdb.Database) {
+	db := rawdb.NewMemoryDatabase()
+	(&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(chain.Stop)
+	return chain, db
+}
+
+// Tests that a replica follows the primary's chain through the change-log, also
+// across reorgs, from a directory, over HTTP and over gRPC.
+func TestReplicaFollowsPrimary(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		primary, _ = newTestChain(t)
+		engine     = ethash.NewFaker()
+		genDB      = rawdb.NewMemoryDatabase()
+	)
+	(&core.Genesis{Config: params.TestChainConfig}).MustCommit(genDB)
+	exporter, err := NewExporter(primary, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, _ := core.GenerateChain(params.TestChainConfig, primary.Genesis(), engine, genDB, maxSegmentBlocks+10, nil)
+	if _, err := primary.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Export(); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	grpcSrv := NewServer(dir, "127.0.0.1:0")
+	if err := grpcSrv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer grpcSrv.Stop()
+
+	dirReplica, dirDB := newTestChain(t)
+	httpReplica, httpDB := newTestChain(t)
+	grpcReplica, grpcDB := newTestChain(t)
+	dirSource, _ := NewSource(dir)
+	httpSource, _ := NewSource(srv.URL)
+	grpcSource, err := NewSource("grpc://" + grpcSrv.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	followers := map[*core.BlockChain]*Follower{
+		dirReplica:  NewFollower(dirReplica, dirDB, dirSource, nil),
+		httpReplica: NewFollower(httpReplica, httpDB, httpSource, nil),
+		grpcReplica: NewFollower(grpcReplica, grpcDB, grpcSource, nil),
+	}
+	check := func(want *types.Block) {
+		t.Helper()
+		for chain, follower := range followers {
+			if err := follower.Sync(context.Background()); err != nil {
+				t.Fatalf("failed to sync replica: %v", err)
+			}
+			if head := chain.CurrentBlock(); head.Hash() != want.Hash() {
+				t.Fatalf("replica head mismatch: have %d, want %d", head.NumberU64(), want.NumberU64())
+			}
+		}
+	}
+	check(blocks[len(blocks)-1])
+
+	// Reorg the primary to a longer side chain forking off a few blocks back
+	fork := blocks[len(blocks)-5]
+	side, _ := core.GenerateChain(params.TestChainConfig, fork, engine, genDB, 10, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := primary.InsertChain(side); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Export(); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	check(side[len(side)-1])
+
+	// Resuming the exporter should not export anything again
+	exporter, err = NewExporter(primary, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := exporter.state.Seq
+	if err := exporter.Export(); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if exporter.state.Seq != seq {
+		t.Fatalf("resumed exporter wrote %d segments", exporter.state.Seq-seq)
+	}
+}
+
+// Tests that the import loop of a follower can be stopped and started again, as
+// done when promoting a standby node fails.
+func TestFollowerRestart(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		primary, _ = newTestChain(t)
+		genDB      = rawdb.NewMemoryDatabase()
+	)
+	(&core.Genesis{Config: params.TestChainConfig}).MustCommit(genDB)
+	exporter, err := NewExporter(primary, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, _ := core.GenerateChain(params.TestChainConfig, primary.Genesis(), ethash.NewFaker(), genDB, 10, nil)
+	if _, err := primary.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Export(); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	replica, db := newTestChain(t)
+	source, _ := NewSource(dir)
+	follower := NewFollower(replica, db, source, nil)
+
+	follower.Start()
+	follower.Start() // no-op while running
+	follower.Stop()
+	follower.Stop() // no-op while stopped
+	follower.Start()
+	defer follower.Stop()
+
+	for i := 0; replica.CurrentBlock().NumberU64() != 10; i++ {
+		if i == 100 {
+			t.Fatalf("replica not synced, head %d", replica.CurrentBlock().NumberU64())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Tests that a stopped exporter does not write any further segments.
+func TestExporterStop(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		primary, _ = newTestChain(t)
+		genDB      = rawdb.NewMemoryDatabase()
+	)
+	(&core.Genesis{Config: params.TestChainConfig}).MustCommit(genDB)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, primary.Genesis(), ethash.NewFaker(), genDB, 2*maxSegmentBlocks, nil)
+	if _, err := primary.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	exporter, err := NewExporter(primary, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter.Stop()
+	if err := exporter.Export(); err != errExportStopped {
+		t.Fatalf("export error mismatch: have %v, want %v", err, errExportStopped)
+	}
+	if exporter.state.Seq != 0 {
+		t.Fatalf("stopped exporter wrote %d segments", exporter.state.Seq)
+	}
+}
+
+// Tests that sealed segments are only imported by replicas holding the key, and
+// that they can't be swapped.
+func TestSealedChangeLog(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		primary, _ = newTestChain(t)
+		genDB      = rawdb.NewMemoryDatabase()
+	)
+	(&core.Genesis{Config: params.TestChainConfig}).MustCommit(genDB)
+	aead, _ := encrypted.NewCipher(bytes.Repeat([]byte{0x01}, encrypted.KeySize))
+	other, _ := encrypted.NewCipher(bytes.Repeat([]byte{0x02}, encrypted.KeySize))
+
+	exporter, err := NewExporter(primary, dir, aead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, _ := core.GenerateChain(params.TestChainConfig, primary.Genesis(), ethash.NewFaker(), genDB, maxSegmentBlocks+10, nil)
+	if _, err := primary.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Export(); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	source, _ := NewSource(dir)
+	for _, key := range []cipher.AEAD{nil, other} {
+		replica, db := newTestChain(t)
+		if err := NewFollower(replica, db, source, key).Sync(context.Background()); err == nil {
+			t.Fatal("replica imported sealed segment without the key")
+		}
+		if head := replica.CurrentBlock().NumberU64(); head != 0 {
+			t.Fatalf("replica without the key imported blocks, head %d", head)
+		}
+	}
+	// Swapping two segments must be detected
+	first, second := filepath.Join(dir, segmentName(0)), filepath.Join(dir, segmentName(1))
+	blob0, _ := os.ReadFile(first)
+	blob1, _ := os.ReadFile(second)
+	os.WriteFile(first, blob1, 0644)
+	os.WriteFile(second, blob0, 0644)
+
+	replica, db := newTestChain(t)
+	if err := NewFollower(replica, db, source, aead).Sync(context.Background()); err == nil {
+		t.Fatal("replica imported swapped segment")
+	}
+	os.WriteFile(first, blob0, 0644)
+	os.WriteFile(second, blob1, 0644)
+
+	if err := NewFollower(replica, db, source, aead).Sync(context.Background()); err != nil {
+		t.Fatalf("failed to sync replica: %v", err)
+	}
+	if head := replica.CurrentBlock(); head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("replica head mismatch: have %d, want %d", head.NumberU64(), len(blocks))
+	}
+}
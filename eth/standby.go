@@ -0,0 +1,110 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errReadOnlyReplica is returned by operations modifying the chain of a replica,
+// which only imports the change-log of its primary.
+var errReadOnlyReplica = errors.New("read-only replica")
+
+// standby tracks a hot standby node, which follows the change-log of a primary
+// sharing its node key and etherbase, until it is promoted to take over.
+type standby struct {
+	startNetworking func() error // Enables the networking held back by the node
+
+	lock     sync.Mutex
+	promoted bool
+	mining   *int // Mining threads requested before the promotion, if any
+}
+
+// holdMining defers starting the miner with the given threads until the standby
+// is promoted, returning false if it already has been.
+func (sb *standby) holdMining(threads *int) bool {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	if sb.promoted {
+		return false
+	}
+	sb.mining = threads
+	return true
+}
+
+// Promote turns a hot standby node into the primary. It imports what is left of
+// the former primary's change-log, stops following it and starts peer-to-peer
+// networking with the shared node identity, followed by mining if requested.
+//
+// The former primary must have stopped producing blocks and publishing its
+// change-log, it is not fenced off by the promotion.
+func (s *Ethereum) Promote(ctx context.Context) error {
+	sb := s.standby
+	if sb == nil {
+		return errors.New("node is not a standby")
+	}
+	sb.lock.Lock()
+	if sb.promoted {
+		sb.lock.Unlock()
+		return errors.New("standby already promoted")
+	}
+	// Catch up with the primary, resuming to follow it if anything fails
+	s.follower.Stop()
+	if err := s.follower.Sync(ctx); err != nil {
+		s.follower.Start()
+		sb.lock.Unlock()
+		return fmt.Errorf("failed to import change-log: %v", err)
+	}
+	if err := sb.startNetworking(); err != nil {
+		s.follower.Start()
+		sb.lock.Unlock()
+		return fmt.Errorf("failed to enable networking: %v", err)
+	}
+	sb.promoted = true
+	threads := sb.mining
+	sb.lock.Unlock()
+
+	head := s.blockchain.CurrentBlock()
+	log.Info("Promoted standby node", "number", head.Number(), "hash", head.Hash())
+
+	if threads != nil {
+		return s.StartMining(*threads)
+	}
+	return nil
+}
+
+// readOnly reports whether the node is a read replica, whose chain may only be
+// modified by following its primary. Standby nodes stop being read only once
+// they are promoted.
+func (s *Ethereum) readOnly() bool {
+	if s.follower == nil {
+		return false
+	}
+	if s.standby == nil {
+		return true
+	}
+	s.standby.lock.Lock()
+	defer s.standby.lock.Unlock()
+
+	return !s.standby.promoted
+}
@@ -28,6 +28,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/metadata"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
@@ -883,6 +884,12 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	// Default tracer is the struct logger
 	tracer = logger.NewStructLogger(config.Config)
 	if config.Tracer != nil {
+		if db := api.backend.ChainDb(); rawdb.HasContractMetadata(db) {
+			txctx.ContractMetadata = func(hash common.Hash, code []byte) *metadata.Metadata {
+				meta, _ := metadata.Lookup(db, hash, code)
+				return meta
+			}
+		}
 		tracer, err = New(*config.Tracer, txctx, config.TracerConfig)
 		if err != nil {
 			return nil, err
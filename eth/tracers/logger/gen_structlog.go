@@ -29,6 +29,7 @@ func (s StructLog) MarshalJSON() ([]byte, error) {
 		Depth         int                         `json:"depth"`
 		RefundCounter uint64                      `json:"refund"`
 		Err           error                       `json:"-"`
+		Annotation    *Annotation                 `json:"annotation,omitempty"`
 		OpName        string                      `json:"opName"`
 		ErrorString   string                      `json:"error,omitempty"`
 	}
@@ -45,6 +46,7 @@ func (s StructLog) MarshalJSON() ([]byte, error) {
 	enc.Depth = s.Depth
 	enc.RefundCounter = s.RefundCounter
 	enc.Err = s.Err
+	enc.Annotation = s.Annotation
 	enc.OpName = s.OpName()
 	enc.ErrorString = s.ErrorString()
 	return json.Marshal(&enc)
@@ -65,6 +67,7 @@ func (s *StructLog) UnmarshalJSON(input []byte) error {
 		Depth         *int                        `json:"depth"`
 		RefundCounter *uint64                     `json:"refund"`
 		Err           error                       `json:"-"`
+		Annotation    *Annotation                 `json:"annotation,omitempty"`
 	}
 	var dec StructLog
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -106,5 +109,8 @@ func (s *StructLog) UnmarshalJSON(input []byte) error {
 	if dec.Err != nil {
 		s.Err = dec.Err
 	}
+	if dec.Annotation != nil {
+		s.Annotation = dec.Annotation
+	}
 	return nil
 }
@@ -29,8 +29,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/asm"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 )
@@ -55,6 +57,8 @@ type Config struct {
 	EnableReturnData bool // enable return data capture
 	Debug            bool // print output during capture end
 	Limit            int  // maximum length of output, but zero means unlimited
+	// Annotate logs with the basic block and dispatched function selector
+	EnableAnnotations bool
 	// Chain overrides, can be used to execute a trace using future fork rules
 	Overrides *params.ChainConfig `json:"overrides,omitempty"`
 }
@@ -76,6 +80,14 @@ type StructLog struct {
 	Depth         int                         `json:"depth"`
 	RefundCounter uint64                      `json:"refund"`
 	Err           error                       `json:"-"`
+	Annotation    *Annotation                 `json:"annotation,omitempty"`
+}
+
+// Annotation locates a logged instruction in the control flow structure of the
+// executing code.
+type Annotation struct {
+	Block    uint64 `json:"block"`              // Start PC of the enclosing basic block
+	Function string `json:"function,omitempty"` // Selector of the function entered via the dispatcher
 }
 
 // overrides for gencodec
@@ -117,6 +129,9 @@ type StructLogger struct {
 	gasLimit uint64
 	usedGas  uint64
 
+	programs  map[common.Hash]*asm.Program // Lifted code of the executed contracts
+	functions []string                     // Selector of the function executing in each call frame
+
 	interrupt uint32 // Atomic flag to signal execution interruption
 	reason    error  // Textual reason for the interruption
 }
@@ -137,12 +152,14 @@ func (l *StructLogger) Reset() {
 	l.storage = make(map[common.Address]Storage)
 	l.output = make([]byte, 0)
 	l.logs = l.logs[:0]
+	l.functions = l.functions[:0]
 	l.err = nil
 }
 
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
 func (l *StructLogger) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
 	l.env = env
+	l.functions = append(l.functions[:0], "")
 }
 
 // CaptureState logs a new structured log message and pushes it out to the environment
@@ -209,11 +226,45 @@ func (l *StructLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, s
 		rdata = make([]byte, len(rData))
 		copy(rdata, rData)
 	}
+	var annotation *Annotation
+	if l.cfg.EnableAnnotations {
+		annotation = l.annotate(pc, contract, depth)
+	}
 	// create a new snapshot of the EVM.
-	log := StructLog{pc, op, gas, cost, mem, memory.Len(), stck, rdata, storage, depth, l.env.StateDB.GetRefund(), err}
+	log := StructLog{pc, op, gas, cost, mem, memory.Len(), stck, rdata, storage, depth, l.env.StateDB.GetRefund(), err, annotation}
 	l.logs = append(l.logs, log)
 }
 
+// annotate resolves the basic block of the instruction and tracks the function
+// entered by the dispatcher of the current call frame.
+func (l *StructLogger) annotate(pc uint64, contract *vm.Contract, depth int) *Annotation {
+	hash := contract.CodeHash
+	if hash == (common.Hash{}) {
+		hash = crypto.Keccak256Hash(contract.Code)
+	}
+	prog := l.programs[hash]
+	if prog == nil {
+		if l.programs == nil {
+			l.programs = make(map[common.Hash]*asm.Program)
+		}
+		prog = asm.Lift(contract.Code)
+		l.programs[hash] = prog
+	}
+	// Frames are pushed and popped on call entry and exit, only fill the gaps
+	// if the tracer missed those.
+	for len(l.functions) < depth {
+		l.functions = append(l.functions, "")
+	}
+	if selector, ok := prog.Selectors[pc]; ok {
+		l.functions[depth-1] = hexutil.Encode(selector[:])
+	}
+	annotation := &Annotation{Block: pc, Function: l.functions[depth-1]}
+	if block := prog.Block(pc); block != nil {
+		annotation.Block = block.Start
+	}
+	return annotation
+}
+
 // CaptureFault implements the EVMLogger interface to trace an execution fault
 // while running an opcode.
 func (l *StructLogger) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
@@ -232,9 +283,13 @@ func (l *StructLogger) CaptureEnd(output []byte, gasUsed uint64, t time.Duration
 }
 
 func (l *StructLogger) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	l.functions = append(l.functions, "")
 }
 
 func (l *StructLogger) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if len(l.functions) > 0 {
+		l.functions = l.functions[:len(l.functions)-1]
+	}
 }
 
 func (l *StructLogger) GetResult() (json.RawMessage, error) {
@@ -423,6 +478,7 @@ type StructLogRes struct {
 	Memory        *[]string          `json:"memory,omitempty"`
 	Storage       *map[string]string `json:"storage,omitempty"`
 	RefundCounter uint64             `json:"refund,omitempty"`
+	Annotation    *Annotation        `json:"annotation,omitempty"`
 }
 
 // formatLogs formats EVM returned structured logs for json output
@@ -437,6 +493,7 @@ func formatLogs(logs []StructLog) []StructLogRes {
 			Depth:         trace.Depth,
 			Error:         trace.ErrorString(),
 			RefundCounter: trace.RefundCounter,
+			Annotation:    trace.Annotation,
 		}
 		if trace.Stack != nil {
 			stack := make([]string, len(trace.Stack))
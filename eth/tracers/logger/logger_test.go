@@ -75,6 +75,69 @@ func TestStoreCapture(t *testing.T) {
 	}
 }
 
+// Tests that annotated logs carry the basic block and the function selector the
+// dispatcher jumped to.
+func TestAnnotationCapture(t *testing.T) {
+	var (
+		logger   = NewStructLogger(&Config{EnableAnnotations: true})
+		env      = vm.NewEVM(vm.BlockContext{BlockNumber: big.NewInt(1)}, vm.TxContext{}, &dummyStatedb{}, params.TestChainConfig, vm.Config{Debug: true, Tracer: logger})
+		contract = vm.NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 100000)
+	)
+	// PUSH1 0, CALLDATALOAD, PUSH1 0xe0, SHR, DUP1, PUSH4 0xaabbccdd, EQ,
+	// PUSH1 0x12, JUMPI, DUP1, REVERT, JUMPDEST, STOP
+	contract.Code = common.FromHex("0x60003560e01c8063aabbccdd14601257" + "80fd5b00")
+	logger.CaptureStart(env, common.Address{}, contract.Address(), false, nil, 0, nil)
+	if _, err := env.Interpreter().Run(contract, common.FromHex("0xaabbccdd"), false); err != nil {
+		t.Fatal(err)
+	}
+	logs := logger.StructLogs()
+	if len(logs) != 11 {
+		t.Fatalf("log count mismatch: have %d, want 11", len(logs))
+	}
+	for i, log := range logs {
+		want := Annotation{Block: 0}
+		if log.Pc >= 18 {
+			want = Annotation{Block: 18, Function: "0xaabbccdd"}
+		}
+		if log.Annotation == nil || *log.Annotation != want {
+			t.Errorf("log %d (pc %d): annotation mismatch: have %+v, want %+v", i, log.Pc, log.Annotation, want)
+		}
+	}
+}
+
+// Tests that the function of a call frame doesn't leak into a later call at the
+// same depth, nor the function of a returned call into its caller.
+func TestAnnotationCallFrames(t *testing.T) {
+	var (
+		logger   = NewStructLogger(&Config{EnableAnnotations: true})
+		env      = vm.NewEVM(vm.BlockContext{BlockNumber: big.NewInt(1)}, vm.TxContext{}, &dummyStatedb{}, params.TestChainConfig, vm.Config{Debug: true, Tracer: logger})
+		dispatch = vm.NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 100000)
+		plain    = vm.NewContract(&dummyContractRef{}, &dummyContractRef{}, new(big.Int), 100000)
+	)
+	// Same dispatcher as above, jumping to the function at pc 18
+	dispatch.Code = common.FromHex("0x60003560e01c8063aabbccdd14601257" + "80fd5b00")
+	plain.Code = []byte{byte(vm.STOP)}
+
+	check := func(pc uint64, contract *vm.Contract, depth int, want string) {
+		t.Helper()
+		if have := logger.annotate(pc, contract, depth).Function; have != want {
+			t.Errorf("depth %d, pc %d: function mismatch: have %q, want %q", depth, pc, have, want)
+		}
+	}
+	logger.CaptureStart(env, common.Address{}, dispatch.Address(), false, nil, 0, nil)
+	check(18, dispatch, 1, "0xaabbccdd")
+
+	logger.CaptureEnter(vm.CALL, common.Address{}, common.Address{}, nil, 0, nil)
+	check(18, dispatch, 2, "0xaabbccdd")
+	logger.CaptureExit(nil, 0, nil)
+
+	logger.CaptureEnter(vm.CALL, common.Address{}, common.Address{}, nil, 0, nil)
+	check(0, plain, 2, "")
+	logger.CaptureExit(nil, 0, nil)
+
+	check(19, dispatch, 1, "0xaabbccdd")
+}
+
 // Tests that blank fields don't appear in logs when JSON marshalled, to reduce
 // logs bloat and confusion. See https://github.com/ethereum/go-ethereum/issues/24487
 func TestStructLogMarshalingOmitEmpty(t *testing.T) {
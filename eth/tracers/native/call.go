@@ -25,6 +25,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/metadata"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers"
@@ -35,16 +36,18 @@ func init() {
 }
 
 type callFrame struct {
-	Type    string      `json:"type"`
-	From    string      `json:"from"`
-	To      string      `json:"to,omitempty"`
-	Value   string      `json:"value,omitempty"`
-	Gas     string      `json:"gas"`
-	GasUsed string      `json:"gasUsed"`
-	Input   string      `json:"input"`
-	Output  string      `json:"output,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Calls   []callFrame `json:"calls,omitempty"`
+	Type     string      `json:"type"`
+	From     string      `json:"from"`
+	To       string      `json:"to,omitempty"`
+	Value    string      `json:"value,omitempty"`
+	Gas      string      `json:"gas"`
+	GasUsed  string      `json:"gasUsed"`
+	Input    string      `json:"input"`
+	Output   string      `json:"output,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Contract string      `json:"contract,omitempty"` // Name of the called contract, if verified
+	Method   string      `json:"method,omitempty"`   // Signature of the called method, if verified
+	Calls    []callFrame `json:"calls,omitempty"`
 }
 
 type callTracer struct {
@@ -53,6 +56,9 @@ type callTracer struct {
 	config    callTracerConfig
 	interrupt uint32 // Atomic flag to signal execution interruption
 	reason    error  // Textual reason for the interruption
+
+	metadata  func(common.Hash, []byte) *metadata.Metadata // Contract metadata lookup, nil if unavailable
+	contracts map[common.Hash]*metadata.Metadata           // Metadata of the called code, nil if unverified
 }
 
 type callTracerConfig struct {
@@ -70,7 +76,12 @@ func newCallTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, e
 	}
 	// First callframe contains tx context info
 	// and is populated on start and end.
-	return &callTracer{callstack: make([]callFrame, 1), config: config}, nil
+	t := &callTracer{callstack: make([]callFrame, 1), config: config}
+	if ctx != nil && ctx.ContractMetadata != nil {
+		t.metadata = ctx.ContractMetadata
+		t.contracts = make(map[common.Hash]*metadata.Metadata)
+	}
+	return t, nil
 }
 
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
@@ -86,6 +97,8 @@ func (t *callTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Ad
 	}
 	if create {
 		t.callstack[0].Type = "CREATE"
+	} else {
+		t.annotate(&t.callstack[0], to, input)
 	}
 }
 
@@ -129,9 +142,36 @@ func (t *callTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.
 		Gas:   uintToHex(gas),
 		Value: bigToHex(value),
 	}
+	switch typ {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.annotate(&call, to, input)
+	}
 	t.callstack = append(t.callstack, call)
 }
 
+// annotate names the contract and method called by a frame, if the metadata of
+// the executed code is available.
+func (t *callTracer) annotate(call *callFrame, to common.Address, input []byte) {
+	if t.metadata == nil {
+		return
+	}
+	hash := t.env.StateDB.GetCodeHash(to)
+	meta, ok := t.contracts[hash]
+	if !ok {
+		if code := t.env.StateDB.GetCode(to); len(code) > 0 {
+			meta = t.metadata(hash, code)
+		}
+		t.contracts[hash] = meta
+	}
+	if meta == nil {
+		return
+	}
+	call.Contract = meta.Name
+	if method := meta.Method(input); method != nil {
+		call.Method = method.Sig
+	}
+}
+
 // CaptureExit is called when EVM exits a scope, even if the scope didn't
 // execute any code.
 func (t *callTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
@@ -0,0 +1,116 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package plugins loads tracers from Go plugins. It's kept apart from the tracers
+// package, as importing the plugin package requires cgo and disables dead code
+// elimination in the linker for the whole binary.
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// Symbol is the name of the variable through which a tracer plugin
+// provides its tracers. Plugins are Go packages built with -buildmode=plugin,
+// declaring the tracer constructors by name:
+//
+//	var Tracers = map[string]func(*tracers.Context, json.RawMessage) (tracers.Tracer, error){
+//		"myTracer": newMyTracer,
+//	}
+//
+// The constructors have the same signature as the native tracers, and the
+// tracers get the same hooks. Plugins must be built with the same Go version
+// and go-ethereum sources as the node loading them.
+const Symbol = "Tracers"
+
+// pluginCtor is the constructor signature of plugin tracers.
+type pluginCtor = func(*tracers.Context, json.RawMessage) (tracers.Tracer, error)
+
+var (
+	pluginLock  sync.RWMutex
+	pluginCtors map[string]pluginCtor
+)
+
+// Load loads the tracers of a plugin, making them available by name like the
+// built-in tracers. Plugin tracers take precedence over built-in ones of the same
+// name. It returns the names of the loaded tracers.
+func Load(path string) ([]string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".so":
+	case ".wasm":
+		return nil, errors.New("WASM tracer plugins are not supported, build the tracer as a Go plugin (.so)")
+	default:
+		return nil, fmt.Errorf("unknown tracer plugin type %q", filepath.Ext(path))
+	}
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(Symbol)
+	if err != nil {
+		return nil, err
+	}
+	ctors, ok := sym.(*map[string]func(*tracers.Context, json.RawMessage) (tracers.Tracer, error))
+	if !ok {
+		return nil, fmt.Errorf("plugin symbol %s has invalid type %T", Symbol, sym)
+	}
+	return registerPlugin(*ctors)
+}
+
+// registerPlugin adds the tracers of a plugin, failing if any of them has been
+// added by a previous plugin.
+func registerPlugin(ctors map[string]pluginCtor) ([]string, error) {
+	pluginLock.Lock()
+	defer pluginLock.Unlock()
+
+	names := make([]string, 0, len(ctors))
+	for name := range ctors {
+		if _, ok := pluginCtors[name]; ok {
+			return nil, fmt.Errorf("tracer %q already loaded from another plugin", name)
+		}
+		names = append(names, name)
+	}
+	if pluginCtors == nil {
+		pluginCtors = make(map[string]pluginCtor)
+		tracers.RegisterLookup(false, lookupPlugin)
+	}
+	for name, ctor := range ctors {
+		pluginCtors[name] = ctor
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// lookupPlugin returns a tracer of a loaded plugin, if one has the given name.
+func lookupPlugin(name string, ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	pluginLock.RLock()
+	ctor, ok := pluginCtors[name]
+	pluginLock.RUnlock()
+
+	if !ok {
+		return nil, errors.New("no tracer found")
+	}
+	return ctor(ctx, cfg)
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package plugins
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// pluginTracer is a tracer counting the opcodes executed.
+type pluginTracer struct {
+	steps int
+}
+
+func (t *pluginTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+func (t *pluginTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {}
+func (t *pluginTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.steps++
+}
+func (t *pluginTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+func (t *pluginTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+func (t *pluginTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (t *pluginTracer) CaptureTxStart(gasLimit uint64)                       {}
+func (t *pluginTracer) CaptureTxEnd(restGas uint64)                          {}
+func (t *pluginTracer) GetResult() (json.RawMessage, error)                  { return json.Marshal(t.steps) }
+func (t *pluginTracer) Stop(err error)                                       {}
+
+func TestPluginTracers(t *testing.T) {
+	ctor := func(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+		return new(pluginTracer), nil
+	}
+	names, err := registerPlugin(map[string]pluginCtor{"testPluginB": ctor, "testPluginA": ctor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "testPluginA" || names[1] != "testPluginB" {
+		t.Fatalf("wrong tracer names: %v", names)
+	}
+	tracer, err := tracers.New("testPluginA", new(tracers.Context), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tracer.(*pluginTracer); !ok {
+		t.Fatalf("wrong tracer type %T", tracer)
+	}
+	// Tracers can't be loaded twice
+	if _, err := registerPlugin(map[string]pluginCtor{"testPluginB": ctor}); err == nil {
+		t.Fatal("duplicate tracer loaded")
+	}
+}
+
+func TestLoadPluginErrors(t *testing.T) {
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"tracer.wasm", "WASM tracer plugins are not supported"},
+		{"tracer.js", "unknown tracer plugin type"},
+		{"missing.so", "missing.so"},
+	}
+	for _, test := range tests {
+		_, err := Load(test.path)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: wrong error: %v", test.path, err)
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/metadata"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/vm"
 )
@@ -31,6 +32,10 @@ type Context struct {
 	BlockHash common.Hash // Hash of the block the tx is contained within (zero if dangling tx or call)
 	TxIndex   int         // Index of the transaction within a block (zero if dangling tx or call)
 	TxHash    common.Hash // Hash of the transaction being traced (zero if dangling call)
+
+	// ContractMetadata retrieves the metadata of a verified contract by its code
+	// hash and code, nil if no contract metadata is available.
+	ContractMetadata func(hash common.Hash, code []byte) *metadata.Metadata
 }
 
 // Tracer interface extends vm.EVMLogger and additionally
@@ -0,0 +1,127 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// TxPropagation is the audit trail of a transaction passing through the node.
+type TxPropagation struct {
+	Hash      common.Hash `json:"hash"`
+	Arrival   *TxArrival  `json:"arrival"`   // First delivery from a peer, nil if not received from the network
+	Forwarded []TxForward `json:"forwarded"` // Peers the transaction was propagated to
+}
+
+// TxArrival records the first delivery of a transaction by a peer.
+type TxArrival struct {
+	Peer      string    `json:"peer"`
+	Time      time.Time `json:"time"`
+	Requested bool      `json:"requested"` // Whether the transaction was requested after an announcement
+}
+
+// TxForward records the propagation of a transaction to a peer.
+type TxForward struct {
+	Peer      string    `json:"peer"`
+	Time      time.Time `json:"time"`
+	Announced bool      `json:"announced"` // Whether only the hash was announced
+}
+
+// txAudit keeps the propagation audit trail of the most recently seen transactions.
+// All methods are no-ops on a nil audit, which is used if auditing is disabled.
+type txAudit struct {
+	records *lru.Cache // Transaction hash -> *TxPropagation
+	lock    sync.Mutex // Protects the records while being modified
+}
+
+// newTxAudit creates an audit trail retaining up to limit transactions, or nil
+// if limit is zero.
+func newTxAudit(limit int) *txAudit {
+	if limit <= 0 {
+		return nil
+	}
+	records, _ := lru.New(limit)
+	return &txAudit{records: records}
+}
+
+// record returns the audit record of a transaction, creating it if needed. The
+// lock must be held.
+func (a *txAudit) record(hash common.Hash) *TxPropagation {
+	if r, ok := a.records.Get(hash); ok {
+		return r.(*TxPropagation)
+	}
+	r := &TxPropagation{Hash: hash}
+	a.records.Add(hash, r)
+	return r
+}
+
+// arrived records the delivery of transactions by a peer.
+func (a *txAudit) arrived(peer string, txs []*types.Transaction, requested bool) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := time.Now()
+	for _, tx := range txs {
+		if r := a.record(tx.Hash()); r.Arrival == nil {
+			r.Arrival = &TxArrival{Peer: peer, Time: now, Requested: requested}
+		}
+	}
+}
+
+// forwarded records the propagation of transactions to a peer.
+func (a *txAudit) forwarded(peer string, hashes []common.Hash, announced bool) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := time.Now()
+	for _, hash := range hashes {
+		r := a.record(hash)
+		r.Forwarded = append(r.Forwarded, TxForward{Peer: peer, Time: now, Announced: announced})
+	}
+}
+
+// propagation returns a copy of the audit record of a transaction.
+func (a *txAudit) propagation(hash common.Hash) *TxPropagation {
+	if a == nil {
+		return nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	r, ok := a.records.Peek(hash)
+	if !ok {
+		return nil
+	}
+	cpy := *r.(*TxPropagation)
+	if cpy.Arrival != nil {
+		arrival := *cpy.Arrival
+		cpy.Arrival = &arrival
+	}
+	cpy.Forwarded = append([]TxForward{}, cpy.Forwarded...)
+	return &cpy
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestTxAudit(t *testing.T) {
+	// A disabled audit should silently ignore everything
+	disabled := newTxAudit(0)
+	disabled.arrived("a", nil, false)
+	if disabled.propagation(common.Hash{}) != nil {
+		t.Fatal("disabled audit returned a record")
+	}
+	audit := newTxAudit(2)
+
+	txs := []*types.Transaction{
+		types.NewTransaction(0, common.Address{}, nil, 0, nil, nil),
+		types.NewTransaction(1, common.Address{}, nil, 0, nil, nil),
+		types.NewTransaction(2, common.Address{}, nil, 0, nil, nil),
+	}
+	audit.arrived("a", txs[:1], false)
+	audit.arrived("b", txs[:1], true)
+	audit.forwarded("c", []common.Hash{txs[0].Hash()}, false)
+	audit.forwarded("d", []common.Hash{txs[0].Hash()}, true)
+
+	r := audit.propagation(txs[0].Hash())
+	if r == nil || r.Arrival == nil {
+		t.Fatal("missing propagation record")
+	}
+	if r.Arrival.Peer != "a" || r.Arrival.Requested {
+		t.Errorf("arrival mismatch: have %v, want first delivery from a", r.Arrival)
+	}
+	if len(r.Forwarded) != 2 || r.Forwarded[0].Peer != "c" || r.Forwarded[0].Announced || r.Forwarded[1].Peer != "d" || !r.Forwarded[1].Announced {
+		t.Errorf("forwards mismatch: %v", r.Forwarded)
+	}
+	// Exceeding the limit should evict the oldest record
+	audit.arrived("a", txs[1:], false)
+	if audit.propagation(txs[0].Hash()) != nil {
+		t.Error("record not evicted")
+	}
+	if audit.propagation(txs[2].Hash()) == nil {
+		t.Error("record missing")
+	}
+}
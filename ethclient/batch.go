@@ -0,0 +1,276 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultBatchSize is the maximum number of calls sent in a single JSON-RPC
+// batch, unless changed via Batch.SetMaxSize.
+const DefaultBatchSize = 100
+
+// errBatchNotSent is reported by the results of a batch that was not executed.
+var errBatchNotSent = errors.New("batch not sent")
+
+// Batch collects calls of arbitrary kinds and sends them as JSON-RPC batches.
+// Every queued call returns a typed result, which is filled in once the batch
+// has been executed.
+//
+// A Batch is not safe for concurrent use and can only be executed once.
+type Batch struct {
+	client   *Client
+	size     int
+	elems    []rpc.BatchElem
+	decoders []func(error) error // Post-processing of the raw results, one per call
+}
+
+// NewBatch creates an empty batch of calls.
+func (ec *Client) NewBatch() *Batch {
+	return &Batch{client: ec, size: DefaultBatchSize}
+}
+
+// SetMaxSize sets the maximum number of calls sent in a single JSON-RPC batch.
+// Larger batches are split up.
+func (b *Batch) SetMaxSize(size int) *Batch {
+	if size > 0 {
+		b.size = size
+	}
+	return b
+}
+
+// Len returns the number of queued calls.
+func (b *Batch) Len() int {
+	return len(b.elems)
+}
+
+// BigResult is the result of a batched call returning a number.
+type BigResult struct {
+	Value *big.Int
+	Err   error
+}
+
+// BytesResult is the result of a batched call returning binary data.
+type BytesResult struct {
+	Value []byte
+	Err   error
+}
+
+// Uint64Result is the result of a batched call returning a quantity.
+type Uint64Result struct {
+	Value uint64
+	Err   error
+}
+
+// HeaderResult is the result of a batched header retrieval.
+type HeaderResult struct {
+	Value *types.Header
+	Err   error
+}
+
+// ReceiptResult is the result of a batched receipt retrieval.
+type ReceiptResult struct {
+	Value *types.Receipt
+	Err   error
+}
+
+// RawResult is the result of an arbitrary batched call, decoded by the caller.
+type RawResult struct {
+	Value json.RawMessage
+	Err   error
+}
+
+// Decode unmarshals the raw result into v.
+func (r *RawResult) Decode(v interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	return json.Unmarshal(r.Value, v)
+}
+
+// add queues a call, invoking decode with the call's error once it completes.
+// The decoder returns the final error of the call.
+func (b *Batch) add(result interface{}, decode func(error) error, method string, args ...interface{}) {
+	b.elems = append(b.elems, rpc.BatchElem{Method: method, Args: args, Result: result, Error: errBatchNotSent})
+	b.decoders = append(b.decoders, decode)
+}
+
+// BalanceAt queues the retrieval of the wei balance of the given account. The
+// block number can be nil, in which case the balance is taken from the latest
+// known block.
+func (b *Batch) BalanceAt(account common.Address, blockNumber *big.Int) *BigResult {
+	var (
+		res = new(BigResult)
+		raw hexutil.Big
+	)
+	b.add(&raw, func(err error) error {
+		res.Value, res.Err = (*big.Int)(&raw), err
+		return err
+	}, "eth_getBalance", account, toBlockNumArg(blockNumber))
+	return res
+}
+
+// StorageAt queues the retrieval of the value of key in the contract storage of
+// the given account.
+func (b *Batch) StorageAt(account common.Address, key common.Hash, blockNumber *big.Int) *BytesResult {
+	return b.bytesCall("eth_getStorageAt", account, key, toBlockNumArg(blockNumber))
+}
+
+// CodeAt queues the retrieval of the contract code of the given account.
+func (b *Batch) CodeAt(account common.Address, blockNumber *big.Int) *BytesResult {
+	return b.bytesCall("eth_getCode", account, toBlockNumArg(blockNumber))
+}
+
+// NonceAt queues the retrieval of the account nonce of the given account.
+func (b *Batch) NonceAt(account common.Address, blockNumber *big.Int) *Uint64Result {
+	var (
+		res = new(Uint64Result)
+		raw hexutil.Uint64
+	)
+	b.add(&raw, func(err error) error {
+		res.Value, res.Err = uint64(raw), err
+		return err
+	}, "eth_getTransactionCount", account, toBlockNumArg(blockNumber))
+	return res
+}
+
+// CallContract queues a message call executed in the state of the given block.
+func (b *Batch) CallContract(msg ethereum.CallMsg, blockNumber *big.Int) *BytesResult {
+	return b.bytesCall("eth_call", toCallArg(msg), toBlockNumArg(blockNumber))
+}
+
+// HeaderByNumber queues the retrieval of a block header. If number is nil, the
+// latest known header is returned.
+func (b *Batch) HeaderByNumber(number *big.Int) *HeaderResult {
+	var (
+		res = new(HeaderResult)
+		raw *types.Header
+	)
+	b.add(&raw, func(err error) error {
+		if err == nil && raw == nil {
+			err = ethereum.NotFound
+		}
+		res.Value, res.Err = raw, err
+		return err
+	}, "eth_getBlockByNumber", toBlockNumArg(number), false)
+	return res
+}
+
+// TransactionReceipt queues the retrieval of the receipt of a transaction.
+func (b *Batch) TransactionReceipt(txHash common.Hash) *ReceiptResult {
+	var (
+		res = new(ReceiptResult)
+		raw *types.Receipt
+	)
+	b.add(&raw, func(err error) error {
+		if err == nil && raw == nil {
+			err = ethereum.NotFound
+		}
+		res.Value, res.Err = raw, err
+		return err
+	}, "eth_getTransactionReceipt", txHash)
+	return res
+}
+
+// Call queues an arbitrary call, leaving the decoding of its result to the
+// caller.
+func (b *Batch) Call(method string, args ...interface{}) *RawResult {
+	res := new(RawResult)
+	b.add(&res.Value, func(err error) error {
+		res.Err = err
+		return err
+	}, method, args...)
+	return res
+}
+
+// bytesCall queues a call returning hex encoded binary data.
+func (b *Batch) bytesCall(method string, args ...interface{}) *BytesResult {
+	var (
+		res = new(BytesResult)
+		raw hexutil.Bytes
+	)
+	b.add(&raw, func(err error) error {
+		res.Value, res.Err = raw, err
+		return err
+	}, method, args...)
+	return res
+}
+
+// BatchError is returned by Batch.Execute if some of the calls failed while the
+// batch as a whole was delivered. The individual errors are also available in
+// the results of the failed calls.
+type BatchError struct {
+	Failed map[int]error // Errors of the failed calls, keyed by their index in the batch
+}
+
+// Error implements error.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d batched calls failed", len(e.Failed))
+}
+
+// Execute sends the queued calls, split into batches of the maximum size, and
+// fills in their results. The batches are sent through the client, so they are
+// retried as a whole according to the failover config, if any.
+//
+// A non-nil error either reports a batch that could not be sent, in which case
+// the results of all calls not delivered carry that error, or is a *BatchError
+// listing the calls failing individually.
+func (b *Batch) Execute(ctx context.Context) error {
+	var sendErr error
+	for start := 0; start < len(b.elems) && sendErr == nil; start += b.size {
+		end := start + b.size
+		if end > len(b.elems) {
+			end = len(b.elems)
+		}
+		chunk := b.elems[start:end]
+		for i := range chunk {
+			chunk[i].Error = nil
+		}
+		if err := b.client.batchCallContext(ctx, chunk); err != nil {
+			for i := range chunk {
+				chunk[i].Error = err
+			}
+			sendErr = err
+		}
+	}
+	failed := make(map[int]error)
+	for i, elem := range b.elems {
+		if elem.Error == errBatchNotSent && sendErr != nil {
+			elem.Error = sendErr
+		}
+		if err := b.decoders[i](elem.Error); err != nil && sendErr == nil {
+			failed[i] = err
+		}
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	if len(failed) > 0 {
+		return &BatchError{Failed: failed}
+	}
+	return nil
+}
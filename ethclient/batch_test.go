@@ -0,0 +1,93 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func testBatch(t *testing.T, client *rpc.Client) {
+	ec := NewClient(client)
+
+	batch := ec.NewBatch().SetMaxSize(2)
+	var (
+		balance = batch.BalanceAt(testAddr, common.Big0)
+		nonce   = batch.NonceAt(testAddr, common.Big0)
+		code    = batch.CodeAt(testAddr, nil)
+		header  = batch.HeaderByNumber(big.NewInt(1))
+		missing = batch.HeaderByNumber(big.NewInt(1000))
+		receipt = batch.TransactionReceipt(common.Hash{})
+		chainID = batch.Call("eth_chainId")
+		bad     = batch.Call("eth_nonexistent")
+	)
+	err := batch.Execute(context.Background())
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected batch error, got %v", err)
+	}
+	if len(batchErr.Failed) != 3 || batchErr.Failed[4] == nil || batchErr.Failed[5] == nil || batchErr.Failed[7] == nil {
+		t.Fatalf("failed calls mismatch: %v", batchErr.Failed)
+	}
+	if balance.Err != nil || balance.Value.Cmp(testBalance) != 0 {
+		t.Errorf("balance mismatch: have %v (%v), want %v", balance.Value, balance.Err, testBalance)
+	}
+	if nonce.Err != nil || nonce.Value != 0 {
+		t.Errorf("nonce mismatch: have %d (%v), want 0", nonce.Value, nonce.Err)
+	}
+	if code.Err != nil || len(code.Value) != 0 {
+		t.Errorf("code mismatch: have %x (%v), want empty", code.Value, code.Err)
+	}
+	if header.Err != nil || header.Value.Number.Uint64() != 1 {
+		t.Errorf("header mismatch: have %v (%v), want block 1", header.Value, header.Err)
+	}
+	if !errors.Is(missing.Err, ethereum.NotFound) {
+		t.Errorf("missing header error mismatch: have %v, want %v", missing.Err, ethereum.NotFound)
+	}
+	if !errors.Is(receipt.Err, ethereum.NotFound) {
+		t.Errorf("missing receipt error mismatch: have %v, want %v", receipt.Err, ethereum.NotFound)
+	}
+	var id hexutil.Big
+	if err := chainID.Decode(&id); err != nil || id.ToInt().Cmp(genesis.Config.ChainID) != 0 {
+		t.Errorf("chain id mismatch: have %v (%v), want %v", id.ToInt(), err, genesis.Config.ChainID)
+	}
+	if bad.Err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
+
+func TestBatchNotSent(t *testing.T) {
+	client := rpc.DialInProc(rpc.NewServer())
+	client.Close()
+
+	batch := NewClient(client).NewBatch()
+	balance := batch.BalanceAt(common.Address{}, nil)
+	if err := batch.Execute(context.Background()); err == nil {
+		t.Fatal("expected error from closed client")
+	}
+	if balance.Err == nil {
+		t.Error("expected error in result of undelivered call")
+	}
+}
@@ -294,6 +294,9 @@ func TestEthClient(t *testing.T) {
 		"TransactionSender": {
 			func(t *testing.T) { testTransactionSender(t, client) },
 		},
+		"Batch": {
+			func(t *testing.T) { testBatch(t, client) },
+		},
 	}
 
 	t.Parallel()
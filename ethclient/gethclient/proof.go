@@ -0,0 +1,148 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// Verify checks the account and storage proofs of a GetProof result against a
+// trusted state root, e.g. the root of a header validated by the caller, and the
+// account and storage keys which were requested. If it succeeds, the account
+// fields and storage values of the result are proven to be part of that state,
+// so they can be used even if the endpoint providing them is not trusted.
+func (r *AccountResult) Verify(stateRoot common.Hash, account common.Address, keys []string) error {
+	// The proofs must be of the requested account and slots, not of whatever
+	// the endpoint chose to prove instead.
+	if r.Address != account {
+		return fmt.Errorf("account mismatch: have %x, requested %x", r.Address, account)
+	}
+	if len(r.StorageProof) != len(keys) {
+		return fmt.Errorf("storage proof count mismatch: have %d, requested %d", len(r.StorageProof), len(keys))
+	}
+	for i, slot := range r.StorageProof {
+		if common.HexToHash(slot.Key) != common.HexToHash(keys[i]) {
+			return fmt.Errorf("storage key mismatch: have %s, requested %s", slot.Key, keys[i])
+		}
+	}
+	db, err := proofDB(r.AccountProof)
+	if err != nil {
+		return fmt.Errorf("invalid account proof: %v", err)
+	}
+	value, err := trie.VerifyProof(stateRoot, crypto.Keccak256(account[:]), db)
+	if err != nil {
+		return fmt.Errorf("invalid account proof: %v", err)
+	}
+	var state types.StateAccount
+	if value == nil {
+		// The account does not exist, it must be reported as empty. Endpoints
+		// may return either the zero hash or the hash of the empty code/trie.
+		if r.CodeHash != (common.Hash{}) && r.CodeHash != emptyCodeHash {
+			return fmt.Errorf("code hash mismatch for missing account: %x", r.CodeHash)
+		}
+		if r.StorageHash != (common.Hash{}) && r.StorageHash != types.EmptyRootHash {
+			return fmt.Errorf("storage hash mismatch for missing account: %x", r.StorageHash)
+		}
+		state = types.StateAccount{Balance: new(big.Int), Root: types.EmptyRootHash, CodeHash: emptyCodeHash[:]}
+	} else {
+		if err := rlp.DecodeBytes(value, &state); err != nil {
+			return fmt.Errorf("invalid account in proof: %v", err)
+		}
+		if r.CodeHash != common.BytesToHash(state.CodeHash) {
+			return fmt.Errorf("code hash mismatch: have %x, proven %x", r.CodeHash, state.CodeHash)
+		}
+		if r.StorageHash != state.Root {
+			return fmt.Errorf("storage hash mismatch: have %x, proven %x", r.StorageHash, state.Root)
+		}
+	}
+	if r.Nonce != state.Nonce {
+		return fmt.Errorf("nonce mismatch: have %d, proven %d", r.Nonce, state.Nonce)
+	}
+	if bigOrZero(r.Balance).Cmp(state.Balance) != 0 {
+		return fmt.Errorf("balance mismatch: have %v, proven %v", r.Balance, state.Balance)
+	}
+	for i, slot := range r.StorageProof {
+		if err := slot.verify(state.Root, common.HexToHash(keys[i])); err != nil {
+			return fmt.Errorf("storage slot %s: %v", slot.Key, err)
+		}
+	}
+	return nil
+}
+
+// verify checks the storage proof of the given slot against the storage root of
+// the account.
+func (r *StorageResult) verify(storageRoot common.Hash, key common.Hash) error {
+	if storageRoot == types.EmptyRootHash {
+		// Nothing to prove in an empty storage, all slots are zero
+		if bigOrZero(r.Value).Sign() != 0 {
+			return fmt.Errorf("value mismatch: have %v, proven 0", r.Value)
+		}
+		return nil
+	}
+	db, err := proofDB(r.Proof)
+	if err != nil {
+		return fmt.Errorf("invalid proof: %v", err)
+	}
+	value, err := trie.VerifyProof(storageRoot, crypto.Keccak256(key[:]), db)
+	if err != nil {
+		return fmt.Errorf("invalid proof: %v", err)
+	}
+	proven := new(big.Int)
+	if value != nil {
+		_, content, _, err := rlp.Split(value)
+		if err != nil {
+			return fmt.Errorf("invalid value in proof: %v", err)
+		}
+		proven.SetBytes(content)
+	}
+	if bigOrZero(r.Value).Cmp(proven) != 0 {
+		return fmt.Errorf("value mismatch: have %v, proven %v", r.Value, proven)
+	}
+	return nil
+}
+
+// proofDB collects the hex encoded trie nodes of a proof into a database keyed
+// by their hashes.
+func proofDB(proof []string) (*memorydb.Database, error) {
+	db := memorydb.New()
+	for _, encoded := range proof {
+		node, err := hexutil.Decode(encoded)
+		if err != nil {
+			return nil, err
+		}
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db, nil
+}
+
+func bigOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
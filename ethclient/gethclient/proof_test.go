@@ -0,0 +1,82 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func testVerifyProof(t *testing.T, client *rpc.Client) {
+	ec := New(client)
+	head, err := ethclient.NewClient(client).HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getProof := func(account common.Address, keys ...string) *AccountResult {
+		result, err := ec.GetProof(context.Background(), account, keys, head.Number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	var (
+		missing = common.Address{0xff}
+		keys    = []string{testSlot.String(), "0x01"}
+	)
+	// Valid proofs of existing and missing accounts and slots
+	if err := getProof(testAddr, keys...).Verify(head.Root, testAddr, keys); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	if err := getProof(missing, "0x01").Verify(head.Root, missing, []string{"0x01"}); err != nil {
+		t.Fatalf("valid proof of missing account rejected: %v", err)
+	}
+	// Proofs against another root or with forged values must fail
+	if err := getProof(testAddr).Verify(common.Hash{0x01}, testAddr, nil); err == nil {
+		t.Error("proof verified against wrong root")
+	}
+	result := getProof(testAddr, testSlot.String())
+	result.Balance = new(big.Int).Add(result.Balance, big.NewInt(1))
+	if err := result.Verify(head.Root, testAddr, keys[:1]); err == nil {
+		t.Error("forged balance verified")
+	}
+	result = getProof(testAddr, testSlot.String())
+	result.StorageProof[0].Value = big.NewInt(1)
+	if err := result.Verify(head.Root, testAddr, keys[:1]); err == nil {
+		t.Error("forged storage value verified")
+	}
+	result = getProof(missing)
+	result.Nonce = 1
+	if err := result.Verify(head.Root, missing, nil); err == nil {
+		t.Error("forged nonce of missing account verified")
+	}
+	// Valid proofs of other accounts or slots than requested must fail
+	if err := getProof(missing).Verify(head.Root, testAddr, nil); err == nil {
+		t.Error("proof of another account verified")
+	}
+	if err := getProof(testAddr, "0x01").Verify(head.Root, testAddr, keys[:1]); err == nil {
+		t.Error("proof of another slot verified")
+	}
+	if err := getProof(testAddr, keys...).Verify(head.Root, testAddr, keys[:1]); err == nil {
+		t.Error("proof with extra slots verified")
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BufferPolicy decides how a multiplexed subscription behaves when its
+// subscriber does not keep up with the notifications.
+type BufferPolicy int
+
+const (
+	// BufferBlock stops receiving notifications while the buffer is full. If the
+	// connection level queue overflows in the meantime, the subscription is
+	// re-established, losing the notifications in between.
+	BufferBlock BufferPolicy = iota
+
+	// BufferDropOldest discards the oldest buffered notification to make room
+	// for a new one.
+	BufferDropOldest
+
+	// BufferDropNewest discards new notifications while the buffer is full.
+	BufferDropNewest
+)
+
+// SubscriptionBuffer configures the buffering of a multiplexed subscription.
+type SubscriptionBuffer struct {
+	Size   int // Number of notifications buffered for the subscriber (0 = 1024)
+	Policy BufferPolicy
+}
+
+// SubscriptionMux multiplexes any number of subscriptions over a single
+// connection. Subscriptions dropped by the endpoint or lost together with the
+// connection are re-established automatically, the connection being redialled
+// by the underlying RPC client. Notifications sent while a subscription is down
+// are lost.
+type SubscriptionMux struct {
+	client     *rpc.Client
+	backoffMax time.Duration // Upper bound of the delay between resubscription attempts
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// DialSubscriptionMux connects to the given WebSocket or IPC endpoint and
+// creates a subscription multiplexer for it.
+func DialSubscriptionMux(ctx context.Context, rawurl string) (*SubscriptionMux, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewSubscriptionMux(c), nil
+}
+
+// NewSubscriptionMux creates a subscription multiplexer using the given client.
+// The client must support subscriptions and is closed along with the mux.
+func NewSubscriptionMux(c *rpc.Client) *SubscriptionMux {
+	return &SubscriptionMux{
+		client:     c,
+		backoffMax: 10 * time.Second,
+		closed:     make(chan struct{}),
+	}
+}
+
+// Close ends all subscriptions and closes the connection.
+func (m *SubscriptionMux) Close() {
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		m.client.Close()
+	})
+}
+
+// SubscribeNewHead subscribes to notifications about the current blockchain head.
+func (m *SubscriptionMux) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header, buffer SubscriptionBuffer) (ethereum.Subscription, error) {
+	return m.Subscribe(ctx, ch, buffer, "newHeads")
+}
+
+// SubscribeFilterLogs subscribes to the results of a streaming filter query.
+func (m *SubscriptionMux) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log, buffer SubscriptionBuffer) (ethereum.Subscription, error) {
+	arg, err := toFilterArg(q)
+	if err != nil {
+		return nil, err
+	}
+	return m.Subscribe(ctx, ch, buffer, "logs", arg)
+}
+
+// Subscribe creates an arbitrary subscription in the "eth" namespace, delivering
+// the notifications to the given channel. The initial subscription is created
+// before returning, so invalid arguments are reported to the caller.
+func (m *SubscriptionMux) Subscribe(ctx context.Context, channel interface{}, buffer SubscriptionBuffer, args ...interface{}) (ethereum.Subscription, error) {
+	out := reflect.ValueOf(channel)
+	if out.Kind() != reflect.Chan || out.Type().ChanDir()&reflect.SendDir == 0 {
+		panic("ethclient: channel argument of Subscribe must be a writable channel")
+	}
+	if buffer.Size <= 0 {
+		buffer.Size = 1024
+	}
+	// The server side subscriptions all deliver into the same internal channel,
+	// which survives resubscriptions along with the buffered notifications.
+	in := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, out.Type().Elem()), 0)
+
+	first, err := m.client.EthSubscribe(ctx, in.Interface(), args...)
+	if err != nil {
+		return nil, err
+	}
+	s := &muxSubscription{
+		in:     in,
+		out:    out,
+		buffer: buffer,
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+		err:    make(chan error, 1),
+	}
+	s.sub = event.ResubscribeErr(m.backoffMax, func(ctx context.Context, lastErr error) (event.Subscription, error) {
+		if first != nil {
+			sub := first
+			first = nil
+			return sub, nil
+		}
+		log.Debug("Resubscribing multiplexed subscription", "args", args, "err", lastErr)
+		sub, err := m.client.EthSubscribe(ctx, in.Interface(), args...)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddUint64(&s.resubscribed, 1)
+		return sub, nil
+	})
+	go s.loop(m.closed)
+	return s, nil
+}
+
+// muxSubscription is a subscription of the mux, buffering the notifications of
+// the server side subscription for the subscriber.
+type muxSubscription struct {
+	in     reflect.Value // Channel receiving the notifications from the endpoint
+	out    reflect.Value // Channel of the subscriber
+	buffer SubscriptionBuffer
+	queue  []reflect.Value
+
+	sub          event.Subscription // Resubscribing server side subscription
+	resubscribed uint64             // Number of times the subscription was re-established (atomic)
+	dropped      uint64             // Number of notifications dropped by the buffer policy (atomic)
+
+	unsubOnce sync.Once
+	quit      chan struct{}
+	done      chan struct{}
+	err       chan error
+}
+
+// Unsubscribe ends the subscription and closes the error channel.
+func (s *muxSubscription) Unsubscribe() {
+	s.unsubOnce.Do(func() {
+		close(s.quit)
+		<-s.done
+	})
+}
+
+// Err returns the subscription error channel. It receives an error if the mux
+// is closed and is closed on unsubscribe.
+func (s *muxSubscription) Err() <-chan error {
+	return s.err
+}
+
+// loop forwards the notifications to the subscriber until unsubscribed or the
+// mux is closed.
+func (s *muxSubscription) loop(closed <-chan struct{}) {
+	defer close(s.done)
+	defer close(s.err)
+	defer s.sub.Unsubscribe()
+
+	const (
+		quitCase = iota
+		closedCase
+		recvCase
+		sendCase
+	)
+	cases := []reflect.SelectCase{
+		quitCase:   {Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.quit)},
+		closedCase: {Dir: reflect.SelectRecv, Chan: reflect.ValueOf(closed)},
+		recvCase:   {Dir: reflect.SelectRecv, Chan: s.in},
+		sendCase:   {Dir: reflect.SelectSend, Chan: s.out},
+	}
+	for {
+		// Stop receiving while full if the subscriber is to be waited for, and
+		// only try sending while there is something to send.
+		cases[recvCase].Chan = s.in
+		if s.buffer.Policy == BufferBlock && len(s.queue) >= s.buffer.Size {
+			cases[recvCase].Chan = reflect.Value{}
+		}
+		active := cases[:recvCase+1]
+		if len(s.queue) > 0 {
+			cases[sendCase].Send = s.queue[0]
+			active = cases
+		}
+		chosen, recv, _ := reflect.Select(active)
+		switch chosen {
+		case quitCase:
+			return
+		case closedCase:
+			s.err <- rpc.ErrClientQuit
+			return
+		case recvCase:
+			if !s.enqueue(recv) {
+				atomic.AddUint64(&s.dropped, 1)
+			}
+		case sendCase:
+			s.queue[0] = reflect.Value{}
+			s.queue = s.queue[1:]
+		}
+	}
+}
+
+// enqueue buffers a notification according to the buffer policy, reporting
+// whether it was added without dropping any notification.
+func (s *muxSubscription) enqueue(v reflect.Value) bool {
+	if len(s.queue) < s.buffer.Size {
+		s.queue = append(s.queue, v)
+		return true
+	}
+	switch s.buffer.Policy {
+	case BufferDropOldest:
+		s.queue = append(s.queue[1:], v)
+	case BufferDropNewest:
+	default:
+		panic("ethclient: blocking subscription buffer overflow")
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testHeadService serves newHeads subscriptions fed by the test.
+type testHeadService struct {
+	feed event.Feed
+}
+
+func (s *testHeadService) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, _ := rpc.NotifierFromContext(ctx)
+	sub := notifier.CreateSubscription()
+
+	heads := make(chan *types.Header)
+	feedSub := s.feed.Subscribe(heads)
+	go func() {
+		defer feedSub.Unsubscribe()
+		for {
+			select {
+			case head := <-heads:
+				notifier.Notify(sub.ID, head)
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// swappableServer is a WebSocket endpoint whose RPC server can be replaced,
+// dropping all connections of the previous one.
+type swappableServer struct {
+	service *testHeadService
+	mu      sync.Mutex
+	server  *rpc.Server
+}
+
+func (s *swappableServer) swap() {
+	server := rpc.NewServer()
+	server.RegisterName("eth", s.service)
+
+	s.mu.Lock()
+	old := s.server
+	s.server = server
+	s.mu.Unlock()
+	if old != nil {
+		old.Stop()
+	}
+}
+
+func (s *swappableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	server := s.server
+	s.mu.Unlock()
+	server.WebsocketHandler([]string{"*"}).ServeHTTP(w, r)
+}
+
+// Tests that multiplexed subscriptions share the connection and survive the
+// loss of it.
+func TestSubscriptionMuxResubscribe(t *testing.T) {
+	endpoint := &swappableServer{service: new(testHeadService)}
+	endpoint.swap()
+	httpsrv := httptest.NewServer(endpoint)
+	defer httpsrv.Close()
+
+	mux, err := DialSubscriptionMux(context.Background(), "ws:"+strings.TrimPrefix(httpsrv.URL, "http:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.backoffMax = 50 * time.Millisecond
+	defer mux.Close()
+
+	var (
+		chans = make([]chan *types.Header, 3)
+		subs  = make([]*muxSubscription, 3)
+	)
+	for i := range chans {
+		chans[i] = make(chan *types.Header)
+		sub, err := mux.SubscribeNewHead(context.Background(), chans[i], SubscriptionBuffer{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Unsubscribe()
+		subs[i] = sub.(*muxSubscription)
+	}
+	// Deliver heads until all subscribers received one with the given number,
+	// resending while subscriptions are being re-established.
+	deliver := func(number int64) {
+		timeout := time.After(5 * time.Second)
+		for i, ch := range chans {
+			for received := false; !received; {
+				endpoint.service.feed.Send(&types.Header{Number: big.NewInt(number), Difficulty: new(big.Int)})
+				select {
+				case head := <-ch:
+					received = head.Number.Int64() == number
+				case <-time.After(20 * time.Millisecond):
+				case <-timeout:
+					t.Fatalf("subscriber %d: head %d not received", i, number)
+				}
+			}
+		}
+	}
+	deliver(1)
+	endpoint.swap()
+	deliver(2)
+
+	for i, sub := range subs {
+		if n := atomic.LoadUint64(&sub.resubscribed); n != 1 {
+			t.Errorf("subscription %d: resubscribed %d times, want 1", i, n)
+		}
+	}
+	// Closing the mux must end the subscriptions
+	mux.Close()
+	for i, sub := range subs {
+		select {
+		case err := <-sub.Err():
+			if err != rpc.ErrClientQuit {
+				t.Errorf("subscription %d: error mismatch: have %v, want %v", i, err, rpc.ErrClientQuit)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("subscription %d not ended", i)
+		}
+	}
+}
+
+func TestSubscriptionBufferPolicy(t *testing.T) {
+	tests := []struct {
+		policy  BufferPolicy
+		want    []int
+		dropped uint64
+	}{
+		{BufferDropOldest, []int{3, 4}, 3},
+		{BufferDropNewest, []int{0, 1}, 3},
+	}
+	for _, test := range tests {
+		s := &muxSubscription{buffer: SubscriptionBuffer{Size: 2, Policy: test.policy}}
+		for i := 0; i < 5; i++ {
+			if !s.enqueue(reflect.ValueOf(i)) {
+				s.dropped++
+			}
+		}
+		var have []int
+		for _, v := range s.queue {
+			have = append(have, int(v.Int()))
+		}
+		if !reflect.DeepEqual(have, test.want) || s.dropped != test.dropped {
+			t.Errorf("policy %d: buffer mismatch: have %v (%d dropped), want %v (%d dropped)", test.policy, have, s.dropped, test.want, test.dropped)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrorCategory classifies the errors returned by the client, allowing callers
+// to react to failures without matching on error messages.
+type ErrorCategory int
+
+const (
+	ErrorOther           ErrorCategory = iota // Errors not belonging to any other category
+	ErrorNotFound                             // The requested object does not exist, or is not known to the endpoint
+	ErrorRateLimited                          // The endpoint refused the request due to rate limiting
+	ErrorMissingTrieNode                      // The endpoint lacks the requested state, e.g. pruned historical state
+	ErrorReverted                             // The executed call reverted
+	ErrorTransport                            // The request did not reach the endpoint or got no valid response
+)
+
+// String implements fmt.Stringer.
+func (c ErrorCategory) String() string {
+	switch c {
+	case ErrorNotFound:
+		return "not found"
+	case ErrorRateLimited:
+		return "rate limited"
+	case ErrorMissingTrieNode:
+		return "missing trie node"
+	case ErrorReverted:
+		return "reverted"
+	case ErrorTransport:
+		return "transport"
+	default:
+		return "other"
+	}
+}
+
+// Categorize returns the category of an error returned by the client.
+//
+// Note, a missing object is reported as ethereum.NotFound by the client when the
+// endpoint returns null, but some endpoints return an error instead; both fall
+// into ErrorNotFound.
+func Categorize(err error) ErrorCategory {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, rpc.ErrClientQuit) {
+		return ErrorOther
+	}
+	if errors.Is(err, ethereum.NotFound) {
+		return ErrorNotFound
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode == 429:
+			return ErrorRateLimited
+		case httpErr.StatusCode >= 500:
+			return ErrorTransport
+		}
+		return ErrorOther
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		msg := strings.ToLower(rpcErr.Error())
+		switch {
+		case rpcErr.ErrorCode() == -32005 || strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests"):
+			return ErrorRateLimited
+		case strings.Contains(msg, "missing trie node"):
+			return ErrorMissingTrieNode
+		case rpcErr.ErrorCode() == 3 || strings.HasPrefix(msg, "execution reverted"):
+			return ErrorReverted
+		case strings.Contains(msg, "not found"):
+			return ErrorNotFound
+		}
+		return ErrorOther
+	}
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return ErrorOther
+	}
+	// Anything else failed below the JSON-RPC layer
+	return ErrorTransport
+}
+
+// isAlreadyKnown reports whether a transaction submission was rejected because
+// the endpoint already has the transaction.
+func isAlreadyKnown(err error) bool {
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return strings.Contains(strings.ToLower(rpcErr.Error()), "already known")
+}
+
+// RetryConfig configures the retrying of failed calls.
+type RetryConfig struct {
+	Attempts   int              // Maximum number of attempts per call including the first, 0 or 1 disables retries
+	MinBackoff time.Duration    // Delay before the first retry, doubled on every further one
+	MaxBackoff time.Duration    // Upper bound of the delay between two attempts (0 = 30 seconds)
+	Retryable  func(error) bool // Decides whether a failed call is retried (nil = DefaultRetryable)
+}
+
+// DefaultRetryable retries calls which failed due to rate limiting or transport
+// errors.
+func DefaultRetryable(err error) bool {
+	switch Categorize(err) {
+	case ErrorRateLimited, ErrorTransport:
+		return true
+	}
+	return false
+}
+
+// NewFailoverClient creates a client that retries failed calls according to the
+// config, rotating to the next endpoint after every failed attempt. The endpoint
+// serving a call successfully is kept for the following calls. Subscriptions are
+// created on the current endpoint and are not moved.
+func NewFailoverClient(config RetryConfig, endpoints ...*rpc.Client) *Client {
+	if len(endpoints) == 0 {
+		panic("ethclient: no endpoints")
+	}
+	if config.Retryable == nil {
+		config.Retryable = DefaultRetryable
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	return &Client{c: endpoints[0], endpoints: endpoints, retry: &config}
+}
+
+// DialFailover connects to all the given URLs and creates a failover client for
+// them.
+func DialFailover(ctx context.Context, config RetryConfig, rawurls ...string) (*Client, error) {
+	endpoints := make([]*rpc.Client, 0, len(rawurls))
+	for _, rawurl := range rawurls {
+		c, err := rpc.DialContext(ctx, rawurl)
+		if err != nil {
+			for _, c := range endpoints {
+				c.Close()
+			}
+			return nil, err
+		}
+		endpoints = append(endpoints, c)
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoints")
+	}
+	return NewFailoverClient(config, endpoints...), nil
+}
+
+// endpoint returns the RPC client currently in use.
+func (ec *Client) endpoint() *rpc.Client {
+	if len(ec.endpoints) == 0 {
+		return ec.c
+	}
+	return ec.endpoints[atomic.LoadUint32(&ec.current)%uint32(len(ec.endpoints))]
+}
+
+// callContext performs a JSON-RPC call, retrying according to the config.
+func (ec *Client) callContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return ec.withRetry(ctx, func(c *rpc.Client) error {
+		return c.CallContext(ctx, result, method, args...)
+	})
+}
+
+// batchCallContext sends a JSON-RPC batch, retrying according to the config if
+// the batch as a whole fails.
+func (ec *Client) batchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	return ec.withRetry(ctx, func(c *rpc.Client) error {
+		return c.BatchCallContext(ctx, b)
+	})
+}
+
+// withRetry runs the request against the current endpoint, rotating endpoints
+// and backing off between failed attempts.
+func (ec *Client) withRetry(ctx context.Context, request func(*rpc.Client) error) error {
+	if ec.retry == nil {
+		return request(ec.c)
+	}
+	for attempt := 0; ; attempt++ {
+		current := atomic.LoadUint32(&ec.current)
+		err := request(ec.endpoints[current%uint32(len(ec.endpoints))])
+		if err == nil || attempt+1 >= ec.retry.Attempts || !ec.retry.Retryable(err) {
+			return err
+		}
+		// Rotate away from the failing endpoint, unless a concurrent call did
+		atomic.CompareAndSwapUint32(&ec.current, current, current+1)
+
+		timer := time.NewTimer(ec.retry.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// backoff returns the jittered delay before the retry following the given
+// attempt.
+func (c *RetryConfig) backoff(attempt int) time.Duration {
+	delay := c.MinBackoff
+	for i := 0; i < attempt && delay < c.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > c.MaxBackoff {
+		delay = c.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// Wait between half and the full delay, so that clients failing at the same
+	// time spread their retries
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type testRPCError struct {
+	code int
+	msg  string
+}
+
+func (e *testRPCError) Error() string  { return e.msg }
+func (e *testRPCError) ErrorCode() int { return e.code }
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorCategory
+	}{
+		{ethereum.NotFound, ErrorNotFound},
+		{fmt.Errorf("wrapped: %w", ethereum.NotFound), ErrorNotFound},
+		{&testRPCError{-32000, "header not found"}, ErrorNotFound},
+		{rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, ErrorRateLimited},
+		{&testRPCError{-32005, "limit exceeded"}, ErrorRateLimited},
+		{&testRPCError{-32000, "missing trie node 1234 (path )"}, ErrorMissingTrieNode},
+		{&testRPCError{3, "execution reverted: nope"}, ErrorReverted},
+		{rpc.HTTPError{StatusCode: 502, Status: "502 Bad Gateway"}, ErrorTransport},
+		{errors.New("connection refused"), ErrorTransport},
+		{rpc.HTTPError{StatusCode: 401, Status: "401 Unauthorized"}, ErrorOther},
+		{&testRPCError{-32601, "the method eth_foo does not exist/is not available"}, ErrorOther},
+		{context.DeadlineExceeded, ErrorOther},
+	}
+	for i, test := range tests {
+		if have := Categorize(test.err); have != test.want {
+			t.Errorf("test %d (%v): category mismatch: have %v, want %v", i, test.err, have, test.want)
+		}
+	}
+}
+
+type testChainService struct{}
+
+func (s *testChainService) ChainId() hexutil.Uint64 { return 1337 }
+
+// Tests that a failover client rotates away from a rate limited endpoint and
+// keeps using the healthy one.
+func TestFailover(t *testing.T) {
+	var limited int32
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&limited, 1)
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer bad.Close()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", new(testChainService)); err != nil {
+		t.Fatal(err)
+	}
+	good := httptest.NewServer(server)
+	defer good.Close()
+
+	client, err := DialFailover(context.Background(), RetryConfig{Attempts: 2, MinBackoff: time.Millisecond}, bad.URL, good.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		id, err := client.ChainID(context.Background())
+		if err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+		if id.Uint64() != 1337 {
+			t.Fatalf("call %d: chain id mismatch: have %v, want 1337", i, id)
+		}
+	}
+	if n := atomic.LoadInt32(&limited); n != 1 {
+		t.Errorf("rate limited endpoint called %d times, want 1", n)
+	}
+	// A single attempt must surface the error
+	client, _ = DialFailover(context.Background(), RetryConfig{Attempts: 1}, bad.URL, good.URL)
+	defer client.Close()
+	if _, err := client.ChainID(context.Background()); Categorize(err) != ErrorRateLimited {
+		t.Errorf("error category mismatch: have %v (%v), want rate limited", Categorize(err), err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	config := RetryConfig{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for i := 0; i < 10; i++ {
+			if have := config.backoff(attempt); have < want/2 || have > want {
+				t.Errorf("attempt %d: backoff %v out of range [%v, %v]", attempt, have, want/2, want)
+			}
+		}
+	}
+}
+
+// Tests that a retried transaction submission is sent unchanged and succeeds if
+// the endpoint already received it on the failed attempt.
+func TestFailoverSendTransaction(t *testing.T) {
+	var (
+		calls int32
+		raws  = make(chan string, 2)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Params []string        `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Params) != 1 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		raws <- req.Params[0]
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// Accept the transaction, but fail the response
+			http.Error(w, "gateway timeout", http.StatusGatewayTimeout)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32000,"message":"already known"}}`, req.ID)
+	}))
+	defer srv.Close()
+
+	client, err := DialFailover(context.Background(), RetryConfig{Attempts: 2, MinBackoff: time.Millisecond}, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000, GasPrice: big.NewInt(1)})
+	if err := client.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if first, second := <-raws, <-raws; first != second {
+		t.Errorf("retry sent different transaction: have %s, want %s", second, first)
+	}
+	// An already known transaction on the first attempt is still reported
+	if err := client.SendTransaction(context.Background(), tx); err == nil || !isAlreadyKnown(err) {
+		t.Errorf("error mismatch: have %v, want already known", err)
+	}
+}
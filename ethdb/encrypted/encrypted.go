@@ -0,0 +1,285 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package encrypted implements transparent encryption at rest for key-value
+// stores.
+//
+// Values are sealed with XChaCha20-Poly1305 under a random nonce, using the
+// database key as additional data so that values can't be swapped between keys.
+// The 192-bit nonces are large enough to be picked at random for any number of
+// writes under the same key. Keys are left in plain text, as the database relies
+// on their ordering for iteration.
+package encrypted
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// KeySize is the size of the master key used for database encryption.
+const KeySize = 32
+
+// checkKey is the database key of the canary value used to detect a wrong master
+// key or an attempt to encrypt an existing plain text database.
+var checkKey = []byte("EncryptionCheck")
+
+var (
+	// ErrDecrypt is returned if a value can't be authenticated with the master key.
+	ErrDecrypt = errors.New("failed to decrypt database value")
+
+	// ErrWrongKey is returned when opening a database with a different master key
+	// than the one it was created with.
+	ErrWrongKey = errors.New("wrong database encryption key")
+
+	// ErrNotEncrypted is returned when opening an existing plain text database
+	// with encryption enabled.
+	ErrNotEncrypted = errors.New("database is not encrypted")
+)
+
+// NewCipher creates the authenticated cipher for a master key.
+func NewCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid encryption key length %d, want %d", len(key), KeySize)
+	}
+	return chacha20poly1305.NewX(key)
+}
+
+// LoadCipher reads a hex encoded master key from a file and creates the cipher
+// for it.
+func LoadCipher(file string) (cipher.AEAD, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key file: %v", err)
+	}
+	return NewCipher(key)
+}
+
+// Seal encrypts and authenticates a plain text, prepending the random nonce.
+func Seal(aead cipher.AEAD, plaintext, ad []byte) []byte {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return aead.Seal(nonce, nonce, plaintext, ad)
+}
+
+// Open authenticates and decrypts a cipher text created by Seal.
+func Open(aead cipher.AEAD, ciphertext, ad []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// Database wraps a key-value store, encrypting all values written to it.
+type Database struct {
+	db   ethdb.KeyValueStore
+	aead cipher.AEAD
+}
+
+// New wraps a key-value store with transparent value encryption. Fresh stores
+// are marked as encrypted unless opened read-only, existing ones are checked to
+// have been encrypted with the same master key.
+func New(db ethdb.KeyValueStore, aead cipher.AEAD, readonly bool) (*Database, error) {
+	edb := &Database{db: db, aead: aead}
+
+	check, err := db.Get(checkKey)
+	if err != nil {
+		// No canary, only allow encrypting a fresh database
+		it := db.NewIterator(nil, nil)
+		exists := it.Next()
+		it.Release()
+		if exists {
+			return nil, ErrNotEncrypted
+		}
+		if readonly {
+			return edb, nil
+		}
+		if err := edb.Put(checkKey, checkKey); err != nil {
+			return nil, err
+		}
+		return edb, nil
+	}
+	if plain, err := Open(aead, check, checkKey); err != nil || !bytes.Equal(plain, checkKey) {
+		return nil, ErrWrongKey
+	}
+	return edb, nil
+}
+
+// Has retrieves if a key is present in the key-value data store.
+func (db *Database) Has(key []byte) (bool, error) {
+	return db.db.Has(key)
+}
+
+// Get retrieves the given key if it's present in the key-value data store.
+func (db *Database) Get(key []byte) ([]byte, error) {
+	value, err := db.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return Open(db.aead, value, key)
+}
+
+// Put inserts the given value into the key-value data store.
+func (db *Database) Put(key []byte, value []byte) error {
+	return db.db.Put(key, Seal(db.aead, value, key))
+}
+
+// Delete removes the key from the key-value data store.
+func (db *Database) Delete(key []byte) error {
+	return db.db.Delete(key)
+}
+
+// Stat returns a particular internal stat of the database.
+func (db *Database) Stat(property string) (string, error) {
+	return db.db.Stat(property)
+}
+
+// Compact flattens the underlying data store for the given key range.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	return db.db.Compact(start, limit)
+}
+
+// Close closes the underlying key-value store.
+func (db *Database) Close() error {
+	return db.db.Close()
+}
+
+// NewBatch creates a write-only batch encrypting the values put into it.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{Batch: db.db.NewBatch(), aead: db.aead}
+}
+
+// NewBatchWithSize creates a write-only batch with pre-allocated buffer.
+func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
+	return &batch{Batch: db.db.NewBatchWithSize(size), aead: db.aead}
+}
+
+// NewIterator creates an iterator decrypting the values of the underlying store.
+func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	return &iterator{Iterator: db.db.NewIterator(prefix, start), aead: db.aead}
+}
+
+// NewSnapshot creates a database snapshot decrypting the values read from it.
+func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
+	snap, err := db.db.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot{Snapshot: snap, aead: db.aead}, nil
+}
+
+// batch encrypts the values written into a batch of the underlying store.
+type batch struct {
+	ethdb.Batch
+	aead cipher.AEAD
+}
+
+// Put inserts the given value into the batch.
+func (b *batch) Put(key []byte, value []byte) error {
+	return b.Batch.Put(key, Seal(b.aead, value, key))
+}
+
+// Replay replays the batch contents, decrypting the values.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	return b.Batch.Replay(&replayer{w: w, aead: b.aead})
+}
+
+// replayer decrypts the values of a batch being replayed.
+type replayer struct {
+	w    ethdb.KeyValueWriter
+	aead cipher.AEAD
+}
+
+func (r *replayer) Put(key []byte, value []byte) error {
+	plain, err := Open(r.aead, value, key)
+	if err != nil {
+		return err
+	}
+	return r.w.Put(key, plain)
+}
+
+func (r *replayer) Delete(key []byte) error {
+	return r.w.Delete(key)
+}
+
+// iterator decrypts the values of an underlying iterator.
+type iterator struct {
+	ethdb.Iterator
+	aead  cipher.AEAD
+	value []byte
+	err   error
+}
+
+// Next moves the iterator to the next key/value pair, decrypting its value. The
+// encryption canary is skipped.
+func (it *iterator) Next() bool {
+	if it.err != nil || !it.Iterator.Next() {
+		it.value = nil
+		return false
+	}
+	if bytes.Equal(it.Iterator.Key(), checkKey) {
+		return it.Next()
+	}
+	it.value, it.err = Open(it.aead, it.Iterator.Value(), it.Iterator.Key())
+	return it.err == nil
+}
+
+// Error returns any accumulated error.
+func (it *iterator) Error() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.Iterator.Error()
+}
+
+// Value returns the decrypted value of the current key/value pair.
+func (it *iterator) Value() []byte {
+	return it.value
+}
+
+// snapshot decrypts the values read from an underlying snapshot.
+type snapshot struct {
+	ethdb.Snapshot
+	aead cipher.AEAD
+}
+
+// Get retrieves the given key if it's present in the snapshot.
+func (s *snapshot) Get(key []byte) ([]byte, error) {
+	value, err := s.Snapshot.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return Open(s.aead, value, key)
+}
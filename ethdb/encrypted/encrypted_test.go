@@ -0,0 +1,99 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package encrypted
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+func newTestCipher(t *testing.T, seed byte) cipher.AEAD {
+	aead, err := NewCipher(bytes.Repeat([]byte{seed}, KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
+
+func TestEncryptedDB(t *testing.T) {
+	aead := newTestCipher(t, 1)
+	dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+		db, err := New(memorydb.New(), aead, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
+// Tests that values are stored encrypted and bound to their keys, and that the
+// master key is checked when reopening a database.
+func TestEncryptedDBAtRest(t *testing.T) {
+	var (
+		raw   = memorydb.New()
+		value = []byte("secret value")
+	)
+	db, err := New(raw, newTestCipher(t, 1), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Put([]byte("a"), value)
+	db.Put([]byte("b"), []byte("other value"))
+
+	if stored, _ := raw.Get([]byte("a")); bytes.Contains(stored, value) {
+		t.Fatal("value stored in plain text")
+	}
+	if got, err := db.Get([]byte("a")); err != nil || !bytes.Equal(got, value) {
+		t.Fatalf("wrong value: %q, %v", got, err)
+	}
+	// Swapping ciphertexts between keys must be detected
+	stored, _ := raw.Get([]byte("b"))
+	raw.Put([]byte("a"), stored)
+	if _, err := db.Get([]byte("a")); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("swapped value not detected: %v", err)
+	}
+	// Reopening must verify the master key
+	if _, err := New(raw, newTestCipher(t, 1), false); err != nil {
+		t.Fatalf("failed to reopen with correct key: %v", err)
+	}
+	if _, err := New(raw, newTestCipher(t, 2), false); err != ErrWrongKey {
+		t.Fatalf("wrong key error mismatch: have %v, want %v", err, ErrWrongKey)
+	}
+	if _, err := New(raw, newTestCipher(t, 2), true); err != ErrWrongKey {
+		t.Fatalf("read-only wrong key error mismatch: have %v, want %v", err, ErrWrongKey)
+	}
+	// Fresh databases must only be marked encrypted if writable
+	fresh := memorydb.New()
+	if _, err := New(fresh, newTestCipher(t, 1), true); err != nil {
+		t.Fatalf("failed to open fresh database read-only: %v", err)
+	}
+	if ok, _ := fresh.Has(checkKey); ok {
+		t.Fatal("canary written to read-only database")
+	}
+	// Existing plain text databases must not be opened encrypted
+	plain := memorydb.New()
+	plain.Put([]byte("a"), value)
+	if _, err := New(plain, newTestCipher(t, 1), false); err != ErrNotEncrypted {
+		t.Fatalf("plain database error mismatch: have %v, want %v", err, ErrNotEncrypted)
+	}
+}
@@ -0,0 +1,559 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package remotekv implements the key-value database layer on top of a remote
+// store speaking the gRPC service defined in kv.proto. It allows multiple nodes,
+// e.g. stateless read replicas, to share a single chain database kept in a
+// distributed store like TiKV or FoundationDB, connected through a gateway
+// implementing the service.
+package remotekv
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/grpcwire"
+	"github.com/ethereum/go-ethereum/log"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// service is the name of the gRPC service defined in kv.proto.
+	service = "ethereum.kv.v1.KV"
+
+	// scanPageSize is the number of entries an iterator retrieves at once.
+	scanPageSize = 1024
+
+	// maxWriteSize is the maximum encoded size of a write, kept just below the
+	// gRPC message limit. Larger batches are rejected, as splitting them over
+	// multiple writes would break their atomicity.
+	maxWriteSize = 4*1024*1024 - 64*1024
+
+	// callTimeout is the time after which calls to the store are aborted.
+	callTimeout = 30 * time.Second
+)
+
+var (
+	// errNotFound is returned if a requested key is not present in the store.
+	errNotFound = errors.New("not found")
+
+	// errReadOnly is returned on writes if the database was opened read-only.
+	errReadOnly = errors.New("remote database is read-only")
+
+	// errWriteTooLarge is returned if a write exceeds the maximum message size.
+	errWriteTooLarge = errors.New("remote database write too large")
+
+	// errSnapshotInvalidated is returned on snapshot reads if the database failed
+	// over to another endpoint since the snapshot was opened.
+	errSnapshotInvalidated = errors.New("remote database snapshot invalidated by endpoint failover")
+)
+
+// Database is a key-value store kept in a remote store. All databases sharing a
+// store are isolated from each other by their keyspace.
+type Database struct {
+	endpoints []string
+	clients   []*grpcwire.Client
+	current   uint32 // Index of the endpoint in use (atomic)
+	failovers uint32 // Number of times the endpoint in use changed (atomic)
+	keyspace  string
+	readonly  bool
+}
+
+// New connects to a remote store through the given grpc:// or grpcs:// gateway
+// endpoints, opening the database in the given keyspace. Calls are made to one
+// endpoint at a time, failing over to the others if it becomes unreachable.
+func New(endpoints []string, keyspace string, readonly bool) (*Database, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no remote database endpoints")
+	}
+	db := &Database{
+		endpoints: endpoints,
+		keyspace:  keyspace,
+		readonly:  readonly,
+	}
+	for _, endpoint := range endpoints {
+		client, err := grpcwire.Dial(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		db.clients = append(db.clients, client)
+	}
+	// Check that the store is reachable
+	if _, err := db.Stat(""); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// call invokes a method of the store on the endpoint in use. If the endpoint is
+// unreachable, the call is retried on the other ones.
+func (db *Database) call(method string, req []byte) ([]byte, error) {
+	res, _, err := db.callIndex(method, req)
+	return res, err
+}
+
+// callIndex is like call, but also returns the index of the endpoint that served
+// the call.
+func (db *Database) callIndex(method string, req []byte) ([]byte, uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	var (
+		start = atomic.LoadUint32(&db.current)
+		err   error
+	)
+	for i := range db.clients {
+		index := (start + uint32(i)) % uint32(len(db.clients))
+
+		var res []byte
+		res, err = db.clients[index].Call(ctx, "/"+service+"/"+method, req)
+		if _, status := err.(*grpcwire.Error); err == nil || status {
+			if atomic.SwapUint32(&db.current, index) != index {
+				atomic.AddUint32(&db.failovers, 1)
+			}
+			return res, index, err
+		}
+		log.Warn("Remote database endpoint failed", "endpoint", db.endpoints[index], "err", err)
+	}
+	return nil, 0, err
+}
+
+// callEndpoint invokes a method of the store on the given endpoint, without
+// failing over to the others.
+func (db *Database) callEndpoint(index uint32, method string, req []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	return db.clients[index].Call(ctx, "/"+service+"/"+method, req)
+}
+
+// Has retrieves if a key is present in the key-value store.
+func (db *Database) Has(key []byte) (bool, error) {
+	res, err := db.call("Has", db.keyRequest(key, 0))
+	if err != nil {
+		return false, err
+	}
+	return decodeHas(res)
+}
+
+// Get retrieves the given key if it's present in the key-value store.
+func (db *Database) Get(key []byte) ([]byte, error) {
+	res, err := db.call("Get", db.keyRequest(key, 0))
+	if err != nil {
+		return nil, err
+	}
+	return decodeGet(res)
+}
+
+func decodeHas(res []byte) (bool, error) {
+	found, err := grpcwire.DecodeUint(res, 1)
+	return found != 0, err
+}
+
+func decodeGet(res []byte) ([]byte, error) {
+	found, err := grpcwire.DecodeUint(res, 1)
+	if err != nil {
+		return nil, err
+	}
+	if found == 0 {
+		return nil, errNotFound
+	}
+	values, err := grpcwire.DecodeBytes(res, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return []byte{}, nil
+	}
+	return common.CopyBytes(values[len(values)-1]), nil
+}
+
+func (db *Database) keyRequest(key []byte, snapshot uint64) []byte {
+	req := db.appendKeyspace(nil)
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendBytes(req, key)
+	if snapshot != 0 {
+		req = protowire.AppendTag(req, 3, protowire.VarintType)
+		req = protowire.AppendVarint(req, snapshot)
+	}
+	return req
+}
+
+func (db *Database) appendKeyspace(req []byte) []byte {
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	return protowire.AppendString(req, db.keyspace)
+}
+
+// Put inserts the given value into the key-value store.
+func (db *Database) Put(key []byte, value []byte) error {
+	return db.write([]keyvalue{{common.CopyBytes(key), common.CopyBytes(value), false}})
+}
+
+// Delete removes the key from the key-value store.
+func (db *Database) Delete(key []byte) error {
+	return db.write([]keyvalue{{common.CopyBytes(key), nil, true}})
+}
+
+// write sends the given operations to the store in a single atomic write.
+// Operations exceeding the maximum message size are rejected as a whole.
+func (db *Database) write(ops []keyvalue) error {
+	if db.readonly {
+		return errReadOnly
+	}
+	req := db.appendKeyspace(nil)
+	for _, kv := range ops {
+		var op []byte
+		op = protowire.AppendTag(op, 1, protowire.BytesType)
+		op = protowire.AppendBytes(op, kv.key)
+		if kv.delete {
+			op = protowire.AppendTag(op, 3, protowire.VarintType)
+			op = protowire.AppendVarint(op, 1)
+		} else {
+			op = protowire.AppendTag(op, 2, protowire.BytesType)
+			op = protowire.AppendBytes(op, kv.value)
+		}
+		req = protowire.AppendTag(req, 2, protowire.BytesType)
+		req = protowire.AppendBytes(req, op)
+
+		if len(req) > maxWriteSize {
+			return errWriteTooLarge
+		}
+	}
+	_, err := db.call("Write", req)
+	return err
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{db: db}
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
+	return &batch{db: db}
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset
+// of database content with a particular key prefix, starting at a particular
+// initial key (or after, if it does not exist).
+//
+// Entries are retrieved from the store in pages as the iteration advances, so
+// the iterator does not reflect a consistent view of the database.
+func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	return &iterator{
+		db:     db,
+		prefix: common.CopyBytes(prefix),
+		next:   append([]byte{}, start...),
+		index:  -1,
+	}
+}
+
+// NewSnapshot creates a database snapshot based on the current state, kept by
+// the store until released.
+//
+// Snapshots are bound to the endpoint they were opened on. If the database fails
+// over to another endpoint, all open snapshots are invalidated.
+func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
+	res, endpoint, err := db.callIndex("OpenSnapshot", db.appendKeyspace(nil))
+	if err != nil {
+		return nil, err
+	}
+	id, err := grpcwire.DecodeUint(res, 1)
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		return nil, errors.New("invalid snapshot id")
+	}
+	snap := &snapshot{db: db, id: id, endpoint: endpoint, failovers: atomic.LoadUint32(&db.failovers)}
+	if atomic.LoadUint32(&db.current) != endpoint {
+		// Another call failed over while the snapshot was being opened
+		snap.Release()
+		return nil, errSnapshotInvalidated
+	}
+	return snap, nil
+}
+
+// Stat returns a particular internal stat of the store.
+func (db *Database) Stat(property string) (string, error) {
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendString(req, property)
+
+	res, err := db.call("Stat", req)
+	if err != nil {
+		return "", err
+	}
+	return grpcwire.DecodeString(res, 1)
+}
+
+// Compact flattens the underlying data store for the given key range, if the
+// store supports it.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	if db.readonly {
+		return errReadOnly
+	}
+	req := db.appendKeyspace(nil)
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendBytes(req, start)
+	req = protowire.AppendTag(req, 3, protowire.BytesType)
+	req = protowire.AppendBytes(req, limit)
+
+	_, err := db.call("Compact", req)
+	return err
+}
+
+// Close closes the connections to the store.
+func (db *Database) Close() error {
+	for _, client := range db.clients {
+		client.Close()
+	}
+	return nil
+}
+
+// keyvalue is a write operation buffered in a batch.
+type keyvalue struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// batch is a write-only remote database that commits changes to its host
+// database when Write is called. A batch cannot be used concurrently.
+type batch struct {
+	db     *Database
+	writes []keyvalue
+	size   int
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), common.CopyBytes(value), false})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete inserts the a key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), nil, true})
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write flushes any accumulated data to the store.
+func (b *batch) Write() error {
+	return b.db.write(b.writes)
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	for _, keyvalue := range b.writes {
+		if keyvalue.delete {
+			if err := w.Delete(keyvalue.key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Put(keyvalue.key, keyvalue.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterator walks the entries of a key prefix in the store, retrieving them in
+// pages.
+type iterator struct {
+	db      *Database
+	prefix  []byte
+	next    []byte // Start of the next page relative to the prefix, nil if exhausted
+	entries []keyvalue
+	index   int
+	err     error
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.index+1 < len(it.entries) {
+		it.index++
+		return true
+	}
+	if it.next == nil {
+		it.entries, it.index = nil, -1
+		return false
+	}
+	if it.err = it.fetch(); it.err != nil || len(it.entries) == 0 {
+		it.entries, it.index = nil, -1
+		return false
+	}
+	it.index = 0
+	return true
+}
+
+// fetch retrieves the next page of entries.
+func (it *iterator) fetch() error {
+	req := it.db.appendKeyspace(nil)
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendBytes(req, it.prefix)
+	req = protowire.AppendTag(req, 3, protowire.BytesType)
+	req = protowire.AppendBytes(req, it.next)
+	req = protowire.AppendTag(req, 4, protowire.VarintType)
+	req = protowire.AppendVarint(req, scanPageSize)
+
+	res, err := it.db.call("Scan", req)
+	if err != nil {
+		return err
+	}
+	entries, err := grpcwire.DecodeBytes(res, 1)
+	if err != nil {
+		return err
+	}
+	more, err := grpcwire.DecodeUint(res, 2)
+	if err != nil {
+		return err
+	}
+	it.entries = it.entries[:0]
+	for _, entry := range entries {
+		keys, err := grpcwire.DecodeBytes(entry, 1)
+		if err != nil {
+			return err
+		}
+		values, err := grpcwire.DecodeBytes(entry, 2)
+		if err != nil {
+			return err
+		}
+		kv := keyvalue{key: []byte{}, value: []byte{}}
+		if len(keys) > 0 {
+			kv.key = keys[len(keys)-1]
+		}
+		if len(values) > 0 {
+			kv.value = values[len(values)-1]
+		}
+		it.entries = append(it.entries, kv)
+	}
+	it.next = nil
+	if more != 0 && len(it.entries) > 0 {
+		// Continue right after the last key of the page
+		last := it.entries[len(it.entries)-1].key
+		if len(last) < len(it.prefix) {
+			return errors.New("scanned key outside of the prefix")
+		}
+		it.next = append(common.CopyBytes(last[len(it.prefix):]), 0)
+	}
+	return nil
+}
+
+// Error returns any accumulated error. Exhausting all the key/value pairs
+// is not considered to be an error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done. The caller
+// should not modify the contents of the returned slice, and its contents may
+// change on the next call to Next.
+func (it *iterator) Key() []byte {
+	if it.index < 0 || it.index >= len(it.entries) {
+		return nil
+	}
+	return it.entries[it.index].key
+}
+
+// Value returns the value of the current key/value pair, or nil if done. The
+// caller should not modify the contents of the returned slice, and its contents
+// may change on the next call to Next.
+func (it *iterator) Value() []byte {
+	if it.index < 0 || it.index >= len(it.entries) {
+		return nil
+	}
+	return it.entries[it.index].value
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (it *iterator) Release() {
+	it.entries, it.next, it.index = nil, nil, -1
+}
+
+// snapshot is a consistent view of the database kept by the store. Snapshot
+// IDs are only meaningful to the endpoint that opened them, so reads are never
+// failed over and are rejected once the database switched endpoints.
+type snapshot struct {
+	db        *Database
+	id        uint64
+	endpoint  uint32 // Index of the endpoint holding the snapshot
+	failovers uint32 // Failover count of the database when the snapshot was opened
+	release   sync.Once
+}
+
+// call invokes a method of the store on the endpoint holding the snapshot.
+func (snap *snapshot) call(method string, req []byte) ([]byte, error) {
+	if atomic.LoadUint32(&snap.db.failovers) != snap.failovers {
+		return nil, errSnapshotInvalidated
+	}
+	return snap.db.callEndpoint(snap.endpoint, method, req)
+}
+
+// Has retrieves if a key is present in the snapshot.
+func (snap *snapshot) Has(key []byte) (bool, error) {
+	res, err := snap.call("Has", snap.db.keyRequest(key, snap.id))
+	if err != nil {
+		return false, err
+	}
+	return decodeHas(res)
+}
+
+// Get retrieves the given key if it's present in the snapshot.
+func (snap *snapshot) Get(key []byte) ([]byte, error) {
+	res, err := snap.call("Get", snap.db.keyRequest(key, snap.id))
+	if err != nil {
+		return nil, err
+	}
+	return decodeGet(res)
+}
+
+// Release releases the snapshot in the store.
+func (snap *snapshot) Release() {
+	snap.release.Do(func() {
+		var req []byte
+		req = protowire.AppendTag(req, 1, protowire.VarintType)
+		req = protowire.AppendVarint(req, snap.id)
+		if _, err := snap.db.callEndpoint(snap.endpoint, "ReleaseSnapshot", req); err != nil {
+			log.Warn("Failed to release remote database snapshot", "err", err)
+		}
+	})
+}
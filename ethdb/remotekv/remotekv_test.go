@@ -0,0 +1,186 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotekv
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func newTestServer(t *testing.T, db ethdb.KeyValueStore) string {
+	srv := NewServer(db)
+	server := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		server.Close()
+		srv.Close()
+	})
+	return "grpc://" + strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestRemoteDB(t *testing.T) {
+	dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+		db, err := New([]string{newTestServer(t, memorydb.New())}, "chaindata", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
+// Tests that keyspaces are isolated from each other and that iteration spans
+// multiple scan pages.
+func TestRemoteDBKeyspaces(t *testing.T) {
+	endpoint := newTestServer(t, memorydb.New())
+
+	a, err := New([]string{endpoint}, "a", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New([]string{endpoint}, "ab", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := a.NewBatch()
+	value := bytes.Repeat([]byte{0xff}, 1024)
+	for i := 0; i < 3*scanPageSize; i++ {
+		batch.Put([]byte{byte(i >> 8), byte(i)}, value)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put([]byte{0x00, 0x00}, []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	it := a.NewIterator(nil, nil)
+	count := 0
+	for it.Next() {
+		if want := []byte{byte(count >> 8), byte(count)}; !bytes.Equal(it.Key(), want) {
+			t.Fatalf("key %d mismatch: have %x, want %x", count, it.Key(), want)
+		}
+		if !bytes.Equal(it.Value(), value) {
+			t.Fatalf("value %d mismatch", count)
+		}
+		count++
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	it.Release()
+	if count != 3*scanPageSize {
+		t.Fatalf("iterated entry count mismatch: have %d, want %d", count, 3*scanPageSize)
+	}
+	if value, err := b.Get([]byte{0x00, 0x00}); err != nil || string(value) != "b" {
+		t.Fatalf("keyspace value mismatch: have %q, %v", value, err)
+	}
+}
+
+// Tests that calls fail over to the next endpoint if one is unreachable and that
+// read-only databases reject writes.
+func TestRemoteDBFailover(t *testing.T) {
+	dead := httptest.NewServer(nil)
+	dead.Close()
+
+	endpoints := []string{"grpc://" + strings.TrimPrefix(dead.URL, "http://"), newTestServer(t, memorydb.New())}
+	db, err := New(endpoints, "chaindata", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	ro, err := New(endpoints, "chaindata", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := ro.Get([]byte("key")); err != nil || string(value) != "value" {
+		t.Fatalf("value mismatch: have %q, %v", value, err)
+	}
+	if err := ro.Put([]byte("key"), nil); err != errReadOnly {
+		t.Fatalf("read-only write error mismatch: have %v, want %v", err, errReadOnly)
+	}
+}
+
+// Tests that batches too large for a single write are rejected as a whole
+// instead of being committed partially.
+func TestRemoteDBLargeBatch(t *testing.T) {
+	db, err := New([]string{newTestServer(t, memorydb.New())}, "chaindata", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := db.NewBatch()
+	value := bytes.Repeat([]byte{0xff}, 1024)
+	for i := 0; i < 2*maxWriteSize/len(value); i++ {
+		batch.Put([]byte{byte(i >> 8), byte(i)}, value)
+	}
+	if err := batch.Write(); err != errWriteTooLarge {
+		t.Fatalf("large batch error mismatch: have %v, want %v", err, errWriteTooLarge)
+	}
+	if ok, _ := db.Has([]byte{0x00, 0x00}); ok {
+		t.Fatalf("rejected batch partially written")
+	}
+}
+
+// Tests that snapshots are invalidated when the database fails over to another
+// endpoint, as their IDs are meaningless to the other stores.
+func TestRemoteDBSnapshotFailover(t *testing.T) {
+	// Serve the first store through a handler that can be taken down, as the
+	// h2c connections outlive the closing of the test server
+	var (
+		srv  = NewServer(memorydb.New())
+		dead int32
+	)
+	first := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&dead) != 0 {
+			panic(http.ErrAbortHandler)
+		}
+		srv.server.ServeHTTP(w, r)
+	}), new(http2.Server)))
+	defer first.Close()
+
+	endpoints := []string{"grpc://" + strings.TrimPrefix(first.URL, "http://"), newTestServer(t, memorydb.New())}
+
+	db, err := New(endpoints, "chaindata", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := db.NewSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Release()
+
+	if _, err := snap.Has([]byte("key")); err != nil {
+		t.Fatalf("snapshot read failed: %v", err)
+	}
+	atomic.StoreInt32(&dead, 1)
+	if err := db.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatalf("failover write failed: %v", err)
+	}
+	if _, err := snap.Has([]byte("key")); err != errSnapshotInvalidated {
+		t.Fatalf("snapshot error mismatch: have %v, want %v", err, errSnapshotInvalidated)
+	}
+}
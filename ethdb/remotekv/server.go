@@ -0,0 +1,365 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotekv
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/grpcwire"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// maxScanEntries and maxScanSize limit the entries returned in one scan.
+	maxScanEntries = 1024
+	maxScanSize    = 1024 * 1024
+
+	// maxSnapshots is the maximum number of snapshots open at the same time.
+	maxSnapshots = 64
+
+	// snapshotTimeout is the time after which idle snapshots may be released.
+	snapshotTimeout = 5 * time.Minute
+
+	// codeResourceExhausted is the gRPC status returned if too many snapshots
+	// are open.
+	codeResourceExhausted = 8
+)
+
+var errUnknownSnapshot = &grpcwire.Error{Code: grpcwire.CodeNotFound, Message: "unknown snapshot"}
+
+// serverSnapshot is a snapshot opened by a client.
+type serverSnapshot struct {
+	snap   ethdb.Snapshot
+	prefix []byte
+	used   time.Time
+}
+
+// Server implements the remote key-value store service on top of a local
+// database, keeping every keyspace under its own key prefix. It is a reference
+// implementation of the service, e.g. to share a database in tests, and not
+// meant to front production data.
+type Server struct {
+	db     ethdb.KeyValueStore
+	server *grpcwire.Server
+
+	lock      sync.Mutex
+	snapshots map[uint64]*serverSnapshot
+	nextID    uint64
+}
+
+// NewServer creates a key-value store server backed by the given database.
+func NewServer(db ethdb.KeyValueStore) *Server {
+	s := &Server{
+		db:        db,
+		server:    grpcwire.NewServer(service),
+		snapshots: make(map[uint64]*serverSnapshot),
+	}
+	s.server.Register("Get", s.get)
+	s.server.Register("Has", s.has)
+	s.server.Register("Write", s.write)
+	s.server.Register("Scan", s.scan)
+	s.server.Register("OpenSnapshot", s.openSnapshot)
+	s.server.Register("ReleaseSnapshot", s.releaseSnapshot)
+	s.server.Register("Stat", s.stat)
+	s.server.Register("Compact", s.compact)
+	return s
+}
+
+// Handler returns the HTTP handler serving the service.
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler()
+}
+
+// Close releases all open snapshots.
+func (s *Server) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, snap := range s.snapshots {
+		snap.snap.Release()
+		delete(s.snapshots, id)
+	}
+}
+
+// keyspacePrefix returns the key prefix of the keyspace of a request. The length
+// of the keyspace is included, so no keyspace is a prefix of another.
+func keyspacePrefix(req []byte) ([]byte, error) {
+	keyspace, err := grpcwire.DecodeString(req, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyspace) > 255 {
+		return nil, errors.New("keyspace too long")
+	}
+	return append([]byte{byte(len(keyspace))}, keyspace...), nil
+}
+
+// decodeKey returns the last occurrence of a bytes field, or nil.
+func decodeKey(req []byte, field protowire.Number) ([]byte, error) {
+	values, err := grpcwire.DecodeBytes(req, field)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	return values[len(values)-1], nil
+}
+
+// reader returns the data source of a Get or Has request along with the prefixed
+// key to look up.
+func (s *Server) reader(req []byte) (ethdb.KeyValueReader, []byte, error) {
+	key, err := decodeKey(req, 2)
+	if err != nil {
+		return nil, nil, invalidArgument(err)
+	}
+	id, err := grpcwire.DecodeUint(req, 3)
+	if err != nil {
+		return nil, nil, invalidArgument(err)
+	}
+	if id != 0 {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		snap, ok := s.snapshots[id]
+		if !ok {
+			return nil, nil, errUnknownSnapshot
+		}
+		snap.used = time.Now()
+		return snap.snap, append(append([]byte{}, snap.prefix...), key...), nil
+	}
+	prefix, err := keyspacePrefix(req)
+	if err != nil {
+		return nil, nil, invalidArgument(err)
+	}
+	return s.db, append(prefix, key...), nil
+}
+
+func (s *Server) get(req []byte) ([]byte, error) {
+	reader, key, err := s.reader(req)
+	if err != nil {
+		return nil, err
+	}
+	value, err := reader.Get(key)
+	if err != nil {
+		// Missing keys are reported as errors, tell them apart from failures
+		if found, herr := reader.Has(key); herr != nil || found {
+			return nil, err
+		}
+		return appendBool(nil, 1, false), nil
+	}
+	res := appendBool(nil, 1, true)
+	res = protowire.AppendTag(res, 2, protowire.BytesType)
+	res = protowire.AppendBytes(res, value)
+	return res, nil
+}
+
+func (s *Server) has(req []byte) ([]byte, error) {
+	reader, key, err := s.reader(req)
+	if err != nil {
+		return nil, err
+	}
+	found, err := reader.Has(key)
+	if err != nil {
+		return nil, err
+	}
+	return appendBool(nil, 1, found), nil
+}
+
+func (s *Server) write(req []byte) ([]byte, error) {
+	prefix, err := keyspacePrefix(req)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	ops, err := grpcwire.DecodeBytes(req, 2)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	batch := s.db.NewBatch()
+	for _, op := range ops {
+		key, err := decodeKey(op, 1)
+		if err != nil {
+			return nil, invalidArgument(err)
+		}
+		del, err := grpcwire.DecodeUint(op, 3)
+		if err != nil {
+			return nil, invalidArgument(err)
+		}
+		key = append(append([]byte{}, prefix...), key...)
+		if del != 0 {
+			err = batch.Delete(key)
+		} else {
+			var value []byte
+			if value, err = decodeKey(op, 2); err != nil {
+				return nil, invalidArgument(err)
+			}
+			if value == nil {
+				value = []byte{}
+			}
+			err = batch.Put(key, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, batch.Write()
+}
+
+func (s *Server) scan(req []byte) ([]byte, error) {
+	prefix, err := keyspacePrefix(req)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	sub, err := decodeKey(req, 2)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	start, err := decodeKey(req, 3)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	limit, err := grpcwire.DecodeUint(req, 4)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	if limit == 0 || limit > maxScanEntries {
+		limit = maxScanEntries
+	}
+	it := s.db.NewIterator(append(prefix, sub...), start)
+	defer it.Release()
+
+	var (
+		res  []byte
+		more bool
+	)
+	for n := uint64(0); it.Next(); n++ {
+		if n >= limit || len(res) >= maxScanSize {
+			more = true
+			break
+		}
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, it.Key()[len(prefix):])
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, it.Value())
+
+		res = protowire.AppendTag(res, 1, protowire.BytesType)
+		res = protowire.AppendBytes(res, entry)
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return appendBool(res, 2, more), nil
+}
+
+func (s *Server) openSnapshot(req []byte) ([]byte, error) {
+	prefix, err := keyspacePrefix(req)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.snapshots) >= maxSnapshots {
+		// Make room by releasing the snapshots abandoned by their clients
+		for id, snap := range s.snapshots {
+			if time.Since(snap.used) > snapshotTimeout {
+				snap.snap.Release()
+				delete(s.snapshots, id)
+			}
+		}
+		if len(s.snapshots) >= maxSnapshots {
+			return nil, &grpcwire.Error{Code: codeResourceExhausted, Message: "too many open snapshots"}
+		}
+	}
+	snap, err := s.db.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	s.nextID++
+	s.snapshots[s.nextID] = &serverSnapshot{snap: snap, prefix: prefix, used: time.Now()}
+
+	var res []byte
+	res = protowire.AppendTag(res, 1, protowire.VarintType)
+	res = protowire.AppendVarint(res, s.nextID)
+	return res, nil
+}
+
+func (s *Server) releaseSnapshot(req []byte) ([]byte, error) {
+	id, err := grpcwire.DecodeUint(req, 1)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snap, ok := s.snapshots[id]
+	if !ok {
+		return nil, errUnknownSnapshot
+	}
+	snap.snap.Release()
+	delete(s.snapshots, id)
+	return nil, nil
+}
+
+func (s *Server) stat(req []byte) ([]byte, error) {
+	property, err := grpcwire.DecodeString(req, 1)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	var value string
+	if property != "" {
+		if value, err = s.db.Stat(property); err != nil {
+			return nil, err
+		}
+	}
+	var res []byte
+	res = protowire.AppendTag(res, 1, protowire.BytesType)
+	res = protowire.AppendString(res, value)
+	return res, nil
+}
+
+func (s *Server) compact(req []byte) ([]byte, error) {
+	prefix, err := keyspacePrefix(req)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	start, err := decodeKey(req, 2)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	limit, err := decodeKey(req, 3)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	// An empty limit compacts to the end of the keyspace, and beyond
+	var end []byte
+	if len(limit) > 0 {
+		end = append(append([]byte{}, prefix...), limit...)
+	}
+	return nil, s.db.Compact(append(prefix, start...), end)
+}
+
+func appendBool(b []byte, field protowire.Number, v bool) []byte {
+	b = protowire.AppendTag(b, field, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeBool(v))
+}
+
+func invalidArgument(err error) error {
+	return &grpcwire.Error{Code: grpcwire.CodeInvalidArgument, Message: err.Error()}
+}
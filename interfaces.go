@@ -122,6 +122,11 @@ type SyncProgress struct {
 
 	HealingTrienodes uint64 // Number of state trie nodes pending
 	HealingBytecode  uint64 // Number of bytecodes pending
+
+	// Transaction index fields, informational only: indexing doesn't count as
+	// synchronisation.
+	TxIndexFinishedBlocks  uint64 // Number of blocks whose transactions are indexed
+	TxIndexRemainingBlocks uint64 // Number of blocks whose transactions are not indexed yet
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
@@ -167,11 +167,17 @@ func (*HandlerT) WriteBlockProfile(file string) error {
 // MutexProfile turns on mutex profiling for nsec seconds and writes profile data to file.
 // It uses a profile rate of 1 for most accurate information. If a different rate is
 // desired, set the rate and write the profile manually.
-func (*HandlerT) MutexProfile(file string, nsec uint) error {
+//
+// If file is empty, the profile is returned instead, either as a JSON summary (the
+// default) or pprof encoded if format is "pprof".
+func (*HandlerT) MutexProfile(file string, nsec uint, format *string) (interface{}, error) {
+	if file == "" {
+		return captureMutexProfile(nsec, format)
+	}
 	runtime.SetMutexProfileFraction(1)
 	time.Sleep(time.Duration(nsec) * time.Second)
 	defer runtime.SetMutexProfileFraction(0)
-	return writeProfile("mutex", file)
+	return nil, writeProfile("mutex", file)
 }
 
 // SetMutexProfileFraction sets the rate of mutex profiling.
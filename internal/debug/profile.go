@@ -0,0 +1,185 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Formats of the profiles returned over RPC.
+const (
+	profileFormatJSON  = "json"  // Summary of the profile records, see ContentionRecord and GoroutineRecord
+	profileFormatPprof = "pprof" // Gzipped protobuf, as written to profile files
+)
+
+// ContentionRecord summarizes the blocking or mutex contention events sharing a
+// stack in a JSON profile.
+type ContentionRecord struct {
+	Count  int64    `json:"count"`  // Number of contention events
+	Cycles int64    `json:"cycles"` // Total delay in CPU cycles
+	Stack  []string `json:"stack"`
+}
+
+// GoroutineRecord summarizes the goroutines sharing a stack in a JSON profile.
+type GoroutineRecord struct {
+	Count int      `json:"count"`
+	Stack []string `json:"stack"`
+}
+
+// GoroutineProfile returns a snapshot of the stacks of all goroutines, either as
+// a JSON summary grouping the goroutines by stack (the default) or pprof encoded
+// if format is "pprof".
+func (*HandlerT) GoroutineProfile(format *string) (interface{}, error) {
+	f, err := parseProfileFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	if f == profileFormatPprof {
+		return encodeProfile("goroutine")
+	}
+	var records []runtime.StackRecord
+	for n := runtime.NumGoroutine(); ; {
+		records = make([]runtime.StackRecord, n+10)
+		if n, ok := runtime.GoroutineProfile(records); ok {
+			records = records[:n]
+			break
+		}
+		n = runtime.NumGoroutine()
+	}
+	var (
+		summary []*GoroutineRecord
+		stacks  = make(map[[32]uintptr]*GoroutineRecord)
+	)
+	for _, r := range records {
+		if rec, ok := stacks[r.Stack0]; ok {
+			rec.Count++
+			continue
+		}
+		rec := &GoroutineRecord{Count: 1, Stack: formatStack(r.Stack())}
+		stacks[r.Stack0] = rec
+		summary = append(summary, rec)
+	}
+	sort.SliceStable(summary, func(i, j int) bool { return summary[i].Count > summary[j].Count })
+	return summary, nil
+}
+
+// captureMutexProfile turns on mutex profiling for nsec seconds and returns the
+// collected profile in the given format.
+func captureMutexProfile(nsec uint, format *string) (interface{}, error) {
+	f, err := parseProfileFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	runtime.SetMutexProfileFraction(1)
+	time.Sleep(time.Duration(nsec) * time.Second)
+	defer runtime.SetMutexProfileFraction(0)
+
+	if f == profileFormatPprof {
+		return encodeProfile("mutex")
+	}
+	return contentionSummary(runtime.MutexProfile), nil
+}
+
+// BlockProfileRate turns on goroutine block profiling at the given rate for nsec
+// seconds and returns the collected profile, either as a JSON summary (the
+// default) or pprof encoded if format is "pprof". A rate of 1 records every
+// blocking event.
+func (*HandlerT) BlockProfileRate(rate int, nsec uint, format *string) (interface{}, error) {
+	f, err := parseProfileFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("invalid block profile rate %d", rate)
+	}
+	runtime.SetBlockProfileRate(rate)
+	time.Sleep(time.Duration(nsec) * time.Second)
+	defer runtime.SetBlockProfileRate(0)
+
+	if f == profileFormatPprof {
+		return encodeProfile("block")
+	}
+	return contentionSummary(runtime.BlockProfile), nil
+}
+
+func parseProfileFormat(format *string) (string, error) {
+	if format == nil || *format == "" {
+		return profileFormatJSON, nil
+	}
+	switch *format {
+	case profileFormatJSON, profileFormatPprof:
+		return *format, nil
+	default:
+		return "", fmt.Errorf("unknown profile format %q", *format)
+	}
+}
+
+// encodeProfile returns the pprof encoding of the named profile.
+func encodeProfile(name string) (hexutil.Bytes, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// contentionSummary collects the records of a blocking or mutex profile, sorted
+// by their total delay.
+func contentionSummary(profile func([]runtime.BlockProfileRecord) (int, bool)) []*ContentionRecord {
+	var records []runtime.BlockProfileRecord
+	for n, _ := profile(nil); ; {
+		records = make([]runtime.BlockProfileRecord, n+50)
+		if n, ok := profile(records); ok {
+			records = records[:n]
+			break
+		}
+		n, _ = profile(nil)
+	}
+	summary := make([]*ContentionRecord, 0, len(records))
+	for _, r := range records {
+		summary = append(summary, &ContentionRecord{
+			Count:  r.Count,
+			Cycles: r.Cycles,
+			Stack:  formatStack(r.Stack()),
+		})
+	}
+	sort.SliceStable(summary, func(i, j int) bool { return summary[i].Cycles > summary[j].Cycles })
+	return summary
+}
+
+// formatStack symbolizes the program counters of a stack.
+func formatStack(pcs []uintptr) []string {
+	var (
+		stack  []string
+		frames = runtime.CallersFrames(pcs)
+	)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
+	}
+	return stack
+}
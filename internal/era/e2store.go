@@ -0,0 +1,135 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// headerSize is the size of the header preceding every e2store entry: a two byte
+// type, a four byte length and two reserved zero bytes.
+const headerSize = 8
+
+var errReservedBytes = errors.New("reserved bytes of entry header non-zero")
+
+// Entry is a type-length-value record of an e2store file.
+type Entry struct {
+	Type  uint16
+	Value []byte
+}
+
+// Writer writes e2store entries to an underlying stream.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter creates an e2store writer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a single entry, returning the number of bytes written including
+// the header.
+func (w *Writer) Write(typ uint16, value []byte) (int, error) {
+	if uint64(len(value)) > uint64(^uint32(0)) {
+		return 0, fmt.Errorf("entry too large: %d bytes", len(value))
+	}
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint16(header[:2], typ)
+	binary.LittleEndian.PutUint32(header[2:6], uint32(len(value)))
+	if n, err := w.w.Write(header[:]); err != nil {
+		return n, err
+	}
+	n, err := w.w.Write(value)
+	return headerSize + n, err
+}
+
+// Reader reads e2store entries from an underlying source, either sequentially
+// or at given offsets.
+type Reader struct {
+	r      io.ReaderAt
+	offset int64
+}
+
+// NewReader creates an e2store reader.
+func NewReader(r io.ReaderAt) *Reader {
+	return &Reader{r: r}
+}
+
+// Read reads the next entry, returning io.EOF at the end of the stream.
+func (r *Reader) Read() (*Entry, error) {
+	entry, n, err := r.ReadAt(r.offset)
+	if err != nil {
+		return nil, err
+	}
+	r.offset += int64(n)
+	return entry, nil
+}
+
+// ReadAt reads the entry at the given offset, returning it along with its size
+// including the header.
+func (r *Reader) ReadAt(off int64) (*Entry, int, error) {
+	typ, length, err := r.ReadMetadataAt(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	entry := &Entry{Type: typ, Value: make([]byte, length)}
+	if length > 0 {
+		if _, err := r.r.ReadAt(entry.Value, off+headerSize); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, 0, err
+		}
+	}
+	return entry, headerSize + int(length), nil
+}
+
+// ReadMetadataAt reads the type and the value length of the entry at the given
+// offset.
+func (r *Reader) ReadMetadataAt(off int64) (uint16, uint32, error) {
+	var header [headerSize]byte
+	if n, err := r.r.ReadAt(header[:], off); err != nil {
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	if header[6] != 0 || header[7] != 0 {
+		return 0, 0, errReservedBytes
+	}
+	return binary.LittleEndian.Uint16(header[:2]), binary.LittleEndian.Uint32(header[2:6]), nil
+}
+
+// Find returns the first entry of the given type, starting at the beginning of
+// the stream.
+func (r *Reader) Find(typ uint16) (*Entry, error) {
+	for off := int64(0); ; {
+		t, length, err := r.ReadMetadataAt(off)
+		if err != nil {
+			return nil, err
+		}
+		if t == typ {
+			entry, _, err := r.ReadAt(off)
+			return entry, err
+		}
+		off += headerSize + int64(length)
+	}
+}
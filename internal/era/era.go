@@ -0,0 +1,438 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era implements the era1 archive format, storing a fixed range of
+// pre-merge style history (headers, bodies, receipts and total difficulties) in a
+// single e2store file together with an accumulator committing to its content.
+//
+// An era1 file is laid out as
+//
+//	Version | (CompressedHeader | CompressedBody | CompressedReceipts | TotalDifficulty)* | Accumulator | BlockIndex
+//
+// where the accumulator is the SSZ hash tree root of the list of (hash, total
+// difficulty) records of the blocks, and the block index maps block numbers to
+// the offsets of their entries.
+package era
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/ssz"
+	"github.com/golang/snappy"
+)
+
+// Entry types of era1 files.
+const (
+	TypeVersion            uint16 = 0x3265
+	TypeCompressedHeader   uint16 = 0x03
+	TypeCompressedBody     uint16 = 0x04
+	TypeCompressedReceipts uint16 = 0x05
+	TypeTotalDifficulty    uint16 = 0x06
+	TypeAccumulator        uint16 = 0x07
+	TypeBlockIndex         uint16 = 0x3266
+)
+
+// MaxEra1Size is the maximum number of blocks in an era1 file.
+const MaxEra1Size = 8192
+
+var (
+	errTooManyBlocks = errors.New("era1 file full")
+	errEmptyEra      = errors.New("era1 file contains no blocks")
+	errOutOfRange    = errors.New("block number out of range")
+)
+
+// Filename returns the name of the era1 file of the given epoch, in the form
+// <network>-<epoch>-<short root>.era1.
+func Filename(network string, epoch int, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%s.era1", network, epoch, hex.EncodeToString(root[:4]))
+}
+
+// ReadDir returns the names of the era1 files of the given network in the
+// directory, ordered by epoch. The epochs of the files must be contiguous.
+func ReadDir(dir, network string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		files []string
+		next  = -1
+	)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".era1" || !strings.HasPrefix(name, network+"-") {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(name, ".era1"), "-")
+		if len(parts) != 3 || parts[0] != network {
+			return nil, fmt.Errorf("malformed era1 filename: %s", name)
+		}
+		epoch, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed era1 filename: %s", name)
+		}
+		if next != -1 && epoch != next {
+			return nil, fmt.Errorf("missing epoch %d", next)
+		}
+		next = epoch + 1
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+// ComputeAccumulator returns the hash tree root of the header records of the
+// given blocks, committing to their hashes and total difficulties.
+func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) (common.Hash, error) {
+	if len(hashes) != len(tds) {
+		return common.Hash{}, fmt.Errorf("have %d hashes and %d total difficulties", len(hashes), len(tds))
+	}
+	if len(hashes) > MaxEra1Size {
+		return common.Hash{}, errTooManyBlocks
+	}
+	records := make([][32]byte, len(hashes))
+	for i := range hashes {
+		td, err := ssz.Uint256(tds[i])
+		if err != nil {
+			return common.Hash{}, err
+		}
+		var chunk [32]byte
+		copy(chunk[:], td)
+		records[i] = ssz.Merkleize([][32]byte{hashes[i], chunk}, 0)
+	}
+	return ssz.ListRoot(records, MaxEra1Size), nil
+}
+
+// Builder writes an era1 file. Blocks must be added in ascending order, after
+// the last one Finalize writes the accumulator and the block index.
+type Builder struct {
+	w       *Writer
+	start   uint64
+	offsets []uint64 // Offsets of the header entries of the blocks
+	hashes  []common.Hash
+	tds     []*big.Int
+	written uint64
+
+	buf    *bytes.Buffer
+	snappy *snappy.Writer
+}
+
+// NewBuilder creates an era1 builder writing to w.
+func NewBuilder(w io.Writer) *Builder {
+	buf := new(bytes.Buffer)
+	return &Builder{
+		w:      NewWriter(w),
+		buf:    buf,
+		snappy: snappy.NewBufferedWriter(buf),
+	}
+}
+
+// Add appends a block along with its receipts and the total difficulty of the
+// chain including the block.
+func (b *Builder) Add(block *types.Block, receipts types.Receipts, td *big.Int) error {
+	header, err := rlp.EncodeToBytes(block.Header())
+	if err != nil {
+		return err
+	}
+	body, err := rlp.EncodeToBytes(block.Body())
+	if err != nil {
+		return err
+	}
+	if receipts == nil {
+		receipts = types.Receipts{}
+	}
+	rs, err := rlp.EncodeToBytes(receipts)
+	if err != nil {
+		return err
+	}
+	return b.AddRLP(header, body, rs, block.NumberU64(), block.Hash(), td)
+}
+
+// AddRLP appends a block given in its RLP encoded parts.
+func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash common.Hash, td *big.Int) error {
+	if len(b.offsets) >= MaxEra1Size {
+		return errTooManyBlocks
+	}
+	if len(b.offsets) == 0 {
+		b.start = number
+		if err := b.write(TypeVersion, nil); err != nil {
+			return err
+		}
+	} else if want := b.start + uint64(len(b.offsets)); number != want {
+		return fmt.Errorf("non contiguous block %d, want %d", number, want)
+	}
+	b.offsets = append(b.offsets, b.written)
+	b.hashes = append(b.hashes, hash)
+	b.tds = append(b.tds, new(big.Int).Set(td))
+
+	for _, entry := range []struct {
+		typ  uint16
+		data []byte
+	}{
+		{TypeCompressedHeader, header},
+		{TypeCompressedBody, body},
+		{TypeCompressedReceipts, receipts},
+	} {
+		if err := b.writeCompressed(entry.typ, entry.data); err != nil {
+			return err
+		}
+	}
+	ltd, err := ssz.Uint256(td)
+	if err != nil {
+		return err
+	}
+	return b.write(TypeTotalDifficulty, ltd)
+}
+
+// Finalize writes the accumulator and the block index, returning the
+// accumulator root.
+func (b *Builder) Finalize() (common.Hash, error) {
+	if len(b.offsets) == 0 {
+		return common.Hash{}, errEmptyEra
+	}
+	root, err := ComputeAccumulator(b.hashes, b.tds)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := b.write(TypeAccumulator, root[:]); err != nil {
+		return common.Hash{}, err
+	}
+	// The offsets of the index are relative to the index entry itself
+	index := make([]byte, 16+8*len(b.offsets))
+	binary.LittleEndian.PutUint64(index, b.start)
+	for i, offset := range b.offsets {
+		binary.LittleEndian.PutUint64(index[8+8*i:], uint64(int64(offset)-int64(b.written)))
+	}
+	binary.LittleEndian.PutUint64(index[8+8*len(b.offsets):], uint64(len(b.offsets)))
+	if err := b.write(TypeBlockIndex, index); err != nil {
+		return common.Hash{}, err
+	}
+	return root, nil
+}
+
+func (b *Builder) write(typ uint16, data []byte) error {
+	n, err := b.w.Write(typ, data)
+	b.written += uint64(n)
+	return err
+}
+
+func (b *Builder) writeCompressed(typ uint16, data []byte) error {
+	b.buf.Reset()
+	b.snappy.Reset(b.buf)
+	if _, err := b.snappy.Write(data); err != nil {
+		return err
+	}
+	if err := b.snappy.Flush(); err != nil {
+		return err
+	}
+	return b.write(typ, b.buf.Bytes())
+}
+
+// ReadAtSeekCloser is the interface of the source of an era1 file.
+type ReadAtSeekCloser interface {
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// Era is a reader of an era1 file.
+type Era struct {
+	f     ReadAtSeekCloser
+	s     *Reader
+	start uint64
+	count uint64
+	index int64 // Offset of the block index entry
+}
+
+// Open opens the era1 file at the given path.
+func Open(filename string) (*Era, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	e, err := From(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// From creates an era1 reader from an opened file, locating its block index.
+func From(f ReadAtSeekCloser) (*Era, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if size < headerSize+24 {
+		return nil, errEmptyEra
+	}
+	var buf [8]byte
+	if _, err := f.ReadAt(buf[:], size-8); err != nil {
+		return nil, err
+	}
+	count := binary.LittleEndian.Uint64(buf[:])
+	if count == 0 || count > MaxEra1Size {
+		return nil, fmt.Errorf("invalid block count %d", count)
+	}
+	e := &Era{
+		f:     f,
+		s:     NewReader(f),
+		count: count,
+		index: size - int64(headerSize+16+8*count),
+	}
+	typ, length, err := e.s.ReadMetadataAt(e.index)
+	if err != nil {
+		return nil, err
+	}
+	if typ != TypeBlockIndex || uint64(length) != 16+8*count {
+		return nil, errors.New("invalid block index")
+	}
+	if _, err := f.ReadAt(buf[:], e.index+headerSize); err != nil {
+		return nil, err
+	}
+	e.start = binary.LittleEndian.Uint64(buf[:])
+
+	version, _, err := e.s.ReadAt(0)
+	if err != nil {
+		return nil, err
+	}
+	if version.Type != TypeVersion {
+		return nil, errors.New("missing version entry")
+	}
+	return e, nil
+}
+
+// Close closes the underlying file.
+func (e *Era) Close() error {
+	return e.f.Close()
+}
+
+// Start returns the number of the first block of the file.
+func (e *Era) Start() uint64 {
+	return e.start
+}
+
+// Count returns the number of blocks in the file.
+func (e *Era) Count() uint64 {
+	return e.count
+}
+
+// Accumulator returns the accumulator root stored in the file.
+func (e *Era) Accumulator() (common.Hash, error) {
+	entry, err := e.s.Find(TypeAccumulator)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(entry.Value) != common.HashLength {
+		return common.Hash{}, errors.New("invalid accumulator")
+	}
+	return common.BytesToHash(entry.Value), nil
+}
+
+// GetBlockByNumber returns the block with the given number.
+func (e *Era) GetBlockByNumber(num uint64) (*types.Block, error) {
+	block, _, _, err := e.ReadBlock(num)
+	return block, err
+}
+
+// GetReceiptsByNumber returns the receipts of the block with the given number.
+func (e *Era) GetReceiptsByNumber(num uint64) (types.Receipts, error) {
+	_, receipts, _, err := e.ReadBlock(num)
+	return receipts, err
+}
+
+// ReadBlock returns the block with the given number along with its receipts and
+// the total difficulty of the chain including it.
+func (e *Era) ReadBlock(num uint64) (*types.Block, types.Receipts, *big.Int, error) {
+	off, err := e.offset(num)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var (
+		header   types.Header
+		body     types.Body
+		receipts types.Receipts
+	)
+	for _, entry := range []struct {
+		typ uint16
+		val interface{}
+	}{
+		{TypeCompressedHeader, &header},
+		{TypeCompressedBody, &body},
+		{TypeCompressedReceipts, &receipts},
+	} {
+		n, err := e.readCompressed(off, entry.typ, entry.val)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("block %d: %v", num, err)
+		}
+		off += int64(n)
+	}
+	entry, _, err := e.s.ReadAt(off)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if entry.Type != TypeTotalDifficulty {
+		return nil, nil, nil, fmt.Errorf("block %d: unexpected entry type %#x, want total difficulty", num, entry.Type)
+	}
+	td, err := ssz.DecodeUint256(entry.Value)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	block := types.NewBlockWithHeader(&header).WithBody(body.Transactions, body.Uncles)
+	return block, receipts, td, nil
+}
+
+// offset returns the offset of the header entry of the given block.
+func (e *Era) offset(num uint64) (int64, error) {
+	if num < e.start || num >= e.start+e.count {
+		return 0, errOutOfRange
+	}
+	var buf [8]byte
+	if _, err := e.f.ReadAt(buf[:], e.index+headerSize+8+8*int64(num-e.start)); err != nil {
+		return 0, err
+	}
+	return e.index + int64(binary.LittleEndian.Uint64(buf[:])), nil
+}
+
+// readCompressed decodes the compressed entry of the given type at the offset,
+// returning the size of the entry.
+func (e *Era) readCompressed(off int64, typ uint16, val interface{}) (int, error) {
+	entry, n, err := e.s.ReadAt(off)
+	if err != nil {
+		return 0, err
+	}
+	if entry.Type != typ {
+		return 0, fmt.Errorf("unexpected entry type %#x, want %#x", entry.Type, typ)
+	}
+	data, err := io.ReadAll(snappy.NewReader(bytes.NewReader(entry.Value)))
+	if err != nil {
+		return 0, err
+	}
+	return n, rlp.DecodeBytes(data, val)
+}
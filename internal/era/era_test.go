@@ -0,0 +1,167 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestE2Store(t *testing.T) {
+	var (
+		buf     = new(bytes.Buffer)
+		w       = NewWriter(buf)
+		entries = []Entry{
+			{Type: 0xffff, Value: nil},
+			{Type: 0x42, Value: []byte{1, 2, 3}},
+			{Type: 0x1000, Value: bytes.Repeat([]byte{0xaa}, 1024)},
+		}
+	)
+	for _, e := range entries {
+		if _, err := w.Write(e.Type, e.Value); err != nil {
+			t.Fatalf("failed to write entry: %v", err)
+		}
+	}
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	for i, want := range entries {
+		have, err := r.Read()
+		if err != nil {
+			t.Fatalf("entry %d: failed to read: %v", i, err)
+		}
+		if have.Type != want.Type || !bytes.Equal(have.Value, want.Value) {
+			t.Fatalf("entry %d: mismatch: have %x/%x, want %x/%x", i, have.Type, have.Value, want.Type, want.Value)
+		}
+	}
+	if _, err := r.Read(); err == nil {
+		t.Fatal("expected error at end of stream")
+	}
+	if e, err := r.Find(0x1000); err != nil || len(e.Value) != 1024 {
+		t.Fatalf("failed to find entry: %v", err)
+	}
+	// Non-zero reserved bytes must be rejected
+	corrupt := append([]byte{}, buf.Bytes()...)
+	corrupt[7] = 1
+	if _, err := NewReader(bytes.NewReader(corrupt)).Read(); err != errReservedBytes {
+		t.Fatalf("corrupt header error mismatch: have %v, want %v", err, errReservedBytes)
+	}
+}
+
+func TestEra1(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		db      = rawdb.NewMemoryDatabase()
+		genesis = (&core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}).MustCommit(db)
+		signer = types.LatestSigner(params.TestChainConfig)
+	)
+	blocks, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 128, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{0x01}, big.NewInt(1), params.TxGas, b.BaseFee(), nil), signer, key)
+		b.AddTx(tx)
+	})
+	var (
+		buf     = new(bytes.Buffer)
+		builder = NewBuilder(buf)
+		hashes  []common.Hash
+		tds     []*big.Int
+		td      = new(big.Int).Set(genesis.Difficulty())
+	)
+	for i, block := range blocks {
+		td.Add(td, block.Difficulty())
+		if err := builder.Add(block, receipts[i], td); err != nil {
+			t.Fatalf("failed to add block %d: %v", block.NumberU64(), err)
+		}
+		hashes = append(hashes, block.Hash())
+		tds = append(tds, new(big.Int).Set(td))
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatalf("failed to finalize: %v", err)
+	}
+	if want, _ := ComputeAccumulator(hashes, tds); root != want {
+		t.Fatalf("accumulator mismatch: have %x, want %x", root, want)
+	}
+	// Write the file and read everything back
+	path := filepath.Join(t.TempDir(), Filename("test", 0, root))
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open era1 file: %v", err)
+	}
+	defer e.Close()
+
+	if e.Start() != 1 || e.Count() != uint64(len(blocks)) {
+		t.Fatalf("range mismatch: have %d+%d, want 1+%d", e.Start(), e.Count(), len(blocks))
+	}
+	if have, err := e.Accumulator(); err != nil || have != root {
+		t.Fatalf("stored accumulator mismatch: have %x, want %x (err %v)", have, root, err)
+	}
+	for i, want := range blocks {
+		block, rs, td, err := e.ReadBlock(want.NumberU64())
+		if err != nil {
+			t.Fatalf("failed to read block %d: %v", want.NumberU64(), err)
+		}
+		if block.Hash() != want.Hash() {
+			t.Fatalf("block %d: hash mismatch", want.NumberU64())
+		}
+		if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != want.Transactions()[0].Hash() {
+			t.Fatalf("block %d: body mismatch", want.NumberU64())
+		}
+		if types.DeriveSha(rs, trie.NewStackTrie(nil)) != want.ReceiptHash() {
+			t.Fatalf("block %d: receipts mismatch", want.NumberU64())
+		}
+		if td.Cmp(tds[i]) != 0 {
+			t.Fatalf("block %d: td mismatch: have %v, want %v", want.NumberU64(), td, tds[i])
+		}
+	}
+	if _, err := e.GetBlockByNumber(0); err != errOutOfRange {
+		t.Fatalf("out of range error mismatch: have %v, want %v", err, errOutOfRange)
+	}
+	if _, err := e.GetBlockByNumber(uint64(len(blocks)) + 1); err != errOutOfRange {
+		t.Fatalf("out of range error mismatch: have %v, want %v", err, errOutOfRange)
+	}
+}
+
+func TestBuilderNonContiguous(t *testing.T) {
+	var (
+		genesis = new(core.Genesis).ToBlock()
+		b       = NewBuilder(new(bytes.Buffer))
+	)
+	if err := b.Add(genesis, nil, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	next := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2)})
+	if err := b.Add(next, nil, big.NewInt(2)); err == nil {
+		t.Fatal("expected error for non contiguous block")
+	}
+}
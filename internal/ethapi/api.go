@@ -18,6 +18,7 @@ package ethapi
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -35,10 +36,13 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/genesis"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -84,11 +88,32 @@ type feeHistoryResult struct {
 	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
 	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
 	GasUsedRatio []float64        `json:"gasUsedRatio"`
+
+	// RewardByType holds the reward percentiles of each block broken down by
+	// transaction type. It is only included if requested.
+	RewardByType []map[hexutil.Uint64][]*hexutil.Big `json:"rewardByType,omitempty"`
+
+	// Congestion holds the current inputs of the priority fee suggestion. It is
+	// only included when the history ends at the latest or pending block.
+	Congestion *gasprice.Congestion `json:"congestion,omitempty"`
 }
 
-// FeeHistory returns the fee market history.
-func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHex, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
-	oldest, reward, baseFee, gasUsed, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+// FeeHistory returns the fee market history. If rewardByType is set, the reward
+// percentiles are additionally broken down by transaction type.
+func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHex, lastBlock rpc.BlockNumber, rewardPercentiles []float64, rewardByType *bool) (*feeHistoryResult, error) {
+	var (
+		oldest     *big.Int
+		reward     [][]*big.Int
+		typeReward []map[uint8][]*big.Int
+		baseFee    []*big.Int
+		gasUsed    []float64
+		err        error
+	)
+	if rewardByType != nil && *rewardByType {
+		oldest, reward, typeReward, baseFee, gasUsed, err = s.b.FeeHistoryByType(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	} else {
+		oldest, reward, baseFee, gasUsed, err = s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -105,12 +130,30 @@ func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHe
 			}
 		}
 	}
+	if typeReward != nil {
+		results.RewardByType = make([]map[hexutil.Uint64][]*hexutil.Big, len(typeReward))
+		for i, byType := range typeReward {
+			results.RewardByType[i] = make(map[hexutil.Uint64][]*hexutil.Big, len(byType))
+			for txType, w := range byType {
+				rewards := make([]*hexutil.Big, len(w))
+				for j, v := range w {
+					rewards[j] = (*hexutil.Big)(v)
+				}
+				results.RewardByType[i][hexutil.Uint64(txType)] = rewards
+			}
+		}
+	}
 	if baseFee != nil {
 		results.BaseFee = make([]*hexutil.Big, len(baseFee))
 		for i, v := range baseFee {
 			results.BaseFee[i] = (*hexutil.Big)(v)
 		}
 	}
+	if lastBlock == rpc.LatestBlockNumber || lastBlock == rpc.PendingBlockNumber {
+		if results.Congestion, err = s.b.FeeCongestion(ctx); err != nil {
+			return nil, err
+		}
+	}
 	return results, nil
 }
 
@@ -145,9 +188,24 @@ func (s *EthereumAPI) Syncing() (interface{}, error) {
 		"healedBytecodeBytes": hexutil.Uint64(progress.HealedBytecodeBytes),
 		"healingTrienodes":    hexutil.Uint64(progress.HealingTrienodes),
 		"healingBytecode":     hexutil.Uint64(progress.HealingBytecode),
+
+		"txIndexFinishedBlocks":  hexutil.Uint64(progress.TxIndexFinishedBlocks),
+		"txIndexRemainingBlocks": hexutil.Uint64(progress.TxIndexRemainingBlocks),
 	}, nil
 }
 
+// TxIndexProgress returns the progress of the transaction indexing, which is
+// reported independently of the chain synchronisation:
+// - indexedBlocks:   number of blocks whose transactions are indexed
+// - remainingBlocks: number of blocks whose transactions are not indexed yet
+func (s *EthereumAPI) TxIndexProgress() map[string]interface{} {
+	progress := s.b.SyncProgress()
+	return map[string]interface{}{
+		"indexedBlocks":   hexutil.Uint64(progress.TxIndexFinishedBlocks),
+		"remainingBlocks": hexutil.Uint64(progress.TxIndexRemainingBlocks),
+	}
+}
+
 // TxPoolAPI offers and API for the transaction pool. It only operates on data that is non confidential.
 type TxPoolAPI struct {
 	b Backend
@@ -158,8 +216,13 @@ func NewTxPoolAPI(b Backend) *TxPoolAPI {
 	return &TxPoolAPI{b}
 }
 
-// Content returns the transactions contained within the transaction pool.
-func (s *TxPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction {
+// Content returns the transactions contained within the transaction pool. If a
+// query is given, only the matching transactions are returned as a sorted page
+// instead of the whole pool.
+func (s *TxPoolAPI) Content(query *TxPoolQuery) (interface{}, error) {
+	if query != nil {
+		return s.query(query)
+	}
 	content := map[string]map[string]map[string]*RPCTransaction{
 		"pending": make(map[string]map[string]*RPCTransaction),
 		"queued":  make(map[string]map[string]*RPCTransaction),
@@ -182,7 +245,7 @@ func (s *TxPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction {
 		}
 		content["queued"][account.Hex()] = dump
 	}
-	return content
+	return content, nil
 }
 
 // ContentFrom returns the transactions contained within the transaction pool.
@@ -208,13 +271,25 @@ func (s *TxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCT
 	return content
 }
 
-// Status returns the number of pending and queued transaction in the pool.
-func (s *TxPoolAPI) Status() map[string]hexutil.Uint {
+// Status returns the number of pending and queued transaction in the pool, and
+// the slot usage of its lanes.
+func (s *TxPoolAPI) Status() map[string]interface{} {
 	pending, queue := s.b.Stats()
-	return map[string]hexutil.Uint{
+	status := map[string]interface{}{
 		"pending": hexutil.Uint(pending),
 		"queued":  hexutil.Uint(queue),
 	}
+	if stats := s.b.TxPoolLanes(); len(stats) > 0 {
+		lanes := make(map[string]map[string]hexutil.Uint, len(stats))
+		for name, lane := range stats {
+			lanes[name] = map[string]hexutil.Uint{
+				"slots": hexutil.Uint(lane.Slots),
+				"limit": hexutil.Uint(lane.Limit),
+			}
+		}
+		status["lanes"] = lanes
+	}
+	return status
 }
 
 // Inspect retrieves the content of the transaction pool and flattens it into an
@@ -601,12 +676,14 @@ func (s *PersonalAccountAPI) Unpair(ctx context.Context, url string, pin string)
 
 // BlockChainAPI provides an API to access Ethereum blockchain data.
 type BlockChainAPI struct {
-	b Backend
+	b          Backend
+	timestamps *timestampIndex
+	cache      *responseCache
 }
 
 // NewBlockChainAPI creates a new Ethereum blockchain API.
 func NewBlockChainAPI(b Backend) *BlockChainAPI {
-	return &BlockChainAPI{b}
+	return &BlockChainAPI{b, newTimestampIndex(), newResponseCache(b.RPCCacheSize())}
 }
 
 // ChainId is the EIP-155 replay-protection chain id for the current Ethereum chain config.
@@ -753,9 +830,18 @@ func (s *BlockChainAPI) GetBlockByNumber(ctx context.Context, number rpc.BlockNu
 // GetBlockByHash returns the requested block. When fullTx is true all transactions in the block are returned in full
 // detail, otherwise only the transaction hash is returned.
 func (s *BlockChainAPI) GetBlockByHash(ctx context.Context, hash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	key := fmt.Sprintf("%x-%t", hash, fullTx)
+	if fields, ok := s.cache.get(ctx, s.b, "eth_getBlockByHash", key); ok {
+		return fields.(map[string]interface{}), nil
+	}
 	block, err := s.b.BlockByHash(ctx, hash)
 	if block != nil {
-		return s.rpcMarshalBlock(ctx, block, true, fullTx)
+		fields, err := s.rpcMarshalBlock(ctx, block, true, fullTx)
+		if err == nil {
+			// The content of a block is fixed by its hash, even if reorged
+			s.cache.add("eth_getBlockByHash", key, fields, 0, common.Hash{})
+		}
+		return fields, err
 	}
 	return nil, err
 }
@@ -921,6 +1007,37 @@ func (diff *BlockOverrides) Apply(blockCtx *vm.BlockContext) {
 	}
 }
 
+// MakeHeader returns a copy of the given header with the overridden fields
+// applied.
+func (diff *BlockOverrides) MakeHeader(header *types.Header) *types.Header {
+	header = types.CopyHeader(header)
+	if diff == nil {
+		return header
+	}
+	if diff.Number != nil {
+		header.Number = diff.Number.ToInt()
+	}
+	if diff.Difficulty != nil {
+		header.Difficulty = diff.Difficulty.ToInt()
+	}
+	if diff.Time != nil {
+		header.Time = diff.Time.ToInt().Uint64()
+	}
+	if diff.GasLimit != nil {
+		header.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		header.Coinbase = *diff.Coinbase
+	}
+	if diff.Random != nil {
+		header.MixDigest = *diff.Random
+	}
+	if diff.BaseFee != nil {
+		header.BaseFee = diff.BaseFee.ToInt()
+	}
+	return header
+}
+
 func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
@@ -1013,6 +1130,12 @@ func (e *revertError) ErrorData() interface{} {
 // Note, this function doesn't make and changes in the state/blockchain and is
 // useful to execute and retrieve values.
 func (s *BlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Bytes, error) {
+	key, cacheable := s.callCacheKey(ctx, args, blockNrOrHash, overrides)
+	if cacheable {
+		if res, ok := s.cache.get(ctx, s.b, "eth_call", key); ok {
+			return res.(hexutil.Bytes), nil
+		}
+	}
 	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, s.b.RPCEVMTimeout(), s.b.RPCGasCap())
 	if err != nil {
 		return nil, err
@@ -1021,9 +1144,30 @@ func (s *BlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockNrO
 	if len(result.Revert()) > 0 {
 		return nil, newRevertError(result)
 	}
+	if cacheable && result.Err == nil {
+		s.cache.add("eth_call", key, hexutil.Bytes(result.Return()), 0, common.Hash{})
+	}
 	return result.Return(), result.Err
 }
 
+// callCacheKey returns the key of a call in the response cache. Only calls on
+// finalized blocks without state overrides are cached, as their results can't
+// change anymore.
+func (s *BlockChainAPI) callCacheKey(ctx context.Context, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (string, bool) {
+	if s.cache == nil || overrides != nil {
+		return "", false
+	}
+	header, err := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil || header == nil || !isFinalized(ctx, s.b, header.Number.Uint64()) {
+		return "", false
+	}
+	enc, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%x-%s", header.Hash(), enc), true
+}
+
 func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap uint64) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
@@ -1154,6 +1298,63 @@ func (s *BlockChainAPI) EstimateGas(ctx context.Context, args TransactionArgs, b
 	return DoEstimateGas(ctx, s.b, args, bNrOrHash, s.b.RPCGasCap())
 }
 
+// IntrinsicGasResult is the itemised intrinsic gas of a transaction, as returned
+// by eth_intrinsicGas.
+type IntrinsicGasResult struct {
+	Version      hexutil.Uint64 `json:"version"` // Version of the calculation, see core.IntrinsicGasVersion
+	Fork         string         `json:"fork"`    // Latest fork active at the block the rules were taken from
+	Base         hexutil.Uint64 `json:"base"`
+	Data         hexutil.Uint64 `json:"data"`
+	AccessList   hexutil.Uint64 `json:"accessList"`
+	Total        hexutil.Uint64 `json:"total"`
+	ZeroBytes    hexutil.Uint64 `json:"zeroBytes"`
+	NonZeroBytes hexutil.Uint64 `json:"nonZeroBytes"`
+}
+
+// IntrinsicGas returns the itemised intrinsic gas of the given transaction under
+// the fork rules of the given block, or of the pending block if none is given.
+func (s *BlockChainAPI) IntrinsicGas(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*IntrinsicGasResult, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	header, err := s.b.HeaderByNumberOrHash(ctx, bNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("header not found")
+	}
+	var (
+		config = s.b.ChainConfig()
+		rules  = config.Rules(header.Number, header.Difficulty.Sign() == 0)
+	)
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	cost, err := core.IntrinsicGasOf(args.data(), accessList, args.To == nil, rules)
+	if err != nil {
+		return nil, err
+	}
+	fork := "frontier"
+	for _, name := range genesis.Forks {
+		if block, _ := genesis.ForkBlock(config, name); block != nil && block.Cmp(header.Number) <= 0 {
+			fork = name
+		}
+	}
+	return &IntrinsicGasResult{
+		Version:      core.IntrinsicGasVersion,
+		Fork:         fork,
+		Base:         hexutil.Uint64(cost.Base),
+		Data:         hexutil.Uint64(cost.Data),
+		AccessList:   hexutil.Uint64(cost.AccessList),
+		Total:        hexutil.Uint64(cost.Total),
+		ZeroBytes:    hexutil.Uint64(cost.ZeroBytes),
+		NonZeroBytes: hexutil.Uint64(cost.NonZeroBytes),
+	}, nil
+}
+
 // RPCMarshalHeader converts the given header to the RPC output .
 func RPCMarshalHeader(head *types.Header) map[string]interface{} {
 	result := map[string]interface{}{
@@ -1179,6 +1380,21 @@ func RPCMarshalHeader(head *types.Header) map[string]interface{} {
 	if head.BaseFee != nil {
 		result["baseFeePerGas"] = (*hexutil.Big)(head.BaseFee)
 	}
+	if head.WithdrawalsHash != nil {
+		result["withdrawalsRoot"] = head.WithdrawalsHash
+	}
+	if head.BlobGasUsed != nil {
+		result["blobGasUsed"] = hexutil.Uint64(*head.BlobGasUsed)
+	}
+	if head.ExcessBlobGas != nil {
+		result["excessBlobGas"] = hexutil.Uint64(*head.ExcessBlobGas)
+	}
+	if head.ParentBeaconRoot != nil {
+		result["parentBeaconBlockRoot"] = head.ParentBeaconRoot
+	}
+	if head.RequestsHash != nil {
+		result["requestsHash"] = head.RequestsHash
+	}
 
 	return result
 }
@@ -1454,6 +1670,9 @@ type TransactionAPI struct {
 	b         Backend
 	nonceLock *AddrLocker
 	signer    types.Signer
+	abis      *abi.Registry
+	cache     *responseCache
+	preconf   *receiptSimulator
 }
 
 // NewTransactionAPI creates a new RPC service with methods for interacting with transactions.
@@ -1461,7 +1680,9 @@ func NewTransactionAPI(b Backend, nonceLock *AddrLocker) *TransactionAPI {
 	// The signer used by the API should always be the 'latest' known one because we expect
 	// signers to be backwards-compatible with old transactions.
 	signer := types.LatestSigner(b.ChainConfig())
-	return &TransactionAPI{b, nonceLock, signer}
+	api := &TransactionAPI{b: b, nonceLock: nonceLock, signer: signer, abis: abi.NewRegistry(), cache: newResponseCache(b.RPCCacheSize())}
+	api.preconf = newReceiptSimulator(api)
+	return api
 }
 
 // GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
@@ -1575,6 +1796,9 @@ func (s *TransactionAPI) GetRawTransactionByHash(ctx context.Context, hash commo
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
 func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	if fields, ok := s.cache.get(ctx, s.b, "eth_getTransactionReceipt", hash.Hex()); ok {
+		return fields.(map[string]interface{}), nil
+	}
 	tx, blockHash, blockNumber, index, err := s.b.GetTransaction(ctx, hash)
 	if err != nil {
 		// When the transaction doesn't exist, the RPC method should return JSON null
@@ -1633,6 +1857,8 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
+	// The receipt is only valid while its block is canonical
+	s.cache.add("eth_getTransactionReceipt", hash.Hex(), fields, blockNumber, blockHash)
 	return fields, nil
 }
 
@@ -1880,12 +2106,13 @@ func (s *TransactionAPI) Resend(ctx context.Context, sendArgs TransactionArgs, g
 // DebugAPI is the collection of Ethereum APIs exposed over the debugging
 // namespace.
 type DebugAPI struct {
-	b Backend
+	b    Backend
+	abis *abi.Registry
 }
 
 // NewDebugAPI creates a new instance of DebugAPI.
 func NewDebugAPI(b Backend) *DebugAPI {
-	return &DebugAPI{b: b}
+	return &DebugAPI{b: b, abis: abi.NewRegistry()}
 }
 
 // GetHeaderRlp retrieves the RLP encoded for of a single header.
@@ -1974,6 +2201,17 @@ func (api *DebugAPI) ChaindbCompact() error {
 	return nil
 }
 
+// ChaindbMigrateFreezer rewrites the ancient tables of the chain database in
+// place, e.g. to change their compression, while the node keeps running.
+func (api *DebugAPI) ChaindbMigrateFreezer(config rawdb.FreezerMigrationConfig) error {
+	log.Info("Rewriting freezer tables", "tables", config.Tables, "compression", config.Compression, "filesize", config.MaxFileSize)
+	if err := rawdb.MigrateFreezer(api.b.ChainDb(), &config); err != nil {
+		log.Error("Freezer table rewrite failed", "err", err)
+		return err
+	}
+	return nil
+}
+
 // SetHead rewinds the head of the blockchain to a previous block.
 func (api *DebugAPI) SetHead(number hexutil.Uint64) {
 	api.b.SetHead(uint64(number))
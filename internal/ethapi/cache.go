@@ -0,0 +1,124 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+var (
+	responseCacheHitMeter         = metrics.NewRegisteredMeter("rpc/cache/hit", nil)
+	responseCacheMissMeter        = metrics.NewRegisteredMeter("rpc/cache/miss", nil)
+	responseCacheInvalidatedMeter = metrics.NewRegisteredMeter("rpc/cache/invalidated", nil)
+)
+
+// responseCache is an in-memory cache of the responses of idempotent calls, such
+// as blocks by hash, transaction receipts and calls on finalized blocks.
+//
+// Responses derived from blocks which are not finalized yet are tagged with the
+// block they depend on. Such a response is only served while its block is still
+// canonical, it is dropped as soon as a hit finds the block reorged away.
+//
+// The cached responses are shared between calls and must not be modified.
+type responseCache struct {
+	entries *lru.Cache // method and request key -> *cachedResponse
+}
+
+// cachedResponse is a response of the cache. Immutable responses, which don't
+// depend on the canonical chain, have a zero block hash.
+type cachedResponse struct {
+	value  interface{}
+	number uint64
+	hash   common.Hash
+}
+
+// responseCacheKey identifies a request in the cache.
+type responseCacheKey struct {
+	method string
+	key    string
+}
+
+// newResponseCache creates a cache holding up to the given number of responses.
+// Nil is returned if the size is not positive, disabling the cache.
+func newResponseCache(size int) *responseCache {
+	if size <= 0 {
+		return nil
+	}
+	entries, _ := lru.New(size)
+	return &responseCache{entries: entries}
+}
+
+// get retrieves the cached response of a request, checking that the block it
+// depends on is still canonical.
+func (c *responseCache) get(ctx context.Context, b Backend, method string, key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	id := responseCacheKey{method, key}
+	item, ok := c.entries.Get(id)
+	if !ok {
+		c.mark(method, false)
+		return nil, false
+	}
+	entry := item.(*cachedResponse)
+	if entry.hash != (common.Hash{}) && !isFinalized(ctx, b, entry.number) {
+		header, _ := b.HeaderByNumber(ctx, rpc.BlockNumber(entry.number))
+		if header == nil || header.Hash() != entry.hash {
+			c.entries.Remove(id)
+			responseCacheInvalidatedMeter.Mark(1)
+			c.mark(method, false)
+			return nil, false
+		}
+	}
+	c.mark(method, true)
+	return entry.value, true
+}
+
+// add caches the response of a request, depending on the given canonical block.
+// A zero hash marks the response as immutable.
+func (c *responseCache) add(method string, key string, value interface{}, number uint64, hash common.Hash) {
+	if c == nil {
+		return
+	}
+	c.entries.Add(responseCacheKey{method, key}, &cachedResponse{value: value, number: number, hash: hash})
+}
+
+// mark updates the hit rate meters of the cache.
+func (c *responseCache) mark(method string, hit bool) {
+	if hit {
+		responseCacheHitMeter.Mark(1)
+		metrics.GetOrRegisterMeter("rpc/cache/hit/"+method, nil).Mark(1)
+	} else {
+		responseCacheMissMeter.Mark(1)
+		metrics.GetOrRegisterMeter("rpc/cache/miss/"+method, nil).Mark(1)
+	}
+}
+
+// isFinalized reports whether the block with the given number is finalized,
+// which is never the case for chains without a finalized block.
+func isFinalized(ctx context.Context, b Backend, number uint64) bool {
+	header, err := b.HeaderByNumber(ctx, rpc.FinalizedBlockNumber)
+	if err != nil || header == nil {
+		return false
+	}
+	return number <= header.Number.Uint64()
+}
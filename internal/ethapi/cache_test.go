@@ -0,0 +1,138 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// cacheBackendMock is a backend serving a chain of headers with a finalized
+// block, counting the block retrievals.
+type cacheBackendMock struct {
+	*backendMock
+	headers   []*types.Header
+	finalized int // -1 = no finalized block
+	blocks    int
+}
+
+func newCacheBackendMock(n int) *cacheBackendMock {
+	b := &cacheBackendMock{backendMock: newBackendMock(), finalized: -1}
+	for i := 0; i < n; i++ {
+		b.headers = append(b.headers, &types.Header{Number: big.NewInt(int64(i)), Difficulty: common.Big1})
+	}
+	return b
+}
+
+func (b *cacheBackendMock) RPCCacheSize() int { return 16 }
+
+func (b *cacheBackendMock) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.FinalizedBlockNumber {
+		if b.finalized < 0 {
+			return nil, errors.New("finalized block not found")
+		}
+		number = rpc.BlockNumber(b.finalized)
+	}
+	if number < 0 || int(number) >= len(b.headers) {
+		return nil, nil
+	}
+	return b.headers[number], nil
+}
+
+func (b *cacheBackendMock) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	b.blocks++
+	for _, header := range b.headers {
+		if header.Hash() == hash {
+			return types.NewBlockWithHeader(header), nil
+		}
+	}
+	return nil, nil
+}
+
+func TestResponseCacheReorg(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		backend = newCacheBackendMock(10)
+		cache   = newResponseCache(16)
+	)
+	cache.add("test", "immutable", 1, 0, common.Hash{})
+	cache.add("test", "old", 2, 3, backend.headers[3].Hash())
+	cache.add("test", "new", 3, 8, backend.headers[8].Hash())
+
+	for key, want := range map[string]int{"immutable": 1, "old": 2, "new": 3} {
+		if have, ok := cache.get(ctx, backend, "test", key); !ok || have != want {
+			t.Fatalf("%s: have %v (cached %t), want %d", key, have, ok, want)
+		}
+	}
+	// Finalize up to block 5, and reorg blocks 3 and above. Responses of blocks
+	// finalized in the meantime are no longer checked against the chain.
+	backend.finalized = 5
+	for i := 3; i < len(backend.headers); i++ {
+		backend.headers[i] = &types.Header{Number: big.NewInt(int64(i)), Difficulty: common.Big2}
+	}
+	if _, ok := cache.get(ctx, backend, "test", "new"); ok {
+		t.Fatal("response of reorged block served")
+	}
+	if _, ok := cache.get(ctx, backend, "test", "old"); !ok {
+		t.Fatal("response of finalized block dropped")
+	}
+	if _, ok := cache.get(ctx, backend, "test", "immutable"); !ok {
+		t.Fatal("immutable response dropped")
+	}
+	// A disabled cache never serves responses
+	var disabled *responseCache
+	disabled.add("test", "immutable", 1, 0, common.Hash{})
+	if _, ok := disabled.get(ctx, backend, "test", "immutable"); ok {
+		t.Fatal("disabled cache served a response")
+	}
+}
+
+func TestGetBlockByHashCached(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		backend = newCacheBackendMock(4)
+		api     = NewBlockChainAPI(backend)
+		hash    = backend.headers[2].Hash()
+	)
+	for i := 0; i < 3; i++ {
+		block, err := api.GetBlockByHash(ctx, hash, false)
+		if err != nil {
+			t.Fatalf("failed to retrieve block: %v", err)
+		}
+		if block["hash"] != hash {
+			t.Fatalf("wrong block: have %v, want %x", block["hash"], hash)
+		}
+	}
+	if backend.blocks != 1 {
+		t.Errorf("block retrieved %d times, want once", backend.blocks)
+	}
+	// Missing blocks are not cached, they may still arrive
+	for i := 0; i < 2; i++ {
+		if block, _ := api.GetBlockByHash(ctx, common.Hash{1}, false); block != nil {
+			t.Fatal("unknown block found")
+		}
+	}
+	if backend.blocks != 3 {
+		t.Errorf("unknown block retrieved %d times, want twice", backend.blocks-1)
+	}
+}
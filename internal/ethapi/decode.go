@@ -0,0 +1,247 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/metadata"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxRegisteredABIs is the number of contract ABIs that can be registered for
+// decoding calldata.
+const maxRegisteredABIs = 1024
+
+// DecodedTransaction is a raw transaction decoded by eth_decodeTransaction.
+type DecodedTransaction struct {
+	*RPCTransaction
+	SenderError string          `json:"senderError,omitempty"` // Reason the sender could not be recovered
+	Size        hexutil.Uint    `json:"size"`
+	Call        *RPCDecodedCall `json:"call,omitempty"` // Decoded calldata, if the target's ABI is known
+}
+
+// RPCDecodedCall is the JSON representation of calldata decoded against a
+// contract ABI.
+type RPCDecodedCall struct {
+	Method    string               `json:"method,omitempty"` // Signature of the called method
+	Arguments []RPCDecodedArgument `json:"arguments"`
+	Error     string               `json:"error,omitempty"` // Reason the arguments could not be decoded
+}
+
+// RPCDecodedArgument is an argument of a decoded call.
+type RPCDecodedArgument struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// RegisterABI registers the ABI of a contract, so transactions calling it can be
+// decoded by eth_decodeTransaction. Registrations are kept in memory only and are
+// shared by all clients, hence the method lives in the debug namespace.
+func (s *DebugAPI) RegisterABI(address common.Address, definition json.RawMessage) error {
+	parsed, err := abi.JSON(bytes.NewReader(definition))
+	if err != nil {
+		return err
+	}
+	// Contracts are registered under their address, replacing older ABIs
+	name := address.Hex()
+	if contracts := s.abis.Contracts(); len(contracts) >= maxRegisteredABIs {
+		if !containsString(contracts, name) {
+			return errors.New("too many registered ABIs")
+		}
+	}
+	s.abis.Register(name, &parsed)
+	return s.abis.Bind(address, name)
+}
+
+// containsString reports whether the sorted list contains the given string.
+func containsString(list []string, s string) bool {
+	i := sort.SearchStrings(list, s)
+	return i < len(list) && list[i] == s
+}
+
+// DecodeTransaction decodes a raw transaction of any supported type, recovering
+// its sender. If an ABI is given, one was registered for the target contract or
+// the target is a contract with stored metadata, the calldata is decoded too. Only the transaction types known to the node can
+// be decoded, which don't include blob transactions, so there are no sidecars to
+// validate.
+func (s *TransactionAPI) DecodeTransaction(ctx context.Context, input hexutil.Bytes, definition *json.RawMessage) (*DecodedTransaction, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return nil, err
+	}
+	head := s.b.CurrentHeader()
+	result := &DecodedTransaction{
+		RPCTransaction: newRPCPendingTransaction(tx, head, s.b.ChainConfig()),
+		Size:           hexutil.Uint(len(input)),
+	}
+	// Recover the sender with the latest signer, unless the transaction is not
+	// replay protected, which the latest signer would reject.
+	signer := s.signer
+	if !tx.Protected() {
+		signer = types.HomesteadSigner{}
+	}
+	if from, err := types.Sender(signer, tx); err != nil {
+		result.From, result.SenderError = common.Address{}, err.Error()
+	} else {
+		result.From = from
+	}
+	// Decode the calldata if the target's ABI is known
+	if tx.To() == nil || len(tx.Data()) < 4 {
+		return result, nil
+	}
+	var (
+		call *abi.DecodedCall
+		err  error
+	)
+	switch {
+	case definition != nil:
+		parsed, perr := abi.JSON(bytes.NewReader(*definition))
+		if perr != nil {
+			return nil, fmt.Errorf("invalid abi: %v", perr)
+		}
+		call, err = resolveCall(&parsed, *tx.To(), tx.Data())
+	default:
+		call, err = s.abis.ResolveBound(*tx.To(), tx.Data())
+		if err == abi.ErrUnknownMethod {
+			if meta, _ := lookupContractMetadata(ctx, s.b, *tx.To()); meta != nil {
+				if contract, _ := meta.ParsedABI(); contract != nil {
+					call, err = resolveCall(contract, *tx.To(), tx.Data())
+				}
+			}
+		}
+	}
+	switch {
+	case call != nil:
+		result.Call = newRPCDecodedCall(call)
+	case err != abi.ErrUnknownMethod:
+		result.Call = &RPCDecodedCall{Arguments: []RPCDecodedArgument{}, Error: err.Error()}
+	}
+	return result, nil
+}
+
+// resolveCall decodes calldata sent to the given address against a single
+// contract ABI.
+func resolveCall(contract *abi.ABI, to common.Address, data []byte) (*abi.DecodedCall, error) {
+	registry := abi.NewRegistry()
+	registry.Register(to.Hex(), contract)
+	if err := registry.Bind(to, to.Hex()); err != nil {
+		return nil, err
+	}
+	return registry.ResolveBound(to, data)
+}
+
+// RPCContractMetadata is the metadata of a verified contract, as returned by
+// eth_getContractMetadata.
+type RPCContractMetadata struct {
+	*metadata.Metadata
+	CodeHash common.Hash `json:"codeHash"` // Hash of the code deployed at the queried address
+	Similar  bool        `json:"similar"`  // Whether the metadata was verified for code differing in compiler metadata only
+}
+
+// GetContractMetadata returns the metadata of the verified contract deployed at
+// the given address in the latest state, or nil if none is stored. Metadata is
+// imported with geth import-contract-metadata.
+func (s *BlockChainAPI) GetContractMetadata(ctx context.Context, address common.Address) (*RPCContractMetadata, error) {
+	return lookupContractMetadata(ctx, s.b, address)
+}
+
+// lookupContractMetadata retrieves the metadata of the contract deployed at the
+// given address in the latest state.
+func lookupContractMetadata(ctx context.Context, b Backend, address common.Address) (*RPCContractMetadata, error) {
+	state, _, err := b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if state == nil || err != nil {
+		return nil, err
+	}
+	code := state.GetCode(address)
+	if len(code) == 0 {
+		return nil, state.Error()
+	}
+	hash := state.GetCodeHash(address)
+	meta, similar := metadata.Lookup(b.ChainDb(), hash, code)
+	if meta == nil {
+		return nil, nil
+	}
+	return &RPCContractMetadata{Metadata: meta, CodeHash: hash, Similar: similar}, nil
+}
+
+// newRPCDecodedCall converts a call decoded by the ABI registry into its JSON
+// representation.
+func newRPCDecodedCall(call *abi.DecodedCall) *RPCDecodedCall {
+	result := &RPCDecodedCall{Method: call.Method.Sig, Arguments: []RPCDecodedArgument{}}
+	for i, arg := range call.Method.Inputs {
+		result.Arguments = append(result.Arguments, RPCDecodedArgument{
+			Name:  arg.Name,
+			Type:  arg.Type.String(),
+			Value: rpcABIValue(arg.Type, reflect.ValueOf(call.Args[i].Value)),
+		})
+	}
+	return result
+}
+
+// rpcABIValue converts a value unpacked by the abi package into a representation
+// suitable for JSON clients: integers and byte strings as hex, tuples as objects
+// keyed by component name.
+func rpcABIValue(typ abi.Type, v reflect.Value) interface{} {
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		if value, ok := v.Interface().(*big.Int); ok {
+			return (*hexutil.Big)(value)
+		}
+		if v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64 {
+			return (*hexutil.Big)(big.NewInt(v.Int()))
+		}
+		return (*hexutil.Big)(new(big.Int).SetUint64(v.Uint()))
+
+	case abi.BytesTy, abi.FixedBytesTy, abi.FunctionTy:
+		blob := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(blob), v)
+		return hexutil.Bytes(blob)
+
+	case abi.SliceTy, abi.ArrayTy:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = rpcABIValue(*typ.Elem, v.Index(i))
+		}
+		return items
+
+	case abi.TupleTy:
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		fields := make(map[string]interface{}, len(typ.TupleElems))
+		for i, elem := range typ.TupleElems {
+			fields[typ.TupleRawNames[i]] = rpcABIValue(*elem, v.Field(i))
+		}
+		return fields
+
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const decodeTestABI = `[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[]}]`
+
+func TestDecodeTransaction(t *testing.T) {
+	var (
+		backend  = newBackendMock()
+		api      = NewTransactionAPI(backend, nil)
+		debug    = NewDebugAPI(backend)
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		token    = common.Address{0x70}
+		to       = common.Address{0xaa}
+		parsed   = mustParseABI(t, decodeTestABI)
+		input, _ = parsed.Pack("transfer", to, big.NewInt(1000))
+	)
+	tx := types.MustSignNewTx(key, types.LatestSigner(backend.config), &types.DynamicFeeTx{
+		ChainID:   backend.config.ChainID,
+		Gas:       50000,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(100),
+		To:        &token,
+		Data:      input,
+	})
+	raw, _ := tx.MarshalBinary()
+
+	// Without an ABI, only the transaction should be decoded
+	decoded, err := api.DecodeTransaction(context.Background(), raw, nil)
+	if err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if decoded.From != sender || decoded.Hash != tx.Hash() || decoded.Call != nil {
+		t.Fatalf("decoded transaction mismatch: from %x, hash %x, call %v", decoded.From, decoded.Hash, decoded.Call)
+	}
+	// Once the ABI is registered, the call should be decoded too
+	debug.abis = api.abis
+	if err := debug.RegisterABI(token, json.RawMessage(decodeTestABI)); err != nil {
+		t.Fatalf("failed to register abi: %v", err)
+	}
+	if decoded, err = api.DecodeTransaction(context.Background(), raw, nil); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	call := decoded.Call
+	if call == nil || call.Method != "transfer(address,uint256)" || len(call.Arguments) != 2 {
+		t.Fatalf("decoded call mismatch: %+v", call)
+	}
+	if call.Arguments[0].Value != to || call.Arguments[1].Value.(*hexutil.Big).ToInt().Int64() != 1000 {
+		t.Errorf("decoded arguments mismatch: %+v", call.Arguments)
+	}
+	// Calls to other contracts must not be decoded with the registered ABI
+	other := types.MustSignNewTx(key, types.LatestSigner(backend.config), &types.LegacyTx{Gas: 50000, GasPrice: big.NewInt(1), To: &to, Data: input})
+	raw, _ = other.MarshalBinary()
+	if decoded, err = api.DecodeTransaction(context.Background(), raw, nil); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if decoded.Call != nil {
+		t.Errorf("call to unregistered contract decoded: %+v", decoded.Call)
+	}
+	// Malformed arguments should be reported
+	malformed := types.MustSignNewTx(key, types.LatestSigner(backend.config), &types.LegacyTx{Gas: 50000, GasPrice: big.NewInt(1), To: &token, Data: input[:20]})
+	raw, _ = malformed.MarshalBinary()
+	if decoded, err = api.DecodeTransaction(context.Background(), raw, nil); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if decoded.Call == nil || decoded.Call.Error == "" {
+		t.Errorf("malformed call not reported: %+v", decoded.Call)
+	}
+	// Transactions signed for another chain should report the sender error
+	foreign := types.MustSignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.LegacyTx{Gas: 21000, GasPrice: big.NewInt(1), To: &to})
+	raw, _ = foreign.MarshalBinary()
+	if decoded, err = api.DecodeTransaction(context.Background(), raw, nil); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if decoded.SenderError == "" {
+		t.Error("sender of foreign transaction recovered")
+	}
+	if _, err := api.DecodeTransaction(context.Background(), hexutil.Bytes{0x05, 0x01}, nil); err == nil {
+		t.Error("invalid transaction decoded")
+	}
+}
+
+func mustParseABI(t *testing.T, definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
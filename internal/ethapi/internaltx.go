@@ -0,0 +1,179 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultInternalTxPageSize is the number of transfers returned by an internal
+	// transaction query if no limit is given.
+	defaultInternalTxPageSize = 100
+
+	// maxInternalTxPageSize is the maximum number of transfers returned by an
+	// internal transaction query.
+	maxInternalTxPageSize = 10000
+)
+
+var errNoInternalTxIndex = errors.New("internal transaction index not maintained (enable with --internaltxindex)")
+
+// InternalTxQuery selects and paginates the internal transfers returned by
+// eth_getInternalTransactions.
+type InternalTxQuery struct {
+	Address   common.Address   `json:"address"`   // Sender or recipient of the transfers
+	FromBlock *rpc.BlockNumber `json:"fromBlock"` // First block to search, the oldest indexed one if omitted
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`   // Last block to search, the latest one if omitted
+
+	Offset hexutil.Uint64 `json:"offset"` // Number of matching transfers to skip
+	Limit  hexutil.Uint64 `json:"limit"`  // Maximum number of transfers to return
+}
+
+// InternalTxPage is a page of internal transfers matching a query.
+type InternalTxPage struct {
+	Transfers []*RPCInternalTx `json:"transfers"`
+	More      bool             `json:"more"` // Whether more transfers match beyond this page
+}
+
+// RPCInternalTx is a value transfer of a nested call, as returned over RPC.
+type RPCInternalTx struct {
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	Index            hexutil.Uint64 `json:"index"` // Index of the transfer within the transaction
+	Type             string         `json:"type"`
+	From             common.Address `json:"from"`
+	To               common.Address `json:"to"`
+	Value            *hexutil.Big   `json:"value"`
+}
+
+func newRPCInternalTx(itx *types.InternalTx) *RPCInternalTx {
+	return &RPCInternalTx{
+		BlockHash:        itx.BlockHash,
+		BlockNumber:      hexutil.Uint64(itx.BlockNumber),
+		TransactionHash:  itx.TxHash,
+		TransactionIndex: hexutil.Uint64(itx.TxIndex),
+		Index:            hexutil.Uint64(itx.Index),
+		Type:             itx.Type,
+		From:             itx.From,
+		To:               itx.To,
+		Value:            (*hexutil.Big)(itx.Value),
+	}
+}
+
+// GetInternalTransactions returns the value transfers of nested calls, contract
+// creations and self-destructs in canonical transactions which were sent or
+// received by the queried address, in chain order. Transfers of reverted calls
+// are omitted. It requires the node to maintain the internal transaction index.
+func (s *BlockChainAPI) GetInternalTransactions(ctx context.Context, query InternalTxQuery) (*InternalTxPage, error) {
+	db := s.b.ChainDb()
+	tail := rawdb.ReadInternalTxIndexTail(db)
+	if tail == nil {
+		return nil, errNoInternalTxIndex
+	}
+	limit := int(query.Limit)
+	if limit == 0 {
+		limit = defaultInternalTxPageSize
+	}
+	if limit > maxInternalTxPageSize {
+		return nil, fmt.Errorf("limit too large: %d > %d", limit, maxInternalTxPageSize)
+	}
+	from := *tail
+	if query.FromBlock != nil {
+		number, err := s.resolveBlockNumber(ctx, *query.FromBlock)
+		if err != nil {
+			return nil, err
+		}
+		if number < *tail {
+			return nil, fmt.Errorf("blocks before #%d are not indexed", *tail)
+		}
+		from = number
+	}
+	to := s.b.CurrentHeader().Number.Uint64()
+	if query.ToBlock != nil {
+		number, err := s.resolveBlockNumber(ctx, *query.ToBlock)
+		if err != nil {
+			return nil, err
+		}
+		to = number
+	}
+	var (
+		page      = &InternalTxPage{Transfers: []*RPCInternalTx{}}
+		skip      = uint64(query.Offset)
+		canonical = make(map[uint64]common.Hash)
+		ctxErr    error
+	)
+	if from > to {
+		return page, nil
+	}
+	err := rawdb.IterateInternalTxs(db, query.Address, from, to, func(itx *types.InternalTx) bool {
+		if ctxErr = ctx.Err(); ctxErr != nil {
+			return false
+		}
+		// The index may contain transfers of blocks reorged out, skip those
+		hash, ok := canonical[itx.BlockNumber]
+		if !ok {
+			hash = rawdb.ReadCanonicalHash(db, itx.BlockNumber)
+			canonical[itx.BlockNumber] = hash
+		}
+		if itx.BlockHash != hash {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		if len(page.Transfers) == limit {
+			page.More = true
+			return false
+		}
+		page.Transfers = append(page.Transfers, newRPCInternalTx(itx))
+		return true
+	})
+	if ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// resolveBlockNumber returns the number of the block referenced by a block
+// number or tag.
+func (s *BlockChainAPI) resolveBlockNumber(ctx context.Context, number rpc.BlockNumber) (uint64, error) {
+	if number >= 0 {
+		return uint64(number), nil
+	}
+	header, err := s.b.HeaderByNumber(ctx, number)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block %v not found", number)
+	}
+	return header.Number.Uint64(), nil
+}
@@ -0,0 +1,306 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// SimulatedReceipt is the receipt a pending transaction would produce if it was
+// included right on top of the latest block. The nonce of the transaction is not
+// checked, as its predecessors may still be pending themselves.
+type SimulatedReceipt struct {
+	TxHash          common.Hash     `json:"transactionHash"`
+	From            common.Address  `json:"from"`
+	To              *common.Address `json:"to"`
+	ContractAddress *common.Address `json:"contractAddress"`
+	StateBlockHash  common.Hash     `json:"stateBlockHash"`   // Block the simulation ran on top of
+	StateBlock      hexutil.Uint64  `json:"stateBlockNumber"` // Number of the block the simulation ran on top of
+	Status          hexutil.Uint64  `json:"status"`
+	GasUsed         hexutil.Uint64  `json:"gasUsed"`
+	Logs            []*types.Log    `json:"logs"`
+	RevertReason    string          `json:"revertReason,omitempty"`
+	RevertData      hexutil.Bytes   `json:"revertData,omitempty"`
+	Error           string          `json:"error,omitempty"` // Execution error, or the reason the transaction is not includable
+}
+
+const (
+	// preconfQueueSize is the number of transaction pool events waiting for
+	// simulation. Events arriving while the queue is full are dropped, so that
+	// a slow simulation never stalls the transaction pool.
+	preconfQueueSize = 16
+
+	// preconfSubBuffer is the number of simulated receipts buffered for each
+	// subscriber. Receipts for a subscriber falling further behind are dropped.
+	preconfSubBuffer = 256
+)
+
+// receiptSimulator is the single worker shared by all pending receipt
+// subscriptions. It listens for new pool transactions while there is at least
+// one subscriber, simulates them once and fans the receipts out to everyone.
+type receiptSimulator struct {
+	api *TransactionAPI
+
+	subs map[chan *SimulatedReceipt]struct{}
+	quit chan struct{} // Closed when the last subscriber leaves
+	lock sync.Mutex
+}
+
+func newReceiptSimulator(api *TransactionAPI) *receiptSimulator {
+	return &receiptSimulator{
+		api:  api,
+		subs: make(map[chan *SimulatedReceipt]struct{}),
+	}
+}
+
+// subscribe registers a new receipt subscriber, starting the simulation worker
+// if it is the first one.
+func (r *receiptSimulator) subscribe() chan *SimulatedReceipt {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ch := make(chan *SimulatedReceipt, preconfSubBuffer)
+	if len(r.subs) == 0 {
+		r.quit = make(chan struct{})
+		go r.loop(r.quit)
+	}
+	r.subs[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes a receipt subscriber, stopping the simulation worker if
+// it was the last one.
+func (r *receiptSimulator) unsubscribe(ch chan *SimulatedReceipt) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.subs[ch]; !ok {
+		return
+	}
+	delete(r.subs, ch)
+	if len(r.subs) == 0 {
+		close(r.quit)
+	}
+}
+
+// loop moves transaction pool events into the bounded simulation queue without
+// ever blocking the pool's event feed.
+func (r *receiptSimulator) loop(quit chan struct{}) {
+	txsCh := make(chan core.NewTxsEvent, 128)
+	txsSub := r.api.b.SubscribeNewTxsEvent(txsCh)
+	defer txsSub.Unsubscribe()
+
+	queue := make(chan []*types.Transaction, preconfQueueSize)
+	go r.simulate(queue, quit)
+
+	for {
+		select {
+		case ev := <-txsCh:
+			select {
+			case queue <- ev.Txs:
+			default:
+				log.Debug("Dropping pending transactions, simulation queue full", "count", len(ev.Txs))
+			}
+		case <-txsSub.Err():
+			return
+		case <-quit:
+			return
+		}
+	}
+}
+
+// simulate executes queued transactions one batch at a time and delivers the
+// resulting receipts to the subscribers.
+func (r *receiptSimulator) simulate(queue chan []*types.Transaction, quit chan struct{}) {
+	for {
+		select {
+		case txs := <-queue:
+			receipts, err := r.api.simulateReceipts(txs)
+			if err != nil {
+				log.Debug("Failed to simulate pending transactions", "count", len(txs), "err", err)
+				continue
+			}
+			r.deliver(receipts)
+		case <-quit:
+			return
+		}
+	}
+}
+
+// deliver hands the receipts to every subscriber, dropping them for those whose
+// buffer is full.
+func (r *receiptSimulator) deliver(receipts []*SimulatedReceipt) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for ch := range r.subs {
+		for _, receipt := range receipts {
+			select {
+			case ch <- receipt:
+			default:
+			}
+		}
+	}
+}
+
+// PendingTransactionReceipts creates a subscription that is triggered each time
+// a transaction enters the transaction pool, delivering the receipt it would
+// produce if it was included on top of the latest block. It gives instant
+// feedback on the outcome of a transaction without polling eth_call.
+//
+// Transactions are simulated on a best effort basis: they are skipped if the
+// simulation falls behind the transaction pool or the subscriber falls behind
+// the simulation.
+func (s *TransactionAPI) PendingTransactionReceipts(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	receipts := s.preconf.subscribe()
+	go func() {
+		defer s.preconf.unsubscribe(receipts)
+
+		for {
+			select {
+			case receipt := <-receipts:
+				notifier.Notify(rpcSub.ID, receipt)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// simulateReceipts executes each of the given transactions independently on top
+// of the latest block and assembles their receipts.
+func (s *TransactionAPI) simulateReceipts(txs []*types.Transaction) ([]*SimulatedReceipt, error) {
+	statedb, header, err := s.b.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	receipts := make([]*SimulatedReceipt, 0, len(txs))
+	for i, tx := range txs {
+		receipt, err := s.simulateReceipt(statedb, header, tx, i)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// simulateReceipt executes a single transaction on top of the given state and
+// reverts the state afterwards. The execution is capped by the RPC gas cap and
+// aborted after the RPC EVM timeout, same as eth_call.
+func (s *TransactionAPI) simulateReceipt(statedb *state.StateDB, header *types.Header, tx *types.Transaction, index int) (*SimulatedReceipt, error) {
+	receipt := &SimulatedReceipt{
+		TxHash:         tx.Hash(),
+		To:             tx.To(),
+		StateBlockHash: header.Hash(),
+		StateBlock:     hexutil.Uint64(header.Number.Uint64()),
+		Logs:           []*types.Log{},
+	}
+	msg, err := tx.AsMessage(s.signer, header.BaseFee)
+	if err != nil {
+		receipt.Error = err.Error()
+		return receipt, nil
+	}
+	receipt.From = msg.From()
+	if tx.To() == nil {
+		addr := crypto.CreateAddress(msg.From(), tx.Nonce())
+		receipt.ContractAddress = &addr
+	}
+	// Skip the nonce check, the sender may have other transactions pending
+	gas := msg.Gas()
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && gas > gasCap {
+		gas = gasCap
+	}
+	msg = types.NewMessage(msg.From(), msg.To(), msg.Nonce(), msg.Value(), gas, msg.GasPrice(), msg.GasFeeCap(), msg.GasTipCap(), msg.Data(), msg.AccessList(), true)
+
+	// Setup context so it may be cancelled when the simulation takes too long
+	var (
+		ctx     context.Context
+		cancel  context.CancelFunc
+		timeout = s.b.RPCEVMTimeout()
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
+	snapshot := statedb.Snapshot()
+	defer statedb.RevertToSnapshot(snapshot)
+
+	statedb.Prepare(tx.Hash(), index)
+	evm, vmError, err := s.b.GetEVM(ctx, msg, statedb, header, &vm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(header.GasLimit))
+	if err := vmError(); err != nil {
+		return nil, err
+	}
+	if evm.Cancelled() {
+		receipt.Error = fmt.Sprintf("execution aborted (timeout = %v)", timeout)
+		return receipt, nil
+	}
+	if err != nil {
+		receipt.Error = err.Error()
+		return receipt, nil
+	}
+	receipt.GasUsed = hexutil.Uint64(result.UsedGas)
+	if logs := statedb.GetLogs(tx.Hash(), common.Hash{}); logs != nil {
+		receipt.Logs = logs
+	}
+	if result.Failed() {
+		receipt.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+		receipt.Error = result.Err.Error()
+		if revert := result.Revert(); len(revert) > 0 {
+			receipt.RevertData = revert
+			if reason, err := abi.UnpackRevert(revert); err == nil {
+				receipt.RevertReason = reason
+			}
+		}
+	} else {
+		receipt.Status = hexutil.Uint64(types.ReceiptStatusSuccessful)
+	}
+	return receipt, nil
+}
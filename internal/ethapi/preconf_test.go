@@ -0,0 +1,157 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// stateBackendMock is a backend executing calls on top of a fixed state.
+type stateBackendMock struct {
+	*backendMock
+	statedb *state.StateDB
+	gasCap  uint64
+}
+
+func (b *stateBackendMock) RPCGasCap() uint64 { return b.gasCap }
+
+func (b *stateBackendMock) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.statedb, b.current, nil
+}
+
+func (b *stateBackendMock) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	return b.statedb, b.current, nil
+}
+
+func (b *stateBackendMock) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	blockCtx := core.NewEVMBlockContext(header, nil, &header.Coinbase)
+	return vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), state, b.config, *vmConfig), func() error { return nil }, nil
+}
+
+func TestSimulateReceipts(t *testing.T) {
+	var (
+		mock       = newBackendMock()
+		signer     = types.LatestSigner(mock.config)
+		key, _     = crypto.GenerateKey()
+		poor, _    = crypto.GenerateKey()
+		sender     = crypto.PubkeyToAddress(key.PublicKey)
+		logger     = common.Address{0xaa}
+		reverter   = common.Address{0xbb}
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	)
+	statedb.SetBalance(sender, big.NewInt(params.Ether))
+
+	// Emit an empty log
+	statedb.SetCode(logger, common.FromHex("0x60006000a000"))
+
+	// Revert with Error("nope"), copying the reason from the code
+	reason := common.FromHex("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6e6f706500000000000000000000000000000000000000000000000000000000")
+	statedb.SetCode(reverter, append(common.FromHex("0x6064600c60003960646000fd"), reason...))
+
+	api := NewTransactionAPI(&stateBackendMock{backendMock: mock, statedb: statedb}, nil)
+	txs := []*types.Transaction{
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &logger, Gas: 100000, GasPrice: big.NewInt(100)}),
+		// The nonce is ahead of the state, the predecessor is still pending
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 1, To: &reverter, Gas: 100000, GasPrice: big.NewInt(100)}),
+		types.MustSignNewTx(poor, signer, &types.LegacyTx{Nonce: 0, To: &logger, Gas: 100000, GasPrice: big.NewInt(100)}),
+	}
+	receipts, err := api.simulateReceipts(txs)
+	if err != nil {
+		t.Fatalf("failed to simulate receipts: %v", err)
+	}
+	if len(receipts) != len(txs) {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(receipts), len(txs))
+	}
+	if r := receipts[0]; r.Status != 1 || r.From != sender || len(r.Logs) != 1 || r.Logs[0].Address != logger || r.Error != "" {
+		t.Errorf("successful receipt mismatch: %+v", r)
+	}
+	if r := receipts[0]; r.GasUsed <= 21000 || uint64(r.StateBlock) != mock.current.Number.Uint64() {
+		t.Errorf("successful receipt metadata mismatch: %+v", r)
+	}
+	if r := receipts[1]; r.Status != 0 || r.RevertReason != "nope" || len(r.Logs) != 0 || r.Error != vm.ErrExecutionReverted.Error() {
+		t.Errorf("reverted receipt mismatch: %+v", r)
+	}
+	if r := receipts[2]; r.Status != 0 || r.GasUsed != 0 || !strings.Contains(r.Error, core.ErrInsufficientFunds.Error()) {
+		t.Errorf("invalid receipt mismatch: %+v", r)
+	}
+	// The simulations must leave the state untouched
+	if nonce := statedb.GetNonce(sender); nonce != 0 {
+		t.Errorf("state modified by simulation: sender nonce %d", nonce)
+	}
+}
+
+// Tests that simulations are capped by the RPC gas cap.
+func TestSimulateReceiptGasCap(t *testing.T) {
+	var (
+		mock       = newBackendMock()
+		signer     = types.LatestSigner(mock.config)
+		key, _     = crypto.GenerateKey()
+		looper     = common.Address{0xcc}
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	)
+	statedb.SetBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(params.Ether))
+
+	// Loop forever, burning all the gas available
+	statedb.SetCode(looper, common.FromHex("0x5b600056"))
+
+	api := NewTransactionAPI(&stateBackendMock{backendMock: mock, statedb: statedb, gasCap: 50000}, nil)
+	tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &looper, Gas: 1000000, GasPrice: big.NewInt(100)})
+
+	receipts, err := api.simulateReceipts([]*types.Transaction{tx})
+	if err != nil {
+		t.Fatalf("failed to simulate receipts: %v", err)
+	}
+	if r := receipts[0]; r.Status != 0 || r.GasUsed != 50000 || r.Error != vm.ErrOutOfGas.Error() {
+		t.Errorf("capped receipt mismatch: %+v", r)
+	}
+}
+
+// Tests that receipt delivery never blocks on subscribers that fall behind.
+func TestReceiptSimulatorDeliver(t *testing.T) {
+	sim := newReceiptSimulator(nil)
+	sim.subs[make(chan *SimulatedReceipt)] = struct{}{} // Never read, unbuffered
+	fast := make(chan *SimulatedReceipt, 2)
+	sim.subs[fast] = struct{}{}
+
+	receipts := []*SimulatedReceipt{{Status: 1}, {Status: 2}, {Status: 3}}
+	sim.deliver(receipts)
+
+	if len(fast) != 2 {
+		t.Fatalf("buffered receipt count mismatch: have %d, want 2", len(fast))
+	}
+	for i := 0; i < 2; i++ {
+		if r := <-fast; r != receipts[i] {
+			t.Errorf("receipt %d mismatch: have %+v, want %+v", i, r, receipts[i])
+		}
+	}
+}
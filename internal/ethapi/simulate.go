@@ -0,0 +1,466 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks a single simulation may
+	// produce, including the empty ones filling the gaps between block numbers.
+	maxSimulateBlocks = 256
+
+	// simulateBlockTime is the default time in seconds between two simulated
+	// blocks.
+	simulateBlockTime = 12
+
+	// errCodeVMError is the JSON-RPC error code of calls failing with an EVM
+	// error other than a revert.
+	errCodeVMError = -32015
+)
+
+var (
+	// transferAddress is the pseudo contract emitting the logs of ether transfers
+	// if transfer tracing is enabled.
+	transferAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+	// transferTopic is the topic of the ether transfer logs, equal to the one of
+	// the ERC-20 Transfer event.
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+)
+
+// SimulateBlock is a synthetic block of a simulation. The state overrides are
+// applied before executing the calls of the block.
+type SimulateBlock struct {
+	BlockOverrides *BlockOverrides   `json:"blockOverrides"`
+	StateOverrides *StateOverride    `json:"stateOverrides"`
+	Calls          []TransactionArgs `json:"calls"`
+}
+
+// SimulateOpts are the inputs of eth_simulateV1.
+type SimulateOpts struct {
+	BlockStateCalls        []SimulateBlock `json:"blockStateCalls"`
+	TraceTransfers         bool            `json:"traceTransfers"`         // Emit a log for every ether transfer
+	Validation             bool            `json:"validation"`             // Check nonces and fees like a real block would
+	ReturnFullTransactions bool            `json:"returnFullTransactions"` // Return transaction objects instead of hashes
+}
+
+// SimulateCallResult is the outcome of a single call of a simulated block.
+type SimulateCallResult struct {
+	ReturnData hexutil.Bytes      `json:"returnData"`
+	Logs       []*types.Log       `json:"logs"`
+	GasUsed    hexutil.Uint64     `json:"gasUsed"`
+	Status     hexutil.Uint64     `json:"status"`
+	Error      *SimulateCallError `json:"error,omitempty"`
+}
+
+// SimulateCallError is the error of a failed call, encoded like the JSON-RPC
+// error eth_call would have returned.
+type SimulateCallError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// SimulateV1 executes a series of synthetic blocks on top of the given block, or
+// the latest one if none is given. Each block may override header fields and
+// account state, and its calls run one after the other so that later calls and
+// blocks see the effects of the earlier ones. The simulated blocks are returned
+// with the result and logs of every call.
+//
+// Note, this function doesn't make any changes in the state/blockchain.
+func (s *BlockChainAPI) SimulateV1(ctx context.Context, opts SimulateOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errors.New("empty input")
+	}
+	if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks: %d > %d", len(opts.BlockStateCalls), maxSimulateBlocks)
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	statedb, base, err := s.b.StateAndHeaderByNumberOrHash(ctx, bNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	timeout := s.b.RPCEVMTimeout()
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	sim := &simulator{
+		b:       s.b,
+		state:   statedb,
+		base:    base,
+		config:  s.b.ChainConfig(),
+		opts:    &opts,
+		timeout: timeout,
+		gasCap:  s.b.RPCGasCap(),
+		headers: make(map[common.Hash]*types.Header),
+	}
+	sim.gasRemaining = sim.gasCap
+	return sim.execute(ctx, opts.BlockStateCalls)
+}
+
+// simulator executes the blocks of a single eth_simulateV1 request on top of a
+// shared state.
+type simulator struct {
+	b       Backend
+	state   *state.StateDB
+	base    *types.Header
+	config  *params.ChainConfig
+	opts    *SimulateOpts
+	timeout time.Duration
+
+	gasCap       uint64 // Gas allowance of the whole simulation, zero if unlimited
+	gasRemaining uint64 // Gas allowance left for the remaining calls
+
+	headers map[common.Hash]*types.Header // Simulated headers, for the BLOCKHASH opcode
+}
+
+// Engine retrieves the consensus engine of the chain, implementing
+// core.ChainContext.
+func (sim *simulator) Engine() consensus.Engine {
+	return sim.b.Engine()
+}
+
+// GetHeader retrieves a simulated or a chain header, implementing
+// core.ChainContext.
+func (sim *simulator) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := sim.headers[hash]; ok {
+		return header
+	}
+	header, err := sim.b.HeaderByHash(context.Background(), hash)
+	if err != nil || header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+// execute runs the given blocks and returns their RPC representations.
+func (sim *simulator) execute(ctx context.Context, blocks []SimulateBlock) ([]map[string]interface{}, error) {
+	headers, blocks, err := sim.makeHeaders(blocks)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		parent  = sim.base
+		results = make([]map[string]interface{}, 0, len(blocks))
+	)
+	for i, header := range headers {
+		header.ParentHash = parent.Hash()
+		if sim.config.IsLondon(header.Number) && header.BaseFee == nil {
+			header.BaseFee = misc.CalcBaseFee(sim.config, parent)
+		}
+		block, senders, calls, err := sim.processBlock(ctx, &blocks[i], header)
+		if err != nil {
+			return nil, err
+		}
+		enc, err := RPCMarshalBlock(block, true, sim.opts.ReturnFullTransactions, sim.config)
+		if err != nil {
+			return nil, err
+		}
+		if sim.opts.ReturnFullTransactions {
+			// The simulated transactions are unsigned, fill in the senders
+			for j, tx := range enc["transactions"].([]interface{}) {
+				tx.(*RPCTransaction).From = senders[j]
+			}
+		}
+		enc["calls"] = calls
+		results = append(results, enc)
+
+		parent = block.Header()
+		sim.headers[block.Hash()] = parent
+	}
+	return results, nil
+}
+
+// makeHeaders assembles the headers of the simulated blocks from their overrides.
+// Block numbers and timestamps default to following the previous block and must
+// be strictly increasing. Gaps between block numbers are filled with empty
+// blocks, which are returned alongside.
+func (sim *simulator) makeHeaders(blocks []SimulateBlock) ([]*types.Header, []SimulateBlock, error) {
+	var (
+		headers   = make([]*types.Header, 0, len(blocks))
+		filled    = make([]SimulateBlock, 0, len(blocks))
+		number    = sim.base.Number
+		timestamp = sim.base.Time
+	)
+	for _, block := range blocks {
+		next := new(big.Int).Add(number, common.Big1)
+		if override := block.BlockOverrides; override != nil && override.Number != nil {
+			if override.Number.ToInt().Cmp(next) < 0 {
+				return nil, nil, fmt.Errorf("block numbers must be in order: %d <= %d", override.Number.ToInt(), number)
+			}
+			// Fill the gap up to the requested block number
+			for ; next.Cmp(override.Number.ToInt()) < 0; next.Add(next, common.Big1) {
+				if len(headers) >= maxSimulateBlocks {
+					return nil, nil, fmt.Errorf("too many blocks: more than %d", maxSimulateBlocks)
+				}
+				timestamp += simulateBlockTime
+				headers = append(headers, sim.makeHeader(nil, next, timestamp))
+				filled = append(filled, SimulateBlock{})
+			}
+		}
+		header := sim.makeHeader(block.BlockOverrides, next, timestamp+simulateBlockTime)
+		if header.Time <= timestamp {
+			return nil, nil, fmt.Errorf("block timestamps must be in order: %d <= %d", header.Time, timestamp)
+		}
+		if len(headers) >= maxSimulateBlocks {
+			return nil, nil, fmt.Errorf("too many blocks: more than %d", maxSimulateBlocks)
+		}
+		headers = append(headers, header)
+		filled = append(filled, block)
+
+		number, timestamp = header.Number, header.Time
+	}
+	return headers, filled, nil
+}
+
+// makeHeader creates the header of a simulated block with the given number and
+// timestamp, inheriting the gas limit and difficulty of the base block unless
+// overridden.
+func (sim *simulator) makeHeader(override *BlockOverrides, number *big.Int, timestamp uint64) *types.Header {
+	return override.MakeHeader(&types.Header{
+		Number:     new(big.Int).Set(number),
+		Time:       timestamp,
+		GasLimit:   sim.base.GasLimit,
+		Difficulty: new(big.Int).Set(sim.base.Difficulty),
+	})
+}
+
+// processBlock executes the calls of a simulated block on top of the shared
+// state and assembles the resulting block. The senders of the transactions are
+// returned alongside, as the simulated transactions are unsigned.
+func (sim *simulator) processBlock(ctx context.Context, block *SimulateBlock, header *types.Header) (*types.Block, []common.Address, []*SimulateCallResult, error) {
+	if err := block.StateOverrides.Apply(sim.state); err != nil {
+		return nil, nil, nil, err
+	}
+	var (
+		gasUsed  uint64
+		txs      = make([]*types.Transaction, 0, len(block.Calls))
+		senders  = make([]common.Address, 0, len(block.Calls))
+		receipts = make([]*types.Receipt, 0, len(block.Calls))
+		results  = make([]*SimulateCallResult, 0, len(block.Calls))
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		blockCtx = core.NewEVMBlockContext(header, sim, &header.Coinbase)
+		vmConfig = vm.Config{NoBaseFee: !sim.opts.Validation}
+	)
+	if sim.opts.TraceTransfers {
+		vmConfig.Debug = true
+		vmConfig.Tracer = new(transferTracer)
+	}
+	for i := range block.Calls {
+		args := &block.Calls[i]
+		if err := sim.setCallDefaults(args, header, gasUsed); err != nil {
+			return nil, nil, nil, fmt.Errorf("block %d, call %d: %w", header.Number, i, err)
+		}
+		msg, err := args.ToMessage(sim.gasRemaining, header.BaseFee)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("block %d, call %d: %w", header.Number, i, err)
+		}
+		// Execute with the requested nonce, only checking it if validating
+		msg = types.NewMessage(msg.From(), msg.To(), uint64(*args.Nonce), msg.Value(), msg.Gas(), msg.GasPrice(), msg.GasFeeCap(), msg.GasTipCap(), msg.Data(), msg.AccessList(), !sim.opts.Validation)
+
+		var (
+			tx       = args.toTransaction()
+			nonce    = sim.state.GetNonce(msg.From())
+			prevLogs = len(sim.state.GetLogs(tx.Hash(), common.Hash{}))
+		)
+		sim.state.Prepare(tx.Hash(), i)
+		evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), sim.state, sim.config, vmConfig)
+		result, err := sim.applyMessage(ctx, evm, msg, gp)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("block %d, call %d: %w", header.Number, i, err)
+		}
+		if sim.config.IsByzantium(header.Number) {
+			sim.state.Finalise(true)
+		} else {
+			sim.state.IntermediateRoot(sim.config.IsEIP158(header.Number))
+		}
+		gasUsed += result.UsedGas
+		if sim.gasCap != 0 {
+			sim.gasRemaining -= result.UsedGas
+		}
+		logs := sim.state.GetLogs(tx.Hash(), common.Hash{})[prevLogs:]
+
+		receipt := &types.Receipt{
+			Type:              tx.Type(),
+			CumulativeGasUsed: gasUsed,
+			TxHash:            tx.Hash(),
+			GasUsed:           result.UsedGas,
+			Logs:              logs,
+			TransactionIndex:  uint(i),
+		}
+		call := &SimulateCallResult{
+			ReturnData: result.Return(),
+			Logs:       logs,
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+		}
+		if result.Failed() {
+			receipt.Status = types.ReceiptStatusFailed
+			call.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if errors.Is(result.Err, vm.ErrExecutionReverted) {
+				err := newRevertError(result)
+				call.Error = &SimulateCallError{Code: err.ErrorCode(), Message: err.Error(), Data: err.reason}
+			} else {
+				call.Error = &SimulateCallError{Code: errCodeVMError, Message: result.Err.Error()}
+			}
+		} else {
+			receipt.Status = types.ReceiptStatusSuccessful
+			call.Status = hexutil.Uint64(types.ReceiptStatusSuccessful)
+		}
+		if msg.To() == nil {
+			receipt.ContractAddress = crypto.CreateAddress(msg.From(), nonce)
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		txs = append(txs, tx)
+		senders = append(senders, msg.From())
+		receipts = append(receipts, receipt)
+		results = append(results, call)
+	}
+	header.GasUsed = gasUsed
+	header.Root = sim.state.IntermediateRoot(sim.config.IsEIP158(header.Number))
+
+	assembled := types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
+
+	// Number the logs within the block, now that its hash is known
+	var index uint
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			log.BlockNumber = header.Number.Uint64()
+			log.BlockHash = assembled.Hash()
+			log.Index = index
+			index++
+		}
+	}
+	return assembled, senders, results, nil
+}
+
+// setCallDefaults fills in the nonce, gas and chain id of a call if they were
+// not specified, and makes sure it fits into the block.
+func (sim *simulator) setCallDefaults(args *TransactionArgs, header *types.Header, gasUsed uint64) error {
+	if args.Nonce == nil {
+		nonce := hexutil.Uint64(sim.state.GetNonce(args.from()))
+		args.Nonce = &nonce
+	}
+	if sim.gasCap != 0 && sim.gasRemaining == 0 {
+		return fmt.Errorf("gas cap of %d exhausted", sim.gasCap)
+	}
+	available := header.GasLimit - gasUsed
+	if args.Gas == nil {
+		gas := hexutil.Uint64(available)
+		if sim.gasCap != 0 && sim.gasRemaining < available {
+			gas = hexutil.Uint64(sim.gasRemaining)
+		}
+		args.Gas = &gas
+	}
+	if uint64(*args.Gas) > available {
+		return fmt.Errorf("block gas limit reached: %d > %d", *args.Gas, available)
+	}
+	if args.ChainID == nil {
+		args.ChainID = (*hexutil.Big)(sim.config.ChainID)
+	}
+	return nil
+}
+
+// applyMessage executes a message, aborting it once the context is done.
+func (sim *simulator) applyMessage(ctx context.Context, evm *vm.EVM, msg types.Message, gp *core.GasPool) (*core.ExecutionResult, error) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+	result, err := core.ApplyMessage(evm, msg, gp)
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", sim.timeout)
+	}
+	return result, err
+}
+
+// transferTracer emits a log for every ether transfer into the state, mimicking
+// an ERC-20 Transfer event of transferAddress. The logs are journaled like any
+// other, so the ones of reverted call frames are dropped with them.
+type transferTracer struct {
+	env *vm.EVM
+}
+
+func (t *transferTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *transferTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *transferTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.transfer(from, to, value)
+}
+
+func (t *transferTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {}
+
+func (t *transferTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	switch typ {
+	case vm.CALL, vm.CREATE, vm.CREATE2, vm.SELFDESTRUCT:
+		t.transfer(from, to, value)
+	}
+}
+
+func (t *transferTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (t *transferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (t *transferTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// transfer adds the log of a value transfer, if there is any value.
+func (t *transferTracer) transfer(from, to common.Address, value *big.Int) {
+	if value == nil || value.Sign() == 0 {
+		return
+	}
+	t.env.StateDB.AddLog(&types.Log{
+		Address: transferAddress,
+		Topics:  []common.Hash{transferTopic, from.Hash(), to.Hash()},
+		Data:    common.BigToHash(value).Bytes(),
+	})
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestSimulateV1(t *testing.T) {
+	var (
+		mock       = newBackendMock()
+		sender     = common.Address{0x01}
+		recipient  = common.Address{0x02}
+		counter    = common.Address{0xaa}
+		clock      = common.Address{0xbb}
+		hasher     = common.Address{0xcc}
+		reverter   = common.Address{0xdd}
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	)
+	// Increment slot 0 and return the new value
+	statedb.SetCode(counter, common.FromHex("0x6000546001018060005560005260206000f3"))
+	// Return the block timestamp
+	statedb.SetCode(clock, common.FromHex("0x4260005260206000f3"))
+	// Return the hash of the parent block
+	statedb.SetCode(hasher, common.FromHex("0x600143034060005260206000f3"))
+	// Revert without data
+	statedb.SetCode(reverter, common.FromHex("0x60006000fd"))
+
+	var (
+		api     = NewBlockChainAPI(&stateBackendMock{backendMock: mock, statedb: statedb})
+		balance = (*hexutil.Big)(big.NewInt(params.Ether))
+		value   = (*hexutil.Big)(big.NewInt(1000))
+		number  = (*hexutil.Big)(new(big.Int).Add(mock.current.Number, big.NewInt(3)))
+		time    = (*hexutil.Big)(big.NewInt(10000))
+	)
+	opts := SimulateOpts{
+		TraceTransfers: true,
+		BlockStateCalls: []SimulateBlock{
+			{
+				StateOverrides: &StateOverride{sender: OverrideAccount{Balance: &balance}},
+				Calls: []TransactionArgs{
+					{From: &sender, To: &counter},
+					{From: &sender, To: &counter},
+					{From: &sender, To: &recipient, Value: value},
+				},
+			},
+			{
+				BlockOverrides: &BlockOverrides{Number: number, Time: time},
+				Calls: []TransactionArgs{
+					{From: &sender, To: &counter},
+					{From: &sender, To: &clock},
+					{From: &sender, To: &hasher},
+				},
+			},
+			{
+				Calls: []TransactionArgs{{From: &sender, To: &reverter}},
+			},
+		},
+	}
+	blocks, err := api.SimulateV1(context.Background(), opts, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	// The gap before the second block must be filled with an empty one
+	if len(blocks) != 4 {
+		t.Fatalf("block count mismatch: have %d, want 4", len(blocks))
+	}
+	parent := mock.current.Hash()
+	for i, block := range blocks {
+		if have, want := block["number"].(*hexutil.Big).ToInt().Uint64(), mock.current.Number.Uint64()+uint64(i)+1; have != want {
+			t.Errorf("block %d: number mismatch: have %d, want %d", i, have, want)
+		}
+		if have := block["parentHash"].(common.Hash); have != parent {
+			t.Errorf("block %d: parent mismatch: have %x, want %x", i, have, parent)
+		}
+		parent = block["hash"].(common.Hash)
+	}
+	if have := uint64(blocks[1]["timestamp"].(hexutil.Uint64)); have != mock.current.Time+2*simulateBlockTime {
+		t.Errorf("gap block timestamp mismatch: have %d", have)
+	}
+	if have := len(blocks[1]["calls"].([]*SimulateCallResult)); have != 0 {
+		t.Errorf("gap block has %d calls", have)
+	}
+	// Calls must see the effects of the preceding ones, across blocks too
+	first := blocks[0]["calls"].([]*SimulateCallResult)
+	if have := new(big.Int).SetBytes(first[1].ReturnData); have.Uint64() != 2 {
+		t.Errorf("second call result mismatch: have %d, want 2", have)
+	}
+	if logs := first[2].Logs; len(logs) != 1 || logs[0].Address != transferAddress || logs[0].Topics[2] != recipient.Hash() || logs[0].Index != 0 {
+		t.Errorf("transfer log mismatch: %+v", logs)
+	}
+	if logs := first[2].Logs; len(logs) == 1 && logs[0].BlockHash != blocks[0]["hash"].(common.Hash) {
+		t.Errorf("transfer log block hash mismatch: have %x", logs[0].BlockHash)
+	}
+	second := blocks[2]["calls"].([]*SimulateCallResult)
+	if have := new(big.Int).SetBytes(second[0].ReturnData); have.Uint64() != 3 {
+		t.Errorf("counter result mismatch: have %d, want 3", have)
+	}
+	if have := new(big.Int).SetBytes(second[1].ReturnData); have.Cmp(time.ToInt()) != 0 {
+		t.Errorf("timestamp mismatch: have %d, want %d", have, time.ToInt())
+	}
+	if have := common.BytesToHash(second[2].ReturnData); have != blocks[1]["hash"].(common.Hash) {
+		t.Errorf("block hash mismatch: have %x, want %x", have, blocks[1]["hash"])
+	}
+	third := blocks[3]["calls"].([]*SimulateCallResult)
+	if call := third[0]; call.Status != 0 || call.Error == nil || call.Error.Code != 3 {
+		t.Errorf("reverted call mismatch: %+v", call)
+	}
+}
+
+func TestSimulateV1Invalid(t *testing.T) {
+	var (
+		mock       = newBackendMock()
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		api        = NewBlockChainAPI(&stateBackendMock{backendMock: mock, statedb: statedb})
+		past       = (*hexutil.Big)(mock.current.Number)
+		early      = (*hexutil.Big)(new(big.Int).SetUint64(mock.current.Time))
+		far        = (*hexutil.Big)(new(big.Int).Add(mock.current.Number, big.NewInt(maxSimulateBlocks+1)))
+		sender     = common.Address{0x01}
+	)
+	tests := []SimulateOpts{
+		{},
+		{BlockStateCalls: []SimulateBlock{{BlockOverrides: &BlockOverrides{Number: past}}}},
+		{BlockStateCalls: []SimulateBlock{{BlockOverrides: &BlockOverrides{Time: early}}}},
+		{BlockStateCalls: []SimulateBlock{{BlockOverrides: &BlockOverrides{Number: far}}}},
+		// Zero fee calls are rejected below the base fee when validating
+		{Validation: true, BlockStateCalls: []SimulateBlock{{Calls: []TransactionArgs{{From: &sender, To: &sender}}}}},
+	}
+	for i, opts := range tests {
+		if _, err := api.SimulateV1(context.Background(), opts, nil); err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
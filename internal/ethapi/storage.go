@@ -0,0 +1,76 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// stateStorage serves storage slots of a fixed state to a storage layout reader.
+type stateStorage struct {
+	state *state.StateDB
+}
+
+func (s *stateStorage) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	value := s.state.GetState(account, key)
+	return value[:], s.state.Error()
+}
+
+// ReadStorage decodes a state variable of a contract given the contract's storage
+// layout as emitted by solc --storage-layout. The path names the variable and
+// optionally indexes into it, e.g. `balances[0x1234...]` or `users["alice"].age`.
+func (api *DebugAPI) ReadStorage(ctx context.Context, address common.Address, layout json.RawMessage, path string, blockNrOrHash rpc.BlockNumberOrHash) (interface{}, error) {
+	parsed, err := storage.ParseLayout(layout)
+	if err != nil {
+		return nil, err
+	}
+	state, _, err := api.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	value, err := storage.NewReader(&stateStorage{state}, address, parsed).Read(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return rpcStorageValue(value), nil
+}
+
+// rpcStorageValue converts the integers of a decoded storage value to their hex
+// representation, so they survive JavaScript clients.
+func rpcStorageValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return (*hexutil.Big)(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = rpcStorageValue(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = rpcStorageValue(v[k])
+		}
+	}
+	return value
+}
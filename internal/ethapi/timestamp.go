@@ -0,0 +1,120 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// timestampCacheSize is the number of block timestamps kept in memory to
+	// speed up repeated searches by timestamp.
+	timestampCacheSize = 16384
+
+	// timestampCacheDepth is the number of blocks below the chain head whose
+	// timestamps are not cached, as they may still be reorganised.
+	timestampCacheDepth = 128
+)
+
+// timestampIndex is an in-memory index of the timestamps of canonical blocks,
+// filled by the binary searches resolving blocks by timestamp. Since every
+// search probes the same upper levels of the search tree, those are served from
+// memory after the first query.
+type timestampIndex struct {
+	times *lru.Cache // block number -> timestamp
+}
+
+func newTimestampIndex() *timestampIndex {
+	times, _ := lru.New(timestampCacheSize)
+	return &timestampIndex{times: times}
+}
+
+// timeAt returns the timestamp of the canonical block with the given number.
+func (idx *timestampIndex) timeAt(ctx context.Context, b Backend, number, head uint64) (uint64, error) {
+	if time, ok := idx.times.Get(number); ok {
+		return time.(uint64), nil
+	}
+	header, err := b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("header #%d not found", number)
+	}
+	if number+timestampCacheDepth <= head {
+		idx.times.Add(number, header.Time)
+	}
+	return header.Time, nil
+}
+
+// search returns the number of the last block up to the given head with a
+// timestamp at or before the given time. False is returned if all blocks are
+// newer than the time.
+func (idx *timestampIndex) search(ctx context.Context, b Backend, head *types.Header, time uint64) (uint64, bool, error) {
+	headNumber := head.Number.Uint64()
+	if head.Time <= time {
+		return headNumber, true, nil
+	}
+	genesisTime, err := idx.timeAt(ctx, b, 0, headNumber)
+	if err != nil {
+		return 0, false, err
+	}
+	if genesisTime > time {
+		return 0, false, nil
+	}
+	// Block timestamps are strictly increasing, keep time(lo) <= time < time(hi)
+	lo, hi := uint64(0), headNumber
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		midTime, err := idx.timeAt(ctx, b, mid, headNumber)
+		if err != nil {
+			return 0, false, err
+		}
+		if midTime <= time {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, true, nil
+}
+
+// GetBlockByTimestamp returns the last canonical block with a timestamp at or
+// before the given one, or null if the timestamp precedes the genesis block.
+// When fullTx is true all transactions in the block are returned, otherwise
+// only the transaction hash is returned.
+func (s *BlockChainAPI) GetBlockByTimestamp(ctx context.Context, timestamp hexutil.Uint64, fullTx bool) (map[string]interface{}, error) {
+	head, err := s.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil || err != nil {
+		return nil, err
+	}
+	number, ok, err := s.timestamps.search(ctx, s.b, head, uint64(timestamp))
+	if !ok || err != nil {
+		return nil, err
+	}
+	block, err := s.b.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return s.rpcMarshalBlock(ctx, block, true, fullTx)
+}
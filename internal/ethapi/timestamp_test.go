@@ -0,0 +1,105 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// chainBackendMock is a backend serving a fixed chain of headers.
+type chainBackendMock struct {
+	*backendMock
+	headers []*types.Header
+	queries int
+}
+
+func (b *chainBackendMock) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	b.queries++
+	if number == rpc.LatestBlockNumber {
+		number = rpc.BlockNumber(len(b.headers) - 1)
+	}
+	if number < 0 || int(number) >= len(b.headers) {
+		return nil, nil
+	}
+	return b.headers[number], nil
+}
+
+func (b *chainBackendMock) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	header, err := b.HeaderByNumber(ctx, number)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	return types.NewBlockWithHeader(header), nil
+}
+
+func TestGetBlockByTimestamp(t *testing.T) {
+	// Blocks every 10 seconds, starting at 1000
+	backend := &chainBackendMock{backendMock: newBackendMock()}
+	for i := 0; i < 1000; i++ {
+		backend.headers = append(backend.headers, &types.Header{
+			Number: big.NewInt(int64(i)),
+			Time:   uint64(1000 + 10*i),
+		})
+	}
+	api := NewBlockChainAPI(backend)
+
+	tests := []struct {
+		time   uint64
+		number int64 // -1 = no block
+	}{
+		{999, -1},
+		{1000, 0},
+		{1009, 0},
+		{1010, 1},
+		{5555, 455},
+		{10980, 998},
+		{10990, 999},
+		{20000, 999},
+	}
+	for _, tt := range tests {
+		block, err := api.GetBlockByTimestamp(context.Background(), hexutil.Uint64(tt.time), false)
+		if err != nil {
+			t.Fatalf("time %d: search failed: %v", tt.time, err)
+		}
+		if tt.number < 0 {
+			if block != nil {
+				t.Errorf("time %d: have block %v, want none", tt.time, block["number"])
+			}
+			continue
+		}
+		if block == nil {
+			t.Fatalf("time %d: block not found", tt.time)
+		}
+		if number := block["number"].(*hexutil.Big).ToInt(); number.Int64() != tt.number {
+			t.Errorf("time %d: block mismatch: have %d, want %d", tt.time, number, tt.number)
+		}
+	}
+	// Repeated searches should be served from the index
+	backend.queries = 0
+	if _, err := api.GetBlockByTimestamp(context.Background(), 5555, false); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if backend.queries > 2 {
+		t.Errorf("repeated search queried %d headers, want at most 2", backend.queries)
+	}
+}
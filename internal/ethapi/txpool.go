@@ -0,0 +1,190 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// defaultTxPoolPageSize is the number of transactions returned by a pool query
+	// if no limit is given.
+	defaultTxPoolPageSize = 100
+
+	// maxTxPoolPageSize is the maximum number of transactions returned by a pool
+	// query.
+	maxTxPoolPageSize = 10000
+)
+
+// TxPoolQuery selects, sorts and paginates the transactions returned from the pool.
+// All filters are optional.
+type TxPoolQuery struct {
+	From   *common.Address `json:"from"`   // Only transactions sent by this account
+	To     *common.Address `json:"to"`     // Only transactions sent to this address
+	MinTip *hexutil.Big    `json:"minTip"` // Only transactions paying at least this effective tip at the current base fee
+	Type   *hexutil.Uint64 `json:"type"`   // Only transactions of this type
+	Status string          `json:"status"` // Only "pending" or "queued" transactions
+
+	SortBy string `json:"sortBy"` // Sort order: "sender" (default, by sender and nonce), "tip", "gas" or "hash"
+	Desc   bool   `json:"desc"`   // Reverse the sort order
+
+	Offset hexutil.Uint64 `json:"offset"` // Number of matching transactions to skip
+	Limit  hexutil.Uint64 `json:"limit"`  // Maximum number of transactions to return
+}
+
+// TxPoolPage is a page of pool transactions matching a query.
+type TxPoolPage struct {
+	Total        int            `json:"total"` // Number of matching transactions in the pool
+	Transactions []*TxPoolEntry `json:"transactions"`
+}
+
+// TxPoolEntry is a pool transaction along with its pool status.
+type TxPoolEntry struct {
+	Status string `json:"status"`
+	*RPCTransaction
+}
+
+// poolTx is a pool transaction being filtered and sorted.
+type poolTx struct {
+	tx      *types.Transaction
+	sender  common.Address
+	pending bool
+	tip     *big.Int
+}
+
+// query returns the page of pool transactions matching the query.
+func (s *TxPoolAPI) query(query *TxPoolQuery) (*TxPoolPage, error) {
+	if query.Status != "" && query.Status != "pending" && query.Status != "queued" {
+		return nil, fmt.Errorf("invalid status %q", query.Status)
+	}
+	limit := int(query.Limit)
+	if limit == 0 {
+		limit = defaultTxPoolPageSize
+	}
+	if limit > maxTxPoolPageSize {
+		return nil, fmt.Errorf("limit too large: %d > %d", limit, maxTxPoolPageSize)
+	}
+	var (
+		curHeader = s.b.CurrentHeader()
+		txs       []*poolTx
+	)
+	add := func(sender common.Address, list types.Transactions, pending bool) {
+		if query.Status == "pending" && !pending || query.Status == "queued" && pending {
+			return
+		}
+		for _, tx := range list {
+			if query.To != nil && (tx.To() == nil || *tx.To() != *query.To) {
+				continue
+			}
+			if query.Type != nil && uint64(tx.Type()) != uint64(*query.Type) {
+				continue
+			}
+			tip := tx.EffectiveGasTipValue(curHeader.BaseFee)
+			if query.MinTip != nil && tip.Cmp(query.MinTip.ToInt()) < 0 {
+				continue
+			}
+			txs = append(txs, &poolTx{tx: tx, sender: sender, pending: pending, tip: tip})
+		}
+	}
+	if query.From != nil {
+		pending, queue := s.b.TxPoolContentFrom(*query.From)
+		add(*query.From, pending, true)
+		add(*query.From, queue, false)
+	} else {
+		pending, queue := s.b.TxPoolContent()
+		for sender, list := range pending {
+			add(sender, list, true)
+		}
+		for sender, list := range queue {
+			add(sender, list, false)
+		}
+	}
+	less, err := txPoolOrder(query.SortBy)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if query.Desc {
+			return less(txs[j], txs[i])
+		}
+		return less(txs[i], txs[j])
+	})
+	page := &TxPoolPage{Total: len(txs), Transactions: []*TxPoolEntry{}}
+	if uint64(query.Offset) >= uint64(len(txs)) {
+		return page, nil
+	}
+	txs = txs[query.Offset:]
+	if len(txs) > limit {
+		txs = txs[:limit]
+	}
+	for _, tx := range txs {
+		status := "queued"
+		if tx.pending {
+			status = "pending"
+		}
+		page.Transactions = append(page.Transactions, &TxPoolEntry{
+			Status:         status,
+			RPCTransaction: newRPCPendingTransaction(tx.tx, curHeader, s.b.ChainConfig()),
+		})
+	}
+	return page, nil
+}
+
+// txPoolOrder returns the ordering function for a sort key. All orders fall back
+// to the transaction hash to be deterministic across pages.
+func txPoolOrder(key string) (func(a, b *poolTx) bool, error) {
+	byHash := func(a, b *poolTx) bool {
+		return bytes.Compare(a.tx.Hash().Bytes(), b.tx.Hash().Bytes()) < 0
+	}
+	switch key {
+	case "", "sender":
+		return func(a, b *poolTx) bool {
+			if c := bytes.Compare(a.sender.Bytes(), b.sender.Bytes()); c != 0 {
+				return c < 0
+			}
+			if a.tx.Nonce() != b.tx.Nonce() {
+				return a.tx.Nonce() < b.tx.Nonce()
+			}
+			return byHash(a, b)
+		}, nil
+	case "tip":
+		return func(a, b *poolTx) bool {
+			if c := a.tip.Cmp(b.tip); c != 0 {
+				return c < 0
+			}
+			return byHash(a, b)
+		}, nil
+	case "gas":
+		return func(a, b *poolTx) bool {
+			if a.tx.Gas() != b.tx.Gas() {
+				return a.tx.Gas() < b.tx.Gas()
+			}
+			return byHash(a, b)
+		}, nil
+	case "hash":
+		return byHash, nil
+	default:
+		return nil, fmt.Errorf("invalid sort key %q", key)
+	}
+}
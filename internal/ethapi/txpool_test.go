@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// poolBackendMock is a backend serving a fixed transaction pool.
+type poolBackendMock struct {
+	*backendMock
+	pending, queued map[common.Address]types.Transactions
+}
+
+func (b *poolBackendMock) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return b.pending, b.queued
+}
+
+func (b *poolBackendMock) TxPoolContentFrom(addr common.Address) (types.Transactions, types.Transactions) {
+	return b.pending[addr], b.queued[addr]
+}
+
+func TestTxPoolQuery(t *testing.T) {
+	var (
+		mock    = newBackendMock()
+		signer  = types.LatestSigner(mock.config)
+		backend = &poolBackendMock{
+			backendMock: mock,
+			pending:     make(map[common.Address]types.Transactions),
+			queued:      make(map[common.Address]types.Transactions),
+		}
+		to = common.Address{0xaa}
+	)
+	// Two senders with three pending and two queued transactions each
+	var senders []common.Address
+	for i := 0; i < 2; i++ {
+		key, _ := crypto.GenerateKey()
+		sender := crypto.PubkeyToAddress(key.PublicKey)
+		senders = append(senders, sender)
+		for nonce := uint64(0); nonce < 5; nonce++ {
+			var data types.TxData = &types.DynamicFeeTx{
+				ChainID:   mock.config.ChainID,
+				Nonce:     nonce,
+				Gas:       21000 + nonce,
+				GasTipCap: big.NewInt(int64(nonce + 1)),
+				GasFeeCap: big.NewInt(100),
+				To:        &to,
+			}
+			if nonce == 0 {
+				data = &types.LegacyTx{Nonce: nonce, Gas: 21000, GasPrice: big.NewInt(100)}
+			}
+			tx := types.MustSignNewTx(key, signer, data)
+			if nonce < 3 {
+				backend.pending[sender] = append(backend.pending[sender], tx)
+			} else {
+				backend.queued[sender] = append(backend.queued[sender], tx)
+			}
+		}
+	}
+	api := NewTxPoolAPI(backend)
+	query := func(q *TxPoolQuery) *TxPoolPage {
+		t.Helper()
+		res, err := api.Content(q)
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		return res.(*TxPoolPage)
+	}
+	typ := hexutil.Uint64(types.DynamicFeeTxType)
+	tests := []struct {
+		query *TxPoolQuery
+		total int
+		check func(page *TxPoolPage) bool
+	}{
+		{&TxPoolQuery{}, 10, nil},
+		{&TxPoolQuery{From: &senders[0]}, 5, func(p *TxPoolPage) bool {
+			return p.Transactions[0].Nonce == 0 && p.Transactions[4].Nonce == 4
+		}},
+		{&TxPoolQuery{To: &to, Status: "pending"}, 4, nil},
+		{&TxPoolQuery{Type: &typ}, 8, nil},
+		{&TxPoolQuery{MinTip: (*hexutil.Big)(big.NewInt(4))}, 6, nil}, // legacy txs pay 90 over the base fee
+		{&TxPoolQuery{Type: &typ, SortBy: "tip", Desc: true, Limit: 2}, 8, func(p *TxPoolPage) bool {
+			return len(p.Transactions) == 2 && p.Transactions[0].GasTipCap.ToInt().Int64() == 5
+		}},
+		{&TxPoolQuery{SortBy: "gas", Offset: 9, Limit: 5}, 10, func(p *TxPoolPage) bool {
+			return len(p.Transactions) == 1 && p.Transactions[0].Gas == 21004
+		}},
+		{&TxPoolQuery{Offset: 20}, 10, func(p *TxPoolPage) bool {
+			return len(p.Transactions) == 0
+		}},
+	}
+	for i, tt := range tests {
+		page := query(tt.query)
+		if page.Total != tt.total {
+			t.Errorf("test %d: total mismatch: have %d, want %d", i, page.Total, tt.total)
+		}
+		if tt.check != nil && !tt.check(page) {
+			t.Errorf("test %d: unexpected page content", i)
+		}
+	}
+	if _, err := api.Content(&TxPoolQuery{SortBy: "value"}); err == nil {
+		t.Error("invalid sort key accepted")
+	}
+	// Without a query, the full content should be returned as before
+	res, _ := api.Content(nil)
+	if content := res.(map[string]map[string]map[string]*RPCTransaction); len(content["pending"]) != 2 || len(content["queued"]) != 2 {
+		t.Error("unfiltered content mismatch")
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package grpcwire implements unary gRPC calls over HTTP/2 without depending on
+// the gRPC libraries. Messages are encoded and decoded with protowire.
+package grpcwire
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"golang.org/x/net/http2"
+)
+
+// maxMessageSize is the maximum size of a message accepted from the peer.
+const maxMessageSize = 4 * 1024 * 1024
+
+// Client is a minimal client for unary gRPC calls, speaking the gRPC wire
+// protocol over HTTP/2. The "grpc" scheme connects in cleartext (h2c), "grpcs"
+// uses TLS.
+type Client struct {
+	base   string // Base URL of the service methods
+	client *http.Client
+}
+
+// Error is an error status returned by a gRPC server.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("grpc error %d: %s", e.Code, e.Message)
+}
+
+// Dial creates a client for the service at the given grpc:// or grpcs://
+// endpoint. Connections are established lazily on the first call.
+func Dial(endpoint string) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in gRPC endpoint %q", endpoint)
+	}
+	transport := new(http2.Transport)
+	switch u.Scheme {
+	case "grpc":
+		u.Scheme = "http"
+		transport.AllowHTTP = true
+		transport.DialTLS = func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		}
+	case "grpcs":
+		u.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("unsupported gRPC scheme %q", u.Scheme)
+	}
+	return &Client{
+		base:   u.Scheme + "://" + u.Host,
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+// Close closes the idle connections of the client. It can still be used to
+// make calls afterwards, establishing new connections.
+func (c *Client) Close() {
+	c.client.CloseIdleConnections()
+}
+
+// Call invokes the given method (in the /package.Service/Method form) with the
+// encoded request message, returning the encoded response message.
+func (c *Client) Call(ctx context.Context, method string, request []byte) ([]byte, error) {
+	body := make([]byte, 5+len(request))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(request)))
+	copy(body[5:], request)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc+proto")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gRPC request failed: %s", resp.Status)
+	}
+	// Errors may be reported without a body in the headers, or in the trailers
+	// following the response message.
+	if err := grpcStatus(resp.Header); err != nil {
+		return nil, err
+	}
+	var response []byte
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(resp.Body, header); err == nil {
+		if header[0] != 0 {
+			return nil, errors.New("compressed gRPC responses not supported")
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if size > maxMessageSize {
+			return nil, fmt.Errorf("gRPC response too large: %d bytes", size)
+		}
+		response = make([]byte, size)
+		if _, err := io.ReadFull(resp.Body, response); err != nil {
+			return nil, err
+		}
+	} else if err != io.EOF {
+		return nil, err
+	}
+	// Drain the body for the trailers to arrive
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return nil, err
+	}
+	if err := grpcStatus(resp.Trailer); err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, errors.New("missing gRPC response")
+	}
+	return response, nil
+}
+
+// grpcStatus returns the error reported by the gRPC status fields, if any.
+func grpcStatus(fields http.Header) error {
+	status := fields.Get("Grpc-Status")
+	if status == "" || status == "0" {
+		return nil
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return fmt.Errorf("invalid gRPC status %q", status)
+	}
+	message, err := url.PathUnescape(fields.Get("Grpc-Message"))
+	if err != nil {
+		message = fields.Get("Grpc-Message")
+	}
+	return &Error{Code: code, Message: message}
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package grpcwire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Status codes of the gRPC protocol used by the server.
+const (
+	CodeInvalidArgument = 3
+	CodeNotFound        = 5
+	CodeUnimplemented   = 12
+	CodeInternal        = 13
+)
+
+// Method handles a unary call, receiving the encoded request message and
+// returning the encoded response message. Returning an *Error reports its
+// status code to the client, other errors are reported as internal errors.
+type Method func(req []byte) ([]byte, error)
+
+// Server serves the unary methods of a gRPC service.
+type Server struct {
+	prefix  string // Path prefix of the service methods
+	methods map[string]Method
+}
+
+// NewServer creates a server for the given service, in the package.Service form.
+func NewServer(service string) *Server {
+	return &Server{
+		prefix:  "/" + service + "/",
+		methods: make(map[string]Method),
+	}
+}
+
+// Register adds a method to the service. It must not be called while serving.
+func (s *Server) Register(name string, method Method) {
+	s.methods[name] = method
+}
+
+// Handler returns an HTTP handler serving the service over HTTP/2, accepting
+// cleartext (h2c) connections.
+func (s *Server) Handler() http.Handler {
+	return h2c.NewHandler(s, new(http2.Server))
+}
+
+// ServeHTTP implements http.Handler, serving a single call.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "not a gRPC request", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc+proto")
+
+	method, ok := s.methods[strings.TrimPrefix(r.URL.Path, s.prefix)]
+	if !ok || !strings.HasPrefix(r.URL.Path, s.prefix) {
+		writeStatus(w.Header(), &Error{Code: CodeUnimplemented, Message: "unknown method"})
+		return
+	}
+	req, err := readMessage(r.Body)
+	if err != nil {
+		writeStatus(w.Header(), &Error{Code: CodeInvalidArgument, Message: err.Error()})
+		return
+	}
+	res, err := method(req)
+	if err != nil {
+		writeStatus(w.Header(), err)
+		return
+	}
+	frame := make([]byte, 5, 5+len(res))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(res)))
+	w.Write(append(frame, res...))
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+}
+
+// readMessage reads the single message of a unary request.
+func readMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.New("missing request message")
+	}
+	if header[0] != 0 {
+		return nil, errors.New("compressed requests not supported")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("request too large: %d bytes", size)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeStatus reports the error of a call without a response message, in the
+// headers of the response.
+func writeStatus(header http.Header, err error) {
+	status, ok := err.(*Error)
+	if !ok {
+		status = &Error{Code: CodeInternal, Message: err.Error()}
+	}
+	header.Set("Grpc-Status", strconv.Itoa(status.Code))
+	header.Set("Grpc-Message", url.PathEscape(status.Message))
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package grpcwire
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func newTestServer(t *testing.T) *Client {
+	srv := NewServer("test.v1.Echo")
+	srv.Register("Echo", func(req []byte) ([]byte, error) {
+		text, err := DecodeString(req, 1)
+		if err != nil {
+			return nil, err
+		}
+		count, err := DecodeUint(req, 2)
+		if err != nil {
+			return nil, err
+		}
+		var res []byte
+		for i := uint64(0); i < count; i++ {
+			res = protowire.AppendTag(res, 1, protowire.BytesType)
+			res = protowire.AppendString(res, text)
+		}
+		return res, nil
+	})
+	srv.Register("Fail", func(req []byte) ([]byte, error) {
+		return nil, errors.New("failure")
+	})
+	srv.Register("NotFound", func(req []byte) ([]byte, error) {
+		return nil, &Error{Code: CodeNotFound, Message: "no such thing"}
+	})
+	server := httptest.NewServer(srv.Handler())
+	t.Cleanup(server.Close)
+
+	client, err := Dial("grpc://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestCall(t *testing.T) {
+	client := newTestServer(t)
+
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendString(req, "hello")
+	req = protowire.AppendTag(req, 2, protowire.VarintType)
+	req = protowire.AppendVarint(req, 3)
+
+	res, err := client.Call(context.Background(), "/test.v1.Echo/Echo", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := DecodeBytes(res, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 3 || string(values[2]) != "hello" {
+		t.Fatalf("wrong response: %q", values)
+	}
+	// An empty response message is still a response
+	if _, err := client.Call(context.Background(), "/test.v1.Echo/Echo", nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	client := newTestServer(t)
+
+	tests := []struct {
+		method string
+		code   int
+		msg    string
+	}{
+		{"/test.v1.Echo/Fail", CodeInternal, "failure"},
+		{"/test.v1.Echo/NotFound", CodeNotFound, "no such thing"},
+		{"/test.v1.Echo/Missing", CodeUnimplemented, "unknown method"},
+		{"/test.v1.Other/Echo", CodeUnimplemented, "unknown method"},
+	}
+	for _, test := range tests {
+		_, err := client.Call(context.Background(), test.method, nil)
+		if err, ok := err.(*Error); !ok || err.Code != test.code || err.Message != test.msg {
+			t.Errorf("%s: wrong error %v", test.method, err)
+		}
+	}
+}
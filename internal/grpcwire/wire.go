@@ -0,0 +1,85 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package grpcwire
+
+import "google.golang.org/protobuf/encoding/protowire"
+
+// DecodeBytes returns all occurrences of the given length-delimited field in
+// the protobuf message, skipping any other fields.
+func DecodeBytes(msg []byte, field protowire.Number) ([][]byte, error) {
+	var values [][]byte
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		if num == field && typ == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(msg)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			values = append(values, value)
+			msg = msg[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, msg)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		msg = msg[n:]
+	}
+	return values, nil
+}
+
+// DecodeString returns the last occurrence of the given string field in the
+// protobuf message, or the empty string if it is not present.
+func DecodeString(msg []byte, field protowire.Number) (string, error) {
+	values, err := DecodeBytes(msg, field)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return string(values[len(values)-1]), nil
+}
+
+// DecodeUint returns the last occurrence of the given varint field in the
+// protobuf message, or zero if it is not present.
+func DecodeUint(msg []byte, field protowire.Number) (uint64, error) {
+	var value uint64
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		msg = msg[n:]
+		if num == field && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(msg)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			value = v
+			msg = msg[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, msg)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		msg = msg[n:]
+	}
+	return value, nil
+}
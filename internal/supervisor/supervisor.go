@@ -0,0 +1,79 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package supervisor integrates the process with the service manager running it,
+// i.e. systemd on Linux and the Service Control Manager on Windows.
+package supervisor
+
+import "sync"
+
+// Supervisor reports the lifecycle of the process to the service manager it runs
+// under. If the process is not run by a service manager, all reports are no-ops.
+type Supervisor struct {
+	manager manager // Platform specific state of the service manager connection
+
+	stopRequested chan struct{} // Closed when the service manager requests a shutdown
+	stopping      chan struct{} // Closed when the process starts shutting down
+	stopped       chan struct{} // Closed when the process is done shutting down
+
+	stopRequestOnce sync.Once
+	stoppingOnce    sync.Once
+	stoppedOnce     sync.Once
+}
+
+// New connects to the service manager running the process, if any. It should be
+// called before any lengthy initialization, as the Windows service manager gives
+// up on services which don't connect to it in time.
+func New(name string) *Supervisor {
+	s := &Supervisor{
+		stopRequested: make(chan struct{}),
+		stopping:      make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	s.connect(name)
+	return s
+}
+
+// Start runs the given function to start up the process. The process is reported
+// as starting to the service manager meanwhile, and as ready once start succeeds.
+func (s *Supervisor) Start(start func() error) error {
+	return s.start(start)
+}
+
+// StopRequested returns a channel which is closed when the service manager asks
+// the process to shut down. The process is expected to initiate the same graceful
+// shutdown as an interrupt would.
+func (s *Supervisor) StopRequested() <-chan struct{} {
+	return s.stopRequested
+}
+
+// requestStop forwards a shutdown request of the service manager.
+func (s *Supervisor) requestStop() {
+	s.stopRequestOnce.Do(func() { close(s.stopRequested) })
+}
+
+// Stopping reports that the process started shutting down.
+func (s *Supervisor) Stopping() {
+	s.stoppingOnce.Do(func() {
+		close(s.stopping)
+		s.notifyStopping()
+	})
+}
+
+// Stopped reports that the process is done shutting down and about to exit.
+func (s *Supervisor) Stopped() {
+	s.stoppedOnce.Do(func() { close(s.stopped) })
+}
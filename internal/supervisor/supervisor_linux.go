@@ -0,0 +1,115 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package supervisor
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// notify sends a state update to systemd over the socket given in NOTIFY_SOCKET,
+// as described in sd_notify(3). Without a notification socket it does nothing.
+func notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// A leading '@' denotes a socket in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval returns the interval at which systemd expects keep-alive
+// notifications from the process, or zero if the watchdog is disabled.
+func watchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" || os.Getenv("NOTIFY_SOCKET") == "" {
+		return 0, nil
+	}
+	// The watchdog may be meant for another process of the service
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(usec, 10, 64)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", usec)
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
+
+type manager struct{}
+
+// connect is a no-op, as systemd only needs to be notified once the process is
+// ready. It stops the service with SIGTERM, which is handled by the caller.
+func (s *Supervisor) connect(name string) {}
+
+func (s *Supervisor) start(start func() error) error {
+	if err := start(); err != nil {
+		return err
+	}
+	if err := notify("READY=1"); err != nil {
+		log.Warn("Failed to notify systemd", "err", err)
+		return nil
+	}
+	interval, err := watchdogInterval()
+	if err != nil {
+		log.Warn("Failed to configure systemd watchdog", "err", err)
+		return nil
+	}
+	if interval > 0 {
+		log.Info("Enabled systemd watchdog", "interval", interval)
+		go s.watchdog(interval / 2)
+	}
+	return nil
+}
+
+// watchdog sends keep-alive notifications to systemd until shutdown starts.
+func (s *Supervisor) watchdog(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := notify("WATCHDOG=1"); err != nil {
+				log.Warn("Failed to notify systemd watchdog", "err", err)
+			}
+		case <-s.stopping:
+			return
+		}
+	}
+}
+
+func (s *Supervisor) notifyStopping() {
+	if err := notify("STOPPING=1"); err != nil {
+		log.Warn("Failed to notify systemd", "err", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package supervisor
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/sys/windows/svc"
+)
+
+// startWaitHint is the time within which the service reports progress to the
+// Service Control Manager while starting up.
+const startWaitHint = 30 * time.Second
+
+// manager tracks the connection to the Service Control Manager.
+type manager struct {
+	starts chan startRequest // Start functions to run within the service handler
+	exited chan struct{}     // Closed when the service dispatcher returns
+}
+
+// startRequest is a start function handed to the service handler, along with
+// the channel to deliver its result on.
+type startRequest struct {
+	start  func() error
+	result chan error
+}
+
+// connect runs the service dispatcher, which connects to the Service Control
+// Manager. This needs to happen soon after the process launched, otherwise the
+// service start fails with a timeout (error 1053).
+func (s *Supervisor) connect(name string) {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		log.Warn("Failed to detect Windows service", "err", err)
+		return
+	}
+	if !isService {
+		return
+	}
+	s.manager.starts = make(chan startRequest)
+	s.manager.exited = make(chan struct{})
+
+	go func() {
+		defer close(s.manager.exited)
+		if err := svc.Run(name, &serviceHandler{s}); err != nil {
+			log.Error("Windows service failed", "err", err)
+		}
+	}()
+}
+
+// start runs the start function within the service handler, so that the start
+// is reported as pending until it completes.
+func (s *Supervisor) start(start func() error) error {
+	if s.manager.starts == nil {
+		return start()
+	}
+	req := startRequest{start: start, result: make(chan error, 1)}
+	select {
+	case s.manager.starts <- req:
+		return <-req.result
+	case <-s.manager.exited:
+		return start()
+	}
+}
+
+func (s *Supervisor) notifyStopping() {}
+
+// serviceHandler reports the process state to the Service Control Manager and
+// forwards its stop requests.
+type serviceHandler struct {
+	s *Supervisor
+}
+
+// Execute implements svc.Handler.
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	if !h.startup(requests, status) {
+		return true, 1
+	}
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+loop:
+	for {
+		select {
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				log.Info("Stop requested by Windows service manager")
+				h.s.requestStop()
+				break loop
+			}
+		case <-h.s.stopping:
+			break loop
+		}
+	}
+	// Keep the service manager informed until the shutdown completes, it
+	// terminates the process as soon as the service is reported stopped.
+	status <- svc.Status{State: svc.StopPending}
+	for {
+		select {
+		case req := <-requests:
+			if req.Cmd == svc.Interrogate {
+				status <- svc.Status{State: svc.StopPending}
+			}
+		case <-h.s.stopped:
+			return false, 0
+		}
+	}
+}
+
+// startup reports the service as pending until the process has been started,
+// advancing the checkpoint periodically so that a slow start (e.g. opening a
+// large database) isn't considered hung. It returns whether the start succeeded.
+func (h *serviceHandler) startup(requests <-chan svc.ChangeRequest, status chan<- svc.Status) bool {
+	pending := svc.Status{State: svc.StartPending, WaitHint: uint32(startWaitHint / time.Millisecond)}
+	status <- pending
+
+	ticker := time.NewTicker(startWaitHint / 3)
+	defer ticker.Stop()
+
+	var (
+		req      startRequest
+		done     chan error
+		stopping = h.s.stopping
+	)
+	for {
+		select {
+		case req = <-h.s.manager.starts:
+			// The start function has to deliver its result, so shutdowns
+			// are only considered before it runs.
+			done, stopping = make(chan error, 1), nil
+			go func(start func() error) { done <- start() }(req.start)
+
+		case err := <-done:
+			req.result <- err
+			return err == nil
+
+		case <-stopping:
+			return false
+
+		case r := <-requests:
+			if r.Cmd == svc.Interrogate {
+				status <- pending
+			}
+		case <-ticker.C:
+			pending.CheckPoint++
+			status <- pending
+		}
+	}
+}
@@ -141,6 +141,21 @@ web3._extend({
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setNodeRecordEntry',
+			call: 'admin_setNodeRecordEntry',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'deleteNodeRecordEntry',
+			call: 'admin_deleteNodeRecordEntry',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'peerRecordEntries',
+			call: 'admin_peerRecordEntries',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -152,6 +167,19 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'chainConfigCheck',
+			call: 'admin_chainConfigCheck',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'peerLatency',
+			call: 'admin_peerLatency'
+		}),
+		new web3._extend.Method({
+			name: 'promote',
+			call: 'admin_promote'
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -189,6 +217,11 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'restartService',
+			call: 'admin_restartService',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -203,6 +236,14 @@ web3._extend({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'protocolStats',
+			getter: 'admin_protocolStats'
+		}),
+		new web3._extend.Property({
+			name: 'services',
+			getter: 'admin_services'
+		}),
 	]
 });
 `
@@ -223,6 +264,12 @@ web3._extend({
 			params: 1,
 			outputFormatter: console.log
 		}),
+		new web3._extend.Method({
+			name: 'registerABI',
+			call: 'debug_registerABI',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'getHeaderRlp',
 			call: 'debug_getHeaderRlp',
@@ -254,6 +301,23 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'genesisFromState',
+			call: 'debug_genesisFromState',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'storageStats',
+			call: 'debug_storageStats',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'executionBundle',
+			call: 'debug_executionBundle',
+			params: 1,
+		}),
 		new web3._extend.Method({
 			name: 'chaindbProperty',
 			call: 'debug_chaindbProperty',
@@ -264,6 +328,11 @@ web3._extend({
 			name: 'chaindbCompact',
 			call: 'debug_chaindbCompact',
 		}),
+		new web3._extend.Method({
+			name: 'chaindbMigrateFreezer',
+			call: 'debug_chaindbMigrateFreezer',
+			params: 1,
+		}),
 		new web3._extend.Method({
 			name: 'verbosity',
 			call: 'debug_verbosity',
@@ -351,6 +420,12 @@ web3._extend({
 			call: 'debug_writeBlockProfile',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'blockProfileRate',
+			call: 'debug_blockProfileRate',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'mutexProfile',
 			call: 'debug_mutexProfile',
@@ -366,6 +441,22 @@ web3._extend({
 			call: 'debug_writeMutexProfile',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'goroutineProfile',
+			call: 'debug_goroutineProfile',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'trieFlushPolicy',
+			call: 'debug_trieFlushPolicy',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'setTrieFlushPolicy',
+			call: 'debug_setTrieFlushPolicy',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'writeMemProfile',
 			call: 'debug_writeMemProfile',
@@ -437,6 +528,37 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'readStorage',
+			call: 'debug_readStorage',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'dumpTxPool',
+			call: 'debug_dumpTxPool',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'loadTxPool',
+			call: 'debug_loadTxPool',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'forceReorg',
+			call: 'debug_forceReorg',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'txPropagation',
+			call: 'debug_txPropagation',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'rareHistory',
+			call: 'debug_rareHistory',
+			params: 1,
+		}),
 		new web3._extend.Method({
 			name: 'getBadBlocks',
 			call: 'debug_getBadBlocks',
@@ -459,6 +581,12 @@ web3._extend({
 			params: 2,
 			inputFormatter:[null, null],
 		}),
+		new web3._extend.Method({
+			name: 'stateDiff',
+			call: 'debug_stateDiff',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter],
+		}),
 		new web3._extend.Method({
 			name: 'freezeClient',
 			call: 'debug_freezeClient',
@@ -524,6 +652,18 @@ web3._extend({
 			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputBlockNumberFormatter],
 			outputFormatter: web3._extend.utils.toDecimal
 		}),
+		new web3._extend.Method({
+			name: 'simulateV1',
+			call: 'eth_simulateV1',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'intrinsicGas',
+			call: 'eth_intrinsicGas',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'submitTransaction',
 			call: 'eth_submitTransaction',
@@ -559,6 +699,39 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, function (val) { return !!val; }]
 		}),
+		new web3._extend.Method({
+			name: 'ackLogsBulk',
+			call: 'eth_ackLogsBulk',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactions',
+			call: 'eth_getInternalTransactions',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getBlockByTimestamp',
+			call: 'eth_getBlockByTimestamp',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, function (val) { return !!val; }]
+		}),
+		new web3._extend.Method({
+			name: 'decodeTransaction',
+			call: 'eth_decodeTransaction',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getContractMetadata',
+			call: 'eth_getContractMetadata',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'txIndexProgress',
+			call: 'eth_txIndexProgress',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransaction',
 			call: 'eth_getRawTransactionByHash',
@@ -638,6 +811,11 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'setEtherbaseSchedule',
+			call: 'miner_setEtherbaseSchedule',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'setExtra',
 			call: 'miner_setExtra',
@@ -779,6 +957,11 @@ web3._extend({
 			call: 'txpool_contentFrom',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'query',
+			call: 'txpool_content',
+			params: 1,
+		}),
 	]
 });
 `
@@ -20,6 +20,7 @@ package catalyst
 import (
 	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/beacon"
@@ -101,7 +102,10 @@ func (api *ConsensusAPI) GetPayloadV1(payloadID beacon.PayloadID) (*beacon.Execu
 
 // ExecutePayloadV1 creates an Eth1 block, inserts it in the chain, and returns the status of the chain.
 func (api *ConsensusAPI) ExecutePayloadV1(params beacon.ExecutableDataV1) (beacon.PayloadStatusV1, error) {
-	block, err := beacon.ExecutableDataToBlock(params)
+	if api.les.BlockChain().Config().IsPrague(new(big.Int).SetUint64(params.Number)) {
+		return api.invalid(), beacon.UnsupportedFork.With(errors.New("Prague payloads not supported in light client mode"))
+	}
+	block, err := beacon.ExecutableDataToBlock(params, nil, nil)
 	if err != nil {
 		return api.invalid(), err
 	}
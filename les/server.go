@@ -18,6 +18,7 @@ package les
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/mclock"
@@ -77,6 +78,9 @@ type LesServer struct {
 }
 
 func NewLesServer(node *node.Node, e ethBackend, config *ethconfig.Config) (*LesServer, error) {
+	if e.BloomIndexer() == nil {
+		return nil, errors.New("light server requires the bloom bits index")
+	}
 	lesDb, err := node.OpenDatabase("les.server", 0, 0, "eth/db/lesserver/", false)
 	if err != nil {
 		return nil, err
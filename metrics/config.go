@@ -33,6 +33,11 @@ type Config struct {
 	InfluxDBToken        string `toml:",omitempty"`
 	InfluxDBBucket       string `toml:",omitempty"`
 	InfluxDBOrganization string `toml:",omitempty"`
+
+	EnableOTLP   bool   `toml:",omitempty"`
+	OTLPEndpoint string `toml:",omitempty"`
+	OTLPResource string `toml:",omitempty"`
+	OTLPHeaders  string `toml:",omitempty"`
 }
 
 // DefaultConfig is the default config for metrics used in go-ethereum.
@@ -53,4 +58,9 @@ var DefaultConfig = Config{
 	InfluxDBToken:        "test",
 	InfluxDBBucket:       "geth",
 	InfluxDBOrganization: "geth",
+
+	// OpenTelemetry-specific flags
+	EnableOTLP:   false,
+	OTLPEndpoint: "http://localhost:4318/v1/metrics",
+	OTLPResource: "service.name=geth",
 }
@@ -0,0 +1,174 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// exemplarSlots is the number of most recent exemplars retained by a histogram.
+const exemplarSlots = 16
+
+// Exemplar is a single measurement annotated with the context it was taken in,
+// such as the request it belongs to, linking aggregated metrics to concrete
+// occurrences.
+type Exemplar struct {
+	Value  int64
+	Time   time.Time
+	Labels map[string]string
+}
+
+// HistogramBuckets is the distribution of all values recorded by a histogram
+// over fixed buckets. Counts[i] is the number of values in the range
+// (Bounds[i-1], Bounds[i]], the last count covering the values above all bounds.
+type HistogramBuckets struct {
+	Bounds []int64
+	Counts []uint64
+	Count  uint64
+	Sum    int64
+}
+
+// ExemplarHistograms are histograms which on top of their sample maintain the
+// cumulative distribution of values over fixed buckets and retain the most
+// recent exemplars, allowing them to be exported as native histograms.
+type ExemplarHistogram interface {
+	Histogram
+	UpdateExemplar(int64, map[string]string)
+	Buckets() HistogramBuckets
+	Exemplars() []Exemplar
+}
+
+// GetOrRegisterExemplarHistogramLazy returns an existing ExemplarHistogram or
+// constructs and registers a new StandardExemplarHistogram.
+func GetOrRegisterExemplarHistogramLazy(name string, r Registry, bounds []int64, s func() Sample) ExemplarHistogram {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return r.GetOrRegister(name, func() ExemplarHistogram { return NewExemplarHistogram(bounds, s()) }).(ExemplarHistogram)
+}
+
+// NewExemplarHistogram constructs a new StandardExemplarHistogram from a Sample
+// and the upper bounds of its buckets in increasing order.
+func NewExemplarHistogram(bounds []int64, s Sample) ExemplarHistogram {
+	if !Enabled {
+		return NilExemplarHistogram{}
+	}
+	return &StandardExemplarHistogram{
+		Histogram: &StandardHistogram{sample: s},
+		buckets: HistogramBuckets{
+			Bounds: bounds,
+			Counts: make([]uint64, len(bounds)+1),
+		},
+	}
+}
+
+// NilExemplarHistogram is a no-op ExemplarHistogram.
+type NilExemplarHistogram struct {
+	NilHistogram
+}
+
+// UpdateExemplar is a no-op.
+func (NilExemplarHistogram) UpdateExemplar(int64, map[string]string) {}
+
+// Buckets is a no-op.
+func (NilExemplarHistogram) Buckets() HistogramBuckets { return HistogramBuckets{} }
+
+// Exemplars is a no-op.
+func (NilExemplarHistogram) Exemplars() []Exemplar { return nil }
+
+// StandardExemplarHistogram is the standard implementation of an
+// ExemplarHistogram, wrapping a sample based Histogram.
+type StandardExemplarHistogram struct {
+	Histogram
+
+	lock      sync.Mutex
+	buckets   HistogramBuckets
+	exemplars [exemplarSlots]Exemplar
+	next      int // Slot of the next exemplar
+	stored    int // Number of exemplars retained
+}
+
+// Clear clears the histogram, its buckets and exemplars.
+func (h *StandardExemplarHistogram) Clear() {
+	h.Histogram.Clear()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.buckets.Counts = make([]uint64, len(h.buckets.Bounds)+1)
+	h.buckets.Count, h.buckets.Sum = 0, 0
+	h.exemplars = [exemplarSlots]Exemplar{}
+	h.next, h.stored = 0, 0
+}
+
+// Update records a value.
+func (h *StandardExemplarHistogram) Update(v int64) {
+	h.Histogram.Update(v)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.observe(v)
+}
+
+// UpdateExemplar records a value and retains it as an exemplar along with the
+// given labels, evicting the oldest exemplar if needed.
+func (h *StandardExemplarHistogram) UpdateExemplar(v int64, labels map[string]string) {
+	h.Histogram.Update(v)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.observe(v)
+	h.exemplars[h.next] = Exemplar{Value: v, Time: time.Now(), Labels: labels}
+	h.next = (h.next + 1) % exemplarSlots
+	if h.stored < exemplarSlots {
+		h.stored++
+	}
+}
+
+// observe adds a value to the bucket it belongs to. The lock must be held.
+func (h *StandardExemplarHistogram) observe(v int64) {
+	bounds := h.buckets.Bounds
+	h.buckets.Counts[sort.Search(len(bounds), func(i int) bool { return v <= bounds[i] })]++
+	h.buckets.Count++
+	h.buckets.Sum += v
+}
+
+// Buckets returns a copy of the distribution of all recorded values.
+func (h *StandardExemplarHistogram) Buckets() HistogramBuckets {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	buckets := h.buckets
+	buckets.Counts = append([]uint64(nil), h.buckets.Counts...)
+	return buckets
+}
+
+// Exemplars returns the retained exemplars, oldest first.
+func (h *StandardExemplarHistogram) Exemplars() []Exemplar {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	exemplars := make([]Exemplar, 0, h.stored)
+	for i := h.stored; i > 0; i-- {
+		exemplars = append(exemplars, h.exemplars[(h.next-i+exemplarSlots)%exemplarSlots])
+	}
+	return exemplars
+}
@@ -0,0 +1,270 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package otlp implements an exporter pushing metrics to an OpenTelemetry
+// collector over the OTLP/HTTP protocol, using its JSON encoding.
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// scopeName is the instrumentation scope all metrics are reported under.
+	scopeName = "github.com/ethereum/go-ethereum/metrics"
+
+	// defaultServiceName is the service name reported if the resource attributes
+	// don't specify one.
+	defaultServiceName = "geth"
+
+	// pushTimeout is the maximum time allowed for a single push to the collector.
+	pushTimeout = 10 * time.Second
+)
+
+// quantiles are the quantiles exported for sample based histograms and timers.
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+
+type reporter struct {
+	reg       metrics.Registry
+	interval  time.Duration
+	endpoint  string
+	namespace string
+	resource  []keyValue
+	headers   map[string]string
+	client    *http.Client
+
+	start    time.Time // Start of the cumulative aggregations
+	lastPush time.Time // Exemplars taken before were already exported
+}
+
+// OTLP starts an OpenTelemetry exporter which will push the metrics of the given
+// registry to the collector endpoint at each d interval. The resource attributes
+// identify the node, with service.name defaulting to geth, and the headers are
+// added to every request, e.g. for authentication.
+func OTLP(r metrics.Registry, d time.Duration, endpoint string, namespace string, resource map[string]string, headers map[string]string) {
+	newReporter(r, d, endpoint, namespace, resource, headers).run()
+}
+
+func newReporter(r metrics.Registry, d time.Duration, endpoint string, namespace string, resource map[string]string, headers map[string]string) *reporter {
+	if _, ok := resource["service.name"]; !ok {
+		attrs := map[string]string{"service.name": defaultServiceName}
+		for k, v := range resource {
+			attrs[k] = v
+		}
+		resource = attrs
+	}
+	return &reporter{
+		reg:       r,
+		interval:  d,
+		endpoint:  endpoint,
+		namespace: namespace,
+		resource:  attributes(resource),
+		headers:   headers,
+		client:    &http.Client{Timeout: pushTimeout},
+		start:     time.Now(),
+	}
+}
+
+func (r *reporter) run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := r.send(); err != nil {
+			log.Warn("Unable to push metrics to OTLP collector", "err", err)
+		}
+	}
+}
+
+// send pushes a single export request with the current values of all metrics.
+func (r *reporter) send() error {
+	now := time.Now()
+	blob, err := json.Marshal(r.collect(now))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("collector returned %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	r.lastPush = now
+	return nil
+}
+
+// collect assembles an export request from the current values of all metrics.
+func (r *reporter) collect(now time.Time) *exportRequest {
+	var (
+		start = uint64(r.start.UnixNano())
+		stamp = uint64(now.UnixNano())
+		list  []metric
+	)
+	r.reg.Each(func(name string, i interface{}) {
+		name = r.namespace + name
+
+		switch m := i.(type) {
+		case metrics.Counter:
+			count := m.Count()
+			list = append(list, metric{Name: name, Sum: &sum{
+				DataPoints:             []numberDataPoint{{StartTimeUnixNano: start, TimeUnixNano: stamp, AsInt: &count}},
+				AggregationTemporality: temporalityCumulative,
+			}})
+
+		case metrics.Gauge:
+			value := m.Snapshot().Value()
+			list = append(list, metric{Name: name, Gauge: &gauge{
+				DataPoints: []numberDataPoint{{TimeUnixNano: stamp, AsInt: &value}},
+			}})
+
+		case metrics.GaugeFloat64:
+			value := m.Snapshot().Value()
+			list = append(list, metric{Name: name, Gauge: &gauge{
+				DataPoints: []numberDataPoint{{TimeUnixNano: stamp, AsDouble: &value}},
+			}})
+
+		case metrics.Meter:
+			count := m.Snapshot().Count()
+			list = append(list, metric{Name: name, Sum: &sum{
+				DataPoints:             []numberDataPoint{{StartTimeUnixNano: start, TimeUnixNano: stamp, AsInt: &count}},
+				AggregationTemporality: temporalityCumulative,
+				IsMonotonic:            true,
+			}})
+
+		case metrics.ExemplarHistogram:
+			list = append(list, metric{Name: name, Histogram: r.histogram(m, start, stamp)})
+
+		case metrics.Histogram:
+			ms := m.Snapshot()
+			if ms.Count() > 0 {
+				list = append(list, metric{Name: name, Summary: newSummary(start, stamp, uint64(ms.Count()), float64(ms.Sum()), ms.Percentiles(quantiles))})
+			}
+
+		case metrics.Timer:
+			ms := m.Snapshot()
+			if ms.Count() > 0 {
+				list = append(list, metric{Name: name, Unit: "ns", Summary: newSummary(start, stamp, uint64(ms.Count()), float64(ms.Sum()), ms.Percentiles(quantiles))})
+			}
+
+		case metrics.ResettingTimer:
+			ms := m.Snapshot()
+			values := ms.Values()
+			if len(values) == 0 {
+				return
+			}
+			var total int64
+			for _, v := range values {
+				total += v
+			}
+			ps := ms.Percentiles([]float64{50, 95, 99})
+			list = append(list, metric{Name: name, Unit: "ns", Summary: newSummary(start, stamp, uint64(len(values)), float64(total), []float64{float64(ps[0]), float64(ps[1]), float64(ps[2])}, 0.5, 0.95, 0.99)})
+		}
+	})
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return &exportRequest{ResourceMetrics: []resourceMetrics{{
+		Resource: resource{Attributes: r.resource},
+		ScopeMetrics: []scopeMetrics{{
+			Scope:   scope{Name: scopeName},
+			Metrics: list,
+		}},
+	}}}
+}
+
+// histogram converts an exemplar histogram into a native OTLP histogram along
+// with the exemplars taken since the last successful push.
+func (r *reporter) histogram(m metrics.ExemplarHistogram, start, stamp uint64) *histogram {
+	buckets := m.Buckets()
+
+	point := histogramDataPoint{
+		StartTimeUnixNano: start,
+		TimeUnixNano:      stamp,
+		Count:             buckets.Count,
+		Sum:               float64(buckets.Sum),
+		BucketCounts:      make([]string, len(buckets.Counts)),
+		ExplicitBounds:    make([]float64, len(buckets.Bounds)),
+	}
+	for i, count := range buckets.Counts {
+		point.BucketCounts[i] = fmt.Sprint(count)
+	}
+	for i, bound := range buckets.Bounds {
+		point.ExplicitBounds[i] = float64(bound)
+	}
+	for _, e := range m.Exemplars() {
+		if !e.Time.After(r.lastPush) {
+			continue
+		}
+		point.Exemplars = append(point.Exemplars, exemplar{
+			FilteredAttributes: attributes(e.Labels),
+			TimeUnixNano:       uint64(e.Time.UnixNano()),
+			AsInt:              e.Value,
+		})
+	}
+	return &histogram{
+		DataPoints:             []histogramDataPoint{point},
+		AggregationTemporality: temporalityCumulative,
+	}
+}
+
+// newSummary creates a summary from the values of the given quantiles, which
+// default to the standard exported ones if not specified.
+func newSummary(start, stamp, count uint64, total float64, values []float64, qs ...float64) *summary {
+	if len(qs) == 0 {
+		qs = quantiles
+	}
+	point := summaryDataPoint{
+		StartTimeUnixNano: start,
+		TimeUnixNano:      stamp,
+		Count:             count,
+		Sum:               total,
+		QuantileValues:    make([]quantileValue, len(qs)),
+	}
+	for i, q := range qs {
+		point.QuantileValues[i] = quantileValue{Quantile: q, Value: values[i]}
+	}
+	return &summary{DataPoints: []summaryDataPoint{point}}
+}
+
+// attributes converts a label set into OTLP attributes, sorted by key.
+func attributes(labels map[string]string) []keyValue {
+	attrs := make([]keyValue, 0, len(labels))
+	for k, v := range labels {
+		attrs = append(attrs, keyValue{Key: k, Value: anyValue{StringValue: v}})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
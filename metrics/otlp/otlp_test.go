@@ -0,0 +1,107 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package otlp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func init() {
+	metrics.Enabled = true
+}
+
+// Tests that metrics are pushed in the OTLP encoding, with histogram exemplars
+// exported only once.
+func TestExport(t *testing.T) {
+	var (
+		requests []*exportRequest
+		auth     []string
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(exportRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Errorf("invalid export request: %v", err)
+		}
+		requests = append(requests, req)
+		auth = append(auth, r.Header.Get("Authorization"))
+	}))
+	defer collector.Close()
+
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("test/counter", reg).Inc(3)
+	metrics.NewRegisteredGaugeFloat64("test/gauge", reg).Update(1.5)
+	hist := metrics.GetOrRegisterExemplarHistogramLazy("test/hist", reg, []int64{10, 100}, func() metrics.Sample {
+		return metrics.NewUniformSample(100)
+	})
+	hist.Update(5)
+	hist.UpdateExemplar(50, map[string]string{"id": "1"})
+	hist.UpdateExemplar(500, map[string]string{"id": "2"})
+
+	r := newReporter(reg, time.Minute, collector.URL, "geth.", map[string]string{"host": "test"}, map[string]string{"Authorization": "Bearer secret"})
+	if err := r.send(); err != nil {
+		t.Fatalf("failed to push metrics: %v", err)
+	}
+	if err := r.send(); err != nil {
+		t.Fatalf("failed to push metrics: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("push count mismatch: have %d, want 2", len(requests))
+	}
+	if auth[0] != "Bearer secret" {
+		t.Errorf("headers not forwarded: have %q", auth[0])
+	}
+	res := requests[0].ResourceMetrics[0]
+	want := []keyValue{{Key: "host", Value: anyValue{"test"}}, {Key: "service.name", Value: anyValue{"geth"}}}
+	if len(res.Resource.Attributes) != 2 || res.Resource.Attributes[0] != want[0] || res.Resource.Attributes[1] != want[1] {
+		t.Errorf("resource attributes mismatch: have %v, want %v", res.Resource.Attributes, want)
+	}
+	list := res.ScopeMetrics[0].Metrics
+	if len(list) != 3 {
+		t.Fatalf("metric count mismatch: have %d, want 3", len(list))
+	}
+	if m := list[0]; m.Name != "geth.test/counter" || m.Sum == nil || *m.Sum.DataPoints[0].AsInt != 3 {
+		t.Errorf("counter mismatch: %+v", m)
+	}
+	if m := list[1]; m.Name != "geth.test/gauge" || m.Gauge == nil || *m.Gauge.DataPoints[0].AsDouble != 1.5 {
+		t.Errorf("gauge mismatch: %+v", m)
+	}
+	m := list[2]
+	if m.Name != "geth.test/hist" || m.Histogram == nil {
+		t.Fatalf("histogram mismatch: %+v", m)
+	}
+	point := m.Histogram.DataPoints[0]
+	if point.Count != 3 || point.Sum != 555 {
+		t.Errorf("histogram totals mismatch: have count %d sum %v, want 3 and 555", point.Count, point.Sum)
+	}
+	if len(point.BucketCounts) != 3 || point.BucketCounts[0] != "1" || point.BucketCounts[1] != "1" || point.BucketCounts[2] != "1" {
+		t.Errorf("histogram buckets mismatch: %v", point.BucketCounts)
+	}
+	if len(point.Exemplars) != 2 || point.Exemplars[0].AsInt != 50 || point.Exemplars[1].FilteredAttributes[0].Value.StringValue != "2" {
+		t.Errorf("histogram exemplars mismatch: %+v", point.Exemplars)
+	}
+	// Exemplars should only be pushed once
+	point = requests[1].ResourceMetrics[0].ScopeMetrics[0].Metrics[2].Histogram.DataPoints[0]
+	if point.Count != 3 || len(point.Exemplars) != 0 {
+		t.Errorf("second push mismatch: have count %d with %d exemplars, want 3 and 0", point.Count, len(point.Exemplars))
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package otlp
+
+// This file contains the subset of the OTLP metrics data model needed by the
+// exporter, following the JSON encoding of the protocol: 64 bit integers are
+// encoded as decimal strings and enums as their numeric values.
+
+// temporalityCumulative is the AGGREGATION_TEMPORALITY_CUMULATIVE enum value.
+const temporalityCumulative = 2
+
+type exportRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type scopeMetrics struct {
+	Scope   scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type metric struct {
+	Name      string     `json:"name"`
+	Unit      string     `json:"unit,omitempty"`
+	Gauge     *gauge     `json:"gauge,omitempty"`
+	Sum       *sum       `json:"sum,omitempty"`
+	Histogram *histogram `json:"histogram,omitempty"`
+	Summary   *summary   `json:"summary,omitempty"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type sum struct {
+	DataPoints             []numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type numberDataPoint struct {
+	StartTimeUnixNano uint64   `json:"startTimeUnixNano,omitempty,string"`
+	TimeUnixNano      uint64   `json:"timeUnixNano,string"`
+	AsInt             *int64   `json:"asInt,omitempty,string"`
+	AsDouble          *float64 `json:"asDouble,omitempty"`
+}
+
+type histogram struct {
+	DataPoints             []histogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type histogramDataPoint struct {
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string"`
+	TimeUnixNano      uint64     `json:"timeUnixNano,string"`
+	Count             uint64     `json:"count,string"`
+	Sum               float64    `json:"sum"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+	Exemplars         []exemplar `json:"exemplars,omitempty"`
+}
+
+type exemplar struct {
+	FilteredAttributes []keyValue `json:"filteredAttributes,omitempty"`
+	TimeUnixNano       uint64     `json:"timeUnixNano,string"`
+	AsInt              int64      `json:"asInt,string"`
+}
+
+type summary struct {
+	DataPoints []summaryDataPoint `json:"dataPoints"`
+}
+
+type summaryDataPoint struct {
+	StartTimeUnixNano uint64          `json:"startTimeUnixNano,string"`
+	TimeUnixNano      uint64          `json:"timeUnixNano,string"`
+	Count             uint64          `json:"count,string"`
+	Sum               float64         `json:"sum"`
+	QuantileValues    []quantileValue `json:"quantileValues"`
+}
+
+type quantileValue struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
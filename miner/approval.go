@@ -0,0 +1,109 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// defaultSealApprovalTimeout is the time allowed for the approval endpoint to
+// answer if none is configured.
+const defaultSealApprovalTimeout = 2 * time.Second
+
+// sealApprovalRequest is the JSON payload submitted to the approval endpoint for
+// every block the node is about to seal.
+type sealApprovalRequest struct {
+	SealHash     common.Hash      `json:"sealHash"`
+	Header       *types.Header    `json:"header"`
+	Transactions []common.Hash    `json:"transactions"`
+	Senders      []common.Address `json:"senders"`
+}
+
+// sealApprovalResponse is the answer of the approval endpoint. The annotation is
+// logged alongside the decision, e.g. to reference a compliance record.
+type sealApprovalResponse struct {
+	Approve    bool   `json:"approve"`
+	Annotation string `json:"annotation,omitempty"`
+}
+
+// sealApprover submits blocks to an external HTTP endpoint for approval before
+// they are sealed.
+type sealApprover struct {
+	url    string
+	allow  bool // Whether to seal if the endpoint fails to answer
+	client *http.Client
+}
+
+// newSealApprover creates an approver for the configured endpoint, or returns
+// nil if sealing approval is disabled.
+func newSealApprover(config *Config) *sealApprover {
+	if config.SealApproval == "" {
+		return nil
+	}
+	timeout := config.SealApprovalTimeout
+	if timeout <= 0 {
+		timeout = defaultSealApprovalTimeout
+	}
+	return &sealApprover{
+		url:    config.SealApproval,
+		allow:  config.SealApprovalAllow,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// approve submits the block to the approval endpoint and returns whether it may
+// be sealed, along with the annotation of the endpoint. If the endpoint fails to
+// answer in time or with a valid response, the default policy is applied and the
+// failure returned.
+func (a *sealApprover) approve(block *types.Block, sealHash common.Hash, signer types.Signer) (bool, string, error) {
+	txs := block.Transactions()
+	req := &sealApprovalRequest{
+		SealHash:     sealHash,
+		Header:       block.Header(),
+		Transactions: make([]common.Hash, len(txs)),
+		Senders:      make([]common.Address, len(txs)),
+	}
+	for i, tx := range txs {
+		req.Transactions[i] = tx.Hash()
+		req.Senders[i], _ = types.Sender(signer, tx)
+	}
+	blob, err := json.Marshal(req)
+	if err != nil {
+		return a.allow, "", err
+	}
+	res, err := a.client.Post(a.url, "application/json", bytes.NewReader(blob))
+	if err != nil {
+		return a.allow, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return a.allow, "", fmt.Errorf("unexpected status: %s", res.Status)
+	}
+	var resp sealApprovalResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return a.allow, "", fmt.Errorf("invalid response: %v", err)
+	}
+	return resp.Approve, resp.Annotation, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func TestSealApproval(t *testing.T) {
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		tx     = types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: 0, To: &testUserAddress, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: big.NewInt(params.InitialBaseFee)})
+		block  = types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{tx}, nil, nil, trie.NewStackTrie(nil))
+		sealed = common.Hash{0x01}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req sealApprovalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		switch {
+		case req.SealHash != sealed:
+			t.Errorf("seal hash mismatch: have %x, want %x", req.SealHash, sealed)
+		case len(req.Senders) != 1 || req.Senders[0] != testBankAddress:
+			t.Errorf("senders mismatch: have %x, want %x", req.Senders, testBankAddress)
+		}
+		switch req.Header.Number.Uint64() {
+		case 1:
+			json.NewEncoder(w).Encode(&sealApprovalResponse{Approve: true, Annotation: "ok"})
+		case 2:
+			json.NewEncoder(w).Encode(&sealApprovalResponse{Approve: false, Annotation: "flagged"})
+		case 3:
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	for _, allow := range []bool{false, true} {
+		approver := newSealApprover(&Config{SealApproval: server.URL, SealApprovalTimeout: 100 * time.Millisecond, SealApprovalAllow: allow})
+
+		if ok, note, err := approver.approve(block, sealed, signer); !ok || note != "ok" || err != nil {
+			t.Errorf("allow %v: approved block mismatch: approved %v, annotation %q, err %v", allow, ok, note, err)
+		}
+		for _, number := range []int64{2, 3, 4} {
+			header := block.Header()
+			header.Number = big.NewInt(number)
+
+			ok, _, err := approver.approve(block.WithSeal(header), sealed, signer)
+			if number == 2 && (ok || err != nil) {
+				t.Errorf("allow %v: denied block approved: approved %v, err %v", allow, ok, err)
+			}
+			if number > 2 && (ok != allow || err == nil) {
+				t.Errorf("allow %v, block %d: failure policy not applied: approved %v, err %v", allow, number, ok, err)
+			}
+		}
+	}
+	if newSealApprover(&Config{}) != nil {
+		t.Error("approver created without endpoint")
+	}
+}
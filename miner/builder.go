@@ -0,0 +1,185 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// localBuilderName is the name of the block builder of the node itself.
+const localBuilderName = "local"
+
+// BuildPayloadArgs are the parameters of a payload requested by the consensus
+// client.
+type BuildPayloadArgs struct {
+	Parent       common.Hash    // Hash of the block to build on top of
+	Timestamp    uint64         // Timestamp of the block
+	FeeRecipient common.Address // Address receiving the value of the payload
+	Random       common.Hash    // Randomness of the beacon chain
+	BeaconRoot   *common.Hash   // Beacon root of the parent block, required since Prague
+}
+
+// BuiltPayload is a block built for the consensus client, along with the value
+// it pays to the fee recipient and, since Prague, the requests of the block.
+type BuiltPayload struct {
+	Block    *types.Block
+	Requests types.Requests // Requests committed to by the block header, nil before Prague
+	Value    *big.Int       // Value of the block to the fee recipient, in wei (measured by the miner for external builders)
+}
+
+// PayloadBuilder builds execution payloads. Besides the local builder of the
+// node, external builders can be registered with the miner to compete for
+// every payload, the one of the highest value being proposed. The payloads of
+// external builders are executed by the node, their value being the balance
+// change of the fee recipient rather than the one reported by the builder.
+type PayloadBuilder interface {
+	// Name identifies the builder in logs.
+	Name() string
+
+	// BuildPayload starts building a block with the given parameters. The block
+	// is delivered on the returned channel, or nil if building it failed. Blocks
+	// not delivered by the time the payload is retrieved are ignored.
+	BuildPayload(args *BuildPayloadArgs) (<-chan *BuiltPayload, error)
+}
+
+// PayloadValidator is a hook running additional checks on the payloads of
+// external builders once they were executed, e.g. filtering their content.
+// Payloads failing validation are discarded.
+type PayloadValidator func(args *BuildPayloadArgs, payload *BuiltPayload) error
+
+// localBuilder is the block builder of the node, backed by the worker.
+type localBuilder struct {
+	worker *worker
+}
+
+func (b *localBuilder) Name() string { return localBuilderName }
+
+func (b *localBuilder) BuildPayload(args *BuildPayloadArgs) (<-chan *BuiltPayload, error) {
+	resCh, _, err := b.worker.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, args.BeaconRoot, false)
+	if err != nil {
+		return nil, err
+	}
+	return resCh, nil
+}
+
+// RegisterPayloadBuilder registers an external builder competing with the local
+// one for the payloads requested by the consensus client.
+func (miner *Miner) RegisterPayloadBuilder(builder PayloadBuilder) error {
+	miner.buildersLock.Lock()
+	defer miner.buildersLock.Unlock()
+
+	if builder.Name() == localBuilderName {
+		return fmt.Errorf("builder name %q is reserved", localBuilderName)
+	}
+	for _, b := range miner.builders {
+		if b.Name() == builder.Name() {
+			return fmt.Errorf("builder %q already registered", builder.Name())
+		}
+	}
+	miner.builders = append(miner.builders, builder)
+	return nil
+}
+
+// SetPayloadValidator sets the hook validating the payloads of external builders.
+func (miner *Miner) SetPayloadValidator(validator PayloadValidator) {
+	miner.buildersLock.Lock()
+	defer miner.buildersLock.Unlock()
+
+	miner.validator = validator
+}
+
+// PayloadBuilders returns the builders competing for payloads, the local one
+// first.
+func (miner *Miner) PayloadBuilders() []PayloadBuilder {
+	miner.buildersLock.RLock()
+	defer miner.buildersLock.RUnlock()
+
+	builders := []PayloadBuilder{&localBuilder{miner.worker}}
+	return append(builders, miner.builders...)
+}
+
+// ValidatePayload checks that an external payload matches the requested
+// parameters and executes it on top of its parent, replacing the value reported
+// by the builder with the balance change of the fee recipient. The validation
+// hook is run last, if set.
+func (miner *Miner) ValidatePayload(args *BuildPayloadArgs, payload *BuiltPayload) error {
+	if payload.Block == nil {
+		return errors.New("missing block")
+	}
+	block := payload.Block
+	if block.ParentHash() != args.Parent {
+		return fmt.Errorf("wrong parent %x, want %x", block.ParentHash(), args.Parent)
+	}
+	if block.Time() != args.Timestamp {
+		return fmt.Errorf("wrong timestamp %d, want %d", block.Time(), args.Timestamp)
+	}
+	if block.MixDigest() != args.Random {
+		return fmt.Errorf("wrong random %x, want %x", block.MixDigest(), args.Random)
+	}
+	if root := block.Header().ParentBeaconRoot; args.BeaconRoot != nil && (root == nil || *root != *args.BeaconRoot) {
+		return fmt.Errorf("wrong parent beacon root %v, want %x", root, *args.BeaconRoot)
+	}
+	// The requests are not part of the block, the header commits to them and the
+	// execution below checks the commitment against the requests of the block.
+	if hash := block.Header().RequestsHash; hash != nil && *hash != payload.Requests.Hash() {
+		return fmt.Errorf("requests mismatch: have hash %x, want %x", payload.Requests.Hash(), *hash)
+	}
+	// Execute the block, enforcing all the consensus rules
+	chain := miner.eth.BlockChain()
+	parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if err := chain.Engine().VerifyHeader(chain, block.Header(), false); err != nil {
+		return err
+	}
+	if err := chain.Validator().ValidateBody(block); err != nil {
+		return err
+	}
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return err
+	}
+	before := new(big.Int).Set(statedb.GetBalance(args.FeeRecipient))
+	receipts, _, usedGas, err := chain.Processor().Process(block, statedb, *chain.GetVMConfig())
+	if err != nil {
+		return err
+	}
+	if err := chain.Validator().ValidateState(block, statedb, receipts, usedGas); err != nil {
+		return err
+	}
+	value := new(big.Int).Sub(statedb.GetBalance(args.FeeRecipient), before)
+	if value.Sign() < 0 {
+		return fmt.Errorf("payload takes %v wei from the fee recipient", new(big.Int).Neg(value))
+	}
+	payload.Value = value
+
+	miner.buildersLock.RLock()
+	validator := miner.validator
+	miner.buildersLock.RUnlock()
+
+	if validator != nil {
+		return validator(args, payload)
+	}
+	return nil
+}
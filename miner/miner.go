@@ -53,6 +53,12 @@ type Config struct {
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
+
+	SealApproval        string        `toml:",omitempty"` // HTTP URL to request approval from before sealing a block
+	SealApprovalTimeout time.Duration `toml:",omitempty"` // Time to wait for the approval endpoint to answer
+	SealApprovalAllow   bool          `toml:",omitempty"` // Seal blocks if the approval endpoint fails to answer
+
+	EtherbaseSchedule *EtherbaseSchedule `toml:",omitempty"` // Rotation of the fee recipient between several addresses
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -66,6 +72,10 @@ type Miner struct {
 	startCh  chan common.Address
 	stopCh   chan struct{}
 
+	builders     []PayloadBuilder // External payload builders
+	validator    PayloadValidator // Hook validating external payloads
+	buildersLock sync.RWMutex
+
 	wg sync.WaitGroup
 }
 
@@ -211,6 +221,32 @@ func (miner *Miner) SetEtherbase(addr common.Address) {
 	miner.worker.setEtherbase(addr)
 }
 
+// SetEtherbaseSchedule sets the schedule rotating the fee recipient of the mined
+// blocks between several addresses, or reverts to the etherbase if nil.
+func (miner *Miner) SetEtherbaseSchedule(schedule *EtherbaseSchedule) error {
+	if schedule != nil {
+		if err := schedule.Validate(); err != nil {
+			return err
+		}
+	}
+	miner.worker.setEtherbaseSchedule(schedule)
+	return nil
+}
+
+// ScheduledEtherbase returns the fee recipient of the next block according to
+// the etherbase schedule, and false if there is no schedule.
+func (miner *Miner) ScheduledEtherbase() (common.Address, bool) {
+	miner.worker.mu.RLock()
+	schedule := miner.worker.schedule
+	miner.worker.mu.RUnlock()
+
+	if schedule == nil {
+		return common.Address{}, false
+	}
+	head := miner.eth.BlockChain().CurrentBlock()
+	return schedule.recipient(head.NumberU64()+1, uint64(time.Now().Unix())), true
+}
+
 // SetGasCeil sets the gaslimit to strive for when mining blocks post 1559.
 // For pre-1559 blocks, it sets the ceiling.
 func (miner *Miner) SetGasCeil(ceil uint64) {
@@ -246,20 +282,44 @@ func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscript
 // The difference is that if the execution fails, the returned result is nil
 // and the concrete error is dropped silently.
 func (miner *Miner) GetSealingBlockAsync(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (chan *types.Block, error) {
-	resCh, _, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, noTxs)
+	resCh, _, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, nil, noTxs)
 	if err != nil {
 		return nil, err
 	}
-	return resCh, nil
+	blockCh := make(chan *types.Block, 1)
+	go func() {
+		if payload := <-resCh; payload != nil {
+			blockCh <- payload.Block
+		} else {
+			blockCh <- nil
+		}
+	}()
+	return blockCh, nil
 }
 
 // GetSealingBlockSync creates a sealing block according to the given parameters.
 // If the generation is failed or the underlying work is already closed, an error
 // will be returned.
 func (miner *Miner) GetSealingBlockSync(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (*types.Block, error) {
-	resCh, errCh, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, noTxs)
+	payload, err := miner.GetSealingPayloadSync(&BuildPayloadArgs{Parent: parent, Timestamp: timestamp, FeeRecipient: coinbase, Random: random}, noTxs)
+	if payload == nil {
+		return nil, err
+	}
+	return payload.Block, err
+}
+
+// GetSealingPayloadSync creates a sealing block according to the given payload
+// parameters, returning it along with the requests of the block. If the
+// generation is failed or the underlying work is already closed, an error will
+// be returned.
+func (miner *Miner) GetSealingPayloadSync(args *BuildPayloadArgs, noTxs bool) (*BuiltPayload, error) {
+	resCh, errCh, err := miner.worker.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, args.BeaconRoot, noTxs)
 	if err != nil {
 		return nil, err
 	}
-	return <-resCh, <-errCh
+	payload, err := <-resCh, <-errCh
+	if payload == nil {
+		return nil, err
+	}
+	return payload, err
 }
@@ -0,0 +1,100 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxEtherbaseWeight is the maximum sum of the recipient weights of a schedule.
+const maxEtherbaseWeight = 1 << 32
+
+// EtherbaseRecipient is a fee recipient of an etherbase schedule.
+type EtherbaseRecipient struct {
+	Address common.Address `json:"address"`
+	Weight  uint64         `json:"weight,omitempty"` // Number of consecutive windows paid to the address, 1 if unset
+}
+
+// EtherbaseSchedule rotates the fee recipient of the mined blocks between
+// several addresses. Blocks are grouped into windows of a fixed number of blocks
+// or a fixed duration, and the windows are assigned to the recipients in turn,
+// each receiving as many consecutive windows as its weight. The assignment only
+// depends on the block number or timestamp, so it survives restarts.
+//
+// The schedule only applies to blocks sealed by the local miner. Post-merge, the
+// fee recipient of built payloads is chosen by the consensus client through the
+// engine API payload attributes, and the schedule is ignored.
+type EtherbaseSchedule struct {
+	Recipients []EtherbaseRecipient `json:"recipients"`
+	Blocks     uint64               `json:"blocks,omitempty"` // Length of a window in blocks
+	Period     uint64               `json:"period,omitempty"` // Length of a window in seconds
+}
+
+// Validate checks that the schedule is well formed.
+func (s *EtherbaseSchedule) Validate() error {
+	if len(s.Recipients) == 0 {
+		return errors.New("no recipients")
+	}
+	var total uint64
+	for _, r := range s.Recipients {
+		if r.Address == (common.Address{}) {
+			return errors.New("zero recipient address")
+		}
+		if r.Weight > maxEtherbaseWeight || total+weight(r) > maxEtherbaseWeight {
+			return fmt.Errorf("total weight exceeds %d", uint64(maxEtherbaseWeight))
+		}
+		total += weight(r)
+	}
+	if (s.Blocks == 0) == (s.Period == 0) {
+		return errors.New("exactly one of blocks and period must be set")
+	}
+	return nil
+}
+
+// recipient returns the fee recipient of the block with the given number and
+// timestamp.
+func (s *EtherbaseSchedule) recipient(number, time uint64) common.Address {
+	var window uint64
+	if s.Blocks > 0 {
+		window = number / s.Blocks
+	} else {
+		window = time / s.Period
+	}
+	var total uint64
+	for _, r := range s.Recipients {
+		total += weight(r)
+	}
+	slot := window % total
+	for _, r := range s.Recipients {
+		if slot < weight(r) {
+			return r.Address
+		}
+		slot -= weight(r)
+	}
+	return common.Address{} // Unreachable
+}
+
+// weight returns the number of consecutive windows paid to a recipient.
+func weight(r EtherbaseRecipient) uint64 {
+	if r.Weight == 0 {
+		return 1
+	}
+	return r.Weight
+}
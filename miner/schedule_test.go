@@ -0,0 +1,117 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+func TestEtherbaseScheduleBlocks(t *testing.T) {
+	var (
+		a = common.Address{0xa}
+		b = common.Address{0xb}
+	)
+	schedule := &EtherbaseSchedule{
+		Recipients: []EtherbaseRecipient{{Address: a, Weight: 2}, {Address: b}},
+		Blocks:     10,
+	}
+	if err := schedule.Validate(); err != nil {
+		t.Fatalf("failed to validate schedule: %v", err)
+	}
+	tests := []struct {
+		number uint64
+		want   common.Address
+	}{
+		{0, a}, {9, a}, {10, a}, {19, a}, {20, b}, {29, b}, {30, a}, {59, b}, {60, a},
+	}
+	for _, tt := range tests {
+		if have := schedule.recipient(tt.number, 0); have != tt.want {
+			t.Errorf("block %d: recipient mismatch: have %x, want %x", tt.number, have, tt.want)
+		}
+	}
+}
+
+func TestEtherbaseSchedulePeriod(t *testing.T) {
+	var (
+		a = common.Address{0xa}
+		b = common.Address{0xb}
+	)
+	schedule := &EtherbaseSchedule{
+		Recipients: []EtherbaseRecipient{{Address: a}, {Address: b}},
+		Period:     3600,
+	}
+	if err := schedule.Validate(); err != nil {
+		t.Fatalf("failed to validate schedule: %v", err)
+	}
+	tests := []struct {
+		time uint64
+		want common.Address
+	}{
+		{0, a}, {3599, a}, {3600, b}, {7199, b}, {7200, a},
+	}
+	for _, tt := range tests {
+		if have := schedule.recipient(1, tt.time); have != tt.want {
+			t.Errorf("time %d: recipient mismatch: have %x, want %x", tt.time, have, tt.want)
+		}
+	}
+}
+
+func TestEtherbaseScheduleValidate(t *testing.T) {
+	recipients := []EtherbaseRecipient{{Address: common.Address{0xa}}}
+	tests := []*EtherbaseSchedule{
+		{Blocks: 1},
+		{Recipients: []EtherbaseRecipient{{}}, Blocks: 1},
+		{Recipients: recipients},
+		{Recipients: recipients, Blocks: 1, Period: 1},
+		{Recipients: []EtherbaseRecipient{{Address: common.Address{0xa}, Weight: math.MaxUint64}, {Address: common.Address{0xb}, Weight: 1}}, Blocks: 1},
+		{Recipients: []EtherbaseRecipient{{Address: common.Address{0xa}, Weight: maxEtherbaseWeight}, {Address: common.Address{0xb}}}, Blocks: 1},
+	}
+	for i, schedule := range tests {
+		if err := schedule.Validate(); err == nil {
+			t.Errorf("test %d: invalid schedule accepted", i)
+		}
+	}
+}
+
+// Tests that a worker with an etherbase schedule mines even without an etherbase.
+func TestEtherbaseScheduleWithoutEtherbase(t *testing.T) {
+	w, _ := newTestWorker(t, ethashChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	w.setEtherbase(common.Address{})
+	w.setEtherbaseSchedule(&EtherbaseSchedule{Recipients: []EtherbaseRecipient{{Address: testUserAddress}}, Blocks: 1})
+
+	sub := w.mux.Subscribe(core.NewMinedBlockEvent{})
+	defer sub.Unsubscribe()
+	w.start()
+
+	select {
+	case ev := <-sub.Chan():
+		if coinbase := ev.Data.(core.NewMinedBlockEvent).Block.Coinbase(); coinbase != testUserAddress {
+			t.Fatalf("coinbase mismatch: have %x, want %x", coinbase, testUserAddress)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no block mined")
+	}
+}
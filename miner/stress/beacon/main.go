@@ -199,7 +199,7 @@ func (n *ethNode) insertBlockAndSetHead(parent *types.Header, ed beacon.Executab
 	if err := n.insertBlock(ed); err != nil {
 		return err
 	}
-	block, err := beacon.ExecutableDataToBlock(ed)
+	block, err := beacon.ExecutableDataToBlock(ed, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -363,7 +363,7 @@ func (mgr *nodeManager) run() {
 				log.Error("Failed to assemble the block", "err", err)
 				continue
 			}
-			block, _ := beacon.ExecutableDataToBlock(*ed)
+			block, _ := beacon.ExecutableDataToBlock(*ed, nil, nil)
 
 			nodes := mgr.getNodes(eth2MiningNode)
 			nodes = append(nodes, mgr.getNodes(eth2NormalNode)...)
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -170,7 +171,7 @@ type newWorkReq struct {
 // getWorkReq represents a request for getting a new sealing work with provided parameters.
 type getWorkReq struct {
 	params *generateParams
-	result chan *types.Block // non-blocking channel
+	result chan *BuiltPayload // non-blocking channel
 	err    chan error
 }
 
@@ -188,6 +189,7 @@ type worker struct {
 	engine      consensus.Engine
 	eth         Backend
 	chain       *core.BlockChain
+	approver    *sealApprover // External approval of blocks before sealing, nil if disabled
 
 	// Feeds
 	pendingLogsFeed event.Feed
@@ -218,8 +220,9 @@ type worker struct {
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
+	mu       sync.RWMutex // The lock used to protect the coinbase, schedule and extra fields
 	coinbase common.Address
+	schedule *EtherbaseSchedule // Rotation of the fee recipient overriding the coinbase, nil if disabled
 	extra    []byte
 
 	pendingMu    sync.RWMutex
@@ -259,6 +262,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		eth:                eth,
 		mux:                mux,
 		chain:              eth.BlockChain(),
+		approver:           newSealApprover(config),
 		isLocalBlock:       isLocalBlock,
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
@@ -275,6 +279,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		startCh:            make(chan struct{}, 1),
 		resubmitIntervalCh: make(chan time.Duration),
 		resubmitAdjustCh:   make(chan *intervalAdjust, resubmitAdjustChanSize),
+		schedule:           config.EtherbaseSchedule,
 	}
 	// Subscribe NewTxsEvent for tx pool
 	worker.txsSub = eth.TxPool().SubscribeNewTxsEvent(worker.txsCh)
@@ -309,6 +314,25 @@ func (w *worker) setEtherbase(addr common.Address) {
 	w.coinbase = addr
 }
 
+// setEtherbaseSchedule sets the schedule rotating the fee recipient of the mined
+// blocks, or disables the rotation if nil.
+func (w *worker) setEtherbaseSchedule(schedule *EtherbaseSchedule) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.schedule = schedule
+}
+
+// etherbase returns the fee recipient of the block with the given number and
+// timestamp, which is the etherbase unless a schedule is set.
+func (w *worker) etherbase(number, time uint64) common.Address {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.schedule != nil {
+		return w.schedule.recipient(number, time)
+	}
+	return w.coinbase
+}
+
 func (w *worker) setGasCeil(ceil uint64) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
@@ -534,13 +558,13 @@ func (w *worker) mainLoop() {
 			w.commitWork(req.interrupt, req.noempty, req.timestamp)
 
 		case req := <-w.getWorkCh:
-			block, err := w.generateWork(req.params)
+			payload, err := w.generateWork(req.params)
 			if err != nil {
 				req.err <- err
 				req.result <- nil
 			} else {
 				req.err <- nil
-				req.result <- block
+				req.result <- payload
 			}
 		case ev := <-w.chainSideCh:
 			// Short circuit for duplicate side blocks
@@ -661,6 +685,18 @@ func (w *worker) taskLoop() {
 			if w.skipSealHook != nil && w.skipSealHook(task) {
 				continue
 			}
+			if w.approver != nil {
+				signer := types.MakeSigner(w.chainConfig, task.block.Number())
+				approved, annotation, err := w.approver.approve(task.block, sealHash, signer)
+				if err != nil {
+					log.Warn("Block seal approval failed", "number", task.block.Number(), "sealhash", sealHash, "approved", approved, "err", err)
+				}
+				if !approved {
+					log.Warn("Block sealing denied", "number", task.block.Number(), "sealhash", sealHash, "annotation", annotation)
+					continue
+				}
+				log.Debug("Block sealing approved", "number", task.block.Number(), "sealhash", sealHash, "annotation", annotation)
+			}
 			w.pendingMu.Lock()
 			w.pendingTasks[sealHash] = task
 			w.pendingMu.Unlock()
@@ -957,6 +993,7 @@ type generateParams struct {
 	parentHash common.Hash    // Parent block hash, empty means the latest chain head
 	coinbase   common.Address // The fee recipient address for including transaction
 	random     common.Hash    // The randomness generated by beacon chain, empty before the merge
+	beaconRoot *common.Hash   // The beacon root of the parent block, nil if not provided
 	noUncle    bool           // Flag whether the uncle block inclusion is allowed
 	noExtra    bool           // Flag whether the extra field assignment is allowed
 	noTxs      bool           // Flag whether an empty block without any transaction is expected
@@ -1010,6 +1047,17 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.config.GasCeil)
 		}
 	}
+	// Set the Cancun fields preceding the requests hash since Prague. Neither
+	// withdrawals nor blobs are supported, so these hold the empty values.
+	if w.chainConfig.IsPrague(header.Number) {
+		withdrawalsHash := types.EmptyRootHash
+		header.WithdrawalsHash = &withdrawalsHash
+		header.BlobGasUsed, header.ExcessBlobGas = new(uint64), new(uint64)
+		header.ParentBeaconRoot = new(common.Hash)
+		if genParams.beaconRoot != nil {
+			*header.ParentBeaconRoot = *genParams.beaconRoot
+		}
+	}
 	// Run the consensus preparation with the default or customized consensus engine.
 	if err := w.engine.Prepare(w.chain, header); err != nil {
 		log.Error("Failed to prepare header for sealing", "err", err)
@@ -1074,7 +1122,7 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment) error {
 }
 
 // generateWork generates a sealing block based on the given parameters.
-func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
+func (w *worker) generateWork(params *generateParams) (*BuiltPayload, error) {
 	work, err := w.prepareWork(params)
 	if err != nil {
 		return nil, err
@@ -1084,7 +1132,11 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
 	if !params.noTxs {
 		w.fillTransactions(nil, work)
 	}
-	return w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts)
+	block, requests, err := w.finalizeAndAssemble(work)
+	if err != nil {
+		return nil, err
+	}
+	return &BuiltPayload{Block: block, Requests: requests, Value: blockFees(block, work.receipts)}, nil
 }
 
 // commitWork generates several new sealing tasks based on the parent block
@@ -1095,11 +1147,12 @@ func (w *worker) commitWork(interrupt *int32, noempty bool, timestamp int64) {
 	// Set the coinbase if the worker is running or it's required
 	var coinbase common.Address
 	if w.isRunning() {
-		if w.coinbase == (common.Address{}) {
+		// Use the preset or scheduled address as the fee recipient
+		coinbase = w.etherbase(w.chain.CurrentBlock().NumberU64()+1, uint64(timestamp))
+		if coinbase == (common.Address{}) {
 			log.Error("Refusing to mine without etherbase")
 			return
 		}
-		coinbase = w.coinbase // Use the preset address as the fee recipient
 	}
 	work, err := w.prepareWork(&generateParams{
 		timestamp: uint64(timestamp),
@@ -1142,7 +1195,7 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 		// Create a local environment copy, avoid the data race with snapshot state.
 		// https://github.com/ethereum/go-ethereum/issues/24299
 		env := env.copy()
-		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts)
+		block, _, err := w.finalizeAndAssemble(env)
 		if err != nil {
 			return err
 		}
@@ -1167,12 +1220,36 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 	return nil
 }
 
+// finalizeAndAssemble collects the requests of the block since Prague, then runs
+// the post-transaction state modifications of the consensus engine and assembles
+// the final block. The requests are not part of the block, they are returned for
+// the consensus client alongside it.
+func (w *worker) finalizeAndAssemble(env *environment) (*types.Block, types.Requests, error) {
+	var requests types.Requests
+	if w.chainConfig.IsPrague(env.header.Number) {
+		blockContext := core.NewEVMBlockContext(env.header, w.chain, &env.coinbase)
+		vmenv := vm.NewEVM(blockContext, vm.TxContext{}, env.state, w.chainConfig, *w.chain.GetVMConfig())
+
+		var err error
+		if requests, err = core.ProcessRequests(w.chainConfig, vmenv, env.state, env.receipts); err != nil {
+			return nil, nil, err
+		}
+		hash := requests.Hash()
+		env.header.RequestsHash = &hash
+	}
+	block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, requests, nil
+}
+
 // getSealingBlock generates the sealing block based on the given parameters.
 // The generation result will be passed back via the given channel no matter
 // the generation itself succeeds or not.
-func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (chan *types.Block, chan error, error) {
+func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, beaconRoot *common.Hash, noTxs bool) (chan *BuiltPayload, chan error, error) {
 	var (
-		resCh = make(chan *types.Block, 1)
+		resCh = make(chan *BuiltPayload, 1)
 		errCh = make(chan error, 1)
 	)
 	req := &getWorkReq{
@@ -1182,6 +1259,7 @@ func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase
 			parentHash: parent,
 			coinbase:   coinbase,
 			random:     random,
+			beaconRoot: beaconRoot,
 			noUncle:    true,
 			noExtra:    true,
 			noTxs:      noTxs,
@@ -1224,10 +1302,15 @@ func (w *worker) postSideBlock(event core.ChainSideEvent) {
 
 // totalFees computes total consumed miner fees in ETH. Block transactions and receipts have to have the same order.
 func totalFees(block *types.Block, receipts []*types.Receipt) *big.Float {
+	return new(big.Float).Quo(new(big.Float).SetInt(blockFees(block, receipts)), new(big.Float).SetInt(big.NewInt(params.Ether)))
+}
+
+// blockFees computes total consumed miner fees in wei. Block transactions and receipts have to have the same order.
+func blockFees(block *types.Block, receipts []*types.Receipt) *big.Int {
 	feesWei := new(big.Int)
 	for i, tx := range block.Transactions() {
 		minerFee, _ := tx.EffectiveGasTip(block.BaseFee())
 		feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), minerFee))
 	}
-	return new(big.Float).Quo(new(big.Float).SetInt(feesWei), new(big.Float).SetInt(big.NewInt(params.Ether)))
+	return feesWei
 }
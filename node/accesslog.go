@@ -0,0 +1,188 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// accessLogBackups is the number of rotated access log files to keep.
+const accessLogBackups = 10
+
+// accessLogEntry is a single line of the JSON-RPC access log.
+type accessLogEntry struct {
+	Time      time.Time       `json:"time"`
+	Transport string          `json:"transport"`
+	Remote    string          `json:"remote"`
+	Client    string          `json:"client,omitempty"`
+	UserAgent string          `json:"userAgent,omitempty"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Size      int             `json:"size"`
+	Duration  float64         `json:"duration"` // Seconds
+	Code      int             `json:"code,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// accessLog writes a structured log of the JSON-RPC calls served, rotating the
+// file once it reaches its maximum size.
+type accessLog struct {
+	path    string
+	maxSize int64    // Size in bytes after which the file is rotated, 0 to disable
+	sample  float64  // Fraction of successful calls to log, failures are always logged
+	redact  []string // Methods (or method prefixes ending in '*') whose parameters are omitted
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// newAccessLog opens the access log configured for the node.
+func newAccessLog(path string, maxSize int, sample float64, redact []string) (*accessLog, error) {
+	if sample <= 0 || sample > 1 {
+		sample = 1
+	}
+	l := &accessLog{
+		path:    path,
+		maxSize: int64(maxSize) * 1024 * 1024,
+		sample:  sample,
+		redact:  redact,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the log file for appending.
+func (l *accessLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file, l.size = file, stat.Size()
+	return nil
+}
+
+// redacted returns whether the parameters of the method must be omitted.
+func (l *accessLog) redacted(method string) bool {
+	for _, pattern := range l.redact {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(method, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if method == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// record implements rpc.CallRecorder, writing the call to the log.
+func (l *accessLog) record(rec *rpc.CallRecord) {
+	if rec.ErrorCode == 0 && l.sample < 1 && rand.Float64() >= l.sample {
+		return
+	}
+	entry := &accessLogEntry{
+		Time:      rec.Start,
+		Transport: rec.Peer.Transport,
+		Remote:    rec.Peer.RemoteAddr,
+		Client:    rec.Peer.HTTP.ClientID,
+		UserAgent: rec.Peer.HTTP.UserAgent,
+		Method:    rec.Method,
+		Size:      len(rec.Params),
+		Duration:  rec.Duration.Seconds(),
+		Code:      rec.ErrorCode,
+		Error:     rec.Error,
+	}
+	if !l.redacted(rec.Method) {
+		entry.Params = rec.Params
+	}
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		log.Warn("Failed to encode RPC access log entry", "method", rec.Method, "err", err)
+		return
+	}
+	blob = append(blob, '\n')
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return
+	}
+	if l.maxSize > 0 && l.size+int64(len(blob)) > l.maxSize && l.size > 0 {
+		if err := l.rotate(); err != nil {
+			log.Warn("Failed to rotate RPC access log", "path", l.path, "err", err)
+			if l.file == nil {
+				return
+			}
+		}
+	}
+	n, err := l.file.Write(blob)
+	l.size += int64(n)
+	if err != nil {
+		log.Warn("Failed to write RPC access log", "path", l.path, "err", err)
+	}
+}
+
+// rotate renames the current log file to path.1, shifting older files up to
+// the maximum number of backups, and reopens an empty log. The caller must hold
+// the lock.
+func (l *accessLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", l.path, accessLogBackups))
+	for i := accessLogBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	err := os.Rename(l.path, l.path+".1")
+	if openErr := l.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// close closes the log file.
+func (l *accessLog) close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// readAccessLog parses the entries of an access log file.
+func readAccessLog(t *testing.T, path string) []*accessLogEntry {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []*accessLogEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		entry := new(accessLogEntry)
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestAccessLogRedaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	l, err := newAccessLog(path, 0, 1, []string{"personal_*", "eth_sign"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := json.RawMessage(`["secret"]`)
+	for _, method := range []string{"eth_call", "personal_unlockAccount", "eth_sign", "eth_signTransaction"} {
+		l.record(&rpc.CallRecord{Method: method, Params: params, Start: time.Now(), Duration: time.Millisecond})
+	}
+	l.close()
+
+	entries := readAccessLog(t, path)
+	if len(entries) != 4 {
+		t.Fatalf("wrong number of entries: have %d, want 4", len(entries))
+	}
+	for i, redacted := range []bool{false, true, true, false} {
+		if entries[i].Size != len(params) {
+			t.Errorf("entry %d: wrong params size %d", i, entries[i].Size)
+		}
+		if (entries[i].Params == nil) != redacted {
+			t.Errorf("entry %d (%s): redaction mismatch: have params %s, want redacted %v", i, entries[i].Method, entries[i].Params, redacted)
+		}
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	l, err := newAccessLog(path, 0, 0.000001, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		l.record(&rpc.CallRecord{Method: "eth_call"})
+	}
+	l.record(&rpc.CallRecord{Method: "eth_call", ErrorCode: -32000, Error: "execution reverted"})
+	l.close()
+
+	// Only the failed call should have been logged, barring a miracle
+	entries := readAccessLog(t, path)
+	if len(entries) != 1 || entries[0].Code != -32000 {
+		t.Fatalf("wrong entries logged: %v", entries)
+	}
+}
+
+func TestAccessLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	l, err := newAccessLog(path, 1, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write enough entries to rotate the log a few times
+	params := json.RawMessage(`["` + strings.Repeat("x", 100*1024) + `"]`)
+	for i := 0; i < 25; i++ {
+		l.record(&rpc.CallRecord{Method: "eth_call", Params: params})
+	}
+	l.close()
+
+	var total int
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		stat, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("missing log file %s: %v", name, err)
+		}
+		if stat.Size() > 1024*1024 {
+			t.Errorf("log file %s exceeds maximum size: %d", name, stat.Size())
+		}
+		total += len(readAccessLog(t, name))
+	}
+	if total != 25 {
+		t.Errorf("wrong number of entries: have %d, want 25", total)
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Errorf("unexpected log file rotated")
+	}
+}
@@ -27,6 +27,8 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -307,11 +309,84 @@ func (api *adminAPI) NodeInfo() (*p2p.NodeInfo, error) {
 	return server.NodeInfo(), nil
 }
 
+// ProtocolStats retrieves the traffic and handling statistics of every message
+// type of the subprotocols run by the node, keyed by protocol, version and code.
+func (api *adminAPI) ProtocolStats() map[string]p2p.MessageStats {
+	return p2p.ProtocolStats()
+}
+
+// SetNodeRecordEntry puts an application defined entry into the local node record
+// and returns the sequence number of the updated record. The value must be RLP
+// encoded.
+func (api *adminAPI) SetNodeRecordEntry(key string, value hexutil.Bytes) (uint64, error) {
+	server := api.node.Server()
+	if server == nil {
+		return 0, ErrNodeStopped
+	}
+	if _, _, rest, err := rlp.Split(value); err != nil || len(rest) > 0 {
+		return 0, fmt.Errorf("invalid RLP value for key %q", key)
+	}
+	ln := server.LocalNode()
+	if err := ln.SetApp(enr.WithEntry(key, rlp.RawValue(value))); err != nil {
+		return 0, err
+	}
+	return ln.Node().Seq(), nil
+}
+
+// DeleteNodeRecordEntry removes an application defined entry from the local node
+// record and returns the sequence number of the updated record.
+func (api *adminAPI) DeleteNodeRecordEntry(key string) (uint64, error) {
+	server := api.node.Server()
+	if server == nil {
+		return 0, ErrNodeStopped
+	}
+	ln := server.LocalNode()
+	if err := ln.DeleteApp(key); err != nil {
+		return 0, err
+	}
+	return ln.Node().Seq(), nil
+}
+
+// PeerRecordEntries retrieves the RLP encoded value of the given node record
+// entry from every connected peer advertising it, keyed by node ID.
+func (api *adminAPI) PeerRecordEntries(key string) (map[enode.ID]hexutil.Bytes, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	entries := make(map[enode.ID]hexutil.Bytes)
+	for _, peer := range server.Peers() {
+		if value, err := peer.Node().RawEntry(key); err == nil {
+			entries[peer.ID()] = hexutil.Bytes(value)
+		}
+	}
+	return entries, nil
+}
+
 // Datadir retrieves the current data directory the node is using.
 func (api *adminAPI) Datadir() string {
 	return api.node.DataDir()
 }
 
+// Services retrieves the state and dependencies of the services registered on
+// the node.
+func (api *adminAPI) Services() []ServiceInfo {
+	return api.node.Services()
+}
+
+// RestartService restarts the service with the given name, along with the
+// services depending on it. Only restartable services are supported.
+func (api *adminAPI) RestartService(name string) (bool, error) {
+	lifecycle, ok := api.node.lifecycleByName(name)
+	if !ok {
+		return false, fmt.Errorf("unknown service %q", name)
+	}
+	if err := api.node.RestartLifecycle(lifecycle); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // web3API offers helper utils
 type web3API struct {
 	stack *Node
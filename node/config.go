@@ -68,6 +68,11 @@ type Config struct {
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
+	// P2PStandby holds back peer-to-peer networking when the node starts, until it
+	// is enabled with Node.StartNetworking. Hot standby nodes use it to share the
+	// node key of an active primary without competing for its identity.
+	P2PStandby bool `toml:",omitempty"`
+
 	// KeyStoreDir is the file system folder that contains private keys. The directory can
 	// be specified as a relative path, in which case it is resolved relative to the
 	// current directory.
@@ -94,9 +99,19 @@ type Config struct {
 	// USB enables hardware wallet monitoring and connectivity.
 	USB bool `toml:",omitempty"`
 
+	// USBSimulatorSeed attaches simulated Ledger and Trezor hardware wallets
+	// deriving their keys from the given seed. It is meant for testing wallet
+	// flows only.
+	USBSimulatorSeed string `toml:",omitempty"`
+
 	// SmartCardDaemonPath is the path to the smartcard daemon's socket
 	SmartCardDaemonPath string `toml:",omitempty"`
 
+	// QRWalletKeys is the path to a file with the keys exported by air-gapped QR
+	// code wallets, as crypto-hdkey URs. Sign requests are exchanged with the
+	// wallets as UR parts on the standard input and output.
+	QRWalletKeys string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -138,6 +153,34 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// RPCAccessLog is the file to write a structured log of the JSON-RPC calls
+	// served over HTTP and WebSocket to. Access logging is disabled if empty.
+	RPCAccessLog string `toml:",omitempty"`
+
+	// RPCAccessLogSample is the fraction of successful calls written to the access
+	// log. Failed calls are always logged.
+	RPCAccessLogSample float64 `toml:",omitempty"`
+
+	// RPCAccessLogRedact lists the methods whose parameters are omitted from the
+	// access log. Entries ending in '*' match all methods with the prefix.
+	RPCAccessLogRedact []string `toml:",omitempty"`
+
+	// RPCAccessLogMaxSize is the size in megabytes after which the access log is
+	// rotated. Zero disables rotation.
+	RPCAccessLogMaxSize int `toml:",omitempty"`
+
+	// RPCRateLimit limits the rate of the calls served to each client over HTTP
+	// and WebSocket, identified by its IP address.
+	RPCRateLimit rpc.RateLimitConfig `toml:",omitempty"`
+
+	// RPCSignResponses enables attaching a signature made with the node key to the
+	// results of the RPCSignedMethods served over HTTP and WebSocket.
+	RPCSignResponses bool `toml:",omitempty"`
+
+	// RPCSignedMethods lists the methods whose results are signed if response
+	// signing is enabled.
+	RPCSignedMethods []string `toml:",omitempty"`
+
 	// AuthAddr is the listening address on which authenticated APIs are provided.
 	AuthAddr string `toml:",omitempty"`
 
@@ -203,6 +246,19 @@ type Config struct {
 
 	// JWTSecret is the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
+
+	// DBEncryptionKey is the path of the file containing the hex-encoded master key
+	// used to encrypt the databases at rest. Encryption is disabled if empty.
+	DBEncryptionKey string `toml:",omitempty"`
+
+	// DBEngine is the storage engine of the databases: "leveldb" (the default) for
+	// local databases in the data directory, or "remote" for a remote key-value
+	// store reached through DBRemoteEndpoints.
+	DBEngine string `toml:",omitempty"`
+
+	// DBRemoteEndpoints are the grpc:// or grpcs:// endpoints of the remote
+	// key-value store, used if DBEngine is "remote".
+	DBRemoteEndpoints []string `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
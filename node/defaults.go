@@ -59,10 +59,20 @@ var DefaultConfig = Config{
 	WSPort:              DefaultWSPort,
 	WSModules:           []string{"net", "web3"},
 	GraphQLVirtualHosts: []string{"localhost"},
+	RPCAccessLogSample:  1,
+	RPCAccessLogRedact:  []string{"personal_*", "eth_sign*"},
+	RPCAccessLogMaxSize: 100,
+	RPCSignedMethods: []string{
+		"eth_getBlockByNumber", "eth_getBlockByHash", "eth_getHeaderByNumber", "eth_getHeaderByHash",
+		"eth_getTransactionReceipt", "eth_getProof",
+	},
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
 		NAT:        nat.Any(),
+
+		AdaptiveCPULimit:      0.8,
+		AdaptiveDiskWaitLimit: 0.2,
 	},
 }
 
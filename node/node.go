@@ -17,6 +17,7 @@
 package node
 
 import (
+	"crypto/cipher"
 	crand "crypto/rand"
 	"errors"
 	"fmt"
@@ -27,12 +28,15 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encrypted"
+	"github.com/ethereum/go-ethereum/ethdb/remotekv"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -55,14 +59,16 @@ type Node struct {
 	state         int               // Tracks state of node lifecycle
 
 	lock          sync.Mutex
-	lifecycles    []Lifecycle // All registered backends, services, and auxiliary services that have a lifecycle
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	http          *httpServer //
-	ws            *httpServer //
-	httpAuth      *httpServer //
-	wsAuth        *httpServer //
-	ipc           *ipcServer  // Stores information about the ipc http server
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	lifecycles    []Lifecycle            // All registered backends, services, and auxiliary services that have a lifecycle
+	services      map[Lifecycle]*service // Dependencies and states of the registered lifecycles
+	rpcAPIs       []rpc.API              // List of APIs currently provided by the node
+	http          *httpServer            //
+	ws            *httpServer            //
+	httpAuth      *httpServer            //
+	wsAuth        *httpServer            //
+	ipc           *ipcServer             // Stores information about the ipc http server
+	inprocHandler *rpc.Server            // In-process RPC request handler to process the API requests
+	accessLog     *accessLog             // JSON-RPC access log of the HTTP and WebSocket servers
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -110,6 +116,7 @@ func New(conf *Config) (*Node, error) {
 		stop:          make(chan struct{}),
 		server:        &p2p.Server{Config: conf.P2P},
 		databases:     make(map[*closeTrackingDB]struct{}),
+		services:      make(map[Lifecycle]*service),
 	}
 
 	// Register built-in APIs.
@@ -162,7 +169,8 @@ func New(conf *Config) (*Node, error) {
 }
 
 // Start starts all registered lifecycles, RPC services and p2p networking.
-// Node can only be started once.
+// Lifecycles are started after the lifecycles they depend on, in registration
+// order otherwise. Node can only be started once.
 func (n *Node) Start() error {
 	n.startStopLock.Lock()
 	defer n.startStopLock.Unlock()
@@ -176,9 +184,15 @@ func (n *Node) Start() error {
 		n.lock.Unlock()
 		return ErrNodeStopped
 	}
+	sorted, err := sortLifecycles(n.lifecycles, n.services)
+	if err != nil {
+		n.lock.Unlock()
+		return err
+	}
+	n.lifecycles = sorted
 	n.state = runningState
 	// open networking and RPC endpoints
-	err := n.openEndpoints()
+	err = n.openEndpoints()
 	lifecycles := make([]Lifecycle, len(n.lifecycles))
 	copy(lifecycles, n.lifecycles)
 	n.lock.Unlock()
@@ -191,7 +205,7 @@ func (n *Node) Start() error {
 	// Start all registered lifecycles.
 	var started []Lifecycle
 	for _, lifecycle := range lifecycles {
-		if err = lifecycle.Start(); err != nil {
+		if err = n.startService(n.services[lifecycle]); err != nil {
 			break
 		}
 		started = append(started, lifecycle)
@@ -268,7 +282,8 @@ func (n *Node) doClose(errs []error) error {
 
 // openEndpoints starts all network and RPC endpoints.
 func (n *Node) openEndpoints() error {
-	// start networking endpoints
+	// start networking endpoints, without any connectivity in standby
+	n.server.Standby = n.config.P2PStandby
 	n.log.Info("Starting peer-to-peer node", "instance", n.server.Name)
 	if err := n.server.Start(); err != nil {
 		return convertFileLockError(err)
@@ -282,6 +297,22 @@ func (n *Node) openEndpoints() error {
 	return err
 }
 
+// StartNetworking enables the peer-to-peer networking held back by the P2PStandby
+// option. The p2p server keeps running throughout, so the local node record and
+// the discovery sources of the protocols are preserved.
+func (n *Node) StartNetworking() error {
+	n.startStopLock.Lock()
+	defer n.startStopLock.Unlock()
+
+	n.lock.Lock()
+	running := n.state == runningState
+	n.lock.Unlock()
+	if !running {
+		return errors.New("node not running")
+	}
+	return n.server.EnableNetworking()
+}
+
 // containsLifecycle checks if 'lfs' contains 'l'.
 func containsLifecycle(lfs []Lifecycle, l Lifecycle) bool {
 	for _, obj := range lfs {
@@ -300,7 +331,7 @@ func (n *Node) stopServices(running []Lifecycle) error {
 	// Stop running lifecycles in reverse order.
 	failure := &StopError{Services: make(map[reflect.Type]error)}
 	for i := len(running) - 1; i >= 0; i-- {
-		if err := running[i].Stop(); err != nil {
+		if err := n.stopService(n.services[running[i]]); err != nil {
 			failure.Services[reflect.TypeOf(running[i])] = err
 		}
 	}
@@ -394,7 +425,26 @@ func (n *Node) startRPC() error {
 	var (
 		servers   []*httpServer
 		open, all = n.GetAPIs()
+		recorder  rpc.CallRecorder
+		limiter   *rpc.RateLimiter
+		signer    *rpc.ResponseSigner
 	)
+	if n.config.RPCAccessLog != "" {
+		accessLog, err := newAccessLog(n.config.ResolvePath(n.config.RPCAccessLog), n.config.RPCAccessLogMaxSize, n.config.RPCAccessLogSample, n.config.RPCAccessLogRedact)
+		if err != nil {
+			return err
+		}
+		n.accessLog, recorder = accessLog, accessLog.record
+	}
+	if n.config.RPCRateLimit.Enabled() {
+		var err error
+		if limiter, err = rpc.NewRateLimiter(n.config.RPCRateLimit); err != nil {
+			return err
+		}
+	}
+	if n.config.RPCSignResponses {
+		signer = rpc.NewResponseSigner(n.server.PrivateKey, n.config.RPCSignedMethods)
+	}
 
 	initHttp := func(server *httpServer, apis []rpc.API, port int) error {
 		if err := server.setListenAddr(n.config.HTTPHost, port); err != nil {
@@ -405,6 +455,9 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			recorder:           recorder,
+			limiter:            limiter,
+			signer:             signer,
 		}); err != nil {
 			return err
 		}
@@ -418,9 +471,12 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(n.rpcAPIs, wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
-			prefix:  n.config.WSPathPrefix,
+			Modules:  n.config.WSModules,
+			Origins:  n.config.WSOrigins,
+			prefix:   n.config.WSPathPrefix,
+			recorder: recorder,
+			limiter:  limiter,
+			signer:   signer,
 		}); err != nil {
 			return err
 		}
@@ -440,6 +496,7 @@ func (n *Node) startRPC() error {
 			Modules:            DefaultAuthModules,
 			prefix:             DefaultAuthPrefix,
 			jwtSecret:          secret,
+			recorder:           recorder,
 		}); err != nil {
 			return err
 		}
@@ -454,6 +511,7 @@ func (n *Node) startRPC() error {
 			Origins:   DefaultAuthOrigins,
 			prefix:    DefaultAuthPrefix,
 			jwtSecret: secret,
+			recorder:  recorder,
 		}); err != nil {
 			return err
 		}
@@ -512,6 +570,10 @@ func (n *Node) stopRPC() {
 	n.wsAuth.stop()
 	n.ipc.stop()
 	n.stopInProc()
+	if n.accessLog != nil {
+		n.accessLog.close()
+		n.accessLog = nil
+	}
 }
 
 // startInProc registers all RPC APIs on the inproc server.
@@ -534,8 +596,10 @@ func (n *Node) Wait() {
 	<-n.stop
 }
 
-// RegisterLifecycle registers the given Lifecycle on the node.
-func (n *Node) RegisterLifecycle(lifecycle Lifecycle) {
+// RegisterLifecycle registers the given Lifecycle on the node. The lifecycle is
+// started after and stopped before the given dependencies, which must also be
+// registered before the node is started.
+func (n *Node) RegisterLifecycle(lifecycle Lifecycle, deps ...Lifecycle) {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
@@ -546,6 +610,16 @@ func (n *Node) RegisterLifecycle(lifecycle Lifecycle) {
 		panic(fmt.Sprintf("attempt to register lifecycle %T more than once", lifecycle))
 	}
 	n.lifecycles = append(n.lifecycles, lifecycle)
+
+	// Name the service after its type, disambiguating multiple instances
+	name := fmt.Sprintf("%T", lifecycle)
+	for i := 2; ; i++ {
+		if _, taken := n.lifecycleByNameLocked(name); !taken {
+			break
+		}
+		name = fmt.Sprintf("%T#%d", lifecycle, i)
+	}
+	n.services[lifecycle] = &service{lifecycle: lifecycle, name: name, deps: deps, since: time.Now()}
 }
 
 // RegisterProtocols adds backend's protocols to the node's p2p server.
@@ -686,8 +760,15 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 
 	var db ethdb.Database
 	var err error
-	if n.config.DataDir == "" {
+	if n.config.DBEngine == "remote" {
+		db, err = n.openRemoteDatabase(name, readonly)
+	} else if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
+	} else if n.config.DBEncryptionKey != "" {
+		var aead cipher.AEAD
+		if aead, err = encrypted.LoadCipher(n.config.DBEncryptionKey); err == nil {
+			db, err = rawdb.NewEncryptedLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace, readonly, aead)
+		}
 	} else {
 		db, err = rawdb.NewLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace, readonly)
 	}
@@ -712,8 +793,15 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 
 	var db ethdb.Database
 	var err error
-	if n.config.DataDir == "" {
+	if n.config.DBEngine == "remote" {
+		db, err = n.openRemoteDatabase(name, readonly)
+	} else if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
+	} else if n.config.DBEncryptionKey != "" {
+		var aead cipher.AEAD
+		if aead, err = encrypted.LoadCipher(n.config.DBEncryptionKey); err == nil {
+			db, err = rawdb.NewEncryptedLevelDBDatabaseWithFreezer(n.ResolvePath(name), cache, handles, n.ResolveAncient(name, ancient), namespace, readonly, aead)
+		}
 	} else {
 		db, err = rawdb.NewLevelDBDatabaseWithFreezer(n.ResolvePath(name), cache, handles, n.ResolveAncient(name, ancient), namespace, readonly)
 	}
@@ -724,6 +812,30 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	return db, err
 }
 
+// openRemoteDatabase opens the database with the given name in the remote
+// key-value store. There is no freezer, ancient chain data is kept in the store
+// along with the rest of the database.
+func (n *Node) openRemoteDatabase(name string, readonly bool) (ethdb.Database, error) {
+	remote, err := remotekv.New(n.config.DBRemoteEndpoints, name, readonly)
+	if err != nil {
+		return nil, err
+	}
+	if n.config.DBEncryptionKey == "" {
+		return rawdb.NewDatabase(remote), nil
+	}
+	aead, err := encrypted.LoadCipher(n.config.DBEncryptionKey)
+	if err != nil {
+		remote.Close()
+		return nil, err
+	}
+	kv, err := encrypted.New(remote, aead, readonly)
+	if err != nil {
+		remote.Close()
+		return nil, err
+	}
+	return rawdb.NewDatabase(kv), nil
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
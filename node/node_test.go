@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/stretchr/testify/assert"
@@ -83,6 +84,41 @@ func TestNodeStartMultipleTimes(t *testing.T) {
 	}
 }
 
+// Tests that networking held back in standby is only enabled on request.
+func TestNodeStartNetworking(t *testing.T) {
+	config := testNodeConfig()
+	config.P2P.ListenAddr = "127.0.0.1:0"
+	config.P2P.MaxPeers = 10
+	config.P2PStandby = true
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	defer stack.Close()
+
+	if err := stack.StartNetworking(); err == nil {
+		t.Fatal("networking enabled before node start")
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	if stack.Server().Self().TCP() != 0 {
+		t.Fatal("standby node listening")
+	}
+	if err := stack.StartNetworking(); err != nil {
+		t.Fatalf("failed to enable networking: %v", err)
+	}
+	if stack.Server().Self().TCP() == 0 {
+		t.Fatal("node not listening after enabling networking")
+	}
+	if stack.Server().Self().ID() != enode.PubkeyToIDV4(&testNodeKey.PublicKey) {
+		t.Fatal("node identity changed")
+	}
+	if err := stack.StartNetworking(); err == nil {
+		t.Fatal("networking enabled twice")
+	}
+}
+
 // Tests that if the data dir is already in use, an appropriate error is returned.
 func TestNodeUsedDataDir(t *testing.T) {
 	// Create a temporary folder to use as the data directory
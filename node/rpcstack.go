@@ -39,16 +39,22 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
-	prefix             string // path prefix on which to mount http handler
-	jwtSecret          []byte // optional JWT secret
+	prefix             string              // path prefix on which to mount http handler
+	jwtSecret          []byte              // optional JWT secret
+	recorder           rpc.CallRecorder    // optional access logger
+	limiter            *rpc.RateLimiter    // optional rate limiter
+	signer             *rpc.ResponseSigner // optional response signer
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
 	Origins   []string
 	Modules   []string
-	prefix    string // path prefix on which to mount ws handler
-	jwtSecret []byte // optional JWT secret
+	prefix    string              // path prefix on which to mount ws handler
+	jwtSecret []byte              // optional JWT secret
+	recorder  rpc.CallRecorder    // optional access logger
+	limiter   *rpc.RateLimiter    // optional rate limiter
+	signer    *rpc.ResponseSigner // optional response signer
 }
 
 type rpcHandler struct {
@@ -293,6 +299,9 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetCallRecorder(config.recorder)
+	srv.SetRateLimiter(config.limiter)
+	srv.SetResponseSigner(config.signer)
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
@@ -324,6 +333,9 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	}
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetCallRecorder(config.recorder)
+	srv.SetRateLimiter(config.limiter)
+	srv.SetResponseSigner(config.signer)
 	if err := RegisterApis(apis, config.Modules, srv); err != nil {
 		return err
 	}
@@ -0,0 +1,256 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ServiceState is the state of a lifecycle registered on the node.
+type ServiceState int
+
+const (
+	ServiceStopped ServiceState = iota // Not started yet, or stopped
+	ServiceRunning                     // Started successfully
+	ServiceFailed                      // Failed to start or stop
+)
+
+// String implements fmt.Stringer.
+func (s ServiceState) String() string {
+	switch s {
+	case ServiceRunning:
+		return "running"
+	case ServiceFailed:
+		return "failed"
+	default:
+		return "stopped"
+	}
+}
+
+// Restartable is implemented by lifecycles which support being started again
+// after having been stopped. Only these can be restarted while the node runs.
+type Restartable interface {
+	Lifecycle
+	Restartable()
+}
+
+// service tracks a registered lifecycle and its dependencies.
+type service struct {
+	lifecycle Lifecycle
+	name      string
+	deps      []Lifecycle
+
+	state   ServiceState
+	err     error     // Error causing the failed state
+	since   time.Time // Time of the last state change
+	started bool      // Whether Start succeeded without a matching Stop
+}
+
+func (s *service) setState(state ServiceState, err error) {
+	s.state, s.err, s.since = state, err, time.Now()
+}
+
+// ServiceInfo describes a lifecycle registered on the node.
+type ServiceInfo struct {
+	Name         string    `json:"name"`
+	State        string    `json:"state"`
+	Since        time.Time `json:"since"`
+	Dependencies []string  `json:"dependencies"`
+	Restartable  bool      `json:"restartable"`
+	Error        string    `json:"error,omitempty"` // Error of a failed service
+}
+
+// sortLifecycles orders the lifecycles so that all dependencies of a lifecycle
+// precede it, keeping the registration order otherwise.
+func sortLifecycles(lifecycles []Lifecycle, services map[Lifecycle]*service) ([]Lifecycle, error) {
+	var (
+		sorted = make([]Lifecycle, 0, len(lifecycles))
+		marks  = make(map[Lifecycle]int) // 1 = visiting, 2 = done
+		visit  func(l Lifecycle) error
+	)
+	visit = func(l Lifecycle) error {
+		switch marks[l] {
+		case 1:
+			return fmt.Errorf("dependency cycle at service %s", services[l].name)
+		case 2:
+			return nil
+		}
+		marks[l] = 1
+		for _, dep := range services[l].deps {
+			if _, ok := services[dep]; !ok {
+				return fmt.Errorf("service %s depends on unregistered lifecycle %T", services[l].name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		marks[l] = 2
+		sorted = append(sorted, l)
+		return nil
+	}
+	for _, l := range lifecycles {
+		if err := visit(l); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// RestartLifecycle stops and restarts a registered lifecycle of the running node,
+// along with all lifecycles depending on it. Dependents are stopped first and
+// only restarted if they were running before. The lifecycle and its running
+// dependents must all implement Restartable.
+func (n *Node) RestartLifecycle(lifecycle Lifecycle) error {
+	n.startStopLock.Lock()
+	defer n.startStopLock.Unlock()
+
+	n.lock.Lock()
+	if n.state != runningState {
+		n.lock.Unlock()
+		return errors.New("node not running")
+	}
+	target, ok := n.services[lifecycle]
+	if !ok {
+		n.lock.Unlock()
+		return fmt.Errorf("lifecycle %T not registered", lifecycle)
+	}
+	// Collect the lifecycle and its transitive dependents in start order
+	var (
+		affected = []*service{target}
+		involved = map[Lifecycle]bool{lifecycle: true}
+	)
+	for _, l := range n.lifecycles {
+		svc := n.services[l]
+		for _, dep := range svc.deps {
+			if involved[dep] && !involved[l] {
+				involved[l] = true
+				affected = append(affected, svc)
+			}
+		}
+	}
+	wasRunning := make(map[*service]bool)
+	for _, svc := range affected {
+		wasRunning[svc] = svc.started
+		if _, ok := svc.lifecycle.(Restartable); !ok && (svc == target || svc.started) {
+			n.lock.Unlock()
+			return fmt.Errorf("service %s can't be restarted", svc.name)
+		}
+	}
+	n.lock.Unlock()
+
+	n.log.Info("Restarting service", "service", target.name, "dependents", len(affected)-1)
+	for i := len(affected) - 1; i >= 0; i-- {
+		if err := n.stopService(affected[i]); err != nil {
+			n.log.Warn("Failed to stop service", "service", affected[i].name, "err", err)
+		}
+	}
+	for _, svc := range affected {
+		if svc != target && !wasRunning[svc] {
+			continue
+		}
+		if err := n.startService(svc); err != nil {
+			return fmt.Errorf("failed to start service %s: %v", svc.name, err)
+		}
+	}
+	return nil
+}
+
+// startService starts a single lifecycle, tracking its state.
+func (n *Node) startService(svc *service) error {
+	err := svc.lifecycle.Start()
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if err != nil {
+		svc.setState(ServiceFailed, err)
+		return err
+	}
+	svc.started = true
+	svc.setState(ServiceRunning, nil)
+	return nil
+}
+
+// stopService stops a single lifecycle if it was started, tracking its state.
+func (n *Node) stopService(svc *service) error {
+	n.lock.Lock()
+	started := svc.started
+	n.lock.Unlock()
+	if !started {
+		return nil
+	}
+	err := svc.lifecycle.Stop()
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	svc.started = false
+	if err != nil {
+		svc.setState(ServiceFailed, err)
+		return err
+	}
+	svc.setState(ServiceStopped, nil)
+	return nil
+}
+
+// Services returns the state of all registered lifecycles, in start order once
+// the node has been started.
+func (n *Node) Services() []ServiceInfo {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	infos := make([]ServiceInfo, 0, len(n.lifecycles))
+	for _, l := range n.lifecycles {
+		svc := n.services[l]
+		info := ServiceInfo{
+			Name:         svc.name,
+			State:        svc.state.String(),
+			Since:        svc.since,
+			Dependencies: make([]string, 0, len(svc.deps)),
+		}
+		for _, dep := range svc.deps {
+			if depsvc, ok := n.services[dep]; ok {
+				info.Dependencies = append(info.Dependencies, depsvc.name)
+			} else {
+				info.Dependencies = append(info.Dependencies, fmt.Sprintf("%T", dep))
+			}
+		}
+		if svc.err != nil {
+			info.Error = svc.err.Error()
+		}
+		_, info.Restartable = l.(Restartable)
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// lifecycleByName returns the registered lifecycle with the given service name.
+func (n *Node) lifecycleByName(name string) (Lifecycle, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.lifecycleByNameLocked(name)
+}
+
+func (n *Node) lifecycleByNameLocked(name string) (Lifecycle, bool) {
+	for l, svc := range n.services {
+		if svc.name == name {
+			return l, true
+		}
+	}
+	return nil, false
+}
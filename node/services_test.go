@@ -0,0 +1,172 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests that lifecycles are started after their dependencies and stopped
+// before them, regardless of the registration order.
+func TestServiceDependencyOrder(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	newService := func(name string) *InstrumentedService {
+		return &InstrumentedService{
+			startHook: func() { events = append(events, "start "+name) },
+			stopHook:  func() { events = append(events, "stop "+name) },
+		}
+	}
+	var (
+		api = newService("api")
+		db  = newService("db")
+		eth = newService("eth")
+	)
+	stack.RegisterLifecycle(api, eth)
+	stack.RegisterLifecycle(eth, db)
+	stack.RegisterLifecycle(db)
+
+	if err := stack.Start(); err != nil {
+		t.Fatal(err)
+	}
+	stack.Close()
+
+	want := []string{"start db", "start eth", "start api", "stop api", "stop eth", "stop db"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("event order mismatch:\nhave %v\nwant %v", events, want)
+	}
+}
+
+func TestServiceDependencyErrors(t *testing.T) {
+	// Unregistered dependency
+	stack, _ := New(testNodeConfig())
+	stack.RegisterLifecycle(new(InstrumentedService), new(InstrumentedService))
+	if err := stack.Start(); err == nil || !strings.Contains(err.Error(), "unregistered") {
+		t.Errorf("unexpected error for unregistered dependency: %v", err)
+	}
+	stack.Close()
+
+	// Dependency cycle
+	stack, _ = New(testNodeConfig())
+	a, b := new(InstrumentedService), new(InstrumentedService)
+	stack.RegisterLifecycle(a, b)
+	stack.RegisterLifecycle(b, a)
+	if err := stack.Start(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("unexpected error for dependency cycle: %v", err)
+	}
+	stack.Close()
+}
+
+type restartableService struct {
+	InstrumentedService
+}
+
+func (s *restartableService) Restartable() {}
+
+// Tests that a restartable service can be restarted with its dependents while
+// the node keeps running, and that the services are reported accordingly.
+func TestServiceRestart(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stack.Close()
+
+	var starts = make(map[string]int)
+	var (
+		db  = new(restartableService)
+		eth = new(restartableService)
+		api = new(Noop)
+	)
+	db.startHook = func() { starts["db"]++ }
+	eth.startHook = func() { starts["eth"]++ }
+	stack.RegisterLifecycle(db)
+	stack.RegisterLifecycle(eth, db)
+	stack.RegisterLifecycle(api)
+
+	if err := stack.Start(); err != nil {
+		t.Fatal(err)
+	}
+	infos := stack.Services()
+	if len(infos) != 3 {
+		t.Fatalf("service count mismatch: have %d, want 3", len(infos))
+	}
+	if infos[1].State != "running" || !reflect.DeepEqual(infos[1].Dependencies, []string{infos[0].Name}) {
+		t.Errorf("dependent service reported as %s, dependencies %v", infos[1].State, infos[1].Dependencies)
+	}
+	if !infos[0].Restartable || infos[2].Restartable {
+		t.Errorf("restartability mismatch: %v, %v", infos[0].Restartable, infos[2].Restartable)
+	}
+	// Restart the service through the admin API
+	if _, err := (&adminAPI{stack}).RestartService(infos[0].Name); err != nil {
+		t.Fatal(err)
+	}
+	if starts["db"] != 2 || starts["eth"] != 2 {
+		t.Errorf("restart count mismatch: %v", starts)
+	}
+	infos = stack.Services()
+	for _, info := range infos {
+		if info.State != "running" {
+			t.Errorf("service %s reported as %s after restart", info.Name, info.State)
+		}
+	}
+	// Services not supporting restarts are refused
+	if err := stack.RestartLifecycle(api); err == nil {
+		t.Fatal("restarted non-restartable service")
+	}
+	// A failing restart must not bring the node down
+	eth.start = errors.New("nope")
+	if err := stack.RestartLifecycle(db); err == nil {
+		t.Fatal("expected restart error")
+	}
+	if infos = stack.Services(); infos[1].State != "failed" || infos[1].Error != "nope" || infos[2].State != "running" {
+		t.Errorf("unexpected states after failed restart: %s, %s", infos[1].State, infos[2].State)
+	}
+}
+
+// Tests that running dependents not supporting restarts block the restart.
+func TestServiceRestartDependents(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stack.Close()
+
+	var (
+		db  = new(restartableService)
+		eth = new(InstrumentedService)
+	)
+	stack.RegisterLifecycle(db)
+	stack.RegisterLifecycle(eth, db)
+	if err := stack.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stack.RestartLifecycle(db); err == nil {
+		t.Fatal("restarted service with non-restartable dependent")
+	}
+	for _, info := range stack.Services() {
+		if info.State != "running" {
+			t.Errorf("service %s reported as %s after refused restart", info.Name, info.State)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// adaptPeersInterval is the time between two adjustments of the adaptive
+	// peer limit.
+	adaptPeersInterval = 30 * time.Second
+
+	// adaptPeersIdleRatio is the resource pressure below which the peer limit is
+	// raised again.
+	adaptPeersIdleRatio = 0.5
+
+	// adaptPeersDecrease is the factor the peer limit is scaled by when
+	// resources are under pressure.
+	adaptPeersDecrease = 0.9
+
+	// cpuTicksPerSecond is the resolution of the times reported by
+	// metrics.ReadCPUStats.
+	cpuTicksPerSecond = 100
+)
+
+var (
+	adaptivePeerLimitGauge = metrics.NewRegisteredGauge("p2p/adaptive/limit", nil)
+	adaptiveCPUGauge       = metrics.NewRegisteredGaugeFloat64("p2p/adaptive/cpu", nil)
+	adaptiveDiskWaitGauge  = metrics.NewRegisteredGaugeFloat64("p2p/adaptive/diskwait", nil)
+	adaptiveBandwidthGauge = metrics.NewRegisteredGauge("p2p/adaptive/bandwidth", nil)
+)
+
+// resourceUsage is a measurement of the local resources over one adaptation
+// interval.
+type resourceUsage struct {
+	cpu       float64 // Fraction of the total CPU time spent working
+	diskWait  float64 // Fraction of the total CPU time spent waiting on disk
+	bandwidth uint64  // P2P traffic in bytes per second
+}
+
+// resourceSampler turns the cumulative resource counters into rates.
+type resourceSampler struct {
+	time    time.Time
+	cpu     metrics.CPUStats
+	traffic uint64
+}
+
+// sample reads the resource counters and returns the usage since the previous
+// call. The first call only initializes the sampler and reports no usage.
+func (s *resourceSampler) sample(now time.Time) resourceUsage {
+	var (
+		cpu     metrics.CPUStats
+		traffic uint64
+	)
+	metrics.ReadCPUStats(&cpu)
+	for _, stats := range ProtocolStats() {
+		traffic += stats.InBytes + stats.OutBytes
+	}
+	var usage resourceUsage
+	if !s.time.IsZero() {
+		if elapsed := now.Sub(s.time).Seconds(); elapsed > 0 {
+			ticks := elapsed * cpuTicksPerSecond * float64(runtime.NumCPU())
+			usage.cpu = float64(cpu.GlobalTime-s.cpu.GlobalTime) / ticks
+			usage.diskWait = float64(cpu.GlobalWait-s.cpu.GlobalWait) / ticks
+			usage.bandwidth = uint64(float64(traffic-s.traffic) / elapsed)
+		}
+	}
+	s.time, s.cpu, s.traffic = now, cpu, traffic
+	return usage
+}
+
+// adaptiveLimits are the operator configured bounds of the adaptive peer count.
+type adaptiveLimits struct {
+	minPeers, maxPeers int
+
+	cpu       float64
+	diskWait  float64
+	bandwidth uint64
+}
+
+// pressure returns the highest ratio between the usage of a resource and its
+// configured limit. Resources without a limit are ignored.
+func (l *adaptiveLimits) pressure(usage resourceUsage) float64 {
+	var ratio float64
+	if l.cpu > 0 && usage.cpu/l.cpu > ratio {
+		ratio = usage.cpu / l.cpu
+	}
+	if l.diskWait > 0 && usage.diskWait/l.diskWait > ratio {
+		ratio = usage.diskWait / l.diskWait
+	}
+	if l.bandwidth > 0 && float64(usage.bandwidth)/float64(l.bandwidth) > ratio {
+		ratio = float64(usage.bandwidth) / float64(l.bandwidth)
+	}
+	return ratio
+}
+
+// next returns the peer limit following the current one under the given
+// resource pressure. The limit is lowered multiplicatively while any resource is
+// over its limit and raised additively once all of them are idle, so the peer
+// count backs off quickly but recovers gently.
+func (l *adaptiveLimits) next(current int, pressure float64) int {
+	switch {
+	case pressure > 1:
+		lowered := int(float64(current) * adaptPeersDecrease)
+		if lowered == current {
+			lowered--
+		}
+		if lowered < l.minPeers {
+			lowered = l.minPeers
+		}
+		return lowered
+
+	case pressure < adaptPeersIdleRatio:
+		step := l.maxPeers / 20
+		if step < 1 {
+			step = 1
+		}
+		raised := current + step
+		if raised > l.maxPeers {
+			raised = l.maxPeers
+		}
+		return raised
+	}
+	return current
+}
+
+// adaptiveLimits returns the configured bounds of the adaptive peer count.
+func (srv *Server) adaptiveLimits() *adaptiveLimits {
+	limits := &adaptiveLimits{
+		minPeers:  srv.MinPeers,
+		maxPeers:  srv.MaxPeers,
+		cpu:       srv.AdaptiveCPULimit,
+		diskWait:  srv.AdaptiveDiskWaitLimit,
+		bandwidth: srv.AdaptiveBandwidthLimit,
+	}
+	if limits.minPeers <= 0 {
+		limits.minPeers = srv.MaxPeers / 4
+	}
+	if limits.minPeers < 1 {
+		limits.minPeers = 1
+	}
+	if limits.minPeers > limits.maxPeers {
+		limits.minPeers = limits.maxPeers
+	}
+	return limits
+}
+
+// adaptPeersLoop periodically measures the local resource usage and scales the
+// peer limit between MinPeers and MaxPeers accordingly.
+func (srv *Server) adaptPeersLoop() {
+	defer srv.loopWG.Done()
+
+	var (
+		limits  = srv.adaptiveLimits()
+		sampler resourceSampler
+		timer   = time.NewTicker(adaptPeersInterval)
+	)
+	defer timer.Stop()
+
+	srv.log.Info("Adaptive peer count enabled", "min", limits.minPeers, "max", limits.maxPeers,
+		"cpu", limits.cpu, "diskwait", limits.diskWait, "bandwidth", limits.bandwidth)
+	sampler.sample(time.Now())
+	adaptivePeerLimitGauge.Update(int64(limits.maxPeers))
+
+	for {
+		select {
+		case now := <-timer.C:
+			usage := sampler.sample(now)
+			adaptiveCPUGauge.Update(usage.cpu)
+			adaptiveDiskWaitGauge.Update(usage.diskWait)
+			adaptiveBandwidthGauge.Update(int64(usage.bandwidth))
+
+			var (
+				pressure = limits.pressure(usage)
+				current  = srv.maxPeers()
+				limit    = limits.next(current, pressure)
+			)
+			if limit == current {
+				continue
+			}
+			srv.log.Info("Adjusting peer limit", "old", current, "new", limit, "pressure", pressure,
+				"cpu", usage.cpu, "diskwait", usage.diskWait, "bandwidth", usage.bandwidth)
+			srv.setPeerLimit(limit)
+
+		case <-srv.quit:
+			return
+		}
+	}
+}
+
+// setPeerLimit changes the current peer limit, updating the dial scheduler and
+// disconnecting the peers above the new limit if it was lowered.
+func (srv *Server) setPeerLimit(limit int) {
+	atomic.StoreInt32(&srv.peerLimit, int32(limit))
+	adaptivePeerLimitGauge.Update(int64(limit))
+	srv.dialsched.setMaxDialPeers(srv.maxDialedConns())
+
+	// Trusted and static peers are exempt from the limit, drop excess
+	// peers from the rest.
+	var excess []*Peer
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		drop := len(peers) - limit
+		for _, p := range peers {
+			if drop <= 0 {
+				break
+			}
+			if p.rw.is(trustedConn | staticDialedConn) {
+				continue
+			}
+			excess = append(excess, p)
+			drop--
+		}
+	})
+	for _, p := range excess {
+		srv.log.Debug("Dropping peer above adaptive limit", "id", p.ID(), "limit", limit)
+		p.Disconnect(DiscTooManyPeers)
+	}
+}
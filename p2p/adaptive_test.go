@@ -0,0 +1,85 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import "testing"
+
+func TestAdaptiveLimitsNext(t *testing.T) {
+	limits := &adaptiveLimits{minPeers: 10, maxPeers: 50, cpu: 0.8}
+
+	tests := []struct {
+		current  int
+		pressure float64
+		want     int
+	}{
+		{50, 0.7, 50}, // busy but within limits, keep
+		{50, 1.5, 45}, // over the limit, back off
+		{11, 1.5, 10}, // backing off stops at the minimum
+		{10, 2.0, 10}, // already at the minimum
+		{5, 1.1, 10},  // below the minimum, clamp
+		{40, 0.1, 42}, // idle, recover slowly
+		{49, 0.1, 50}, // recovery stops at the maximum
+		{50, 0.0, 50}, // already at the maximum
+	}
+	for i, tt := range tests {
+		if have := limits.next(tt.current, tt.pressure); have != tt.want {
+			t.Errorf("test %d: limit mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+	// Small limits must still change by at least one peer.
+	small := &adaptiveLimits{minPeers: 1, maxPeers: 5}
+	if have := small.next(5, 2); have != 4 {
+		t.Errorf("small limit not lowered: have %d, want 4", have)
+	}
+	if have := small.next(4, 0); have != 5 {
+		t.Errorf("small limit not raised: have %d, want 5", have)
+	}
+}
+
+func TestAdaptiveLimitsPressure(t *testing.T) {
+	limits := &adaptiveLimits{cpu: 0.8, bandwidth: 1000}
+
+	usage := resourceUsage{cpu: 0.4, diskWait: 0.9, bandwidth: 1500}
+	if have := limits.pressure(usage); have != 1.5 {
+		t.Errorf("pressure mismatch: have %v, want 1.5", have)
+	}
+	usage.bandwidth = 100
+	if have := limits.pressure(usage); have != 0.5 {
+		t.Errorf("pressure mismatch: have %v, want 0.5", have)
+	}
+	if have := new(adaptiveLimits).pressure(usage); have != 0 {
+		t.Errorf("pressure without limits: have %v, want 0", have)
+	}
+}
+
+func TestServerAdaptiveLimits(t *testing.T) {
+	srv := &Server{Config: Config{MaxPeers: 50}}
+	if limits := srv.adaptiveLimits(); limits.minPeers != 12 {
+		t.Errorf("default min peers mismatch: have %d, want 12", limits.minPeers)
+	}
+	srv.MaxPeers = 2
+	if limits := srv.adaptiveLimits(); limits.minPeers != 1 {
+		t.Errorf("min peers not clamped: have %d, want 1", limits.minPeers)
+	}
+	if have := srv.maxPeers(); have != 2 {
+		t.Errorf("peer limit mismatch: have %d, want 2", have)
+	}
+	srv.peerLimit = 1
+	if have := srv.maxPeers(); have != 1 {
+		t.Errorf("adaptive peer limit not applied: have %d, want 1", have)
+	}
+}
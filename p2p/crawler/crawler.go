@@ -0,0 +1,318 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package crawler implements a devp2p network crawler.
+//
+// The crawler walks the discovery DHT through the given node iterators, requests
+// the latest record of every node found and optionally probes it with an RLPx
+// protocol handshake. Results are streamed to pluggable sinks and collected in a
+// node set, which can be persisted and passed back in to resume a crawl.
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// defaultProbeWorkers is the number of concurrent protocol handshake probes if no
+// explicit limit is configured.
+const defaultProbeWorkers = 16
+
+// Resolver requests the latest record of a node, e.g. a discv4 or discv5 node.
+type Resolver interface {
+	RequestENR(*enode.Node) (*enode.Node, error)
+}
+
+// Config contains the settings of a crawl.
+type Config struct {
+	Resolver  Resolver         // Used to fetch and validate node records (mandatory)
+	Iterators []enode.Iterator // Sources of new nodes, e.g. DHT random walks
+
+	// RevalidateInterval is the minimum time between two liveness checks of the
+	// same node. Zero means every node is checked whenever found.
+	RevalidateInterval time.Duration
+
+	// Timeout is the time to keep crawling after all input nodes have been
+	// revalidated. Zero means to crawl until all iterators are exhausted.
+	Timeout time.Duration
+
+	Prober       Prober // Optional protocol handshake prober for live nodes
+	ProbeWorkers int    // Number of concurrent probes, defaults to 16
+	Sinks        []Sink // Receivers of crawl results as they are found
+
+	// StateFile, if set, is where the node set is checkpointed every checkpoint
+	// interval and when the crawl ends, allowing an interrupted crawl to resume.
+	StateFile          string
+	CheckpointInterval time.Duration
+
+	Log log.Logger // Logger to use, defaults to the root logger
+}
+
+// Crawler walks the network, tracking the liveness of every node it finds.
+type Crawler struct {
+	config    Config
+	input     NodeSet
+	output    NodeSet
+	iters     []enode.Iterator
+	inputIter enode.Iterator
+	ch        chan *enode.Node
+	closed    chan struct{}
+	log       log.Logger
+
+	// Probes of live nodes are queued by the run loop and handed out to a
+	// bounded set of workers, which send back the probed records.
+	probing    map[enode.ID]struct{}
+	probeQueue []NodeRecord
+	probeCh    chan NodeRecord
+	probeDone  chan NodeRecord
+	probeWG    sync.WaitGroup
+}
+
+// New creates a crawler. The input node set, e.g. the output of a previous crawl
+// loaded with LoadNodeSet, is revalidated first and nodes which fail validation
+// are dropped from the output.
+func New(input NodeSet, config Config) *Crawler {
+	c := &Crawler{
+		config:    config,
+		input:     input,
+		output:    input.copy(),
+		iters:     append([]enode.Iterator{}, config.Iterators...),
+		inputIter: enode.IterNodes(input.Nodes()),
+		ch:        make(chan *enode.Node),
+		closed:    make(chan struct{}),
+		log:       config.Log,
+		probing:   make(map[enode.ID]struct{}),
+		probeCh:   make(chan NodeRecord),
+		probeDone: make(chan NodeRecord),
+	}
+	if c.log == nil {
+		c.log = log.Root()
+	}
+	c.iters = append(c.iters, c.inputIter)
+	return c
+}
+
+// Run crawls until the context is cancelled, the timeout expires after the input
+// has been revalidated, or all iterators are exhausted. It returns the resulting
+// node set. A crawler can only be run once.
+func (c *Crawler) Run(ctx context.Context) NodeSet {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		timeoutTimer = time.NewTimer(c.config.Timeout)
+		timeoutCh    <-chan time.Time
+		checkpointCh <-chan time.Time
+		doneCh       = make(chan enode.Iterator, len(c.iters))
+		liveIters    = len(c.iters)
+	)
+	defer timeoutTimer.Stop()
+	if c.config.StateFile != "" && c.config.CheckpointInterval > 0 {
+		ticker := time.NewTicker(c.config.CheckpointInterval)
+		defer ticker.Stop()
+		checkpointCh = ticker.C
+	}
+	for _, it := range c.iters {
+		go c.runIterator(doneCh, it)
+	}
+	if c.config.Prober != nil {
+		workers := c.config.ProbeWorkers
+		if workers <= 0 {
+			workers = defaultProbeWorkers
+		}
+		c.probeWG.Add(workers)
+		for i := 0; i < workers; i++ {
+			go c.runProber(ctx)
+		}
+	}
+
+loop:
+	for {
+		var (
+			probeCh chan<- NodeRecord
+			next    NodeRecord
+		)
+		if len(c.probeQueue) > 0 {
+			probeCh, next = c.probeCh, c.probeQueue[0]
+		}
+		select {
+		case n := <-c.ch:
+			c.updateNode(n)
+		case probeCh <- next:
+			c.probeQueue = c.probeQueue[1:]
+		case node := <-c.probeDone:
+			delete(c.probing, node.N.ID())
+			c.storeNode(node.N.ID(), node)
+		case it := <-doneCh:
+			if it == c.inputIter {
+				// Enable timeout when we're done revalidating the input nodes.
+				c.log.Info("Revalidation of input set is done", "len", len(c.input))
+				if c.config.Timeout > 0 {
+					timeoutCh = timeoutTimer.C
+				}
+			}
+			liveIters--
+		case <-checkpointCh:
+			c.checkpoint()
+		case <-timeoutCh:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+		// Once all iterators are exhausted, wait for the probes still running.
+		if liveIters == 0 && len(c.probing) == 0 {
+			break
+		}
+	}
+
+	close(c.closed)
+	for _, it := range c.iters {
+		it.Close()
+	}
+	for ; liveIters > 0; liveIters-- {
+		<-doneCh
+	}
+	// Abort running probes and store the nodes still waiting for one without
+	// the probe result.
+	cancel()
+	close(c.probeCh)
+	go func() {
+		c.probeWG.Wait()
+		close(c.probeDone)
+	}()
+	for node := range c.probeDone {
+		c.storeNode(node.N.ID(), node)
+	}
+	for _, node := range c.probeQueue {
+		c.storeNode(node.N.ID(), node)
+	}
+	if c.config.StateFile != "" {
+		c.checkpoint()
+	}
+	return c.output
+}
+
+func (c *Crawler) runIterator(done chan<- enode.Iterator, it enode.Iterator) {
+	defer func() { done <- it }()
+	for it.Next() {
+		select {
+		case c.ch <- it.Node():
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// checkpoint persists the current node set into the state file.
+func (c *Crawler) checkpoint() {
+	if err := c.output.Save(c.config.StateFile); err != nil {
+		c.log.Warn("Failed to save crawl state", "file", c.config.StateFile, "err", err)
+	}
+}
+
+func (c *Crawler) updateNode(n *enode.Node) {
+	// Skip nodes with a probe in flight, they are updated when it's done.
+	if _, ok := c.probing[n.ID()]; ok {
+		return
+	}
+	node, ok := c.output[n.ID()]
+
+	// Skip validation of recently-seen nodes.
+	if ok && time.Since(node.LastCheck) < c.config.RevalidateInterval {
+		return
+	}
+
+	// Request the node record.
+	nn, err := c.config.Resolver.RequestENR(n)
+	node.LastCheck = truncNow()
+	if err != nil {
+		if node.Score == 0 {
+			// Node doesn't implement EIP-868.
+			c.log.Debug("Skipping node", "id", n.ID())
+			return
+		}
+		node.Score /= 2
+	} else {
+		node.N = nn
+		node.Seq = nn.Seq()
+		node.Score++
+		if node.FirstResponse.IsZero() {
+			node.FirstResponse = node.LastCheck
+		}
+		node.LastResponse = node.LastCheck
+
+		// Queue the live node for probing, it is stored once the probe is done.
+		if c.config.Prober != nil {
+			c.probing[n.ID()] = struct{}{}
+			c.probeQueue = append(c.probeQueue, node)
+			return
+		}
+	}
+	c.storeNode(n.ID(), node)
+}
+
+// storeNode stores or updates the node in the output set and reports it to the
+// sinks.
+func (c *Crawler) storeNode(id enode.ID, node NodeRecord) {
+	if node.Score <= 0 {
+		c.log.Info("Removing node", "id", id)
+		delete(c.output, id)
+		for _, sink := range c.config.Sinks {
+			sink.NodeRemoved(id)
+		}
+	} else {
+		c.log.Info("Updating node", "id", id, "seq", node.Seq, "score", node.Score)
+		c.output[id] = node
+		for _, sink := range c.config.Sinks {
+			sink.NodeUpdated(id, node)
+		}
+	}
+}
+
+// runProber probes the nodes handed out by the run loop until the probe channel
+// is closed.
+func (c *Crawler) runProber(ctx context.Context) {
+	defer c.probeWG.Done()
+	for node := range c.probeCh {
+		c.probeNode(ctx, &node)
+		c.probeDone <- node
+	}
+}
+
+// probeNode runs the protocol handshake probe against a live node, if enabled.
+func (c *Crawler) probeNode(ctx context.Context, node *NodeRecord) {
+	if c.config.Prober == nil {
+		return
+	}
+	res, err := c.config.Prober.Probe(ctx, node.N)
+	if err != nil {
+		c.log.Debug("Node probe failed", "id", node.N.ID(), "err", err)
+		return
+	}
+	node.Client = res.Name
+	node.Caps = make([]string, len(res.Caps))
+	for i, cap := range res.Caps {
+		node.Caps[i] = cap.String()
+	}
+}
+
+func truncNow() time.Time {
+	return time.Now().UTC().Truncate(1 * time.Second)
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+// testResolver answers record requests for a fixed set of live nodes.
+type testResolver map[enode.ID]*enode.Node
+
+func (r testResolver) RequestENR(n *enode.Node) (*enode.Node, error) {
+	if nn, ok := r[n.ID()]; ok {
+		return nn, nil
+	}
+	return nil, errors.New("node offline")
+}
+
+func newTestNode(t *testing.T, seq uint64) *enode.Node {
+	key, _ := crypto.GenerateKey()
+	var r enr.Record
+	r.Set(enr.IP(net.IP{127, 0, 0, 1}))
+	r.SetSeq(seq)
+	if err := enode.SignV4(&r, key); err != nil {
+		t.Fatal(err)
+	}
+	n, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// Tests that a crawl picks up live nodes, drops dead ones from the resumed
+// state, reports everything to the sinks and checkpoints the result.
+func TestCrawl(t *testing.T) {
+	var (
+		live    = newTestNode(t, 1)
+		dead    = newTestNode(t, 1)
+		fresh   = newTestNode(t, 2)
+		offline = newTestNode(t, 1)
+		state   = filepath.Join(t.TempDir(), "nodes.json")
+		events  bytes.Buffer
+
+		updated = make(map[enode.ID]bool)
+		removed = make(map[enode.ID]bool)
+	)
+	input := NodeSet{
+		live.ID(): {N: live, Seq: 1, Score: 3},
+		dead.ID(): {N: dead, Seq: 1, Score: 1},
+	}
+	c := New(input, Config{
+		Resolver:  testResolver{live.ID(): live, fresh.ID(): fresh},
+		Iterators: []enode.Iterator{enode.IterNodes([]*enode.Node{fresh, offline})},
+		Sinks: []Sink{
+			NewJSONSink(&events),
+			FuncSink{
+				Updated: func(id enode.ID, node NodeRecord) { updated[id] = true },
+				Removed: func(id enode.ID) { removed[id] = true },
+			},
+		},
+		StateFile: state,
+	})
+	output := c.Run(context.Background())
+
+	if len(output) != 2 {
+		t.Fatalf("wrong output size: have %d, want 2", len(output))
+	}
+	if n := output[live.ID()]; n.Score != 4 || n.LastResponse.IsZero() {
+		t.Errorf("live node not updated: %+v", n)
+	}
+	if n := output[fresh.ID()]; n.Score != 1 || n.Seq != 2 {
+		t.Errorf("fresh node not added: %+v", n)
+	}
+	if !reflect.DeepEqual(updated, map[enode.ID]bool{live.ID(): true, fresh.ID(): true}) {
+		t.Errorf("wrong updated nodes reported: %v", updated)
+	}
+	if !reflect.DeepEqual(removed, map[enode.ID]bool{dead.ID(): true}) {
+		t.Errorf("wrong removed nodes reported: %v", removed)
+	}
+	if lines := strings.Count(events.String(), "\n"); lines != 3 {
+		t.Errorf("wrong number of JSON events: have %d, want 3", lines)
+	}
+	// The checkpoint should allow resuming from the same state
+	saved, err := LoadNodeSet(state)
+	if err != nil {
+		t.Fatalf("failed to load crawl state: %v", err)
+	}
+	if !reflect.DeepEqual(saved.Nodes(), output.Nodes()) {
+		t.Errorf("saved crawl state mismatch")
+	}
+}
+
+// testProber records the number of concurrently running probes.
+type testProber struct {
+	mu        sync.Mutex
+	active    int
+	maxActive int
+}
+
+func (p *testProber) Probe(ctx context.Context, n *enode.Node) (*ProbeResult, error) {
+	p.mu.Lock()
+	p.active++
+	if p.active > p.maxActive {
+		p.maxActive = p.active
+	}
+	p.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	p.mu.Lock()
+	p.active--
+	p.mu.Unlock()
+	return &ProbeResult{Name: "test-client"}, nil
+}
+
+// Tests that live nodes are probed concurrently, but by no more than the
+// configured number of workers.
+func TestCrawlProbeWorkers(t *testing.T) {
+	var (
+		nodes    []*enode.Node
+		resolver = make(testResolver)
+		prober   = new(testProber)
+	)
+	for i := 0; i < 8; i++ {
+		n := newTestNode(t, 1)
+		nodes = append(nodes, n)
+		resolver[n.ID()] = n
+	}
+	c := New(NodeSet{}, Config{
+		Resolver:     resolver,
+		Iterators:    []enode.Iterator{enode.IterNodes(nodes)},
+		Prober:       prober,
+		ProbeWorkers: 3,
+	})
+	output := c.Run(context.Background())
+
+	if len(output) != len(nodes) {
+		t.Fatalf("wrong output size: have %d, want %d", len(output), len(nodes))
+	}
+	for _, n := range nodes {
+		if client := output[n.ID()].Client; client != "test-client" {
+			t.Errorf("node %v not probed: client %q", n.ID(), client)
+		}
+	}
+	if prober.maxActive < 2 || prober.maxActive > 3 {
+		t.Errorf("wrong number of concurrent probes: have %d, want 2-3", prober.maxActive)
+	}
+}
+
+// Tests that the RLPx prober retrieves the client name and capabilities.
+func TestRLPxProbe(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	srv := &p2p.Server{
+		Config: p2p.Config{
+			PrivateKey:  key,
+			Name:        "test-client",
+			MaxPeers:    10,
+			ListenAddr:  "127.0.0.1:0",
+			NoDiscovery: true,
+			Protocols: []p2p.Protocol{{
+				Name:    "test",
+				Version: 1,
+				Length:  1,
+				Run:     func(*p2p.Peer, p2p.MsgReadWriter) error { return nil },
+			}},
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	ourKey, _ := crypto.GenerateKey()
+	prober := &RLPxProber{Key: ourKey}
+	res, err := prober.Probe(context.Background(), srv.Self())
+	if err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	if res.Name != "test-client" {
+		t.Errorf("wrong client name: have %q, want %q", res.Name, "test-client")
+	}
+	if want := []p2p.Cap{{Name: "test", Version: 1}}; !reflect.DeepEqual(res.Caps, want) {
+		t.Errorf("wrong caps: have %v, want %v", res.Caps, want)
+	}
+}
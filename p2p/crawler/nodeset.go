@@ -0,0 +1,97 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// NodeSet is the result of a crawl and the state it can be resumed from. It is
+// stored as a JSON object keyed by node ID (the nodes.json file format).
+type NodeSet map[enode.ID]NodeRecord
+
+// NodeRecord is everything known about a single crawled node.
+type NodeRecord struct {
+	Seq uint64      `json:"seq"`
+	N   *enode.Node `json:"record"`
+
+	// The score tracks how many liveness checks were performed. It is incremented by one
+	// every time the node passes a check, and halved every time it doesn't.
+	Score int `json:"score,omitempty"`
+	// These two track the time of last successful contact.
+	FirstResponse time.Time `json:"firstResponse,omitempty"`
+	LastResponse  time.Time `json:"lastResponse,omitempty"`
+	// This one tracks the time of our last attempt to contact the node.
+	LastCheck time.Time `json:"lastCheck,omitempty"`
+
+	// These are filled in by the protocol handshake probe, if enabled.
+	Client string   `json:"client,omitempty"`
+	Caps   []string `json:"caps,omitempty"`
+}
+
+// LoadNodeSet reads a node set from a JSON file.
+func LoadNodeSet(file string) (NodeSet, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var nodes NodeSet
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Save writes the node set to a JSON file, replacing it atomically.
+func (ns NodeSet) Save(file string) error {
+	data, err := json.MarshalIndent(ns, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// Nodes returns the node records contained in the set, sorted by ID.
+func (ns NodeSet) Nodes() []*enode.Node {
+	result := make([]*enode.Node, 0, len(ns))
+	for _, n := range ns {
+		result = append(result, n.N)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].ID().Bytes(), result[j].ID().Bytes()) < 0
+	})
+	return result
+}
+
+// copy returns a shallow copy of the set.
+func (ns NodeSet) copy() NodeSet {
+	cpy := make(NodeSet, len(ns))
+	for id, n := range ns {
+		cpy[id] = n
+	}
+	return cpy
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package crawler
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// defaultProbeTimeout is the time allowed for a protocol handshake probe if no
+// explicit timeout is configured.
+const defaultProbeTimeout = 10 * time.Second
+
+var errNoTCPEndpoint = errors.New("node has no TCP endpoint")
+
+// Prober checks which client and protocols a node runs.
+type Prober interface {
+	Probe(ctx context.Context, n *enode.Node) (*ProbeResult, error)
+}
+
+// ProbeResult is the information gathered by probing a node.
+type ProbeResult struct {
+	Name string    // Client identifier advertised by the node
+	Caps []p2p.Cap // Protocols supported by the node
+}
+
+// hello is the devp2p protocol handshake message.
+type hello struct {
+	Version    uint64
+	Name       string
+	Caps       []p2p.Cap
+	ListenPort uint64
+	ID         []byte
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// RLPxProber probes nodes by performing the RLPx encryption handshake and reading
+// the devp2p hello message sent by the remote side.
+type RLPxProber struct {
+	Key     *ecdsa.PrivateKey // Key to perform the handshake with
+	Timeout time.Duration     // Time allowed for a single probe
+}
+
+// Probe implements Prober.
+func (p *RLPxProber) Probe(ctx context.Context, n *enode.Node) (*ProbeResult, error) {
+	if n.TCP() == 0 || n.IP() == nil {
+		return nil, errNoTCPEndpoint
+	}
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = defaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	fd, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%v:%d", n.IP(), n.TCP()))
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	deadline, _ := ctx.Deadline()
+	fd.SetDeadline(deadline)
+
+	conn := rlpx.NewConn(fd, n.Pubkey())
+	if _, err := conn.Handshake(p.Key); err != nil {
+		return nil, err
+	}
+	code, data, _, err := conn.Read()
+	if err != nil {
+		return nil, err
+	}
+	switch code {
+	case 0:
+		var h hello
+		if err := rlp.DecodeBytes(data, &h); err != nil {
+			return nil, fmt.Errorf("invalid handshake: %v", err)
+		}
+		return &ProbeResult{Name: h.Name, Caps: h.Caps}, nil
+	case 1:
+		var msg []p2p.DiscReason
+		if rlp.DecodeBytes(data, &msg); len(msg) == 0 {
+			return nil, errors.New("invalid disconnect message")
+		}
+		return nil, fmt.Errorf("received disconnect message: %v", msg[0])
+	default:
+		return nil, fmt.Errorf("invalid message code %d, expected handshake (code zero)", code)
+	}
+}
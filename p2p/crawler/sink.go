@@ -0,0 +1,92 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package crawler
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Sink receives the results of a crawl as they are found. Sinks are invoked from
+// the crawl loop and should not block for long.
+type Sink interface {
+	// NodeUpdated is called when a node passed a liveness check.
+	NodeUpdated(id enode.ID, node NodeRecord)
+
+	// NodeRemoved is called when a node failed too many liveness checks and was
+	// dropped from the node set.
+	NodeRemoved(id enode.ID)
+}
+
+// FuncSink is an adapter to use plain functions as a crawl result sink. Either
+// function may be nil.
+type FuncSink struct {
+	Updated func(id enode.ID, node NodeRecord)
+	Removed func(id enode.ID)
+}
+
+// NodeUpdated implements Sink.
+func (s FuncSink) NodeUpdated(id enode.ID, node NodeRecord) {
+	if s.Updated != nil {
+		s.Updated(id, node)
+	}
+}
+
+// NodeRemoved implements Sink.
+func (s FuncSink) NodeRemoved(id enode.ID) {
+	if s.Removed != nil {
+		s.Removed(id)
+	}
+}
+
+// jsonEvent is a single line written by the JSON sink.
+type jsonEvent struct {
+	Event string      `json:"event"`
+	ID    enode.ID    `json:"id"`
+	Node  *NodeRecord `json:"node,omitempty"`
+}
+
+// JSONSink writes every crawl result as a line of JSON into an output stream.
+type JSONSink struct {
+	enc  *json.Encoder
+	lock sync.Mutex
+}
+
+// NewJSONSink creates a sink streaming crawl results into w.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+// NodeUpdated implements Sink.
+func (s *JSONSink) NodeUpdated(id enode.ID, node NodeRecord) {
+	s.write(jsonEvent{Event: "update", ID: id, Node: &node})
+}
+
+// NodeRemoved implements Sink.
+func (s *JSONSink) NodeRemoved(id enode.ID) {
+	s.write(jsonEvent{Event: "remove", ID: id})
+}
+
+func (s *JSONSink) write(ev jsonEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.enc.Encode(ev)
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 )
 
@@ -46,6 +47,10 @@ const (
 	// Endpoint resolution is throttled with bounded backoff.
 	initialResolveDelay = 60 * time.Second
 	maxResolveDelay     = time.Hour
+
+	// This is the time an IPv6 connection attempt gets before an attempt over IPv4
+	// is raced against it, as recommended by RFC 8305 (happy eyeballs).
+	connectionAttemptDelay = 250 * time.Millisecond
 )
 
 // NodeDialer is used to connect to nodes in the network, typically by using
@@ -63,14 +68,113 @@ type tcpDialer struct {
 	d *net.Dialer
 }
 
+// Dial connects to the node. If the node advertises both an IPv6 and an IPv4
+// endpoint, connection attempts are raced with IPv6 going first.
 func (t tcpDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
-	return t.d.DialContext(ctx, "tcp", nodeAddr(dest).String())
+	addrs := nodeAddrs(dest)
+	if len(addrs) == 1 {
+		return t.d.DialContext(ctx, "tcp", addrs[0].String())
+	}
+	dial := func(ctx context.Context, addr *net.TCPAddr) (net.Conn, error) {
+		return t.d.DialContext(ctx, "tcp", addr.String())
+	}
+	return raceDial(ctx, addrs, connectionAttemptDelay, dial)
 }
 
 func nodeAddr(n *enode.Node) net.Addr {
 	return &net.TCPAddr{IP: n.IP(), Port: n.TCP()}
 }
 
+// nodeAddrs returns the TCP endpoints of a node in the order they should be
+// attempted. IPv6 is preferred over IPv4 if the node advertises both.
+func nodeAddrs(n *enode.Node) []*net.TCPAddr {
+	var (
+		ip4   enr.IPv4
+		ip6   enr.IPv6
+		tcp   enr.TCP
+		tcp6  enr.TCP6
+		addrs []*net.TCPAddr
+	)
+	n.Load(&tcp)
+	if n.Load(&tcp6) != nil {
+		tcp6 = enr.TCP6(tcp)
+	}
+	if n.Load(&ip6) == nil && tcp6 != 0 {
+		addrs = append(addrs, &net.TCPAddr{IP: net.IP(ip6), Port: int(tcp6)})
+	}
+	if n.Load(&ip4) == nil && tcp != 0 {
+		addrs = append(addrs, &net.TCPAddr{IP: net.IP(ip4), Port: int(tcp)})
+	}
+	if len(addrs) == 0 {
+		addrs = append(addrs, nodeAddr(n).(*net.TCPAddr))
+	}
+	return addrs
+}
+
+// raceDial attempts to connect to the given addresses in order, starting the
+// next attempt when the previous one fails or doesn't finish within the attempt
+// delay. The first established connection is returned, all others are closed.
+func raceDial(ctx context.Context, addrs []*net.TCPAddr, delay time.Duration, dial func(context.Context, *net.TCPAddr) (net.Conn, error)) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	var (
+		results = make(chan result, len(addrs))
+		timer   = time.NewTimer(0)
+		started int
+		failed  int
+		lastErr error
+	)
+	defer timer.Stop()
+	<-timer.C
+
+	start := func() {
+		addr := addrs[started]
+		started++
+		go func() {
+			conn, err := dial(ctx, addr)
+			results <- result{conn, err}
+		}()
+		if started < len(addrs) {
+			timer.Reset(delay)
+		}
+	}
+	start()
+	for {
+		select {
+		case res := <-results:
+			if res.err == nil {
+				// Close any connections established by the losing attempts
+				go func(pending int) {
+					for i := 0; i < pending; i++ {
+						if res := <-results; res.err == nil {
+							res.conn.Close()
+						}
+					}
+				}(started - failed - 1)
+				return res.conn, nil
+			}
+			failed++
+			lastErr = res.err
+			if failed == len(addrs) {
+				return nil, lastErr
+			}
+			if started < len(addrs) {
+				if !timer.Stop() {
+					<-timer.C
+				}
+				start()
+			}
+		case <-timer.C:
+			start()
+		}
+	}
+}
+
 // checkDial errors:
 var (
 	errSelf             = errors.New("is self")
@@ -103,6 +207,8 @@ type dialScheduler struct {
 	remStaticCh chan *enode.Node
 	addPeerCh   chan *conn
 	remPeerCh   chan *conn
+	maxDialCh   chan int
+	releaseCh   chan nodeResolver
 
 	// Everything below here belongs to loop and
 	// should only be accessed by code on the loop goroutine.
@@ -135,6 +241,7 @@ type dialConfig struct {
 	maxActiveDials int              // maximum number of active dials
 	netRestrict    *netutil.Netlist // IP netrestrict list, disabled if nil
 	resolver       nodeResolver
+	held           bool // Don't dial until released
 	dialer         NodeDialer
 	log            log.Logger
 	clock          mclock.Clock
@@ -173,6 +280,8 @@ func newDialScheduler(config dialConfig, it enode.Iterator, setupFunc dialSetupF
 		remStaticCh: make(chan *enode.Node),
 		addPeerCh:   make(chan *conn),
 		remPeerCh:   make(chan *conn),
+		maxDialCh:   make(chan int),
+		releaseCh:   make(chan nodeResolver),
 	}
 	d.lastStatsLog = d.clock.Now()
 	d.ctx, d.cancel = context.WithCancel(context.Background())
@@ -204,6 +313,23 @@ func (d *dialScheduler) removeStatic(n *enode.Node) {
 	}
 }
 
+// setMaxDialPeers changes the maximum number of dialed peers.
+func (d *dialScheduler) setMaxDialPeers(n int) {
+	select {
+	case d.maxDialCh <- n:
+	case <-d.ctx.Done():
+	}
+}
+
+// release starts dialing if held back, resolving static nodes with the given
+// resolver from then on.
+func (d *dialScheduler) release(resolver nodeResolver) {
+	select {
+	case d.releaseCh <- resolver:
+	case <-d.ctx.Done():
+	}
+}
+
 // peerAdded updates the peer set.
 func (d *dialScheduler) peerAdded(c *conn) {
 	select {
@@ -230,8 +356,11 @@ func (d *dialScheduler) loop(it enode.Iterator) {
 loop:
 	for {
 		// Launch new dials if slots are available.
-		slots := d.freeDialSlots()
-		slots -= d.startStaticDials(slots)
+		var slots int
+		if !d.held {
+			slots = d.freeDialSlots()
+			slots -= d.startStaticDials(slots)
+		}
 		if slots > 0 {
 			nodesCh = d.nodesIn
 		} else {
@@ -298,6 +427,14 @@ loop:
 				}
 			}
 
+		case n := <-d.maxDialCh:
+			d.log.Trace("Changing dialed peer limit", "old", d.maxDialPeers, "new", n)
+			d.maxDialPeers = n
+
+		case resolver := <-d.releaseCh:
+			d.log.Trace("Releasing held back dials")
+			d.held, d.resolver = false, resolver
+
 		case <-historyExp:
 			d.expireHistory()
 
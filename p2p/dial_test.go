@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/internal/testlog"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 )
 
@@ -394,6 +395,74 @@ func TestDialSchedResolve(t *testing.T) {
 	})
 }
 
+// This test checks that dual-stack nodes are dialed over IPv6 first.
+func TestNodeAddrs(t *testing.T) {
+	var r enr.Record
+	r.Set(enr.IPv4{1, 2, 3, 4})
+	r.Set(enr.TCP(30303))
+	if addrs := nodeAddrs(enode.SignNull(&r, enode.ID{1})); len(addrs) != 1 || addrs[0].String() != "1.2.3.4:30303" {
+		t.Fatalf("wrong IPv4-only addresses: %v", addrs)
+	}
+	r.Set(enr.IPv6(net.ParseIP("2001:db8::1")))
+	if addrs := nodeAddrs(enode.SignNull(&r, enode.ID{1})); len(addrs) != 2 || addrs[0].String() != "[2001:db8::1]:30303" || addrs[1].String() != "1.2.3.4:30303" {
+		t.Fatalf("wrong dual-stack addresses: %v", addrs)
+	}
+	r.Set(enr.TCP6(30304))
+	if addrs := nodeAddrs(enode.SignNull(&r, enode.ID{1})); len(addrs) != 2 || addrs[0].String() != "[2001:db8::1]:30304" {
+		t.Fatalf("wrong dual-stack addresses with tcp6: %v", addrs)
+	}
+}
+
+// This test checks that connection attempts are raced as per RFC 8305.
+func TestRaceDial(t *testing.T) {
+	t.Parallel()
+
+	var (
+		v6 = &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 30303}
+		v4 = &net.TCPAddr{IP: net.IP{1, 2, 3, 4}, Port: 30303}
+	)
+	// An unresponsive IPv6 endpoint should fall back to IPv4 after the delay.
+	start := time.Now()
+	conn, err := raceDial(context.Background(), []*net.TCPAddr{v6, v4}, 50*time.Millisecond, func(ctx context.Context, addr *net.TCPAddr) (net.Conn, error) {
+		if addr == v6 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		c, _ := net.Pipe()
+		return c, nil
+	})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("IPv4 attempted too early: %v", elapsed)
+	}
+	// A failing IPv6 endpoint should fall back to IPv4 immediately.
+	start = time.Now()
+	conn, err = raceDial(context.Background(), []*net.TCPAddr{v6, v4}, time.Minute, func(ctx context.Context, addr *net.TCPAddr) (net.Conn, error) {
+		if addr == v6 {
+			return nil, errors.New("unreachable")
+		}
+		c, _ := net.Pipe()
+		return c, nil
+	})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("IPv4 attempt waited for the delay: %v", elapsed)
+	}
+	// If all attempts fail, an error should be returned.
+	_, err = raceDial(context.Background(), []*net.TCPAddr{v6, v4}, time.Minute, func(ctx context.Context, addr *net.TCPAddr) (net.Conn, error) {
+		return nil, fmt.Errorf("unreachable %v", addr)
+	})
+	if err == nil {
+		t.Fatal("dial succeeded without reachable endpoints")
+	}
+}
+
 // -------
 // Code below here is the framework for the tests above.
 
@@ -19,6 +19,7 @@ package discover
 import (
 	"crypto/ecdsa"
 	"net"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/log"
@@ -67,6 +68,68 @@ func ListenUDP(c UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv4, error) {
 	return ListenV4(c, ln, cfg)
 }
 
+// endpointSelector chooses the UDP endpoint discovery uses to contact a node. The
+// address families it can pick from follow the local socket: sockets bound to the
+// IPv6 wildcard address are dual-stack and contact nodes advertising both families
+// over IPv6 first. Once a write to an IPv6 address fails, the host is assumed to
+// lack IPv6 connectivity and IPv4 is used instead where available.
+type endpointSelector struct {
+	ip4, ip6 bool
+	noIPv6   int32 // set atomically when IPv6 writes fail on a dual-stack socket
+}
+
+func newEndpointSelector(laddr net.Addr) *endpointSelector {
+	s := &endpointSelector{ip4: true}
+	if addr, ok := laddr.(*net.UDPAddr); ok && addr.IP != nil && addr.IP.To4() == nil {
+		s.ip4, s.ip6 = addr.IP.IsUnspecified(), true
+	}
+	return s
+}
+
+// endpoint returns the address to contact n on.
+func (s *endpointSelector) endpoint(n *enode.Node) *net.UDPAddr {
+	var (
+		ip6  enr.IPv6
+		udp6 enr.UDP6
+	)
+	if s.ip6 && n.Load(&ip6) == nil && (!s.ip4 || atomic.LoadInt32(&s.noIPv6) == 0) {
+		if n.Load(&udp6) == nil {
+			return &net.UDPAddr{IP: net.IP(ip6), Port: int(udp6)}
+		}
+		return &net.UDPAddr{IP: net.IP(ip6), Port: n.UDP()}
+	}
+	return &net.UDPAddr{IP: n.IP(), Port: n.UDP()}
+}
+
+// writeFailed records a failed write to addr, falling back to IPv4 if the write
+// was the IPv6 attempt of a dual-stack socket.
+func (s *endpointSelector) writeFailed(addr *net.UDPAddr, log log.Logger) {
+	if s.ip4 && s.ip6 && addr.IP.To4() == nil && atomic.CompareAndSwapInt32(&s.noIPv6, 0, 1) {
+		log.Info("IPv6 unreachable, using IPv4 for dual-stack discovery", "addr", addr)
+	}
+}
+
+// nodeIPFor returns the address of n in the address family of ip, or the
+// default address of n if it doesn't advertise one in that family.
+func nodeIPFor(n *enode.Node, ip net.IP) net.IP {
+	var ip6 enr.IPv6
+	if ip.To4() == nil && n.Load(&ip6) == nil {
+		return net.IP(ip6)
+	}
+	return n.IP()
+}
+
+// checkRelayNode checks that all addresses of n may be relayed by sender.
+func checkRelayNode(sender net.IP, n *enode.Node) error {
+	var ip6 enr.IPv6
+	if n.Load(&ip6) == nil {
+		if err := netutil.CheckRelayIP(sender, net.IP(ip6)); err != nil {
+			return err
+		}
+	}
+	return netutil.CheckRelayIP(sender, n.IP())
+}
+
 // ReadPacket is a packet that couldn't be handled. Those packets are sent to the unhandled
 // channel if configured.
 type ReadPacket struct {
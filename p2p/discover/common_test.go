@@ -0,0 +1,96 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"net"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+// newDualStackNode creates a node advertising both an IPv4 and an IPv6 endpoint.
+func newDualStackNode(t *testing.T) *enode.Node {
+	var r enr.Record
+	r.Set(enr.IPv4(net.IP{203, 0, 113, 1}))
+	r.Set(enr.IPv6(net.ParseIP("2001:db8::1")))
+	r.Set(enr.UDP(30303))
+	r.Set(enr.UDP6(30304))
+	if err := enode.SignV4(&r, newkey()); err != nil {
+		t.Fatal(err)
+	}
+	n, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// This checks that nodes are contacted in the address family of the local socket,
+// preferring IPv6 on dual-stack sockets until it proves unreachable.
+func TestEndpointSelector(t *testing.T) {
+	var (
+		n     = newDualStackNode(t)
+		want4 = &net.UDPAddr{IP: net.IP{203, 0, 113, 1}, Port: 30303}
+		want6 = &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 30304}
+	)
+	tests := []struct {
+		laddr net.Addr
+		want  *net.UDPAddr
+	}{
+		{&net.UDPAddr{IP: net.IP{127, 0, 0, 1}}, want4},
+		{&net.UDPAddr{IP: net.IPv4zero}, want4},
+		{&net.UDPAddr{IP: net.IPv6loopback}, want6},
+		{&net.UDPAddr{IP: net.IPv6unspecified}, want6},
+		{nil, want4},
+	}
+	for _, tt := range tests {
+		if have := newEndpointSelector(tt.laddr).endpoint(n); have.String() != tt.want.String() {
+			t.Errorf("%v: wrong endpoint: have %v, want %v", tt.laddr, have, tt.want)
+		}
+	}
+
+	// Dual-stack sockets fall back to IPv4 once IPv6 writes fail.
+	dual := newEndpointSelector(&net.UDPAddr{IP: net.IPv6unspecified})
+	dual.writeFailed(want4, log.Root())
+	if have := dual.endpoint(n); have.String() != want6.String() {
+		t.Errorf("IPv4 write failure changed endpoint: have %v, want %v", have, want6)
+	}
+	dual.writeFailed(want6, log.Root())
+	if have := dual.endpoint(n); have.String() != want4.String() {
+		t.Errorf("wrong endpoint after IPv6 failure: have %v, want %v", have, want4)
+	}
+	// IPv6-only sockets keep using IPv6.
+	single := newEndpointSelector(&net.UDPAddr{IP: net.IPv6loopback})
+	single.writeFailed(want6, log.Root())
+	if have := single.endpoint(n); have.String() != want6.String() {
+		t.Errorf("IPv6-only socket changed endpoint: have %v, want %v", have, want6)
+	}
+}
+
+// This checks that relayed addresses follow the address family of the requester.
+func TestNodeIPFor(t *testing.T) {
+	n := newDualStackNode(t)
+	if ip := nodeIPFor(n, net.IP{198, 51, 100, 1}); !ip.Equal(net.IP{203, 0, 113, 1}) {
+		t.Errorf("wrong address for IPv4 requester: %v", ip)
+	}
+	if ip := nodeIPFor(n, net.ParseIP("2001:db8::2")); !ip.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("wrong address for IPv6 requester: %v", ip)
+	}
+}
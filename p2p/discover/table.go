@@ -52,6 +52,7 @@ const (
 	// IP address limits.
 	bucketIPLimit, bucketSubnet = 2, 24 // at most 2 addresses from the same /24
 	tableIPLimit, tableSubnet   = 10, 24
+	bucketSubnet6, tableSubnet6 = 48, 48 // IPv6 addresses are limited per /48
 
 	refreshInterval    = 30 * time.Minute
 	revalidateInterval = 10 * time.Second
@@ -69,7 +70,7 @@ type Table struct {
 	buckets [nBuckets]*bucket // index of known nodes by distance
 	nursery []*node           // bootstrap nodes
 	rand    *mrand.Rand       // source of randomness, periodically reseeded
-	ips     netutil.DistinctNetSet
+	ips     ipLimits
 
 	log        log.Logger
 	db         *enode.DB // database of known nodes
@@ -96,7 +97,42 @@ type transport interface {
 type bucket struct {
 	entries      []*node // live entries, sorted by time of last contact
 	replacements []*node // recently seen nodes to be used if revalidation fails
-	ips          netutil.DistinctNetSet
+	ips          ipLimits
+}
+
+// ipLimits limits the number of IP addresses from the same subnet. IPv6 networks
+// are allocated in much larger blocks than IPv4 ones, so each address family is
+// tracked in its own set with its own subnet size.
+type ipLimits struct {
+	ip4, ip6 netutil.DistinctNetSet
+}
+
+func newIPLimits(limit, subnet4, subnet6 uint) ipLimits {
+	return ipLimits{
+		ip4: netutil.DistinctNetSet{Subnet: subnet4, Limit: limit},
+		ip6: netutil.DistinctNetSet{Subnet: subnet6, Limit: limit},
+	}
+}
+
+func (l *ipLimits) set(ip net.IP) *netutil.DistinctNetSet {
+	if ip.To4() != nil {
+		return &l.ip4
+	}
+	return &l.ip6
+}
+
+// Add adds an IP address, returning false if the limit of its subnet is reached.
+func (l *ipLimits) Add(ip net.IP) bool {
+	return l.set(ip).Add(ip)
+}
+
+// Remove removes an IP address.
+func (l *ipLimits) Remove(ip net.IP) {
+	l.set(ip).Remove(ip)
+}
+
+func (l ipLimits) String() string {
+	return l.ip4.String() + " " + l.ip6.String()
 }
 
 func newTable(t transport, db *enode.DB, bootnodes []*enode.Node, log log.Logger) (*Table, error) {
@@ -108,7 +144,7 @@ func newTable(t transport, db *enode.DB, bootnodes []*enode.Node, log log.Logger
 		closeReq:   make(chan struct{}),
 		closed:     make(chan struct{}),
 		rand:       mrand.New(mrand.NewSource(0)),
-		ips:        netutil.DistinctNetSet{Subnet: tableSubnet, Limit: tableIPLimit},
+		ips:        newIPLimits(tableIPLimit, tableSubnet, tableSubnet6),
 		log:        log,
 	}
 	if err := tab.setFallbackNodes(bootnodes); err != nil {
@@ -116,7 +152,7 @@ func newTable(t transport, db *enode.DB, bootnodes []*enode.Node, log log.Logger
 	}
 	for i := range tab.buckets {
 		tab.buckets[i] = &bucket{
-			ips: netutil.DistinctNetSet{Subnet: bucketSubnet, Limit: bucketIPLimit},
+			ips: newIPLimits(bucketIPLimit, bucketSubnet, bucketSubnet6),
 		}
 	}
 	tab.seedRand()
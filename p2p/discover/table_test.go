@@ -30,7 +30,6 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
-	"github.com/ethereum/go-ethereum/p2p/netutil"
 )
 
 func TestTable_pingReplace(t *testing.T) {
@@ -174,12 +173,38 @@ func TestTable_BucketIPLimit(t *testing.T) {
 	checkIPLimitInvariant(t, tab)
 }
 
+// This checks that IPv6 addresses are limited per /48 rather than by the IPv4 subnet size.
+func TestTable_IPLimitIPv6(t *testing.T) {
+	transport := newPingRecorder()
+	tab, db := newTestTable(transport)
+	defer db.Close()
+	defer tab.close()
+
+	// Addresses in distinct /48 networks of the same /24 are all accepted.
+	for i := 0; i < tableIPLimit+1; i++ {
+		ip := net.ParseIP(fmt.Sprintf("2001:db8:%x::1", i+1))
+		tab.addSeenNode(nodeAtDistance(tab.self().ID(), 256-i, ip))
+	}
+	if tab.len() != tableIPLimit+1 {
+		t.Errorf("wrong number of nodes in table: have %d, want %d", tab.len(), tableIPLimit+1)
+	}
+	// Addresses within a single /48 are limited.
+	for i := 0; i < tableIPLimit+1; i++ {
+		ip := net.ParseIP(fmt.Sprintf("2001:db9::%x", i+1))
+		tab.addSeenNode(nodeAtDistance(tab.self().ID(), 256-i, ip))
+	}
+	if tab.len() > 2*tableIPLimit+1 {
+		t.Errorf("too many nodes in table")
+	}
+	checkIPLimitInvariant(t, tab)
+}
+
 // checkIPLimitInvariant checks that ip limit sets contain an entry for every
 // node in the table and no extra entries.
 func checkIPLimitInvariant(t *testing.T, tab *Table) {
 	t.Helper()
 
-	tabset := netutil.DistinctNetSet{Subnet: tableSubnet, Limit: tableIPLimit}
+	tabset := newIPLimits(tableIPLimit, tableSubnet, tableSubnet6)
 	for _, b := range tab.buckets {
 		for _, n := range b.entries {
 			tabset.Add(n.IP())
@@ -72,6 +72,7 @@ type UDPv4 struct {
 	localNode   *enode.LocalNode
 	db          *enode.DB
 	tab         *Table
+	endpoints   *endpointSelector
 	closeOnce   sync.Once
 	wg          sync.WaitGroup
 
@@ -135,6 +136,7 @@ func ListenV4(c UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv4, error) {
 		netrestrict:     cfg.NetRestrict,
 		localNode:       ln,
 		db:              ln.Database(),
+		endpoints:       newEndpointSelector(c.LocalAddr()),
 		gotreply:        make(chan reply),
 		addReplyMatcher: make(chan *replyMatcher),
 		closeCtx:        closeCtx,
@@ -214,7 +216,7 @@ func (t *UDPv4) Ping(n *enode.Node) error {
 
 // ping sends a ping message to the given node and waits for a reply.
 func (t *UDPv4) ping(n *enode.Node) (seq uint64, err error) {
-	rm := t.sendPing(n.ID(), &net.UDPAddr{IP: n.IP(), Port: n.UDP()}, nil)
+	rm := t.sendPing(n.ID(), t.endpoints.endpoint(n), nil)
 	if err = <-rm.errc; err == nil {
 		seq = rm.reply.(*v4wire.Pong).ENRSeq
 	}
@@ -291,7 +293,7 @@ func (t *UDPv4) newLookup(ctx context.Context, targetKey encPubkey) *lookup {
 	target := enode.ID(crypto.Keccak256Hash(targetKey[:]))
 	ekey := v4wire.Pubkey(targetKey)
 	it := newLookup(ctx, t.tab, target, func(n *node) ([]*node, error) {
-		return t.findnode(n.ID(), n.addr(), ekey)
+		return t.findnode(n.ID(), t.endpoints.endpoint(unwrapNode(n)), ekey)
 	})
 	return it
 }
@@ -336,7 +338,7 @@ func (t *UDPv4) findnode(toid enode.ID, toaddr *net.UDPAddr, target v4wire.Pubke
 
 // RequestENR sends ENRRequest to the given node and waits for a response.
 func (t *UDPv4) RequestENR(n *enode.Node) (*enode.Node, error) {
-	addr := &net.UDPAddr{IP: n.IP(), Port: n.UDP()}
+	addr := t.endpoints.endpoint(n)
 	t.ensureBond(n.ID(), addr)
 
 	req := &v4wire.ENRRequest{
@@ -369,7 +371,7 @@ func (t *UDPv4) RequestENR(n *enode.Node) (*enode.Node, error) {
 	if respN.Seq() < n.Seq() {
 		return n, nil // response record is older
 	}
-	if err := netutil.CheckRelayIP(addr.IP, respN.IP()); err != nil {
+	if err := checkRelayNode(addr.IP, respN); err != nil {
 		return nil, fmt.Errorf("invalid IP in response record: %v", err)
 	}
 	return respN, nil
@@ -507,6 +509,9 @@ func (t *UDPv4) send(toaddr *net.UDPAddr, toid enode.ID, req v4wire.Packet) ([]b
 func (t *UDPv4) write(toaddr *net.UDPAddr, toid enode.ID, what string, packet []byte) error {
 	_, err := t.conn.WriteToUDP(packet, toaddr)
 	t.log.Trace(">> "+what, "id", toid, "addr", toaddr, "err", err)
+	if err != nil {
+		t.endpoints.writeFailed(toaddr, t.log)
+	}
 	return err
 }
 
@@ -728,8 +733,13 @@ func (t *UDPv4) handleFindnode(h *packetHandlerV4, from *net.UDPAddr, fromID eno
 	p := v4wire.Neighbors{Expiration: uint64(time.Now().Add(expiration).Unix())}
 	var sent bool
 	for _, n := range closest {
-		if netutil.CheckRelayIP(from.IP, n.IP()) == nil {
-			p.Nodes = append(p.Nodes, nodeToRPC(n))
+		// Relay the address in the requester's family, so dual-stack nodes
+		// are reachable by IPv6-only requesters.
+		ip := nodeIPFor(unwrapNode(n), from.IP)
+		if netutil.CheckRelayIP(from.IP, ip) == nil {
+			rn := nodeToRPC(n)
+			rn.IP = ip
+			p.Nodes = append(p.Nodes, rn)
 		}
 		if len(p.Nodes) == v4wire.MaxNeighbors {
 			t.send(from, fromID, &p)
@@ -71,6 +71,7 @@ type UDPv5 struct {
 	log          log.Logger
 	clock        mclock.Clock
 	validSchemes enr.IdentityScheme
+	endpoints    *endpointSelector
 
 	// talkreq handler registry
 	trlock     sync.Mutex
@@ -109,6 +110,7 @@ type callV5 struct {
 	err          chan error         // errors sent here
 
 	// Valid for active calls only:
+	addr           *net.UDPAddr      // endpoint of the request
 	nonce          v5wire.Nonce      // nonce of request packet
 	handshakeCount int               // # times we attempted handshake for this call
 	challenge      *v5wire.Whoareyou // last sent handshake challenge
@@ -149,6 +151,7 @@ func newUDPv5(conn UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv5, error) {
 		validSchemes: cfg.ValidSchemes,
 		clock:        cfg.Clock,
 		trhandlers:   make(map[string]TalkRequestHandler),
+		endpoints:    newEndpointSelector(conn.LocalAddr()),
 		// channels into dispatch
 		packetInCh:    make(chan ReadPacket, 1),
 		readNextCh:    make(chan struct{}, 1),
@@ -404,10 +407,10 @@ func (t *UDPv5) verifyResponseNode(c *callV5, r *enr.Record, distances []uint, s
 	if err != nil {
 		return nil, err
 	}
-	if err := netutil.CheckRelayIP(c.node.IP(), node.IP()); err != nil {
+	if err := checkRelayNode(c.addr.IP, node); err != nil {
 		return nil, err
 	}
-	if t.netrestrict != nil && !t.netrestrict.Contains(node.IP()) {
+	if t.netrestrict != nil && !t.netrestrict.Contains(t.endpoints.endpoint(node).IP) {
 		return nil, errors.New("not contained in netrestrict list")
 	}
 	if c.node.UDP() <= 1024 {
@@ -586,8 +589,8 @@ func (t *UDPv5) sendCall(c *callV5) {
 		delete(t.activeCallByAuth, c.nonce)
 	}
 
-	addr := &net.UDPAddr{IP: c.node.IP(), Port: c.node.UDP()}
-	newNonce, _ := t.send(c.node.ID(), addr, c.packet, c.challenge)
+	c.addr = t.endpoints.endpoint(c.node)
+	newNonce, _ := t.send(c.node.ID(), c.addr, c.packet, c.challenge)
 	c.nonce = newNonce
 	t.activeCallByAuth[newNonce] = c
 	t.startResponseTimeout(c)
@@ -610,6 +613,9 @@ func (t *UDPv5) send(toID enode.ID, toAddr *net.UDPAddr, packet v5wire.Packet, c
 	}
 	_, err = t.conn.WriteToUDP(enc, toAddr)
 	t.log.Trace(">> "+packet.Name(), "id", toID, "addr", addr)
+	if err != nil {
+		t.endpoints.writeFailed(toAddr, t.log)
+	}
 	return nonce, err
 }
 
@@ -672,7 +678,7 @@ func (t *UDPv5) handleCallResponse(fromID enode.ID, fromAddr *net.UDPAddr, p v5w
 		t.log.Debug(fmt.Sprintf("Unsolicited/late %s response", p.Name()), "id", fromID, "addr", fromAddr)
 		return false
 	}
-	if !fromAddr.IP.Equal(ac.node.IP()) || fromAddr.Port != ac.node.UDP() {
+	if !fromAddr.IP.Equal(ac.addr.IP) || fromAddr.Port != ac.addr.Port {
 		t.log.Debug(fmt.Sprintf("%s from wrong endpoint", p.Name()), "id", fromID, "addr", fromAddr)
 		return false
 	}
@@ -814,7 +820,7 @@ func (t *UDPv5) collectTableNodes(rip net.IP, distances []uint, limit int) []*en
 		// Apply some pre-checks to avoid sending invalid nodes.
 		for _, n := range bn {
 			// TODO livenessChecks > 1
-			if netutil.CheckRelayIP(rip, n.IP()) != nil {
+			if checkRelayNode(rip, n) != nil {
 				continue
 			}
 			nodes = append(nodes, n)
@@ -18,7 +18,9 @@ package enode
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
+	"math"
 	"net"
 	"reflect"
 	"strconv"
@@ -29,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 const (
@@ -41,6 +44,27 @@ const (
 	recordUpdateThrottle = time.Millisecond
 )
 
+var errReservedKey = errors.New("reserved node record key")
+
+// reservedKeys are the record keys maintained by the local node itself, which
+// can't be modified through SetApp and DeleteApp.
+var reservedKeys = map[string]bool{
+	"id": true, "secp256k1": true,
+	"ip": true, "ip6": true,
+	"tcp": true, "tcp6": true,
+	"udp": true, "udp6": true,
+}
+
+// maxEndpoint are the endpoint entries of the largest encoded size. The record
+// size checks reserve room for them, as the local node may set them at any time
+// and can't sign a record exceeding the size limit.
+var maxEndpoint = []enr.Entry{
+	enr.IPv4(net.IP{255, 255, 255, 255}),
+	enr.IPv6(net.IP{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255}),
+	enr.TCP(math.MaxUint16), enr.TCP6(math.MaxUint16),
+	enr.UDP(math.MaxUint16), enr.UDP6(math.MaxUint16),
+}
+
 // LocalNode produces the signed node record of a local node, i.e. a node run in the
 // current process. Setting ENR entries via the Set method updates the record. A new version
 // of the record is signed on demand when the Node method is called.
@@ -175,6 +199,82 @@ func (ln *LocalNode) delete(e enr.Entry) {
 	}
 }
 
+// SetApp puts an application defined entry into the local record, overwriting
+// any existing value. Unlike Set, it refuses to touch the identity and endpoint
+// entries and fails if the signed record would exceed the size limit. Like any
+// other update, the sequence number is bumped when the record is next signed.
+func (ln *LocalNode) SetApp(e enr.Entry) error {
+	key := e.ENRKey()
+	if reservedKeys[key] {
+		return fmt.Errorf("%w %q", errReservedKey, key)
+	}
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	// Check the size of the record with the new entry before committing it
+	old, exists := ln.entries[key]
+	ln.entries[key] = e
+	_, err := ln.recordSize()
+	if exists {
+		ln.entries[key] = old
+	} else {
+		delete(ln.entries, key)
+	}
+	if err != nil {
+		return err
+	}
+	ln.set(e)
+	return nil
+}
+
+// DeleteApp removes an application defined entry from the local record.
+func (ln *LocalNode) DeleteApp(key string) error {
+	if reservedKeys[key] {
+		return fmt.Errorf("%w %q", errReservedKey, key)
+	}
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	ln.delete(enr.WithEntry(key, nil))
+	return nil
+}
+
+// RecordSpace returns the number of bytes still available for new entries in the
+// local record before it reaches the size limit, keeping room for the endpoints.
+func (ln *LocalNode) RecordSpace() int {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	size, err := ln.recordSize()
+	if err != nil {
+		return 0
+	}
+	return enr.SizeLimit - size
+}
+
+// recordSize returns the encoded size of the largest version of the local record,
+// with all endpoint entries set and the highest sequence number.
+//
+// The method assumes that the lock is held!
+func (ln *LocalNode) recordSize() (int, error) {
+	var r enr.Record
+	for _, e := range ln.entries {
+		r.Set(e)
+	}
+	for _, e := range maxEndpoint {
+		r.Set(e)
+	}
+	r.SetSeq(math.MaxUint64)
+	if err := SignV4(&r, ln.key); err != nil {
+		return 0, err
+	}
+	enc, err := rlp.EncodeToBytes(&r)
+	if err != nil {
+		return 0, err
+	}
+	return len(enc), nil
+}
+
 func (ln *LocalNode) endpointForIP(ip net.IP) *lnEndpoint {
 	if ip.To4() != nil {
 		return &ln.endpoint4
@@ -127,3 +127,74 @@ func TestLocalNodeEndpoint(t *testing.T) {
 	assert.Equal(t, fallback.Port, ln.Node().UDP())
 	assert.Equal(t, initialSeq+3, ln.Node().Seq())
 }
+
+// This test checks that application entries are size budgeted and can't override
+// the entries maintained by the local node.
+func TestLocalNodeAppEntries(t *testing.T) {
+	ln, db := newLocalNodeForTesting()
+	defer db.Close()
+
+	seq := ln.Node().Seq()
+	space := ln.RecordSpace()
+	if space <= 0 || space >= enr.SizeLimit {
+		t.Fatalf("invalid initial record space %d", space)
+	}
+	if err := ln.SetApp(enr.WithEntry("app", []byte("hello"))); err != nil {
+		t.Fatal("can't set app entry:", err)
+	}
+	n := ln.Node()
+	if n.Seq() != seq+1 {
+		t.Fatalf("sequence number not bumped: have %d, want %d", n.Seq(), seq+1)
+	}
+	raw, err := n.RawEntry("app")
+	if err != nil {
+		t.Fatal("can't load raw app entry:", err)
+	}
+	assert.Equal(t, []byte{0x85, 'h', 'e', 'l', 'l', 'o'}, []byte(raw))
+
+	// Oversized and reserved entries should be rejected
+	if err := ln.SetApp(enr.WithEntry("big", make([]byte, enr.SizeLimit))); err == nil {
+		t.Fatal("oversized entry accepted")
+	}
+	if err := ln.SetApp(enr.WithEntry("big", make([]byte, ln.RecordSpace()))); err == nil {
+		t.Fatal("entry exceeding the remaining space accepted")
+	}
+	if _, err := ln.Node().RawEntry("big"); !enr.IsNotFound(err) {
+		t.Fatal("rejected entry present in record")
+	}
+	if err := ln.SetApp(enr.IPv4{1, 2, 3, 4}); err == nil {
+		t.Fatal("reserved entry accepted")
+	}
+	if err := ln.DeleteApp("id"); err == nil {
+		t.Fatal("reserved entry deleted")
+	}
+	// Deleting the entry should restore the original budget
+	if err := ln.DeleteApp("app"); err != nil {
+		t.Fatal("can't delete app entry:", err)
+	}
+	if _, err := ln.Node().RawEntry("app"); !enr.IsNotFound(err) {
+		t.Fatal("deleted entry present in record")
+	}
+	if have := ln.RecordSpace(); have != space {
+		t.Fatalf("record space mismatch after delete: have %d, want %d", have, space)
+	}
+}
+
+// Tests that application entries leave room for the endpoint entries set later.
+func TestLocalNodeAppEndpointRoom(t *testing.T) {
+	ln, db := newLocalNodeForTesting()
+	defer db.Close()
+
+	if err := ln.SetApp(enr.WithEntry("big", make([]byte, ln.RecordSpace()-8))); err != nil {
+		t.Fatal("can't set app entry:", err)
+	}
+	ln.Set(enr.IPv4{1, 2, 3, 4})
+	ln.Set(enr.IPv6(net.ParseIP("2001:db8::1")))
+	ln.Set(enr.TCP(30303))
+	ln.Set(enr.TCP6(30303))
+	ln.Set(enr.UDP(30303))
+	ln.Set(enr.UDP6(30303))
+	if ln.Node().TCP() != 30303 {
+		t.Fatal("endpoint not set")
+	}
+}
@@ -99,6 +99,16 @@ func (n *Node) Load(k enr.Entry) error {
 	return n.r.Load(k)
 }
 
+// RawEntry returns the RLP encoded value of the record entry with the given key,
+// which may be an application defined one.
+func (n *Node) RawEntry(key string) (rlp.RawValue, error) {
+	var value rlp.RawValue
+	if err := n.Load(enr.WithEntry(key, &value)); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
 // IP returns the IP address of the node. This prefers IPv4 addresses.
 func (n *Node) IP() net.IP {
 	var (
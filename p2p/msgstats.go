@@ -0,0 +1,134 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// MessageStats contains the accumulated traffic and handling statistics of a
+// single message type of a subprotocol.
+type MessageStats struct {
+	InPackets  uint64        `json:"inPackets"`  // Number of messages received
+	InBytes    uint64        `json:"inBytes"`    // Compressed size of the messages received
+	OutPackets uint64        `json:"outPackets"` // Number of messages sent
+	OutBytes   uint64        `json:"outBytes"`   // Compressed size of the messages sent
+	Handled    uint64        `json:"handled"`    // Number of messages processed by the protocol handler
+	Errors     uint64        `json:"errors"`     // Number of messages the protocol handler failed on
+	HandleTime time.Duration `json:"handleTime"` // Total time spent in the protocol handler
+}
+
+// messageCounters is the live, concurrently updated version of MessageStats.
+type messageCounters struct {
+	inPackets, inBytes   uint64
+	outPackets, outBytes uint64
+	handled, errors      uint64
+	handleTime           int64
+}
+
+// messageKey identifies a message type within a subprotocol.
+type messageKey struct {
+	proto   string
+	version uint
+	code    uint64
+}
+
+// String returns the metrics style name of the message type.
+func (k messageKey) String() string {
+	return fmt.Sprintf("%s/%d/%#02x", k.proto, k.version, k.code)
+}
+
+// protocolStats holds the message statistics of all subprotocols run by this
+// process, keyed by messageKey.
+var protocolStats sync.Map
+
+// messageStats returns the counters of a message type, creating them if needed.
+func messageStats(proto string, version uint, code uint64) *messageCounters {
+	key := messageKey{proto, version, code}
+	if c, ok := protocolStats.Load(key); ok {
+		return c.(*messageCounters)
+	}
+	c, _ := protocolStats.LoadOrStore(key, new(messageCounters))
+	return c.(*messageCounters)
+}
+
+// meterIngress accounts for a received subprotocol message.
+func meterIngress(proto string, version uint, code uint64, size uint32) {
+	c := messageStats(proto, version, code)
+	atomic.AddUint64(&c.inPackets, 1)
+	atomic.AddUint64(&c.inBytes, uint64(size))
+}
+
+// meterEgress accounts for a sent subprotocol message.
+func meterEgress(proto string, version uint, code uint64, size uint32) {
+	c := messageStats(proto, version, code)
+	atomic.AddUint64(&c.outPackets, 1)
+	atomic.AddUint64(&c.outBytes, uint64(size))
+}
+
+// MeterHandle accounts for a subprotocol message having been processed by its
+// handler, which started at the given time and returned the given error. If the
+// metrics system is enabled, the handling time and failures are also reported
+// under HandleHistName.
+func MeterHandle(proto string, version uint, code uint64, start time.Time, err error) {
+	elapsed := time.Since(start)
+
+	c := messageStats(proto, version, code)
+	atomic.AddUint64(&c.handled, 1)
+	atomic.AddInt64(&c.handleTime, int64(elapsed))
+	if err != nil {
+		atomic.AddUint64(&c.errors, 1)
+	}
+	if metrics.Enabled {
+		h := fmt.Sprintf("%s/%s", HandleHistName, messageKey{proto, version, code})
+		sampler := func() metrics.Sample {
+			return metrics.ResettingSample(
+				metrics.NewExpDecaySample(1028, 0.015),
+			)
+		}
+		metrics.GetOrRegisterHistogramLazy(h, nil, sampler).Update(elapsed.Microseconds())
+		if err != nil {
+			metrics.GetOrRegisterMeter(h+"/errors", nil).Mark(1)
+		}
+	}
+}
+
+// ProtocolStats returns the accumulated statistics of every subprotocol message
+// type seen so far, keyed by protocol name, version and message code in the same
+// format as the metrics (e.g. "eth/66/0x05").
+func ProtocolStats() map[string]MessageStats {
+	stats := make(map[string]MessageStats)
+	protocolStats.Range(func(key, value interface{}) bool {
+		c := value.(*messageCounters)
+		stats[key.(messageKey).String()] = MessageStats{
+			InPackets:  atomic.LoadUint64(&c.inPackets),
+			InBytes:    atomic.LoadUint64(&c.inBytes),
+			OutPackets: atomic.LoadUint64(&c.outPackets),
+			OutBytes:   atomic.LoadUint64(&c.outBytes),
+			Handled:    atomic.LoadUint64(&c.handled),
+			Errors:     atomic.LoadUint64(&c.errors),
+			HandleTime: time.Duration(atomic.LoadInt64(&c.handleTime)),
+		}
+		return true
+	})
+	return stats
+}
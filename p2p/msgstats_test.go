@@ -0,0 +1,52 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProtocolStats(t *testing.T) {
+	meterIngress("stats", 1, 0x02, 100)
+	meterIngress("stats", 1, 0x02, 50)
+	meterEgress("stats", 1, 0x02, 10)
+	meterEgress("stats", 1, 0x03, 20)
+
+	start := time.Now().Add(-time.Second)
+	MeterHandle("stats", 1, 0x02, start, nil)
+	MeterHandle("stats", 1, 0x02, start, errors.New("failed"))
+
+	stats := ProtocolStats()
+	have := stats["stats/1/0x02"]
+	if have.InPackets != 2 || have.InBytes != 150 {
+		t.Errorf("ingress mismatch: have %d packets/%d bytes, want 2/150", have.InPackets, have.InBytes)
+	}
+	if have.OutPackets != 1 || have.OutBytes != 10 {
+		t.Errorf("egress mismatch: have %d packets/%d bytes, want 1/10", have.OutPackets, have.OutBytes)
+	}
+	if have.Handled != 2 || have.Errors != 1 {
+		t.Errorf("handling mismatch: have %d handled/%d errors, want 2/1", have.Handled, have.Errors)
+	}
+	if have.HandleTime < 2*time.Second {
+		t.Errorf("handle time too low: have %v, want >= 2s", have.HandleTime)
+	}
+	if other := stats["stats/1/0x03"]; other.OutPackets != 1 || other.OutBytes != 20 || other.InPackets != 0 {
+		t.Errorf("second message type mismatch: %+v", other)
+	}
+}
@@ -75,6 +75,29 @@ type Config struct {
 	// connected. It must be greater than zero.
 	MaxPeers int
 
+	// AdaptivePeers lets the server lower the peer limit, down to MinPeers, while
+	// local resources are under pressure and raise it back up to MaxPeers when
+	// they are idle. The pressure thresholds are set by the Adaptive* limits.
+	AdaptivePeers bool `toml:",omitempty"`
+
+	// MinPeers is the lowest peer limit the adaptive peer count may scale down to.
+	// Zero defaults to a quarter of MaxPeers.
+	MinPeers int `toml:",omitempty"`
+
+	// AdaptiveCPULimit is the fraction of the total system CPU time in use above
+	// which the peer limit is lowered. Zero disables the check.
+	AdaptiveCPULimit float64 `toml:",omitempty"`
+
+	// AdaptiveDiskWaitLimit is the fraction of the total system CPU time spent
+	// waiting on disk IO above which the peer limit is lowered. Zero disables the
+	// check.
+	AdaptiveDiskWaitLimit float64 `toml:",omitempty"`
+
+	// AdaptiveBandwidthLimit is the rate of p2p traffic in bytes per second, both
+	// directions combined, above which the peer limit is lowered. Zero disables
+	// the check.
+	AdaptiveBandwidthLimit uint64 `toml:",omitempty"`
+
 	// MaxPendingPeers is the maximum number of peers that can be pending in the
 	// handshake phase, counted separately for inbound and outbound connections.
 	// Zero defaults to preset values.
@@ -138,6 +161,10 @@ type Config struct {
 
 	// If DiscAddr is set to a non-nil value, the server will use ListenAddr
 	// for TCP and DiscAddr for the UDP discovery protocol.
+	//
+	// Discovery is dual-stack when its address has no host part or uses the
+	// IPv6 wildcard "[::]". Nodes advertising both address families are then
+	// contacted over IPv6 first.
 	DiscAddr string
 
 	// If set to a non-nil value, the given NAT port mapper
@@ -152,6 +179,10 @@ type Config struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
+	// If Standby is true, the server holds back listening, discovery and dialing
+	// when started, until they are enabled with EnableNetworking.
+	Standby bool `toml:"-"`
+
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
@@ -173,8 +204,9 @@ type Server struct {
 	newPeerHook  func(*Peer)
 	listenFunc   func(network, addr string) (net.Listener, error)
 
-	lock    sync.Mutex // protects running
+	lock    sync.Mutex // protects running and held
 	running bool
+	held    bool // Networking held back in standby
 
 	listener     net.Listener
 	ourHandshake *protoHandshake
@@ -201,6 +233,8 @@ type Server struct {
 
 	// State of run loop and listenLoop.
 	inboundHistory expHeap
+
+	peerLimit int32 // Current adaptive peer limit, zero if MaxPeers applies (atomic)
 }
 
 type peerOpFunc func(map[enode.ID]*Peer)
@@ -454,6 +488,7 @@ func (srv *Server) Start() (err error) {
 	if srv.NoDial && srv.ListenAddr == "" {
 		srv.log.Warn("P2P server will be useless, neither dialing nor listening")
 	}
+	srv.held = srv.Standby
 
 	// static fields
 	if srv.PrivateKey == nil {
@@ -477,18 +512,23 @@ func (srv *Server) Start() (err error) {
 	if err := srv.setupLocalNode(); err != nil {
 		return err
 	}
-	if srv.ListenAddr != "" {
-		if err := srv.setupListening(); err != nil {
+	srv.discmix = enode.NewFairMix(discmixTimeout)
+	if !srv.held {
+		if err := srv.setupNetworking(); err != nil {
 			return err
 		}
-	}
-	if err := srv.setupDiscovery(); err != nil {
-		return err
+	} else {
+		srv.log.Info("Holding back peer-to-peer networking until enabled")
 	}
 	srv.setupDialScheduler()
 
 	srv.loopWG.Add(1)
 	go srv.run()
+
+	if srv.AdaptivePeers && srv.MaxPeers > 0 {
+		srv.loopWG.Add(1)
+		go srv.adaptPeersLoop()
+	}
 	return nil
 }
 
@@ -536,9 +576,45 @@ func (srv *Server) setupLocalNode() error {
 	return nil
 }
 
-func (srv *Server) setupDiscovery() error {
-	srv.discmix = enode.NewFairMix(discmixTimeout)
+// EnableNetworking starts the listening, discovery and dialing held back by the
+// Standby option on a running server.
+func (srv *Server) EnableNetworking() error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
 
+	if !srv.running {
+		return errServerStopped
+	}
+	if !srv.held {
+		return errors.New("networking already enabled")
+	}
+	if err := srv.setupNetworking(); err != nil {
+		return err
+	}
+	srv.held = false
+	srv.dialsched.release(srv.resolver())
+	srv.log.Info("Enabled peer-to-peer networking")
+	return nil
+}
+
+// setupNetworking starts listening for connections and discovering peers.
+func (srv *Server) setupNetworking() error {
+	if srv.ListenAddr != "" {
+		if err := srv.setupListening(); err != nil {
+			return err
+		}
+	}
+	if err := srv.setupDiscovery(); err != nil {
+		if srv.listener != nil {
+			srv.listener.Close()
+			srv.listener = nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (srv *Server) setupDiscovery() error {
 	// Add protocol-specific discovery sources.
 	added := make(map[string]bool)
 	for _, proto := range srv.Protocols {
@@ -635,9 +711,8 @@ func (srv *Server) setupDialScheduler() {
 		netRestrict:    srv.NetRestrict,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
-	}
-	if srv.ntab != nil {
-		config.resolver = srv.ntab
+		held:           srv.held,
+		resolver:       srv.resolver(),
 	}
 	if config.dialer == nil {
 		config.dialer = tcpDialer{&net.Dialer{Timeout: defaultDialTimeout}}
@@ -648,18 +723,37 @@ func (srv *Server) setupDialScheduler() {
 	}
 }
 
+// resolver returns the node resolver of the dial scheduler, which is the
+// discovery table if running.
+func (srv *Server) resolver() nodeResolver {
+	if srv.ntab == nil {
+		return nil
+	}
+	return srv.ntab
+}
+
+// maxPeers returns the current peer limit, which is below MaxPeers while the
+// adaptive peer count is scaled down.
+func (srv *Server) maxPeers() int {
+	if limit := atomic.LoadInt32(&srv.peerLimit); limit > 0 {
+		return int(limit)
+	}
+	return srv.MaxPeers
+}
+
 func (srv *Server) maxInboundConns() int {
-	return srv.MaxPeers - srv.maxDialedConns()
+	return srv.maxPeers() - srv.maxDialedConns()
 }
 
 func (srv *Server) maxDialedConns() (limit int) {
-	if srv.NoDial || srv.MaxPeers == 0 {
+	maxPeers := srv.maxPeers()
+	if srv.NoDial || maxPeers == 0 {
 		return 0
 	}
 	if srv.DialRatio == 0 {
-		limit = srv.MaxPeers / defaultDialRatio
+		limit = maxPeers / defaultDialRatio
 	} else {
-		limit = srv.MaxPeers / srv.DialRatio
+		limit = maxPeers / srv.DialRatio
 	}
 	if limit == 0 {
 		limit = 1
@@ -814,7 +908,7 @@ running:
 
 func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
 	switch {
-	case !c.is(trustedConn) && len(peers) >= srv.MaxPeers:
+	case !c.is(trustedConn) && len(peers) >= srv.maxPeers():
 		return DiscTooManyPeers
 	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
 		return DiscTooManyPeers
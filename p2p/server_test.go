@@ -123,6 +123,50 @@ func TestServerListen(t *testing.T) {
 	}
 }
 
+// Tests that networking held back in standby is started on the running server,
+// keeping the local node record.
+func TestServerStandby(t *testing.T) {
+	srv := &Server{Config: Config{
+		Name:        "test",
+		MaxPeers:    10,
+		ListenAddr:  "127.0.0.1:0",
+		NoDiscovery: true,
+		Standby:     true,
+		PrivateKey:  newkey(),
+		Logger:      testlog.Logger(t, log.LvlTrace),
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start server: %v", err)
+	}
+	defer srv.Stop()
+
+	if srv.listener != nil || srv.Self().TCP() != 0 {
+		t.Fatal("standby server listening")
+	}
+	ln := srv.LocalNode()
+	ln.Set(enr.WithEntry("app", uint(1)))
+
+	if err := srv.EnableNetworking(); err != nil {
+		t.Fatalf("could not enable networking: %v", err)
+	}
+	if srv.LocalNode() != ln {
+		t.Fatal("local node replaced")
+	}
+	var app uint
+	if err := srv.Self().Load(enr.WithEntry("app", &app)); err != nil || app != 1 {
+		t.Fatalf("local node entry lost: %v", err)
+	}
+	conn, err := net.DialTimeout("tcp", srv.ListenAddr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	conn.Close()
+
+	if err := srv.EnableNetworking(); err == nil {
+		t.Fatal("networking enabled twice")
+	}
+}
+
 func TestServerDial(t *testing.T) {
 	// run a one-shot TCP server to handle the connection.
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
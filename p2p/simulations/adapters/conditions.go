@@ -0,0 +1,158 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package adapters
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// minRetransmitTimeout is the minimum delay incurred by a simulated lost packet,
+// mirroring the minimum TCP retransmission timeout.
+const minRetransmitTimeout = 200 * time.Millisecond
+
+// errLinkBlocked is returned when dialing or writing over a partitioned link.
+var errLinkBlocked = errors.New("simulated link blocked")
+
+// LinkConditions describes the simulated quality of the network link between
+// two nodes. The zero value is a perfect link.
+type LinkConditions struct {
+	Latency    time.Duration // One-way delay added to every write
+	Jitter     time.Duration // Maximum random delay added on top of the latency
+	PacketLoss float64       // Probability of a write being lost and retransmitted
+	Blocked    bool          // Whether the link is partitioned away entirely
+}
+
+// delay returns the time a single write should be held back.
+func (c LinkConditions) delay() time.Duration {
+	delay := c.Latency
+	if c.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(c.Jitter)))
+	}
+	if c.PacketLoss > 0 && rand.Float64() < c.PacketLoss {
+		rto := 3 * c.Latency
+		if rto < minRetransmitTimeout {
+			rto = minRetransmitTimeout
+		}
+		delay += rto
+	}
+	return delay
+}
+
+// LinkConditionsFunc returns the current link conditions for traffic flowing
+// from one node to another.
+type LinkConditionsFunc func(from, to enode.ID) LinkConditions
+
+// SetLinkConditions installs a callback to shape the traffic of all simulated
+// connections, live ones included. Connections whose link becomes blocked are
+// torn down immediately and cannot be re-established until unblocked. Passing
+// nil restores perfect links.
+func (s *SimAdapter) SetLinkConditions(fn LinkConditionsFunc) {
+	s.mtx.Lock()
+	s.conditions = fn
+
+	var blocked []*conditionedConn
+	for conn := range s.conns {
+		if s.linkConditions(conn.from, conn.to).Blocked {
+			blocked = append(blocked, conn)
+		}
+	}
+	s.mtx.Unlock()
+
+	for _, conn := range blocked {
+		conn.Close()
+	}
+}
+
+// linkConditions returns the current conditions of the link between two nodes.
+//
+// The method assumes that the adapter lock is held!
+func (s *SimAdapter) linkConditions(from, to enode.ID) LinkConditions {
+	if s.conditions == nil {
+		return LinkConditions{}
+	}
+	return s.conditions(from, to)
+}
+
+// simDialer implements p2p.NodeDialer on behalf of a single simulated node, so
+// that the link conditions of its outbound connections can be resolved.
+type simDialer struct {
+	adapter *SimAdapter
+	self    enode.ID
+}
+
+// Dial implements p2p.NodeDialer.
+func (d *simDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	return d.adapter.dial(ctx, d.self, dest)
+}
+
+// conditionedConn wraps one end of a simulated connection, delaying or failing
+// writes according to the current conditions of its link.
+type conditionedConn struct {
+	net.Conn
+	adapter  *SimAdapter
+	from, to enode.ID
+
+	lock sync.Mutex // Serializes writes so delays don't reorder the stream
+	once sync.Once
+}
+
+// newConditionedConn wraps a connection end and starts tracking it.
+//
+// The method assumes that the adapter lock is held!
+func (s *SimAdapter) newConditionedConn(conn net.Conn, from, to enode.ID) *conditionedConn {
+	c := &conditionedConn{Conn: conn, adapter: s, from: from, to: to}
+	s.conns[c] = struct{}{}
+	return c
+}
+
+// Write delays the data by the link latency before passing it on, or tears the
+// connection down if the link is blocked.
+func (c *conditionedConn) Write(b []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.adapter.mtx.RLock()
+	conditions := c.adapter.linkConditions(c.from, c.to)
+	c.adapter.mtx.RUnlock()
+
+	if conditions.Blocked {
+		c.Close()
+		return 0, errLinkBlocked
+	}
+	if delay := conditions.delay(); delay > 0 {
+		time.Sleep(delay)
+	}
+	return c.Conn.Write(b)
+}
+
+// Close closes the underlying connection and stops tracking it.
+func (c *conditionedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		c.adapter.mtx.Lock()
+		delete(c.adapter.conns, c)
+		c.adapter.mtx.Unlock()
+	})
+	return err
+}
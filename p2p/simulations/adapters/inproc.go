@@ -41,6 +41,9 @@ type SimAdapter struct {
 	mtx        sync.RWMutex
 	nodes      map[enode.ID]*SimNode
 	lifecycles LifecycleConstructors
+
+	conditions LinkConditionsFunc            // Optional traffic shaping of simulated links
+	conns      map[*conditionedConn]struct{} // Live connection ends, torn down on partitions
 }
 
 // NewSimAdapter creates a SimAdapter which is capable of running in-memory
@@ -52,6 +55,7 @@ func NewSimAdapter(services LifecycleConstructors) *SimAdapter {
 		pipe:       pipes.NetPipe,
 		nodes:      make(map[enode.ID]*SimNode),
 		lifecycles: services,
+		conns:      make(map[*conditionedConn]struct{}),
 	}
 }
 
@@ -96,7 +100,7 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 			PrivateKey:      config.PrivateKey,
 			MaxPeers:        math.MaxInt32,
 			NoDiscovery:     true,
-			Dialer:          s,
+			Dialer:          &simDialer{adapter: s, self: id},
 			EnableMsgEvents: config.EnableMsgEvents,
 		},
 		ExternalSigner: config.ExternalSigner,
@@ -120,6 +124,12 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 // Dial implements the p2p.NodeDialer interface by connecting to the node using
 // an in-memory net.Pipe
 func (s *SimAdapter) Dial(ctx context.Context, dest *enode.Node) (conn net.Conn, err error) {
+	return s.dial(ctx, enode.ID{}, dest)
+}
+
+// dial connects the source node to the destination node, applying the link
+// conditions between the two to both ends of the connection.
+func (s *SimAdapter) dial(ctx context.Context, src enode.ID, dest *enode.Node) (conn net.Conn, err error) {
 	node, ok := s.GetNode(dest.ID())
 	if !ok {
 		return nil, fmt.Errorf("unknown node: %s", dest.ID())
@@ -133,6 +143,17 @@ func (s *SimAdapter) Dial(ctx context.Context, dest *enode.Node) (conn net.Conn,
 	if err != nil {
 		return nil, err
 	}
+	s.mtx.Lock()
+	if s.linkConditions(src, dest.ID()).Blocked {
+		s.mtx.Unlock()
+		pipe1.Close()
+		pipe2.Close()
+		return nil, errLinkBlocked
+	}
+	pipe1 = s.newConditionedConn(pipe1, dest.ID(), src)
+	pipe2 = s.newConditionedConn(pipe2, src, dest.ID())
+	s.mtx.Unlock()
+
 	// this is simulated 'listening'
 	// asynchronously call the dialed destination node's p2p server
 	// to set up connection on the 'listening' side
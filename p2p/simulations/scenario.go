@@ -0,0 +1,450 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// assertionPollInterval is the frequency at which unmet scenario assertions
+// are re-evaluated until their timeout.
+const assertionPollInterval = 100 * time.Millisecond
+
+// Duration is a time.Duration which is JSON encoded as a string like "1m30s".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(input []byte) error {
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		return err
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+// Scenario is a declarative description of a simulated network run: the nodes
+// and topology to create, the conditions of the links between them, the churn
+// and partitions to inject over time and the assertions to check at the end.
+type Scenario struct {
+	Nodes    int      `json:"nodes"`             // Number of nodes to create
+	Service  string   `json:"service,omitempty"` // Service to run (defaults to the network's default)
+	Topology string   `json:"topology"`          // Initial topology: ring, chain, star or full
+	Duration Duration `json:"duration"`          // Duration of the fault injection phase
+
+	Link       LinkProfile      `json:"link"`                 // Conditions of every link
+	Churn      *ChurnProfile    `json:"churn,omitempty"`      // Optional node outages
+	Partitions []PartitionEvent `json:"partitions,omitempty"` // Network splits over time
+	Assertions []Assertion      `json:"assertions,omitempty"` // Checks to run at the end
+}
+
+// LinkProfile describes the latency and packet loss distribution of links.
+type LinkProfile struct {
+	Latency    Duration `json:"latency,omitempty"`    // Base one-way latency
+	Jitter     Duration `json:"jitter,omitempty"`     // Uniformly distributed extra latency
+	PacketLoss float64  `json:"packetLoss,omitempty"` // Probability of a retransmission
+}
+
+// ChurnProfile describes the rate at which nodes drop off the network and come
+// back. Churned nodes keep running but all their links are cut for the downtime.
+type ChurnProfile struct {
+	Interval Duration `json:"interval"` // Time between churn rounds
+	Rate     float64  `json:"rate"`     // Fraction of the online nodes taken offline per round
+	Downtime Duration `json:"downtime"` // Time a churned node stays offline
+}
+
+// PartitionEvent splits the network into isolated groups of nodes for a period
+// of time. Groups reference nodes by their index in the scenario; nodes absent
+// from all groups form one additional group together.
+type PartitionEvent struct {
+	Start  Duration `json:"start"` // Offset from the scenario start
+	End    Duration `json:"end"`   // Offset at which the partition heals
+	Groups [][]int  `json:"groups"`
+}
+
+// Assertion kinds supported by scenarios.
+const (
+	AssertMinPeers   = "minPeers"   // Every live node has at least Value peers
+	AssertMinUpNodes = "minUpNodes" // At least Value nodes are live and online
+)
+
+// Assertion is a condition the network must reach after the fault injection
+// phase, within the given timeout.
+type Assertion struct {
+	Kind    string   `json:"kind"`
+	Value   int      `json:"value"`
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// ScenarioResult is the outcome of running a scenario.
+type ScenarioResult struct {
+	Nodes      []enode.ID `json:"nodes"`
+	Outages    int        `json:"outages"`
+	Partitions int        `json:"partitions"`
+	Failures   []string   `json:"failures,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt time.Time  `json:"finishedAt"`
+}
+
+// Passed returns whether all the assertions of the scenario held.
+func (r *ScenarioResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// LoadScenario reads a JSON encoded scenario and validates it.
+func LoadScenario(r io.Reader) (*Scenario, error) {
+	scenario := new(Scenario)
+	if err := json.NewDecoder(r).Decode(scenario); err != nil {
+		return nil, err
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+	return scenario, nil
+}
+
+// Validate checks the scenario for consistency.
+func (s *Scenario) Validate() error {
+	if s.Nodes < 1 {
+		return errors.New("scenario needs at least one node")
+	}
+	switch s.Topology {
+	case "", "ring", "chain", "star", "full":
+	default:
+		return fmt.Errorf("unknown topology %q", s.Topology)
+	}
+	if s.Link.PacketLoss < 0 || s.Link.PacketLoss > 1 {
+		return fmt.Errorf("invalid packet loss %v", s.Link.PacketLoss)
+	}
+	if s.Churn != nil && (s.Churn.Interval <= 0 || s.Churn.Rate < 0 || s.Churn.Rate > 1) {
+		return errors.New("invalid churn profile")
+	}
+	for i, p := range s.Partitions {
+		if p.End <= p.Start {
+			return fmt.Errorf("partition %d heals before it starts", i)
+		}
+		for _, group := range p.Groups {
+			for _, idx := range group {
+				if idx < 0 || idx >= s.Nodes {
+					return fmt.Errorf("partition %d references unknown node %d", i, idx)
+				}
+			}
+		}
+	}
+	for _, a := range s.Assertions {
+		switch a.Kind {
+		case AssertMinPeers, AssertMinUpNodes:
+		default:
+			return fmt.Errorf("unknown assertion %q", a.Kind)
+		}
+	}
+	return nil
+}
+
+// scenarioEvent is a timed action of a running scenario.
+type scenarioEvent struct {
+	at     time.Duration
+	action func()
+}
+
+// scenarioRun is the state of a scenario being executed on a network.
+type scenarioRun struct {
+	scenario *Scenario
+	net      *Network
+	result   *ScenarioResult
+
+	start  time.Time                // Time the fault injection phase started
+	events []scenarioEvent          // Pending timed events, sorted by time
+	groups map[int]map[enode.ID]int // Node groups of the active partitions by index
+	down   map[enode.ID]struct{}    // Nodes taken offline by churn
+	lock   sync.RWMutex             // Protects the active partitions and churned nodes
+}
+
+// RunScenario creates the nodes of the scenario in the network, injects the
+// described faults and finally evaluates the assertions. Since faults are
+// injected by shaping links, the network must use a SimAdapter.
+func (net *Network) RunScenario(ctx context.Context, scenario *Scenario) (*ScenarioResult, error) {
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+	run := &scenarioRun{
+		scenario: scenario,
+		net:      net,
+		result:   &ScenarioResult{StartedAt: time.Now()},
+		groups:   make(map[int]map[enode.ID]int),
+		down:     make(map[enode.ID]struct{}),
+	}
+	defer func() { run.result.FinishedAt = time.Now() }()
+
+	// Install the link conditions before any connection is made
+	sim, ok := net.nodeAdapter.(*adapters.SimAdapter)
+	if !ok {
+		return nil, fmt.Errorf("scenarios unsupported by %s", net.nodeAdapter.Name())
+	}
+	sim.SetLinkConditions(run.linkConditions)
+	defer sim.SetLinkConditions(nil)
+
+	if err := run.setup(); err != nil {
+		return nil, err
+	}
+	// Schedule all the faults and run them until the scenario ends
+	for i, p := range scenario.Partitions {
+		i, p := i, p
+		run.schedule(time.Duration(p.Start), func() { run.partition(sim, i, p.Groups) })
+		run.schedule(time.Duration(p.End), func() { run.heal(sim, i) })
+	}
+	if scenario.Churn != nil {
+		run.schedule(time.Duration(scenario.Churn.Interval), func() { run.churn(sim) })
+	}
+	run.start = time.Now()
+	for len(run.events) > 0 && run.events[0].at < time.Duration(scenario.Duration) {
+		next := run.events[0]
+		run.events = run.events[1:]
+
+		select {
+		case <-time.After(time.Until(run.start.Add(next.at))):
+			next.action()
+		case <-ctx.Done():
+			return run.result, ctx.Err()
+		}
+	}
+	select {
+	case <-time.After(time.Until(run.start.Add(time.Duration(scenario.Duration)))):
+	case <-ctx.Done():
+		return run.result, ctx.Err()
+	}
+	// Bring back any churned nodes, heal the network and check the assertions
+	run.lock.Lock()
+	run.groups = make(map[int]map[enode.ID]int)
+	run.down = make(map[enode.ID]struct{})
+	run.lock.Unlock()
+
+	for _, a := range scenario.Assertions {
+		if err := run.check(ctx, a); err != nil {
+			run.result.Failures = append(run.result.Failures, err.Error())
+		}
+	}
+	return run.result, nil
+}
+
+// setup creates, starts and connects the nodes of the scenario.
+func (run *scenarioRun) setup() error {
+	for i := 0; i < run.scenario.Nodes; i++ {
+		conf := adapters.RandomNodeConfig()
+		if run.scenario.Service != "" {
+			conf.Lifecycles = []string{run.scenario.Service}
+		}
+		node, err := run.net.NewNodeWithConfig(conf)
+		if err != nil {
+			return err
+		}
+		if err := run.net.Start(node.ID()); err != nil {
+			return err
+		}
+		run.result.Nodes = append(run.result.Nodes, node.ID())
+	}
+	ids := run.result.Nodes
+	switch run.scenario.Topology {
+	case "chain":
+		return run.net.ConnectNodesChain(ids)
+	case "star":
+		return run.net.ConnectNodesStar(ids[1:], ids[0])
+	case "full":
+		return run.net.ConnectNodesFull(ids)
+	default:
+		return run.net.ConnectNodesRing(ids)
+	}
+}
+
+// schedule queues up an action to run at the given offset from the start.
+func (run *scenarioRun) schedule(at time.Duration, action func()) {
+	run.events = append(run.events, scenarioEvent{at: at, action: action})
+	sort.SliceStable(run.events, func(i, j int) bool {
+		return run.events[i].at < run.events[j].at
+	})
+}
+
+// linkConditions implements adapters.LinkConditionsFunc, blocking the links
+// crossing any active partition.
+func (run *scenarioRun) linkConditions(from, to enode.ID) adapters.LinkConditions {
+	conditions := adapters.LinkConditions{
+		Latency:    time.Duration(run.scenario.Link.Latency),
+		Jitter:     time.Duration(run.scenario.Link.Jitter),
+		PacketLoss: run.scenario.Link.PacketLoss,
+	}
+	run.lock.RLock()
+	defer run.lock.RUnlock()
+
+	if _, ok := run.down[from]; ok {
+		conditions.Blocked = true
+	}
+	if _, ok := run.down[to]; ok {
+		conditions.Blocked = true
+	}
+	for _, groups := range run.groups {
+		// Unknown nodes (e.g. dial sources not known) map to the default group
+		if groups[from] != groups[to] {
+			conditions.Blocked = true
+			break
+		}
+	}
+	return conditions
+}
+
+// partition activates a network split between the given node groups.
+func (run *scenarioRun) partition(sim *adapters.SimAdapter, index int, groups [][]int) {
+	assignment := make(map[enode.ID]int)
+	for i, group := range groups {
+		for _, idx := range group {
+			assignment[run.result.Nodes[idx]] = i + 1
+		}
+	}
+	log.Info("Partitioning simulated network", "groups", len(groups)+1)
+
+	run.lock.Lock()
+	run.groups[index] = assignment
+	run.lock.Unlock()
+
+	run.result.Partitions++
+	sim.SetLinkConditions(run.linkConditions)
+}
+
+// heal deactivates a previously created network split.
+func (run *scenarioRun) heal(sim *adapters.SimAdapter, index int) {
+	log.Info("Healing simulated network partition")
+
+	run.lock.Lock()
+	delete(run.groups, index)
+	run.lock.Unlock()
+
+	sim.SetLinkConditions(run.linkConditions)
+}
+
+// churn takes a fraction of the online nodes offline and schedules them to
+// come back after the downtime, then schedules the next churn round.
+func (run *scenarioRun) churn(sim *adapters.SimAdapter) {
+	var (
+		profile = run.scenario.Churn
+		online  []enode.ID
+		now     = run.elapsed()
+	)
+	run.lock.Lock()
+	for _, id := range run.result.Nodes {
+		if _, ok := run.down[id]; !ok {
+			online = append(online, id)
+		}
+	}
+	count := int(math.Ceil(profile.Rate * float64(len(online))))
+	for _, i := range rand.Perm(len(online))[:count] {
+		id := online[i]
+		log.Info("Taking simulated node offline", "id", id)
+		run.down[id] = struct{}{}
+		run.result.Outages++
+
+		run.schedule(now+time.Duration(profile.Downtime), func() {
+			log.Info("Bringing simulated node online", "id", id)
+			run.lock.Lock()
+			delete(run.down, id)
+			run.lock.Unlock()
+			sim.SetLinkConditions(run.linkConditions)
+		})
+	}
+	run.lock.Unlock()
+
+	sim.SetLinkConditions(run.linkConditions)
+	run.schedule(now+time.Duration(profile.Interval), func() { run.churn(sim) })
+}
+
+// elapsed returns the time passed since the fault injection phase started.
+func (run *scenarioRun) elapsed() time.Duration {
+	return time.Since(run.start)
+}
+
+// check polls the network until the assertion holds or its timeout expires.
+func (run *scenarioRun) check(ctx context.Context, a Assertion) error {
+	deadline := time.Now().Add(time.Duration(a.Timeout))
+	for {
+		err := run.evaluate(a)
+		if err == nil || !time.Now().Before(deadline) {
+			return err
+		}
+		select {
+		case <-time.After(assertionPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// evaluate checks the assertion against the current state of the network.
+func (run *scenarioRun) evaluate(a Assertion) error {
+	switch a.Kind {
+	case AssertMinUpNodes:
+		run.net.lock.RLock()
+		up := len(run.net.getUpNodeIDs())
+		run.net.lock.RUnlock()
+
+		run.lock.RLock()
+		up -= len(run.down)
+		run.lock.RUnlock()
+
+		if up < a.Value {
+			return fmt.Errorf("%s: have %d live nodes, want at least %d", a.Kind, up, a.Value)
+		}
+	case AssertMinPeers:
+		for _, node := range run.net.GetNodes() {
+			if !node.Up() {
+				continue
+			}
+			client, err := node.Client()
+			if err != nil {
+				return err
+			}
+			var peers []*p2p.PeerInfo
+			if err := client.Call(&peers, "admin_peers"); err != nil {
+				return err
+			}
+			if len(peers) < a.Value {
+				return fmt.Errorf("%s: node %s has %d peers, want at least %d", a.Kind, node.ID().TerminalString(), len(peers), a.Value)
+			}
+		}
+	}
+	return nil
+}
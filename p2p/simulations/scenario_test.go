@@ -0,0 +1,162 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulations
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+func TestLoadScenario(t *testing.T) {
+	scenario, err := LoadScenario(strings.NewReader(`{
+		"nodes": 4,
+		"topology": "full",
+		"duration": "1s",
+		"link": {"latency": "10ms", "jitter": "5ms", "packetLoss": 0.01},
+		"churn": {"interval": "200ms", "rate": 0.25, "downtime": "100ms"},
+		"partitions": [{"start": "100ms", "end": "500ms", "groups": [[0, 1]]}],
+		"assertions": [{"kind": "minUpNodes", "value": 4}]
+	}`))
+	if err != nil {
+		t.Fatalf("failed to load scenario: %v", err)
+	}
+	if scenario.Link.Latency != Duration(10*time.Millisecond) {
+		t.Errorf("latency mismatch: have %v, want %v", time.Duration(scenario.Link.Latency), 10*time.Millisecond)
+	}
+	if len(scenario.Partitions) != 1 || scenario.Partitions[0].End != Duration(500*time.Millisecond) {
+		t.Errorf("partition mismatch: %+v", scenario.Partitions)
+	}
+	invalid := []string{
+		`{"nodes": 0}`,
+		`{"nodes": 2, "topology": "mesh"}`,
+		`{"nodes": 2, "link": {"packetLoss": 2}}`,
+		`{"nodes": 2, "partitions": [{"start": "1s", "end": "1s"}]}`,
+		`{"nodes": 2, "partitions": [{"start": "1s", "end": "2s", "groups": [[2]]}]}`,
+		`{"nodes": 2, "assertions": [{"kind": "maxPeers"}]}`,
+		`{"nodes": 2, "duration": "soon"}`,
+	}
+	for _, input := range invalid {
+		if _, err := LoadScenario(strings.NewReader(input)); err == nil {
+			t.Errorf("scenario %s: expected error", input)
+		}
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	adapter := newNoopAdapter()
+	network := NewNetwork(adapter, &NetworkConfig{
+		DefaultService: "noop",
+	})
+	defer network.Shutdown()
+
+	scenario := &Scenario{
+		Nodes:    4,
+		Topology: "full",
+		Duration: Duration(600 * time.Millisecond),
+		Link:     LinkProfile{Latency: Duration(time.Millisecond), Jitter: Duration(time.Millisecond)},
+		Churn:    &ChurnProfile{Interval: Duration(200 * time.Millisecond), Rate: 0.25, Downtime: Duration(100 * time.Millisecond)},
+		Partitions: []PartitionEvent{
+			{Start: Duration(100 * time.Millisecond), End: Duration(400 * time.Millisecond), Groups: [][]int{{0, 1}}},
+		},
+		Assertions: []Assertion{{Kind: AssertMinUpNodes, Value: 4, Timeout: Duration(time.Second)}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := network.RunScenario(ctx, scenario)
+	if err != nil {
+		t.Fatalf("failed to run scenario: %v", err)
+	}
+	if !result.Passed() {
+		t.Fatalf("scenario failed: %v", result.Failures)
+	}
+	if len(result.Nodes) != 4 {
+		t.Errorf("node count mismatch: have %d, want 4", len(result.Nodes))
+	}
+	if result.Partitions != 1 {
+		t.Errorf("partition count mismatch: have %d, want 1", result.Partitions)
+	}
+	if result.Outages == 0 {
+		t.Errorf("no node outages were injected")
+	}
+}
+
+// Tests that blocking a simulated link tears down the live connection over it.
+func TestLinkConditionsPartition(t *testing.T) {
+	adapter := newNoopAdapter()
+	network := NewNetwork(adapter, &NetworkConfig{
+		DefaultService: "noop",
+	})
+	defer network.Shutdown()
+
+	var ids []enode.ID
+	for i := 0; i < 2; i++ {
+		node, err := network.NewNodeWithConfig(adapters.RandomNodeConfig())
+		if err != nil {
+			t.Fatalf("error creating node: %s", err)
+		}
+		if err := network.Start(node.ID()); err != nil {
+			t.Fatalf("error starting node: %s", err)
+		}
+		ids = append(ids, node.ID())
+	}
+	events := make(chan *Event, 16)
+	sub := network.Events().Subscribe(events)
+	defer sub.Unsubscribe()
+
+	if err := network.Connect(ids[0], ids[1]); err != nil {
+		t.Fatalf("error connecting nodes: %s", err)
+	}
+	waitConn := func(up bool) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case ev := <-events:
+				if ev.Type == EventTypeConn && !ev.Control && ev.Conn.Up == up {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("timeout waiting for connection up=%v", up)
+			}
+		}
+	}
+	waitConn(true)
+
+	adapter.SetLinkConditions(func(from, to enode.ID) adapters.LinkConditions {
+		return adapters.LinkConditions{Blocked: true}
+	})
+	waitConn(false)
+}
+
+// newNoopAdapter creates a simulation adapter running a protocol that doesn't
+// do anything besides keeping the peer connected.
+func newNoopAdapter() *adapters.SimAdapter {
+	return adapters.NewSimAdapter(adapters.LifecycleConstructors{
+		"noop": func(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+			svc := NewNoopService(nil)
+			stack.RegisterProtocols(svc.Protocols())
+			return svc, nil
+		},
+	})
+}
@@ -77,6 +77,7 @@ var (
 		ArrowGlacierBlock:       big.NewInt(13_773_000),
 		GrayGlacierBlock:        big.NewInt(15_050_000),
 		TerminalTotalDifficulty: MainnetTerminalTotalDifficulty, // 58_750_000_000_000_000_000_000
+		DepositContractAddress:  newAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa"),
 		Ethash:                  new(EthashConfig),
 	}
 
@@ -163,6 +164,7 @@ var (
 		TerminalTotalDifficulty:       big.NewInt(17_000_000_000_000_000),
 		TerminalTotalDifficultyPassed: true,
 		MergeNetsplitBlock:            big.NewInt(1735371),
+		DepositContractAddress:        newAddress("0x7f02C3E3c98b133055B8B348B2Ac625669Ed295D"),
 		Ethash:                        new(EthashConfig),
 	}
 
@@ -237,6 +239,7 @@ var (
 		ArrowGlacierBlock:             nil,
 		TerminalTotalDifficulty:       big.NewInt(10_790_000),
 		TerminalTotalDifficultyPassed: true,
+		DepositContractAddress:        newAddress("0xff50ed3d0ec03aC01D4C79aAd74928BFF48a7b2b"),
 		Clique: &CliqueConfig{
 			Period: 15,
 			Epoch:  30000,
@@ -269,16 +272,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, false, new(EthashConfig), nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, false, new(EthashConfig), nil, nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, false, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, false, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, false, new(EthashConfig), nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, false, new(EthashConfig), nil, nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int), false)
 )
 
@@ -371,6 +374,21 @@ type ChainConfig struct {
 	MergeNetsplitBlock  *big.Int `json:"mergeNetsplitBlock,omitempty"`  // Virtual fork after The Merge to use as a network splitter
 	ShanghaiBlock       *big.Int `json:"shanghaiBlock,omitempty"`       // Shanghai switch block (nil = no fork, 0 = already on shanghai)
 	CancunBlock         *big.Int `json:"cancunBlock,omitempty"`         // Cancun switch block (nil = no fork, 0 = already on cancun)
+	PragueBlock         *big.Int `json:"pragueBlock,omitempty"`         // Prague switch block (nil = no fork, 0 = already on prague)
+
+	// DepositContractAddress is the address of the beacon chain deposit contract,
+	// whose deposit logs are included as requests in blocks since Prague (EIP-6110).
+	DepositContractAddress *common.Address `json:"depositContractAddress,omitempty"`
+
+	// P256VerifyBlock activates the secp256r1 signature verification precompile
+	// of RIP-7212 (nil = disabled), allowing appchains and testnets to support
+	// passkey based wallets independently of the mainnet forks.
+	P256VerifyBlock *big.Int `json:"p256VerifyBlock,omitempty"`
+
+	// EOFBlock activates the EVM object format of EIP-3540 along with the code
+	// validation and control flow changes of EIP-3670, EIP-4200 and EIP-4750
+	// (nil = disabled). It is meant for devnets experimenting ahead of mainnet.
+	EOFBlock *big.Int `json:"eofBlock,omitempty"`
 
 	// TerminalTotalDifficulty is the amount of total difficulty reached by
 	// the network that triggers the consensus upgrade.
@@ -384,6 +402,14 @@ type ChainConfig struct {
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
+
+	// Milestone configures signed finalized milestones, beyond which the chain
+	// refuses to reorg. Meant for private and consortium networks.
+	Milestone *MilestoneConfig `json:"milestone,omitempty"`
+
+	// GasOverrides reprices opcodes and precompiles, allowing private and
+	// consortium networks to adjust the gas schedule.
+	GasOverrides *GasOverrides `json:"gasOverrides,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -398,6 +424,11 @@ func (c *EthashConfig) String() string {
 type CliqueConfig struct {
 	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
 	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
+
+	// Optional consortium rules, changing them requires all signers to upgrade
+	InactivityEpochs uint64                    `json:"inactivityEpochs,omitempty"` // Epochs without sealing after which signers propose dropping a signer (0 = disabled)
+	Weights          map[common.Address]uint64 `json:"weights,omitempty"`          // Voting weights of signers (default 1), proposals pass above half of the total weight
+	WeightsBlock     *big.Int                  `json:"weightsBlock,omitempty"`     // Block the voting weights are active from (nil = never)
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -405,6 +436,41 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// IsWeighted returns whether num is either equal to the voting weights fork
+// block or greater.
+func (c *CliqueConfig) IsWeighted(num *big.Int) bool {
+	return isForked(c.WeightsBlock, num)
+}
+
+// weightsEqual reports whether two clique configs assign the same voting weights.
+func (c *CliqueConfig) weightsEqual(other *CliqueConfig) bool {
+	if len(c.Weights) != len(other.Weights) {
+		return false
+	}
+	for signer, weight := range c.Weights {
+		if otherWeight, ok := other.Weights[signer]; !ok || otherWeight != weight {
+			return false
+		}
+	}
+	return true
+}
+
+// MilestoneConfig is the set of keys trusted to sign chain milestones and the
+// locations the milestones are published at.
+type MilestoneConfig struct {
+	Signers   []common.Address `json:"signers"`   // Addresses allowed to sign milestones
+	Threshold uint64           `json:"threshold"` // Number of signatures required to accept a milestone
+	URLs      []string         `json:"urls"`      // Locations to fetch milestones from (https:// or dns://)
+}
+
+// GasOverrides is a table of gas costs replacing the ones of the active fork from
+// the given block on.
+type GasOverrides struct {
+	Block       *big.Int                  `json:"block,omitempty"`       // Activation block (nil = active from genesis)
+	Opcodes     map[string]uint64         `json:"opcodes,omitempty"`     // Constant gas by opcode name, only for opcodes without dynamic costs
+	Precompiles map[common.Address]uint64 `json:"precompiles,omitempty"` // Gas by precompile address
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var banner string
@@ -469,6 +535,18 @@ func (c *ChainConfig) String() string {
 	if c.CancunBlock != nil {
 		banner += fmt.Sprintf(" - Cancun:                      %-8v\n", c.CancunBlock)
 	}
+	if c.PragueBlock != nil {
+		banner += fmt.Sprintf(" - Prague:                      %-8v\n", c.PragueBlock)
+	}
+	if c.P256VerifyBlock != nil {
+		banner += fmt.Sprintf(" - P256 verification (RIP-7212): %-8v\n", c.P256VerifyBlock)
+	}
+	if c.EOFBlock != nil {
+		banner += fmt.Sprintf(" - EVM object format (EIP-3540): %-8v\n", c.EOFBlock)
+	}
+	if o := c.GasOverrides; o != nil {
+		banner += fmt.Sprintf(" - Gas overrides:               %-8v (%d opcodes, %d precompiles)\n", o.Block, len(o.Opcodes), len(o.Precompiles))
+	}
 	banner += "\n"
 
 	// Add a special section for the merge as it's non-obvious
@@ -575,6 +653,77 @@ func (c *ChainConfig) IsCancun(num *big.Int) bool {
 	return isForked(c.CancunBlock, num)
 }
 
+// IsPrague returns whether num is either equal to the Prague fork block or greater.
+func (c *ChainConfig) IsPrague(num *big.Int) bool {
+	return isForked(c.PragueBlock, num)
+}
+
+// IsP256Verify returns whether num is either equal to the block activating the
+// RIP-7212 precompile or greater.
+func (c *ChainConfig) IsP256Verify(num *big.Int) bool {
+	return isForked(c.P256VerifyBlock, num)
+}
+
+// IsEOF returns whether num is either equal to the block activating the EVM
+// object format or greater.
+func (c *ChainConfig) IsEOF(num *big.Int) bool {
+	return isForked(c.EOFBlock, num)
+}
+
+// GasOverridesAt returns the gas overrides active at block num, or nil if there
+// are none.
+func (c *ChainConfig) GasOverridesAt(num *big.Int) *GasOverrides {
+	if c.GasOverrides == nil {
+		return nil
+	}
+	if c.GasOverrides.Block != nil && !isForked(c.GasOverrides.Block, num) {
+		return nil
+	}
+	return c.GasOverrides
+}
+
+// activation returns the block the gas overrides are active from, or nil if
+// there are no overrides.
+func (o *GasOverrides) activation() *big.Int {
+	if o == nil {
+		return nil
+	}
+	if o.Block == nil {
+		return common.Big0
+	}
+	return o.Block
+}
+
+// equal reports whether two override tables reprice the same gas costs. Empty
+// and missing tables are considered equal, as they don't survive a JSON round
+// trip through the stored chain config.
+func (o *GasOverrides) equal(other *GasOverrides) bool {
+	var (
+		opcodes, otherOpcodes         map[string]uint64
+		precompiles, otherPrecompiles map[common.Address]uint64
+	)
+	if o != nil {
+		opcodes, precompiles = o.Opcodes, o.Precompiles
+	}
+	if other != nil {
+		otherOpcodes, otherPrecompiles = other.Opcodes, other.Precompiles
+	}
+	if len(opcodes) != len(otherOpcodes) || len(precompiles) != len(otherPrecompiles) {
+		return false
+	}
+	for op, gas := range opcodes {
+		if otherGas, ok := otherOpcodes[op]; !ok || otherGas != gas {
+			return false
+		}
+	}
+	for addr, gas := range precompiles {
+		if otherGas, ok := otherPrecompiles[addr]; !ok || otherGas != gas {
+			return false
+		}
+	}
+	return true
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -620,6 +769,7 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		{name: "mergeNetsplitBlock", block: c.MergeNetsplitBlock, optional: true},
 		{name: "shanghaiBlock", block: c.ShanghaiBlock, optional: true},
 		{name: "cancunBlock", block: c.CancunBlock, optional: true},
+		{name: "pragueBlock", block: c.PragueBlock, optional: true},
 	} {
 		if lastFork.name != "" {
 			// Next one must be higher number
@@ -704,9 +854,43 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.CancunBlock, newcfg.CancunBlock, head) {
 		return newCompatError("Cancun fork block", c.CancunBlock, newcfg.CancunBlock)
 	}
+	if isForkIncompatible(c.PragueBlock, newcfg.PragueBlock, head) {
+		return newCompatError("Prague fork block", c.PragueBlock, newcfg.PragueBlock)
+	}
+	if c.IsPrague(head) && !addressEqual(c.DepositContractAddress, newcfg.DepositContractAddress) {
+		return newCompatError("Deposit contract address", c.PragueBlock, newcfg.PragueBlock)
+	}
+	if isForkIncompatible(c.P256VerifyBlock, newcfg.P256VerifyBlock, head) {
+		return newCompatError("P256 verification block", c.P256VerifyBlock, newcfg.P256VerifyBlock)
+	}
+	if isForkIncompatible(c.EOFBlock, newcfg.EOFBlock, head) {
+		return newCompatError("EOF block", c.EOFBlock, newcfg.EOFBlock)
+	}
+	if isForkIncompatible(c.GasOverrides.activation(), newcfg.GasOverrides.activation(), head) {
+		return newCompatError("gas overrides block", c.GasOverrides.activation(), newcfg.GasOverrides.activation())
+	}
+	if isForked(c.GasOverrides.activation(), head) && !c.GasOverrides.equal(newcfg.GasOverrides) {
+		return newCompatError("gas overrides table", c.GasOverrides.activation(), newcfg.GasOverrides.activation())
+	}
+	if c.Clique != nil && newcfg.Clique != nil {
+		if isForkIncompatible(c.Clique.WeightsBlock, newcfg.Clique.WeightsBlock, head) {
+			return newCompatError("Clique weights fork block", c.Clique.WeightsBlock, newcfg.Clique.WeightsBlock)
+		}
+		if isForked(c.Clique.WeightsBlock, head) && !c.Clique.weightsEqual(newcfg.Clique) {
+			return newCompatError("Clique weights", c.Clique.WeightsBlock, newcfg.Clique.WeightsBlock)
+		}
+	}
 	return nil
 }
 
+// addressEqual reports whether two optional addresses are the same.
+func addressEqual(a, b *common.Address) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -721,6 +905,12 @@ func isForked(s, head *big.Int) bool {
 	return s.Cmp(head) <= 0
 }
 
+// newAddress returns a pointer to the address in the given hex string.
+func newAddress(hex string) *common.Address {
+	addr := common.HexToAddress(hex)
+	return &addr
+}
+
 func configNumEqual(x, y *big.Int) bool {
 	if x == nil {
 		return y == nil
@@ -772,7 +962,8 @@ type Rules struct {
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158               bool
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
 	IsBerlin, IsLondon                                      bool
-	IsMerge, IsShanghai, isCancun                           bool
+	IsMerge, IsShanghai, isCancun, IsPrague                 bool
+	IsP256Verify, IsEOF                                     bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -796,5 +987,8 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool) Rules {
 		IsMerge:          isMerge,
 		IsShanghai:       c.IsShanghai(num),
 		isCancun:         c.IsCancun(num),
+		IsPrague:         c.IsPrague(num),
+		IsP256Verify:     c.IsP256Verify(num),
+		IsEOF:            c.IsEOF(num),
 	}
 }
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -87,6 +89,85 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     30,
 			},
 		},
+		{
+			stored:  &ChainConfig{GasOverrides: &GasOverrides{Block: big.NewInt(10), Opcodes: map[string]uint64{"SLOAD": 5000}}},
+			new:     &ChainConfig{GasOverrides: &GasOverrides{Block: big.NewInt(10), Opcodes: map[string]uint64{"SLOAD": 6000}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{GasOverrides: &GasOverrides{Block: big.NewInt(10), Opcodes: map[string]uint64{"SLOAD": 5000}}},
+			new:    &ChainConfig{GasOverrides: &GasOverrides{Block: big.NewInt(20), Opcodes: map[string]uint64{"SLOAD": 5000}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "gas overrides block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{GasOverrides: &GasOverrides{Block: big.NewInt(10), Opcodes: map[string]uint64{"SLOAD": 5000}}},
+			new:    &ChainConfig{GasOverrides: &GasOverrides{Block: big.NewInt(10), Opcodes: map[string]uint64{"SLOAD": 6000}}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "gas overrides table",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{GasOverrides: &GasOverrides{Block: big.NewInt(10), Opcodes: map[string]uint64{"SLOAD": 5000}}},
+			new:     &ChainConfig{GasOverrides: &GasOverrides{Block: big.NewInt(10), Opcodes: map[string]uint64{"SLOAD": 5000}, Precompiles: map[common.Address]uint64{}}},
+			head:    10,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Clique: &CliqueConfig{}},
+			new:    &ChainConfig{Clique: &CliqueConfig{Weights: map[common.Address]uint64{{0x01}: 3}, WeightsBlock: big.NewInt(10)}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Clique weights fork block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{Clique: &CliqueConfig{Weights: map[common.Address]uint64{{0x01}: 3}, WeightsBlock: big.NewInt(10)}},
+			new:    &ChainConfig{Clique: &CliqueConfig{Weights: map[common.Address]uint64{{0x01}: 4}, WeightsBlock: big.NewInt(10)}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "Clique weights",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{Clique: &CliqueConfig{Weights: map[common.Address]uint64{{0x01}: 3}, WeightsBlock: big.NewInt(10)}},
+			new:     &ChainConfig{Clique: &CliqueConfig{Weights: map[common.Address]uint64{{0x01}: 4}, WeightsBlock: big.NewInt(10)}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{PragueBlock: big.NewInt(10), DepositContractAddress: &common.Address{0x01}},
+			new:    &ChainConfig{PragueBlock: big.NewInt(10), DepositContractAddress: &common.Address{0x02}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "Deposit contract address",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{PragueBlock: big.NewInt(10), DepositContractAddress: &common.Address{0x01}},
+			new:     &ChainConfig{PragueBlock: big.NewInt(10), DepositContractAddress: &common.Address{0x02}},
+			head:    9,
+			wantErr: nil,
+		},
 	}
 
 	for _, test := range tests {
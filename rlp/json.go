@@ -0,0 +1,395 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ToJSON converts RLP encoded data into JSON, e.g. for inspecting it or for
+// keeping it in test fixtures.
+//
+// If schema is nil, the JSON mirrors the structure of the encoding: lists become
+// arrays and strings become 0x-prefixed hex strings. Otherwise the data is decoded
+// into a value of the type of schema, or the type schema points to, and the JSON
+// is annotated accordingly: structs become objects keyed by field name, integers
+// become numbers, booleans and text strings keep their JSON type and byte slices
+// and arrays become hex strings. Values of types implementing Encoder are opaque
+// and rendered like the schema-less structure of their encoding.
+func ToJSON(data []byte, schema interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if schema == nil {
+		if err := writeRawJSON(&buf, data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	val := reflect.New(schemaType(schema))
+	if err := DecodeBytes(data, val.Interface()); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(&buf, val.Elem()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FromJSON converts JSON produced by ToJSON with the same schema back into its
+// RLP encoding. Struct fields missing from the JSON are encoded as zero values.
+func FromJSON(input []byte, schema interface{}) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("rlp: trailing data after JSON value")
+	}
+	if schema == nil {
+		return rawFromJSON(tree)
+	}
+	val := reflect.New(schemaType(schema))
+	if err := readJSON(tree, val.Elem()); err != nil {
+		return nil, err
+	}
+	return EncodeToBytes(val.Interface())
+}
+
+// schemaType returns the type of the schema value, dereferencing pointers to
+// allow passing new(T) as a schema.
+func schemaType(schema interface{}) reflect.Type {
+	typ := reflect.TypeOf(schema)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// writeRawJSON writes the structure of a single encoded value as JSON.
+func writeRawJSON(buf *bytes.Buffer, data []byte) error {
+	kind, content, rest, err := Split(data)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return ErrMoreThanOneValue
+	}
+	if kind != List {
+		writeHexJSON(buf, content)
+		return nil
+	}
+	buf.WriteByte('[')
+	for i := 0; len(content) > 0; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		_, _, rest, err := Split(content)
+		if err != nil {
+			return err
+		}
+		if err := writeRawJSON(buf, content[:len(content)-len(rest)]); err != nil {
+			return err
+		}
+		content = rest
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+func writeHexJSON(buf *bytes.Buffer, b []byte) {
+	buf.WriteString(`"0x`)
+	buf.WriteString(hex.EncodeToString(b))
+	buf.WriteByte('"')
+}
+
+// writeJSON writes a decoded value as annotated JSON. The value must be
+// addressable.
+func writeJSON(buf *bytes.Buffer, val reflect.Value) error {
+	typ := val.Type()
+	kind := typ.Kind()
+	switch {
+	case typ == rawValueType:
+		return writeRawJSON(buf, val.Bytes())
+	case typ.AssignableTo(reflect.PtrTo(bigInt)):
+		if val.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteString(val.Interface().(*big.Int).String())
+	case typ.AssignableTo(bigInt):
+		i := val.Interface().(big.Int)
+		buf.WriteString(i.String())
+	case kind == reflect.Ptr:
+		if val.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSON(buf, val.Elem())
+	case reflect.PtrTo(typ).Implements(encoderInterface):
+		enc, err := EncodeToBytes(val.Addr().Interface())
+		if err != nil {
+			return err
+		}
+		return writeRawJSON(buf, enc)
+	case isUint(kind):
+		buf.WriteString(strconv.FormatUint(val.Uint(), 10))
+	case kind == reflect.Bool:
+		buf.WriteString(strconv.FormatBool(val.Bool()))
+	case kind == reflect.String:
+		enc, err := json.Marshal(val.String())
+		if err != nil {
+			return err
+		}
+		buf.Write(enc)
+	case (kind == reflect.Slice || kind == reflect.Array) && isByte(typ.Elem()):
+		b := make([]byte, val.Len())
+		for i := range b {
+			b[i] = byte(val.Index(i).Uint())
+		}
+		writeHexJSON(buf, b)
+	case kind == reflect.Slice || kind == reflect.Array:
+		buf.WriteByte('[')
+		for i := 0; i < val.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, val.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case kind == reflect.Struct:
+		fields, _, err := processStructFields(typ)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i, f := range fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(f.Name)
+			buf.Write(name)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, val.Field(f.Index)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case kind == reflect.Interface:
+		if val.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		enc, err := EncodeToBytes(val.Interface())
+		if err != nil {
+			return err
+		}
+		return writeRawJSON(buf, enc)
+	default:
+		return fmt.Errorf("rlp: type %v is not RLP-serializable", typ)
+	}
+	return nil
+}
+
+// rawFromJSON encodes JSON mirroring the structure of an encoding.
+func rawFromJSON(tree interface{}) ([]byte, error) {
+	val, err := rawValueFromJSON(tree)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeToBytes(val)
+}
+
+func rawValueFromJSON(tree interface{}) (interface{}, error) {
+	switch tree := tree.(type) {
+	case string:
+		return hexFromJSON(tree)
+	case []interface{}:
+		list := make([]interface{}, len(tree))
+		for i, item := range tree {
+			val, err := rawValueFromJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = val
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("rlp: invalid JSON value %v, want hex string or array", tree)
+	}
+}
+
+func hexFromJSON(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("rlp: hex string %q without 0x prefix", s)
+	}
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, fmt.Errorf("rlp: invalid hex string %q: %v", s, err)
+	}
+	return b, nil
+}
+
+// readJSON sets a value from its annotated JSON. The value must be settable.
+func readJSON(tree interface{}, val reflect.Value) error {
+	typ := val.Type()
+	kind := typ.Kind()
+
+	if tree == nil {
+		if kind != reflect.Ptr && kind != reflect.Interface {
+			return fmt.Errorf("rlp: null JSON value for %v", typ)
+		}
+		val.Set(reflect.Zero(typ))
+		return nil
+	}
+	switch {
+	case typ == rawValueType:
+		enc, err := rawFromJSON(tree)
+		if err != nil {
+			return err
+		}
+		val.SetBytes(enc)
+	case typ.AssignableTo(reflect.PtrTo(bigInt)):
+		i, err := bigFromJSON(tree)
+		if err != nil {
+			return err
+		}
+		val.Set(reflect.ValueOf(i))
+	case typ.AssignableTo(bigInt):
+		i, err := bigFromJSON(tree)
+		if err != nil {
+			return err
+		}
+		val.Set(reflect.ValueOf(*i))
+	case kind == reflect.Ptr:
+		elem := reflect.New(typ.Elem())
+		if err := readJSON(tree, elem.Elem()); err != nil {
+			return err
+		}
+		val.Set(elem)
+	case reflect.PtrTo(typ).Implements(decoderInterface), kind == reflect.Interface:
+		enc, err := rawFromJSON(tree)
+		if err != nil {
+			return err
+		}
+		return DecodeBytes(enc, val.Addr().Interface())
+	case isUint(kind):
+		n, ok := tree.(json.Number)
+		if !ok {
+			return fmt.Errorf("rlp: invalid JSON value %v for %v, want number", tree, typ)
+		}
+		i, err := strconv.ParseUint(n.String(), 10, typ.Bits())
+		if err != nil {
+			return fmt.Errorf("rlp: invalid number %v for %v: %v", n, typ, err)
+		}
+		val.SetUint(i)
+	case kind == reflect.Bool:
+		b, ok := tree.(bool)
+		if !ok {
+			return fmt.Errorf("rlp: invalid JSON value %v for %v, want boolean", tree, typ)
+		}
+		val.SetBool(b)
+	case kind == reflect.String:
+		s, ok := tree.(string)
+		if !ok {
+			return fmt.Errorf("rlp: invalid JSON value %v for %v, want string", tree, typ)
+		}
+		val.SetString(s)
+	case (kind == reflect.Slice || kind == reflect.Array) && isByte(typ.Elem()):
+		s, ok := tree.(string)
+		if !ok {
+			return fmt.Errorf("rlp: invalid JSON value %v for %v, want hex string", tree, typ)
+		}
+		b, err := hexFromJSON(s)
+		if err != nil {
+			return err
+		}
+		if kind == reflect.Slice {
+			val.Set(reflect.MakeSlice(typ, len(b), len(b)))
+		} else if len(b) != val.Len() {
+			return fmt.Errorf("rlp: invalid length %d of hex string for %v", len(b), typ)
+		}
+		for i := range b {
+			val.Index(i).SetUint(uint64(b[i]))
+		}
+	case kind == reflect.Slice || kind == reflect.Array:
+		items, ok := tree.([]interface{})
+		if !ok {
+			return fmt.Errorf("rlp: invalid JSON value %v for %v, want array", tree, typ)
+		}
+		if kind == reflect.Slice {
+			val.Set(reflect.MakeSlice(typ, len(items), len(items)))
+		} else if len(items) != val.Len() {
+			return fmt.Errorf("rlp: invalid length %d of array for %v", len(items), typ)
+		}
+		for i, item := range items {
+			if err := readJSON(item, val.Index(i)); err != nil {
+				return err
+			}
+		}
+	case kind == reflect.Struct:
+		obj, ok := tree.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("rlp: invalid JSON value %v for %v, want object", tree, typ)
+		}
+		fields, _, err := processStructFields(typ)
+		if err != nil {
+			return err
+		}
+		known := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			known[f.Name] = true
+			if item, ok := obj[f.Name]; ok {
+				if err := readJSON(item, val.Field(f.Index)); err != nil {
+					return fmt.Errorf("%v (struct field %v.%s)", err, typ, f.Name)
+				}
+			}
+		}
+		for name := range obj {
+			if !known[name] {
+				return fmt.Errorf("rlp: unknown field %q for %v", name, typ)
+			}
+		}
+	default:
+		return fmt.Errorf("rlp: type %v is not RLP-serializable", typ)
+	}
+	return nil
+}
+
+func bigFromJSON(tree interface{}) (*big.Int, error) {
+	n, ok := tree.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("rlp: invalid JSON value %v for big.Int, want number", tree)
+	}
+	i, ok := new(big.Int).SetString(n.String(), 10)
+	if !ok || i.Sign() < 0 {
+		return nil, fmt.Errorf("rlp: invalid number %v for big.Int", n)
+	}
+	return i, nil
+}
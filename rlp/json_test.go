@@ -0,0 +1,107 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlp
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+type jsonTestStruct struct {
+	Nonce   uint64
+	Price   *big.Int
+	To      *[3]byte `rlp:"nil"`
+	Payload []byte
+	Memo    string
+	Flag    bool
+	Raw     RawValue
+	Items   []jsonTestItem
+	Extra   uint64 `rlp:"optional"`
+}
+
+type jsonTestItem struct {
+	A uint
+	B []interface{}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	val := &jsonTestStruct{
+		Nonce:   5,
+		Price:   new(big.Int).Lsh(big.NewInt(1), 100),
+		Payload: []byte{0xde, 0xad},
+		Memo:    "hello \"world\"",
+		Flag:    true,
+		Raw:     unhex("C20102"),
+		Items:   []jsonTestItem{{A: 1, B: []interface{}{[]byte{0x01}, []interface{}{}}}},
+	}
+	enc, err := EncodeToBytes(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		schema interface{}
+		want   string
+	}{
+		{
+			schema: nil,
+			want:   `["0x05","0x10000000000000000000000000","0x","0xdead","0x68656c6c6f2022776f726c6422","0x01",["0x01","0x02"],[["0x01",["0x01",[]]]]]`,
+		},
+		{
+			schema: new(jsonTestStruct),
+			want:   `{"Nonce":5,"Price":1267650600228229401496703205376,"To":null,"Payload":"0xdead","Memo":"hello \"world\"","Flag":true,"Raw":["0x01","0x02"],"Items":[{"A":1,"B":["0x01",[]]}],"Extra":0}`,
+		},
+	}
+	for i, test := range tests {
+		js, err := ToJSON(enc, test.schema)
+		if err != nil {
+			t.Fatalf("test %d: ToJSON error: %v", i, err)
+		}
+		if string(js) != test.want {
+			t.Errorf("test %d: JSON mismatch\nhave %s\nwant %s", i, js, test.want)
+		}
+		back, err := FromJSON(js, test.schema)
+		if err != nil {
+			t.Fatalf("test %d: FromJSON error: %v", i, err)
+		}
+		if !bytes.Equal(back, enc) {
+			t.Errorf("test %d: round trip mismatch\nhave %x\nwant %x", i, back, enc)
+		}
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		schema interface{}
+	}{
+		{`["0x01", 1]`, nil},
+		{`"01"`, nil},
+		{`"0x0"`, nil},
+		{`["0x01"] ["0x02"]`, nil},
+		{`{"A":1,"C":2}`, new(jsonTestItem)},
+		{`{"A":-1}`, new(jsonTestItem)},
+		{`{"A":"0x01"}`, new(jsonTestItem)},
+		{`{"To":"0x0102"}`, new(jsonTestStruct)},
+		{`{"Price":-1}`, new(jsonTestStruct)},
+	}
+	for i, test := range tests {
+		if _, err := FromJSON([]byte(test.input), test.schema); err == nil {
+			t.Errorf("test %d: no error for %s", i, test.input)
+		}
+	}
+}
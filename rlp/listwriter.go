@@ -0,0 +1,133 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+)
+
+// listWriterMemoryLimit is the amount of encoded list content a ListWriter keeps
+// in memory before spilling it to a temporary file.
+const listWriterMemoryLimit = 4 * 1024 * 1024
+
+var errListWriterClosed = errors.New("rlp: list writer closed")
+
+// ListWriter encodes an RLP list of arbitrary length, appending the items one at
+// a time. Since the list header contains the size of the list content, nothing
+// is written to the destination before Close. Content beyond a small in-memory
+// buffer is spilled into a temporary file, so memory use remains bounded
+// regardless of the size of the list.
+type ListWriter struct {
+	w     io.Writer
+	item  *encBuffer   // Encoder of the item being appended
+	mem   bytes.Buffer // List content while below the memory limit
+	spill *os.File     // Temporary file holding the list content above the memory limit
+	limit int          // Memory limit of the content buffer
+	size  uint64       // Size of the list content
+	err   error        // Sticky error of writing the content
+}
+
+// NewListWriter creates a list encoder writing to w.
+func NewListWriter(w io.Writer) *ListWriter {
+	return &ListWriter{
+		w:     w,
+		item:  getEncBuffer(),
+		limit: listWriterMemoryLimit,
+	}
+}
+
+// Append encodes val as the next item of the list. If val can't be encoded, the
+// error is returned and the list is left unchanged.
+func (s *ListWriter) Append(val interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.item.reset()
+	if err := s.item.encode(val); err != nil {
+		return err
+	}
+	size := s.item.size()
+	if s.spill == nil && s.mem.Len()+size > s.limit {
+		if s.err = s.spillContent(); s.err != nil {
+			return s.err
+		}
+	}
+	if s.spill != nil {
+		s.err = s.item.writeTo(s.spill)
+	} else {
+		s.err = s.item.writeTo(&s.mem)
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.size += uint64(size)
+	return nil
+}
+
+// spillContent moves the buffered content into a temporary file.
+func (s *ListWriter) spillContent() error {
+	f, err := os.CreateTemp("", "rlp-list-")
+	if err != nil {
+		return err
+	}
+	s.spill = f
+	if _, err := s.mem.WriteTo(f); err != nil {
+		return err
+	}
+	s.mem = bytes.Buffer{}
+	return nil
+}
+
+// Close writes the list to the destination writer and releases all resources
+// of the encoder. The writer can't be used after closing it.
+func (s *ListWriter) Close() error {
+	if s.err == errListWriterClosed {
+		return s.err
+	}
+	defer s.release()
+
+	if s.err != nil {
+		return s.err
+	}
+	head := make([]byte, 9)
+	if _, err := s.w.Write(head[:puthead(head, 0xC0, 0xF7, s.size)]); err != nil {
+		return err
+	}
+	if s.spill == nil {
+		_, err := s.mem.WriteTo(s.w)
+		return err
+	}
+	if _, err := s.spill.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(s.w, s.spill)
+	return err
+}
+
+// release removes the temporary file and returns the encoder buffer.
+func (s *ListWriter) release() {
+	if s.spill != nil {
+		s.spill.Close()
+		os.Remove(s.spill.Name())
+		s.spill = nil
+	}
+	encBufferPool.Put(s.item)
+	s.item, s.mem, s.err = nil, bytes.Buffer{}, errListWriterClosed
+}
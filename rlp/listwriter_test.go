@@ -0,0 +1,97 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rlp
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+type listWriterItem struct {
+	A uint64
+	B []byte
+	C *big.Int
+}
+
+func makeListWriterItems(n int) []listWriterItem {
+	items := make([]listWriterItem, n)
+	for i := range items {
+		items[i] = listWriterItem{
+			A: uint64(i),
+			B: bytes.Repeat([]byte{byte(i)}, i),
+			C: new(big.Int).Lsh(big.NewInt(1), uint(i)),
+		}
+	}
+	return items
+}
+
+func TestListWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []listWriterItem
+		limit int
+	}{
+		{name: "empty", items: []listWriterItem{}, limit: listWriterMemoryLimit},
+		{name: "short", items: makeListWriterItems(3), limit: listWriterMemoryLimit},
+		{name: "long", items: makeListWriterItems(100), limit: listWriterMemoryLimit},
+		{name: "spilled", items: makeListWriterItems(100), limit: 64},
+	}
+	for _, test := range tests {
+		var (
+			out bytes.Buffer
+			w   = NewListWriter(&out)
+		)
+		w.limit = test.limit
+		for _, item := range test.items {
+			if err := w.Append(item); err != nil {
+				t.Fatalf("%s: append failed: %v", test.name, err)
+			}
+		}
+		if spilled := w.spill != nil; spilled != (test.limit < listWriterMemoryLimit) {
+			t.Errorf("%s: spill mismatch: have %v", test.name, spilled)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: close failed: %v", test.name, err)
+		}
+		want, _ := EncodeToBytes(test.items)
+		if !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("%s: output mismatch:\nhave %x\nwant %x", test.name, out.Bytes(), want)
+		}
+		if err := w.Append(uint64(1)); err != errListWriterClosed {
+			t.Errorf("%s: append after close error mismatch: have %v, want %v", test.name, err, errListWriterClosed)
+		}
+	}
+}
+
+func TestListWriterEncodeError(t *testing.T) {
+	var out bytes.Buffer
+	w := NewListWriter(&out)
+	if err := w.Append(uint64(1)); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	// Items failing to encode should leave the list unchanged
+	if err := w.Append(make(chan int)); err == nil {
+		t.Fatal("no error for unencodable item")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if want := []byte{0xC1, 0x01}; !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("output mismatch: have %x, want %x", out.Bytes(), want)
+	}
+}
@@ -126,6 +126,24 @@ type field struct {
 
 // structFields resolves the typeinfo of all public fields in a struct type.
 func structFields(typ reflect.Type) (fields []field, err error) {
+	structFields, structTags, err := processStructFields(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve typeinfo.
+	for i, sf := range structFields {
+		typ := typ.Field(sf.Index).Type
+		tags := structTags[i]
+		info := theTC.infoWhileGenerating(typ, tags)
+		fields = append(fields, field{sf.Index, info, tags.Optional})
+	}
+	return fields, nil
+}
+
+// processStructFields returns the encoded fields of a struct type along with
+// their tags.
+func processStructFields(typ reflect.Type) ([]rlpstruct.Field, []rlpstruct.Tags, error) {
 	// Convert fields to rlpstruct.Field.
 	var allStructFields []rlpstruct.Field
 	for i := 0; i < typ.NumField(); i++ {
@@ -144,19 +162,11 @@ func structFields(typ reflect.Type) (fields []field, err error) {
 	if err != nil {
 		if tagErr, ok := err.(rlpstruct.TagError); ok {
 			tagErr.StructType = typ.String()
-			return nil, tagErr
+			return nil, nil, tagErr
 		}
-		return nil, err
-	}
-
-	// Resolve typeinfo.
-	for i, sf := range structFields {
-		typ := typ.Field(sf.Index).Type
-		tags := structTags[i]
-		info := theTC.infoWhileGenerating(typ, tags)
-		fields = append(fields, field{sf.Index, info, tags.Optional})
+		return nil, nil, err
 	}
-	return fields, nil
+	return structFields, structTags, nil
 }
 
 // firstOptionalField returns the index of the first field with "optional" tag.
@@ -76,6 +76,9 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool      // connection type: http, ws or ipc
 	services *serviceRegistry
+	recorder CallRecorder    // invoked for calls served to the remote end
+	limiter  *RateLimiter    // limits the calls served to the remote end
+	signer   *ResponseSigner // signs the results of calls served to the remote end
 
 	idCounter uint32
 
@@ -113,6 +116,9 @@ func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx = context.WithValue(ctx, clientContextKey{}, c)
 	ctx = context.WithValue(ctx, peerInfoContextKey{}, conn.peerInfo())
 	handler := newHandler(ctx, conn, c.idgen, c.services)
+	handler.recorder = c.recorder
+	handler.limiter = c.limiter
+	handler.signer = c.signer
 	return &clientConn{conn, handler}
 }
 
@@ -198,17 +204,20 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), nil, nil, nil)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, recorder CallRecorder, limiter *RateLimiter, signer *ResponseSigner) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		isHTTP:      isHTTP,
 		idgen:       idgen,
 		services:    services,
+		recorder:    recorder,
+		limiter:     limiter,
+		signer:      signer,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
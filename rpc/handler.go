@@ -61,6 +61,9 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	recorder       CallRecorder    // invoked for every served call, if set
+	limiter        *RateLimiter    // rejects calls over the rate limits, if set
+	signer         *ResponseSigner // signs the results of selected methods, if set
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -291,11 +294,13 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	start := time.Now()
 	switch {
 	case msg.isNotification():
-		h.handleCall(ctx, msg)
+		resp := h.handleCall(ctx, msg)
+		h.record(ctx, msg, resp, start)
 		h.log.Debug("Served "+msg.Method, "duration", time.Since(start))
 		return nil
 	case msg.isCall():
 		resp := h.handleCall(ctx, msg)
+		h.record(ctx, msg, resp, start)
 		var ctx []interface{}
 		ctx = append(ctx, "reqid", idForLog{msg.ID}, "duration", time.Since(start))
 		if resp.Error != nil {
@@ -315,8 +320,31 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	}
 }
 
+// record reports a served call to the call recorder, if one is set.
+func (h *handler) record(cp *callProc, msg *jsonrpcMessage, resp *jsonrpcMessage, start time.Time) {
+	if h.recorder == nil {
+		return
+	}
+	rec := &CallRecord{
+		Method:   msg.Method,
+		Params:   msg.Params,
+		Peer:     PeerInfoFromContext(cp.ctx),
+		Start:    start,
+		Duration: time.Since(start),
+	}
+	if resp != nil && resp.Error != nil {
+		rec.ErrorCode, rec.Error = resp.Error.Code, resp.Error.Message
+	}
+	h.recorder(rec)
+}
+
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if h.limiter != nil {
+		if err := h.limiter.allow(PeerInfoFromContext(cp.ctx), msg.Method, msg.namespace()); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -335,6 +363,13 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	}
 	start := time.Now()
 	answer := h.runMethod(cp.ctx, msg, callb, args)
+	if h.signer != nil && answer.Error == nil {
+		sig, err := h.signer.sign(msg.Method, msg.Params, answer.Result)
+		if err != nil {
+			return msg.errorResponse(err)
+		}
+		answer.Signature = sig
+	}
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -346,7 +381,7 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 			successfulRequestGauge.Inc(1)
 		}
 		rpcServingTimer.UpdateSince(start)
-		updateServeTimeHistogram(msg.Method, answer.Error == nil, time.Since(start))
+		updateServeTimeHistogram(msg.Method, msg.ID, answer.Error == nil, time.Since(start))
 	}
 	return answer
 }
@@ -198,3 +198,37 @@ func TestHTTPPeerInfo(t *testing.T) {
 		t.Errorf("wrong HTTP.Origin %q", info.HTTP.UserAgent)
 	}
 }
+
+func TestHTTPCallRecorder(t *testing.T) {
+	var (
+		s       = newTestServer()
+		records = make(chan *CallRecord, 2)
+	)
+	s.SetCallRecorder(func(rec *CallRecord) { records <- rec })
+	defer s.Stop()
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetHeader("X-Client-ID", "client-testing")
+
+	if err := c.Call(nil, "test_echo", "x", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Call(nil, "test_unknown"); err == nil {
+		t.Fatal("unknown method call succeeded")
+	}
+	rec := <-records
+	if rec.Method != "test_echo" || string(rec.Params) != `["x",1]` || rec.ErrorCode != 0 {
+		t.Errorf("wrong record for successful call: %+v", rec)
+	}
+	if rec.Peer.Transport != "http" || rec.Peer.HTTP.ClientID != "client-testing" {
+		t.Errorf("wrong peer info %+v", rec.Peer)
+	}
+	if rec = <-records; rec.Method != "test_unknown" || rec.ErrorCode != (&methodNotFoundError{}).ErrorCode() || rec.Error == "" {
+		t.Errorf("wrong record for failed call: %+v", rec)
+	}
+}
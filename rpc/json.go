@@ -55,6 +55,8 @@ type jsonrpcMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Error   *jsonError      `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
+
+	Signature *ResponseSignature `json:"signature,omitempty"` // Node signature of the result, if signed
 }
 
 func (msg *jsonrpcMessage) isNotification() bool {
@@ -17,6 +17,7 @@
 package rpc
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -32,10 +33,22 @@ var (
 	serveTimeHistName = "rpc/duration"
 
 	rpcServingTimer = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+
+	// serveTimeBuckets are the bucket bounds of the serving time histograms, in
+	// microseconds.
+	serveTimeBuckets = []int64{
+		100, 250, 500, 1000, 2500, 5000, 10000, 25000, 50000,
+		100000, 250000, 500000, 1000000, 2500000, 5000000, 10000000,
+	}
 )
 
-// updateServeTimeHistogram tracks the serving time of a remote RPC call.
-func updateServeTimeHistogram(method string, success bool, elapsed time.Duration) {
+// updateServeTimeHistogram tracks the serving time of a remote RPC call. The
+// call is retained as an exemplar of the histogram, labelled with its request
+// id, so that slow requests can be traced back from exported metrics.
+func updateServeTimeHistogram(method string, id json.RawMessage, success bool, elapsed time.Duration) {
+	if !metrics.Enabled {
+		return
+	}
 	note := "success"
 	if !success {
 		note = "failure"
@@ -46,5 +59,6 @@ func updateServeTimeHistogram(method string, success bool, elapsed time.Duration
 			metrics.NewExpDecaySample(1028, 0.015),
 		)
 	}
-	metrics.GetOrRegisterHistogramLazy(h, nil, sampler).Update(elapsed.Microseconds())
+	labels := map[string]string{"rpc.jsonrpc.request_id": string(id)}
+	metrics.GetOrRegisterExemplarHistogramLazy(h, nil, serveTimeBuckets, sampler).UpdateExemplar(elapsed.Microseconds(), labels)
 }
@@ -0,0 +1,226 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// rateLimitSweepInterval is the interval at which the buckets of idle clients
+	// are dropped.
+	rateLimitSweepInterval = time.Minute
+
+	// maxRateLimitBuckets is the number of buckets tracked at most. Beyond it, the
+	// least recently used ones are dropped, so that a flood of client addresses
+	// can't exhaust memory.
+	maxRateLimitBuckets = 65536
+)
+
+var rateLimitedMeter = metrics.NewRegisteredMeter("rpc/ratelimited", nil)
+
+// RateLimit is a token bucket limiting the rate of calls.
+type RateLimit struct {
+	Rate  float64 // Average number of calls allowed per second
+	Burst int     `toml:",omitempty"` // Number of calls allowed in a burst, defaults to the rate
+}
+
+// RateLimitConfig configures the limits of the calls made by a single client,
+// identified by its IP address. All limits applying to a call must allow it.
+type RateLimitConfig struct {
+	Client     *RateLimit           `toml:",omitempty"` // Limit across all methods
+	Namespaces map[string]RateLimit `toml:",omitempty"` // Limits per API namespace, e.g. "eth"
+	Methods    map[string]RateLimit `toml:",omitempty"` // Limits per method, e.g. "eth_call"
+}
+
+// Enabled returns whether any limit is configured.
+func (c *RateLimitConfig) Enabled() bool {
+	return c.Client != nil || len(c.Namespaces) > 0 || len(c.Methods) > 0
+}
+
+// RateLimiter limits the rate of calls of each client of a server. Calls over
+// the limit are rejected with a "limit exceeded" error. Clients without a
+// network address, i.e. IPC and in-process ones, are never limited.
+type RateLimiter struct {
+	config  RateLimitConfig
+	buckets *lru.Cache // rateLimitKey -> *rateBucket
+	swept   mclock.AbsTime
+	clock   mclock.Clock
+	lock    sync.Mutex
+}
+
+// rateLimitKey identifies the bucket of a client for a limit. The scope is the
+// name of the limited namespace or method, empty for the client-wide limit.
+type rateLimitKey struct {
+	client string
+	scope  string
+}
+
+type rateBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   mclock.AbsTime
+}
+
+// NewRateLimiter creates a rate limiter enforcing the given limits.
+func NewRateLimiter(config RateLimitConfig) (*RateLimiter, error) {
+	return newRateLimiter(config, mclock.System{})
+}
+
+func newRateLimiter(config RateLimitConfig, clock mclock.Clock) (*RateLimiter, error) {
+	check := func(name string, limit RateLimit) error {
+		if limit.Rate <= 0 || math.IsInf(limit.Rate, 0) || math.IsNaN(limit.Rate) {
+			return fmt.Errorf("invalid rate limit for %s: rate %v", name, limit.Rate)
+		}
+		if limit.Burst < 0 {
+			return fmt.Errorf("invalid rate limit for %s: burst %d", name, limit.Burst)
+		}
+		return nil
+	}
+	if config.Client != nil {
+		if err := check("client", *config.Client); err != nil {
+			return nil, err
+		}
+	}
+	for name, limit := range config.Namespaces {
+		if err := check("namespace "+name, limit); err != nil {
+			return nil, err
+		}
+	}
+	for name, limit := range config.Methods {
+		if err := check("method "+name, limit); err != nil {
+			return nil, err
+		}
+	}
+	buckets, _ := lru.New(maxRateLimitBuckets)
+	return &RateLimiter{
+		config:  config,
+		buckets: buckets,
+		swept:   clock.Now(),
+		clock:   clock,
+	}, nil
+}
+
+// allow consumes a token from all buckets limiting the given call of a client,
+// returning an error if any of them is empty.
+func (l *RateLimiter) allow(peer PeerInfo, method, namespace string) error {
+	client := clientIP(peer)
+	if client == "" {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	if now.Sub(l.swept) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+	// Check all applicable buckets before taking any tokens, so that calls
+	// rejected by one limit don't drain the others.
+	var (
+		buckets []*rateBucket
+		scopes  []string
+	)
+	if l.config.Client != nil {
+		buckets = append(buckets, l.bucket(rateLimitKey{client, ""}, *l.config.Client, now))
+		scopes = append(scopes, "client")
+	}
+	if limit, ok := l.config.Namespaces[namespace]; ok {
+		buckets = append(buckets, l.bucket(rateLimitKey{client, namespace}, limit, now))
+		scopes = append(scopes, namespace)
+	}
+	if limit, ok := l.config.Methods[method]; ok {
+		buckets = append(buckets, l.bucket(rateLimitKey{client, method}, limit, now))
+		scopes = append(scopes, method)
+	}
+	for i, bucket := range buckets {
+		if bucket.tokens < 1 {
+			rateLimitedMeter.Mark(1)
+			metrics.GetOrRegisterMeter("rpc/ratelimited/"+scopes[i], nil).Mark(1)
+			return &rateLimitedError{scope: scopes[i]}
+		}
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return nil
+}
+
+// bucket returns the bucket of the given key, refilled up to the current time.
+func (l *RateLimiter) bucket(key rateLimitKey, limit RateLimit, now mclock.AbsTime) *rateBucket {
+	if cached, ok := l.buckets.Get(key); ok {
+		bucket := cached.(*rateBucket)
+		bucket.refill(now)
+		return bucket
+	}
+	bucket := &rateBucket{limit: limit, tokens: limit.burst(), last: now}
+	l.buckets.Add(key, bucket)
+	return bucket
+}
+
+// sweep drops the buckets which are full again, their clients being idle.
+func (l *RateLimiter) sweep(now mclock.AbsTime) {
+	for _, key := range l.buckets.Keys() {
+		cached, ok := l.buckets.Peek(key)
+		if !ok {
+			continue
+		}
+		bucket := cached.(*rateBucket)
+		if bucket.refill(now); bucket.tokens >= bucket.limit.burst() {
+			l.buckets.Remove(key)
+		}
+	}
+	l.swept = now
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (b *rateBucket) refill(now mclock.AbsTime) {
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if burst := b.limit.burst(); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+}
+
+// burst returns the capacity of the bucket.
+func (limit RateLimit) burst() float64 {
+	if limit.Burst > 0 {
+		return float64(limit.Burst)
+	}
+	return math.Max(1, math.Ceil(limit.Rate))
+}
+
+// clientIP returns the IP address identifying a client, or an empty string for
+// clients without a network address.
+func clientIP(peer PeerInfo) string {
+	if peer.Transport != "http" && peer.Transport != "ws" {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(peer.RemoteAddr)
+	if err != nil {
+		return peer.RemoteAddr
+	}
+	return host
+}
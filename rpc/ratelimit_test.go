@@ -0,0 +1,160 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := new(mclock.Simulated)
+	limiter, err := newRateLimiter(RateLimitConfig{
+		Client:     &RateLimit{Rate: 10, Burst: 5},
+		Namespaces: map[string]RateLimit{"debug": {Rate: 1, Burst: 2}},
+		Methods:    map[string]RateLimit{"eth_call": {Rate: 1}},
+	}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		alice = PeerInfo{Transport: "http", RemoteAddr: "10.0.0.1:1234"}
+		bob   = PeerInfo{Transport: "ws", RemoteAddr: "10.0.0.2:1234"}
+		local = PeerInfo{Transport: "ipc"}
+	)
+	check := func(peer PeerInfo, method, namespace string, want error) {
+		t.Helper()
+		err := limiter.allow(peer, method, namespace)
+		if (err == nil) != (want == nil) || (err != nil && err.Error() != want.Error()) {
+			t.Fatalf("%s from %s: have error %v, want %v", method, peer.RemoteAddr, err, want)
+		}
+	}
+	// Method limit without a burst allows a single call per second
+	check(alice, "eth_call", "eth", nil)
+	check(alice, "eth_call", "eth", &rateLimitedError{"eth_call"})
+	check(bob, "eth_call", "eth", nil)
+
+	// Namespace limit, rejected calls don't consume from the client limit
+	check(alice, "debug_a", "debug", nil)
+	check(alice, "debug_b", "debug", nil)
+	check(alice, "debug_c", "debug", &rateLimitedError{"debug"})
+
+	// Client limit, two tokens left after the three calls above
+	check(alice, "eth_blockNumber", "eth", nil)
+	check(alice, "eth_blockNumber", "eth", nil)
+	check(alice, "eth_blockNumber", "eth", &rateLimitedError{"client"})
+
+	// Clients without a network address are not limited
+	for i := 0; i < 10; i++ {
+		check(local, "eth_call", "eth", nil)
+	}
+	// Tokens are refilled over time
+	clock.Run(time.Second)
+	check(alice, "eth_call", "eth", nil)
+	check(alice, "debug_a", "debug", nil)
+
+	// Buckets of idle clients are dropped
+	clock.Run(rateLimitSweepInterval)
+	check(bob, "eth_blockNumber", "eth", nil)
+	if n := limiter.buckets.Len(); n != 1 {
+		t.Fatalf("have %d buckets after sweep, want 1", n)
+	}
+}
+
+// Tests that the number of tracked buckets is capped, dropping the least
+// recently used ones.
+func TestRateLimiterBucketCap(t *testing.T) {
+	clock := new(mclock.Simulated)
+	limiter, err := newRateLimiter(RateLimitConfig{
+		Client: &RateLimit{Rate: 1},
+	}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := PeerInfo{Transport: "http", RemoteAddr: "10.0.0.1:1234"}
+	if err := limiter.allow(alice, "eth_call", "eth"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxRateLimitBuckets; i++ {
+		peer := PeerInfo{Transport: "http", RemoteAddr: fmt.Sprintf("10.%d.%d.%d:1234", 1+i>>16, byte(i>>8), byte(i))}
+		if err := limiter.allow(peer, "eth_call", "eth"); err != nil {
+			t.Fatalf("client %d: %v", i, err)
+		}
+	}
+	if n := limiter.buckets.Len(); n != maxRateLimitBuckets {
+		t.Fatalf("have %d buckets, want %d", n, maxRateLimitBuckets)
+	}
+	// The bucket of the least recently seen client should have been dropped
+	if err := limiter.allow(alice, "eth_call", "eth"); err != nil {
+		t.Fatalf("evicted client still limited: %v", err)
+	}
+}
+
+func TestRateLimiterConfig(t *testing.T) {
+	invalid := []RateLimitConfig{
+		{Client: &RateLimit{Rate: 0}},
+		{Namespaces: map[string]RateLimit{"eth": {Rate: -1}}},
+		{Methods: map[string]RateLimit{"eth_call": {Rate: 1, Burst: -1}}},
+	}
+	for i, config := range invalid {
+		if _, err := NewRateLimiter(config); err == nil {
+			t.Errorf("config %d: no error for invalid limits", i)
+		}
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{
+		Methods: map[string]RateLimit{"test_echo": {Rate: 0.001, Burst: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer()
+	server.SetRateLimiter(limiter)
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var result echoResult
+	for i := 0; i < 2; i++ {
+		if err := client.Call(&result, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	err = client.Call(&result, "test_echo", "hello", 10, &echoArgs{"world"})
+	var rpcErr Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	// Other methods are not limited
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CallRecord describes a method call served by the server, e.g. for access
+// logging.
+type CallRecord struct {
+	Method    string
+	Params    json.RawMessage // Raw parameters of the call, must not be modified
+	Peer      PeerInfo
+	Start     time.Time
+	Duration  time.Duration
+	ErrorCode int    // JSON-RPC error code, zero if the call succeeded
+	Error     string // JSON-RPC error message
+}
+
+// CallRecorder is invoked with every method call served, once it completed. It
+// is called on the serving goroutine, so it must not block.
+type CallRecorder func(rec *CallRecord)
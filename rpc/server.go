@@ -47,6 +47,9 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	recorder CallRecorder
+	limiter  *RateLimiter
+	signer   *ResponseSigner
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -67,6 +70,24 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetCallRecorder sets a function to invoke for every method call served. It
+// must be set before the server starts serving requests.
+func (s *Server) SetCallRecorder(recorder CallRecorder) {
+	s.recorder = recorder
+}
+
+// SetRateLimiter sets the rate limiter applied to the method calls of remote
+// clients. It must be set before the server starts serving requests.
+func (s *Server) SetRateLimiter(limiter *RateLimiter) {
+	s.limiter = limiter
+}
+
+// SetResponseSigner sets the signer attaching node signatures to the results of
+// selected methods. It must be set before the server starts serving requests.
+func (s *Server) SetResponseSigner(signer *ResponseSigner) {
+	s.signer = signer
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
@@ -84,7 +105,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.recorder, s.limiter, s.signer)
 	<-codec.closed()
 	c.Close()
 }
@@ -100,6 +121,9 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 
 	h := newHandler(ctx, codec, s.idgen, &s.services)
 	h.allowSubscribe = false
+	h.recorder = s.recorder
+	h.limiter = s.limiter
+	h.signer = s.signer
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.readBatch()
@@ -168,6 +192,8 @@ type PeerInfo struct {
 		UserAgent string
 		Origin    string
 		Host      string
+		// Client application identifier, from the X-Client-ID header.
+		ClientID string
 	}
 }
 
@@ -0,0 +1,135 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// responseHashPrefix domain separates the signed response hashes from other data
+// signed with the node key.
+var responseHashPrefix = []byte("\x19Ethereum RPC Response:\n")
+
+// ResponseSignature is attached as the "signature" member to the responses of
+// signed method calls.
+type ResponseSignature struct {
+	Node      hexutil.Bytes `json:"node"` // ID of the signing node, keccak256 of its public key
+	Hash      common.Hash   `json:"hash"` // Canonical hash of the response, see ResponseHash
+	Signature hexutil.Bytes `json:"sig"`  // 65 byte [R || S || V] signature of the hash
+}
+
+// ResponseSigner signs the results of selected methods with the identity key of
+// the node, so downstream caches and clients can attribute responses to the
+// node serving them.
+type ResponseSigner struct {
+	key     *ecdsa.PrivateKey
+	id      []byte
+	methods map[string]bool
+}
+
+// NewResponseSigner creates a signer for the results of the given methods.
+func NewResponseSigner(key *ecdsa.PrivateKey, methods []string) *ResponseSigner {
+	s := &ResponseSigner{
+		key:     key,
+		id:      nodeID(&key.PublicKey),
+		methods: make(map[string]bool, len(methods)),
+	}
+	for _, method := range methods {
+		s.methods[method] = true
+	}
+	return s
+}
+
+// sign returns the signature of a call result, or nil if the results of the
+// method are not signed.
+func (s *ResponseSigner) sign(method string, params, result json.RawMessage) (*ResponseSignature, error) {
+	if !s.methods[method] {
+		return nil, nil
+	}
+	hash, err := ResponseHash(method, params, result)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(hash[:], s.key)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseSignature{Node: s.id, Hash: hash, Signature: sig}, nil
+}
+
+// ResponseHash computes the canonical hash of the response to a method call,
+// which is signed by the serving node. The parameters and result are hashed in
+// their compact JSON encoding, so whitespace changes made by intermediaries do
+// not invalidate the signature:
+//
+//	keccak256("\x19Ethereum RPC Response:\n" || keccak256(method) || keccak256(params) || keccak256(result))
+//
+// Missing parameters are hashed as the empty array.
+func ResponseHash(method string, params, result json.RawMessage) (common.Hash, error) {
+	if len(params) == 0 {
+		params = json.RawMessage("[]")
+	}
+	var cparams, cresult bytes.Buffer
+	if err := json.Compact(&cparams, params); err != nil {
+		return common.Hash{}, fmt.Errorf("invalid params: %v", err)
+	}
+	if err := json.Compact(&cresult, result); err != nil {
+		return common.Hash{}, fmt.Errorf("invalid result: %v", err)
+	}
+	return crypto.Keccak256Hash(
+		responseHashPrefix,
+		crypto.Keccak256([]byte(method)),
+		crypto.Keccak256(cparams.Bytes()),
+		crypto.Keccak256(cresult.Bytes()),
+	), nil
+}
+
+// VerifyResponse checks the signature of the response to a method call and
+// returns the public key of the signing node.
+func VerifyResponse(method string, params, result json.RawMessage, sig *ResponseSignature) (*ecdsa.PublicKey, error) {
+	if sig == nil {
+		return nil, errors.New("response not signed")
+	}
+	hash, err := ResponseHash(method, params, result)
+	if err != nil {
+		return nil, err
+	}
+	if hash != sig.Hash {
+		return nil, fmt.Errorf("response hash mismatch: have %x, signed %x", hash, sig.Hash)
+	}
+	pub, err := crypto.SigToPub(hash[:], sig.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(nodeID(pub), sig.Node) {
+		return nil, fmt.Errorf("response signed by %x, not by node %x", nodeID(pub), sig.Node)
+	}
+	return pub, nil
+}
+
+// nodeID returns the node ID of the public key, as in the v4 identity scheme.
+func nodeID(pub *ecdsa.PublicKey) []byte {
+	return crypto.Keccak256(crypto.FromECDSAPub(pub)[1:])
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestServerResponseSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := newTestServer()
+	server.SetResponseSigner(NewResponseSigner(key, []string{"test_echo"}))
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	call := func(body string) *jsonrpcMessage {
+		t.Helper()
+		resp, err := http.Post(httpsrv.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		msg := new(jsonrpcMessage)
+		if err := json.NewDecoder(resp.Body).Decode(msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	// Results of selected methods are signed
+	params := `[ "hello", 10, {"S": "world"} ]`
+	msg := call(`{"jsonrpc":"2.0","id":1,"method":"test_echo","params":` + params + `}`)
+	if msg.Signature == nil {
+		t.Fatalf("response not signed: %s", msg)
+	}
+	pub, err := VerifyResponse("test_echo", json.RawMessage(`["hello",10,{"S":"world"}]`), msg.Result, msg.Signature)
+	if err != nil {
+		t.Fatalf("invalid signature: %v", err)
+	}
+	if crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatal("response signed by wrong key")
+	}
+	// The signature covers the method, parameters and result
+	if _, err := VerifyResponse("test_echoWithCtx", json.RawMessage(params), msg.Result, msg.Signature); err == nil {
+		t.Error("signature valid for different method")
+	}
+	if _, err := VerifyResponse("test_echo", json.RawMessage(`["hello",11,{"S":"world"}]`), msg.Result, msg.Signature); err == nil {
+		t.Error("signature valid for different params")
+	}
+	if _, err := VerifyResponse("test_echo", json.RawMessage(params), json.RawMessage(`{"String":"hello","Int":11,"Args":{"S":"world"}}`), msg.Signature); err == nil {
+		t.Error("signature valid for different result")
+	}
+	// Other methods and errors are not signed
+	if msg := call(`{"jsonrpc":"2.0","id":1,"method":"test_echoWithCtx","params":` + params + `}`); msg.Signature != nil {
+		t.Errorf("unselected method signed: %s", msg)
+	}
+	if msg := call(`{"jsonrpc":"2.0","id":1,"method":"test_echo","params":[]}`); msg.Error == nil || msg.Signature != nil {
+		t.Errorf("failed call signed: %s", msg)
+	}
+}
@@ -255,6 +255,12 @@ func (sub *ClientSubscription) Err() <-chan error {
 	return sub.err
 }
 
+// ID returns the server assigned ID of the subscription, e.g. for methods which
+// take a subscription as argument.
+func (sub *ClientSubscription) ID() ID {
+	return ID(sub.subid)
+}
+
 // Unsubscribe unsubscribes the notification and closes the error channel.
 // It can safely be called more than once.
 func (sub *ClientSubscription) Unsubscribe() {
@@ -42,9 +42,9 @@ const (
 	// numberOfAccountsToDerive For hardware wallets, the number of accounts to derive
 	numberOfAccountsToDerive = 10
 	// ExternalAPIVersion -- see extapi_changelog.md
-	ExternalAPIVersion = "6.1.0"
+	ExternalAPIVersion = "6.2.0"
 	// InternalAPIVersion -- see intapi_changelog.md
-	InternalAPIVersion = "7.0.1"
+	InternalAPIVersion = "7.1.0"
 )
 
 // ExternalAPI defines the external API through which signing requests are made.
@@ -380,6 +380,12 @@ func (api *SignerAPI) derivationLoop(events chan accounts.WalletEvent) {
 		case accounts.WalletDropped:
 			log.Info("Old wallet dropped", "url", event.Wallet.URL())
 			event.Wallet.Close()
+		case accounts.KeyFileCorrupted:
+			log.Warn("Keystore file corrupted on disk", "url", event.Wallet.URL())
+		case accounts.KeyFileReplaced:
+			log.Warn("Keystore file replaced on disk", "url", event.Wallet.URL(), "address", event.Wallet.Accounts()[0].Address)
+		case accounts.PassphraseRotated:
+			log.Info("Keystore passphrase rotated", "url", event.Wallet.URL())
 		}
 	}
 }
@@ -318,3 +318,19 @@ func TestSignTx(t *testing.T) {
 		t.Error("Expected tx to be modified by UI")
 	}
 }
+
+func TestAddSignatures(t *testing.T) {
+	api, _ := setup(t)
+	uiApi := core.NewUIServerAPI(api)
+
+	added, err := uiApi.AddSignatures([]string{"clefTestTransfer(address,uint256,bytes32)", "event ClefTestTransfer(address,address,uint256)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Errorf("added signatures mismatch: have %d, want 2", added)
+	}
+	if added, _ = uiApi.AddSignatures([]string{"clefTestTransfer(address,uint256,bytes32)"}); added != 0 {
+		t.Errorf("known signature added again")
+	}
+}
@@ -30,7 +30,6 @@ import (
 	"unicode"
 	"unicode/utf8"
 
-	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
@@ -173,21 +172,23 @@ type SigFormat struct {
 	ByteVersion byte
 }
 
+// The signature formats use the mime types defined in package accounts, which
+// can't be referenced here as accounts depends on this package.
 var (
 	IntendedValidator = SigFormat{
-		accounts.MimetypeDataWithValidator,
+		"data/validator",
 		0x00,
 	}
 	DataTyped = SigFormat{
-		accounts.MimetypeTypedData,
+		"data/typed",
 		0x01,
 	}
 	ApplicationClique = SigFormat{
-		accounts.MimetypeClique,
+		"application/x-clique-header",
 		0x02,
 	}
 	TextPlain = SigFormat{
-		accounts.MimetypeTextPlain,
+		"text/plain",
 		0x45,
 	}
 )
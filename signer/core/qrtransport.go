@@ -0,0 +1,57 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/qrwallet"
+)
+
+// uiTransport exchanges the sign requests of air-gapped QR code wallets via the
+// UI, which is expected to render the displayed UR parts as QR codes and to
+// return the parts scanned from the wallet as input.
+type uiTransport struct {
+	ui UIClientAPI
+}
+
+// NewUITransport creates a QR wallet transport showing the sign requests to the
+// user and asking them for the scanned responses.
+func NewUITransport(ui UIClientAPI) qrwallet.Transport {
+	return &uiTransport{ui: ui}
+}
+
+func (t *uiTransport) Display(parts []string) error {
+	t.ui.ShowInfo("Scan the sign request with the air-gapped wallet:\n\n" + strings.Join(parts, "\n"))
+	return nil
+}
+
+func (t *uiTransport) Scan() (string, error) {
+	resp, err := t.ui.OnInputRequired(UserInputRequest{
+		Title:  "QR wallet signature",
+		Prompt: "Enter the part scanned from the air-gapped wallet (empty to abort)",
+	})
+	if err != nil {
+		return "", err
+	}
+	part := strings.TrimSpace(resp.Text)
+	if part == "" {
+		return "", errors.New("signing aborted")
+	}
+	return part, nil
+}
@@ -205,6 +205,26 @@ func (api *UIServerAPI) New(ctx context.Context) (common.Address, error) {
 	return api.extApi.newAccount()
 }
 
+// signatureStore is implemented by validators backed by a signature database
+// which accepts new signatures.
+type signatureStore interface {
+	AddSignatures(signatures []string) (int, error)
+}
+
+// AddSignatures adds method signatures, and event signatures prefixed by "event ",
+// to the signature database used to decode call data, returning the number of new
+// ones. Identifiers are derived from the signatures and known ones are never
+// overwritten.
+// Example call
+// {"jsonrpc":"2.0","method":"clef_addSignatures","params":[["transfer(address,uint256)"]], "id":5}
+func (api *UIServerAPI) AddSignatures(signatures []string) (int, error) {
+	store, ok := api.extApi.validator.(signatureStore)
+	if !ok {
+		return 0, errors.New("signature database not available")
+	}
+	return store.AddSignatures(signatures)
+}
+
 // Other methods to be added, not yet implemented are:
 // - Ruleset interaction: add rules, attest rulefiles
 // - Store metadata about accounts, e.g. naming of accounts
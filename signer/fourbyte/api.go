@@ -0,0 +1,43 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fourbyte
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// API exposes lookups in the signature database over RPC, in the signer namespace.
+// Signatures are added via the UI API only.
+type API struct {
+	db *Database
+}
+
+// NewAPI creates an RPC API for the signature database.
+func NewAPI(db *Database) *API {
+	return &API{db: db}
+}
+
+// LookupMethod returns the signature of the method with the given selector.
+func (api *API) LookupMethod(selector hexutil.Bytes) (string, error) {
+	return api.db.Selector(selector)
+}
+
+// LookupEvent returns the signature of the event with the given topic.
+func (api *API) LookupEvent(topic common.Hash) (string, error) {
+	return api.db.Event(topic)
+}
@@ -23,6 +23,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
 )
 
 //go:embed 4byte.json
@@ -34,6 +37,9 @@ type Database struct {
 	embedded   map[string]string
 	custom     map[string]string
 	customPath string
+	lock       sync.RWMutex // Protects the custom dataset
+
+	registry *abi.Registry // Contract ABIs to decode calls with, if any
 }
 
 // newEmpty exists for testing purposes.
@@ -73,9 +79,11 @@ func NewFromFile(path string) (*Database, error) {
 // file) as well as a custom database. The latter will be used to write new
 // values into if they are submitted via the API.
 func NewWithFile(path string) (*Database, error) {
-	db := &Database{make(map[string]string), make(map[string]string), path}
-	db.customPath = path
-
+	db := &Database{
+		embedded:   make(map[string]string),
+		custom:     make(map[string]string),
+		customPath: path,
+	}
 	if err := json.Unmarshal(embeddedJSON, &db.embedded); err != nil {
 		return nil, err
 	}
@@ -92,8 +100,20 @@ func NewWithFile(path string) (*Database, error) {
 	return db, nil
 }
 
+// SetRegistry sets the contract ABIs to decode transactions with before falling
+// back to the 4byte signatures.
+func (db *Database) SetRegistry(registry *abi.Registry) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.registry = registry
+}
+
 // Size returns the number of 4byte entries in the embedded and custom datasets.
 func (db *Database) Size() (int, int) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
 	return len(db.embedded), len(db.custom)
 }
 
@@ -104,6 +124,9 @@ func (db *Database) Selector(id []byte) (string, error) {
 	if len(id) < 4 {
 		return "", fmt.Errorf("expected 4-byte id, got %d", len(id))
 	}
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
 	sig := hex.EncodeToString(id[:4])
 	if selector, exists := db.embedded[sig]; exists {
 		return selector, nil
@@ -124,11 +147,24 @@ func (db *Database) AddSelector(selector string, data []byte) error {
 	if len(data) < 4 {
 		return nil
 	}
-	if _, err := db.Selector(data[:4]); err == nil {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	id := hex.EncodeToString(data[:4])
+	if _, exists := db.embedded[id]; exists {
+		return nil
+	}
+	if _, exists := db.custom[id]; exists {
 		return nil
 	}
 	// Inject the custom selector into the database and persist if needed
-	db.custom[hex.EncodeToString(data[:4])] = selector
+	db.custom[id] = selector
+	return db.save()
+}
+
+// save persists the custom dataset to disk, if custom database saving is enabled.
+// The lock must be held.
+func (db *Database) save() error {
 	if db.customPath == "" {
 		return nil
 	}
@@ -85,3 +85,53 @@ func TestCustomDatabase(t *testing.T) {
 		t.Fatalf("Failed to find a match for persisted abi signature: %v", err)
 	}
 }
+
+// Tests that signature dumps can be imported in all supported formats.
+func TestImportSignatures(t *testing.T) {
+	filename := fmt.Sprintf("%s/4byte_custom.json", t.TempDir())
+
+	db, err := NewWithFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.embedded = make(map[string]string)
+
+	tests := []struct {
+		dump  string
+		added int
+	}{
+		{`{"a9059cbb": "transfer(address,uint256)"}`, 1},
+		{`["transfer(address,uint256)", "approve(address,uint256)"]`, 1},
+		{"# comment\nevent Transfer(address,address,uint256)\nbalanceOf(address)\n", 2},
+		{`{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef": "Transfer(address,address,uint256)"}`, 0},
+	}
+	for i, tt := range tests {
+		added, err := db.Import(strings.NewReader(tt.dump))
+		if err != nil {
+			t.Fatalf("test %d: import failed: %v", i, err)
+		}
+		if added != tt.added {
+			t.Errorf("test %d: added mismatch: have %d, want %d", i, added, tt.added)
+		}
+	}
+	// Mismatching identifiers and invalid signatures should be rejected
+	if _, err := db.Import(strings.NewReader(`{"deadbeef": "transfer(address,uint256)"}`)); err == nil {
+		t.Error("mismatching identifier accepted")
+	}
+	if _, err := db.AddSignatures([]string{"transfer(address,"}); err == nil {
+		t.Error("invalid signature accepted")
+	}
+	// Check the lookups on a reloaded database
+	db2, err := NewFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db2.custom, db2.embedded = db2.embedded, make(map[string]string)
+	if sig, err := db2.Selector(common.FromHex("0x70a08231")); err != nil || sig != "balanceOf(address)" {
+		t.Errorf("method lookup mismatch: have %q (%v)", sig, err)
+	}
+	topic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	if sig, err := db2.Event(topic); err != nil || sig != "Transfer(address,address,uint256)" {
+		t.Errorf("event lookup mismatch: have %q (%v)", sig, err)
+	}
+}
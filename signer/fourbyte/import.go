@@ -0,0 +1,162 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fourbyte
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// eventPrefix marks event signatures in signature lists.
+const eventPrefix = "event "
+
+// Event looks up the signature of an event by its topic. Event signatures are
+// only available if they were added to the custom dataset.
+func (db *Database) Event(topic common.Hash) (string, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	id := hex.EncodeToString(topic[:])
+	if signature, exists := db.custom[id]; exists {
+		return signature, nil
+	}
+	return "", fmt.Errorf("event %v not found", id)
+}
+
+// AddSignatures adds text signatures to the custom dataset, persisting it if
+// custom database saving is enabled. Method signatures are given as is, e.g.
+// "transfer(address,uint256)", event signatures prefixed by "event ".
+//
+// The identifiers are derived from the signatures, so the database can't be
+// poisoned with wrong ones, and known identifiers are never overwritten. The
+// number of newly added signatures is returned.
+func (db *Database) AddSignatures(signatures []string) (int, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	added := make(map[string]string)
+	for _, signature := range signatures {
+		id, signature, err := signatureID(signature)
+		if err != nil {
+			return 0, err
+		}
+		if _, exists := db.embedded[id]; exists {
+			continue
+		}
+		if _, exists := db.custom[id]; exists {
+			continue
+		}
+		added[id] = signature
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+	for id, signature := range added {
+		db.custom[id] = signature
+	}
+	return len(added), db.save()
+}
+
+// Import adds the signatures of a dump to the custom dataset. The dump is either
+// a JSON object mapping hex identifiers to signatures, like the embedded dataset,
+// a JSON array of signatures, or plain text with one signature per line. In the
+// latter two, event signatures are prefixed by "event ".
+//
+// Identifiers in JSON objects are checked against their signatures; 4 byte ones
+// denote methods and 32 byte ones events. The number of newly added signatures
+// is returned.
+func (db *Database) Import(r io.Reader) (int, error) {
+	dump, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	var signatures []string
+	switch trimmed := bytes.TrimSpace(dump); {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var entries map[string]string
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return 0, err
+		}
+		for id, signature := range entries {
+			id = strings.ToLower(strings.TrimPrefix(id, "0x"))
+			if len(id) == 64 {
+				signature = eventPrefix + signature
+			}
+			derived, _, err := signatureID(signature)
+			if err != nil {
+				return 0, err
+			}
+			if derived != id {
+				return 0, fmt.Errorf("signature %q doesn't match identifier %s", signature, id)
+			}
+			signatures = append(signatures, signature)
+		}
+
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &signatures); err != nil {
+			return 0, err
+		}
+
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				signatures = append(signatures, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return 0, err
+		}
+	}
+	return db.AddSignatures(signatures)
+}
+
+// signatureID validates a method or event signature and derives its identifier,
+// returning it along with the canonical signature without any event prefix.
+func signatureID(signature string) (string, string, error) {
+	signature = strings.TrimSpace(signature)
+	event := strings.HasPrefix(signature, eventPrefix)
+	if event {
+		signature = strings.TrimSpace(strings.TrimPrefix(signature, eventPrefix))
+	}
+	// Hash the canonical form of the signature, as e.g. "uint" abbreviates "uint256"
+	abidata, err := parseSelector(signature)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid signature %q: %v", signature, err)
+	}
+	spec, err := abi.JSON(bytes.NewReader(abidata))
+	if err != nil || len(spec.Methods) != 1 {
+		return "", "", fmt.Errorf("invalid signature %q: %v", signature, err)
+	}
+	for _, method := range spec.Methods {
+		signature = method.Sig
+	}
+	hash := crypto.Keccak256([]byte(signature))
+	if event {
+		return hex.EncodeToString(hash), signature, nil
+	}
+	return hex.EncodeToString(hash[:4]), signature, nil
+}
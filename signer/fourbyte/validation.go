@@ -84,6 +84,9 @@ func (db *Database) ValidateTransaction(selector *string, tx *apitypes.SendTxArg
 		messages.Crit("Both 'gasPrice' and 'maxPriorityFeePerGas' specified.")
 	}
 	// Semantic fields validated, try to make heads or tails of the call data
+	if selector == nil && db.resolveCallData(tx.To.Address(), data, messages) {
+		return messages, nil
+	}
 	db.ValidateCallData(selector, data, messages)
 	return messages, nil
 }
@@ -125,3 +128,26 @@ func (db *Database) ValidateCallData(selector *string, data []byte, messages *ap
 		messages.Info(fmt.Sprintf("Transaction invokes the following method: %q", info.String()))
 	}
 }
+
+// resolveCallData decodes the call data with the contract ABIs of the registry,
+// reporting whether a matching method was found. The method is only attributed
+// to a contract if the target address is bound to it.
+func (db *Database) resolveCallData(to common.Address, data []byte, messages *apitypes.ValidationMessages) bool {
+	db.lock.RLock()
+	registry := db.registry
+	db.lock.RUnlock()
+
+	if registry == nil || len(data) == 0 {
+		return false
+	}
+	if call, err := registry.ResolveBound(to, data); err == nil {
+		messages.Info(fmt.Sprintf("Transaction invokes the following method of contract %s: %s", call.Contract, call))
+		return true
+	}
+	call, err := registry.Resolve(&to, data)
+	if err != nil {
+		return false
+	}
+	messages.Info(fmt.Sprintf("Transaction invokes the following method: %s", call))
+	return true
+}
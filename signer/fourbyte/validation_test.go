@@ -18,8 +18,10 @@ package fourbyte
 
 import (
 	"math/big"
+	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
@@ -134,3 +136,38 @@ func TestTransactionValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestTransactionValidationWithRegistry(t *testing.T) {
+	registry := abi.NewRegistry()
+	err := registry.Load("Token", strings.NewReader(`[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := newEmpty()
+	db.SetRegistry(registry)
+
+	tx := dummyTxArgs(txtestcase{
+		from: "000000000000000000000000000000000000dead", to: "0x000000000000000000000000000000000000dEaD",
+		n: "0x01", g: "0x20", gp: "0x40", value: "0x00",
+		d: "0xa9059cbb000000000000000000000000000000000000000000000000000000000000dead00000000000000000000000000000000000000000000000000000000000003e8",
+	})
+	msgs, err := db.ValidateTransaction(nil, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Transaction invokes the following method: transfer(to=0x000000000000000000000000000000000000dEaD, amount=1000)"
+	if len(msgs.Messages) != 1 || msgs.Messages[0].Message != want {
+		t.Fatalf("unexpected validation messages: %v", msgs.Messages)
+	}
+	// Once the target is bound to the contract, the call is attributed to it
+	if err := registry.Bind(common.HexToAddress("0x000000000000000000000000000000000000dEaD"), "Token"); err != nil {
+		t.Fatal(err)
+	}
+	if msgs, err = db.ValidateTransaction(nil, tx); err != nil {
+		t.Fatal(err)
+	}
+	want = "Transaction invokes the following method of contract Token: transfer(to=0x000000000000000000000000000000000000dEaD, amount=1000)"
+	if len(msgs.Messages) != 1 || msgs.Messages[0].Message != want {
+		t.Fatalf("unexpected validation messages: %v", msgs.Messages)
+	}
+}
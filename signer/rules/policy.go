@@ -0,0 +1,427 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/ethereum/go-ethereum/signer/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyFile is the format of a declarative ruleset, written in YAML or JSON.
+// Transactions to a denied address are rejected. Otherwise the first rule
+// matching the transaction decides: it's approved if within the spending caps of
+// the rule, rejected if not. Spending caps apply to the value of a transaction
+// plus the maximum fee it may pay for gas. Transactions matching no rule are passed on for
+// manual approval, or rejected if the default is "reject". All other requests
+// are always passed on for manual approval.
+type PolicyFile struct {
+	Deny    []string     `yaml:"deny"`    // Destination addresses to always reject
+	Default string       `yaml:"default"` // "manual" (default) or "reject"
+	Rules   []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule approves the transactions matching all of its filters, within its
+// spending caps.
+type PolicyRule struct {
+	Name    string   `yaml:"name"`    // Name of the rule, identifying its spending record
+	From    []string `yaml:"from"`    // Sending accounts, any if empty
+	To      []string `yaml:"to"`      // Destination addresses, any if empty
+	Create  bool     `yaml:"create"`  // Whether contract creations match, instead of calls
+	Methods []string `yaml:"methods"` // Method signatures or 4 byte selectors, "0x" for no calldata, any if empty
+
+	MaxValue string       `yaml:"maxValue"` // Maximum spend of a single transaction, e.g. "0.5 ether"
+	Limit    *PolicyLimit `yaml:"limit"`    // Maximum spend of the transactions within a time window
+}
+
+// PolicyLimit caps the total spend of the transactions approved by a rule within
+// a sliding time window.
+type PolicyLimit struct {
+	Value  string `yaml:"value"`  // Maximum total value, e.g. "10 ether"
+	Window string `yaml:"window"` // Length of the window, e.g. "24h"
+}
+
+// policyRule is a parsed PolicyRule.
+type policyRule struct {
+	name     string
+	from     map[common.Address]bool
+	to       map[common.Address]bool
+	create   bool
+	methods  map[string]bool // Hex encoded selectors, "0x" for no calldata
+	maxValue *big.Int
+	limit    *big.Int
+	window   time.Duration
+}
+
+// spend is an approved transaction spend recorded for the window limit of a rule.
+type spend struct {
+	Time  int64        `json:"time"`
+	Value *hexutil.Big `json:"value"`
+}
+
+// policyUI is an implementation of UIClientAPI evaluating a declarative ruleset
+// natively, without a JavaScript VM.
+type policyUI struct {
+	next    core.UIClientAPI // The next handler, for manual processing
+	storage storage.Storage  // Storage of the spending records
+	deny    map[common.Address]bool
+	reject  bool // Whether to reject unmatched transactions
+	rules   []*policyRule
+	lock    sync.Mutex // Serializes limit checks with spending updates
+	now     func() time.Time
+}
+
+// NewPolicyEvaluator creates a UI evaluating the given YAML or JSON ruleset,
+// keeping the spending records of the rules in the given storage.
+func NewPolicyEvaluator(next core.UIClientAPI, store storage.Storage, policy []byte) (*policyUI, error) {
+	var file PolicyFile
+	dec := yaml.NewDecoder(bytes.NewReader(policy))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid policy: %v", err)
+	}
+	ui := &policyUI{
+		next:    next,
+		storage: store,
+		deny:    make(map[common.Address]bool),
+		now:     time.Now,
+	}
+	switch file.Default {
+	case "", "manual":
+	case "reject":
+		ui.reject = true
+	default:
+		return nil, fmt.Errorf("invalid default %q", file.Default)
+	}
+	if err := parseAddresses(file.Deny, ui.deny); err != nil {
+		return nil, fmt.Errorf("invalid deny list: %v", err)
+	}
+	names := make(map[string]bool)
+	for i, rule := range file.Rules {
+		parsed, err := parsePolicyRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+		if parsed.name == "" {
+			parsed.name = fmt.Sprintf("rule%d", i)
+		}
+		if names[parsed.name] {
+			return nil, fmt.Errorf("rule %d: duplicate name %q", i, parsed.name)
+		}
+		names[parsed.name] = true
+		ui.rules = append(ui.rules, parsed)
+	}
+	return ui, nil
+}
+
+func parsePolicyRule(rule PolicyRule) (*policyRule, error) {
+	parsed := &policyRule{
+		name:   rule.Name,
+		create: rule.Create,
+	}
+	if len(rule.From) > 0 {
+		parsed.from = make(map[common.Address]bool)
+		if err := parseAddresses(rule.From, parsed.from); err != nil {
+			return nil, fmt.Errorf("invalid from: %v", err)
+		}
+	}
+	if len(rule.To) > 0 {
+		if rule.Create {
+			return nil, errors.New("contract creations have no destination")
+		}
+		parsed.to = make(map[common.Address]bool)
+		if err := parseAddresses(rule.To, parsed.to); err != nil {
+			return nil, fmt.Errorf("invalid to: %v", err)
+		}
+	}
+	if len(rule.Methods) > 0 {
+		parsed.methods = make(map[string]bool)
+		for _, method := range rule.Methods {
+			selector, err := parseSelector(method)
+			if err != nil {
+				return nil, err
+			}
+			parsed.methods[selector] = true
+		}
+	}
+	var err error
+	if rule.MaxValue != "" {
+		if parsed.maxValue, err = parseValue(rule.MaxValue); err != nil {
+			return nil, fmt.Errorf("invalid maxValue: %v", err)
+		}
+	}
+	if rule.Limit != nil {
+		if parsed.limit, err = parseValue(rule.Limit.Value); err != nil {
+			return nil, fmt.Errorf("invalid limit value: %v", err)
+		}
+		if parsed.window, err = time.ParseDuration(rule.Limit.Window); err != nil {
+			return nil, fmt.Errorf("invalid limit window: %v", err)
+		}
+		if parsed.window <= 0 {
+			return nil, errors.New("limit window must be positive")
+		}
+	}
+	return parsed, nil
+}
+
+func parseAddresses(list []string, set map[common.Address]bool) error {
+	for _, addr := range list {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("invalid address %q", addr)
+		}
+		set[common.HexToAddress(addr)] = true
+	}
+	return nil
+}
+
+// parseSelector converts a method signature or selector into a hex encoded
+// selector.
+func parseSelector(method string) (string, error) {
+	switch {
+	case method == "0x":
+		return method, nil
+	case strings.HasPrefix(method, "0x"):
+		selector, err := hexutil.Decode(method)
+		if err != nil || len(selector) != 4 {
+			return "", fmt.Errorf("invalid method selector %q", method)
+		}
+		return hexutil.Encode(selector), nil
+	case strings.Contains(method, "(") && strings.HasSuffix(method, ")"):
+		return hexutil.Encode(crypto.Keccak256([]byte(strings.ReplaceAll(method, " ", "")))[:4]), nil
+	default:
+		return "", fmt.Errorf("invalid method %q", method)
+	}
+}
+
+// parseValue parses an amount of wei, optionally with a gwei or ether unit.
+func parseValue(value string) (*big.Int, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid value %q", value)
+	}
+	unit := big.NewInt(1)
+	if len(fields) == 2 {
+		switch strings.ToLower(fields[1]) {
+		case "wei":
+		case "gwei":
+			unit.SetUint64(params.GWei)
+		case "ether", "eth":
+			unit.SetUint64(params.Ether)
+		default:
+			return nil, fmt.Errorf("unknown unit %q", fields[1])
+		}
+	}
+	amount, ok := new(big.Rat).SetString(fields[0])
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid value %q", value)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt(unit))
+	if !amount.IsInt() {
+		return nil, fmt.Errorf("value %q is not a whole amount of wei", value)
+	}
+	return amount.Num(), nil
+}
+
+// matches reports whether the rule applies to the transaction.
+func (r *policyRule) matches(tx *core.SignTxRequest) bool {
+	args := tx.Transaction
+	if r.from != nil && !r.from[args.From.Address()] {
+		return false
+	}
+	if args.To == nil {
+		if !r.create {
+			return false
+		}
+	} else if r.create || r.to != nil && !r.to[args.To.Address()] {
+		return false
+	}
+	if r.methods != nil {
+		var data []byte
+		if args.Input != nil {
+			data = *args.Input
+		} else if args.Data != nil {
+			data = *args.Data
+		}
+		switch {
+		case len(data) == 0:
+			return r.methods["0x"]
+		case len(data) < 4:
+			return false
+		default:
+			return r.methods[hexutil.Encode(data[:4])]
+		}
+	}
+	return true
+}
+
+// spends returns the values approved by the rule within its window.
+func (ui *policyUI) spends(rule *policyRule, now time.Time) []spend {
+	data, err := ui.storage.Get("policy/" + rule.name)
+	if err != nil {
+		return nil
+	}
+	var all, recent []spend
+	if err := json.Unmarshal([]byte(data), &all); err != nil {
+		log.Warn("Corrupt policy spending record", "rule", rule.name, "err", err)
+		return nil
+	}
+	cutoff := now.Add(-rule.window).Unix()
+	for _, s := range all {
+		if s.Time > cutoff && s.Value != nil {
+			recent = append(recent, s)
+		}
+	}
+	return recent
+}
+
+// maxSpend returns the most a transaction may take from the sender's balance,
+// its value plus the gas limit at the maximum gas price.
+func maxSpend(args *apitypes.SendTxArgs) *big.Int {
+	price := new(big.Int)
+	if args.GasPrice != nil {
+		price.Set(args.GasPrice.ToInt())
+	}
+	if args.MaxFeePerGas != nil && args.MaxFeePerGas.ToInt().Cmp(price) > 0 {
+		price.Set(args.MaxFeePerGas.ToInt())
+	}
+	spend := new(big.Int).Mul(price, new(big.Int).SetUint64(uint64(args.Gas)))
+	return spend.Add(spend, args.Value.ToInt())
+}
+
+// decision is the outcome of evaluating a transaction against the policy.
+type decision int
+
+const (
+	decideManual decision = iota // Up to the next UI
+	decideApprove
+	decideReject
+)
+
+// evaluate decides on a transaction, along with the reason for the decision.
+func (ui *policyUI) evaluate(request *core.SignTxRequest) (decision, string) {
+	if to := request.Transaction.To; to != nil && ui.deny[to.Address()] {
+		return decideReject, "destination denied"
+	}
+	var rule *policyRule
+	for _, r := range ui.rules {
+		if r.matches(request) {
+			rule = r
+			break
+		}
+	}
+	if rule == nil {
+		if ui.reject {
+			return decideReject, "no matching rule"
+		}
+		return decideManual, "no matching rule"
+	}
+	amount := maxSpend(&request.Transaction)
+	if rule.maxValue != nil && amount.Cmp(rule.maxValue) > 0 {
+		return decideReject, fmt.Sprintf("spend above maximum of rule %s", rule.name)
+	}
+	if rule.limit == nil {
+		return decideApprove, fmt.Sprintf("matched rule %s", rule.name)
+	}
+	now := ui.now()
+	spends := ui.spends(rule, now)
+
+	total := new(big.Int).Set(amount)
+	for _, s := range spends {
+		total.Add(total, s.Value.ToInt())
+	}
+	if total.Cmp(rule.limit) > 0 {
+		return decideReject, fmt.Sprintf("spend above window limit of rule %s", rule.name)
+	}
+	// Count the spend towards the limit at approval, even if signing fails later
+	spends = append(spends, spend{Time: now.Unix(), Value: (*hexutil.Big)(amount)})
+	data, _ := json.Marshal(spends)
+	ui.storage.Put("policy/"+rule.name, string(data))
+
+	return decideApprove, fmt.Sprintf("matched rule %s", rule.name)
+}
+
+func (ui *policyUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	ui.lock.Lock()
+	result, reason := ui.evaluate(request)
+	ui.lock.Unlock()
+
+	switch result {
+	case decideManual:
+		log.Info("Policy undecided, going to manual", "reason", reason)
+		return ui.next.ApproveTx(request)
+	case decideApprove:
+		log.Info("Policy approved transaction", "reason", reason)
+		return core.SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+	default:
+		log.Info("Policy rejected transaction", "reason", reason)
+		return core.SignTxResponse{Approved: false}, nil
+	}
+}
+
+func (ui *policyUI) RegisterUIServer(api *core.UIServerAPI) {
+	ui.next.RegisterUIServer(api)
+}
+
+func (ui *policyUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	return ui.next.ApproveSignData(request)
+}
+
+func (ui *policyUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	return ui.next.ApproveListing(request)
+}
+
+func (ui *policyUI) ApproveNewAccount(request *core.NewAccountRequest) (core.NewAccountResponse, error) {
+	return ui.next.ApproveNewAccount(request)
+}
+
+func (ui *policyUI) OnInputRequired(info core.UserInputRequest) (core.UserInputResponse, error) {
+	return ui.next.OnInputRequired(info)
+}
+
+func (ui *policyUI) ShowError(message string) {
+	log.Error(message)
+	ui.next.ShowError(message)
+}
+
+func (ui *policyUI) ShowInfo(message string) {
+	log.Info(message)
+	ui.next.ShowInfo(message)
+}
+
+func (ui *policyUI) OnSignerStartup(info core.StartupInfo) {
+	ui.next.OnSignerStartup(info)
+}
+
+func (ui *policyUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	ui.next.OnApprovedTx(tx)
+}
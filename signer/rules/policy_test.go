@@ -0,0 +1,205 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/signer/core"
+	"github.com/ethereum/go-ethereum/signer/storage"
+)
+
+const testPolicy = `
+deny:
+  - "0x000000000000000000000000000000000000bad0"
+rules:
+  - name: token
+    from: ["0x0000000000000000000000000000000000001337"]
+    to: ["0x000000000000000000000000000000000000c0de"]
+    methods: ["transfer(address, uint256)", "0x095ea7b3"]
+    maxValue: 0
+  - name: petty
+    methods: ["0x"]
+    maxValue: 0.5 ether
+    limit:
+      value: 1 ether
+      window: 1h
+`
+
+// manualUI records the requests passed on for manual processing, rejecting them.
+type manualUI struct {
+	alwaysDenyUI
+	requests int
+}
+
+func (ui *manualUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	ui.requests++
+	return core.SignTxResponse{Approved: false}, nil
+}
+
+// policyTx creates a transaction request without any gas fees.
+func policyTx(from, to string, value *big.Int, data []byte) *core.SignTxRequest {
+	req := dummyTx(hexutil.Big(*value))
+	req.Transaction.GasPrice = (*hexutil.Big)(new(big.Int))
+	fromAddr, _ := mixAddr(from)
+	req.Transaction.From = *fromAddr
+	req.Transaction.To, _ = mixAddr(to)
+	if data != nil {
+		input := hexutil.Bytes(data)
+		req.Transaction.Input = &input
+	}
+	return req
+}
+
+func TestPolicy(t *testing.T) {
+	manual := new(manualUI)
+	ui, err := NewPolicyEvaluator(manual, storage.NewEphemeralStorage(), []byte(testPolicy))
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+	now := time.Unix(1000000, 0)
+	ui.now = func() time.Time { return now }
+
+	var (
+		owner    = "0x0000000000000000000000000000000000001337"
+		other    = "0x0000000000000000000000000000000000004242"
+		token    = "0x000000000000000000000000000000000000c0de"
+		bad      = "0x000000000000000000000000000000000000bad0"
+		transfer = common.FromHex("0xa9059cbb0000")
+		approve  = common.FromHex("0x095ea7b3")
+		burn     = common.FromHex("0x42966c68")
+		ether    = big.NewInt(params.Ether)
+		half     = new(big.Int).Div(ether, big.NewInt(2))
+		quarter  = new(big.Int).Div(ether, big.NewInt(4))
+	)
+	tests := []struct {
+		tx       *core.SignTxRequest
+		approved bool
+		manual   bool
+		advance  time.Duration
+	}{
+		{tx: policyTx(owner, token, common.Big0, transfer), approved: true},  // token rule
+		{tx: policyTx(owner, token, common.Big0, approve), approved: true},   // selector
+		{tx: policyTx(owner, token, common.Big1, transfer), approved: false}, // value above max
+		{tx: policyTx(owner, token, common.Big0, burn), manual: true},        // unknown method
+		{tx: policyTx(other, token, common.Big0, transfer), manual: true},    // unknown sender
+		{tx: policyTx(owner, bad, common.Big0, nil), approved: false},        // denied
+		{tx: policyTx(other, bad, common.Big1, nil), approved: false},        // denied before petty
+		{tx: policyTx(other, other, half, nil), approved: true},              // petty
+		{tx: policyTx(other, other, ether, nil), approved: false},            // above max
+		{tx: policyTx(other, other, half, nil), approved: true},              // reaches limit
+		{tx: policyTx(other, other, quarter, nil), approved: false},          // above limit
+		{tx: policyTx(other, other, quarter, nil), approved: true, advance: time.Hour},
+	}
+	for i, tt := range tests {
+		now = now.Add(tt.advance)
+		requests := manual.requests
+		resp, err := ui.ApproveTx(tt.tx)
+		switch {
+		case (manual.requests > requests) != tt.manual:
+			t.Errorf("test %d: manual processing mismatch: have %v, want %v", i, manual.requests > requests, tt.manual)
+		case err != nil:
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		case resp.Approved != tt.approved:
+			t.Errorf("test %d: approval mismatch: have %v, want %v", i, resp.Approved, tt.approved)
+		}
+	}
+}
+
+func TestPolicyCreate(t *testing.T) {
+	policy := `{"default": "reject", "rules": [{"create": true, "maxValue": "0"}]}`
+	ui, err := NewPolicyEvaluator(&alwaysDenyUI{}, storage.NewEphemeralStorage(), []byte(policy))
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+	create := policyTx("0x0000000000000000000000000000000000001337", "0x0000000000000000000000000000000000004242", common.Big0, []byte{0x60})
+	create.Transaction.To = nil
+	if resp, err := ui.ApproveTx(create); err != nil || !resp.Approved {
+		t.Errorf("contract creation not approved: %v", err)
+	}
+	call := policyTx("0x0000000000000000000000000000000000001337", "0x0000000000000000000000000000000000004242", common.Big0, nil)
+	if resp, err := ui.ApproveTx(call); err != nil || resp.Approved {
+		t.Errorf("call approved by creation rule: %v", err)
+	}
+}
+
+// Tests that the spending caps include the maximum gas fee of a transaction.
+func TestPolicyGasFees(t *testing.T) {
+	policy := `{"rules": [{"maxValue": "1 ether", "limit": {"value": "1.5 ether", "window": "1h"}}]}`
+	ui, err := NewPolicyEvaluator(&alwaysDenyUI{}, storage.NewEphemeralStorage(), []byte(policy))
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+	var (
+		from  = "0x0000000000000000000000000000000000001337"
+		to    = "0x0000000000000000000000000000000000004242"
+		half  = new(big.Int).Div(big.NewInt(params.Ether), big.NewInt(2))
+		price = new(big.Int).Div(half, big.NewInt(100000)) // 0.5 ether for 100000 gas
+	)
+	feeTx := func(legacy bool) *core.SignTxRequest {
+		tx := policyTx(from, to, half, nil)
+		tx.Transaction.Gas = 100000
+		if legacy {
+			tx.Transaction.GasPrice = (*hexutil.Big)(price)
+		} else {
+			tx.Transaction.GasPrice = nil
+			tx.Transaction.MaxFeePerGas = (*hexutil.Big)(price)
+		}
+		return tx
+	}
+	// Value plus fees right at the maximum, leaving half an ether of the limit
+	if resp, err := ui.ApproveTx(feeTx(false)); err != nil || !resp.Approved {
+		t.Fatalf("transaction within caps not approved: %v", err)
+	}
+	// Value within the remaining limit, but not with fees
+	if resp, err := ui.ApproveTx(feeTx(false)); err != nil || resp.Approved {
+		t.Fatalf("transaction with fees above limit approved: %v", err)
+	}
+	if resp, err := ui.ApproveTx(policyTx(from, to, half, nil)); err != nil || !resp.Approved {
+		t.Fatalf("transaction within caps not approved: %v", err)
+	}
+	// Legacy gas prices count towards the maximum too
+	ui, _ = NewPolicyEvaluator(&alwaysDenyUI{}, storage.NewEphemeralStorage(), []byte(policy))
+	tx := feeTx(true)
+	tx.Transaction.Gas++
+	if resp, err := ui.ApproveTx(tx); err != nil || resp.Approved {
+		t.Fatalf("transaction with fees above maximum approved: %v", err)
+	}
+}
+
+func TestPolicyInvalid(t *testing.T) {
+	for i, policy := range []string{
+		`rules: [{to: ["0x1234"]}]`,
+		`rules: [{methods: ["transfer"]}]`,
+		`rules: [{maxValue: "1 finney"}]`,
+		`rules: [{maxValue: "0.5 wei"}]`,
+		`rules: [{limit: {value: "1 ether", window: "0s"}}]`,
+		`rules: [{name: a}, {name: a}]`,
+		`rules: [{create: true, to: ["0x0000000000000000000000000000000000001337"]}]`,
+		`default: approve`,
+		`unknown: true`,
+	} {
+		if _, err := NewPolicyEvaluator(&alwaysDenyUI{}, storage.NewEphemeralStorage(), []byte(policy)); err == nil {
+			t.Errorf("policy %d: expected error", i)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ssz
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// BytesPerChunk is the size of the chunks merkleized into hash tree roots.
+const BytesPerChunk = 32
+
+// zeroHashes are the roots of the merkle trees of zero chunks, by depth.
+var zeroHashes [65][32]byte
+
+func init() {
+	for i := 1; i < len(zeroHashes); i++ {
+		zeroHashes[i] = hashPair(zeroHashes[i-1], zeroHashes[i-1])
+	}
+}
+
+func hashPair(a, b [32]byte) [32]byte {
+	h := sha256.New()
+	h.Write(a[:])
+	h.Write(b[:])
+
+	var out [32]byte
+	h.Sum(out[:0])
+	return out
+}
+
+// Pack splits data into chunks, padding the last one with zeroes.
+func Pack(data []byte) [][32]byte {
+	chunks := make([][32]byte, (len(data)+BytesPerChunk-1)/BytesPerChunk)
+	for i := range chunks {
+		copy(chunks[i][:], data[i*BytesPerChunk:])
+	}
+	return chunks
+}
+
+// Merkleize returns the root of the merkle tree of the chunks, padded with zero
+// chunks to the limit rounded up to the next power of two. A zero limit pads to
+// the number of chunks.
+func Merkleize(chunks [][32]byte, limit uint64) [32]byte {
+	if limit < uint64(len(chunks)) {
+		limit = uint64(len(chunks))
+	}
+	depth := 0
+	for uint64(1)<<depth < limit {
+		depth++
+	}
+	if len(chunks) == 0 {
+		return zeroHashes[depth]
+	}
+	layer := append([][32]byte{}, chunks...)
+	for d := 0; d < depth; d++ {
+		if len(layer)%2 == 1 {
+			layer = append(layer, zeroHashes[d])
+		}
+		for i := 0; i < len(layer)/2; i++ {
+			layer[i] = hashPair(layer[2*i], layer[2*i+1])
+		}
+		layer = layer[:len(layer)/2]
+	}
+	return layer[0]
+}
+
+// MixInLength mixes the length of a list into the root of its elements.
+func MixInLength(root [32]byte, length uint64) [32]byte {
+	var chunk [32]byte
+	binary.LittleEndian.PutUint64(chunk[:], length)
+	return hashPair(root, chunk)
+}
+
+// MixInSelector mixes the selector of a union into the root of its value.
+func MixInSelector(root [32]byte, selector byte) [32]byte {
+	var chunk [32]byte
+	chunk[0] = selector
+	return hashPair(root, chunk)
+}
+
+// VectorRoot returns the hash tree root of a byte vector, or the serialization of
+// any other basic value like an integer.
+func VectorRoot(data []byte) [32]byte {
+	return Merkleize(Pack(data), 0)
+}
+
+// ByteListRoot returns the hash tree root of a byte list with the given maximum
+// length.
+func ByteListRoot(data []byte, limit uint64) [32]byte {
+	return MixInLength(Merkleize(Pack(data), (limit+BytesPerChunk-1)/BytesPerChunk), uint64(len(data)))
+}
+
+// Uint64Root returns the hash tree root of a uint64.
+func Uint64Root(v uint64) [32]byte {
+	var chunk [32]byte
+	binary.LittleEndian.PutUint64(chunk[:], v)
+	return chunk
+}
+
+// ListRoot returns the hash tree root of a list of composite elements, given the
+// roots of the elements and the maximum number of elements.
+func ListRoot(roots [][32]byte, limit uint64) [32]byte {
+	return MixInLength(Merkleize(roots, limit), uint64(len(roots)))
+}
@@ -0,0 +1,254 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ssz implements the building blocks of the SimpleSerialize (SSZ)
+// encoding of the consensus layer: the serialization of containers and lists
+// with their offsets, and the merkleization computing hash tree roots.
+//
+// The package does not use reflection. Types supporting SSZ assemble their
+// encoding from these primitives, see the core/types package for examples.
+package ssz
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+// Variable is the size passed to SplitContainer and SplitList for fields and
+// elements of variable size.
+const Variable = -1
+
+// offsetSize is the size of the offsets pointing to variable size data.
+const offsetSize = 4
+
+var (
+	ErrSize     = errors.New("ssz: invalid size")
+	ErrOffset   = errors.New("ssz: invalid offset")
+	ErrLimit    = errors.New("ssz: list exceeds its limit")
+	ErrUint256  = errors.New("ssz: integer exceeds 256 bits")
+	ErrSelector = errors.New("ssz: invalid union selector")
+)
+
+// Container assembles the serialization of a container from its fields, which
+// must be added in order.
+type Container struct {
+	fixed    []byte
+	variable [][]byte
+	offsets  []int // Positions of the offsets of the variable fields in fixed
+}
+
+// Fixed adds a fixed-size field.
+func (c *Container) Fixed(data []byte) {
+	c.fixed = append(c.fixed, data...)
+}
+
+// Uint64 adds a uint64 field.
+func (c *Container) Uint64(v uint64) {
+	c.fixed = append(c.fixed, Uint64(v)...)
+}
+
+// Variable adds a variable-size field.
+func (c *Container) Variable(data []byte) {
+	c.offsets = append(c.offsets, len(c.fixed))
+	c.fixed = append(c.fixed, make([]byte, offsetSize)...)
+	c.variable = append(c.variable, data)
+}
+
+// Bytes returns the serialization of the container.
+func (c *Container) Bytes() []byte {
+	out := make([]byte, len(c.fixed), len(c.fixed)+variableSize(c.variable))
+	copy(out, c.fixed)
+
+	offset := len(c.fixed)
+	for i, data := range c.variable {
+		binary.LittleEndian.PutUint32(out[c.offsets[i]:], uint32(offset))
+		out = append(out, data...)
+		offset += len(data)
+	}
+	return out
+}
+
+func variableSize(fields [][]byte) int {
+	size := 0
+	for _, field := range fields {
+		size += len(field)
+	}
+	return size
+}
+
+// SplitContainer splits the serialization of a container into its fields, given
+// their sizes in order. Variable-size fields are denoted by Variable.
+func SplitContainer(data []byte, sizes ...int) ([][]byte, error) {
+	fixed := 0
+	for _, size := range sizes {
+		if size == Variable {
+			fixed += offsetSize
+		} else {
+			fixed += size
+		}
+	}
+	if len(data) < fixed {
+		return nil, ErrSize
+	}
+	var (
+		fields  = make([][]byte, len(sizes))
+		pos     = 0
+		vars    []int // Indices of the variable-size fields
+		offsets []int
+	)
+	for i, size := range sizes {
+		if size != Variable {
+			fields[i] = data[pos : pos+size]
+			pos += size
+			continue
+		}
+		vars = append(vars, i)
+		offsets = append(offsets, int(binary.LittleEndian.Uint32(data[pos:])))
+		pos += offsetSize
+	}
+	if len(vars) == 0 {
+		if len(data) != fixed {
+			return nil, ErrSize
+		}
+		return fields, nil
+	}
+	if offsets[0] != fixed {
+		return nil, ErrOffset
+	}
+	offsets = append(offsets, len(data))
+	for j, i := range vars {
+		if offsets[j+1] < offsets[j] || offsets[j+1] > len(data) {
+			return nil, ErrOffset
+		}
+		fields[i] = data[offsets[j]:offsets[j+1]]
+	}
+	return fields, nil
+}
+
+// EncodeList returns the serialization of a list of serialized elements. If the
+// elements are of variable size, they are preceded by their offsets.
+func EncodeList(elems [][]byte, variable bool) []byte {
+	if !variable {
+		out := make([]byte, 0, variableSize(elems))
+		for _, elem := range elems {
+			out = append(out, elem...)
+		}
+		return out
+	}
+	var c Container
+	for _, elem := range elems {
+		c.Variable(elem)
+	}
+	return c.Bytes()
+}
+
+// SplitList splits the serialization of a list into its elements, given the size
+// of the elements or Variable, and the maximum number of elements.
+func SplitList(data []byte, size int, limit uint64) ([][]byte, error) {
+	if size != Variable {
+		if size == 0 || len(data)%size != 0 {
+			return nil, ErrSize
+		}
+		count := len(data) / size
+		if uint64(count) > limit {
+			return nil, ErrLimit
+		}
+		elems := make([][]byte, count)
+		for i := range elems {
+			elems[i] = data[i*size : (i+1)*size]
+		}
+		return elems, nil
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) < offsetSize {
+		return nil, ErrSize
+	}
+	first := binary.LittleEndian.Uint32(data)
+	if first%offsetSize != 0 || first == 0 {
+		return nil, ErrOffset
+	}
+	count := int(first / offsetSize)
+	if uint64(count) > limit {
+		return nil, ErrLimit
+	}
+	sizes := make([]int, count)
+	for i := range sizes {
+		sizes[i] = Variable
+	}
+	return SplitContainer(data, sizes...)
+}
+
+// EncodeUnion returns the serialization of a union value.
+func EncodeUnion(selector byte, value []byte) []byte {
+	return append([]byte{selector}, value...)
+}
+
+// SplitUnion splits the serialization of a union into its selector and value.
+func SplitUnion(data []byte) (byte, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, ErrSize
+	}
+	if data[0] > 127 {
+		return 0, nil, ErrSelector
+	}
+	return data[0], data[1:], nil
+}
+
+// Uint64 returns the serialization of a uint64.
+func Uint64(v uint64) []byte {
+	out := make([]byte, 8)
+	binary.LittleEndian.PutUint64(out, v)
+	return out
+}
+
+// DecodeUint64 decodes a serialized uint64.
+func DecodeUint64(data []byte) (uint64, error) {
+	if len(data) != 8 {
+		return 0, ErrSize
+	}
+	return binary.LittleEndian.Uint64(data), nil
+}
+
+// Uint256 returns the serialization of a uint256. A nil value encodes as zero.
+func Uint256(v *big.Int) ([]byte, error) {
+	out := make([]byte, 32)
+	if v == nil {
+		return out, nil
+	}
+	if v.Sign() < 0 || v.BitLen() > 256 {
+		return nil, ErrUint256
+	}
+	be := v.Bytes()
+	for i, b := range be {
+		out[len(be)-1-i] = b
+	}
+	return out, nil
+}
+
+// DecodeUint256 decodes a serialized uint256.
+func DecodeUint256(data []byte) (*big.Int, error) {
+	if len(data) != 32 {
+		return nil, ErrSize
+	}
+	be := make([]byte, 32)
+	for i, b := range data {
+		be[31-i] = b
+	}
+	return new(big.Int).SetBytes(be), nil
+}
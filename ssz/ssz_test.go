@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ssz
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestMerkleize(t *testing.T) {
+	var (
+		zero  [32]byte
+		depth = func(s string) [32]byte {
+			var h [32]byte
+			b, _ := hex.DecodeString(s)
+			copy(h[:], b)
+			return h
+		}
+	)
+	tests := []struct {
+		chunks [][32]byte
+		limit  uint64
+		want   [32]byte
+	}{
+		{nil, 0, zero},
+		{[][32]byte{zero}, 0, zero},
+		{nil, 2, depth("f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b")},
+		{[][32]byte{zero, zero}, 0, depth("f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b")},
+		{[][32]byte{zero}, 4, depth("db56114e00fdd4c1f85c892bf35ac9a89289aaecb1ebd0a96cde606a748b5d71")},
+		{[][32]byte{zero, zero, zero}, 0, depth("db56114e00fdd4c1f85c892bf35ac9a89289aaecb1ebd0a96cde606a748b5d71")},
+	}
+	for i, test := range tests {
+		if have := Merkleize(test.chunks, test.limit); have != test.want {
+			t.Errorf("test %d: root mismatch: have %x, want %x", i, have, test.want)
+		}
+	}
+	// A list root only depends on the limit through the depth
+	chunks := Pack(bytes.Repeat([]byte{0xff}, 100))
+	if Merkleize(chunks, 5) != Merkleize(chunks, 8) || Merkleize(chunks, 8) == Merkleize(chunks, 9) {
+		t.Errorf("padding mismatch")
+	}
+}
+
+func TestContainer(t *testing.T) {
+	var c Container
+	c.Uint64(1)
+	c.Variable([]byte("abc"))
+	c.Fixed([]byte{0xff})
+	c.Variable([]byte("de"))
+
+	enc := c.Bytes()
+	want, _ := hex.DecodeString("0100000000000000" + "11000000" + "ff" + "14000000" + "616263" + "6465")
+	if !bytes.Equal(enc, want) {
+		t.Fatalf("encoding mismatch: have %x, want %x", enc, want)
+	}
+	fields, err := SplitContainer(enc, 8, Variable, 1, Variable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fields[1]) != "abc" || string(fields[3]) != "de" || fields[2][0] != 0xff {
+		t.Fatalf("decoded fields mismatch: %x", fields)
+	}
+	// Invalid encodings
+	invalid := [][]byte{
+		enc[:10],
+		append(append([]byte{}, enc[:8]...), append([]byte{0x10, 0, 0, 0}, enc[12:]...)...),
+		append(append([]byte{}, enc[:13]...), append([]byte{0x40, 0, 0, 0}, enc[17:]...)...),
+	}
+	for i, data := range invalid {
+		if _, err := SplitContainer(data, 8, Variable, 1, Variable); err == nil {
+			t.Errorf("invalid encoding %d accepted", i)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	elems := [][]byte{[]byte("a"), nil, []byte("bcd")}
+	enc := EncodeList(elems, true)
+	dec, err := SplitList(enc, Variable, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dec) != 3 || string(dec[0]) != "a" || len(dec[1]) != 0 || string(dec[2]) != "bcd" {
+		t.Fatalf("decoded list mismatch: %q", dec)
+	}
+	if _, err := SplitList(enc, Variable, 2); err != ErrLimit {
+		t.Fatalf("limit error mismatch: have %v, want %v", err, ErrLimit)
+	}
+	fixed := EncodeList([][]byte{{1, 2}, {3, 4}}, false)
+	if dec, err := SplitList(fixed, 2, 2); err != nil || len(dec) != 2 || dec[1][1] != 4 {
+		t.Fatalf("decoded fixed list mismatch: %x, %v", dec, err)
+	}
+	if _, err := SplitList(fixed[:3], 2, 2); err != ErrSize {
+		t.Fatalf("size error mismatch: have %v, want %v", err, ErrSize)
+	}
+}
+
+func TestUint256(t *testing.T) {
+	v := new(big.Int).SetBytes([]byte{0x01, 0x02, 0x03})
+	enc, err := Uint256(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc[0] != 0x03 || enc[1] != 0x02 || enc[2] != 0x01 || len(enc) != 32 {
+		t.Fatalf("encoding mismatch: %x", enc)
+	}
+	if dec, err := DecodeUint256(enc); err != nil || dec.Cmp(v) != 0 {
+		t.Fatalf("decoding mismatch: have %v, %v", dec, err)
+	}
+	if _, err := Uint256(new(big.Int).Lsh(big.NewInt(1), 256)); err != ErrUint256 {
+		t.Fatalf("overflow error mismatch: have %v, want %v", err, ErrUint256)
+	}
+}
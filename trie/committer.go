@@ -18,6 +18,7 @@ package trie
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -30,17 +31,23 @@ type leaf struct {
 
 // committer is the tool used for the trie Commit operation. The committer will
 // capture all dirty nodes during the commit process and keep them cached in
-// insertion order.
+// insertion order, children always preceding their parents.
+//
+// If a worker pool is given, dirty subtries are committed concurrently into
+// separate node sets, which are merged into the parent's before the parent node
+// itself is collected.
 type committer struct {
 	nodes       *NodeSet
 	collectLeaf bool
+	pool        *workerPool // Workers to commit subtries in parallel with, nil if sequential
 }
 
-// newCommitter creates a new committer or picks one from the pool.
-func newCommitter(owner common.Hash, collectLeaf bool) *committer {
+// newCommitter creates a new committer.
+func newCommitter(owner common.Hash, collectLeaf bool, pool *workerPool) *committer {
 	return &committer{
 		nodes:       NewNodeSet(owner),
 		collectLeaf: collectLeaf,
+		pool:        pool,
 	}
 }
 
@@ -105,7 +112,12 @@ func (c *committer) commit(path []byte, n node) (node, error) {
 
 // commitChildren commits the children of the given fullnode
 func (c *committer) commitChildren(path []byte, n *fullNode) ([17]node, error) {
-	var children [17]node
+	var (
+		children [17]node
+		subs     [16]*committer
+		errs     [16]error
+		wg       sync.WaitGroup
+	)
 	for i := 0; i < 16; i++ {
 		child := n.Children[i]
 		if child == nil {
@@ -118,15 +130,41 @@ func (c *committer) commitChildren(path []byte, n *fullNode) ([17]node, error) {
 			children[i] = hn
 			continue
 		}
+		// Hand dirty branches over to idle workers, committing them into their
+		// own node sets. The path needs to be copied as the inline commits of
+		// the siblings keep appending to the original.
+		if _, ok := child.(*fullNode); ok && isDirty(child) && c.pool.tryAcquire() {
+			subs[i] = newCommitter(c.nodes.owner, c.collectLeaf, c.pool)
+
+			wg.Add(1)
+			go func(i int, path []byte) {
+				defer wg.Done()
+				defer c.pool.release()
+
+				children[i], errs[i] = subs[i].commit(path, n.Children[i])
+			}(i, append(common.CopyBytes(path), byte(i)))
+			continue
+		}
 		// Commit the child recursively and store the "hashed" value.
 		// Note the returned node can be some embedded nodes, so it's
 		// possible the type is not hashNode.
 		hashed, err := c.commit(append(path, byte(i)), child)
 		if err != nil {
+			wg.Wait()
 			return children, err
 		}
 		children[i] = hashed
 	}
+	wg.Wait()
+	for i, sub := range subs {
+		if sub == nil {
+			continue
+		}
+		if errs[i] != nil {
+			return children, errs[i]
+		}
+		c.nodes.merge(sub.nodes)
+	}
 	// For the 17th child, it's possible the type is valuenode.
 	if n.Children[16] != nil {
 		children[16] = n.Children[16]
@@ -175,6 +213,12 @@ func (c *committer) store(path []byte, n node) node {
 	return hash
 }
 
+// isDirty reports whether a node was modified since it was last committed.
+func isDirty(n node) bool {
+	_, dirty := n.cache()
+	return dirty
+}
+
 // estimateSize estimates the size of an rlp-encoded node, without actually
 // rlp-encoding it (zero allocs). This method has been experimentally tried, and with a trie
 // with 1000 leaves, the only errors above 1% are on small shortnodes, where this
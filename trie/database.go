@@ -86,6 +86,7 @@ type Database struct {
 	dirtiesSize  common.StorageSize // Storage size of the dirty node cache (exc. metadata)
 	childrenSize common.StorageSize // Storage size of the external children tracking
 	preimages    *preimageStore     // The store for caching preimages
+	workers      *workerPool        // Workers to hash and commit large tries with, nil if sequential
 
 	lock sync.RWMutex
 }
@@ -262,9 +263,10 @@ func expandNode(hash hashNode, n node) node {
 
 // Config defines all necessary options for database.
 type Config struct {
-	Cache     int    // Memory allowance (MB) to use for caching trie nodes in memory
-	Journal   string // Journal of clean cache to survive node restarts
-	Preimages bool   // Flag whether the preimage of trie key is recorded
+	Cache       int    // Memory allowance (MB) to use for caching trie nodes in memory
+	Journal     string // Journal of clean cache to survive node restarts
+	Preimages   bool   // Flag whether the preimage of trie key is recorded
+	Parallelism int    // Number of threads to hash and commit large tries with (0 = number of CPUs)
 }
 
 // NewDatabase creates a new trie database to store ephemeral trie content before
@@ -290,6 +292,10 @@ func NewDatabaseWithConfig(diskdb ethdb.KeyValueStore, config *Config) *Database
 	if config != nil && config.Preimages {
 		preimage = newPreimageStore(diskdb)
 	}
+	workers := defaultWorkers
+	if config != nil && config.Parallelism > 0 {
+		workers = newWorkerPool(config.Parallelism)
+	}
 	db := &Database{
 		diskdb: diskdb,
 		cleans: cleans,
@@ -297,6 +303,7 @@ func NewDatabaseWithConfig(diskdb ethdb.KeyValueStore, config *Config) *Database
 			children: make(map[common.Hash]uint16),
 		}},
 		preimages: preimage,
+		workers:   workers,
 	}
 	return db
 }
@@ -27,10 +27,10 @@ import (
 // hasher is a type used for the trie Hash operation. A hasher has some
 // internal preallocated temp space
 type hasher struct {
-	sha      crypto.KeccakState
-	tmp      []byte
-	encbuf   rlp.EncoderBuffer
-	parallel bool // Whether to use parallel threads when hashing
+	sha    crypto.KeccakState
+	tmp    []byte
+	encbuf rlp.EncoderBuffer
+	pool   *workerPool // Workers to hash subtries in parallel with, nil if sequential
 }
 
 // hasherPool holds pureHashers
@@ -44,9 +44,9 @@ var hasherPool = sync.Pool{
 	},
 }
 
-func newHasher(parallel bool) *hasher {
+func newHasher(pool *workerPool) *hasher {
 	h := hasherPool.Get().(*hasher)
-	h.parallel = parallel
+	h.pool = pool
 	return h
 }
 
@@ -54,6 +54,12 @@ func returnHasherToPool(h *hasher) {
 	hasherPool.Put(h)
 }
 
+// isUnhashed reports whether the hash of a node still needs to be computed.
+func isUnhashed(n node) bool {
+	hash, _ := n.cache()
+	return hash == nil
+}
+
 // hash collapses a node down into a hash node, also returning a copy of the
 // original node initialized with the computed hash to replace the original one.
 func (h *hasher) hash(n node, force bool) (hashed node, cached node) {
@@ -111,31 +117,29 @@ func (h *hasher) hashFullNodeChildren(n *fullNode) (collapsed *fullNode, cached
 	// Hash the full node's children, caching the newly hashed subtrees
 	cached = n.copy()
 	collapsed = n.copy()
-	if h.parallel {
-		var wg sync.WaitGroup
-		wg.Add(16)
-		for i := 0; i < 16; i++ {
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		child := n.Children[i]
+		if child == nil {
+			collapsed.Children[i] = nilValueNode
+			continue
+		}
+		// Hand unhashed branches over to idle workers, the rest is hashed inline
+		if _, ok := child.(*fullNode); ok && isUnhashed(child) && h.pool.tryAcquire() {
+			wg.Add(1)
 			go func(i int) {
-				hasher := newHasher(false)
-				if child := n.Children[i]; child != nil {
-					collapsed.Children[i], cached.Children[i] = hasher.hash(child, false)
-				} else {
-					collapsed.Children[i] = nilValueNode
-				}
+				defer wg.Done()
+				defer h.pool.release()
+
+				hasher := newHasher(h.pool)
+				collapsed.Children[i], cached.Children[i] = hasher.hash(n.Children[i], false)
 				returnHasherToPool(hasher)
-				wg.Done()
 			}(i)
+			continue
 		}
-		wg.Wait()
-	} else {
-		for i := 0; i < 16; i++ {
-			if child := n.Children[i]; child != nil {
-				collapsed.Children[i], cached.Children[i] = h.hash(child, false)
-			} else {
-				collapsed.Children[i] = nilValueNode
-			}
-		}
+		collapsed.Children[i], cached.Children[i] = h.hash(child, false)
 	}
+	wg.Wait()
 	return collapsed, cached
 }
 
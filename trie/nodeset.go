@@ -55,6 +55,15 @@ func (set *NodeSet) add(path string, node *memoryNode) {
 	set.nodes[path] = node
 }
 
+// merge moves the nodes and leaves collected in the given set of the same trie
+// into this set, keeping their insertion order.
+func (set *NodeSet) merge(other *NodeSet) {
+	for _, path := range other.paths {
+		set.add(path, other.nodes[path])
+	}
+	set.leaves = append(set.leaves, other.leaves...)
+}
+
 // addLeaf caches the provided leaf node.
 func (set *NodeSet) addLeaf(node *leaf) {
 	set.leaves = append(set.leaves, node)
@@ -256,7 +256,7 @@ func (t *StateTrie) NodeIterator(start []byte) NodeIterator {
 // The caller must not hold onto the return value because it will become
 // invalid on the next call to hashKey or secKey.
 func (t *StateTrie) hashKey(key []byte) []byte {
-	h := newHasher(false)
+	h := newHasher(nil)
 	h.sha.Reset()
 	h.sha.Write(key)
 	h.sha.Read(t.hashKeyBuf[:])
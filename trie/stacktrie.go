@@ -384,7 +384,7 @@ func (st *StackTrie) insert(key, value []byte) {
 //
 // This method also sets 'st.type' to hashedNode, and clears 'st.key'.
 func (st *StackTrie) hash() {
-	h := newHasher(false)
+	h := newHasher(nil)
 	defer returnHasherToPool(h)
 
 	st.hashRec(h)
@@ -476,7 +476,7 @@ func (st *StackTrie) hashRec(hasher *hasher) {
 
 // Hash returns the hash of the current node.
 func (st *StackTrie) Hash() (h common.Hash) {
-	hasher := newHasher(false)
+	hasher := newHasher(nil)
 	defer returnHasherToPool(hasher)
 
 	st.hashRec(hasher)
@@ -506,7 +506,7 @@ func (st *StackTrie) Commit() (h common.Hash, err error) {
 		return common.Hash{}, ErrCommitDisabled
 	}
 
-	hasher := newHasher(false)
+	hasher := newHasher(nil)
 	defer returnHasherToPool(hasher)
 
 	st.hashRec(hasher)
@@ -595,7 +595,13 @@ func (t *Trie) Commit(collectLeaf bool) (common.Hash, *NodeSet, error) {
 		return emptyRoot, nil, nil
 	}
 	// Derive the hash for all dirty nodes first. We hold the assumption
-	// in the following procedure that all nodes are hashed.
+	// in the following procedure that all nodes are hashed. Whether the
+	// changes are worth committing in parallel needs checking beforehand,
+	// as hashing resets the counter.
+	var pool *workerPool
+	if t.unhashed >= parallelThreshold {
+		pool = t.workers()
+	}
 	rootHash := t.Hash()
 
 	// Do a quick check if we really need to commit. This can happen e.g.
@@ -606,7 +612,7 @@ func (t *Trie) Commit(collectLeaf bool) (common.Hash, *NodeSet, error) {
 		t.root = hashedNode
 		return rootHash, nil, nil
 	}
-	h := newCommitter(t.owner, collectLeaf)
+	h := newCommitter(t.owner, collectLeaf, pool)
 	newRoot, nodes, err := h.Commit(t.root)
 	if err != nil {
 		return common.Hash{}, nil, err
@@ -621,13 +627,25 @@ func (t *Trie) hashRoot() (node, node, error) {
 		return hashNode(emptyRoot.Bytes()), nil, nil
 	}
 	// If the number of changes is below 100, we let one thread handle it
-	h := newHasher(t.unhashed >= 100)
+	var pool *workerPool
+	if t.unhashed >= parallelThreshold {
+		pool = t.workers()
+	}
+	h := newHasher(pool)
 	defer returnHasherToPool(h)
 	hashed, cached := h.hash(t.root, true)
 	t.unhashed = 0
 	return hashed, cached, nil
 }
 
+// workers returns the pool of workers to hash and commit the trie with.
+func (t *Trie) workers() *workerPool {
+	if t.db == nil {
+		return defaultWorkers
+	}
+	return t.db.workers
+}
+
 // Reset drops the referenced root node and cleans all internal state.
 func (t *Trie) Reset() {
 	t.root = nil
@@ -943,6 +943,53 @@ func TestCommitSequenceSmallRoot(t *testing.T) {
 	}
 }
 
+// Tests that hashing and committing a large trie in parallel yields the same root
+// and dirty nodes as doing it sequentially, with children preceding parents.
+func TestParallelCommit(t *testing.T) {
+	addresses, accounts := makeAccounts(5000)
+
+	commit := func(parallelism int) (common.Hash, *NodeSet) {
+		trie := NewEmpty(NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), &Config{Parallelism: parallelism}))
+		for i := range addresses {
+			trie.Update(crypto.Keccak256(addresses[i][:]), accounts[i])
+		}
+		root, nodes, err := trie.Commit(true)
+		if err != nil {
+			t.Fatalf("failed to commit trie: %v", err)
+		}
+		return root, nodes
+	}
+	root, nodes := commit(1)
+	for _, parallelism := range []int{2, 16} {
+		proot, pnodes := commit(parallelism)
+		if proot != root {
+			t.Fatalf("parallelism %d: root mismatch: have %x, want %x", parallelism, proot, root)
+		}
+		if pnodes.Len() != nodes.Len() || len(pnodes.paths) != len(nodes.paths) {
+			t.Fatalf("parallelism %d: node count mismatch: have %d, want %d", parallelism, pnodes.Len(), nodes.Len())
+		}
+		if len(pnodes.leaves) != len(nodes.leaves) {
+			t.Fatalf("parallelism %d: leaf count mismatch: have %d, want %d", parallelism, len(pnodes.leaves), len(nodes.leaves))
+		}
+		seen := make(map[string]bool)
+		for _, path := range pnodes.paths {
+			want, ok := nodes.nodes[path]
+			if !ok {
+				t.Fatalf("parallelism %d: unexpected node at path %x", parallelism, path)
+			}
+			if have := pnodes.nodes[path]; have.hash != want.hash {
+				t.Fatalf("parallelism %d: node mismatch at path %x: have %x, want %x", parallelism, path, have.hash, want.hash)
+			}
+			for i := 0; i < len(path); i++ {
+				if seen[path[:i]] {
+					t.Fatalf("parallelism %d: node at path %x committed after its parent", parallelism, path)
+				}
+			}
+			seen[path] = true
+		}
+	}
+}
+
 // BenchmarkCommitAfterHashFixedSize benchmarks the Commit (after Hash) of a fixed number of updates to a trie.
 // This benchmark is meant to capture the difference on efficiency of small versus large changes. Typically,
 // storage tries are small (a couple of entries), whereas the full post-block account trie update is large (a couple
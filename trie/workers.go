@@ -0,0 +1,64 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import "runtime"
+
+// parallelThreshold is the number of changes to a trie from which on it's worth
+// hashing and committing it in parallel.
+const parallelThreshold = 100
+
+// workerPool bounds the number of goroutines hashing and committing subtries of
+// large tries concurrently. Workers are never waited for: if all of them are
+// busy, the subtrie is processed inline by the caller instead. This keeps both
+// the number of goroutines and the number of in-flight hashers and partial node
+// sets bounded, regardless of the size of the trie.
+type workerPool struct {
+	tokens chan struct{}
+}
+
+// defaultWorkers is the pool used by tries without a database, or with one
+// that was created without an explicit parallelism.
+var defaultWorkers = newWorkerPool(runtime.NumCPU())
+
+// newWorkerPool creates a pool allowing the given number of goroutines to run
+// concurrently, including the calling one. A nil pool is returned if there is
+// nothing to parallelize.
+func newWorkerPool(workers int) *workerPool {
+	if workers <= 1 {
+		return nil
+	}
+	return &workerPool{tokens: make(chan struct{}, workers-1)}
+}
+
+// tryAcquire reserves a worker if one is available, without blocking.
+func (p *workerPool) tryAcquire() bool {
+	if p == nil {
+		return false
+	}
+	select {
+	case p.tokens <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release returns a worker reserved via tryAcquire to the pool.
+func (p *workerPool) release() {
+	<-p.tokens
+}